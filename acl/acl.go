@@ -0,0 +1,312 @@
+package acl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ManagementACL is a singleton used for management tokens
+var ManagementACL *ACL
+
+func init() {
+	var err error
+	ManagementACL, err = NewACL(true, nil)
+	if err != nil {
+		panic(fmt.Errorf("failed to setup management ACL: %v", err))
+	}
+}
+
+// capabilitySet is a type wrapper to help managing a set of capabilities
+type capabilitySet map[string]struct{}
+
+func (c capabilitySet) Check(k string) bool {
+	_, ok := c[k]
+	return ok
+}
+
+func (c capabilitySet) Set(k string) {
+	c[k] = struct{}{}
+}
+
+func (c capabilitySet) Clear() {
+	for cap := range c {
+		delete(c, cap)
+	}
+}
+
+// ACL object is used to convert a set of policies into a structure that
+// can be efficiently evaluated to determine if an action is allowed.
+type ACL struct {
+	// management tokens are allowed to do anything
+	management bool
+
+	// namespaces maps a namespace to a capabilitySet
+	namespaces map[string]capabilitySet
+
+	// variables maps a namespace to the capabilitySet of each variables
+	// path specification
+	variables map[string]map[string]capabilitySet
+
+	agent    string
+	node     string
+	operator string
+	quota    string
+}
+
+// mergeVariables adds the capabilities of the variables policy to the path
+// specifications of the namespace
+func (a *ACL) mergeVariables(ns string, policy *VariablesPolicy) {
+	paths, ok := a.variables[ns]
+	if !ok {
+		paths = make(map[string]capabilitySet)
+		a.variables[ns] = paths
+	}
+
+PATHS:
+	for _, path := range policy.Paths {
+		capabilities, ok := paths[path.PathSpec]
+		if !ok {
+			capabilities = make(capabilitySet)
+			paths[path.PathSpec] = capabilities
+		}
+
+		// Deny always takes precedence
+		if capabilities.Check(VariablesCapabilityDeny) {
+			continue
+		}
+		for _, cap := range path.Capabilities {
+			if cap == VariablesCapabilityDeny {
+				capabilities.Clear()
+				capabilities.Set(VariablesCapabilityDeny)
+				continue PATHS
+			}
+			capabilities.Set(cap)
+		}
+	}
+}
+
+// maxPrivilege returns the policy which grants the most privilege
+// This handles the case of Deny always taking maximum precedence.
+func maxPrivilege(a, b string) string {
+	switch {
+	case a == PolicyDeny || b == PolicyDeny:
+		return PolicyDeny
+	case a == PolicyWrite || b == PolicyWrite:
+		return PolicyWrite
+	case a == PolicyRead || b == PolicyRead:
+		return PolicyRead
+	default:
+		return ""
+	}
+}
+
+// NewACL compiles a set of policies into an ACL object
+func NewACL(management bool, policies []*Policy) (*ACL, error) {
+	// Hot-path management tokens
+	if management {
+		return &ACL{management: true}, nil
+	}
+
+	// Create the ACL object
+	acl := &ACL{
+		namespaces: make(map[string]capabilitySet),
+		variables:  make(map[string]map[string]capabilitySet),
+	}
+
+	for _, policy := range policies {
+	NAMESPACES:
+		for _, ns := range policy.Namespaces {
+			if ns.Variables != nil {
+				acl.mergeVariables(ns.Name, ns.Variables)
+			}
+
+			// Check for existing capabilities
+			capabilities, ok := acl.namespaces[ns.Name]
+			if !ok {
+				capabilities = make(capabilitySet)
+				acl.namespaces[ns.Name] = capabilities
+			}
+
+			// Deny always takes precedence
+			if capabilities.Check(NamespaceCapabilityDeny) {
+				continue NAMESPACES
+			}
+
+			// Add in all the capabilities
+			for _, cap := range ns.Capabilities {
+				if cap == NamespaceCapabilityDeny {
+					// Overwrite any existing capabilities
+					capabilities.Clear()
+					capabilities.Set(NamespaceCapabilityDeny)
+					continue NAMESPACES
+				}
+				capabilities.Set(cap)
+			}
+		}
+
+		// Take the maximum privilege for agent, node, operator and quota
+		if policy.Agent != nil {
+			acl.agent = maxPrivilege(acl.agent, policy.Agent.Policy)
+		}
+		if policy.Node != nil {
+			acl.node = maxPrivilege(acl.node, policy.Node.Policy)
+		}
+		if policy.Operator != nil {
+			acl.operator = maxPrivilege(acl.operator, policy.Operator.Policy)
+		}
+		if policy.Quota != nil {
+			acl.quota = maxPrivilege(acl.quota, policy.Quota.Policy)
+		}
+	}
+	return acl, nil
+}
+
+// AllowNamespaceOperation checks if a given operation is allowed for a namespace
+func (a *ACL) AllowNamespaceOperation(ns string, op string) bool {
+	// Hot path management tokens
+	if a.management {
+		return true
+	}
+
+	// Check for a matching capability set
+	capabilities, ok := a.namespaces[ns]
+	if !ok {
+		return false
+	}
+
+	// Check if the capability has been granted
+	return capabilities.Check(op)
+}
+
+// AllowNamespace checks if any operations are allowed for a namespace
+func (a *ACL) AllowNamespace(ns string) bool {
+	// Hot path management tokens
+	if a.management {
+		return true
+	}
+
+	// Check for a matching capability set
+	capabilities, ok := a.namespaces[ns]
+	if !ok {
+		return false
+	}
+
+	// Check if the capability has been granted
+	if len(capabilities) == 0 {
+		return false
+	}
+
+	return !capabilities.Check(NamespaceCapabilityDeny)
+}
+
+// AllowVariableOperation checks if a given operation is allowed for a
+// variable path of a namespace. The capabilities of the most specific path
+// specification matching the path apply, where an exact match takes
+// precedence over the longest matching prefix.
+func (a *ACL) AllowVariableOperation(ns, path, op string) bool {
+	// Hot path management tokens
+	if a.management {
+		return true
+	}
+
+	// A denied namespace denies all of its variables
+	if capabilities, ok := a.namespaces[ns]; ok && capabilities.Check(NamespaceCapabilityDeny) {
+		return false
+	}
+
+	paths, ok := a.variables[ns]
+	if !ok {
+		return false
+	}
+
+	capabilities, ok := paths[path]
+	if !ok {
+		longest := -1
+		for spec, caps := range paths {
+			if !strings.HasSuffix(spec, "*") {
+				continue
+			}
+			prefix := strings.TrimSuffix(spec, "*")
+			if strings.HasPrefix(path, prefix) && len(prefix) > longest {
+				longest = len(prefix)
+				capabilities = caps
+			}
+		}
+		if capabilities == nil {
+			return false
+		}
+	}
+	if capabilities.Check(VariablesCapabilityDeny) {
+		return false
+	}
+	return capabilities.Check(op)
+}
+
+// AllowAgentRead checks if read operations are allowed for an agent
+func (a *ACL) AllowAgentRead() bool {
+	return a.allowRead(a.agent)
+}
+
+// AllowAgentWrite checks if write operations are allowed for an agent
+func (a *ACL) AllowAgentWrite() bool {
+	return a.allowWrite(a.agent)
+}
+
+// AllowNodeRead checks if read operations are allowed for a node
+func (a *ACL) AllowNodeRead() bool {
+	return a.allowRead(a.node)
+}
+
+// AllowNodeWrite checks if write operations are allowed for a node
+func (a *ACL) AllowNodeWrite() bool {
+	return a.allowWrite(a.node)
+}
+
+// AllowOperatorRead checks if read operations are allowed for a operator
+func (a *ACL) AllowOperatorRead() bool {
+	return a.allowRead(a.operator)
+}
+
+// AllowOperatorWrite checks if write operations are allowed for a operator
+func (a *ACL) AllowOperatorWrite() bool {
+	return a.allowWrite(a.operator)
+}
+
+// AllowQuotaRead checks if read operations are allowed for all quotas
+func (a *ACL) AllowQuotaRead() bool {
+	return a.allowRead(a.quota)
+}
+
+// AllowQuotaWrite checks if write operations are allowed for quotas
+func (a *ACL) AllowQuotaWrite() bool {
+	return a.allowWrite(a.quota)
+}
+
+// IsManagement checks if this represents a management token
+func (a *ACL) IsManagement() bool {
+	return a.management
+}
+
+func (a *ACL) allowRead(policy string) bool {
+	switch {
+	case a.management:
+		return true
+	case policy == PolicyWrite:
+		return true
+	case policy == PolicyRead:
+		return true
+	default:
+		return false
+	}
+}
+
+func (a *ACL) allowWrite(policy string) bool {
+	switch {
+	case a.management:
+		return true
+	case policy == PolicyWrite:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,296 @@
+package acl
+
+import (
+	"testing"
+)
+
+func TestCapabilitySet(t *testing.T) {
+	var cs capabilitySet = make(map[string]struct{})
+
+	if cs.Check(PolicyDeny) {
+		t.Fatalf("bad")
+	}
+
+	cs.Set(PolicyDeny)
+	if !cs.Check(PolicyDeny) {
+		t.Fatalf("bad")
+	}
+
+	// Clear the set
+	cs.Clear()
+	if cs.Check(PolicyDeny) {
+		t.Fatalf("bad")
+	}
+}
+
+func TestMaxPrivilege(t *testing.T) {
+	type tcase struct {
+		Privilege      string
+		PrecedenceOver []string
+	}
+	tcases := []tcase{
+		{
+			Privilege:      PolicyDeny,
+			PrecedenceOver: []string{PolicyWrite, PolicyRead, ""},
+		},
+		{
+			Privilege:      PolicyWrite,
+			PrecedenceOver: []string{PolicyRead, ""},
+		},
+		{
+			Privilege:      PolicyRead,
+			PrecedenceOver: []string{""},
+		},
+	}
+
+	for idx1, tc := range tcases {
+		for idx2, po := range tc.PrecedenceOver {
+			if maxPrivilege(tc.Privilege, po) != tc.Privilege {
+				t.Fatalf("failed %d %d", idx1, idx2)
+			}
+			if maxPrivilege(po, tc.Privilege) != tc.Privilege {
+				t.Fatalf("failed %d %d", idx1, idx2)
+			}
+		}
+	}
+}
+
+func TestACLManagement(t *testing.T) {
+	// Create management ACL
+	acl, err := NewACL(true, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Check default namespace rights
+	if !acl.AllowNamespaceOperation("default", NamespaceCapabilityListJobs) {
+		t.Fatalf("should allow")
+	}
+	if !acl.AllowNamespaceOperation("default", NamespaceCapabilitySubmitJob) {
+		t.Fatalf("should allow")
+	}
+	if !acl.AllowNamespace("foo") {
+		t.Fatalf("should allow")
+	}
+
+	// Check the other simpler operations
+	if !acl.IsManagement() {
+		t.Fatalf("should be management")
+	}
+	if !acl.AllowAgentWrite() || !acl.AllowNodeWrite() || !acl.AllowOperatorWrite() || !acl.AllowQuotaWrite() {
+		t.Fatalf("should allow")
+	}
+}
+
+func TestACLMerge(t *testing.T) {
+	// Merge read + write policy
+	p1, err := Parse(readAll)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	p2, err := Parse(writeAll)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	acl, err := NewACL(false, []*Policy{p1, p2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Check default namespace rights
+	if !acl.AllowNamespaceOperation("default", NamespaceCapabilityListJobs) {
+		t.Fatalf("should allow")
+	}
+	if !acl.AllowNamespaceOperation("default", NamespaceCapabilitySubmitJob) {
+		t.Fatalf("should allow")
+	}
+
+	// Check non-specified namespace
+	if acl.AllowNamespaceOperation("foo", NamespaceCapabilityListJobs) {
+		t.Fatalf("should not allow")
+	}
+	if acl.AllowNamespace("foo") {
+		t.Fatalf("should not allow")
+	}
+
+	// Check the other simpler operations
+	if acl.IsManagement() {
+		t.Fatalf("should not be management")
+	}
+	if !acl.AllowAgentRead() || !acl.AllowAgentWrite() {
+		t.Fatalf("should allow")
+	}
+	if !acl.AllowNodeRead() || !acl.AllowNodeWrite() {
+		t.Fatalf("should allow")
+	}
+	if !acl.AllowOperatorRead() || !acl.AllowOperatorWrite() {
+		t.Fatalf("should allow")
+	}
+	if !acl.AllowQuotaRead() || !acl.AllowQuotaWrite() {
+		t.Fatalf("should allow")
+	}
+
+	// Merge read + blank
+	p3, err := Parse("")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	acl, err = NewACL(false, []*Policy{p1, p3})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !acl.AllowNamespaceOperation("default", NamespaceCapabilityListJobs) {
+		t.Fatalf("should allow")
+	}
+	if acl.AllowNamespaceOperation("default", NamespaceCapabilitySubmitJob) {
+		t.Fatalf("should not allow")
+	}
+	if !acl.AllowNodeRead() || acl.AllowNodeWrite() {
+		t.Fatalf("bad")
+	}
+
+	// Merge read + deny
+	p4, err := Parse(denyAll)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	acl, err = NewACL(false, []*Policy{p1, p4})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if acl.AllowNamespaceOperation("default", NamespaceCapabilityListJobs) {
+		t.Fatalf("should not allow")
+	}
+	if acl.AllowNamespace("default") {
+		t.Fatalf("should not allow")
+	}
+	if acl.AllowAgentRead() || acl.AllowNodeRead() || acl.AllowOperatorRead() || acl.AllowQuotaRead() {
+		t.Fatalf("should not allow")
+	}
+}
+
+func TestACLVariables(t *testing.T) {
+	p1, err := Parse(`
+namespace "default" {
+	variables {
+		path "*" {
+			capabilities = ["list"]
+		}
+		path "project/*" {
+			capabilities = ["read", "write"]
+		}
+		path "project/secret" {
+			capabilities = ["deny"]
+		}
+	}
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	p2, err := Parse(`
+namespace "default" {
+	variables {
+		path "project/*" {
+			capabilities = ["destroy"]
+		}
+	}
+}
+namespace "other" {
+	policy = "read"
+}
+namespace "denied" {
+	policy = "deny"
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	acl, err := NewACL(false, []*Policy{p1, p2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	type tcase struct {
+		Namespace, Path, Op string
+		Allow               bool
+	}
+	tcases := []tcase{
+		{"default", "project/db", VariablesCapabilityRead, true},
+		{"default", "project/db", VariablesCapabilityWrite, true},
+		{"default", "project/db", VariablesCapabilityDestroy, true},
+		{"default", "project/db", VariablesCapabilityList, false},
+		{"default", "project/secret", VariablesCapabilityRead, false},
+		{"default", "other", VariablesCapabilityList, true},
+		{"default", "other", VariablesCapabilityRead, false},
+		{"other", "project/db", VariablesCapabilityRead, true},
+		{"other", "project/db", VariablesCapabilityWrite, false},
+		{"denied", "project/db", VariablesCapabilityRead, false},
+		{"unknown", "project/db", VariablesCapabilityRead, false},
+	}
+	for idx, tc := range tcases {
+		if allow := acl.AllowVariableOperation(tc.Namespace, tc.Path, tc.Op); allow != tc.Allow {
+			t.Fatalf("case %d: %#v: got %v", idx, tc, allow)
+		}
+	}
+
+	if !ManagementACL.AllowVariableOperation("default", "project/secret", VariablesCapabilityRead) {
+		t.Fatalf("should allow")
+	}
+}
+
+var readAll = `
+namespace "default" {
+	policy = "read"
+}
+agent {
+	policy = "read"
+}
+node {
+	policy = "read"
+}
+operator {
+	policy = "read"
+}
+quota {
+	policy = "read"
+}
+`
+
+var writeAll = `
+namespace "default" {
+	policy = "write"
+}
+agent {
+	policy = "write"
+}
+node {
+	policy = "write"
+}
+operator {
+	policy = "write"
+}
+quota {
+	policy = "write"
+}
+`
+
+var denyAll = `
+namespace "default" {
+	policy = "deny"
+}
+agent {
+	policy = "deny"
+}
+node {
+	policy = "deny"
+}
+operator {
+	policy = "deny"
+}
+quota {
+	policy = "deny"
+}
+`
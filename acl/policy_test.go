@@ -0,0 +1,272 @@
+package acl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	type tcase struct {
+		Raw    string
+		ErrStr string
+		Expect *Policy
+	}
+	tcases := []tcase{
+		{
+			`
+			namespace "default" {
+				policy = "read"
+			}
+			`,
+			"",
+			&Policy{
+				Namespaces: []*NamespacePolicy{
+					&NamespacePolicy{
+						Name:   "default",
+						Policy: PolicyRead,
+						Capabilities: []string{
+							NamespaceCapabilityListJobs,
+							NamespaceCapabilityReadJob,
+							NamespaceCapabilityCSIReadVolume,
+						},
+						Variables: &VariablesPolicy{
+							Paths: []*VariablesPathPolicy{
+								&VariablesPathPolicy{
+									PathSpec: "*",
+									Capabilities: []string{
+										VariablesCapabilityList,
+										VariablesCapabilityRead,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			`
+			namespace "default" {
+				policy = "read"
+			}
+			namespace "other" {
+				policy = "write"
+			}
+			namespace "secret" {
+				capabilities = ["deny", "read-logs"]
+			}
+			agent {
+				policy = "read"
+			}
+			node {
+				policy = "write"
+			}
+			operator {
+				policy = "deny"
+			}
+			quota {
+				policy = "read"
+			}
+			`,
+			"",
+			&Policy{
+				Namespaces: []*NamespacePolicy{
+					&NamespacePolicy{
+						Name:   "default",
+						Policy: PolicyRead,
+						Capabilities: []string{
+							NamespaceCapabilityListJobs,
+							NamespaceCapabilityReadJob,
+							NamespaceCapabilityCSIReadVolume,
+						},
+						Variables: &VariablesPolicy{
+							Paths: []*VariablesPathPolicy{
+								&VariablesPathPolicy{
+									PathSpec: "*",
+									Capabilities: []string{
+										VariablesCapabilityList,
+										VariablesCapabilityRead,
+									},
+								},
+							},
+						},
+					},
+					&NamespacePolicy{
+						Name:   "other",
+						Policy: PolicyWrite,
+						Capabilities: []string{
+							NamespaceCapabilityListJobs,
+							NamespaceCapabilityReadJob,
+							NamespaceCapabilitySubmitJob,
+							NamespaceCapabilityReadLogs,
+							NamespaceCapabilityReadFS,
+							NamespaceCapabilityScaleJob,
+							NamespaceCapabilityAllocExec,
+							NamespaceCapabilityCSIReadVolume,
+							NamespaceCapabilityCSIWriteVolume,
+						},
+						Variables: &VariablesPolicy{
+							Paths: []*VariablesPathPolicy{
+								&VariablesPathPolicy{
+									PathSpec: "*",
+									Capabilities: []string{
+										VariablesCapabilityList,
+										VariablesCapabilityRead,
+										VariablesCapabilityWrite,
+										VariablesCapabilityDestroy,
+									},
+								},
+							},
+						},
+					},
+					&NamespacePolicy{
+						Name: "secret",
+						Capabilities: []string{
+							NamespaceCapabilityDeny,
+							NamespaceCapabilityReadLogs,
+						},
+					},
+				},
+				Agent: &AgentPolicy{
+					Policy: PolicyRead,
+				},
+				Node: &NodePolicy{
+					Policy: PolicyWrite,
+				},
+				Operator: &OperatorPolicy{
+					Policy: PolicyDeny,
+				},
+				Quota: &QuotaPolicy{
+					Policy: PolicyRead,
+				},
+			},
+		},
+		{
+			`
+			namespace "default" {
+				variables {
+					path "project/*" {
+						capabilities = ["read", "write"]
+					}
+					path "project/secret" {
+						capabilities = ["deny"]
+					}
+				}
+			}
+			`,
+			"",
+			&Policy{
+				Namespaces: []*NamespacePolicy{
+					&NamespacePolicy{
+						Name: "default",
+						Variables: &VariablesPolicy{
+							Paths: []*VariablesPathPolicy{
+								&VariablesPathPolicy{
+									PathSpec: "project/*",
+									Capabilities: []string{
+										VariablesCapabilityRead,
+										VariablesCapabilityWrite,
+									},
+								},
+								&VariablesPathPolicy{
+									PathSpec: "project/secret",
+									Capabilities: []string{
+										VariablesCapabilityDeny,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			`
+			namespace "default" {
+				policy = "foo"
+			}
+			`,
+			"Invalid namespace policy",
+			nil,
+		},
+		{
+			`
+			namespace "default" {
+				variables {
+					path "project/*" {
+						capabilities = ["submit-job"]
+					}
+				}
+			}
+			`,
+			"Invalid variables capability",
+			nil,
+		},
+		{
+			`
+			namespace "default" {
+				capabilities = ["deny", "foo"]
+			}
+			`,
+			"Invalid namespace capability",
+			nil,
+		},
+		{
+			`
+			agent {
+				policy = "foo"
+			}
+			`,
+			"Invalid agent policy",
+			nil,
+		},
+		{
+			`
+			node {
+				policy = "foo"
+			}
+			`,
+			"Invalid node policy",
+			nil,
+		},
+		{
+			`
+			operator {
+				policy = "foo"
+			}
+			`,
+			"Invalid operator policy",
+			nil,
+		},
+		{
+			`
+			quota {
+				policy = "foo"
+			}
+			`,
+			"Invalid quota policy",
+			nil,
+		},
+	}
+
+	for idx, tc := range tcases {
+		p, err := Parse(tc.Raw)
+		if err != nil {
+			if tc.ErrStr == "" {
+				t.Fatalf("case %d: err: %v", idx, err)
+			}
+			if !strings.Contains(err.Error(), tc.ErrStr) {
+				t.Fatalf("case %d: expected error %q, got: %v", idx, tc.ErrStr, err)
+			}
+			continue
+		}
+		if tc.ErrStr != "" {
+			t.Fatalf("case %d: expected error %q", idx, tc.ErrStr)
+		}
+		tc.Expect.Raw = tc.Raw
+		if !reflect.DeepEqual(p, tc.Expect) {
+			t.Fatalf("case %d: got %#v, expected %#v", idx, p, tc.Expect)
+		}
+	}
+}
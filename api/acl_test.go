@@ -0,0 +1,143 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestACLPolicies_Upsert_List_Delete(t *testing.T) {
+	c, s, _ := makeACLClient(t, nil, nil)
+	defer s.Stop()
+	ap := c.ACLPolicies()
+
+	// Listing when nothing exists returns empty
+	result, qm, err := ap.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if qm.LastIndex != 1 {
+		t.Fatalf("bad index: %d", qm.LastIndex)
+	}
+	if n := len(result); n != 0 {
+		t.Fatalf("expected 0 policies, got: %d", n)
+	}
+
+	// Register a policy
+	policy := &ACLPolicy{
+		Name:        "test",
+		Description: "test",
+		Rules: `namespace "default" {
+			policy = "read"
+		}
+		`,
+	}
+	wm, err := ap.Upsert(policy, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	// Check the list again
+	result, qm, err = ap.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if len(result) != 1 {
+		t.Fatalf("expected policy, got: %#v", result)
+	}
+
+	// Query the policy
+	out, qm, err := ap.Info("test", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if out.Name != policy.Name || out.Rules != policy.Rules {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Delete the policy
+	wm, err = ap.Delete(policy.Name, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	// Check the list again
+	result, qm, err = ap.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if len(result) != 0 {
+		t.Fatalf("unexpected policy, got: %#v", result)
+	}
+}
+
+func TestACLTokens_CreateUpdateDelete(t *testing.T) {
+	c, s, root := makeACLClient(t, nil, nil)
+	defer s.Stop()
+	at := c.ACLTokens()
+
+	// The bootstrap token is the only token
+	result, qm, err := at.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if len(result) != 1 || result[0].AccessorID != root.AccessorID {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// The client uses the bootstrap token
+	self, _, err := at.Self(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if self.AccessorID != root.AccessorID {
+		t.Fatalf("bad: %#v", self)
+	}
+
+	// Create a token
+	token := &ACLToken{
+		Name:     "foo",
+		Type:     "client",
+		Policies: []string{"foo1"},
+	}
+	out, wm, err := at.Create(token, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+	if out.AccessorID == "" || out.SecretID == "" {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Update the token
+	out.Name = "other"
+	out2, wm, err := at.Update(out, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+	if out2.Name != "other" || out2.SecretID != out.SecretID {
+		t.Fatalf("bad: %#v", out2)
+	}
+
+	// Query the token
+	info, qm, err := at.Info(out.AccessorID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if info.Name != "other" {
+		t.Fatalf("bad: %#v", info)
+	}
+
+	// Delete the token
+	wm, err = at.Delete(out.AccessorID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+}
@@ -157,6 +157,123 @@ func (a *Agent) SetServers(addrs []string) error {
 	return err
 }
 
+// GossipKeyringResponse is the result of an operation on the keyring
+// encrypting the gossip traffic between servers
+type GossipKeyringResponse struct {
+	// Messages maps the names of the servers that failed the operation to
+	// their error
+	Messages map[string]string
+
+	// Keys maps the installed keys to the number of servers they are
+	// installed on
+	Keys map[string]int
+
+	// NumNodes is the number of servers in the gossip pool
+	NumNodes int
+}
+
+// gossipKeyringRequest is used to send the key of a keyring operation
+type gossipKeyringRequest struct {
+	Key string
+}
+
+// ListGossipKeys is used to list the keys installed on the servers to
+// encrypt the gossip traffic.
+func (a *Agent) ListGossipKeys() (*GossipKeyringResponse, error) {
+	var resp GossipKeyringResponse
+	_, err := a.client.query("/v1/agent/gossip/keyring/list", &resp, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// InstallGossipKey is used to install a new gossip encryption key on every
+// server. The key is used to decrypt the gossip traffic but it isn't used to
+// encrypt it until it is made the primary key with UseGossipKey.
+func (a *Agent) InstallGossipKey(key string) (*GossipKeyringResponse, error) {
+	return a.gossipKeyringOp("install", key)
+}
+
+// UseGossipKey is used to make an installed key the primary key encrypting
+// the gossip traffic.
+func (a *Agent) UseGossipKey(key string) (*GossipKeyringResponse, error) {
+	return a.gossipKeyringOp("use", key)
+}
+
+// RemoveGossipKey is used to remove a key from the keyring of every server.
+// The primary key can't be removed.
+func (a *Agent) RemoveGossipKey(key string) (*GossipKeyringResponse, error) {
+	return a.gossipKeyringOp("remove", key)
+}
+
+func (a *Agent) gossipKeyringOp(op, key string) (*GossipKeyringResponse, error) {
+	var resp GossipKeyringResponse
+	_, err := a.client.write("/v1/agent/gossip/keyring/"+op, &gossipKeyringRequest{Key: key}, &resp, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// MetricsSummary is the summary of the metrics aggregated by an agent over
+// the current interval
+type MetricsSummary struct {
+	Timestamp string
+	Gauges    []GaugeValue
+	Counters  []SampledValue
+	Samples   []SampledValue
+}
+
+// GaugeValue is the last value of a gauge
+type GaugeValue struct {
+	Name  string
+	Value float32
+}
+
+// SampledValue is the aggregate of the values of a counter or a timer
+type SampledValue struct {
+	Name   string
+	Count  int
+	Sum    float64
+	Min    float64
+	Max    float64
+	Mean   float64
+	Stddev float64
+}
+
+// Metrics is used to query the metrics of the agent
+func (a *Agent) Metrics() (*MetricsSummary, error) {
+	var resp MetricsSummary
+	if _, err := a.client.query("/v1/metrics", &resp, nil); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AgentHealth is the health of either the client or the server of an agent
+type AgentHealth struct {
+	Ok      bool
+	Message string
+}
+
+// AgentHealthResponse is the health of the client and the server of an
+// agent. A role that isn't checked is nil.
+type AgentHealthResponse struct {
+	Client *AgentHealth
+	Server *AgentHealth
+}
+
+// Health is used to check the health of the agent. An unhealthy agent is
+// reported with a 503 status code, which is returned as an error.
+func (a *Agent) Health() (*AgentHealthResponse, error) {
+	var resp AgentHealthResponse
+	if _, err := a.client.query("/v1/agent/health", &resp, nil); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 // joinResponse is used to decode the response we get while
 // sending a member join request.
 type joinResponse struct {
@@ -111,6 +111,35 @@ func TestAgent_Members(t *testing.T) {
 	}
 }
 
+func TestAgent_Metrics(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	a := c.Agent()
+
+	metrics, err := a.Metrics()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if metrics.Timestamp == "" || len(metrics.Gauges) == 0 {
+		t.Fatalf("bad: %#v", metrics)
+	}
+}
+
+func TestAgent_Health(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	a := c.Agent()
+
+	// The test server runs no client
+	health, err := a.Health()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if health.Client != nil || health.Server == nil || !health.Server.Ok {
+		t.Fatalf("bad: %#v", health)
+	}
+}
+
 func TestAgent_ForceLeave(t *testing.T) {
 	c, s := makeClient(t, nil, nil)
 	defer s.Stop()
@@ -124,6 +153,36 @@ func TestAgent_ForceLeave(t *testing.T) {
 	// TODO: test force-leave on an existing node
 }
 
+func TestAgent_GossipKeyring(t *testing.T) {
+	key1 := "tbLJg26ZJyJ9pK3qhc9jig=="
+	key2 := "4leC33rgtXKIVUr9Nr0snQ=="
+
+	c, s := makeClient(t, nil, func(c *testutil.TestServerConfig) {
+		c.Server.EncryptKey = key1
+	})
+	defer s.Stop()
+	a := c.Agent()
+
+	// Install and use a new key, then remove the old one
+	if _, err := a.InstallGossipKey(key2); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := a.UseGossipKey(key2); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := a.RemoveGossipKey(key1); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	resp, err := a.ListGossipKeys()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resp.Keys) != 1 || resp.Keys[key2] != 1 {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
 func (a *AgentMember) String() string {
 	return "{Name: " + a.Name + " Region: " + a.Tags["region"] + " DC: " + a.Tags["dc"] + "}"
 }
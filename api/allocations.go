@@ -2,10 +2,9 @@ package api
 
 import (
 	"fmt"
+	"net/url"
 	"sort"
 	"time"
-
-	"github.com/hashicorp/go-cleanhttp"
 )
 
 // Allocations is used to query the alloc-related endpoints.
@@ -44,28 +43,78 @@ func (a *Allocations) Info(allocID string, q *QueryOptions) (*Allocation, *Query
 }
 
 func (a *Allocations) Stats(alloc *Allocation, q *QueryOptions) (*AllocResourceUsage, error) {
-	node, _, err := a.client.Nodes().Info(alloc.NodeID, q)
+	client, err := a.nodeClient(alloc, q)
 	if err != nil {
 		return nil, err
 	}
-	if node.HTTPAddr == "" {
-		return nil, fmt.Errorf("http addr of the node where alloc %q is running is not advertised", alloc.ID)
+	var resp AllocResourceUsage
+	_, err = client.query("/v1/client/allocation/"+alloc.ID+"/stats", &resp, nil)
+	return &resp, err
+}
+
+// Restart restarts the tasks of the allocation in place on the client it is
+// running on. If the task name is set only the given task is restarted.
+func (a *Allocations) Restart(alloc *Allocation, taskName string, q *QueryOptions) error {
+	return a.clientAction(alloc, "restart", taskName, nil, q)
+}
+
+// Signal sends the signal, such as SIGHUP, to the running tasks of the
+// allocation on the client it is running on. If the task name is set only the
+// given task is signaled.
+func (a *Allocations) Signal(alloc *Allocation, taskName, signal string, q *QueryOptions) error {
+	return a.clientAction(alloc, "signal", taskName, url.Values{"signal": []string{signal}}, q)
+}
+
+// Pause pauses the running tasks of the allocation on the client it is running
+// on. If the task name is set only the given task is paused.
+func (a *Allocations) Pause(alloc *Allocation, taskName string, q *QueryOptions) error {
+	return a.clientAction(alloc, "pause", taskName, nil, q)
+}
+
+// Resume resumes the paused tasks of the allocation on the client it is
+// running on. If the task name is set only the given task is resumed.
+func (a *Allocations) Resume(alloc *Allocation, taskName string, q *QueryOptions) error {
+	return a.clientAction(alloc, "resume", taskName, nil, q)
+}
+
+// clientAction invokes an action on the allocation through the HTTP API of
+// the client it is running on, with the optional parameters of the action
+func (a *Allocations) clientAction(alloc *Allocation, action, taskName string, params url.Values, q *QueryOptions) error {
+	client, err := a.nodeClient(alloc, q)
+	if err != nil {
+		return err
+	}
+	if params == nil {
+		params = url.Values{}
+	}
+	if taskName != "" {
+		params.Set("task", taskName)
 	}
-	client, err := NewClient(&Config{
-		Address:    fmt.Sprintf("http://%s", node.HTTPAddr),
-		HttpClient: cleanhttp.DefaultClient(),
-	})
+	endpoint := "/v1/client/allocation/" + alloc.ID + "/" + action
+	if len(params) != 0 {
+		endpoint += "?" + params.Encode()
+	}
+	_, err = client.write(endpoint, nil, nil, nil)
+	return err
+}
+
+// nodeClient returns a client for the HTTP API of the node the allocation is
+// running on
+func (a *Allocations) nodeClient(alloc *Allocation, q *QueryOptions) (*Client, error) {
+	node, _, err := a.client.Nodes().Info(alloc.NodeID, q)
 	if err != nil {
 		return nil, err
 	}
-	var resp AllocResourceUsage
-	_, err = client.query("/v1/client/allocation/"+alloc.ID+"/stats", &resp, nil)
-	return &resp, err
+	if node.HTTPAddr == "" {
+		return nil, fmt.Errorf("http addr of the node where alloc %q is running is not advertised", alloc.ID)
+	}
+	return NewClient(a.client.config.nodeConfig(node.HTTPAddr))
 }
 
 // Allocation is used for serialization of allocations.
 type Allocation struct {
 	ID                 string
+	Namespace          string
 	EvalID             string
 	Name               string
 	NodeID             string
@@ -81,11 +130,28 @@ type Allocation struct {
 	ClientStatus       string
 	ClientDescription  string
 	TaskStates         map[string]*TaskState
+	PreviousAllocation string
+	RescheduleTracker  *RescheduleTracker
 	CreateIndex        uint64
 	ModifyIndex        uint64
 	CreateTime         int64
 }
 
+// RescheduleTracker encapsulates the reschedule attempts that led to an
+// allocation.
+type RescheduleTracker struct {
+	Events []*RescheduleEvent
+}
+
+// RescheduleEvent is used to deserialize a single reschedule attempt.
+type RescheduleEvent struct {
+	RescheduleTime int64
+	PrevAllocID    string
+	PrevNodeID     string
+	PrevStatus     string
+	Delay          time.Duration
+}
+
 // AllocationMetric is used to deserialize allocation metrics.
 type AllocationMetric struct {
 	NodesEvaluated     int
@@ -105,6 +171,7 @@ type AllocationMetric struct {
 // during list operations.
 type AllocationListStub struct {
 	ID                 string
+	Namespace          string
 	EvalID             string
 	Name               string
 	NodeID             string
@@ -115,6 +182,9 @@ type AllocationListStub struct {
 	ClientStatus       string
 	ClientDescription  string
 	TaskStates         map[string]*TaskState
+	PreviousAllocation string
+	RescheduleAttempts int
+	JobModifyIndex     uint64
 	CreateIndex        uint64
 	ModifyIndex        uint64
 	CreateTime         int64
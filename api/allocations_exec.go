@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/hashicorp/nomad/helper/websocket"
+)
+
+// TerminalSize is the size of a terminal in characters
+type TerminalSize struct {
+	Height int `json:"height,omitempty"`
+	Width  int `json:"width,omitempty"`
+}
+
+// ExecStreamingIOOperation is the data of a standard stream of an exec
+// session. Close marks the end of the stream.
+type ExecStreamingIOOperation struct {
+	Data  []byte `json:"data,omitempty"`
+	Close bool   `json:"close,omitempty"`
+}
+
+// ExecStreamingInput is a message sent to the agent during an exec session
+type ExecStreamingInput struct {
+	Stdin   *ExecStreamingIOOperation `json:"stdin,omitempty"`
+	TTYSize *TerminalSize             `json:"tty_size,omitempty"`
+}
+
+// ExecStreamingOutput is a message received from the agent during an exec
+// session. The last message marks the command as exited and holds its
+// result.
+type ExecStreamingOutput struct {
+	Stdout *ExecStreamingIOOperation `json:"stdout,omitempty"`
+	Stderr *ExecStreamingIOOperation `json:"stderr,omitempty"`
+	Exited bool                      `json:"exited,omitempty"`
+	Result *ExecStreamingExitResult  `json:"result,omitempty"`
+}
+
+// ExecStreamingExitResult is the result of the command of an exec session
+type ExecStreamingExitResult struct {
+	ExitCode int `json:"exit_code"`
+}
+
+// Exec runs a command inside a task of the allocation on the client it is
+// running on and returns the exit code of the command. The input is streamed
+// from stdin, which may be nil, and the output to stdout and stderr. When tty
+// is set a terminal is allocated to the command, all of its output is written
+// to stdout and the sizes received on terminalSizeCh resize the terminal.
+// Canceling the context kills the command.
+func (a *Allocations) Exec(ctx context.Context, alloc *Allocation, task string, tty bool, command []string,
+	stdin io.Reader, stdout, stderr io.Writer, terminalSizeCh <-chan TerminalSize, q *QueryOptions) (int, error) {
+
+	client, err := a.nodeClient(alloc, q)
+	if err != nil {
+		return -1, err
+	}
+	cmdJSON, err := json.Marshal(command)
+	if err != nil {
+		return -1, err
+	}
+
+	r := client.newRequest("GET", "/v1/client/allocation/"+alloc.ID+"/exec")
+	r.setQueryOptions(q)
+	r.params.Set("task", task)
+	r.params.Set("tty", strconv.FormatBool(tty))
+	r.params.Set("command", string(cmdJSON))
+	req, err := r.toHTTP()
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Del("Accept-Encoding")
+
+	u := *req.URL
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	var tlsConf *tls.Config
+	if t, ok := client.config.HttpClient.Transport.(*http.Transport); ok {
+		tlsConf = t.TLSClientConfig
+	}
+	conn, err := websocket.Dial(u.String(), req.Header, tlsConf)
+	if err != nil {
+		return -1, err
+	}
+	defer conn.Close()
+
+	send := func(in *ExecStreamingInput) error {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		return conn.WriteMessage(websocket.TextMessage, buf)
+	}
+
+	// Stream the input and the terminal size changes until the session ends,
+	// closing the connection if the context is canceled
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go func() {
+		for {
+			select {
+			case size, ok := <-terminalSizeCh:
+				if !ok {
+					terminalSizeCh = nil
+					continue
+				}
+				if err := send(&ExecStreamingInput{TTYSize: &size}); err != nil {
+					return
+				}
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-doneCh:
+				return
+			}
+		}
+	}()
+	if stdin != nil {
+		go func() {
+			buf := make([]byte, 4096)
+			for {
+				n, err := stdin.Read(buf)
+				if n > 0 {
+					data := make([]byte, n)
+					copy(data, buf[:n])
+					if send(&ExecStreamingInput{Stdin: &ExecStreamingIOOperation{Data: data}}) != nil {
+						return
+					}
+				}
+				if err != nil {
+					send(&ExecStreamingInput{Stdin: &ExecStreamingIOOperation{Close: true}})
+					return
+				}
+			}
+		}()
+	}
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return -1, ctx.Err()
+			}
+			if closeErr, ok := err.(*websocket.CloseError); ok && closeErr.Reason != "" {
+				return -1, fmt.Errorf("exec failed: %s", closeErr.Reason)
+			}
+			return -1, err
+		}
+
+		var out ExecStreamingOutput
+		if err := json.Unmarshal(msg, &out); err != nil {
+			return -1, fmt.Errorf("failed to decode exec output: %v", err)
+		}
+		if out.Stdout != nil && len(out.Stdout.Data) != 0 {
+			if _, err := stdout.Write(out.Stdout.Data); err != nil {
+				return -1, err
+			}
+		}
+		if out.Stderr != nil && len(out.Stderr.Data) != 0 {
+			if _, err := stderr.Write(out.Stderr.Data); err != nil {
+				return -1, err
+			}
+		}
+		if out.Exited && out.Result != nil {
+			return out.Result.ExitCode, nil
+		}
+	}
+}
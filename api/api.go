@@ -3,9 +3,12 @@ package api
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
@@ -16,12 +19,19 @@ import (
 	"github.com/hashicorp/go-cleanhttp"
 )
 
+// AllNamespacesNamespace is the namespace given to list queries to query
+// every namespace the token may access.
+const AllNamespacesNamespace = "*"
+
 // QueryOptions are used to parameterize a query
 type QueryOptions struct {
 	// Providing a datacenter overwrites the region provided
 	// by the Config
 	Region string
 
+	// Namespace is the target namespace for the query.
+	Namespace string
+
 	// AllowStale allows any Nomad server (non-leader) to service
 	// a read. This allows for lower latency and higher throughput
 	AllowStale bool
@@ -37,8 +47,23 @@ type QueryOptions struct {
 	// If set, used as prefix for resource list searches
 	Prefix string
 
+	// PerPage is the maximum number of objects returned by list queries. If
+	// zero, all the objects are returned.
+	PerPage int32
+
+	// NextToken is the QueryMeta.NextToken of a previous page of a list query
+	// to resume the query from
+	NextToken string
+
+	// Filter is the expression the objects returned by list queries must
+	// match
+	Filter string
+
 	// Set HTTP parameters on the query.
 	Params map[string]string
+
+	// AuthToken is the secret ID of an ACL token
+	AuthToken string
 }
 
 // WriteOptions are used to parameterize a write
@@ -46,6 +71,12 @@ type WriteOptions struct {
 	// Providing a datacenter overwrites the region provided
 	// by the Config
 	Region string
+
+	// Namespace is the target namespace for the write.
+	Namespace string
+
+	// AuthToken is the secret ID of an ACL token
+	AuthToken string
 }
 
 // QueryMeta is used to return meta data about a query
@@ -63,6 +94,10 @@ type QueryMeta struct {
 
 	// How long did the request take
 	RequestTime time.Duration
+
+	// NextToken is set if a list query has more results than the page, and
+	// is used as the QueryOptions.NextToken of the next page
+	NextToken string
 }
 
 // WriteMeta is used to return meta data about a write
@@ -92,6 +127,12 @@ type Config struct {
 	// Region to use. If not provided, the default agent region is used.
 	Region string
 
+	// Namespace to use. If not provided the default namespace is used.
+	Namespace string
+
+	// SecretID to use. This can be overwritten per request.
+	SecretID string
+
 	// HttpClient is the client to use. Default will be
 	// used if not provided.
 	HttpClient *http.Client
@@ -102,6 +143,112 @@ type Config struct {
 	// WaitTime limits how long a Watch will block. If not provided,
 	// the agent default values will be used.
 	WaitTime time.Duration
+
+	// TLSConfig provides the TLS configuration used to talk to agents
+	// serving the HTTP API over TLS.
+	TLSConfig *TLSConfig
+
+	// MaxRetries is the number of times a request is retried while the
+	// servers have no leader, such as during a leader election. Zero
+	// disables the retries.
+	MaxRetries int
+
+	// RetryWait is how long to wait before retrying a request.
+	RetryWait time.Duration
+}
+
+const (
+	// DefaultMaxRetries is the default number of retries of the requests
+	// failing during a leader transition
+	DefaultMaxRetries = 5
+
+	// DefaultRetryWait is the default wait between the retries
+	DefaultRetryWait = 1 * time.Second
+)
+
+// leaderTransitionErrors are the errors returned by the servers while they
+// elect a leader. Requests failing with them weren't applied and are safe to
+// retry.
+var leaderTransitionErrors = []string{
+	"No cluster leader",
+	"node is not the leader",
+}
+
+// TLSConfig contains the parameters needed to configure TLS on the HTTP
+// client used to communicate with Nomad.
+type TLSConfig struct {
+	// CACert is the path to a PEM-encoded CA cert file to use to verify the
+	// certificate of the Nomad agent.
+	CACert string
+
+	// ClientCert is the path to the certificate presented to the Nomad
+	// agent when it verifies its HTTPS clients.
+	ClientCert string
+
+	// ClientKey is the path to the private key of the client certificate.
+	ClientKey string
+
+	// Insecure disables the verification of the certificate of the agent.
+	Insecure bool
+}
+
+// ConfigureTLS configures the transport of the HTTP client with the TLS
+// configuration, if any.
+func (c *Config) ConfigureTLS() error {
+	if c.TLSConfig == nil {
+		return nil
+	}
+	if c.HttpClient == nil {
+		return fmt.Errorf("config HTTP Client must be set")
+	}
+	transport, ok := c.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("unsupported HTTP transport %T", c.HttpClient.Transport)
+	}
+
+	tlsConf := &tls.Config{
+		InsecureSkipVerify: c.TLSConfig.Insecure,
+	}
+	if c.TLSConfig.ClientCert != "" || c.TLSConfig.ClientKey != "" {
+		if c.TLSConfig.ClientCert == "" || c.TLSConfig.ClientKey == "" {
+			return fmt.Errorf("both a client certificate and key must be provided")
+		}
+		cert, err := tls.LoadX509KeyPair(c.TLSConfig.ClientCert, c.TLSConfig.ClientKey)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+	if c.TLSConfig.CACert != "" {
+		data, err := ioutil.ReadFile(c.TLSConfig.CACert)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("failed to parse any CA certificates")
+		}
+		tlsConf.RootCAs = pool
+	}
+
+	transport.TLSClientConfig = tlsConf
+	return nil
+}
+
+// nodeConfig returns the configuration of a client for the HTTP API of the
+// node at the given address. Nodes are reached with the same scheme and TLS
+// configuration as the agent of this configuration.
+func (c *Config) nodeConfig(httpAddr string) *Config {
+	scheme := "http"
+	if u, err := url.Parse(c.Address); err == nil && u.Scheme == "https" {
+		scheme = "https"
+	}
+	return &Config{
+		Address:    fmt.Sprintf("%s://%s", scheme, httpAddr),
+		HttpClient: cleanhttp.DefaultClient(),
+		SecretID:   c.SecretID,
+		TLSConfig:  c.TLSConfig,
+	}
 }
 
 // DefaultConfig returns a default configuration for the client
@@ -109,10 +256,18 @@ func DefaultConfig() *Config {
 	config := &Config{
 		Address:    "http://127.0.0.1:4646",
 		HttpClient: cleanhttp.DefaultClient(),
+		MaxRetries: DefaultMaxRetries,
+		RetryWait:  DefaultRetryWait,
 	}
 	if addr := os.Getenv("NOMAD_ADDR"); addr != "" {
 		config.Address = addr
 	}
+	if namespace := os.Getenv("NOMAD_NAMESPACE"); namespace != "" {
+		config.Namespace = namespace
+	}
+	if token := os.Getenv("NOMAD_TOKEN"); token != "" {
+		config.SecretID = token
+	}
 	if auth := os.Getenv("NOMAD_HTTP_AUTH"); auth != "" {
 		var username, password string
 		if strings.Contains(auth, ":") {
@@ -128,9 +283,33 @@ func DefaultConfig() *Config {
 			Password: password,
 		}
 	}
+
+	// Read the TLS specific env vars
+	if v := os.Getenv("NOMAD_CACERT"); v != "" {
+		config.tlsConfig().CACert = v
+	}
+	if v := os.Getenv("NOMAD_CLIENT_CERT"); v != "" {
+		config.tlsConfig().ClientCert = v
+	}
+	if v := os.Getenv("NOMAD_CLIENT_KEY"); v != "" {
+		config.tlsConfig().ClientKey = v
+	}
+	if v := os.Getenv("NOMAD_SKIP_VERIFY"); v != "" {
+		if insecure, err := strconv.ParseBool(v); err == nil {
+			config.tlsConfig().Insecure = insecure
+		}
+	}
 	return config
 }
 
+// tlsConfig returns the TLS configuration, creating it if needed
+func (c *Config) tlsConfig() *TLSConfig {
+	if c.TLSConfig == nil {
+		c.TLSConfig = &TLSConfig{}
+	}
+	return c.TLSConfig
+}
+
 // Client provides a client to the Nomad API
 type Client struct {
 	config Config
@@ -150,6 +329,9 @@ func NewClient(config *Config) (*Client, error) {
 	if config.HttpClient == nil {
 		config.HttpClient = defConfig.HttpClient
 	}
+	if err := config.ConfigureTLS(); err != nil {
+		return nil, err
+	}
 
 	client := &Client{
 		config: *config,
@@ -162,12 +344,23 @@ func (c *Client) SetRegion(region string) {
 	c.config.Region = region
 }
 
+// SetNamespace sets the namespace to forward API requests to.
+func (c *Client) SetNamespace(namespace string) {
+	c.config.Namespace = namespace
+}
+
+// SetSecretID sets the ACL token secret for API requests.
+func (c *Client) SetSecretID(secretID string) {
+	c.config.SecretID = secretID
+}
+
 // request is used to help build up a request
 type request struct {
 	config *Config
 	method string
 	url    *url.URL
 	params url.Values
+	token  string
 	body   io.Reader
 	obj    interface{}
 }
@@ -181,6 +374,12 @@ func (r *request) setQueryOptions(q *QueryOptions) {
 	if q.Region != "" {
 		r.params.Set("region", q.Region)
 	}
+	if q.Namespace != "" {
+		r.params.Set("namespace", q.Namespace)
+	}
+	if q.AuthToken != "" {
+		r.token = q.AuthToken
+	}
 	if q.AllowStale {
 		r.params.Set("stale", "")
 	}
@@ -193,6 +392,15 @@ func (r *request) setQueryOptions(q *QueryOptions) {
 	if q.Prefix != "" {
 		r.params.Set("prefix", q.Prefix)
 	}
+	if q.PerPage != 0 {
+		r.params.Set("per_page", strconv.FormatInt(int64(q.PerPage), 10))
+	}
+	if q.NextToken != "" {
+		r.params.Set("next_token", q.NextToken)
+	}
+	if q.Filter != "" {
+		r.params.Set("filter", q.Filter)
+	}
 	for k, v := range q.Params {
 		r.params.Set(k, v)
 	}
@@ -212,6 +420,12 @@ func (r *request) setWriteOptions(q *WriteOptions) {
 	if q.Region != "" {
 		r.params.Set("region", q.Region)
 	}
+	if q.Namespace != "" {
+		r.params.Set("namespace", q.Namespace)
+	}
+	if q.AuthToken != "" {
+		r.token = q.AuthToken
+	}
 }
 
 // toHTTP converts the request to an HTTP request
@@ -243,6 +457,10 @@ func (r *request) toHTTP() (*http.Request, error) {
 		req.SetBasicAuth(r.config.HttpAuth.Username, r.config.HttpAuth.Password)
 	}
 
+	if r.token != "" {
+		req.Header.Set("X-Nomad-Token", r.token)
+	}
+
 	req.Header.Add("Accept-Encoding", "gzip")
 	req.URL.Host = r.url.Host
 	req.URL.Scheme = r.url.Scheme
@@ -264,10 +482,14 @@ func (c *Client) newRequest(method, path string) *request {
 			Path:   u.Path,
 		},
 		params: make(map[string][]string),
+		token:  c.config.SecretID,
 	}
 	if c.config.Region != "" {
 		r.params.Set("region", c.config.Region)
 	}
+	if c.config.Namespace != "" {
+		r.params.Set("namespace", c.config.Namespace)
+	}
 	if c.config.WaitTime != 0 {
 		r.params.Set("wait", durToMsec(r.config.WaitTime))
 	}
@@ -302,39 +524,77 @@ func (m *multiCloser) Read(p []byte) (int, error) {
 	return m.reader.Read(p)
 }
 
-// doRequest runs a request with our client
+// doRequest runs a request with our client. Requests failing because the
+// servers have no leader are retried, unless their body was given as a
+// reader that can't be read again.
 func (c *Client) doRequest(r *request) (time.Duration, *http.Response, error) {
-	req, err := r.toHTTP()
+	retryable := r.body == nil
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		if retryable {
+			// Encode the object again, the previous body was consumed
+			r.body = nil
+		}
+		req, err := r.toHTTP()
+		if err != nil {
+			return 0, nil, err
+		}
+		resp, err := c.config.HttpClient.Do(req)
+		diff := time.Now().Sub(start)
+		if err == nil {
+			resp, err = decompress(resp)
+		}
+		if err != nil || !retryable || attempt >= c.config.MaxRetries || !leaderTransition(resp) {
+			return diff, resp, err
+		}
+		resp.Body.Close()
+		time.Sleep(c.config.RetryWait)
+	}
+}
+
+// leaderTransition returns whether the response is an error returned while
+// the servers have no leader. The body of other error responses is kept so
+// that it can still be read.
+func leaderTransition(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusInternalServerError {
+		return false
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
 	if err != nil {
-		return 0, nil, err
+		return false
 	}
-	start := time.Now()
-	resp, err := c.config.HttpClient.Do(req)
-	diff := time.Now().Sub(start)
-
-	// If the response is compressed, we swap the body's reader.
-	if resp != nil && resp.Header != nil {
-		var reader io.ReadCloser
-		switch resp.Header.Get("Content-Encoding") {
-		case "gzip":
-			greader, err := gzip.NewReader(resp.Body)
-			if err != nil {
-				return 0, nil, err
-			}
-
-			// The gzip reader doesn't close the wrapped reader so we use
-			// multiCloser.
-			reader = &multiCloser{
-				reader:       greader,
-				inorderClose: []io.Closer{greader, resp.Body},
-			}
-		default:
-			reader = resp.Body
+	for _, msg := range leaderTransitionErrors {
+		if bytes.Contains(body, []byte(msg)) {
+			return true
 		}
-		resp.Body = reader
 	}
+	return false
+}
 
-	return diff, resp, err
+// decompress swaps the body of a compressed response for a reader of the
+// decompressed body
+func decompress(resp *http.Response) (*http.Response, error) {
+	if resp.Header == nil {
+		return resp, nil
+	}
+	switch resp.Header.Get("Content-Encoding") {
+	case "gzip":
+		greader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+
+		// The gzip reader doesn't close the wrapped reader so we use
+		// multiCloser.
+		resp.Body = &multiCloser{
+			reader:       greader,
+			inorderClose: []io.Closer{greader, resp.Body},
+		}
+	}
+	return resp, nil
 }
 
 // rawQuery makes a GET request to the specified endpoint but returns just the
@@ -372,6 +632,29 @@ func (c *Client) query(endpoint string, out interface{}, q *QueryOptions) (*Quer
 	return qm, nil
 }
 
+// putQuery is used to do a PUT request for a query whose arguments are
+// given in the body, and deserialize the response using the standard Nomad
+// conventions.
+func (c *Client) putQuery(endpoint string, in, out interface{}, q *QueryOptions) (*QueryMeta, error) {
+	r := c.newRequest("PUT", endpoint)
+	r.setQueryOptions(q)
+	r.obj = in
+	rtt, resp, err := requireOK(c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	if err := decodeBody(resp, out); err != nil {
+		return nil, err
+	}
+	return qm, nil
+}
+
 // write is used to do a PUT request against an endpoint
 // and serialize/deserialized using the standard Nomad conventions.
 func (c *Client) write(endpoint string, in, out interface{}, q *WriteOptions) (*WriteMeta, error) {
@@ -442,6 +725,9 @@ func parseQueryMeta(resp *http.Response, q *QueryMeta) error {
 	default:
 		q.KnownLeader = false
 	}
+
+	// Parse the X-Nomad-NextToken
+	q.NextToken = header.Get("X-Nomad-NextToken")
 	return nil
 }
 
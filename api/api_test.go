@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -42,6 +43,27 @@ func makeClient(t *testing.T, cb1 configCallback,
 	return client, server
 }
 
+// makeACLClient is like makeClient but enables ACLs on the server and
+// configures the client with the bootstrapped management token.
+func makeACLClient(t *testing.T, cb1 configCallback,
+	cb2 testutil.ServerConfigCallback) (*Client, *testutil.TestServer, *ACLToken) {
+	client, server := makeClient(t, cb1, func(c *testutil.TestServerConfig) {
+		c.ACL.Enabled = true
+		if cb2 != nil {
+			cb2(c)
+		}
+	})
+
+	// Get the root token
+	root, _, err := client.ACLTokens().Bootstrap(nil)
+	if err != nil {
+		server.Stop()
+		t.Fatalf("failed to bootstrap ACLs: %v", err)
+	}
+	client.SetSecretID(root.SecretID)
+	return client, server, root
+}
+
 func TestRequestTime(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		time.Sleep(100 * time.Millisecond)
@@ -89,6 +111,67 @@ func TestRequestTime(t *testing.T) {
 	}
 }
 
+func TestRequestRetry_LeaderTransition(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch {
+		case r.URL.Path == "/bad":
+			http.Error(w, "permission denied", http.StatusInternalServerError)
+		case attempts < 3:
+			http.Error(w, "rpc error: No cluster leader", http.StatusInternalServerError)
+		default:
+			// The body must be sent again with every attempt
+			var in struct{ S string }
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.S != "input" {
+				http.Error(w, "bad body", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("X-Nomad-Index", "1")
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer srv.Close()
+
+	conf := DefaultConfig()
+	conf.Address = srv.URL
+	conf.RetryWait = 10 * time.Millisecond
+	client, err := NewClient(conf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The request succeeds once a leader is elected
+	var out interface{}
+	if _, err := client.write("/", struct{ S string }{"input"}, &out, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("bad: %d", attempts)
+	}
+
+	// Other errors aren't retried and keep their body
+	attempts = 0
+	_, err = client.query("/bad", &out, nil)
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("bad: %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("bad: %d", attempts)
+	}
+
+	// The retries are limited
+	attempts = 0
+	client.config.MaxRetries = 1
+	_, err = client.query("/", &out, nil)
+	if err == nil || !strings.Contains(err.Error(), "No cluster leader") {
+		t.Fatalf("bad: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("bad: %d", attempts)
+	}
+}
+
 func TestDefaultConfig_env(t *testing.T) {
 	url := "http://1.2.3.4:5678"
 	auth := []string{"nomaduser", "12345"}
@@ -114,6 +197,34 @@ func TestDefaultConfig_env(t *testing.T) {
 	}
 }
 
+func TestDefaultConfig_envTLS(t *testing.T) {
+	os.Setenv("NOMAD_CACERT", "ca.pem")
+	defer os.Setenv("NOMAD_CACERT", "")
+	os.Setenv("NOMAD_CLIENT_CERT", "client.pem")
+	defer os.Setenv("NOMAD_CLIENT_CERT", "")
+	os.Setenv("NOMAD_CLIENT_KEY", "client-key.pem")
+	defer os.Setenv("NOMAD_CLIENT_KEY", "")
+	os.Setenv("NOMAD_SKIP_VERIFY", "true")
+	defer os.Setenv("NOMAD_SKIP_VERIFY", "")
+
+	config := DefaultConfig()
+	expected := &TLSConfig{
+		CACert:     "ca.pem",
+		ClientCert: "client.pem",
+		ClientKey:  "client-key.pem",
+		Insecure:   true,
+	}
+	if !reflect.DeepEqual(config.TLSConfig, expected) {
+		t.Fatalf("bad: %#v", config.TLSConfig)
+	}
+
+	// A client certificate without its key is rejected
+	config.TLSConfig.ClientKey = ""
+	if _, err := NewClient(config); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
 func TestSetQueryOptions(t *testing.T) {
 	c, s := makeClient(t, nil, nil)
 	defer s.Stop()
@@ -124,6 +235,9 @@ func TestSetQueryOptions(t *testing.T) {
 		AllowStale: true,
 		WaitIndex:  1000,
 		WaitTime:   100 * time.Second,
+		PerPage:    10,
+		NextToken:  "foo",
+		Filter:     `Status == "running"`,
 	}
 	r.setQueryOptions(q)
 
@@ -139,6 +253,15 @@ func TestSetQueryOptions(t *testing.T) {
 	if r.params.Get("wait") != "100000ms" {
 		t.Fatalf("bad: %v", r.params)
 	}
+	if r.params.Get("per_page") != "10" {
+		t.Fatalf("bad: %v", r.params)
+	}
+	if r.params.Get("next_token") != "foo" {
+		t.Fatalf("bad: %v", r.params)
+	}
+	if r.params.Get("filter") != `Status == "running"` {
+		t.Fatalf("bad: %v", r.params)
+	}
 }
 
 func TestSetWriteOptions(t *testing.T) {
@@ -185,6 +308,7 @@ func TestParseQueryMeta(t *testing.T) {
 	resp.Header.Set("X-Nomad-Index", "12345")
 	resp.Header.Set("X-Nomad-LastContact", "80")
 	resp.Header.Set("X-Nomad-KnownLeader", "true")
+	resp.Header.Set("X-Nomad-NextToken", "foo")
 
 	qm := &QueryMeta{}
 	if err := parseQueryMeta(resp, qm); err != nil {
@@ -200,6 +324,9 @@ func TestParseQueryMeta(t *testing.T) {
 	if !qm.KnownLeader {
 		t.Fatalf("Bad: %v", qm)
 	}
+	if qm.NextToken != "foo" {
+		t.Fatalf("Bad: %v", qm)
+	}
 }
 
 func TestParseWriteMeta(t *testing.T) {
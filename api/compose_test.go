@@ -20,7 +20,7 @@ func TestCompose(t *testing.T) {
 				&NetworkResource{
 					CIDR:          "0.0.0.0/0",
 					MBits:         100,
-					ReservedPorts: []Port{{"", 80}, {"", 443}},
+					ReservedPorts: []Port{{"", 80, 0, ""}, {"", 443, 0, ""}},
 				},
 			},
 		})
@@ -83,8 +83,8 @@ func TestCompose(t *testing.T) {
 									CIDR:  "0.0.0.0/0",
 									MBits: 100,
 									ReservedPorts: []Port{
-										{"", 80},
-										{"", 443},
+										{"", 80, 0, ""},
+										{"", 443, 0, ""},
 									},
 								},
 							},
@@ -0,0 +1,398 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+const (
+	// CSIPluginTypeController, CSIPluginTypeNode and CSIPluginTypeMonolith
+	// are the types of CSI plugin tasks
+	CSIPluginTypeController = "controller"
+	CSIPluginTypeNode       = "node"
+	CSIPluginTypeMonolith   = "monolith"
+)
+
+// CSIVolumes is used to query the CSI volume endpoints.
+type CSIVolumes struct {
+	client *Client
+}
+
+// CSIVolumes returns a new handle on the CSI volumes.
+func (c *Client) CSIVolumes() *CSIVolumes {
+	return &CSIVolumes{client: c}
+}
+
+// List is used to dump all of the CSI volumes.
+func (v *CSIVolumes) List(q *QueryOptions) ([]*CSIVolumeListStub, *QueryMeta, error) {
+	var resp []*CSIVolumeListStub
+	qm, err := v.client.query("/v1/volumes", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Sort(CSIVolumeIndexSort(resp))
+	return resp, qm, nil
+}
+
+// PluginList is used to list the CSI volumes managed by a plugin.
+func (v *CSIVolumes) PluginList(pluginID string, q *QueryOptions) ([]*CSIVolumeListStub, *QueryMeta, error) {
+	var resp []*CSIVolumeListStub
+	qm, err := v.client.query("/v1/volumes?plugin_id="+url.QueryEscape(pluginID), &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Sort(CSIVolumeIndexSort(resp))
+	return resp, qm, nil
+}
+
+// Info is used to query a single CSI volume by its ID.
+func (v *CSIVolumes) Info(id string, q *QueryOptions) (*CSIVolume, *QueryMeta, error) {
+	var resp CSIVolume
+	qm, err := v.client.query("/v1/volume/csi/"+id, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Register is used to register an existing volume of the storage provider,
+// identified by its ExternalID.
+func (v *CSIVolumes) Register(vol *CSIVolume, q *WriteOptions) (*WriteMeta, error) {
+	if vol == nil || vol.ID == "" {
+		return nil, fmt.Errorf("missing volume ID")
+	}
+	req := CSIVolumeRegisterRequest{
+		Volumes: []*CSIVolume{vol},
+	}
+	wm, err := v.client.write("/v1/volume/csi/"+vol.ID, req, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Create is used to create a volume with the controller of its plugin and
+// register it. The created volume is returned.
+func (v *CSIVolumes) Create(vol *CSIVolume, q *WriteOptions) (*CSIVolume, *WriteMeta, error) {
+	if vol == nil || vol.ID == "" {
+		return nil, nil, fmt.Errorf("missing volume ID")
+	}
+	req := CSIVolumeRegisterRequest{
+		Volumes: []*CSIVolume{vol},
+	}
+	var resp CSIVolumeCreateResponse
+	wm, err := v.client.write(fmt.Sprintf("/v1/volume/csi/%s/create", vol.ID), req, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(resp.Volumes) != 1 {
+		return nil, nil, fmt.Errorf("unexpected response: %v", resp.Volumes)
+	}
+	return resp.Volumes[0], wm, nil
+}
+
+// Deregister is used to deregister a CSI volume. Volumes claimed by
+// allocations are only deregistered if force is set to true.
+func (v *CSIVolumes) Deregister(id string, force bool, q *WriteOptions) (*WriteMeta, error) {
+	wm, err := v.client.delete(fmt.Sprintf("/v1/volume/csi/%s?force=%t", id, force), nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Delete is used to delete a volume with the controller of its plugin and
+// deregister it. Volumes claimed by allocations can't be deleted.
+func (v *CSIVolumes) Delete(id string, q *WriteOptions) (*WriteMeta, error) {
+	wm, err := v.client.delete(fmt.Sprintf("/v1/volume/csi/%s/delete", id), nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Expand is used to grow a volume to a capacity between the bounds, in
+// bytes, with the controller of its plugin. A zero maximum is unbounded. The
+// new capacity of the volume is returned.
+func (v *CSIVolumes) Expand(id string, min, max int64, q *WriteOptions) (int64, *WriteMeta, error) {
+	req := CSIVolumeExpandRequest{
+		RequestedCapacityMin: min,
+		RequestedCapacityMax: max,
+	}
+	var resp CSIVolumeExpandResponse
+	wm, err := v.client.write(fmt.Sprintf("/v1/volume/csi/%s/expand", id), req, &resp, q)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.Capacity, wm, nil
+}
+
+// CreateSnapshot is used to snapshot a volume with the controller of its
+// plugin. The snapshot must specify its SourceVolumeID. The snapshot taken
+// is returned.
+func (v *CSIVolumes) CreateSnapshot(snap *CSISnapshot, q *WriteOptions) (*CSISnapshot, *WriteMeta, error) {
+	if snap == nil || snap.SourceVolumeID == "" {
+		return nil, nil, fmt.Errorf("missing source volume ID")
+	}
+	req := CSISnapshotCreateRequest{
+		Snapshots: []*CSISnapshot{snap},
+	}
+	var resp CSISnapshotCreateResponse
+	wm, err := v.client.write("/v1/volumes/snapshot", req, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(resp.Snapshots) != 1 {
+		return nil, nil, fmt.Errorf("unexpected response: %v", resp.Snapshots)
+	}
+	return resp.Snapshots[0], wm, nil
+}
+
+// DeleteSnapshot is used to delete a snapshot, identified by its ID and
+// plugin ID, with the controller of its plugin.
+func (v *CSIVolumes) DeleteSnapshot(snap *CSISnapshot, q *WriteOptions) (*WriteMeta, error) {
+	if snap == nil || snap.ID == "" || snap.PluginID == "" {
+		return nil, fmt.Errorf("missing snapshot ID or plugin ID")
+	}
+	qv := url.Values{}
+	qv.Set("snapshot_id", snap.ID)
+	qv.Set("plugin_id", snap.PluginID)
+	wm, err := v.client.delete("/v1/volumes/snapshot?"+qv.Encode(), nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// ListSnapshots is used to list the snapshots of a plugin. At most perPage
+// snapshots are returned if set, starting from the nextToken returned by a
+// previous call. The returned token is empty on the last page.
+func (v *CSIVolumes) ListSnapshots(pluginID string, perPage int, nextToken string,
+	q *QueryOptions) ([]*CSISnapshot, string, *QueryMeta, error) {
+	qv := url.Values{}
+	qv.Set("plugin_id", pluginID)
+	if perPage != 0 {
+		qv.Set("per_page", fmt.Sprint(perPage))
+	}
+	if nextToken != "" {
+		qv.Set("next_token", nextToken)
+	}
+	var resp CSISnapshotListResponse
+	qm, err := v.client.query("/v1/volumes/snapshot?"+qv.Encode(), &resp, q)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return resp.Snapshots, resp.NextToken, qm, nil
+}
+
+// CSIPlugins is used to query the CSI plugin endpoints.
+type CSIPlugins struct {
+	client *Client
+}
+
+// CSIPlugins returns a new handle on the CSI plugins.
+func (c *Client) CSIPlugins() *CSIPlugins {
+	return &CSIPlugins{client: c}
+}
+
+// List is used to dump all of the CSI plugins.
+func (p *CSIPlugins) List(q *QueryOptions) ([]*CSIPluginListStub, *QueryMeta, error) {
+	var resp []*CSIPluginListStub
+	qm, err := p.client.query("/v1/plugins", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// Info is used to query a single CSI plugin by its ID.
+func (p *CSIPlugins) Info(id string, q *QueryOptions) (*CSIPlugin, *QueryMeta, error) {
+	var resp CSIPlugin
+	qm, err := p.client.query("/v1/plugin/csi/"+id, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// TaskCSIPluginConfig marks a task as a CSI plugin. The plugin serves on the
+// socket csi.sock of its MountDir.
+type TaskCSIPluginConfig struct {
+	ID       string
+	Type     string
+	MountDir string `mapstructure:"mount_dir"`
+}
+
+// CSITopology is a set of segments, such as a zone or a rack, identifying
+// where nodes are and where volumes are accessible from
+type CSITopology struct {
+	Segments map[string]string
+}
+
+// CSIInfo is the fingerprint of a CSI plugin running on a node
+type CSIInfo struct {
+	PluginID                 string
+	AllocID                  string
+	Provider                 string
+	ProviderVersion          string
+	Healthy                  bool
+	HealthDescription        string
+	RequiresControllerPlugin bool
+	RequiresTopologies       bool
+	NodeInfo                 *CSINodeInfo
+}
+
+// CSINodeInfo is the fingerprint of a CSI node plugin
+type CSINodeInfo struct {
+	ID                      string
+	MaxVolumes              int64
+	AccessibleTopology      *CSITopology
+	RequiresNodeStageVolume bool
+}
+
+// CSIMountOptions are the options of the file systems of volumes
+type CSIMountOptions struct {
+	FSType     string
+	MountFlags []string
+}
+
+// CSIVolume is a volume managed by a CSI plugin.
+type CSIVolume struct {
+	ID                   string
+	Name                 string
+	Namespace            string
+	ExternalID           string
+	PluginID             string
+	AccessMode           string
+	AttachmentMode       string
+	MountOptions         *CSIMountOptions
+	Capacity             int64
+	RequestedCapacityMin int64
+	RequestedCapacityMax int64
+	Parameters           map[string]string
+	SnapshotID           string
+	Context              map[string]string
+	Secrets              map[string]string
+	Topologies           []*CSITopology
+	Claims               map[string]*CSIVolumeClaim
+	CreateIndex          uint64
+	ModifyIndex          uint64
+}
+
+// CSIVolumeClaim is the claim of an allocation on a volume
+type CSIVolumeClaim struct {
+	AllocID        string
+	NodeID         string
+	Mode           string
+	ExternalNodeID string
+}
+
+// CSIVolumeListStub is used for listing CSI volumes.
+type CSIVolumeListStub struct {
+	ID             string
+	Namespace      string
+	Name           string
+	ExternalID     string
+	PluginID       string
+	AccessMode     string
+	AttachmentMode string
+	Capacity       int64
+	Readers        int
+	Writers        int
+	CreateIndex    uint64
+	ModifyIndex    uint64
+}
+
+// CSIVolumeIndexSort is a wrapper to sort CSI volumes by CreateIndex. We
+// reverse the test so that we get the highest index first.
+type CSIVolumeIndexSort []*CSIVolumeListStub
+
+func (v CSIVolumeIndexSort) Len() int {
+	return len(v)
+}
+
+func (v CSIVolumeIndexSort) Less(i, j int) bool {
+	return v[i].CreateIndex > v[j].CreateIndex
+}
+
+func (v CSIVolumeIndexSort) Swap(i, j int) {
+	v[i], v[j] = v[j], v[i]
+}
+
+// CSIPlugin is a CSI plugin, aggregated from the fingerprints of the nodes
+// running it. Controllers and Nodes are keyed by node ID.
+type CSIPlugin struct {
+	ID                 string
+	Provider           string
+	ProviderVersion    string
+	ControllerRequired bool
+	Controllers        map[string]*CSIInfo
+	Nodes              map[string]*CSIInfo
+	ControllersHealthy int
+	NodesHealthy       int
+}
+
+// CSIPluginListStub is used for listing CSI plugins.
+type CSIPluginListStub struct {
+	ID                 string
+	Provider           string
+	ControllerRequired bool
+	ControllersHealthy int
+	Controllers        int
+	NodesHealthy       int
+	Nodes              int
+}
+
+// CSIVolumeRegisterRequest is the body of CSI volume registrations and
+// creations
+type CSIVolumeRegisterRequest struct {
+	Volumes []*CSIVolume
+}
+
+// CSIVolumeCreateResponse is the response of a CSI volume creation
+type CSIVolumeCreateResponse struct {
+	Volumes []*CSIVolume
+}
+
+// CSIVolumeExpandRequest is the body of a CSI volume expansion
+type CSIVolumeExpandRequest struct {
+	RequestedCapacityMin int64
+	RequestedCapacityMax int64
+}
+
+// CSIVolumeExpandResponse is the response of a CSI volume expansion
+type CSIVolumeExpandResponse struct {
+	Capacity int64
+}
+
+// CSISnapshot is a snapshot of a CSI volume taken by the controller of its
+// plugin. CreateTime is in nanoseconds since the Unix epoch.
+type CSISnapshot struct {
+	ID                     string
+	SourceVolumeID         string
+	ExternalSourceVolumeID string
+	PluginID               string
+	Name                   string
+	SizeBytes              int64
+	CreateTime             int64
+	IsReady                bool
+	Parameters             map[string]string
+	Secrets                map[string]string
+}
+
+// CSISnapshotCreateRequest is the body of a CSI snapshot creation
+type CSISnapshotCreateRequest struct {
+	Snapshots []*CSISnapshot
+}
+
+// CSISnapshotCreateResponse is the response of a CSI snapshot creation
+type CSISnapshotCreateResponse struct {
+	Snapshots []*CSISnapshot
+}
+
+// CSISnapshotListResponse is the response of a CSI snapshot listing
+type CSISnapshotListResponse struct {
+	Snapshots []*CSISnapshot
+	NextToken string
+}
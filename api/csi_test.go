@@ -0,0 +1,110 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestCSIVolumes_Register_List_Deregister(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	volumes := c.CSIVolumes()
+
+	// No volumes exist initially
+	resp, qm, err := volumes.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if len(resp) != 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Register a volume
+	vol := &CSIVolume{
+		ID:             "db-data",
+		Name:           "db data",
+		ExternalID:     "vol-12345",
+		PluginID:       "ebs",
+		AccessMode:     "single-node-writer",
+		AttachmentMode: "file-system",
+		Topologies: []*CSITopology{
+			{Segments: map[string]string{"zone": "us-east-1a"}},
+		},
+	}
+	wm, err := volumes.Register(vol, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	// Query the volume back
+	out, qm, err := volumes.Info(vol.ID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if out.ExternalID != vol.ExternalID || out.PluginID != vol.PluginID || len(out.Topologies) != 1 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// List the volumes of the plugin
+	resp, _, err = volumes.PluginList("ebs", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resp) != 1 || resp[0].ID != vol.ID {
+		t.Fatalf("bad: %#v", resp)
+	}
+	resp, _, err = volumes.PluginList("other", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resp) != 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The plugin has no controller to expand the volume or snapshot it with
+	if _, _, err := volumes.Expand(vol.ID, 1<<30, 0, nil); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, _, err := volumes.CreateSnapshot(&CSISnapshot{SourceVolumeID: vol.ID, Name: "backup"}, nil); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, _, _, err := volumes.ListSnapshots("ebs", 10, "", nil); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// Deregister the volume
+	wm, err = volumes.Deregister(vol.ID, false, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	resp, _, err = volumes.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resp) != 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestCSIPlugins_List(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+
+	// No plugins run without clients
+	resp, qm, err := c.CSIPlugins().List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if len(resp) != 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	if _, _, err := c.CSIPlugins().Info("ebs", nil); err == nil {
+		t.Fatalf("expected error")
+	}
+}
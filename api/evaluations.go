@@ -55,9 +55,11 @@ func (e *Evaluations) Allocations(evalID string, q *QueryOptions) ([]*Allocation
 // Evaluation is used to serialize an evaluation.
 type Evaluation struct {
 	ID                string
+	Namespace         string
 	Priority          int
 	Type              string
 	TriggeredBy       string
+	TriggerReason     string
 	JobID             string
 	JobModifyIndex    uint64
 	NodeID            string
@@ -69,10 +71,23 @@ type Evaluation struct {
 	PreviousEval      string
 	BlockedEval       string
 	FailedTGAllocs    map[string]*AllocationMetric
+	AttemptedNodes    map[string][]string
+	Timing            *EvalTiming
 	CreateIndex       uint64
 	ModifyIndex       uint64
 }
 
+// EvalTiming is used to deserialize the time spent in each phase of
+// processing an evaluation.
+type EvalTiming struct {
+	QueueWait   time.Duration
+	Snapshot    time.Duration
+	Feasibility time.Duration
+	Ranking     time.Duration
+	PlanSubmit  time.Duration
+	RaftApply   time.Duration
+}
+
 // EvalIndexSort is a wrapper to sort evaluations by CreateIndex.
 // We reverse the test so that we get the highest index first.
 type EvalIndexSort []*Evaluation
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+const (
+	TopicJob        = "Job"
+	TopicAllocation = "Allocation"
+	TopicNode       = "Node"
+	TopicEvaluation = "Evaluation"
+
+	// TopicAll subscribes to every topic, or every key of a topic
+	TopicAll = "*"
+)
+
+// Event is a change to an object of the state of the servers
+type Event struct {
+	Topic     string
+	Type      string
+	Key       string
+	Namespace string
+	Index     uint64
+	Payload   *EventPayload
+}
+
+// EventPayload holds the object an event is about. Only the field of the
+// topic of the event is set.
+type EventPayload struct {
+	Job        *Job
+	Allocation *AllocationListStub
+	Node       *NodeListStub
+	Evaluation *Evaluation
+}
+
+// Events are the events published at a Raft index
+type Events struct {
+	Index  uint64
+	Events []*Event
+}
+
+// IsHeartbeat returns whether the events are a heartbeat of the stream
+func (e *Events) IsHeartbeat() bool {
+	return e.Index == 0 && len(e.Events) == 0
+}
+
+// EventStream is used to stream the events of the servers
+type EventStream struct {
+	client *Client
+}
+
+// EventStream returns a handle on the event stream endpoint
+func (c *Client) EventStream() *EventStream {
+	return &EventStream{client: c}
+}
+
+// Stream streams the events published after the WaitIndex of the query
+// options. An error is returned if the events after the WaitIndex were
+// already evicted by the server. The topics to subscribe to are mapped to the keys of the events to
+// return, where TopicAll matches every topic or key. Without topics, every
+// event is streamed. Streaming ends once the cancel channel is closed.
+//
+// The return value is a channel that will emit the events of each Raft index
+// as they are published. It is closed once the stream ends.
+func (e *EventStream) Stream(topics map[string][]string, cancel <-chan struct{},
+	q *QueryOptions) (<-chan *Events, error) {
+	r := e.client.newRequest("GET", "/v1/event/stream")
+	r.setQueryOptions(q)
+
+	names := make([]string, 0, len(topics))
+	for topic := range topics {
+		names = append(names, topic)
+	}
+	sort.Strings(names)
+	for _, topic := range names {
+		for _, key := range topics[topic] {
+			r.params.Add("topic", topic+":"+key)
+		}
+	}
+
+	_, resp, err := requireOK(e.client.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the output channel
+	out := make(chan *Events, 10)
+
+	go func() {
+		// Close the body
+		defer resp.Body.Close()
+		defer close(out)
+
+		// Create a decoder
+		dec := json.NewDecoder(resp.Body)
+
+		for {
+			// Decode the next events
+			var events Events
+			if err := dec.Decode(&events); err != nil {
+				return
+			}
+
+			// Discard heartbeats
+			if events.IsHeartbeat() {
+				continue
+			}
+
+			select {
+			case out <- &events:
+			case <-cancel:
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
@@ -2,7 +2,9 @@ package api
 
 import (
 	"fmt"
+	"net/url"
 	"sort"
+	"strconv"
 	"time"
 )
 
@@ -33,26 +35,38 @@ func (c *Client) Jobs() *Jobs {
 // Register is used to register a new job. It returns the ID
 // of the evaluation, along with any errors encountered.
 func (j *Jobs) Register(job *Job, q *WriteOptions) (string, *WriteMeta, error) {
-
-	var resp registerJobResponse
-
-	req := &RegisterJobRequest{Job: job}
-	wm, err := j.client.write("/v1/jobs", req, &resp, q)
-	if err != nil {
-		return "", nil, err
-	}
-	return resp.EvalID, wm, nil
+	return j.RegisterOpts(job, nil, q)
 }
 
 // EnforceRegister is used to register a job enforcing its job modify index.
 func (j *Jobs) EnforceRegister(job *Job, modifyIndex uint64, q *WriteOptions) (string, *WriteMeta, error) {
+	opts := &RegisterOptions{
+		EnforceIndex: true,
+		ModifyIndex:  modifyIndex,
+	}
+	return j.RegisterOpts(job, opts, q)
+}
+
+// RegisterOptions is used to pass through job registration parameters
+type RegisterOptions struct {
+	EnforceIndex bool
+	ModifyIndex  uint64
+
+	// Submission is the source the job was parsed from, to be retrieved
+	// with Submission
+	Submission *JobSubmission
+}
+
+// RegisterOpts is used to register a new job with the passed RegisterOptions.
+func (j *Jobs) RegisterOpts(job *Job, opts *RegisterOptions, q *WriteOptions) (string, *WriteMeta, error) {
 
 	var resp registerJobResponse
 
-	req := &RegisterJobRequest{
-		Job:            job,
-		EnforceIndex:   true,
-		JobModifyIndex: modifyIndex,
+	req := &RegisterJobRequest{Job: job}
+	if opts != nil {
+		req.EnforceIndex = opts.EnforceIndex
+		req.JobModifyIndex = opts.ModifyIndex
+		req.Submission = opts.Submission
 	}
 	wm, err := j.client.write("/v1/jobs", req, &resp, q)
 	if err != nil {
@@ -111,10 +125,12 @@ func (j *Jobs) Evaluations(jobID string, q *QueryOptions) ([]*Evaluation, *Query
 	return resp, qm, nil
 }
 
-// Deregister is used to remove an existing job.
-func (j *Jobs) Deregister(jobID string, q *WriteOptions) (string, *WriteMeta, error) {
+// Deregister is used to stop an existing job. If purge is set to true, the
+// job is removed from the system immediately rather than being marked as
+// stopped and left for garbage collection.
+func (j *Jobs) Deregister(jobID string, purge bool, q *WriteOptions) (string, *WriteMeta, error) {
 	var resp deregisterJobResponse
-	wm, err := j.client.delete("/v1/job/"+jobID, &resp, q)
+	wm, err := j.client.delete(fmt.Sprintf("/v1/job/%v?purge=%t", jobID, purge), &resp, q)
 	if err != nil {
 		return "", nil, err
 	}
@@ -131,6 +147,22 @@ func (j *Jobs) ForceEvaluate(jobID string, q *WriteOptions) (string, *WriteMeta,
 	return resp.EvalID, wm, nil
 }
 
+// Trigger creates an evaluation of an existing job on behalf of an external
+// system, recording the reason it was triggered. The evaluation ID is
+// returned.
+func (j *Jobs) Trigger(jobID, reason string, q *WriteOptions) (string, *WriteMeta, error) {
+	var resp registerJobResponse
+	req := &JobTriggerRequest{
+		JobID:  jobID,
+		Reason: reason,
+	}
+	wm, err := j.client.write("/v1/job/"+jobID+"/trigger", req, &resp, q)
+	if err != nil {
+		return "", nil, err
+	}
+	return resp.EvalID, wm, nil
+}
+
 // PeriodicForce spawns a new instance of the periodic job and returns the eval ID
 func (j *Jobs) PeriodicForce(jobID string, q *WriteOptions) (string, *WriteMeta, error) {
 	var resp periodicForceResponse
@@ -141,6 +173,139 @@ func (j *Jobs) PeriodicForce(jobID string, q *WriteOptions) (string, *WriteMeta,
 	return resp.EvalID, wm, nil
 }
 
+// PeriodicLaunches returns the next launch time of each of the specs of the
+// periodic job
+func (j *Jobs) PeriodicLaunches(jobID string, q *QueryOptions) ([]*PeriodicLaunchTime, *QueryMeta, error) {
+	var resp []*PeriodicLaunchTime
+	qm, err := j.client.query("/v1/job/"+jobID+"/periodic/launches", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// Dispatch is used to dispatch an instance of the parameterized job with the
+// given meta and payload
+func (j *Jobs) Dispatch(jobID string, meta map[string]string,
+	payload []byte, q *WriteOptions) (*JobDispatchResponse, *WriteMeta, error) {
+	var resp JobDispatchResponse
+	req := &JobDispatchRequest{
+		JobID:   jobID,
+		Meta:    meta,
+		Payload: payload,
+	}
+	wm, err := j.client.write("/v1/job/"+jobID+"/dispatch", req, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// Scale is used to set the count of a task group of the job. A nil count only
+// records a scaling event with the given message, for example to report an
+// autoscaler error.
+func (j *Jobs) Scale(jobID, group string, count *int64, message string, isError bool,
+	meta map[string]interface{}, q *WriteOptions) (*JobRegisterResponse, *WriteMeta, error) {
+	req := &ScalingRequest{
+		JobID:   jobID,
+		Target:  map[string]string{"Group": group},
+		Count:   count,
+		Message: message,
+		Error:   isError,
+		Meta:    meta,
+	}
+	return j.scale(req, q)
+}
+
+// EnforceScale is used to scale a task group of a job enforcing the job
+// modify index, so retried scaling requests are only applied once.
+func (j *Jobs) EnforceScale(jobID, group string, count *int64, modifyIndex uint64, message string,
+	isError bool, meta map[string]interface{}, q *WriteOptions) (*JobRegisterResponse, *WriteMeta, error) {
+	req := &ScalingRequest{
+		JobID:          jobID,
+		Target:         map[string]string{"Group": group},
+		Count:          count,
+		Message:        message,
+		Error:          isError,
+		Meta:           meta,
+		EnforceIndex:   true,
+		JobModifyIndex: modifyIndex,
+	}
+	return j.scale(req, q)
+}
+
+func (j *Jobs) scale(req *ScalingRequest, q *WriteOptions) (*JobRegisterResponse, *WriteMeta, error) {
+	var resp JobRegisterResponse
+	wm, err := j.client.write("/v1/job/"+req.JobID+"/scale", req, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// ScaleStatus is used to retrieve the scaling status of the task groups of
+// the job
+func (j *Jobs) ScaleStatus(jobID string, q *QueryOptions) (*JobScaleStatus, *QueryMeta, error) {
+	var resp JobScaleStatus
+	qm, err := j.client.query("/v1/job/"+jobID+"/scale", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Submission is used to retrieve the source of the latest submission of the
+// job, exactly as it was sent when the job was registered.
+func (j *Jobs) Submission(jobID string, q *QueryOptions) (*JobSubmission, *QueryMeta, error) {
+	var resp JobSubmission
+	qm, err := j.client.query("/v1/job/"+jobID+"/submission", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Versions is used to retrieve the tracked versions of the job, most recent
+// first
+func (j *Jobs) Versions(jobID string, q *QueryOptions) ([]*Job, *QueryMeta, error) {
+	var resp []*Job
+	qm, err := j.client.query("/v1/job/"+jobID+"/versions", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// VersionDiff is used to compute the changes going from one tracked version
+// of the job to another, without scheduling anything. A contextual diff
+// includes the unchanged fields of the edited objects.
+func (j *Jobs) VersionDiff(jobID string, from, to uint64, contextual bool, q *QueryOptions) (*JobDiff, *QueryMeta, error) {
+	v := url.Values{}
+	v.Set("from", strconv.FormatUint(from, 10))
+	v.Set("to", strconv.FormatUint(to, 10))
+	if contextual {
+		v.Set("contextual", "true")
+	}
+
+	var resp JobDiff
+	qm, err := j.client.query("/v1/job/"+jobID+"/versions/diff?"+v.Encode(), &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Rollout is used to retrieve the multi-region rollout of the job. Rollouts
+// are tracked by the region the job was submitted to.
+func (j *Jobs) Rollout(jobID string, q *QueryOptions) (*MultiregionRollout, *QueryMeta, error) {
+	var resp MultiregionRollout
+	qm, err := j.client.query("/v1/job/"+jobID+"/rollout", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
 func (j *Jobs) Plan(job *Job, diff bool, q *WriteOptions) (*JobPlanResponse, *WriteMeta, error) {
 	if job == nil {
 		return nil, nil, fmt.Errorf("must pass non-nil job")
@@ -179,17 +344,72 @@ type UpdateStrategy struct {
 	MaxParallel int
 }
 
+// MultiregionStrategy is used to serialize the rollout strategy of a job
+// registered in multiple regions
+type MultiregionStrategy struct {
+	MaxParallel int
+	OnFailure   string
+}
+
+// MultiregionRollout is the region-by-region rollout of a multi-region job
+type MultiregionRollout struct {
+	JobID             string
+	Namespace         string
+	Strategy          *MultiregionStrategy
+	Regions           []*MultiregionRolloutRegion
+	Status            string
+	StatusDescription string
+	CreateIndex       uint64
+	ModifyIndex       uint64
+}
+
+// MultiregionRolloutRegion is the rollout of a multi-region job in one of its
+// regions
+type MultiregionRolloutRegion struct {
+	Region            string
+	JobModifyIndex    uint64
+	EvalID            string
+	Status            string
+	StatusDescription string
+}
+
 // PeriodicConfig is for serializing periodic config for a job.
 type PeriodicConfig struct {
 	Enabled         bool
 	Spec            string
 	SpecType        string
 	ProhibitOverlap bool
+	TimeZone        string
+	Specs           []*PeriodicSpec
+}
+
+// ParameterizedJobConfig is used to configure the parameterized job
+type ParameterizedJobConfig struct {
+	Payload      string
+	MetaRequired []string `mapstructure:"meta_required"`
+	MetaOptional []string `mapstructure:"meta_optional"`
+}
+
+// PeriodicSpec is an additional spec a periodic job is launched at
+type PeriodicSpec struct {
+	Spec    string
+	Enabled bool
+}
+
+// PeriodicLaunchTime is the next launch time of a periodic job for one of its
+// specs
+type PeriodicLaunchTime struct {
+	Spec    string
+	Enabled bool
+	Next    time.Time
 }
 
 // Job is used to serialize a job.
 type Job struct {
 	Region            string
+	Regions           []string
+	Multiregion       *MultiregionStrategy
+	Namespace         string
 	ID                string
 	ParentID          string
 	Name              string
@@ -201,10 +421,15 @@ type Job struct {
 	TaskGroups        []*TaskGroup
 	Update            *UpdateStrategy
 	Periodic          *PeriodicConfig
+	ParameterizedJob  *ParameterizedJobConfig
+	Dispatched        bool
+	Payload           []byte
 	Meta              map[string]string
 	VaultToken        string
+	Stop              bool
 	Status            string
 	StatusDescription string
+	Version           uint64
 	CreateIndex       uint64
 	ModifyIndex       uint64
 	JobModifyIndex    uint64
@@ -236,9 +461,12 @@ type TaskGroupSummary struct {
 type JobListStub struct {
 	ID                string
 	ParentID          string
+	Namespace         string
 	Name              string
 	Type              string
 	Priority          int
+	Stop              bool
+	ParameterizedJob  bool
 	Status            string
 	StatusDescription string
 	JobSummary        *JobSummary
@@ -323,8 +551,18 @@ func (j *Job) AddPeriodicConfig(cfg *PeriodicConfig) *Job {
 // RegisterJobRequest is used to serialize a job registration
 type RegisterJobRequest struct {
 	Job            *Job
-	EnforceIndex   bool   `json:",omitempty"`
-	JobModifyIndex uint64 `json:",omitempty"`
+	EnforceIndex   bool           `json:",omitempty"`
+	JobModifyIndex uint64         `json:",omitempty"`
+	Submission     *JobSubmission `json:",omitempty"`
+}
+
+// JobSubmission is the source a job was parsed from
+type JobSubmission struct {
+	JobID          string
+	Source         string
+	Format         string
+	JobModifyIndex uint64
+	SubmitTime     int64
 }
 
 // registerJobResponse is used to deserialize a job response
@@ -337,6 +575,79 @@ type deregisterJobResponse struct {
 	EvalID string
 }
 
+// JobTriggerRequest is used to trigger an evaluation of a job
+type JobTriggerRequest struct {
+	JobID  string
+	Reason string
+}
+
+// JobDispatchRequest is used to dispatch a parameterized job
+type JobDispatchRequest struct {
+	JobID   string
+	Payload []byte
+	Meta    map[string]string
+}
+
+// JobDispatchResponse is used to decode a dispatch response
+type JobDispatchResponse struct {
+	DispatchedJobID string
+	EvalID          string
+	EvalCreateIndex uint64
+	JobCreateIndex  uint64
+	WriteMeta
+}
+
+// ScalingRequest is used to scale a task group of a job
+type ScalingRequest struct {
+	JobID          string
+	Target         map[string]string
+	Count          *int64
+	Message        string
+	Error          bool
+	Meta           map[string]interface{}
+	PolicyOverride bool
+	EnforceIndex   bool
+	JobModifyIndex uint64
+}
+
+// JobRegisterResponse is used to decode the response of requests that
+// register a job or change it
+type JobRegisterResponse struct {
+	EvalID          string
+	EvalCreateIndex uint64
+	JobModifyIndex  uint64
+	RegionEvalIDs   map[string]string
+}
+
+// JobScaleStatus is the scaling status of the task groups of a job
+type JobScaleStatus struct {
+	JobID          string
+	JobCreateIndex uint64
+	JobModifyIndex uint64
+	JobStopped     bool
+	TaskGroups     map[string]*TaskGroupScaleStatus
+}
+
+// TaskGroupScaleStatus is the scaling status of a task group
+type TaskGroupScaleStatus struct {
+	Desired int
+	Placed  int
+	Running int
+	Events  []*ScalingEvent
+}
+
+// ScalingEvent is a recorded request to scale a task group
+type ScalingEvent struct {
+	Time          int64
+	Count         *int64
+	PreviousCount int64
+	Message       string
+	Error         bool
+	Meta          map[string]interface{}
+	EvalID        string
+	CreateIndex   uint64
+}
+
 type JobPlanRequest struct {
 	Job  *Job
 	Diff bool
@@ -391,7 +702,8 @@ type ObjectDiff struct {
 }
 
 type PlanAnnotations struct {
-	DesiredTGUpdates map[string]*DesiredUpdates
+	DesiredTGUpdates   map[string]*DesiredUpdates
+	MaintenanceWindows []*MaintenanceWindow
 }
 
 type DesiredUpdates struct {
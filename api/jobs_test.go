@@ -151,6 +151,73 @@ func TestJobs_Info(t *testing.T) {
 	}
 }
 
+func TestJobs_Submission(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	jobs := c.Jobs()
+
+	// Register the job along with its source
+	job := testJob()
+	opts := &RegisterOptions{
+		Submission: &JobSubmission{
+			Source: `job "job1" {}`,
+			Format: "hcl",
+		},
+	}
+	_, wm, err := jobs.RegisterOpts(job, opts, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	// Query the source back
+	result, qm, err := jobs.Submission("job1", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+
+	if result.JobID != job.ID || result.Source != opts.Submission.Source || result.Format != "hcl" {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestJobs_Versions(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	jobs := c.Jobs()
+
+	// Register the job twice
+	job := testJob()
+	if _, _, err := jobs.Register(job, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	job.Priority = 90
+	if _, _, err := jobs.Register(job, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Query the versions back
+	versions, qm, err := jobs.Versions("job1", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if len(versions) != 2 || versions[0].Version != 1 || versions[0].Priority != 90 {
+		t.Fatalf("bad: %#v", versions)
+	}
+
+	// Diff them
+	diff, qm, err := jobs.VersionDiff("job1", 0, 1, false, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if diff.Type != "Edited" || len(diff.Fields) != 1 || diff.Fields[0].Name != "Priority" {
+		t.Fatalf("bad: %#v", diff)
+	}
+}
+
 func TestJobs_PrefixList(t *testing.T) {
 	c, s := makeClient(t, nil, nil)
 	defer s.Stop()
@@ -303,13 +370,13 @@ func TestJobs_Deregister(t *testing.T) {
 	assertWriteMeta(t, wm)
 
 	// Attempting delete on non-existing job returns an error
-	if _, _, err = jobs.Deregister("nope", nil); err != nil {
+	if _, _, err = jobs.Deregister("nope", false, nil); err != nil {
 		t.Fatalf("unexpected error deregistering job: %v", err)
 
 	}
 
-	// Deleting an existing job works
-	evalID, wm3, err := jobs.Deregister("job1", nil)
+	// Stopping an existing job works
+	evalID, wm3, err := jobs.Deregister("job1", false, nil)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -318,6 +385,25 @@ func TestJobs_Deregister(t *testing.T) {
 		t.Fatalf("missing eval ID")
 	}
 
+	// Check that the job is still queryable but stopped
+	out, _, err := jobs.Info("job1", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !out.Stop {
+		t.Fatalf("job should be marked as stopped")
+	}
+
+	// Purging the job works
+	evalID, wm4, err := jobs.Deregister("job1", true, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm4)
+	if evalID == "" {
+		t.Fatalf("missing eval ID")
+	}
+
 	// Check that the job is really gone
 	result, qm, err := jobs.List(nil)
 	if err != nil {
@@ -368,6 +454,66 @@ func TestJobs_ForceEvaluate(t *testing.T) {
 	t.Fatalf("evaluation %q missing", evalID)
 }
 
+func TestJobs_Trigger(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	jobs := c.Jobs()
+
+	// Create a new job
+	_, wm, err := jobs.Register(testJob(), nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	// Trigger an evaluation
+	evalID, wm, err := jobs.Trigger("job1", "new data", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	// The evaluation records the reason
+	eval, qm, err := c.Evaluations().Info(evalID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if eval.TriggeredBy != "custom" || eval.TriggerReason != "new data" {
+		t.Fatalf("bad: %#v", eval)
+	}
+}
+
+func TestJobs_PeriodicLaunches(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	jobs := c.Jobs()
+
+	// Create a new job with an additional disabled spec
+	job := testPeriodicJob()
+	job.Periodic.Specs = []*PeriodicSpec{{Spec: "0 9 * * *", Enabled: false}}
+	_, _, err := jobs.Register(job, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	launches, qm, err := jobs.PeriodicLaunches(job.ID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+
+	if len(launches) != 2 {
+		t.Fatalf("bad: %#v", launches)
+	}
+	if launches[0].Spec != job.Periodic.Spec || launches[0].Next.IsZero() {
+		t.Fatalf("bad: %#v", launches[0])
+	}
+	if launches[1].Spec != "0 9 * * *" || launches[1].Enabled || !launches[1].Next.IsZero() {
+		t.Fatalf("bad: %#v", launches[1])
+	}
+}
+
 func TestJobs_PeriodicForce(t *testing.T) {
 	c, s := makeClient(t, nil, nil)
 	defer s.Stop()
@@ -420,6 +566,134 @@ func TestJobs_PeriodicForce(t *testing.T) {
 	t.Fatalf("evaluation %q missing", evalID)
 }
 
+func TestJobs_Dispatch(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	jobs := c.Jobs()
+
+	// Register a parameterized job
+	job := testJob()
+	job.ParameterizedJob = &ParameterizedJobConfig{
+		MetaRequired: []string{"foo"},
+	}
+	_, _, err := jobs.Register(job, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Dispatching without the required meta fails
+	_, _, err = jobs.Dispatch(job.ID, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "required meta keys") {
+		t.Fatalf("expected missing meta error, got: %#v", err)
+	}
+
+	// Dispatch the job
+	resp, wm, err := jobs.Dispatch(job.ID, map[string]string{"foo": "bar"}, []byte("hello"), nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+	if resp.EvalID == "" || resp.DispatchedJobID == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The dispatched job carries the meta and payload
+	out, _, err := jobs.Info(resp.DispatchedJobID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if out.ParentID != job.ID || !out.Dispatched {
+		t.Fatalf("bad: %#v", out)
+	}
+	if out.Meta["foo"] != "bar" || string(out.Payload) != "hello" {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestJobs_Scale(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	jobs := c.Jobs()
+
+	// Register a job with a scaling policy
+	job := testJob()
+	job.TaskGroups[0].Scaling = &ScalingPolicy{
+		Min:     1,
+		Max:     3,
+		Enabled: true,
+	}
+	_, _, err := jobs.Register(job, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Scaling beyond the policy fails
+	count := int64(5)
+	_, _, err = jobs.Scale(job.ID, "group1", &count, "", false, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "scaling policy maximum") {
+		t.Fatalf("expected policy error, got: %#v", err)
+	}
+
+	// Scale the job
+	count = 2
+	resp, wm, err := jobs.Scale(job.ID, "group1", &count, "scaling out", false, nil, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+	if resp.EvalID == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The scaling status has the new count and the event
+	status, qm, err := jobs.ScaleStatus(job.ID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	group := status.TaskGroups["group1"]
+	if group == nil || group.Desired != 2 || len(group.Events) != 1 {
+		t.Fatalf("bad: %#v", status)
+	}
+	if event := group.Events[0]; *event.Count != 2 || event.PreviousCount != 1 || event.Message != "scaling out" {
+		t.Fatalf("bad: %#v", event)
+	}
+
+	// The policy is listed
+	policies, qm, err := c.Scaling().ListPolicies(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if len(policies) != 1 || policies[0].JobID != job.ID || policies[0].Max != 3 {
+		t.Fatalf("bad: %#v", policies)
+	}
+
+	// The policy can be read along with the job modify index
+	policy, qm, err := c.Scaling().GetPolicy(job.ID, "group1", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if policy.Policy == nil || policy.Policy.Max != 3 || policy.JobModifyIndex != resp.JobModifyIndex {
+		t.Fatalf("bad: %#v", policy)
+	}
+
+	// Scaling enforcing an outdated index fails
+	count = 3
+	_, _, err = jobs.EnforceScale(job.ID, "group1", &count, policy.JobModifyIndex-1, "", false, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "conflicting job modify index") {
+		t.Fatalf("expected enforcement error, got: %#v", err)
+	}
+
+	// Scaling enforcing the current index succeeds
+	_, wm, err = jobs.EnforceScale(job.ID, "group1", &count, policy.JobModifyIndex, "", false, nil, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+}
+
 func TestJobs_Plan(t *testing.T) {
 	c, s := makeClient(t, nil, nil)
 	defer s.Stop()
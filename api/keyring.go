@@ -0,0 +1,69 @@
+package api
+
+// RootKey is the metadata of a key used to encrypt variables. The key
+// material is never returned by the API.
+type RootKey struct {
+	KeyID       string
+	Algorithm   string
+	CreateTime  int64
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// WorkloadIdentityKey is the public part of a key used to sign workload
+// identities.
+type WorkloadIdentityKey struct {
+	KeyID       string
+	Algorithm   string
+	PublicKey   []byte
+	CreateTime  int64
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// KeyringKeys is the metadata of the keys of the keyring along with the IDs
+// of the active keys.
+type KeyringKeys struct {
+	RootKeys                    []*RootKey
+	WorkloadIdentityKeys        []*WorkloadIdentityKey
+	ActiveRootKeyID             string
+	ActiveWorkloadIdentityKeyID string
+}
+
+// KeyringRotation is the result of a rotation of the keyring.
+type KeyringRotation struct {
+	RootKey             *RootKey
+	WorkloadIdentityKey *WorkloadIdentityKey
+	Rekeyed             int
+}
+
+// Keyring is used to query the keyring endpoints.
+type Keyring struct {
+	client *Client
+}
+
+// Keyring returns a new handle on the keyring.
+func (c *Client) Keyring() *Keyring {
+	return &Keyring{client: c}
+}
+
+// List is used to list the keys of the keyring.
+func (k *Keyring) List(q *QueryOptions) (*KeyringKeys, *QueryMeta, error) {
+	var resp KeyringKeys
+	qm, err := k.client.query("/v1/operator/keyring/keys", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Rotate is used to generate new active keys. Existing variables are
+// re-encrypted with the new root key.
+func (k *Keyring) Rotate(q *WriteOptions) (*KeyringRotation, *WriteMeta, error) {
+	var resp KeyringRotation
+	wm, err := k.client.write("/v1/operator/keyring/rotate", nil, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestKeyring_Rotate_List(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	keyring := c.Keyring()
+
+	// Rotate the keyring
+	rotation, wm, err := keyring.Rotate(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+	if rotation.RootKey == nil || rotation.WorkloadIdentityKey == nil {
+		t.Fatalf("bad: %#v", rotation)
+	}
+
+	// The new keys are active
+	keys, qm, err := keyring.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if keys.ActiveRootKeyID != rotation.RootKey.KeyID {
+		t.Fatalf("bad: %#v", keys)
+	}
+	if keys.ActiveWorkloadIdentityKeyID != rotation.WorkloadIdentityKey.KeyID {
+		t.Fatalf("bad: %#v", keys)
+	}
+	if len(keys.RootKeys) == 0 || len(keys.RootKeys[0].KeyID) == 0 {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// MaintenanceModeSoft avoids placing allocations on the nodes of the
+	// node class of the window
+	MaintenanceModeSoft = "soft"
+
+	// MaintenanceModeHard blocks placements on the nodes of the node class
+	// of the window and migrates their allocations away
+	MaintenanceModeHard = "hard"
+)
+
+// MaintenanceWindows is used to query the maintenance window endpoints.
+type MaintenanceWindows struct {
+	client *Client
+}
+
+// MaintenanceWindows returns a new handle on the maintenance windows.
+func (c *Client) MaintenanceWindows() *MaintenanceWindows {
+	return &MaintenanceWindows{client: c}
+}
+
+// List is used to dump all of the maintenance windows.
+func (m *MaintenanceWindows) List(q *QueryOptions) ([]*MaintenanceWindow, *QueryMeta, error) {
+	var resp []*MaintenanceWindow
+	qm, err := m.client.query("/v1/maintenance/windows", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// Info is used to query a single maintenance window by its ID.
+func (m *MaintenanceWindows) Info(id string, q *QueryOptions) (*MaintenanceWindow, *QueryMeta, error) {
+	var resp MaintenanceWindow
+	qm, err := m.client.query("/v1/maintenance/window/"+id, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Register is used to create or update a maintenance window. The ID of the
+// window is generated when it is empty, and returned.
+func (m *MaintenanceWindows) Register(window *MaintenanceWindow, q *WriteOptions) (string, *WriteMeta, error) {
+	if window == nil || window.NodeClass == "" {
+		return "", nil, fmt.Errorf("missing node class")
+	}
+	var resp maintenanceWindowUpsertResponse
+	wm, err := m.client.write("/v1/maintenance/window", window, &resp, q)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(resp.WindowIDs) != 1 {
+		return "", nil, fmt.Errorf("unexpected response: %v", resp.WindowIDs)
+	}
+	return resp.WindowIDs[0], wm, nil
+}
+
+// Delete is used to delete a maintenance window
+func (m *MaintenanceWindows) Delete(id string, q *WriteOptions) (*WriteMeta, error) {
+	wm, err := m.client.delete(fmt.Sprintf("/v1/maintenance/window/%s", id), nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// MaintenanceWindow is a period of time during which the nodes of a node
+// class undergo maintenance, so the schedulers avoid or stop using them.
+type MaintenanceWindow struct {
+	ID            string
+	NodeClass     string
+	Mode          string
+	Start         time.Time
+	End           time.Time
+	MigrateBefore time.Duration
+	Description   string
+	CreateIndex   uint64
+	ModifyIndex   uint64
+}
+
+// maintenanceWindowUpsertResponse is the response of a maintenance window
+// registration
+type maintenanceWindowUpsertResponse struct {
+	WindowIDs []string
+}
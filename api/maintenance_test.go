@@ -0,0 +1,62 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindows_Register_List_Delete(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	windows := c.MaintenanceWindows()
+
+	// No windows exist initially
+	resp, qm, err := windows.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if len(resp) != 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Register a window
+	now := time.Now().UTC()
+	window := &MaintenanceWindow{
+		NodeClass:     "gpu",
+		Mode:          MaintenanceModeHard,
+		Start:         now.Add(time.Hour),
+		End:           now.Add(2 * time.Hour),
+		MigrateBefore: 30 * time.Minute,
+	}
+	id, wm, err := windows.Register(window, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	// Query the window back
+	out, qm, err := windows.Info(id, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if out.ID != id || out.NodeClass != window.NodeClass || out.MigrateBefore != window.MigrateBefore {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Delete the window
+	wm, err = windows.Delete(id, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	resp, _, err = windows.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resp) != 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
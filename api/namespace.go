@@ -0,0 +1,133 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Namespaces is used to query the namespace endpoints.
+type Namespaces struct {
+	client *Client
+}
+
+// Namespaces returns a new handle on the namespaces.
+func (c *Client) Namespaces() *Namespaces {
+	return &Namespaces{client: c}
+}
+
+// List is used to dump all of the namespaces.
+func (n *Namespaces) List(q *QueryOptions) ([]*Namespace, *QueryMeta, error) {
+	var resp []*Namespace
+	qm, err := n.client.query("/v1/namespaces", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Sort(NamespaceNameSort(resp))
+	return resp, qm, nil
+}
+
+// PrefixList is used to do a PrefixList search over namespaces
+func (n *Namespaces) PrefixList(prefix string, q *QueryOptions) ([]*Namespace, *QueryMeta, error) {
+	if q == nil {
+		q = &QueryOptions{Prefix: prefix}
+	} else {
+		q.Prefix = prefix
+	}
+
+	return n.List(q)
+}
+
+// Info is used to query a single namespace by its name.
+func (n *Namespaces) Info(name string, q *QueryOptions) (*Namespace, *QueryMeta, error) {
+	var resp Namespace
+	qm, err := n.client.query("/v1/namespace/"+name, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Register is used to register a namespace.
+func (n *Namespaces) Register(namespace *Namespace, q *WriteOptions) (*WriteMeta, error) {
+	if namespace == nil || namespace.Name == "" {
+		return nil, fmt.Errorf("missing namespace name")
+	}
+	wm, err := n.client.write("/v1/namespace", namespace, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Delete is used to delete a namespace
+func (n *Namespaces) Delete(namespace string, q *WriteOptions) (*WriteMeta, error) {
+	wm, err := n.client.delete(fmt.Sprintf("/v1/namespace/%s", namespace), nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Replication is used to query the status of the replication of namespaces
+// and quota specifications from the authoritative region.
+func (n *Namespaces) Replication(q *QueryOptions) (*NamespaceReplicationStatus, *QueryMeta, error) {
+	var resp NamespaceReplicationStatus
+	qm, err := n.client.query("/v1/namespaces/replication", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Namespace is used to serialize a namespace.
+type Namespace struct {
+	Name          string
+	Description   string
+	Quota         string
+	NetworkPolicy *NamespaceNetworkPolicy
+	CreateIndex   uint64
+	ModifyIndex   uint64
+}
+
+// NamespaceNetworkPolicy restricts the networking the jobs of a namespace may
+// request
+type NamespaceNetworkPolicy struct {
+	DenyPrivilegedPorts bool
+	DenyHostNetwork     bool
+	ExemptJobs          []string
+}
+
+// NamespaceNameSort sorts namespaces by name.
+type NamespaceNameSort []*Namespace
+
+func (n NamespaceNameSort) Len() int {
+	return len(n)
+}
+
+func (n NamespaceNameSort) Less(i, j int) bool {
+	return n[i].Name < n[j].Name
+}
+
+func (n NamespaceNameSort) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
+
+// NamespaceReplicationStatus is the status of the replication of the
+// namespaces and quota specifications of a region from the authoritative
+// region
+type NamespaceReplicationStatus struct {
+	Enabled      bool
+	Running      bool
+	SourceRegion string
+	Namespaces   *ReplicationProgress
+	QuotaSpecs   *ReplicationProgress
+	Conflicts    []*ReplicationConflict
+}
+
+// ReplicationConflict is an object that could not be made to match the
+// authoritative region
+type ReplicationConflict struct {
+	Type   string
+	Name   string
+	Reason string
+}
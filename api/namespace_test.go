@@ -0,0 +1,66 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestNamespaces_Register_List_Delete(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	namespaces := c.Namespaces()
+
+	// Only the default namespace exists initially
+	resp, qm, err := namespaces.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if len(resp) != 1 || resp[0].Name != "default" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Register a namespace
+	ns := &Namespace{
+		Name:        "api-test",
+		Description: "testing",
+	}
+	wm, err := namespaces.Register(ns, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	// Query the namespace back
+	out, qm, err := namespaces.Info("api-test", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if out.Name != ns.Name || out.Description != ns.Description {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Both namespaces are listed, sorted by name
+	resp, _, err = namespaces.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resp) != 2 || resp[0].Name != "api-test" || resp[1].Name != "default" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Delete the namespace
+	wm, err = namespaces.Delete("api-test", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	resp, _, err = namespaces.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resp) != 1 {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
@@ -4,8 +4,14 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"time"
+)
 
-	"github.com/hashicorp/go-cleanhttp"
+const (
+	// NodeSchedulingEligible and NodeSchedulingIneligible are the scheduling
+	// eligibilities of a node
+	NodeSchedulingEligible   = "eligible"
+	NodeSchedulingIneligible = "ineligible"
 )
 
 // Nodes is used to query node-related API endpoints
@@ -53,6 +59,41 @@ func (n *Nodes) ToggleDrain(nodeID string, drain bool, q *WriteOptions) (*WriteM
 	return wm, nil
 }
 
+// UpdateDrain is used to update the drain of a given node. A nil spec stops
+// draining the node, which is then eligible for scheduling again. When
+// draining, the returned response describes the order in which jobs will be
+// migrated off of the node.
+func (n *Nodes) UpdateDrain(nodeID string, spec *DrainSpec, q *WriteOptions) (*NodeDrainUpdateResponse, *WriteMeta, error) {
+	endpoint := fmt.Sprintf("/v1/node/%s/drain?enable=%t", nodeID, spec != nil)
+	if spec != nil {
+		endpoint += "&deadline=" + spec.Deadline.String()
+		if spec.IgnoreSystemJobs {
+			endpoint += "&ignore_system=true"
+		}
+	}
+
+	var resp NodeDrainUpdateResponse
+	wm, err := n.client.write(endpoint, nil, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// ToggleEligibility is used to toggle the scheduling eligibility of a given
+// node. Ineligible nodes keep running their allocations but no new
+// allocations are placed on them.
+func (n *Nodes) ToggleEligibility(nodeID string, eligible bool, q *WriteOptions) (*NodeEligibilityUpdateResponse, *WriteMeta, error) {
+	endpoint := fmt.Sprintf("/v1/node/%s/eligibility?enable=%t", nodeID, eligible)
+
+	var resp NodeEligibilityUpdateResponse
+	wm, err := n.client.write(endpoint, nil, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
 // Allocations is used to return the allocations associated with a node.
 func (n *Nodes) Allocations(nodeID string, q *QueryOptions) ([]*Allocation, *QueryMeta, error) {
 	var resp []*Allocation
@@ -82,10 +123,7 @@ func (n *Nodes) Stats(nodeID string, q *QueryOptions) (*HostStats, error) {
 	if node.HTTPAddr == "" {
 		return nil, fmt.Errorf("http addr of the node %q is running is not advertised", nodeID)
 	}
-	client, err := NewClient(&Config{
-		Address:    fmt.Sprintf("http://%s", node.HTTPAddr),
-		HttpClient: cleanhttp.DefaultClient(),
-	})
+	client, err := NewClient(n.client.config.nodeConfig(node.HTTPAddr))
 	if err != nil {
 		return nil, err
 	}
@@ -96,24 +134,168 @@ func (n *Nodes) Stats(nodeID string, q *QueryOptions) (*HostStats, error) {
 	return &resp, nil
 }
 
+// Prefetch instructs the client of the node to fetch the docker images and
+// artifacts of the request in the background, ahead of a deployment using
+// them. The status of the fetches is returned.
+func (n *Nodes) Prefetch(nodeID string, req *PrefetchRequest, q *QueryOptions) ([]*PrefetchStatus, error) {
+	client, err := n.nodeClient(nodeID, q)
+	if err != nil {
+		return nil, err
+	}
+	var resp []*PrefetchStatus
+	if _, err := client.write("/v1/client/prefetch", req, &resp, nil); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// PrefetchStatus returns the status of the images and artifacts fetched by
+// the client of the node on request.
+func (n *Nodes) PrefetchStatus(nodeID string, q *QueryOptions) ([]*PrefetchStatus, error) {
+	client, err := n.nodeClient(nodeID, q)
+	if err != nil {
+		return nil, err
+	}
+	var resp []*PrefetchStatus
+	if _, err := client.query("/v1/client/prefetch", &resp, nil); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RebuildNetworkIndex instructs the client of the node to rebuild its port
+// accounting from its live allocations. Ports reserved because host processes
+// bound them are released once no longer bound, and ports claimed more than
+// once are reported.
+func (n *Nodes) RebuildNetworkIndex(nodeID string, q *QueryOptions) (*NetworkIndexReport, error) {
+	client, err := n.nodeClient(nodeID, q)
+	if err != nil {
+		return nil, err
+	}
+	var resp NetworkIndexReport
+	if _, err := client.write("/v1/client/network/rebuild", nil, &resp, nil); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// nodeClient returns a client dialing the HTTP address of the node
+func (n *Nodes) nodeClient(nodeID string, q *QueryOptions) (*Client, error) {
+	node, _, err := n.client.Nodes().Info(nodeID, q)
+	if err != nil {
+		return nil, err
+	}
+	if node.HTTPAddr == "" {
+		return nil, fmt.Errorf("http addr of the node %q is not advertised", nodeID)
+	}
+	return NewClient(n.client.config.nodeConfig(node.HTTPAddr))
+}
+
+// PrefetchRequest is used to request a client to fetch docker images and
+// artifacts ahead of the tasks using them being placed on it.
+type PrefetchRequest struct {
+	Images    []string
+	Artifacts []*TaskArtifact
+}
+
+// PrefetchStatus is the status of fetching an image or artifact
+type PrefetchStatus struct {
+	Type      string
+	Source    string
+	Status    string
+	Error     string
+	StartTime int64
+	EndTime   int64
+}
+
+// NetworkIndexReport is the outcome of rebuilding the port accounting of a
+// node
+type NetworkIndexReport struct {
+	ReleasedPorts []string
+	Collisions    []string
+}
+
 // Node is used to deserialize a node entry.
 type Node struct {
+	ID                    string
+	Datacenter            string
+	Name                  string
+	HTTPAddr              string
+	Attributes            map[string]string
+	Resources             *Resources
+	Reserved              *Resources
+	TaskTmpfsMB           int
+	Links                 map[string]string
+	Meta                  map[string]string
+	NodeClass             string
+	Drain                 bool
+	DrainStrategy         *DrainStrategy
+	SchedulingEligibility string
+	Status                string
+	StatusDescription     string
+	StatusUpdatedAt       int64
+	Devices               []*NodeDeviceResource
+	HostVolumes           map[string]*HostVolumeInfo
+	CSIControllerPlugins  map[string]*CSIInfo
+	CSINodePlugins        map[string]*CSIInfo
+	Reliability           NodeReliability
+	Events                []*NodeEvent
+	CreateIndex           uint64
+	ModifyIndex           uint64
+}
+
+// DrainSpec describes how a node should be drained. A zero deadline waits
+// for the allocations to migrate however long it takes, while a negative
+// deadline stops them all right away.
+type DrainSpec struct {
+	Deadline         time.Duration
+	IgnoreSystemJobs bool
+}
+
+// DrainStrategy is the drain of a node, along with the time at which it
+// started and the time at which its remaining allocations are stopped
+type DrainStrategy struct {
+	DrainSpec
+	ForceDeadline time.Time
+	StartedAt     time.Time
+}
+
+// HostVolumeInfo is a directory of the host of a node that tasks can mount
+type HostVolumeInfo struct {
+	Path     string
+	ReadOnly bool
+}
+
+// NodeEvent is a significant event in the lifecycle of a node
+type NodeEvent struct {
+	Message     string
+	Subsystem   string
+	Details     map[string]string
+	Timestamp   int64
+	CreateIndex uint64
+}
+
+// NodeDeviceResource is a group of identical devices of a node
+type NodeDeviceResource struct {
+	Vendor     string
+	Type       string
+	Name       string
+	Instances  []*NodeDevice
+	Attributes map[string]string
+}
+
+// NodeDevice is a device of a node
+type NodeDevice struct {
 	ID                string
-	Datacenter        string
-	Name              string
-	HTTPAddr          string
-	Attributes        map[string]string
-	Resources         *Resources
-	Reserved          *Resources
-	Links             map[string]string
-	Meta              map[string]string
-	NodeClass         string
-	Drain             bool
-	Status            string
-	StatusDescription string
-	StatusUpdatedAt   int64
-	CreateIndex       uint64
-	ModifyIndex       uint64
+	Healthy           bool
+	HealthDescription string
+}
+
+// NodeReliability is the penalty of a node for the failures seen on it, as
+// of UpdateTime. The penalty decays over time.
+type NodeReliability struct {
+	Penalty    float64
+	UpdateTime int64
 }
 
 // HostStats represents resource usage stats of the host running a Nomad client
@@ -152,15 +334,16 @@ type HostDiskStats struct {
 // NodeListStub is a subset of information returned during
 // node list operations.
 type NodeListStub struct {
-	ID                string
-	Datacenter        string
-	Name              string
-	NodeClass         string
-	Drain             bool
-	Status            string
-	StatusDescription string
-	CreateIndex       uint64
-	ModifyIndex       uint64
+	ID                    string
+	Datacenter            string
+	Name                  string
+	NodeClass             string
+	Drain                 bool
+	SchedulingEligibility string
+	Status                string
+	StatusDescription     string
+	CreateIndex           uint64
+	ModifyIndex           uint64
 }
 
 // NodeIndexSort reverse sorts nodes by CreateIndex
@@ -178,6 +361,32 @@ func (n NodeIndexSort) Swap(i, j int) {
 	n[i], n[j] = n[j], n[i]
 }
 
+// NodeDrainUpdateResponse is used to decode a drain update.
+type NodeDrainUpdateResponse struct {
+	EvalIDs         []string
+	EvalCreateIndex uint64
+	NodeModifyIndex uint64
+	DrainOrder      []*NodeDrainJob
+}
+
+// NodeEligibilityUpdateResponse is used to decode a scheduling eligibility
+// update.
+type NodeEligibilityUpdateResponse struct {
+	EvalIDs         []string
+	EvalCreateIndex uint64
+	NodeModifyIndex uint64
+}
+
+// NodeDrainJob describes where a job was placed in the migration order of a
+// draining node.
+type NodeDrainJob struct {
+	JobID    string
+	Type     string
+	Priority int
+	Rank     int
+	Skipped  bool
+}
+
 // nodeEvalResponse is used to decode a force-eval.
 type nodeEvalResponse struct {
 	EvalID string
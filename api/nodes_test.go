@@ -195,6 +195,119 @@ func TestNodes_ToggleDrain(t *testing.T) {
 	}
 }
 
+func TestNodes_UpdateDrain(t *testing.T) {
+	c, s := makeClient(t, nil, func(c *testutil.TestServerConfig) {
+		c.DevMode = true
+	})
+	defer s.Stop()
+	nodes := c.Nodes()
+
+	// Wait for node registration and get the ID
+	var nodeID string
+	testutil.WaitForResult(func() (bool, error) {
+		out, _, err := nodes.List(nil)
+		if err != nil {
+			return false, err
+		}
+		if n := len(out); n != 1 {
+			return false, fmt.Errorf("expected 1 node, got: %d", n)
+		}
+		nodeID = out[0].ID
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %s", err)
+	})
+
+	// Drain the node with a deadline
+	spec := &DrainSpec{Deadline: time.Hour, IgnoreSystemJobs: true}
+	_, wm, err := nodes.UpdateDrain(nodeID, spec, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	out, _, err := nodes.Info(nodeID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !out.Drain || out.DrainStrategy == nil || out.SchedulingEligibility != NodeSchedulingIneligible {
+		t.Fatalf("bad: %#v", out)
+	}
+	if out.DrainStrategy.DrainSpec != *spec {
+		t.Fatalf("bad: %#v", out.DrainStrategy)
+	}
+	if d := out.DrainStrategy.ForceDeadline.Sub(out.DrainStrategy.StartedAt); d != time.Hour {
+		t.Fatalf("bad: %v", d)
+	}
+
+	// Stop draining the node
+	_, wm, err = nodes.UpdateDrain(nodeID, nil, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	out, _, err = nodes.Info(nodeID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if out.Drain || out.DrainStrategy != nil || out.SchedulingEligibility != NodeSchedulingEligible {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestNodes_ToggleEligibility(t *testing.T) {
+	c, s := makeClient(t, nil, func(c *testutil.TestServerConfig) {
+		c.DevMode = true
+	})
+	defer s.Stop()
+	nodes := c.Nodes()
+
+	// Wait for node registration and get the ID
+	var nodeID string
+	testutil.WaitForResult(func() (bool, error) {
+		out, _, err := nodes.List(nil)
+		if err != nil {
+			return false, err
+		}
+		if n := len(out); n != 1 {
+			return false, fmt.Errorf("expected 1 node, got: %d", n)
+		}
+		nodeID = out[0].ID
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %s", err)
+	})
+
+	// Check the eligibility
+	out, _, err := nodes.Info(nodeID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if out.SchedulingEligibility != NodeSchedulingEligible {
+		t.Fatalf("node should be eligible")
+	}
+
+	// Toggle it off
+	resp, wm, err := nodes.ToggleEligibility(nodeID, false, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+	if resp.NodeModifyIndex == 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Check again
+	out, _, err = nodes.Info(nodeID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if out.SchedulingEligibility != NodeSchedulingIneligible || out.Drain {
+		t.Fatalf("node should be ineligible")
+	}
+}
+
 func TestNodes_Allocations(t *testing.T) {
 	c, s := makeClient(t, nil, nil)
 	defer s.Stop()
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+)
+
+// Operator is used to perform maintenance operations on the cluster.
+type Operator struct {
+	client *Client
+}
+
+// Operator returns a handle on the operator endpoints.
+func (c *Client) Operator() *Operator {
+	return &Operator{client: c}
+}
+
+// RaftServer has information about a server in the Raft configuration.
+type RaftServer struct {
+	// ID is the unique ID for the server. Raft identifies servers by their
+	// address, so it is the same as Address.
+	ID string
+
+	// Node is the node name of the server, as known by the gossip pool.
+	Node string
+
+	// Address is the IP:port of the server, used for Raft communications.
+	Address string
+
+	// Leader is true if this server is the current cluster leader.
+	Leader bool
+
+	// Voter is true if this server has a vote in the cluster.
+	Voter bool
+}
+
+// RaftConfiguration is returned when querying for the current Raft
+// configuration.
+type RaftConfiguration struct {
+	// Servers has the list of servers in the Raft configuration.
+	Servers []*RaftServer
+
+	// Index has the Raft index of this configuration.
+	Index uint64
+}
+
+// RaftGetConfiguration is used to query the current Raft peer set.
+func (op *Operator) RaftGetConfiguration(q *QueryOptions) (*RaftConfiguration, error) {
+	var resp RaftConfiguration
+	if _, err := op.client.query("/v1/operator/raft/configuration", &resp, q); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RaftRemovePeerByAddress is used to kick a stale peer, one that is in the
+// Raft configuration but has failed, by its address in the form of "IP:port".
+func (op *Operator) RaftRemovePeerByAddress(address string, q *WriteOptions) error {
+	_, err := op.client.delete("/v1/operator/raft/peer?address="+url.QueryEscape(address), nil, q)
+	return err
+}
+
+// SnapshotSave is used to take a snapshot of the state of the cluster. The
+// returned reader streams the snapshot archive and must be closed by the
+// caller.
+func (op *Operator) SnapshotSave(q *QueryOptions) (io.ReadCloser, error) {
+	return op.client.rawQuery("/v1/operator/snapshot", q)
+}
+
+// SnapshotRestore is used to replace the state of the cluster with the one
+// held by the snapshot archive read from in.
+func (op *Operator) SnapshotRestore(in io.Reader, q *WriteOptions) (*WriteMeta, error) {
+	r := op.client.newRequest("PUT", "/v1/operator/snapshot")
+	r.setWriteOptions(q)
+	r.body = in
+	rtt, resp, err := requireOK(op.client.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	parseWriteMeta(resp, wm)
+	return wm, nil
+}
+
+// AutopilotConfiguration is used for querying/setting the Autopilot configuration.
+// Autopilot tracks the health of the servers of the region and cleans up the
+// dead ones.
+type AutopilotConfiguration struct {
+	// CleanupDeadServers controls whether to remove dead servers from the Raft
+	// peer list when a new server joins
+	CleanupDeadServers bool
+
+	// LastContactThreshold is the limit on the amount of time a server can go
+	// without leader contact before being considered unhealthy.
+	LastContactThreshold time.Duration
+
+	// MaxTrailingLogs is the amount of entries in the Raft Log that a server can
+	// be behind before being considered unhealthy.
+	MaxTrailingLogs uint64
+
+	// CreateIndex holds the index corresponding the creation of this configuration.
+	// This is a read-only field.
+	CreateIndex uint64
+
+	// ModifyIndex will be set to the index of the last update when retrieving the
+	// Autopilot configuration. Resubmitting a configuration with
+	// AutopilotCASConfiguration will perform a check-and-set operation which ensures
+	// there hasn't been a subsequent update since the configuration was retrieved.
+	ModifyIndex uint64
+}
+
+// ServerHealth is the health (from the leader's point of view) of a server.
+type ServerHealth struct {
+	// ID is the raft ID of the server.
+	ID string
+
+	// Name is the node name of the server.
+	Name string
+
+	// Address is the address of the server.
+	Address string
+
+	// SerfStatus is the status of the server in the gossip pool.
+	SerfStatus string
+
+	// Version is the Nomad version of the server.
+	Version string
+
+	// Leader is whether this server is currently the leader.
+	Leader bool
+
+	// LastContact is the time since this node's last contact with the leader.
+	LastContact time.Duration
+
+	// LastTerm is the highest leader term this server has a record of in its Raft log.
+	LastTerm uint64
+
+	// LastIndex is the last log index this server has a record of in its Raft log.
+	LastIndex uint64
+
+	// Healthy is whether or not the server is healthy according to the current
+	// Autopilot config.
+	Healthy bool
+
+	// Voter is whether this is a voting server.
+	Voter bool
+
+	// StableSince is the last time this server's Healthy value changed.
+	StableSince time.Time
+}
+
+// OperatorHealthReply is a representation of the overall health of the cluster
+type OperatorHealthReply struct {
+	// Healthy is true if all the servers in the cluster are healthy.
+	Healthy bool
+
+	// FailureTolerance is the number of healthy servers that could be lost without
+	// an outage occurring.
+	FailureTolerance int
+
+	// Servers holds the health of each server.
+	Servers []ServerHealth
+}
+
+// AutopilotGetConfiguration is used to query the current Autopilot configuration.
+func (op *Operator) AutopilotGetConfiguration(q *QueryOptions) (*AutopilotConfiguration, error) {
+	var resp AutopilotConfiguration
+	if _, err := op.client.query("/v1/operator/autopilot/configuration", &resp, q); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AutopilotSetConfiguration is used to set the current Autopilot configuration.
+func (op *Operator) AutopilotSetConfiguration(conf *AutopilotConfiguration, q *WriteOptions) (*WriteMeta, error) {
+	return op.client.write("/v1/operator/autopilot/configuration", conf, nil, q)
+}
+
+// AutopilotCASConfiguration is used to perform a Check-And-Set update on the
+// Autopilot configuration. The ModifyIndex value will be respected. Returns
+// true on success or false on failures.
+func (op *Operator) AutopilotCASConfiguration(conf *AutopilotConfiguration, q *WriteOptions) (bool, *WriteMeta, error) {
+	var out bool
+	endpoint := fmt.Sprintf("/v1/operator/autopilot/configuration?cas=%d", conf.ModifyIndex)
+	wm, err := op.client.write(endpoint, conf, &out, q)
+	if err != nil {
+		return false, nil, err
+	}
+	return out, wm, nil
+}
+
+// AutopilotServerHealth is used to query the health of the servers of the
+// region. A region that isn't healthy is reported with a 429 status code,
+// which is returned as an error.
+func (op *Operator) AutopilotServerHealth(q *QueryOptions) (*OperatorHealthReply, error) {
+	var out OperatorHealthReply
+	if _, err := op.client.query("/v1/operator/autopilot/health", &out, q); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SchedulerConfiguration is the configuration of the schedulers that can be
+// changed at runtime.
+type SchedulerConfiguration struct {
+	// SchedulerAlgorithm is how nodes are scored for placements, either
+	// "binpack" or "spread"
+	SchedulerAlgorithm string
+
+	// PreemptionConfig controls which schedulers may evict lower priority
+	// allocations to make room for a placement
+	PreemptionConfig PreemptionConfig
+
+	// SchedulingPaused stops the evaluation of jobs cluster-wide, for
+	// instance during maintenance. Evaluations queue until it is unset.
+	SchedulingPaused bool
+
+	// NodeConstraintPolicy is what happens to running allocations when their
+	// node no longer satisfies their constraints, either "ignore" or
+	// "migrate"
+	NodeConstraintPolicy string
+
+	// NodeReliability configures the penalty of the nodes failures are seen
+	// on
+	NodeReliability NodeReliabilityConfig
+
+	// CreateIndex is the index at which the configuration was first set.
+	// This is a read-only field.
+	CreateIndex uint64
+
+	// ModifyIndex is the index of the last update of the configuration.
+	// Resubmitting a configuration with SchedulerCASConfiguration ensures
+	// there hasn't been a subsequent update since it was retrieved.
+	ModifyIndex uint64
+}
+
+// PreemptionConfig controls preemption for each type of scheduler
+type PreemptionConfig struct {
+	SystemSchedulerEnabled  bool
+	ServiceSchedulerEnabled bool
+	BatchSchedulerEnabled   bool
+}
+
+// NodeReliabilityConfig configures the reliability penalty of the nodes.
+// Failed allocations, rejected plans and missed heartbeats add their weight
+// to the penalty of their node, which halves every HalfLife and is
+// subtracted from the score of the node, up to MaxPenalty.
+type NodeReliabilityConfig struct {
+	Enabled               bool
+	HalfLife              time.Duration
+	FailedAllocWeight     float64
+	PlanRejectionWeight   float64
+	MissedHeartbeatWeight float64
+	MaxPenalty            float64
+}
+
+// SchedulerGetConfiguration is used to query the configuration of the
+// schedulers.
+func (op *Operator) SchedulerGetConfiguration(q *QueryOptions) (*SchedulerConfiguration, *QueryMeta, error) {
+	var resp SchedulerConfiguration
+	qm, err := op.client.query("/v1/operator/scheduler/configuration", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// SchedulerSetConfiguration is used to set the configuration of the
+// schedulers.
+func (op *Operator) SchedulerSetConfiguration(conf *SchedulerConfiguration, q *WriteOptions) (*WriteMeta, error) {
+	return op.client.write("/v1/operator/scheduler/configuration", conf, nil, q)
+}
+
+// SchedulerCASConfiguration is used to perform a Check-And-Set update of the
+// configuration of the schedulers. The ModifyIndex value will be respected.
+// Returns true on success or false on failures.
+func (op *Operator) SchedulerCASConfiguration(conf *SchedulerConfiguration, q *WriteOptions) (bool, *WriteMeta, error) {
+	var out bool
+	endpoint := fmt.Sprintf("/v1/operator/scheduler/configuration?cas=%d", conf.ModifyIndex)
+	wm, err := op.client.write(endpoint, conf, &out, q)
+	if err != nil {
+		return false, nil, err
+	}
+	return out, wm, nil
+}
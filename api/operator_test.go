@@ -0,0 +1,244 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestOperator_SnapshotSaveRestore(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	operator := c.Operator()
+
+	// Save a snapshot
+	snap, err := operator.SnapshotSave(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer snap.Close()
+	archive, err := ioutil.ReadAll(snap)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(archive) == 0 {
+		t.Fatalf("empty snapshot")
+	}
+
+	// Restore it
+	wm, err := operator.SnapshotRestore(bytes.NewReader(archive), nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	// A corrupted snapshot is rejected
+	if _, err := operator.SnapshotRestore(bytes.NewReader(archive[:len(archive)/2]), nil); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestOperator_RaftGetConfiguration(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+
+	operator := c.Operator()
+	out, err := operator.RaftGetConfiguration(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out.Servers) != 1 ||
+		!out.Servers[0].Leader ||
+		!out.Servers[0].Voter {
+		t.Fatalf("bad: %v", out)
+	}
+}
+
+func TestOperator_RaftRemovePeerByAddress(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+
+	// If we get this error, it proves we sent the address all the way
+	// through.
+	operator := c.Operator()
+	err := operator.RaftRemovePeerByAddress("nope", nil)
+	if err == nil || !strings.Contains(err.Error(),
+		"address \"nope\" was not found in the Raft configuration") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestOperator_AutopilotGetSetConfiguration(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+
+	operator := c.Operator()
+	config, err := operator.AutopilotGetConfiguration(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !config.CleanupDeadServers {
+		t.Fatalf("bad: %v", config)
+	}
+
+	// Change a config setting
+	newConf := &AutopilotConfiguration{
+		CleanupDeadServers:   false,
+		LastContactThreshold: time.Second,
+		MaxTrailingLogs:      100,
+	}
+	if _, err := operator.AutopilotSetConfiguration(newConf, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	config, err = operator.AutopilotGetConfiguration(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if config.CleanupDeadServers || config.MaxTrailingLogs != 100 {
+		t.Fatalf("bad: %v", config)
+	}
+}
+
+func TestOperator_AutopilotCASConfiguration(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+
+	operator := c.Operator()
+	newConf := &AutopilotConfiguration{
+		CleanupDeadServers:   true,
+		LastContactThreshold: time.Second,
+		MaxTrailingLogs:      100,
+	}
+	if _, err := operator.AutopilotSetConfiguration(newConf, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	config, err := operator.AutopilotGetConfiguration(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Pass an invalid ModifyIndex
+	{
+		config.CleanupDeadServers = false
+		config.ModifyIndex--
+		resp, _, err := operator.AutopilotCASConfiguration(config, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp {
+			t.Fatalf("bad: %v", resp)
+		}
+	}
+
+	// Pass a valid ModifyIndex
+	{
+		config.ModifyIndex++
+		resp, _, err := operator.AutopilotCASConfiguration(config, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !resp {
+			t.Fatalf("bad: %v", resp)
+		}
+	}
+}
+
+func TestOperator_SchedulerGetSetConfiguration(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+
+	operator := c.Operator()
+	config, _, err := operator.SchedulerGetConfiguration(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if config.SchedulerAlgorithm != "binpack" || config.SchedulingPaused {
+		t.Fatalf("bad: %v", config)
+	}
+
+	// Change a config setting
+	newConf := &SchedulerConfiguration{
+		SchedulerAlgorithm: "spread",
+		PreemptionConfig: PreemptionConfig{
+			SystemSchedulerEnabled: true,
+		},
+	}
+	if _, err := operator.SchedulerSetConfiguration(newConf, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	config, _, err = operator.SchedulerGetConfiguration(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if config.SchedulerAlgorithm != "spread" || config.PreemptionConfig.ServiceSchedulerEnabled {
+		t.Fatalf("bad: %v", config)
+	}
+}
+
+func TestOperator_SchedulerCASConfiguration(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+
+	operator := c.Operator()
+	newConf := &SchedulerConfiguration{SchedulerAlgorithm: "binpack"}
+	if _, err := operator.SchedulerSetConfiguration(newConf, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	config, _, err := operator.SchedulerGetConfiguration(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Pass an invalid ModifyIndex
+	{
+		config.SchedulingPaused = true
+		config.ModifyIndex--
+		resp, _, err := operator.SchedulerCASConfiguration(config, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp {
+			t.Fatalf("bad: %v", resp)
+		}
+	}
+
+	// Pass a valid ModifyIndex
+	{
+		config.ModifyIndex++
+		resp, _, err := operator.SchedulerCASConfiguration(config, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !resp {
+			t.Fatalf("bad: %v", resp)
+		}
+	}
+}
+
+func TestOperator_AutopilotServerHealth(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+
+	operator := c.Operator()
+	testutil.WaitForResult(func() (bool, error) {
+		out, err := operator.AutopilotServerHealth(nil)
+		if err != nil {
+			return false, err
+		}
+		if len(out.Servers) != 1 ||
+			!out.Servers[0].Healthy ||
+			out.Servers[0].Name == "" {
+			return false, fmt.Errorf("bad: %v", out)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Quotas is used to query the quota endpoints.
+type Quotas struct {
+	client *Client
+}
+
+// Quotas returns a new handle on the quotas.
+func (c *Client) Quotas() *Quotas {
+	return &Quotas{client: c}
+}
+
+// List is used to dump all of the quota specifications.
+func (q *Quotas) List(qo *QueryOptions) ([]*QuotaSpec, *QueryMeta, error) {
+	var resp []*QuotaSpec
+	qm, err := q.client.query("/v1/quotas", &resp, qo)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Sort(QuotaSpecNameSort(resp))
+	return resp, qm, nil
+}
+
+// PrefixList is used to do a PrefixList search over quota specifications
+func (q *Quotas) PrefixList(prefix string, qo *QueryOptions) ([]*QuotaSpec, *QueryMeta, error) {
+	if qo == nil {
+		qo = &QueryOptions{Prefix: prefix}
+	} else {
+		qo.Prefix = prefix
+	}
+
+	return q.List(qo)
+}
+
+// Info is used to query a single quota specification by its name.
+func (q *Quotas) Info(name string, qo *QueryOptions) (*QuotaSpec, *QueryMeta, error) {
+	var resp QuotaSpec
+	qm, err := q.client.query("/v1/quota/"+name, &resp, qo)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Usage is used to query the usage of a quota specification in the region.
+func (q *Quotas) Usage(name string, qo *QueryOptions) (*QuotaUsage, *QueryMeta, error) {
+	var resp QuotaUsage
+	qm, err := q.client.query("/v1/quota/usage/"+name, &resp, qo)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Register is used to register a quota specification.
+func (q *Quotas) Register(spec *QuotaSpec, qo *WriteOptions) (*WriteMeta, error) {
+	if spec == nil || spec.Name == "" {
+		return nil, fmt.Errorf("missing quota name")
+	}
+	wm, err := q.client.write("/v1/quota", spec, nil, qo)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Delete is used to delete a quota specification
+func (q *Quotas) Delete(name string, qo *WriteOptions) (*WriteMeta, error) {
+	wm, err := q.client.delete(fmt.Sprintf("/v1/quota/%s", name), nil, qo)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// QuotaSpec is used to serialize a quota specification.
+type QuotaSpec struct {
+	Name        string
+	Description string
+	Limits      []*QuotaLimit
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// QuotaLimit is the resources that may be consumed in a region. A value of
+// zero means the resource is not limited.
+type QuotaLimit struct {
+	Region   string
+	CPU      int
+	MemoryMB int
+	Count    int
+}
+
+// QuotaUsage is the resources consumed in a region by the namespaces
+// attached to a quota specification.
+type QuotaUsage struct {
+	Name       string
+	Namespaces []string
+	Used       *QuotaLimit
+	Limit      *QuotaLimit
+}
+
+// QuotaSpecNameSort sorts quota specifications by name.
+type QuotaSpecNameSort []*QuotaSpec
+
+func (q QuotaSpecNameSort) Len() int {
+	return len(q)
+}
+
+func (q QuotaSpecNameSort) Less(i, j int) bool {
+	return q[i].Name < q[j].Name
+}
+
+func (q QuotaSpecNameSort) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestQuotas_Register_List_Delete(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	quotas := c.Quotas()
+
+	// No quotas exist initially
+	resp, qm, err := quotas.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if len(resp) != 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Register a quota
+	spec := &QuotaSpec{
+		Name:        "api-test",
+		Description: "testing",
+		Limits: []*QuotaLimit{
+			&QuotaLimit{
+				Region:   "global",
+				CPU:      1000,
+				MemoryMB: 1000,
+			},
+		},
+	}
+	wm, err := quotas.Register(spec, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	// Query the quota back
+	out, qm, err := quotas.Info("api-test", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if out.Name != spec.Name || len(out.Limits) != 1 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Query the usage
+	usage, qm, err := quotas.Usage("api-test", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if usage.Used == nil || usage.Used.Count != 0 {
+		t.Fatalf("bad: %#v", usage)
+	}
+
+	// Delete the quota
+	wm, err = quotas.Delete("api-test", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	resp, _, err = quotas.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resp) != 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
@@ -8,20 +8,32 @@ type Resources struct {
 	DiskMB   int
 	IOPS     int
 	Networks []*NetworkResource
+	Devices  []*RequestedDevice
+}
+
+// RequestedDevice is a request of a task for devices of the node, selected by
+// <type>, <vendor>/<type> or <vendor>/<type>/<name>
+type RequestedDevice struct {
+	Name  string
+	Count int
 }
 
 type Port struct {
-	Label string
-	Value int
+	Label       string
+	Value       int
+	To          int
+	HostNetwork string
 }
 
 // NetworkResource is used to describe required network
 // resources of a given task.
 type NetworkResource struct {
+	Mode          string
 	Public        bool
 	CIDR          string
 	ReservedPorts []Port
 	DynamicPorts  []Port
 	IP            string
 	MBits         int
+	HostNetwork   string
 }
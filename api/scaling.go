@@ -0,0 +1,133 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// ScalingPolicyListStub is the scaling policy of a task group as returned
+// when listing the policies
+type ScalingPolicyListStub struct {
+	Namespace      string
+	JobID          string
+	Group          string
+	Min            int64
+	Max            int64
+	Enabled        bool
+	JobModifyIndex uint64
+}
+
+// ScalingPolicyResponse is the scaling policy of a task group along with the
+// modify index of its job, which scale requests may enforce
+type ScalingPolicyResponse struct {
+	Policy         *ScalingPolicy
+	JobModifyIndex uint64
+}
+
+// TaskGroupResourceUsage holds the summed resource usage of the running
+// allocations of a task group
+type TaskGroupResourceUsage struct {
+	Namespace     string
+	JobID         string
+	TaskGroup     string
+	Allocs        int
+	ResourceUsage *ResourceUsage
+	Timestamp     int64
+}
+
+// Scaling is used to query the scaling endpoints.
+type Scaling struct {
+	client *Client
+}
+
+// Scaling returns a new handle on the scaling endpoints.
+func (c *Client) Scaling() *Scaling {
+	return &Scaling{client: c}
+}
+
+// ListPolicies is used to list the scaling policies of the task groups of
+// the jobs.
+func (s *Scaling) ListPolicies(q *QueryOptions) ([]*ScalingPolicyListStub, *QueryMeta, error) {
+	var resp []*ScalingPolicyListStub
+	qm, err := s.client.query("/v1/scaling/policies", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// GetPolicy is used to get the scaling policy of a task group of a job.
+func (s *Scaling) GetPolicy(jobID, group string, q *QueryOptions) (*ScalingPolicyResponse, *QueryMeta, error) {
+	var resp ScalingPolicyResponse
+	endpoint := "/v1/scaling/policy/" + jobID + "?group=" + url.QueryEscape(group)
+	qm, err := s.client.query(endpoint, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// TaskGroupUsage is used to get the resource usage of the running allocations
+// of a job summed by task group. The usage is queried from each client
+// running allocations of the job.
+func (s *Scaling) TaskGroupUsage(jobID string, q *QueryOptions) ([]*TaskGroupResourceUsage, error) {
+	allocs, _, err := s.client.Jobs().Allocations(jobID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := s.client.config.Namespace
+	if q != nil && q.Namespace != "" {
+		namespace = q.Namespace
+	}
+
+	var usages []*TaskGroupResourceUsage
+	byGroup := make(map[string]*TaskGroupResourceUsage)
+	seen := make(map[string]struct{})
+	for _, alloc := range allocs {
+		if alloc.ClientStatus != "running" {
+			continue
+		}
+		if _, ok := seen[alloc.NodeID]; ok {
+			continue
+		}
+		seen[alloc.NodeID] = struct{}{}
+
+		node, _, err := s.client.Nodes().Info(alloc.NodeID, q)
+		if err != nil {
+			return nil, err
+		}
+		if node.HTTPAddr == "" {
+			return nil, fmt.Errorf("http addr of the node %q is not advertised", alloc.NodeID)
+		}
+		conf := s.client.config.nodeConfig(node.HTTPAddr)
+		conf.Namespace = namespace
+		client, err := NewClient(conf)
+		if err != nil {
+			return nil, err
+		}
+		var resp []*TaskGroupResourceUsage
+		if _, err := client.query("/v1/client/job/"+jobID+"/stats", &resp, nil); err != nil {
+			return nil, err
+		}
+
+		// Sum the usage reported by each client
+		for _, usage := range resp {
+			summed, ok := byGroup[usage.TaskGroup]
+			if !ok {
+				byGroup[usage.TaskGroup] = usage
+				usages = append(usages, usage)
+				continue
+			}
+			summed.Allocs += usage.Allocs
+			if summed.ResourceUsage == nil {
+				summed.ResourceUsage = &ResourceUsage{}
+			}
+			summed.ResourceUsage.add(usage.ResourceUsage)
+			if usage.Timestamp > summed.Timestamp {
+				summed.Timestamp = usage.Timestamp
+			}
+		}
+	}
+	return usages, nil
+}
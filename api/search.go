@@ -0,0 +1,80 @@
+package api
+
+// SearchContext is the type of the objects a search looks for
+type SearchContext string
+
+const (
+	SearchContextAll    SearchContext = "all"
+	SearchContextJobs   SearchContext = "jobs"
+	SearchContextAllocs SearchContext = "allocs"
+	SearchContextNodes  SearchContext = "nodes"
+	SearchContextEvals  SearchContext = "evals"
+)
+
+// Search is used to query the search endpoints.
+type Search struct {
+	client *Client
+}
+
+// Search returns a handle on the search endpoints.
+func (c *Client) Search() *Search {
+	return &Search{client: c}
+}
+
+// PrefixSearch returns the IDs of the objects of the context starting with
+// the prefix. The context may be SearchContextAll to search every context.
+func (s *Search) PrefixSearch(prefix string, context SearchContext, q *QueryOptions) (*SearchResponse, *QueryMeta, error) {
+	var resp SearchResponse
+	req := &SearchRequest{Prefix: prefix, Context: context}
+	qm, err := s.client.putQuery("/v1/search", req, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// FuzzySearch returns the objects of the context whose name contains the
+// text, best matches first. The context may be SearchContextAll to search
+// every context.
+func (s *Search) FuzzySearch(text string, context SearchContext, q *QueryOptions) (*FuzzySearchResponse, *QueryMeta, error) {
+	var resp FuzzySearchResponse
+	req := &FuzzySearchRequest{Text: text, Context: context}
+	qm, err := s.client.putQuery("/v1/search/fuzzy", req, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// SearchRequest is used to search for objects by prefix
+type SearchRequest struct {
+	Prefix  string
+	Context SearchContext
+}
+
+// SearchResponse holds the IDs of the objects matching a prefix search by
+// context, and whether the matches of a context were truncated
+type SearchResponse struct {
+	Matches     map[SearchContext][]string
+	Truncations map[SearchContext]bool
+}
+
+// FuzzySearchRequest is used to search for objects by name
+type FuzzySearchRequest struct {
+	Text    string
+	Context SearchContext
+}
+
+// FuzzyMatch is an object matching a fuzzy search. ID is the name of the
+// object and Scope the path of IDs identifying it.
+type FuzzyMatch struct {
+	ID    string
+	Scope []string
+}
+
+// FuzzySearchResponse holds the objects matching a fuzzy search by context,
+// and whether the matches of a context were truncated
+type FuzzySearchResponse struct {
+	Matches     map[SearchContext][]FuzzyMatch
+	Truncations map[SearchContext]bool
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearch_PrefixSearch(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+
+	job := testJob()
+	if _, _, err := c.Jobs().Register(job, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	resp, qm, err := c.Search().PrefixSearch("job", SearchContextAll, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if !reflect.DeepEqual(resp.Matches[SearchContextJobs], []string{job.ID}) {
+		t.Fatalf("bad: %#v", resp.Matches)
+	}
+	if resp.Truncations[SearchContextJobs] {
+		t.Fatalf("bad: %#v", resp.Truncations)
+	}
+}
+
+func TestSearch_FuzzySearch(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+
+	job := testJob()
+	if _, _, err := c.Jobs().Register(job, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	resp, qm, err := c.Search().FuzzySearch("edi", SearchContextJobs, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	expected := []FuzzyMatch{{ID: job.Name, Scope: []string{"default", job.ID}}}
+	if !reflect.DeepEqual(resp.Matches[SearchContextJobs], expected) {
+		t.Fatalf("bad: %#v", resp.Matches)
+	}
+
+	// The text must be long enough
+	if _, _, err := c.Search().FuzzySearch("e", SearchContextJobs, nil); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+// ServiceRegistration is the registration of a task's service in Nomad's
+// built-in service catalog.
+type ServiceRegistration struct {
+	ID          string
+	ServiceName string
+	Namespace   string
+	NodeID      string
+	Datacenter  string
+	JobID       string
+	AllocID     string
+	Task        string
+	Tags        []string
+	Address     string
+	Port        int
+	Health      string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// ServiceRegistrationListStub summarizes the services registered under a name
+// in a namespace.
+type ServiceRegistrationListStub struct {
+	Namespace   string
+	ServiceName string
+	Tags        []string
+}
+
+// Services is used to query the services in Nomad's built-in service catalog.
+type Services struct {
+	client *Client
+}
+
+// Services returns a new handle on the services.
+func (c *Client) Services() *Services {
+	return &Services{client: c}
+}
+
+// List is used to list the registered services.
+func (s *Services) List(q *QueryOptions) ([]*ServiceRegistrationListStub, *QueryMeta, error) {
+	var resp []*ServiceRegistrationListStub
+	qm, err := s.client.query("/v1/services", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// Get is used to query the registrations of a service.
+func (s *Services) Get(name string, q *QueryOptions) ([]*ServiceRegistration, *QueryMeta, error) {
+	var resp []*ServiceRegistration
+	qm, err := s.client.query("/v1/service/"+name, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
@@ -32,12 +32,57 @@ type ResourceUsage struct {
 	CpuStats    *CpuStats
 }
 
+// add sums the resource usage of the other into the resource usage
+func (ru *ResourceUsage) add(other *ResourceUsage) {
+	if other == nil {
+		return
+	}
+	if ru.MemoryStats == nil {
+		ru.MemoryStats = &MemoryStats{}
+	}
+	if m := other.MemoryStats; m != nil {
+		ru.MemoryStats.RSS += m.RSS
+		ru.MemoryStats.Cache += m.Cache
+		ru.MemoryStats.Swap += m.Swap
+		ru.MemoryStats.MaxUsage += m.MaxUsage
+		ru.MemoryStats.KernelUsage += m.KernelUsage
+		ru.MemoryStats.KernelMaxUsage += m.KernelMaxUsage
+	}
+	if ru.CpuStats == nil {
+		ru.CpuStats = &CpuStats{}
+	}
+	if c := other.CpuStats; c != nil {
+		ru.CpuStats.SystemMode += c.SystemMode
+		ru.CpuStats.UserMode += c.UserMode
+		ru.CpuStats.TotalTicks += c.TotalTicks
+		ru.CpuStats.ThrottledPeriods += c.ThrottledPeriods
+		ru.CpuStats.ThrottledTime += c.ThrottledTime
+		ru.CpuStats.Percent += c.Percent
+	}
+}
+
 // TaskResourceUsage holds aggregated resource usage of all processes in a Task
 // and the resource usage of the individual pids
 type TaskResourceUsage struct {
 	ResourceUsage *ResourceUsage
 	Timestamp     int64
 	Pids          map[string]*ResourceUsage
+	DriverStats   map[string]float64
+	DeviceStats   []*DeviceGroupStats
+}
+
+// DeviceGroupStats holds the statistics of the devices of a group
+type DeviceGroupStats struct {
+	Vendor        string
+	Type          string
+	Name          string
+	InstanceStats map[string]*DeviceStats
+}
+
+// DeviceStats holds the statistics of a device
+type DeviceStats struct {
+	Stats     map[string]float64
+	Timestamp int64
 }
 
 // AllocResourceUsage holds the aggregated task resource usage of the
@@ -71,6 +116,17 @@ type ServiceCheck struct {
 	Interval      time.Duration
 	Timeout       time.Duration
 	InitialStatus string `mapstructure:"initial_status"`
+	OnUpdate      string `mapstructure:"on_update"`
+	Readiness     bool
+	CheckRestart  *CheckRestart `mapstructure:"check_restart"`
+}
+
+// CheckRestart restarts a task once one of its checks has been unhealthy for
+// a number of consecutive check intervals
+type CheckRestart struct {
+	Limit          int
+	Grace          time.Duration
+	IgnoreWarnings bool `mapstructure:"ignore_warnings"`
 }
 
 // The Service model represents a Consul service definition
@@ -80,12 +136,14 @@ type Service struct {
 	Tags      []string
 	PortLabel string `mapstructure:"port"`
 	Checks    []ServiceCheck
+	Provider  string
 }
 
 // EphemeralDisk is an ephemeral disk object
 type EphemeralDisk struct {
-	Sticky bool
-	SizeMB int `mapstructure:"size"`
+	Sticky  bool
+	Migrate bool
+	SizeMB  int `mapstructure:"size"`
 }
 
 // TaskGroup is the unit of scheduling.
@@ -96,7 +154,35 @@ type TaskGroup struct {
 	Tasks         []*Task
 	RestartPolicy *RestartPolicy
 	EphemeralDisk *EphemeralDisk
+	Networks      []*NetworkResource
+	Volumes       map[string]*VolumeRequest
 	Meta          map[string]string
+	Scaling       *ScalingPolicy
+	Migrate       *MigrateStrategy
+}
+
+// MigrateStrategy is how the allocations of a task group are migrated off of
+// a draining node
+type MigrateStrategy struct {
+	MaxParallel    int
+	MinHealthyTime time.Duration
+}
+
+// VolumeRequest is a volume the tasks of a task group can mount. Host volumes
+// are sourced from the host volume of the same name of the client, and CSI
+// volumes from the registered CSI volume of the same ID.
+type VolumeRequest struct {
+	Name     string
+	Type     string
+	Source   string
+	ReadOnly bool
+}
+
+// VolumeMount mounts a volume of the task group into a task
+type VolumeMount struct {
+	Volume      string
+	Destination string
+	ReadOnly    bool
 }
 
 // NewTaskGroup creates a new TaskGroup.
@@ -134,6 +220,21 @@ func (g *TaskGroup) RequireDisk(disk *EphemeralDisk) *TaskGroup {
 	return g
 }
 
+// RequireNetwork adds a network shared by the tasks to the task group
+func (g *TaskGroup) RequireNetwork(network *NetworkResource) *TaskGroup {
+	g.Networks = append(g.Networks, network)
+	return g
+}
+
+// ScalingPolicy bounds the count of a task group and carries the policy
+// document of an external autoscaler
+type ScalingPolicy struct {
+	Min     int64
+	Max     int64
+	Policy  map[string]interface{}
+	Enabled bool
+}
+
 // LogConfig provides configuration for log rotation
 type LogConfig struct {
 	MaxFiles      int
@@ -142,20 +243,28 @@ type LogConfig struct {
 
 // Task is a single process in a task group.
 type Task struct {
-	Name        string
-	Driver      string
-	User        string
-	Config      map[string]interface{}
-	Constraints []*Constraint
-	Env         map[string]string
-	Services    []Service
-	Resources   *Resources
-	Meta        map[string]string
-	KillTimeout time.Duration
-	LogConfig   *LogConfig
-	Artifacts   []*TaskArtifact
-	Vault       *Vault
-	Templates   []*Template
+	Name            string
+	Driver          string
+	User            string
+	Config          map[string]interface{}
+	Constraints     []*Constraint
+	Env             map[string]string
+	Services        []Service
+	Resources       *Resources
+	Meta            map[string]string
+	KillTimeout     time.Duration
+	LogConfig       *LogConfig
+	Artifacts       []*TaskArtifact
+	Vault           *Vault
+	Consul          *Consul
+	Templates       []*Template
+	Schedule        *TaskSchedule
+	DispatchPayload *DispatchPayloadConfig
+	ShutdownOrder   int
+	Lifecycle       *TaskLifecycle
+	RestartPolicy   *RestartPolicy
+	VolumeMounts    []*VolumeMount
+	CSIPluginConfig *TaskCSIPluginConfig
 }
 
 // TaskArtifact is used to download artifacts before running a task.
@@ -163,6 +272,9 @@ type TaskArtifact struct {
 	GetterSource  string
 	GetterOptions map[string]string
 	RelativeDest  string
+	OnError       string
+	RetryAttempts int
+	RetryDelay    time.Duration
 }
 
 type Template struct {
@@ -178,6 +290,31 @@ type Template struct {
 type Vault struct {
 	Policies []string
 	Env      bool
+	Cluster  string
+}
+
+// Consul selects the Consul cluster the services of a task are registered
+// with.
+type Consul struct {
+	Cluster string
+}
+
+// TaskSchedule pauses a running task during recurring time windows.
+type TaskSchedule struct {
+	Pause    string
+	Duration time.Duration
+}
+
+// TaskLifecycle orders a task relative to the main tasks of its group.
+type TaskLifecycle struct {
+	Hook    string
+	Sidecar bool
+}
+
+// DispatchPayloadConfig configures how a task gets its input from a job
+// dispatch
+type DispatchPayloadConfig struct {
+	File string
 }
 
 // NewTask creates and initializes a new Task.
@@ -247,25 +384,37 @@ const (
 	TaskDiskExceeded           = "Disk Exceeded"
 	TaskVaultRenewalFailed     = "Vault token renewal failed"
 	TaskSiblingFailed          = "Sibling task failed"
+	TaskRestartSignal          = "Restart Signaled"
+	TaskPaused                 = "Paused"
+	TaskResumed                = "Resumed"
+	TaskPortConflict           = "Port Conflict"
+	TaskMemoryPressure         = "Memory Pressure"
+	TaskSignaling              = "Signaling"
 )
 
 // TaskEvent is an event that effects the state of a task and contains meta-data
 // appropriate to the events type.
 type TaskEvent struct {
-	Type            string
-	Time            int64
-	RestartReason   string
-	DriverError     string
-	ExitCode        int
-	Signal          int
-	Message         string
-	KillTimeout     time.Duration
-	KillError       string
-	StartDelay      int64
-	DownloadError   string
-	ValidationError string
-	DiskLimit       int64
-	DiskSize        int64
-	FailedSibling   string
-	VaultError      string
+	Type             string
+	Time             int64
+	RestartReason    string
+	DriverError      string
+	ExitCode         int
+	Signal           int
+	Message          string
+	KillTimeout      time.Duration
+	KillError        string
+	StartDelay       int64
+	DownloadError    string
+	DownloadAttempt  int
+	ValidationError  string
+	DiskLimit        int64
+	DiskSize         int64
+	FailedSibling    string
+	VaultError       string
+	PauseReason      string
+	PortConflicts    []string
+	MemoryPressure   string
+	TaskSignal       string
+	TaskSignalReason string
 }
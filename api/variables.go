@@ -0,0 +1,113 @@
+package api
+
+import (
+	"fmt"
+)
+
+// VariableMetadata is the unencrypted metadata of a variable.
+type VariableMetadata struct {
+	Namespace   string
+	Path        string
+	CreateTime  int64
+	ModifyTime  int64
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// Variable is a set of key/value items stored encrypted at a path of a
+// namespace.
+type Variable struct {
+	Namespace   string
+	Path        string
+	Items       map[string]string
+	CreateTime  int64
+	ModifyTime  int64
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// Variables is used to query the variables endpoints.
+type Variables struct {
+	client *Client
+}
+
+// Variables returns a new handle on the variables.
+func (c *Client) Variables() *Variables {
+	return &Variables{client: c}
+}
+
+// List is used to list the metadata of the variables.
+func (v *Variables) List(q *QueryOptions) ([]*VariableMetadata, *QueryMeta, error) {
+	var resp []*VariableMetadata
+	qm, err := v.client.query("/v1/vars", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// PrefixList is used to list the metadata of the variables whose path has
+// the given prefix.
+func (v *Variables) PrefixList(prefix string, q *QueryOptions) ([]*VariableMetadata, *QueryMeta, error) {
+	if q == nil {
+		q = &QueryOptions{Prefix: prefix}
+	} else {
+		q.Prefix = prefix
+	}
+
+	return v.List(q)
+}
+
+// Read is used to read the variable at the given path.
+func (v *Variables) Read(path string, q *QueryOptions) (*Variable, *QueryMeta, error) {
+	var resp Variable
+	qm, err := v.client.query("/v1/var/"+path, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Put is used to create or update a variable.
+func (v *Variables) Put(variable *Variable, q *WriteOptions) (*VariableMetadata, *WriteMeta, error) {
+	return v.put(variable, "", q)
+}
+
+// CheckedPut is used to create or update a variable only if its modify index
+// matches the given index. An index of zero creates the variable only if it
+// doesn't exist yet.
+func (v *Variables) CheckedPut(variable *Variable, index uint64, q *WriteOptions) (*VariableMetadata, *WriteMeta, error) {
+	return v.put(variable, fmt.Sprintf("?cas=%d", index), q)
+}
+
+func (v *Variables) put(variable *Variable, query string, q *WriteOptions) (*VariableMetadata, *WriteMeta, error) {
+	if variable == nil || variable.Path == "" {
+		return nil, nil, fmt.Errorf("missing variable path")
+	}
+
+	var resp VariableMetadata
+	wm, err := v.client.write("/v1/var/"+variable.Path+query, variable, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// Delete is used to delete the variable at the given path.
+func (v *Variables) Delete(path string, q *WriteOptions) (*WriteMeta, error) {
+	wm, err := v.client.delete("/v1/var/"+path, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// CheckedDelete is used to delete the variable at the given path only if its
+// modify index matches the given index.
+func (v *Variables) CheckedDelete(path string, index uint64, q *WriteOptions) (*WriteMeta, error) {
+	wm, err := v.client.delete(fmt.Sprintf("/v1/var/%s?cas=%d", path, index), nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
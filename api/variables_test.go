@@ -0,0 +1,74 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVariables_Put_Read_List_Delete(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	variables := c.Variables()
+
+	// No variables exist initially
+	resp, qm, err := variables.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if len(resp) != 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Create a variable
+	variable := &Variable{
+		Path:  "project/db",
+		Items: map[string]string{"password": "hunter2"},
+	}
+	meta, wm, err := variables.CheckedPut(variable, 0, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+	if meta.Path != variable.Path || meta.ModifyIndex == 0 {
+		t.Fatalf("bad: %#v", meta)
+	}
+
+	// Creating it again conflicts
+	if _, _, err := variables.CheckedPut(variable, 0, nil); err == nil || !strings.Contains(err.Error(), "409") {
+		t.Fatalf("expected conflict: %v", err)
+	}
+
+	// Query the variable back
+	out, qm, err := variables.Read("project/db", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if out.Items["password"] != "hunter2" || out.ModifyIndex != meta.ModifyIndex {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	resp, _, err = variables.PrefixList("project/", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resp) != 1 || resp[0].Path != variable.Path {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Delete the variable
+	wm, err = variables.CheckedDelete("project/db", meta.ModifyIndex, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	resp, _, err = variables.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resp) != 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
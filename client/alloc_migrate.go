@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/consul/tlsutil"
+	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// AllocDataMigrator is used to migrate the data of the previous allocation of
+// an allocation into the alloc dir of the allocation before its tasks start
+type AllocDataMigrator interface {
+	MigrateAllocData(alloc *structs.Allocation, dest *allocdir.AllocDir, stopCh <-chan struct{}) error
+}
+
+// MigrateAllocData copies the data dir and the task local dirs of the previous
+// allocation of a sticky allocation into its alloc dir. The data is copied
+// from the alloc dir of the previous allocation if it ran on this node, and
+// otherwise fetched from the node it ran on if the ephemeral disk is set to
+// migrate, once the previous allocation stopped.
+func (c *Client) MigrateAllocData(alloc *structs.Allocation, dest *allocdir.AllocDir, stopCh <-chan struct{}) error {
+	if alloc.PreviousAllocation == "" {
+		return nil
+	}
+	tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+	if tg == nil || tg.EphemeralDisk == nil || !tg.EphemeralDisk.Sticky {
+		return nil
+	}
+
+	// Previous allocations on this node are blocking the allocation until
+	// they terminate, so their data is complete
+	if prevFS, err := c.GetAllocFS(alloc.PreviousAllocation); err == nil {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(prevFS.Snapshot(pw))
+		}()
+		err := dest.Restore(pr)
+		pr.Close()
+		return err
+	}
+
+	if !tg.EphemeralDisk.Migrate {
+		return nil
+	}
+	return c.migrateRemoteAllocData(alloc, dest, stopCh)
+}
+
+// migrateRemoteAllocData fetches the data of the previous allocation of the
+// allocation from the HTTP API of the node it ran on
+func (c *Client) migrateRemoteAllocData(alloc *structs.Allocation, dest *allocdir.AllocDir, stopCh <-chan struct{}) error {
+	prev, err := c.waitForTerminalAlloc(alloc.PreviousAllocation, stopCh)
+	if err != nil {
+		return err
+	}
+	if prev == nil || prev.NodeID == c.Node().ID {
+		// The previous allocation was collected, with its data
+		return nil
+	}
+
+	// Look up the address of the node of the previous allocation
+	nodeReq := structs.NodeSpecificRequest{
+		NodeID: prev.NodeID,
+		QueryOptions: structs.QueryOptions{
+			Region:     c.Region(),
+			AuthToken:  c.Node().SecretID,
+			AllowStale: true,
+		},
+	}
+	var nodeResp structs.SingleNodeResponse
+	if err := c.RPC("Node.GetNode", &nodeReq, &nodeResp); err != nil {
+		return fmt.Errorf("failed to look up node %q: %v", prev.NodeID, err)
+	}
+	if nodeResp.Node == nil || nodeResp.Node.HTTPAddr == "" {
+		return fmt.Errorf("address of node %q not found", prev.NodeID)
+	}
+
+	httpClient, scheme, err := c.migrateHTTPClient()
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s://%s/v1/client/allocation/%s/snapshot", scheme, nodeResp.Node.HTTPAddr, prev.ID)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	if token := c.migrateToken(alloc.ID); token != "" {
+		req.Header.Set("X-Nomad-Token", token)
+	}
+
+	// Abort the download if the allocation is stopped
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to fetch snapshot of allocation %q: %v", prev.ID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("failed to fetch snapshot of allocation %q: unexpected response code %d (%s)",
+			prev.ID, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return dest.Restore(resp.Body)
+}
+
+// waitForTerminalAlloc blocks until the allocation terminates and returns it.
+// A nil allocation is returned if it doesn't exist anymore.
+func (c *Client) waitForTerminalAlloc(allocID string, stopCh <-chan struct{}) (*structs.Allocation, error) {
+	req := structs.AllocsGetRequest{
+		AllocIDs: []string{allocID},
+		QueryOptions: structs.QueryOptions{
+			Region:     c.Region(),
+			AllowStale: true,
+		},
+	}
+	for {
+		var resp structs.AllocsGetResponse
+		if err := c.RPC("Alloc.GetAllocs", &req, &resp); err != nil {
+			if strings.Contains(err.Error(), "unknown alloc id") {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if len(resp.Allocs) == 1 && resp.Allocs[0].Terminated() {
+			return resp.Allocs[0], nil
+		}
+
+		select {
+		case <-stopCh:
+			return nil, fmt.Errorf("allocation stopped while waiting for allocation %q to terminate", allocID)
+		case <-c.shutdownCh:
+			return nil, fmt.Errorf("client shutdown while waiting for allocation %q to terminate", allocID)
+		default:
+		}
+		if resp.Index > req.MinQueryIndex {
+			req.MinQueryIndex = resp.Index
+		}
+	}
+}
+
+// migrateHTTPClient returns the HTTP client and the scheme used to fetch the
+// data of allocations from other nodes, which serve their API over TLS when
+// this node does
+func (c *Client) migrateHTTPClient() (*http.Client, string, error) {
+	conf := c.config.TLSConfig
+	if conf == nil || !conf.EnableHTTP {
+		return &http.Client{}, "http", nil
+	}
+
+	tc := &tlsutil.Config{
+		CAFile:   conf.CAFile,
+		CertFile: conf.CertFile,
+		KeyFile:  conf.KeyFile,
+	}
+	tlsConf := &tls.Config{RootCAs: x509.NewCertPool()}
+	if err := tc.AppendCA(tlsConf.RootCAs); err != nil {
+		return nil, "", err
+	}
+	cert, err := tc.KeyPair()
+	if err != nil {
+		return nil, "", err
+	} else if cert != nil {
+		tlsConf.Certificates = []tls.Certificate{*cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConf}}, "https", nil
+}
+
+// migrateToken returns the token authorizing the migration of the data of
+// the previous allocation of the allocation, if ACLs are enabled
+func (c *Client) migrateToken(allocID string) string {
+	c.migrateTokensLock.RLock()
+	defer c.migrateTokensLock.RUnlock()
+	return c.migrateTokens[allocID]
+}
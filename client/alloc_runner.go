@@ -1,11 +1,15 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +17,7 @@ import (
 	"github.com/hashicorp/nomad/client/allocdir"
 	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/client/driver"
+	"github.com/hashicorp/nomad/client/network"
 	"github.com/hashicorp/nomad/client/vaultclient"
 	"github.com/hashicorp/nomad/nomad/structs"
 
@@ -35,11 +40,25 @@ const (
 	// vaultTokenFile is the name of the file holding the Vault token inside the
 	// task's secret directory
 	vaultTokenFile = "vault_token"
+
+	// identityTokenFile is the name of the file holding the workload identity
+	// inside the task's secret directory
+	identityTokenFile = "nomad_identity_token"
+
+	// variablesFile is the name of the file holding the variables the task's
+	// workload identity grants access to inside the task's secret directory
+	variablesFile = "nomad_variables.json"
 )
 
 // AllocStateUpdater is used to update the status of an allocation
 type AllocStateUpdater func(alloc *structs.Allocation)
 
+// VariableReader is used to read the variables a workload identity grants
+// access to
+type VariableReader interface {
+	ReadVariable(token, namespace, path string) (*structs.VariableDecrypted, error)
+}
+
 type AllocStatsReporter interface {
 	LatestAllocStats(taskFilter string) (*cstructs.AllocResourceUsage, error)
 }
@@ -64,13 +83,66 @@ type AllocRunner struct {
 	restored   map[string]struct{}
 	taskLock   sync.RWMutex
 
+	// started are the tasks whose runners were started. Tasks are started in
+	// the order of their lifecycle hooks, and lifecycleCh is notified when the
+	// state of a task changes so that the next tasks can be started. exited
+	// are the started tasks whose runners have since returned.
+	started     map[string]struct{}
+	exited      map[string]struct{}
+	lifecycleCh chan struct{}
+
 	taskStatusLock sync.RWMutex
 
 	updateCh chan *structs.Allocation
 
+	// evictCh is used to fail the allocation and kill its tasks
+	evictCh chan *allocEviction
+
 	vaultClient vaultclient.VaultClient
 	vaultTokens map[string]vaultToken
 
+	// vaultClusters are the clients of the additional, named Vault
+	// clusters. vaultClient is the client of the default cluster.
+	vaultClusters map[string]vaultclient.VaultClient
+
+	// network configures the network namespace shared by the tasks of
+	// allocations using an isolated task group network
+	network *network.Manager
+
+	// serviceRegs is used by the tasks to register their services in
+	// Nomad's built-in service catalog
+	serviceRegs ServiceRegistrationHandler
+
+	// variables is used to read the variables exposed to the tasks through
+	// their workload identity
+	variables VariableReader
+
+	// taskVariables are the items of the variables exposed to each task,
+	// with the items of the most specific paths taking precedence
+	taskVariables map[string]map[string]string
+
+	// templateReader is used by the templates of the tasks to query the
+	// variables and services of Nomad
+	templateReader TemplateReader
+
+	// ports is used to reserve the static ports of the allocation that are
+	// found to be bound by host processes
+	ports PortReserver
+
+	// devices is used to reserve the devices requested by the tasks
+	devices DeviceReserver
+
+	// csi is used to register the CSI plugins run by the tasks and to mount
+	// the CSI volumes requested by the task group. csiVolumes are the
+	// volumes mounted for the allocation, keyed by volume name.
+	csi        CSIManager
+	csiVolumes map[string]*structs.CSIVolume
+	csiLock    sync.Mutex
+
+	// migrator is used to migrate the data of the previous allocation into
+	// the alloc dir of a new allocation
+	migrator AllocDataMigrator
+
 	destroy     bool
 	destroyCh   chan struct{}
 	destroyLock sync.Mutex
@@ -80,6 +152,13 @@ type AllocRunner struct {
 	persistLock sync.Mutex
 }
 
+// allocEviction is the event killing the tasks of an evicted allocation and
+// the description of its failure
+type allocEviction struct {
+	event *structs.TaskEvent
+	desc  string
+}
+
 // allocRunnerState is used to snapshot the state of the alloc runner
 type allocRunnerState struct {
 	Version                string
@@ -87,11 +166,21 @@ type allocRunnerState struct {
 	AllocClientStatus      string
 	AllocClientDescription string
 	Context                *driver.ExecContext
+
+	// StartedTasks are the tasks whose runners were started. All the tasks
+	// were started if it is nil, as in snapshots taken before lifecycle
+	// hooks.
+	StartedTasks []string
+
+	// CSIVolumes are the CSI volumes mounted for the allocation, which are
+	// unmounted once its tasks are dead
+	CSIVolumes map[string]*structs.CSIVolume
 }
 
 // NewAllocRunner is used to create a new allocation context
 func NewAllocRunner(logger *log.Logger, config *config.Config, updater AllocStateUpdater,
-	alloc *structs.Allocation, vaultClient vaultclient.VaultClient) *AllocRunner {
+	alloc *structs.Allocation, vaultClient vaultclient.VaultClient,
+	serviceRegs ServiceRegistrationHandler) *AllocRunner {
 	ar := &AllocRunner{
 		config:      config,
 		updater:     updater,
@@ -101,14 +190,62 @@ func NewAllocRunner(logger *log.Logger, config *config.Config, updater AllocStat
 		tasks:       make(map[string]*TaskRunner),
 		taskStates:  copyTaskStates(alloc.TaskStates),
 		restored:    make(map[string]struct{}),
+		started:     make(map[string]struct{}),
+		exited:      make(map[string]struct{}),
+		lifecycleCh: make(chan struct{}, 1),
 		updateCh:    make(chan *structs.Allocation, 64),
+		evictCh:     make(chan *allocEviction, 1),
 		destroyCh:   make(chan struct{}),
 		waitCh:      make(chan struct{}),
 		vaultClient: vaultClient,
+		serviceRegs: serviceRegs,
+		network:     network.NewManager(config, logger),
 	}
 	return ar
 }
 
+// SetVaultClusters is used to set the clients of the named Vault clusters
+// the tasks of the allocation may derive their tokens from
+func (r *AllocRunner) SetVaultClusters(clients map[string]vaultclient.VaultClient) {
+	r.vaultClusters = clients
+}
+
+// SetVariableReader is used to set the reader of the variables exposed to
+// the tasks of the allocation. If no reader is set, no variables are exposed.
+func (r *AllocRunner) SetVariableReader(reader VariableReader) {
+	r.variables = reader
+}
+
+// SetTemplateReader is used to set the reader the templates of the tasks query
+// the variables and services of Nomad with
+func (r *AllocRunner) SetTemplateReader(reader TemplateReader) {
+	r.templateReader = reader
+}
+
+// SetPortReserver is used to set the reserver of the static ports that are
+// found to be bound by host processes when the allocation starts.
+func (r *AllocRunner) SetPortReserver(reserver PortReserver) {
+	r.ports = reserver
+}
+
+// SetDeviceReserver is used to set the reserver of the devices requested by
+// the tasks of the allocation
+func (r *AllocRunner) SetDeviceReserver(reserver DeviceReserver) {
+	r.devices = reserver
+}
+
+// SetCSIManager is used to set the manager registering the CSI plugins run by
+// the tasks and mounting the CSI volumes of the allocation
+func (r *AllocRunner) SetCSIManager(manager CSIManager) {
+	r.csi = manager
+}
+
+// SetDataMigrator is used to set the migrator of the data of the previous
+// allocation. If no migrator is set, the allocation starts with empty dirs.
+func (r *AllocRunner) SetDataMigrator(migrator AllocDataMigrator) {
+	r.migrator = migrator
+}
+
 // stateFilePath returns the path to our state file
 func (r *AllocRunner) stateFilePath() string {
 	r.allocLock.Lock()
@@ -131,6 +268,7 @@ func (r *AllocRunner) RestoreState() error {
 	r.allocClientStatus = snap.AllocClientStatus
 	r.allocClientDescription = snap.AllocClientDescription
 	r.taskStates = snap.Alloc.TaskStates
+	r.csiVolumes = snap.CSIVolumes
 
 	var snapshotErrors multierror.Error
 	if r.alloc == nil {
@@ -146,7 +284,17 @@ func (r *AllocRunner) RestoreState() error {
 	// Recover the Vault tokens
 	vaultErr := r.recoverVaultTokens()
 
+	// Recover the variables of the tasks
+	r.recoverTaskVariables()
+
 	// Restore the task runners
+	var started map[string]struct{}
+	if snap.StartedTasks != nil {
+		started = make(map[string]struct{}, len(snap.StartedTasks))
+		for _, name := range snap.StartedTasks {
+			started[name] = struct{}{}
+		}
+	}
 	var mErr multierror.Error
 	for name, state := range r.taskStates {
 		// Mark the task as restored.
@@ -155,14 +303,29 @@ func (r *AllocRunner) RestoreState() error {
 		task := &structs.Task{Name: name}
 		tr := NewTaskRunner(r.logger, r.config, r.setTaskState, r.ctx, r.Alloc(),
 			task)
+		tr.SetServiceRegistrationHandler(r.serviceRegs)
+		tr.SetTemplateReader(r.templateReader)
+		tr.SetDeviceReserver(r.devices)
 		r.tasks[name] = tr
 
 		if vt, ok := r.vaultTokens[name]; ok {
 			tr.SetVaultToken(vt.token, vt.renewalCh)
 		}
+		if items, ok := r.taskVariables[name]; ok {
+			tr.SetVariables(items)
+		}
+
+		// Tasks whose lifecycle hook wasn't due yet are started by Run
+		if started != nil {
+			if _, ok := started[name]; !ok {
+				continue
+			}
+		}
+		r.started[name] = struct{}{}
 
 		// Skip tasks in terminal states.
 		if state.State == structs.TaskStateDead {
+			r.exited[name] = struct{}{}
 			continue
 		}
 
@@ -171,7 +334,7 @@ func (r *AllocRunner) RestoreState() error {
 			mErr.Errors = append(mErr.Errors, err)
 		} else if !r.alloc.TerminalStatus() {
 			// Only start if the alloc isn't in a terminal status.
-			go tr.Run()
+			go r.runTaskRunner(name, tr)
 		}
 	}
 
@@ -228,12 +391,28 @@ func (r *AllocRunner) saveAllocRunnerState() error {
 	ctx := r.ctx
 	r.ctxLock.Unlock()
 
+	r.taskLock.RLock()
+	started := make([]string, 0, len(r.started))
+	for name := range r.started {
+		started = append(started, name)
+	}
+	r.taskLock.RUnlock()
+
+	r.csiLock.Lock()
+	csiVolumes := make(map[string]*structs.CSIVolume, len(r.csiVolumes))
+	for name, vol := range r.csiVolumes {
+		csiVolumes[name] = vol
+	}
+	r.csiLock.Unlock()
+
 	snap := allocRunnerState{
 		Version:                r.config.Version,
 		Alloc:                  alloc,
 		Context:                ctx,
 		AllocClientStatus:      allocClientStatus,
 		AllocClientDescription: allocClientDescription,
+		StartedTasks:           started,
+		CSIVolumes:             csiVolumes,
 	}
 	return persistState(r.stateFilePath(), &snap)
 }
@@ -253,7 +432,76 @@ func (r *AllocRunner) DestroyState() error {
 
 // DestroyContext is used to destroy the context
 func (r *AllocRunner) DestroyContext() error {
-	return r.ctx.AllocDir.Destroy()
+	var mErr multierror.Error
+	if err := r.teardownNetwork(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+	if err := r.ctx.AllocDir.Destroy(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+	r.deregisterCSIPlugins()
+	if err := r.unmountCSIVolumes(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+	return mErr.ErrorOrNil()
+}
+
+// groupNetwork returns the isolated network assigned to the task group of the
+// allocation, if any
+func (r *AllocRunner) groupNetwork() *structs.NetworkResource {
+	alloc := r.Alloc()
+	if alloc.SharedResources == nil {
+		return nil
+	}
+	for _, n := range alloc.SharedResources.Networks {
+		if n.Isolated() {
+			return n
+		}
+	}
+	return nil
+}
+
+// setupNetwork creates the network namespace of the allocation if its task
+// group uses an isolated network. The namespace is kept in the execution
+// context so that it is reused when the alloc runner is restored.
+func (r *AllocRunner) setupNetwork() error {
+	n := r.groupNetwork()
+	if n == nil {
+		return nil
+	}
+
+	r.ctxLock.Lock()
+	defer r.ctxLock.Unlock()
+	if r.ctx.NetworkNamespace != "" {
+		return nil
+	}
+
+	path, err := r.network.Setup(r.alloc.ID, n)
+	if err != nil {
+		return err
+	}
+	r.ctx.NetworkNamespace = path
+	return nil
+}
+
+// teardownNetwork removes the network namespace of the allocation
+func (r *AllocRunner) teardownNetwork() error {
+	r.ctxLock.Lock()
+	defer r.ctxLock.Unlock()
+	if r.ctx == nil || r.ctx.NetworkNamespace == "" {
+		return nil
+	}
+
+	n := r.groupNetwork()
+	if n == nil {
+		return fmt.Errorf("network of task group %q not found to remove network namespace %q",
+			r.alloc.TaskGroup, r.ctx.NetworkNamespace)
+	}
+	if err := r.network.Teardown(r.alloc.ID, r.ctx.NetworkNamespace, n); err != nil {
+		return err
+	}
+	r.ctx.NetworkNamespace = ""
+	return nil
 }
 
 // copyTaskStates returns a copy of the passed task states.
@@ -368,7 +616,7 @@ func (r *AllocRunner) setTaskState(taskName, state string, event *structs.TaskEv
 	if state == structs.TaskStateDead {
 		// If the task has a Vault token, stop renewing it
 		if vt, ok := r.vaultTokens[taskName]; ok {
-			if err := r.vaultClient.StopRenewToken(vt.token); err != nil {
+			if err := vt.client.StopRenewToken(vt.token); err != nil {
 				r.logger.Printf("[ERR] client: stopping token renewal for task %q failed: %v", taskName, err)
 			}
 		}
@@ -392,6 +640,10 @@ func (r *AllocRunner) setTaskState(taskName, state string, event *structs.TaskEv
 	case r.dirtyCh <- struct{}{}:
 	default:
 	}
+	select {
+	case r.lifecycleCh <- struct{}{}:
+	default:
+	}
 }
 
 // appendTaskEvent updates the task status by appending the new event.
@@ -427,8 +679,19 @@ func (r *AllocRunner) Run() {
 
 	// Create the execution context
 	r.ctxLock.Lock()
-	if r.ctx == nil {
+	newCtx := r.ctx == nil
+	if newCtx {
 		allocDir := allocdir.NewAllocDir(filepath.Join(r.config.AllocDir, r.alloc.ID), r.Alloc().Resources.DiskMB)
+		allocDir.SecretsSizeMB = r.config.SecretsTmpfsSize
+		allocDir.LocalSizeMB = r.config.LocalTmpfsSize
+		volumeMounts, err := r.volumeMounts(tg)
+		if err != nil {
+			r.logger.Printf("[ERR] client: failed to mount volumes of alloc %q: %v", alloc.ID, err)
+			r.setStatus(structs.AllocClientStatusFailed, fmt.Sprintf("failed to mount volumes: %v", err))
+			r.ctxLock.Unlock()
+			return
+		}
+		allocDir.VolumeMounts = volumeMounts
 		if err := allocDir.Build(tg.Tasks); err != nil {
 			r.logger.Printf("[WARN] client: failed to build task directories: %v", err)
 			r.setStatus(structs.AllocClientStatusFailed, fmt.Sprintf("failed to build task dirs for '%s'", alloc.TaskGroup))
@@ -449,6 +712,25 @@ func (r *AllocRunner) Run() {
 		return
 	}
 
+	// Register the CSI plugins run by the tasks, which are fingerprinted once
+	// they serve on the sockets of their plugin dirs
+	if err := r.registerCSIPlugins(); err != nil {
+		msg := fmt.Sprintf("failed to register CSI plugins of allocation %q: %v", r.alloc.ID, err)
+		r.logger.Printf("[ERR] client: %s", msg)
+		r.setStatus(structs.AllocClientStatusFailed, msg)
+		return
+	}
+
+	// Migrate the data of the previous allocation into the new alloc dir.
+	// The migration is best effort and the tasks start with empty dirs if it
+	// fails.
+	if newCtx && r.migrator != nil {
+		if err := r.migrator.MigrateAllocData(alloc, r.ctx.AllocDir, r.destroyCh); err != nil {
+			r.logger.Printf("[WARN] client: failed to migrate data of previous allocation %q to alloc %q: %v",
+				alloc.PreviousAllocation, alloc.ID, err)
+		}
+	}
+
 	// Request Vault tokens for the tasks that require them
 	err := r.deriveVaultTokens()
 	if err != nil {
@@ -458,7 +740,53 @@ func (r *AllocRunner) Run() {
 		return
 	}
 
-	// Start the task runners
+	// Write the workload identities of the tasks
+	if err := r.writeIdentityTokens(); err != nil {
+		msg := fmt.Sprintf("failed to write workload identities for allocation %q: %v", r.alloc.ID, err)
+		r.logger.Printf("[ERR] client: %s", msg)
+		r.setStatus(structs.AllocClientStatusFailed, msg)
+		return
+	}
+
+	// Write the variables the workload identities grant access to
+	if err := r.writeTaskVariables(); err != nil {
+		msg := fmt.Sprintf("failed to write variables for allocation %q: %v", r.alloc.ID, err)
+		r.logger.Printf("[ERR] client: %s", msg)
+		r.setStatus(structs.AllocClientStatusFailed, msg)
+		return
+	}
+
+	// Fail the tasks rather than letting them crash loop if their static
+	// ports are already bound on the host
+	if conflicts := r.portConflicts(); len(conflicts) != 0 {
+		addrs := portConflictAddrs(conflicts)
+		for _, task := range tg.Tasks {
+			r.setTaskState(task.Name, structs.TaskStateDead,
+				structs.NewTaskEvent(structs.TaskPortConflict).SetPortConflicts(addrs))
+		}
+		if r.ports != nil {
+			r.ports.ReserveHostPorts(conflicts)
+		}
+
+		msg := fmt.Sprintf("static ports of allocation %q already in use on the host: %s", r.alloc.ID, strings.Join(addrs, ", "))
+		r.logger.Printf("[ERR] client: %s", msg)
+		r.setStatus(structs.AllocClientStatusFailed, msg)
+		return
+	}
+
+	// Create the network namespace shared by the tasks
+	if err := r.setupNetwork(); err != nil {
+		msg := fmt.Sprintf("failed to set up network for allocation %q: %v", r.alloc.ID, err)
+		r.logger.Printf("[ERR] client: %s", msg)
+		r.setStatus(structs.AllocClientStatusFailed, msg)
+		return
+	}
+	if err := r.saveAllocRunnerState(); err != nil {
+		r.logger.Printf("[WARN] client: failed to save state for alloc %q: %v", r.alloc.ID, err)
+	}
+
+	// Create the task runners, which are started in the order of the
+	// lifecycle hooks of their tasks
 	r.logger.Printf("[DEBUG] client: starting task runners for alloc '%s'", r.alloc.ID)
 	r.taskLock.Lock()
 	for _, task := range tg.Tasks {
@@ -467,6 +795,9 @@ func (r *AllocRunner) Run() {
 		}
 
 		tr := NewTaskRunner(r.logger, r.config, r.setTaskState, r.ctx, r.Alloc(), task.Copy())
+		tr.SetServiceRegistrationHandler(r.serviceRegs)
+		tr.SetTemplateReader(r.templateReader)
+		tr.SetDeviceReserver(r.devices)
 		r.tasks[task.Name] = tr
 		tr.MarkReceived()
 
@@ -475,9 +806,13 @@ func (r *AllocRunner) Run() {
 			tr.SetVaultToken(vt.token, vt.renewalCh)
 		}
 
-		go tr.Run()
+		// Expose the variables to the task's environment variables
+		if items, ok := r.taskVariables[task.Name]; ok {
+			tr.SetVariables(items)
+		}
 	}
 	r.taskLock.Unlock()
+	r.advanceLifecycle(tg)
 
 	// Start watching the shared allocation directory for disk usage
 	go r.ctx.AllocDir.StartDiskWatcher()
@@ -510,12 +845,18 @@ OUTER:
 			for _, tr := range runners {
 				tr.Update(update)
 			}
+		case <-r.lifecycleCh:
+			r.advanceLifecycle(tg)
 		case <-watchdog.C:
 			if event, desc := r.checkResources(); event != nil {
 				r.setStatus(structs.AllocClientStatusFailed, desc)
 				taskDestroyEvent = event
 				break OUTER
 			}
+		case eviction := <-r.evictCh:
+			r.setStatus(structs.AllocClientStatusFailed, eviction.desc)
+			taskDestroyEvent = eviction.event
+			break OUTER
 		case <-r.destroyCh:
 			taskDestroyEvent = structs.NewTaskEvent(structs.TaskKilled)
 			break OUTER
@@ -525,6 +866,9 @@ OUTER:
 	// Kill the task runners
 	r.destroyTaskRunners(taskDestroyEvent)
 
+	// Release the CSI plugins and volumes now that the tasks are dead
+	r.releaseCSI()
+
 	// Stop watching the shared allocation directory
 	r.ctx.AllocDir.StopDiskWatcher()
 
@@ -534,27 +878,262 @@ OUTER:
 }
 
 // destroyTaskRunners destroys the task runners, waits for them to terminate and
-// then saves state.
+// then saves state. The task runners are destroyed in the stages of their
+// shutdown order, so a stage is only destroyed once the previous stages have
+// terminated. The poststop tasks are then run if the main tasks were started.
 func (r *AllocRunner) destroyTaskRunners(destroyEvent *structs.TaskEvent) {
-	// Destroy each sub-task
-	runners := r.getTaskRunners()
-	for _, tr := range runners {
-		tr.Destroy(destroyEvent)
+	tg := r.Alloc().Job.LookupTaskGroup(r.alloc.TaskGroup)
+
+	// Tasks that were never started are dead with the event
+	r.taskLock.RLock()
+	var unstarted []string
+	for name := range r.tasks {
+		if _, ok := r.started[name]; !ok && !isPoststopTask(tg, name) {
+			unstarted = append(unstarted, name)
+		}
+	}
+	r.taskLock.RUnlock()
+	for _, name := range unstarted {
+		r.setTaskState(name, structs.TaskStateDead, destroyEvent)
 	}
 
-	// Wait for termination of the task runners
-	for _, tr := range runners {
-		<-tr.WaitCh()
+	for _, runners := range r.shutdownStages() {
+		// Destroy each sub-task of the stage
+		for _, tr := range runners {
+			tr.Destroy(destroyEvent)
+		}
+
+		// Wait for termination of the task runners
+		for _, tr := range runners {
+			<-tr.WaitCh()
+		}
+	}
+
+	// Run the poststop tasks to completion
+	if tg != nil {
+		var poststop []*TaskRunner
+		mainStarted := false
+		r.taskLock.RLock()
+		for _, task := range tg.Tasks {
+			if _, ok := r.started[task.Name]; ok && task.IsMainTask() {
+				mainStarted = true
+			}
+		}
+		r.taskLock.RUnlock()
+		for _, task := range tg.Tasks {
+			if !isPoststopTask(tg, task.Name) {
+				continue
+			}
+			if !mainStarted {
+				r.setTaskState(task.Name, structs.TaskStateDead, destroyEvent)
+				continue
+			}
+			if tr := r.startTaskRunner(task.Name); tr != nil {
+				poststop = append(poststop, tr)
+			}
+		}
+		for _, tr := range poststop {
+			<-tr.WaitCh()
+		}
 	}
 
 	// Final state sync
 	r.syncStatus()
 }
 
-// vaultToken acts as a tuple of the token and renewal channel
+// shutdownStages returns the started task runners grouped by the shutdown
+// order of their tasks, in increasing shutdown order. Sidecars are stopped
+// after the other tasks and poststop tasks aren't stopped.
+func (r *AllocRunner) shutdownStages() [][]*TaskRunner {
+	alloc := r.Alloc()
+	tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+	orders := make(map[string]int)
+	sidecars := make(map[string]bool)
+	if tg != nil {
+		for _, task := range tg.Tasks {
+			orders[task.Name] = task.ShutdownOrder
+			sidecars[task.Name] = task.Lifecycle != nil && task.Lifecycle.Sidecar
+		}
+	}
+
+	r.taskLock.RLock()
+	byOrder := make(map[int][]*TaskRunner)
+	sidecarsByOrder := make(map[int][]*TaskRunner)
+	for name, tr := range r.tasks {
+		if _, ok := r.started[name]; !ok || isPoststopTask(tg, name) {
+			continue
+		}
+		if _, ok := r.exited[name]; ok {
+			continue
+		}
+		order := orders[name]
+		if sidecars[name] {
+			sidecarsByOrder[order] = append(sidecarsByOrder[order], tr)
+		} else {
+			byOrder[order] = append(byOrder[order], tr)
+		}
+	}
+	r.taskLock.RUnlock()
+
+	return append(orderedStages(byOrder), orderedStages(sidecarsByOrder)...)
+}
+
+// orderedStages returns the task runners grouped by shutdown order in
+// increasing order
+func orderedStages(byOrder map[int][]*TaskRunner) [][]*TaskRunner {
+	keys := make([]int, 0, len(byOrder))
+	for order := range byOrder {
+		keys = append(keys, order)
+	}
+	sort.Ints(keys)
+
+	stages := make([][]*TaskRunner, 0, len(keys))
+	for _, order := range keys {
+		stages = append(stages, byOrder[order])
+	}
+	return stages
+}
+
+// isPoststopTask returns whether the task of the group runs once the main
+// tasks have exited
+func isPoststopTask(tg *structs.TaskGroup, name string) bool {
+	if tg == nil {
+		return false
+	}
+	task := tg.LookupTask(name)
+	return task != nil && task.Lifecycle != nil && task.Lifecycle.Hook == structs.TaskLifecycleHookPoststop
+}
+
+// startTaskRunner starts the runner of the task unless it was already started
+// and returns it
+func (r *AllocRunner) startTaskRunner(name string) *TaskRunner {
+	r.taskLock.Lock()
+	defer r.taskLock.Unlock()
+
+	tr, ok := r.tasks[name]
+	if !ok {
+		return nil
+	}
+	if _, ok := r.started[name]; !ok {
+		r.started[name] = struct{}{}
+		go r.runTaskRunner(name, tr)
+	}
+	return tr
+}
+
+// runTaskRunner runs the task runner and records that it exited, so that the
+// tasks whose lifecycle hook depends on it can be started
+func (r *AllocRunner) runTaskRunner(name string, tr *TaskRunner) {
+	tr.Run()
+
+	r.taskLock.Lock()
+	r.exited[name] = struct{}{}
+	r.taskLock.Unlock()
+
+	select {
+	case r.lifecycleCh <- struct{}{}:
+	default:
+	}
+}
+
+// advanceLifecycle starts the tasks whose lifecycle hook is due given the
+// state of the other tasks. Prestart tasks are started first and the main
+// tasks once the prestart tasks have completed successfully and the prestart
+// sidecars are running. Poststart tasks are started once the main tasks are
+// running, and poststop tasks once the main tasks have exited and the
+// sidecars, which are then stopped, have exited. No task is started once a
+// task failed, as the allocation is then failed.
+func (r *AllocRunner) advanceLifecycle(tg *structs.TaskGroup) {
+	r.taskStatusLock.RLock()
+	states := copyTaskStates(r.taskStates)
+	r.taskStatusLock.RUnlock()
+	for _, state := range states {
+		if state.Failed() {
+			return
+		}
+	}
+
+	// A task is only considered dead once its runner exited, as the task is
+	// dead in between restarts
+	r.taskLock.RLock()
+	started := make(map[string]struct{}, len(r.started))
+	for name := range r.started {
+		started[name] = struct{}{}
+	}
+	exited := make(map[string]struct{}, len(r.exited))
+	for name := range r.exited {
+		exited[name] = struct{}{}
+	}
+	r.taskLock.RUnlock()
+
+	prestartDone, mainsStarted, mainsDead, sidecarsDead := true, true, true, true
+	for _, task := range tg.Tasks {
+		state, ok := states[task.Name]
+		running := ok && state.State == structs.TaskStateRunning
+		_, dead := exited[task.Name]
+		_, taskStarted := started[task.Name]
+
+		switch {
+		case task.IsMainTask():
+			mainsStarted = mainsStarted && taskStarted && (running || dead)
+			mainsDead = mainsDead && taskStarted && dead
+		case task.Lifecycle.Sidecar:
+			if task.Lifecycle.Hook == structs.TaskLifecycleHookPrestart {
+				prestartDone = prestartDone && running
+			}
+			sidecarsDead = sidecarsDead && (!taskStarted || dead)
+		case task.Lifecycle.Hook == structs.TaskLifecycleHookPrestart:
+			prestartDone = prestartDone && dead && ok && state.Successful()
+		}
+	}
+
+	for _, task := range tg.Tasks {
+		hook := ""
+		if !task.IsMainTask() {
+			hook = task.Lifecycle.Hook
+		}
+
+		var due bool
+		switch hook {
+		case structs.TaskLifecycleHookPrestart:
+			due = true
+		case "":
+			due = prestartDone
+		case structs.TaskLifecycleHookPoststart:
+			due = prestartDone && mainsStarted
+		case structs.TaskLifecycleHookPoststop:
+			due = mainsDead && sidecarsDead
+		}
+
+		state, ok := states[task.Name]
+		_, taskStarted := started[task.Name]
+		_, dead := exited[task.Name]
+		switch {
+		case due && !taskStarted:
+			if mainsDead && hook != structs.TaskLifecycleHookPoststop {
+				// The main tasks exited before the task was due
+				if !ok || state.State != structs.TaskStateDead {
+					r.setTaskState(task.Name, structs.TaskStateDead, structs.NewTaskEvent(structs.TaskKilled))
+				}
+				continue
+			}
+			r.startTaskRunner(task.Name)
+		case mainsDead && taskStarted && !dead && task.Lifecycle != nil && task.Lifecycle.Sidecar:
+			// Stop the sidecars once the main tasks have exited
+			r.taskLock.RLock()
+			tr := r.tasks[task.Name]
+			r.taskLock.RUnlock()
+			tr.Destroy(structs.NewTaskEvent(structs.TaskKilled))
+		}
+	}
+}
+
+// vaultToken acts as a tuple of the token, renewal channel and the client of
+// the Vault cluster renewing it
 type vaultToken struct {
 	token     string
 	renewalCh <-chan error
+	client    vaultclient.VaultClient
 }
 
 // deriveVaultTokens derives the required vault tokens and returns a map of the
@@ -575,10 +1154,27 @@ func (r *AllocRunner) deriveVaultTokens() error {
 		r.vaultTokens = make(map[string]vaultToken, len(required))
 	}
 
-	// Get the tokens
-	tokens, err := r.vaultClient.DeriveToken(r.Alloc(), required)
-	if err != nil {
-		return fmt.Errorf("failed to derive Vault tokens: %v", err)
+	// Get the tokens from the Vault cluster of each task
+	clients := make(map[string]vaultclient.VaultClient, len(required))
+	byClient := make(map[vaultclient.VaultClient][]string)
+	for _, task := range required {
+		v, err := r.vaultClientFor(task)
+		if err != nil {
+			return err
+		}
+		clients[task] = v
+		byClient[v] = append(byClient[v], task)
+	}
+
+	tokens := make(map[string]string, len(required))
+	for v, tasks := range byClient {
+		derived, err := v.DeriveToken(r.Alloc(), tasks)
+		if err != nil {
+			return fmt.Errorf("failed to derive Vault tokens: %v", err)
+		}
+		for task, token := range derived {
+			tokens[task] = token
+		}
 	}
 
 	// Persist the tokens to the appropriate secret directories
@@ -601,7 +1197,7 @@ func (r *AllocRunner) deriveVaultTokens() error {
 		}
 
 		// Start renewing the token
-		renewCh, err := r.vaultClient.RenewToken(token, 10)
+		renewCh, err := clients[task].RenewToken(token, 10)
 		if err != nil {
 			var mErr multierror.Error
 			errMsg := fmt.Errorf("failed to renew Vault token for task %q in alloc %q: %v", task, r.alloc.ID, err)
@@ -609,19 +1205,124 @@ func (r *AllocRunner) deriveVaultTokens() error {
 
 			// Clean up any token that we have started renewing
 			for _, token := range r.vaultTokens {
-				if err := r.vaultClient.StopRenewToken(token.token); err != nil {
+				if err := token.client.StopRenewToken(token.token); err != nil {
 					multierror.Append(&mErr, err)
 				}
 			}
 
 			return mErr.ErrorOrNil()
 		}
-		r.vaultTokens[task] = vaultToken{token: token, renewalCh: renewCh}
+		r.vaultTokens[task] = vaultToken{token: token, renewalCh: renewCh, client: clients[task]}
 	}
 
 	return nil
 }
 
+// writeIdentityTokens writes the workload identity signed by the servers for
+// each task to the task's secret directory. This must be called after the
+// allocation directory is created.
+func (r *AllocRunner) writeIdentityTokens() error {
+	alloc := r.Alloc()
+	adir := r.ctx.AllocDir
+	for task, token := range alloc.SignedIdentities {
+		secretDir, err := adir.GetSecretDir(task)
+		if err != nil {
+			return fmt.Errorf("failed to determine task %s secret dir in alloc %q: %v", task, alloc.ID, err)
+		}
+
+		tokenPath := filepath.Join(secretDir, identityTokenFile)
+		if err := ioutil.WriteFile(tokenPath, []byte(token), 0666); err != nil {
+			return fmt.Errorf("failed to save workload identity to secret dir for task %q in alloc %q: %v", task, alloc.ID, err)
+		}
+	}
+	return nil
+}
+
+// writeTaskVariables reads the variables of the job paths each task's workload
+// identity grants access to and writes them, keyed by path, to the task's
+// secret directory. Tasks without any variables get no file.
+func (r *AllocRunner) writeTaskVariables() error {
+	if r.variables == nil {
+		return nil
+	}
+
+	alloc := r.Alloc()
+	adir := r.ctx.AllocDir
+	r.taskVariables = make(map[string]map[string]string)
+	for task, token := range alloc.SignedIdentities {
+		claims := structs.NewWorkloadIdentityClaims(alloc, task, time.Now())
+		items := make(map[string]structs.VariableItems)
+		for _, path := range structs.WorkloadVariablePaths(claims) {
+			variable, err := r.variables.ReadVariable(token, alloc.Namespace, path)
+			if err != nil {
+				return fmt.Errorf("failed to read variable %q for task %q: %v", path, task, err)
+			}
+			if variable != nil {
+				items[path] = variable.Items
+			}
+		}
+		if len(items) == 0 {
+			continue
+		}
+		r.taskVariables[task] = flattenVariables(items)
+
+		secretDir, err := adir.GetSecretDir(task)
+		if err != nil {
+			return fmt.Errorf("failed to determine task %s secret dir in alloc %q: %v", task, alloc.ID, err)
+		}
+		raw, err := json.Marshal(items)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(secretDir, variablesFile), raw, 0666); err != nil {
+			return fmt.Errorf("failed to save variables to secret dir for task %q in alloc %q: %v", task, alloc.ID, err)
+		}
+	}
+	return nil
+}
+
+// recoverTaskVariables reads back the variables written to the secret
+// directory of each task. Tasks whose variables can't be read are restored
+// without them.
+func (r *AllocRunner) recoverTaskVariables() {
+	r.taskVariables = make(map[string]map[string]string)
+	for task := range r.Alloc().SignedIdentities {
+		secretDir, err := r.ctx.AllocDir.GetSecretDir(task)
+		if err != nil {
+			continue
+		}
+		raw, err := ioutil.ReadFile(filepath.Join(secretDir, variablesFile))
+		if err != nil {
+			continue
+		}
+		var items map[string]structs.VariableItems
+		if err := json.Unmarshal(raw, &items); err != nil {
+			r.logger.Printf("[WARN] client: failed to read variables of task %q in alloc %q: %v", task, r.alloc.ID, err)
+			continue
+		}
+		r.taskVariables[task] = flattenVariables(items)
+	}
+}
+
+// flattenVariables merges the items of the variables keyed by path. As the
+// paths exposed to a task nest, the items of the longer, more specific paths
+// take precedence.
+func flattenVariables(variables map[string]structs.VariableItems) map[string]string {
+	paths := make([]string, 0, len(variables))
+	for path := range variables {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool { return len(paths[i]) < len(paths[j]) })
+
+	flattened := make(map[string]string)
+	for _, path := range paths {
+		for k, v := range variables[path] {
+			flattened[k] = v
+		}
+	}
+	return flattened
+}
+
 // tasksRequiringVaultTokens returns the set of tasks that require a Vault token
 func (r *AllocRunner) tasksRequiringVaultTokens() ([]string, error) {
 	// Get the tasks
@@ -641,6 +1342,29 @@ func (r *AllocRunner) tasksRequiringVaultTokens() ([]string, error) {
 	return required, nil
 }
 
+// vaultClientFor returns the client of the Vault cluster the task derives its
+// token from
+func (r *AllocRunner) vaultClientFor(taskName string) (vaultclient.VaultClient, error) {
+	tg := r.alloc.Job.LookupTaskGroup(r.alloc.TaskGroup)
+	if tg == nil {
+		return nil, fmt.Errorf("Failed to lookup task group in alloc")
+	}
+
+	cluster := structs.DefaultCluster
+	if task := tg.LookupTask(taskName); task != nil {
+		cluster = task.Vault.ClusterName()
+	}
+	if cluster == structs.DefaultCluster {
+		return r.vaultClient, nil
+	}
+
+	v, ok := r.vaultClusters[cluster]
+	if !ok {
+		return nil, fmt.Errorf("Vault cluster %q of task %q not configured", cluster, taskName)
+	}
+	return v, nil
+}
+
 // recoverVaultTokens reads the Vault tokens for the tasks that have Vault
 // tokens off disk. If there is an error, it is returned, otherwise token
 // renewal is started.
@@ -671,7 +1395,11 @@ func (r *AllocRunner) recoverVaultTokens() error {
 		}
 
 		token := string(data)
-		renewCh, err := r.vaultClient.RenewToken(token, 10)
+		v, err := r.vaultClientFor(task)
+		if err != nil {
+			return err
+		}
+		renewCh, err := v.RenewToken(token, 10)
 		if err != nil {
 			var mErr multierror.Error
 			errMsg := fmt.Errorf("failed to renew Vault token for task %q in alloc %q: %v", task, r.alloc.ID, err)
@@ -679,7 +1407,7 @@ func (r *AllocRunner) recoverVaultTokens() error {
 
 			// Clean up any token that we have started renewing
 			for _, token := range renewingTokens {
-				if err := r.vaultClient.StopRenewToken(token.token); err != nil {
+				if err := token.client.StopRenewToken(token.token); err != nil {
 					multierror.Append(&mErr, err)
 				}
 			}
@@ -687,13 +1415,215 @@ func (r *AllocRunner) recoverVaultTokens() error {
 			return mErr.ErrorOrNil()
 		}
 
-		renewingTokens[task] = vaultToken{token: token, renewalCh: renewCh}
+		renewingTokens[task] = vaultToken{token: token, renewalCh: renewCh, client: v}
 	}
 
 	r.vaultTokens = renewingTokens
 	return nil
 }
 
+// portConflicts returns the static ports of the allocation that are bound by
+// other processes on the host. Allocations with restored tasks are skipped as
+// the ports are bound by the tasks themselves.
+func (r *AllocRunner) portConflicts() []*structs.NetworkResource {
+	if len(r.restored) != 0 {
+		return nil
+	}
+	return staticPortConflicts(r.Alloc())
+}
+
+// volumeMounts resolves the volumes the tasks of the task group mount to the
+// host volumes of the node and to the CSI volumes mounted for the
+// allocation, and mounts the plugin dirs into the tasks of CSI plugins. A
+// mount is read-only if the host volume, the volume request or the mount is.
+func (r *AllocRunner) volumeMounts(tg *structs.TaskGroup) (map[string][]*allocdir.VolumeMount, error) {
+	csiPaths, err := r.mountCSIVolumes(tg)
+	if err != nil {
+		return nil, err
+	}
+
+	var mounts map[string][]*allocdir.VolumeMount
+	addMount := func(task string, m *allocdir.VolumeMount) {
+		if mounts == nil {
+			mounts = make(map[string][]*allocdir.VolumeMount)
+		}
+		mounts[task] = append(mounts[task], m)
+	}
+	fail := func(err error) (map[string][]*allocdir.VolumeMount, error) {
+		if uerr := r.unmountCSIVolumes(); uerr != nil {
+			r.logger.Printf("[ERR] client: failed to unmount CSI volumes of alloc %q: %v", r.alloc.ID, uerr)
+		}
+		return nil, err
+	}
+
+	for _, task := range tg.Tasks {
+		if config := task.CSIPluginConfig; config != nil {
+			if r.csi == nil {
+				return fail(fmt.Errorf("task %q is a CSI plugin but CSI plugins aren't supported", task.Name))
+			}
+			addMount(task.Name, &allocdir.VolumeMount{
+				HostPath: r.csi.CSIPluginDir(config),
+				TaskPath: config.MountDir,
+				Shared:   true,
+			})
+		}
+
+		for _, m := range task.VolumeMounts {
+			req, ok := tg.Volumes[m.Volume]
+			if !ok {
+				return fail(fmt.Errorf("task %q mounts unknown volume %q", task.Name, m.Volume))
+			}
+
+			mount := &allocdir.VolumeMount{
+				TaskPath: m.Destination,
+				ReadOnly: req.ReadOnly || m.ReadOnly,
+			}
+			if req.Type == structs.VolumeTypeCSI {
+				mount.HostPath = csiPaths[m.Volume]
+			} else {
+				hostVolume, ok := r.config.Node.HostVolumes[req.Source]
+				if !ok {
+					return fail(fmt.Errorf("host volume %q of volume %q not found", req.Source, m.Volume))
+				}
+				mount.HostPath = hostVolume.Path
+				mount.ReadOnly = mount.ReadOnly || hostVolume.ReadOnly
+			}
+			addMount(task.Name, mount)
+		}
+	}
+	return mounts, nil
+}
+
+// mountCSIVolumes claims and mounts the CSI volumes requested by the task
+// group, returning the paths of the host they are mounted at keyed by volume
+// name. The volumes already mounted are unmounted if one fails to mount.
+func (r *AllocRunner) mountCSIVolumes(tg *structs.TaskGroup) (map[string]string, error) {
+	var names []string
+	for name, req := range tg.Volumes {
+		if req.Type == structs.VolumeTypeCSI {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if r.csi == nil {
+		return nil, fmt.Errorf("CSI volumes aren't supported")
+	}
+	sort.Strings(names)
+
+	alloc := r.Alloc()
+	paths := make(map[string]string, len(names))
+	for _, name := range names {
+		vol, path, err := r.csi.MountCSIVolume(alloc, tg.Volumes[name])
+		if err != nil {
+			if uerr := r.unmountCSIVolumes(); uerr != nil {
+				r.logger.Printf("[ERR] client: failed to unmount CSI volumes of alloc %q: %v", alloc.ID, uerr)
+			}
+			return nil, err
+		}
+		paths[name] = path
+
+		// The secrets of the volume aren't needed to unmount it
+		r.csiLock.Lock()
+		if r.csiVolumes == nil {
+			r.csiVolumes = make(map[string]*structs.CSIVolume)
+		}
+		r.csiVolumes[name] = vol.Sanitize()
+		r.csiLock.Unlock()
+	}
+	return paths, nil
+}
+
+// unmountCSIVolumes unmounts the CSI volumes mounted for the allocation and
+// releases their claims. Volumes failing to unmount are kept, so that
+// unmounting them is retried when the allocation is destroyed.
+func (r *AllocRunner) unmountCSIVolumes() error {
+	r.csiLock.Lock()
+	defer r.csiLock.Unlock()
+	if r.csi == nil || len(r.csiVolumes) == 0 {
+		return nil
+	}
+
+	alloc := r.Alloc()
+	var mErr multierror.Error
+	for name, vol := range r.csiVolumes {
+		if err := r.csi.UnmountCSIVolume(alloc, vol); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+			continue
+		}
+		delete(r.csiVolumes, name)
+	}
+	return mErr.ErrorOrNil()
+}
+
+// csiPluginConfigs returns the configs of the tasks running CSI plugins
+func (r *AllocRunner) csiPluginConfigs() []*structs.TaskCSIPluginConfig {
+	alloc := r.Alloc()
+	tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+	if tg == nil {
+		return nil
+	}
+	var configs []*structs.TaskCSIPluginConfig
+	for _, task := range tg.Tasks {
+		if task.CSIPluginConfig != nil {
+			configs = append(configs, task.CSIPluginConfig)
+		}
+	}
+	return configs
+}
+
+// registerCSIPlugins registers the CSI plugins run by the tasks
+func (r *AllocRunner) registerCSIPlugins() error {
+	configs := r.csiPluginConfigs()
+	if len(configs) == 0 {
+		return nil
+	}
+	if r.csi == nil {
+		return fmt.Errorf("CSI plugins aren't supported")
+	}
+	for _, config := range configs {
+		if err := r.csi.RegisterCSIPlugin(r.alloc.ID, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deregisterCSIPlugins deregisters the CSI plugins run by the tasks
+func (r *AllocRunner) deregisterCSIPlugins() {
+	if r.csi == nil {
+		return
+	}
+	for _, config := range r.csiPluginConfigs() {
+		r.csi.DeregisterCSIPlugin(r.alloc.ID, config)
+	}
+}
+
+// releaseCSI deregisters the CSI plugins run by the tasks and unmounts the
+// CSI volumes of the allocation once its tasks are dead. The volumes are
+// unmounted from the task dirs before being unmounted by their node plugins.
+func (r *AllocRunner) releaseCSI() {
+	r.deregisterCSIPlugins()
+
+	r.csiLock.Lock()
+	mounted := len(r.csiVolumes) != 0
+	r.csiLock.Unlock()
+	if !mounted {
+		return
+	}
+
+	if err := r.ctx.AllocDir.UnmountVolumes(); err != nil {
+		r.logger.Printf("[WARN] client: failed to unmount volumes of alloc %q: %v", r.alloc.ID, err)
+	}
+	if err := r.unmountCSIVolumes(); err != nil {
+		r.logger.Printf("[ERR] client: failed to unmount CSI volumes of alloc %q: %v", r.alloc.ID, err)
+	}
+	if err := r.saveAllocRunnerState(); err != nil {
+		r.logger.Printf("[WARN] client: failed to save state for alloc %q: %v", r.alloc.ID, err)
+	}
+}
+
 // checkResources monitors and enforces alloc resource usage. It returns an
 // appropriate task event describing why the allocation had to be killed.
 func (r *AllocRunner) checkResources() (*structs.TaskEvent, string) {
@@ -731,6 +1661,91 @@ func (r *AllocRunner) Update(update *structs.Allocation) {
 	}
 }
 
+// Restart restarts the running tasks of the allocation in place. If the
+// optional taskFilter is set only the given task is restarted.
+func (r *AllocRunner) Restart(taskFilter, reason string) error {
+	return r.forEachTask(taskFilter, func(tr *TaskRunner) error {
+		return tr.Restart(reason)
+	})
+}
+
+// Signal sends the signal to the running tasks of the allocation. If the
+// optional taskFilter is set only the given task is signaled.
+func (r *AllocRunner) Signal(taskFilter, sig, reason string) error {
+	return r.forEachTask(taskFilter, func(tr *TaskRunner) error {
+		return tr.Signal(sig, reason)
+	})
+}
+
+// Evict fails the allocation, so that it is rescheduled, and kills its tasks
+// with the given event. The description explains the failure of the
+// allocation.
+func (r *AllocRunner) Evict(event *structs.TaskEvent, desc string) {
+	select {
+	case r.evictCh <- &allocEviction{event: event, desc: desc}:
+	default:
+		r.logger.Printf("[DEBUG] client: alloc %q is already being evicted", r.alloc.ID)
+	}
+}
+
+// Pause pauses the running tasks of the allocation. If the optional
+// taskFilter is set only the given task is paused.
+func (r *AllocRunner) Pause(taskFilter, reason string) error {
+	return r.forEachTask(taskFilter, func(tr *TaskRunner) error {
+		return tr.Pause(reason)
+	})
+}
+
+// Resume resumes the paused tasks of the allocation. If the optional
+// taskFilter is set only the given task is resumed.
+func (r *AllocRunner) Resume(taskFilter, reason string) error {
+	return r.forEachTask(taskFilter, func(tr *TaskRunner) error {
+		return tr.Resume(reason)
+	})
+}
+
+// Exec runs a command inside the given task of the allocation. The task may
+// be omitted if the allocation has a single task.
+func (r *AllocRunner) Exec(ctx context.Context, task string, opts *driver.ExecOptions) (int, error) {
+	r.taskLock.RLock()
+	if task == "" && len(r.tasks) == 1 {
+		for name := range r.tasks {
+			task = name
+		}
+	}
+	tr, ok := r.tasks[task]
+	r.taskLock.RUnlock()
+	if task == "" {
+		return 0, fmt.Errorf("allocation %q has multiple tasks, a task must be specified", r.alloc.ID)
+	}
+	if !ok {
+		return 0, fmt.Errorf("allocation %q has no task %q", r.alloc.ID, task)
+	}
+	return tr.Exec(ctx, opts)
+}
+
+// forEachTask calls the function for all the task runners of the allocation,
+// or only the given task if the taskFilter is set, and returns the errors
+func (r *AllocRunner) forEachTask(taskFilter string, f func(tr *TaskRunner) error) error {
+	if taskFilter != "" {
+		r.taskLock.RLock()
+		tr, ok := r.tasks[taskFilter]
+		r.taskLock.RUnlock()
+		if !ok {
+			return fmt.Errorf("allocation %q has no task %q", r.alloc.ID, taskFilter)
+		}
+		return f(tr)
+	}
+
+	var mErr multierror.Error
+	for _, tr := range r.getTaskRunners() {
+		if err := f(tr); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
 // StatsReporter returns an interface to query resource usage statistics of an
 // allocation
 func (r *AllocRunner) StatsReporter() AllocStatsReporter {
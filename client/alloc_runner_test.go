@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -39,7 +41,7 @@ func testAllocRunnerFromAlloc(alloc *structs.Allocation, restarts bool) (*MockAl
 		alloc.Job.Type = structs.JobTypeBatch
 	}
 	vclient := vaultclient.NewMockVaultClient()
-	ar := NewAllocRunner(logger, conf, upd.Update, alloc, vclient)
+	ar := NewAllocRunner(logger, conf, upd.Update, alloc, vclient, nil)
 	return upd, ar
 }
 
@@ -315,6 +317,60 @@ func TestAllocRunner_DiskExceeded_Destroy(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	})
 }
+func TestAllocRunner_Evict(t *testing.T) {
+	ctestutil.ExecCompatible(t)
+	upd, ar := testAllocRunner(false)
+
+	// Ensure task takes some time
+	task := ar.alloc.Job.TaskGroups[0].Tasks[0]
+	task.Config["command"] = "/bin/sleep"
+	task.Config["args"] = []string{"60"}
+	go ar.Run()
+	defer ar.Destroy()
+
+	testutil.WaitForResult(func() (bool, error) {
+		if upd.Count == 0 {
+			return false, fmt.Errorf("No updates")
+		}
+		last := upd.Allocs[upd.Count-1]
+		if last.ClientStatus != structs.AllocClientStatusRunning {
+			return false, fmt.Errorf("got status %v; want %v", last.ClientStatus, structs.AllocClientStatusRunning)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	ar.Evict(structs.NewTaskEvent(structs.TaskMemoryPressure).SetMemoryPressure("testing"), "evicted")
+
+	testutil.WaitForResult(func() (bool, error) {
+		last := upd.Allocs[upd.Count-1]
+		if last.ClientStatus != structs.AllocClientStatusFailed {
+			return false, fmt.Errorf("got client status %v; want %v", last.ClientStatus, structs.AllocClientStatusFailed)
+		}
+		if last.ClientDescription != "evicted" {
+			return false, fmt.Errorf("got client description %q", last.ClientDescription)
+		}
+
+		state := last.TaskStates[task.Name]
+		if state.State != structs.TaskStateDead {
+			return false, fmt.Errorf("got state %v; want %v", state.State, structs.TaskStateDead)
+		}
+		found := false
+		for _, e := range state.Events {
+			if e.Type == structs.TaskMemoryPressure && e.MemoryPressure == "testing" {
+				found = true
+			}
+		}
+		if !found {
+			return false, fmt.Errorf("no memory pressure event: %#v", state.Events)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
+
 func TestAllocRunner_Destroy(t *testing.T) {
 	ctestutil.ExecCompatible(t)
 	upd, ar := testAllocRunner(false)
@@ -419,7 +475,7 @@ func TestAllocRunner_SaveRestoreState(t *testing.T) {
 
 	// Create a new alloc runner
 	ar2 := NewAllocRunner(ar.logger, ar.config, upd.Update,
-		&structs.Allocation{ID: ar.alloc.ID}, ar.vaultClient)
+		&structs.Allocation{ID: ar.alloc.ID}, ar.vaultClient, ar.serviceRegs)
 	err = ar2.RestoreState()
 	if err != nil {
 		t.Fatalf("err: %v", err)
@@ -507,7 +563,7 @@ func TestAllocRunner_SaveRestoreState_TerminalAlloc(t *testing.T) {
 
 	// Create a new alloc runner
 	ar2 := NewAllocRunner(ar.logger, ar.config, upd.Update,
-		&structs.Allocation{ID: ar.alloc.ID}, ar.vaultClient)
+		&structs.Allocation{ID: ar.alloc.ID}, ar.vaultClient, ar.serviceRegs)
 	ar2.logger = prefixedTestLogger("ar2: ")
 	err = ar2.RestoreState()
 	if err != nil {
@@ -567,6 +623,96 @@ func TestAllocRunner_SaveRestoreState_TerminalAlloc(t *testing.T) {
 	})
 }
 
+func TestAllocRunner_ShutdownStages(t *testing.T) {
+	_, ar := testAllocRunner(false)
+
+	tg := ar.alloc.Job.TaskGroups[0]
+	proxy := tg.Tasks[0].Copy()
+	proxy.Name = "proxy"
+	proxy.ShutdownOrder = 1
+	logs := tg.Tasks[0].Copy()
+	logs.Name = "logs"
+	logs.ShutdownOrder = -1
+	tg.Tasks = append(tg.Tasks, proxy, logs)
+
+	for _, task := range tg.Tasks {
+		ar.tasks[task.Name] = NewTaskRunner(ar.logger, ar.config, ar.setTaskState, ar.ctx, ar.Alloc(), task.Copy())
+		ar.started[task.Name] = struct{}{}
+	}
+
+	stages := ar.shutdownStages()
+	if len(stages) != 3 {
+		t.Fatalf("got %d stages; want 3", len(stages))
+	}
+	for i, name := range []string{"logs", "web", "proxy"} {
+		if len(stages[i]) != 1 || stages[i][0].task.Name != name {
+			t.Fatalf("stage %d: got %v; want %q", i, stages[i], name)
+		}
+	}
+}
+
+func TestAllocRunner_Lifecycle(t *testing.T) {
+	alloc := mock.Alloc()
+	alloc.Job.Type = structs.JobTypeBatch
+	tg := alloc.Job.TaskGroups[0]
+	main := tg.Tasks[0]
+	main.Driver = "mock_driver"
+	main.Config = map[string]interface{}{"run_for": "100ms"}
+
+	init := main.Copy()
+	init.Name = "init"
+	init.Lifecycle = &structs.TaskLifecycleConfig{Hook: structs.TaskLifecycleHookPrestart}
+	proxy := main.Copy()
+	proxy.Name = "proxy"
+	proxy.Config = map[string]interface{}{"run_for": "10s"}
+	proxy.Lifecycle = &structs.TaskLifecycleConfig{Hook: structs.TaskLifecycleHookPrestart, Sidecar: true}
+	cleanup := main.Copy()
+	cleanup.Name = "cleanup"
+	cleanup.Lifecycle = &structs.TaskLifecycleConfig{Hook: structs.TaskLifecycleHookPoststop}
+	tg.Tasks = append(tg.Tasks, init, proxy, cleanup)
+
+	upd, ar := testAllocRunnerFromAlloc(alloc, false)
+	go ar.Run()
+	defer ar.Destroy()
+
+	testutil.WaitForResult(func() (bool, error) {
+		if upd.Count == 0 {
+			return false, fmt.Errorf("No updates")
+		}
+		last := upd.Allocs[upd.Count-1]
+		if last.ClientStatus != structs.AllocClientStatusComplete {
+			return false, fmt.Errorf("got status %v; want %v", last.ClientStatus, structs.AllocClientStatusComplete)
+		}
+		for _, name := range []string{"init", "proxy", "cleanup"} {
+			if state := last.TaskStates[name]; state == nil || state.State != structs.TaskStateDead {
+				return false, fmt.Errorf("task %q not dead: %v", name, state)
+			}
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// The main task is started once the prestart task completed and the
+	// poststop task once the sidecar was stopped
+	states := ar.Alloc().TaskStates
+	eventTime := func(name, eventType string) int64 {
+		for _, e := range states[name].Events {
+			if e.Type == eventType {
+				return e.Time
+			}
+		}
+		t.Fatalf("task %q has no %q event", name, eventType)
+		return 0
+	}
+	if eventTime("init", structs.TaskTerminated) > eventTime(main.Name, structs.TaskStarted) {
+		t.Fatalf("main task started before the prestart task completed")
+	}
+	if eventTime("proxy", structs.TaskKilled) > eventTime("cleanup", structs.TaskStarted) {
+		t.Fatalf("poststop task started before the sidecar was stopped")
+	}
+}
+
 func TestAllocRunner_TaskFailed_KillTG(t *testing.T) {
 	ctestutil.ExecCompatible(t)
 	upd, ar := testAllocRunner(false)
@@ -620,6 +766,62 @@ func TestAllocRunner_TaskFailed_KillTG(t *testing.T) {
 	})
 }
 
+type mockPortReserver struct {
+	networks []*structs.NetworkResource
+}
+
+func (m *mockPortReserver) ReserveHostPorts(networks []*structs.NetworkResource) {
+	m.networks = append(m.networks, networks...)
+}
+
+func TestAllocRunner_PortConflict(t *testing.T) {
+	// Bind a port as a host process would
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	alloc := mock.Alloc()
+	alloc.TaskResources["web"].Networks[0].IP = "127.0.0.1"
+	alloc.TaskResources["web"].Networks[0].ReservedPorts = []structs.Port{{Label: "main", Value: port}}
+	upd, ar := testAllocRunnerFromAlloc(alloc, false)
+	reserver := &mockPortReserver{}
+	ar.SetPortReserver(reserver)
+	go ar.Run()
+	defer ar.Destroy()
+
+	testutil.WaitForResult(func() (bool, error) {
+		if upd.Count == 0 {
+			return false, fmt.Errorf("No updates")
+		}
+		last := upd.Allocs[upd.Count-1]
+		if last.ClientStatus != structs.AllocClientStatusFailed {
+			return false, fmt.Errorf("got status %v; want %v", last.ClientStatus, structs.AllocClientStatusFailed)
+		}
+
+		state := last.TaskStates["web"]
+		if state == nil || !state.Failed() {
+			return false, fmt.Errorf("task should have failed: %#v", state)
+		}
+		event := state.Events[len(state.Events)-1]
+		if event.Type != structs.TaskPortConflict {
+			return false, fmt.Errorf("got last event %v; want %v", event.Type, structs.TaskPortConflict)
+		}
+		if len(event.PortConflicts) != 1 || event.PortConflicts[0] != l.Addr().String() {
+			return false, fmt.Errorf("bad port conflicts: %v", event.PortConflicts)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	if len(reserver.networks) != 1 || reserver.networks[0].ReservedPorts[0].Value != port {
+		t.Fatalf("bad reserved networks: %#v", reserver.networks)
+	}
+}
+
 func TestAllocRunner_SimpleRun_VaultToken(t *testing.T) {
 	alloc := mock.Alloc()
 	task := alloc.Job.TaskGroups[0].Tasks[0]
@@ -724,7 +926,7 @@ func TestAllocRunner_SaveRestoreState_VaultTokens_Valid(t *testing.T) {
 
 	// Create a new alloc runner
 	ar2 := NewAllocRunner(ar.logger, ar.config, upd.Update,
-		&structs.Allocation{ID: ar.alloc.ID}, ar.vaultClient)
+		&structs.Allocation{ID: ar.alloc.ID}, ar.vaultClient, ar.serviceRegs)
 	err = ar2.RestoreState()
 	if err != nil {
 		t.Fatalf("err: %v", err)
@@ -818,7 +1020,7 @@ func TestAllocRunner_SaveRestoreState_VaultTokens_Invalid(t *testing.T) {
 
 	// Create a new alloc runner
 	ar2 := NewAllocRunner(ar.logger, ar.config, upd.Update,
-		&structs.Allocation{ID: ar.alloc.ID}, ar.vaultClient)
+		&structs.Allocation{ID: ar.alloc.ID}, ar.vaultClient, ar.serviceRegs)
 
 	// Invalidate the token
 	mockVC := ar2.vaultClient.(*vaultclient.MockVaultClient)
@@ -861,3 +1063,17 @@ func TestAllocRunner_SaveRestoreState_VaultTokens_Invalid(t *testing.T) {
 		t.Fatalf("took too long to terminate")
 	}
 }
+
+func TestFlattenVariables(t *testing.T) {
+	variables := map[string]structs.VariableItems{
+		"nomad/jobs/web/frontend": {"user": "group", "port": "8080"},
+		"nomad/jobs":              {"user": "all", "region": "eu"},
+		"nomad/jobs/web":          {"user": "job"},
+	}
+
+	// The items of the most specific paths take precedence
+	exp := map[string]string{"user": "group", "port": "8080", "region": "eu"}
+	if act := flattenVariables(variables); !reflect.DeepEqual(act, exp) {
+		t.Fatalf("bad: %v", act)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -31,6 +32,10 @@ const (
 	// otherwise be possible for a number of minutes if we started with the
 	// minCheckDiskInterval.
 	checkDiskMaxEnforcePeriod = 5 * time.Minute
+
+	// secretDirTmpfsSize is the default size of the tmpfs of the secrets dir
+	// per task in MBs
+	secretDirTmpfsSize = 1
 )
 
 var (
@@ -93,6 +98,36 @@ type AllocDir struct {
 	// MaxSize represents the total amount of megabytes that the shared allocation
 	// directory is allowed to consume.
 	MaxSize int
+
+	// SecretsSizeMB is the size in megabytes of the tmpfs backing the secrets
+	// directory of each task. If zero, the default size is used.
+	SecretsSizeMB int
+
+	// LocalSizeMB is the size in megabytes of the tmpfs backing the local
+	// directory of each task. If zero, the local directory is kept on disk.
+	LocalSizeMB int
+
+	// VolumeMounts are the host volumes mounted into the file system of each
+	// task, keyed by the name of the task
+	VolumeMounts map[string][]*VolumeMount
+}
+
+// VolumeMount is a directory of the host mounted into the file system of a
+// task
+type VolumeMount struct {
+	// HostPath is the directory of the host
+	HostPath string
+
+	// TaskPath is the absolute path of the mount within the task
+	TaskPath string
+
+	// ReadOnly mounts the directory read-only
+	ReadOnly bool
+
+	// Shared mounts the directory with bidirectional mount propagation, so
+	// that the mounts the task makes below it are visible on the host. The
+	// plugin dirs of CSI plugins are mounted shared.
+	Shared bool
 }
 
 // AllocFileInfo holds information about a file inside the AllocDir
@@ -129,6 +164,27 @@ func NewAllocDir(allocDir string, maxSize int) *AllocDir {
 	return d
 }
 
+// secretsSize returns the size in megabytes of the tmpfs of the secrets dirs
+func (d *AllocDir) secretsSize() int {
+	if d.SecretsSizeMB != 0 {
+		return d.SecretsSizeMB
+	}
+	return secretDirTmpfsSize
+}
+
+// TaskTmpfsSize returns the memory in megabytes consumed by the tmpfs mounts
+// of each task given the configured sizes of the secrets and local dirs. It
+// is zero if the dirs can't be mounted on tmpfs.
+func TaskTmpfsSize(secretsSizeMB, localSizeMB int) int {
+	if !TmpfsSupported() {
+		return 0
+	}
+	if secretsSizeMB == 0 {
+		secretsSizeMB = secretDirTmpfsSize
+	}
+	return secretsSizeMB + localSizeMB
+}
+
 // Snapshot creates an archive of the files and directories in the data dir of
 // the allocation and the task local directories
 func (d *AllocDir) Snapshot(w io.Writer) error {
@@ -190,8 +246,71 @@ func (d *AllocDir) Snapshot(w io.Writer) error {
 	return nil
 }
 
+// Restore extracts an archive made by Snapshot into the data dir of the
+// allocation and the local directories of its tasks. Entries outside these
+// directories, such as the local directories of tasks the allocation doesn't
+// run, are skipped.
+func (d *AllocDir) Restore(r io.Reader) error {
+	roots := []string{filepath.Join(d.SharedDir, "data")}
+	for _, path := range d.TaskDirs {
+		roots = append(roots, filepath.Join(path, TaskLocal))
+	}
+	inRoots := func(path string) bool {
+		for _, root := range roots {
+			if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading snapshot: %v", err)
+		}
+
+		path := filepath.Join(d.AllocDir, filepath.FromSlash(hdr.Name))
+		if !inRoots(path) {
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			// The roots are created by Build and may be mount points, so
+			// only their contents are restored
+			if err := os.MkdirAll(path, os.FileMode(hdr.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, tr)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
 // Tears down previously build directory structure.
 func (d *AllocDir) Destroy() error {
+	// Removing the alloc dir while host volumes are still mounted into it
+	// would delete the data of the volumes, so it is kept if they can't be
+	// unmounted.
+	if err := d.UnmountVolumes(); err != nil {
+		return fmt.Errorf("failed to unmount volumes, keeping alloc dir %q: %v", d.AllocDir, err)
+	}
 
 	// Unmount all mounted shared alloc dirs.
 	var mErr multierror.Error
@@ -199,6 +318,20 @@ func (d *AllocDir) Destroy() error {
 		mErr.Errors = append(mErr.Errors, err)
 	}
 
+	// The local dirs on tmpfs are only unmounted on destroy since they must
+	// survive the restarts of the tasks.
+	if d.LocalSizeMB != 0 {
+		for _, dir := range d.TaskDirs {
+			taskLocal := filepath.Join(dir, TaskLocal)
+			if d.pathExists(taskLocal) {
+				if err := d.removeTmpfsDir(taskLocal); err != nil {
+					mErr.Errors = append(mErr.Errors,
+						fmt.Errorf("failed to remove the local dir %q: %v", taskLocal, err))
+				}
+			}
+		}
+	}
+
 	if err := os.RemoveAll(d.AllocDir); err != nil {
 		mErr.Errors = append(mErr.Errors, err)
 	}
@@ -208,6 +341,10 @@ func (d *AllocDir) Destroy() error {
 
 func (d *AllocDir) UnmountAll() error {
 	var mErr multierror.Error
+	if err := d.UnmountVolumes(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+
 	for _, dir := range d.TaskDirs {
 		// Check if the directory has the shared alloc mounted.
 		taskAlloc := filepath.Join(dir, SharedAllocName)
@@ -223,7 +360,7 @@ func (d *AllocDir) UnmountAll() error {
 
 		taskSecret := filepath.Join(dir, TaskSecrets)
 		if d.pathExists(taskSecret) {
-			if err := d.removeSecretDir(taskSecret); err != nil {
+			if err := d.removeTmpfsDir(taskSecret); err != nil {
 				mErr.Errors = append(mErr.Errors,
 					fmt.Errorf("failed to remove the secret dir %q: %v", taskSecret, err))
 			}
@@ -275,9 +412,14 @@ func (d *AllocDir) Build(tasks []*structs.Task) error {
 			return err
 		}
 
-		// Create a local directory that each task can use.
+		// Create a local directory that each task can use, on a tmpfs if
+		// configured.
 		local := filepath.Join(taskDir, TaskLocal)
-		if err := os.MkdirAll(local, 0777); err != nil {
+		if d.LocalSizeMB != 0 {
+			if err := d.createTmpfsDir(local, d.LocalSizeMB); err != nil {
+				return err
+			}
+		} else if err := os.MkdirAll(local, 0777); err != nil {
 			return err
 		}
 
@@ -301,7 +443,7 @@ func (d *AllocDir) Build(tasks []*structs.Task) error {
 
 		// Create the secret directory
 		secret := filepath.Join(taskDir, TaskSecrets)
-		if err := d.createSecretDir(secret); err != nil {
+		if err := d.createTmpfsDir(secret, d.secretsSize()); err != nil {
 			return err
 		}
 
@@ -424,6 +566,47 @@ func (d *AllocDir) MountSharedDir(task string) error {
 	return nil
 }
 
+// MountVolumes mounts the host volumes of the task into its directory, for
+// tasks chrooted into their directory. Mount is documented at an OS level in
+// their respective implementation files.
+func (d *AllocDir) MountVolumes(task string) error {
+	taskDir, ok := d.TaskDirs[task]
+	if !ok {
+		return fmt.Errorf("No task directory exists for %v", task)
+	}
+
+	for _, m := range d.VolumeMounts[task] {
+		dest := filepath.Join(taskDir, m.TaskPath)
+		if err := d.mountVolume(m.HostPath, dest, m.ReadOnly, m.Shared); err != nil {
+			return fmt.Errorf("Failed to mount volume %q at %q for task %v: %v", m.HostPath, m.TaskPath, task, err)
+		}
+	}
+	return nil
+}
+
+// UnmountVolumes unmounts the host volumes mounted into the directories of
+// the tasks
+func (d *AllocDir) UnmountVolumes() error {
+	var mErr multierror.Error
+	for task, mounts := range d.VolumeMounts {
+		taskDir, ok := d.TaskDirs[task]
+		if !ok {
+			continue
+		}
+		for _, m := range mounts {
+			dest := filepath.Join(taskDir, m.TaskPath)
+			if !d.pathExists(dest) {
+				continue
+			}
+			if err := d.unmountVolume(dest); err != nil {
+				mErr.Errors = append(mErr.Errors,
+					fmt.Errorf("failed to unmount volume %q: %v", dest, err))
+			}
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
 // LogDir returns the log dir in the current allocation directory
 func (d *AllocDir) LogDir() string {
 	return filepath.Join(d.AllocDir, SharedAllocName, LogDirName)
@@ -1,6 +1,7 @@
 package allocdir
 
 import (
+	"errors"
 	"os"
 	"syscall"
 )
@@ -15,13 +16,31 @@ func (d *AllocDir) unmountSharedDir(dir string) error {
 	return syscall.Unlink(dir)
 }
 
-// createSecretDir creates the secrets dir folder at the given path
-func (d *AllocDir) createSecretDir(dir string) error {
+// mountVolume mounts the directory of the host at the destination. Volumes
+// can't be mounted into the task dirs on darwin.
+func (d *AllocDir) mountVolume(src, dest string, readOnly, shared bool) error {
+	return errors.New("volumes can't be mounted on darwin")
+}
+
+// unmountVolume unmounts the volume mounted at the destination. It's a no-op
+// on darwin.
+func (d *AllocDir) unmountVolume(dest string) error {
+	return nil
+}
+
+// TmpfsSupported returns whether the task dirs can be mounted on a tmpfs.
+// It's always false on darwin.
+func TmpfsSupported() bool {
+	return false
+}
+
+// createTmpfsDir creates the folder at the given path on disk
+func (d *AllocDir) createTmpfsDir(dir string, sizeMB int) error {
 	return os.MkdirAll(dir, 0777)
 }
 
-// removeSecretDir removes the secrets dir folder
-func (d *AllocDir) removeSecretDir(dir string) error {
+// removeTmpfsDir removes the folder
+func (d *AllocDir) removeTmpfsDir(dir string) error {
 	return os.RemoveAll(dir)
 }
 
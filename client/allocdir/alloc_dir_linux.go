@@ -11,11 +11,6 @@ import (
 	"github.com/hashicorp/go-multierror"
 )
 
-const (
-	// secretDirTmpfsSize is the size of the tmpfs per task in MBs
-	secretDirTmpfsSize = 1
-)
-
 // Bind mounts the shared directory into the task directory. Must be root to
 // run.
 func (d *AllocDir) mountSharedDir(taskDir string) error {
@@ -30,18 +25,60 @@ func (d *AllocDir) unmountSharedDir(dir string) error {
 	return syscall.Unmount(dir, 0)
 }
 
-// createSecretDir creates the secrets dir folder at the given path using a
-// tmpfs
-func (d *AllocDir) createSecretDir(dir string) error {
+// mountVolume bind mounts the directory of the host at the destination,
+// remounting it read-only and making it shared if requested. Must be root to
+// run.
+func (d *AllocDir) mountVolume(src, dest string, readOnly, shared bool) error {
+	if err := os.MkdirAll(dest, 0777); err != nil {
+		return err
+	}
+
+	if err := syscall.Mount(src, dest, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return os.NewSyscallError("mount", err)
+	}
+	if readOnly {
+		flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY)
+		if err := syscall.Mount("", dest, "", flags, ""); err != nil {
+			syscall.Unmount(dest, syscall.MNT_DETACH)
+			return os.NewSyscallError("mount", err)
+		}
+	}
+	if shared {
+		if err := syscall.Mount("", dest, "", syscall.MS_SHARED|syscall.MS_REC, ""); err != nil {
+			syscall.Unmount(dest, syscall.MNT_DETACH)
+			return os.NewSyscallError("mount", err)
+		}
+	}
+	return nil
+}
+
+// unmountVolume unmounts the volume mounted at the destination. Mount points
+// that are no longer mounted are ignored.
+func (d *AllocDir) unmountVolume(dest string) error {
+	if err := syscall.Unmount(dest, syscall.MNT_DETACH); err != nil && err != syscall.EINVAL {
+		return os.NewSyscallError("unmount", err)
+	}
+	return nil
+}
+
+// TmpfsSupported returns whether the task dirs can be mounted on a tmpfs,
+// which requires running as root
+func TmpfsSupported() bool {
+	return unix.Geteuid() == 0
+}
+
+// createTmpfsDir creates a folder at the given path using a tmpfs of the
+// given size in MBs
+func (d *AllocDir) createTmpfsDir(dir string, sizeMB int) error {
 	// Only mount the tmpfs if we are root
-	if unix.Geteuid() == 0 {
+	if TmpfsSupported() {
 		if err := os.MkdirAll(dir, 0777); err != nil {
 			return err
 		}
 
 		var flags uintptr
 		flags = syscall.MS_NOEXEC
-		options := fmt.Sprintf("size=%dm", secretDirTmpfsSize)
+		options := fmt.Sprintf("size=%dm", sizeMB)
 		err := syscall.Mount("tmpfs", dir, "tmpfs", flags, options)
 		return os.NewSyscallError("mount", err)
 	}
@@ -49,9 +86,9 @@ func (d *AllocDir) createSecretDir(dir string) error {
 	return os.MkdirAll(dir, 0777)
 }
 
-// createSecretDir removes the secrets dir folder
-func (d *AllocDir) removeSecretDir(dir string) error {
-	if unix.Geteuid() == 0 {
+// removeTmpfsDir unmounts and removes the tmpfs folder
+func (d *AllocDir) removeTmpfsDir(dir string) error {
+	if TmpfsSupported() {
 		if err := syscall.Unmount(dir, 0); err != nil {
 			return os.NewSyscallError("unmount", err)
 		}
@@ -83,6 +83,50 @@ func TestAllocDir_BuildAlloc(t *testing.T) {
 	}
 }
 
+func TestAllocDir_BuildAlloc_LocalTmpfs(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "AllocDir")
+	if err != nil {
+		t.Fatalf("Couldn't create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	d := NewAllocDir(tmp, structs.DefaultResources().DiskMB)
+	d.SecretsSizeMB = 2
+	d.LocalSizeMB = 8
+	tasks := []*structs.Task{t1, t2}
+	if err := d.Build(tasks); err != nil {
+		t.Fatalf("Build(%v) failed: %v", tasks, err)
+	}
+
+	// The local dir survives the unmount done when the tasks exit
+	if err := d.UnmountAll(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, task := range tasks {
+		local := filepath.Join(d.TaskDirs[task.Name], TaskLocal)
+		if _, err := os.Stat(local); os.IsNotExist(err) {
+			t.Fatalf("Build(%v) didn't create local dir %v", tasks, local)
+		}
+	}
+
+	if err := d.Destroy(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := os.Stat(d.AllocDir); !os.IsNotExist(err) {
+		t.Fatalf("AllocDir %v not removed: %v", d.AllocDir, err)
+	}
+}
+
+func TestTaskTmpfsSize(t *testing.T) {
+	expected := 0
+	if TmpfsSupported() {
+		expected = secretDirTmpfsSize + 64
+	}
+	if size := TaskTmpfsSize(0, 64); size != expected {
+		t.Fatalf("bad: %d", size)
+	}
+}
+
 func TestAllocDir_LogDir(t *testing.T) {
 	tmp, err := ioutil.TempDir("", "AllocDir")
 	if err != nil {
@@ -230,6 +274,71 @@ func TestAllocDir_MountSharedAlloc(t *testing.T) {
 	}
 }
 
+// Test that host volumes are mounted into the task directories and that
+// destroying the alloc dir keeps their data.
+func TestAllocDir_MountVolumes(t *testing.T) {
+	testutil.MountCompatible(t)
+	if runtime.GOOS != "linux" {
+		t.Skip("Volumes are only mounted on linux")
+	}
+	tmp, err := ioutil.TempDir("", "AllocDir")
+	if err != nil {
+		t.Fatalf("Couldn't create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	host, err := ioutil.TempDir("", "HostVolume")
+	if err != nil {
+		t.Fatalf("Couldn't create temp dir: %v", err)
+	}
+	defer os.RemoveAll(host)
+	exp := []byte{'f', 'o', 'o'}
+	if err := ioutil.WriteFile(filepath.Join(host, "bar"), exp, 0666); err != nil {
+		t.Fatalf("Couldn't write file to host volume: %v", err)
+	}
+
+	d := NewAllocDir(tmp, structs.DefaultResources().DiskMB)
+	d.VolumeMounts = map[string][]*VolumeMount{
+		t1.Name: {
+			{HostPath: host, TaskPath: "/srv/data"},
+			{HostPath: host, TaskPath: "/srv/ro", ReadOnly: true},
+		},
+	}
+	if err := d.Build([]*structs.Task{t1, t2}); err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	if err := d.MountVolumes(t1.Name); err != nil {
+		d.Destroy()
+		t.Fatalf("MountVolumes() failed: %v", err)
+	}
+
+	taskDir := d.TaskDirs[t1.Name]
+	act, err := ioutil.ReadFile(filepath.Join(taskDir, "srv", "data", "bar"))
+	if err != nil {
+		d.Destroy()
+		t.Fatalf("Failed to read host volume file from task dir: %v", err)
+	}
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatalf("Incorrect data read from task dir: want %v; got %v", exp, act)
+	}
+	if err := ioutil.WriteFile(filepath.Join(taskDir, "srv", "data", "baz"), exp, 0666); err != nil {
+		t.Fatalf("Couldn't write to host volume from task dir: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(taskDir, "srv", "ro", "baz"), exp, 0666); err == nil {
+		t.Fatalf("Wrote to read-only host volume")
+	}
+
+	// Destroying the alloc dir unmounts the volumes before removing it
+	if err := d.Destroy(); err != nil {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+	for _, file := range []string{"bar", "baz"} {
+		if _, err := os.Stat(filepath.Join(host, file)); err != nil {
+			t.Fatalf("Host volume file %q removed: %v", file, err)
+		}
+	}
+}
+
 func TestAllocDir_Snapshot(t *testing.T) {
 	tmp, err := ioutil.TempDir("", "AllocDir")
 	if err != nil {
@@ -288,3 +397,63 @@ func TestAllocDir_Snapshot(t *testing.T) {
 		t.Fatalf("bad files: %#v", files)
 	}
 }
+
+func TestAllocDir_Restore(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "AllocDir")
+	if err != nil {
+		t.Fatalf("Couldn't create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	src := NewAllocDir(filepath.Join(tmp, "src"), structs.DefaultResources().DiskMB)
+	defer src.Destroy()
+	if err := src.Build([]*structs.Task{t1, t2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Write files to the shared data dir and the local dirs of both tasks
+	if err := os.MkdirAll(filepath.Join(src.SharedDir, "data", "dir"), 0777); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src.SharedDir, "data", "dir", "foo"), []byte("foo"), 0666); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src.TaskDirs[t1.Name], TaskLocal, "bar"), []byte("bar"), 0666); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src.TaskDirs[t2.Name], TaskLocal, "baz"), []byte("baz"), 0666); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := src.Snapshot(&b); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Restore into an allocation only running the first task
+	dst := NewAllocDir(filepath.Join(tmp, "dst"), structs.DefaultResources().DiskMB)
+	defer dst.Destroy()
+	if err := dst.Build([]*structs.Task{t1}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := dst.Restore(&b); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	expected := map[string]string{
+		filepath.Join(dst.SharedDir, "data", "dir", "foo"):     "foo",
+		filepath.Join(dst.TaskDirs[t1.Name], TaskLocal, "bar"): "bar",
+	}
+	for path, content := range expected {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if string(data) != content {
+			t.Fatalf("bad content of %q: %q", path, data)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dst.AllocDir, t2.Name)); !os.IsNotExist(err) {
+		t.Fatalf("the directory of the missing task was restored: %v", err)
+	}
+}
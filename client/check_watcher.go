@@ -0,0 +1,168 @@
+package client
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/client/driver/env"
+	"github.com/hashicorp/nomad/command/agent/consul"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// checkWatchInterval is how often the statuses of the watched checks are
+	// queried from the Consul agent
+	checkWatchInterval = 1 * time.Second
+)
+
+// watchedCheck is a check whose failures restart the task
+type watchedCheck struct {
+	serviceID string
+	name      string
+	interval  time.Duration
+	restart   *structs.CheckRestart
+
+	// next is when the status of the check is next counted and failures the
+	// number of consecutive unhealthy intervals
+	next     time.Time
+	failures int
+}
+
+// checkWatcher restarts a task once one of its checks with a check_restart
+// has been unhealthy for the limit of consecutive check intervals
+type checkWatcher struct {
+	checks  []*watchedCheck
+	consul  *consulapi.Client
+	restart func(reason string) error
+	logger  *log.Logger
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// newCheckWatcher returns a watcher of the checks of the task's services that
+// restart the task, or nil if there are none. The names of the services and
+// checks are interpolated as when they are registered, and the checks are
+// only counted once their grace period has passed.
+func newCheckWatcher(allocID string, task *structs.Task, taskEnv *env.TaskEnvironment,
+	client *consulapi.Client, restart func(reason string) error, logger *log.Logger) *checkWatcher {
+
+	domain := consul.NewExecutorDomain(allocID, task.Name)
+	now := time.Now()
+	var checks []*watchedCheck
+	for _, service := range task.Services {
+		if service.Provider == structs.ServiceProviderNomad {
+			continue
+		}
+
+		interpolated := service.Copy()
+		interpolated.Name = taskEnv.ReplaceEnv(service.Name)
+		interpolated.Tags = taskEnv.ParseAndReplace(service.Tags)
+		serviceID := consul.GenerateServiceID(domain, interpolated)
+
+		for _, check := range service.Checks {
+			if check.CheckRestart == nil || check.CheckRestart.Limit == 0 {
+				continue
+			}
+			checks = append(checks, &watchedCheck{
+				serviceID: serviceID,
+				name:      taskEnv.ReplaceEnv(check.Name),
+				interval:  check.Interval,
+				restart:   check.CheckRestart,
+				next:      now.Add(check.CheckRestart.Grace),
+			})
+		}
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+
+	return &checkWatcher{
+		checks:  checks,
+		consul:  client,
+		restart: restart,
+		logger:  logger,
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// Run watches the checks until the task is restarted or the watcher is
+// stopped
+func (w *checkWatcher) Run() {
+	ticker := time.NewTicker(checkWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		statuses, err := w.consul.Agent().Checks()
+		if err != nil {
+			w.logger.Printf("[WARN] client: failed to query the status of checks: %v", err)
+			continue
+		}
+
+		reason := w.evaluate(statuses, time.Now())
+		if reason == "" {
+			continue
+		}
+		if err := w.restart(reason); err != nil {
+			w.logger.Printf("[ERR] client: failed to restart task on unhealthy check: %v", err)
+		}
+		return
+	}
+}
+
+// Stop stops watching the checks
+func (w *checkWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+	})
+}
+
+// evaluate counts the unhealthy intervals of the checks due at the given time
+// and returns the reason to restart the task once a check reaches its limit.
+// Checks not yet registered are not counted.
+func (w *checkWatcher) evaluate(statuses map[string]*consulapi.AgentCheck, now time.Time) string {
+	type checkKey struct{ serviceID, name string }
+	byCheck := make(map[checkKey]string, len(statuses))
+	for _, status := range statuses {
+		byCheck[checkKey{status.ServiceID, status.Name}] = status.Status
+	}
+
+	for _, c := range w.checks {
+		if now.Before(c.next) {
+			continue
+		}
+		c.next = now.Add(c.interval)
+
+		status, ok := byCheck[checkKey{c.serviceID, c.name}]
+		if !ok {
+			continue
+		}
+
+		unhealthy := false
+		switch status {
+		case consulapi.HealthCritical:
+			unhealthy = true
+		case consulapi.HealthWarning:
+			unhealthy = !c.restart.IgnoreWarnings
+		}
+		if !unhealthy {
+			c.failures = 0
+			continue
+		}
+
+		c.failures++
+		if c.failures >= c.restart.Limit {
+			return fmt.Sprintf("healthcheck: check %q unhealthy", c.name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/client/driver/env"
+	"github.com/hashicorp/nomad/command/agent/consul"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func testCheckWatcher(restart *structs.CheckRestart) (*checkWatcher, string) {
+	alloc := mock.Alloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Services = []*structs.Service{
+		{
+			Name:      "web-${NOMAD_TASK_NAME}",
+			PortLabel: "http",
+			Checks: []*structs.ServiceCheck{
+				{
+					Name:         "alive",
+					Type:         structs.ServiceCheckHTTP,
+					Path:         "/health",
+					Interval:     10 * time.Second,
+					Timeout:      2 * time.Second,
+					CheckRestart: restart,
+				},
+				{
+					Name:     "ready",
+					Type:     structs.ServiceCheckHTTP,
+					Path:     "/ready",
+					Interval: 10 * time.Second,
+					Timeout:  2 * time.Second,
+				},
+			},
+		},
+	}
+	taskEnv := env.NewTaskEnvironment(mock.Node()).SetTaskName(task.Name).Build()
+
+	w := newCheckWatcher(alloc.ID, task, taskEnv, nil, nil, testLogger())
+	if w == nil {
+		return nil, ""
+	}
+	service := task.Services[0].Copy()
+	service.Name = "web-" + task.Name
+	return w, consul.GenerateServiceID(consul.NewExecutorDomain(alloc.ID, task.Name), service)
+}
+
+func testCheckStatuses(serviceID, alive, ready string) map[string]*consulapi.AgentCheck {
+	return map[string]*consulapi.AgentCheck{
+		"1": {ServiceID: serviceID, Name: "alive", Status: alive},
+		"2": {ServiceID: serviceID, Name: "ready", Status: ready},
+		"3": {ServiceID: "other", Name: "alive", Status: consulapi.HealthCritical},
+	}
+}
+
+func TestCheckWatcher_NoCheckRestart(t *testing.T) {
+	if w, _ := testCheckWatcher(nil); w != nil {
+		t.Fatalf("expected no watcher")
+	}
+	if w, _ := testCheckWatcher(&structs.CheckRestart{Limit: 0}); w != nil {
+		t.Fatalf("expected no watcher")
+	}
+}
+
+func TestCheckWatcher_Evaluate(t *testing.T) {
+	w, serviceID := testCheckWatcher(&structs.CheckRestart{Limit: 2, Grace: time.Minute})
+	if w == nil {
+		t.Fatalf("expected a watcher")
+	}
+
+	// The check is ignored during the grace period
+	now := time.Now()
+	critical := testCheckStatuses(serviceID, consulapi.HealthCritical, consulapi.HealthCritical)
+	if reason := w.evaluate(critical, now); reason != "" {
+		t.Fatalf("bad: %q", reason)
+	}
+
+	// A passing status resets the failures
+	now = now.Add(time.Minute)
+	if reason := w.evaluate(critical, now); reason != "" {
+		t.Fatalf("bad: %q", reason)
+	}
+	now = now.Add(10 * time.Second)
+	if reason := w.evaluate(testCheckStatuses(serviceID, consulapi.HealthPassing, consulapi.HealthCritical), now); reason != "" {
+		t.Fatalf("bad: %q", reason)
+	}
+
+	// The check is only counted once per interval
+	now = now.Add(10 * time.Second)
+	if reason := w.evaluate(critical, now); reason != "" {
+		t.Fatalf("bad: %q", reason)
+	}
+	if reason := w.evaluate(critical, now.Add(time.Second)); reason != "" {
+		t.Fatalf("bad: %q", reason)
+	}
+	now = now.Add(10 * time.Second)
+	if reason := w.evaluate(critical, now); !strings.Contains(reason, `check "alive" unhealthy`) {
+		t.Fatalf("bad: %q", reason)
+	}
+}
+
+func TestCheckWatcher_Evaluate_IgnoreWarnings(t *testing.T) {
+	w, serviceID := testCheckWatcher(&structs.CheckRestart{Limit: 1, IgnoreWarnings: true})
+	if w == nil {
+		t.Fatalf("expected a watcher")
+	}
+
+	now := time.Now()
+	if reason := w.evaluate(testCheckStatuses(serviceID, consulapi.HealthWarning, consulapi.HealthCritical), now); reason != "" {
+		t.Fatalf("bad: %q", reason)
+	}
+	now = now.Add(10 * time.Second)
+	if reason := w.evaluate(testCheckStatuses(serviceID, consulapi.HealthCritical, consulapi.HealthPassing), now); reason == "" {
+		t.Fatalf("expected a restart")
+	}
+}
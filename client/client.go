@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -19,14 +20,18 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/nomad/client/allocdir"
 	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/csimanager"
+	"github.com/hashicorp/nomad/client/devicemanager"
 	"github.com/hashicorp/nomad/client/driver"
 	"github.com/hashicorp/nomad/client/fingerprint"
 	"github.com/hashicorp/nomad/client/rpcproxy"
 	"github.com/hashicorp/nomad/client/stats"
+	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/client/vaultclient"
 	"github.com/hashicorp/nomad/command/agent/consul"
 	"github.com/hashicorp/nomad/nomad"
 	"github.com/hashicorp/nomad/nomad/structs"
+	sconfig "github.com/hashicorp/nomad/nomad/structs/config"
 	vaultapi "github.com/hashicorp/vault/api"
 	"github.com/mitchellh/hashstructure"
 )
@@ -79,6 +84,10 @@ const (
 	// allocSyncRetryIntv is the interval on which we retry updating
 	// the status of the allocation
 	allocSyncRetryIntv = 5 * time.Second
+
+	// networkIndexRebuildIntv is how often the client rebuilds the port
+	// accounting of the node from its live allocations
+	networkIndexRebuildIntv = 5 * time.Minute
 )
 
 // ClientStatsReporter exposes all the APIs related to resource usage of a Nomad
@@ -90,6 +99,10 @@ type ClientStatsReporter interface {
 
 	// LatestHostStats returns the latest resource usage stats for the host
 	LatestHostStats() *stats.HostStats
+
+	// TaskGroupStats returns the resource usage of the running allocations of
+	// the job on the client, summed by task group
+	TaskGroupStats(namespace, jobID string) []*cstructs.TaskGroupResourceUsage
 }
 
 // Client is used to implement the client interaction with Nomad. Clients
@@ -135,6 +148,12 @@ type Client struct {
 	blockedAllocations map[string]*structs.Allocation
 	blockedAllocsLock  sync.RWMutex
 
+	// migrateTokens authorize the migration of the data of the previous
+	// allocations of the allocations from other nodes, keyed by the ID of
+	// the allocation
+	migrateTokens     map[string]string
+	migrateTokensLock sync.RWMutex
+
 	// allocUpdates stores allocations that need to be synced to the server.
 	allocUpdates chan *structs.Allocation
 
@@ -146,28 +165,60 @@ type Client struct {
 	resourceUsage      *stats.HostStats
 	resourceUsageLock  sync.RWMutex
 
+	// lastMemoryPressureAction is when an allocation was last acted on to
+	// relieve memory pressure on the host
+	lastMemoryPressureAction time.Time
+
+	// hostReservedPorts are the addresses of the ports reserved at runtime
+	// because processes on the host bound them. They are released by the
+	// rebuilds of the network index once no longer bound. It is guarded by
+	// the configLock.
+	hostReservedPorts map[string]struct{}
+
 	shutdown     bool
 	shutdownCh   chan struct{}
 	shutdownLock sync.Mutex
 
 	// vaultClient is used to interact with Vault for token and secret renewals
 	vaultClient vaultclient.VaultClient
+
+	// vaultClusters are the clients of the additional, named Vault clusters
+	vaultClusters map[string]vaultclient.VaultClient
+
+	// prefetches tracks the images and artifacts fetched on request, keyed
+	// by their type and source
+	prefetches   map[string]*cstructs.PrefetchStatus
+	prefetchLock sync.RWMutex
+
+	// devices runs the device plugins. It is nil if there are none.
+	devices *devicemanager.Manager
+
+	// csi tracks the CSI plugins running on the node
+	csi *csimanager.Manager
 }
 
 // NewClient is used to create a new client from the given configuration
 func NewClient(cfg *config.Config, consulSyncer *consul.Syncer, logger *log.Logger) (*Client, error) {
+	// Configure TLS for RPC
+	tlsWrap, err := nomad.NewTLSWrapper(cfg.TLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %v", err)
+	}
+
 	// Create the client
 	c := &Client{
 		config:             cfg,
 		consulSyncer:       consulSyncer,
 		start:              time.Now(),
-		connPool:           nomad.NewPool(cfg.LogOutput, clientRPCCache, clientMaxStreams, nil),
+		connPool:           nomad.NewPool(cfg.LogOutput, clientRPCCache, clientMaxStreams, tlsWrap),
 		logger:             logger,
 		hostStatsCollector: stats.NewHostStatsCollector(),
 		allocs:             make(map[string]*AllocRunner),
 		blockedAllocations: make(map[string]*structs.Allocation),
 		allocUpdates:       make(chan *structs.Allocation, 64),
 		shutdownCh:         make(chan struct{}),
+		prefetches:         make(map[string]*cstructs.PrefetchStatus),
+		hostReservedPorts:  make(map[string]struct{}),
 	}
 
 	// Initialize the client
@@ -190,6 +241,14 @@ func NewClient(cfg *config.Config, consulSyncer *consul.Syncer, logger *log.Logg
 		return nil, fmt.Errorf("driver setup failed: %v", err)
 	}
 
+	// Launch the device plugins
+	if err := c.setupDevices(); err != nil {
+		return nil, fmt.Errorf("device setup failed: %v", err)
+	}
+
+	// Track the CSI plugins run by the allocations
+	c.setupCSI()
+
 	// Setup the reserved resources
 	c.reservePorts()
 
@@ -238,6 +297,9 @@ func NewClient(cfg *config.Config, consulSyncer *consul.Syncer, logger *log.Logg
 	// Start collecting stats
 	go c.collectHostStats()
 
+	// Begin releasing the leaked port reservations of the node
+	go c.periodicNetworkIndexRebuild()
+
 	// Start the RPCProxy maintenance task.  This task periodically
 	// shuffles the list of Nomad Server Endpoints this Client will use
 	// when communicating with Nomad Servers via RPC.  This is done in
@@ -319,6 +381,38 @@ func (c *Client) RPCMinorVersion() int {
 	return structs.ApiMinorVersion
 }
 
+// ReloadTLS reloads the certificates used for RPC to the servers from the
+// given TLS configuration. Enabling or disabling TLS requires a restart.
+func (c *Client) ReloadTLS(newConf *sconfig.TLSConfig) error {
+	oldEnabled := c.config.TLSConfig != nil && c.config.TLSConfig.EnableRPC
+	if newEnabled := newConf != nil && newConf.EnableRPC; newEnabled != oldEnabled {
+		return fmt.Errorf("enabling or disabling TLS for RPC requires a restart")
+	}
+
+	tlsWrap, err := nomad.NewTLSWrapper(newConf)
+	if err != nil {
+		return err
+	}
+	c.config.TLSConfig = newConf
+	c.connPool.ReloadTLS(tlsWrap)
+	c.logger.Printf("[INFO] client: reloaded TLS certificates")
+	return nil
+}
+
+// UpdateMeta replaces the metadata of the node. The registration of the node
+// is updated once the change is detected by watchNodeUpdates.
+func (c *Client) UpdateMeta(meta map[string]string) {
+	newMeta := make(map[string]string, len(meta))
+	for k, v := range meta {
+		newMeta[k] = v
+	}
+
+	c.configLock.Lock()
+	c.config.Node.Meta = newMeta
+	c.configLock.Unlock()
+	c.logger.Printf("[INFO] client: reloaded node meta")
+}
+
 // Shutdown is used to tear down the client
 func (c *Client) Shutdown() error {
 	c.logger.Printf("[INFO] client: shutting down")
@@ -333,6 +427,9 @@ func (c *Client) Shutdown() error {
 	if c.vaultClient != nil {
 		c.vaultClient.Stop()
 	}
+	for _, v := range c.vaultClusters {
+		v.Stop()
+	}
 
 	// Destroy all the running allocations.
 	if c.config.DevMode {
@@ -347,6 +444,9 @@ func (c *Client) Shutdown() error {
 	c.shutdown = true
 	close(c.shutdownCh)
 	c.connPool.Shutdown()
+	if c.devices != nil {
+		c.devices.Shutdown()
+	}
 	return c.saveState()
 }
 
@@ -426,6 +526,53 @@ func (c *Client) LatestHostStats() *stats.HostStats {
 	return c.resourceUsage
 }
 
+// TaskGroupStats returns the resource usage of the running allocations of the
+// job on the client, summed by task group. Autoscalers sum the usage reported
+// by each client running allocations of the job.
+func (c *Client) TaskGroupStats(namespace, jobID string) []*cstructs.TaskGroupResourceUsage {
+	c.allocLock.RLock()
+	runners := make([]*AllocRunner, 0, len(c.allocs))
+	for _, ar := range c.allocs {
+		runners = append(runners, ar)
+	}
+	c.allocLock.RUnlock()
+
+	var usages []*cstructs.TaskGroupResourceUsage
+	byGroup := make(map[string]*cstructs.TaskGroupResourceUsage)
+	for _, ar := range runners {
+		alloc := ar.Alloc()
+		if alloc.Namespace != namespace || alloc.JobID != jobID ||
+			alloc.ClientStatus != structs.AllocClientStatusRunning {
+			continue
+		}
+		astat, err := ar.LatestAllocStats("")
+		if err != nil {
+			continue
+		}
+
+		usage, ok := byGroup[alloc.TaskGroup]
+		if !ok {
+			usage = &cstructs.TaskGroupResourceUsage{
+				Namespace: namespace,
+				JobID:     jobID,
+				TaskGroup: alloc.TaskGroup,
+				ResourceUsage: &cstructs.ResourceUsage{
+					MemoryStats: &cstructs.MemoryStats{},
+					CpuStats:    &cstructs.CpuStats{},
+				},
+			}
+			byGroup[alloc.TaskGroup] = usage
+			usages = append(usages, usage)
+		}
+		usage.Allocs++
+		usage.ResourceUsage.Add(astat.ResourceUsage)
+		if astat.Timestamp > usage.Timestamp {
+			usage.Timestamp = astat.Timestamp
+		}
+	}
+	return usages
+}
+
 // GetAllocFS returns the AllocFS interface for the alloc dir of an allocation
 func (c *Client) GetAllocFS(allocID string) (allocdir.AllocDirFS, error) {
 	c.allocLock.RLock()
@@ -438,6 +585,81 @@ func (c *Client) GetAllocFS(allocID string) (allocdir.AllocDirFS, error) {
 	return ar.ctx.AllocDir, nil
 }
 
+// RestartAlloc restarts the tasks of the allocation with the given ID in
+// place. If the task is set only the given task is restarted.
+func (c *Client) RestartAlloc(allocID, task, reason string) error {
+	ar, err := c.getAllocRunner(allocID)
+	if err != nil {
+		return err
+	}
+	return ar.Restart(task, reason)
+}
+
+// SignalAlloc sends the signal to the tasks of the allocation with the given
+// ID. If the task is set only the given task is signaled.
+func (c *Client) SignalAlloc(allocID, task, sig, reason string) error {
+	ar, err := c.getAllocRunner(allocID)
+	if err != nil {
+		return err
+	}
+	return ar.Signal(task, sig, reason)
+}
+
+// PauseAlloc pauses the tasks of the allocation with the given ID. If the
+// task is set only the given task is paused.
+func (c *Client) PauseAlloc(allocID, task, reason string) error {
+	ar, err := c.getAllocRunner(allocID)
+	if err != nil {
+		return err
+	}
+	return ar.Pause(task, reason)
+}
+
+// ResumeAlloc resumes the paused tasks of the allocation with the given ID.
+// If the task is set only the given task is resumed.
+func (c *Client) ResumeAlloc(allocID, task, reason string) error {
+	ar, err := c.getAllocRunner(allocID)
+	if err != nil {
+		return err
+	}
+	return ar.Resume(task, reason)
+}
+
+// ExecAlloc runs a command inside the given task of the allocation with the
+// given ID and returns the exit code of the command
+func (c *Client) ExecAlloc(ctx context.Context, allocID, task string, opts *driver.ExecOptions) (int, error) {
+	ar, err := c.getAllocRunner(allocID)
+	if err != nil {
+		return 0, err
+	}
+	return ar.Exec(ctx, task, opts)
+}
+
+// getAllocRunner returns the runner of the allocation with the given ID
+func (c *Client) getAllocRunner(allocID string) (*AllocRunner, error) {
+	c.allocLock.RLock()
+	defer c.allocLock.RUnlock()
+
+	ar, ok := c.allocs[allocID]
+	if !ok {
+		return nil, fmt.Errorf("alloc not found")
+	}
+	return ar, nil
+}
+
+// GetAlloc returns the allocation with the given ID if it is running on the
+// client
+func (c *Client) GetAlloc(allocID string) (*structs.Allocation, error) {
+	c.allocLock.RLock()
+	defer c.allocLock.RUnlock()
+
+	ar, ok := c.allocs[allocID]
+	if !ok {
+		return nil, fmt.Errorf("alloc not found")
+	}
+	return ar.Alloc(), nil
+}
+
 // AddPrimaryServerToRPCProxy adds serverAddr to the RPC Proxy's primary
 // server list.
 func (c *Client) AddPrimaryServerToRPCProxy(serverAddr string) *rpcproxy.ServerEndpoint {
@@ -464,7 +686,13 @@ func (c *Client) restoreState() error {
 		id := entry.Name()
 		alloc := &structs.Allocation{ID: id}
 		c.configLock.RLock()
-		ar := NewAllocRunner(c.logger, c.configCopy, c.updateAllocStatus, alloc, c.vaultClient)
+		ar := NewAllocRunner(c.logger, c.configCopy, c.updateAllocStatus, alloc, c.vaultClient, c)
+		ar.SetVaultClusters(c.vaultClusters)
+		ar.SetVariableReader(c)
+		ar.SetTemplateReader(c)
+		ar.SetPortReserver(c)
+		ar.SetDeviceReserver(c)
+		ar.SetCSIManager(c)
 		c.configLock.RUnlock()
 		c.allocLock.Lock()
 		c.allocs[id] = ar
@@ -596,6 +824,7 @@ func (c *Client) setupNode() error {
 	if node.Name == "" {
 		node.Name = node.ID
 	}
+	node.TaskTmpfsMB = allocdir.TaskTmpfsSize(c.config.SecretsTmpfsSize, c.config.LocalTmpfsSize)
 	node.Status = structs.NodeStatusInit
 	return nil
 }
@@ -644,20 +873,78 @@ func (c *Client) reservePorts() {
 	}
 }
 
+// ReserveHostPorts adds the ports that are bound by processes on the host to
+// the node's reserved networks and updates the node's registration, so that
+// the ports are no longer offered to allocations. It is a no-op unless the
+// client is configured to reserve port conflicts.
+func (c *Client) ReserveHostPorts(networks []*structs.NetworkResource) {
+	c.configLock.Lock()
+	if !c.config.ReservePortConflicts {
+		c.configLock.Unlock()
+		return
+	}
+
+	node := c.config.Node
+	if node.Reserved == nil {
+		node.Reserved = new(structs.Resources)
+	}
+	var reserved []string
+	for _, n := range networks {
+		var res *structs.NetworkResource
+		for _, existing := range node.Reserved.Networks {
+			if existing.IP == n.IP {
+				res = existing
+				break
+			}
+		}
+		if res == nil {
+			res = &structs.NetworkResource{Device: n.Device, IP: n.IP}
+			node.Reserved.Networks = append(node.Reserved.Networks, res)
+		}
+
+	PORTS:
+		for _, port := range n.ReservedPorts {
+			for _, existing := range res.ReservedPorts {
+				if existing.Value == port.Value {
+					continue PORTS
+				}
+			}
+			res.ReservedPorts = append(res.ReservedPorts, structs.Port{Value: port.Value})
+			addr := net.JoinHostPort(n.IP, strconv.Itoa(port.Value))
+			c.hostReservedPorts[addr] = struct{}{}
+			reserved = append(reserved, addr)
+		}
+	}
+	c.configCopy.Node = node.Copy()
+	c.configLock.Unlock()
+
+	if len(reserved) == 0 {
+		return
+	}
+	c.logger.Printf("[INFO] client: reserving ports in use on the host: %v", reserved)
+	go c.retryRegisterNode()
+}
+
 // fingerprint is used to fingerprint the client and setup the node
 func (c *Client) fingerprint() error {
 	whitelist := c.config.ReadStringListToMap("fingerprint.whitelist")
 	whitelistEnabled := len(whitelist) > 0
+	blacklist := c.config.ReadStringListToMap("fingerprint.blacklist")
 	c.logger.Printf("[DEBUG] client: built-in fingerprints: %v", fingerprint.BuiltinFingerprints())
 
 	var applied []string
 	var skipped []string
 	for _, name := range fingerprint.BuiltinFingerprints() {
-		// Skip modules that are not in the whitelist if it is enabled.
+		// Skip modules that are not in the whitelist if it is enabled, and
+		// the ones in the blacklist.
 		if _, ok := whitelist[name]; whitelistEnabled && !ok {
 			skipped = append(skipped, name)
 			continue
 		}
+		if _, ok := blacklist[name]; ok {
+			skipped = append(skipped, name)
+			continue
+		}
 		f, err := fingerprint.NewFingerprint(name, c.logger)
 		if err != nil {
 			return err
@@ -682,7 +969,7 @@ func (c *Client) fingerprint() error {
 	}
 	c.logger.Printf("[DEBUG] client: applied fingerprints %v", applied)
 	if len(skipped) != 0 {
-		c.logger.Printf("[DEBUG] client: fingerprint modules skipped due to whitelist: %v", skipped)
+		c.logger.Printf("[DEBUG] client: fingerprint modules skipped due to white/blacklist: %v", skipped)
 	}
 	return nil
 }
@@ -710,6 +997,17 @@ func (c *Client) setupDrivers() error {
 	whitelist := c.config.ReadStringListToMap("driver.whitelist")
 	whitelistEnabled := len(whitelist) > 0
 
+	// Register the external drivers found in the plugin directory
+	if c.config.PluginDir != "" {
+		external, err := driver.LoadExternalDrivers(c.config.PluginDir, c.logger)
+		if err != nil {
+			return err
+		}
+		if len(external) != 0 {
+			c.logger.Printf("[DEBUG] client: found external drivers %v", external)
+		}
+	}
+
 	var avail []string
 	var skipped []string
 	driverCtx := driver.NewDriverContext("", c.config, c.config.Node, c.logger, nil)
@@ -727,6 +1025,13 @@ func (c *Client) setupDrivers() error {
 		}
 		c.configLock.Lock()
 		applies, err := d.Fingerprint(c.config, c.config.Node)
+		if err == nil && applies {
+			// Advertise the capabilities of the driver so the scheduler can
+			// validate the tasks placed on this node against them
+			for k, v := range d.Capabilities().Attributes(name) {
+				c.config.Node.Attributes[k] = v
+			}
+		}
 		c.configLock.Unlock()
 		if err != nil {
 			return err
@@ -850,7 +1155,11 @@ func (c *Client) run() {
 func (c *Client) hasNodeChanged(oldAttrHash uint64, oldMetaHash uint64) (bool, uint64, uint64) {
 	c.configLock.RLock()
 	defer c.configLock.RUnlock()
-	newAttrHash, err := hashstructure.Hash(c.config.Node.Attributes, nil)
+	// The links, devices and CSI plugins are hashed with the attributes as
+	// their changes, such as the health of devices, must also be sent to the
+	// servers
+	newAttrHash, err := hashstructure.Hash([]interface{}{c.config.Node.Attributes, c.config.Node.Links,
+		c.config.Node.Devices, c.config.Node.CSIControllerPlugins, c.config.Node.CSINodePlugins}, nil)
 	if err != nil {
 		c.logger.Printf("[DEBUG] client: unable to calculate node attributes hash: %v", err)
 	}
@@ -921,12 +1230,16 @@ func (c *Client) updateNodeStatus() error {
 	req := structs.NodeUpdateStatusRequest{
 		NodeID:       node.ID,
 		Status:       structs.NodeStatusReady,
+		ClientTime:   time.Now().UnixNano(),
 		WriteRequest: structs.WriteRequest{Region: c.Region()},
 	}
 	var resp structs.NodeUpdateResponse
 	if err := c.RPC("Node.UpdateStatus", &req, &resp); err != nil {
 		return fmt.Errorf("failed to update status: %v", err)
 	}
+	if resp.ClockSkew != 0 {
+		c.logger.Printf("[WARN] client: clock is skewed by %v from the servers' clock", resp.ClockSkew)
+	}
 	if len(resp.EvalIDs) != 0 {
 		c.logger.Printf("[DEBUG] client: %d evaluations triggered by node update", len(resp.EvalIDs))
 	}
@@ -1096,6 +1409,10 @@ func (c *Client) watchAllocations(updates chan *allocUpdates) {
 		default:
 		}
 
+		c.migrateTokensLock.Lock()
+		c.migrateTokens = resp.MigrateTokens
+		c.migrateTokensLock.Unlock()
+
 		// Filter all allocations whose AllocModifyIndex was not incremented.
 		// These are the allocations who have either not been updated, or whose
 		// updates are a result of the client sending an update for the alloc.
@@ -1280,7 +1597,14 @@ func (c *Client) updateAlloc(exist, update *structs.Allocation) error {
 // addAlloc is invoked when we should add an allocation
 func (c *Client) addAlloc(alloc *structs.Allocation) error {
 	c.configLock.RLock()
-	ar := NewAllocRunner(c.logger, c.configCopy, c.updateAllocStatus, alloc, c.vaultClient)
+	ar := NewAllocRunner(c.logger, c.configCopy, c.updateAllocStatus, alloc, c.vaultClient, c)
+	ar.SetVaultClusters(c.vaultClusters)
+	ar.SetVariableReader(c)
+	ar.SetTemplateReader(c)
+	ar.SetPortReserver(c)
+	ar.SetDeviceReserver(c)
+	ar.SetCSIManager(c)
+	ar.SetDataMigrator(c)
 	c.configLock.RUnlock()
 	go ar.Run()
 
@@ -1308,6 +1632,115 @@ func (c *Client) setupVaultClient() error {
 	// Start renewing tokens and secrets
 	c.vaultClient.Start()
 
+	// Create the clients of the named Vault clusters. The tokens of tasks
+	// are renewed with the cluster they were derived from.
+	c.vaultClusters = make(map[string]vaultclient.VaultClient, len(c.config.VaultClusters))
+	for name, conf := range c.config.VaultClusters {
+		v, err := vaultclient.NewVaultClient(conf, c.logger, c.deriveToken)
+		if err != nil {
+			return fmt.Errorf("failed to create client of Vault cluster %q: %v", name, err)
+		}
+		v.Start()
+		c.vaultClusters[name] = v
+	}
+
+	return nil
+}
+
+// UpsertServiceRegistrations registers the services of tasks in Nomad's
+// built-in service catalog
+func (c *Client) UpsertServiceRegistrations(services []*structs.ServiceRegistration) error {
+	req := structs.ServiceRegistrationUpsertRequest{
+		Services:     services,
+		NodeID:       c.Node().ID,
+		SecretID:     c.Node().SecretID,
+		WriteRequest: structs.WriteRequest{Region: c.Region()},
+	}
+	var resp structs.GenericResponse
+	if err := c.RPC("ServiceRegistration.Upsert", &req, &resp); err != nil {
+		return fmt.Errorf("failed to register services: %v", err)
+	}
+	return nil
+}
+
+// ReadVariable reads the variable at the path using the workload identity of a
+// task. A nil variable is returned if the path has no variable.
+func (c *Client) ReadVariable(token, namespace, path string) (*structs.VariableDecrypted, error) {
+	req := structs.VariablesReadRequest{
+		Path: path,
+		QueryOptions: structs.QueryOptions{
+			Region:     c.Region(),
+			Namespace:  namespace,
+			AuthToken:  token,
+			AllowStale: true,
+		},
+	}
+	var resp structs.VariablesReadResponse
+	if err := c.RPC("Variables.Read", &req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Variable, nil
+}
+
+// QueryVariable reads the variable at the path using the workload identity of
+// a task. If the index is not zero, the query blocks until the variables
+// change from it. A nil variable is returned if the path has no variable.
+func (c *Client) QueryVariable(token, namespace, path string, index uint64) (*structs.VariableDecrypted, uint64, error) {
+	req := structs.VariablesReadRequest{
+		Path:         path,
+		QueryOptions: c.templateQueryOptions(token, namespace, index),
+	}
+	var resp structs.VariablesReadResponse
+	if err := c.RPC("Variables.Read", &req, &resp); err != nil {
+		return nil, 0, err
+	}
+	return resp.Variable, resp.Index, nil
+}
+
+// QueryServiceRegistrations returns the registrations of a service in Nomad's
+// built-in service catalog using the workload identity of a task. If the index
+// is not zero, the query blocks until the registrations change from it.
+func (c *Client) QueryServiceRegistrations(token, namespace, name string, index uint64) ([]*structs.ServiceRegistration, uint64, error) {
+	req := structs.ServiceRegistrationByNameRequest{
+		ServiceName:  name,
+		QueryOptions: c.templateQueryOptions(token, namespace, index),
+	}
+	var resp structs.ServiceRegistrationByNameResponse
+	if err := c.RPC("ServiceRegistration.GetService", &req, &resp); err != nil {
+		return nil, 0, err
+	}
+	return resp.Services, resp.Index, nil
+}
+
+// templateQueryOptions returns the options of the queries made for the
+// templates of a task
+func (c *Client) templateQueryOptions(token, namespace string, index uint64) structs.QueryOptions {
+	q := structs.QueryOptions{
+		Region:        c.Region(),
+		Namespace:     namespace,
+		AuthToken:     token,
+		AllowStale:    true,
+		MinQueryIndex: index,
+	}
+	if index != 0 {
+		q.MaxQueryTime = templateWaitTime
+	}
+	return q
+}
+
+// DeleteServiceRegistrations removes the registrations of the services of
+// tasks from Nomad's built-in service catalog
+func (c *Client) DeleteServiceRegistrations(ids []string) error {
+	req := structs.ServiceRegistrationDeleteRequest{
+		IDs:          ids,
+		NodeID:       c.Node().ID,
+		SecretID:     c.Node().SecretID,
+		WriteRequest: structs.WriteRequest{Region: c.Region()},
+	}
+	var resp structs.GenericResponse
+	if err := c.RPC("ServiceRegistration.Delete", &req, &resp); err != nil {
+		return fmt.Errorf("failed to deregister services: %v", err)
+	}
 	return nil
 }
 
@@ -1585,6 +2018,8 @@ func (c *Client) collectHostStats() {
 			if c.config.PublishNodeMetrics {
 				c.emitStats(ru)
 			}
+
+			c.checkMemoryPressure(ru)
 		case <-c.shutdownCh:
 			return
 		}
@@ -1615,6 +2050,28 @@ func (c *Client) emitStats(hStats *stats.HostStats) {
 		metrics.SetGauge([]string{"client", "host", "disk", nodeID, disk.Device, "used_percent"}, float32(disk.UsedPercent))
 		metrics.SetGauge([]string{"client", "host", "disk", nodeID, disk.Device, "inodes_percent"}, float32(disk.InodesUsedPercent))
 	}
+
+	c.emitAllocStats(nodeID)
+}
+
+// emitAllocStats pushes the number of allocations of the client by status to
+// remote metrics collection sinks
+func (c *Client) emitAllocStats(nodeID string) {
+	var pending, running, terminal float32
+	for _, ar := range c.getAllocRunners() {
+		switch ar.Alloc().ClientStatus {
+		case structs.AllocClientStatusPending:
+			pending++
+		case structs.AllocClientStatusRunning:
+			running++
+		default:
+			terminal++
+		}
+	}
+
+	metrics.SetGauge([]string{"client", "allocations", nodeID, "pending"}, pending)
+	metrics.SetGauge([]string{"client", "allocations", nodeID, "running"}, running)
+	metrics.SetGauge([]string{"client", "allocations", nodeID, "terminal"}, terminal)
 }
 
 // RPCProxy returns the Client's RPCProxy instance
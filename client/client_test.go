@@ -7,11 +7,13 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/nomad/client/allocdir"
 	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/command/agent/consul"
 	"github.com/hashicorp/nomad/nomad"
@@ -150,6 +152,25 @@ func TestClient_RPC_Passthrough(t *testing.T) {
 	})
 }
 
+func TestClient_UpdateMeta(t *testing.T) {
+	c := testClient(t, func(c *config.Config) {
+		c.Node = &structs.Node{Meta: map[string]string{"rack": "r1"}}
+	})
+	defer c.Shutdown()
+
+	meta := map[string]string{"rack": "r2", "zone": "a"}
+	c.UpdateMeta(meta)
+	if node := c.Node(); !reflect.DeepEqual(node.Meta, meta) {
+		t.Fatalf("bad: %v", node.Meta)
+	}
+
+	// The node doesn't share the map passed in
+	meta["zone"] = "b"
+	if node := c.Node(); node.Meta["zone"] != "a" {
+		t.Fatalf("bad: %v", node.Meta)
+	}
+}
+
 func TestClient_Fingerprint(t *testing.T) {
 	c := testClient(t, nil)
 	defer c.Shutdown()
@@ -169,7 +190,8 @@ func TestClient_HasNodeChanged(t *testing.T) {
 	defer c.Shutdown()
 
 	node := c.Node()
-	attrHash, err := hashstructure.Hash(node.Attributes, nil)
+	attrHash, err := hashstructure.Hash([]interface{}{node.Attributes, node.Links, node.Devices,
+		node.CSIControllerPlugins, node.CSINodePlugins}, nil)
 	if err != nil {
 		c.logger.Printf("[DEBUG] client: unable to calculate node attributes hash: %v", err)
 	}
@@ -188,6 +210,27 @@ func TestClient_HasNodeChanged(t *testing.T) {
 		t.Fatalf("Expected hash change in attributes: %d vs %d", attrHash, newAttrHash)
 	}
 
+	// Change node links
+	if node.Links == nil {
+		node.Links = make(map[string]string)
+	}
+	node.Links["consul"] = "dc1.node1"
+	if changed, _, _ := c.hasNodeChanged(attrHash, metaHash); !changed {
+		t.Fatalf("Expected hash change in links")
+	}
+
+	// Change node devices
+	node.Devices = []*structs.NodeDeviceResource{{Vendor: "nvidia", Type: "gpu", Name: "1080ti"}}
+	if changed, _, _ := c.hasNodeChanged(attrHash, metaHash); !changed {
+		t.Fatalf("Expected hash change in devices")
+	}
+
+	// Change node CSI plugins
+	node.CSINodePlugins = map[string]*structs.CSIInfo{"ebs": {PluginID: "ebs", Healthy: true}}
+	if changed, _, _ := c.hasNodeChanged(attrHash, metaHash); !changed {
+		t.Fatalf("Expected hash change in CSI plugins")
+	}
+
 	// Change node meta map
 	node.Meta["foo"] = "bar"
 	if changed, _, newMetaHash := c.hasNodeChanged(attrHash, metaHash); !changed {
@@ -228,6 +271,26 @@ func TestClient_Fingerprint_OutOfWhitelist(t *testing.T) {
 	}
 }
 
+func TestClient_Fingerprint_InBlacklist(t *testing.T) {
+	c := testClient(t, func(c *config.Config) {
+		if c.Options == nil {
+			c.Options = make(map[string]string)
+		}
+
+		// Weird spacing to test trimming. Blacklist cpu.
+		c.Options["fingerprint.blacklist"] = "  cpu	"
+	})
+	defer c.Shutdown()
+
+	node := c.Node()
+	if node.Attributes["cpu.frequency"] != "" {
+		t.Fatalf("found cpu fingerprint module")
+	}
+	if node.Attributes["arch"] == "" {
+		t.Fatalf("missing arch fingerprint module")
+	}
+}
+
 func TestClient_Drivers(t *testing.T) {
 	c := testClient(t, nil)
 	defer c.Shutdown()
@@ -710,3 +773,92 @@ func TestClient_BlockedAllocations(t *testing.T) {
 	c1.allocLock.Unlock()
 
 }
+
+func TestClient_MigrateAllocData_Sticky(t *testing.T) {
+	s1, _ := testServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	c1 := testClient(t, func(c *config.Config) {
+		c.RPCHandler = s1
+	})
+	defer c1.Shutdown()
+	waitTilNodeReady(c1, t)
+
+	// Add a sticky allocation
+	state := s1.State()
+	alloc := mock.Alloc()
+	alloc.NodeID = c1.Node().ID
+	alloc.Job.TaskGroups[0].EphemeralDisk.Sticky = true
+	alloc.Job.TaskGroups[0].Tasks[0].Driver = "mock_driver"
+	alloc.Job.TaskGroups[0].Tasks[0].Config = map[string]interface{}{
+		"run_for": "100s",
+	}
+	state.UpsertJobSummary(99, mock.JobSummary(alloc.JobID))
+	state.UpsertAllocs(100, []*structs.Allocation{alloc})
+
+	// Wait until the allocation runs and write to its data dir
+	testutil.WaitForResult(func() (bool, error) {
+		out, err := state.AllocByID(alloc.ID)
+		if err != nil {
+			return false, err
+		}
+		if out == nil || out.ClientStatus != structs.AllocClientStatusRunning {
+			return false, fmt.Errorf("bad alloc: %#v", out)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+	ar, err := c1.getAllocRunner(alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	dataFile := filepath.Join(allocdir.SharedAllocName, "data", "foo")
+	if err := ioutil.WriteFile(filepath.Join(ar.ctx.AllocDir.AllocDir, dataFile), []byte("foo"), 0666); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Replace the allocation
+	alloc2 := alloc.Copy()
+	alloc2.ID = structs.GenerateUUID()
+	alloc2.Job = alloc.Job
+	alloc2.PreviousAllocation = alloc.ID
+	alloc1 := alloc.Copy()
+	alloc1.DesiredStatus = structs.AllocDesiredStatusStop
+	if err := state.UpsertAllocs(200, []*structs.Allocation{alloc1, alloc2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The data of the previous allocation is copied to the new one
+	testutil.WaitForResult(func() (bool, error) {
+		ar2, err := c1.getAllocRunner(alloc2.ID)
+		if err != nil {
+			return false, err
+		}
+		ar2.ctxLock.Lock()
+		ctx := ar2.ctx
+		ar2.ctxLock.Unlock()
+		if ctx == nil {
+			return false, fmt.Errorf("alloc dir not built")
+		}
+		data, err := ioutil.ReadFile(filepath.Join(ctx.AllocDir.AllocDir, dataFile))
+		if err != nil {
+			return false, err
+		}
+		if string(data) != "foo" {
+			return false, fmt.Errorf("bad data: %q", data)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// Destroy all the allocations
+	c1.allocLock.Lock()
+	for _, ar := range c1.allocs {
+		ar.Destroy()
+		<-ar.WaitCh()
+	}
+	c1.allocLock.Unlock()
+}
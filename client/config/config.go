@@ -59,6 +59,9 @@ type Config struct {
 	// AllocDir is where we store data for allocations
 	AllocDir string
 
+	// PluginDir is where the external task driver plugins are found
+	PluginDir string
+
 	// LogOutput is the destination for logs
 	LogOutput io.Writer
 
@@ -99,10 +102,50 @@ type Config struct {
 	// devices and IPs.
 	GloballyReservedPorts []int
 
+	// ReservePortConflicts marks the static ports of allocations that are
+	// found to be bound by processes on the host as reserved on the node
+	ReservePortConflicts bool
+
+	// SecretsTmpfsSize is the size in megabytes of the tmpfs backing the
+	// secrets dir of each task. If zero, the default size is used.
+	SecretsTmpfsSize int
+
+	// LocalTmpfsSize is the size in megabytes of the tmpfs backing the local
+	// dir of each task. If zero, the local dir is kept on disk.
+	LocalTmpfsSize int
+
+	// MemoryPressure configures how the client relieves memory pressure on
+	// the host. If nil, the client leaves it to the kernel.
+	MemoryPressure *MemoryPressureConfig
+
 	// A mapping of directories on the host OS to attempt to embed inside each
 	// task's chroot.
 	ChrootEnv map[string]string
 
+	// CNIPath is the list of paths, separated by colons, CNI plugins are
+	// searched in
+	CNIPath string
+
+	// CNIConfigDir is the directory the CNI network configurations used by
+	// task groups in "cni/<name>" networking mode are loaded from
+	CNIConfigDir string
+
+	// BridgeNetworkName is the name of the bridge the network namespaces of
+	// allocations in bridge networking mode are attached to
+	BridgeNetworkName string
+
+	// BridgeNetworkSubnet is the subnet the addresses of allocations in
+	// bridge networking mode are allocated from
+	BridgeNetworkSubnet string
+
+	// HostNetworks are the named networks of the host that ports of tasks can
+	// bind to
+	HostNetworks []*HostNetwork
+
+	// HostVolumes are the directories of the host that tasks can mount, keyed
+	// by their name
+	HostVolumes map[string]*structs.ClientHostVolumeConfig
+
 	// Options provides arbitrary key-value configuration for nomad internals,
 	// like fingerprinters and drivers. The format is:
 	//
@@ -121,6 +164,17 @@ type Config struct {
 	// VaultConfig is this Agent's Vault configuration
 	VaultConfig *config.VaultConfig
 
+	// ConsulClusters are the additional Consul clusters tasks may register
+	// their services with, keyed by name
+	ConsulClusters map[string]*config.ConsulConfig
+
+	// VaultClusters are the additional Vault clusters tasks may derive their
+	// tokens from, keyed by name
+	VaultClusters map[string]*config.VaultConfig
+
+	// TLSConfig holds the TLS configuration of the RPC traffic to servers
+	TLSConfig *config.TLSConfig
+
 	// StatsCollectionInterval is the interval at which the Nomad client
 	// collects resource usage stats
 	StatsCollectionInterval time.Duration
@@ -132,6 +186,15 @@ type Config struct {
 	// PublishAllocationMetrics determines whether nomad is going to publish
 	// allocation metrics to remote Telemetry sinks
 	PublishAllocationMetrics bool
+
+	// PublishDriverMetrics determines whether the driver specific metrics of
+	// the tasks are exposed in the allocation stats and, along with the
+	// allocation metrics, published to remote Telemetry sinks
+	PublishDriverMetrics bool
+
+	// DriverMetricsLimit is the maximum number of driver specific metrics
+	// kept per task, bounding the cardinality of the published metrics
+	DriverMetricsLimit int
 }
 
 func (c *Config) Copy() *Config {
@@ -141,19 +204,130 @@ func (c *Config) Copy() *Config {
 	nc.Servers = structs.CopySliceString(nc.Servers)
 	nc.Options = structs.CopyMapStringString(nc.Options)
 	nc.GloballyReservedPorts = structs.CopySliceInt(c.GloballyReservedPorts)
+	if c.HostNetworks != nil {
+		nc.HostNetworks = make([]*HostNetwork, len(c.HostNetworks))
+		for i, n := range c.HostNetworks {
+			nc.HostNetworks[i] = n.Copy()
+		}
+	}
+	nc.HostVolumes = structs.CopyMapStringClientHostVolumeConfig(c.HostVolumes)
+	nc.MemoryPressure = c.MemoryPressure.Copy()
 	nc.ConsulConfig = c.ConsulConfig.Copy()
 	nc.VaultConfig = c.VaultConfig.Copy()
+	if c.ConsulClusters != nil {
+		nc.ConsulClusters = make(map[string]*config.ConsulConfig, len(c.ConsulClusters))
+		for name, cc := range c.ConsulClusters {
+			nc.ConsulClusters[name] = cc.Copy()
+		}
+	}
+	if c.VaultClusters != nil {
+		nc.VaultClusters = make(map[string]*config.VaultConfig, len(c.VaultClusters))
+		for name, vc := range c.VaultClusters {
+			nc.VaultClusters[name] = vc.Copy()
+		}
+	}
+	nc.TLSConfig = c.TLSConfig.Copy()
 	return nc
 }
 
+// ConsulClusterConfig returns the configuration of the named Consul cluster.
+// The default cluster is returned for the empty name.
+func (c *Config) ConsulClusterConfig(name string) (*config.ConsulConfig, bool) {
+	if name == "" || name == structs.DefaultCluster {
+		return c.ConsulConfig, true
+	}
+	cc, ok := c.ConsulClusters[name]
+	return cc, ok
+}
+
+// VaultClusterConfig returns the configuration of the named Vault cluster.
+// The default cluster is returned for the empty name.
+func (c *Config) VaultClusterConfig(name string) (*config.VaultConfig, bool) {
+	if name == "" || name == structs.DefaultCluster {
+		return c.VaultConfig, true
+	}
+	vc, ok := c.VaultClusters[name]
+	return vc, ok
+}
+
+// HostNetwork is a named network of the host. Its address is the address of
+// the interface, if one is given, matching the CIDR.
+type HostNetwork struct {
+	// Name is the name ports of tasks use to bind to the network
+	Name string
+
+	// CIDR is the range the address of the network is in
+	CIDR string
+
+	// Interface is the name of the interface the address of the network is
+	// configured on
+	Interface string
+}
+
+func (n *HostNetwork) Copy() *HostNetwork {
+	if n == nil {
+		return nil
+	}
+	nn := new(HostNetwork)
+	*nn = *n
+	return nn
+}
+
+const (
+	// MemoryPressureActionRestart restarts the tasks of the chosen allocation
+	// in place
+	MemoryPressureActionRestart = "restart"
+
+	// MemoryPressureActionEvict fails the chosen allocation so that it is
+	// rescheduled
+	MemoryPressureActionEvict = "evict"
+
+	// MemoryPressureVictimOverLimit chooses the allocation using the most
+	// memory beyond its limit
+	MemoryPressureVictimOverLimit = "over_limit"
+
+	// MemoryPressureVictimLowestPriority chooses the allocation of the job
+	// with the lowest priority
+	MemoryPressureVictimLowestPriority = "lowest_priority"
+)
+
+// MemoryPressureConfig configures how the client relieves memory pressure on
+// the host before the kernel OOM killer picks a process to kill.
+type MemoryPressureConfig struct {
+	// Threshold is the percentage of the host memory in use above which the
+	// host is under memory pressure
+	Threshold float64
+
+	// Action is what is done to the chosen allocation
+	Action string
+
+	// Victim is how the allocation to act on is chosen
+	Victim string
+}
+
+func (m *MemoryPressureConfig) Copy() *MemoryPressureConfig {
+	if m == nil {
+		return nil
+	}
+	nm := new(MemoryPressureConfig)
+	*nm = *m
+	return nm
+}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
 		VaultConfig:             config.DefaultVaultConfig(),
 		ConsulConfig:            config.DefaultConsulConfig(),
+		TLSConfig:               &config.TLSConfig{},
 		LogOutput:               os.Stderr,
 		Region:                  "global",
 		StatsCollectionInterval: 1 * time.Second,
+		DriverMetricsLimit:      32,
+		CNIPath:                 "/opt/cni/bin",
+		CNIConfigDir:            "/opt/cni/config",
+		BridgeNetworkName:       "nomad",
+		BridgeNetworkSubnet:     "172.26.64.0/20",
 	}
 }
 
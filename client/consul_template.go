@@ -0,0 +1,629 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/client/driver/env"
+	"github.com/hashicorp/nomad/nomad/structs"
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+const (
+	// templateWaitTime is how long the blocking queries watching the Consul
+	// data of templates wait for a change
+	templateWaitTime = 5 * time.Minute
+
+	// templateRetryInterval is how long to wait before retrying to fetch the
+	// data of templates after a failure
+	templateRetryInterval = 5 * time.Second
+
+	// templateSecretRefresh is how often Vault secrets without a lease are
+	// read again
+	templateSecretRefresh = 5 * time.Minute
+
+	// templateMinSecretRefresh is the minimum interval between reads of a
+	// Vault secret
+	templateMinSecretRefresh = 30 * time.Second
+)
+
+const (
+	// templateDepKey, templateDepList, templateDepService,
+	// templateDepSecret, templateDepNomadVar and templateDepNomadService are
+	// the kinds of data templates depend on
+	templateDepKey          = "key"
+	templateDepList         = "ls"
+	templateDepService      = "service"
+	templateDepSecret       = "secret"
+	templateDepNomadVar     = "nomadVar"
+	templateDepNomadService = "nomadService"
+)
+
+// TemplateReader is used by templates to query the variables and the built-in
+// service catalog of Nomad with the workload identity of a task. Queries block
+// until the data changes from the given index if it is not zero.
+type TemplateReader interface {
+	QueryVariable(token, namespace, path string, index uint64) (*structs.VariableDecrypted, uint64, error)
+	QueryServiceRegistrations(token, namespace, name string, index uint64) ([]*structs.ServiceRegistration, uint64, error)
+}
+
+// templateNomad is used to query Nomad on behalf of a task
+type templateNomad struct {
+	reader TemplateReader
+
+	// token is the workload identity of the task and namespace the
+	// namespace of its allocation
+	token     string
+	namespace string
+}
+
+// templateKeyPair is a Consul key returned by the ls template function
+type templateKeyPair struct {
+	Key   string
+	Value string
+}
+
+// templateService is an instance of a service returned by the service and
+// nomadService template functions. Node is the name of the Consul node or the
+// ID of the Nomad node the instance runs on.
+type templateService struct {
+	Node    string
+	Address string
+	ID      string
+	Name    string
+	Tags    []string
+	Port    int
+}
+
+// templateDependency is the data fetched for a dependency of the templates
+type templateDependency struct {
+	kind  string
+	arg   string
+	data  interface{}
+	index uint64
+
+	// watching marks whether a watcher was started for the dependency
+	watching bool
+}
+
+// templateHooks are the actions the template manager takes on the task when
+// its templates are re-rendered
+type templateHooks struct {
+	// restart restarts the task with the given reason
+	restart func(reason string) error
+
+	// signal sends the signal to the task with the given reason
+	signal func(sig, reason string) error
+}
+
+// taskTemplateManager renders the templates of a task into its directory and
+// re-renders them when the Consul, Vault or Nomad data they use changes. The
+// templates use the text/template syntax with a subset of the consul-template
+// functions: key, keyOrDefault, ls, service, secret, nomadVar, nomadService
+// and env.
+type taskTemplateManager struct {
+	templates []*structs.Template
+	taskDir   string
+	taskEnv   *env.TaskEnvironment
+	consul    *consulapi.Client
+	vault     *vaultapi.Client
+	nomad     *templateNomad
+	hooks     *templateHooks
+	logger    *log.Logger
+
+	// deps is the data the templates depend on, keyed by kind and argument
+	deps     map[string]*templateDependency
+	depsLock sync.Mutex
+
+	// rendered is the last content rendered for each template
+	rendered []string
+
+	// changeCh is notified when the data of a dependency changed
+	changeCh chan struct{}
+
+	shutdownCh   chan struct{}
+	shutdown     bool
+	shutdownLock sync.Mutex
+}
+
+// newTaskTemplateManager returns a manager of the templates of a task. The
+// Vault client is nil if the task has no Vault token, and nomad is nil if the
+// task has no workload identity.
+func newTaskTemplateManager(templates []*structs.Template, taskDir string, taskEnv *env.TaskEnvironment,
+	consul *consulapi.Client, vault *vaultapi.Client, nomad *templateNomad, hooks *templateHooks,
+	logger *log.Logger) *taskTemplateManager {
+	return &taskTemplateManager{
+		templates:  templates,
+		taskDir:    taskDir,
+		taskEnv:    taskEnv,
+		consul:     consul,
+		vault:      vault,
+		nomad:      nomad,
+		hooks:      hooks,
+		logger:     logger,
+		deps:       make(map[string]*templateDependency),
+		rendered:   make([]string, len(templates)),
+		changeCh:   make(chan struct{}, 1),
+		shutdownCh: make(chan struct{}),
+	}
+}
+
+// Render renders all the templates into the task directory. It returns an
+// error if any of them fails to render.
+func (m *taskTemplateManager) Render() error {
+	for i, tmpl := range m.templates {
+		if _, err := m.render(i, tmpl); err != nil {
+			return fmt.Errorf("failed to render template %q: %v", tmpl.DestPath, err)
+		}
+	}
+	return nil
+}
+
+// Run watches the data the templates depend on and re-renders them when it
+// changes, acting on the task as per their change modes. It blocks until the
+// manager is stopped.
+func (m *taskTemplateManager) Run() {
+	m.watchDependencies()
+	for {
+		select {
+		case <-m.shutdownCh:
+			return
+		case <-m.changeCh:
+		}
+
+		var changed []*structs.Template
+		for i, tmpl := range m.templates {
+			if tmpl.Once {
+				continue
+			}
+			ok, err := m.render(i, tmpl)
+			if err != nil {
+				m.logger.Printf("[ERR] client: failed to re-render template %q: %v", tmpl.DestPath, err)
+				continue
+			}
+			if ok {
+				changed = append(changed, tmpl)
+			}
+		}
+
+		// Templates may depend on new data once re-rendered
+		m.watchDependencies()
+		m.handleChanges(changed)
+	}
+}
+
+// Stop stops watching the data of the templates
+func (m *taskTemplateManager) Stop() {
+	m.shutdownLock.Lock()
+	defer m.shutdownLock.Unlock()
+
+	if m.shutdown {
+		return
+	}
+	m.shutdown = true
+	close(m.shutdownCh)
+}
+
+// handleChanges restarts or signals the task after a random splay if the
+// changed templates require it. A restart supersedes any signal.
+func (m *taskTemplateManager) handleChanges(changed []*structs.Template) {
+	var restart bool
+	var splay time.Duration
+	signals := make(map[string]struct{})
+	var dests []string
+	for _, tmpl := range changed {
+		switch tmpl.ChangeMode {
+		case structs.TemplateChangeModeRestart:
+			restart = true
+		case structs.TemplateChangeModeSignal:
+			signals[tmpl.RestartSignal] = struct{}{}
+		default:
+			continue
+		}
+		dests = append(dests, tmpl.DestPath)
+		if tmpl.Splay > splay {
+			splay = tmpl.Splay
+		}
+	}
+	if !restart && len(signals) == 0 {
+		return
+	}
+
+	if splay > 0 {
+		select {
+		case <-time.After(time.Duration(rand.Int63n(int64(splay)))):
+		case <-m.shutdownCh:
+			return
+		}
+	}
+
+	reason := fmt.Sprintf("Template %s re-rendered", strings.Join(dests, ", "))
+	if restart {
+		if err := m.hooks.restart(reason); err != nil {
+			m.logger.Printf("[DEBUG] client: not restarting task for re-rendered templates: %v", err)
+		}
+		return
+	}
+	for sig := range signals {
+		if err := m.hooks.signal(sig, reason); err != nil {
+			m.logger.Printf("[ERR] client: failed to send %s for re-rendered templates: %v", sig, err)
+		}
+	}
+}
+
+// render renders the template and writes it to its destination if its
+// content changed. It returns whether the content changed.
+func (m *taskTemplateManager) render(i int, tmpl *structs.Template) (bool, error) {
+	source := tmpl.EmbededTmpl
+	if tmpl.SourcePath != "" {
+		path := filepath.Join(m.taskDir, m.taskEnv.ReplaceEnv(tmpl.SourcePath))
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		source = string(data)
+	}
+
+	t, err := template.New(tmpl.DestPath).Funcs(m.funcs()).Parse(source)
+	if err != nil {
+		return false, err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, nil); err != nil {
+		return false, err
+	}
+
+	dest := filepath.Join(m.taskDir, m.taskEnv.ReplaceEnv(tmpl.DestPath))
+	content := buf.String()
+	if m.rendered[i] == content {
+		if _, err := os.Stat(dest); err == nil {
+			return false, nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0777); err != nil {
+		return false, err
+	}
+	if err := ioutil.WriteFile(dest, buf.Bytes(), 0644); err != nil {
+		return false, err
+	}
+	m.rendered[i] = content
+	return true, nil
+}
+
+// funcs returns the functions available to the templates
+func (m *taskTemplateManager) funcs() template.FuncMap {
+	return template.FuncMap{
+		"key": func(key string) (string, error) {
+			data, err := m.dependency(templateDepKey, key)
+			if err != nil {
+				return "", err
+			}
+			if data == nil {
+				return "", fmt.Errorf("key %q not found", key)
+			}
+			return string(data.(*consulapi.KVPair).Value), nil
+		},
+		"keyOrDefault": func(key, def string) (string, error) {
+			data, err := m.dependency(templateDepKey, key)
+			if err != nil {
+				return "", err
+			}
+			if data == nil {
+				return def, nil
+			}
+			return string(data.(*consulapi.KVPair).Value), nil
+		},
+		"ls": func(prefix string) ([]*templateKeyPair, error) {
+			data, err := m.dependency(templateDepList, prefix)
+			if err != nil {
+				return nil, err
+			}
+			return data.([]*templateKeyPair), nil
+		},
+		"service": func(name string) ([]*templateService, error) {
+			data, err := m.dependency(templateDepService, name)
+			if err != nil {
+				return nil, err
+			}
+			return data.([]*templateService), nil
+		},
+		"secret": func(path string) (*vaultapi.Secret, error) {
+			data, err := m.dependency(templateDepSecret, path)
+			if err != nil {
+				return nil, err
+			}
+			if data == nil {
+				return nil, fmt.Errorf("secret %q not found", path)
+			}
+			return data.(*vaultapi.Secret), nil
+		},
+		"nomadVar": func(path string) (structs.VariableItems, error) {
+			data, err := m.dependency(templateDepNomadVar, path)
+			if err != nil {
+				return nil, err
+			}
+			if data == nil {
+				return nil, fmt.Errorf("variable %q not found", path)
+			}
+			return data.(structs.VariableItems), nil
+		},
+		"nomadService": func(name string) ([]*templateService, error) {
+			data, err := m.dependency(templateDepNomadService, name)
+			if err != nil {
+				return nil, err
+			}
+			return data.([]*templateService), nil
+		},
+		"env": func(name string) string {
+			return m.taskEnv.EnvMap()[name]
+		},
+	}
+}
+
+// dependency returns the data of the dependency, fetching it the first time
+// it is used
+func (m *taskTemplateManager) dependency(kind, arg string) (interface{}, error) {
+	id := kind + "/" + arg
+	m.depsLock.Lock()
+	dep, ok := m.deps[id]
+	m.depsLock.Unlock()
+	if ok {
+		return dep.data, nil
+	}
+
+	data, index, err := m.fetch(kind, arg, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	m.depsLock.Lock()
+	m.deps[id] = &templateDependency{kind: kind, arg: arg, data: data, index: index}
+	m.depsLock.Unlock()
+	return data, nil
+}
+
+// watchDependencies starts watching the dependencies not yet watched
+func (m *taskTemplateManager) watchDependencies() {
+	m.depsLock.Lock()
+	defer m.depsLock.Unlock()
+
+	for _, dep := range m.deps {
+		if !dep.watching {
+			dep.watching = true
+			go m.watch(dep)
+		}
+	}
+}
+
+// watch fetches the data of the dependency when it changes until the manager
+// is stopped. Consul and Nomad data is watched with blocking queries while
+// Vault secrets are read again before their lease expires.
+func (m *taskTemplateManager) watch(dep *templateDependency) {
+	m.depsLock.Lock()
+	index := dep.index
+	lastData := dep.data
+	m.depsLock.Unlock()
+
+	for {
+		if dep.kind == templateDepSecret {
+			select {
+			case <-time.After(secretRefreshInterval(lastData)):
+			case <-m.shutdownCh:
+				return
+			}
+		}
+
+		data, newIndex, err := m.fetch(dep.kind, dep.arg, index)
+		select {
+		case <-m.shutdownCh:
+			return
+		default:
+		}
+		if err != nil {
+			m.logger.Printf("[WARN] client: failed to fetch %s %q for templates: %v", dep.kind, dep.arg, err)
+			select {
+			case <-time.After(templateRetryInterval):
+			case <-m.shutdownCh:
+				return
+			}
+			continue
+		}
+
+		// Reset the index if it went backwards, as Consul does on a restore
+		if newIndex < index {
+			newIndex = 0
+		}
+		index = newIndex
+		if reflect.DeepEqual(data, lastData) {
+			continue
+		}
+		lastData = data
+
+		m.depsLock.Lock()
+		dep.data = data
+		dep.index = index
+		m.depsLock.Unlock()
+
+		select {
+		case m.changeCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// secretRefreshInterval returns how long to wait before reading the secret
+// again, which is half of its lease
+func secretRefreshInterval(data interface{}) time.Duration {
+	secret, ok := data.(*vaultapi.Secret)
+	if !ok || secret == nil || secret.LeaseDuration == 0 {
+		return templateSecretRefresh
+	}
+	interval := time.Duration(secret.LeaseDuration) * time.Second / 2
+	if interval < templateMinSecretRefresh {
+		return templateMinSecretRefresh
+	}
+	return interval
+}
+
+// fetch fetches the data of a dependency. Consul and Nomad queries block until
+// the data changes from the given index if it is not zero.
+func (m *taskTemplateManager) fetch(kind, arg string, index uint64) (interface{}, uint64, error) {
+	q := &consulapi.QueryOptions{WaitIndex: index}
+	if index != 0 {
+		q.WaitTime = templateWaitTime
+	}
+
+	switch kind {
+	case templateDepNomadVar, templateDepNomadService:
+		return m.fetchNomad(kind, arg, index)
+	case templateDepKey:
+		pair, meta, err := m.consul.KV().Get(arg, q)
+		if err != nil {
+			return nil, 0, err
+		}
+		if pair == nil {
+			// Keep the interface nil for a missing key
+			return nil, meta.LastIndex, nil
+		}
+		return pair, meta.LastIndex, nil
+	case templateDepList:
+		pairs, meta, err := m.consul.KV().List(arg, q)
+		if err != nil {
+			return nil, 0, err
+		}
+		prefix := strings.TrimSuffix(arg, "/") + "/"
+		list := make([]*templateKeyPair, 0, len(pairs))
+		for _, pair := range pairs {
+			key := strings.TrimPrefix(pair.Key, prefix)
+			if key == "" || strings.Contains(key, "/") {
+				continue
+			}
+			list = append(list, &templateKeyPair{Key: key, Value: string(pair.Value)})
+		}
+		return list, meta.LastIndex, nil
+	case templateDepService:
+		// The name may be prefixed by a tag, as in "tag.name"
+		name, tag := arg, ""
+		if i := strings.LastIndex(arg, "."); i != -1 {
+			tag, name = arg[:i], arg[i+1:]
+		}
+		entries, meta, err := m.consul.Health().Service(name, tag, true, q)
+		if err != nil {
+			return nil, 0, err
+		}
+		services := make([]*templateService, 0, len(entries))
+		for _, entry := range entries {
+			s := &templateService{
+				Node:    entry.Node.Node,
+				Address: entry.Service.Address,
+				ID:      entry.Service.ID,
+				Name:    entry.Service.Service,
+				Tags:    entry.Service.Tags,
+				Port:    entry.Service.Port,
+			}
+			if s.Address == "" {
+				s.Address = entry.Node.Address
+			}
+			services = append(services, s)
+		}
+		sort.Slice(services, func(i, j int) bool {
+			if services[i].Node != services[j].Node {
+				return services[i].Node < services[j].Node
+			}
+			return services[i].ID < services[j].ID
+		})
+		return services, meta.LastIndex, nil
+	case templateDepSecret:
+		if m.vault == nil {
+			return nil, 0, fmt.Errorf("task has no Vault token to read secrets with")
+		}
+		secret, err := m.vault.Logical().Read(arg)
+		if err != nil {
+			return nil, 0, err
+		}
+		if secret == nil {
+			return nil, 0, nil
+		}
+		return secret, 0, nil
+	default:
+		return nil, 0, fmt.Errorf("unknown template dependency %q", kind)
+	}
+}
+
+// fetchNomad fetches the data of a dependency on a Nomad variable or service
+// using the workload identity of the task
+func (m *taskTemplateManager) fetchNomad(kind, arg string, index uint64) (interface{}, uint64, error) {
+	if m.nomad == nil || m.nomad.reader == nil || m.nomad.token == "" {
+		return nil, 0, fmt.Errorf("task has no workload identity to query Nomad with")
+	}
+
+	switch kind {
+	case templateDepNomadVar:
+		variable, newIndex, err := m.nomad.reader.QueryVariable(m.nomad.token, m.nomad.namespace, arg, index)
+		if err != nil {
+			return nil, 0, err
+		}
+		if variable == nil {
+			// Keep the interface nil for a missing variable
+			return nil, newIndex, nil
+		}
+		return variable.Items, newIndex, nil
+	default:
+		// The name may be prefixed by a tag, as in "tag.name"
+		name, tag := arg, ""
+		if i := strings.LastIndex(arg, "."); i != -1 {
+			tag, name = arg[:i], arg[i+1:]
+		}
+		regs, newIndex, err := m.nomad.reader.QueryServiceRegistrations(m.nomad.token, m.nomad.namespace, name, index)
+		if err != nil {
+			return nil, 0, err
+		}
+		services := make([]*templateService, 0, len(regs))
+		for _, reg := range regs {
+			if reg.Health != structs.ServiceRegistrationHealthPassing {
+				continue
+			}
+			if tag != "" && !hasTag(reg.Tags, tag) {
+				continue
+			}
+			services = append(services, &templateService{
+				Node:    reg.NodeID,
+				Address: reg.Address,
+				ID:      reg.ID,
+				Name:    reg.ServiceName,
+				Tags:    reg.Tags,
+				Port:    reg.Port,
+			})
+		}
+		sort.Slice(services, func(i, j int) bool {
+			if services[i].Node != services[j].Node {
+				return services[i].Node < services[j].Node
+			}
+			return services[i].ID < services[j].ID
+		})
+		return services, newIndex, nil
+	}
+}
+
+// hasTag returns whether the tag is in the list of tags
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
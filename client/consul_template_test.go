@@ -0,0 +1,348 @@
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/client/driver/env"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// testConsulKV serves the Consul KV endpoint from a map. Queries waiting on
+// the current index return after a short delay, as blocking queries would.
+type testConsulKV struct {
+	index uint64
+	kv    map[string]string
+	l     sync.Mutex
+}
+
+func (c *testConsulKV) Set(key, value string) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	c.index++
+	c.kv[key] = value
+}
+
+func (c *testConsulKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.l.Lock()
+	index := c.index
+	c.l.Unlock()
+	if wait, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); wait >= index {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(c.index, 10))
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	value, ok := c.kv[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode([]*consulapi.KVPair{{Key: key, Value: []byte(value), ModifyIndex: c.index}})
+}
+
+// testTemplateReader serves Nomad variables and service registrations from
+// maps. Queries waiting on the current index return after a short delay, as
+// blocking queries would.
+type testTemplateReader struct {
+	index     uint64
+	variables map[string]structs.VariableItems
+	services  map[string][]*structs.ServiceRegistration
+	l         sync.Mutex
+}
+
+func (r *testTemplateReader) SetVariable(path string, items structs.VariableItems) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	r.index++
+	r.variables[path] = items
+}
+
+func (r *testTemplateReader) wait(index uint64) {
+	r.l.Lock()
+	current := r.index
+	r.l.Unlock()
+	if index >= current {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (r *testTemplateReader) QueryVariable(token, namespace, path string, index uint64) (*structs.VariableDecrypted, uint64, error) {
+	if token != "identity" || namespace != structs.DefaultNamespace {
+		return nil, 0, structs.ErrPermissionDenied
+	}
+	r.wait(index)
+
+	r.l.Lock()
+	defer r.l.Unlock()
+	items, ok := r.variables[path]
+	if !ok {
+		return nil, r.index, nil
+	}
+	return &structs.VariableDecrypted{Path: path, Items: items}, r.index, nil
+}
+
+func (r *testTemplateReader) QueryServiceRegistrations(token, namespace, name string, index uint64) ([]*structs.ServiceRegistration, uint64, error) {
+	if token != "identity" || namespace != structs.DefaultNamespace {
+		return nil, 0, structs.ErrPermissionDenied
+	}
+	r.wait(index)
+
+	r.l.Lock()
+	defer r.l.Unlock()
+	return r.services[name], r.index, nil
+}
+
+func testTemplateManager(t *testing.T, kv *testConsulKV, templates []*structs.Template, hooks *templateHooks) (*taskTemplateManager, string, func()) {
+	return testNomadTemplateManager(t, kv, nil, templates, hooks)
+}
+
+func testNomadTemplateManager(t *testing.T, kv *testConsulKV, reader TemplateReader, templates []*structs.Template, hooks *templateHooks) (*taskTemplateManager, string, func()) {
+	ts := httptest.NewServer(kv)
+	conf := consulapi.DefaultConfig()
+	conf.Address = ts.Listener.Addr().String()
+	consul, err := consulapi.NewClient(conf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	taskDir, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	taskEnv := env.NewTaskEnvironment(mock.Node()).SetTaskName("web").Build()
+
+	var nomad *templateNomad
+	if reader != nil {
+		nomad = &templateNomad{reader: reader, token: "identity", namespace: structs.DefaultNamespace}
+	}
+
+	m := newTaskTemplateManager(templates, taskDir, taskEnv, consul, nil, nomad, hooks, testLogger())
+	return m, taskDir, func() {
+		m.Stop()
+		ts.Close()
+		os.RemoveAll(taskDir)
+	}
+}
+
+func TestTaskTemplateManager_Render(t *testing.T) {
+	kv := &testConsulKV{kv: map[string]string{"app/port": "8080"}}
+	templates := []*structs.Template{
+		{
+			EmbededTmpl: `port={{ key "app/port" }} host={{ keyOrDefault "app/host" "localhost" }} task={{ env "NOMAD_TASK_NAME" }}`,
+			DestPath:    "local/app.conf",
+			ChangeMode:  structs.TemplateChangeModeNoop,
+		},
+	}
+	m, taskDir, cleanup := testTemplateManager(t, kv, templates, &templateHooks{})
+	defer cleanup()
+
+	if err := m.Render(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := ioutil.ReadFile(filepath.Join(taskDir, "local", "app.conf"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(out) != "port=8080 host=localhost task=web" {
+		t.Fatalf("bad: %q", out)
+	}
+}
+
+func TestTaskTemplateManager_Render_MissingKey(t *testing.T) {
+	kv := &testConsulKV{kv: map[string]string{}}
+	templates := []*structs.Template{
+		{
+			EmbededTmpl: `{{ key "app/port" }}`,
+			DestPath:    "local/app.conf",
+			ChangeMode:  structs.TemplateChangeModeNoop,
+		},
+	}
+	m, _, cleanup := testTemplateManager(t, kv, templates, &templateHooks{})
+	defer cleanup()
+
+	if err := m.Render(); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected missing key error, got: %v", err)
+	}
+}
+
+func TestTaskTemplateManager_Rerender(t *testing.T) {
+	kv := &testConsulKV{kv: map[string]string{"app/port": "8080"}}
+	templates := []*structs.Template{
+		{
+			EmbededTmpl: `port={{ key "app/port" }}`,
+			DestPath:    "local/app.conf",
+			ChangeMode:  structs.TemplateChangeModeRestart,
+		},
+	}
+	restartCh := make(chan string, 1)
+	hooks := &templateHooks{
+		restart: func(reason string) error {
+			restartCh <- reason
+			return nil
+		},
+	}
+	m, taskDir, cleanup := testTemplateManager(t, kv, templates, hooks)
+	defer cleanup()
+
+	if err := m.Render(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go m.Run()
+
+	kv.Set("app/port", "9090")
+	select {
+	case reason := <-restartCh:
+		if !strings.Contains(reason, "local/app.conf") {
+			t.Fatalf("bad: %q", reason)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("task not restarted")
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join(taskDir, "local", "app.conf"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(out) != "port=9090" {
+		t.Fatalf("bad: %q", out)
+	}
+}
+
+func TestTaskTemplateManager_Render_Nomad(t *testing.T) {
+	reader := &testTemplateReader{
+		variables: map[string]structs.VariableItems{
+			"nomad/jobs/web": {"db_password": "hunter2"},
+		},
+		services: map[string][]*structs.ServiceRegistration{
+			"db": {
+				{
+					ID:          "b",
+					ServiceName: "db",
+					NodeID:      "node1",
+					Address:     "10.0.0.2",
+					Port:        5432,
+					Tags:        []string{"primary"},
+					Health:      structs.ServiceRegistrationHealthPassing,
+				},
+				{
+					ID:          "a",
+					ServiceName: "db",
+					NodeID:      "node1",
+					Address:     "10.0.0.1",
+					Port:        5432,
+					Tags:        []string{"replica"},
+					Health:      structs.ServiceRegistrationHealthPassing,
+				},
+				{
+					ID:          "c",
+					ServiceName: "db",
+					NodeID:      "node2",
+					Address:     "10.0.0.3",
+					Port:        5432,
+					Health:      structs.ServiceRegistrationHealthCritical,
+				},
+			},
+		},
+	}
+	templates := []*structs.Template{
+		{
+			EmbededTmpl: `password={{ with nomadVar "nomad/jobs/web" }}{{ .db_password }}{{ end }}` +
+				`{{ range nomadService "db" }} db={{ .Address }}:{{ .Port }}{{ end }}` +
+				`{{ range nomadService "primary.db" }} primary={{ .Address }}{{ end }}`,
+			DestPath:   "local/app.conf",
+			ChangeMode: structs.TemplateChangeModeNoop,
+		},
+	}
+	m, taskDir, cleanup := testNomadTemplateManager(t, &testConsulKV{kv: map[string]string{}}, reader, templates, &templateHooks{})
+	defer cleanup()
+
+	if err := m.Render(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := ioutil.ReadFile(filepath.Join(taskDir, "local", "app.conf"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := "password=hunter2 db=10.0.0.1:5432 db=10.0.0.2:5432 primary=10.0.0.2"
+	if string(out) != expected {
+		t.Fatalf("got %q; want %q", out, expected)
+	}
+}
+
+func TestTaskTemplateManager_Render_NomadWithoutIdentity(t *testing.T) {
+	templates := []*structs.Template{
+		{
+			EmbededTmpl: `{{ with nomadVar "nomad/jobs/web" }}{{ .db_password }}{{ end }}`,
+			DestPath:    "local/app.conf",
+			ChangeMode:  structs.TemplateChangeModeNoop,
+		},
+	}
+	m, _, cleanup := testTemplateManager(t, &testConsulKV{kv: map[string]string{}}, templates, &templateHooks{})
+	defer cleanup()
+
+	if err := m.Render(); err == nil || !strings.Contains(err.Error(), "workload identity") {
+		t.Fatalf("expected missing identity error, got: %v", err)
+	}
+}
+
+func TestTaskTemplateManager_Rerender_NomadVar(t *testing.T) {
+	reader := &testTemplateReader{
+		variables: map[string]structs.VariableItems{
+			"nomad/jobs/web": {"db_password": "hunter2"},
+		},
+	}
+	templates := []*structs.Template{
+		{
+			EmbededTmpl:   `password={{ with nomadVar "nomad/jobs/web" }}{{ .db_password }}{{ end }}`,
+			DestPath:      "local/app.conf",
+			ChangeMode:    structs.TemplateChangeModeSignal,
+			RestartSignal: "SIGHUP",
+		},
+	}
+	signalCh := make(chan string, 1)
+	hooks := &templateHooks{
+		signal: func(sig, reason string) error {
+			signalCh <- sig
+			return nil
+		},
+	}
+	m, taskDir, cleanup := testNomadTemplateManager(t, &testConsulKV{kv: map[string]string{}}, reader, templates, hooks)
+	defer cleanup()
+
+	if err := m.Render(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go m.Run()
+
+	reader.SetVariable("nomad/jobs/web", structs.VariableItems{"db_password": "correcthorse"})
+	select {
+	case sig := <-signalCh:
+		if sig != "SIGHUP" {
+			t.Fatalf("bad: %q", sig)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("task not signaled")
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join(taskDir, "local", "app.conf"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(out) != "password=correcthorse" {
+		t.Fatalf("bad: %q", out)
+	}
+}
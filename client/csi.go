@@ -0,0 +1,149 @@
+package client
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/nomad/client/csimanager"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/plugins/csi"
+)
+
+const (
+	// csiFingerprintInterval is the interval at which the CSI plugins of the
+	// node are fingerprinted to track their health
+	csiFingerprintInterval = 15 * time.Second
+)
+
+// CSIManager is used by the allocations running CSI plugins to register
+// them, and by the allocations requesting CSI volumes to mount them
+type CSIManager interface {
+	CSIPluginDir(config *structs.TaskCSIPluginConfig) string
+	RegisterCSIPlugin(allocID string, config *structs.TaskCSIPluginConfig) error
+	DeregisterCSIPlugin(allocID string, config *structs.TaskCSIPluginConfig)
+	MountCSIVolume(alloc *structs.Allocation, req *structs.VolumeRequest) (*structs.CSIVolume, string, error)
+	UnmountCSIVolume(alloc *structs.Allocation, vol *structs.CSIVolume) error
+}
+
+// setupCSI creates the manager of the CSI plugins, which keep their plugin
+// dirs in the state dir, and fingerprints the plugins periodically
+func (c *Client) setupCSI() {
+	c.csi = csimanager.NewManager(filepath.Join(c.config.StateDir, "csi"), c.logger)
+	go c.fingerprintCSIPeriodic()
+}
+
+// fingerprintCSI updates the CSI plugins of the node. Changes are sent to the
+// servers once detected by watchNodeUpdates.
+func (c *Client) fingerprintCSI() {
+	controllers, nodes := c.csi.Fingerprint()
+	c.configLock.Lock()
+	c.config.Node.CSIControllerPlugins = controllers
+	c.config.Node.CSINodePlugins = nodes
+	c.configLock.Unlock()
+}
+
+// fingerprintCSIPeriodic fingerprints the CSI plugins of the node
+// periodically to track their health
+func (c *Client) fingerprintCSIPeriodic() {
+	for {
+		select {
+		case <-time.After(csiFingerprintInterval):
+			c.fingerprintCSI()
+		case <-c.shutdownCh:
+			return
+		}
+	}
+}
+
+// CSIPluginDir returns the directory of the host mounted into the plugin task
+func (c *Client) CSIPluginDir(config *structs.TaskCSIPluginConfig) string {
+	return c.csi.PluginDir(config)
+}
+
+// RegisterCSIPlugin registers the plugin task of the allocation. The plugin
+// is fingerprinted once its task serves on the socket of its plugin dir.
+func (c *Client) RegisterCSIPlugin(allocID string, config *structs.TaskCSIPluginConfig) error {
+	return c.csi.Register(allocID, config)
+}
+
+// DeregisterCSIPlugin deregisters the plugin task of the allocation
+func (c *Client) DeregisterCSIPlugin(allocID string, config *structs.TaskCSIPluginConfig) {
+	c.csi.Deregister(allocID, config)
+	c.fingerprintCSI()
+}
+
+// CSIControllerCall calls the controller of the plugin running on the node on
+// behalf of the servers
+func (c *Client) CSIControllerCall(pluginID string, f func(p csi.Plugin) error) error {
+	return c.csi.ControllerCall(pluginID, f)
+}
+
+// ExpandCSIVolume expands the file system of the volume published on the
+// node on behalf of the servers, once its controller expanded it
+func (c *Client) ExpandCSIVolume(vol *structs.CSIVolume) error {
+	return c.csi.ExpandVolume(vol)
+}
+
+// MountCSIVolume claims the volume requested by the allocation and mounts it
+// with the node plugin. It returns the claimed volume and the path of the
+// host it is mounted at.
+func (c *Client) MountCSIVolume(alloc *structs.Allocation, req *structs.VolumeRequest) (*structs.CSIVolume, string, error) {
+	mode := structs.CSIVolumeClaimWrite
+	if req.ReadOnly {
+		mode = structs.CSIVolumeClaimRead
+	}
+	args := structs.CSIVolumeClaimRequest{
+		VolumeID:     req.Source,
+		AllocationID: alloc.ID,
+		Mode:         mode,
+		NodeID:       c.Node().ID,
+		SecretID:     c.Node().SecretID,
+		WriteRequest: structs.WriteRequest{
+			Region:    c.Region(),
+			Namespace: alloc.Namespace,
+		},
+	}
+	var resp structs.CSIVolumeClaimResponse
+	if err := c.RPC("CSIVolume.Claim", &args, &resp); err != nil {
+		return nil, "", fmt.Errorf("failed to claim volume %q: %v", req.Source, err)
+	}
+
+	path, err := c.csi.MountVolume(resp.Volume, alloc.ID, req.ReadOnly, resp.PublishContext)
+	if err != nil {
+		if err := c.releaseCSIVolume(alloc, resp.Volume); err != nil {
+			c.logger.Printf("[ERR] client: %v", err)
+		}
+		return nil, "", err
+	}
+	return resp.Volume, path, nil
+}
+
+// UnmountCSIVolume unmounts the volume of the allocation with the node plugin
+// and releases its claim
+func (c *Client) UnmountCSIVolume(alloc *structs.Allocation, vol *structs.CSIVolume) error {
+	if err := c.csi.UnmountVolume(vol, alloc.ID); err != nil {
+		return err
+	}
+	return c.releaseCSIVolume(alloc, vol)
+}
+
+// releaseCSIVolume releases the claim of the allocation on the volume
+func (c *Client) releaseCSIVolume(alloc *structs.Allocation, vol *structs.CSIVolume) error {
+	args := structs.CSIVolumeClaimRequest{
+		VolumeID:     vol.ID,
+		AllocationID: alloc.ID,
+		Mode:         structs.CSIVolumeClaimRelease,
+		NodeID:       c.Node().ID,
+		SecretID:     c.Node().SecretID,
+		WriteRequest: structs.WriteRequest{
+			Region:    c.Region(),
+			Namespace: alloc.Namespace,
+		},
+	}
+	var resp structs.CSIVolumeClaimResponse
+	if err := c.RPC("CSIVolume.Claim", &args, &resp); err != nil {
+		return fmt.Errorf("failed to release claim of volume %q: %v", vol.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,426 @@
+// Package csimanager tracks the CSI plugins running on the client. It
+// fingerprints the plugins and mounts the CSI volumes of the allocations
+// with the node plugins.
+package csimanager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/plugins/csi"
+)
+
+const (
+	// stagingDir is the directory of the plugin dir volumes are staged in,
+	// once per node
+	stagingDir = "staging"
+
+	// perAllocDir is the directory of the plugin dir volumes are published
+	// in, once per allocation
+	perAllocDir = "per-alloc"
+)
+
+// plugin is a plugin task running on the node
+type plugin struct {
+	allocID string
+	config  *structs.TaskCSIPluginConfig
+
+	// dir is the plugin dir on the host, which is mounted into the task at
+	// config.MountDir
+	dir string
+
+	// client is the connection to the plugin. It is nil until the plugin is
+	// first reached, and reset when a call fails.
+	client *csi.Client
+
+	// info is the plugin info last fingerprinted
+	info *csi.PluginInfo
+}
+
+// call runs f with the client of the plugin, connecting to the plugin if
+// needed. The connection is closed if the call fails so that the next call
+// reconnects, such as after the plugin task restarted.
+func (p *plugin) call(f func(c *csi.Client) error) error {
+	if p.client == nil {
+		c, err := csi.NewClient(filepath.Join(p.dir, csi.SocketName))
+		if err != nil {
+			return fmt.Errorf("failed to connect to plugin: %v", err)
+		}
+		p.client = c
+	}
+	err := f(p.client)
+	if err != nil && err != csi.ErrNotSupported {
+		p.client.Close()
+		p.client = nil
+	}
+	return err
+}
+
+// path returns the path within the plugin task of a path relative to the
+// plugin dir
+func (p *plugin) path(rel string) string {
+	return filepath.Join(p.config.MountDir, rel)
+}
+
+// Manager tracks the CSI plugins registered by the allocations running them
+type Manager struct {
+	logger *log.Logger
+
+	// dir is the directory the plugin dirs are created in
+	dir string
+
+	// plugins are the registered plugins, keyed by type and ID
+	plugins map[string]*plugin
+	l       sync.Mutex
+}
+
+// NewManager returns a manager creating the plugin dirs in the directory
+func NewManager(dir string, logger *log.Logger) *Manager {
+	return &Manager{
+		logger:  logger,
+		dir:     dir,
+		plugins: make(map[string]*plugin),
+	}
+}
+
+// pluginKey returns the key of a plugin in the registered plugins
+func pluginKey(typ, id string) string {
+	return typ + "/" + id
+}
+
+// PluginDir returns the directory of the host mounted into the plugin task
+func (m *Manager) PluginDir(config *structs.TaskCSIPluginConfig) string {
+	return filepath.Join(m.dir, config.Type, config.ID)
+}
+
+// Register registers the plugin task of the allocation and creates its
+// plugin dir. The plugin dir is made a shared mount, so that the volumes the
+// plugin mounts below it from within its task are visible on the host. A
+// plugin registered by another allocation is replaced.
+func (m *Manager) Register(allocID string, config *structs.TaskCSIPluginConfig) error {
+	dir := m.PluginDir(config)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create plugin dir %q: %v", dir, err)
+	}
+	if err := makeShared(dir); err != nil {
+		m.logger.Printf("[WARN] client.csi: failed to make plugin dir %q a shared mount, "+
+			"volumes mounted by plugin %q won't be visible to tasks: %v", dir, config.ID, err)
+	}
+
+	m.l.Lock()
+	defer m.l.Unlock()
+	key := pluginKey(config.Type, config.ID)
+	if existing, ok := m.plugins[key]; ok {
+		if existing.allocID == allocID {
+			return nil
+		}
+		m.logger.Printf("[WARN] client.csi: %s plugin %q of alloc %q replaced by alloc %q",
+			config.Type, config.ID, existing.allocID, allocID)
+		if existing.client != nil {
+			existing.client.Close()
+		}
+	}
+	m.plugins[key] = &plugin{
+		allocID: allocID,
+		config:  config.Copy(),
+		dir:     dir,
+	}
+	return nil
+}
+
+// Deregister deregisters the plugin task of the allocation. It is a no-op if
+// the plugin was registered again by another allocation.
+func (m *Manager) Deregister(allocID string, config *structs.TaskCSIPluginConfig) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	key := pluginKey(config.Type, config.ID)
+	p, ok := m.plugins[key]
+	if !ok || p.allocID != allocID {
+		return
+	}
+	if p.client != nil {
+		p.client.Close()
+	}
+	delete(m.plugins, key)
+}
+
+// Fingerprint probes the registered plugins and returns the fingerprints of
+// the controller and node plugins, keyed by plugin ID. Monolith plugins are
+// both.
+func (m *Manager) Fingerprint() (controllers, nodes map[string]*structs.CSIInfo) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for _, p := range m.plugins {
+		info := m.fingerprint(p)
+		if p.config.Type != structs.CSIPluginTypeNode {
+			if controllers == nil {
+				controllers = make(map[string]*structs.CSIInfo)
+			}
+			controller := info.Copy()
+			controller.NodeInfo = nil
+			controllers[p.config.ID] = controller
+		}
+		if p.config.Type != structs.CSIPluginTypeController {
+			if nodes == nil {
+				nodes = make(map[string]*structs.CSIInfo)
+			}
+			nodes[p.config.ID] = info
+		}
+	}
+	return controllers, nodes
+}
+
+// fingerprint probes the plugin. The lock must be held.
+func (m *Manager) fingerprint(p *plugin) *structs.CSIInfo {
+	info := &structs.CSIInfo{
+		PluginID: p.config.ID,
+		AllocID:  p.allocID,
+	}
+
+	var pluginInfo *csi.PluginInfo
+	var nodeInfo *csi.NodeInfo
+	err := p.call(func(c *csi.Client) error {
+		var err error
+		if pluginInfo, err = c.PluginInfo(); err != nil {
+			return fmt.Errorf("failed to get plugin info: %v", err)
+		}
+		if err := c.Probe(); err != nil {
+			return fmt.Errorf("plugin not ready: %v", err)
+		}
+		if p.config.Type != structs.CSIPluginTypeController {
+			if nodeInfo, err = c.NodeGetInfo(); err != nil {
+				return fmt.Errorf("failed to get node info: %v", err)
+			}
+		}
+		return nil
+	})
+	if pluginInfo != nil {
+		p.info = pluginInfo
+		info.Provider = pluginInfo.Name
+		info.ProviderVersion = pluginInfo.Version
+		info.RequiresControllerPlugin = pluginInfo.PublishUnpublishVolume
+		info.RequiresTopologies = pluginInfo.VolumeAccessibilityConstraints
+	}
+	if nodeInfo != nil {
+		info.NodeInfo = &structs.CSINodeInfo{
+			ID:                      nodeInfo.NodeID,
+			MaxVolumes:              nodeInfo.MaxVolumes,
+			AccessibleTopology:      structsTopology(nodeInfo.AccessibleTopology),
+			RequiresNodeStageVolume: pluginInfo.StageUnstageVolume,
+		}
+	}
+	if err != nil {
+		info.HealthDescription = err.Error()
+		return info
+	}
+	info.Healthy = true
+	info.HealthDescription = "healthy"
+	return info
+}
+
+// structsTopology converts a topology of the plugin
+func structsTopology(t *csi.Topology) *structs.CSITopology {
+	if t == nil {
+		return nil
+	}
+	return &structs.CSITopology{Segments: structs.CopyMapStringString(t.Segments)}
+}
+
+// controllerLocked returns the controller of the plugin. The lock must be
+// held.
+func (m *Manager) controllerLocked(pluginID string) (*plugin, error) {
+	for _, typ := range []string{structs.CSIPluginTypeController, structs.CSIPluginTypeMonolith} {
+		if p, ok := m.plugins[pluginKey(typ, pluginID)]; ok {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no controller of CSI plugin %q running on the node", pluginID)
+}
+
+// nodeLocked returns the node plugin of the plugin. The lock must be held.
+func (m *Manager) nodeLocked(pluginID string) (*plugin, error) {
+	for _, typ := range []string{structs.CSIPluginTypeNode, structs.CSIPluginTypeMonolith} {
+		if p, ok := m.plugins[pluginKey(typ, pluginID)]; ok {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no node plugin of CSI plugin %q running on the node", pluginID)
+}
+
+// ControllerCall calls the controller of the plugin
+func (m *Manager) ControllerCall(pluginID string, f func(p csi.Plugin) error) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+	p, err := m.controllerLocked(pluginID)
+	if err != nil {
+		return err
+	}
+	return p.call(func(c *csi.Client) error {
+		return f(c)
+	})
+}
+
+// capability returns the capability the volume is used with
+func capability(vol *structs.CSIVolume) *csi.VolumeCapability {
+	c := &csi.VolumeCapability{
+		AccessMode:     vol.AccessMode,
+		AttachmentMode: vol.AttachmentMode,
+	}
+	if vol.MountOptions != nil {
+		c.FSType = vol.MountOptions.FSType
+		c.MountFlags = vol.MountOptions.MountFlags
+	}
+	return c
+}
+
+// MountVolume stages the volume on the node if the node plugin requires it,
+// and publishes it for the allocation. It returns the path of the host the
+// volume is published at. The publish context is the one returned by the
+// controller when publishing the volume to the node.
+func (m *Manager) MountVolume(vol *structs.CSIVolume, allocID string, readOnly bool,
+	publishContext map[string]string) (string, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	p, err := m.nodeLocked(vol.PluginID)
+	if err != nil {
+		return "", err
+	}
+
+	var staging string
+	if p.info != nil && p.info.StageUnstageVolume {
+		staging = p.path(filepath.Join(stagingDir, vol.ID))
+		err := p.call(func(c *csi.Client) error {
+			return c.NodeStageVolume(&csi.NodeStageVolumeRequest{
+				ExternalID:     vol.ExternalID,
+				PublishContext: publishContext,
+				StagingPath:    staging,
+				Capability:     capability(vol),
+				Secrets:        vol.Secrets,
+				Context:        vol.Context,
+			})
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to stage volume %q: %v", vol.ID, err)
+		}
+	}
+
+	target := filepath.Join(perAllocDir, allocID, vol.ID)
+	err = p.call(func(c *csi.Client) error {
+		return c.NodePublishVolume(&csi.NodePublishVolumeRequest{
+			ExternalID:     vol.ExternalID,
+			PublishContext: publishContext,
+			StagingPath:    staging,
+			TargetPath:     p.path(target),
+			Capability:     capability(vol),
+			ReadOnly:       readOnly,
+			Secrets:        vol.Secrets,
+			Context:        vol.Context,
+		})
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to publish volume %q: %v", vol.ID, err)
+	}
+	return filepath.Join(p.dir, target), nil
+}
+
+// UnmountVolume unpublishes the volume of the allocation, and unstages it
+// from the node once no other allocation of the node has it published
+func (m *Manager) UnmountVolume(vol *structs.CSIVolume, allocID string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+	p, err := m.nodeLocked(vol.PluginID)
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(perAllocDir, allocID, vol.ID)
+	err = p.call(func(c *csi.Client) error {
+		return c.NodeUnpublishVolume(&csi.NodeUnpublishVolumeRequest{
+			ExternalID: vol.ExternalID,
+			TargetPath: p.path(target),
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unpublish volume %q: %v", vol.ID, err)
+	}
+	os.Remove(filepath.Join(p.dir, target))
+	os.Remove(filepath.Join(p.dir, perAllocDir, allocID))
+
+	if p.info == nil || !p.info.StageUnstageVolume || published(p.dir, vol.ID) {
+		return nil
+	}
+	err = p.call(func(c *csi.Client) error {
+		return c.NodeUnstageVolume(&csi.NodeUnstageVolumeRequest{
+			ExternalID:  vol.ExternalID,
+			StagingPath: p.path(filepath.Join(stagingDir, vol.ID)),
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unstage volume %q: %v", vol.ID, err)
+	}
+	return nil
+}
+
+// ExpandVolume expands the file system of the volume to its requested
+// capacity with the node plugin, once the controller expanded the volume.
+// Volumes not published on the node are expanded when next published.
+func (m *Manager) ExpandVolume(vol *structs.CSIVolume) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+	p, err := m.nodeLocked(vol.PluginID)
+	if err != nil {
+		return err
+	}
+
+	target := publishedTarget(p.dir, vol.ID)
+	if target == "" {
+		return nil
+	}
+	var staging string
+	if p.info != nil && p.info.StageUnstageVolume {
+		staging = p.path(filepath.Join(stagingDir, vol.ID))
+	}
+	err = p.call(func(c *csi.Client) error {
+		_, err := c.NodeExpandVolume(&csi.NodeExpandVolumeRequest{
+			ExternalID:  vol.ExternalID,
+			VolumePath:  p.path(target),
+			StagingPath: staging,
+			CapacityMin: vol.RequestedCapacityMin,
+			CapacityMax: vol.RequestedCapacityMax,
+			Capability:  capability(vol),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to expand volume %q: %v", vol.ID, err)
+	}
+	return nil
+}
+
+// published returns whether the volume is published for an allocation in
+// the plugin dir
+func published(dir, volumeID string) bool {
+	return publishedTarget(dir, volumeID) != ""
+}
+
+// publishedTarget returns the path relative to the plugin dir of a target
+// the volume is published at, or an empty string if it isn't published
+func publishedTarget(dir, volumeID string) string {
+	allocs, err := ioutil.ReadDir(filepath.Join(dir, perAllocDir))
+	if err != nil {
+		return ""
+	}
+	for _, alloc := range allocs {
+		target := filepath.Join(perAllocDir, alloc.Name(), volumeID)
+		if _, err := os.Stat(filepath.Join(dir, target)); err == nil {
+			return target
+		}
+	}
+	return ""
+}
@@ -0,0 +1,172 @@
+package csimanager
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/plugins/csi"
+)
+
+// testManager returns a manager with a monolith plugin served by a
+// TestPlugin. The mount dir of the plugin is its plugin dir, as the
+// TestPlugin runs on the host.
+func testManager(t *testing.T) (*Manager, *csi.TestPlugin, func()) {
+	dir, err := ioutil.TempDir("", "csimanager")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	m := NewManager(dir, log.New(os.Stderr, "", log.LstdFlags))
+
+	config := &structs.TaskCSIPluginConfig{
+		ID:   "ebs",
+		Type: structs.CSIPluginTypeMonolith,
+	}
+	config.MountDir = m.PluginDir(config)
+	if err := m.Register("alloc", config); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("err: %v", err)
+	}
+	cleanup := func() {
+		unmountShared(config.MountDir)
+		os.RemoveAll(dir)
+	}
+
+	impl := csi.NewTestPlugin()
+	go csi.Serve(impl, filepath.Join(config.MountDir, csi.SocketName))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, nodes := m.Fingerprint()
+		if nodes["ebs"].Healthy {
+			break
+		}
+		if time.Now().After(deadline) {
+			cleanup()
+			t.Fatalf("plugin unhealthy: %s", nodes["ebs"].HealthDescription)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return m, impl, cleanup
+}
+
+func TestManager_Fingerprint(t *testing.T) {
+	m, impl, cleanup := testManager(t)
+	defer cleanup()
+
+	controllers, nodes := m.Fingerprint()
+	controller, ok := controllers["ebs"]
+	if !ok {
+		t.Fatalf("missing controller: %#v", controllers)
+	}
+	if controller.AllocID != "alloc" || controller.Provider != "test" ||
+		!controller.RequiresControllerPlugin || controller.NodeInfo != nil {
+		t.Fatalf("bad: %#v", controller)
+	}
+	node := nodes["ebs"]
+	if node.NodeInfo == nil || node.NodeInfo.ID != "test-node" || node.NodeInfo.MaxVolumes != 8 ||
+		!node.NodeInfo.RequiresNodeStageVolume {
+		t.Fatalf("bad: %#v", node.NodeInfo)
+	}
+
+	// Plugins failing their probe are unhealthy
+	impl.ProbeErr = csi.ErrNotSupported
+	_, nodes = m.Fingerprint()
+	if nodes["ebs"].Healthy {
+		t.Fatalf("expected unhealthy plugin")
+	}
+
+	// Deregistering the plugin of another allocation is a no-op
+	config := &structs.TaskCSIPluginConfig{ID: "ebs", Type: structs.CSIPluginTypeMonolith}
+	m.Deregister("other", config)
+	if controllers, _ = m.Fingerprint(); len(controllers) != 1 {
+		t.Fatalf("bad: %#v", controllers)
+	}
+	m.Deregister("alloc", config)
+	if controllers, nodes = m.Fingerprint(); len(controllers) != 0 || len(nodes) != 0 {
+		t.Fatalf("bad: %#v %#v", controllers, nodes)
+	}
+}
+
+func TestManager_MountVolume(t *testing.T) {
+	m, impl, cleanup := testManager(t)
+	defer cleanup()
+
+	vol := &structs.CSIVolume{
+		ID:             "data",
+		ExternalID:     "vol-data",
+		PluginID:       "ebs",
+		AccessMode:     structs.CSIVolumeAccessModeMultiNodeReader,
+		AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+	}
+	publishContext := map[string]string{"device": "/dev/vol-data"}
+
+	path1, err := m.MountVolume(vol, "alloc1", true, publishContext)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	path2, err := m.MountVolume(vol, "alloc2", true, publishContext)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if impl.Target(path1) != vol.ExternalID || impl.Target(path2) != vol.ExternalID {
+		t.Fatalf("volume not published")
+	}
+	if impl.Staged(vol.ExternalID) == "" {
+		t.Fatalf("volume not staged")
+	}
+
+	// The volume stays staged until no allocation has it published
+	if err := m.UnmountVolume(vol, "alloc1"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if impl.Target(path1) != "" || impl.Staged(vol.ExternalID) == "" {
+		t.Fatalf("bad: target %q, staged %q", impl.Target(path1), impl.Staged(vol.ExternalID))
+	}
+	if err := m.UnmountVolume(vol, "alloc2"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if impl.Target(path2) != "" || impl.Staged(vol.ExternalID) != "" {
+		t.Fatalf("volume not unstaged")
+	}
+
+	// Volumes of plugins not running on the node can't be mounted
+	vol.PluginID = "other"
+	if _, err := m.MountVolume(vol, "alloc1", true, publishContext); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestManager_ExpandVolume(t *testing.T) {
+	m, impl, cleanup := testManager(t)
+	defer cleanup()
+
+	vol := &structs.CSIVolume{
+		ID:                   "data",
+		ExternalID:           "vol-data",
+		PluginID:             "ebs",
+		AccessMode:           structs.CSIVolumeAccessModeSingleNodeWriter,
+		AttachmentMode:       structs.CSIVolumeAttachmentModeFilesystem,
+		RequestedCapacityMin: 2 << 30,
+	}
+
+	// Volumes not published on the node are left alone
+	if err := m.ExpandVolume(vol); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	publishContext := map[string]string{"device": "/dev/vol-data"}
+	path, err := m.MountVolume(vol, "alloc1", false, publishContext)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := m.ExpandVolume(vol); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if size := impl.Expanded(path); size != vol.RequestedCapacityMin {
+		t.Fatalf("bad: %d", size)
+	}
+}
@@ -0,0 +1,9 @@
+// +build !linux
+
+package csimanager
+
+// makeShared makes the directory a shared mount. It's a no-op on platforms
+// other than linux, where the volumes of CSI plugins can't be mounted.
+func makeShared(dir string) error {
+	return nil
+}
@@ -0,0 +1,7 @@
+// +build !linux
+
+package csimanager
+
+// unmountShared unmounts the plugin dir made a shared mount. It's a no-op on
+// platforms other than linux.
+func unmountShared(dir string) {}
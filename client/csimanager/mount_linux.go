@@ -0,0 +1,60 @@
+package csimanager
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// makeShared makes the directory a shared mount by bind mounting it onto
+// itself, unless it already is a mount point. Must be root to run.
+func makeShared(dir string) error {
+	mounted, shared, err := mountPoint(dir)
+	if err != nil {
+		return err
+	}
+	if shared {
+		return nil
+	}
+	if !mounted {
+		if err := syscall.Mount(dir, dir, "", syscall.MS_BIND, ""); err != nil {
+			return os.NewSyscallError("mount", err)
+		}
+	}
+	if err := syscall.Mount("", dir, "", syscall.MS_SHARED|syscall.MS_REC, ""); err != nil {
+		return os.NewSyscallError("mount", err)
+	}
+	return nil
+}
+
+// mountPoint returns whether the directory is a mount point and whether the
+// mount is shared, as listed by /proc/self/mountinfo
+func mountPoint(dir string) (mounted, shared bool, err error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return false, false, err
+	}
+	defer f.Close()
+
+	// The fields are the mount ID, the parent ID, the device, the root, the
+	// mount point, the mount options and the optional fields ending with a
+	// "-" field. The last mount of a mount point is the visible one.
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 || fields[4] != dir {
+			continue
+		}
+		mounted, shared = true, false
+		for _, field := range fields[6:] {
+			if field == "-" {
+				break
+			}
+			if strings.HasPrefix(field, "shared:") {
+				shared = true
+			}
+		}
+	}
+	return mounted, shared, scanner.Err()
+}
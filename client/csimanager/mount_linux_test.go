@@ -0,0 +1,41 @@
+package csimanager
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// unmountShared unmounts the plugin dir made a shared mount
+func unmountShared(dir string) {
+	syscall.Unmount(dir, syscall.MNT_DETACH)
+}
+
+func TestMakeShared(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Must be root to mount")
+	}
+	dir, err := ioutil.TempDir("", "csimanager")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := makeShared(dir); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer unmountShared(dir)
+	if mounted, shared, err := mountPoint(dir); err != nil || !mounted || !shared {
+		t.Fatalf("bad: mounted %v, shared %v, err %v", mounted, shared, err)
+	}
+
+	// Making the dir shared again doesn't mount it again
+	if err := makeShared(dir); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	unmountShared(dir)
+	if mounted, _, err := mountPoint(dir); err != nil || mounted {
+		t.Fatalf("bad: mounted %v, err %v", mounted, err)
+	}
+}
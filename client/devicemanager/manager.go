@@ -0,0 +1,329 @@
+// Package devicemanager runs the device plugins of the client. It reports the
+// devices of the node and reserves them for the tasks requesting them.
+package devicemanager
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-plugin"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/plugins/devices"
+)
+
+// Reservation is the reservation of devices for a task
+type Reservation struct {
+	// Devices are the IDs of the reserved devices, as
+	// <vendor>/<type>/<name>/<device ID>
+	Devices []string
+
+	// Envs are the environment variables exposing the devices to the task
+	Envs map[string]string
+}
+
+// devicePlugin is a running device plugin
+type devicePlugin struct {
+	name   string
+	client *plugin.Client
+	impl   devices.DevicePlugin
+
+	// groups are the device groups last fingerprinted
+	groups []*devices.DeviceGroup
+}
+
+// Manager runs the device plugins found in the plugin directory
+type Manager struct {
+	logger  *log.Logger
+	plugins []*devicePlugin
+
+	// reserved maps the IDs of the reserved devices to the task they are
+	// reserved for, and reservations the tasks to their devices
+	reserved     map[string]string
+	reservations map[string][]string
+	l            sync.Mutex
+}
+
+// NewManager launches the device plugins found in the plugin directory. A
+// plugin that fails to launch is skipped.
+func NewManager(dir string, w io.Writer, logger *log.Logger) (*Manager, error) {
+	m := &Manager{
+		logger:       logger,
+		reserved:     make(map[string]string),
+		reservations: make(map[string][]string),
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, fmt.Errorf("failed to read plugin directory %q: %v", dir, err)
+	}
+
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), devices.ExecutablePrefix) {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(f.Name(), devices.ExecutablePrefix), ".exe")
+		p, err := launch(name, filepath.Join(dir, f.Name()), w)
+		if err != nil {
+			logger.Printf("[ERR] client.devices: skipping device plugin %q: %v", name, err)
+			continue
+		}
+		m.plugins = append(m.plugins, p)
+	}
+	return m, nil
+}
+
+// launch starts the executable of a device plugin
+func launch(name, path string, w io.Writer) (*devicePlugin, error) {
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig: devices.HandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			devices.PluginName: new(devices.Plugin),
+		},
+		Cmd:    exec.Command(path),
+		Stderr: w,
+	})
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("error creating rpc client for device plugin: %v", err)
+	}
+	raw, err := rpcClient.Dispense(devices.PluginName)
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("unable to dispense the device plugin: %v", err)
+	}
+	return &devicePlugin{name: name, client: client, impl: raw.(devices.DevicePlugin)}, nil
+}
+
+// Plugins returns the names of the running device plugins
+func (m *Manager) Plugins() []string {
+	names := make([]string, 0, len(m.plugins))
+	for _, p := range m.plugins {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// Fingerprint fingerprints the devices of the plugins, returning the device
+// groups of the node. The devices of a plugin that fails to fingerprint are
+// reported as unhealthy.
+func (m *Manager) Fingerprint() []*structs.NodeDeviceResource {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	var resources []*structs.NodeDeviceResource
+	for _, p := range m.plugins {
+		groups, err := p.impl.Fingerprint()
+		if err != nil {
+			m.logger.Printf("[ERR] client.devices: failed to fingerprint device plugin %q: %v", p.name, err)
+			for _, g := range p.groups {
+				for _, d := range g.Devices {
+					d.Healthy = false
+					d.HealthDescription = fmt.Sprintf("device plugin failed to fingerprint: %v", err)
+				}
+			}
+		} else {
+			p.groups = groups
+		}
+
+		for _, g := range p.groups {
+			r := &structs.NodeDeviceResource{
+				Vendor:     g.Vendor,
+				Type:       g.Type,
+				Name:       g.Name,
+				Attributes: structs.CopyMapStringString(g.Attributes),
+			}
+			for _, d := range g.Devices {
+				r.Instances = append(r.Instances, &structs.NodeDevice{
+					ID:                d.ID,
+					Healthy:           d.Healthy,
+					HealthDescription: d.HealthDescription,
+				})
+			}
+			resources = append(resources, r)
+		}
+	}
+	return resources
+}
+
+// deviceID returns the ID of a device across plugins
+func deviceID(g *devices.DeviceGroup, id string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", g.Vendor, g.Type, g.Name, id)
+}
+
+// Reserve reserves devices for the task, which is identified by owner. The
+// devices already reserved for the task are reserved again, so they are kept
+// across restarts of the task.
+func (m *Manager) Reserve(owner string, requests []*structs.RequestedDevice) (*Reservation, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	// Select the devices if none are reserved for the task yet
+	ids, ok := m.reservations[owner]
+	if !ok {
+		var err error
+		if ids, err = m.selectLocked(owner, requests); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &Reservation{
+		Devices: ids,
+		Envs:    make(map[string]string),
+	}
+	for _, p := range m.plugins {
+		for _, g := range p.groups {
+			var groupIDs []string
+			for _, d := range g.Devices {
+				if m.reserved[deviceID(g, d.ID)] == owner {
+					groupIDs = append(groupIDs, d.ID)
+				}
+			}
+			if len(groupIDs) == 0 {
+				continue
+			}
+
+			out, err := p.impl.Reserve(&devices.ReserveRequest{
+				Vendor:    g.Vendor,
+				Type:      g.Type,
+				Name:      g.Name,
+				DeviceIDs: groupIDs,
+			})
+			if err != nil {
+				m.releaseLocked(owner)
+				return nil, fmt.Errorf("device plugin %q failed to reserve devices: %v", p.name, err)
+			}
+			for k, v := range out.Envs {
+				res.Envs[k] = v
+			}
+		}
+	}
+	return res, nil
+}
+
+// selectLocked selects healthy unreserved devices satisfying the requests and
+// marks them reserved for the task. Each request is satisfied by the devices
+// of a single group. The lock must be held.
+func (m *Manager) selectLocked(owner string, requests []*structs.RequestedDevice) ([]string, error) {
+	var ids []string
+REQUESTS:
+	for _, req := range requests {
+		for _, p := range m.plugins {
+			for _, g := range p.groups {
+				r := structs.NodeDeviceResource{Vendor: g.Vendor, Type: g.Type, Name: g.Name}
+				if !r.Matches(req.Name) {
+					continue
+				}
+
+				var free []string
+				for _, d := range g.Devices {
+					id := deviceID(g, d.ID)
+					if _, ok := m.reserved[id]; !ok && d.Healthy {
+						free = append(free, id)
+					}
+				}
+				if len(free) < req.Count {
+					continue
+				}
+
+				for _, id := range free[:req.Count] {
+					m.reserved[id] = owner
+					ids = append(ids, id)
+				}
+				continue REQUESTS
+			}
+		}
+
+		// Release the devices selected for the previous requests
+		for _, id := range ids {
+			delete(m.reserved, id)
+		}
+		return nil, fmt.Errorf("not enough healthy devices %q available", req.Name)
+	}
+
+	m.reservations[owner] = ids
+	return ids, nil
+}
+
+// Restore marks the devices as reserved for the task, such as when the task
+// is restored after the client restarted
+func (m *Manager) Restore(owner string, ids []string) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for _, id := range ids {
+		m.reserved[id] = owner
+	}
+	m.reservations[owner] = ids
+}
+
+// Release releases the devices reserved for the task
+func (m *Manager) Release(owner string) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	m.releaseLocked(owner)
+}
+
+// releaseLocked releases the devices reserved for the task. The lock must be
+// held.
+func (m *Manager) releaseLocked(owner string) {
+	for _, id := range m.reservations[owner] {
+		delete(m.reserved, id)
+	}
+	delete(m.reservations, owner)
+}
+
+// Stats returns the statistics of the devices reserved for the task
+func (m *Manager) Stats(owner string) []*cstructs.DeviceGroupStats {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if len(m.reservations[owner]) == 0 {
+		return nil
+	}
+
+	var stats []*cstructs.DeviceGroupStats
+	for _, p := range m.plugins {
+		groups, err := p.impl.Stats()
+		if err != nil {
+			m.logger.Printf("[WARN] client.devices: failed to fetch stats of device plugin %q: %v", p.name, err)
+			continue
+		}
+		for _, g := range groups {
+			s := &cstructs.DeviceGroupStats{
+				Vendor:        g.Vendor,
+				Type:          g.Type,
+				Name:          g.Name,
+				InstanceStats: make(map[string]*cstructs.DeviceStats),
+			}
+			for id, instance := range g.InstanceStats {
+				full := fmt.Sprintf("%s/%s/%s/%s", g.Vendor, g.Type, g.Name, id)
+				if m.reserved[full] == owner {
+					s.InstanceStats[id] = instance
+				}
+			}
+			if len(s.InstanceStats) != 0 {
+				stats = append(stats, s)
+			}
+		}
+	}
+	return stats
+}
+
+// Shutdown stops the device plugins
+func (m *Manager) Shutdown() {
+	for _, p := range m.plugins {
+		p.client.Kill()
+	}
+}
@@ -0,0 +1,151 @@
+package devicemanager
+
+import (
+	"io/ioutil"
+	"log"
+	"reflect"
+	"sort"
+	"testing"
+
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/plugins/devices"
+)
+
+// testPlugin is a device plugin with two healthy GPUs and an unhealthy one
+type testPlugin struct{}
+
+func (p *testPlugin) Fingerprint() ([]*devices.DeviceGroup, error) {
+	return []*devices.DeviceGroup{
+		{
+			Vendor: "nvidia",
+			Type:   "gpu",
+			Name:   "1080ti",
+			Devices: []*devices.Device{
+				{ID: "0", Healthy: true},
+				{ID: "1", Healthy: true},
+				{ID: "2", Healthy: false, HealthDescription: "overheating"},
+			},
+		},
+	}, nil
+}
+
+func (p *testPlugin) Reserve(req *devices.ReserveRequest) (*devices.Reservation, error) {
+	ids := append([]string{}, req.DeviceIDs...)
+	sort.Strings(ids)
+	visible := ""
+	for i, id := range ids {
+		if i != 0 {
+			visible += ","
+		}
+		visible += id
+	}
+	return &devices.Reservation{Envs: map[string]string{"NVIDIA_VISIBLE_DEVICES": visible}}, nil
+}
+
+func (p *testPlugin) Stats() ([]*cstructs.DeviceGroupStats, error) {
+	return []*cstructs.DeviceGroupStats{
+		{
+			Vendor: "nvidia",
+			Type:   "gpu",
+			Name:   "1080ti",
+			InstanceStats: map[string]*cstructs.DeviceStats{
+				"0": {Stats: map[string]float64{"utilization": 10}},
+				"1": {Stats: map[string]float64{"utilization": 20}},
+				"2": {Stats: map[string]float64{"utilization": 0}},
+			},
+		},
+	}, nil
+}
+
+func testManager() *Manager {
+	return &Manager{
+		logger:       log.New(ioutil.Discard, "", 0),
+		plugins:      []*devicePlugin{{name: "nvidia", impl: &testPlugin{}}},
+		reserved:     make(map[string]string),
+		reservations: make(map[string][]string),
+	}
+}
+
+func TestManager_Fingerprint(t *testing.T) {
+	m := testManager()
+	resources := m.Fingerprint()
+	if len(resources) != 1 {
+		t.Fatalf("bad: %#v", resources)
+	}
+	r := resources[0]
+	if r.ID() != "nvidia/gpu/1080ti" || len(r.Instances) != 3 || r.HealthyInstances() != 2 {
+		t.Fatalf("bad: %#v", r)
+	}
+	if r.Instances[2].HealthDescription != "overheating" {
+		t.Fatalf("bad: %#v", r.Instances[2])
+	}
+}
+
+func TestManager_Reserve(t *testing.T) {
+	m := testManager()
+	m.Fingerprint()
+
+	req := []*structs.RequestedDevice{{Name: "nvidia/gpu", Count: 1}}
+	res1, err := m.Reserve("alloc1/web", req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(res1.Devices, []string{"nvidia/gpu/1080ti/0"}) {
+		t.Fatalf("bad: %#v", res1)
+	}
+	if res1.Envs["NVIDIA_VISIBLE_DEVICES"] != "0" {
+		t.Fatalf("bad: %#v", res1.Envs)
+	}
+
+	// Reserving again for the same task keeps its devices
+	again, err := m.Reserve("alloc1/web", req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(again, res1) {
+		t.Fatalf("bad: %#v", again)
+	}
+
+	// Another task gets the other healthy device
+	res2, err := m.Reserve("alloc2/web", req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if res2.Envs["NVIDIA_VISIBLE_DEVICES"] != "1" {
+		t.Fatalf("bad: %#v", res2.Envs)
+	}
+
+	// The unhealthy device is never reserved
+	if _, err := m.Reserve("alloc3/web", req); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// Released devices can be reserved again
+	m.Release("alloc1/web")
+	res3, err := m.Reserve("alloc3/web", req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if res3.Envs["NVIDIA_VISIBLE_DEVICES"] != "0" {
+		t.Fatalf("bad: %#v", res3.Envs)
+	}
+}
+
+func TestManager_Stats(t *testing.T) {
+	m := testManager()
+	m.Fingerprint()
+
+	if stats := m.Stats("alloc1/web"); len(stats) != 0 {
+		t.Fatalf("bad: %#v", stats)
+	}
+
+	m.Restore("alloc1/web", []string{"nvidia/gpu/1080ti/1"})
+	stats := m.Stats("alloc1/web")
+	if len(stats) != 1 || len(stats[0].InstanceStats) != 1 {
+		t.Fatalf("bad: %#v", stats)
+	}
+	if stats[0].InstanceStats["1"].Stats["utilization"] != 20 {
+		t.Fatalf("bad: %#v", stats[0].InstanceStats)
+	}
+}
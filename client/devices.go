@@ -0,0 +1,99 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/nomad/client/devicemanager"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// deviceFingerprintInterval is the interval at which the devices of the
+	// node are fingerprinted to track their health
+	deviceFingerprintInterval = 30 * time.Second
+)
+
+// DeviceReserver is used to reserve the devices of the node, such as GPUs,
+// for the tasks requesting them. Tasks are identified by owner.
+type DeviceReserver interface {
+	ReserveDevices(owner string, requests []*structs.RequestedDevice) (*devicemanager.Reservation, error)
+	RestoreDevices(owner string, ids []string)
+	ReleaseDevices(owner string)
+	DeviceStats(owner string) []*cstructs.DeviceGroupStats
+}
+
+// setupDevices launches the device plugins found in the plugin directory and
+// fingerprints the devices of the node
+func (c *Client) setupDevices() error {
+	if c.config.PluginDir == "" {
+		return nil
+	}
+
+	m, err := devicemanager.NewManager(c.config.PluginDir, c.config.LogOutput, c.logger)
+	if err != nil {
+		return err
+	}
+	if len(m.Plugins()) == 0 {
+		return nil
+	}
+	c.devices = m
+	c.logger.Printf("[DEBUG] client: found device plugins %v", m.Plugins())
+
+	c.fingerprintDevices()
+	go c.fingerprintDevicesPeriodic()
+	return nil
+}
+
+// fingerprintDevices updates the devices of the node. Changes are sent to the
+// servers once detected by watchNodeUpdates.
+func (c *Client) fingerprintDevices() {
+	resources := c.devices.Fingerprint()
+	c.configLock.Lock()
+	c.config.Node.Devices = resources
+	c.configLock.Unlock()
+}
+
+// fingerprintDevicesPeriodic fingerprints the devices of the node
+// periodically to track their health
+func (c *Client) fingerprintDevicesPeriodic() {
+	for {
+		select {
+		case <-time.After(deviceFingerprintInterval):
+			c.fingerprintDevices()
+		case <-c.shutdownCh:
+			return
+		}
+	}
+}
+
+// ReserveDevices reserves devices satisfying the requests for the task
+func (c *Client) ReserveDevices(owner string, requests []*structs.RequestedDevice) (*devicemanager.Reservation, error) {
+	if c.devices == nil {
+		return nil, fmt.Errorf("no device plugins are running on the node")
+	}
+	return c.devices.Reserve(owner, requests)
+}
+
+// RestoreDevices marks the devices as reserved for a restored task
+func (c *Client) RestoreDevices(owner string, ids []string) {
+	if c.devices != nil {
+		c.devices.Restore(owner, ids)
+	}
+}
+
+// ReleaseDevices releases the devices reserved for the task
+func (c *Client) ReleaseDevices(owner string) {
+	if c.devices != nil {
+		c.devices.Release(owner)
+	}
+}
+
+// DeviceStats returns the statistics of the devices reserved for the task
+func (c *Client) DeviceStats(owner string) []*cstructs.DeviceGroupStats {
+	if c.devices == nil {
+		return nil
+	}
+	return c.devices.Stats(owner)
+}
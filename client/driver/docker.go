@@ -1,6 +1,7 @@
 package driver
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -61,6 +62,11 @@ const (
 	// dockerTimeout is the length of time a request can be outstanding before
 	// it is timed out.
 	dockerTimeout = 1 * time.Minute
+
+	// dockerAllocLabel is the label set on the containers to the ID of their
+	// allocation, so that the containers of allocations that are gone can be
+	// found and removed.
+	dockerAllocLabel = "com.hashicorp.nomad.alloc_id"
 )
 
 type DockerDriver struct {
@@ -305,6 +311,17 @@ func (d *DockerDriver) dockerClients() (*docker.Client, *docker.Client, error) {
 	return client, waitClient, merr.ErrorOrNil()
 }
 
+// Capabilities returns the features the Docker driver supports
+func (d *DockerDriver) Capabilities() *Capabilities {
+	return &Capabilities{
+		SendSignals:      true,
+		Exec:             true,
+		Pause:            true,
+		MountVolumes:     true,
+		NetworkIsolation: []string{"host", defaultNetworkMode, "none"},
+	}
+}
+
 func (d *DockerDriver) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
 	// Get the current status so that we can log any debug messages only if the
 	// state changes
@@ -339,9 +356,59 @@ func (d *DockerDriver) Fingerprint(cfg *config.Config, node *structs.Node) (bool
 
 	node.Attributes[dockerDriverAttr] = "1"
 	node.Attributes["driver.docker.version"] = env.Get("Version")
+
+	// Fingerprinting is periodic, so this is also where the containers left
+	// behind by allocations that are gone get removed
+	if d.config.ReadBoolDefault("docker.cleanup.container", true) {
+		d.removeDanglingContainers(client, cfg.AllocDir)
+	}
 	return true, nil
 }
 
+// removeDanglingContainers removes the containers created by Nomad whose
+// allocation directory no longer exists, which happens when the client was
+// stopped or crashed before it could remove them and the allocation was then
+// garbage collected. The images of the containers are removed as well unless
+// docker.cleanup.image is disabled.
+func (d *DockerDriver) removeDanglingContainers(client *docker.Client, allocDir string) {
+	containers, err := client.ListContainers(docker.ListContainersOptions{
+		All: true,
+		Filters: map[string][]string{
+			"label": []string{dockerAllocLabel},
+		},
+	})
+	if err != nil {
+		d.logger.Printf("[DEBUG] driver.docker: failed to list containers: %v", err)
+		return
+	}
+
+	cleanupImage := d.config.ReadBoolDefault("docker.cleanup.image", true)
+	for _, container := range containers {
+		allocID := container.Labels[dockerAllocLabel]
+		if allocID == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(allocDir, allocID)); !os.IsNotExist(err) {
+			continue
+		}
+
+		err := client.RemoveContainer(docker.RemoveContainerOptions{ID: container.ID, RemoveVolumes: true, Force: true})
+		if err != nil {
+			if _, noSuchContainer := err.(*docker.NoSuchContainer); !noSuchContainer {
+				d.logger.Printf("[ERR] driver.docker: failed to remove dangling container %s: %v", container.ID, err)
+			}
+			continue
+		}
+		d.logger.Printf("[INFO] driver.docker: removed dangling container %s of alloc %q", container.ID, allocID)
+
+		if cleanupImage {
+			if err := client.RemoveImage(container.Image); err != nil {
+				d.logger.Printf("[DEBUG] driver.docker: error removing image: %v", err)
+			}
+		}
+	}
+}
+
 func (d *DockerDriver) containerBinds(alloc *allocdir.AllocDir, task *structs.Task) ([]string, error) {
 	shared := alloc.SharedDir
 	local, ok := alloc.TaskDirs[task.Name]
@@ -357,16 +424,37 @@ func (d *DockerDriver) containerBinds(alloc *allocdir.AllocDir, task *structs.Ta
 	taskLocalBind := fmt.Sprintf("%s:%s", local, allocdir.TaskLocalContainerPath)
 	secretDirBind := fmt.Sprintf("%s:%s", secret, allocdir.TaskSecretsContainerPath)
 
-	if selinuxLabel := d.config.Read("docker.volumes.selinuxlabel"); selinuxLabel != "" {
+	selinuxLabel := d.config.Read("docker.volumes.selinuxlabel")
+	if selinuxLabel != "" {
 		allocDirBind = fmt.Sprintf("%s:%s", allocDirBind, selinuxLabel)
 		taskLocalBind = fmt.Sprintf("%s:%s", taskLocalBind, selinuxLabel)
 		secretDirBind = fmt.Sprintf("%s:%s", secretDirBind, selinuxLabel)
 	}
-	return []string{
+	binds := []string{
 		allocDirBind,
 		taskLocalBind,
 		secretDirBind,
-	}, nil
+	}
+
+	// Bind the host volumes the task mounts
+	for _, m := range alloc.VolumeMounts[task.Name] {
+		var opts []string
+		if m.ReadOnly {
+			opts = append(opts, "ro")
+		}
+		if m.Shared {
+			opts = append(opts, "rshared")
+		}
+		if selinuxLabel != "" {
+			opts = append(opts, selinuxLabel)
+		}
+		bind := fmt.Sprintf("%s:%s", m.HostPath, m.TaskPath)
+		if len(opts) != 0 {
+			bind = fmt.Sprintf("%s:%s", bind, strings.Join(opts, ","))
+		}
+		binds = append(binds, bind)
+	}
+	return binds, nil
 }
 
 // createContainer initializes a struct needed to call docker.client.CreateContainer()
@@ -544,10 +632,12 @@ func (d *DockerDriver) createContainer(ctx *ExecContext, task *structs.Task,
 		config.Cmd = parsedArgs
 	}
 
-	if len(driverConfig.Labels) > 0 {
-		config.Labels = driverConfig.Labels
-		d.logger.Printf("[DEBUG] driver.docker: applied labels on the container: %+v", config.Labels)
+	config.Labels = make(map[string]string, len(driverConfig.Labels)+1)
+	for k, v := range driverConfig.Labels {
+		config.Labels[k] = v
 	}
+	config.Labels[dockerAllocLabel] = ctx.AllocID
+	d.logger.Printf("[DEBUG] driver.docker: applied labels on the container: %+v", config.Labels)
 
 	config.Env = d.taskEnv.EnvList()
 
@@ -658,6 +748,21 @@ func (d *DockerDriver) pullImage(driverConfig *DockerDriverConfig, client *docke
 	return nil
 }
 
+// PrefetchImage pulls the image from its registry ahead of the tasks using it
+// being started. Registry credentials are read from docker.auth.config.
+func (d *DockerDriver) PrefetchImage(image string) error {
+	client, _, err := d.dockerClients()
+	if err != nil {
+		return fmt.Errorf("Failed to connect to docker daemon: %s", err)
+	}
+
+	repo, tag := docker.ParseRepositoryTag(image)
+	if tag == "" {
+		tag = "latest"
+	}
+	return d.pullImage(&DockerDriverConfig{ImageName: image}, client, repo, tag)
+}
+
 // loadImage creates an image by loading it from the file system
 func (d *DockerDriver) loadImage(driverConfig *DockerDriverConfig, client *docker.Client, taskDir string) error {
 	var errors multierror.Error
@@ -683,6 +788,11 @@ func (d *DockerDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle
 		return nil, err
 	}
 
+	// The image and command may interpolate the task's environment, such as
+	// "redis:${NOMAD_META_VERSION}"
+	driverConfig.ImageName = d.taskEnv.ReplaceEnv(driverConfig.ImageName)
+	driverConfig.Command = d.taskEnv.ReplaceEnv(driverConfig.Command)
+
 	cleanupImage := d.config.ReadBoolDefault("docker.cleanup.image", true)
 
 	taskDir, ok := ctx.AllocDir.TaskDirs[d.DriverContext.taskName]
@@ -965,6 +1075,92 @@ func (h *DockerHandle) Kill() error {
 	return nil
 }
 
+// Pause pauses all the processes of the container
+func (h *DockerHandle) Pause() error {
+	if err := h.client.PauseContainer(h.containerID); err != nil {
+		return fmt.Errorf("Failed to pause container %s: %s", h.containerID, err)
+	}
+	return nil
+}
+
+// Resume unpauses the processes of the container
+func (h *DockerHandle) Resume() error {
+	if err := h.client.UnpauseContainer(h.containerID); err != nil {
+		return fmt.Errorf("Failed to unpause container %s: %s", h.containerID, err)
+	}
+	return nil
+}
+
+// Signal sends the named signal to the container's main process
+func (h *DockerHandle) Signal(sig string) error {
+	s, err := parseSignal(sig)
+	if err != nil {
+		return err
+	}
+	opts := docker.KillContainerOptions{ID: h.containerID, Signal: docker.Signal(s)}
+	if err := h.client.KillContainer(opts); err != nil {
+		return fmt.Errorf("Failed to signal container %s: %s", h.containerID, err)
+	}
+	return nil
+}
+
+// Exec runs a command in the container of the task
+func (h *DockerHandle) Exec(ctx context.Context, opts *ExecOptions) (int, error) {
+	dockerExec, err := h.client.CreateExec(docker.CreateExecOptions{
+		AttachStdin:  opts.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          opts.Tty,
+		Cmd:          opts.Command,
+		Container:    h.containerID,
+		Context:      ctx,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Failed to create exec in container %s: %s", h.containerID, err)
+	}
+
+	// The terminal can only be resized once the exec is running, so resizes
+	// that fail are ignored
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go func() {
+		for {
+			select {
+			case size, ok := <-opts.ResizeCh:
+				if !ok {
+					return
+				}
+				h.client.ResizeExecTTY(dockerExec.ID, size.Height, size.Width)
+			case <-doneCh:
+				return
+			}
+		}
+	}()
+
+	startErr := h.client.StartExec(dockerExec.ID, docker.StartExecOptions{
+		InputStream:  opts.Stdin,
+		OutputStream: opts.Stdout,
+		ErrorStream:  opts.Stderr,
+		Tty:          opts.Tty,
+		RawTerminal:  opts.Tty,
+		Context:      ctx,
+	})
+
+	// Closing the input when the output ends may surface as an error from
+	// StartExec, so the exec is inspected to tell whether it completed
+	inspect, err := h.client.InspectExec(dockerExec.ID)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to inspect exec in container %s: %s", h.containerID, err)
+	}
+	if inspect.Running {
+		if startErr == nil {
+			startErr = fmt.Errorf("exec still running")
+		}
+		return 0, fmt.Errorf("Failed to exec in container %s: %s", h.containerID, startErr)
+	}
+	return inspect.ExitCode, nil
+}
+
 func (h *DockerHandle) Stats() (*cstructs.TaskResourceUsage, error) {
 	h.resourceUsageLock.RLock()
 	defer h.resourceUsageLock.RUnlock()
@@ -1072,7 +1268,8 @@ func (h *DockerHandle) collectStats() {
 						MemoryStats: ms,
 						CpuStats:    cs,
 					},
-					Timestamp: s.Read.UTC().UnixNano(),
+					Timestamp:   s.Read.UTC().UnixNano(),
+					DriverStats: dockerDriverStats(s),
 				}
 				h.resourceUsageLock.Unlock()
 			}
@@ -1082,6 +1279,34 @@ func (h *DockerHandle) collectStats() {
 	}
 }
 
+// dockerDriverStats returns the Docker specific metrics of a container: the
+// bytes read and written per block device, the traffic per network interface
+// and the number of processes.
+func dockerDriverStats(s *docker.Stats) map[string]float64 {
+	stats := map[string]float64{
+		"pids.current": float64(s.PidsStats.Current),
+	}
+	for _, entry := range s.BlkioStats.IOServiceBytesRecursive {
+		var op string
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			op = "read_bytes"
+		case "write":
+			op = "write_bytes"
+		default:
+			continue
+		}
+		stats[fmt.Sprintf("blkio.%d:%d.%s", entry.Major, entry.Minor, op)] = float64(entry.Value)
+	}
+	for iface, n := range s.Networks {
+		stats[fmt.Sprintf("network.%s.rx_bytes", iface)] = float64(n.RxBytes)
+		stats[fmt.Sprintf("network.%s.tx_bytes", iface)] = float64(n.TxBytes)
+		stats[fmt.Sprintf("network.%s.rx_dropped", iface)] = float64(n.RxDropped)
+		stats[fmt.Sprintf("network.%s.tx_dropped", iface)] = float64(n.TxDropped)
+	}
+	return stats
+}
+
 func calculatePercent(newSample, oldSample, newTotal, oldTotal uint64, cores int) float64 {
 	numerator := newSample - oldSample
 	denom := newTotal - oldTotal
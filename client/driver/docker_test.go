@@ -64,8 +64,8 @@ func dockerTask() (*structs.Task, int, int) {
 			Networks: []*structs.NetworkResource{
 				&structs.NetworkResource{
 					IP:            "127.0.0.1",
-					ReservedPorts: []structs.Port{{"main", docker_reserved}},
-					DynamicPorts:  []structs.Port{{"REDIS", docker_dynamic}},
+					ReservedPorts: []structs.Port{{"main", docker_reserved, 0, ""}},
+					DynamicPorts:  []structs.Port{{"REDIS", docker_dynamic, 0, ""}},
 				},
 			},
 		},
@@ -558,13 +558,17 @@ func TestDockerDriver_Labels(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	if want, got := 2, len(container.Config.Labels); want != got {
+	if want, got := 3, len(container.Config.Labels); want != got {
 		t.Errorf("Wrong labels count for docker job. Expect: %d, got: %d", want, got)
 	}
 
 	if want, got := "value1", container.Config.Labels["label1"]; want != got {
 		t.Errorf("Wrong label value docker job. Expect: %s, got: %s", want, got)
 	}
+
+	if container.Config.Labels[dockerAllocLabel] == "" {
+		t.Errorf("Missing alloc label on docker job: %#v", container.Config.Labels)
+	}
 }
 
 func TestDockerDriver_DNS(t *testing.T) {
@@ -866,3 +870,29 @@ func copyImage(execCtx *ExecContext, task *structs.Task, image string, t *testin
 	dst := filepath.Join(taskDir, allocdir.TaskLocal, image)
 	copyFile(filepath.Join("./test-resources/docker", image), dst, t)
 }
+
+func TestDockerDriver_DriverStats(t *testing.T) {
+	s := &docker.Stats{}
+	s.PidsStats.Current = 3
+	s.BlkioStats.IOServiceBytesRecursive = []docker.BlkioStatsEntry{
+		{Major: 8, Minor: 0, Op: "Read", Value: 1024},
+		{Major: 8, Minor: 0, Op: "Write", Value: 2048},
+		{Major: 8, Minor: 0, Op: "Total", Value: 3072},
+	}
+	s.Networks = map[string]docker.NetworkStats{
+		"eth0": {RxBytes: 10, TxBytes: 20},
+	}
+
+	expected := map[string]float64{
+		"pids.current":            3,
+		"blkio.8:0.read_bytes":    1024,
+		"blkio.8:0.write_bytes":   2048,
+		"network.eth0.rx_bytes":   10,
+		"network.eth0.tx_bytes":   20,
+		"network.eth0.rx_dropped": 0,
+		"network.eth0.tx_dropped": 0,
+	}
+	if stats := dockerDriverStats(s); !reflect.DeepEqual(stats, expected) {
+		t.Fatalf("bad: %#v", stats)
+	}
+}
@@ -1,9 +1,14 @@
 package driver
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/hashicorp/nomad/client/allocdir"
 	"github.com/hashicorp/nomad/client/config"
@@ -20,10 +25,12 @@ import (
 var BuiltinDrivers = map[string]Factory{
 	"docker":   NewDockerDriver,
 	"exec":     NewExecDriver,
+	"exec2":    NewExec2Driver,
 	"raw_exec": NewRawExecDriver,
 	"java":     NewJavaDriver,
 	"qemu":     NewQemuDriver,
 	"rkt":      NewRktDriver,
+	"wasm":     NewWasmDriver,
 }
 
 // NewDriver is used to instantiate and return a new driver
@@ -58,6 +65,103 @@ type Driver interface {
 
 	// Drivers must validate their configuration
 	Validate(map[string]interface{}) error
+
+	// Capabilities returns the capabilities of the driver
+	Capabilities() *Capabilities
+}
+
+// Capabilities describes the features a driver supports. They are advertised
+// as node attributes so the scheduler only places tasks on nodes whose
+// drivers support the features the tasks rely on.
+type Capabilities struct {
+	// SendSignals marks whether the driver can send signals to a task
+	SendSignals bool
+
+	// Exec marks whether the driver can execute commands, such as script
+	// checks, in the context of a task
+	Exec bool
+
+	// Pause marks whether the driver can pause and resume a task
+	Pause bool
+
+	// MountVolumes marks whether the driver can mount volumes into a task
+	MountVolumes bool
+
+	// NetworkIsolation is the set of network isolation modes the driver
+	// supports
+	NetworkIsolation []string
+}
+
+// executorNetworkIsolation returns the network isolation modes of drivers
+// launching tasks with the executor. Tasks are started in the network
+// namespace of their allocation, which is only supported on Linux.
+func executorNetworkIsolation() []string {
+	if runtime.GOOS == "linux" {
+		return []string{"host", structs.DriverNetworkIsolationGroup}
+	}
+	return []string{"host"}
+}
+
+// Supports returns whether the driver has the given capability
+func (c *Capabilities) Supports(capability string) bool {
+	switch capability {
+	case structs.DriverCapabilitySignals:
+		return c.SendSignals
+	case structs.DriverCapabilityExec:
+		return c.Exec
+	case structs.DriverCapabilityPause:
+		return c.Pause
+	case structs.DriverCapabilityMountVolumes:
+		return c.MountVolumes
+	case structs.DriverCapabilityNetworkIsolation:
+		return len(c.NetworkIsolation) != 0
+	default:
+		return false
+	}
+}
+
+// SupportsNetworkIsolation returns whether the driver supports the given
+// network isolation mode
+func (c *Capabilities) SupportsNetworkIsolation(mode string) bool {
+	for _, m := range c.NetworkIsolation {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Attributes returns the node attributes advertising the capabilities of the
+// named driver
+func (c *Capabilities) Attributes(driver string) map[string]string {
+	attrs := make(map[string]string)
+	for _, capability := range []string{
+		structs.DriverCapabilitySignals,
+		structs.DriverCapabilityExec,
+		structs.DriverCapabilityPause,
+		structs.DriverCapabilityMountVolumes,
+	} {
+		attrs[structs.DriverCapabilityAttribute(driver, capability)] = strconv.FormatBool(c.Supports(capability))
+	}
+	attrs[structs.DriverCapabilityAttribute(driver, structs.DriverCapabilityNetworkIsolation)] =
+		strings.Join(c.NetworkIsolation, ",")
+	return attrs
+}
+
+// Validate returns an error if the named driver lacks a capability the task
+// relies on
+func (c *Capabilities) Validate(driver string, task *structs.Task) error {
+	var missing []string
+	for _, capability := range task.RequiredDriverCapabilities() {
+		if !c.Supports(capability) {
+			missing = append(missing, capability)
+		}
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("driver %q does not support the capabilities required by task %q: %s",
+			driver, task.Name, strings.Join(missing, ", "))
+	}
+	return nil
 }
 
 // DriverContext is a means to inject dependencies such as loggers, configs, and
@@ -118,6 +222,64 @@ type DriverHandle interface {
 	Stats() (*cstructs.TaskResourceUsage, error)
 }
 
+// PausableHandle is implemented by the handles of drivers that can pause a
+// running task without stopping it
+type PausableHandle interface {
+	// Pause suspends the execution of the task
+	Pause() error
+
+	// Resume continues the execution of a paused task
+	Resume() error
+}
+
+// SignalableHandle is implemented by the handles of drivers that can send
+// signals to a running task
+type SignalableHandle interface {
+	// Signal sends the named signal, such as SIGHUP, to the task
+	Signal(sig string) error
+}
+
+// TerminalSize is the size of a terminal in characters
+type TerminalSize struct {
+	Height int
+	Width  int
+}
+
+// ExecOptions configures a command run inside a running task
+type ExecOptions struct {
+	// Command is the command and its arguments
+	Command []string
+
+	// Tty allocates a terminal to the command, in which case all of its
+	// output is written to Stdout
+	Tty bool
+
+	// Stdin, Stdout and Stderr are the streams of the command. Stdin may be
+	// nil if the command doesn't read input.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// ResizeCh receives the size of the terminal when it changes
+	ResizeCh <-chan TerminalSize
+}
+
+// ExecHandle is implemented by the handles of drivers that can run commands
+// inside the isolation context of a running task
+type ExecHandle interface {
+	// Exec runs the command until it exits or the context is done and
+	// returns its exit code
+	Exec(ctx context.Context, opts *ExecOptions) (int, error)
+}
+
+// ImagePrefetcher is implemented by drivers that can fetch the images of tasks
+// before the tasks are started
+type ImagePrefetcher interface {
+	// PrefetchImage fetches the image so starting tasks using it doesn't wait
+	// on fetching it
+	PrefetchImage(image string) error
+}
+
 // ExecContext is shared between drivers within an allocation
 type ExecContext struct {
 	// AllocDir contains information about the alloc directory structure.
@@ -125,6 +287,12 @@ type ExecContext struct {
 
 	// Alloc ID
 	AllocID string
+
+	// NetworkNamespace is the path of the network namespace of the
+	// allocation that tasks using drivers supporting the group network
+	// isolation mode join. It is empty if the allocation uses the network
+	// of the host.
+	NetworkNamespace string
 }
 
 // NewExecContext is used to create a new execution context
@@ -156,14 +324,37 @@ func GetTaskEnv(allocDir *allocdir.AllocDir, node *structs.Node,
 		env.SetSecretDir(filepath.Join(taskdir, allocdir.TaskSecrets))
 	}
 
+	var networks []*structs.NetworkResource
 	if task.Resources != nil {
 		env.SetMemLimit(task.Resources.MemoryMB).
-			SetCpuLimit(task.Resources.CPU).
-			SetNetworks(task.Resources.Networks)
+			SetCpuLimit(task.Resources.CPU)
+		networks = append(networks, task.Resources.Networks...)
+	}
+
+	// The ports of the task group network are shared by all the tasks. Ports
+	// of isolated networks are mapped to the port inside the namespace.
+	if alloc != nil && alloc.SharedResources != nil {
+		portMap := make(map[string]int)
+		for _, network := range alloc.SharedResources.Networks {
+			networks = append(networks, network)
+			if !network.Isolated() {
+				continue
+			}
+			for _, port := range append(network.ReservedPorts, network.DynamicPorts...) {
+				if port.To != 0 {
+					portMap[port.Label] = port.To
+				}
+			}
+		}
+		if len(portMap) != 0 {
+			env.SetPortMap(portMap)
+		}
 	}
+	env.SetNetworks(networks)
 
 	if alloc != nil {
 		env.SetAlloc(alloc)
+		env.SetIdentityToken(alloc.SignedIdentities[task.Name])
 	}
 
 	if task.Vault != nil {
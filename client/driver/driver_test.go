@@ -1,12 +1,16 @@
 package driver
 
 import (
+	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +19,7 @@ import (
 	"github.com/hashicorp/nomad/helper/testtask"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
 )
 
 var basicResources = &structs.Resources{
@@ -24,8 +29,8 @@ var basicResources = &structs.Resources{
 	Networks: []*structs.NetworkResource{
 		&structs.NetworkResource{
 			IP:            "0.0.0.0",
-			ReservedPorts: []structs.Port{{"main", 12345}},
-			DynamicPorts:  []structs.Port{{"HTTP", 43330}},
+			ReservedPorts: []structs.Port{{"main", 12345, 0, ""}},
+			DynamicPorts:  []structs.Port{{"HTTP", 43330, 0, ""}},
 		},
 	},
 }
@@ -65,6 +70,67 @@ func copyFile(src, dst string, t *testing.T) {
 	return
 }
 
+// pauseChildScript is a shell script that forks a child process which keeps
+// incrementing the counter in the file given as first argument
+const pauseChildScript = `(i=0; while true; do i=$((i+1)); echo $i > "$0.tmp"; mv "$0.tmp" "$0"; sleep 0.1; done) & wait`
+
+// testPauseChild pauses and resumes the task of the handle, which runs the
+// pauseChildScript, and checks that the child process of the task is paused
+// along with it
+func testPauseChild(t *testing.T, handle DriverHandle, counterFile string) {
+	readCounter := func() (int, error) {
+		data, err := ioutil.ReadFile(counterFile)
+		if err != nil {
+			return 0, err
+		}
+		return strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+
+	// Wait for the child process to start counting
+	testutil.WaitForResult(func() (bool, error) {
+		_, err := readCounter()
+		return err == nil, err
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	pausable, ok := handle.(PausableHandle)
+	if !ok {
+		t.Fatalf("handle is not pausable")
+	}
+	if err := pausable.Pause(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The counter must not move while the task is paused
+	time.Sleep(500 * time.Millisecond)
+	paused, err := readCounter()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	time.Sleep(1 * time.Second)
+	if counter, err := readCounter(); err != nil || counter != paused {
+		t.Fatalf("child process kept running: %d != %d (%v)", counter, paused, err)
+	}
+
+	// The counter moves again once the task is resumed
+	if err := pausable.Resume(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	testutil.WaitForResult(func() (bool, error) {
+		counter, err := readCounter()
+		if err != nil {
+			return false, err
+		}
+		if counter <= paused {
+			return false, fmt.Errorf("child process wasn't resumed: %d", counter)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
+
 func testLogger() *log.Logger {
 	return log.New(os.Stderr, "", log.LstdFlags)
 }
@@ -106,8 +172,8 @@ func TestDriver_GetTaskEnv(t *testing.T) {
 			Networks: []*structs.NetworkResource{
 				&structs.NetworkResource{
 					IP:            "1.2.3.4",
-					ReservedPorts: []structs.Port{{"one", 80}, {"two", 443}},
-					DynamicPorts:  []structs.Port{{"admin", 8081}, {"web", 8086}},
+					ReservedPorts: []structs.Port{{"one", 80, 0, ""}, {"two", 443, 0, ""}},
+					DynamicPorts:  []structs.Port{{"admin", 8081, 0, ""}, {"web", 8086, 0, ""}},
 				},
 			},
 		},
@@ -161,6 +227,129 @@ func TestDriver_GetTaskEnv(t *testing.T) {
 	}
 }
 
+func TestDriver_GetTaskEnv_GroupNetwork(t *testing.T) {
+	task := &structs.Task{
+		Name: "Foo",
+		Resources: &structs.Resources{
+			CPU:      1000,
+			MemoryMB: 500,
+		},
+	}
+
+	alloc := mock.Alloc()
+	alloc.SharedResources.Networks = []*structs.NetworkResource{
+		{
+			Mode:          structs.NetworkModeBridge,
+			IP:            "1.2.3.4",
+			ReservedPorts: []structs.Port{{Label: "http", Value: 80, To: 8080}},
+			DynamicPorts:  []structs.Port{{Label: "admin", Value: 23456}},
+		},
+	}
+	env, err := GetTaskEnv(nil, nil, task, alloc, "")
+	if err != nil {
+		t.Fatalf("GetTaskEnv() failed: %v", err)
+	}
+
+	act := env.EnvMap()
+	exp := map[string]string{
+		"NOMAD_ADDR_http":       "1.2.3.4:8080",
+		"NOMAD_IP_http":         "1.2.3.4",
+		"NOMAD_PORT_http":       "8080",
+		"NOMAD_HOST_PORT_http":  "80",
+		"NOMAD_ADDR_admin":      "1.2.3.4:23456",
+		"NOMAD_PORT_admin":      "23456",
+		"NOMAD_HOST_PORT_admin": "23456",
+	}
+	for k, v := range exp {
+		if act[k] != v {
+			t.Fatalf("GetTaskEnv() returned %q for %q; want %q", act[k], k, v)
+		}
+	}
+}
+
+func TestCapabilities_Validate(t *testing.T) {
+	task := &structs.Task{
+		Name:   "foo",
+		Driver: "raw_exec",
+		Services: []*structs.Service{
+			{
+				Name: "foo",
+				Checks: []*structs.ServiceCheck{
+					{Name: "script", Type: structs.ServiceCheckScript},
+				},
+			},
+		},
+	}
+
+	caps := NewRawExecDriver(NewEmptyDriverContext()).Capabilities()
+	if err := caps.Validate("raw_exec", task); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	caps = NewQemuDriver(NewEmptyDriverContext()).Capabilities()
+	err := caps.Validate("qemu", task)
+	if err == nil || !strings.Contains(err.Error(), `driver "qemu" does not support`) ||
+		!strings.Contains(err.Error(), structs.DriverCapabilityExec) {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestCapabilities_Signals(t *testing.T) {
+	task := &structs.Task{
+		Name: "foo",
+		Templates: []*structs.Template{
+			{
+				DestPath:      "local/app.conf",
+				ChangeMode:    structs.TemplateChangeModeSignal,
+				RestartSignal: "SIGHUP",
+			},
+		},
+	}
+
+	// Drivers whose handles can be signaled must advertise it so that
+	// templates using the signal change mode can be placed on them
+	signalable := map[string]struct{}{
+		"docker":   {},
+		"exec":     {},
+		"exec2":    {},
+		"java":     {},
+		"qemu":     {},
+		"raw_exec": {},
+	}
+	for name, factory := range BuiltinDrivers {
+		_, signals := signalable[name]
+		err := factory(NewEmptyDriverContext()).Capabilities().Validate(name, task)
+		if signals && err != nil {
+			t.Fatalf("driver %q: %v", name, err)
+		}
+		if !signals && err == nil {
+			t.Fatalf("driver %q should not support signals", name)
+		}
+	}
+}
+
+func TestCapabilities_Attributes(t *testing.T) {
+	caps := &Capabilities{
+		Exec:             true,
+		Pause:            true,
+		NetworkIsolation: []string{"host", "bridge"},
+	}
+	expected := map[string]string{
+		"driver.foo.capability.signals":           "false",
+		"driver.foo.capability.exec":              "true",
+		"driver.foo.capability.pause":             "true",
+		"driver.foo.capability.volumes":           "false",
+		"driver.foo.capability.network_isolation": "host,bridge",
+	}
+	if attrs := caps.Attributes("foo"); !reflect.DeepEqual(attrs, expected) {
+		t.Fatalf("bad: %#v", attrs)
+	}
+
+	if !caps.SupportsNetworkIsolation("bridge") || caps.SupportsNetworkIsolation(structs.DriverNetworkIsolationGroup) {
+		t.Fatalf("bad: %#v", caps.NetworkIsolation)
+	}
+}
+
 func TestMapMergeStrInt(t *testing.T) {
 	a := map[string]int{
 		"cakes":   5,
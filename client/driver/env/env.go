@@ -63,6 +63,10 @@ const (
 
 	// VaultToken is the environment variable for passing the Vault token
 	VaultToken = "VAULT_TOKEN"
+
+	// IdentityToken is the environment variable for passing the task's
+	// workload identity
+	IdentityToken = "NOMAD_IDENTITY_TOKEN"
 )
 
 // The node values that can be interpreted.
@@ -75,6 +79,10 @@ const (
 	// Prefixes used for lookups.
 	nodeAttributePrefix = "attr."
 	nodeMetaPrefix      = "meta."
+
+	// variablePrefix is the prefix for interpolating the items of the
+	// variables exposed to the task in its environment variables.
+	variablePrefix = "nomad_var."
 )
 
 // TaskEnvironment is used to expose information to a task via environment
@@ -98,6 +106,8 @@ type TaskEnvironment struct {
 	PortMap          map[string]int
 	VaultToken       string
 	InjectVaultToken bool
+	IdentityToken    string
+	Variables        map[string]string
 
 	// taskEnv is the variables that will be set in the tasks environment
 	TaskEnv map[string]string
@@ -213,10 +223,23 @@ func (t *TaskEnvironment) Build() *TaskEnvironment {
 		t.TaskEnv[VaultToken] = t.VaultToken
 	}
 
+	// Build the workload identity
+	if t.IdentityToken != "" {
+		t.TaskEnv[IdentityToken] = t.IdentityToken
+	}
+
+	// Set up the variable items, which may only be interpolated in the
+	// environment variables so that they aren't exposed in the task's
+	// arguments or service definitions.
+	variables := make(map[string]string, len(t.Variables))
+	for k, v := range t.Variables {
+		variables[fmt.Sprintf("%s%s", variablePrefix, k)] = v
+	}
+
 	// Interpret the environment variables
 	interpreted := make(map[string]string, len(t.Env))
 	for k, v := range t.Env {
-		interpreted[k] = hargs.ReplaceEnv(v, t.NodeValues, t.TaskEnv)
+		interpreted[k] = hargs.ReplaceEnv(v, t.NodeValues, variables, t.TaskEnv)
 	}
 
 	for k, v := range interpreted {
@@ -468,3 +491,23 @@ func (t *TaskEnvironment) ClearVaultToken() *TaskEnvironment {
 	t.InjectVaultToken = false
 	return t
 }
+
+func (t *TaskEnvironment) SetIdentityToken(token string) *TaskEnvironment {
+	t.IdentityToken = token
+	return t
+}
+
+func (t *TaskEnvironment) ClearIdentityToken() *TaskEnvironment {
+	t.IdentityToken = ""
+	return t
+}
+
+func (t *TaskEnvironment) SetVariables(items map[string]string) *TaskEnvironment {
+	t.Variables = items
+	return t
+}
+
+func (t *TaskEnvironment) ClearVariables() *TaskEnvironment {
+	t.Variables = nil
+	return t
+}
@@ -33,8 +33,8 @@ var (
 	networks = []*structs.NetworkResource{
 		&structs.NetworkResource{
 			IP:            "127.0.0.1",
-			ReservedPorts: []structs.Port{{"http", 80}},
-			DynamicPorts:  []structs.Port{{"https", 8080}},
+			ReservedPorts: []structs.Port{{"http", 80, 0, ""}},
+			DynamicPorts:  []structs.Port{{"https", 8080, 0, ""}},
 		},
 	}
 	portMap = map[string]int{
@@ -180,6 +180,41 @@ func TestEnvironment_VaultToken(t *testing.T) {
 	}
 }
 
+func TestEnvironment_IdentityToken(t *testing.T) {
+	n := mock.Node()
+	env := NewTaskEnvironment(n).SetIdentityToken("123").Build()
+
+	act := env.EnvList()
+	exp := []string{"NOMAD_IDENTITY_TOKEN=123"}
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatalf("env.List() returned %v; want %v", act, exp)
+	}
+
+	act = env.ClearIdentityToken().Build().EnvList()
+	if len(act) != 0 {
+		t.Fatalf("Unexpected environment variables: %v", act)
+	}
+}
+
+func TestEnvironment_Variables(t *testing.T) {
+	n := mock.Node()
+	env := NewTaskEnvironment(n).
+		SetEnvvars(map[string]string{"DB_PASSWORD": "${nomad_var.password}", "DB_USER": "${nomad_var.user}"}).
+		SetVariables(map[string]string{"password": "secret"}).
+		Build()
+
+	act := env.EnvMap()
+	exp := map[string]string{"DB_PASSWORD": "secret", "DB_USER": "${nomad_var.user}"}
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatalf("env.EnvMap() returned %v; want %v", act, exp)
+	}
+
+	// The items are only interpolated in the environment variables
+	if out := env.ReplaceEnv("${nomad_var.password}"); out != "${nomad_var.password}" {
+		t.Fatalf("bad: %v", out)
+	}
+}
+
 func TestEnvironment_ClearEnvvars(t *testing.T) {
 	n := mock.Node()
 	env := NewTaskEnvironment(n).
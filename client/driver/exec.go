@@ -1,6 +1,7 @@
 package driver
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -81,6 +82,17 @@ func (d *ExecDriver) Validate(config map[string]interface{}) error {
 	return nil
 }
 
+// Capabilities returns the features the exec driver supports
+func (d *ExecDriver) Capabilities() *Capabilities {
+	return &Capabilities{
+		SendSignals:      true,
+		Exec:             true,
+		Pause:            true,
+		MountVolumes:     true,
+		NetworkIsolation: executorNetworkIsolation(),
+	}
+}
+
 func (d *ExecDriver) Periodic() (bool, time.Duration) {
 	return true, 15 * time.Second
 }
@@ -130,11 +142,13 @@ func (d *ExecDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle,
 	}
 
 	ps, err := exec.LaunchCmd(&executor.ExecCommand{
-		Cmd:            command,
-		Args:           driverConfig.Args,
-		FSIsolation:    true,
-		ResourceLimits: true,
-		User:           getExecutorUser(task),
+		Cmd:              command,
+		Args:             driverConfig.Args,
+		FSIsolation:      true,
+		Namespaces:       d.config.ReadBoolDefault("exec.namespaces.enabled", true),
+		ResourceLimits:   true,
+		User:             getExecutorUser(task),
+		NetworkNamespace: ctx.NetworkNamespace,
 	}, executorCtx)
 	if err != nil {
 		pluginClient.Kill()
@@ -285,6 +299,33 @@ func (h *execHandle) Stats() (*cstructs.TaskResourceUsage, error) {
 	return h.executor.Stats()
 }
 
+// Pause pauses the task by freezing its cgroup, which also stops the processes
+// the task forked
+func (h *execHandle) Pause() error {
+	if h.isolationConfig == nil {
+		return pauseProcess(h.userPid)
+	}
+	return executor.ClientFreeze(h.isolationConfig, true)
+}
+
+// Resume resumes the task by thawing its frozen cgroup
+func (h *execHandle) Resume() error {
+	if h.isolationConfig == nil {
+		return resumeProcess(h.userPid)
+	}
+	return executor.ClientFreeze(h.isolationConfig, false)
+}
+
+// Exec runs a command next to the task's process, chrooted into the task
+func (h *execHandle) Exec(ctx context.Context, opts *ExecOptions) (int, error) {
+	return execInTask(ctx, h.userPid, true, opts)
+}
+
+// Signal sends the named signal to the task's process
+func (h *execHandle) Signal(sig string) error {
+	return signalProcess(h.userPid, sig)
+}
+
 func (h *execHandle) run() {
 	ps, err := h.executor.Wait()
 	close(h.doneCh)
@@ -0,0 +1,162 @@
+package driver
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/driver/executor"
+	dstructs "github.com/hashicorp/nomad/client/driver/structs"
+	"github.com/hashicorp/nomad/helper/discover"
+	"github.com/hashicorp/nomad/helper/fields"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	// The key populated in Node Attributes to indicate the presence of the
+	// Exec2 driver
+	exec2DriverAttr = "driver.exec2"
+
+	// The key populated in Node Attributes with the version of the Landlock
+	// ABI supported by the kernel
+	exec2LandlockAttr = "driver.exec2.landlock"
+)
+
+// Exec2Driver fork/execs tasks isolated with Landlock and user namespaces
+// instead of a chroot. Tasks may only access their task directory, the
+// shared alloc directory, the system libraries and the paths they unveil,
+// which saves building and tearing down a chroot for every task.
+type Exec2Driver struct {
+	ExecDriver
+}
+
+type Exec2DriverConfig struct {
+	Command string   `mapstructure:"command"`
+	Args    []string `mapstructure:"args"`
+	Unveil  []string `mapstructure:"unveil"`
+}
+
+// NewExec2Driver is used to create a new exec2 driver
+func NewExec2Driver(ctx *DriverContext) Driver {
+	return &Exec2Driver{ExecDriver: ExecDriver{DriverContext: *ctx}}
+}
+
+// Validate is used to validate the driver configuration
+func (d *Exec2Driver) Validate(config map[string]interface{}) error {
+	fd := &fields.FieldData{
+		Raw: config,
+		Schema: map[string]*fields.FieldSchema{
+			"command": &fields.FieldSchema{
+				Type:     fields.TypeString,
+				Required: true,
+			},
+			"args": &fields.FieldSchema{
+				Type: fields.TypeArray,
+			},
+			"unveil": &fields.FieldSchema{
+				Type: fields.TypeArray,
+			},
+		},
+	}
+
+	if err := fd.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Capabilities returns the features the exec2 driver supports. Volumes
+// can't be mounted since tasks have no chroot to mount them in.
+func (d *Exec2Driver) Capabilities() *Capabilities {
+	return &Capabilities{
+		SendSignals:      true,
+		Exec:             true,
+		Pause:            true,
+		NetworkIsolation: executorNetworkIsolation(),
+	}
+}
+
+func (d *Exec2Driver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle, error) {
+	var driverConfig Exec2DriverConfig
+	if err := mapstructure.WeakDecode(task.Config, &driverConfig); err != nil {
+		return nil, err
+	}
+
+	// Get the command to be ran
+	command := driverConfig.Command
+	if err := validateCommand(command, "args"); err != nil {
+		return nil, err
+	}
+
+	// Set the host environment variables.
+	filter := strings.Split(d.config.ReadDefault("env.blacklist", config.DefaultEnvBlacklist), ",")
+	d.taskEnv.AppendHostEnvvars(filter)
+
+	// Get the task directory for storing the executor logs.
+	taskDir, ok := ctx.AllocDir.TaskDirs[d.DriverContext.taskName]
+	if !ok {
+		return nil, fmt.Errorf("Could not find task directory for task: %v", d.DriverContext.taskName)
+	}
+
+	bin, err := discover.NomadExecutable()
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the nomad binary: %v", err)
+	}
+	pluginLogFile := filepath.Join(taskDir, fmt.Sprintf("%s-executor.out", task.Name))
+	pluginConfig := &plugin.ClientConfig{
+		Cmd: exec.Command(bin, "executor", pluginLogFile),
+	}
+
+	exec, pluginClient, err := createExecutor(pluginConfig, d.config.LogOutput, d.config)
+	if err != nil {
+		return nil, err
+	}
+	executorCtx := &executor.ExecutorContext{
+		TaskEnv:  d.taskEnv,
+		Driver:   "exec2",
+		AllocDir: ctx.AllocDir,
+		AllocID:  ctx.AllocID,
+		Task:     task,
+	}
+
+	ps, err := exec.LaunchCmd(&executor.ExecCommand{
+		Cmd:              command,
+		Args:             driverConfig.Args,
+		Sandbox:          true,
+		Unveil:           driverConfig.Unveil,
+		ResourceLimits:   true,
+		User:             getExecutorUser(task),
+		NetworkNamespace: ctx.NetworkNamespace,
+	}, executorCtx)
+	if err != nil {
+		pluginClient.Kill()
+		return nil, err
+	}
+	d.logger.Printf("[DEBUG] driver.exec2: started process via plugin with pid: %v", ps.Pid)
+
+	// Return a driver handle
+	maxKill := d.DriverContext.config.MaxKillTimeout
+	h := &execHandle{
+		pluginClient:    pluginClient,
+		userPid:         ps.Pid,
+		executor:        exec,
+		allocDir:        ctx.AllocDir,
+		isolationConfig: ps.IsolationConfig,
+		killTimeout:     GetKillTimeout(task.KillTimeout, maxKill),
+		maxKillTimeout:  maxKill,
+		logger:          d.logger,
+		version:         d.config.Version,
+		doneCh:          make(chan struct{}),
+		waitCh:          make(chan *dstructs.WaitResult, 1),
+	}
+	if err := exec.SyncServices(consulContext(d.config, "")); err != nil {
+		d.logger.Printf("[ERR] driver.exec2: error registering services with consul for task: %q: %v", task.Name, err)
+	}
+	go h.run()
+	return h, nil
+}
@@ -0,0 +1,12 @@
+//+build darwin dragonfly freebsd netbsd openbsd solaris windows
+
+package driver
+
+import (
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func (d *Exec2Driver) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,42 @@
+package driver
+
+import (
+	"strconv"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/driver/executor"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"golang.org/x/sys/unix"
+)
+
+func (d *Exec2Driver) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
+	// Get the current status so that we can log any debug messages only if the
+	// state changes
+	_, currentlyEnabled := node.Attributes[exec2DriverAttr]
+
+	// Only enable if cgroups and Landlock are available and we are root
+	disable := func(reason string) (bool, error) {
+		if currentlyEnabled {
+			d.logger.Printf("[DEBUG] driver.exec2: %s, disabling", reason)
+		}
+		delete(node.Attributes, exec2DriverAttr)
+		delete(node.Attributes, exec2LandlockAttr)
+		return false, nil
+	}
+	if _, ok := node.Attributes["unique.cgroup.mountpoint"]; !ok {
+		return disable("cgroups unavailable")
+	} else if unix.Geteuid() != 0 {
+		return disable("must run as root user")
+	}
+	abi := executor.LandlockABI()
+	if abi < 1 {
+		return disable("landlock unavailable")
+	}
+
+	if !currentlyEnabled {
+		d.logger.Printf("[DEBUG] driver.exec2: exec2 driver is enabled")
+	}
+	node.Attributes[exec2DriverAttr] = "1"
+	node.Attributes[exec2LandlockAttr] = strconv.Itoa(abi)
+	return true, nil
+}
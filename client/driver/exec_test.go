@@ -292,6 +292,40 @@ func TestExecDriver_Start_Kill_Wait(t *testing.T) {
 	}
 }
 
+func TestExecDriver_Pause_Child(t *testing.T) {
+	ctestutils.ExecCompatible(t)
+	task := &structs.Task{
+		Name: "sleep",
+		Config: map[string]interface{}{
+			"command": "/bin/sh",
+			"args": []string{
+				"-c", pauseChildScript,
+				fmt.Sprintf("${%s}/counter", env.AllocDir),
+			},
+		},
+		LogConfig: &structs.LogConfig{
+			MaxFiles:      10,
+			MaxFileSizeMB: 10,
+		},
+		Resources: basicResources,
+	}
+
+	driverCtx, execCtx := testDriverContexts(task)
+	defer execCtx.AllocDir.Destroy()
+	d := NewExecDriver(driverCtx)
+
+	handle, err := d.Start(execCtx, task)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if handle == nil {
+		t.Fatalf("missing handle")
+	}
+	defer handle.Kill()
+
+	testPauseChild(t, handle, filepath.Join(execCtx.AllocDir.SharedDir, "counter"))
+}
+
 func TestExecDriverUser(t *testing.T) {
 	ctestutils.ExecCompatible(t)
 	task := &structs.Task{
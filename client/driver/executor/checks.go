@@ -138,6 +138,7 @@ type ExecScriptCheck struct {
 	cmd      string        // command of the check
 	args     []string      // args passed to the check
 	taskDir  string        // the root directory of the check
+	netns    string        // the network namespace the check is run in
 
 	FSIsolation bool // indicates whether the check has to be run within a chroot
 }
@@ -150,7 +151,7 @@ func (e *ExecScriptCheck) Run() *cstructs.CheckResult {
 	cmd.Stderr = buf
 	e.setChroot(cmd)
 	ts := time.Now()
-	if err := cmd.Start(); err != nil {
+	if err := startCmd(cmd, e.netns); err != nil {
 		return &cstructs.CheckResult{Err: err}
 	}
 	errCh := make(chan error, 2)
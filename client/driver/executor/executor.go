@@ -22,6 +22,7 @@ import (
 	"github.com/hashicorp/nomad/client/allocdir"
 	"github.com/hashicorp/nomad/client/driver/env"
 	"github.com/hashicorp/nomad/client/driver/logging"
+	"github.com/hashicorp/nomad/client/network"
 	"github.com/hashicorp/nomad/client/stats"
 	"github.com/hashicorp/nomad/command/agent/consul"
 	shelpers "github.com/hashicorp/nomad/helper/stats"
@@ -130,12 +131,34 @@ type ExecCommand struct {
 	// FSIsolation determines whether the command would be run in a chroot.
 	FSIsolation bool
 
+	// Namespaces determines whether the command is run in its own PID, mount
+	// and IPC namespaces. It only applies along with FSIsolation.
+	Namespaces bool
+
 	// User is the user which the executor uses to run the command.
 	User string
 
 	// ResourceLimits determines whether resource limits are enforced by the
 	// executor.
 	ResourceLimits bool
+
+	// NetworkNamespace is the path of the network namespace the command is
+	// started in. The command uses the network of the host if it is empty.
+	NetworkNamespace string
+
+	// Sandbox determines whether the command is restricted to its task
+	// directory and the unveiled paths with Landlock, and run in its own
+	// user namespace, instead of being run in a chroot.
+	Sandbox bool
+
+	// Unveil are the paths the sandboxed command may access on top of the
+	// default ones, in the form "<modes>:<path>".
+	Unveil []string
+
+	// ProcessGroup determines whether the command is started as the leader
+	// of its own process group, so that it can be signalled along with the
+	// processes it forks.
+	ProcessGroup bool
 }
 
 // ProcessState holds information about the state of a user process.
@@ -297,10 +320,28 @@ func (e *UniversalExecutor) LaunchCmd(command *ExecCommand, ctx *ExecutorContext
 	e.cmd.Args = append([]string{e.cmd.Path}, ctx.TaskEnv.ParseAndReplace(command.Args)...)
 	e.cmd.Env = ctx.TaskEnv.EnvList()
 
+	if command.ProcessGroup {
+		setProcessGroup(&e.cmd)
+	}
+
 	// Start the process
-	if err := e.cmd.Start(); err != nil {
+	if command.Sandbox {
+		rules, err := e.sandboxRules()
+		if err != nil {
+			return nil, err
+		}
+		if err := startSandboxedCmd(&e.cmd, command.NetworkNamespace, rules); err != nil {
+			return nil, err
+		}
+	} else if err := startCmd(&e.cmd, command.NetworkNamespace); err != nil {
 		return nil, err
 	}
+
+	// Now that the user process was started in the resource container, leave
+	// it so the executor keeps running while the task is paused.
+	if err := e.leaveCgroup(); err != nil {
+		e.logger.Printf("[ERR] executor: error leaving cgroup: %v", err)
+	}
 	go e.collectPids()
 	go e.wait()
 	ic := e.resConCtx.getIsolationConfig()
@@ -382,6 +423,10 @@ func (e *UniversalExecutor) UpdateTask(task *structs.Task) error {
 func generateServiceKeys(allocID string, services []*structs.Service) map[consul.ServiceKey]*structs.Service {
 	keys := make(map[consul.ServiceKey]*structs.Service, len(services))
 	for _, service := range services {
+		// Services using the Nomad provider are registered by the client
+		if service.Provider == structs.ServiceProviderNomad {
+			continue
+		}
 		key := consul.GenerateServiceKey(service)
 		keys[key] = service
 	}
@@ -433,6 +478,13 @@ func ClientCleanup(ic *dstructs.IsolationConfig, pid int) error {
 	return clientCleanup(ic, pid)
 }
 
+// ClientFreeze is the routine that a Nomad Client uses to freeze or thaw the
+// resource container of a child UniversalExecutor, and so all the processes
+// of the task.
+func ClientFreeze(ic *dstructs.IsolationConfig, frozen bool) error {
+	return clientFreeze(ic, frozen)
+}
+
 // Exit cleans up the alloc directory, destroys resource container and kills the
 // user process
 func (e *UniversalExecutor) Exit() error {
@@ -493,6 +545,19 @@ func (e *UniversalExecutor) ShutDown() error {
 		}
 		return nil
 	}
+	// Kill the command right away if it would ignore the interrupt. The
+	// mounts of the chroot are removed as Exit would after the kill timeout,
+	// so that they aren't mounted again when the task restarts.
+	if e.interruptIgnored() {
+		if err := proc.Kill(); err != nil && err.Error() != finishedErr {
+			return fmt.Errorf("executor.shutdown error: %v", err)
+		}
+		<-e.processExited
+		if e.command.FSIsolation {
+			return e.removeChrootMounts()
+		}
+		return nil
+	}
 	if err = proc.Signal(os.Interrupt); err != nil && err.Error() != finishedErr {
 		return fmt.Errorf("executor.shutdown error: %v", err)
 	}
@@ -705,6 +770,7 @@ func (e *UniversalExecutor) createCheck(check *structs.ServiceCheck, checkID str
 			cmd:         check.Command,
 			args:        check.Args,
 			taskDir:     e.taskDir,
+			netns:       e.command.NetworkNamespace,
 			FSIsolation: e.command.FSIsolation,
 		}, nil
 
@@ -712,18 +778,18 @@ func (e *UniversalExecutor) createCheck(check *structs.ServiceCheck, checkID str
 	return nil, fmt.Errorf("couldn't create check for %v", check.Name)
 }
 
-// interpolateServices interpolates tags in a service and checks with values from the
-// task's environment.
+// interpolateServices interpolates the name and tags of a service
+// and the fields of its checks with values from the task's environment.
 func (e *UniversalExecutor) interpolateServices(task *structs.Task) {
 	e.ctx.TaskEnv.Build()
 	for _, service := range task.Services {
 		for _, check := range service.Checks {
+			check.Name = e.ctx.TaskEnv.ReplaceEnv(check.Name)
+			check.Path = e.ctx.TaskEnv.ReplaceEnv(check.Path)
+			check.Protocol = e.ctx.TaskEnv.ReplaceEnv(check.Protocol)
 			if check.Type == structs.ServiceCheckScript {
-				check.Name = e.ctx.TaskEnv.ReplaceEnv(check.Name)
 				check.Command = e.ctx.TaskEnv.ReplaceEnv(check.Command)
 				check.Args = e.ctx.TaskEnv.ParseAndReplace(check.Args)
-				check.Path = e.ctx.TaskEnv.ReplaceEnv(check.Path)
-				check.Protocol = e.ctx.TaskEnv.ReplaceEnv(check.Protocol)
 			}
 		}
 		service.Name = e.ctx.TaskEnv.ReplaceEnv(service.Name)
@@ -854,3 +920,12 @@ func (e *UniversalExecutor) aggregatedResourceUsage(pidStats map[string]*cstruct
 		Pids:          pidStats,
 	}
 }
+
+// startCmd starts the command in the network namespace at the given path. The
+// command uses the network of the host if the path is empty.
+func startCmd(cmd *exec.Cmd, netns string) error {
+	if netns == "" {
+		return cmd.Start()
+	}
+	return network.WithNetNS(netns, cmd.Start)
+}
@@ -25,6 +25,14 @@ func (e *UniversalExecutor) applyLimits(pid int) error {
 	return nil
 }
 
+func (e *UniversalExecutor) leaveCgroup() error {
+	return nil
+}
+
+func (e *UniversalExecutor) interruptIgnored() bool {
+	return false
+}
+
 func (e *UniversalExecutor) configureIsolation() error {
 	return nil
 }
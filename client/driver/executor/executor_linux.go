@@ -2,6 +2,7 @@ package executor
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -51,6 +52,11 @@ func (e *UniversalExecutor) configureIsolation() error {
 		if err := e.configureChroot(); err != nil {
 			return err
 		}
+		if e.command.Namespaces {
+			e.configureNamespaces()
+		}
+	} else if e.command.Sandbox {
+		e.configureUserNamespace()
 	}
 
 	if e.command.ResourceLimits {
@@ -91,6 +97,18 @@ func (e *UniversalExecutor) applyLimits(pid int) error {
 	return nil
 }
 
+// leaveCgroup moves the executor back out of the pre-configured cgroup once
+// the user process has been started in it, so that freezing the cgroup to
+// pause the task doesn't freeze the executor as well
+func (e *UniversalExecutor) leaveCgroup() error {
+	if !e.command.ResourceLimits {
+		return nil
+	}
+
+	rootGroup := &cgroupConfig.Cgroup{Path: "/", Resources: &cgroupConfig.Resources{}}
+	return getCgroupManager(rootGroup, nil).Apply(os.Getpid())
+}
+
 // configureCgroups converts a Nomad Resources specification into the equivalent
 // cgroup configuration. It returns an error if the resources are invalid.
 func (e *UniversalExecutor) configureCgroups(resources *structs.Resources) error {
@@ -236,6 +254,12 @@ func (e *UniversalExecutor) configureChroot() error {
 		return err
 	}
 
+	// The volumes are mounted once the chroot is built so that building it
+	// doesn't write into them
+	if err := allocDir.MountVolumes(e.ctx.Task.Name); err != nil {
+		return err
+	}
+
 	// Set the tasks AllocDir environment variable.
 	e.ctx.TaskEnv.
 		SetAllocDir(filepath.Join("/", allocdir.SharedAllocName)).
@@ -256,6 +280,50 @@ func (e *UniversalExecutor) configureChroot() error {
 	return nil
 }
 
+// configureNamespaces runs the command in its own PID, mount and IPC
+// namespaces, so that it can't signal the processes of the host nor of the
+// other tasks, and the mounts and IPC objects it creates stay private. The
+// command is the init process of its PID namespace, so the other processes
+// of the task are killed along with it.
+func (e *UniversalExecutor) configureNamespaces() {
+	if e.cmd.SysProcAttr == nil {
+		e.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	e.cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWPID | syscall.CLONE_NEWNS | syscall.CLONE_NEWIPC
+}
+
+// interruptIgnored returns whether the command would ignore an interrupt.
+// The kernel drops the signals that the init process of a PID namespace has
+// no handler for, so a command run in its own namespaces that doesn't catch
+// interrupts would only be killed once the kill timeout expires.
+func (e *UniversalExecutor) interruptIgnored() bool {
+	if e.cmd.SysProcAttr == nil || e.cmd.SysProcAttr.Cloneflags&syscall.CLONE_NEWPID == 0 {
+		return false
+	}
+	caught, err := catchesSignal(e.cmd.Process.Pid, syscall.SIGINT)
+	return err == nil && !caught
+}
+
+// catchesSignal returns whether the process has installed a handler for the
+// signal, as listed in the caught signals mask of its status
+func catchesSignal(pid int, sig syscall.Signal) (bool, error) {
+	status, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return false, err
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if !strings.HasPrefix(line, "SigCgt:") {
+			continue
+		}
+		mask, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "SigCgt:")), 16, 64)
+		if err != nil {
+			return false, err
+		}
+		return mask&(1<<uint(sig-1)) != 0, nil
+	}
+	return false, fmt.Errorf("caught signals of process %d not found", pid)
+}
+
 // cleanTaskDir is an idempotent operation to clean the task directory and
 // should be called when tearing down the task.
 func (e *UniversalExecutor) removeChrootMounts() error {
@@ -367,6 +435,19 @@ func DestroyCgroup(groups *cgroupConfig.Cgroup, cgPaths map[string]string, execu
 	return mErrs.ErrorOrNil()
 }
 
+// FreezeCgroup freezes or thaws all the processes in the cgroup
+func FreezeCgroup(groups *cgroupConfig.Cgroup, cgPaths map[string]string, frozen bool) error {
+	if groups == nil {
+		return fmt.Errorf("Can't freeze: cgroup configuration empty")
+	}
+
+	state := cgroupConfig.Thawed
+	if frozen {
+		state = cgroupConfig.Frozen
+	}
+	return getCgroupManager(groups, cgPaths).Freeze(state)
+}
+
 // getCgroupManager returns the correct libcontainer cgroup manager.
 func getCgroupManager(groups *cgroupConfig.Cgroup, paths map[string]string) cgroups.Manager {
 	return &cgroupFs.Manager{Cgroups: groups, Paths: paths}
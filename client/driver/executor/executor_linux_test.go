@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	cstructs "github.com/hashicorp/nomad/client/driver/structs"
 	"github.com/hashicorp/nomad/client/testutil"
 	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
 )
 
 func testExecutorContextWithChroot(t *testing.T) *ExecutorContext {
@@ -109,3 +111,86 @@ ld.so.conf.d/`
 		t.Fatalf("Command output incorrectly: want %v; got %v", expected, act)
 	}
 }
+
+func TestExecutor_NetworkNamespace(t *testing.T) {
+	testutil.ExecCompatible(t)
+	if _, err := exec.LookPath("ip"); err != nil {
+		t.Skip("ip command is required to create network namespaces")
+	}
+
+	name := structs.GenerateUUID()
+	if out, err := exec.Command("ip", "netns", "add", name).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create network namespace: %v: %s", err, out)
+	}
+	defer exec.Command("ip", "netns", "delete", name).Run()
+	netns := filepath.Join("/var/run/netns", name)
+
+	execCmd := ExecCommand{Cmd: "/bin/readlink", Args: []string{"/proc/self/ns/net"}, NetworkNamespace: netns}
+	ctx := testExecutorContext(t)
+	defer ctx.AllocDir.Destroy()
+	executor := NewExecutor(log.New(os.Stdout, "", log.LstdFlags))
+	if _, err := executor.LaunchCmd(&execCmd, ctx); err != nil {
+		t.Fatalf("error in launching command: %v", err)
+	}
+	if _, err := executor.Wait(); err != nil {
+		t.Fatalf("error in waiting for command: %v", err)
+	}
+	if err := executor.Exit(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	hostNS, err := os.Readlink("/proc/self/ns/net")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	output, err := ioutil.ReadFile(filepath.Join(ctx.AllocDir.LogDir(), "web.stdout.0"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if act := strings.TrimSpace(string(output)); act == "" || act == hostNS {
+		t.Fatalf("command not started in network namespace %q: %q", netns, act)
+	}
+}
+
+func TestExecutor_Namespaces(t *testing.T) {
+	testutil.ExecCompatible(t)
+
+	execCmd := ExecCommand{
+		Cmd:         "/bin/readlink",
+		Args:        []string{"/proc/self/ns/pid", "/proc/self/ns/ipc", "/proc/self/ns/mnt"},
+		FSIsolation: true,
+		Namespaces:  true,
+	}
+	ctx := testExecutorContextWithChroot(t)
+	ctx.ChrootEnv["/bin/readlink"] = "/bin/readlink"
+	defer ctx.AllocDir.Destroy()
+
+	executor := NewExecutor(log.New(os.Stdout, "", log.LstdFlags))
+	if _, err := executor.LaunchCmd(&execCmd, ctx); err != nil {
+		t.Fatalf("error in launching command: %v", err)
+	}
+	if _, err := executor.Wait(); err != nil {
+		t.Fatalf("error in waiting for command: %v", err)
+	}
+	if err := executor.Exit(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	output, err := ioutil.ReadFile(filepath.Join(ctx.AllocDir.LogDir(), "web.stdout.0"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	namespaces := strings.Fields(string(output))
+	if len(namespaces) != 3 {
+		t.Fatalf("bad: %q", output)
+	}
+	for i, ns := range []string{"pid", "ipc", "mnt"} {
+		hostNS, err := os.Readlink(filepath.Join("/proc/self/ns", ns))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if namespaces[i] == hostNS {
+			t.Fatalf("command not started in its own %s namespace: %q", ns, namespaces[i])
+		}
+	}
+}
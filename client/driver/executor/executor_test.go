@@ -269,6 +269,16 @@ func TestExecutor_MakeExecutable(t *testing.T) {
 
 func TestExecutorInterpolateServices(t *testing.T) {
 	task := mock.Job().TaskGroups[0].Tasks[0]
+	task.Services[1].Checks = []*structs.ServiceCheck{
+		{
+			Name:     "${node.datacenter}-health",
+			Type:     structs.ServiceCheckHTTP,
+			Path:     "/health/${meta.version}",
+			Protocol: "http",
+			Interval: 30 * time.Second,
+			Timeout:  5 * time.Second,
+		},
+	}
 	// Make a fake exececutor
 	ctx := testExecutorContext(t)
 	defer ctx.AllocDir.Destroy()
@@ -290,6 +300,11 @@ func TestExecutorInterpolateServices(t *testing.T) {
 	if !reflect.DeepEqual(task.Services[0].Checks[0].Args, expectedCheckArgs) {
 		t.Fatalf("expected: %v, actual: %v", expectedCheckArgs, task.Services[0].Checks[0].Args)
 	}
+
+	httpCheck := task.Services[1].Checks[0]
+	if httpCheck.Name != "dc1-health" || httpCheck.Path != "/health/5.6" {
+		t.Fatalf("bad: %#v", httpCheck)
+	}
 }
 
 func TestScanPids(t *testing.T) {
@@ -0,0 +1,11 @@
+// +build windows
+
+package executor
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is not supported on Windows
+func setProcessGroup(cmd *exec.Cmd) {
+}
@@ -3,6 +3,8 @@
 package executor
 
 import (
+	"fmt"
+
 	dstructs "github.com/hashicorp/nomad/client/driver/structs"
 )
 
@@ -15,6 +17,10 @@ func clientCleanup(ic *dstructs.IsolationConfig, pid int) error {
 	return nil
 }
 
+func clientFreeze(ic *dstructs.IsolationConfig, frozen bool) error {
+	return fmt.Errorf("freezing resource containers is not supported")
+}
+
 func (rc *resourceContainerContext) executorCleanup() error {
 	return nil
 }
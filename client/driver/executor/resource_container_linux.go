@@ -24,6 +24,12 @@ func clientCleanup(ic *dstructs.IsolationConfig, pid int) error {
 	return nil
 }
 
+// clientFreeze freezes or thaws this host's Cgroup from the Nomad Client's
+// context
+func clientFreeze(ic *dstructs.IsolationConfig, frozen bool) error {
+	return FreezeCgroup(ic.Cgroup, ic.CgroupPaths, frozen)
+}
+
 // cleanup removes this host's Cgroup from within an Executor's context
 func (rc *resourceContainerContext) executorCleanup() error {
 	rc.cgLock.Lock()
@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// defaultUnveil are the paths every sandboxed command may access, so
+	// that it can load its libraries and resolve names. Paths that don't
+	// exist on the host are skipped.
+	defaultUnveil = []string{
+		"rx:/bin",
+		"rx:/lib",
+		"rx:/lib32",
+		"rx:/lib64",
+		"rx:/sbin",
+		"rx:/usr",
+		"r:/etc",
+		"r:/run/resolvconf",
+
+		// The user namespace mappings of the command are written to /proc
+		// by the thread starting it, which is restricted by then. The
+		// command holds no capability, so its user still limits what it
+		// may write there.
+		"rw:/proc",
+
+		"rw:/dev/null",
+		"rw:/dev/zero",
+		"r:/dev/random",
+		"r:/dev/urandom",
+	}
+)
+
+// unveilRule is a path a sandboxed command may access, along with the modes
+// it may access it with: "r" to read, "w" to write, "x" to execute and "c"
+// to create and remove files beneath it.
+type unveilRule struct {
+	Path  string
+	Modes string
+}
+
+// parseUnveil parses a rule of the form "<modes>:<path>"
+func parseUnveil(s string) (*unveilRule, error) {
+	idx := strings.Index(s, ":")
+	if idx < 1 {
+		return nil, fmt.Errorf("unveil %q: must be of the form \"<modes>:<path>\"", s)
+	}
+	rule := &unveilRule{
+		Modes: s[:idx],
+		Path:  filepath.Clean(s[idx+1:]),
+	}
+	for _, m := range rule.Modes {
+		if !strings.ContainsRune("rwxc", m) {
+			return nil, fmt.Errorf("unveil %q: invalid mode %q", s, m)
+		}
+	}
+	if !filepath.IsAbs(rule.Path) {
+		return nil, fmt.Errorf("unveil %q: path must be absolute", s)
+	}
+	return rule, nil
+}
+
+// sandboxRules returns the paths the sandboxed command may access: the
+// default ones, its task directory, the shared alloc directory and the
+// unveiled paths of the command
+func (e *UniversalExecutor) sandboxRules() ([]*unveilRule, error) {
+	var rules []*unveilRule
+	for _, s := range defaultUnveil {
+		rule, err := parseUnveil(s)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(rule.Path); err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	rules = append(rules,
+		&unveilRule{Path: e.taskDir, Modes: "rwxc"},
+		&unveilRule{Path: e.ctx.AllocDir.SharedDir, Modes: "rwc"},
+	)
+
+	for _, s := range e.command.Unveil {
+		rule, err := parseUnveil(e.ctx.TaskEnv.ReplaceEnv(s))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(rule.Path); err != nil {
+			return nil, fmt.Errorf("unveil %q: %v", s, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,19 @@
+// +build darwin dragonfly freebsd netbsd openbsd solaris windows
+
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// LandlockABI returns the version of the Landlock ABI supported by the
+// kernel, which is 0 when Landlock isn't available
+func LandlockABI() int {
+	return 0
+}
+
+// startSandboxedCmd starts the command restricted to the paths of the rules
+func startSandboxedCmd(cmd *exec.Cmd, netns string, rules []*unveilRule) error {
+	return fmt.Errorf("sandboxing is only supported on linux")
+}
@@ -0,0 +1,206 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/hashicorp/nomad/client/network"
+)
+
+const (
+	// The system calls of Landlock, which share their numbers across
+	// architectures
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	// landlockCreateRulesetVersion queries the version of the ABI
+	landlockCreateRulesetVersion = 1 << 0
+
+	// landlockRulePathBeneath is the type of the rules allowing access to a
+	// file or the hierarchy beneath a directory
+	landlockRulePathBeneath = 1
+
+	// The filesystem access rights of the first version of the ABI
+	landlockAccessExecute    = 1 << 0
+	landlockAccessWriteFile  = 1 << 1
+	landlockAccessReadFile   = 1 << 2
+	landlockAccessReadDir    = 1 << 3
+	landlockAccessRemoveDir  = 1 << 4
+	landlockAccessRemoveFile = 1 << 5
+	landlockAccessMakeChar   = 1 << 6
+	landlockAccessMakeDir    = 1 << 7
+	landlockAccessMakeReg    = 1 << 8
+	landlockAccessMakeSock   = 1 << 9
+	landlockAccessMakeFifo   = 1 << 10
+	landlockAccessMakeBlock  = 1 << 11
+	landlockAccessMakeSym    = 1 << 12
+
+	// landlockHandledAccess are the access rights denied unless allowed by
+	// a rule
+	landlockHandledAccess = 1<<13 - 1
+
+	// landlockFileAccess are the access rights that apply to files, rules
+	// on files may only allow those
+	landlockFileAccess = landlockAccessExecute | landlockAccessWriteFile | landlockAccessReadFile
+
+	// prSetNoNewPrivs is the prctl option preventing a thread and its
+	// children from gaining privileges, which Landlock requires
+	prSetNoNewPrivs = 38
+
+	// oPath opens a file only to refer to it
+	oPath = 0x200000
+)
+
+// landlockRulesetAttr is the landlock_ruleset_attr of the kernel
+type landlockRulesetAttr struct {
+	HandledAccessFS uint64
+}
+
+// landlockPathBeneathAttr is the landlock_path_beneath_attr of the kernel.
+// The kernel struct is packed, which only affects its trailing padding.
+type landlockPathBeneathAttr struct {
+	AllowedAccess uint64
+	ParentFd      int32
+}
+
+// LandlockABI returns the version of the Landlock ABI supported by the
+// kernel, which is 0 when Landlock isn't available
+func LandlockABI() int {
+	abi, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return 0
+	}
+	return int(abi)
+}
+
+// landlockAccess returns the access rights of the modes of a rule
+func landlockAccess(modes string) uint64 {
+	var access uint64
+	for _, m := range modes {
+		switch m {
+		case 'r':
+			access |= landlockAccessReadFile | landlockAccessReadDir
+		case 'w':
+			access |= landlockAccessWriteFile
+		case 'x':
+			access |= landlockAccessExecute
+		case 'c':
+			access |= landlockAccessRemoveDir | landlockAccessRemoveFile |
+				landlockAccessMakeChar | landlockAccessMakeDir | landlockAccessMakeReg |
+				landlockAccessMakeSock | landlockAccessMakeFifo | landlockAccessMakeBlock |
+				landlockAccessMakeSym
+		}
+	}
+	return access
+}
+
+// configureUserNamespace runs the command in its own user and IPC
+// namespaces. The user of the command is mapped to itself, so that it
+// keeps access to its files while holding no capability on the host.
+func (e *UniversalExecutor) configureUserNamespace() {
+	if e.cmd.SysProcAttr == nil {
+		e.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	uid, gid := os.Getuid(), os.Getgid()
+	if cred := e.cmd.SysProcAttr.Credential; cred != nil {
+		uid, gid = int(cred.Uid), int(cred.Gid)
+
+		// Supplementary groups can't be set within the user namespace
+		cred.NoSetGroups = true
+	}
+	e.cmd.SysProcAttr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWIPC
+	e.cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+	e.cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+}
+
+// startSandboxedCmd starts the command restricted by Landlock to the paths
+// of the rules. Landlock restricts the thread enforcing it and the processes
+// it forks, so the command is started from a thread dedicated to it. The
+// thread is never unlocked, so that the runtime discards it once done
+// rather than reusing it or spawning threads from it.
+func startSandboxedCmd(cmd *exec.Cmd, netns string, rules []*unveilRule) error {
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+
+		start := func() error {
+			if err := landlockRestrictSelf(rules); err != nil {
+				return err
+			}
+			return cmd.Start()
+		}
+		if netns == "" {
+			errCh <- start()
+			return
+		}
+		errCh <- network.WithNetNS(netns, start)
+	}()
+	return <-errCh
+}
+
+// landlockRestrictSelf restricts the current thread to the paths of the
+// rules
+func landlockRestrictSelf(rules []*unveilRule) error {
+	if LandlockABI() < 1 {
+		return fmt.Errorf("landlock is not supported by the kernel")
+	}
+
+	attr := landlockRulesetAttr{HandledAccessFS: landlockHandledAccess}
+	fd, _, errno := syscall.Syscall(sysLandlockCreateRuleset,
+		uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to create landlock ruleset: %v", errno)
+	}
+	defer syscall.Close(int(fd))
+
+	for _, rule := range rules {
+		if err := landlockAddRule(int(fd), rule); err != nil {
+			return err
+		}
+	}
+
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to set no_new_privs: %v", errno)
+	}
+	if _, _, errno := syscall.Syscall(sysLandlockRestrictSelf, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to enforce landlock ruleset: %v", errno)
+	}
+	return nil
+}
+
+// landlockAddRule adds a rule to a ruleset
+func landlockAddRule(rulesetFd int, rule *unveilRule) error {
+	fd, err := syscall.Open(rule.Path, oPath|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %v", rule.Path, err)
+	}
+	defer syscall.Close(fd)
+
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		return fmt.Errorf("failed to stat %q: %v", rule.Path, err)
+	}
+	access := landlockAccess(rule.Modes)
+	if st.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		access &= landlockFileAccess
+	}
+	if access == 0 {
+		return nil
+	}
+
+	attr := landlockPathBeneathAttr{
+		AllowedAccess: access,
+		ParentFd:      int32(fd),
+	}
+	_, _, errno := syscall.Syscall6(sysLandlockAddRule, uintptr(rulesetFd),
+		landlockRulePathBeneath, uintptr(unsafe.Pointer(&attr)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to add landlock rule for %q: %v", rule.Path, errno)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseUnveil(t *testing.T) {
+	rule, err := parseUnveil("rwc:/opt/data/")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := &unveilRule{Path: "/opt/data", Modes: "rwc"}
+	if !reflect.DeepEqual(rule, expected) {
+		t.Fatalf("bad: %#v", rule)
+	}
+
+	for _, s := range []string{"/opt/data", ":/opt/data", "rz:/opt/data", "r:opt/data"} {
+		if _, err := parseUnveil(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
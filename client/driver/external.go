@@ -0,0 +1,426 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/client/config"
+	dstructs "github.com/hashicorp/nomad/client/driver/structs"
+	"github.com/hashicorp/nomad/client/fingerprint"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/plugins/drivers"
+)
+
+// externalDriver is an external task driver found in the plugin directory
+type externalDriver struct {
+	name string
+	path string
+
+	// caps caches the capabilities of the driver, which are fetched when
+	// it is fingerprinted
+	caps     *Capabilities
+	capsLock sync.Mutex
+}
+
+// LoadExternalDrivers registers the external task drivers found in the
+// plugin directory, returning their names. Executables named like a builtin
+// driver are skipped.
+func LoadExternalDrivers(dir string, logger *log.Logger) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read plugin directory %q: %v", dir, err)
+	}
+
+	var names []string
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), drivers.ExecutablePrefix) {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(f.Name(), drivers.ExecutablePrefix), ".exe")
+		if name == "" {
+			continue
+		}
+		if _, ok := BuiltinDrivers[name]; ok {
+			logger.Printf("[WARN] driver: skipping external driver %q: a builtin driver has the same name", name)
+			continue
+		}
+
+		ext := &externalDriver{name: name, path: filepath.Join(dir, f.Name())}
+		BuiltinDrivers[name] = func(ctx *DriverContext) Driver {
+			return newExternalDriver(ctx, ext)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// launch starts the driver's executable, or reattaches to it if reattach is
+// set, and dispenses the task driver it serves
+func (e *externalDriver) launch(reattach *plugin.ReattachConfig, w io.Writer,
+	clientConfig *config.Config) (drivers.TaskDriver, *plugin.Client, error) {
+	pluginConfig := &plugin.ClientConfig{
+		HandshakeConfig: drivers.HandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			drivers.PluginName: new(drivers.Plugin),
+		},
+		Reattach: reattach,
+		Stderr:   w,
+	}
+	if clientConfig != nil {
+		pluginConfig.MinPort = clientConfig.ClientMinPort
+		pluginConfig.MaxPort = clientConfig.ClientMaxPort
+	}
+	if reattach == nil {
+		// Isolate the driver from the signals sent to the client so that
+		// its tasks survive restarts of the client
+		pluginConfig.Cmd = exec.Command(e.path)
+		isolateCommand(pluginConfig.Cmd)
+	}
+
+	pluginClient := plugin.NewClient(pluginConfig)
+	rpcClient, err := pluginClient.Client()
+	if err != nil {
+		pluginClient.Kill()
+		return nil, nil, fmt.Errorf("error creating rpc client for driver plugin %q: %v", e.name, err)
+	}
+	raw, err := rpcClient.Dispense(drivers.PluginName)
+	if err != nil {
+		pluginClient.Kill()
+		return nil, nil, fmt.Errorf("unable to dispense the driver plugin %q: %v", e.name, err)
+	}
+	return raw.(drivers.TaskDriver), pluginClient, nil
+}
+
+// capabilities returns the cached capabilities of the driver, fetching them
+// if they weren't yet
+func (e *externalDriver) capabilities(w io.Writer) (*Capabilities, error) {
+	e.capsLock.Lock()
+	defer e.capsLock.Unlock()
+	if e.caps != nil {
+		return e.caps, nil
+	}
+
+	impl, pluginClient, err := e.launch(nil, w, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer pluginClient.Kill()
+	return e.fetchCapabilitiesLocked(impl)
+}
+
+// refreshCapabilities fetches the capabilities of the driver from a running
+// instance of it
+func (e *externalDriver) refreshCapabilities(impl drivers.TaskDriver) error {
+	e.capsLock.Lock()
+	defer e.capsLock.Unlock()
+	_, err := e.fetchCapabilitiesLocked(impl)
+	return err
+}
+
+// fetchCapabilitiesLocked fetches and caches the capabilities of the driver.
+// The capsLock must be held.
+func (e *externalDriver) fetchCapabilitiesLocked(impl drivers.TaskDriver) (*Capabilities, error) {
+	caps, err := impl.Capabilities()
+	if err != nil {
+		return nil, err
+	}
+	e.caps = &Capabilities{
+		SendSignals:      caps.SendSignals,
+		Exec:             caps.Exec,
+		MountVolumes:     caps.MountVolumes,
+		NetworkIsolation: caps.NetworkIsolation,
+	}
+	return e.caps, nil
+}
+
+// ExternalDriver runs tasks with an external task driver. Each task is
+// started by its own instance of the driver's executable, which the client
+// reattaches to after restarting.
+type ExternalDriver struct {
+	DriverContext
+	fingerprint.StaticFingerprinter
+
+	external *externalDriver
+}
+
+// externalHandle is returned from Start/Open as a handle to a task of an
+// external driver
+type externalHandle struct {
+	name           string
+	pluginClient   *plugin.Client
+	impl           drivers.TaskDriver
+	taskHandle     *drivers.TaskHandle
+	killTimeout    time.Duration
+	maxKillTimeout time.Duration
+	logger         *log.Logger
+	waitCh         chan *dstructs.WaitResult
+	doneCh         chan struct{}
+}
+
+// newExternalDriver is used to create a new driver running tasks with the
+// given external driver
+func newExternalDriver(ctx *DriverContext, external *externalDriver) Driver {
+	return &ExternalDriver{DriverContext: *ctx, external: external}
+}
+
+// logOutput returns the writer the output of the driver's executable is
+// written to
+func (d *ExternalDriver) logOutput() io.Writer {
+	if d.config == nil || d.config.LogOutput == nil {
+		return os.Stderr
+	}
+	return d.config.LogOutput
+}
+
+// Validate is used to validate the driver configuration. The configuration
+// is validated by the external driver when the task is started.
+func (d *ExternalDriver) Validate(config map[string]interface{}) error {
+	return nil
+}
+
+// Capabilities returns the features the external driver supports
+func (d *ExternalDriver) Capabilities() *Capabilities {
+	caps, err := d.external.capabilities(d.logOutput())
+	if err != nil {
+		if d.logger != nil {
+			d.logger.Printf("[ERR] driver.%s: failed to fetch capabilities: %v", d.external.name, err)
+		}
+		return &Capabilities{}
+	}
+	return caps
+}
+
+func (d *ExternalDriver) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
+	attr := fmt.Sprintf("driver.%s", d.external.name)
+
+	// A broken external driver is reported as undetected rather than
+	// failing the client
+	impl, pluginClient, err := d.external.launch(nil, d.logOutput(), cfg)
+	if err != nil {
+		d.logger.Printf("[ERR] driver.%s: failed to launch driver: %v", d.external.name, err)
+		delete(node.Attributes, attr)
+		return false, nil
+	}
+	defer pluginClient.Kill()
+
+	resp, err := impl.Fingerprint(&drivers.FingerprintRequest{
+		Options:    cfg.Options,
+		Attributes: node.Attributes,
+	})
+	if err != nil {
+		d.logger.Printf("[ERR] driver.%s: failed to fingerprint: %v", d.external.name, err)
+		delete(node.Attributes, attr)
+		return false, nil
+	}
+	if !resp.Detected {
+		delete(node.Attributes, attr)
+		return false, nil
+	}
+
+	// Fetch the capabilities while the driver is running so they are
+	// advertised without launching it again
+	if err := d.external.refreshCapabilities(impl); err != nil {
+		d.logger.Printf("[ERR] driver.%s: failed to fetch capabilities: %v", d.external.name, err)
+		delete(node.Attributes, attr)
+		return false, nil
+	}
+
+	for k, v := range resp.Attributes {
+		node.Attributes[k] = v
+	}
+	node.Attributes[attr] = "1"
+	return true, nil
+}
+
+func (d *ExternalDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle, error) {
+	taskDir, ok := ctx.AllocDir.TaskDirs[d.DriverContext.taskName]
+	if !ok {
+		return nil, fmt.Errorf("Could not find task directory for task: %v", d.DriverContext.taskName)
+	}
+
+	impl, pluginClient, err := d.external.launch(nil, d.logOutput(), d.config)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &drivers.TaskConfig{
+		ID:         fmt.Sprintf("%s/%s", ctx.AllocID, task.Name),
+		Name:       task.Name,
+		AllocID:    ctx.AllocID,
+		Config:     task.Config,
+		Env:        d.taskEnv.EnvMap(),
+		User:       task.User,
+		AllocDir:   ctx.AllocDir.SharedDir,
+		TaskDir:    taskDir,
+		SecretsDir: filepath.Join(taskDir, allocdir.TaskSecrets),
+		StdoutPath: filepath.Join(ctx.AllocDir.LogDir(), fmt.Sprintf("%s.stdout.0", task.Name)),
+		StderrPath: filepath.Join(ctx.AllocDir.LogDir(), fmt.Sprintf("%s.stderr.0", task.Name)),
+	}
+	if task.Resources != nil {
+		cfg.CPU = task.Resources.CPU
+		cfg.MemoryMB = task.Resources.MemoryMB
+	}
+	for _, m := range ctx.AllocDir.VolumeMounts[task.Name] {
+		cfg.Mounts = append(cfg.Mounts, &drivers.MountConfig{
+			HostPath: m.HostPath,
+			TaskPath: m.TaskPath,
+			ReadOnly: m.ReadOnly,
+			Shared:   m.Shared,
+		})
+	}
+
+	taskHandle, err := impl.StartTask(cfg)
+	if err != nil {
+		pluginClient.Kill()
+		return nil, err
+	}
+	d.logger.Printf("[DEBUG] driver.%s: started task %q", d.external.name, taskHandle.ID)
+
+	maxKill := d.DriverContext.config.MaxKillTimeout
+	h := &externalHandle{
+		name:           d.external.name,
+		pluginClient:   pluginClient,
+		impl:           impl,
+		taskHandle:     taskHandle,
+		killTimeout:    GetKillTimeout(task.KillTimeout, maxKill),
+		maxKillTimeout: maxKill,
+		logger:         d.logger,
+		doneCh:         make(chan struct{}),
+		waitCh:         make(chan *dstructs.WaitResult, 1),
+	}
+	go h.run()
+	return h, nil
+}
+
+type externalId struct {
+	KillTimeout    time.Duration
+	MaxKillTimeout time.Duration
+	PluginConfig   *PluginReattachConfig
+	TaskHandle     *drivers.TaskHandle
+}
+
+func (d *ExternalDriver) Open(ctx *ExecContext, handleID string) (DriverHandle, error) {
+	id := &externalId{}
+	if err := json.Unmarshal([]byte(handleID), id); err != nil {
+		return nil, fmt.Errorf("Failed to parse handle '%s': %v", handleID, err)
+	}
+	if id.TaskHandle == nil {
+		return nil, fmt.Errorf("handle '%s' has no task", handleID)
+	}
+
+	// Reattach to the running instance of the driver, or launch a new one
+	// recovering the task if it exited
+	impl, pluginClient, err := d.external.launch(id.PluginConfig.PluginConfig(), d.logOutput(), d.config)
+	if err != nil {
+		d.logger.Printf("[WARN] driver.%s: error reattaching to plugin, recovering task %q: %v",
+			d.external.name, id.TaskHandle.ID, err)
+
+		impl, pluginClient, err = d.external.launch(nil, d.logOutput(), d.config)
+		if err != nil {
+			return nil, err
+		}
+		if err := impl.RecoverTask(id.TaskHandle); err != nil {
+			pluginClient.Kill()
+			return nil, fmt.Errorf("error recovering task %q: %v", id.TaskHandle.ID, err)
+		}
+	}
+
+	h := &externalHandle{
+		name:           d.external.name,
+		pluginClient:   pluginClient,
+		impl:           impl,
+		taskHandle:     id.TaskHandle,
+		killTimeout:    id.KillTimeout,
+		maxKillTimeout: id.MaxKillTimeout,
+		logger:         d.logger,
+		doneCh:         make(chan struct{}),
+		waitCh:         make(chan *dstructs.WaitResult, 1),
+	}
+	go h.run()
+	return h, nil
+}
+
+func (h *externalHandle) ID() string {
+	id := externalId{
+		KillTimeout:    h.killTimeout,
+		MaxKillTimeout: h.maxKillTimeout,
+		PluginConfig:   NewPluginReattachConfig(h.pluginClient.ReattachConfig()),
+		TaskHandle:     h.taskHandle,
+	}
+
+	data, err := json.Marshal(id)
+	if err != nil {
+		h.logger.Printf("[ERR] driver.%s: failed to marshal ID to JSON: %s", h.name, err)
+	}
+	return string(data)
+}
+
+func (h *externalHandle) WaitCh() chan *dstructs.WaitResult {
+	return h.waitCh
+}
+
+func (h *externalHandle) Update(task *structs.Task) error {
+	// Store the updated kill timeout.
+	h.killTimeout = GetKillTimeout(task.KillTimeout, h.maxKillTimeout)
+
+	// Update is not possible
+	return nil
+}
+
+func (h *externalHandle) Kill() error {
+	if err := h.impl.StopTask(h.taskHandle.ID, h.killTimeout, "SIGINT"); err != nil {
+		if h.pluginClient.Exited() {
+			return nil
+		}
+		return fmt.Errorf("stopping task failed: %v", err)
+	}
+
+	select {
+	case <-h.doneCh:
+		return nil
+	case <-time.After(h.killTimeout):
+		h.pluginClient.Kill()
+		return nil
+	}
+}
+
+func (h *externalHandle) Signal(sig string) error {
+	return h.impl.SignalTask(h.taskHandle.ID, sig)
+}
+
+func (h *externalHandle) Stats() (*cstructs.TaskResourceUsage, error) {
+	return h.impl.TaskStats(h.taskHandle.ID)
+}
+
+func (h *externalHandle) run() {
+	res, err := h.impl.WaitTask(h.taskHandle.ID)
+	close(h.doneCh)
+
+	result := &dstructs.WaitResult{Err: err}
+	if err == nil {
+		result.ExitCode = res.ExitCode
+		result.Signal = res.Signal
+		if res.Err != "" {
+			result.Err = errors.New(res.Err)
+		}
+	}
+	h.waitCh <- result
+	close(h.waitCh)
+	h.pluginClient.Kill()
+}
@@ -0,0 +1,57 @@
+package driver
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLoadExternalDrivers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"nomad-driver-podman", "nomad-driver-firecracker", "nomad-driver-docker", "other"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0755); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "nomad-driver-dir"), 0755); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	names, err := LoadExternalDrivers(dir, log.New(ioutil.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer func() {
+		for _, name := range names {
+			delete(BuiltinDrivers, name)
+		}
+	}()
+
+	// Drivers named like a builtin driver are skipped
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"firecracker", "podman"}) {
+		t.Fatalf("bad: %v", names)
+	}
+	d, err := NewDriver("podman", NewEmptyDriverContext())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := d.(*ExternalDriver); !ok {
+		t.Fatalf("bad: %T", d)
+	}
+
+	// A missing plugin directory is not an error
+	missing, err := LoadExternalDrivers(filepath.Join(dir, "missing"), nil)
+	if err != nil || len(missing) != 0 {
+		t.Fatalf("bad: %v %v", missing, err)
+	}
+}
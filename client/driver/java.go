@@ -2,6 +2,7 @@ package driver
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -93,6 +94,17 @@ func (d *JavaDriver) Validate(config map[string]interface{}) error {
 	return nil
 }
 
+// Capabilities returns the features the Java driver supports
+func (d *JavaDriver) Capabilities() *Capabilities {
+	return &Capabilities{
+		SendSignals:      true,
+		Exec:             true,
+		Pause:            true,
+		MountVolumes:     true,
+		NetworkIsolation: executorNetworkIsolation(),
+	}
+}
+
 func (d *JavaDriver) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
 	// Get the current status so that we can log any debug messages only if the
 	// state changes
@@ -217,11 +229,12 @@ func (d *JavaDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle,
 	}
 
 	ps, err := execIntf.LaunchCmd(&executor.ExecCommand{
-		Cmd:            absPath,
-		Args:           args,
-		FSIsolation:    true,
-		ResourceLimits: true,
-		User:           getExecutorUser(task),
+		Cmd:              absPath,
+		Args:             args,
+		FSIsolation:      true,
+		ResourceLimits:   true,
+		User:             getExecutorUser(task),
+		NetworkNamespace: ctx.NetworkNamespace,
 	}, executorCtx)
 	if err != nil {
 		pluginClient.Kill()
@@ -385,6 +398,26 @@ func (h *javaHandle) Stats() (*cstructs.TaskResourceUsage, error) {
 	return h.executor.Stats()
 }
 
+// Pause pauses the task by stopping its process
+func (h *javaHandle) Pause() error {
+	return pauseProcess(h.userPid)
+}
+
+// Resume resumes the task by continuing its stopped process
+func (h *javaHandle) Resume() error {
+	return resumeProcess(h.userPid)
+}
+
+// Exec runs a command next to the task's process, chrooted into the task
+func (h *javaHandle) Exec(ctx context.Context, opts *ExecOptions) (int, error) {
+	return execInTask(ctx, h.userPid, true, opts)
+}
+
+// Signal sends the named signal to the task's process
+func (h *javaHandle) Signal(sig string) error {
+	return signalProcess(h.userPid, sig)
+}
+
 func (h *javaHandle) run() {
 	ps, err := h.executor.Wait()
 	close(h.doneCh)
@@ -97,6 +97,15 @@ func (m *MockDriver) Validate(map[string]interface{}) error {
 	return nil
 }
 
+// Capabilities returns the features the mock driver supports. Exec is
+// claimed so tasks with script checks can be run with the mock driver.
+func (m *MockDriver) Capabilities() *Capabilities {
+	return &Capabilities{
+		Exec:             true,
+		NetworkIsolation: []string{"host"},
+	}
+}
+
 // Fingerprint fingerprints a node and returns if MockDriver is enabled
 func (m *MockDriver) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
 	node.Attributes["driver.mock_driver"] = "1"
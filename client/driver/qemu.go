@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"os/exec"
 	"path/filepath"
 	"regexp"
@@ -32,6 +33,18 @@ const (
 	// The key populated in Node Attributes to indicate presence of the Qemu
 	// driver
 	qemuDriverAttr = "driver.qemu"
+
+	// qemuMonitorSocket is the name of the QEMU monitor socket in the task
+	// directory, used to gracefully shut the VM down
+	qemuMonitorSocket = "qemu-monitor.sock"
+
+	// qemuGracefulShutdownMsg is the monitor command asking the guest to
+	// power down, as if the power button was pressed
+	qemuGracefulShutdownMsg = "system_powerdown\n"
+
+	// qemuMaxSocketPathLen is the maximum length of the path of a Unix
+	// socket
+	qemuMaxSocketPathLen = 108
 )
 
 // QemuDriver is a driver for running images via Qemu
@@ -47,6 +60,10 @@ type QemuDriverConfig struct {
 	Accelerator string           `mapstructure:"accelerator"`
 	PortMap     []map[string]int `mapstructure:"port_map"` // A map of host port labels and to guest ports.
 	Args        []string         `mapstructure:"args"`     // extra arguments to qemu executable
+
+	// GracefulShutdown enables the monitor socket, used to ask the guest to
+	// power down before the VM is stopped
+	GracefulShutdown bool `mapstructure:"graceful_shutdown"`
 }
 
 // qemuHandle is returned from Start/Open as a handle to the PID
@@ -59,6 +76,7 @@ type qemuHandle struct {
 	maxKillTimeout time.Duration
 	logger         *log.Logger
 	version        string
+	monitorPath    string
 	waitCh         chan *dstructs.WaitResult
 	doneCh         chan struct{}
 }
@@ -86,6 +104,9 @@ func (d *QemuDriver) Validate(config map[string]interface{}) error {
 			"args": &fields.FieldSchema{
 				Type: fields.TypeArray,
 			},
+			"graceful_shutdown": &fields.FieldSchema{
+				Type: fields.TypeBool,
+			},
 		},
 	}
 
@@ -96,6 +117,15 @@ func (d *QemuDriver) Validate(config map[string]interface{}) error {
 	return nil
 }
 
+// Capabilities returns the features the Qemu driver supports
+func (d *QemuDriver) Capabilities() *Capabilities {
+	return &Capabilities{
+		SendSignals:      true,
+		Pause:            true,
+		NetworkIsolation: []string{"nat"},
+	}
+}
+
 func (d *QemuDriver) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
 	// Get the current status so that we can log any debug messages only if the
 	// state changes
@@ -176,6 +206,17 @@ func (d *QemuDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle,
 		"-nographic",
 	}
 
+	// Expose the monitor on a socket in the task directory so the guest can
+	// be asked to power down when the task is killed
+	var monitorPath string
+	if driverConfig.GracefulShutdown {
+		monitorPath = filepath.Join(taskDir, qemuMonitorSocket)
+		if len(monitorPath) > qemuMaxSocketPathLen {
+			return nil, fmt.Errorf("monitor socket path %q is longer than %d characters", monitorPath, qemuMaxSocketPathLen)
+		}
+		args = append(args, "-monitor", fmt.Sprintf("unix:%s,server,nowait", monitorPath))
+	}
+
 	// Add pass through arguments to qemu executable. A user can specify
 	// these arguments in driver task configuration. These arguments are
 	// passed directly to the qemu driver as command line options.
@@ -268,6 +309,7 @@ func (d *QemuDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle,
 		killTimeout:    GetKillTimeout(task.KillTimeout, maxKill),
 		maxKillTimeout: maxKill,
 		version:        d.config.Version,
+		monitorPath:    monitorPath,
 		logger:         d.logger,
 		doneCh:         make(chan struct{}),
 		waitCh:         make(chan *dstructs.WaitResult, 1),
@@ -287,6 +329,7 @@ type qemuId struct {
 	UserPid        int
 	PluginConfig   *PluginReattachConfig
 	AllocDir       *allocdir.AllocDir
+	MonitorPath    string
 }
 
 func (d *QemuDriver) Open(ctx *ExecContext, handleID string) (DriverHandle, error) {
@@ -320,6 +363,7 @@ func (d *QemuDriver) Open(ctx *ExecContext, handleID string) (DriverHandle, erro
 		killTimeout:    id.KillTimeout,
 		maxKillTimeout: id.MaxKillTimeout,
 		version:        id.Version,
+		monitorPath:    id.MonitorPath,
 		doneCh:         make(chan struct{}),
 		waitCh:         make(chan *dstructs.WaitResult, 1),
 	}
@@ -338,6 +382,7 @@ func (h *qemuHandle) ID() string {
 		PluginConfig:   NewPluginReattachConfig(h.pluginClient.ReattachConfig()),
 		UserPid:        h.userPid,
 		AllocDir:       h.allocDir,
+		MonitorPath:    h.monitorPath,
 	}
 
 	data, err := json.Marshal(id)
@@ -360,9 +405,22 @@ func (h *qemuHandle) Update(task *structs.Task) error {
 	return nil
 }
 
-// TODO: allow a 'shutdown_command' that can be executed over a ssh connection
-// to the VM
+// Kill stops the VM. With graceful shutdown enabled, the guest is first asked
+// to power down and given the kill timeout to do so.
 func (h *qemuHandle) Kill() error {
+	if h.monitorPath != "" {
+		if err := sendQemuShutdown(h.monitorPath); err != nil {
+			h.logger.Printf("[WARN] driver.qemu: failed to send graceful shutdown to the VM: %v", err)
+		} else {
+			select {
+			case <-h.doneCh:
+				return nil
+			case <-time.After(h.killTimeout):
+				h.logger.Printf("[WARN] driver.qemu: VM didn't power down within the kill timeout, stopping it")
+			}
+		}
+	}
+
 	if err := h.executor.ShutDown(); err != nil {
 		if h.pluginClient.Exited() {
 			return nil
@@ -385,10 +443,38 @@ func (h *qemuHandle) Kill() error {
 	}
 }
 
+// sendQemuShutdown asks the guest to power down through the QEMU monitor
+// listening on the given socket
+func sendQemuShutdown(monitorPath string) error {
+	conn, err := net.DialTimeout("unix", monitorPath, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(qemuGracefulShutdownMsg))
+	return err
+}
+
 func (h *qemuHandle) Stats() (*cstructs.TaskResourceUsage, error) {
 	return h.executor.Stats()
 }
 
+// Pause pauses the task by stopping its process
+func (h *qemuHandle) Pause() error {
+	return pauseProcess(h.userPid)
+}
+
+// Resume resumes the task by continuing its stopped process
+func (h *qemuHandle) Resume() error {
+	return resumeProcess(h.userPid)
+}
+
+// Signal sends the named signal to the task's process
+func (h *qemuHandle) Signal(sig string) error {
+	return signalProcess(h.userPid, sig)
+}
+
 func (h *qemuHandle) run() {
 	ps, err := h.executor.Wait()
 	if ps.ExitCode == 0 && err != nil {
@@ -2,6 +2,9 @@ package driver
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -62,7 +65,7 @@ func TestQemuDriver_StartOpen_Wait(t *testing.T) {
 			MemoryMB: 512,
 			Networks: []*structs.NetworkResource{
 				&structs.NetworkResource{
-					ReservedPorts: []structs.Port{{"main", 22000}, {"web", 80}},
+					ReservedPorts: []structs.Port{{"main", 22000, 0, ""}, {"web", 80, 0, ""}},
 				},
 			},
 		},
@@ -122,7 +125,7 @@ func TestQemuDriverUser(t *testing.T) {
 			MemoryMB: 512,
 			Networks: []*structs.NetworkResource{
 				&structs.NetworkResource{
-					ReservedPorts: []structs.Port{{"main", 22000}, {"web", 80}},
+					ReservedPorts: []structs.Port{{"main", 22000, 0, ""}, {"web", 80, 0, ""}},
 				},
 			},
 		},
@@ -142,3 +145,42 @@ func TestQemuDriverUser(t *testing.T) {
 		t.Fatalf("Expecting '%v' in '%v'", msg, err)
 	}
 }
+
+func TestQemuDriver_SendShutdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qemu")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Listen on a socket standing in for the monitor
+	monitorPath := filepath.Join(dir, qemuMonitorSocket)
+	l, err := net.Listen("unix", monitorPath)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+
+	recvCh := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		raw, _ := ioutil.ReadAll(conn)
+		recvCh <- string(raw)
+	}()
+
+	if err := sendQemuShutdown(monitorPath); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if msg := <-recvCh; msg != qemuGracefulShutdownMsg {
+		t.Fatalf("bad: %q", msg)
+	}
+
+	// Sending fails when the monitor isn't listening
+	if err := sendQemuShutdown(filepath.Join(dir, "missing.sock")); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -1,6 +1,7 @@
 package driver
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -80,6 +81,16 @@ func (d *RawExecDriver) Validate(config map[string]interface{}) error {
 	return nil
 }
 
+// Capabilities returns the features the raw exec driver supports
+func (d *RawExecDriver) Capabilities() *Capabilities {
+	return &Capabilities{
+		SendSignals:      true,
+		Exec:             true,
+		Pause:            true,
+		NetworkIsolation: executorNetworkIsolation(),
+	}
+}
+
 func (d *RawExecDriver) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
 	// Get the current status so that we can log any debug messages only if the
 	// state changes
@@ -144,9 +155,11 @@ func (d *RawExecDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandl
 	}
 
 	ps, err := exec.LaunchCmd(&executor.ExecCommand{
-		Cmd:  command,
-		Args: driverConfig.Args,
-		User: task.User,
+		Cmd:              command,
+		Args:             driverConfig.Args,
+		User:             task.User,
+		NetworkNamespace: ctx.NetworkNamespace,
+		ProcessGroup:     true,
 	}, executorCtx)
 	if err != nil {
 		pluginClient.Kill()
@@ -282,6 +295,26 @@ func (h *rawExecHandle) Stats() (*cstructs.TaskResourceUsage, error) {
 	return h.executor.Stats()
 }
 
+// Pause pauses the task by stopping its process group
+func (h *rawExecHandle) Pause() error {
+	return pauseProcessGroup(h.userPid)
+}
+
+// Resume resumes the task by continuing its stopped process group
+func (h *rawExecHandle) Resume() error {
+	return resumeProcessGroup(h.userPid)
+}
+
+// Exec runs a command next to the task's process, in the task's working directory
+func (h *rawExecHandle) Exec(ctx context.Context, opts *ExecOptions) (int, error) {
+	return execInTask(ctx, h.userPid, false, opts)
+}
+
+// Signal sends the named signal to the task's process
+func (h *rawExecHandle) Signal(sig string) error {
+	return signalProcess(h.userPid, sig)
+}
+
 func (h *rawExecHandle) run() {
 	ps, err := h.executor.Wait()
 	close(h.doneCh)
@@ -247,6 +247,39 @@ func TestRawExecDriver_Start_Kill_Wait(t *testing.T) {
 	}
 }
 
+func TestRawExecDriver_Pause_Child(t *testing.T) {
+	task := &structs.Task{
+		Name: "sleep",
+		Config: map[string]interface{}{
+			"command": "/bin/sh",
+			"args": []string{
+				"-c", pauseChildScript,
+				fmt.Sprintf("${%s}/counter", env.AllocDir),
+			},
+		},
+		LogConfig: &structs.LogConfig{
+			MaxFiles:      10,
+			MaxFileSizeMB: 10,
+		},
+		Resources: basicResources,
+	}
+
+	driverCtx, execCtx := testDriverContexts(task)
+	defer execCtx.AllocDir.Destroy()
+	d := NewRawExecDriver(driverCtx)
+
+	handle, err := d.Start(execCtx, task)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if handle == nil {
+		t.Fatalf("missing handle")
+	}
+	defer handle.Kill()
+
+	testPauseChild(t, handle, filepath.Join(execCtx.AllocDir.SharedDir, "counter"))
+}
+
 func TestRawExecDriverUser(t *testing.T) {
 	task := &structs.Task{
 		Name: "sleep",
@@ -127,6 +127,14 @@ func (d *RktDriver) Validate(config map[string]interface{}) error {
 	return nil
 }
 
+// Capabilities returns the features the rkt driver supports
+func (d *RktDriver) Capabilities() *Capabilities {
+	return &Capabilities{
+		MountVolumes:     true,
+		NetworkIsolation: []string{"bridge"},
+	}
+}
+
 func (d *RktDriver) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
 	// Get the current status so that we can log any debug messages only if the
 	// state changes
@@ -212,6 +220,11 @@ func (d *RktDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle, e
 	cmdArgs = append(cmdArgs, "run")
 	cmdArgs = append(cmdArgs, fmt.Sprintf("--volume=%s,kind=host,source=%s", task.Name, ctx.AllocDir.SharedDir))
 	cmdArgs = append(cmdArgs, fmt.Sprintf("--mount=volume=%s,target=%s", task.Name, ctx.AllocDir.SharedDir))
+	for i, m := range ctx.AllocDir.VolumeMounts[task.Name] {
+		name := fmt.Sprintf("%s-volume-%d", task.Name, i)
+		cmdArgs = append(cmdArgs, fmt.Sprintf("--volume=%s,kind=host,source=%s,readOnly=%t", name, m.HostPath, m.ReadOnly))
+		cmdArgs = append(cmdArgs, fmt.Sprintf("--mount=volume=%s,target=%s", name, m.TaskPath))
+	}
 	cmdArgs = append(cmdArgs, img)
 	if insecure == true {
 		cmdArgs = append(cmdArgs, "--insecure-options=all")
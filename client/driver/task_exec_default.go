@@ -0,0 +1,14 @@
+// +build !linux
+
+package driver
+
+import (
+	"context"
+	"fmt"
+)
+
+// execInTask is only supported on Linux, where the isolation context of a
+// task's process can be joined
+func execInTask(ctx context.Context, pid int, isolated bool, opts *ExecOptions) (int, error) {
+	return 0, fmt.Errorf("executing commands in tasks is only supported on Linux")
+}
@@ -0,0 +1,229 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/hashicorp/nomad/client/network"
+)
+
+// execInTask runs a command next to the process of a task with the task's
+// environment, user and network namespace. The command is chrooted into the
+// root of the task's process when the task is isolated, and otherwise runs in
+// the task's working directory.
+func execInTask(ctx context.Context, pid int, isolated bool, opts *ExecOptions) (int, error) {
+	if len(opts.Command) == 0 {
+		return 0, fmt.Errorf("missing command")
+	}
+
+	procDir := fmt.Sprintf("/proc/%d", pid)
+	info, err := os.Stat(procDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find task process %d: %v", pid, err)
+	}
+
+	environ, err := ioutil.ReadFile(filepath.Join(procDir, "environ"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read task environment: %v", err)
+	}
+	env := strings.Split(strings.TrimRight(string(environ), "\x00"), "\x00")
+
+	cmd := &exec.Cmd{
+		Args:        opts.Command,
+		Env:         env,
+		SysProcAttr: &syscall.SysProcAttr{Setsid: true},
+	}
+
+	root := "/"
+	if isolated {
+		root = filepath.Join(procDir, "root")
+		cmd.SysProcAttr.Chroot = root
+		cmd.Dir = "/"
+	} else if cmd.Dir, err = os.Readlink(filepath.Join(procDir, "cwd")); err != nil {
+		return 0, fmt.Errorf("failed to find task working directory: %v", err)
+	}
+
+	// The executable is looked up in the task's root using the task's path,
+	// as exec.Command would look it up on the host
+	if cmd.Path, err = lookPathIn(root, opts.Command[0], env); err != nil {
+		return 0, err
+	}
+
+	// Run the command as the user of the task
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && os.Getuid() == 0 {
+		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: stat.Uid, Gid: stat.Gid}
+	}
+
+	// Attach the streams of the command, copying stdin from a goroutine so
+	// waiting on the command doesn't wait on the client sending more input
+	var stdinW io.WriteCloser
+	var ptyMaster *os.File
+	if opts.Tty {
+		master, slave, err := openPty()
+		if err != nil {
+			return 0, err
+		}
+		defer master.Close()
+		defer slave.Close()
+
+		ptyMaster, stdinW = master, master
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+		cmd.SysProcAttr.Setctty = true
+	} else {
+		cmd.Stdout, cmd.Stderr = opts.Stdout, opts.Stderr
+		if opts.Stdin != nil {
+			if stdinW, err = cmd.StdinPipe(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	// Join the network namespace of the task if it differs from ours
+	start := cmd.Start
+	taskNS, _ := os.Readlink(filepath.Join(procDir, "ns", "net"))
+	selfNS, _ := os.Readlink("/proc/self/ns/net")
+	if taskNS != "" && taskNS != selfNS {
+		start = func() error {
+			return network.WithNetNS(filepath.Join(procDir, "ns", "net"), cmd.Start)
+		}
+	}
+	if err := start(); err != nil {
+		return 0, fmt.Errorf("failed to start command: %v", err)
+	}
+
+	if opts.Stdin != nil && stdinW != nil {
+		go func() {
+			io.Copy(stdinW, opts.Stdin)
+			if !opts.Tty {
+				stdinW.Close()
+			}
+		}()
+	}
+
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go func() {
+		for {
+			select {
+			case size, ok := <-opts.ResizeCh:
+				if !ok {
+					return
+				}
+				if ptyMaster != nil {
+					setWinsize(ptyMaster, size)
+				}
+			case <-ctx.Done():
+				cmd.Process.Kill()
+				return
+			case <-doneCh:
+				return
+			}
+		}
+	}()
+
+	// The output of a terminal is read until the command and its children
+	// close the terminal
+	outputCh := make(chan struct{})
+	if ptyMaster != nil {
+		cmd.Stdin.(*os.File).Close()
+		go func() {
+			io.Copy(opts.Stdout, ptyMaster)
+			close(outputCh)
+		}()
+	} else {
+		close(outputCh)
+	}
+
+	err = cmd.Wait()
+	<-outputCh
+	if err == nil {
+		return 0, nil
+	}
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return 0, err
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok {
+		return 0, err
+	}
+	if status.Signaled() {
+		return 128 + int(status.Signal()), nil
+	}
+	return status.ExitStatus(), nil
+}
+
+// lookPathIn returns the path of the executable within the root directory,
+// searching the PATH of the environment if the name has no slash
+func lookPathIn(root, name string, env []string) (string, error) {
+	if strings.Contains(name, "/") {
+		return name, nil
+	}
+
+	path := "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "PATH=") {
+			path = strings.TrimPrefix(kv, "PATH=")
+		}
+	}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		candidate := filepath.Join(dir, name)
+		info, err := os.Stat(filepath.Join(root, candidate))
+		if err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("executable %q not found in task path", name)
+}
+
+// openPty opens a new pseudo terminal and returns its master and slave
+func openPty() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open terminal: %v", err)
+	}
+
+	var unlock int32
+	if err := ioctl(master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("failed to unlock terminal: %v", err)
+	}
+	var n uint32
+	if err := ioctl(master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("failed to find terminal: %v", err)
+	}
+
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("failed to open terminal: %v", err)
+	}
+	return master, slave, nil
+}
+
+// setWinsize sets the size of the terminal
+func setWinsize(f *os.File, size TerminalSize) error {
+	ws := struct {
+		Row, Col, X, Y uint16
+	}{Row: uint16(size.Height), Col: uint16(size.Width)}
+	return ioctl(f.Fd(), syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&ws)))
+}
+
+func ioctl(fd, req, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
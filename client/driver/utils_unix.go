@@ -3,10 +3,101 @@
 package driver
 
 import (
+	"fmt"
 	"os/exec"
+	"strings"
 	"syscall"
 )
 
+// signals maps the names of the signals that can be sent to tasks to their
+// values
+var signals = map[string]syscall.Signal{
+	"SIGABRT":   syscall.SIGABRT,
+	"SIGALRM":   syscall.SIGALRM,
+	"SIGBUS":    syscall.SIGBUS,
+	"SIGCHLD":   syscall.SIGCHLD,
+	"SIGCONT":   syscall.SIGCONT,
+	"SIGFPE":    syscall.SIGFPE,
+	"SIGHUP":    syscall.SIGHUP,
+	"SIGILL":    syscall.SIGILL,
+	"SIGINT":    syscall.SIGINT,
+	"SIGKILL":   syscall.SIGKILL,
+	"SIGPIPE":   syscall.SIGPIPE,
+	"SIGPROF":   syscall.SIGPROF,
+	"SIGQUIT":   syscall.SIGQUIT,
+	"SIGSEGV":   syscall.SIGSEGV,
+	"SIGSTOP":   syscall.SIGSTOP,
+	"SIGSYS":    syscall.SIGSYS,
+	"SIGTERM":   syscall.SIGTERM,
+	"SIGTRAP":   syscall.SIGTRAP,
+	"SIGTSTP":   syscall.SIGTSTP,
+	"SIGTTIN":   syscall.SIGTTIN,
+	"SIGTTOU":   syscall.SIGTTOU,
+	"SIGURG":    syscall.SIGURG,
+	"SIGUSR1":   syscall.SIGUSR1,
+	"SIGUSR2":   syscall.SIGUSR2,
+	"SIGVTALRM": syscall.SIGVTALRM,
+	"SIGWINCH":  syscall.SIGWINCH,
+	"SIGXCPU":   syscall.SIGXCPU,
+	"SIGXFSZ":   syscall.SIGXFSZ,
+}
+
+// parseSignal returns the signal with the given name. The SIG prefix of the
+// name is optional and case is ignored, so "hup" is SIGHUP.
+func parseSignal(name string) (syscall.Signal, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig, ok := signals[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown signal %q", name)
+	}
+	return sig, nil
+}
+
+// signalProcess sends the named signal to the process with the given pid
+func signalProcess(pid int, name string) error {
+	sig, err := parseSignal(name)
+	if err != nil {
+		return err
+	}
+	return syscall.Kill(pid, sig)
+}
+
+// pauseProcess suspends the process with the given pid
+func pauseProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGSTOP)
+}
+
+// resumeProcess continues the suspended process with the given pid
+func resumeProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGCONT)
+}
+
+// pauseProcessGroup suspends the process with the given pid along with the
+// processes it forked if it leads its own process group
+func pauseProcessGroup(pid int) error {
+	return syscall.Kill(processGroup(pid), syscall.SIGSTOP)
+}
+
+// resumeProcessGroup continues the suspended process with the given pid along
+// with the processes it forked if it leads its own process group
+func resumeProcessGroup(pid int) error {
+	return syscall.Kill(processGroup(pid), syscall.SIGCONT)
+}
+
+// processGroup returns the negated pid, which signals the whole process group,
+// if the process with the given pid is the leader of its process group, and
+// the pid otherwise. Processes started by older clients don't lead their own
+// process group.
+func processGroup(pid int) int {
+	if pgid, err := syscall.Getpgid(pid); err == nil && pgid == pid {
+		return -pid
+	}
+	return pid
+}
+
 // isolateCommand sets the setsid flag in exec.Cmd to true so that the process
 // becomes the process leader in a new session and doesn't receive signals that
 // are sent to the parent process.
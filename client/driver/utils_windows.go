@@ -1,9 +1,41 @@
 package driver
 
 import (
+	"fmt"
 	"os/exec"
+	"syscall"
 )
 
+// pauseProcess is not supported on Windows
+func pauseProcess(pid int) error {
+	return fmt.Errorf("pausing processes is not supported on Windows")
+}
+
+// resumeProcess is not supported on Windows
+func resumeProcess(pid int) error {
+	return fmt.Errorf("resuming processes is not supported on Windows")
+}
+
+// pauseProcessGroup is not supported on Windows
+func pauseProcessGroup(pid int) error {
+	return fmt.Errorf("pausing processes is not supported on Windows")
+}
+
+// resumeProcessGroup is not supported on Windows
+func resumeProcessGroup(pid int) error {
+	return fmt.Errorf("resuming processes is not supported on Windows")
+}
+
+// parseSignal is not supported on Windows
+func parseSignal(name string) (syscall.Signal, error) {
+	return 0, fmt.Errorf("signals are not supported on Windows")
+}
+
+// signalProcess is not supported on Windows
+func signalProcess(pid int, name string) error {
+	return fmt.Errorf("signals are not supported on Windows")
+}
+
 // TODO Figure out if this is needed in Wondows
 func isolateCommand(cmd *exec.Cmd) {
 }
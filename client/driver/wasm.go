@@ -0,0 +1,247 @@
+package driver
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/driver/executor"
+	dstructs "github.com/hashicorp/nomad/client/driver/structs"
+	"github.com/hashicorp/nomad/helper/discover"
+	"github.com/hashicorp/nomad/helper/fields"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	// The key populated in Node Attributes to indicate the presence of the
+	// WASM driver
+	wasmDriverAttr = "driver.wasm"
+
+	// minWasmtimeVersion is the first version of wasmtime accepting the
+	// -W and -S options used to limit and configure modules
+	minWasmtimeVersion = "14.0.0"
+)
+
+var (
+	reWasmtimeVersion = regexp.MustCompile(`wasmtime(?:-cli)? (\d+\.\d+\.\d+)`)
+)
+
+// WasmDriver runs WebAssembly modules with wasmtime. Modules are sandboxed by
+// the runtime and may only access the task's local, secrets and alloc
+// directories, so no chroot has to be built, making tasks fast to start and
+// cheap to pack densely.
+type WasmDriver struct {
+	ExecDriver
+}
+
+type WasmDriverConfig struct {
+	Module string   `mapstructure:"module"`
+	Args   []string `mapstructure:"args"`
+	Fuel   uint64   `mapstructure:"fuel"`
+}
+
+// NewWasmDriver is used to create a new wasm driver
+func NewWasmDriver(ctx *DriverContext) Driver {
+	return &WasmDriver{ExecDriver: ExecDriver{DriverContext: *ctx}}
+}
+
+// Validate is used to validate the driver configuration
+func (d *WasmDriver) Validate(config map[string]interface{}) error {
+	fd := &fields.FieldData{
+		Raw: config,
+		Schema: map[string]*fields.FieldSchema{
+			"module": &fields.FieldSchema{
+				Type:     fields.TypeString,
+				Required: true,
+			},
+			"args": &fields.FieldSchema{
+				Type: fields.TypeArray,
+			},
+			"fuel": &fields.FieldSchema{
+				Type: fields.TypeInt,
+			},
+		},
+	}
+
+	if err := fd.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Capabilities returns the features the wasm driver supports. Modules only
+// see the directories preopened by the driver, so volumes can't be mounted.
+func (d *WasmDriver) Capabilities() *Capabilities {
+	return &Capabilities{
+		Exec:             true,
+		Pause:            true,
+		NetworkIsolation: executorNetworkIsolation(),
+	}
+}
+
+func (d *WasmDriver) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
+	// Get the current status so that we can log any debug messages only if the
+	// state changes
+	_, currentlyEnabled := node.Attributes[wasmDriverAttr]
+
+	// Only enable if we are root and cgroups are mounted when running on linux systems.
+	_, cgroupsMounted := node.Attributes["unique.cgroup.mountpoint"]
+	if runtime.GOOS == "linux" && (syscall.Geteuid() != 0 || !cgroupsMounted) {
+		if currentlyEnabled {
+			d.logger.Printf("[DEBUG] driver.wasm: root priviledges and mounted cgroups required on linux, disabling")
+		}
+		delete(node.Attributes, wasmDriverAttr)
+		return false, nil
+	}
+
+	out, err := exec.Command("wasmtime", "--version").Output()
+	if err != nil {
+		// assume wasmtime wasn't found
+		delete(node.Attributes, wasmDriverAttr)
+		return false, nil
+	}
+
+	matches := reWasmtimeVersion.FindStringSubmatch(string(out))
+	if len(matches) != 2 {
+		delete(node.Attributes, wasmDriverAttr)
+		return false, fmt.Errorf("Unable to parse wasmtime version string: %q", out)
+	}
+
+	minVersion, _ := version.NewVersion(minWasmtimeVersion)
+	currentVersion, err := version.NewVersion(matches[1])
+	if err != nil || currentVersion.LessThan(minVersion) {
+		if currentlyEnabled {
+			d.logger.Printf("[WARN] driver.wasm: please upgrade wasmtime to a version >= %s", minVersion)
+		}
+		delete(node.Attributes, wasmDriverAttr)
+		return false, nil
+	}
+
+	if !currentlyEnabled {
+		d.logger.Printf("[DEBUG] driver.wasm: wasm driver is enabled")
+	}
+	node.Attributes[wasmDriverAttr] = "1"
+	node.Attributes["driver.wasm.version"] = matches[1]
+	return true, nil
+}
+
+func (d *WasmDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle, error) {
+	var driverConfig WasmDriverConfig
+	if err := mapstructure.WeakDecode(task.Config, &driverConfig); err != nil {
+		return nil, err
+	}
+
+	if driverConfig.Module == "" {
+		return nil, fmt.Errorf("module must be specified")
+	}
+
+	// Set the host environment variables.
+	filter := strings.Split(d.config.ReadDefault("env.blacklist", config.DefaultEnvBlacklist), ",")
+	d.taskEnv.AppendHostEnvvars(filter)
+
+	// Modules see the task directories at the paths they are preopened at
+	d.taskEnv.SetAllocDir(allocdir.SharedAllocContainerPath)
+	d.taskEnv.SetTaskLocalDir(allocdir.TaskLocalContainerPath)
+	d.taskEnv.SetSecretDir(allocdir.TaskSecretsContainerPath)
+
+	// Get the task directory for storing the executor logs.
+	taskDir, ok := ctx.AllocDir.TaskDirs[d.DriverContext.taskName]
+	if !ok {
+		return nil, fmt.Errorf("Could not find task directory for task: %v", d.DriverContext.taskName)
+	}
+
+	bin, err := discover.NomadExecutable()
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the nomad binary: %v", err)
+	}
+	pluginLogFile := filepath.Join(taskDir, fmt.Sprintf("%s-executor.out", task.Name))
+	pluginConfig := &plugin.ClientConfig{
+		Cmd: exec.Command(bin, "executor", pluginLogFile),
+	}
+
+	exec, pluginClient, err := createExecutor(pluginConfig, d.config.LogOutput, d.config)
+	if err != nil {
+		return nil, err
+	}
+	executorCtx := &executor.ExecutorContext{
+		TaskEnv:  d.taskEnv,
+		Driver:   "wasm",
+		AllocDir: ctx.AllocDir,
+		AllocID:  ctx.AllocID,
+		Task:     task,
+	}
+
+	absPath, err := GetAbsolutePath("wasmtime")
+	if err != nil {
+		pluginClient.Kill()
+		return nil, err
+	}
+
+	ps, err := exec.LaunchCmd(&executor.ExecCommand{
+		Cmd:              absPath,
+		Args:             wasmtimeArgs(&driverConfig, task.Resources, taskDir, ctx.AllocDir.SharedDir),
+		ResourceLimits:   true,
+		User:             getExecutorUser(task),
+		NetworkNamespace: ctx.NetworkNamespace,
+	}, executorCtx)
+	if err != nil {
+		pluginClient.Kill()
+		return nil, err
+	}
+	d.logger.Printf("[DEBUG] driver.wasm: started process via plugin with pid: %v", ps.Pid)
+
+	// Return a driver handle
+	maxKill := d.DriverContext.config.MaxKillTimeout
+	h := &execHandle{
+		pluginClient:    pluginClient,
+		userPid:         ps.Pid,
+		executor:        exec,
+		allocDir:        ctx.AllocDir,
+		isolationConfig: ps.IsolationConfig,
+		killTimeout:     GetKillTimeout(task.KillTimeout, maxKill),
+		maxKillTimeout:  maxKill,
+		logger:          d.logger,
+		version:         d.config.Version,
+		doneCh:          make(chan struct{}),
+		waitCh:          make(chan *dstructs.WaitResult, 1),
+	}
+	if err := exec.SyncServices(consulContext(d.config, "")); err != nil {
+		d.logger.Printf("[ERR] driver.wasm: error registering services with consul for task: %q: %v", task.Name, err)
+	}
+	go h.run()
+	return h, nil
+}
+
+// wasmtimeArgs returns the arguments of wasmtime to run the module of the
+// task. The linear memory of the module is limited to the memory of the
+// task, and its execution to the configured fuel, if any. The CPU share of
+// the task is enforced by the resource container.
+func wasmtimeArgs(driverConfig *WasmDriverConfig, resources *structs.Resources,
+	taskDir, sharedDir string) []string {
+	args := []string{"run"}
+	if resources != nil && resources.MemoryMB > 0 {
+		args = append(args, "-W", fmt.Sprintf("max-memory-size=%d", resources.MemoryMB*1024*1024))
+	}
+	if driverConfig.Fuel > 0 {
+		args = append(args, "-W", fmt.Sprintf("fuel=%d", driverConfig.Fuel))
+	}
+
+	// Expose the environment and the task directories to the module
+	args = append(args, "-S", "inherit-env",
+		"--dir", filepath.Join(taskDir, allocdir.TaskLocal)+"::"+allocdir.TaskLocalContainerPath,
+		"--dir", filepath.Join(taskDir, allocdir.TaskSecrets)+"::"+allocdir.TaskSecretsContainerPath,
+		"--dir", sharedDir+"::"+allocdir.SharedAllocContainerPath)
+
+	args = append(args, driverConfig.Module)
+	return append(args, driverConfig.Args...)
+}
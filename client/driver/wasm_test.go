@@ -0,0 +1,112 @@
+package driver
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+
+	ctestutils "github.com/hashicorp/nomad/client/testutil"
+)
+
+// wasmtimeLocated checks whether wasmtime is installed so we can run modules.
+func wasmtimeLocated() bool {
+	_, err := exec.Command("wasmtime", "--version").CombinedOutput()
+	return err == nil
+}
+
+func TestWasmDriver_Fingerprint(t *testing.T) {
+	ctestutils.ExecCompatible(t)
+	if !wasmtimeLocated() {
+		t.Skip("wasmtime not found; skipping")
+	}
+	task := &structs.Task{
+		Name:      "foo",
+		Resources: structs.DefaultResources(),
+	}
+	driverCtx, execCtx := testDriverContexts(task)
+	defer execCtx.AllocDir.Destroy()
+	d := NewWasmDriver(driverCtx)
+	node := &structs.Node{
+		Attributes: map[string]string{
+			"unique.cgroup.mountpoint": "/sys/fs/cgroup",
+		},
+	}
+	apply, err := d.Fingerprint(&config.Config{}, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !apply {
+		t.Fatalf("should apply")
+	}
+	if node.Attributes["driver.wasm"] != "1" {
+		t.Fatalf("missing driver")
+	}
+	if node.Attributes["driver.wasm.version"] == "" {
+		t.Fatalf("missing version")
+	}
+}
+
+func TestWasmDriver_Validate(t *testing.T) {
+	task := &structs.Task{
+		Name:      "foo",
+		Resources: structs.DefaultResources(),
+	}
+	driverCtx, execCtx := testDriverContexts(task)
+	defer execCtx.AllocDir.Destroy()
+	d := NewWasmDriver(driverCtx)
+
+	if err := d.Validate(map[string]interface{}{"module": "local/app.wasm", "fuel": 1000}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := d.Validate(map[string]interface{}{"args": []string{"foo"}}); err == nil {
+		t.Fatalf("expected error without a module")
+	}
+}
+
+func TestWasmDriver_WasmtimeArgs(t *testing.T) {
+	driverConfig := &WasmDriverConfig{
+		Module: "local/app.wasm",
+		Args:   []string{"-v", "${NOMAD_TASK_NAME}"},
+		Fuel:   1000000,
+	}
+	resources := &structs.Resources{MemoryMB: 64}
+
+	args := wasmtimeArgs(driverConfig, resources, "/alloc/web", "/alloc/alloc")
+	expected := []string{
+		"run",
+		"-W", "max-memory-size=67108864",
+		"-W", "fuel=1000000",
+		"-S", "inherit-env",
+		"--dir", "/alloc/web/local::/local",
+		"--dir", "/alloc/web/secrets::/secrets",
+		"--dir", "/alloc/alloc::/alloc",
+		"local/app.wasm", "-v", "${NOMAD_TASK_NAME}",
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	// Fuel is unlimited unless configured
+	driverConfig.Fuel = 0
+	args = wasmtimeArgs(driverConfig, resources, "/alloc/web", "/alloc/alloc")
+	for _, arg := range args {
+		if arg == "fuel=0" {
+			t.Fatalf("bad: %#v", args)
+		}
+	}
+}
+
+func TestWasmDriver_VersionRegexp(t *testing.T) {
+	for input, expected := range map[string]string{
+		"wasmtime 14.0.4 (3f2fa3b4e 2023-11-06)\n": "14.0.4",
+		"wasmtime-cli 0.40.1\n":                    "0.40.1",
+	} {
+		matches := reWasmtimeVersion.FindStringSubmatch(input)
+		if len(matches) != 2 || matches[1] != expected {
+			t.Fatalf("bad: %q: %#v", input, matches)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	client "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/nomad/structs"
+	sconfig "github.com/hashicorp/nomad/nomad/structs/config"
 )
 
 const (
@@ -19,14 +20,21 @@ const (
 
 // ConsulFingerprint is used to fingerprint for Consul
 type ConsulFingerprint struct {
-	logger    *log.Logger
+	logger *log.Logger
+
+	// clusters tracks the state of each Consul cluster, keyed by name
+	clusters map[string]*consulCluster
+}
+
+// consulCluster is the client and last known state of a Consul cluster
+type consulCluster struct {
 	client    *consul.Client
 	lastState string
 }
 
 // NewConsulFingerprint is used to create a Consul fingerprint
 func NewConsulFingerprint(logger *log.Logger) Fingerprint {
-	return &ConsulFingerprint{logger: logger, lastState: consulUnavailable}
+	return &ConsulFingerprint{logger: logger, clusters: make(map[string]*consulCluster)}
 }
 
 func (f *ConsulFingerprint) Fingerprint(config *client.Config, node *structs.Node) (bool, error) {
@@ -35,15 +43,43 @@ func (f *ConsulFingerprint) Fingerprint(config *client.Config, node *structs.Nod
 		node.Links = map[string]string{}
 	}
 
+	// The default cluster is fingerprinted under consul.* and the named ones
+	// under consul.<name>.*
+	applies, err := f.fingerprintCluster(structs.DefaultCluster, "consul", config.ConsulConfig, node)
+	if err != nil {
+		return false, err
+	}
+	for name, conf := range config.ConsulClusters {
+		if conf == nil {
+			continue
+		}
+		ok, err := f.fingerprintCluster(name, "consul."+name, conf, node)
+		if err != nil {
+			return false, err
+		}
+		applies = applies || ok
+	}
+	return applies, nil
+}
+
+// fingerprintCluster fingerprints a Consul cluster, setting its attributes
+// and link under the given prefix
+func (f *ConsulFingerprint) fingerprintCluster(name, prefix string, conf *sconfig.ConsulConfig, node *structs.Node) (bool, error) {
+	cluster, ok := f.clusters[name]
+	if !ok {
+		cluster = &consulCluster{lastState: consulUnavailable}
+		f.clusters[name] = cluster
+	}
+
 	// Only create the client once to avoid creating too many connections to
 	// Consul.
-	if f.client == nil {
-		consulConfig, err := config.ConsulConfig.ApiConfig()
+	if cluster.client == nil {
+		consulConfig, err := conf.ApiConfig()
 		if err != nil {
 			return false, fmt.Errorf("Failed to initialize the Consul client config: %v", err)
 		}
 
-		f.client, err = consul.NewClient(consulConfig)
+		cluster.client, err = consul.NewClient(consulConfig)
 		if err != nil {
 			return false, fmt.Errorf("Failed to initialize consul client: %s", err)
 		}
@@ -51,48 +87,48 @@ func (f *ConsulFingerprint) Fingerprint(config *client.Config, node *structs.Nod
 
 	// We'll try to detect consul by making a query to to the agent's self API.
 	// If we can't hit this URL consul is probably not running on this machine.
-	info, err := f.client.Agent().Self()
+	info, err := cluster.client.Agent().Self()
 	if err != nil {
 		// Clear any attributes set by a previous fingerprint.
-		f.clearConsulAttributes(node)
+		f.clearConsulAttributes(node, prefix)
 
 		// Print a message indicating that the Consul Agent is not available
 		// anymore
-		if f.lastState == consulAvailable {
-			f.logger.Printf("[INFO] fingerprint.consul: consul agent is unavailable")
+		if cluster.lastState == consulAvailable {
+			f.logger.Printf("[INFO] fingerprint.consul: consul agent of cluster %q is unavailable", name)
 		}
-		f.lastState = consulUnavailable
+		cluster.lastState = consulUnavailable
 		return false, nil
 	}
 
-	node.Attributes["consul.server"] = strconv.FormatBool(info["Config"]["Server"].(bool))
-	node.Attributes["consul.version"] = info["Config"]["Version"].(string)
-	node.Attributes["consul.revision"] = info["Config"]["Revision"].(string)
-	node.Attributes["unique.consul.name"] = info["Config"]["NodeName"].(string)
-	node.Attributes["consul.datacenter"] = info["Config"]["Datacenter"].(string)
+	node.Attributes[prefix+".server"] = strconv.FormatBool(info["Config"]["Server"].(bool))
+	node.Attributes[prefix+".version"] = info["Config"]["Version"].(string)
+	node.Attributes[prefix+".revision"] = info["Config"]["Revision"].(string)
+	node.Attributes["unique."+prefix+".name"] = info["Config"]["NodeName"].(string)
+	node.Attributes[prefix+".datacenter"] = info["Config"]["Datacenter"].(string)
 
-	node.Links["consul"] = fmt.Sprintf("%s.%s",
-		node.Attributes["consul.datacenter"],
-		node.Attributes["unique.consul.name"])
+	node.Links[prefix] = fmt.Sprintf("%s.%s",
+		node.Attributes[prefix+".datacenter"],
+		node.Attributes["unique."+prefix+".name"])
 
 	// If the Consul Agent was previously unavailable print a message to
 	// indicate the Agent is available now
-	if f.lastState == consulUnavailable {
-		f.logger.Printf("[INFO] fingerprint.consul: consul agent is available")
+	if cluster.lastState == consulUnavailable {
+		f.logger.Printf("[INFO] fingerprint.consul: consul agent of cluster %q is available", name)
 	}
-	f.lastState = consulAvailable
+	cluster.lastState = consulAvailable
 	return true, nil
 }
 
-// clearConsulAttributes removes consul attributes and links from the passed
-// Node.
-func (f *ConsulFingerprint) clearConsulAttributes(n *structs.Node) {
-	delete(n.Attributes, "consul.server")
-	delete(n.Attributes, "consul.version")
-	delete(n.Attributes, "consul.revision")
-	delete(n.Attributes, "unique.consul.name")
-	delete(n.Attributes, "consul.datacenter")
-	delete(n.Links, "consul")
+// clearConsulAttributes removes the consul attributes and link set under the
+// prefix from the passed Node.
+func (f *ConsulFingerprint) clearConsulAttributes(n *structs.Node, prefix string) {
+	delete(n.Attributes, prefix+".server")
+	delete(n.Attributes, prefix+".version")
+	delete(n.Attributes, prefix+".revision")
+	delete(n.Attributes, "unique."+prefix+".name")
+	delete(n.Attributes, prefix+".datacenter")
+	delete(n.Links, prefix)
 }
 
 func (f *ConsulFingerprint) Periodic() (bool, time.Duration) {
@@ -9,6 +9,7 @@ import (
 
 	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/nomad/structs"
+	sconfig "github.com/hashicorp/nomad/nomad/structs/config"
 )
 
 func TestConsulFingerprint(t *testing.T) {
@@ -45,6 +46,46 @@ func TestConsulFingerprint(t *testing.T) {
 	}
 }
 
+func TestConsulFingerprint_Clusters(t *testing.T) {
+	fp := NewConsulFingerprint(testLogger())
+	node := &structs.Node{
+		Attributes: make(map[string]string),
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, mockConsulResponse)
+	}))
+	defer ts.Close()
+
+	config := config.DefaultConfig()
+	config.ConsulConfig.Addr = strings.TrimPrefix(ts.URL, "http://")
+	config.ConsulClusters = map[string]*sconfig.ConsulConfig{
+		"secondary": &sconfig.ConsulConfig{
+			Addr: strings.TrimPrefix(ts.URL, "http://"),
+		},
+	}
+
+	ok, err := fp.Fingerprint(config, node)
+	if err != nil {
+		t.Fatalf("Failed to fingerprint: %s", err)
+	}
+	if !ok {
+		t.Fatalf("Failed to apply node attributes")
+	}
+
+	assertNodeAttributeContains(t, node, "consul.version")
+	assertNodeAttributeContains(t, node, "consul.secondary.server")
+	assertNodeAttributeContains(t, node, "consul.secondary.version")
+	assertNodeAttributeContains(t, node, "consul.secondary.revision")
+	assertNodeAttributeContains(t, node, "unique.consul.secondary.name")
+	assertNodeAttributeContains(t, node, "consul.secondary.datacenter")
+
+	if _, ok := node.Links["consul.secondary"]; !ok {
+		t.Errorf("Expected a link to the secondary consul cluster, none found")
+	}
+}
+
 // Taken from tryconsul using consul release 0.5.2
 const mockConsulResponse = `
 {
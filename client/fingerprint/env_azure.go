@@ -0,0 +1,200 @@
+package fingerprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// This is where the Azure instance metadata service normally resides. We
+	// hardcode the "instance" path as well since it's the only one we access
+	// here.
+	DEFAULT_AZURE_URL = "http://169.254.169.254/metadata/instance/"
+
+	// azureAPIVersion is the version of the instance metadata API queried
+	azureAPIVersion = "2017-08-01"
+)
+
+// AzureMetadataNetworkInterface is an interface of the instance as returned
+// by the metadata service
+type AzureMetadataNetworkInterface struct {
+	IPv4 struct {
+		IPAddress []struct {
+			PrivateIPAddress string `json:"privateIpAddress"`
+			PublicIPAddress  string `json:"publicIpAddress"`
+		} `json:"ipAddress"`
+	} `json:"ipv4"`
+	MACAddress string `json:"macAddress"`
+}
+
+// EnvAzureFingerprint is used to fingerprint Azure metadata
+type EnvAzureFingerprint struct {
+	StaticFingerprinter
+	client      *http.Client
+	logger      *log.Logger
+	metadataURL string
+}
+
+// NewEnvAzureFingerprint is used to create a fingerprint from Azure metadata
+func NewEnvAzureFingerprint(logger *log.Logger) Fingerprint {
+	// Read the internal metadata URL from the environment, allowing test files to
+	// provide their own
+	metadataURL := os.Getenv("AZURE_ENV_URL")
+	if metadataURL == "" {
+		metadataURL = DEFAULT_AZURE_URL
+	}
+
+	// assume 2 seconds is enough time for inside Azure network
+	client := &http.Client{
+		Timeout:   2 * time.Second,
+		Transport: cleanhttp.DefaultTransport(),
+	}
+
+	return &EnvAzureFingerprint{
+		client:      client,
+		logger:      logger,
+		metadataURL: metadataURL,
+	}
+}
+
+// Get returns the value of the attribute of the instance, in the format
+// (text or json) requested
+func (f *EnvAzureFingerprint) Get(attribute string, format string) (string, error) {
+	reqURL := fmt.Sprintf("%s%s?api-version=%s&format=%s", f.metadataURL, attribute, azureAPIVersion, format)
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata", "true")
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		f.logger.Printf("[DEBUG] fingerprint.env_azure: Could not read value for attribute %q", attribute)
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", ReqError{res.StatusCode}
+	}
+
+	resp, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		f.logger.Printf("[ERR] fingerprint.env_azure: Error reading response body for Azure %s", attribute)
+		return "", err
+	}
+	return strings.TrimSpace(string(resp)), nil
+}
+
+func (f *EnvAzureFingerprint) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
+	if !f.isAzure() {
+		return false, nil
+	}
+
+	if node.Links == nil {
+		node.Links = make(map[string]string)
+	}
+
+	// Keys and whether they should be namespaced as unique. Any key whose value
+	// uniquely identifies a node, such as its ID, should be marked as unique.
+	// When marked as unique, the key isn't included in the computed node
+	// class.
+	keys := map[string]struct {
+		attr   string
+		unique bool
+	}{
+		"compute/vmId":                 {"id", true},
+		"compute/name":                 {"name", true},
+		"compute/location":             {"location", false},
+		"compute/vmSize":               {"vm-size", false},
+		"compute/resourceGroupName":    {"resource-group", false},
+		"compute/platformFaultDomain":  {"fault-domain", false},
+		"compute/platformUpdateDomain": {"update-domain", false},
+	}
+	for k, v := range keys {
+		value, err := f.Get(k, "text")
+		if err != nil {
+			f.logger.Printf("[DEBUG] fingerprint.env_azure: Error querying Azure %s, skipping", k)
+			continue
+		}
+
+		key := "platform.azure." + v.attr
+		if v.unique {
+			key = structs.UniqueNamespace(key)
+		}
+		node.Attributes[key] = value
+	}
+
+	// Get the private and public IPs of the interfaces
+	value, err := f.Get("network/interface", "json")
+	if err == nil {
+		var interfaces []AzureMetadataNetworkInterface
+		if err := json.Unmarshal([]byte(value), &interfaces); err != nil {
+			f.logger.Printf("[WARN] fingerprint.env_azure: Error decoding network interface information: %s", err.Error())
+		}
+		for i, intf := range interfaces {
+			if len(intf.IPv4.IPAddress) == 0 {
+				continue
+			}
+			prefix := "unique.platform.azure.network." + strconv.Itoa(i)
+			addr := intf.IPv4.IPAddress[0]
+			node.Attributes[prefix+".ip"] = addr.PrivateIPAddress
+			if addr.PublicIPAddress != "" {
+				node.Attributes[prefix+".public-ip"] = addr.PublicIPAddress
+			}
+		}
+	}
+
+	// Tags are returned as "key:value" pairs separated by semicolons
+	value, err = f.Get("compute/tags", "text")
+	if err == nil && value != "" {
+		for _, tag := range strings.Split(value, ";") {
+			parts := strings.SplitN(tag, ":", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				continue
+			}
+			k, v := parts[0], parts[1]
+			attr := "platform.azure.tag."
+			var key string
+
+			// If the tag is namespaced as unique, we strip it from the tag
+			// and prepend to the whole attribute.
+			if structs.IsUniqueNamespace(k) {
+				k = strings.TrimPrefix(k, structs.NodeUniqueNamespace)
+				key = fmt.Sprintf("%s%s%s", structs.NodeUniqueNamespace, attr, k)
+			} else {
+				key = fmt.Sprintf("%s%s", attr, k)
+			}
+			node.Attributes[key] = v
+		}
+	}
+
+	// populate Links
+	node.Links["azure"] = node.Attributes["unique.platform.azure.id"]
+
+	return true, nil
+}
+
+// isAzure returns whether the instance metadata service of Azure answers
+// with the ID of the VM
+func (f *EnvAzureFingerprint) isAzure() bool {
+	id, err := f.Get("compute/vmId", "text")
+	if err != nil {
+		if re, ok := err.(ReqError); !ok || re.StatusCode != 404 {
+			// If it wasn't a 404 error, print an error message.
+			f.logger.Printf("[DEBUG] fingerprint.env_azure: Error querying Azure Metadata URL, skipping")
+		}
+		return false
+	}
+	return id != ""
+}
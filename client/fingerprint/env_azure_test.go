@@ -0,0 +1,87 @@
+package fingerprint
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestAzureFingerprint_nonAzure(t *testing.T) {
+	os.Setenv("AZURE_ENV_URL", "http://127.0.0.1/metadata/instance/")
+	f := NewEnvAzureFingerprint(testLogger())
+	node := &structs.Node{
+		Attributes: make(map[string]string),
+	}
+
+	ok, err := f.Fingerprint(&config.Config{}, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if ok {
+		t.Fatalf("Should be false without test server")
+	}
+}
+
+func TestAzureFingerprint(t *testing.T) {
+	node := &structs.Node{
+		Attributes: make(map[string]string),
+	}
+
+	routes := map[string]string{
+		"compute/vmId":                 "13f56399-bd52-4150-9748-7190aae1ff21",
+		"compute/name":                 "nomad-client-1",
+		"compute/location":             "westus",
+		"compute/vmSize":               "Standard_DS2",
+		"compute/resourceGroupName":    "nomad",
+		"compute/platformFaultDomain":  "0",
+		"compute/platformUpdateDomain": "1",
+		"compute/tags":                 "role:web;unique.rack:r12",
+		"network/interface":            `[{"ipv4":{"ipAddress":[{"privateIpAddress":"10.0.0.4","publicIpAddress":"13.64.12.34"}]},"macAddress":"000D3AF806EC"}]`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			t.Fatal("Metadata not present in HTTP request header")
+		}
+		if r.URL.Query().Get("api-version") != azureAPIVersion {
+			t.Fatalf("bad api version: %s", r.URL.RawQuery)
+		}
+
+		value, ok := routes[r.URL.Path[len("/metadata/instance/"):]]
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Fprintln(w, value)
+	}))
+	defer ts.Close()
+	os.Setenv("AZURE_ENV_URL", ts.URL+"/metadata/instance/")
+	defer os.Unsetenv("AZURE_ENV_URL")
+	f := NewEnvAzureFingerprint(testLogger())
+
+	ok, err := f.Fingerprint(&config.Config{}, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !ok {
+		t.Fatalf("should apply")
+	}
+
+	assertNodeLinksContains(t, node, "azure")
+	assertNodeAttributeEquals(t, node, "unique.platform.azure.id", "13f56399-bd52-4150-9748-7190aae1ff21")
+	assertNodeAttributeEquals(t, node, "unique.platform.azure.name", "nomad-client-1")
+	assertNodeAttributeEquals(t, node, "platform.azure.location", "westus")
+	assertNodeAttributeEquals(t, node, "platform.azure.vm-size", "Standard_DS2")
+	assertNodeAttributeEquals(t, node, "platform.azure.resource-group", "nomad")
+	assertNodeAttributeEquals(t, node, "platform.azure.fault-domain", "0")
+	assertNodeAttributeEquals(t, node, "platform.azure.update-domain", "1")
+	assertNodeAttributeEquals(t, node, "platform.azure.tag.role", "web")
+	assertNodeAttributeEquals(t, node, "unique.platform.azure.tag.rack", "r12")
+	assertNodeAttributeEquals(t, node, "unique.platform.azure.network.0.ip", "10.0.0.4")
+	assertNodeAttributeEquals(t, node, "unique.platform.azure.network.0.public-ip", "13.64.12.34")
+}
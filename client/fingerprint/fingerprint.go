@@ -20,8 +20,10 @@ func init() {
 	builtinFingerprintMap["consul"] = NewConsulFingerprint
 	builtinFingerprintMap["cpu"] = NewCPUFingerprint
 	builtinFingerprintMap["env_aws"] = NewEnvAWSFingerprint
+	builtinFingerprintMap["env_azure"] = NewEnvAzureFingerprint
 	builtinFingerprintMap["env_gce"] = NewEnvGCEFingerprint
 	builtinFingerprintMap["host"] = NewHostFingerprint
+	builtinFingerprintMap["host_volume"] = NewHostVolumeFingerprint
 	builtinFingerprintMap["memory"] = NewMemoryFingerprint
 	builtinFingerprintMap["network"] = NewNetworkFingerprint
 	builtinFingerprintMap["nomad"] = NewNomadFingerprint
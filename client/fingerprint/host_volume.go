@@ -0,0 +1,58 @@
+package fingerprint
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// HostVolumeFingerprint is used to fingerprint the host volumes of the client
+type HostVolumeFingerprint struct {
+	StaticFingerprinter
+	logger *log.Logger
+}
+
+// NewHostVolumeFingerprint is used to create a host volume fingerprint
+func NewHostVolumeFingerprint(logger *log.Logger) Fingerprint {
+	f := &HostVolumeFingerprint{logger: logger}
+	return f
+}
+
+func (f *HostVolumeFingerprint) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
+	if len(cfg.HostVolumes) == 0 {
+		return false, nil
+	}
+
+	// Add the host volumes whose directory exists. Missing directories are
+	// skipped so that the node can still run the tasks using the other
+	// volumes.
+	volumes := make(map[string]*structs.ClientHostVolumeConfig, len(cfg.HostVolumes))
+	for name, volume := range cfg.HostVolumes {
+		if err := checkHostVolumePath(volume.Path); err != nil {
+			f.logger.Printf("[WARN] fingerprint.host_volume: Skipping host volume %q: %v", name, err)
+			continue
+		}
+
+		v := volume.Copy()
+		v.Name = name
+		volumes[name] = v
+		f.logger.Printf("[DEBUG] fingerprint.host_volume: Detected host volume %q at %v", name, v.Path)
+	}
+	node.HostVolumes = volumes
+	return true, nil
+}
+
+// checkHostVolumePath checks the path of a host volume is a directory
+func checkHostVolumePath(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%q is not a directory", path)
+	}
+	return nil
+}
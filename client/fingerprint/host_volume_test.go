@@ -0,0 +1,65 @@
+package fingerprint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHostVolumeFingerprint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nomad-host-volume")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, []byte("foo"), 0644); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	f := NewHostVolumeFingerprint(testLogger())
+	node := &structs.Node{
+		Attributes: make(map[string]string),
+	}
+	cfg := &config.Config{
+		HostVolumes: map[string]*structs.ClientHostVolumeConfig{
+			"data":    {Path: dir, ReadOnly: true},
+			"file":    {Path: file},
+			"missing": {Path: filepath.Join(dir, "missing")},
+		},
+	}
+
+	ok, err := f.Fingerprint(cfg, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !ok {
+		t.Fatalf("should apply")
+	}
+
+	// Only the volumes backed by a directory are added
+	if len(node.HostVolumes) != 1 {
+		t.Fatalf("Expected a single host volume: %#v", node.HostVolumes)
+	}
+	data := node.HostVolumes["data"]
+	if data == nil || data.Name != "data" || data.Path != dir || !data.ReadOnly {
+		t.Fatalf("Bad data volume: %#v", data)
+	}
+	if cfg.HostVolumes["data"].Name != "" {
+		t.Fatalf("Config modified: %#v", cfg.HostVolumes["data"])
+	}
+
+	// Clients without host volumes don't apply
+	ok, err = f.Fingerprint(&config.Config{}, &structs.Node{})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ok {
+		t.Fatalf("shouldn't apply")
+	}
+}
@@ -74,13 +74,7 @@ func (f *NetworkFingerprint) Fingerprint(cfg *config.Config, node *structs.Node)
 
 	f.logger.Printf("[DEBUG] fingerprint.network: Detected interface %v with IP %v during fingerprinting", intf.Name, ip)
 
-	if throughput := f.linkSpeed(intf.Name); throughput > 0 {
-		newNetwork.MBits = throughput
-		f.logger.Printf("[DEBUG] fingerprint.network: link speed for %v set to %v", intf.Name, newNetwork.MBits)
-	} else {
-		f.logger.Printf("[DEBUG] fingerprint.network: Unable to read link speed; setting to default %v", cfg.NetworkSpeed)
-		newNetwork.MBits = cfg.NetworkSpeed
-	}
+	newNetwork.MBits = f.throughput(cfg, intf.Name)
 
 	if node.Resources == nil {
 		node.Resources = &structs.Resources{}
@@ -88,10 +82,100 @@ func (f *NetworkFingerprint) Fingerprint(cfg *config.Config, node *structs.Node)
 
 	node.Resources.Networks = append(node.Resources.Networks, newNetwork)
 
+	// Add the named host networks. Host networks without an address are
+	// skipped so that the node can still run tasks using the other networks.
+	for _, hostNetwork := range cfg.HostNetworks {
+		intf, ip, err := f.hostNetworkAddress(hostNetwork)
+		if err != nil {
+			f.logger.Printf("[WARN] fingerprint.network: Unable to find address of host network %q: %v", hostNetwork.Name, err)
+			continue
+		}
+
+		node.Attributes[fmt.Sprintf("unique.network.%s.ip-address", hostNetwork.Name)] = ip
+		node.Resources.Networks = append(node.Resources.Networks, &structs.NetworkResource{
+			Device:      intf.Name,
+			IP:          ip,
+			CIDR:        ip + "/32",
+			MBits:       f.throughput(cfg, intf.Name),
+			HostNetwork: hostNetwork.Name,
+		})
+		f.logger.Printf("[DEBUG] fingerprint.network: Detected host network %q on interface %v with IP %v", hostNetwork.Name, intf.Name, ip)
+	}
+
 	// return true, because we have a network connection
 	return true, nil
 }
 
+// throughput returns the link speed of the interface, or the configured
+// network speed if it can not be determined
+func (f *NetworkFingerprint) throughput(cfg *config.Config, device string) int {
+	if throughput := f.linkSpeed(device); throughput > 0 {
+		f.logger.Printf("[DEBUG] fingerprint.network: link speed for %v set to %v", device, throughput)
+		return throughput
+	}
+	f.logger.Printf("[DEBUG] fingerprint.network: Unable to read link speed; setting to default %v", cfg.NetworkSpeed)
+	return cfg.NetworkSpeed
+}
+
+// hostNetworkAddress returns the interface and IPv4 address of the host
+// network. The address is the first one of the configured interface within
+// the CIDR of the network. Without an interface, the addresses of all devices
+// marked as UP are searched.
+func (f *NetworkFingerprint) hostNetworkAddress(n *config.HostNetwork) (*net.Interface, string, error) {
+	var cidr *net.IPNet
+	if n.CIDR != "" {
+		var err error
+		if _, cidr, err = net.ParseCIDR(n.CIDR); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var intfs []net.Interface
+	if n.Interface != "" {
+		intf, err := f.interfaceDetector.InterfaceByName(n.Interface)
+		if err != nil {
+			return nil, "", err
+		}
+		intfs = append(intfs, *intf)
+	} else {
+		all, err := f.interfaceDetector.Interfaces()
+		if err != nil {
+			return nil, "", err
+		}
+		for _, intf := range all {
+			if f.isDeviceEnabled(&intf) {
+				intfs = append(intfs, intf)
+			}
+		}
+	}
+
+	for i := range intfs {
+		intf := &intfs[i]
+		addrs, err := f.interfaceDetector.Addrs(intf)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, addr := range addrs {
+			var ip net.IP
+			switch v := (addr).(type) {
+			case *net.IPNet:
+				ip = v.IP
+			case *net.IPAddr:
+				ip = v.IP
+			}
+			if ip.To4() == nil || (cidr != nil && !cidr.Contains(ip)) {
+				continue
+			}
+			return intf, ip.String(), nil
+		}
+	}
+
+	if cidr != nil {
+		return nil, "", fmt.Errorf("no address in %s found", n.CIDR)
+	}
+	return nil, "", fmt.Errorf("interface %s has no IPv4 address", n.Interface)
+}
+
 // Gets the ipv4 addr for a network interface
 func (f *NetworkFingerprint) ipAddress(intf *net.Interface) (string, error) {
 	var addrs []net.Addr
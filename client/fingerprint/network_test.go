@@ -308,3 +308,49 @@ func TestNetworkFingerPrint_excludelo_down_interfaces(t *testing.T) {
 		t.Fatal("Expected Network Resource to have a non-zero bandwith")
 	}
 }
+
+func TestNetworkFingerPrint_host_networks(t *testing.T) {
+	f := &NetworkFingerprint{logger: testLogger(), interfaceDetector: &NetworkInterfaceDetectorMultipleInterfaces{}}
+	node := &structs.Node{
+		Attributes: make(map[string]string),
+	}
+	cfg := &config.Config{
+		NetworkSpeed: 100,
+		HostNetworks: []*config.HostNetwork{
+			{Name: "private", Interface: "eth1"},
+			{Name: "public", CIDR: "100.64.0.0/10"},
+			{Name: "storage", CIDR: "192.168.0.0/16"},
+		},
+	}
+
+	ok, err := f.Fingerprint(cfg, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !ok {
+		t.Fatalf("should apply")
+	}
+
+	// The storage network has no address and is skipped
+	networks := node.Resources.Networks
+	if len(networks) != 3 {
+		t.Fatalf("Expected default and two host networks: %#v", networks)
+	}
+	if networks[0].HostNetwork != "" || networks[0].Device != "eth0" {
+		t.Fatalf("Bad default network: %#v", networks[0])
+	}
+	private := networks[1]
+	if private.HostNetwork != "private" || private.Device != "eth1" || private.CIDR != private.IP+"/32" || private.MBits == 0 {
+		t.Fatalf("Bad private network: %#v", private)
+	}
+	public := networks[2]
+	if public.HostNetwork != "public" || public.Device != "eth0" || public.IP != "100.64.0.0" {
+		t.Fatalf("Bad public network: %#v", public)
+	}
+
+	assertNodeAttributeContains(t, node, "unique.network.private.ip-address")
+	assertNodeAttributeContains(t, node, "unique.network.public.ip-address")
+	if _, ok := node.Attributes["unique.network.storage.ip-address"]; ok {
+		t.Fatalf("Unexpected storage network address")
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	client "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/nomad/structs"
+	sconfig "github.com/hashicorp/nomad/nomad/structs/config"
 	vapi "github.com/hashicorp/vault/api"
 )
 
@@ -19,70 +20,105 @@ const (
 
 // VaultFingerprint is used to fingerprint for Vault
 type VaultFingerprint struct {
-	logger    *log.Logger
+	logger *log.Logger
+
+	// clusters tracks the state of each Vault cluster, keyed by name
+	clusters map[string]*vaultCluster
+}
+
+// vaultCluster is the client and last known state of a Vault cluster
+type vaultCluster struct {
 	client    *vapi.Client
 	lastState string
 }
 
 // NewVaultFingerprint is used to create a Vault fingerprint
 func NewVaultFingerprint(logger *log.Logger) Fingerprint {
-	return &VaultFingerprint{logger: logger, lastState: vaultUnavailable}
+	return &VaultFingerprint{logger: logger, clusters: make(map[string]*vaultCluster)}
 }
 
 func (f *VaultFingerprint) Fingerprint(config *client.Config, node *structs.Node) (bool, error) {
-	if config.VaultConfig == nil || !config.VaultConfig.Enabled {
-		return false, nil
+	// The default cluster is fingerprinted under vault.* and the named ones
+	// under vault.<name>.*
+	applies := false
+	if config.VaultConfig != nil && config.VaultConfig.Enabled {
+		ok, err := f.fingerprintCluster(structs.DefaultCluster, "vault", config.VaultConfig, node)
+		if err != nil {
+			return false, err
+		}
+		applies = applies || ok
+	}
+	for name, conf := range config.VaultClusters {
+		if conf == nil || !conf.Enabled {
+			continue
+		}
+		ok, err := f.fingerprintCluster(name, "vault."+name, conf, node)
+		if err != nil {
+			return false, err
+		}
+		applies = applies || ok
+	}
+	return applies, nil
+}
+
+// fingerprintCluster fingerprints a Vault cluster, setting its attributes
+// under the given prefix
+func (f *VaultFingerprint) fingerprintCluster(name, prefix string, conf *sconfig.VaultConfig, node *structs.Node) (bool, error) {
+	cluster, ok := f.clusters[name]
+	if !ok {
+		cluster = &vaultCluster{lastState: vaultUnavailable}
+		f.clusters[name] = cluster
 	}
 
 	// Only create the client once to avoid creating too many connections to
 	// Vault.
-	if f.client == nil {
-		vaultConfig, err := config.VaultConfig.ApiConfig()
+	if cluster.client == nil {
+		vaultConfig, err := conf.ApiConfig()
 		if err != nil {
 			return false, fmt.Errorf("Failed to initialize the Vault client config: %v", err)
 		}
 
-		f.client, err = vapi.NewClient(vaultConfig)
+		cluster.client, err = vapi.NewClient(vaultConfig)
 		if err != nil {
 			return false, fmt.Errorf("Failed to initialize Vault client: %s", err)
 		}
 	}
 
 	// Connect to vault and parse its information
-	status, err := f.client.Sys().SealStatus()
+	status, err := cluster.client.Sys().SealStatus()
 	if err != nil {
 		// Clear any attributes set by a previous fingerprint.
-		f.clearVaultAttributes(node)
+		f.clearVaultAttributes(node, prefix)
 
 		// Print a message indicating that Vault is not available anymore
-		if f.lastState == vaultAvailable {
-			f.logger.Printf("[INFO] fingerprint.vault: Vault is unavailable")
+		if cluster.lastState == vaultAvailable {
+			f.logger.Printf("[INFO] fingerprint.vault: Vault cluster %q is unavailable", name)
 		}
-		f.lastState = vaultUnavailable
+		cluster.lastState = vaultUnavailable
 		return false, nil
 	}
 
-	node.Attributes["vault.accessible"] = strconv.FormatBool(true)
+	node.Attributes[prefix+".accessible"] = strconv.FormatBool(true)
 	// We strip the Vault prefix becasue < 0.6.2 the version looks like:
 	// status.Version = "Vault v0.6.1"
-	node.Attributes["vault.version"] = strings.TrimPrefix(status.Version, "Vault ")
-	node.Attributes["vault.cluster_id"] = status.ClusterID
-	node.Attributes["vault.cluster_name"] = status.ClusterName
+	node.Attributes[prefix+".version"] = strings.TrimPrefix(status.Version, "Vault ")
+	node.Attributes[prefix+".cluster_id"] = status.ClusterID
+	node.Attributes[prefix+".cluster_name"] = status.ClusterName
 
 	// If Vault was previously unavailable print a message to indicate the Agent
 	// is available now
-	if f.lastState == vaultUnavailable {
-		f.logger.Printf("[INFO] fingerprint.vault: Vault is available")
+	if cluster.lastState == vaultUnavailable {
+		f.logger.Printf("[INFO] fingerprint.vault: Vault cluster %q is available", name)
 	}
-	f.lastState = vaultAvailable
+	cluster.lastState = vaultAvailable
 	return true, nil
 }
 
-func (f *VaultFingerprint) clearVaultAttributes(n *structs.Node) {
-	delete(n.Attributes, "vault.accessible")
-	delete(n.Attributes, "vault.version")
-	delete(n.Attributes, "vault.cluster_id")
-	delete(n.Attributes, "vault.cluster_name")
+func (f *VaultFingerprint) clearVaultAttributes(n *structs.Node, prefix string) {
+	delete(n.Attributes, prefix+".accessible")
+	delete(n.Attributes, prefix+".version")
+	delete(n.Attributes, prefix+".cluster_id")
+	delete(n.Attributes, prefix+".cluster_name")
 }
 
 func (f *VaultFingerprint) Periodic() (bool, time.Duration) {
@@ -5,6 +5,7 @@ import (
 
 	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/nomad/structs"
+	sconfig "github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/hashicorp/nomad/testutil"
 )
 
@@ -19,6 +20,9 @@ func TestVaultFingerprint(t *testing.T) {
 
 	config := config.DefaultConfig()
 	config.VaultConfig = tv.Config
+	config.VaultClusters = map[string]*sconfig.VaultConfig{
+		"secondary": tv.Config,
+	}
 
 	ok, err := fp.Fingerprint(config, node)
 	if err != nil {
@@ -32,4 +36,10 @@ func TestVaultFingerprint(t *testing.T) {
 	assertNodeAttributeContains(t, node, "vault.version")
 	assertNodeAttributeContains(t, node, "vault.cluster_id")
 	assertNodeAttributeContains(t, node, "vault.cluster_name")
+
+	// Named clusters are fingerprinted under their name
+	assertNodeAttributeContains(t, node, "vault.secondary.accessible")
+	assertNodeAttributeContains(t, node, "vault.secondary.version")
+	assertNodeAttributeContains(t, node, "vault.secondary.cluster_id")
+	assertNodeAttributeContains(t, node, "vault.secondary.cluster_name")
 }
@@ -1,9 +1,14 @@
 package getter
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"net/url"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	gg "github.com/hashicorp/go-getter"
@@ -17,8 +22,9 @@ var (
 	getters map[string]gg.Getter
 	lock    sync.Mutex
 
-	// supported is the set of download schemes supported by Nomad
-	supported = []string{"http", "https", "s3"}
+	// supported is the set of download schemes supported by Nomad. Git
+	// sources require git to be installed on the client.
+	supported = []string{"http", "https", "s3", "git"}
 )
 
 // getClient returns a client that is suitable for Nomad downloading artifacts.
@@ -36,10 +42,17 @@ func getClient(src, dst string) *gg.Client {
 		}
 	}
 
+	// Git sources are repositories, which are cloned into the destination
+	// directory rather than downloaded as files
+	mode := gg.ClientModeAny
+	if strings.HasPrefix(src, "git::") {
+		mode = gg.ClientModeDir
+	}
+
 	return &gg.Client{
 		Src:     src,
 		Dst:     dst,
-		Mode:    gg.ClientModeAny,
+		Mode:    mode,
 		Getters: getters,
 	}
 }
@@ -61,18 +74,100 @@ func getGetterUrl(taskEnv *env.TaskEnvironment, artifact *structs.TaskArtifact)
 	return u.String(), nil
 }
 
-// GetArtifact downloads an artifact into the specified task directory.
-func GetArtifact(taskEnv *env.TaskEnvironment, artifact *structs.TaskArtifact, taskDir string) error {
+// GetArtifact downloads an artifact into the specified task directory. If the
+// artifact has been prefetched into the cache directory, it is copied from the
+// cache instead.
+func GetArtifact(taskEnv *env.TaskEnvironment, artifact *structs.TaskArtifact, taskDir, cacheDir string) error {
 	url, err := getGetterUrl(taskEnv, artifact)
 	if err != nil {
 		return err
 	}
+	dest := filepath.Join(taskDir, artifact.RelativeDest)
+
+	// Copy the artifact from the cache if it has been prefetched
+	if cacheDir != "" {
+		cached := filepath.Join(cacheDir, cacheKey(url))
+		if _, err := os.Stat(cached); err == nil {
+			if err := copyDir(cached, dest); err != nil {
+				return fmt.Errorf("failed to copy cached artifact: %v", err)
+			}
+			return nil
+		}
+	}
 
 	// Download the artifact
-	dest := filepath.Join(taskDir, artifact.RelativeDest)
 	if err := getClient(url, dest).Get(); err != nil {
 		return fmt.Errorf("GET error: %v", err)
 	}
 
 	return nil
 }
+
+// PrefetchArtifact downloads an artifact into the cache directory so that
+// tasks downloading the same artifact copy it from the cache. An artifact that
+// was already prefetched is downloaded again.
+func PrefetchArtifact(taskEnv *env.TaskEnvironment, artifact *structs.TaskArtifact, cacheDir string) error {
+	url, err := getGetterUrl(taskEnv, artifact)
+	if err != nil {
+		return err
+	}
+
+	// Download into a temporary directory so tasks never copy a partially
+	// downloaded artifact from the cache
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+	dest := filepath.Join(cacheDir, cacheKey(url))
+	tmp := dest + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := getClient(url, tmp).Get(); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("GET error: %v", err)
+	}
+
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+// cacheKey returns the name an artifact downloaded from the URL is cached at
+func cacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// copyDir copies the contents of the src directory into the dst directory
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode())
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_RDWR|os.O_CREATE|os.O_TRUNC, info.Mode())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
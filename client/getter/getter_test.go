@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
 	"strings"
@@ -39,7 +40,7 @@ func TestGetArtifact_FileAndChecksum(t *testing.T) {
 
 	// Download the artifact
 	taskEnv := env.NewTaskEnvironment(mock.Node())
-	if err := GetArtifact(taskEnv, artifact, taskDir); err != nil {
+	if err := GetArtifact(taskEnv, artifact, taskDir, ""); err != nil {
 		t.Fatalf("GetArtifact failed: %v", err)
 	}
 
@@ -74,7 +75,7 @@ func TestGetArtifact_File_RelativeDest(t *testing.T) {
 
 	// Download the artifact
 	taskEnv := env.NewTaskEnvironment(mock.Node())
-	if err := GetArtifact(taskEnv, artifact, taskDir); err != nil {
+	if err := GetArtifact(taskEnv, artifact, taskDir, ""); err != nil {
 		t.Fatalf("GetArtifact failed: %v", err)
 	}
 
@@ -125,7 +126,7 @@ func TestGetArtifact_InvalidChecksum(t *testing.T) {
 
 	// Download the artifact and expect an error
 	taskEnv := env.NewTaskEnvironment(mock.Node())
-	if err := GetArtifact(taskEnv, artifact, taskDir); err == nil {
+	if err := GetArtifact(taskEnv, artifact, taskDir, ""); err == nil {
 		t.Fatalf("GetArtifact should have failed")
 	}
 }
@@ -191,7 +192,7 @@ func TestGetArtifact_Archive(t *testing.T) {
 	}
 
 	taskEnv := env.NewTaskEnvironment(mock.Node())
-	if err := GetArtifact(taskEnv, artifact, taskDir); err != nil {
+	if err := GetArtifact(taskEnv, artifact, taskDir, ""); err != nil {
 		t.Fatalf("GetArtifact failed: %v", err)
 	}
 
@@ -204,3 +205,99 @@ func TestGetArtifact_Archive(t *testing.T) {
 	}
 	checkContents(taskDir, expected, t)
 }
+
+func TestGetArtifact_Prefetched(t *testing.T) {
+	// Create the test server hosting the file to download
+	ts := httptest.NewServer(http.FileServer(http.Dir(filepath.Dir("./test-fixtures/"))))
+
+	// Create temp directories to prefetch and download into
+	cacheDir, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("failed to make temp directory: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+	taskDir, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("failed to make temp directory: %v", err)
+	}
+	defer os.RemoveAll(taskDir)
+
+	file := "archive.tar.gz"
+	artifact := &structs.TaskArtifact{
+		GetterSource: fmt.Sprintf("%s/%s", ts.URL, file),
+		GetterOptions: map[string]string{
+			"checksum": "sha1:20bab73c72c56490856f913cf594bad9a4d730f6",
+		},
+		RelativeDest: "local/",
+	}
+
+	// Prefetch the artifact and stop the server
+	taskEnv := env.NewTaskEnvironment(mock.Node())
+	if err := PrefetchArtifact(taskEnv, artifact, cacheDir); err != nil {
+		t.Fatalf("PrefetchArtifact failed: %v", err)
+	}
+	ts.Close()
+
+	// The artifact is copied from the cache
+	if err := GetArtifact(taskEnv, artifact, taskDir, cacheDir); err != nil {
+		t.Fatalf("GetArtifact failed: %v", err)
+	}
+	expected := map[string]string{
+		"local/exist/my.config": "hello world\n",
+		"local/new/my.config":   "hello world\n",
+		"local/test.sh":         "sleep 1\n",
+	}
+	checkContents(taskDir, expected, t)
+
+	// Artifacts that weren't prefetched are still downloaded
+	artifact.GetterSource = fmt.Sprintf("%s/%s", ts.URL, "test.sh")
+	if err := GetArtifact(taskEnv, artifact, taskDir, cacheDir); err == nil {
+		t.Fatalf("GetArtifact should have failed")
+	}
+}
+
+func TestGetArtifact_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	// Create a repository to clone
+	repo, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("failed to make temp directory: %v", err)
+	}
+	defer os.RemoveAll(repo)
+	if err := ioutil.WriteFile(filepath.Join(repo, "test.sh"), []byte("echo hello"), 0755); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, args := range [][]string{
+		{"init"},
+		{"add", "test.sh"},
+		{"-c", "user.name=nomad", "-c", "user.email=nomad@example.com", "commit", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+
+	taskDir, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("failed to make temp directory: %v", err)
+	}
+	defer os.RemoveAll(taskDir)
+
+	artifact := &structs.TaskArtifact{
+		GetterSource: "git::file://" + repo,
+		RelativeDest: "local/repo",
+	}
+	taskEnv := env.NewTaskEnvironment(mock.Node())
+	if err := GetArtifact(taskEnv, artifact, taskDir, ""); err != nil {
+		t.Fatalf("GetArtifact failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(taskDir, "local", "repo", "test.sh")); err != nil {
+		t.Fatalf("file not found: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/stats"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// memoryPressureCooldown is how long the client waits after acting on
+	// memory pressure before acting again, so that the memory freed shows in
+	// the host stats
+	memoryPressureCooldown = 30 * time.Second
+)
+
+// memoryPressureCandidate is an allocation that may be acted on to relieve
+// memory pressure on the host
+type memoryPressureCandidate struct {
+	runner *AllocRunner
+	alloc  *structs.Allocation
+
+	// usage and limit are the memory used by and allocated to the
+	// allocation, in bytes
+	usage uint64
+	limit uint64
+}
+
+// overLimit returns the memory the allocation uses beyond its limit
+func (c *memoryPressureCandidate) overLimit() uint64 {
+	if c.usage <= c.limit {
+		return 0
+	}
+	return c.usage - c.limit
+}
+
+// checkMemoryPressure relieves memory pressure on the host, once the memory
+// in use crosses the configured threshold, by restarting or evicting an
+// allocation rather than letting the kernel OOM killer pick a process.
+func (c *Client) checkMemoryPressure(hs *stats.HostStats) {
+	conf := c.config.MemoryPressure
+	if conf == nil || hs.Memory == nil || hs.Memory.Total == 0 {
+		return
+	}
+
+	used := float64(hs.Memory.Total-hs.Memory.Available) / float64(hs.Memory.Total) * 100
+	if used < conf.Threshold {
+		return
+	}
+	if time.Since(c.lastMemoryPressureAction) < memoryPressureCooldown {
+		return
+	}
+
+	victim := selectMemoryPressureVictim(c.memoryPressureCandidates(), conf.Victim)
+	if victim == nil {
+		c.logger.Printf("[WARN] client: %.1f%% of host memory used, above the %.1f%% threshold, but no allocation to relieve it",
+			used, conf.Threshold)
+		return
+	}
+	c.lastMemoryPressureAction = time.Now()
+
+	reason := fmt.Sprintf("%.1f%% of host memory used, above the %.1f%% threshold; allocation uses %d MiB of its %d MiB",
+		used, conf.Threshold, victim.usage/1024/1024, victim.limit/1024/1024)
+	switch conf.Action {
+	case config.MemoryPressureActionEvict:
+		c.logger.Printf("[WARN] client: evicting alloc %q to relieve memory pressure: %s", victim.alloc.ID, reason)
+		event := structs.NewTaskEvent(structs.TaskMemoryPressure).SetMemoryPressure(reason)
+		victim.runner.Evict(event, "evicted to relieve memory pressure on the host")
+	default:
+		c.logger.Printf("[WARN] client: restarting alloc %q to relieve memory pressure: %s", victim.alloc.ID, reason)
+		if err := victim.runner.Restart("", "memory pressure: "+reason); err != nil {
+			c.logger.Printf("[ERR] client: failed to restart alloc %q: %v", victim.alloc.ID, err)
+		}
+	}
+	metrics.IncrCounter([]string{"client", "memory_pressure", conf.Action}, 1)
+}
+
+// memoryPressureCandidates returns the running allocations along with their
+// memory usage. Allocations whose usage isn't known yet are skipped, as
+// acting on them may not free any memory.
+func (c *Client) memoryPressureCandidates() []*memoryPressureCandidate {
+	var candidates []*memoryPressureCandidate
+	for _, ar := range c.getAllocRunners() {
+		alloc := ar.Alloc()
+		if alloc.TerminalStatus() || alloc.ClientStatus != structs.AllocClientStatusRunning {
+			continue
+		}
+
+		usage, err := ar.LatestAllocStats("")
+		if err != nil || usage.ResourceUsage == nil || usage.ResourceUsage.MemoryStats == nil {
+			continue
+		}
+		rss := usage.ResourceUsage.MemoryStats.RSS
+		if rss == 0 {
+			continue
+		}
+
+		var limit uint64
+		if alloc.Resources != nil {
+			limit = uint64(alloc.Resources.MemoryMB) * 1024 * 1024
+		}
+		candidates = append(candidates, &memoryPressureCandidate{
+			runner: ar,
+			alloc:  alloc,
+			usage:  rss,
+			limit:  limit,
+		})
+	}
+	return candidates
+}
+
+// selectMemoryPressureVictim returns the allocation to act on to relieve
+// memory pressure. With the over_limit policy the allocation using the most
+// memory beyond its limit is chosen, falling back to the lowest_priority
+// policy when none is over its limit. With the lowest_priority policy the
+// allocation of the job with the lowest priority is chosen, the one using the
+// most memory breaking ties.
+func selectMemoryPressureVictim(candidates []*memoryPressureCandidate, victim string) *memoryPressureCandidate {
+	if victim == config.MemoryPressureVictimOverLimit {
+		var worst *memoryPressureCandidate
+		for _, c := range candidates {
+			if c.overLimit() == 0 {
+				continue
+			}
+			if worst == nil || c.overLimit() > worst.overLimit() ||
+				(c.overLimit() == worst.overLimit() && c.alloc.ID < worst.alloc.ID) {
+				worst = c
+			}
+		}
+		if worst != nil {
+			return worst
+		}
+	}
+
+	var lowest *memoryPressureCandidate
+	for _, c := range candidates {
+		if lowest == nil {
+			lowest = c
+			continue
+		}
+		cp, lp := c.alloc.Job.Priority, lowest.alloc.Job.Priority
+		switch {
+		case cp != lp:
+			if cp < lp {
+				lowest = c
+			}
+		case c.usage != lowest.usage:
+			if c.usage > lowest.usage {
+				lowest = c
+			}
+		case c.alloc.ID < lowest.alloc.ID:
+			lowest = c
+		}
+	}
+	return lowest
+}
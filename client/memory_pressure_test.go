@@ -0,0 +1,59 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/mock"
+)
+
+func testMemoryPressureCandidate(id string, priority int, usageMB, limitMB uint64) *memoryPressureCandidate {
+	alloc := mock.Alloc()
+	alloc.ID = id
+	alloc.Job.Priority = priority
+	return &memoryPressureCandidate{
+		alloc: alloc,
+		usage: usageMB * 1024 * 1024,
+		limit: limitMB * 1024 * 1024,
+	}
+}
+
+func TestMemoryPressure_SelectVictim_OverLimit(t *testing.T) {
+	candidates := []*memoryPressureCandidate{
+		testMemoryPressureCandidate("a", 10, 300, 256),
+		testMemoryPressureCandidate("b", 90, 900, 512),
+		testMemoryPressureCandidate("c", 50, 100, 256),
+	}
+
+	// The allocation furthest over its limit is chosen regardless of its
+	// priority
+	victim := selectMemoryPressureVictim(candidates, config.MemoryPressureVictimOverLimit)
+	if victim == nil || victim.alloc.ID != "b" {
+		t.Fatalf("bad: %#v", victim)
+	}
+
+	// Without any allocation over its limit, the lowest priority one is
+	victim = selectMemoryPressureVictim(candidates[2:], config.MemoryPressureVictimOverLimit)
+	if victim == nil || victim.alloc.ID != "c" {
+		t.Fatalf("bad: %#v", victim)
+	}
+}
+
+func TestMemoryPressure_SelectVictim_LowestPriority(t *testing.T) {
+	candidates := []*memoryPressureCandidate{
+		testMemoryPressureCandidate("a", 50, 900, 512),
+		testMemoryPressureCandidate("b", 20, 100, 256),
+		testMemoryPressureCandidate("c", 20, 200, 256),
+	}
+
+	// The lowest priority wins over being over the limit, the memory used
+	// breaks ties
+	victim := selectMemoryPressureVictim(candidates, config.MemoryPressureVictimLowestPriority)
+	if victim == nil || victim.alloc.ID != "c" {
+		t.Fatalf("bad: %#v", victim)
+	}
+
+	if victim := selectMemoryPressureVictim(nil, config.MemoryPressureVictimLowestPriority); victim != nil {
+		t.Fatalf("bad: %#v", victim)
+	}
+}
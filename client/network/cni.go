@@ -0,0 +1,162 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	cniCommandAdd = "ADD"
+	cniCommandDel = "DEL"
+)
+
+// invoker executes the CNI plugins of a network configuration following the
+// CNI specification. Plugins are looked up by their type in the plugin path
+// and are passed their configuration on stdin.
+type invoker struct {
+	containerID string
+	netns       string
+	ifName      string
+	path        []string
+}
+
+// cniError is the error a CNI plugin returns on stdout when it fails
+type cniError struct {
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+	Details string `json:"details"`
+}
+
+// Add invokes the plugins of the network in order, passing the result of each
+// plugin to the next. The port mappings, from host port to the port inside
+// the network namespace, are passed to plugins supporting them.
+func (i *invoker) Add(list *networkConfigList, ports map[int]int) error {
+	var prevResult interface{}
+	for _, plugin := range list.Plugins {
+		conf := i.pluginConfig(list, plugin, ports)
+		if prevResult != nil {
+			conf["prevResult"] = prevResult
+		}
+
+		out, err := i.exec(cniCommandAdd, list, conf)
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(out)) != 0 {
+			var result interface{}
+			if err := json.Unmarshal(out, &result); err != nil {
+				return fmt.Errorf("failed to parse result of CNI plugin %q: %v", conf["type"], err)
+			}
+			prevResult = result
+		}
+	}
+	return nil
+}
+
+// Del invokes the plugins of the network in reverse order to remove the
+// configuration they added
+func (i *invoker) Del(list *networkConfigList, ports map[int]int) error {
+	for idx := len(list.Plugins) - 1; idx >= 0; idx-- {
+		conf := i.pluginConfig(list, list.Plugins[idx], ports)
+		if _, err := i.exec(cniCommandDel, list, conf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pluginConfig builds the configuration passed to a plugin of the network
+func (i *invoker) pluginConfig(list *networkConfigList, plugin map[string]interface{}, ports map[int]int) map[string]interface{} {
+	conf := make(map[string]interface{}, len(plugin)+2)
+	for k, v := range plugin {
+		conf[k] = v
+	}
+	conf["name"] = list.Name
+	conf["cniVersion"] = list.CNIVersion
+
+	// Plugins declare the runtime configuration they support as capabilities
+	if capabilities, ok := plugin["capabilities"].(map[string]interface{}); ok {
+		if enabled, _ := capabilities["portMappings"].(bool); enabled && len(ports) != 0 {
+			hostPorts := make([]int, 0, len(ports))
+			for host := range ports {
+				hostPorts = append(hostPorts, host)
+			}
+			sort.Ints(hostPorts)
+
+			var mappings []map[string]interface{}
+			for _, host := range hostPorts {
+				for _, protocol := range []string{"tcp", "udp"} {
+					mappings = append(mappings, map[string]interface{}{
+						"hostPort":      host,
+						"containerPort": ports[host],
+						"protocol":      protocol,
+					})
+				}
+			}
+			conf["runtimeConfig"] = map[string]interface{}{"portMappings": mappings}
+		}
+	}
+	return conf
+}
+
+// exec executes the plugin of the given configuration with the CNI command
+// and returns its output
+func (i *invoker) exec(command string, list *networkConfigList, conf map[string]interface{}) ([]byte, error) {
+	pluginType, _ := conf["type"].(string)
+	if pluginType == "" {
+		return nil, fmt.Errorf("CNI network %q has a plugin without a type", list.Name)
+	}
+	bin, err := i.findPlugin(pluginType)
+	if err != nil {
+		return nil, err
+	}
+
+	stdin, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	ifName := i.ifName
+	if list.IfName != "" {
+		ifName = list.IfName
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(bin)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(),
+		"CNI_COMMAND="+command,
+		"CNI_CONTAINERID="+i.containerID,
+		"CNI_NETNS="+i.netns,
+		"CNI_IFNAME="+ifName,
+		"CNI_PATH="+strings.Join(i.path, string(filepath.ListSeparator)),
+	)
+	if err := cmd.Run(); err != nil {
+		var cerr cniError
+		if jerr := json.Unmarshal(stdout.Bytes(), &cerr); jerr == nil && cerr.Msg != "" {
+			return nil, fmt.Errorf("CNI plugin %q failed to %s network %q: %s", pluginType, command, list.Name, cerr.Msg)
+		}
+		return nil, fmt.Errorf("CNI plugin %q failed to %s network %q: %v: %s",
+			pluginType, command, list.Name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// findPlugin returns the path of the plugin binary of the given type
+func (i *invoker) findPlugin(pluginType string) (string, error) {
+	for _, dir := range i.path {
+		bin := filepath.Join(dir, pluginType)
+		if info, err := os.Stat(bin); err == nil && !info.IsDir() {
+			return bin, nil
+		}
+	}
+	return "", fmt.Errorf("CNI plugin %q not found in %q", pluginType, strings.Join(i.path, string(filepath.ListSeparator)))
+}
@@ -0,0 +1,25 @@
+// +build darwin dragonfly freebsd netbsd openbsd solaris windows
+
+package network
+
+import (
+	"fmt"
+)
+
+// errNetNSUnsupported is returned as network namespaces are only supported on
+// Linux
+var errNetNSUnsupported = fmt.Errorf("network namespaces are not supported on this platform")
+
+func createNetNS(name string) (string, error) {
+	return "", errNetNSUnsupported
+}
+
+func removeNetNS(path string) error {
+	return errNetNSUnsupported
+}
+
+// WithNetNS calls f on a thread switched to the network namespace at the
+// given path.
+func WithNetNS(path string, f func() error) error {
+	return errNetNSUnsupported
+}
@@ -0,0 +1,100 @@
+package network
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+// netNSDir is the directory the network namespaces of allocations are
+// persisted in by bind mounting them
+const netNSDir = "/var/run/netns"
+
+// createNetNS creates a network namespace persisted under the given name and
+// returns its path
+func createNetNS(name string) (string, error) {
+	if err := os.MkdirAll(netNSDir, 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(netNSDir, name)
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+
+	// The namespace is created on a locked thread that is switched back to
+	// its original namespace once the new one is bind mounted
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		errCh <- withThreadNetNS(func(threadNS string) error {
+			if err := syscall.Unshare(syscall.CLONE_NEWNET); err != nil {
+				return fmt.Errorf("failed to unshare network namespace: %v", err)
+			}
+			return syscall.Mount(threadNS, path, "none", syscall.MS_BIND, "")
+		})
+	}()
+
+	if err := <-errCh; err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// removeNetNS unmounts and removes the network namespace at the given path
+func removeNetNS(path string) error {
+	if err := syscall.Unmount(path, syscall.MNT_DETACH); err != nil && err != syscall.EINVAL && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// WithNetNS calls f on a thread switched to the network namespace at the
+// given path. Processes started by f inherit the network namespace.
+func WithNetNS(path string, f func() error) error {
+	ns, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open network namespace: %v", err)
+	}
+	defer ns.Close()
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	return withThreadNetNS(func(string) error {
+		if err := system.Setns(ns.Fd(), syscall.CLONE_NEWNET); err != nil {
+			return fmt.Errorf("failed to join network namespace: %v", err)
+		}
+		return f()
+	})
+}
+
+// withThreadNetNS calls f with the path of the network namespace of the
+// current thread, which must be locked, and restores the namespace of the
+// thread afterwards
+func withThreadNetNS(f func(threadNS string) error) error {
+	threadNS := fmt.Sprintf("/proc/self/task/%d/ns/net", syscall.Gettid())
+	orig, err := os.Open(threadNS)
+	if err != nil {
+		return fmt.Errorf("failed to open network namespace of thread: %v", err)
+	}
+	defer orig.Close()
+
+	ferr := f(threadNS)
+	if err := system.Setns(orig.Fd(), syscall.CLONE_NEWNET); err != nil {
+		return fmt.Errorf("failed to restore network namespace of thread: %v", err)
+	}
+	return ferr
+}
@@ -0,0 +1,56 @@
+package network
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestNetNS_CreateJoinRemove(t *testing.T) {
+	if syscall.Geteuid() != 0 {
+		t.Skip("Must be root to create network namespaces")
+	}
+
+	name := structs.GenerateUUID()
+	path, err := createNetNS(name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer removeNetNS(path)
+
+	threadNS := func() string {
+		ns, err := os.Readlink(fmt.Sprintf("/proc/self/task/%d/ns/net", syscall.Gettid()))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return ns
+	}
+	hostNS, err := os.Readlink("/proc/self/ns/net")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var joined string
+	if err := WithNetNS(path, func() error {
+		joined = threadNS()
+		return nil
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if joined == "" || joined == hostNS {
+		t.Fatalf("network namespace not joined: %q", joined)
+	}
+
+	if err := removeNetNS(path); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("network namespace not removed: %v", err)
+	}
+	if err := WithNetNS(path, func() error { return nil }); err == nil {
+		t.Fatalf("expected error joining removed network namespace")
+	}
+}
@@ -0,0 +1,235 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// cniVersion is the version of the CNI specification the generated
+	// network configurations use
+	cniVersion = "0.4.0"
+
+	// allocIfName is the name of the interface CNI plugins create in the
+	// network namespace of an allocation
+	allocIfName = "eth0"
+)
+
+// Manager creates the network namespaces of allocations and configures them
+// with CNI plugins. Allocations in bridge mode are attached to a bridge on the
+// host, while allocations in "cni/<name>" mode use the named CNI network
+// configuration of the client.
+type Manager struct {
+	config *config.Config
+	logger *log.Logger
+}
+
+// NewManager returns a network manager using the CNI settings of the client
+func NewManager(config *config.Config, logger *log.Logger) *Manager {
+	return &Manager{
+		config: config,
+		logger: logger,
+	}
+}
+
+// Setup creates the network namespace of the allocation and configures it for
+// the given network. The path of the network namespace is returned.
+func (m *Manager) Setup(allocID string, network *structs.NetworkResource) (string, error) {
+	networks, err := m.networks(network)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := createNetNS(allocID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create network namespace: %v", err)
+	}
+
+	for _, n := range networks {
+		if err := m.invoker(allocID, path).Add(n, network.PortMappings()); err != nil {
+			if terr := m.Teardown(allocID, path, network); terr != nil {
+				m.logger.Printf("[ERR] client.network: failed to clean up network of alloc %q: %v", allocID, terr)
+			}
+			return "", err
+		}
+	}
+
+	m.logger.Printf("[DEBUG] client.network: configured %q network namespace %q for alloc %q", network.Mode, path, allocID)
+	return path, nil
+}
+
+// Teardown removes the configuration of the network from the network
+// namespace of the allocation and deletes the namespace.
+func (m *Manager) Teardown(allocID, path string, network *structs.NetworkResource) error {
+	var mErr multierror.Error
+	networks, err := m.networks(network)
+	if err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+
+	// Networks are removed in the reverse order they were added in
+	for i := len(networks) - 1; i >= 0; i-- {
+		if err := m.invoker(allocID, path).Del(networks[i], network.PortMappings()); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+
+	if err := removeNetNS(path); err != nil {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("failed to remove network namespace: %v", err))
+	}
+	return mErr.ErrorOrNil()
+}
+
+func (m *Manager) invoker(allocID, path string) *invoker {
+	return &invoker{
+		containerID: allocID,
+		netns:       path,
+		ifName:      allocIfName,
+		path:        filepath.SplitList(m.config.CNIPath),
+	}
+}
+
+// networks returns the CNI network configurations used for the network
+func (m *Manager) networks(network *structs.NetworkResource) ([]*networkConfigList, error) {
+	if network.Mode == structs.NetworkModeBridge {
+		return []*networkConfigList{
+			loopbackConfig(),
+			bridgeConfig(m.config.BridgeNetworkName, m.config.BridgeNetworkSubnet),
+		}, nil
+	}
+
+	name, ok := network.CNINetwork()
+	if !ok {
+		return nil, fmt.Errorf("unsupported network mode %q", network.Mode)
+	}
+	list, err := loadNetworkConfig(m.config.CNIConfigDir, name)
+	if err != nil {
+		return nil, err
+	}
+	return []*networkConfigList{loopbackConfig(), list}, nil
+}
+
+// networkConfigList is a list of CNI plugins that are invoked in order to
+// configure a network
+type networkConfigList struct {
+	Name       string
+	CNIVersion string
+	Plugins    []map[string]interface{}
+
+	// IfName overrides the name of the interface the plugins configure
+	IfName string
+}
+
+// loopbackConfig returns the network configuration bringing up the loopback
+// interface of the network namespace
+func loopbackConfig() *networkConfigList {
+	return &networkConfigList{
+		Name:       "nomad-loopback",
+		CNIVersion: cniVersion,
+		Plugins: []map[string]interface{}{
+			{"type": "loopback"},
+		},
+		IfName: "lo",
+	}
+}
+
+// bridgeConfig returns the network configuration attaching the network
+// namespace to the named bridge. Addresses are allocated from the subnet and
+// the ports of the allocation are mapped from the host.
+func bridgeConfig(bridge, subnet string) *networkConfigList {
+	return &networkConfigList{
+		Name:       bridge,
+		CNIVersion: cniVersion,
+		Plugins: []map[string]interface{}{
+			{
+				"type":         "bridge",
+				"bridge":       bridge,
+				"ipMasq":       true,
+				"isGateway":    true,
+				"forceAddress": true,
+				"hairpinMode":  true,
+				"ipam": map[string]interface{}{
+					"type": "host-local",
+					"ranges": [][]map[string]interface{}{
+						{{"subnet": subnet}},
+					},
+					"routes": []map[string]interface{}{
+						{"dst": "0.0.0.0/0"},
+					},
+				},
+			},
+			{
+				"type":    "firewall",
+				"backend": "iptables",
+			},
+			{
+				"type":         "portmap",
+				"capabilities": map[string]interface{}{"portMappings": true},
+				"snat":         true,
+			},
+		},
+	}
+}
+
+// loadNetworkConfig loads the named CNI network configuration from the given
+// directory. Both configuration lists and single plugin configurations are
+// supported.
+func loadNetworkConfig(dir, name string) (*networkConfigList, error) {
+	var files []string
+	for _, ext := range []string{"*.conflist", "*.conf", "*.json"} {
+		matches, err := filepath.Glob(filepath.Join(dir, ext))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		raw, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CNI network configuration %q: %v", file, err)
+		}
+
+		var conf map[string]interface{}
+		if err := json.Unmarshal(raw, &conf); err != nil {
+			return nil, fmt.Errorf("failed to parse CNI network configuration %q: %v", file, err)
+		}
+		if n, _ := conf["name"].(string); n != name {
+			continue
+		}
+
+		version, _ := conf["cniVersion"].(string)
+		list := &networkConfigList{
+			Name:       name,
+			CNIVersion: version,
+		}
+		if !strings.HasSuffix(file, ".conflist") {
+			list.Plugins = []map[string]interface{}{conf}
+			return list, nil
+		}
+
+		plugins, _ := conf["plugins"].([]interface{})
+		for _, raw := range plugins {
+			plugin, ok := raw.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid plugin in CNI network configuration %q", file)
+			}
+			list.Plugins = append(list.Plugins, plugin)
+		}
+		if len(list.Plugins) == 0 {
+			return nil, fmt.Errorf("CNI network configuration %q has no plugins", file)
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("CNI network configuration %q not found in %q", name, dir)
+}
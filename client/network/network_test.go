@@ -0,0 +1,216 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// fakePlugin is a CNI plugin recording its invocations in the log file and
+// returning a result naming the plugin
+const fakePlugin = `#!/bin/sh
+conf=$(cat)
+echo "$CNI_COMMAND $CNI_CONTAINERID $CNI_NETNS $CNI_IFNAME $conf" >> %s
+echo '{"cniVersion": "0.4.0", "dns": {"domain": "'$(basename $0)'"}}'
+`
+
+// failingPlugin is a CNI plugin failing with an error result
+const failingPlugin = `#!/bin/sh
+echo '{"code": 7, "msg": "no bridge"}'
+exit 1
+`
+
+type invocation struct {
+	Command, ContainerID, NetNS, IfName string
+	Config                              map[string]interface{}
+}
+
+func testPlugins(t *testing.T, plugins map[string]string) (string, func() []invocation) {
+	dir, err := ioutil.TempDir("", "cni")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	logFile := filepath.Join(dir, "invocations")
+	for name, script := range plugins {
+		if strings.Contains(script, "%s") {
+			script = fmt.Sprintf(script, logFile)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	invocations := func() []invocation {
+		raw, err := ioutil.ReadFile(logFile)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		var out []invocation
+		for _, line := range strings.Split(strings.TrimSpace(string(raw)), "\n") {
+			parts := strings.SplitN(line, " ", 5)
+			inv := invocation{
+				Command:     parts[0],
+				ContainerID: parts[1],
+				NetNS:       parts[2],
+				IfName:      parts[3],
+			}
+			if err := json.Unmarshal([]byte(parts[4]), &inv.Config); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			out = append(out, inv)
+		}
+		return out
+	}
+	return dir, invocations
+}
+
+func TestInvoker_AddDel(t *testing.T) {
+	dir, invocations := testPlugins(t, map[string]string{
+		"bridge":  fakePlugin,
+		"portmap": fakePlugin,
+	})
+	defer os.RemoveAll(dir)
+
+	list := bridgeConfig("nomad", "172.26.64.0/20")
+	list.Plugins = []map[string]interface{}{list.Plugins[0], list.Plugins[2]}
+	i := &invoker{
+		containerID: "alloc",
+		netns:       "/var/run/netns/alloc",
+		ifName:      allocIfName,
+		path:        []string{"/does/not/exist", dir},
+	}
+	if err := i.Add(list, map[int]int{23456: 8080}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := i.Del(list, map[int]int{23456: 8080}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	got := invocations()
+	if len(got) != 4 {
+		t.Fatalf("bad: %#v", got)
+	}
+	for idx, plugin := range []string{"bridge", "portmap", "portmap", "bridge"} {
+		if got[idx].Config["type"] != plugin || got[idx].Config["name"] != "nomad" {
+			t.Fatalf("invocation %d: bad config: %#v", idx, got[idx].Config)
+		}
+		if got[idx].ContainerID != "alloc" || got[idx].NetNS != "/var/run/netns/alloc" || got[idx].IfName != "eth0" {
+			t.Fatalf("invocation %d: bad: %#v", idx, got[idx])
+		}
+	}
+	if got[0].Command != "ADD" || got[3].Command != "DEL" {
+		t.Fatalf("bad: %#v", got)
+	}
+
+	// The result of the bridge plugin is passed to the port mapping plugin
+	// along with the port mappings
+	prev, ok := got[1].Config["prevResult"].(map[string]interface{})
+	if !ok || !reflect.DeepEqual(prev["dns"], map[string]interface{}{"domain": "bridge"}) {
+		t.Fatalf("bad prevResult: %#v", got[1].Config)
+	}
+	runtime, _ := got[1].Config["runtimeConfig"].(map[string]interface{})
+	mappings, _ := runtime["portMappings"].([]interface{})
+	if len(mappings) != 2 {
+		t.Fatalf("bad port mappings: %#v", got[1].Config)
+	}
+	mapping := mappings[0].(map[string]interface{})
+	if mapping["hostPort"] != float64(23456) || mapping["containerPort"] != float64(8080) || mapping["protocol"] != "tcp" {
+		t.Fatalf("bad port mapping: %#v", mapping)
+	}
+	if _, ok := got[0].Config["runtimeConfig"]; ok {
+		t.Fatalf("bridge plugin should not get port mappings: %#v", got[0].Config)
+	}
+}
+
+func TestInvoker_Errors(t *testing.T) {
+	dir, _ := testPlugins(t, map[string]string{
+		"bridge": failingPlugin,
+	})
+	defer os.RemoveAll(dir)
+
+	i := &invoker{
+		containerID: "alloc",
+		netns:       "/var/run/netns/alloc",
+		ifName:      allocIfName,
+		path:        []string{dir},
+	}
+	err := i.Add(bridgeConfig("nomad", "172.26.64.0/20"), nil)
+	if err == nil || !strings.Contains(err.Error(), "no bridge") {
+		t.Fatalf("expected plugin error: %v", err)
+	}
+
+	err = i.Add(loopbackConfig(), nil)
+	if err == nil || !strings.Contains(err.Error(), `CNI plugin "loopback" not found`) {
+		t.Fatalf("expected missing plugin error: %v", err)
+	}
+}
+
+func TestLoadNetworkConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cni-config")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"10-mesh.conflist": `{"cniVersion": "0.4.0", "name": "mesh", "plugins": [{"type": "bridge"}, {"type": "portmap"}]}`,
+		"20-flat.conf":     `{"cniVersion": "0.3.1", "name": "flat", "type": "macvlan"}`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	list, err := loadNetworkConfig(dir, "mesh")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if list.Name != "mesh" || list.CNIVersion != "0.4.0" || len(list.Plugins) != 2 || list.Plugins[1]["type"] != "portmap" {
+		t.Fatalf("bad: %#v", list)
+	}
+
+	list, err = loadNetworkConfig(dir, "flat")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if list.CNIVersion != "0.3.1" || len(list.Plugins) != 1 || list.Plugins[0]["type"] != "macvlan" {
+		t.Fatalf("bad: %#v", list)
+	}
+
+	if _, err := loadNetworkConfig(dir, "missing"); err == nil {
+		t.Fatalf("expected error loading missing network")
+	}
+}
+
+func TestManager_Networks(t *testing.T) {
+	conf := config.DefaultConfig()
+	m := NewManager(conf, log.New(os.Stderr, "", log.LstdFlags))
+
+	networks, err := m.networks(&structs.NetworkResource{Mode: structs.NetworkModeBridge})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(networks) != 2 || networks[0].IfName != "lo" || networks[1].Name != conf.BridgeNetworkName {
+		t.Fatalf("bad: %#v", networks)
+	}
+	ipam := networks[1].Plugins[0]["ipam"].(map[string]interface{})
+	ranges := ipam["ranges"].([][]map[string]interface{})
+	if ranges[0][0]["subnet"] != conf.BridgeNetworkSubnet {
+		t.Fatalf("bad: %#v", ipam)
+	}
+
+	if _, err := m.networks(&structs.NetworkResource{Mode: structs.NetworkModeHost}); err == nil {
+		t.Fatalf("expected error for host networking")
+	}
+}
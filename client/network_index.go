@@ -0,0 +1,120 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// periodicNetworkIndexRebuild periodically rebuilds the network index of the
+// node so that leaked port reservations are released
+func (c *Client) periodicNetworkIndexRebuild() {
+	rebuild := time.After(networkIndexRebuildIntv)
+	for {
+		select {
+		case <-rebuild:
+			rebuild = time.After(networkIndexRebuildIntv)
+			report := c.RebuildNetworkIndex()
+			if len(report.Collisions) != 0 {
+				c.logger.Printf("[WARN] client: ports claimed more than once on the node: %v", report.Collisions)
+			}
+
+		case <-c.shutdownCh:
+			return
+		}
+	}
+}
+
+// RebuildNetworkIndex rebuilds the port accounting of the node from its live
+// allocations. The ports reserved because processes on the host bound them
+// are released once no longer bound, and the registration of the node is
+// updated so that the servers offer them again. The ports claimed more than
+// once by the reserved resources of the node and its live allocations are
+// reported.
+func (c *Client) RebuildNetworkIndex() *cstructs.NetworkIndexReport {
+	var live []*structs.Allocation
+	for _, ar := range c.getAllocRunners() {
+		if alloc := ar.Alloc(); !alloc.TerminalStatus() {
+			live = append(live, alloc)
+		}
+	}
+
+	report := &cstructs.NetworkIndexReport{
+		ReleasedPorts: make([]string, 0),
+	}
+
+	c.configLock.Lock()
+	node := c.config.Node
+	if node.Reserved != nil {
+		for _, n := range node.Reserved.Networks {
+			var ports []structs.Port
+			for _, port := range n.ReservedPorts {
+				addr := net.JoinHostPort(n.IP, strconv.Itoa(port.Value))
+				if _, ok := c.hostReservedPorts[addr]; ok && !portInUse(addr) {
+					delete(c.hostReservedPorts, addr)
+					report.ReleasedPorts = append(report.ReleasedPorts, addr)
+					continue
+				}
+				ports = append(ports, port)
+			}
+			n.ReservedPorts = ports
+		}
+	}
+	report.Collisions = portCollisions(node, live)
+	if len(report.ReleasedPorts) != 0 {
+		c.configCopy.Node = node.Copy()
+	}
+	c.configLock.Unlock()
+
+	sort.Strings(report.ReleasedPorts)
+	if len(report.ReleasedPorts) != 0 {
+		c.logger.Printf("[INFO] client: releasing reserved ports no longer in use on the host: %v", report.ReleasedPorts)
+		go c.retryRegisterNode()
+	}
+	return report
+}
+
+// portCollisions returns the ports claimed more than once by the reserved
+// resources of the node and the given allocations
+func portCollisions(node *structs.Node, allocs []*structs.Allocation) []string {
+	owners := make(map[string]string)
+	collisions := make([]string, 0)
+	claim := func(owner string, networks []*structs.NetworkResource) {
+		for _, n := range networks {
+			for _, ports := range [][]structs.Port{n.ReservedPorts, n.DynamicPorts} {
+				for _, port := range ports {
+					// Dynamic ports not yet assigned have no value
+					if port.Value == 0 {
+						continue
+					}
+					addr := net.JoinHostPort(n.IP, strconv.Itoa(port.Value))
+					if existing, ok := owners[addr]; ok && existing != owner {
+						collisions = append(collisions, fmt.Sprintf("%s (%s, %s)", addr, existing, owner))
+						continue
+					}
+					owners[addr] = owner
+				}
+			}
+		}
+	}
+
+	if node.Reserved != nil {
+		claim("reserved", node.Reserved.Networks)
+	}
+	for _, alloc := range allocs {
+		if alloc.SharedResources != nil {
+			claim(alloc.ID, alloc.SharedResources.Networks)
+		}
+		for _, task := range alloc.TaskResources {
+			claim(alloc.ID, task.Networks)
+		}
+	}
+
+	sort.Strings(collisions)
+	return collisions
+}
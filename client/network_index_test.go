@@ -0,0 +1,81 @@
+package client
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestClient_RebuildNetworkIndex(t *testing.T) {
+	c := testClient(t, func(c *config.Config) {
+		c.ReservePortConflicts = true
+	})
+	defer c.Shutdown()
+
+	// Bind a port as a host process would and reserve it
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+	c.ReserveHostPorts([]*structs.NetworkResource{
+		{
+			Device:        "lo",
+			IP:            "127.0.0.1",
+			ReservedPorts: []structs.Port{{Value: port}},
+		},
+	})
+
+	// The port stays reserved while bound
+	report := c.RebuildNetworkIndex()
+	if len(report.ReleasedPorts) != 0 {
+		t.Fatalf("bad: %#v", report)
+	}
+
+	// It is released once the host process stops
+	l.Close()
+	report = c.RebuildNetworkIndex()
+	if !reflect.DeepEqual(report.ReleasedPorts, []string{l.Addr().String()}) {
+		t.Fatalf("bad: %#v", report)
+	}
+	for _, n := range c.Node().Reserved.Networks {
+		for _, p := range n.ReservedPorts {
+			if n.IP == "127.0.0.1" && p.Value == port {
+				t.Fatalf("port %d still reserved: %#v", port, n)
+			}
+		}
+	}
+}
+
+func TestPortCollisions(t *testing.T) {
+	node := mock.Node()
+	node.Reserved.Networks = []*structs.NetworkResource{
+		{
+			IP:            "192.168.0.100",
+			ReservedPorts: []structs.Port{{Value: 22}},
+		},
+	}
+
+	alloc1 := mock.Alloc()
+	alloc2 := mock.Alloc()
+	network := alloc2.TaskResources["web"].Networks[0]
+	network.ReservedPorts = append(network.ReservedPorts, structs.Port{Label: "ssh", Value: 22})
+
+	collisions := portCollisions(node, []*structs.Allocation{alloc1, alloc2})
+	expected := []string{
+		"192.168.0.100:22 (reserved, " + alloc2.ID + ")",
+		"192.168.0.100:5000 (" + alloc1.ID + ", " + alloc2.ID + ")",
+	}
+	if !reflect.DeepEqual(collisions, expected) {
+		t.Fatalf("bad: %#v", collisions)
+	}
+
+	if collisions := portCollisions(node, []*structs.Allocation{alloc1}); len(collisions) != 0 {
+		t.Fatalf("bad: %#v", collisions)
+	}
+}
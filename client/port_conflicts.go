@@ -0,0 +1,103 @@
+package client
+
+import (
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"syscall"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// PortReserver is used to reserve the ports of the node that are bound by
+// processes on the host Nomad doesn't manage
+type PortReserver interface {
+	ReserveHostPorts(networks []*structs.NetworkResource)
+}
+
+// staticPortConflicts returns the static ports of the allocation that are
+// already bound on the host, grouped by the IP of their network. Ports are
+// checked for both TCP and UDP since tasks may use either.
+func staticPortConflicts(alloc *structs.Allocation) []*structs.NetworkResource {
+	var networks []*structs.NetworkResource
+	if alloc.SharedResources != nil {
+		networks = append(networks, alloc.SharedResources.Networks...)
+	}
+	tasks := make([]string, 0, len(alloc.TaskResources))
+	for task := range alloc.TaskResources {
+		tasks = append(tasks, task)
+	}
+	sort.Strings(tasks)
+	for _, task := range tasks {
+		networks = append(networks, alloc.TaskResources[task].Networks...)
+	}
+
+	var conflicts []*structs.NetworkResource
+	byIP := make(map[string]*structs.NetworkResource)
+	seen := make(map[string]struct{})
+	for _, n := range networks {
+		for _, port := range n.ReservedPorts {
+			addr := net.JoinHostPort(n.IP, strconv.Itoa(port.Value))
+			if _, ok := seen[addr]; ok {
+				continue
+			}
+			seen[addr] = struct{}{}
+			if !portInUse(addr) {
+				continue
+			}
+
+			conflict, ok := byIP[n.IP]
+			if !ok {
+				conflict = &structs.NetworkResource{Device: n.Device, IP: n.IP}
+				byIP[n.IP] = conflict
+				conflicts = append(conflicts, conflict)
+			}
+			conflict.ReservedPorts = append(conflict.ReservedPorts, structs.Port{Label: port.Label, Value: port.Value})
+		}
+	}
+	return conflicts
+}
+
+// portConflictAddrs returns the addresses of the conflicting ports
+func portConflictAddrs(conflicts []*structs.NetworkResource) []string {
+	var addrs []string
+	for _, n := range conflicts {
+		for _, port := range n.ReservedPorts {
+			addrs = append(addrs, net.JoinHostPort(n.IP, strconv.Itoa(port.Value)))
+		}
+	}
+	return addrs
+}
+
+// portInUse returns whether the address is already bound by another process.
+// Other bind errors, such as lacking the permission to bind privileged ports,
+// are left for the task to surface.
+func portInUse(addr string) bool {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return isAddrInUse(err)
+	}
+	l.Close()
+
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return isAddrInUse(err)
+	}
+	pc.Close()
+	return false
+}
+
+// isAddrInUse returns whether the error of a bind is due to the address
+// already being in use
+func isAddrInUse(err error) bool {
+	opErr, ok := err.(*net.OpError)
+	if !ok {
+		return false
+	}
+	sysErr, ok := opErr.Err.(*os.SyscallError)
+	if !ok {
+		return false
+	}
+	return sysErr.Err == syscall.EADDRINUSE
+}
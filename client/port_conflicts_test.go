@@ -0,0 +1,63 @@
+package client
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestStaticPortConflicts(t *testing.T) {
+	// Bind a port as a host process would
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+	used := l.Addr().(*net.TCPAddr).Port
+
+	// Find a free port
+	l2, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	free := l2.Addr().(*net.TCPAddr).Port
+	l2.Close()
+
+	alloc := mock.Alloc()
+	alloc.TaskResources["web"].Networks = []*structs.NetworkResource{
+		{
+			Device: "lo",
+			IP:     "127.0.0.1",
+			ReservedPorts: []structs.Port{
+				{Label: "used", Value: used},
+				{Label: "free", Value: free},
+			},
+		},
+	}
+
+	conflicts := staticPortConflicts(alloc)
+	expected := []*structs.NetworkResource{
+		{
+			Device:        "lo",
+			IP:            "127.0.0.1",
+			ReservedPorts: []structs.Port{{Label: "used", Value: used}},
+		},
+	}
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Fatalf("bad: %#v", conflicts)
+	}
+
+	addrs := portConflictAddrs(conflicts)
+	if len(addrs) != 1 || addrs[0] != l.Addr().String() {
+		t.Fatalf("bad: %v", addrs)
+	}
+
+	// No conflicts once the port is released
+	l.Close()
+	if conflicts := staticPortConflicts(alloc); len(conflicts) != 0 {
+		t.Fatalf("bad: %#v", conflicts)
+	}
+}
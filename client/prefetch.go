@@ -0,0 +1,150 @@
+package client
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/driver"
+	"github.com/hashicorp/nomad/client/driver/env"
+	"github.com/hashicorp/nomad/client/getter"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// artifactCacheDir returns the directory the artifacts prefetched by the
+// client are cached in
+func artifactCacheDir(config *config.Config) string {
+	if config.StateDir == "" {
+		return ""
+	}
+	return filepath.Join(config.StateDir, "artifacts")
+}
+
+// Prefetch starts fetching the docker images and artifacts of the request in
+// the background, so that tasks using them don't wait on downloading them
+// once placed on the client. Fetches of the same image or artifact that are
+// already running are not restarted. The status of the fetches is returned.
+func (c *Client) Prefetch(req *cstructs.PrefetchRequest) ([]*cstructs.PrefetchStatus, error) {
+	if len(req.Images) == 0 && len(req.Artifacts) == 0 {
+		return nil, fmt.Errorf("no images or artifacts to prefetch")
+	}
+
+	var mErr multierror.Error
+	for i, image := range req.Images {
+		if image == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("image %d is empty", i+1))
+		}
+	}
+	for i, artifact := range req.Artifacts {
+		if artifact == nil || artifact.GetterSource == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("artifact %d has no source", i+1))
+		}
+	}
+	if len(req.Images) > 0 {
+		if _, ok := c.Node().Attributes["driver.docker"]; !ok {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("docker driver is not available to pull images"))
+		}
+	}
+	if len(req.Artifacts) > 0 && artifactCacheDir(c.config) == "" {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("client has no state dir to cache artifacts in"))
+	}
+	if err := mErr.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+
+	var statuses []*cstructs.PrefetchStatus
+	for _, image := range req.Images {
+		image := image
+		statuses = append(statuses, c.startPrefetch(cstructs.PrefetchTypeImage, image, func() error {
+			return c.prefetchImage(image)
+		}))
+	}
+	for _, artifact := range req.Artifacts {
+		artifact := artifact.Copy()
+		statuses = append(statuses, c.startPrefetch(cstructs.PrefetchTypeArtifact, artifact.GetterSource, func() error {
+			return c.prefetchArtifact(artifact)
+		}))
+	}
+	return statuses, nil
+}
+
+// PrefetchStatus returns the status of the images and artifacts fetched on
+// request, sorted by type and source.
+func (c *Client) PrefetchStatus() []*cstructs.PrefetchStatus {
+	c.prefetchLock.RLock()
+	defer c.prefetchLock.RUnlock()
+
+	keys := make([]string, 0, len(c.prefetches))
+	for key := range c.prefetches {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	statuses := make([]*cstructs.PrefetchStatus, 0, len(keys))
+	for _, key := range keys {
+		statuses = append(statuses, c.prefetches[key].Copy())
+	}
+	return statuses
+}
+
+// startPrefetch runs the fetch of the image or artifact in the background
+// unless it is already running, and returns its status.
+func (c *Client) startPrefetch(typ, source string, fetch func() error) *cstructs.PrefetchStatus {
+	c.prefetchLock.Lock()
+	defer c.prefetchLock.Unlock()
+
+	key := typ + ":" + source
+	if status, ok := c.prefetches[key]; ok && status.Status == cstructs.PrefetchStatusRunning {
+		return status.Copy()
+	}
+
+	status := &cstructs.PrefetchStatus{
+		Type:      typ,
+		Source:    source,
+		Status:    cstructs.PrefetchStatusRunning,
+		StartTime: time.Now().UnixNano(),
+	}
+	c.prefetches[key] = status
+
+	go func() {
+		err := fetch()
+
+		c.prefetchLock.Lock()
+		defer c.prefetchLock.Unlock()
+		status.EndTime = time.Now().UnixNano()
+		if err != nil {
+			c.logger.Printf("[ERR] client: failed to prefetch %s %q: %v", typ, source, err)
+			status.Status = cstructs.PrefetchStatusFailed
+			status.Error = err.Error()
+			return
+		}
+		c.logger.Printf("[DEBUG] client: prefetched %s %q", typ, source)
+		status.Status = cstructs.PrefetchStatusComplete
+	}()
+	return status.Copy()
+}
+
+// prefetchImage pulls the docker image
+func (c *Client) prefetchImage(image string) error {
+	driverCtx := driver.NewDriverContext("", c.config, c.Node(), c.logger, nil)
+	d, err := driver.NewDriver("docker", driverCtx)
+	if err != nil {
+		return err
+	}
+	prefetcher, ok := d.(driver.ImagePrefetcher)
+	if !ok {
+		return fmt.Errorf("docker driver doesn't support prefetching images")
+	}
+	return prefetcher.PrefetchImage(image)
+}
+
+// prefetchArtifact downloads the artifact into the client's artifact cache.
+// The source of the artifact is interpolated with the node's attributes.
+func (c *Client) prefetchArtifact(artifact *structs.TaskArtifact) error {
+	taskEnv := env.NewTaskEnvironment(c.Node())
+	return getter.PrefetchArtifact(taskEnv, artifact, artifactCacheDir(c.config))
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/driver/env"
+	"github.com/hashicorp/nomad/client/getter"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestClient_Prefetch_Invalid(t *testing.T) {
+	c := testClient(t, nil)
+	defer c.Shutdown()
+
+	reqs := []*cstructs.PrefetchRequest{
+		{},
+		{Images: []string{""}},
+		{Artifacts: []*structs.TaskArtifact{{}}},
+	}
+	for _, req := range reqs {
+		if _, err := c.Prefetch(req); err == nil {
+			t.Fatalf("expected error for %#v", req)
+		}
+	}
+	if statuses := c.PrefetchStatus(); len(statuses) != 0 {
+		t.Fatalf("bad: %#v", statuses)
+	}
+}
+
+func TestClient_Prefetch_Artifact(t *testing.T) {
+	stateDir, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(stateDir)
+	c := testClient(t, func(c *config.Config) {
+		c.StateDir = stateDir
+	})
+	defer c.Shutdown()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello world")
+	}))
+	defer ts.Close()
+
+	artifact := &structs.TaskArtifact{GetterSource: ts.URL + "/hello.txt"}
+	req := &cstructs.PrefetchRequest{Artifacts: []*structs.TaskArtifact{artifact}}
+	statuses, err := c.Prefetch(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Status != cstructs.PrefetchStatusRunning {
+		t.Fatalf("bad: %#v", statuses)
+	}
+
+	testutil.WaitForResult(func() (bool, error) {
+		statuses := c.PrefetchStatus()
+		if len(statuses) != 1 || statuses[0].Status != cstructs.PrefetchStatusComplete {
+			return false, fmt.Errorf("bad: %#v", statuses)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// Tasks copy the artifact from the cache
+	ts.Close()
+	taskDir, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(taskDir)
+	taskEnv := env.NewTaskEnvironment(mock.Node())
+	if err := getter.GetArtifact(taskEnv, artifact, taskDir, artifactCacheDir(c.config)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(taskDir, "hello.txt")); err != nil || string(data) != "hello world" {
+		t.Fatalf("bad: %q %v", data, err)
+	}
+}
@@ -18,6 +18,7 @@ const (
 	ReasonUnrecoverableErrror = "Error was unrecoverable"
 	ReasonWithinPolicy        = "Restart within policy"
 	ReasonDelay               = "Exceeded allowed attempts, applying a delay"
+	ReasonRestartTriggered    = "Restart triggered"
 )
 
 func newRestartTracker(policy *structs.RestartPolicy, jobType string) *RestartTracker {
@@ -34,15 +35,17 @@ func newRestartTracker(policy *structs.RestartPolicy, jobType string) *RestartTr
 }
 
 type RestartTracker struct {
-	waitRes   *cstructs.WaitResult
-	startErr  error
-	count     int       // Current number of attempts.
-	onSuccess bool      // Whether to restart on successful exit code.
-	startTime time.Time // When the interval began
-	reason    string    // The reason for the last state
-	policy    *structs.RestartPolicy
-	rand      *rand.Rand
-	lock      sync.Mutex
+	waitRes          *cstructs.WaitResult
+	startErr         error
+	restartTriggered bool      // Whether the task has been signalled to restart
+	failure          bool      // Whether the triggered restart is due to a failure
+	count            int       // Current number of attempts.
+	onSuccess        bool      // Whether to restart on successful exit code.
+	startTime        time.Time // When the interval began
+	reason           string    // The reason for the last state
+	policy           *structs.RestartPolicy
+	rand             *rand.Rand
+	lock             sync.Mutex
 }
 
 // SetPolicy updates the policy used to determine restarts.
@@ -69,6 +72,22 @@ func (r *RestartTracker) SetWaitResult(res *cstructs.WaitResult) *RestartTracker
 	return r
 }
 
+// SetRestartTriggered is used to mark that the task has been signalled to be
+// restarted. Unless the restart is due to a failure, the next state is a
+// restart without delay that doesn't count against the restart policy.
+// Restarts due to a failure are subject to the restart policy as if the task
+// had failed.
+func (r *RestartTracker) SetRestartTriggered(failure bool) *RestartTracker {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if failure {
+		r.failure = true
+	} else {
+		r.restartTriggered = true
+	}
+	return r
+}
+
 // GetReason returns a human-readable description for the last state returned by
 // GetState.
 func (r *RestartTracker) GetReason() string {
@@ -91,10 +110,21 @@ func (r *RestartTracker) GetState() (string, time.Duration) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
+	// Hot path if a restart was triggered
+	if r.restartTriggered {
+		r.restartTriggered = false
+		r.reason = ReasonRestartTriggered
+		return structs.TaskRestarting, 0
+	}
+
+	// The task was killed due to a failure, so it didn't succeed
+	failure := r.failure
+	r.failure = false
+
 	// Hot path if no attempts are expected
 	if r.policy.Attempts == 0 {
 		r.reason = ReasonNoRestartsAllowed
-		if r.waitRes != nil && r.waitRes.Successful() {
+		if r.waitRes != nil && r.waitRes.Successful() && !failure {
 			return structs.TaskTerminated, 0
 		}
 
@@ -114,7 +144,7 @@ func (r *RestartTracker) GetState() (string, time.Duration) {
 	if r.startErr != nil {
 		return r.handleStartError()
 	} else if r.waitRes != nil {
-		return r.handleWaitResult()
+		return r.handleWaitResult(failure)
 	} else {
 		return "", 0
 	}
@@ -148,11 +178,12 @@ func (r *RestartTracker) handleStartError() (string, time.Duration) {
 }
 
 // handleWaitResult returns the new state and potential wait duration for
-// restarting the task after it has exited.
-func (r *RestartTracker) handleWaitResult() (string, time.Duration) {
+// restarting the task after it has exited. The task is considered failed if it
+// was killed due to a failure.
+func (r *RestartTracker) handleWaitResult(failure bool) (string, time.Duration) {
 	// If the task started successfully and restart on success isn't specified,
 	// don't restart but don't mark as failed.
-	if r.waitRes.Successful() && !r.onSuccess {
+	if r.waitRes.Successful() && !r.onSuccess && !failure {
 		r.reason = "Restart unnecessary as task terminated successfully"
 		return structs.TaskTerminated, 0
 	}
@@ -139,3 +139,43 @@ func TestClient_RestartTracker_StartError_Recoverable_Delay(t *testing.T) {
 		t.Fatalf("NextRestart() returned %v; want > %v and <= %v", when, p.Delay, p.Interval)
 	}
 }
+
+func TestClient_RestartTracker_RestartTriggered(t *testing.T) {
+	t.Parallel()
+	p := testPolicy(true, structs.RestartPolicyModeFail)
+	p.Attempts = 0
+	rt := newRestartTracker(p, structs.JobTypeService)
+	if state, when := rt.SetRestartTriggered(false).GetState(); state != structs.TaskRestarting || when != 0 {
+		t.Fatalf("expect restart immediately, got %v %v", state, when)
+	}
+	if reason := rt.GetReason(); reason != ReasonRestartTriggered {
+		t.Fatalf("bad reason: %q", reason)
+	}
+
+	// The trigger only applies to the next restart
+	if state, _ := rt.SetWaitResult(testWaitResult(1)).GetState(); state != structs.TaskNotRestarting {
+		t.Fatalf("expect no restart, got %v", state)
+	}
+}
+
+func TestClient_RestartTracker_RestartTriggered_Failure(t *testing.T) {
+	t.Parallel()
+	p := testPolicy(true, structs.RestartPolicyModeFail)
+	rt := newRestartTracker(p, structs.JobTypeBatch)
+
+	// A task killed due to a failure is restarted even if it exited
+	// successfully, and the restart counts against the policy
+	for i := 0; i < p.Attempts; i++ {
+		state, when := rt.SetRestartTriggered(true).SetWaitResult(testWaitResult(0)).GetState()
+		if state != structs.TaskRestarting {
+			t.Fatalf("NextRestart() returned %v, want %v", state, structs.TaskRestarting)
+		}
+		if !withinJitter(p.Delay, when) {
+			t.Fatalf("NextRestart() returned %v; want %v+jitter", when, p.Delay)
+		}
+	}
+
+	if state, _ := rt.SetRestartTriggered(true).SetWaitResult(testWaitResult(0)).GetState(); state != structs.TaskNotRestarting {
+		t.Fatalf("expect no restart, got %v", state)
+	}
+}
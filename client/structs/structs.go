@@ -1,5 +1,9 @@
 package structs
 
+import (
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
 // MemoryStats holds memory usage related stats
 type MemoryStats struct {
 	RSS            uint64
@@ -63,6 +67,29 @@ type TaskResourceUsage struct {
 	ResourceUsage *ResourceUsage
 	Timestamp     int64
 	Pids          map[string]*ResourceUsage
+
+	// DriverStats are the driver specific metrics of the task, keyed by
+	// metric name. They are only exposed when the client opts in.
+	DriverStats map[string]float64
+
+	// DeviceStats are the statistics of the devices reserved for the task
+	DeviceStats []*DeviceGroupStats
+}
+
+// DeviceGroupStats holds the statistics of the devices of a group
+type DeviceGroupStats struct {
+	Vendor string
+	Type   string
+	Name   string
+
+	// InstanceStats are the statistics of each device, keyed by device ID
+	InstanceStats map[string]*DeviceStats
+}
+
+// DeviceStats holds the statistics of a device, keyed by statistic name
+type DeviceStats struct {
+	Stats     map[string]float64
+	Timestamp int64
 }
 
 // AllocResourceUsage holds the aggregated task resource usage of the
@@ -78,6 +105,82 @@ type AllocResourceUsage struct {
 	Timestamp int64
 }
 
+// TaskGroupResourceUsage holds the summed resource usage of the running
+// allocations of a task group
+type TaskGroupResourceUsage struct {
+	Namespace string
+	JobID     string
+	TaskGroup string
+
+	// Allocs is the number of allocations whose resource usage is summed
+	Allocs int
+
+	// ResourceUsage is the summation of the allocation resources
+	ResourceUsage *ResourceUsage
+
+	// The max timestamp of all the allocations
+	Timestamp int64
+}
+
+// NetworkIndexReport is the outcome of rebuilding the port accounting of a
+// node from its live allocations
+type NetworkIndexReport struct {
+	// ReleasedPorts are the addresses of the ports reserved because
+	// processes on the host bound them that were released
+	ReleasedPorts []string
+
+	// Collisions are the ports claimed more than once by the reserved
+	// resources of the node and its live allocations, with their owners
+	Collisions []string
+}
+
+// PrefetchRequest is used to instruct a client to fetch docker images and
+// artifacts ahead of the tasks using them being placed on it
+type PrefetchRequest struct {
+	// Images are the docker images to pull
+	Images []string
+
+	// Artifacts are the artifacts to download into the client's cache
+	Artifacts []*structs.TaskArtifact
+}
+
+const (
+	PrefetchTypeImage    = "image"
+	PrefetchTypeArtifact = "artifact"
+)
+
+const (
+	PrefetchStatusRunning  = "running"
+	PrefetchStatusComplete = "complete"
+	PrefetchStatusFailed   = "failed"
+)
+
+// PrefetchStatus is the status of fetching an image or artifact
+type PrefetchStatus struct {
+	// Type is whether an image or an artifact is fetched
+	Type string
+
+	// Source is the name of the image or the source of the artifact
+	Source string
+
+	// Status is the status of the fetch and Error the reason it failed
+	Status string
+	Error  string
+
+	// StartTime and EndTime are the times the fetch started and ended at
+	StartTime int64
+	EndTime   int64
+}
+
+func (p *PrefetchStatus) Copy() *PrefetchStatus {
+	if p == nil {
+		return nil
+	}
+	np := new(PrefetchStatus)
+	*np = *p
+	return np
+}
+
 // joinStringSet takes two slices of strings and joins them
 func joinStringSet(s1, s2 []string) []string {
 	lookup := make(map[string]struct{}, len(s1))
@@ -1,22 +1,29 @@
 package client
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/armon/go-metrics"
+	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/client/allocdir"
 	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/client/driver"
 	"github.com/hashicorp/nomad/client/getter"
 	"github.com/hashicorp/nomad/nomad/structs"
+	sconfig "github.com/hashicorp/nomad/nomad/structs/config"
+	vaultapi "github.com/hashicorp/vault/api"
 
 	"github.com/hashicorp/nomad/client/driver/env"
 	dstructs "github.com/hashicorp/nomad/client/driver/structs"
@@ -35,6 +42,18 @@ const (
 	// killFailureLimit is how many times we will attempt to kill a task before
 	// giving up and potentially leaking resources.
 	killFailureLimit = 5
+
+	// pauseReasonScheduleStart is recorded when the task is paused because a
+	// pause window of its schedule started
+	pauseReasonScheduleStart = "Pause window of the schedule started"
+
+	// pauseReasonScheduleEnd is recorded when the task is resumed because the
+	// pause window of its schedule ended
+	pauseReasonScheduleEnd = "Pause window of the schedule ended"
+
+	// pauseReasonScheduleRemoved is recorded when the task is resumed
+	// because its schedule was removed
+	pauseReasonScheduleRemoved = "Schedule removed"
 )
 
 // TaskRunner is used to wrap a task within an allocation and provide the execution context.
@@ -53,22 +72,58 @@ type TaskRunner struct {
 	resourceUsage     *cstructs.TaskResourceUsage
 	resourceUsageLock sync.RWMutex
 
-	task     *structs.Task
-	taskEnv  *env.TaskEnvironment
-	updateCh chan *structs.Allocation
+	task      *structs.Task
+	taskEnv   *env.TaskEnvironment
+	updateCh  chan *structs.Allocation
+	restartCh chan *restartRequest
 
 	handle     driver.DriverHandle
 	handleLock sync.Mutex
 
+	// paused marks whether the task is paused and pausedBySchedule whether
+	// the pause was caused by the task's schedule
+	paused           bool
+	pausedBySchedule bool
+	pauseLock        sync.Mutex
+
 	// artifactsDownloaded tracks whether the tasks artifacts have been
 	// downloaded
 	artifactsDownloaded bool
 
+	// payloadRendered tracks whether the payload of the dispatched job has
+	// been written into the task directory
+	payloadRendered bool
+
+	// templates renders the task's templates and re-renders them when the
+	// data they use changes
+	templates *taskTemplateManager
+
+	// checkWatcher restarts the task when its checks are unhealthy
+	checkWatcher *checkWatcher
+
 	// vaultToken and vaultRenewalCh are optionally set if the task requires
 	// Vault tokens
 	vaultToken     string
 	vaultRenewalCh <-chan error
 
+	// variables are the items of the variables exposed to the task that may
+	// be interpolated in its environment variables
+	variables map[string]string
+
+	// serviceRegs is used to register the task's services that use the
+	// Nomad provider. registeredServices are the IDs of the registrations
+	// made for the running task.
+	serviceRegs        ServiceRegistrationHandler
+	registeredServices []string
+
+	// templateReader is used by the task's templates to query Nomad
+	templateReader TemplateReader
+
+	// devices is used to reserve the devices the task requests.
+	// reservedDevices are the IDs of the devices reserved for it.
+	devices         DeviceReserver
+	reservedDevices []string
+
 	destroy      bool
 	destroyCh    chan struct{}
 	destroyLock  sync.Mutex
@@ -85,11 +140,19 @@ type taskRunnerState struct {
 	Task               *structs.Task
 	HandleID           string
 	ArtifactDownloaded bool
+	Devices            []string
 }
 
 // TaskStateUpdater is used to signal that tasks state has changed.
 type TaskStateUpdater func(taskName, state string, event *structs.TaskEvent)
 
+// ServiceRegistrationHandler is used to register the services of tasks in
+// Nomad's built-in service catalog.
+type ServiceRegistrationHandler interface {
+	UpsertServiceRegistrations(services []*structs.ServiceRegistration) error
+	DeleteServiceRegistrations(ids []string) error
+}
+
 // NewTaskRunner is used to create a new task context
 func NewTaskRunner(logger *log.Logger, config *config.Config,
 	updater TaskStateUpdater, ctx *driver.ExecContext,
@@ -104,7 +167,13 @@ func NewTaskRunner(logger *log.Logger, config *config.Config,
 		logger.Printf("[ERR] client: alloc '%s' for missing task group '%s'", alloc.ID, alloc.TaskGroup)
 		return nil
 	}
-	restartTracker := newRestartTracker(tg.RestartPolicy, alloc.Job.Type)
+	// Tasks that run to completion in a lifecycle hook of their group aren't
+	// restarted once they succeed, as in batch jobs
+	jobType := alloc.Job.Type
+	if t := tg.LookupTask(task.Name); t != nil && t.Lifecycle != nil && !t.Lifecycle.Sidecar {
+		jobType = structs.JobTypeBatch
+	}
+	restartTracker := newRestartTracker(taskRestartPolicy(tg, task), jobType)
 
 	tc := &TaskRunner{
 		config:         config,
@@ -115,6 +184,7 @@ func NewTaskRunner(logger *log.Logger, config *config.Config,
 		alloc:          alloc,
 		task:           task,
 		updateCh:       make(chan *structs.Allocation, 64),
+		restartCh:      make(chan *restartRequest),
 		destroyCh:      make(chan struct{}),
 		waitCh:         make(chan struct{}),
 	}
@@ -122,6 +192,15 @@ func NewTaskRunner(logger *log.Logger, config *config.Config,
 	return tc
 }
 
+// taskRestartPolicy returns the restart policy of the task, which overrides
+// the one of its group
+func taskRestartPolicy(tg *structs.TaskGroup, task *structs.Task) *structs.RestartPolicy {
+	if task.RestartPolicy != nil {
+		return task.RestartPolicy
+	}
+	return tg.RestartPolicy
+}
+
 // SetVaultToken is used to set the Vault token and renewal channel for the task
 // runner
 func (r *TaskRunner) SetVaultToken(token string, renewalCh <-chan error) {
@@ -129,6 +208,30 @@ func (r *TaskRunner) SetVaultToken(token string, renewalCh <-chan error) {
 	r.vaultRenewalCh = renewalCh
 }
 
+// SetVariables is used to set the items of the variables the task's workload
+// identity grants access to
+func (r *TaskRunner) SetVariables(items map[string]string) {
+	r.variables = items
+}
+
+// SetServiceRegistrationHandler is used to set the handler that registers the
+// task's services in Nomad's built-in service catalog
+func (r *TaskRunner) SetServiceRegistrationHandler(handler ServiceRegistrationHandler) {
+	r.serviceRegs = handler
+}
+
+// SetTemplateReader is used to set the reader the task's templates query the
+// variables and services of Nomad with
+func (r *TaskRunner) SetTemplateReader(reader TemplateReader) {
+	r.templateReader = reader
+}
+
+// SetDeviceReserver is used to set the reserver of the devices the task
+// requests
+func (r *TaskRunner) SetDeviceReserver(reserver DeviceReserver) {
+	r.devices = reserver
+}
+
 // MarkReceived marks the task as received.
 func (r *TaskRunner) MarkReceived() {
 	r.updater(r.task.Name, structs.TaskStatePending, structs.NewTaskEvent(structs.TaskReceived))
@@ -168,6 +271,12 @@ func (r *TaskRunner) RestoreState() error {
 	}
 	r.artifactsDownloaded = snap.ArtifactDownloaded
 
+	// Keep the devices reserved for the task
+	r.reservedDevices = snap.Devices
+	if len(r.reservedDevices) != 0 && r.devices != nil {
+		r.devices.RestoreDevices(r.deviceOwner(), r.reservedDevices)
+	}
+
 	if err := r.setTaskEnv(); err != nil {
 		return fmt.Errorf("client: failed to create task environment for task %q in allocation %q: %v",
 			r.task.Name, r.alloc.ID, err)
@@ -208,6 +317,7 @@ func (r *TaskRunner) SaveState() error {
 		Task:               r.task,
 		Version:            r.config.Version,
 		ArtifactDownloaded: r.artifactsDownloaded,
+		Devices:            r.reservedDevices,
 	}
 	r.handleLock.Lock()
 	if r.handle != nil {
@@ -240,6 +350,9 @@ func (r *TaskRunner) setTaskEnv() error {
 	if err != nil {
 		return err
 	}
+	if len(r.variables) != 0 {
+		taskEnv.SetVariables(r.variables).Build()
+	}
 	r.taskEnv = taskEnv
 	return nil
 }
@@ -250,7 +363,15 @@ func (r *TaskRunner) createDriver() (driver.Driver, error) {
 		return nil, fmt.Errorf("task environment not made for task %q in allocation %q", r.task.Name, r.alloc.ID)
 	}
 
-	driverCtx := driver.NewDriverContext(r.task.Name, r.config, r.config.Node, r.logger, r.taskEnv)
+	// The driver registers the task's services with the Consul cluster the
+	// task selects
+	conf := r.config
+	if cc := r.consulConfig(); cc != r.config.ConsulConfig {
+		conf = r.config.Copy()
+		conf.ConsulConfig = cc
+	}
+
+	driverCtx := driver.NewDriverContext(r.task.Name, conf, r.config.Node, r.logger, r.taskEnv)
 	driver, err := driver.NewDriver(r.task.Driver, driverCtx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create driver '%s' for alloc %s: %v",
@@ -280,9 +401,43 @@ func (r *TaskRunner) Run() {
 	}
 
 	r.run()
+
+	// Stop re-rendering the templates
+	if r.templates != nil {
+		r.templates.Stop()
+	}
+
+	// Release the devices reserved for the task
+	if len(r.reservedDevices) != 0 && r.devices != nil {
+		r.devices.ReleaseDevices(r.deviceOwner())
+	}
 	return
 }
 
+// deviceOwner returns the owner the devices of the task are reserved for
+func (r *TaskRunner) deviceOwner() string {
+	return fmt.Sprintf("%s/%s", r.alloc.ID, r.task.Name)
+}
+
+// reserveDevices reserves the devices the task requests and exposes them to
+// the task through its environment variables
+func (r *TaskRunner) reserveDevices() error {
+	if r.task.Resources == nil || len(r.task.Resources.Devices) == 0 {
+		return nil
+	}
+	if r.devices == nil {
+		return fmt.Errorf("devices can't be reserved on this client")
+	}
+
+	res, err := r.devices.ReserveDevices(r.deviceOwner(), r.task.Resources.Devices)
+	if err != nil {
+		return err
+	}
+	r.reservedDevices = res.Devices
+	r.taskEnv.AppendEnvvars(res.Envs).Build()
+	return nil
+}
+
 // validateTask validates the fields of the task and returns an error if the
 // task is invalid.
 func (r *TaskRunner) validateTask() error {
@@ -309,6 +464,24 @@ func (r *TaskRunner) validateTask() error {
 		}
 	}
 
+	// Validate the driver supports the capabilities the task relies on. An
+	// unknown driver is reported when the task is started.
+	if d, err := driver.NewDriver(r.task.Driver, driver.NewEmptyDriverContext()); err == nil {
+		capabilities := d.Capabilities()
+		if err := capabilities.Validate(r.task.Driver, r.task); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+
+		// Tasks of groups with an isolated network join the network
+		// namespace of the allocation
+		tg := r.alloc.Job.LookupTaskGroup(r.alloc.TaskGroup)
+		if tg != nil && tg.IsolatedNetwork() != nil &&
+			!capabilities.SupportsNetworkIsolation(structs.DriverNetworkIsolationGroup) {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("driver %q does not support joining the %q network of task group %q",
+				r.task.Driver, tg.Networks[0].Mode, tg.Name))
+		}
+	}
+
 	if len(mErr.Errors) == 1 {
 		return mErr.Errors[0]
 	}
@@ -319,8 +492,21 @@ func (r *TaskRunner) run() {
 	// Predeclare things so we can jump to the RESTART
 	var handleEmpty bool
 	var stopCollection chan struct{}
+	var restarting bool
+	var scheduleCh <-chan time.Time
 
 	for {
+		// Write the payload of the dispatched job
+		if !r.payloadRendered && r.task.DispatchPayload != nil {
+			if err := r.writeDispatchPayload(); err != nil {
+				r.setState(structs.TaskStateDead, structs.NewTaskEvent(structs.TaskDriverFailure).SetDriverError(err))
+				r.logger.Printf("[ERR] client: failed to write dispatch payload of alloc %q task %q: %v", r.alloc.ID, r.task.Name, err)
+				r.restartTracker.SetStartError(dstructs.NewRecoverableError(err, false))
+				goto RESTART
+			}
+			r.payloadRendered = true
+		}
+
 		// Download the task's artifacts
 		if !r.artifactsDownloaded && len(r.task.Artifacts) > 0 {
 			r.setState(structs.TaskStatePending, structs.NewTaskEvent(structs.TaskDownloadingArtifacts))
@@ -334,10 +520,17 @@ func (r *TaskRunner) run() {
 			}
 
 			for _, artifact := range r.task.Artifacts {
-				if err := getter.GetArtifact(r.taskEnv, artifact, taskDir); err != nil {
-					r.setState(structs.TaskStatePending,
-						structs.NewTaskEvent(structs.TaskArtifactDownloadFailed).SetDownloadError(err))
-					r.restartTracker.SetStartError(dstructs.NewRecoverableError(err, true))
+				if err := r.downloadArtifact(artifact, taskDir); err != nil {
+					switch artifact.OnError {
+					case structs.ArtifactOnErrorContinue:
+						r.logger.Printf("[WARN] client: skipping artifact %q of alloc %q task %q: %v",
+							artifact.GetterSource, r.alloc.ID, r.task.Name, err)
+						continue
+					case structs.ArtifactOnErrorFail:
+						r.restartTracker.SetStartError(dstructs.NewRecoverableError(err, false))
+					default:
+						r.restartTracker.SetStartError(dstructs.NewRecoverableError(err, true))
+					}
 					goto RESTART
 				}
 			}
@@ -345,6 +538,16 @@ func (r *TaskRunner) run() {
 			r.artifactsDownloaded = true
 		}
 
+		// Render the task's templates, which may use the artifacts
+		if r.templates == nil && len(r.task.Templates) > 0 {
+			if err := r.renderTemplates(); err != nil {
+				r.setState(structs.TaskStateDead, structs.NewTaskEvent(structs.TaskDriverFailure).SetDriverError(err))
+				r.logger.Printf("[ERR] client: failed to render templates of alloc %q task %q: %v", r.alloc.ID, r.task.Name, err)
+				r.restartTracker.SetStartError(dstructs.NewRecoverableError(err, true))
+				goto RESTART
+			}
+		}
+
 		// Start the task if not yet started or it is being forced. This logic
 		// is necessary because in the case of a restore the handle already
 		// exists.
@@ -372,6 +575,15 @@ func (r *TaskRunner) run() {
 			go r.collectResourceUsageStats(stopCollection)
 		}
 
+		// Pause the task if it started within a pause window of its schedule
+		scheduleCh = r.applySchedule()
+
+		// Register the task's services in Nomad's service catalog
+		r.registerServices()
+
+		// Restart the task when its checks are unhealthy
+		r.watchChecks()
+
 		// Wait for updates
 	WAIT:
 		for {
@@ -387,9 +599,18 @@ func (r *TaskRunner) run() {
 
 				// Stop collection of the task's resource usage
 				close(stopCollection)
+				r.deregisterServices()
+				r.stopCheckWatcher()
 
-				// Log whether the task was successful or not.
+				// The task was killed to be restarted, so it isn't dead
 				r.restartTracker.SetWaitResult(waitRes)
+				if restarting {
+					restarting = false
+					r.setState(structs.TaskStatePending, r.waitErrorToEvent(waitRes))
+					break WAIT
+				}
+
+				// Log whether the task was successful or not.
 				r.setState(structs.TaskStateDead, r.waitErrorToEvent(waitRes))
 				if !waitRes.Successful() {
 					r.logger.Printf("[INFO] client: task %q for alloc %q failed: %v", r.task.Name, r.alloc.ID, waitRes)
@@ -402,6 +623,26 @@ func (r *TaskRunner) run() {
 				if err := r.handleUpdate(update); err != nil {
 					r.logger.Printf("[ERR] client: update to task %q failed: %v", r.task.Name, err)
 				}
+
+				// The schedule and services may have changed
+				scheduleCh = r.applySchedule()
+				r.registerServices()
+				r.watchChecks()
+			case <-scheduleCh:
+				scheduleCh = r.applySchedule()
+			case req := <-r.restartCh:
+				if restarting {
+					continue
+				}
+				r.logger.Printf("[INFO] client: restarting task %q for alloc %q: %v", r.task.Name, r.alloc.ID, req.event.RestartReason)
+				r.setState(structs.TaskStateRunning, req.event)
+
+				// Kill the task; the restart is handled once it exits
+				restarting = true
+				r.restartTracker.SetRestartTriggered(req.failure)
+				if destroySuccess, err := r.handleDestroy(); !destroySuccess {
+					r.logger.Printf("[ERR] client: failed to kill task %q for restart: %v", r.task.Name, err)
+				}
 			case err := <-r.vaultRenewalCh:
 				if err == nil {
 					// Only handle once.
@@ -433,6 +674,8 @@ func (r *TaskRunner) run() {
 
 				// Stop collection of the task's resource usage
 				close(stopCollection)
+				r.deregisterServices()
+				r.stopCheckWatcher()
 
 				// Store that the task has been destroyed and any associated error.
 				r.setState(structs.TaskStateDead, structs.NewTaskEvent(structs.TaskKilled).SetKillError(err))
@@ -490,11 +733,173 @@ func (r *TaskRunner) run() {
 		r.handle = nil
 		stopCollection = nil
 		r.handleLock.Unlock()
+
+		// The new task starts unpaused
+		r.pauseLock.Lock()
+		r.paused = false
+		r.pausedBySchedule = false
+		r.pauseLock.Unlock()
+		scheduleCh = nil
+	}
+}
+
+// consulConfig returns the configuration of the Consul cluster the task
+// selects. The default cluster is used if the selected one isn't configured,
+// which the implicit constraint of the cluster prevents.
+func (r *TaskRunner) consulConfig() *sconfig.ConsulConfig {
+	if conf, ok := r.config.ConsulClusterConfig(r.task.Consul.ClusterName()); ok && conf != nil {
+		return conf
+	}
+	return r.config.ConsulConfig
+}
+
+// writeDispatchPayload writes the payload of the dispatched job into the
+// task's local directory at the file configured by the task.
+func (r *TaskRunner) writeDispatchPayload() error {
+	taskDir, ok := r.ctx.AllocDir.TaskDirs[r.task.Name]
+	if !ok {
+		return fmt.Errorf("task directory couldn't be found")
+	}
+
+	path := filepath.Join(taskDir, allocdir.TaskLocal, r.task.DispatchPayload.File)
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, r.alloc.Job.Payload, 0666)
+}
+
+// renderTemplates renders the task's templates into its directory and starts
+// re-rendering them when the Consul or Vault data they use changes
+func (r *TaskRunner) renderTemplates() error {
+	taskDir, ok := r.ctx.AllocDir.TaskDirs[r.task.Name]
+	if !ok {
+		return fmt.Errorf("task directory couldn't be found")
+	}
+
+	consulConf, err := r.consulConfig().ApiConfig()
+	if err != nil {
+		return fmt.Errorf("invalid Consul configuration: %v", err)
+	}
+	consul, err := consulapi.NewClient(consulConf)
+	if err != nil {
+		return fmt.Errorf("failed to create Consul client: %v", err)
+	}
+
+	// Secrets are read with the task's Vault token
+	var vault *vaultapi.Client
+	if r.vaultToken != "" {
+		if conf, ok := r.config.VaultClusterConfig(r.task.Vault.ClusterName()); ok && conf != nil {
+			vaultConf, err := conf.ApiConfig()
+			if err != nil {
+				return fmt.Errorf("invalid Vault configuration: %v", err)
+			}
+			vault, err = vaultapi.NewClient(vaultConf)
+			if err != nil {
+				return fmt.Errorf("failed to create Vault client: %v", err)
+			}
+			vault.SetToken(r.vaultToken)
+		}
+	}
+
+	// Variables and services are queried with the task's workload identity
+	var nomad *templateNomad
+	if token, ok := r.alloc.SignedIdentities[r.task.Name]; ok && r.templateReader != nil {
+		nomad = &templateNomad{
+			reader:    r.templateReader,
+			token:     token,
+			namespace: r.alloc.Namespace,
+		}
+	}
+
+	hooks := &templateHooks{
+		restart: r.Restart,
+		signal:  r.Signal,
+	}
+	m := newTaskTemplateManager(r.task.Templates, taskDir, r.taskEnv, consul, vault, nomad, hooks, r.logger)
+	if err := m.Render(); err != nil {
+		return err
+	}
+	r.templates = m
+	go m.Run()
+	return nil
+}
+
+// watchChecks starts watching the task's checks that restart it when they are
+// unhealthy, replacing the previous watcher
+func (r *TaskRunner) watchChecks() {
+	r.stopCheckWatcher()
+
+	consulConf, err := r.consulConfig().ApiConfig()
+	if err != nil {
+		r.logger.Printf("[ERR] client: invalid Consul configuration for alloc %q task %q: %v", r.alloc.ID, r.task.Name, err)
+		return
+	}
+	consul, err := consulapi.NewClient(consulConf)
+	if err != nil {
+		r.logger.Printf("[ERR] client: failed to create Consul client for alloc %q task %q: %v", r.alloc.ID, r.task.Name, err)
+		return
+	}
+
+	unhealthy := func(reason string) error {
+		return r.restart(reason, true)
+	}
+	r.checkWatcher = newCheckWatcher(r.alloc.ID, r.task, r.taskEnv, consul, unhealthy, r.logger)
+	if r.checkWatcher != nil {
+		go r.checkWatcher.Run()
+	}
+}
+
+// stopCheckWatcher stops watching the task's checks
+func (r *TaskRunner) stopCheckWatcher() {
+	if r.checkWatcher != nil {
+		r.checkWatcher.Stop()
+		r.checkWatcher = nil
+	}
+}
+
+// downloadArtifact downloads the artifact into the task directory. Failed
+// downloads are retried in place with an exponential backoff as configured by
+// the artifact's retry policy, and each failed attempt emits a task event. The
+// error of the last attempt is returned.
+func (r *TaskRunner) downloadArtifact(artifact *structs.TaskArtifact, taskDir string) error {
+	delay := artifact.RetryDelay
+	for attempt := 1; ; attempt++ {
+		err := getter.GetArtifact(r.taskEnv, artifact, taskDir, artifactCacheDir(r.config))
+		if err == nil {
+			return nil
+		}
+
+		r.setState(structs.TaskStatePending,
+			structs.NewTaskEvent(structs.TaskArtifactDownloadFailed).
+				SetDownloadError(err).
+				SetDownloadAttempt(attempt))
+		if artifact.OnError != structs.ArtifactOnErrorRetry || attempt > artifact.RetryAttempts {
+			return err
+		}
+
+		r.logger.Printf("[DEBUG] client: retrying download of artifact %q of alloc %q task %q in %v",
+			artifact.GetterSource, r.alloc.ID, r.task.Name, delay)
+		select {
+		case <-time.After(delay):
+		case <-r.destroyCh:
+			return err
+		}
+
+		delay *= 2
+		if delay > structs.ArtifactMaxRetryDelay {
+			delay = structs.ArtifactMaxRetryDelay
+		}
 	}
 }
 
 // startTask creates the driver and starts the task.
 func (r *TaskRunner) startTask() error {
+	// Reserve the devices the task requests
+	if err := r.reserveDevices(); err != nil {
+		return fmt.Errorf("failed to reserve devices of task '%s' for alloc '%s': %v",
+			r.task.Name, r.alloc.ID, err)
+	}
+
 	// Create a driver
 	driver, err := r.createDriver()
 	if err != nil {
@@ -541,6 +946,20 @@ func (r *TaskRunner) collectResourceUsageStats(stopCollection <-chan struct{}) {
 				continue
 			}
 
+			// Don't modify the usage held by the handle
+			if ru != nil && len(ru.DriverStats) != 0 {
+				limited := *ru
+				limited.DriverStats = limitDriverStats(ru.DriverStats, r.config)
+				ru = &limited
+			}
+
+			// Add the statistics of the devices reserved for the task
+			if ru != nil && len(r.reservedDevices) != 0 && r.devices != nil {
+				withDevices := *ru
+				withDevices.DeviceStats = r.devices.DeviceStats(r.deviceOwner())
+				ru = &withDevices
+			}
+
 			r.resourceUsageLock.Lock()
 			r.resourceUsage = ru
 			r.resourceUsageLock.Unlock()
@@ -603,7 +1022,7 @@ func (r *TaskRunner) handleUpdate(update *structs.Allocation) error {
 
 	// Update the restart policy.
 	if r.restartTracker != nil {
-		r.restartTracker.SetPolicy(tg.RestartPolicy)
+		r.restartTracker.SetPolicy(taskRestartPolicy(tg, updatedTask))
 	}
 
 	// Store the updated alloc.
@@ -617,6 +1036,19 @@ func (r *TaskRunner) handleUpdate(update *structs.Allocation) error {
 // given limit. It returns whether the task was destroyed and the error
 // associated with the last kill attempt.
 func (r *TaskRunner) handleDestroy() (destroyed bool, err error) {
+	// A paused task can't handle the kill signal, so resume it first
+	r.pauseLock.Lock()
+	if r.paused {
+		if h, err := r.pausableHandle(); err != nil {
+			r.logger.Printf("[ERR] client: failed to resume task %q for alloc %q before killing it: %v", r.task.Name, r.alloc.ID, err)
+		} else if err := h.Resume(); err != nil {
+			r.logger.Printf("[ERR] client: failed to resume task %q for alloc %q before killing it: %v", r.task.Name, r.alloc.ID, err)
+		}
+		r.paused = false
+		r.pausedBySchedule = false
+	}
+	r.pauseLock.Unlock()
+
 	// Cap the number of times we attempt to kill the task.
 	for i := 0; i < killFailureLimit; i++ {
 		if err = r.handle.Kill(); err != nil {
@@ -637,6 +1069,84 @@ func (r *TaskRunner) handleDestroy() (destroyed bool, err error) {
 	return
 }
 
+// registerServices registers the task's services that use the Nomad provider
+// in Nomad's built-in service catalog and removes the registrations of
+// services the task no longer has. Failures are logged since the server
+// removes the registrations once the allocation is terminal.
+func (r *TaskRunner) registerServices() {
+	if r.serviceRegs == nil {
+		return
+	}
+
+	var services []*structs.ServiceRegistration
+	for _, service := range r.task.Services {
+		if service.Provider != structs.ServiceProviderNomad {
+			continue
+		}
+
+		address, port := r.task.FindHostAndPortFor(service.PortLabel)
+		services = append(services, &structs.ServiceRegistration{
+			ID:          structs.ServiceRegistrationID(r.alloc.ID, r.task.Name, service),
+			ServiceName: r.taskEnv.ReplaceEnv(service.Name),
+			Namespace:   r.alloc.Namespace,
+			NodeID:      r.config.Node.ID,
+			Datacenter:  r.config.Node.Datacenter,
+			JobID:       r.alloc.JobID,
+			AllocID:     r.alloc.ID,
+			Task:        r.task.Name,
+			Tags:        r.taskEnv.ParseAndReplace(service.Tags),
+			Address:     address,
+			Port:        port,
+			Health:      structs.ServiceRegistrationHealthPassing,
+		})
+	}
+
+	// Remove the registrations of services that were removed from the task
+	current := make(map[string]struct{}, len(services))
+	for _, service := range services {
+		current[service.ID] = struct{}{}
+	}
+	var removed []string
+	for _, id := range r.registeredServices {
+		if _, ok := current[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	if len(removed) != 0 {
+		if err := r.serviceRegs.DeleteServiceRegistrations(removed); err != nil {
+			r.logger.Printf("[ERR] client: failed to deregister services of task %q for alloc %q: %v",
+				r.task.Name, r.alloc.ID, err)
+		}
+	}
+
+	// Track the registrations even if registering fails, since deleting
+	// registrations that don't exist is a no-op
+	r.registeredServices = nil
+	for _, service := range services {
+		r.registeredServices = append(r.registeredServices, service.ID)
+	}
+	if len(services) == 0 {
+		return
+	}
+	if err := r.serviceRegs.UpsertServiceRegistrations(services); err != nil {
+		r.logger.Printf("[ERR] client: failed to register services of task %q for alloc %q: %v",
+			r.task.Name, r.alloc.ID, err)
+	}
+}
+
+// deregisterServices removes the registrations of the task's services from
+// Nomad's built-in service catalog
+func (r *TaskRunner) deregisterServices() {
+	if r.serviceRegs == nil || len(r.registeredServices) == 0 {
+		return
+	}
+	if err := r.serviceRegs.DeleteServiceRegistrations(r.registeredServices); err != nil {
+		r.logger.Printf("[ERR] client: failed to deregister services of task %q for alloc %q: %v",
+			r.task.Name, r.alloc.ID, err)
+	}
+	r.registeredServices = nil
+}
+
 // Helper function for converting a WaitResult into a TaskTerminated event.
 func (r *TaskRunner) waitErrorToEvent(res *dstructs.WaitResult) *structs.TaskEvent {
 	return structs.NewTaskEvent(structs.TaskTerminated).
@@ -655,6 +1165,195 @@ func (r *TaskRunner) Update(update *structs.Allocation) {
 	}
 }
 
+// Pause is used to pause the running task if its driver supports it. The
+// reason is recorded in the task's events.
+func (r *TaskRunner) Pause(reason string) error {
+	return r.pause(reason, false)
+}
+
+// Resume is used to resume the paused task. The reason is recorded in the
+// task's events.
+func (r *TaskRunner) Resume(reason string) error {
+	r.pauseLock.Lock()
+	defer r.pauseLock.Unlock()
+	if !r.paused {
+		return fmt.Errorf("task %q is not paused", r.task.Name)
+	}
+
+	h, err := r.pausableHandle()
+	if err != nil {
+		return err
+	}
+	if err := h.Resume(); err != nil {
+		return fmt.Errorf("failed to resume task %q: %v", r.task.Name, err)
+	}
+	r.paused = false
+	r.pausedBySchedule = false
+	r.setState(structs.TaskStateRunning, structs.NewTaskEvent(structs.TaskResumed).SetPauseReason(reason))
+	return nil
+}
+
+// pause pauses the running task and marks whether this was caused by the
+// task's schedule.
+func (r *TaskRunner) pause(reason string, bySchedule bool) error {
+	r.pauseLock.Lock()
+	defer r.pauseLock.Unlock()
+	if r.paused {
+		return fmt.Errorf("task %q is already paused", r.task.Name)
+	}
+
+	h, err := r.pausableHandle()
+	if err != nil {
+		return err
+	}
+	if err := h.Pause(); err != nil {
+		return fmt.Errorf("failed to pause task %q: %v", r.task.Name, err)
+	}
+	r.paused = true
+	r.pausedBySchedule = bySchedule
+	r.setState(structs.TaskStateRunning, structs.NewTaskEvent(structs.TaskPaused).SetPauseReason(reason))
+	return nil
+}
+
+// pausableHandle returns the handle of the running task if its driver
+// supports pausing tasks.
+func (r *TaskRunner) pausableHandle() (driver.PausableHandle, error) {
+	r.runningLock.Lock()
+	running := r.running
+	r.runningLock.Unlock()
+
+	r.handleLock.Lock()
+	handle := r.handle
+	r.handleLock.Unlock()
+	if !running || handle == nil {
+		return nil, fmt.Errorf("task %q is not running", r.task.Name)
+	}
+
+	h, ok := handle.(driver.PausableHandle)
+	if !ok {
+		return nil, fmt.Errorf("driver %q does not support pausing tasks", r.task.Driver)
+	}
+	return h, nil
+}
+
+// Exec runs a command inside the isolation context of the running task if
+// its driver supports it and returns the exit code of the command
+func (r *TaskRunner) Exec(ctx context.Context, opts *driver.ExecOptions) (int, error) {
+	r.runningLock.Lock()
+	running := r.running
+	r.runningLock.Unlock()
+
+	r.handleLock.Lock()
+	handle := r.handle
+	r.handleLock.Unlock()
+	if !running || handle == nil {
+		return 0, fmt.Errorf("task %q is not running", r.task.Name)
+	}
+
+	h, ok := handle.(driver.ExecHandle)
+	if !ok {
+		return 0, fmt.Errorf("driver %q does not support executing commands", r.task.Driver)
+	}
+	return h.Exec(ctx, opts)
+}
+
+// Signal sends the signal to the running task if its driver supports sending
+// signals. The signal and reason are recorded in the task's events.
+func (r *TaskRunner) Signal(sig, reason string) error {
+	r.handleLock.Lock()
+	handle := r.handle
+	r.handleLock.Unlock()
+	if handle == nil {
+		return fmt.Errorf("task %q is not running", r.task.Name)
+	}
+
+	h, ok := handle.(driver.SignalableHandle)
+	if !ok {
+		return fmt.Errorf("driver %q does not support sending signals", r.task.Driver)
+	}
+	r.setState(structs.TaskStateRunning, structs.NewTaskEvent(structs.TaskSignaling).
+		SetTaskSignal(sig).SetTaskSignalReason(reason))
+	if err := h.Signal(sig); err != nil {
+		return fmt.Errorf("failed to signal task %q: %v", r.task.Name, err)
+	}
+	return nil
+}
+
+// applySchedule pauses or resumes the task according to its schedule and
+// returns a channel that fires at the next transition of the schedule. Only
+// pauses caused by the schedule are resumed when a pause window ends.
+func (r *TaskRunner) applySchedule() <-chan time.Time {
+	r.pauseLock.Lock()
+	paused, bySchedule := r.paused, r.pausedBySchedule
+	r.pauseLock.Unlock()
+
+	schedule := r.task.Schedule
+	if schedule == nil {
+		if paused && bySchedule {
+			if err := r.Resume(pauseReasonScheduleRemoved); err != nil {
+				r.logger.Printf("[ERR] client: failed to resume task %q for alloc %q: %v", r.task.Name, r.alloc.ID, err)
+			}
+		}
+		return nil
+	}
+
+	now := time.Now()
+	inWindow, next := schedule.Window(now)
+	switch {
+	case inWindow && !paused:
+		r.logger.Printf("[INFO] client: pausing task %q for alloc %q until %v", r.task.Name, r.alloc.ID, next)
+		if err := r.pause(pauseReasonScheduleStart, true); err != nil {
+			r.logger.Printf("[ERR] client: failed to pause task %q for alloc %q: %v", r.task.Name, r.alloc.ID, err)
+		}
+	case !inWindow && paused && bySchedule:
+		r.logger.Printf("[INFO] client: resuming task %q for alloc %q", r.task.Name, r.alloc.ID)
+		if err := r.Resume(pauseReasonScheduleEnd); err != nil {
+			r.logger.Printf("[ERR] client: failed to resume task %q for alloc %q: %v", r.task.Name, r.alloc.ID, err)
+		}
+	}
+
+	if next.IsZero() {
+		return nil
+	}
+	return time.After(next.Sub(now))
+}
+
+// restartRequest asks the task runner to restart the running task
+type restartRequest struct {
+	event *structs.TaskEvent
+
+	// failure marks restarts that count against the restart policy
+	failure bool
+}
+
+// Restart is used to restart the running task without changing it. The reason
+// is recorded in the task's events.
+func (r *TaskRunner) Restart(reason string) error {
+	return r.restart(reason, false)
+}
+
+// restart restarts the running task, and the restart counts against the
+// restart policy if it is due to a failure of the task
+func (r *TaskRunner) restart(reason string, failure bool) error {
+	r.runningLock.Lock()
+	running := r.running
+	r.runningLock.Unlock()
+	if !running {
+		return fmt.Errorf("task %q is not running", r.task.Name)
+	}
+
+	req := &restartRequest{
+		event:   structs.NewTaskEvent(structs.TaskRestartSignal).SetRestartReason(reason),
+		failure: failure,
+	}
+	select {
+	case r.restartCh <- req:
+		return nil
+	case <-r.waitCh:
+		return fmt.Errorf("task %q is not running", r.task.Name)
+	}
+}
+
 // Destroy is used to indicate that the task context should be destroyed. The
 // event parameter provides a context for the destroy.
 func (r *TaskRunner) Destroy(event *structs.TaskEvent) {
@@ -689,4 +1388,47 @@ func (r *TaskRunner) emitStats(ru *cstructs.TaskResourceUsage) {
 		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "cpu", "throttled_periods"}, float32(ru.ResourceUsage.CpuStats.ThrottledPeriods))
 		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "cpu", "total_ticks"}, float32(ru.ResourceUsage.CpuStats.TotalTicks))
 	}
+
+	if r.config.PublishAllocationMetrics {
+		for name, value := range ru.DriverStats {
+			metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "driver", name}, float32(value))
+		}
+
+		for _, group := range ru.DeviceStats {
+			for id, instance := range group.InstanceStats {
+				if instance == nil {
+					continue
+				}
+				for name, value := range instance.Stats {
+					metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name,
+						"device", group.Vendor, group.Type, group.Name, id, name}, float32(value))
+				}
+			}
+		}
+	}
+}
+
+// limitDriverStats returns the driver specific metrics to expose for the
+// task. They are dropped unless the client opts in to them, and are capped
+// to the configured limit, keeping the first metrics by name so the same
+// ones are kept across samples.
+func limitDriverStats(stats map[string]float64, conf *config.Config) map[string]float64 {
+	if !conf.PublishDriverMetrics || len(stats) == 0 {
+		return nil
+	}
+	if conf.DriverMetricsLimit <= 0 || len(stats) <= conf.DriverMetricsLimit {
+		return stats
+	}
+
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	limited := make(map[string]float64, conf.DriverMetricsLimit)
+	for _, name := range names[:conf.DriverMetricsLimit] {
+		limited[name] = stats[name]
+	}
+	return limited
 }
@@ -2,11 +2,13 @@ package client
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -53,7 +55,7 @@ func testTaskRunnerFromAlloc(restarts bool, alloc *structs.Allocation) (*MockTas
 	task := alloc.Job.TaskGroups[0].Tasks[0]
 	// Initialize the port listing. This should be done by the offer process but
 	// we have a mock so that doesn't happen.
-	task.Resources.Networks[0].ReservedPorts = []structs.Port{{"", 80}}
+	task.Resources.Networks[0].ReservedPorts = []structs.Port{{"", 80, 0, ""}}
 
 	allocDir := allocdir.NewAllocDir(filepath.Join(conf.AllocDir, alloc.ID), task.Resources.DiskMB)
 	allocDir.Build([]*structs.Task{task})
@@ -163,6 +165,265 @@ func TestTaskRunner_Destroy(t *testing.T) {
 	}
 }
 
+func TestTaskRunner_Restart(t *testing.T) {
+	ctestutil.ExecCompatible(t)
+	upd, tr := testTaskRunner(false)
+	tr.MarkReceived()
+	defer tr.ctx.AllocDir.Destroy()
+
+	// Restarting a task that isn't running fails
+	if err := tr.Restart("test"); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// Change command to ensure we run for a bit
+	tr.task.Config["command"] = "/bin/sleep"
+	tr.task.Config["args"] = []string{"1000"}
+	go tr.Run()
+	defer tr.Destroy(structs.NewTaskEvent(structs.TaskKilled))
+
+	testutil.WaitForResult(func() (bool, error) {
+		if l := len(upd.events); l != 2 {
+			return false, fmt.Errorf("Expect two events; got %v", l)
+		}
+		return upd.events[1].Type == structs.TaskStarted, fmt.Errorf("task not started")
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// Restart the task even though restarts are disabled by the policy
+	if err := tr.Restart("test"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	testutil.WaitForResult(func() (bool, error) {
+		l := len(upd.events)
+		if l != 6 {
+			return false, fmt.Errorf("Expect six events; got %v", l)
+		}
+		return upd.events[l-1].Type == structs.TaskStarted, fmt.Errorf("task not restarted")
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	if upd.events[2].Type != structs.TaskRestartSignal || upd.events[2].RestartReason != "test" {
+		t.Fatalf("Third Event was %#v; want %v", upd.events[2], structs.TaskRestartSignal)
+	}
+	if upd.events[3].Type != structs.TaskTerminated {
+		t.Fatalf("Fourth Event was %v; want %v", upd.events[3].Type, structs.TaskTerminated)
+	}
+	if upd.events[4].Type != structs.TaskRestarting || upd.events[4].StartDelay != 0 {
+		t.Fatalf("Fifth Event was %#v; want %v", upd.events[4], structs.TaskRestarting)
+	}
+	if upd.state != structs.TaskStateRunning {
+		t.Fatalf("TaskState %v; want %v", upd.state, structs.TaskStateRunning)
+	}
+}
+
+// fakeHandle is a driver handle of a task that is never started
+type fakeHandle struct {
+	driver.DriverHandle
+}
+
+func (h *fakeHandle) ID() string {
+	return "fake"
+}
+
+// pausableHandle is a driver handle that records whether it is paused
+type pausableHandle struct {
+	fakeHandle
+	paused bool
+}
+
+func (h *pausableHandle) Pause() error {
+	h.paused = true
+	return nil
+}
+
+func (h *pausableHandle) Resume() error {
+	h.paused = false
+	return nil
+}
+
+func TestTaskRunner_PauseResume(t *testing.T) {
+	upd, tr := testTaskRunner(false)
+	defer tr.ctx.AllocDir.Destroy()
+
+	// Pausing a task that isn't running fails
+	if err := tr.Pause("test"); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Fake a running task
+	handle := &pausableHandle{}
+	tr.handle = handle
+	tr.running = true
+
+	if err := tr.Pause("test"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !handle.paused || upd.state != structs.TaskStateRunning {
+		t.Fatalf("task not paused")
+	}
+	if e := upd.events[len(upd.events)-1]; e.Type != structs.TaskPaused || e.PauseReason != "test" {
+		t.Fatalf("bad event: %#v", e)
+	}
+	if err := tr.Pause("test"); err == nil {
+		t.Fatalf("expected error pausing a paused task")
+	}
+
+	if err := tr.Resume("test"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if handle.paused {
+		t.Fatalf("task not resumed")
+	}
+	if e := upd.events[len(upd.events)-1]; e.Type != structs.TaskResumed {
+		t.Fatalf("bad event: %#v", e)
+	}
+	if err := tr.Resume("test"); err == nil {
+		t.Fatalf("expected error resuming a running task")
+	}
+}
+
+func TestTaskRunner_PauseResume_Unsupported(t *testing.T) {
+	_, tr := testTaskRunner(false)
+	defer tr.ctx.AllocDir.Destroy()
+
+	// Fake a running task whose driver can't pause it
+	tr.handle = &fakeHandle{}
+	tr.running = true
+
+	if err := tr.Pause("test"); err == nil || !strings.Contains(err.Error(), "does not support") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestTaskRunner_Schedule(t *testing.T) {
+	upd, tr := testTaskRunner(false)
+	defer tr.ctx.AllocDir.Destroy()
+
+	// Fake a running task
+	handle := &pausableHandle{}
+	tr.handle = handle
+	tr.running = true
+
+	// A schedule that is always in a pause window pauses the task
+	tr.task.Schedule = &structs.TaskSchedule{
+		Pause:    "* * * * *",
+		Duration: time.Hour,
+	}
+	if ch := tr.applySchedule(); ch == nil {
+		t.Fatalf("expected a next transition")
+	}
+	if !handle.paused || !tr.pausedBySchedule {
+		t.Fatalf("task not paused")
+	}
+	if e := upd.events[len(upd.events)-1]; e.PauseReason != pauseReasonScheduleStart {
+		t.Fatalf("bad event: %#v", e)
+	}
+
+	// Removing the schedule resumes the task
+	tr.task.Schedule = nil
+	if ch := tr.applySchedule(); ch != nil {
+		t.Fatalf("expected no next transition")
+	}
+	if handle.paused {
+		t.Fatalf("task not resumed")
+	}
+
+	// Pauses by the user are not resumed by the schedule
+	if err := tr.Pause("test"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	tr.task.Schedule = &structs.TaskSchedule{
+		Pause:    "0 0 1 1 *",
+		Duration: time.Second,
+	}
+	tr.applySchedule()
+	if !handle.paused {
+		t.Fatalf("user pause was resumed")
+	}
+}
+
+// mockServiceRegs records the service registrations made by a task runner
+type mockServiceRegs struct {
+	services map[string]*structs.ServiceRegistration
+}
+
+func (m *mockServiceRegs) UpsertServiceRegistrations(services []*structs.ServiceRegistration) error {
+	for _, service := range services {
+		m.services[service.ID] = service
+	}
+	return nil
+}
+
+func (m *mockServiceRegs) DeleteServiceRegistrations(ids []string) error {
+	for _, id := range ids {
+		delete(m.services, id)
+	}
+	return nil
+}
+
+func TestTaskRunner_RegisterServices(t *testing.T) {
+	_, tr := testTaskRunner(false)
+	defer tr.ctx.AllocDir.Destroy()
+
+	tr.config.Node = mock.Node()
+	regs := &mockServiceRegs{services: make(map[string]*structs.ServiceRegistration)}
+	tr.SetServiceRegistrationHandler(regs)
+
+	// Only the services using the Nomad provider are registered
+	tr.task.Services = []*structs.Service{
+		{
+			Name:      "web-frontend",
+			PortLabel: "main",
+			Tags:      []string{"datacenter:${node.datacenter}"},
+			Provider:  structs.ServiceProviderNomad,
+		},
+		{
+			Name:      "web-admin",
+			PortLabel: "admin",
+		},
+	}
+	if err := tr.setTaskEnv(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	tr.registerServices()
+
+	id := structs.ServiceRegistrationID(tr.alloc.ID, tr.task.Name, tr.task.Services[0])
+	service, ok := regs.services[id]
+	if !ok || len(regs.services) != 1 {
+		t.Fatalf("bad: %#v", regs.services)
+	}
+	if service.ServiceName != "web-frontend" || service.AllocID != tr.alloc.ID || service.NodeID != tr.config.Node.ID {
+		t.Fatalf("bad: %#v", service)
+	}
+	if service.Address != "192.168.0.100" || service.Port != 5000 {
+		t.Fatalf("bad address: %s:%d", service.Address, service.Port)
+	}
+	if len(service.Tags) != 1 || service.Tags[0] != "datacenter:dc1" {
+		t.Fatalf("bad tags: %v", service.Tags)
+	}
+	if service.Health != structs.ServiceRegistrationHealthPassing {
+		t.Fatalf("bad health: %q", service.Health)
+	}
+
+	// Renaming the service replaces its registration
+	tr.task.Services[0].Name = "web-api"
+	tr.registerServices()
+	newID := structs.ServiceRegistrationID(tr.alloc.ID, tr.task.Name, tr.task.Services[0])
+	if _, ok := regs.services[newID]; !ok || len(regs.services) != 1 {
+		t.Fatalf("bad: %#v", regs.services)
+	}
+
+	// Stopping the task deregisters its services
+	tr.deregisterServices()
+	if len(regs.services) != 0 {
+		t.Fatalf("bad: %#v", regs.services)
+	}
+}
+
 func TestTaskRunner_Update(t *testing.T) {
 	ctestutil.ExecCompatible(t)
 	_, tr := testTaskRunner(false)
@@ -315,6 +576,49 @@ func TestTaskRunner_Download_List(t *testing.T) {
 	}
 }
 
+func TestTaskRunner_DispatchPayload(t *testing.T) {
+	ctestutil.ExecCompatible(t)
+
+	// Create an allocation of a dispatched job with a task that reads the
+	// payload
+	alloc := mock.Alloc()
+	alloc.Job.Type = structs.JobTypeBatch
+	alloc.Job.ParameterizedJob = &structs.ParameterizedJobConfig{}
+	alloc.Job.Dispatched = true
+	alloc.Job.Payload = []byte("hello world")
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.DispatchPayload = &structs.DispatchPayloadConfig{
+		File: "input/payload.txt",
+	}
+
+	upd, tr := testTaskRunnerFromAlloc(false, alloc)
+	tr.MarkReceived()
+	go tr.Run()
+	defer tr.Destroy(structs.NewTaskEvent(structs.TaskKilled))
+	defer tr.ctx.AllocDir.Destroy()
+
+	select {
+	case <-tr.WaitCh():
+	case <-time.After(time.Duration(testutil.TestMultiplier()*15) * time.Second):
+		t.Fatalf("timeout")
+	}
+
+	if upd.state != structs.TaskStateDead {
+		t.Fatalf("TaskState %v; want %v", upd.state, structs.TaskStateDead)
+	}
+
+	// Check the payload was written into the task's local directory
+	taskDir := tr.ctx.AllocDir.TaskDirs[task.Name]
+	path := filepath.Join(taskDir, allocdir.TaskLocal, "input", "payload.txt")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("payload not written: %v", err)
+	}
+	if string(data) != "hello world" {
+		t.Fatalf("bad payload: %q", data)
+	}
+}
+
 func TestTaskRunner_Download_Retries(t *testing.T) {
 	ctestutil.ExecCompatible(t)
 
@@ -383,6 +687,92 @@ func TestTaskRunner_Download_Retries(t *testing.T) {
 	}
 }
 
+func TestTaskRunner_Download_OnError(t *testing.T) {
+	cases := []struct {
+		OnError       string
+		RetryAttempts int
+		Failures      int
+		LastEvent     string
+	}{
+		{
+			OnError:       structs.ArtifactOnErrorRetry,
+			RetryAttempts: 2,
+			Failures:      3,
+			LastEvent:     structs.TaskRestarting,
+		},
+		{
+			OnError:   structs.ArtifactOnErrorFail,
+			Failures:  1,
+			LastEvent: structs.TaskNotRestarting,
+		},
+		{
+			OnError:   structs.ArtifactOnErrorContinue,
+			Failures:  1,
+			LastEvent: structs.TaskStarted,
+		},
+	}
+
+	for i, c := range cases {
+		// Create an allocation that has a task with a bad artifact.
+		alloc := mock.Alloc()
+		task := alloc.Job.TaskGroups[0].Tasks[0]
+		task.Driver = "mock_driver"
+		task.Config = map[string]interface{}{
+			"exit_code": "0",
+			"run_for":   "10s",
+		}
+		task.Artifacts = []*structs.TaskArtifact{
+			{
+				GetterSource:  "http://127.1.1.111:12315/foo/bar/baz",
+				OnError:       c.OnError,
+				RetryAttempts: c.RetryAttempts,
+				RetryDelay:    10 * time.Millisecond,
+			},
+		}
+
+		// Allow a restart, so that retried downloads restart the task once
+		// the retries are exhausted while failed downloads don't
+		alloc.Job.TaskGroups[0].RestartPolicy = &structs.RestartPolicy{
+			Attempts: 1,
+			Interval: 10 * time.Minute,
+			Delay:    10 * time.Minute,
+			Mode:     structs.RestartPolicyModeFail,
+		}
+
+		upd, tr := testTaskRunnerFromAlloc(true, alloc)
+		tr.MarkReceived()
+		go tr.Run()
+
+		testutil.WaitForResult(func() (bool, error) {
+			if l := len(upd.events); l != 0 && upd.events[l-1].Type == c.LastEvent {
+				return true, nil
+			}
+			return false, fmt.Errorf("case %d: last event isn't %v: %#v", i, c.LastEvent, upd.events)
+		}, func(err error) {
+			t.Fatalf("err: %v", err)
+		})
+
+		var failures []*structs.TaskEvent
+		for _, e := range upd.events {
+			if e.Type == structs.TaskArtifactDownloadFailed {
+				failures = append(failures, e)
+			}
+		}
+		if len(failures) != c.Failures {
+			t.Fatalf("case %d: got %d failed downloads; want %d: %#v", i, len(failures), c.Failures, upd.events)
+		}
+		for j, e := range failures {
+			if e.DownloadAttempt != j+1 {
+				t.Fatalf("case %d: bad attempt: %#v", i, e)
+			}
+		}
+
+		tr.Destroy(structs.NewTaskEvent(structs.TaskKilled))
+		<-tr.WaitCh()
+		tr.ctx.AllocDir.Destroy()
+	}
+}
+
 func TestTaskRunner_Validate_UserEnforcement(t *testing.T) {
 	_, tr := testTaskRunner(false)
 	defer tr.Destroy(structs.NewTaskEvent(structs.TaskKilled))
@@ -410,6 +800,48 @@ func TestTaskRunner_Validate_UserEnforcement(t *testing.T) {
 	}
 }
 
+func TestTaskRunner_Validate_DriverCapabilities(t *testing.T) {
+	_, tr := testTaskRunner(false)
+	defer tr.Destroy(structs.NewTaskEvent(structs.TaskKilled))
+	defer tr.ctx.AllocDir.Destroy()
+
+	// The rkt driver can't pause tasks
+	tr.task.Driver = "rkt"
+	tr.task.Schedule = &structs.TaskSchedule{Pause: "0 2 * * *", Duration: time.Hour}
+	err := tr.validateTask()
+	if err == nil || !strings.Contains(err.Error(), structs.DriverCapabilityPause) {
+		t.Fatalf("expected missing capability error: %v", err)
+	}
+
+	// The exec driver can
+	tr.task.Driver = "exec"
+	if err := tr.validateTask(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestTaskRunner_Validate_GroupNetwork(t *testing.T) {
+	_, tr := testTaskRunner(false)
+	defer tr.Destroy(structs.NewTaskEvent(structs.TaskKilled))
+	defer tr.ctx.AllocDir.Destroy()
+
+	tg := tr.alloc.Job.LookupTaskGroup(tr.alloc.TaskGroup)
+	tg.Networks = []*structs.NetworkResource{{Mode: structs.NetworkModeBridge}}
+
+	// The docker driver can't join the network namespace of the allocation
+	tr.task.Driver = "docker"
+	err := tr.validateTask()
+	if err == nil || !strings.Contains(err.Error(), `joining the "bridge" network`) {
+		t.Fatalf("expected network isolation error: %v", err)
+	}
+
+	// The exec driver can
+	tr.task.Driver = "exec"
+	if err := tr.validateTask(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestTaskRunner_VaultTokenRenewal(t *testing.T) {
 	alloc := mock.Alloc()
 	task := alloc.Job.TaskGroups[0].Tasks[0]
@@ -471,3 +903,20 @@ func TestTaskRunner_VaultTokenRenewal(t *testing.T) {
 		t.Fatalf("Fifth Event was %v; want %v", upd.events[4].Type, structs.TaskKilled)
 	}
 }
+
+func TestTaskRunner_LimitDriverStats(t *testing.T) {
+	stats := map[string]float64{"c": 3, "a": 1, "b": 2}
+	conf := config.DefaultConfig()
+
+	// Driver metrics are opt-in
+	if limited := limitDriverStats(stats, conf); limited != nil {
+		t.Fatalf("bad: %v", limited)
+	}
+
+	conf.PublishDriverMetrics = true
+	conf.DriverMetricsLimit = 2
+	limited := limitDriverStats(stats, conf)
+	if len(limited) != 2 || limited["a"] != 1 || limited["b"] != 2 {
+		t.Fatalf("bad: %v", limited)
+	}
+}
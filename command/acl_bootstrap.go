@@ -0,0 +1,61 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ACLBootstrapCommand struct {
+	Meta
+}
+
+func (c *ACLBootstrapCommand) Help() string {
+	helpText := `
+Usage: nomad acl-bootstrap [options]
+
+  Bootstrap the ACL system and create the initial management token. The
+  secret ID of the returned token should be stored securely, it is required
+  to create further policies and tokens. Bootstrapping can only be done once
+  unless it is reset through the reset index file on the leader.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLBootstrapCommand) Synopsis() string {
+	return "Bootstrap the ACL system for initial token"
+}
+
+func (c *ACLBootstrapCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("acl-bootstrap", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Get the bootstrap token
+	token, _, err := client.ACLTokens().Bootstrap(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error bootstrapping: %s", err))
+		return 1
+	}
+
+	// Format the output
+	c.Ui.Output(formatKVACLToken(token))
+	return 0
+}
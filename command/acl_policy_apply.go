@@ -0,0 +1,92 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type ACLPolicyApplyCommand struct {
+	Meta
+}
+
+func (c *ACLPolicyApplyCommand) Help() string {
+	helpText := `
+Usage: nomad acl-policy-apply [options] <name> <path>
+
+  Create or update an ACL policy from the rules in the given file. If the path
+  is "-", the rules are read from stdin. Applying policies requires a
+  management token.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Apply Options:
+
+  -description=""
+    Sets the human readable description for the ACL policy.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLPolicyApplyCommand) Synopsis() string {
+	return "Create or update an ACL policy"
+}
+
+func (c *ACLPolicyApplyCommand) Run(args []string) int {
+	var description string
+	flags := c.Meta.FlagSet("acl-policy-apply", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&description, "description", "", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly two arguments
+	args = flags.Args()
+	if len(args) != 2 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	policyName, path := args[0], args[1]
+
+	// Read the rules
+	var rawPolicy []byte
+	var err error
+	if path == "-" {
+		rawPolicy, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		rawPolicy, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading policy %q: %v", path, err))
+		return 1
+	}
+
+	// Construct the policy
+	ap := &api.ACLPolicy{
+		Name:        policyName,
+		Description: description,
+		Rules:       string(rawPolicy),
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Upsert the policy
+	if _, err := client.ACLPolicies().Upsert(ap, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error writing ACL policy: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully wrote %q ACL policy!", policyName))
+	return 0
+}
@@ -0,0 +1,11 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLPolicyDeleteCommand_Implements(t *testing.T) {
+	var _ cli.Command = &ACLPolicyDeleteCommand{}
+}
@@ -0,0 +1,66 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ACLPolicyListCommand struct {
+	Meta
+}
+
+func (c *ACLPolicyListCommand) Help() string {
+	helpText := `
+Usage: nomad acl-policy-list [options]
+
+  List the ACL policies. Management tokens see all policies, other tokens see
+  only the policies attached to them.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLPolicyListCommand) Synopsis() string {
+	return "List ACL policies"
+}
+
+func (c *ACLPolicyListCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("acl-policy-list", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	policies, _, err := client.ACLPolicies().List(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error listing ACL policies: %s", err))
+		return 1
+	}
+	if len(policies) == 0 {
+		c.Ui.Output("No policies found")
+		return 0
+	}
+
+	out := make([]string, len(policies)+1)
+	out[0] = "Name|Description"
+	for i, p := range policies {
+		out[i+1] = fmt.Sprintf("%s|%s", p.Name, p.Description)
+	}
+	c.Ui.Output(formatList(out))
+	return 0
+}
@@ -0,0 +1,84 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type ACLTokenInfoCommand struct {
+	Meta
+}
+
+func (c *ACLTokenInfoCommand) Help() string {
+	helpText := `
+Usage: nomad acl-token-info [options] <accessor_id>
+
+  Display information about an ACL token. Querying tokens requires a
+  management token.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLTokenInfoCommand) Synopsis() string {
+	return "Fetch information on an existing ACL token"
+}
+
+func (c *ACLTokenInfoCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("acl-token-info", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one argument
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	accessorID := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Query the specified token
+	token, _, err := client.ACLTokens().Info(accessorID, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error fetching token: %s", err))
+		return 1
+	}
+
+	// Format the output
+	c.Ui.Output(formatKVACLToken(token))
+	return 0
+}
+
+// formatKVACLToken returns a K/V formatted ACL token
+func formatKVACLToken(token *api.ACLToken) string {
+	policies := "n/a"
+	if token.Type != "management" {
+		policies = strings.Join(token.Policies, ",")
+	}
+	output := []string{
+		fmt.Sprintf("Accessor ID|%s", token.AccessorID),
+		fmt.Sprintf("Secret ID|%s", token.SecretID),
+		fmt.Sprintf("Name|%s", token.Name),
+		fmt.Sprintf("Type|%s", token.Type),
+		fmt.Sprintf("Global|%v", token.Global),
+		fmt.Sprintf("Policies|%s", policies),
+		fmt.Sprintf("Create Time|%v", token.CreateTime.Format(time.RFC3339)),
+		fmt.Sprintf("Create Index|%d", token.CreateIndex),
+		fmt.Sprintf("Modify Index|%d", token.ModifyIndex),
+	}
+	return formatKV(output)
+}
@@ -0,0 +1,80 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type ACLTokenListCommand struct {
+	Meta
+}
+
+func (c *ACLTokenListCommand) Help() string {
+	helpText := `
+Usage: nomad acl-token-list [options]
+
+  List the ACL tokens. Listing tokens requires a management token.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+List Options:
+
+  -global
+    Only list the global tokens.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLTokenListCommand) Synopsis() string {
+	return "List ACL tokens"
+}
+
+func (c *ACLTokenListCommand) Run(args []string) int {
+	var global bool
+	flags := c.Meta.FlagSet("acl-token-list", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&global, "global", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	var q *api.QueryOptions
+	if global {
+		q = &api.QueryOptions{Params: map[string]string{"global": "true"}}
+	}
+	tokens, _, err := client.ACLTokens().List(q)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error listing tokens: %s", err))
+		return 1
+	}
+	if len(tokens) == 0 {
+		c.Ui.Output("No tokens found")
+		return 0
+	}
+
+	out := make([]string, len(tokens)+1)
+	out[0] = "Name|Type|Global|Accessor ID"
+	for i, token := range tokens {
+		out[i+1] = fmt.Sprintf("%s|%s|%v|%s",
+			token.Name, token.Type, token.Global, token.AccessorID)
+	}
+	c.Ui.Output(formatList(out))
+	return 0
+}
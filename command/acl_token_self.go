@@ -0,0 +1,59 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ACLTokenSelfCommand struct {
+	Meta
+}
+
+func (c *ACLTokenSelfCommand) Help() string {
+	helpText := `
+Usage: nomad acl-token-self [options]
+
+  Display information about the ACL token used to make the request, as given
+  by the -token flag or the NOMAD_TOKEN environment variable.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLTokenSelfCommand) Synopsis() string {
+	return "Lookup the ACL token used to make the request"
+}
+
+func (c *ACLTokenSelfCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("acl-token-self", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Query the token
+	token, _, err := client.ACLTokens().Self(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error fetching self token: %s", err))
+		return 1
+	}
+
+	// Format the output
+	c.Ui.Output(formatKVACLToken(token))
+	return 0
+}
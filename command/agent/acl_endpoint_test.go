@@ -0,0 +1,251 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_ACLPolicyCRUD(t *testing.T) {
+	httpACLTest(t, nil, func(s *TestServer, root *structs.ACLToken) {
+		// Create a policy
+		policy := &structs.ACLPolicy{
+			Name:        "readonly",
+			Description: "read only access",
+			Rules:       `namespace "default" { policy = "read" }`,
+		}
+		buf := encodeReq(policy)
+		req, err := http.NewRequest("PUT", "/v1/acl/policy/readonly", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", root.SecretID)
+		respW := httptest.NewRecorder()
+
+		if _, err := s.Server.ACLPolicySpecificRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Writes without a token are rejected
+		req, err = http.NewRequest("PUT", "/v1/acl/policy/readonly", encodeReq(policy))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		if _, err := s.Server.ACLPolicySpecificRequest(respW, req); err == nil {
+			t.Fatalf("expected permission denied")
+		}
+
+		// Query the policy
+		req, err = http.NewRequest("GET", "/v1/acl/policy/readonly", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", root.SecretID)
+		respW = httptest.NewRecorder()
+
+		obj, err := s.Server.ACLPolicySpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		out := obj.(*structs.ACLPolicy)
+		if out.Name != policy.Name || out.Rules != policy.Rules {
+			t.Fatalf("bad: %#v", out)
+		}
+
+		// List the policies
+		req, err = http.NewRequest("GET", "/v1/acl/policies", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", root.SecretID)
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.ACLPoliciesRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if n := len(obj.([]*structs.ACLPolicyListStub)); n != 1 {
+			t.Fatalf("bad: %d", n)
+		}
+
+		// Delete the policy
+		req, err = http.NewRequest("DELETE", "/v1/acl/policy/readonly", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", root.SecretID)
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.ACLPolicySpecificRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// The policy is gone
+		req, err = http.NewRequest("GET", "/v1/acl/policy/readonly", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", root.SecretID)
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.ACLPolicySpecificRequest(respW, req); err == nil {
+			t.Fatalf("expected not found error")
+		}
+	})
+}
+
+func TestHTTP_ACLTokenCRUD(t *testing.T) {
+	httpACLTest(t, nil, func(s *TestServer, root *structs.ACLToken) {
+		// Bootstrapping again is rejected
+		req, err := http.NewRequest("PUT", "/v1/acl/bootstrap", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		if _, err := s.Server.ACLTokenBootstrap(respW, req); err == nil {
+			t.Fatalf("expected bootstrap error")
+		}
+
+		// Create a token
+		token := &structs.ACLToken{
+			Name:     "ops",
+			Type:     structs.ACLManagementToken,
+			Policies: []string{},
+		}
+		req, err = http.NewRequest("PUT", "/v1/acl/token", encodeReq(token))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", root.SecretID)
+		respW = httptest.NewRecorder()
+
+		obj, err := s.Server.ACLTokenCreateRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		created := obj.(*structs.ACLToken)
+		if created.AccessorID == "" || created.SecretID == "" || created.Name != token.Name {
+			t.Fatalf("bad: %#v", created)
+		}
+
+		// Query the token
+		req, err = http.NewRequest("GET", "/v1/acl/token/"+created.AccessorID, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", root.SecretID)
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.ACLTokenSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out := obj.(*structs.ACLToken); out.SecretID != created.SecretID {
+			t.Fatalf("bad: %#v", out)
+		}
+
+		// Query the token using itself
+		req, err = http.NewRequest("GET", "/v1/acl/token/self", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", created.SecretID)
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.ACLTokenSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out := obj.(*structs.ACLToken); out.AccessorID != created.AccessorID {
+			t.Fatalf("bad: %#v", out)
+		}
+
+		// Update the token
+		created.Name = "operations"
+		req, err = http.NewRequest("PUT", "/v1/acl/token/"+created.AccessorID, encodeReq(created))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", root.SecretID)
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.ACLTokenSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out := obj.(*structs.ACLToken); out.Name != "operations" {
+			t.Fatalf("bad: %#v", out)
+		}
+
+		// List the tokens
+		req, err = http.NewRequest("GET", "/v1/acl/tokens", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", root.SecretID)
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.ACLTokensRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if n := len(obj.([]*structs.ACLTokenListStub)); n != 2 {
+			t.Fatalf("bad: %d", n)
+		}
+
+		// Delete the token
+		req, err = http.NewRequest("DELETE", "/v1/acl/token/"+created.AccessorID, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", root.SecretID)
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.ACLTokenSpecificRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// The token is gone
+		req, err = http.NewRequest("GET", "/v1/acl/token/"+created.AccessorID, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", root.SecretID)
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.ACLTokenSpecificRequest(respW, req); err == nil {
+			t.Fatalf("expected not found error")
+		}
+	})
+}
+
+func TestHTTP_ACLReplicationStatus(t *testing.T) {
+	httpACLTest(t, nil, func(s *TestServer, root *structs.ACLToken) {
+		req, err := http.NewRequest("GET", "/v1/acl/replication", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", root.SecretID)
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.ACLReplicationStatusRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// The authoritative region doesn't replicate
+		status := obj.(*structs.ACLReplicationStatus)
+		if status.Enabled || status.Running || status.SourceRegion != "" {
+			t.Fatalf("bad: %#v", status)
+		}
+	})
+}
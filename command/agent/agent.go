@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"encoding/base64"
 	"fmt"
 	"io"
 	"log"
@@ -13,11 +14,17 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/acl"
 	"github.com/hashicorp/nomad/client"
 	clientconfig "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/command/agent/consul"
 	"github.com/hashicorp/nomad/nomad"
+	"github.com/hashicorp/nomad/nomad/audit"
+	"github.com/hashicorp/nomad/nomad/features"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
 )
 
 const (
@@ -52,23 +59,45 @@ type Agent struct {
 	serverRPCAddr  string
 	serverSerfAddr string
 
+	// auditor records the requests to the HTTP API and the server's RPC
+	// endpoints. It is nil when audit logging is disabled.
+	auditor *audit.Auditor
+
+	// features are the optional subsystems enabled on the agent
+	features *features.Set
+
+	// inmemSink aggregates the metrics of the agent in memory so that they
+	// can be queried through the HTTP API
+	inmemSink *metrics.InmemSink
+
 	shutdown     bool
 	shutdownCh   chan struct{}
 	shutdownLock sync.Mutex
 }
 
-// NewAgent is used to create a new agent with the given configuration
-func NewAgent(config *Config, logOutput io.Writer) (*Agent, error) {
+// NewAgent is used to create a new agent with the given configuration. The
+// in-memory sink is the one the metrics of the agent are collected in.
+func NewAgent(config *Config, logOutput io.Writer, inmem *metrics.InmemSink) (*Agent, error) {
 	a := &Agent{
 		config:     config,
 		logger:     log.New(logOutput, "", log.LstdFlags|log.Lmicroseconds),
 		logOutput:  logOutput,
+		inmemSink:  inmem,
 		shutdownCh: make(chan struct{}),
 	}
 
 	if err := a.setupConsulSyncer(); err != nil {
 		return nil, fmt.Errorf("Failed to initialize Consul syncer task: %v", err)
 	}
+	if err := a.setupFeatures(); err != nil {
+		return nil, fmt.Errorf("Failed to initialize features: %v", err)
+	}
+	if err := a.checkClusters(); err != nil {
+		return nil, err
+	}
+	if err := a.setupAudit(); err != nil {
+		return nil, fmt.Errorf("Failed to initialize audit logging: %v", err)
+	}
 	if err := a.setupServer(); err != nil {
 		return nil, err
 	}
@@ -123,6 +152,21 @@ func (a *Agent) serverConfig() (*nomad.Config, error) {
 	if a.config.Server.DataDir != "" {
 		conf.DataDir = a.config.Server.DataDir
 	}
+
+	// Without an explicit authoritative region the server is authoritative
+	// for its own region
+	conf.AuthoritativeRegion = conf.Region
+	if a.config.Server.AuthoritativeRegion != "" {
+		conf.AuthoritativeRegion = a.config.Server.AuthoritativeRegion
+	}
+	if a.config.ACL.Enabled {
+		conf.ACLEnabled = true
+	}
+	if a.config.ACL.ReplicationToken != "" {
+		conf.ReplicationToken = a.config.ACL.ReplicationToken
+	}
+	conf.Auditor = a.auditor
+	conf.Features = a.features
 	if a.config.Server.ProtocolVersion != 0 {
 		conf.ProtocolVersion = uint8(a.config.Server.ProtocolVersion)
 	}
@@ -132,6 +176,23 @@ func (a *Agent) serverConfig() (*nomad.Config, error) {
 	if len(a.config.Server.EnabledSchedulers) != 0 {
 		conf.EnabledSchedulers = a.config.Server.EnabledSchedulers
 	}
+	if len(a.config.Server.SchedulerMapping) != 0 {
+		conf.SchedulerMapping = a.config.Server.SchedulerMapping
+
+		// Handle the mapped job types unless the enabled schedulers were
+		// restricted explicitly
+		if len(a.config.Server.EnabledSchedulers) == 0 {
+			enabled := make(map[string]struct{}, len(conf.EnabledSchedulers))
+			for _, jobType := range conf.EnabledSchedulers {
+				enabled[jobType] = struct{}{}
+			}
+			for jobType := range conf.SchedulerMapping {
+				if _, ok := enabled[jobType]; !ok {
+					conf.EnabledSchedulers = append(conf.EnabledSchedulers, jobType)
+				}
+			}
+		}
+	}
 
 	// Set up the advertise addrs
 	if addr := a.config.AdvertiseAddrs.Serf; addr != "" {
@@ -171,6 +232,28 @@ func (a *Agent) serverConfig() (*nomad.Config, error) {
 		conf.SerfConfig.MemberlistConfig.BindPort = port
 	}
 
+	// Set up the encryption of the gossip traffic. Dev mode servers don't
+	// persist anything, including their keyring.
+	keyringDir := conf.DataDir
+	if conf.DevMode {
+		keyringDir = ""
+	}
+	if err := setupKeyring(conf.SerfConfig, keyringDir, a.config.Server.EncryptKey); err != nil {
+		return nil, fmt.Errorf("failed to set up gossip encryption: %v", err)
+	}
+
+	// Set up the key wrapping the root keys of the keyring
+	if key := a.config.Server.KeyringEncryptionKey; key != "" {
+		kek, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keyring encryption key: %v", err)
+		}
+		conf.KeyringEncryptionKey = kek
+		if err := conf.CheckKeyringEncryptionKey(); err != nil {
+			return nil, err
+		}
+	}
+
 	// Resolve the Server's HTTP Address
 	if a.config.AdvertiseAddrs.HTTP != "" {
 		a.serverHTTPAddr = a.config.AdvertiseAddrs.HTTP
@@ -226,6 +309,20 @@ func (a *Agent) serverConfig() (*nomad.Config, error) {
 		}
 		conf.NodeGCThreshold = dur
 	}
+	if gcThreshold := a.config.Server.EvalGCThreshold; gcThreshold != "" {
+		dur, err := time.ParseDuration(gcThreshold)
+		if err != nil {
+			return nil, err
+		}
+		conf.EvalGCThreshold = dur
+	}
+	if gcThreshold := a.config.Server.JobGCThreshold; gcThreshold != "" {
+		dur, err := time.ParseDuration(gcThreshold)
+		if err != nil {
+			return nil, err
+		}
+		conf.JobGCThreshold = dur
+	}
 
 	if heartbeatGrace := a.config.Server.HeartbeatGrace; heartbeatGrace != "" {
 		dur, err := time.ParseDuration(heartbeatGrace)
@@ -242,6 +339,11 @@ func (a *Agent) serverConfig() (*nomad.Config, error) {
 	// Add the Consul and Vault configs
 	conf.ConsulConfig = a.config.Consul
 	conf.VaultConfig = a.config.Vault
+	conf.VaultClusters = a.config.VaultClusters
+
+	// Set the TLS config. Plaintext RPC is rejected once TLS is enabled.
+	conf.TLSConfig = a.config.TLSConfig
+	conf.RequireTLS = a.config.TLSConfig.EnableRPC
 
 	return conf, nil
 }
@@ -265,6 +367,7 @@ func (a *Agent) clientConfig() (*clientconfig.Config, error) {
 	if a.config.DataDir != "" {
 		conf.StateDir = filepath.Join(a.config.DataDir, "client")
 		conf.AllocDir = filepath.Join(a.config.DataDir, "alloc")
+		conf.PluginDir = filepath.Join(a.config.DataDir, "plugins")
 	}
 	if a.config.Client.StateDir != "" {
 		conf.StateDir = a.config.Client.StateDir
@@ -272,11 +375,43 @@ func (a *Agent) clientConfig() (*clientconfig.Config, error) {
 	if a.config.Client.AllocDir != "" {
 		conf.AllocDir = a.config.Client.AllocDir
 	}
+	if a.config.Client.PluginDir != "" {
+		conf.PluginDir = a.config.Client.PluginDir
+	}
 	conf.Servers = a.config.Client.Servers
 	if a.config.Client.NetworkInterface != "" {
 		conf.NetworkInterface = a.config.Client.NetworkInterface
 	}
 	conf.ChrootEnv = a.config.Client.ChrootEnv
+	if a.config.Client.CNIPath != "" {
+		conf.CNIPath = a.config.Client.CNIPath
+	}
+	if a.config.Client.CNIConfigDir != "" {
+		conf.CNIConfigDir = a.config.Client.CNIConfigDir
+	}
+	if a.config.Client.BridgeNetworkName != "" {
+		conf.BridgeNetworkName = a.config.Client.BridgeNetworkName
+	}
+	if a.config.Client.BridgeNetworkSubnet != "" {
+		conf.BridgeNetworkSubnet = a.config.Client.BridgeNetworkSubnet
+	}
+	for _, n := range a.config.Client.HostNetworks {
+		conf.HostNetworks = append(conf.HostNetworks, &clientconfig.HostNetwork{
+			Name:      n.Name,
+			CIDR:      n.CIDR,
+			Interface: n.Interface,
+		})
+	}
+	for _, v := range a.config.Client.HostVolumes {
+		if conf.HostVolumes == nil {
+			conf.HostVolumes = make(map[string]*structs.ClientHostVolumeConfig)
+		}
+		conf.HostVolumes[v.Name] = &structs.ClientHostVolumeConfig{
+			Name:     v.Name,
+			Path:     v.Path,
+			ReadOnly: v.ReadOnly,
+		}
+	}
 	conf.Options = a.config.Client.Options
 	// Logging deprecation messages about consul related configuration in client
 	// options
@@ -343,6 +478,16 @@ func (a *Agent) clientConfig() (*clientconfig.Config, error) {
 	r.DiskMB = a.config.Client.Reserved.DiskMB
 	r.IOPS = a.config.Client.Reserved.IOPS
 	conf.GloballyReservedPorts = a.config.Client.Reserved.ParsedReservedPorts
+	conf.ReservePortConflicts = a.config.Client.ReservePortConflicts
+	conf.SecretsTmpfsSize = a.config.Client.SecretsTmpfsSize
+	conf.LocalTmpfsSize = a.config.Client.LocalTmpfsSize
+	if mp := a.config.Client.MemoryPressure; mp != nil && mp.Enabled {
+		conf.MemoryPressure = &clientconfig.MemoryPressureConfig{
+			Threshold: mp.Threshold,
+			Action:    mp.Action,
+			Victim:    mp.Victim,
+		}
+	}
 
 	conf.Version = fmt.Sprintf("%s%s", a.config.Version, a.config.VersionPrerelease)
 	conf.Revision = a.config.Revision
@@ -353,12 +498,120 @@ func (a *Agent) clientConfig() (*clientconfig.Config, error) {
 
 	conf.ConsulConfig = a.config.Consul
 	conf.VaultConfig = a.config.Vault
+	conf.ConsulClusters = a.config.ConsulClusters
+	conf.VaultClusters = a.config.VaultClusters
+	conf.TLSConfig = a.config.TLSConfig
 	conf.StatsCollectionInterval = a.config.Telemetry.collectionInterval
 	conf.PublishNodeMetrics = a.config.Telemetry.PublishNodeMetrics
 	conf.PublishAllocationMetrics = a.config.Telemetry.PublishAllocationMetrics
+	conf.PublishDriverMetrics = a.config.Telemetry.PublishDriverMetrics
+	if a.config.Telemetry.DriverMetricsLimit > 0 {
+		conf.DriverMetricsLimit = a.config.Telemetry.DriverMetricsLimit
+	}
 	return conf, nil
 }
 
+// setupFeatures is used to determine the optional subsystems enabled on
+// the agent
+func (a *Agent) setupFeatures() error {
+	var disabled []string
+	if a.config.Features != nil {
+		disabled = a.config.Features.Disabled
+	}
+	set, err := features.NewSet(disabled)
+	if err != nil {
+		return err
+	}
+	a.features = set
+	return nil
+}
+
+// checkClusters is used to ensure that the features needed to integrate with
+// more than one Vault or Consul cluster are enabled if named clusters are
+// configured
+func (a *Agent) checkClusters() error {
+	if len(a.config.VaultClusters) != 0 {
+		if err := a.features.Check(features.MultiVault); err != nil {
+			return fmt.Errorf("Failed to configure Vault clusters: %v", err)
+		}
+	}
+	if len(a.config.ConsulClusters) != 0 {
+		if err := a.features.Check(features.MultiConsul); err != nil {
+			return fmt.Errorf("Failed to configure Consul clusters: %v", err)
+		}
+	}
+	return nil
+}
+
+// setupAudit is used to setup the audit logging of requests if enabled
+func (a *Agent) setupAudit() error {
+	if a.config.Audit == nil || !a.config.Audit.Enabled {
+		return nil
+	}
+	if err := a.features.Check(features.Audit); err != nil {
+		return err
+	}
+
+	conf := &audit.Config{
+		Logger: a.logger,
+	}
+
+	// Default to a file sink in the data directory
+	sinks := a.config.Audit.Sinks
+	if len(sinks) == 0 {
+		sinks = []*AuditSink{{Name: "audit"}}
+	}
+	for _, s := range sinks {
+		sink := &audit.SinkConfig{
+			Name:              s.Name,
+			Type:              s.Type,
+			Format:            s.Format,
+			Path:              s.Path,
+			DeliveryGuarantee: s.DeliveryGuarantee,
+			RotateBytes:       s.RotateBytes,
+			RotateDuration:    s.rotateDuration,
+			RotateMaxFiles:    s.RotateMaxFiles,
+		}
+		if sink.Type == "" {
+			sink.Type = audit.SinkTypeFile
+		}
+		if sink.Format == "" {
+			sink.Format = audit.SinkFormatJSON
+		}
+		if sink.DeliveryGuarantee == "" {
+			sink.DeliveryGuarantee = audit.DeliveryEnforced
+		}
+		if sink.Path == "" {
+			if a.config.DataDir == "" {
+				return fmt.Errorf("sink %q: path must be set when data_dir is not", s.Name)
+			}
+			sink.Path = filepath.Join(a.config.DataDir, "audit", "audit.log")
+		}
+		conf.Sinks = append(conf.Sinks, sink)
+	}
+
+	for _, f := range a.config.Audit.Filters {
+		filter := &audit.FilterConfig{
+			Name:       f.Name,
+			Type:       f.Type,
+			Endpoints:  f.Endpoints,
+			Stages:     f.Stages,
+			Operations: f.Operations,
+		}
+		if filter.Type == "" {
+			filter.Type = "*"
+		}
+		conf.Filters = append(conf.Filters, filter)
+	}
+
+	auditor, err := audit.New(conf)
+	if err != nil {
+		return err
+	}
+	a.auditor = auditor
+	return nil
+}
+
 // setupServer is used to setup the server if enabled
 func (a *Agent) setupServer() error {
 	if !a.config.Server.Enabled {
@@ -389,7 +642,7 @@ func (a *Agent) setupServer() error {
 					Name:     "Nomad Server HTTP Check",
 					Type:     "http",
 					Path:     "/v1/status/peers",
-					Protocol: "http", // TODO TLS
+					Protocol: a.httpProtocol(),
 					Interval: serverHttpCheckInterval,
 					Timeout:  serverHttpCheckTimeout,
 				},
@@ -468,7 +721,7 @@ func (a *Agent) setupClient() error {
 					Name:     "Nomad Client HTTP Check",
 					Type:     "http",
 					Path:     "/v1/agent/servers",
-					Protocol: "http", // TODO TLS
+					Protocol: a.httpProtocol(),
 					Interval: clientHttpCheckInterval,
 					Timeout:  clientHttpCheckTimeout,
 				},
@@ -594,12 +847,57 @@ func (a *Agent) Shutdown() error {
 		a.logger.Printf("[ERR] agent: shutting down consul service failed: %v", err)
 	}
 
+	if a.auditor != nil {
+		if err := a.auditor.Close(); err != nil {
+			a.logger.Printf("[ERR] agent: closing audit sinks failed: %v", err)
+		}
+	}
+
 	a.logger.Println("[INFO] agent: shutdown complete")
 	a.shutdown = true
 	close(a.shutdownCh)
 	return nil
 }
 
+// ReloadTLS reloads the certificates the server and client use for RPC from
+// the given TLS configuration, without restarting the agent.
+func (a *Agent) ReloadTLS(newConf *config.TLSConfig) error {
+	var mErr multierror.Error
+	if a.server != nil {
+		if err := a.server.ReloadTLS(newConf); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("server: %v", err))
+		}
+	}
+	if a.client != nil {
+		if err := a.client.ReloadTLS(newConf); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("client: %v", err))
+		}
+	}
+	if err := mErr.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	a.config.TLSConfig = newConf
+	return nil
+}
+
+// ReloadACL applies the given ACL configuration to the agent's local
+// endpoints and to its server, without restarting the agent.
+func (a *Agent) ReloadACL(newConf *ACLConfig) {
+	if a.server != nil {
+		a.server.ReloadACL(newConf.Enabled, newConf.ReplicationToken)
+	}
+	a.config.ACL = newConf
+}
+
+// httpProtocol returns the protocol the HTTP API is served over
+func (a *Agent) httpProtocol() string {
+	if a.config.TLSConfig.EnableHTTP {
+		return "https"
+	}
+	return "http"
+}
+
 // RPC is used to make an RPC call to the Nomad servers
 func (a *Agent) RPC(method string, args interface{}, reply interface{}) error {
 	if a.server != nil {
@@ -608,6 +906,88 @@ func (a *Agent) RPC(method string, args interface{}, reply interface{}) error {
 	return a.client.RPC(method, args, reply)
 }
 
+// ResolveToken is used to translate an ACL token secret ID into the ACL
+// object used to enforce the agent's local endpoints. A nil ACL object is
+// returned if ACLs are disabled.
+func (a *Agent) ResolveToken(secretID string) (*acl.ACL, error) {
+	if !a.config.ACL.Enabled {
+		return nil, nil
+	}
+	if a.server != nil {
+		return a.server.ResolveToken(secretID)
+	}
+
+	// Client only agents resolve the token and its policies via the servers
+	token := structs.AnonymousACLToken
+	if secretID != "" {
+		args := structs.ResolveACLTokenRequest{
+			SecretID:     secretID,
+			QueryOptions: structs.QueryOptions{Region: a.config.Region},
+		}
+		var reply structs.ResolveACLTokenResponse
+		if err := a.RPC("ACL.ResolveToken", &args, &reply); err != nil {
+			return nil, err
+		}
+		if reply.Token == nil {
+			return nil, structs.ErrTokenNotFound
+		}
+		token = reply.Token
+	}
+	if token.Type == structs.ACLManagementToken {
+		return acl.ManagementACL, nil
+	}
+
+	args := structs.ACLPolicySetRequest{
+		Names: token.Policies,
+		QueryOptions: structs.QueryOptions{
+			Region:    a.config.Region,
+			AuthToken: secretID,
+		},
+	}
+	var reply structs.ACLPolicySetResponse
+	if err := a.RPC("ACL.GetPolicies", &args, &reply); err != nil {
+		return nil, err
+	}
+	policies := make([]*acl.Policy, 0, len(reply.Policies))
+	for name, policy := range reply.Policies {
+		p, err := acl.Parse(policy.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %v", name, err)
+		}
+		policies = append(policies, p)
+	}
+	return acl.NewACL(false, policies)
+}
+
+// auditTokenResolver returns the resolver of the ACL tokens recorded by the
+// audit log, or nil when ACLs are disabled
+func (a *Agent) auditTokenResolver() audit.TokenResolver {
+	if !a.config.ACL.Enabled {
+		return nil
+	}
+	if a.server != nil {
+		return func(secretID string) (*structs.ACLToken, error) {
+			return a.server.State().ACLTokenBySecretID(secretID)
+		}
+	}
+	return func(secretID string) (*structs.ACLToken, error) {
+		args := structs.ResolveACLTokenRequest{
+			SecretID:     secretID,
+			QueryOptions: structs.QueryOptions{Region: a.config.Region},
+		}
+		var reply structs.ResolveACLTokenResponse
+		if err := a.RPC("ACL.ResolveToken", &args, &reply); err != nil {
+			return nil, err
+		}
+		return reply.Token, nil
+	}
+}
+
+// InmemSink returns the in-memory sink of the metrics of the agent or nil
+func (a *Agent) InmemSink() *metrics.InmemSink {
+	return a.inmemSink
+}
+
 // Client returns the configured client or nil
 func (a *Agent) Client() *client.Client {
 	return a.client
@@ -634,6 +1014,7 @@ func (a *Agent) Stats() map[string]map[string]string {
 			stats[k] = v
 		}
 	}
+	stats["features"] = a.features.Status()
 	return stats
 }
 
@@ -1,9 +1,13 @@
 package agent
 
 import (
+	"fmt"
 	"net"
 	"net/http"
+	"sort"
+	"strings"
 
+	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/serf/serf"
 )
 
@@ -42,6 +46,13 @@ func (s *HTTPServer) AgentSelfRequest(resp http.ResponseWriter, req *http.Reques
 		return nil, CodedError(405, ErrInvalidMethod)
 	}
 
+	// Check agent read permissions
+	if aclObj, err := s.resolveToken(req); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.AllowAgentRead() {
+		return nil, structs.ErrPermissionDenied
+	}
+
 	// Get the member as a server
 	var member serf.Member
 	srv := s.agent.Server()
@@ -66,6 +77,13 @@ func (s *HTTPServer) AgentJoinRequest(resp http.ResponseWriter, req *http.Reques
 		return nil, CodedError(501, ErrInvalidMethod)
 	}
 
+	// Check agent write permissions
+	if aclObj, err := s.resolveToken(req); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.AllowAgentWrite() {
+		return nil, structs.ErrPermissionDenied
+	}
+
 	// Get the join addresses
 	query := req.URL.Query()
 	addrs := query["address"]
@@ -91,6 +109,13 @@ func (s *HTTPServer) AgentMembersRequest(resp http.ResponseWriter, req *http.Req
 		return nil, CodedError(501, ErrInvalidMethod)
 	}
 
+	// Check agent read permissions
+	if aclObj, err := s.resolveToken(req); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.AllowAgentRead() {
+		return nil, structs.ErrPermissionDenied
+	}
+
 	serfMembers := srv.Members()
 	members := make([]Member, len(serfMembers))
 	for i, mem := range serfMembers {
@@ -108,6 +133,13 @@ func (s *HTTPServer) AgentForceLeaveRequest(resp http.ResponseWriter, req *http.
 		return nil, CodedError(501, ErrInvalidMethod)
 	}
 
+	// Check agent write permissions
+	if aclObj, err := s.resolveToken(req); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.AllowAgentWrite() {
+		return nil, structs.ErrPermissionDenied
+	}
+
 	// Get the node to eject
 	node := req.URL.Query().Get("node")
 	if node == "" {
@@ -139,6 +171,13 @@ func (s *HTTPServer) listServers(resp http.ResponseWriter, req *http.Request) (i
 		return nil, CodedError(501, ErrInvalidMethod)
 	}
 
+	// Check agent read permissions
+	if aclObj, err := s.resolveToken(req); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.AllowAgentRead() {
+		return nil, structs.ErrPermissionDenied
+	}
+
 	peers := s.agent.client.RPCProxy().ServerRPCAddrs()
 	return peers, nil
 }
@@ -149,6 +188,13 @@ func (s *HTTPServer) updateServers(resp http.ResponseWriter, req *http.Request)
 		return nil, CodedError(501, ErrInvalidMethod)
 	}
 
+	// Check agent write permissions
+	if aclObj, err := s.resolveToken(req); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.AllowAgentWrite() {
+		return nil, structs.ErrPermissionDenied
+	}
+
 	// Get the servers from the request
 	servers := req.URL.Query()["address"]
 	if len(servers) == 0 {
@@ -166,6 +212,165 @@ func (s *HTTPServer) updateServers(resp http.ResponseWriter, req *http.Request)
 	return nil, nil
 }
 
+// agentHealth is the health of either the client or the server of an agent
+type agentHealth struct {
+	Ok      bool
+	Message string
+}
+
+// agentHealthResponse is the health of the client and the server of an agent.
+// A role that isn't checked is omitted.
+type agentHealthResponse struct {
+	Client *agentHealth `json:",omitempty"`
+	Server *agentHealth `json:",omitempty"`
+}
+
+// AgentHealthRequest is used to check the health of the agent, for instance
+// by a load balancer. The client is healthy when it knows of servers to
+// contact and the server when its region has a leader. The checks can be
+// limited to a role with the type parameter, in which case an agent not
+// running that role is unhealthy. An unhealthy agent is reported with a 503
+// status code.
+func (s *HTTPServer) AgentHealthRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	var health agentHealthResponse
+	checkClient, checkServer := true, true
+	if types, ok := req.URL.Query()["type"]; ok {
+		checkClient, checkServer = false, false
+		for _, t := range types {
+			switch t {
+			case "client":
+				checkClient = true
+				health.Client = &agentHealth{Message: "client not enabled"}
+			case "server":
+				checkServer = true
+				health.Server = &agentHealth{Message: "server not enabled"}
+			default:
+				return nil, CodedError(400, fmt.Sprintf("unknown agent type %q", t))
+			}
+		}
+	}
+
+	if client := s.agent.Client(); checkClient && client != nil {
+		if client.RPCProxy().NumServers() == 0 {
+			health.Client = &agentHealth{Message: "no known servers"}
+		} else {
+			health.Client = &agentHealth{Ok: true, Message: "ok"}
+		}
+	}
+
+	if srv := s.agent.Server(); checkServer && srv != nil {
+		var args structs.GenericRequest
+		var leader string
+		if err := s.agent.RPC("Status.Leader", &args, &leader); err != nil {
+			health.Server = &agentHealth{Message: err.Error()}
+		} else if leader == "" {
+			health.Server = &agentHealth{Message: "no leader"}
+		} else {
+			health.Server = &agentHealth{Ok: true, Message: "ok"}
+		}
+	}
+
+	if (health.Client != nil && !health.Client.Ok) || (health.Server != nil && !health.Server.Ok) {
+		// The status is written before the body, so set its type first
+		resp.Header().Set("Content-Type", "application/json")
+		resp.WriteHeader(http.StatusServiceUnavailable)
+	}
+	return health, nil
+}
+
+// AgentGossipKeyringRequest is used to list, install, use or remove the keys
+// encrypting the gossip traffic between servers. The operation is
+// broadcasted by the local server to every server of the gossip pool.
+func (s *HTTPServer) AgentGossipKeyringRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	srv := s.agent.Server()
+	if srv == nil {
+		return nil, CodedError(501, ErrInvalidMethod)
+	}
+
+	op := strings.TrimPrefix(req.URL.Path, "/v1/agent/gossip/keyring/")
+	switch op {
+	case "list":
+		if req.Method != "GET" {
+			return nil, CodedError(405, ErrInvalidMethod)
+		}
+	case "install", "use", "remove":
+		if req.Method != "PUT" && req.Method != "POST" {
+			return nil, CodedError(405, ErrInvalidMethod)
+		}
+	default:
+		return nil, CodedError(404, "unknown keyring operation")
+	}
+
+	// Check agent write permissions. Listing requires them as well since it
+	// reveals the keys.
+	if aclObj, err := s.resolveToken(req); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.AllowAgentWrite() {
+		return nil, structs.ErrPermissionDenied
+	}
+
+	if !srv.Encrypted() {
+		return nil, CodedError(400, "gossip encryption is not enabled")
+	}
+
+	var args structs.GossipKeyringRequest
+	if op != "list" {
+		if err := decodeBody(req, &args); err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+		if args.Key == "" {
+			return nil, CodedError(400, "missing key")
+		}
+	}
+
+	kmgr := srv.KeyManager()
+	var sresp *serf.KeyResponse
+	var err error
+	switch op {
+	case "list":
+		sresp, err = kmgr.ListKeys()
+	case "install":
+		sresp, err = kmgr.InstallKey(args.Key)
+	case "use":
+		sresp, err = kmgr.UseKey(args.Key)
+	case "remove":
+		sresp, err = kmgr.RemoveKey(args.Key)
+	}
+	if err != nil {
+		return nil, gossipKeyringError(err, sresp)
+	}
+
+	return structs.GossipKeyringResponse{
+		Messages: sresp.Messages,
+		Keys:     sresp.Keys,
+		NumNodes: sresp.NumNodes,
+	}, nil
+}
+
+// gossipKeyringError adds the errors reported by the servers to the error
+// of a keyring operation
+func gossipKeyringError(err error, sresp *serf.KeyResponse) error {
+	if sresp == nil || len(sresp.Messages) == 0 {
+		return err
+	}
+
+	nodes := make([]string, 0, len(sresp.Messages))
+	for node := range sresp.Messages {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	msgs := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", node, sresp.Messages[node]))
+	}
+	return fmt.Errorf("%v: %s", err, strings.Join(msgs, ", "))
+}
+
 type agentSelf struct {
 	Config *Config                      `json:"config"`
 	Member Member                       `json:"member,omitempty"`
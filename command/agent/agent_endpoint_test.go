@@ -2,10 +2,13 @@ package agent
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
 )
 
 func TestHTTP_AgentSelf(t *testing.T) {
@@ -183,3 +186,136 @@ func TestHTTP_AgentSetServers(t *testing.T) {
 		}
 	})
 }
+
+func TestHTTP_AgentHealth(t *testing.T) {
+	httpTest(t, func(c *Config) {
+		c.Client.Enabled = false
+	}, func(s *TestServer) {
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/agent/health", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// The server has a leader and the client isn't checked
+		obj, err := s.Server.AgentHealthRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.Code != 200 {
+			t.Fatalf("bad: %d", respW.Code)
+		}
+		health := obj.(agentHealthResponse)
+		if health.Client != nil || health.Server == nil || !health.Server.Ok {
+			t.Fatalf("bad: %#v", health)
+		}
+
+		// Checking the client fails as it isn't running
+		req, err = http.NewRequest("GET", "/v1/agent/health?type=client", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		obj, err = s.Server.AgentHealthRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.Code != 503 {
+			t.Fatalf("bad: %d", respW.Code)
+		}
+		health = obj.(agentHealthResponse)
+		if health.Server != nil || health.Client == nil || health.Client.Ok {
+			t.Fatalf("bad: %#v", health)
+		}
+
+		// Unknown types are rejected
+		req, err = http.NewRequest("GET", "/v1/agent/health?type=foo", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := s.Server.AgentHealthRequest(httptest.NewRecorder(), req); err == nil || !strings.Contains(err.Error(), "unknown agent type") {
+			t.Fatalf("bad: %v", err)
+		}
+	})
+}
+
+func TestHTTP_AgentGossipKeyring(t *testing.T) {
+	key1 := "tbLJg26ZJyJ9pK3qhc9jig=="
+	key2 := "4leC33rgtXKIVUr9Nr0snQ=="
+
+	httpTest(t, func(c *Config) {
+		c.Server.EncryptKey = key1
+	}, func(s *TestServer) {
+		keyringOp := func(method, op, key string) (*structs.GossipKeyringResponse, error) {
+			var body io.Reader
+			if method != "GET" {
+				body = encodeReq(structs.GossipKeyringRequest{Key: key})
+			}
+			req, err := http.NewRequest(method, "/v1/agent/gossip/keyring/"+op, body)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			out, err := s.Server.AgentGossipKeyringRequest(httptest.NewRecorder(), req)
+			if err != nil {
+				return nil, err
+			}
+			resp := out.(structs.GossipKeyringResponse)
+			return &resp, nil
+		}
+
+		// The configured key is installed
+		resp, err := keyringOp("GET", "list", "")
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(resp.Keys) != 1 || resp.Keys[key1] != 1 {
+			t.Fatalf("bad: %#v", resp)
+		}
+
+		// Rotate to a new key
+		if _, err := keyringOp("PUT", "install", key2); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := keyringOp("PUT", "use", key2); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// The primary key can't be removed
+		if _, err := keyringOp("PUT", "remove", key2); err == nil {
+			t.Fatalf("expected removing the primary key to fail")
+		}
+		if _, err := keyringOp("PUT", "remove", key1); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		resp, err = keyringOp("GET", "list", "")
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(resp.Keys) != 1 || resp.Keys[key2] != 1 {
+			t.Fatalf("bad: %#v", resp)
+		}
+
+		// Unknown operations and missing keys are rejected
+		if _, err := keyringOp("PUT", "rotate", key2); err == nil {
+			t.Fatalf("expected unknown operation to fail")
+		}
+		if _, err := keyringOp("PUT", "install", ""); err == nil || !strings.Contains(err.Error(), "missing key") {
+			t.Fatalf("expected missing key error, got: %v", err)
+		}
+	})
+}
+
+func TestHTTP_AgentGossipKeyring_NotEncrypted(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		req, err := http.NewRequest("GET", "/v1/agent/gossip/keyring/list", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		_, err = s.Server.AgentGossipKeyringRequest(httptest.NewRecorder(), req)
+		if err == nil || !strings.Contains(err.Error(), "not enabled") {
+			t.Fatalf("expected encryption not enabled error, got: %v", err)
+		}
+	})
+}
@@ -5,10 +5,13 @@ import (
 	"io/ioutil"
 	"net"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/armon/go-metrics"
+	clientconfig "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/nomad"
 	sconfig "github.com/hashicorp/nomad/nomad/structs/config"
 )
@@ -72,7 +75,8 @@ func makeAgent(t testing.TB, cb func(*Config)) (string, *Agent) {
 		cb(conf)
 	}
 
-	agent, err := NewAgent(conf, os.Stderr)
+	inmem := metrics.NewInmemSink(10*time.Second, time.Minute)
+	agent, err := NewAgent(conf, os.Stderr, inmem)
 	if err != nil {
 		os.RemoveAll(dir)
 		t.Fatalf("err: %v", err)
@@ -189,6 +193,21 @@ func TestAgent_ServerConfig(t *testing.T) {
 		t.Fatalf("expect 10s, got: %s", threshold)
 	}
 
+	conf.Server.EvalGCThreshold = "42g"
+	out, err = a.serverConfig()
+	if err == nil || !strings.Contains(err.Error(), "unknown unit") {
+		t.Fatalf("expected unknown unit error, got: %#v", err)
+	}
+	conf.Server.EvalGCThreshold = "30m"
+	conf.Server.JobGCThreshold = "2h"
+	out, err = a.serverConfig()
+	if threshold := out.EvalGCThreshold; threshold != 30*time.Minute {
+		t.Fatalf("expect 30m, got: %s", threshold)
+	}
+	if threshold := out.JobGCThreshold; threshold != 2*time.Hour {
+		t.Fatalf("expect 2h, got: %s", threshold)
+	}
+
 	conf.Server.HeartbeatGrace = "42g"
 	out, err = a.serverConfig()
 	if err == nil || !strings.Contains(err.Error(), "unknown unit") {
@@ -254,6 +273,25 @@ func TestAgent_ServerConfig(t *testing.T) {
 	if out.BootstrapExpect != 3 {
 		t.Fatalf("should have bootstrap-expect = 3")
 	}
+
+	// Mapped job types are handled by the workers
+	conf.Server.SchedulerMapping = map[string]string{"gpu": "batch"}
+	out, err = a.serverConfig()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if out.SchedulerMapping.Scheduler("gpu") != "batch" {
+		t.Fatalf("bad: %#v", out.SchedulerMapping)
+	}
+	found := false
+	for _, jobType := range out.EnabledSchedulers {
+		if jobType == "gpu" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected the mapped job type to be enabled: %v", out.EnabledSchedulers)
+	}
 }
 
 func TestAgent_ClientConfig(t *testing.T) {
@@ -288,3 +326,33 @@ func TestAgent_ClientConfig(t *testing.T) {
 		t.Fatalf("Expected http addr: %v, got: %v", expectedHttpAddr, c.Node.HTTPAddr)
 	}
 }
+
+func TestAgent_ClientConfig_MemoryPressure(t *testing.T) {
+	conf := DefaultConfig()
+	a := &Agent{config: conf}
+	conf.Client.Enabled = true
+
+	// Memory pressure protection is disabled by default
+	c, err := a.clientConfig()
+	if err != nil {
+		t.Fatalf("got err: %v", err)
+	}
+	if c.MemoryPressure != nil {
+		t.Fatalf("bad: %#v", c.MemoryPressure)
+	}
+
+	conf.Client.MemoryPressure.Enabled = true
+	conf.Client.MemoryPressure.Action = "evict"
+	c, err = a.clientConfig()
+	if err != nil {
+		t.Fatalf("got err: %v", err)
+	}
+	expected := &clientconfig.MemoryPressureConfig{
+		Threshold: 90,
+		Action:    "evict",
+		Victim:    "over_limit",
+	}
+	if !reflect.DeepEqual(c.MemoryPressure, expected) {
+		t.Fatalf("bad: %#v", c.MemoryPressure)
+	}
+}
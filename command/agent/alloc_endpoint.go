@@ -1,16 +1,35 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/client/driver"
+	"github.com/hashicorp/nomad/helper/websocket"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
 const (
 	allocNotFoundErr    = "allocation not found"
 	resourceNotFoundErr = "resource not found"
+
+	// allocRestartReason is the reason recorded in the task events of
+	// allocations restarted through the HTTP API
+	allocRestartReason = "Restart requested by user"
+
+	// allocPauseReason is the reason recorded in the task events of
+	// allocations paused or resumed through the HTTP API
+	allocPauseReason = "Requested by user"
+
+	// allocSignalReason is the reason recorded in the task events of
+	// allocations signaled through the HTTP API
+	allocSignalReason = "Signal requested by user"
 )
 
 func (s *HTTPServer) AllocsRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
@@ -57,7 +76,19 @@ func (s *HTTPServer) AllocSpecificRequest(resp http.ResponseWriter, req *http.Re
 	if out.Alloc == nil {
 		return nil, CodedError(404, "alloc not found")
 	}
-	return out.Alloc, nil
+	return withoutSignedIdentities(out.Alloc), nil
+}
+
+// withoutSignedIdentities returns a shallow copy of the allocation without its
+// signed workload identities, which must not be exposed over the HTTP API.
+// The allocation may be owned by the state store and can't be modified.
+func withoutSignedIdentities(alloc *structs.Allocation) *structs.Allocation {
+	if alloc.SignedIdentities == nil {
+		return alloc
+	}
+	stripped := *alloc
+	stripped.SignedIdentities = nil
+	return &stripped
 }
 
 func (s *HTTPServer) ClientAllocRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
@@ -76,14 +107,80 @@ func (s *HTTPServer) ClientAllocRequest(resp http.ResponseWriter, req *http.Requ
 	allocID := tokens[0]
 	switch tokens[1] {
 	case "stats":
+		if err := s.checkAllocCapability(req, allocID, acl.NamespaceCapabilityReadJob); err != nil {
+			return nil, err
+		}
 		return s.allocStats(allocID, resp, req)
 	case "snapshot":
+		if !s.validMigrateToken(req, allocID) {
+			if err := s.checkAllocCapability(req, allocID, acl.NamespaceCapabilityReadFS); err != nil {
+				return nil, err
+			}
+		}
 		return s.allocSnapshot(allocID, resp, req)
+	case "restart":
+		if req.Method != "PUT" && req.Method != "POST" {
+			return nil, CodedError(405, ErrInvalidMethod)
+		}
+		if err := s.checkAllocCapability(req, allocID, acl.NamespaceCapabilitySubmitJob); err != nil {
+			return nil, err
+		}
+		return s.allocRestart(allocID, resp, req)
+	case "pause", "resume":
+		if req.Method != "PUT" && req.Method != "POST" {
+			return nil, CodedError(405, ErrInvalidMethod)
+		}
+		if err := s.checkAllocCapability(req, allocID, acl.NamespaceCapabilitySubmitJob); err != nil {
+			return nil, err
+		}
+		return s.allocPause(allocID, tokens[1] == "pause", resp, req)
+	case "signal":
+		if req.Method != "PUT" && req.Method != "POST" {
+			return nil, CodedError(405, ErrInvalidMethod)
+		}
+		if err := s.checkAllocCapability(req, allocID, acl.NamespaceCapabilitySubmitJob); err != nil {
+			return nil, err
+		}
+		return s.allocSignal(allocID, resp, req)
+	case "exec":
+		if err := s.checkAllocCapability(req, allocID, acl.NamespaceCapabilityAllocExec); err != nil {
+			return nil, err
+		}
+		return s.allocExec(allocID, resp, req)
 	}
 
 	return nil, CodedError(404, resourceNotFoundErr)
 }
 
+// checkAllocCapability returns an error if the token of the request is not
+// allowed to perform the operation in the namespace of an allocation running
+// on this client
+func (s *HTTPServer) checkAllocCapability(req *http.Request, allocID, op string) error {
+	aclObj, err := s.resolveToken(req)
+	if err != nil {
+		return err
+	} else if aclObj == nil {
+		return nil
+	}
+
+	alloc, err := s.agent.client.GetAlloc(allocID)
+	if err != nil {
+		return err
+	}
+	if !aclObj.AllowNamespaceOperation(alloc.Namespace, op) {
+		return structs.ErrPermissionDenied
+	}
+	return nil
+}
+
+// validMigrateToken returns whether the request carries a token authorizing
+// another node to migrate the data of the allocation
+func (s *HTTPServer) validMigrateToken(req *http.Request, allocID string) bool {
+	var token string
+	s.parseToken(req, &token)
+	return token != "" && structs.CompareMigrateToken(allocID, s.agent.client.Node().SecretID, token)
+}
+
 func (s *HTTPServer) allocSnapshot(allocID string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	allocFS, err := s.agent.Client().GetAllocFS(allocID)
 	if err != nil {
@@ -95,6 +192,192 @@ func (s *HTTPServer) allocSnapshot(allocID string, resp http.ResponseWriter, req
 	return nil, nil
 }
 
+func (s *HTTPServer) allocRestart(allocID string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if _, err := s.agent.client.GetAlloc(allocID); err != nil {
+		return nil, CodedError(404, allocNotFoundErr)
+	}
+
+	task := req.URL.Query().Get("task")
+	if err := s.agent.client.RestartAlloc(allocID, task, allocRestartReason); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	return nil, nil
+}
+
+func (s *HTTPServer) allocPause(allocID string, pause bool, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if _, err := s.agent.client.GetAlloc(allocID); err != nil {
+		return nil, CodedError(404, allocNotFoundErr)
+	}
+
+	task := req.URL.Query().Get("task")
+	var err error
+	if pause {
+		err = s.agent.client.PauseAlloc(allocID, task, allocPauseReason)
+	} else {
+		err = s.agent.client.ResumeAlloc(allocID, task, allocPauseReason)
+	}
+	if err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	return nil, nil
+}
+
+func (s *HTTPServer) allocSignal(allocID string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if _, err := s.agent.client.GetAlloc(allocID); err != nil {
+		return nil, CodedError(404, allocNotFoundErr)
+	}
+
+	sig := req.URL.Query().Get("signal")
+	if sig == "" {
+		return nil, CodedError(400, "missing signal")
+	}
+	task := req.URL.Query().Get("task")
+	if err := s.agent.client.SignalAlloc(allocID, task, sig, allocSignalReason); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	return nil, nil
+}
+
+// execStreamData is the data of a stream of an exec session. Close marks
+// the end of the stream.
+type execStreamData struct {
+	Data  []byte `json:"data,omitempty"`
+	Close bool   `json:"close,omitempty"`
+}
+
+// execStreamInput is a message sent by the client of an exec session
+type execStreamInput struct {
+	Stdin   *execStreamData      `json:"stdin,omitempty"`
+	TTYSize *driver.TerminalSize `json:"tty_size,omitempty"`
+}
+
+// execStreamOutput is a message sent to the client of an exec session. The
+// last message marks the command as exited and holds its result.
+type execStreamOutput struct {
+	Stdout *execStreamData   `json:"stdout,omitempty"`
+	Stderr *execStreamData   `json:"stderr,omitempty"`
+	Exited bool              `json:"exited,omitempty"`
+	Result *execStreamResult `json:"result,omitempty"`
+}
+
+// execStreamResult is the result of the command of an exec session
+type execStreamResult struct {
+	ExitCode int `json:"exit_code"`
+}
+
+// execStreamWriter sends the data written to it as stdout or stderr messages
+type execStreamWriter struct {
+	conn   *websocket.Conn
+	stderr bool
+}
+
+func (w *execStreamWriter) Write(p []byte) (int, error) {
+	var out execStreamOutput
+	if w.stderr {
+		out.Stderr = &execStreamData{Data: p}
+	} else {
+		out.Stdout = &execStreamData{Data: p}
+	}
+	buf, err := json.Marshal(&out)
+	if err != nil {
+		return 0, err
+	}
+	if err := w.conn.WriteMessage(websocket.TextMessage, buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// allocExec runs a command inside a task of the allocation, streaming the
+// input and output of the command over a websocket connection. Errors once
+// the connection is established are returned as the reason the connection
+// is closed.
+func (s *HTTPServer) allocExec(allocID string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if _, err := s.agent.client.GetAlloc(allocID); err != nil {
+		return nil, CodedError(404, allocNotFoundErr)
+	}
+	if !websocket.IsUpgrade(req) {
+		return nil, CodedError(400, "exec requires a websocket connection")
+	}
+
+	query := req.URL.Query()
+	var command []string
+	if err := json.Unmarshal([]byte(query.Get("command")), &command); err != nil || len(command) == 0 {
+		return nil, CodedError(400, "command must be a non-empty JSON array")
+	}
+	tty := false
+	if v := query.Get("tty"); v != "" {
+		var err error
+		if tty, err = strconv.ParseBool(v); err != nil {
+			return nil, CodedError(400, fmt.Sprintf("invalid tty value %q", v))
+		}
+	}
+
+	conn, err := websocket.Upgrade(resp, req)
+	if err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stdinR, stdinW := io.Pipe()
+	resizeCh := make(chan driver.TerminalSize, 1)
+
+	// Read the input of the command until the connection is closed, which
+	// kills the command if it is still running
+	go func() {
+		defer cancel()
+		defer stdinW.Close()
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var in execStreamInput
+			if err := json.Unmarshal(msg, &in); err != nil {
+				s.logger.Printf("[WARN] http: ignoring invalid exec message for alloc %q: %v", allocID, err)
+				continue
+			}
+			if in.Stdin != nil {
+				if len(in.Stdin.Data) != 0 {
+					stdinW.Write(in.Stdin.Data)
+				}
+				if in.Stdin.Close {
+					stdinW.Close()
+				}
+			}
+			if in.TTYSize != nil {
+				select {
+				case resizeCh <- *in.TTYSize:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	code, err := s.agent.client.ExecAlloc(ctx, allocID, query.Get("task"), &driver.ExecOptions{
+		Command:  command,
+		Tty:      tty,
+		Stdin:    stdinR,
+		Stdout:   &execStreamWriter{conn: conn},
+		Stderr:   &execStreamWriter{conn: conn, stderr: true},
+		ResizeCh: resizeCh,
+	})
+	stdinR.Close()
+	if err != nil {
+		conn.CloseWithReason(websocket.CloseInternalError, err.Error())
+		return nil, nil
+	}
+
+	buf, err := json.Marshal(&execStreamOutput{Exited: true, Result: &execStreamResult{ExitCode: code}})
+	if err == nil {
+		conn.WriteMessage(websocket.TextMessage, buf)
+	}
+	conn.Close()
+	return nil, nil
+}
+
 func (s *HTTPServer) allocStats(allocID string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	clientStats := s.agent.client.StatsReporter()
 	aStats, err := clientStats.GetAllocStats(allocID)
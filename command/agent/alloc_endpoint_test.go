@@ -120,6 +120,7 @@ func TestHTTP_AllocQuery(t *testing.T) {
 		// Directly manipulate the state
 		state := s.Agent.server.State()
 		alloc := mock.Alloc()
+		alloc.SignedIdentities = map[string]string{"web": "secret"}
 		if err := state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID)); err != nil {
 			t.Fatal(err)
 		}
@@ -158,6 +159,18 @@ func TestHTTP_AllocQuery(t *testing.T) {
 		if a.ID != alloc.ID {
 			t.Fatalf("bad: %#v", a)
 		}
+
+		// The workload identities are not exposed, nor removed from the state
+		if a.SignedIdentities != nil {
+			t.Fatalf("bad: %#v", a.SignedIdentities)
+		}
+		out, err := state.AllocByID(alloc.ID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out.SignedIdentities["web"] != "secret" {
+			t.Fatalf("bad: %#v", out.SignedIdentities)
+		}
 	})
 }
 
@@ -194,3 +207,106 @@ func TestHTTP_AllocSnapshot(t *testing.T) {
 		}
 	})
 }
+
+func TestHTTP_AllocRestart(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Only writes are allowed
+		req, err := http.NewRequest("GET", "/v1/client/allocation/123/restart", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		_, err = s.Server.ClientAllocRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), ErrInvalidMethod) {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err = http.NewRequest("PUT", "/v1/client/allocation/123/restart", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		// Make the request
+		_, err = s.Server.ClientAllocRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), allocNotFoundErr) {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
+func TestHTTP_AllocPause(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		for _, action := range []string{"pause", "resume"} {
+			// Only writes are allowed
+			req, err := http.NewRequest("GET", "/v1/client/allocation/123/"+action, nil)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			respW := httptest.NewRecorder()
+			_, err = s.Server.ClientAllocRequest(respW, req)
+			if err == nil || !strings.Contains(err.Error(), ErrInvalidMethod) {
+				t.Fatalf("%s: err: %v", action, err)
+			}
+
+			// Make the HTTP request
+			req, err = http.NewRequest("PUT", "/v1/client/allocation/123/"+action, nil)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			respW = httptest.NewRecorder()
+
+			// Make the request
+			_, err = s.Server.ClientAllocRequest(respW, req)
+			if err == nil || !strings.Contains(err.Error(), allocNotFoundErr) {
+				t.Fatalf("%s: err: %v", action, err)
+			}
+		}
+	})
+}
+
+func TestHTTP_AllocExec(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", `/v1/client/allocation/123/exec?command=["/bin/sh"]`, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		_, err = s.Server.ClientAllocRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), allocNotFoundErr) {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
+func TestHTTP_AllocSignal(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Only writes are allowed
+		req, err := http.NewRequest("GET", "/v1/client/allocation/123/signal?signal=SIGHUP", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		_, err = s.Server.ClientAllocRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), ErrInvalidMethod) {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err = http.NewRequest("PUT", "/v1/client/allocation/123/signal?signal=SIGHUP", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		// Make the request
+		_, err = s.Server.ClientAllocRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), allocNotFoundErr) {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
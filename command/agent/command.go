@@ -291,9 +291,9 @@ func (c *Command) setupLoggers(config *Config) (*gatedwriter.Writer, *logWriter,
 }
 
 // setupAgent is used to start the agent and various interfaces
-func (c *Command) setupAgent(config *Config, logOutput io.Writer) error {
+func (c *Command) setupAgent(config *Config, logOutput io.Writer, inmem *metrics.InmemSink) error {
 	c.Ui.Output("Starting Nomad agent...")
-	agent, err := NewAgent(config, logOutput)
+	agent, err := NewAgent(config, logOutput, inmem)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error starting agent: %s", err))
 		return err
@@ -395,13 +395,14 @@ func (c *Command) Run(args []string) int {
 	}
 
 	// Initialize the telemetry
-	if err := c.setupTelemetry(config); err != nil {
+	inmem, err := c.setupTelemetry(config)
+	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error initializing telemetry: %s", err))
 		return 1
 	}
 
 	// Create the agent
-	if err := c.setupAgent(config, logOutput); err != nil {
+	if err := c.setupAgent(config, logOutput, inmem); err != nil {
 		return 1
 	}
 	defer c.agent.Shutdown()
@@ -532,7 +533,10 @@ WAIT:
 	}
 }
 
-// handleReload is invoked when we should reload our configs, e.g. SIGHUP
+// handleReload is invoked when we should reload our configs, e.g. SIGHUP. The
+// log level, TLS certificates, ACLs, client meta and server join addresses
+// are applied live; changes to the other options are logged as requiring a
+// restart.
 func (c *Command) handleReload(config *Config) *Config {
 	c.Ui.Output("Reloading configuration...")
 	newConf := c.readConfig()
@@ -541,10 +545,15 @@ func (c *Command) handleReload(config *Config) *Config {
 		return config
 	}
 
+	var applied []string
+
 	// Change the log level
 	minLevel := logutils.LogLevel(strings.ToUpper(newConf.LogLevel))
 	if ValidateLevelFilter(minLevel, c.logFilter) {
 		c.logFilter.SetMinLevel(minLevel)
+		if newConf.LogLevel != config.LogLevel {
+			applied = append(applied, "log_level")
+		}
 	} else {
 		c.Ui.Error(fmt.Sprintf(
 			"Invalid log level: %s. Valid log levels are: %v",
@@ -553,11 +562,136 @@ func (c *Command) handleReload(config *Config) *Config {
 		// Keep the current log level
 		newConf.LogLevel = config.LogLevel
 	}
+
+	// Reload the TLS certificates
+	if err := c.agent.ReloadTLS(newConf.TLSConfig); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to reload TLS certificates: %v", err))
+		newConf.TLSConfig = config.TLSConfig
+	} else {
+		if c.httpServer != nil {
+			if err := c.httpServer.ReloadTLS(newConf.TLSConfig); err != nil {
+				c.Ui.Error(fmt.Sprintf("Failed to reload TLS certificates for HTTP: %v", err))
+			}
+		}
+		applied = append(applied, "tls certificates")
+	}
+
+	// Enable or disable ACLs
+	if !reflect.DeepEqual(newConf.ACL, config.ACL) {
+		c.agent.ReloadACL(newConf.ACL)
+		applied = append(applied, "acl")
+	}
+
+	// Update the metadata of the node
+	if client := c.agent.Client(); client != nil && !reflect.DeepEqual(newConf.Client.Meta, config.Client.Meta) {
+		client.UpdateMeta(newConf.Client.Meta)
+		applied = append(applied, "client.meta")
+	}
+
+	// Join the servers added to the join addresses
+	if server := c.agent.Server(); server != nil {
+		if addrs := addedJoinAddrs(config.Server, newConf.Server); len(addrs) != 0 {
+			n, err := server.Join(addrs)
+			if err != nil {
+				c.Ui.Error(fmt.Sprintf("Failed to join the new server addresses: %v", err))
+			} else {
+				c.agent.logger.Printf("[INFO] agent: Join completed. Synced with %d agents", n)
+			}
+			applied = append(applied, "server.start_join", "server.retry_join")
+		}
+	}
+
+	logger := c.agent.logger
+	if len(applied) != 0 {
+		logger.Printf("[INFO] agent: reloaded configuration: %s", strings.Join(applied, ", "))
+	}
+	if restart := reloadRequiresRestart(config, newConf); len(restart) != 0 {
+		logger.Printf("[WARN] agent: changes to %s require a restart of the agent",
+			strings.Join(restart, ", "))
+	}
 	return newConf
 }
 
-// setupTelemetry is used ot setup the telemetry sub-systems
-func (c *Command) setupTelemetry(config *Config) error {
+// addedJoinAddrs returns the join addresses of the new server configuration
+// that aren't in the old one
+func addedJoinAddrs(old, new *ServerConfig) []string {
+	known := make(map[string]struct{}, len(old.StartJoin)+len(old.RetryJoin))
+	for _, addr := range old.StartJoin {
+		known[addr] = struct{}{}
+	}
+	for _, addr := range old.RetryJoin {
+		known[addr] = struct{}{}
+	}
+
+	var added []string
+	for _, addrs := range [][]string{new.StartJoin, new.RetryJoin} {
+		for _, addr := range addrs {
+			if _, ok := known[addr]; ok {
+				continue
+			}
+			known[addr] = struct{}{}
+			added = append(added, addr)
+		}
+	}
+	return added
+}
+
+// reloadRequiresRestart returns the options that changed between the old and
+// new configuration and aren't applied by a reload
+func reloadRequiresRestart(old, new *Config) []string {
+	// Ignore the options of the server and client blocks that are reloaded
+	oldServer, newServer := *old.Server, *new.Server
+	oldServer.StartJoin, newServer.StartJoin = nil, nil
+	oldServer.RetryJoin, newServer.RetryJoin = nil, nil
+	oldClient, newClient := *old.Client, *new.Client
+	oldClient.Meta, newClient.Meta = nil, nil
+
+	// TLS is reloaded unless it is enabled or disabled
+	oldTLS, newTLS := old.TLSConfig, new.TLSConfig
+	tlsToggled := oldTLS.EnableHTTP != newTLS.EnableHTTP || oldTLS.EnableRPC != newTLS.EnableRPC
+
+	options := []struct {
+		name     string
+		old, new interface{}
+	}{
+		{"region", old.Region, new.Region},
+		{"datacenter", old.Datacenter, new.Datacenter},
+		{"name", old.NodeName, new.NodeName},
+		{"data_dir", old.DataDir, new.DataDir},
+		{"bind_addr", old.BindAddr, new.BindAddr},
+		{"enable_debug", old.EnableDebug, new.EnableDebug},
+		{"ui", old.UI, new.UI},
+		{"ports", old.Ports, new.Ports},
+		{"addresses", old.Addresses, new.Addresses},
+		{"advertise", old.AdvertiseAddrs, new.AdvertiseAddrs},
+		{"client", oldClient, newClient},
+		{"server", oldServer, newServer},
+		{"telemetry", old.Telemetry, new.Telemetry},
+		{"audit", old.Audit, new.Audit},
+		{"features", old.Features, new.Features},
+		{"enable_syslog", old.EnableSyslog, new.EnableSyslog},
+		{"syslog_facility", old.SyslogFacility, new.SyslogFacility},
+		{"atlas", old.Atlas, new.Atlas},
+		{"consul", old.Consul, new.Consul},
+		{"vault", old.Vault, new.Vault},
+		{"consul clusters", old.ConsulClusters, new.ConsulClusters},
+		{"vault clusters", old.VaultClusters, new.VaultClusters},
+		{"tls", tlsToggled, false},
+		{"http_api_response_headers", old.HTTPAPIResponseHeaders, new.HTTPAPIResponseHeaders},
+	}
+
+	var restart []string
+	for _, o := range options {
+		if !reflect.DeepEqual(o.old, o.new) {
+			restart = append(restart, o.name)
+		}
+	}
+	return restart
+}
+
+// setupTelemetry is used ot setup the telemetry sub-systems. It returns the
+// in-memory sink the metrics are aggregated in.
+func (c *Command) setupTelemetry(config *Config) (*metrics.InmemSink, error) {
 	/* Setup telemetry
 	Aggregate on 10 second intervals for 1 minute. Expose the
 	metrics over stderr when there is a SIGUSR1 received.
@@ -580,7 +714,7 @@ func (c *Command) setupTelemetry(config *Config) error {
 	if telConfig.StatsiteAddr != "" {
 		sink, err := metrics.NewStatsiteSink(telConfig.StatsiteAddr)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		fanout = append(fanout, sink)
 	}
@@ -589,7 +723,7 @@ func (c *Command) setupTelemetry(config *Config) error {
 	if telConfig.StatsdAddr != "" {
 		sink, err := metrics.NewStatsdSink(telConfig.StatsdAddr)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		fanout = append(fanout, sink)
 	}
@@ -625,7 +759,7 @@ func (c *Command) setupTelemetry(config *Config) error {
 
 		sink, err := circonus.NewCirconusSink(cfg)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		sink.Start()
 		fanout = append(fanout, sink)
@@ -639,7 +773,7 @@ func (c *Command) setupTelemetry(config *Config) error {
 		metricsConf.EnableHostname = false
 		metrics.NewGlobal(metricsConf, inm)
 	}
-	return nil
+	return inm, nil
 }
 
 // setupSCADA is used to start a new SCADA provider and listener,
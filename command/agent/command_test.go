@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -126,3 +127,47 @@ func TestRetryJoin(t *testing.T) {
 		t.Fatalf(err.Error())
 	})
 }
+
+func TestCommand_AddedJoinAddrs(t *testing.T) {
+	old := &ServerConfig{
+		StartJoin: []string{"1.1.1.1"},
+		RetryJoin: []string{"2.2.2.2"},
+	}
+	new := &ServerConfig{
+		StartJoin: []string{"1.1.1.1", "3.3.3.3"},
+		RetryJoin: []string{"3.3.3.3", "4.4.4.4"},
+	}
+	added := addedJoinAddrs(old, new)
+	if !reflect.DeepEqual(added, []string{"3.3.3.3", "4.4.4.4"}) {
+		t.Fatalf("bad: %v", added)
+	}
+	if added := addedJoinAddrs(new, old); !reflect.DeepEqual(added, []string{"2.2.2.2"}) {
+		t.Fatalf("bad: %v", added)
+	}
+}
+
+func TestCommand_ReloadRequiresRestart(t *testing.T) {
+	old := DefaultConfig()
+	new := DefaultConfig()
+
+	// The options applied by a reload don't require a restart
+	new.LogLevel = "DEBUG"
+	new.ACL.Enabled = true
+	new.Client.Meta = map[string]string{"rack": "r1"}
+	new.Server.StartJoin = []string{"1.1.1.1"}
+	new.Server.RetryJoin = []string{"2.2.2.2"}
+	new.TLSConfig.CertFile = "cert.pem"
+	if restart := reloadRequiresRestart(old, new); len(restart) != 0 {
+		t.Fatalf("bad: %v", restart)
+	}
+
+	new.Region = "other"
+	new.Ports.HTTP = 1234
+	new.Server.NumSchedulers = 4
+	new.TLSConfig.EnableRPC = true
+	restart := reloadRequiresRestart(old, new)
+	expected := []string{"region", "ports", "server", "tls"}
+	if !reflect.DeepEqual(restart, expected) {
+		t.Fatalf("bad: %v", restart)
+	}
+}
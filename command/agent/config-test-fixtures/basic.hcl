@@ -5,6 +5,7 @@ data_dir = "/tmp/nomad"
 log_level = "ERR"
 bind_addr = "192.168.0.1"
 enable_debug = true
+ui = true
 ports {
 	http = 1234
 	rpc = 2345
@@ -23,6 +24,7 @@ client {
 	enabled = true
 	state_dir = "/tmp/client-state"
 	alloc_dir = "/tmp/alloc"
+	plugin_dir = "/tmp/plugins"
 	servers = ["a.b.c:80", "127.0.0.1:1234"]
 	node_class = "linux-medium-64bit"
 	meta {
@@ -46,9 +48,32 @@ client {
 		iops = 10
 		reserved_ports = "1,100,10-12"
 	}
+	reserve_port_conflicts = true
+	secrets_tmpfs_size = 4
+	local_tmpfs_size = 64
+	memory_pressure {
+		enabled = true
+		threshold = 95
+		action = "evict"
+		victim = "lowest_priority"
+	}
 	client_min_port = 1000
 	client_max_port = 2000
     max_kill_timeout = "10s"
+    cni_path = "/opt/cni/bin"
+    cni_config_dir = "/etc/cni/net.d"
+    bridge_network_name = "nomad0"
+    bridge_network_subnet = "172.27.0.0/16"
+    host_network "public" {
+        interface = "eth1"
+    }
+    host_network "private" {
+        cidr = "10.0.0.0/8"
+    }
+    host_volume "data" {
+        path = "/srv/data"
+        read_only = true
+    }
     stats {
         data_points = 35
         collection_interval = "5s"
@@ -61,13 +86,46 @@ server {
 	protocol_version = 3
 	num_schedulers = 2
 	enabled_schedulers = ["test"]
+	scheduler_mapping {
+		gpu = "batch"
+	}
 	node_gc_threshold = "12h"
+	eval_gc_threshold = "2h"
+	job_gc_threshold = "8h"
 	heartbeat_grace   = "30s"
 	retry_join = [ "1.1.1.1", "2.2.2.2" ]
 	start_join = [ "1.1.1.1", "2.2.2.2" ]
 	retry_max = 3
 	retry_interval = "15s"
 	rejoin_after_leave = true
+	authoritative_region = "foobar"
+	encrypt = "abc"
+	keyring_encryption_key = "def"
+}
+acl {
+	enabled = true
+	replication_token = "foobar"
+}
+audit {
+	enabled = true
+	sink "file" {
+		type = "file"
+		format = "json"
+		path = "/opt/nomad/audit/audit.log"
+		delivery_guarantee = "best-effort"
+		rotate_bytes = 1048576
+		rotate_duration = "24h"
+		rotate_max_files = 10
+	}
+	filter "health" {
+		type = "HTTPEvent"
+		endpoints = ["/v1/agent/health"]
+		stages = ["*"]
+		operations = ["GET"]
+	}
+}
+features {
+	disabled = ["quotas"]
 }
 telemetry {
 	statsite_address = "127.0.0.1:1234"
@@ -76,6 +134,8 @@ telemetry {
     collection_interval = "3s"
     publish_allocation_metrics = true
     publish_node_metrics = true
+    publish_driver_metrics = true
+    driver_metrics_limit = 16
 }
 leave_on_interrupt = true
 leave_on_terminate = true
@@ -120,3 +180,21 @@ vault {
     tls_server_name = "foobar"
     tls_skip_verify = true
 }
+consul {
+    name = "secondary"
+    address = "127.0.0.1:9600"
+}
+vault {
+    name = "secondary"
+    address = "127.0.0.1:9700"
+    enabled = true
+}
+tls {
+    http = true
+    rpc = true
+    verify_server_hostname = true
+    verify_https_client = true
+    ca_file = "foo"
+    cert_file = "bar"
+    key_file = "pipe"
+}
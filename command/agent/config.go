@@ -41,6 +41,9 @@ type Config struct {
 	// EnableDebug is used to enable debugging HTTP endpoints
 	EnableDebug bool `mapstructure:"enable_debug"`
 
+	// UI is used to serve the web UI from the HTTP server
+	UI bool `mapstructure:"ui"`
+
 	// Ports is used to control the network ports we bind to.
 	Ports *Ports `mapstructure:"ports"`
 
@@ -56,9 +59,18 @@ type Config struct {
 	// Server has our server related settings
 	Server *ServerConfig `mapstructure:"server"`
 
+	// ACL has our acl related settings
+	ACL *ACLConfig `mapstructure:"acl"`
+
 	// Telemetry is used to configure sending telemetry
 	Telemetry *Telemetry `mapstructure:"telemetry"`
 
+	// Audit is used to configure the audit logging of requests
+	Audit *AuditConfig `mapstructure:"audit"`
+
+	// Features is used to disable optional subsystems
+	Features *FeaturesConfig `mapstructure:"features"`
+
 	// LeaveOnInt is used to gracefully leave on the interrupt signal
 	LeaveOnInt bool `mapstructure:"leave_on_interrupt"`
 
@@ -92,6 +104,18 @@ type Config struct {
 	// parameters necessary to derive tokens.
 	Vault *config.VaultConfig `mapstructure:"vault"`
 
+	// ConsulClusters are the additional, named Consul clusters tasks may
+	// register their services with. Consul is the default cluster.
+	ConsulClusters map[string]*config.ConsulConfig `mapstructure:"-"`
+
+	// VaultClusters are the additional, named Vault clusters tasks may
+	// derive their tokens from. Vault is the default cluster.
+	VaultClusters map[string]*config.VaultConfig `mapstructure:"-"`
+
+	// TLSConfig provides TLS related configuration for the Nomad server and
+	// client
+	TLSConfig *config.TLSConfig `mapstructure:"tls"`
+
 	// NomadConfig is used to override the default config.
 	// This is largly used for testing purposes.
 	NomadConfig *nomad.Config `mapstructure:"-" json:"-"`
@@ -145,6 +169,9 @@ type ClientConfig struct {
 	// AllocDir is the directory for storing allocation data
 	AllocDir string `mapstructure:"alloc_dir"`
 
+	// PluginDir is the directory holding the external task driver plugins
+	PluginDir string `mapstructure:"plugin_dir"`
+
 	// Servers is a list of known server addresses. These are as "host:port"
 	Servers []string `mapstructure:"servers"`
 
@@ -173,6 +200,28 @@ type ClientConfig struct {
 	// MaxKillTimeout allows capping the user-specifiable KillTimeout.
 	MaxKillTimeout string `mapstructure:"max_kill_timeout"`
 
+	// CNIPath is the list of paths, separated by colons, CNI plugins are
+	// searched in
+	CNIPath string `mapstructure:"cni_path"`
+
+	// CNIConfigDir is the directory CNI network configurations are loaded from
+	CNIConfigDir string `mapstructure:"cni_config_dir"`
+
+	// BridgeNetworkName is the name of the bridge used by allocations in
+	// bridge networking mode
+	BridgeNetworkName string `mapstructure:"bridge_network_name"`
+
+	// BridgeNetworkSubnet is the subnet the addresses of allocations in
+	// bridge networking mode are allocated from
+	BridgeNetworkSubnet string `mapstructure:"bridge_network_subnet"`
+
+	// HostNetworks are the named networks of the host that ports of tasks can
+	// bind to
+	HostNetworks []*HostNetworkConfig `mapstructure:"host_network"`
+
+	// HostVolumes are the named directories of the host that tasks can mount
+	HostVolumes []*HostVolumeConfig `mapstructure:"host_volume"`
+
 	// ClientMaxPort is the upper range of the ports that the client uses for
 	// communicating with plugin subsystems
 	ClientMaxPort int `mapstructure:"client_max_port"`
@@ -185,6 +234,22 @@ type ClientConfig struct {
 	// be used to target a certain utilization or to prevent Nomad from using a
 	// particular set of ports.
 	Reserved *Resources `mapstructure:"reserved"`
+
+	// ReservePortConflicts reserves the static ports that allocations fail to
+	// start with because they are bound by other processes on the host
+	ReservePortConflicts bool `mapstructure:"reserve_port_conflicts"`
+
+	// SecretsTmpfsSize is the size in megabytes of the tmpfs backing the
+	// secrets dir of each task
+	SecretsTmpfsSize int `mapstructure:"secrets_tmpfs_size"`
+
+	// LocalTmpfsSize is the size in megabytes of the tmpfs backing the local
+	// dir of each task. If zero, the local dir is kept on disk.
+	LocalTmpfsSize int `mapstructure:"local_tmpfs_size"`
+
+	// MemoryPressure configures how the client relieves memory pressure on
+	// the host
+	MemoryPressure *MemoryPressureConfig `mapstructure:"memory_pressure"`
 }
 
 // ServerConfig is configuration specific to the server mode
@@ -213,9 +278,22 @@ type ServerConfig struct {
 	// that the workers dequeue for processing.
 	EnabledSchedulers []string `mapstructure:"enabled_schedulers"`
 
+	// SchedulerMapping maps job types to the name of the scheduler that
+	// processes their evaluations, which may be a scheduler compiled into
+	// Nomad in addition to the built in ones.
+	SchedulerMapping map[string]string `mapstructure:"scheduler_mapping"`
+
 	// NodeGCThreshold controls how "old" a node must be to be collected by GC.
 	NodeGCThreshold string `mapstructure:"node_gc_threshold"`
 
+	// EvalGCThreshold controls how "old" an evaluation must be to be
+	// collected by GC, along with its terminal allocations.
+	EvalGCThreshold string `mapstructure:"eval_gc_threshold"`
+
+	// JobGCThreshold controls how "old" a dead job must be to be collected
+	// by GC.
+	JobGCThreshold string `mapstructure:"job_gc_threshold"`
+
 	// HeartbeatGrace is the grace period beyond the TTL to account for network,
 	// processing delays and clock skew before marking a node as "down".
 	HeartbeatGrace string `mapstructure:"heartbeat_grace"`
@@ -244,6 +322,95 @@ type ServerConfig struct {
 	// the cluster until an explicit join is received. If this is set to
 	// true, we ignore the leave, and rejoin the cluster on start.
 	RejoinAfterLeave bool `mapstructure:"rejoin_after_leave"`
+
+	// AuthoritativeRegion is used to control which region is treated as
+	// the source of truth for global tokens and ACL policies.
+	AuthoritativeRegion string `mapstructure:"authoritative_region"`
+
+	// EncryptKey is the base64 encoded key used to encrypt the gossip
+	// traffic between servers. It is only used to initialize the keyring
+	// when the data directory doesn't hold one yet.
+	EncryptKey string `mapstructure:"encrypt" json:"-"`
+
+	// KeyringEncryptionKey is the base64 encoded AES-256 key wrapping the
+	// root keys that encrypt the variables. It must be the same on all the
+	// servers of the region and is never replicated.
+	KeyringEncryptionKey string `mapstructure:"keyring_encryption_key" json:"-"`
+}
+
+// ACLConfig is configuration specific to the ACL system
+type ACLConfig struct {
+	// Enabled controls if we are enforcing and managing ACLs
+	Enabled bool `mapstructure:"enabled"`
+
+	// ReplicationToken is used by servers to replicate tokens and policies
+	// from the authoritative region. This must be a valid management token
+	// within the authoritative region.
+	ReplicationToken string `mapstructure:"replication_token"`
+}
+
+// AuditConfig is the configuration of the audit logging of the requests made
+// to the HTTP API of the agent and the RPC endpoints of the server
+type AuditConfig struct {
+	// Enabled controls if requests are audited
+	Enabled bool `mapstructure:"enabled"`
+
+	// Sinks are where the audit events are written to. A file sink in the
+	// data directory is used if none is given.
+	Sinks []*AuditSink `mapstructure:"-"`
+
+	// Filters exclude the audit events they match
+	Filters []*AuditFilter `mapstructure:"-"`
+}
+
+// FeaturesConfig is the configuration of the optional subsystems of the
+// agent. Every feature compiled into the binary is enabled unless disabled.
+type FeaturesConfig struct {
+	// Disabled are the names of the features to disable
+	Disabled []string `mapstructure:"disabled"`
+}
+
+// AuditSink is the configuration of a sink of audit events
+type AuditSink struct {
+	// Name is the name of the sink, given as the label of its block
+	Name string `mapstructure:"-"`
+
+	// Type is the type of the sink. Defaults to "file".
+	Type string `mapstructure:"type"`
+
+	// Format is the format of the events. Defaults to "json".
+	Format string `mapstructure:"format"`
+
+	// Path is the file the events are written to. Defaults to
+	// "audit/audit.log" in the data directory.
+	Path string `mapstructure:"path"`
+
+	// DeliveryGuarantee is "enforced" to fail requests whose events can't
+	// be written, or "best-effort". Defaults to "enforced".
+	DeliveryGuarantee string `mapstructure:"delivery_guarantee"`
+
+	// RotateBytes, RotateDuration and RotateMaxFiles control the rotation
+	// of the file and how many rotated files are kept
+	RotateBytes    int64         `mapstructure:"rotate_bytes"`
+	RotateDuration string        `mapstructure:"rotate_duration"`
+	rotateDuration time.Duration `mapstructure:"-"`
+	RotateMaxFiles int           `mapstructure:"rotate_max_files"`
+}
+
+// AuditFilter is the configuration of a filter excluding audit events
+type AuditFilter struct {
+	// Name is the name of the filter, given as the label of its block
+	Name string `mapstructure:"-"`
+
+	// Type is the type of the events to exclude, "HTTPEvent", "RPCEvent"
+	// or "*"
+	Type string `mapstructure:"type"`
+
+	// Endpoints, Stages and Operations are the endpoints, stages and
+	// operations of the events to exclude
+	Endpoints  []string `mapstructure:"endpoints"`
+	Stages     []string `mapstructure:"stages"`
+	Operations []string `mapstructure:"operations"`
 }
 
 // Telemetry is the telemetry configuration for the server
@@ -255,6 +422,8 @@ type Telemetry struct {
 	collectionInterval       time.Duration `mapstructure:"-"`
 	PublishAllocationMetrics bool          `mapstructure:"publish_allocation_metrics"`
 	PublishNodeMetrics       bool          `mapstructure:"publish_node_metrics"`
+	PublishDriverMetrics     bool          `mapstructure:"publish_driver_metrics"`
+	DriverMetricsLimit       int           `mapstructure:"driver_metrics_limit"`
 
 	// Circonus: see https://github.com/circonus-labs/circonus-gometrics
 	// for more details on the various configuration options.
@@ -346,6 +515,57 @@ type AdvertiseAddrs struct {
 	Serf string `mapstructure:"serf"`
 }
 
+// HostNetworkConfig is a named network of the host. The address of the network
+// is looked up on the interface or by the CIDR it is in.
+type HostNetworkConfig struct {
+	Name      string `mapstructure:"-"`
+	CIDR      string `mapstructure:"cidr"`
+	Interface string `mapstructure:"interface"`
+}
+
+// HostVolumeConfig is a named directory of the host that task groups can
+// request as a volume
+type HostVolumeConfig struct {
+	Name     string `mapstructure:"-"`
+	Path     string `mapstructure:"path"`
+	ReadOnly bool   `mapstructure:"read_only"`
+}
+
+// MemoryPressureConfig configures the restart or eviction of allocations when
+// the memory in use on the host crosses a threshold.
+type MemoryPressureConfig struct {
+	// Enabled enables the protection against memory pressure
+	Enabled bool `mapstructure:"enabled"`
+
+	// Threshold is the percentage of the host memory in use above which
+	// allocations are acted on
+	Threshold float64 `mapstructure:"threshold"`
+
+	// Action is either "restart" or "evict"
+	Action string `mapstructure:"action"`
+
+	// Victim is either "over_limit" or "lowest_priority"
+	Victim string `mapstructure:"victim"`
+}
+
+// Merge is used to merge two memory pressure configs together
+func (m *MemoryPressureConfig) Merge(b *MemoryPressureConfig) *MemoryPressureConfig {
+	result := *m
+	if b.Enabled {
+		result.Enabled = true
+	}
+	if b.Threshold != 0 {
+		result.Threshold = b.Threshold
+	}
+	if b.Action != "" {
+		result.Action = b.Action
+	}
+	if b.Victim != "" {
+		result.Victim = b.Victim
+	}
+	return &result
+}
+
 type Resources struct {
 	CPU                 int    `mapstructure:"cpu"`
 	MemoryMB            int    `mapstructure:"memory"`
@@ -453,6 +673,7 @@ func DefaultConfig() *Config {
 		Atlas:          &AtlasConfig{},
 		Consul:         config.DefaultConsulConfig(),
 		Vault:          config.DefaultVaultConfig(),
+		TLSConfig:      &config.TLSConfig{},
 		Client: &ClientConfig{
 			Enabled:        false,
 			NetworkSpeed:   100,
@@ -460,6 +681,11 @@ func DefaultConfig() *Config {
 			ClientMinPort:  14000,
 			ClientMaxPort:  14512,
 			Reserved:       &Resources{},
+			MemoryPressure: &MemoryPressureConfig{
+				Threshold: 90,
+				Action:    "restart",
+				Victim:    "over_limit",
+			},
 		},
 		Server: &ServerConfig{
 			Enabled:          false,
@@ -468,6 +694,13 @@ func DefaultConfig() *Config {
 			RetryInterval:    "30s",
 			RetryMaxAttempts: 0,
 		},
+		ACL: &ACLConfig{
+			Enabled: false,
+		},
+		Audit: &AuditConfig{
+			Enabled: false,
+		},
+		Features:       &FeaturesConfig{},
 		SyslogFacility: "LOCAL0",
 		Telemetry: &Telemetry{
 			CollectionInterval: "1s",
@@ -526,6 +759,9 @@ func (c *Config) Merge(b *Config) *Config {
 	if b.EnableDebug {
 		result.EnableDebug = true
 	}
+	if b.UI {
+		result.UI = true
+	}
 	if b.LeaveOnInt {
 		result.LeaveOnInt = true
 	}
@@ -569,6 +805,30 @@ func (c *Config) Merge(b *Config) *Config {
 		result.Server = result.Server.Merge(b.Server)
 	}
 
+	// Apply the acl config
+	if result.ACL == nil && b.ACL != nil {
+		acl := *b.ACL
+		result.ACL = &acl
+	} else if b.ACL != nil {
+		result.ACL = result.ACL.Merge(b.ACL)
+	}
+
+	// Apply the audit config
+	if result.Audit == nil && b.Audit != nil {
+		audit := *b.Audit
+		result.Audit = &audit
+	} else if b.Audit != nil {
+		result.Audit = result.Audit.Merge(b.Audit)
+	}
+
+	// Apply the features config
+	if result.Features == nil && b.Features != nil {
+		features := *b.Features
+		result.Features = &features
+	} else if b.Features != nil {
+		result.Features = result.Features.Merge(b.Features)
+	}
+
 	// Apply the ports config
 	if result.Ports == nil && b.Ports != nil {
 		ports := *b.Ports
@@ -617,6 +877,46 @@ func (c *Config) Merge(b *Config) *Config {
 		result.Vault = result.Vault.Merge(b.Vault)
 	}
 
+	// Apply the named Consul clusters
+	if len(b.ConsulClusters) != 0 {
+		clusters := make(map[string]*config.ConsulConfig, len(result.ConsulClusters)+len(b.ConsulClusters))
+		for name, c := range result.ConsulClusters {
+			clusters[name] = c
+		}
+		for name, c := range b.ConsulClusters {
+			if existing, ok := clusters[name]; ok {
+				clusters[name] = existing.Merge(c)
+			} else {
+				clusters[name] = c.Copy()
+			}
+		}
+		result.ConsulClusters = clusters
+	}
+
+	// Apply the named Vault clusters
+	if len(b.VaultClusters) != 0 {
+		clusters := make(map[string]*config.VaultConfig, len(result.VaultClusters)+len(b.VaultClusters))
+		for name, c := range result.VaultClusters {
+			clusters[name] = c
+		}
+		for name, c := range b.VaultClusters {
+			if existing, ok := clusters[name]; ok {
+				clusters[name] = existing.Merge(c)
+			} else {
+				clusters[name] = c.Copy()
+			}
+		}
+		result.VaultClusters = clusters
+	}
+
+	// Apply the TLS Config
+	if result.TLSConfig == nil && b.TLSConfig != nil {
+		tlsConfig := *b.TLSConfig
+		result.TLSConfig = &tlsConfig
+	} else if b.TLSConfig != nil {
+		result.TLSConfig = result.TLSConfig.Merge(b.TLSConfig)
+	}
+
 	// Merge config files lists
 	result.Files = append(result.Files, b.Files...)
 
@@ -653,6 +953,12 @@ func (a *ServerConfig) Merge(b *ServerConfig) *ServerConfig {
 	if b.NodeGCThreshold != "" {
 		result.NodeGCThreshold = b.NodeGCThreshold
 	}
+	if b.EvalGCThreshold != "" {
+		result.EvalGCThreshold = b.EvalGCThreshold
+	}
+	if b.JobGCThreshold != "" {
+		result.JobGCThreshold = b.JobGCThreshold
+	}
 	if b.HeartbeatGrace != "" {
 		result.HeartbeatGrace = b.HeartbeatGrace
 	}
@@ -666,10 +972,31 @@ func (a *ServerConfig) Merge(b *ServerConfig) *ServerConfig {
 	if b.RejoinAfterLeave {
 		result.RejoinAfterLeave = true
 	}
+	if b.AuthoritativeRegion != "" {
+		result.AuthoritativeRegion = b.AuthoritativeRegion
+	}
+	if b.EncryptKey != "" {
+		result.EncryptKey = b.EncryptKey
+	}
+	if b.KeyringEncryptionKey != "" {
+		result.KeyringEncryptionKey = b.KeyringEncryptionKey
+	}
 
 	// Add the schedulers
 	result.EnabledSchedulers = append(result.EnabledSchedulers, b.EnabledSchedulers...)
 
+	// Merge the scheduler mappings
+	if len(b.SchedulerMapping) != 0 {
+		mapping := make(map[string]string, len(a.SchedulerMapping)+len(b.SchedulerMapping))
+		for jobType, name := range a.SchedulerMapping {
+			mapping[jobType] = name
+		}
+		for jobType, name := range b.SchedulerMapping {
+			mapping[jobType] = name
+		}
+		result.SchedulerMapping = mapping
+	}
+
 	// Copy the start join addresses
 	result.StartJoin = make([]string, 0, len(a.StartJoin)+len(b.StartJoin))
 	result.StartJoin = append(result.StartJoin, a.StartJoin...)
@@ -683,6 +1010,75 @@ func (a *ServerConfig) Merge(b *ServerConfig) *ServerConfig {
 	return &result
 }
 
+// Merge is used to merge two ACL configs together
+func (a *ACLConfig) Merge(b *ACLConfig) *ACLConfig {
+	result := *a
+
+	if b.Enabled {
+		result.Enabled = true
+	}
+	if b.ReplicationToken != "" {
+		result.ReplicationToken = b.ReplicationToken
+	}
+	return &result
+}
+
+// Merge is used to merge two features configs together. A feature disabled
+// by either config is disabled.
+func (f *FeaturesConfig) Merge(b *FeaturesConfig) *FeaturesConfig {
+	result := *f
+
+	result.Disabled = nil
+	seen := make(map[string]struct{})
+	for _, list := range [][]string{f.Disabled, b.Disabled} {
+		for _, name := range list {
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			result.Disabled = append(result.Disabled, name)
+		}
+	}
+	return &result
+}
+
+// Merge is used to merge two audit configs together. Sinks and filters are
+// merged by name, later ones replacing earlier ones of the same name.
+func (a *AuditConfig) Merge(b *AuditConfig) *AuditConfig {
+	result := *a
+
+	if b.Enabled {
+		result.Enabled = true
+	}
+
+	result.Sinks = nil
+	sinks := make(map[string]int)
+	for _, list := range [][]*AuditSink{a.Sinks, b.Sinks} {
+		for _, s := range list {
+			if i, ok := sinks[s.Name]; ok {
+				result.Sinks[i] = s
+				continue
+			}
+			sinks[s.Name] = len(result.Sinks)
+			result.Sinks = append(result.Sinks, s)
+		}
+	}
+
+	result.Filters = nil
+	filters := make(map[string]int)
+	for _, list := range [][]*AuditFilter{a.Filters, b.Filters} {
+		for _, f := range list {
+			if i, ok := filters[f.Name]; ok {
+				result.Filters[i] = f
+				continue
+			}
+			filters[f.Name] = len(result.Filters)
+			result.Filters = append(result.Filters, f)
+		}
+	}
+	return &result
+}
+
 // Merge is used to merge two client configs together
 func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 	result := *a
@@ -696,6 +1092,9 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 	if b.AllocDir != "" {
 		result.AllocDir = b.AllocDir
 	}
+	if b.PluginDir != "" {
+		result.PluginDir = b.PluginDir
+	}
 	if b.NodeClass != "" {
 		result.NodeClass = b.NodeClass
 	}
@@ -708,6 +1107,18 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 	if b.MaxKillTimeout != "" {
 		result.MaxKillTimeout = b.MaxKillTimeout
 	}
+	if b.CNIPath != "" {
+		result.CNIPath = b.CNIPath
+	}
+	if b.CNIConfigDir != "" {
+		result.CNIConfigDir = b.CNIConfigDir
+	}
+	if b.BridgeNetworkName != "" {
+		result.BridgeNetworkName = b.BridgeNetworkName
+	}
+	if b.BridgeNetworkSubnet != "" {
+		result.BridgeNetworkSubnet = b.BridgeNetworkSubnet
+	}
 	if b.ClientMaxPort != 0 {
 		result.ClientMaxPort = b.ClientMaxPort
 	}
@@ -717,6 +1128,53 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 	if b.Reserved != nil {
 		result.Reserved = result.Reserved.Merge(b.Reserved)
 	}
+	if b.ReservePortConflicts {
+		result.ReservePortConflicts = true
+	}
+	if b.SecretsTmpfsSize != 0 {
+		result.SecretsTmpfsSize = b.SecretsTmpfsSize
+	}
+	if b.LocalTmpfsSize != 0 {
+		result.LocalTmpfsSize = b.LocalTmpfsSize
+	}
+	if result.MemoryPressure == nil && b.MemoryPressure != nil {
+		memoryPressure := *b.MemoryPressure
+		result.MemoryPressure = &memoryPressure
+	} else if b.MemoryPressure != nil {
+		result.MemoryPressure = result.MemoryPressure.Merge(b.MemoryPressure)
+	}
+
+	// Host networks are replaced by name
+	result.HostNetworks = append([]*HostNetworkConfig(nil), a.HostNetworks...)
+	for _, n := range b.HostNetworks {
+		replaced := false
+		for i, existing := range result.HostNetworks {
+			if existing.Name == n.Name {
+				result.HostNetworks[i] = n
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result.HostNetworks = append(result.HostNetworks, n)
+		}
+	}
+
+	// Host volumes are replaced by name
+	result.HostVolumes = append([]*HostVolumeConfig(nil), a.HostVolumes...)
+	for _, v := range b.HostVolumes {
+		replaced := false
+		for i, existing := range result.HostVolumes {
+			if existing.Name == v.Name {
+				result.HostVolumes[i] = v
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result.HostVolumes = append(result.HostVolumes, v)
+		}
+	}
 
 	// Add the servers
 	result.Servers = append(result.Servers, b.Servers...)
@@ -773,6 +1231,12 @@ func (a *Telemetry) Merge(b *Telemetry) *Telemetry {
 	if b.PublishAllocationMetrics {
 		result.PublishAllocationMetrics = true
 	}
+	if b.PublishDriverMetrics {
+		result.PublishDriverMetrics = true
+	}
+	if b.DriverMetricsLimit != 0 {
+		result.DriverMetricsLimit = b.DriverMetricsLimit
+	}
 	if b.CirconusAPIToken != "" {
 		result.CirconusAPIToken = b.CirconusAPIToken
 	}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"time"
@@ -11,6 +12,8 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
+	clientconfig "github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/mitchellh/mapstructure"
 )
@@ -78,12 +81,16 @@ func parseConfig(result *Config, list *ast.ObjectList) error {
 		"log_level",
 		"bind_addr",
 		"enable_debug",
+		"ui",
 		"ports",
 		"addresses",
 		"interfaces",
 		"advertise",
 		"client",
 		"server",
+		"acl",
+		"audit",
+		"features",
 		"telemetry",
 		"leave_on_interrupt",
 		"leave_on_terminate",
@@ -94,6 +101,7 @@ func parseConfig(result *Config, list *ast.ObjectList) error {
 		"atlas",
 		"consul",
 		"vault",
+		"tls",
 		"http_api_response_headers",
 	}
 	if err := checkHCLKeys(list, valid); err != nil {
@@ -111,10 +119,14 @@ func parseConfig(result *Config, list *ast.ObjectList) error {
 	delete(m, "advertise")
 	delete(m, "client")
 	delete(m, "server")
+	delete(m, "acl")
+	delete(m, "audit")
+	delete(m, "features")
 	delete(m, "telemetry")
 	delete(m, "atlas")
 	delete(m, "consul")
 	delete(m, "vault")
+	delete(m, "tls")
 	delete(m, "http_api_response_headers")
 
 	// Decode the rest
@@ -157,6 +169,27 @@ func parseConfig(result *Config, list *ast.ObjectList) error {
 		}
 	}
 
+	// Parse ACL config
+	if o := list.Filter("acl"); len(o.Items) > 0 {
+		if err := parseACL(&result.ACL, o); err != nil {
+			return multierror.Prefix(err, "acl ->")
+		}
+	}
+
+	// Parse audit config
+	if o := list.Filter("audit"); len(o.Items) > 0 {
+		if err := parseAudit(&result.Audit, o); err != nil {
+			return multierror.Prefix(err, "audit ->")
+		}
+	}
+
+	// Parse features config
+	if o := list.Filter("features"); len(o.Items) > 0 {
+		if err := parseFeatures(&result.Features, o); err != nil {
+			return multierror.Prefix(err, "features ->")
+		}
+	}
+
 	// Parse telemetry config
 	if o := list.Filter("telemetry"); len(o.Items) > 0 {
 		if err := parseTelemetry(&result.Telemetry, o); err != nil {
@@ -173,18 +206,25 @@ func parseConfig(result *Config, list *ast.ObjectList) error {
 
 	// Parse the consul config
 	if o := list.Filter("consul"); len(o.Items) > 0 {
-		if err := parseConsulConfig(&result.Consul, o); err != nil {
+		if err := parseConsulConfig(&result.Consul, &result.ConsulClusters, o); err != nil {
 			return multierror.Prefix(err, "consul ->")
 		}
 	}
 
 	// Parse the vault config
 	if o := list.Filter("vault"); len(o.Items) > 0 {
-		if err := parseVaultConfig(&result.Vault, o); err != nil {
+		if err := parseVaultConfig(&result.Vault, &result.VaultClusters, o); err != nil {
 			return multierror.Prefix(err, "vault ->")
 		}
 	}
 
+	// Parse the TLS config
+	if o := list.Filter("tls"); len(o.Items) > 0 {
+		if err := parseTLSConfig(&result.TLSConfig, o); err != nil {
+			return multierror.Prefix(err, "tls ->")
+		}
+	}
+
 	// Parse out http_api_response_headers fields. These are in HCL as a list so
 	// we need to iterate over them and merge them.
 	if headersO := list.Filter("http_api_response_headers"); len(headersO.Items) > 0 {
@@ -320,6 +360,7 @@ func parseClient(result **ClientConfig, list *ast.ObjectList) error {
 		"enabled",
 		"state_dir",
 		"alloc_dir",
+		"plugin_dir",
 		"servers",
 		"node_class",
 		"options",
@@ -328,9 +369,19 @@ func parseClient(result **ClientConfig, list *ast.ObjectList) error {
 		"network_interface",
 		"network_speed",
 		"max_kill_timeout",
+		"cni_path",
+		"cni_config_dir",
+		"bridge_network_name",
+		"bridge_network_subnet",
+		"host_network",
+		"host_volume",
 		"client_max_port",
 		"client_min_port",
 		"reserved",
+		"reserve_port_conflicts",
+		"secrets_tmpfs_size",
+		"local_tmpfs_size",
+		"memory_pressure",
 		"stats",
 	}
 	if err := checkHCLKeys(listVal, valid); err != nil {
@@ -346,6 +397,9 @@ func parseClient(result **ClientConfig, list *ast.ObjectList) error {
 	delete(m, "meta")
 	delete(m, "chroot_env")
 	delete(m, "reserved")
+	delete(m, "host_network")
+	delete(m, "host_volume")
+	delete(m, "memory_pressure")
 	delete(m, "stats")
 
 	var config ClientConfig
@@ -402,10 +456,165 @@ func parseClient(result **ClientConfig, list *ast.ObjectList) error {
 		}
 	}
 
+	// Parse host networks
+	if o := listVal.Filter("host_network"); len(o.Items) > 0 {
+		if err := parseHostNetworks(&config.HostNetworks, o); err != nil {
+			return multierror.Prefix(err, "host_network ->")
+		}
+	}
+
+	// Parse host volumes
+	if o := listVal.Filter("host_volume"); len(o.Items) > 0 {
+		if err := parseHostVolumes(&config.HostVolumes, o); err != nil {
+			return multierror.Prefix(err, "host_volume ->")
+		}
+	}
+
+	// Parse memory pressure config
+	if o := listVal.Filter("memory_pressure"); len(o.Items) > 0 {
+		if err := parseMemoryPressure(&config.MemoryPressure, o); err != nil {
+			return multierror.Prefix(err, "memory_pressure ->")
+		}
+	}
+
 	*result = &config
 	return nil
 }
 
+func parseMemoryPressure(result **MemoryPressureConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'memory_pressure' block allowed")
+	}
+
+	// Get our memory pressure object
+	obj := list.Items[0]
+
+	// Check for invalid keys
+	valid := []string{
+		"enabled",
+		"threshold",
+		"action",
+		"victim",
+	}
+	if err := checkHCLKeys(obj.Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, obj.Val); err != nil {
+		return err
+	}
+
+	var memoryPressure MemoryPressureConfig
+	if err := mapstructure.WeakDecode(m, &memoryPressure); err != nil {
+		return err
+	}
+
+	if memoryPressure.Threshold < 0 || memoryPressure.Threshold > 100 {
+		return fmt.Errorf("threshold must be a percentage between 0 and 100")
+	}
+	switch memoryPressure.Action {
+	case "", clientconfig.MemoryPressureActionRestart, clientconfig.MemoryPressureActionEvict:
+	default:
+		return fmt.Errorf("invalid action %q: must be %q or %q", memoryPressure.Action,
+			clientconfig.MemoryPressureActionRestart, clientconfig.MemoryPressureActionEvict)
+	}
+	switch memoryPressure.Victim {
+	case "", clientconfig.MemoryPressureVictimOverLimit, clientconfig.MemoryPressureVictimLowestPriority:
+	default:
+		return fmt.Errorf("invalid victim %q: must be %q or %q", memoryPressure.Victim,
+			clientconfig.MemoryPressureVictimOverLimit, clientconfig.MemoryPressureVictimLowestPriority)
+	}
+
+	*result = &memoryPressure
+	return nil
+}
+
+func parseHostNetworks(result *[]*HostNetworkConfig, list *ast.ObjectList) error {
+	seen := make(map[string]struct{})
+	for _, item := range list.Items {
+		if len(item.Keys) != 1 {
+			return fmt.Errorf("host_network must be named")
+		}
+		name := item.Keys[0].Token.Value().(string)
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("host_network %q defined more than once", name)
+		}
+		seen[name] = struct{}{}
+
+		// Check for invalid keys
+		valid := []string{
+			"cidr",
+			"interface",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%q ->", name))
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return err
+		}
+
+		network := HostNetworkConfig{Name: name}
+		if err := mapstructure.WeakDecode(m, &network); err != nil {
+			return err
+		}
+		if network.CIDR == "" && network.Interface == "" {
+			return fmt.Errorf("host_network %q must specify a cidr or an interface", name)
+		}
+		if network.CIDR != "" {
+			if _, _, err := net.ParseCIDR(network.CIDR); err != nil {
+				return fmt.Errorf("host_network %q has an invalid cidr: %v", name, err)
+			}
+		}
+		*result = append(*result, &network)
+	}
+	return nil
+}
+
+func parseHostVolumes(result *[]*HostVolumeConfig, list *ast.ObjectList) error {
+	seen := make(map[string]struct{})
+	for _, item := range list.Items {
+		if len(item.Keys) != 1 {
+			return fmt.Errorf("host_volume must be named")
+		}
+		name := item.Keys[0].Token.Value().(string)
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("host_volume %q defined more than once", name)
+		}
+		seen[name] = struct{}{}
+
+		// Check for invalid keys
+		valid := []string{
+			"path",
+			"read_only",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%q ->", name))
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return err
+		}
+
+		volume := HostVolumeConfig{Name: name}
+		if err := mapstructure.WeakDecode(m, &volume); err != nil {
+			return err
+		}
+		if volume.Path == "" {
+			return fmt.Errorf("host_volume %q must specify a path", name)
+		}
+		if !filepath.IsAbs(volume.Path) {
+			return fmt.Errorf("host_volume %q path %q must be absolute", name, volume.Path)
+		}
+		*result = append(*result, &volume)
+	}
+	return nil
+}
+
 func parseReserved(result **Resources, list *ast.ObjectList) error {
 	list = list.Elem()
 	if len(list.Items) > 1 {
@@ -477,13 +686,19 @@ func parseServer(result **ServerConfig, list *ast.ObjectList) error {
 		"protocol_version",
 		"num_schedulers",
 		"enabled_schedulers",
+		"scheduler_mapping",
 		"node_gc_threshold",
+		"eval_gc_threshold",
+		"job_gc_threshold",
 		"heartbeat_grace",
 		"start_join",
 		"retry_join",
 		"retry_max",
 		"retry_interval",
 		"rejoin_after_leave",
+		"authoritative_region",
+		"encrypt",
+		"keyring_encryption_key",
 	}
 	if err := checkHCLKeys(listVal, valid); err != nil {
 		return err
@@ -494,11 +709,219 @@ func parseServer(result **ServerConfig, list *ast.ObjectList) error {
 		return err
 	}
 
+	delete(m, "scheduler_mapping")
+
 	var config ServerConfig
 	if err := mapstructure.WeakDecode(m, &config); err != nil {
 		return err
 	}
 
+	// Parse out scheduler_mapping fields. These are in HCL as a list so we
+	// need to iterate over them and merge them.
+	if mappingO := listVal.Filter("scheduler_mapping"); len(mappingO.Items) > 0 {
+		for _, o := range mappingO.Elem().Items {
+			var m map[string]interface{}
+			if err := hcl.DecodeObject(&m, o.Val); err != nil {
+				return err
+			}
+			if err := mapstructure.WeakDecode(m, &config.SchedulerMapping); err != nil {
+				return err
+			}
+		}
+	}
+
+	*result = &config
+	return nil
+}
+
+func parseACL(result **ACLConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'acl' block allowed")
+	}
+
+	// Get our acl object
+	obj := list.Items[0]
+
+	// Value should be an object
+	var listVal *ast.ObjectList
+	if ot, ok := obj.Val.(*ast.ObjectType); ok {
+		listVal = ot.List
+	} else {
+		return fmt.Errorf("acl value: should be an object")
+	}
+
+	// Check for invalid keys
+	valid := []string{
+		"enabled",
+		"replication_token",
+	}
+	if err := checkHCLKeys(listVal, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, listVal); err != nil {
+		return err
+	}
+
+	var config ACLConfig
+	if err := mapstructure.WeakDecode(m, &config); err != nil {
+		return err
+	}
+
+	*result = &config
+	return nil
+}
+
+func parseFeatures(result **FeaturesConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'features' block allowed")
+	}
+
+	// Get our features object
+	obj := list.Items[0]
+
+	// Value should be an object
+	var listVal *ast.ObjectList
+	if ot, ok := obj.Val.(*ast.ObjectType); ok {
+		listVal = ot.List
+	} else {
+		return fmt.Errorf("features value: should be an object")
+	}
+
+	// Check for invalid keys
+	valid := []string{
+		"disabled",
+	}
+	if err := checkHCLKeys(listVal, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, listVal); err != nil {
+		return err
+	}
+
+	var config FeaturesConfig
+	if err := mapstructure.WeakDecode(m, &config); err != nil {
+		return err
+	}
+
+	*result = &config
+	return nil
+}
+
+func parseAudit(result **AuditConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'audit' block allowed")
+	}
+
+	// Get our audit object
+	obj := list.Items[0]
+
+	// Value should be an object
+	var listVal *ast.ObjectList
+	if ot, ok := obj.Val.(*ast.ObjectType); ok {
+		listVal = ot.List
+	} else {
+		return fmt.Errorf("audit value: should be an object")
+	}
+
+	// Check for invalid keys
+	valid := []string{
+		"enabled",
+		"sink",
+		"filter",
+	}
+	if err := checkHCLKeys(listVal, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, listVal); err != nil {
+		return err
+	}
+	delete(m, "sink")
+	delete(m, "filter")
+
+	var config AuditConfig
+	if err := mapstructure.WeakDecode(m, &config); err != nil {
+		return err
+	}
+
+	// Parse the sinks
+	if o := listVal.Filter("sink"); len(o.Items) > 0 {
+		for _, item := range o.Items {
+			if len(item.Keys) != 1 {
+				return fmt.Errorf("sink: should have exactly one name")
+			}
+			name := item.Keys[0].Token.Value().(string)
+
+			valid := []string{
+				"type",
+				"format",
+				"path",
+				"delivery_guarantee",
+				"rotate_bytes",
+				"rotate_duration",
+				"rotate_max_files",
+			}
+			if err := checkHCLKeys(item.Val, valid); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("sink %q ->", name))
+			}
+
+			var m map[string]interface{}
+			if err := hcl.DecodeObject(&m, item.Val); err != nil {
+				return err
+			}
+			sink := &AuditSink{Name: name}
+			if err := mapstructure.WeakDecode(m, sink); err != nil {
+				return err
+			}
+			if sink.RotateDuration != "" {
+				dur, err := time.ParseDuration(sink.RotateDuration)
+				if err != nil {
+					return fmt.Errorf("sink %q: error parsing value of %q: %v", name, "rotate_duration", err)
+				}
+				sink.rotateDuration = dur
+			}
+			config.Sinks = append(config.Sinks, sink)
+		}
+	}
+
+	// Parse the filters
+	if o := listVal.Filter("filter"); len(o.Items) > 0 {
+		for _, item := range o.Items {
+			if len(item.Keys) != 1 {
+				return fmt.Errorf("filter: should have exactly one name")
+			}
+			name := item.Keys[0].Token.Value().(string)
+
+			valid := []string{
+				"type",
+				"endpoints",
+				"stages",
+				"operations",
+			}
+			if err := checkHCLKeys(item.Val, valid); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("filter %q ->", name))
+			}
+
+			var m map[string]interface{}
+			if err := hcl.DecodeObject(&m, item.Val); err != nil {
+				return err
+			}
+			filter := &AuditFilter{Name: name}
+			if err := mapstructure.WeakDecode(m, filter); err != nil {
+				return err
+			}
+			config.Filters = append(config.Filters, filter)
+		}
+	}
+
 	*result = &config
 	return nil
 }
@@ -520,6 +943,8 @@ func parseTelemetry(result **Telemetry, list *ast.ObjectList) error {
 		"collection_interval",
 		"publish_allocation_metrics",
 		"publish_node_metrics",
+		"publish_driver_metrics",
+		"driver_metrics_limit",
 		"circonus_api_token",
 		"circonus_api_app",
 		"circonus_api_url",
@@ -589,15 +1014,39 @@ func parseAtlas(result **AtlasConfig, list *ast.ObjectList) error {
 	return nil
 }
 
-func parseConsulConfig(result **config.ConsulConfig, list *ast.ObjectList) error {
+func parseConsulConfig(result **config.ConsulConfig, clusters *map[string]*config.ConsulConfig, list *ast.ObjectList) error {
 	list = list.Elem()
-	if len(list.Items) > 1 {
-		return fmt.Errorf("only one 'consul' block allowed")
-	}
 
-	// Get our Consul object
-	listVal := list.Items[0].Val
+	// Blocks without a name configure the default cluster, the others a
+	// named cluster
+	seenDefault := false
+	for _, item := range list.Items {
+		c, err := parseConsulBlock(item.Val)
+		if err != nil {
+			return err
+		}
 
+		if c.Name == "" || c.Name == structs.DefaultCluster {
+			if seenDefault {
+				return fmt.Errorf("only one 'consul' block allowed for the default cluster")
+			}
+			seenDefault = true
+			*result = c
+			continue
+		}
+
+		if *clusters == nil {
+			*clusters = make(map[string]*config.ConsulConfig)
+		}
+		if _, ok := (*clusters)[c.Name]; ok {
+			return fmt.Errorf("only one 'consul' block allowed for cluster %q", c.Name)
+		}
+		(*clusters)[c.Name] = c
+	}
+	return nil
+}
+
+func parseConsulBlock(listVal ast.Node) (*config.ConsulConfig, error) {
 	// Check for invalid keys
 	valid := []string{
 		"address",
@@ -608,6 +1057,7 @@ func parseConsulConfig(result **config.ConsulConfig, list *ast.ObjectList) error
 		"client_auto_join",
 		"client_service_name",
 		"key_file",
+		"name",
 		"server_auto_join",
 		"server_service_name",
 		"ssl",
@@ -617,12 +1067,12 @@ func parseConsulConfig(result **config.ConsulConfig, list *ast.ObjectList) error
 	}
 
 	if err := checkHCLKeys(listVal, valid); err != nil {
-		return err
+		return nil, err
 	}
 
 	var m map[string]interface{}
 	if err := hcl.DecodeObject(&m, listVal); err != nil {
-		return err
+		return nil, err
 	}
 
 	consulConfig := config.DefaultConsulConfig()
@@ -632,30 +1082,54 @@ func parseConsulConfig(result **config.ConsulConfig, list *ast.ObjectList) error
 		Result:           &consulConfig,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if err := dec.Decode(m); err != nil {
-		return err
+		return nil, err
 	}
 
-	*result = consulConfig
-	return nil
+	return consulConfig, nil
 }
 
-func parseVaultConfig(result **config.VaultConfig, list *ast.ObjectList) error {
+func parseVaultConfig(result **config.VaultConfig, clusters *map[string]*config.VaultConfig, list *ast.ObjectList) error {
 	list = list.Elem()
-	if len(list.Items) > 1 {
-		return fmt.Errorf("only one 'vault' block allowed")
-	}
 
-	// Get our Vault object
-	listVal := list.Items[0].Val
+	// Blocks without a name configure the default cluster, the others a
+	// named cluster
+	seenDefault := false
+	for _, item := range list.Items {
+		c, err := parseVaultBlock(item.Val)
+		if err != nil {
+			return err
+		}
 
+		if c.Name == "" || c.Name == structs.DefaultCluster {
+			if seenDefault {
+				return fmt.Errorf("only one 'vault' block allowed for the default cluster")
+			}
+			seenDefault = true
+			*result = c
+			continue
+		}
+
+		if *clusters == nil {
+			*clusters = make(map[string]*config.VaultConfig)
+		}
+		if _, ok := (*clusters)[c.Name]; ok {
+			return fmt.Errorf("only one 'vault' block allowed for cluster %q", c.Name)
+		}
+		(*clusters)[c.Name] = c
+	}
+	return nil
+}
+
+func parseVaultBlock(listVal ast.Node) (*config.VaultConfig, error) {
 	// Check for invalid keys
 	valid := []string{
 		"address",
 		"allow_unauthenticated",
 		"enabled",
+		"name",
 		"task_token_ttl",
 		"tls_ca_file",
 		"tls_ca_path",
@@ -667,12 +1141,12 @@ func parseVaultConfig(result **config.VaultConfig, list *ast.ObjectList) error {
 	}
 
 	if err := checkHCLKeys(listVal, valid); err != nil {
-		return err
+		return nil, err
 	}
 
 	var m map[string]interface{}
 	if err := hcl.DecodeObject(&m, listVal); err != nil {
-		return err
+		return nil, err
 	}
 
 	vaultConfig := config.DefaultVaultConfig()
@@ -682,13 +1156,49 @@ func parseVaultConfig(result **config.VaultConfig, list *ast.ObjectList) error {
 		Result:           &vaultConfig,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if err := dec.Decode(m); err != nil {
+		return nil, err
+	}
+
+	return vaultConfig, nil
+}
+
+func parseTLSConfig(result **config.TLSConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'tls' block allowed")
+	}
+
+	// Get the TLS object
+	listVal := list.Items[0].Val
+
+	valid := []string{
+		"http",
+		"rpc",
+		"verify_server_hostname",
+		"verify_https_client",
+		"ca_file",
+		"cert_file",
+		"key_file",
+	}
+
+	if err := checkHCLKeys(listVal, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, listVal); err != nil {
+		return err
+	}
+
+	var tlsConfig config.TLSConfig
+	if err := mapstructure.WeakDecode(m, &tlsConfig); err != nil {
 		return err
 	}
 
-	*result = vaultConfig
+	*result = &tlsConfig
 	return nil
 }
 
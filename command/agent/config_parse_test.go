@@ -25,6 +25,7 @@ func TestConfig_Parse(t *testing.T) {
 				LogLevel:    "ERR",
 				BindAddr:    "192.168.0.1",
 				EnableDebug: true,
+				UI:          true,
 				Ports: &Ports{
 					HTTP: 1234,
 					RPC:  2345,
@@ -43,6 +44,7 @@ func TestConfig_Parse(t *testing.T) {
 					Enabled:   true,
 					StateDir:  "/tmp/client-state",
 					AllocDir:  "/tmp/alloc",
+					PluginDir: "/tmp/plugins",
 					Servers:   []string{"a.b.c:80", "127.0.0.1:1234"},
 					NodeClass: "linux-medium-64bit",
 					Meta: map[string]string{
@@ -57,11 +59,22 @@ func TestConfig_Parse(t *testing.T) {
 						"/opt/myapp/etc": "/etc",
 						"/opt/myapp/bin": "/bin",
 					},
-					NetworkInterface: "eth0",
-					NetworkSpeed:     100,
-					MaxKillTimeout:   "10s",
-					ClientMinPort:    1000,
-					ClientMaxPort:    2000,
+					NetworkInterface:    "eth0",
+					NetworkSpeed:        100,
+					MaxKillTimeout:      "10s",
+					CNIPath:             "/opt/cni/bin",
+					CNIConfigDir:        "/etc/cni/net.d",
+					BridgeNetworkName:   "nomad0",
+					BridgeNetworkSubnet: "172.27.0.0/16",
+					HostNetworks: []*HostNetworkConfig{
+						{Name: "public", Interface: "eth1"},
+						{Name: "private", CIDR: "10.0.0.0/8"},
+					},
+					HostVolumes: []*HostVolumeConfig{
+						{Name: "data", Path: "/srv/data", ReadOnly: true},
+					},
+					ClientMinPort: 1000,
+					ClientMaxPort: 2000,
 					Reserved: &Resources{
 						CPU:                 10,
 						MemoryMB:            10,
@@ -70,21 +83,68 @@ func TestConfig_Parse(t *testing.T) {
 						ReservedPorts:       "1,100,10-12",
 						ParsedReservedPorts: []int{1, 10, 11, 12, 100},
 					},
+					ReservePortConflicts: true,
+					SecretsTmpfsSize:     4,
+					LocalTmpfsSize:       64,
+					MemoryPressure: &MemoryPressureConfig{
+						Enabled:   true,
+						Threshold: 95,
+						Action:    "evict",
+						Victim:    "lowest_priority",
+					},
 				},
 				Server: &ServerConfig{
-					Enabled:           true,
-					BootstrapExpect:   5,
-					DataDir:           "/tmp/data",
-					ProtocolVersion:   3,
-					NumSchedulers:     2,
-					EnabledSchedulers: []string{"test"},
-					NodeGCThreshold:   "12h",
-					HeartbeatGrace:    "30s",
-					RetryJoin:         []string{"1.1.1.1", "2.2.2.2"},
-					StartJoin:         []string{"1.1.1.1", "2.2.2.2"},
-					RetryInterval:     "15s",
-					RejoinAfterLeave:  true,
-					RetryMaxAttempts:  3,
+					Enabled:              true,
+					BootstrapExpect:      5,
+					DataDir:              "/tmp/data",
+					ProtocolVersion:      3,
+					NumSchedulers:        2,
+					EnabledSchedulers:    []string{"test"},
+					SchedulerMapping:     map[string]string{"gpu": "batch"},
+					NodeGCThreshold:      "12h",
+					EvalGCThreshold:      "2h",
+					JobGCThreshold:       "8h",
+					HeartbeatGrace:       "30s",
+					RetryJoin:            []string{"1.1.1.1", "2.2.2.2"},
+					StartJoin:            []string{"1.1.1.1", "2.2.2.2"},
+					RetryInterval:        "15s",
+					RejoinAfterLeave:     true,
+					RetryMaxAttempts:     3,
+					AuthoritativeRegion:  "foobar",
+					EncryptKey:           "abc",
+					KeyringEncryptionKey: "def",
+				},
+				ACL: &ACLConfig{
+					Enabled:          true,
+					ReplicationToken: "foobar",
+				},
+				Audit: &AuditConfig{
+					Enabled: true,
+					Sinks: []*AuditSink{
+						{
+							Name:              "file",
+							Type:              "file",
+							Format:            "json",
+							Path:              "/opt/nomad/audit/audit.log",
+							DeliveryGuarantee: "best-effort",
+							RotateBytes:       1048576,
+							RotateDuration:    "24h",
+							rotateDuration:    24 * time.Hour,
+							RotateMaxFiles:    10,
+						},
+					},
+					Filters: []*AuditFilter{
+						{
+							Name:       "health",
+							Type:       "HTTPEvent",
+							Endpoints:  []string{"/v1/agent/health"},
+							Stages:     []string{"*"},
+							Operations: []string{"GET"},
+						},
+					},
+				},
+				Features: &FeaturesConfig{
+					Disabled: []string{"quotas"},
 				},
 				Telemetry: &Telemetry{
 					StatsiteAddr:             "127.0.0.1:1234",
@@ -94,6 +154,8 @@ func TestConfig_Parse(t *testing.T) {
 					collectionInterval:       3 * time.Second,
 					PublishAllocationMetrics: true,
 					PublishNodeMetrics:       true,
+					PublishDriverMetrics:     true,
+					DriverMetricsLimit:       16,
 				},
 				LeaveOnInt:                true,
 				LeaveOnTerm:               true,
@@ -135,6 +197,28 @@ func TestConfig_Parse(t *testing.T) {
 					TaskTokenTTL:         "1s",
 					Token:                "12345",
 				},
+				ConsulClusters: map[string]*config.ConsulConfig{
+					"secondary": &config.ConsulConfig{
+						Name: "secondary",
+						Addr: "127.0.0.1:9600",
+					},
+				},
+				VaultClusters: map[string]*config.VaultConfig{
+					"secondary": &config.VaultConfig{
+						Name:    "secondary",
+						Addr:    "127.0.0.1:9700",
+						Enabled: true,
+					},
+				},
+				TLSConfig: &config.TLSConfig{
+					EnableHTTP:           true,
+					EnableRPC:            true,
+					VerifyServerHostname: true,
+					VerifyHTTPSClient:    true,
+					CAFile:               "foo",
+					CertFile:             "bar",
+					KeyFile:              "pipe",
+				},
 				HTTPAPIResponseHeaders: map[string]string{
 					"Access-Control-Allow-Origin": "*",
 				},
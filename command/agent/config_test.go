@@ -20,6 +20,7 @@ func TestConfig_Merge(t *testing.T) {
 		DataDir:                   "/tmp/dir1",
 		LogLevel:                  "INFO",
 		EnableDebug:               false,
+		UI:                        false,
 		LeaveOnInt:                false,
 		LeaveOnTerm:               false,
 		EnableSyslog:              false,
@@ -54,6 +55,12 @@ func TestConfig_Merge(t *testing.T) {
 			NetworkSpeed:   100,
 			MaxKillTimeout: "20s",
 			ClientMaxPort:  19996,
+			HostNetworks: []*HostNetworkConfig{
+				{Name: "public", Interface: "eth0"},
+			},
+			HostVolumes: []*HostVolumeConfig{
+				{Name: "data", Path: "/srv/data"},
+			},
 			Reserved: &Resources{
 				CPU:                 10,
 				MemoryMB:            10,
@@ -62,15 +69,36 @@ func TestConfig_Merge(t *testing.T) {
 				ReservedPorts:       "1,10-30,55",
 				ParsedReservedPorts: []int{1, 2, 4},
 			},
+			MemoryPressure: &MemoryPressureConfig{
+				Threshold: 90,
+				Action:    "restart",
+			},
 		},
 		Server: &ServerConfig{
-			Enabled:         false,
-			BootstrapExpect: 1,
-			DataDir:         "/tmp/data1",
-			ProtocolVersion: 1,
-			NumSchedulers:   1,
-			NodeGCThreshold: "1h",
-			HeartbeatGrace:  "30s",
+			Enabled:              false,
+			BootstrapExpect:      1,
+			DataDir:              "/tmp/data1",
+			ProtocolVersion:      1,
+			NumSchedulers:        1,
+			NodeGCThreshold:      "1h",
+			EvalGCThreshold:      "1h",
+			JobGCThreshold:       "4h",
+			HeartbeatGrace:       "30s",
+			EncryptKey:           "foo",
+			KeyringEncryptionKey: "foo",
+		},
+		ACL: &ACLConfig{
+			Enabled:          true,
+			ReplicationToken: "foo",
+		},
+		Audit: &AuditConfig{
+			Enabled: false,
+			Sinks: []*AuditSink{
+				{Name: "file", Path: "/tmp/audit1.log"},
+			},
+		},
+		Features: &FeaturesConfig{
+			Disabled: []string{"sentinel"},
 		},
 		Ports: &Ports{
 			HTTP: 4646,
@@ -107,6 +135,11 @@ func TestConfig_Merge(t *testing.T) {
 			TLSSkipVerify:        false,
 			TLSServerName:        "1",
 		},
+		TLSConfig: &config.TLSConfig{
+			CAFile:   "1",
+			CertFile: "1",
+			KeyFile:  "1",
+		},
 		Consul: &config.ConsulConfig{
 			ServerServiceName: "1",
 			ClientServiceName: "1",
@@ -123,6 +156,18 @@ func TestConfig_Merge(t *testing.T) {
 			ServerAutoJoin:    false,
 			ClientAutoJoin:    false,
 		},
+		ConsulClusters: map[string]*config.ConsulConfig{
+			"secondary": &config.ConsulConfig{
+				Name: "secondary",
+				Addr: "1",
+			},
+		},
+		VaultClusters: map[string]*config.VaultConfig{
+			"secondary": &config.VaultConfig{
+				Name: "secondary",
+				Addr: "1",
+			},
+		},
 	}
 
 	c2 := &Config{
@@ -132,6 +177,7 @@ func TestConfig_Merge(t *testing.T) {
 		DataDir:                   "/tmp/dir2",
 		LogLevel:                  "DEBUG",
 		EnableDebug:               true,
+		UI:                        true,
 		LeaveOnInt:                true,
 		LeaveOnTerm:               true,
 		EnableSyslog:              true,
@@ -145,6 +191,8 @@ func TestConfig_Merge(t *testing.T) {
 			DisableHostname:                    true,
 			PublishNodeMetrics:                 true,
 			PublishAllocationMetrics:           true,
+			PublishDriverMetrics:               true,
+			DriverMetricsLimit:                 16,
 			CirconusAPIToken:                   "1",
 			CirconusAPIApp:                     "nomad",
 			CirconusAPIURL:                     "https://api.circonus.com/v2",
@@ -161,6 +209,7 @@ func TestConfig_Merge(t *testing.T) {
 			Enabled:   true,
 			StateDir:  "/tmp/state2",
 			AllocDir:  "/tmp/alloc2",
+			PluginDir: "/tmp/plugins2",
 			NodeClass: "class2",
 			Servers:   []string{"server2"},
 			Meta: map[string]string{
@@ -175,6 +224,15 @@ func TestConfig_Merge(t *testing.T) {
 			ClientMinPort:  22000,
 			NetworkSpeed:   105,
 			MaxKillTimeout: "50s",
+			CNIPath:        "/opt/cni/bin",
+			HostNetworks: []*HostNetworkConfig{
+				{Name: "public", Interface: "eth1"},
+				{Name: "private", CIDR: "10.0.0.0/8"},
+			},
+			HostVolumes: []*HostVolumeConfig{
+				{Name: "data", Path: "/srv/data2", ReadOnly: true},
+				{Name: "logs", Path: "/var/log"},
+			},
 			Reserved: &Resources{
 				CPU:                 15,
 				MemoryMB:            15,
@@ -183,21 +241,52 @@ func TestConfig_Merge(t *testing.T) {
 				ReservedPorts:       "2,10-30,55",
 				ParsedReservedPorts: []int{1, 2, 3},
 			},
+			ReservePortConflicts: true,
+			SecretsTmpfsSize:     4,
+			LocalTmpfsSize:       64,
+			MemoryPressure: &MemoryPressureConfig{
+				Enabled:   true,
+				Threshold: 95,
+				Action:    "evict",
+				Victim:    "lowest_priority",
+			},
 		},
 		Server: &ServerConfig{
-			Enabled:           true,
-			BootstrapExpect:   2,
-			DataDir:           "/tmp/data2",
-			ProtocolVersion:   2,
-			NumSchedulers:     2,
-			EnabledSchedulers: []string{structs.JobTypeBatch},
-			NodeGCThreshold:   "12h",
-			HeartbeatGrace:    "2m",
-			RejoinAfterLeave:  true,
-			StartJoin:         []string{"1.1.1.1"},
-			RetryJoin:         []string{"1.1.1.1"},
-			RetryInterval:     "10s",
-			retryInterval:     time.Second * 10,
+			Enabled:              true,
+			BootstrapExpect:      2,
+			DataDir:              "/tmp/data2",
+			ProtocolVersion:      2,
+			NumSchedulers:        2,
+			EnabledSchedulers:    []string{structs.JobTypeBatch},
+			SchedulerMapping:     map[string]string{"gpu": structs.JobTypeBatch},
+			NodeGCThreshold:      "12h",
+			EvalGCThreshold:      "2h",
+			JobGCThreshold:       "8h",
+			HeartbeatGrace:       "2m",
+			RejoinAfterLeave:     true,
+			StartJoin:            []string{"1.1.1.1"},
+			RetryJoin:            []string{"1.1.1.1"},
+			RetryInterval:        "10s",
+			retryInterval:        time.Second * 10,
+			AuthoritativeRegion:  "global",
+			EncryptKey:           "bar",
+			KeyringEncryptionKey: "bar",
+		},
+		ACL: &ACLConfig{
+			Enabled:          true,
+			ReplicationToken: "bar",
+		},
+		Audit: &AuditConfig{
+			Enabled: true,
+			Sinks: []*AuditSink{
+				{Name: "file", Path: "/tmp/audit2.log"},
+			},
+			Filters: []*AuditFilter{
+				{Name: "health", Type: "HTTPEvent", Endpoints: []string{"/v1/agent/health"}},
+			},
+		},
+		Features: &FeaturesConfig{
+			Disabled: []string{"sentinel", "quotas"},
 		},
 		Ports: &Ports{
 			HTTP: 20000,
@@ -235,6 +324,15 @@ func TestConfig_Merge(t *testing.T) {
 			TLSSkipVerify:        true,
 			TLSServerName:        "2",
 		},
+		TLSConfig: &config.TLSConfig{
+			EnableHTTP:           true,
+			EnableRPC:            true,
+			VerifyServerHostname: true,
+			VerifyHTTPSClient:    true,
+			CAFile:               "2",
+			CertFile:             "2",
+			KeyFile:              "2",
+		},
 		Consul: &config.ConsulConfig{
 			ServerServiceName: "2",
 			ClientServiceName: "2",
@@ -251,6 +349,19 @@ func TestConfig_Merge(t *testing.T) {
 			ServerAutoJoin:    true,
 			ClientAutoJoin:    true,
 		},
+		ConsulClusters: map[string]*config.ConsulConfig{
+			"secondary": &config.ConsulConfig{
+				Name: "secondary",
+				Addr: "2",
+			},
+		},
+		VaultClusters: map[string]*config.VaultConfig{
+			"secondary": &config.VaultConfig{
+				Name:    "secondary",
+				Addr:    "2",
+				Enabled: true,
+			},
+		},
 	}
 
 	result := c1.Merge(c2)
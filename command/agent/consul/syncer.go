@@ -204,6 +204,12 @@ func GenerateServiceKey(service *structs.Service) ServiceKey {
 	return ServiceKey(key)
 }
 
+// GenerateServiceID returns the Consul ServiceID of the service registered in
+// the domain
+func GenerateServiceID(domain ServiceDomain, service *structs.Service) string {
+	return string(generateConsulServiceID(domain, GenerateServiceKey(service)))
+}
+
 // SetServices stores the map of Nomad Services to the provided service
 // domain name.
 func (c *Syncer) SetServices(domain ServiceDomain, services map[ServiceKey]*structs.Service) error {
@@ -0,0 +1,494 @@
+package agent
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/plugins/csi"
+)
+
+func (s *HTTPServer) CSIVolumesRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.CSIVolumeListRequest{
+		PluginID: req.URL.Query().Get("plugin_id"),
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.CSIVolumeListResponse
+	if err := s.agent.RPC("CSIVolume.List", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Volumes == nil {
+		out.Volumes = make([]*structs.CSIVolumeListStub, 0)
+	}
+	return out.Volumes, nil
+}
+
+func (s *HTTPServer) CSIVolumeSpecificRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	path := strings.TrimPrefix(req.URL.Path, "/v1/volume/csi/")
+	switch {
+	case strings.HasSuffix(path, "/create"):
+		id := strings.TrimSuffix(path, "/create")
+		return s.csiVolumeCreate(resp, req, id)
+	case strings.HasSuffix(path, "/delete"):
+		id := strings.TrimSuffix(path, "/delete")
+		return s.csiVolumeDelete(resp, req, id)
+	case strings.HasSuffix(path, "/expand"):
+		id := strings.TrimSuffix(path, "/expand")
+		return s.csiVolumeExpand(resp, req, id)
+	case len(path) == 0 || strings.Contains(path, "/"):
+		return nil, CodedError(400, "Missing Volume ID")
+	}
+
+	switch req.Method {
+	case "GET":
+		return s.csiVolumeQuery(resp, req, path)
+	case "PUT", "POST":
+		return s.csiVolumeRegister(resp, req, path)
+	case "DELETE":
+		return s.csiVolumeDeregister(resp, req, path)
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) csiVolumeQuery(resp http.ResponseWriter, req *http.Request,
+	id string) (interface{}, error) {
+	args := structs.CSIVolumeGetRequest{
+		ID: id,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.CSIVolumeGetResponse
+	if err := s.agent.RPC("CSIVolume.Get", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Volume == nil {
+		return nil, CodedError(404, "volume not found")
+	}
+	return out.Volume, nil
+}
+
+// decodeCSIVolumes decodes the volumes of a register or create request, which
+// must all have the ID of the request path
+func decodeCSIVolumes(req *http.Request, id string) ([]*structs.CSIVolume, error) {
+	var body structs.CSIVolumeRegisterRequest
+	if err := decodeBody(req, &body); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	if len(body.Volumes) == 0 {
+		return nil, CodedError(400, "Missing Volumes")
+	}
+	for _, vol := range body.Volumes {
+		if vol.ID == "" {
+			vol.ID = id
+		} else if vol.ID != id {
+			return nil, CodedError(400, "Volume ID does not match request path")
+		}
+	}
+	return body.Volumes, nil
+}
+
+func (s *HTTPServer) csiVolumeRegister(resp http.ResponseWriter, req *http.Request,
+	id string) (interface{}, error) {
+	volumes, err := decodeCSIVolumes(req, id)
+	if err != nil {
+		return nil, err
+	}
+
+	args := structs.CSIVolumeRegisterRequest{
+		Volumes: volumes,
+	}
+	s.parseWriteRequest(req, &args.WriteRequest)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("CSIVolume.Register", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+func (s *HTTPServer) csiVolumeCreate(resp http.ResponseWriter, req *http.Request,
+	id string) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+	volumes, err := decodeCSIVolumes(req, id)
+	if err != nil {
+		return nil, err
+	}
+
+	args := structs.CSIVolumeCreateRequest{
+		Volumes: volumes,
+	}
+	s.parseWriteRequest(req, &args.WriteRequest)
+
+	var out structs.CSIVolumeCreateResponse
+	if err := s.agent.RPC("CSIVolume.Create", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return out, nil
+}
+
+func (s *HTTPServer) csiVolumeDeregister(resp http.ResponseWriter, req *http.Request,
+	id string) (interface{}, error) {
+	args := structs.CSIVolumeDeregisterRequest{
+		VolumeIDs: []string{id},
+		Force:     req.URL.Query().Get("force") == "true",
+	}
+	s.parseWriteRequest(req, &args.WriteRequest)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("CSIVolume.Deregister", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+func (s *HTTPServer) csiVolumeDelete(resp http.ResponseWriter, req *http.Request,
+	id string) (interface{}, error) {
+	if req.Method != "DELETE" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+	if len(id) == 0 || strings.Contains(id, "/") {
+		return nil, CodedError(400, "Missing Volume ID")
+	}
+
+	args := structs.CSIVolumeDeleteRequest{
+		VolumeIDs: []string{id},
+	}
+	s.parseWriteRequest(req, &args.WriteRequest)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("CSIVolume.Delete", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+func (s *HTTPServer) csiVolumeExpand(resp http.ResponseWriter, req *http.Request,
+	id string) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+	if len(id) == 0 || strings.Contains(id, "/") {
+		return nil, CodedError(400, "Missing Volume ID")
+	}
+
+	var args structs.CSIVolumeExpandRequest
+	if err := decodeBody(req, &args); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	args.VolumeID = id
+	s.parseWriteRequest(req, &args.WriteRequest)
+
+	var out structs.CSIVolumeExpandResponse
+	if err := s.agent.RPC("CSIVolume.Expand", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return out, nil
+}
+
+func (s *HTTPServer) CSISnapshotsRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	switch req.Method {
+	case "GET":
+		return s.csiSnapshotList(resp, req)
+	case "PUT", "POST":
+		return s.csiSnapshotCreate(resp, req)
+	case "DELETE":
+		return s.csiSnapshotDelete(resp, req)
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) csiSnapshotList(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	query := req.URL.Query()
+	args := structs.CSISnapshotListRequest{
+		PluginID:  query.Get("plugin_id"),
+		NextToken: query.Get("next_token"),
+	}
+	if args.PluginID == "" {
+		return nil, CodedError(400, "Missing Plugin ID")
+	}
+	if perPage := query.Get("per_page"); perPage != "" {
+		n, err := strconv.Atoi(perPage)
+		if err != nil || n < 0 {
+			return nil, CodedError(400, "Invalid per_page")
+		}
+		args.PerPage = n
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.CSISnapshotListResponse
+	if err := s.agent.RPC("CSIVolume.ListSnapshots", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Snapshots == nil {
+		out.Snapshots = make([]*structs.CSISnapshot, 0)
+	}
+	return out, nil
+}
+
+func (s *HTTPServer) csiSnapshotCreate(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	var args structs.CSISnapshotCreateRequest
+	if err := decodeBody(req, &args); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	if len(args.Snapshots) == 0 {
+		return nil, CodedError(400, "Missing Snapshots")
+	}
+	s.parseWriteRequest(req, &args.WriteRequest)
+
+	var out structs.CSISnapshotCreateResponse
+	if err := s.agent.RPC("CSIVolume.CreateSnapshot", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return out, nil
+}
+
+func (s *HTTPServer) csiSnapshotDelete(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	query := req.URL.Query()
+	snapshot := &structs.CSISnapshot{
+		ID:       query.Get("snapshot_id"),
+		PluginID: query.Get("plugin_id"),
+	}
+	if snapshot.ID == "" || snapshot.PluginID == "" {
+		return nil, CodedError(400, "Missing Snapshot ID or Plugin ID")
+	}
+
+	args := structs.CSISnapshotDeleteRequest{
+		Snapshots: []*structs.CSISnapshot{snapshot},
+	}
+	s.parseWriteRequest(req, &args.WriteRequest)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("CSIVolume.DeleteSnapshot", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+func (s *HTTPServer) CSIPluginsRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.CSIPluginListRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.CSIPluginListResponse
+	if err := s.agent.RPC("CSIPlugin.List", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Plugins == nil {
+		out.Plugins = make([]*structs.CSIPluginListStub, 0)
+	}
+	return out.Plugins, nil
+}
+
+func (s *HTTPServer) CSIPluginSpecificRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	id := strings.TrimPrefix(req.URL.Path, "/v1/plugin/csi/")
+	if len(id) == 0 {
+		return nil, CodedError(400, "Missing Plugin ID")
+	}
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.CSIPluginGetRequest{
+		ID: id,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.CSIPluginGetResponse
+	if err := s.agent.RPC("CSIPlugin.Get", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Plugin == nil {
+		return nil, CodedError(404, "plugin not found")
+	}
+	return out.Plugin, nil
+}
+
+// ClientCSIControllerRequest is used by the servers to call the controller of
+// a CSI plugin running on the node, as /v1/client/csi/controller/<plugin>/<op>.
+// The servers authenticate with a token derived from the secret ID of the
+// node.
+func (s *HTTPServer) ClientCSIControllerRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.agent.client == nil {
+		return nil, clientNotRunning
+	}
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/v1/client/csi/controller/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		return nil, CodedError(404, "Unknown CSI controller operation")
+	}
+	pluginID, op := parts[0], parts[1]
+
+	var token string
+	s.parseToken(req, &token)
+	if !structs.CompareCSIControllerToken(pluginID, s.agent.client.Node().SecretID, token) {
+		return nil, structs.ErrPermissionDenied
+	}
+
+	switch op {
+	case "create_volume":
+		var args csi.ControllerCreateVolumeRequest
+		if err := decodeBody(req, &args); err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+		var out *csi.Volume
+		err := s.agent.client.CSIControllerCall(pluginID, func(p csi.Plugin) (err error) {
+			out, err = p.ControllerCreateVolume(&args)
+			return err
+		})
+		return out, err
+	case "publish_volume":
+		var args csi.ControllerPublishVolumeRequest
+		if err := decodeBody(req, &args); err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+		var out *csi.ControllerPublishVolumeResponse
+		err := s.agent.client.CSIControllerCall(pluginID, func(p csi.Plugin) (err error) {
+			out, err = p.ControllerPublishVolume(&args)
+			return err
+		})
+		return out, err
+	case "unpublish_volume":
+		var args csi.ControllerUnpublishVolumeRequest
+		if err := decodeBody(req, &args); err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+		err := s.agent.client.CSIControllerCall(pluginID, func(p csi.Plugin) error {
+			return p.ControllerUnpublishVolume(&args)
+		})
+		return nil, err
+	case "delete_volume":
+		var args csi.ControllerDeleteVolumeRequest
+		if err := decodeBody(req, &args); err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+		err := s.agent.client.CSIControllerCall(pluginID, func(p csi.Plugin) error {
+			return p.ControllerDeleteVolume(&args)
+		})
+		return nil, err
+	case "expand_volume":
+		var args csi.ControllerExpandVolumeRequest
+		if err := decodeBody(req, &args); err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+		var out *csi.ControllerExpandVolumeResponse
+		err := s.agent.client.CSIControllerCall(pluginID, func(p csi.Plugin) (err error) {
+			out, err = p.ControllerExpandVolume(&args)
+			return err
+		})
+		return out, err
+	case "create_snapshot":
+		var args csi.ControllerCreateSnapshotRequest
+		if err := decodeBody(req, &args); err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+		var out *csi.Snapshot
+		err := s.agent.client.CSIControllerCall(pluginID, func(p csi.Plugin) (err error) {
+			out, err = p.ControllerCreateSnapshot(&args)
+			return err
+		})
+		return out, err
+	case "delete_snapshot":
+		var args csi.ControllerDeleteSnapshotRequest
+		if err := decodeBody(req, &args); err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+		err := s.agent.client.CSIControllerCall(pluginID, func(p csi.Plugin) error {
+			return p.ControllerDeleteSnapshot(&args)
+		})
+		return nil, err
+	case "list_snapshots":
+		var args csi.ControllerListSnapshotsRequest
+		if err := decodeBody(req, &args); err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+		var out *csi.ControllerListSnapshotsResponse
+		err := s.agent.client.CSIControllerCall(pluginID, func(p csi.Plugin) (err error) {
+			out, err = p.ControllerListSnapshots(&args)
+			return err
+		})
+		return out, err
+	default:
+		return nil, CodedError(404, "Unknown CSI controller operation")
+	}
+}
+
+// ClientCSINodeRequest is used by the servers to call the node plugin of a
+// CSI plugin running on the node, as /v1/client/csi/node/<plugin>/<op>. The
+// servers authenticate with a token derived from the secret ID of the node.
+func (s *HTTPServer) ClientCSINodeRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.agent.client == nil {
+		return nil, clientNotRunning
+	}
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/v1/client/csi/node/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		return nil, CodedError(404, "Unknown CSI node operation")
+	}
+	pluginID, op := parts[0], parts[1]
+
+	var token string
+	s.parseToken(req, &token)
+	if !structs.CompareCSINodeToken(pluginID, s.agent.client.Node().SecretID, token) {
+		return nil, structs.ErrPermissionDenied
+	}
+
+	switch op {
+	case "expand_volume":
+		var vol structs.CSIVolume
+		if err := decodeBody(req, &vol); err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+		if vol.PluginID != pluginID {
+			return nil, CodedError(400, "Volume plugin does not match request path")
+		}
+		return nil, s.agent.client.ExpandCSIVolume(&vol)
+	default:
+		return nil, CodedError(404, "Unknown CSI node operation")
+	}
+}
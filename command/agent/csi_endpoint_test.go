@@ -0,0 +1,225 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_CSIVolumeCRUD(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Register a volume
+		vol := mock.CSIVolume()
+		buf := encodeReq(structs.CSIVolumeRegisterRequest{Volumes: []*structs.CSIVolume{vol}})
+		req, err := http.NewRequest("PUT", "/v1/volume/csi/"+vol.ID, buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		if _, err := s.Server.CSIVolumeSpecificRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Query the volume
+		req, err = http.NewRequest("GET", "/v1/volume/csi/"+vol.ID, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err := s.Server.CSIVolumeSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		out := obj.(*structs.CSIVolume)
+		if out.ID != vol.ID || out.ExternalID != vol.ExternalID || out.PluginID != vol.PluginID {
+			t.Fatalf("bad: %#v", out)
+		}
+
+		// List the volumes of the plugin
+		req, err = http.NewRequest("GET", "/v1/volumes?plugin_id=ebs", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.CSIVolumesRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if n := len(obj.([]*structs.CSIVolumeListStub)); n != 1 {
+			t.Fatalf("bad: %d", n)
+		}
+
+		// Deregister the volume
+		req, err = http.NewRequest("DELETE", "/v1/volume/csi/"+vol.ID, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.CSIVolumeSpecificRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// The volume is gone
+		req, err = http.NewRequest("GET", "/v1/volume/csi/"+vol.ID, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.CSIVolumeSpecificRequest(respW, req); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
+
+func TestHTTP_CSIPlugins(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		req, err := http.NewRequest("GET", "/v1/plugins", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.CSIPluginsRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if n := len(obj.([]*structs.CSIPluginListStub)); n != 0 {
+			t.Fatalf("bad: %d", n)
+		}
+
+		// Unknown plugins are not found
+		req, err = http.NewRequest("GET", "/v1/plugin/csi/ebs", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.CSIPluginSpecificRequest(respW, req); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
+
+func TestHTTP_ClientCSIController(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Calls without the controller token are denied
+		buf := encodeReq(map[string]string{"ExternalID": "vol-db"})
+		req, err := http.NewRequest("PUT", "/v1/client/csi/controller/ebs/unpublish_volume", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", "foo")
+		respW := httptest.NewRecorder()
+
+		if _, err := s.Server.ClientCSIControllerRequest(respW, req); err != structs.ErrPermissionDenied {
+			t.Fatalf("expected permission denied, got %v", err)
+		}
+
+		// The plugin isn't running on the node
+		token := structs.GenerateCSIControllerToken("ebs", s.Agent.client.Node().SecretID)
+		buf = encodeReq(map[string]string{"ExternalID": "vol-db"})
+		req, err = http.NewRequest("PUT", "/v1/client/csi/controller/ebs/unpublish_volume", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", token)
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.ClientCSIControllerRequest(respW, req); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
+
+func TestHTTP_CSIVolumeLifecycle(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Unknown volumes can't be expanded
+		buf := encodeReq(map[string]int64{"RequestedCapacityMin": 1 << 30})
+		req, err := http.NewRequest("PUT", "/v1/volume/csi/db/expand", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		if _, err := s.Server.CSIVolumeSpecificRequest(respW, req); err == nil {
+			t.Fatalf("expected error")
+		}
+
+		// Or deleted
+		req, err = http.NewRequest("DELETE", "/v1/volume/csi/db/delete", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.CSIVolumeSpecificRequest(respW, req); err == nil {
+			t.Fatalf("expected error")
+		}
+
+		// Listing snapshots requires a plugin
+		req, err = http.NewRequest("GET", "/v1/volumes/snapshot", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		_, err = s.Server.CSISnapshotsRequest(respW, req)
+		if codedErr, ok := err.(HTTPCodedError); !ok || codedErr.Code() != 400 {
+			t.Fatalf("expected 400, got %v", err)
+		}
+
+		// The plugin has no controller
+		req, err = http.NewRequest("GET", "/v1/volumes/snapshot?plugin_id=ebs&per_page=10", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.CSISnapshotsRequest(respW, req); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
+
+func TestHTTP_ClientCSINode(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		vol := mock.CSIVolume()
+
+		// Calls with the controller token are denied
+		token := structs.GenerateCSIControllerToken("ebs", s.Agent.client.Node().SecretID)
+		req, err := http.NewRequest("PUT", "/v1/client/csi/node/ebs/expand_volume", encodeReq(vol))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", token)
+		respW := httptest.NewRecorder()
+
+		if _, err := s.Server.ClientCSINodeRequest(respW, req); err != structs.ErrPermissionDenied {
+			t.Fatalf("expected permission denied, got %v", err)
+		}
+
+		// The plugin isn't running on the node
+		token = structs.GenerateCSINodeToken("ebs", s.Agent.client.Node().SecretID)
+		req, err = http.NewRequest("PUT", "/v1/client/csi/node/ebs/expand_volume", encodeReq(vol))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req.Header.Set("X-Nomad-Token", token)
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.ClientCSINodeRequest(respW, req); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/ugorji/go/codec"
+)
+
+const (
+	// eventStreamHeartbeat is how long the event stream waits for events
+	// before writing a heartbeat, unless a wait time is requested
+	eventStreamHeartbeat = 10 * time.Second
+)
+
+var (
+	// eventStreamHeartbeatLine is written to the event stream when no event
+	// was published for a while, so that closed connections are detected
+	eventStreamHeartbeatLine = []byte("{}\n")
+)
+
+// EventStream streams the events published by the servers as newline
+// delimited JSON, one object per Raft index. Each "topic" parameter is a
+// topic to subscribe to, optionally followed by ":" and the key of the events
+// to return, and defaults to every event. The "index" parameter is the index
+// to resume streaming after.
+func (s *HTTPServer) EventStream(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.EventListRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+	topics, err := parseEventTopics(req.URL.Query()["topic"])
+	if err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	args.Topics = topics
+	if args.MaxQueryTime == 0 {
+		args.MaxQueryTime = eventStreamHeartbeat
+	}
+
+	// Fetch the first events before streaming, so that errors such as
+	// permission denied are returned with their status code
+	var out structs.EventListResponse
+	if err := s.agent.RPC("Event.List", &args, &out); err != nil {
+		return nil, err
+	}
+
+	// Create an output that gets flushed on every write
+	resp.Header().Set("Content-Type", "application/json")
+	output := ioutils.NewWriteFlusher(resp)
+	enc := codec.NewEncoder(output, jsonHandle)
+
+	for {
+		for _, events := range out.Events {
+			if err := enc.Encode(events); err != nil {
+				return nil, nil
+			}
+			if _, err := output.Write([]byte("\n")); err != nil {
+				return nil, nil
+			}
+		}
+		if len(out.Events) == 0 {
+			if _, err := output.Write(eventStreamHeartbeatLine); err != nil {
+				return nil, nil
+			}
+		}
+
+		args.MinQueryIndex = out.Index
+		out = structs.EventListResponse{}
+		if err := s.agent.RPC("Event.List", &args, &out); err != nil {
+			s.logger.Printf("[ERR] http: event stream ended: %v", err)
+			return nil, nil
+		}
+	}
+}
+
+// parseEventTopics parses the topics of the event stream, given as the topic
+// optionally followed by ":" and the key of the events
+func parseEventTopics(params []string) (map[structs.Topic][]string, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+
+	topics := make(map[structs.Topic][]string)
+	for _, param := range params {
+		parts := strings.SplitN(param, ":", 2)
+		topic := structs.Topic(parts[0])
+		switch topic {
+		case structs.TopicJob, structs.TopicAllocation, structs.TopicNode,
+			structs.TopicEvaluation, structs.TopicAll:
+		default:
+			return nil, fmt.Errorf("Invalid topic %q", parts[0])
+		}
+
+		key := string(structs.TopicAll)
+		if len(parts) == 2 && parts[1] != "" {
+			key = parts[1]
+		}
+		topics[topic] = append(topics[topic], key)
+	}
+	return topics, nil
+}
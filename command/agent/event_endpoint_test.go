@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestParseEventTopics(t *testing.T) {
+	topics, err := parseEventTopics([]string{"Job:example", "Job:other", "Node", "Allocation:"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := map[structs.Topic][]string{
+		structs.TopicJob:        []string{"example", "other"},
+		structs.TopicNode:       []string{"*"},
+		structs.TopicAllocation: []string{"*"},
+	}
+	if !reflect.DeepEqual(topics, expected) {
+		t.Fatalf("bad: %#v", topics)
+	}
+
+	if _, err := parseEventTopics([]string{"Deployment:example"}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestHTTP_EventStream_InvalidTopic(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		req, err := http.NewRequest("GET", "/v1/event/stream?topic=Unknown", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		_, err = s.Server.EventStream(respW, req)
+		if coded, ok := err.(HTTPCodedError); !ok || coded.Code() != 400 {
+			t.Fatalf("expected 400: %v", err)
+		}
+	})
+}
@@ -19,6 +19,7 @@ import (
 	"gopkg.in/tomb.v1"
 
 	"github.com/docker/docker/pkg/ioutils"
+	"github.com/hashicorp/nomad/acl"
 	"github.com/hashicorp/nomad/client/allocdir"
 	"github.com/hpcloud/tail/watch"
 	"github.com/ugorji/go/codec"
@@ -69,6 +70,20 @@ func (s *HTTPServer) FsRequest(resp http.ResponseWriter, req *http.Request) (int
 	}
 
 	path := strings.TrimPrefix(req.URL.Path, "/v1/client/fs/")
+
+	// Check the token may read the file system, or the logs, of the
+	// allocation
+	if idx := strings.Index(path, "/"); idx != -1 {
+		op := acl.NamespaceCapabilityReadFS
+		if strings.HasPrefix(path, "logs/") {
+			op = acl.NamespaceCapabilityReadLogs
+		}
+		allocID := strings.SplitN(path[idx+1:], "/", 2)[0]
+		if err := s.checkAllocCapability(req, allocID, op); err != nil {
+			return nil, err
+		}
+	}
+
 	switch {
 	case strings.HasPrefix(path, "ls/"):
 		return s.DirectoryListRequest(resp, req)
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,10 +11,16 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/NYTimes/gziphandler"
+	"github.com/hashicorp/consul/tlsutil"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/audit"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/ugorji/go/codec"
 )
 
@@ -42,6 +49,12 @@ type HTTPServer struct {
 	listener net.Listener
 	logger   *log.Logger
 	addr     string
+
+	// tlsConf is the TLS configuration of the connections accepted when the
+	// API is served over TLS. It is swapped when the certificates are
+	// reloaded.
+	tlsConf *tls.Config
+	tlsLock sync.RWMutex
 }
 
 // NewHTTPServer starts new HTTP server over the agent
@@ -63,13 +76,83 @@ func NewHTTPServer(agent *Agent, config *Config, logOutput io.Writer) (*HTTPServ
 		logger:   agent.logger,
 		addr:     ln.Addr().String(),
 	}
-	srv.registerHandlers(config.EnableDebug)
+	srv.registerHandlers(config.EnableDebug, config.UI)
+
+	// Serve the API over TLS if enabled
+	if config.TLSConfig != nil && config.TLSConfig.EnableHTTP {
+		tlsConf, err := httpTLSConfig(config.TLSConfig)
+		if err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("failed to setup TLS for HTTP: %v", err)
+		}
+		srv.tlsConf = tlsConf
+		ln = &tlsListener{Listener: ln, srv: srv}
+	}
 
 	// Start the server
 	go http.Serve(ln, gziphandler.GzipHandler(mux))
 	return srv, nil
 }
 
+// httpTLSConfig returns the TLS configuration for the connections to the
+// HTTP API. Clients must present a certificate signed by the CA if
+// verify_https_client is set.
+func httpTLSConfig(conf *config.TLSConfig) (*tls.Config, error) {
+	if conf.CertFile == "" || conf.KeyFile == "" {
+		return nil, fmt.Errorf("TLS for HTTP requires a certificate and key")
+	}
+	tc := &tlsutil.Config{
+		VerifyIncoming: conf.VerifyHTTPSClient,
+		CAFile:         conf.CAFile,
+		CertFile:       conf.CertFile,
+		KeyFile:        conf.KeyFile,
+	}
+	return tc.IncomingTLSConfig()
+}
+
+// ReloadTLS reloads the certificates the API is served with from the given
+// TLS configuration. New connections use the new certificates. Enabling or
+// disabling TLS requires a restart.
+func (s *HTTPServer) ReloadTLS(newConf *config.TLSConfig) error {
+	s.tlsLock.Lock()
+	defer s.tlsLock.Unlock()
+
+	enabled := newConf != nil && newConf.EnableHTTP
+	if enabled != (s.tlsConf != nil) {
+		return fmt.Errorf("enabling or disabling TLS for HTTP requires a restart")
+	}
+	if !enabled {
+		return nil
+	}
+
+	tlsConf, err := httpTLSConfig(newConf)
+	if err != nil {
+		return err
+	}
+	s.tlsConf = tlsConf
+	s.logger.Printf("[INFO] http: reloaded TLS certificates")
+	return nil
+}
+
+// tlsListener wraps the connections accepted by the HTTP server in TLS,
+// using the TLS configuration current at the time they are accepted.
+type tlsListener struct {
+	net.Listener
+	srv *HTTPServer
+}
+
+func (l *tlsListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	l.srv.tlsLock.RLock()
+	tlsConf := l.srv.tlsConf
+	l.srv.tlsLock.RUnlock()
+	return tls.Server(conn, tlsConf), nil
+}
+
 // newScadaHttp creates a new HTTP server wrapping the SCADA
 // listener such that HTTP calls can be sent from the brokers.
 func newScadaHttp(agent *Agent, list net.Listener) *HTTPServer {
@@ -84,7 +167,7 @@ func newScadaHttp(agent *Agent, list net.Listener) *HTTPServer {
 		logger:   agent.logger,
 		addr:     scadaHTTPAddr,
 	}
-	srv.registerHandlers(false) // Never allow debug for SCADA
+	srv.registerHandlers(false, false) // Never allow debug or the UI for SCADA
 
 	// Start the server
 	go http.Serve(list, gziphandler.GzipHandler(mux))
@@ -100,10 +183,13 @@ func (s *HTTPServer) Shutdown() {
 }
 
 // registerHandlers is used to attach our handlers to the mux
-func (s *HTTPServer) registerHandlers(enableDebug bool) {
+func (s *HTTPServer) registerHandlers(enableDebug, enableUI bool) {
 	s.mux.HandleFunc("/v1/jobs", s.wrap(s.JobsRequest))
 	s.mux.HandleFunc("/v1/job/", s.wrap(s.JobSpecificRequest))
 
+	s.mux.HandleFunc("/v1/scaling/policies", s.wrap(s.ScalingPoliciesRequest))
+	s.mux.HandleFunc("/v1/scaling/policy/", s.wrap(s.ScalingPolicySpecificRequest))
+
 	s.mux.HandleFunc("/v1/nodes", s.wrap(s.NodesRequest))
 	s.mux.HandleFunc("/v1/node/", s.wrap(s.NodeSpecificRequest))
 
@@ -113,15 +199,30 @@ func (s *HTTPServer) registerHandlers(enableDebug bool) {
 	s.mux.HandleFunc("/v1/evaluations", s.wrap(s.EvalsRequest))
 	s.mux.HandleFunc("/v1/evaluation/", s.wrap(s.EvalSpecificRequest))
 
+	s.mux.HandleFunc("/v1/volumes", s.wrap(s.CSIVolumesRequest))
+	s.mux.HandleFunc("/v1/volume/csi/", s.wrap(s.CSIVolumeSpecificRequest))
+	s.mux.HandleFunc("/v1/volumes/snapshot", s.wrap(s.CSISnapshotsRequest))
+	s.mux.HandleFunc("/v1/plugins", s.wrap(s.CSIPluginsRequest))
+	s.mux.HandleFunc("/v1/plugin/csi/", s.wrap(s.CSIPluginSpecificRequest))
+
 	s.mux.HandleFunc("/v1/client/fs/", s.wrap(s.FsRequest))
 	s.mux.HandleFunc("/v1/client/stats", s.wrap(s.ClientStatsRequest))
 	s.mux.HandleFunc("/v1/client/allocation/", s.wrap(s.ClientAllocRequest))
+	s.mux.HandleFunc("/v1/client/job/", s.wrap(s.ClientJobRequest))
+	s.mux.HandleFunc("/v1/client/prefetch", s.wrap(s.ClientPrefetchRequest))
+	s.mux.HandleFunc("/v1/client/network/rebuild", s.wrap(s.ClientNetworkRebuildRequest))
+	s.mux.HandleFunc("/v1/client/csi/controller/", s.wrap(s.ClientCSIControllerRequest))
+	s.mux.HandleFunc("/v1/client/csi/node/", s.wrap(s.ClientCSINodeRequest))
 
 	s.mux.HandleFunc("/v1/agent/self", s.wrap(s.AgentSelfRequest))
 	s.mux.HandleFunc("/v1/agent/join", s.wrap(s.AgentJoinRequest))
 	s.mux.HandleFunc("/v1/agent/members", s.wrap(s.AgentMembersRequest))
 	s.mux.HandleFunc("/v1/agent/force-leave", s.wrap(s.AgentForceLeaveRequest))
 	s.mux.HandleFunc("/v1/agent/servers", s.wrap(s.AgentServersRequest))
+	s.mux.HandleFunc("/v1/agent/health", s.wrap(s.AgentHealthRequest))
+	s.mux.HandleFunc("/v1/agent/gossip/keyring/", s.wrap(s.AgentGossipKeyringRequest))
+
+	s.mux.HandleFunc("/v1/metrics", s.wrap(s.MetricsRequest))
 
 	s.mux.HandleFunc("/v1/regions", s.wrap(s.RegionListRequest))
 
@@ -131,6 +232,54 @@ func (s *HTTPServer) registerHandlers(enableDebug bool) {
 	s.mux.HandleFunc("/v1/system/gc", s.wrap(s.GarbageCollectRequest))
 	s.mux.HandleFunc("/v1/system/reconcile/summaries", s.wrap(s.ReconcileJobSummaries))
 
+	s.mux.HandleFunc("/v1/namespaces", s.wrap(s.NamespacesRequest))
+	s.mux.HandleFunc("/v1/namespaces/replication", s.wrap(s.NamespaceReplicationStatusRequest))
+	s.mux.HandleFunc("/v1/namespace", s.wrap(s.NamespaceCreateRequest))
+	s.mux.HandleFunc("/v1/namespace/", s.wrap(s.NamespaceSpecificRequest))
+
+	s.mux.HandleFunc("/v1/quotas", s.wrap(s.QuotasRequest))
+	s.mux.HandleFunc("/v1/quota", s.wrap(s.QuotaCreateRequest))
+	s.mux.HandleFunc("/v1/quota/", s.wrap(s.QuotaSpecificRequest))
+
+	s.mux.HandleFunc("/v1/maintenance/windows", s.wrap(s.MaintenanceWindowsRequest))
+	s.mux.HandleFunc("/v1/maintenance/window", s.wrap(s.MaintenanceWindowCreateRequest))
+	s.mux.HandleFunc("/v1/maintenance/window/", s.wrap(s.MaintenanceWindowSpecificRequest))
+
+	s.mux.HandleFunc("/v1/search", s.wrap(s.SearchRequest))
+	s.mux.HandleFunc("/v1/search/fuzzy", s.wrap(s.FuzzySearchRequest))
+
+	s.mux.HandleFunc("/v1/services", s.wrap(s.ServicesRequest))
+	s.mux.HandleFunc("/v1/service/", s.wrap(s.ServiceSpecificRequest))
+
+	s.mux.HandleFunc("/v1/vars", s.wrap(s.VariablesListRequest))
+	s.mux.HandleFunc("/v1/var/", s.wrap(s.VariableSpecificRequest))
+
+	s.mux.HandleFunc("/v1/operator/keyring/keys", s.wrap(s.KeyringKeysRequest))
+	s.mux.HandleFunc("/v1/operator/keyring/rotate", s.wrap(s.KeyringRotateRequest))
+	s.mux.HandleFunc("/v1/operator/autopilot/configuration", s.wrap(s.OperatorAutopilotConfiguration))
+	s.mux.HandleFunc("/v1/operator/autopilot/health", s.wrap(s.OperatorServerHealth))
+	s.mux.HandleFunc("/v1/operator/raft/configuration", s.wrap(s.OperatorRaftConfiguration))
+	s.mux.HandleFunc("/v1/operator/raft/peer", s.wrap(s.OperatorRaftPeer))
+	s.mux.HandleFunc("/v1/operator/scheduler/configuration", s.wrap(s.OperatorSchedulerConfiguration))
+	s.mux.HandleFunc("/v1/operator/snapshot", s.wrap(s.SnapshotRequest))
+
+	s.mux.HandleFunc("/v1/acl/bootstrap", s.wrap(s.ACLTokenBootstrap))
+	s.mux.HandleFunc("/v1/acl/policies", s.wrap(s.ACLPoliciesRequest))
+	s.mux.HandleFunc("/v1/acl/policy/", s.wrap(s.ACLPolicySpecificRequest))
+	s.mux.HandleFunc("/v1/acl/tokens", s.wrap(s.ACLTokensRequest))
+	s.mux.HandleFunc("/v1/acl/token", s.wrap(s.ACLTokenCreateRequest))
+	s.mux.HandleFunc("/v1/acl/token/", s.wrap(s.ACLTokenSpecificRequest))
+	s.mux.HandleFunc("/v1/acl/replication", s.wrap(s.ACLReplicationStatusRequest))
+
+	s.mux.HandleFunc("/v1/event/stream", s.wrap(s.EventStream))
+
+	s.mux.HandleFunc("/.well-known/jwks.json", s.wrap(s.JWKSRequest))
+
+	if enableUI {
+		s.mux.HandleFunc("/ui/", s.UIRequest)
+		s.mux.HandleFunc("/", s.UIRedirect)
+	}
+
 	if enableDebug {
 		s.mux.HandleFunc("/debug/pprof/", pprof.Index)
 		s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
@@ -172,15 +321,46 @@ func (s *HTTPServer) wrap(handler func(resp http.ResponseWriter, req *http.Reque
 		defer func() {
 			s.logger.Printf("[DEBUG] http: Request %v (%v)", reqURL, time.Now().Sub(start))
 		}()
-		obj, err := handler(resp, req)
+
+		// Record the request in the audit log before handling it, and its
+		// outcome once handled
+		code := 200
+		event, err := s.auditReceived(req)
+		if event != nil {
+			defer func() {
+				s.auditComplete(event, code, err)
+			}()
+		}
+
+		var obj interface{}
+		if err == nil {
+			obj, err = handler(resp, req)
+		}
 
 		// Check for an error
 	HAS_ERR:
 		if err != nil {
 			s.logger.Printf("[ERR] http: Request %v, error: %v", reqURL, err)
-			code := 500
+			code = 500
 			if http, ok := err.(HTTPCodedError); ok {
 				code = http.Code()
+			} else {
+				// ACL errors are returned by the servers as strings
+				switch err.Error() {
+				case structs.ErrPermissionDenied.Error(), structs.ErrTokenNotFound.Error():
+					code = 403
+				case structs.ErrCASConflict.Error():
+					code = 409
+				}
+				if strings.HasPrefix(err.Error(), structs.ErrFeatureDisabled.Error()) {
+					code = 501
+				}
+				if strings.HasPrefix(err.Error(), structs.ErrInvalidFilter.Error()) {
+					code = 400
+				}
+				if strings.HasPrefix(err.Error(), structs.ErrEventsEvicted.Error()) {
+					code = 410
+				}
 			}
 			resp.WriteHeader(code)
 			resp.Write([]byte(err.Error()))
@@ -217,6 +397,56 @@ func (s *HTTPServer) wrap(handler func(resp http.ResponseWriter, req *http.Reque
 	return f
 }
 
+// auditReceived records that a request was received when audit logging is
+// enabled, returning the event to record its outcome with. An error is
+// returned if the request must fail because it couldn't be recorded.
+func (s *HTTPServer) auditReceived(req *http.Request) (*audit.Event, error) {
+	if s.agent.auditor == nil {
+		return nil, nil
+	}
+
+	var secret string
+	s.parseToken(req, &secret)
+	var region string
+	s.parseRegion(req, &region)
+	event := &audit.Event{
+		Type:  audit.HTTPEvent,
+		Stage: audit.StageOperationReceived,
+		Auth:  audit.NewAuth(secret, s.agent.auditTokenResolver()),
+		Request: &audit.Request{
+			ID:         structs.GenerateUUID(),
+			Operation:  req.Method,
+			Endpoint:   req.URL.Path,
+			Region:     region,
+			Namespace:  req.URL.Query().Get("namespace"),
+			RemoteAddr: req.RemoteAddr,
+			UserAgent:  req.UserAgent(),
+			Params:     req.URL.Query(),
+		},
+	}
+	if err := s.agent.auditor.Event(event); err != nil {
+		return nil, CodedError(500, fmt.Sprintf("failed to record request in audit log: %v", err))
+	}
+	return event, nil
+}
+
+// auditComplete records the outcome of a request received by auditReceived
+func (s *HTTPServer) auditComplete(received *audit.Event, code int, err error) {
+	event := &audit.Event{
+		Type:     received.Type,
+		Stage:    audit.StageOperationComplete,
+		Auth:     received.Auth,
+		Request:  received.Request,
+		Response: &audit.Response{StatusCode: code},
+	}
+	if err != nil {
+		event.Response.Error = err.Error()
+	}
+
+	// The response was already written, so a failure is only logged
+	s.agent.auditor.Event(event)
+}
+
 // decodeBody is used to decode a JSON request body
 func decodeBody(req *http.Request, out interface{}) error {
 	dec := json.NewDecoder(req.Body)
@@ -248,6 +478,15 @@ func setMeta(resp http.ResponseWriter, m *structs.QueryMeta) {
 	setIndex(resp, m.Index)
 	setLastContact(resp, m.LastContact)
 	setKnownLeader(resp, m.KnownLeader)
+	setNextToken(resp, m.NextToken)
+}
+
+// setNextToken is used to set the next token header of paginated list
+// queries
+func setNextToken(resp http.ResponseWriter, token string) {
+	if token != "" {
+		resp.Header().Set("X-Nomad-NextToken", token)
+	}
 }
 
 // setHeaders is used to set canonical response header fields
@@ -298,6 +537,35 @@ func parsePrefix(req *http.Request, b *structs.QueryOptions) {
 	}
 }
 
+// parsePagination is used to parse the ?per_page, ?next_token and ?filter
+// query params of list queries. Returns true on error
+func parsePagination(resp http.ResponseWriter, req *http.Request, b *structs.QueryOptions) bool {
+	query := req.URL.Query()
+	if perPage := query.Get("per_page"); perPage != "" {
+		n, err := strconv.ParseInt(perPage, 10, 32)
+		if err != nil || n < 0 {
+			resp.WriteHeader(400)
+			resp.Write([]byte("Invalid per_page"))
+			return true
+		}
+		b.PerPage = int32(n)
+	}
+	if token := query.Get("next_token"); token != "" {
+		b.NextToken = token
+	}
+	if filter := query.Get("filter"); filter != "" {
+		b.Filter = filter
+	}
+	return false
+}
+
+// parseNamespace is used to parse the ?namespace query param
+func parseNamespace(req *http.Request, n *string) {
+	if other := req.URL.Query().Get("namespace"); other != "" {
+		*n = other
+	}
+}
+
 // parseRegion is used to parse the ?region query param
 func (s *HTTPServer) parseRegion(req *http.Request, r *string) {
 	if other := req.URL.Query().Get("region"); other != "" {
@@ -307,10 +575,38 @@ func (s *HTTPServer) parseRegion(req *http.Request, r *string) {
 	}
 }
 
+// parseToken is used to parse the X-Nomad-Token header
+func (s *HTTPServer) parseToken(req *http.Request, token *string) {
+	if other := req.Header.Get("X-Nomad-Token"); other != "" {
+		*token = other
+	}
+}
+
+// resolveToken is used to resolve the ACL object of the token in the
+// X-Nomad-Token header. A nil ACL object is returned if ACLs are disabled.
+func (s *HTTPServer) resolveToken(req *http.Request) (*acl.ACL, error) {
+	var secret string
+	s.parseToken(req, &secret)
+	return s.agent.ResolveToken(secret)
+}
+
 // parse is a convenience method for endpoints that need to parse multiple flags
 func (s *HTTPServer) parse(resp http.ResponseWriter, req *http.Request, r *string, b *structs.QueryOptions) bool {
 	s.parseRegion(req, r)
+	s.parseToken(req, &b.AuthToken)
 	parseConsistency(req, b)
 	parsePrefix(req, b)
+	parseNamespace(req, &b.Namespace)
+	if parsePagination(resp, req, b) {
+		return true
+	}
 	return parseWait(resp, req, b)
 }
+
+// parseWriteRequest is a convenience method for endpoints that need to parse a
+// write request
+func (s *HTTPServer) parseWriteRequest(req *http.Request, w *structs.WriteRequest) {
+	s.parseRegion(req, &w.Region)
+	s.parseToken(req, &w.AuthToken)
+	parseNamespace(req, &w.Namespace)
+}
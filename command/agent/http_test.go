@@ -2,6 +2,8 @@ package agent
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,12 +11,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/nomad/nomad/audit"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/hashicorp/nomad/testutil"
 )
 
@@ -150,6 +155,17 @@ func TestSetMeta(t *testing.T) {
 	if header != "123" {
 		t.Fatalf("Bad: %v", header)
 	}
+	if _, ok := resp.HeaderMap["X-Nomad-Nexttoken"]; ok {
+		t.Fatalf("Bad: %v", resp.HeaderMap)
+	}
+
+	meta.NextToken = "foo"
+	resp = httptest.NewRecorder()
+	setMeta(resp, &meta)
+	header = resp.Header().Get("X-Nomad-NextToken")
+	if header != "foo" {
+		t.Fatalf("Bad: %v", header)
+	}
 }
 
 func TestSetHeaders(t *testing.T) {
@@ -172,6 +188,76 @@ func TestSetHeaders(t *testing.T) {
 
 }
 
+func TestHTTP_Audit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	httpACLTest(t, func(c *Config) {
+		c.Audit = &AuditConfig{
+			Enabled: true,
+			Sinks: []*AuditSink{
+				{Name: "file", Path: path},
+			},
+			Filters: []*AuditFilter{
+				{Name: "health", Endpoints: []string{"/v1/agent/health"}},
+			},
+		}
+	}, func(s *TestServer, root *structs.ACLToken) {
+		handler := func(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+			return nil, CodedError(404, "not found")
+		}
+
+		// The filtered endpoint isn't recorded
+		req, _ := http.NewRequest("GET", "/v1/agent/health", nil)
+		s.Server.wrap(handler)(httptest.NewRecorder(), req)
+
+		req, _ = http.NewRequest("GET", "/v1/job/foo?namespace=team", nil)
+		req.Header.Set("X-Nomad-Token", root.SecretID)
+		s.Server.wrap(handler)(httptest.NewRecorder(), req)
+
+		// Both stages of the request are recorded with the token's accessor
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		defer f.Close()
+		var events []*audit.Event
+		dec := json.NewDecoder(f)
+		for dec.More() {
+			var event audit.Event
+			if err := dec.Decode(&event); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			events = append(events, &event)
+		}
+		if len(events) != 2 {
+			t.Fatalf("bad: %#v", events)
+		}
+
+		received, complete := events[0], events[1]
+		if received.Stage != audit.StageOperationReceived || complete.Stage != audit.StageOperationComplete {
+			t.Fatalf("bad: %#v %#v", received, complete)
+		}
+		if received.Request.ID != complete.Request.ID || received.ID == complete.ID {
+			t.Fatalf("bad: %#v %#v", received.Request, complete.Request)
+		}
+		if received.Auth == nil || received.Auth.AccessorID != root.AccessorID {
+			t.Fatalf("bad: %#v", received.Auth)
+		}
+		r := received.Request
+		if r.Operation != "GET" || r.Endpoint != "/v1/job/foo" || r.Namespace != "team" {
+			t.Fatalf("bad: %#v", r)
+		}
+		if complete.Response == nil || complete.Response.StatusCode != 404 || complete.Response.Error != "not found" {
+			t.Fatalf("bad: %#v", complete.Response)
+		}
+	})
+}
+
 func TestContentTypeIsJSON(t *testing.T) {
 	s := makeHTTPServer(t, nil)
 	defer s.Cleanup()
@@ -296,6 +382,43 @@ func TestParseWait_InvalidIndex(t *testing.T) {
 	}
 }
 
+func TestParsePagination(t *testing.T) {
+	resp := httptest.NewRecorder()
+	var b structs.QueryOptions
+
+	req, err := http.NewRequest("GET",
+		"/v1/jobs?per_page=10&next_token=foo&filter=Status+%3D%3D+%22running%22", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if d := parsePagination(resp, req, &b); d {
+		t.Fatalf("unexpected done")
+	}
+
+	if b.PerPage != 10 || b.NextToken != "foo" || b.Filter != `Status == "running"` {
+		t.Fatalf("Bad: %v", b)
+	}
+}
+
+func TestParsePagination_InvalidPerPage(t *testing.T) {
+	resp := httptest.NewRecorder()
+	var b structs.QueryOptions
+
+	req, err := http.NewRequest("GET", "/v1/jobs?per_page=-1", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if d := parsePagination(resp, req, &b); !d {
+		t.Fatalf("expected done")
+	}
+
+	if resp.Code != 400 {
+		t.Fatalf("bad code: %v", resp.Code)
+	}
+}
+
 func TestParseConsistency(t *testing.T) {
 	var b structs.QueryOptions
 
@@ -381,6 +504,78 @@ func getIndex(t *testing.T, resp *httptest.ResponseRecorder) uint64 {
 	return uint64(val)
 }
 
+func TestHTTP_VerifyHTTPSClient(t *testing.T) {
+	const (
+		cafile  = "../../nomad/test-fixtures/tls/ca.pem"
+		foocert = "../../nomad/test-fixtures/tls/server.pem"
+		fookey  = "../../nomad/test-fixtures/tls/server-key.pem"
+	)
+	s := makeHTTPServer(t, func(c *Config) {
+		c.TLSConfig = &config.TLSConfig{
+			EnableHTTP:        true,
+			EnableRPC:         true,
+			VerifyHTTPSClient: true,
+			CAFile:            cafile,
+			CertFile:          foocert,
+			KeyFile:           fookey,
+		}
+	})
+	defer s.Cleanup()
+	testutil.WaitForLeader(t, s.Agent.RPC)
+
+	// newClient returns an HTTP client trusting the CA and presenting the
+	// client certificate, if requested
+	newClient := func(withCert bool) *http.Client {
+		data, err := ioutil.ReadFile(cafile)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(data)
+		tlsConf := &tls.Config{RootCAs: pool}
+		if withCert {
+			cert, err := tls.LoadX509KeyPair("../../nomad/test-fixtures/tls/client.pem",
+				"../../nomad/test-fixtures/tls/client-key.pem")
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			tlsConf.Certificates = []tls.Certificate{cert}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConf}}
+	}
+	reqURL := fmt.Sprintf("https://%s/v1/agent/self", s.Server.addr)
+
+	// Requests without a client certificate are rejected
+	if _, err := newClient(false).Get(reqURL); err == nil {
+		t.Fatalf("expected request without a client certificate to fail")
+	}
+
+	// Requests with a client certificate signed by the CA are served
+	resp, err := newClient(true).Get(reqURL)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("bad: %d", resp.StatusCode)
+	}
+
+	// Once the CA is reloaded, certificates signed by the old CA are rejected
+	reloaded := s.Agent.config.TLSConfig.Copy()
+	reloaded.CAFile = "../../nomad/test-fixtures/tls/other-ca.pem"
+	if err := s.Server.ReloadTLS(reloaded); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := newClient(true).Get(reqURL); err == nil {
+		t.Fatalf("expected request with a certificate of the old CA to fail")
+	}
+
+	// Disabling TLS requires a restart
+	if err := s.Server.ReloadTLS(&config.TLSConfig{}); err == nil {
+		t.Fatalf("expected disabling TLS to fail")
+	}
+}
+
 func httpTest(t *testing.T, cb func(c *Config), f func(srv *TestServer)) {
 	s := makeHTTPServer(t, cb)
 	defer s.Cleanup()
@@ -388,6 +583,28 @@ func httpTest(t *testing.T, cb func(c *Config), f func(srv *TestServer)) {
 	f(s)
 }
 
+// httpACLTest is like httpTest but enables ACLs and passes the bootstrapped
+// management token to the test function.
+func httpACLTest(t *testing.T, cb func(c *Config), f func(srv *TestServer, root *structs.ACLToken)) {
+	s := makeHTTPServer(t, func(c *Config) {
+		c.ACL.Enabled = true
+		if cb != nil {
+			cb(c)
+		}
+	})
+	defer s.Cleanup()
+	testutil.WaitForLeader(t, s.Agent.RPC)
+
+	args := structs.ACLTokenBootstrapRequest{
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.ACLTokenUpsertResponse
+	if err := s.Agent.RPC("ACL.Bootstrap", &args, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	f(s, resp.Tokens[0])
+}
+
 func encodeReq(obj interface{}) io.ReadCloser {
 	buf := bytes.NewBuffer(nil)
 	enc := json.NewEncoder(buf)
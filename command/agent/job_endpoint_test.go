@@ -3,6 +3,7 @@ package agent
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/nomad/nomad/mock"
@@ -56,6 +57,55 @@ func TestHTTP_JobsList(t *testing.T) {
 	})
 }
 
+func TestHTTP_JobsList_Paginate(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		for i := 0; i < 3; i++ {
+			// Create the job
+			job := mock.Job()
+			args := structs.JobRegisterRequest{
+				Job:          job,
+				WriteRequest: structs.WriteRequest{Region: "global"},
+			}
+			var resp structs.JobRegisterResponse
+			if err := s.Agent.RPC("Job.Register", &args, &resp); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/jobs?per_page=2&filter=Type+%3D%3D+%22service%22", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.JobsRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check the page and the token of the next one
+		j := obj.([]*structs.JobListStub)
+		if len(j) != 2 {
+			t.Fatalf("bad: %#v", j)
+		}
+		if respW.HeaderMap.Get("X-Nomad-NextToken") == "" {
+			t.Fatalf("missing next token")
+		}
+
+		// Invalid filters are rejected
+		req, err = http.NewRequest("GET", "/v1/jobs?filter=Foo", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		_, err = s.Server.JobsRequest(httptest.NewRecorder(), req)
+		if err == nil || !strings.Contains(err.Error(), structs.ErrInvalidFilter.Error()) {
+			t.Fatalf("bad: %v", err)
+		}
+	})
+}
+
 func TestHTTP_PrefixJobsList(t *testing.T) {
 	ids := []string{
 		"aaaaaaaa-e8f7-fd38-c855-ab94ceb89706",
@@ -292,7 +342,7 @@ func TestHTTP_JobDelete(t *testing.T) {
 			t.Fatalf("missing index")
 		}
 
-		// Check the job is gone
+		// Check the job is stopped but not purged
 		getReq := structs.JobSpecificRequest{
 			JobID:        job.ID,
 			QueryOptions: structs.QueryOptions{Region: "global"},
@@ -301,9 +351,40 @@ func TestHTTP_JobDelete(t *testing.T) {
 		if err := s.Agent.RPC("Job.GetJob", &getReq, &getResp); err != nil {
 			t.Fatalf("err: %v", err)
 		}
-		if getResp.Job != nil {
+		if getResp.Job == nil || !getResp.Job.Stop {
+			t.Fatalf("bad: %#v", getResp.Job)
+		}
+
+		// Make the HTTP request to purge the job
+		req, err = http.NewRequest("DELETE", "/v1/job/"+job.ID+"?purge=true", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.JobSpecificRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check the job is gone
+		var getResp2 structs.SingleJobResponse
+		if err := s.Agent.RPC("Job.GetJob", &getReq, &getResp2); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if getResp2.Job != nil {
 			t.Fatalf("job still exists")
 		}
+
+		// Invalid purge values are rejected
+		req, err = http.NewRequest("DELETE", "/v1/job/"+job.ID+"?purge=foo", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.JobSpecificRequest(respW, req); err == nil {
+			t.Fatalf("expected error")
+		}
 	})
 }
 
@@ -346,6 +427,53 @@ func TestHTTP_JobForceEvaluate(t *testing.T) {
 	})
 }
 
+func TestHTTP_JobTrigger(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create the job
+		job := mock.Job()
+		args := structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &args, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		buf := encodeReq(structs.JobTriggerRequest{Reason: "new data"})
+		req, err := http.NewRequest("PUT", "/v1/job/"+job.ID+"/trigger", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.JobSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check the response
+		reg := obj.(structs.JobRegisterResponse)
+		if reg.EvalID == "" {
+			t.Fatalf("bad: %v", reg)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Check the evaluation
+		eval, err := s.Agent.server.State().EvalByID(reg.EvalID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if eval == nil || eval.TriggerReason != "new data" {
+			t.Fatalf("bad: %#v", eval)
+		}
+	})
+}
+
 func TestHTTP_JobEvaluations(t *testing.T) {
 	httpTest(t, nil, func(s *TestServer) {
 		// Create the job
@@ -486,6 +614,46 @@ func TestHTTP_PeriodicForce(t *testing.T) {
 	})
 }
 
+func TestHTTP_PeriodicLaunches(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create and register a periodic job.
+		job := mock.PeriodicJob()
+		job.Periodic.Specs = []*structs.PeriodicSpec{{Spec: "0 9 * * *", Enabled: true}}
+		args := structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &args, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/job/"+job.ID+"/periodic/launches", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.JobSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check for the index
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Check the response
+		launches := obj.([]*structs.PeriodicLaunchTime)
+		if len(launches) != 2 || launches[1].Spec != "0 9 * * *" || launches[1].Next.IsZero() {
+			t.Fatalf("bad: %#v", launches)
+		}
+	})
+}
+
 func TestHTTP_JobPlan(t *testing.T) {
 	httpTest(t, nil, func(s *TestServer) {
 		// Create the job
@@ -521,3 +689,295 @@ func TestHTTP_JobPlan(t *testing.T) {
 		}
 	})
 }
+
+func TestHTTP_JobDispatch(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create the parameterized job
+		job := mock.Job()
+		job.Type = "batch"
+		job.ParameterizedJob = &structs.ParameterizedJobConfig{}
+
+		args := structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &args, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the request
+		respW := httptest.NewRecorder()
+		args2 := structs.JobDispatchRequest{
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		buf := encodeReq(args2)
+
+		// Make the HTTP request
+		req2, err := http.NewRequest("PUT", "/v1/job/"+job.ID+"/dispatch", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the request
+		obj, err := s.Server.JobSpecificRequest(respW, req2)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check the response
+		dispatch := obj.(structs.JobDispatchResponse)
+		if dispatch.EvalID == "" {
+			t.Fatalf("bad: %v", dispatch)
+		}
+
+		if dispatch.DispatchedJobID == "" {
+			t.Fatalf("bad: %v", dispatch)
+		}
+	})
+}
+
+func TestHTTP_JobScale(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create the job
+		job := mock.Job()
+		args := structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &args, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the request
+		count := int64(3)
+		args2 := structs.JobScaleRequest{
+			Target:       map[string]string{structs.ScalingTargetGroup: "web"},
+			Count:        &count,
+			Message:      "scaling in",
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		buf := encodeReq(args2)
+
+		// Make the HTTP request
+		req, err := http.NewRequest("PUT", "/v1/job/"+job.ID+"/scale", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.JobSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check the response
+		scale := obj.(structs.JobRegisterResponse)
+		if scale.EvalID == "" {
+			t.Fatalf("bad: %v", scale)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Query the scaling status
+		req, err = http.NewRequest("GET", "/v1/job/"+job.ID+"/scale", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		obj, err = s.Server.JobSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		status := obj.(*structs.JobScaleStatus)
+		tgStatus := status.TaskGroups["web"]
+		if tgStatus == nil || tgStatus.Desired != 3 || len(tgStatus.Events) != 1 {
+			t.Fatalf("bad: %#v", tgStatus)
+		}
+		if tgStatus.Events[0].Message != "scaling in" || tgStatus.Events[0].EvalID != scale.EvalID {
+			t.Fatalf("bad: %#v", tgStatus.Events[0])
+		}
+	})
+}
+
+func TestHTTP_JobRollout(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create a job with a multi-region strategy
+		job := mock.Job()
+		job.Regions = []string{"global"}
+		job.Multiregion = &structs.MultiregionStrategy{MaxParallel: 1}
+		args := structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &args, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/job/"+job.ID+"/rollout", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.JobSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check the response
+		rollout := obj.(*structs.MultiregionRollout)
+		if len(rollout.Regions) != 1 || rollout.Regions[0].EvalID != resp.EvalID {
+			t.Fatalf("bad: %#v", rollout)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Jobs without a rollout aren't found
+		req, err = http.NewRequest("GET", "/v1/job/unknown/rollout", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		if _, err := s.Server.JobSpecificRequest(respW, req); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Fatalf("expected not found: %v", err)
+		}
+	})
+}
+
+func TestHTTP_JobSubmission(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create a job along with its source
+		job := mock.Job()
+		args := structs.JobRegisterRequest{
+			Job: job,
+			Submission: &structs.JobSubmission{
+				Source: `job "example" {}`,
+				Format: structs.JobSubmissionFormatHCL,
+			},
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &args, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/job/"+job.ID+"/submission", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.JobSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check the response
+		sub := obj.(*structs.JobSubmission)
+		if sub.JobID != job.ID || sub.Source != args.Submission.Source || sub.Format != "hcl" {
+			t.Fatalf("bad: %#v", sub)
+		}
+		if sub.JobModifyIndex != resp.JobModifyIndex {
+			t.Fatalf("bad: %d", sub.JobModifyIndex)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Unknown jobs have no submission
+		req, err = http.NewRequest("GET", "/v1/job/unknown/submission", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		if _, err := s.Server.JobSpecificRequest(respW, req); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Fatalf("bad: %v", err)
+		}
+	})
+}
+
+func TestHTTP_JobVersions(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Register the job twice
+		job := mock.Job()
+		args := structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &args, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		job2 := job.Copy()
+		job2.Priority = 90
+		args.Job = job2
+		if err := s.Agent.RPC("Job.Register", &args, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/job/"+job.ID+"/versions", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.JobSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check the response
+		versions := obj.([]*structs.Job)
+		if len(versions) != 2 || versions[0].Version != 1 || versions[1].Version != 0 {
+			t.Fatalf("bad: %#v", versions)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Diff the versions
+		req, err = http.NewRequest("GET", "/v1/job/"+job.ID+"/versions/diff?from=0&to=1", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		obj, err = s.Server.JobSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		diff := obj.(*structs.JobDiff)
+		if diff.Type != structs.DiffTypeEdited || len(diff.Fields) != 1 || diff.Fields[0].Name != "Priority" {
+			t.Fatalf("bad: %#v", diff)
+		}
+
+		// Untracked versions aren't found
+		req, err = http.NewRequest("GET", "/v1/job/"+job.ID+"/versions/diff?from=0&to=7", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := s.Server.JobSpecificRequest(httptest.NewRecorder(), req); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Fatalf("bad: %v", err)
+		}
+
+		// Versions are required
+		req, err = http.NewRequest("GET", "/v1/job/"+job.ID+"/versions/diff?from=0", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := s.Server.JobSpecificRequest(httptest.NewRecorder(), req); err == nil || !strings.Contains(err.Error(), "invalid to version") {
+			t.Fatalf("bad: %v", err)
+		}
+	})
+}
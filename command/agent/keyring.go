@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/memberlist"
+	"github.com/hashicorp/serf/serf"
+)
+
+const (
+	// serfKeyringFile is the file in the data directory of the server that
+	// persists the keys used to encrypt the gossip traffic. Serf rewrites it
+	// whenever the keyring is modified.
+	serfKeyringFile = "serf.keyring"
+)
+
+// decodeGossipKey decodes a base64 encoded gossip encryption key and checks
+// that it is a valid AES key
+func decodeGossipKey(key string) ([]byte, error) {
+	keyBytes, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gossip encryption key: %v", err)
+	}
+	if l := len(keyBytes); l != 16 && l != 24 && l != 32 {
+		return nil, fmt.Errorf("invalid gossip encryption key: key size must be 16, 24 or 32 bytes")
+	}
+	return keyBytes, nil
+}
+
+// initKeyring writes a keyring file holding the given key, unless the file
+// already exists. An existing keyring takes precedence over the configured
+// key so that keys installed through the keyring API survive restarts.
+func initKeyring(path, key string) error {
+	if _, err := decodeGossipKey(key); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	keyringBytes, err := json.Marshal([]string{key})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	fh, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	if _, err := fh.Write(keyringBytes); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// loadKeyringFile sets up the keyring of the Serf configuration from the
+// keyring file it points to. The first key of the file is used as the
+// primary key.
+func loadKeyringFile(c *serf.Config) error {
+	if c.KeyringFile == "" {
+		return nil
+	}
+
+	keyringData, err := ioutil.ReadFile(c.KeyringFile)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	if err := json.Unmarshal(keyringData, &keys); err != nil {
+		return fmt.Errorf("failed to decode keyring file %q: %v", c.KeyringFile, err)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no keys present in keyring file %q", c.KeyringFile)
+	}
+
+	keysDecoded := make([][]byte, len(keys))
+	for i, key := range keys {
+		keyBytes, err := decodeGossipKey(key)
+		if err != nil {
+			return fmt.Errorf("keyring file %q: %v", c.KeyringFile, err)
+		}
+		keysDecoded[i] = keyBytes
+	}
+
+	keyring, err := memberlist.NewKeyring(keysDecoded, keysDecoded[0])
+	if err != nil {
+		return err
+	}
+	c.MemberlistConfig.Keyring = keyring
+	return nil
+}
+
+// setupKeyring enables the encryption of the gossip traffic of the server.
+// Servers with a data directory persist their keyring in it, so that it can
+// be rotated at runtime. Without a data directory the configured key is used
+// as is.
+func setupKeyring(c *serf.Config, dataDir, key string) error {
+	if dataDir == "" {
+		if key == "" {
+			return nil
+		}
+		keyBytes, err := decodeGossipKey(key)
+		if err != nil {
+			return err
+		}
+		c.MemberlistConfig.SecretKey = keyBytes
+		return nil
+	}
+
+	path := filepath.Join(dataDir, serfKeyringFile)
+	if key != "" {
+		if err := initKeyring(path, key); err != nil {
+			return err
+		}
+	}
+
+	// Without a keyring the gossip traffic stays unencrypted
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	c.KeyringFile = path
+	return loadKeyringFile(c)
+}
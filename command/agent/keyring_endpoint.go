@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func (s *HTTPServer) KeyringKeysRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.KeyringListRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.KeyringListResponse
+	if err := s.agent.RPC("Keyring.List", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.RootKeys == nil {
+		out.RootKeys = make([]*structs.RootKeyStub, 0)
+	}
+	if out.WorkloadIdentityKeys == nil {
+		out.WorkloadIdentityKeys = make([]*structs.WorkloadIdentityPublicKey, 0)
+	}
+	return out.KeyringKeys, nil
+}
+
+func (s *HTTPServer) KeyringRotateRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.KeyringRotateRequest{}
+	s.parseWriteRequest(req, &args.WriteRequest)
+
+	var out structs.KeyringRotateResponse
+	if err := s.agent.RPC("Keyring.Rotate", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return out.KeyringRotation, nil
+}
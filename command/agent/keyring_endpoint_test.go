@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_KeyringRotateAndList(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Rotate the keyring
+		req, err := http.NewRequest("PUT", "/v1/operator/keyring/rotate", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.KeyringRotateRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+		rotation := obj.(structs.KeyringRotation)
+		if rotation.RootKey == nil || rotation.WorkloadIdentityKey == nil {
+			t.Fatalf("bad: %#v", rotation)
+		}
+
+		// List the keys
+		req, err = http.NewRequest("GET", "/v1/operator/keyring/keys", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.KeyringKeysRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+		keys := obj.(structs.KeyringKeys)
+		if keys.ActiveRootKeyID != rotation.RootKey.KeyID || len(keys.RootKeys) != 1 {
+			t.Fatalf("bad: %#v", keys)
+		}
+
+		// Only GET is supported for listing
+		req, err = http.NewRequest("DELETE", "/v1/operator/keyring/keys", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		if _, err := s.Server.KeyringKeysRequest(respW, req); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/serf/serf"
+)
+
+func TestAgent_SetupKeyring(t *testing.T) {
+	dir := tmpDir(t)
+	defer os.RemoveAll(dir)
+
+	key1 := "tbLJg26ZJyJ9pK3qhc9jig=="
+	key2 := "4leC33rgtXKIVUr9Nr0snQ=="
+
+	// Without a key or a keyring the gossip isn't encrypted
+	conf := serf.DefaultConfig()
+	if err := setupKeyring(conf, dir, ""); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if conf.KeyringFile != "" || conf.MemberlistConfig.Keyring != nil {
+		t.Fatalf("bad: %#v", conf)
+	}
+
+	// The configured key initializes the keyring file
+	conf = serf.DefaultConfig()
+	if err := setupKeyring(conf, dir, key1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	path := filepath.Join(dir, serfKeyringFile)
+	if conf.KeyringFile != path {
+		t.Fatalf("bad: %q", conf.KeyringFile)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !bytes.Contains(content, []byte(key1)) {
+		t.Fatalf("bad: %s", content)
+	}
+
+	// An existing keyring takes precedence over the configured key
+	conf = serf.DefaultConfig()
+	if err := setupKeyring(conf, dir, key2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(key1)
+	if primary := conf.MemberlistConfig.Keyring.GetPrimaryKey(); !bytes.Equal(primary, raw) {
+		t.Fatalf("bad: %v", primary)
+	}
+}
+
+func TestAgent_SetupKeyring_NoDataDir(t *testing.T) {
+	conf := serf.DefaultConfig()
+	if err := setupKeyring(conf, "", "tbLJg26ZJyJ9pK3qhc9jig=="); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(conf.MemberlistConfig.SecretKey) != 16 || conf.KeyringFile != "" {
+		t.Fatalf("bad: %#v", conf)
+	}
+
+	// Invalid keys are rejected
+	for _, key := range []string{"not base64", "Zm9v"} {
+		if err := setupKeyring(serf.DefaultConfig(), "", key); err == nil {
+			t.Fatalf("expected key %q to be rejected", key)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func (s *HTTPServer) MaintenanceWindowsRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.MaintenanceWindowListRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.MaintenanceWindowListResponse
+	if err := s.agent.RPC("Maintenance.ListWindows", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Windows == nil {
+		out.Windows = make([]*structs.MaintenanceWindow, 0)
+	}
+	return out.Windows, nil
+}
+
+func (s *HTTPServer) MaintenanceWindowCreateRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+	return s.maintenanceWindowUpdate(resp, req, "")
+}
+
+func (s *HTTPServer) MaintenanceWindowSpecificRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	id := strings.TrimPrefix(req.URL.Path, "/v1/maintenance/window/")
+	if len(id) == 0 {
+		return nil, CodedError(400, "Missing Maintenance Window ID")
+	}
+	switch req.Method {
+	case "GET":
+		return s.maintenanceWindowQuery(resp, req, id)
+	case "PUT", "POST":
+		return s.maintenanceWindowUpdate(resp, req, id)
+	case "DELETE":
+		return s.maintenanceWindowDelete(resp, req, id)
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) maintenanceWindowQuery(resp http.ResponseWriter, req *http.Request,
+	id string) (interface{}, error) {
+	args := structs.MaintenanceWindowSpecificRequest{
+		WindowID: id,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.SingleMaintenanceWindowResponse
+	if err := s.agent.RPC("Maintenance.GetWindow", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Window == nil {
+		return nil, CodedError(404, "Maintenance window not found")
+	}
+	return out.Window, nil
+}
+
+func (s *HTTPServer) maintenanceWindowUpdate(resp http.ResponseWriter, req *http.Request,
+	id string) (interface{}, error) {
+	// Parse the maintenance window
+	var window structs.MaintenanceWindow
+	if err := decodeBody(req, &window); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+
+	// Ensure the window ID matches
+	if id != "" {
+		if window.ID == "" {
+			window.ID = id
+		} else if window.ID != id {
+			return nil, CodedError(400, "Maintenance window ID does not match request path")
+		}
+	}
+
+	args := structs.MaintenanceWindowUpsertRequest{
+		Windows: []*structs.MaintenanceWindow{&window},
+	}
+	s.parseWriteRequest(req, &args.WriteRequest)
+
+	var out structs.MaintenanceWindowUpsertResponse
+	if err := s.agent.RPC("Maintenance.UpsertWindows", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return out, nil
+}
+
+func (s *HTTPServer) maintenanceWindowDelete(resp http.ResponseWriter, req *http.Request,
+	id string) (interface{}, error) {
+
+	args := structs.MaintenanceWindowDeleteRequest{
+		WindowIDs: []string{id},
+	}
+	s.parseWriteRequest(req, &args.WriteRequest)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("Maintenance.DeleteWindows", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_MaintenanceWindowCRUD(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create a window
+		now := time.Now().UTC()
+		window := &structs.MaintenanceWindow{
+			NodeClass: "gpu",
+			Mode:      structs.MaintenanceModeSoft,
+			Start:     now,
+			End:       now.Add(time.Hour),
+		}
+		buf := encodeReq(window)
+		req, err := http.NewRequest("PUT", "/v1/maintenance/window", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.MaintenanceWindowCreateRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+		ids := obj.(structs.MaintenanceWindowUpsertResponse).WindowIDs
+		if len(ids) != 1 || ids[0] == "" {
+			t.Fatalf("bad: %#v", ids)
+		}
+		id := ids[0]
+
+		// Query the window
+		req, err = http.NewRequest("GET", "/v1/maintenance/window/"+id, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.MaintenanceWindowSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		out := obj.(*structs.MaintenanceWindow)
+		if out.ID != id || out.NodeClass != window.NodeClass || out.Mode != window.Mode {
+			t.Fatalf("bad: %#v", out)
+		}
+
+		// List the windows
+		req, err = http.NewRequest("GET", "/v1/maintenance/windows", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.MaintenanceWindowsRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if n := len(obj.([]*structs.MaintenanceWindow)); n != 1 {
+			t.Fatalf("bad: %d", n)
+		}
+
+		// Delete the window
+		req, err = http.NewRequest("DELETE", "/v1/maintenance/window/"+id, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.MaintenanceWindowSpecificRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// The window is gone
+		req, err = http.NewRequest("GET", "/v1/maintenance/window/"+id, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		_, err = s.Server.MaintenanceWindowSpecificRequest(respW, req)
+		if err == nil || err.Error() != "Maintenance window not found" {
+			t.Fatalf("expected not found: %v", err)
+		}
+	})
+}
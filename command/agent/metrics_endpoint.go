@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// metricsFormatPrometheus is the format of the metrics endpoint
+	// compatible with the Prometheus text exposition format
+	metricsFormatPrometheus = "prometheus"
+
+	// prometheusContentType is the content type of the Prometheus text
+	// exposition format
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// MetricsSummary is the summary of the metrics aggregated by the agent over
+// the current interval
+type MetricsSummary struct {
+	Timestamp string
+	Gauges    []GaugeValue
+	Counters  []SampledValue
+	Samples   []SampledValue
+}
+
+// GaugeValue is the last value of a gauge
+type GaugeValue struct {
+	Name  string
+	Value float32
+}
+
+// SampledValue is the aggregate of the values of a counter or a sample
+type SampledValue struct {
+	Name   string
+	Count  int
+	Sum    float64
+	Min    float64
+	Max    float64
+	Mean   float64
+	Stddev float64
+}
+
+// MetricsRequest is used to query the metrics of the agent, either as JSON or
+// in the Prometheus text exposition format
+func (s *HTTPServer) MetricsRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	// Check agent read permissions
+	if aclObj, err := s.resolveToken(req); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.AllowAgentRead() {
+		return nil, structs.ErrPermissionDenied
+	}
+
+	inm := s.agent.InmemSink()
+	if inm == nil {
+		return nil, CodedError(501, "metrics are not collected by the agent")
+	}
+	summary := metricsSummary(inm)
+
+	switch format := req.URL.Query().Get("format"); format {
+	case "":
+		return summary, nil
+	case metricsFormatPrometheus:
+		resp.Header().Set("Content-Type", prometheusContentType)
+		resp.Write(prometheusMetrics(summary))
+		return nil, nil
+	default:
+		return nil, CodedError(400, fmt.Sprintf("unknown metrics format %q", format))
+	}
+}
+
+// metricsSummary summarizes the latest interval of the in-memory sink, with
+// the metrics sorted by name
+func metricsSummary(inm *metrics.InmemSink) *MetricsSummary {
+	summary := &MetricsSummary{
+		Gauges:   make([]GaugeValue, 0),
+		Counters: make([]SampledValue, 0),
+		Samples:  make([]SampledValue, 0),
+	}
+
+	data := inm.Data()
+	if len(data) == 0 {
+		summary.Timestamp = time.Now().UTC().String()
+		return summary
+	}
+
+	interval := data[len(data)-1]
+	interval.RLock()
+	defer interval.RUnlock()
+
+	summary.Timestamp = interval.Interval.UTC().String()
+	for name, value := range interval.Gauges {
+		summary.Gauges = append(summary.Gauges, GaugeValue{Name: name, Value: value})
+	}
+	for name, agg := range interval.Counters {
+		summary.Counters = append(summary.Counters, sampledValue(name, agg))
+	}
+	for name, agg := range interval.Samples {
+		summary.Samples = append(summary.Samples, sampledValue(name, agg))
+	}
+
+	sort.Sort(gaugeValues(summary.Gauges))
+	sort.Sort(sampledValues(summary.Counters))
+	sort.Sort(sampledValues(summary.Samples))
+	return summary
+}
+
+func sampledValue(name string, agg *metrics.AggregateSample) SampledValue {
+	return SampledValue{
+		Name:   name,
+		Count:  agg.Count,
+		Sum:    agg.Sum,
+		Min:    agg.Min,
+		Max:    agg.Max,
+		Mean:   agg.Mean(),
+		Stddev: agg.Stddev(),
+	}
+}
+
+// prometheusMetrics renders the summary in the Prometheus text exposition
+// format. Gauges are exported as gauges. Counters are aggregated by interval,
+// so they are exported untyped with their sum over the interval. Samples are
+// exported as summaries whose 0 and 1 quantiles are their minimum and
+// maximum.
+func prometheusMetrics(summary *MetricsSummary) []byte {
+	var buf bytes.Buffer
+	for _, g := range summary.Gauges {
+		name := prometheusName(g.Name)
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", name)
+		fmt.Fprintf(&buf, "%s %v\n", name, g.Value)
+	}
+	for _, c := range summary.Counters {
+		name := prometheusName(c.Name)
+		fmt.Fprintf(&buf, "# TYPE %s untyped\n", name)
+		fmt.Fprintf(&buf, "%s %v\n", name, c.Sum)
+	}
+	for _, s := range summary.Samples {
+		name := prometheusName(s.Name)
+		fmt.Fprintf(&buf, "# TYPE %s summary\n", name)
+		fmt.Fprintf(&buf, "%s{quantile=\"0\"} %v\n", name, s.Min)
+		fmt.Fprintf(&buf, "%s{quantile=\"1\"} %v\n", name, s.Max)
+		fmt.Fprintf(&buf, "%s_sum %v\n", name, s.Sum)
+		fmt.Fprintf(&buf, "%s_count %d\n", name, s.Count)
+	}
+	return buf.Bytes()
+}
+
+// prometheusName converts the dotted name of a metric to a valid Prometheus
+// metric name
+func prometheusName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+type gaugeValues []GaugeValue
+
+func (g gaugeValues) Len() int           { return len(g) }
+func (g gaugeValues) Swap(i, j int)      { g[i], g[j] = g[j], g[i] }
+func (g gaugeValues) Less(i, j int) bool { return g[i].Name < g[j].Name }
+
+type sampledValues []SampledValue
+
+func (s sampledValues) Len() int           { return len(s) }
+func (s sampledValues) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sampledValues) Less(i, j int) bool { return s[i].Name < s[j].Name }
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTP_Metrics(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		inm := s.Agent.InmemSink()
+		inm.SetGauge([]string{"nomad", "broker", "total_ready"}, 3)
+		inm.IncrCounter([]string{"nomad", "plan", "node_rejected"}, 2)
+		inm.AddSample([]string{"nomad", "worker", "dequeue_to_plan"}, 10)
+		inm.AddSample([]string{"nomad", "worker", "dequeue_to_plan"}, 30)
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/metrics", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.MetricsRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check the summary
+		summary := obj.(*MetricsSummary)
+		var found bool
+		for _, g := range summary.Gauges {
+			if g.Name == "nomad.broker.total_ready" && g.Value == 3 {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("bad: %#v", summary.Gauges)
+		}
+		found = false
+		for _, sample := range summary.Samples {
+			if sample.Name == "nomad.worker.dequeue_to_plan" && sample.Count == 2 && sample.Mean == 20 {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("bad: %#v", summary.Samples)
+		}
+	})
+}
+
+func TestHTTP_Metrics_Prometheus(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		inm := s.Agent.InmemSink()
+		inm.SetGauge([]string{"nomad", "broker", "total_ready"}, 3)
+		inm.IncrCounter([]string{"nomad", "plan", "node_rejected"}, 2)
+		inm.AddSample([]string{"nomad", "worker", "dequeue_to_plan"}, 10)
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/metrics?format=prometheus", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.MetricsRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if obj != nil {
+			t.Fatalf("bad: %#v", obj)
+		}
+
+		if ct := respW.HeaderMap.Get("Content-Type"); ct != prometheusContentType {
+			t.Fatalf("bad: %q", ct)
+		}
+		body := respW.Body.String()
+		expected := []string{
+			"# TYPE nomad_broker_total_ready gauge\nnomad_broker_total_ready 3\n",
+			"# TYPE nomad_plan_node_rejected untyped\nnomad_plan_node_rejected 2\n",
+			"nomad_worker_dequeue_to_plan_count 1\n",
+		}
+		for _, e := range expected {
+			if !strings.Contains(body, e) {
+				t.Fatalf("expected %q in:\n%s", e, body)
+			}
+		}
+
+		// Unknown formats are rejected
+		req, err = http.NewRequest("GET", "/v1/metrics?format=foo", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := s.Server.MetricsRequest(httptest.NewRecorder(), req); err == nil || !strings.Contains(err.Error(), "unknown metrics format") {
+			t.Fatalf("bad: %v", err)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_NamespaceCRUD(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create a namespace
+		ns := &structs.Namespace{
+			Name:        "engineering",
+			Description: "engineering team",
+		}
+		buf := encodeReq(ns)
+		req, err := http.NewRequest("PUT", "/v1/namespace/engineering", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		if _, err := s.Server.NamespaceSpecificRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Query the namespace
+		req, err = http.NewRequest("GET", "/v1/namespace/engineering", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err := s.Server.NamespaceSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		out := obj.(*structs.Namespace)
+		if out.Name != ns.Name || out.Description != ns.Description {
+			t.Fatalf("bad: %#v", out)
+		}
+
+		// List the namespaces
+		req, err = http.NewRequest("GET", "/v1/namespaces", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.NamespacesRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if n := len(obj.([]*structs.Namespace)); n != 2 {
+			t.Fatalf("bad: %d", n)
+		}
+
+		// Delete the namespace
+		req, err = http.NewRequest("DELETE", "/v1/namespace/engineering", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.NamespaceSpecificRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// The namespace is gone
+		req, err = http.NewRequest("GET", "/v1/namespace/engineering", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.NamespaceSpecificRequest(respW, req); err == nil {
+			t.Fatalf("expected not found error")
+		}
+	})
+}
+
+func TestHTTP_NamespaceReplicationStatus(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		req, err := http.NewRequest("GET", "/v1/namespaces/replication", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.NamespaceReplicationStatusRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// The authoritative region doesn't replicate
+		status := obj.(*structs.NamespaceReplicationStatus)
+		if status.Enabled || status.Running || status.SourceRegion != "" {
+			t.Fatalf("bad: %#v", status)
+		}
+	})
+}
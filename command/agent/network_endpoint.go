@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// ClientNetworkRebuildRequest is used to rebuild the port accounting of the
+// node from its live allocations, releasing the leaked port reservations
+func (s *HTTPServer) ClientNetworkRebuildRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.agent.client == nil {
+		return nil, clientNotRunning
+	}
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	// Check node write permissions
+	if aclObj, err := s.resolveToken(req); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.AllowNodeWrite() {
+		return nil, structs.ErrPermissionDenied
+	}
+
+	return s.agent.client.RebuildNetworkIndex(), nil
+}
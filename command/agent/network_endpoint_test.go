@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cstructs "github.com/hashicorp/nomad/client/structs"
+)
+
+func TestHTTP_ClientNetworkRebuild(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Only writes are allowed
+		req, err := http.NewRequest("GET", "/v1/client/network/rebuild", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		if _, err := s.Server.ClientNetworkRebuildRequest(respW, req); err == nil {
+			t.Fatalf("expected an error")
+		}
+
+		// Make the HTTP request
+		req, err = http.NewRequest("PUT", "/v1/client/network/rebuild", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.ClientNetworkRebuildRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Nothing leaked on a new node
+		report := obj.(*cstructs.NetworkIndexReport)
+		if len(report.ReleasedPorts) != 0 || len(report.Collisions) != 0 {
+			t.Fatalf("bad: %#v", report)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/nomad/nomad/structs"
 )
@@ -42,6 +43,9 @@ func (s *HTTPServer) NodeSpecificRequest(resp http.ResponseWriter, req *http.Req
 	case strings.HasSuffix(path, "/drain"):
 		nodeName := strings.TrimSuffix(path, "/drain")
 		return s.nodeToggleDrain(resp, req, nodeName)
+	case strings.HasSuffix(path, "/eligibility"):
+		nodeName := strings.TrimSuffix(path, "/eligibility")
+		return s.nodeToggleEligibility(resp, req, nodeName)
 	default:
 		return s.nodeQuery(resp, req, path)
 	}
@@ -55,7 +59,7 @@ func (s *HTTPServer) nodeForceEvaluate(resp http.ResponseWriter, req *http.Reque
 	args := structs.NodeEvaluateRequest{
 		NodeID: nodeID,
 	}
-	s.parseRegion(req, &args.Region)
+	s.parseWriteRequest(req, &args.WriteRequest)
 
 	var out structs.NodeUpdateResponse
 	if err := s.agent.RPC("Node.Evaluate", &args, &out); err != nil {
@@ -86,6 +90,9 @@ func (s *HTTPServer) nodeAllocations(resp http.ResponseWriter, req *http.Request
 	if out.Allocs == nil {
 		out.Allocs = make([]*structs.Allocation, 0)
 	}
+	for i, alloc := range out.Allocs {
+		out.Allocs[i] = withoutSignedIdentities(alloc)
+	}
 	return out.Allocs, nil
 }
 
@@ -105,11 +112,38 @@ func (s *HTTPServer) nodeToggleDrain(resp http.ResponseWriter, req *http.Request
 		return nil, CodedError(400, "invalid enable value")
 	}
 
+	// Get the optional flag to leave system jobs running
+	var ignoreSystem bool
+	if ignoreRaw := req.URL.Query().Get("ignore_system"); ignoreRaw != "" {
+		ignoreSystem, err = strconv.ParseBool(ignoreRaw)
+		if err != nil {
+			return nil, CodedError(400, "invalid ignore_system value")
+		}
+	}
+
+	// Get the optional deadline of the drain. Without one, the allocations
+	// are migrated however long it takes, while a negative deadline forces
+	// them all to stop right away.
+	var deadline time.Duration
+	if deadlineRaw := req.URL.Query().Get("deadline"); deadlineRaw != "" {
+		deadline, err = time.ParseDuration(deadlineRaw)
+		if err != nil {
+			return nil, CodedError(400, "invalid deadline value")
+		}
+	}
+
 	args := structs.NodeUpdateDrainRequest{
 		NodeID: nodeID,
-		Drain:  enable,
 	}
-	s.parseRegion(req, &args.Region)
+	if enable {
+		args.DrainStrategy = &structs.DrainStrategy{
+			DrainSpec: structs.DrainSpec{
+				Deadline:         deadline,
+				IgnoreSystemJobs: ignoreSystem,
+			},
+		}
+	}
+	s.parseWriteRequest(req, &args.WriteRequest)
 
 	var out structs.NodeDrainUpdateResponse
 	if err := s.agent.RPC("Node.UpdateDrain", &args, &out); err != nil {
@@ -119,6 +153,39 @@ func (s *HTTPServer) nodeToggleDrain(resp http.ResponseWriter, req *http.Request
 	return out, nil
 }
 
+func (s *HTTPServer) nodeToggleEligibility(resp http.ResponseWriter, req *http.Request,
+	nodeID string) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	// Get the enable value
+	enableRaw := req.URL.Query().Get("enable")
+	if enableRaw == "" {
+		return nil, CodedError(400, "missing enable value")
+	}
+	enable, err := strconv.ParseBool(enableRaw)
+	if err != nil {
+		return nil, CodedError(400, "invalid enable value")
+	}
+
+	args := structs.NodeUpdateEligibilityRequest{
+		NodeID:      nodeID,
+		Eligibility: structs.NodeSchedulingIneligible,
+	}
+	if enable {
+		args.Eligibility = structs.NodeSchedulingEligible
+	}
+	s.parseWriteRequest(req, &args.WriteRequest)
+
+	var out structs.NodeEligibilityUpdateResponse
+	if err := s.agent.RPC("Node.UpdateEligibility", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return out, nil
+}
+
 func (s *HTTPServer) nodeQuery(resp http.ResponseWriter, req *http.Request,
 	nodeID string) (interface{}, error) {
 	if req.Method != "GET" {
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -246,7 +247,7 @@ func TestHTTP_NodeDrain(t *testing.T) {
 		}
 
 		// Make the HTTP request
-		req, err := http.NewRequest("POST", "/v1/node/"+node.ID+"/drain?enable=1", nil)
+		req, err := http.NewRequest("POST", "/v1/node/"+node.ID+"/drain?enable=1&deadline=10s", nil)
 		if err != nil {
 			t.Fatalf("err: %v", err)
 		}
@@ -265,9 +266,75 @@ func TestHTTP_NodeDrain(t *testing.T) {
 
 		// Check the response
 		upd := obj.(structs.NodeDrainUpdateResponse)
-		if len(upd.EvalIDs) == 0 {
+		if len(upd.DrainOrder) == 0 {
 			t.Fatalf("bad: %v", upd)
 		}
+
+		// Check that the node is draining with the deadline
+		out, err := state.NodeByID(node.ID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !out.Drain || out.DrainStrategy == nil || out.DrainStrategy.Deadline != 10*time.Second {
+			t.Fatalf("bad: %#v", out)
+		}
+
+		// An invalid deadline is rejected
+		req, err = http.NewRequest("POST", "/v1/node/"+node.ID+"/drain?enable=1&deadline=foo", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := s.Server.NodeSpecificRequest(httptest.NewRecorder(), req); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
+
+func TestHTTP_NodeEligibility(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create the node
+		node := mock.Node()
+		args := structs.NodeRegisterRequest{
+			Node:         node,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.NodeUpdateResponse
+		if err := s.Agent.RPC("Node.Register", &args, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("POST", "/v1/node/"+node.ID+"/eligibility?enable=false", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.NodeSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check for the index
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Check the response
+		upd := obj.(structs.NodeEligibilityUpdateResponse)
+		if upd.NodeModifyIndex == 0 {
+			t.Fatalf("bad: %v", upd)
+		}
+
+		// Check the node in the state
+		out, err := s.Agent.server.State().NodeByID(node.ID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out.SchedulingEligibility != structs.NodeSchedulingIneligible {
+			t.Fatalf("bad: %#v", out)
+		}
 	})
 }
 
@@ -0,0 +1,243 @@
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// SnapshotRequest saves a snapshot of the state of the cluster on GET, which
+// is streamed back as a snapshot archive, and restores the snapshot archive
+// sent as the request body on PUT.
+func (s *HTTPServer) SnapshotRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	switch req.Method {
+	case "GET":
+		return s.snapshotSave(resp, req)
+	case "PUT", "POST":
+		return s.snapshotRestore(resp, req)
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) snapshotSave(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	args := structs.SnapshotSaveRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.SnapshotSaveResponse
+	if err := s.agent.RPC("Operator.SnapshotSave", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	resp.Header().Set("Content-Type", "application/octet-stream")
+	resp.Header().Set("Content-Length", fmt.Sprintf("%d", len(out.Snapshot)))
+	resp.Write(out.Snapshot)
+	return nil, nil
+}
+
+func (s *HTTPServer) snapshotRestore(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	args := structs.SnapshotRestoreRequest{}
+	s.parseWriteRequest(req, &args.WriteRequest)
+
+	if req.Body == nil {
+		return nil, CodedError(400, "missing snapshot")
+	}
+	snapshot, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	if len(snapshot) == 0 {
+		return nil, CodedError(400, "missing snapshot")
+	}
+	args.Snapshot = snapshot
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("Operator.SnapshotRestore", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+// OperatorRaftConfiguration is used to list the servers of the Raft
+// configuration of the region
+func (s *HTTPServer) OperatorRaftConfiguration(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.GenericRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.RaftConfigurationResponse
+	if err := s.agent.RPC("Operator.RaftGetConfiguration", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	return out, nil
+}
+
+// OperatorRaftPeer is used to remove the server at the address given by the
+// address query parameter from the Raft configuration
+func (s *HTTPServer) OperatorRaftPeer(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "DELETE" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.RaftPeerByAddressRequest{
+		Address: req.URL.Query().Get("address"),
+	}
+	if args.Address == "" {
+		return nil, CodedError(400, "Must specify ?address with the address of the peer to remove")
+	}
+	s.parseWriteRequest(req, &args.WriteRequest)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("Operator.RaftRemovePeerByAddress", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+// OperatorAutopilotConfiguration is used to get the configuration of
+// autopilot on GET, and to set it on PUT. Setting the configuration with the
+// cas query parameter only succeeds if its ModifyIndex still matches.
+func (s *HTTPServer) OperatorAutopilotConfiguration(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	switch req.Method {
+	case "GET":
+		args := structs.GenericRequest{}
+		if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+			return nil, nil
+		}
+
+		var out structs.AutopilotConfigResponse
+		if err := s.agent.RPC("Operator.AutopilotGetConfiguration", &args, &out); err != nil {
+			return nil, err
+		}
+
+		setMeta(resp, &out.QueryMeta)
+		return out.Config, nil
+
+	case "PUT", "POST":
+		args := structs.AutopilotSetConfigRequest{}
+		s.parseWriteRequest(req, &args.WriteRequest)
+		if err := decodeBody(req, &args.Config); err != nil {
+			return nil, CodedError(400, fmt.Sprintf("Error parsing autopilot config: %v", err))
+		}
+
+		// Check for cas value
+		if casRaw := req.URL.Query().Get("cas"); casRaw != "" {
+			casVal, err := strconv.ParseUint(casRaw, 10, 64)
+			if err != nil {
+				return nil, CodedError(400, fmt.Sprintf("Error parsing cas value: %v", err))
+			}
+			args.Config.ModifyIndex = casVal
+			args.CAS = true
+		}
+
+		var out structs.AutopilotSetConfigResponse
+		if err := s.agent.RPC("Operator.AutopilotSetConfiguration", &args, &out); err != nil {
+			return nil, err
+		}
+		setIndex(resp, out.Index)
+
+		// Only use the out value if this was a CAS
+		if !args.CAS {
+			return true, nil
+		}
+		return out.Updated, nil
+
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+// OperatorSchedulerConfiguration is used to get the configuration of the
+// schedulers on GET, and to set it on PUT. Setting the configuration with the
+// cas query parameter only succeeds if its ModifyIndex still matches.
+func (s *HTTPServer) OperatorSchedulerConfiguration(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	switch req.Method {
+	case "GET":
+		args := structs.GenericRequest{}
+		if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+			return nil, nil
+		}
+
+		var out structs.SchedulerConfigurationResponse
+		if err := s.agent.RPC("Operator.SchedulerGetConfiguration", &args, &out); err != nil {
+			return nil, err
+		}
+
+		setMeta(resp, &out.QueryMeta)
+		return out.SchedulerConfig, nil
+
+	case "PUT", "POST":
+		args := structs.SchedulerSetConfigRequest{}
+		s.parseWriteRequest(req, &args.WriteRequest)
+		if err := decodeBody(req, &args.Config); err != nil {
+			return nil, CodedError(400, fmt.Sprintf("Error parsing scheduler config: %v", err))
+		}
+
+		// Check for cas value
+		if casRaw := req.URL.Query().Get("cas"); casRaw != "" {
+			casVal, err := strconv.ParseUint(casRaw, 10, 64)
+			if err != nil {
+				return nil, CodedError(400, fmt.Sprintf("Error parsing cas value: %v", err))
+			}
+			args.Config.ModifyIndex = casVal
+			args.CAS = true
+		}
+
+		var out structs.SchedulerSetConfigurationResponse
+		if err := s.agent.RPC("Operator.SchedulerSetConfiguration", &args, &out); err != nil {
+			return nil, err
+		}
+		setIndex(resp, out.Index)
+
+		// Only use the out value if this was a CAS
+		if !args.CAS {
+			return true, nil
+		}
+		return out.Updated, nil
+
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+// OperatorServerHealth is used to get the health of the servers of the
+// region. The response has a 429 status code when the region is unhealthy,
+// so that it can be used as a health check.
+func (s *HTTPServer) OperatorServerHealth(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.GenericRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.OperatorHealthReply
+	if err := s.agent.RPC("Operator.ServerHealth", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if !out.Healthy {
+		// The status is written before the body, so set its type first
+		resp.Header().Set("Content-Type", "application/json")
+		resp.WriteHeader(http.StatusTooManyRequests)
+	}
+	return out, nil
+}
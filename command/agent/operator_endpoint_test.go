@@ -0,0 +1,225 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestHTTP_SnapshotSaveRestore(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Save a snapshot
+		req, err := http.NewRequest("GET", "/v1/operator/snapshot", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		if _, err := s.Server.SnapshotRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+		snapshot := respW.Body.Bytes()
+		if len(snapshot) == 0 {
+			t.Fatalf("empty snapshot")
+		}
+
+		// Restore it
+		req, err = http.NewRequest("PUT", "/v1/operator/snapshot", bytes.NewReader(snapshot))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.SnapshotRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// An empty snapshot is rejected
+		req, err = http.NewRequest("PUT", "/v1/operator/snapshot", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.SnapshotRequest(respW, req); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
+
+func TestHTTP_OperatorRaftConfiguration(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		req, err := http.NewRequest("GET", "/v1/operator/raft/configuration", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.OperatorRaftConfiguration(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		out := obj.(structs.RaftConfigurationResponse)
+		if len(out.Servers) != 1 || !out.Servers[0].Leader || !out.Servers[0].Voter {
+			t.Fatalf("bad: %#v", out.Servers)
+		}
+	})
+}
+
+func TestHTTP_OperatorRaftPeer(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// The address is required
+		req, err := http.NewRequest("DELETE", "/v1/operator/raft/peer", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		if _, err := s.Server.OperatorRaftPeer(respW, req); err == nil {
+			t.Fatalf("expected error")
+		}
+
+		// Unknown peers can't be removed
+		req, err = http.NewRequest("DELETE", "/v1/operator/raft/peer?address=127.0.0.1:1", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		_, err = s.Server.OperatorRaftPeer(respW, req)
+		if err == nil || !strings.Contains(err.Error(), "not found in the Raft configuration") {
+			t.Fatalf("bad: %v", err)
+		}
+	})
+}
+
+func TestHTTP_OperatorAutopilotConfiguration(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		body := bytes.NewBufferString(`{"CleanupDeadServers": false, "LastContactThreshold": 1000000000, "MaxTrailingLogs": 100}`)
+		req, err := http.NewRequest("PUT", "/v1/operator/autopilot/configuration", body)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		if _, err := s.Server.OperatorAutopilotConfiguration(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		req, err = http.NewRequest("GET", "/v1/operator/autopilot/configuration", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		obj, err := s.Server.OperatorAutopilotConfiguration(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		config := obj.(*structs.AutopilotConfig)
+		if config.CleanupDeadServers || config.MaxTrailingLogs != 100 {
+			t.Fatalf("bad: %#v", config)
+		}
+
+		// A check-and-set with a stale index isn't applied
+		body = bytes.NewBufferString(`{"CleanupDeadServers": true, "LastContactThreshold": 1000000000, "MaxTrailingLogs": 100}`)
+		req, err = http.NewRequest("PUT", fmt.Sprintf("/v1/operator/autopilot/configuration?cas=%d", config.ModifyIndex-1), body)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		obj, err = s.Server.OperatorAutopilotConfiguration(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if obj.(bool) {
+			t.Fatalf("expected the update to fail")
+		}
+	})
+}
+
+func TestHTTP_OperatorSchedulerConfiguration(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		body := bytes.NewBufferString(`{"SchedulerAlgorithm": "spread", "SchedulingPaused": true}`)
+		req, err := http.NewRequest("PUT", "/v1/operator/scheduler/configuration", body)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		if _, err := s.Server.OperatorSchedulerConfiguration(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		req, err = http.NewRequest("GET", "/v1/operator/scheduler/configuration", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		obj, err := s.Server.OperatorSchedulerConfiguration(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		config := obj.(*structs.SchedulerConfiguration)
+		if config.SchedulerAlgorithm != structs.SchedulerAlgorithmSpread || !config.SchedulingPaused {
+			t.Fatalf("bad: %#v", config)
+		}
+
+		// A check-and-set with a stale index isn't applied
+		body = bytes.NewBufferString(`{"SchedulerAlgorithm": "binpack"}`)
+		req, err = http.NewRequest("PUT", fmt.Sprintf("/v1/operator/scheduler/configuration?cas=%d", config.ModifyIndex-1), body)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		obj, err = s.Server.OperatorSchedulerConfiguration(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if obj.(bool) {
+			t.Fatalf("expected the update to fail")
+		}
+	})
+}
+
+func TestHTTP_OperatorServerHealth(t *testing.T) {
+	httpTest(t, func(c *Config) {
+		c.NomadConfig.ServerHealthInterval = 50 * time.Millisecond
+	}, func(s *TestServer) {
+		testutil.WaitForResult(func() (bool, error) {
+			req, err := http.NewRequest("GET", "/v1/operator/autopilot/health", nil)
+			if err != nil {
+				return false, err
+			}
+			respW := httptest.NewRecorder()
+			obj, err := s.Server.OperatorServerHealth(respW, req)
+			if err != nil {
+				return false, err
+			}
+			if respW.Code != 200 {
+				return false, fmt.Errorf("bad code: %d", respW.Code)
+			}
+			out := obj.(structs.OperatorHealthReply)
+			if len(out.Servers) != 1 || !out.Servers[0].Healthy {
+				return false, fmt.Errorf("bad: %#v", out)
+			}
+			return true, nil
+		}, func(err error) {
+			t.Fatalf("err: %v", err)
+		})
+	})
+}
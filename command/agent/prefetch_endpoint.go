@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"net/http"
+
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// ClientPrefetchRequest is used to start fetching docker images and artifacts
+// on the client ahead of a deployment, and to query the status of the fetches
+func (s *HTTPServer) ClientPrefetchRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.agent.client == nil {
+		return nil, clientNotRunning
+	}
+	switch req.Method {
+	case "GET":
+		return s.clientPrefetchStatus(resp, req)
+	case "PUT", "POST":
+		return s.clientPrefetch(resp, req)
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) clientPrefetchStatus(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Check node read permissions
+	if aclObj, err := s.resolveToken(req); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.AllowNodeRead() {
+		return nil, structs.ErrPermissionDenied
+	}
+
+	return s.agent.client.PrefetchStatus(), nil
+}
+
+func (s *HTTPServer) clientPrefetch(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Check node write permissions
+	if aclObj, err := s.resolveToken(req); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.AllowNodeWrite() {
+		return nil, structs.ErrPermissionDenied
+	}
+
+	var args cstructs.PrefetchRequest
+	if err := decodeBody(req, &args); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+
+	statuses, err := s.agent.client.Prefetch(&args)
+	if err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	return statuses, nil
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestHTTP_ClientPrefetch(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Requests without images or artifacts are rejected
+		req, err := http.NewRequest("PUT", "/v1/client/prefetch", encodeReq(&cstructs.PrefetchRequest{}))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		if _, err := s.Server.ClientPrefetchRequest(respW, req); err == nil {
+			t.Fatalf("expected error")
+		}
+
+		// Prefetch an artifact
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "hello world")
+		}))
+		defer ts.Close()
+		args := &cstructs.PrefetchRequest{
+			Artifacts: []*structs.TaskArtifact{
+				{GetterSource: ts.URL + "/hello.txt"},
+			},
+		}
+		req, err = http.NewRequest("PUT", "/v1/client/prefetch", encodeReq(args))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		obj, err := s.Server.ClientPrefetchRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		statuses := obj.([]*cstructs.PrefetchStatus)
+		if len(statuses) != 1 || statuses[0].Type != cstructs.PrefetchTypeArtifact ||
+			statuses[0].Source != args.Artifacts[0].GetterSource {
+			t.Fatalf("bad: %#v", statuses)
+		}
+
+		// Wait for the artifact to be fetched
+		testutil.WaitForResult(func() (bool, error) {
+			req, err := http.NewRequest("GET", "/v1/client/prefetch", nil)
+			if err != nil {
+				return false, err
+			}
+			respW := httptest.NewRecorder()
+			obj, err := s.Server.ClientPrefetchRequest(respW, req)
+			if err != nil {
+				return false, err
+			}
+			statuses := obj.([]*cstructs.PrefetchStatus)
+			if len(statuses) != 1 {
+				return false, fmt.Errorf("bad: %#v", statuses)
+			}
+			if statuses[0].Status != cstructs.PrefetchStatusComplete {
+				return false, fmt.Errorf("bad: %#v", statuses[0])
+			}
+			return true, nil
+		}, func(err error) {
+			t.Fatalf("err: %v", err)
+		})
+	})
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_QuotaCRUD(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create a quota
+		quota := &structs.QuotaSpec{
+			Name:        "engineering",
+			Description: "engineering team",
+			Limits: []*structs.QuotaLimit{
+				&structs.QuotaLimit{
+					Region:   "global",
+					CPU:      1000,
+					MemoryMB: 1000,
+				},
+			},
+		}
+		buf := encodeReq(quota)
+		req, err := http.NewRequest("PUT", "/v1/quota/engineering", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		if _, err := s.Server.QuotaSpecificRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Query the quota
+		req, err = http.NewRequest("GET", "/v1/quota/engineering", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err := s.Server.QuotaSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		out := obj.(*structs.QuotaSpec)
+		if out.Name != quota.Name || len(out.Limits) != 1 {
+			t.Fatalf("bad: %#v", out)
+		}
+
+		// Query the usage
+		req, err = http.NewRequest("GET", "/v1/quota/usage/engineering", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.QuotaSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		usage := obj.(*structs.QuotaUsage)
+		if usage.Name != quota.Name || usage.Used.Count != 0 || usage.Limit.CPU != 1000 {
+			t.Fatalf("bad: %#v", usage)
+		}
+
+		// List the quotas
+		req, err = http.NewRequest("GET", "/v1/quotas", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.QuotasRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if n := len(obj.([]*structs.QuotaSpec)); n != 1 {
+			t.Fatalf("bad: %d", n)
+		}
+
+		// Delete the quota
+		req, err = http.NewRequest("DELETE", "/v1/quota/engineering", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.QuotaSpecificRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// The quota is gone
+		req, err = http.NewRequest("GET", "/v1/quota/engineering", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		if _, err := s.Server.QuotaSpecificRequest(respW, req); err == nil {
+			t.Fatalf("expected not found error")
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_ScalingPoliciesList(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create a job with a scaling policy
+		job := mock.Job()
+		job.TaskGroups[0].Scaling = &structs.ScalingPolicy{
+			Min:     1,
+			Max:     10,
+			Enabled: true,
+		}
+		args := structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &args, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/scaling/policies", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.ScalingPoliciesRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check for the index
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Check the policies
+		policies := obj.([]*structs.ScalingPolicyListStub)
+		if len(policies) != 1 || policies[0].JobID != job.ID || policies[0].Max != 10 {
+			t.Fatalf("bad: %#v", policies)
+		}
+	})
+}
+
+func TestHTTP_ScalingPolicyGet(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create a job with a scaling policy
+		job := mock.Job()
+		job.TaskGroups[0].Scaling = &structs.ScalingPolicy{
+			Min:     1,
+			Max:     10,
+			Enabled: true,
+		}
+		args := structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &args, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/scaling/policy/"+job.ID+"?group=web", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.ScalingPolicySpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check for the index
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Check the policy
+		out := obj.(structs.SingleScalingPolicyResponse)
+		if out.Policy == nil || out.Policy.Max != 10 || out.JobModifyIndex != resp.JobModifyIndex {
+			t.Fatalf("bad: %#v", out)
+		}
+
+		// Unknown task groups are not found
+		req, err = http.NewRequest("GET", "/v1/scaling/policy/"+job.ID+"?group=foo", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		_, err = s.Server.ScalingPolicySpecificRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Fatalf("expected not found error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_Search(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create the job
+		job := mock.Job()
+		args := structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var regResp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &args, &regResp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Search the jobs by prefix
+		search := &structs.SearchRequest{
+			Prefix:  job.ID[:4],
+			Context: structs.SearchContextJobs,
+		}
+		req, err := http.NewRequest("POST", "/v1/search", encodeReq(search))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.SearchRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+		out := obj.(structs.SearchResponse)
+		if ids := out.Matches[structs.SearchContextJobs]; len(ids) != 1 || ids[0] != job.ID {
+			t.Fatalf("bad: %#v", out.Matches)
+		}
+		if out.Truncations[structs.SearchContextJobs] {
+			t.Fatalf("bad: %#v", out.Truncations)
+		}
+	})
+}
+
+func TestHTTP_FuzzySearch(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create the job
+		job := mock.Job()
+		job.Name = "my-webapp"
+		args := structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var regResp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &args, &regResp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Search the jobs by name
+		search := &structs.FuzzySearchRequest{
+			Text:    "WEB",
+			Context: structs.SearchContextJobs,
+		}
+		req, err := http.NewRequest("POST", "/v1/search/fuzzy", encodeReq(search))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.FuzzySearchRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		out := obj.(structs.FuzzySearchResponse)
+		matches := out.Matches[structs.SearchContextJobs]
+		if len(matches) != 1 || matches[0].ID != "my-webapp" || matches[0].Scope[1] != job.ID {
+			t.Fatalf("bad: %#v", out.Matches)
+		}
+
+		// Only PUT and POST are allowed
+		req, err = http.NewRequest("GET", "/v1/search/fuzzy", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := s.Server.FuzzySearchRequest(httptest.NewRecorder(), req); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
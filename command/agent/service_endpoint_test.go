@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_Services(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Directly manipulate the state
+		state := s.Agent.server.State()
+		service := mock.ServiceRegistration()
+		if err := state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{service}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// List the services
+		req, err := http.NewRequest("GET", "/v1/services", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.ServicesRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") != "1000" {
+			t.Fatalf("bad index: %q", respW.HeaderMap.Get("X-Nomad-Index"))
+		}
+		stubs := obj.([]*structs.ServiceRegistrationListStub)
+		if len(stubs) != 1 || stubs[0].ServiceName != service.ServiceName {
+			t.Fatalf("bad: %#v", stubs)
+		}
+
+		// Query the registrations of the service
+		req, err = http.NewRequest("GET", "/v1/service/"+service.ServiceName, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.ServiceSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		services := obj.([]*structs.ServiceRegistration)
+		if len(services) != 1 || services[0].ID != service.ID {
+			t.Fatalf("bad: %#v", services)
+		}
+
+		// Unknown services have no registrations
+		req, err = http.NewRequest("GET", "/v1/service/unknown", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.ServiceSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if services := obj.([]*structs.ServiceRegistration); len(services) != 0 {
+			t.Fatalf("bad: %#v", services)
+		}
+	})
+}
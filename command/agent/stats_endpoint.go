@@ -1,12 +1,60 @@
 package agent
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/nomad/acl"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
 
 func (s *HTTPServer) ClientStatsRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	if s.agent.client == nil {
 		return nil, clientNotRunning
 	}
 
+	// Check node read permissions
+	if aclObj, err := s.resolveToken(req); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.AllowNodeRead() {
+		return nil, structs.ErrPermissionDenied
+	}
+
 	clientStats := s.agent.client.StatsReporter()
 	return clientStats.LatestHostStats(), nil
 }
+
+// ClientJobRequest returns the resource usage of the running allocations of a
+// job on the client, summed by task group
+func (s *HTTPServer) ClientJobRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.agent.client == nil {
+		return nil, clientNotRunning
+	}
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v1/client/job/")
+	if !strings.HasSuffix(path, "/stats") {
+		return nil, CodedError(404, resourceNotFoundErr)
+	}
+	jobID := strings.TrimSuffix(path, "/stats")
+	namespace := req.URL.Query().Get("namespace")
+	if namespace == "" {
+		namespace = structs.DefaultNamespace
+	}
+
+	// Check read-job permissions in the namespace of the job
+	if aclObj, err := s.resolveToken(req); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityReadJob) {
+		return nil, structs.ErrPermissionDenied
+	}
+
+	usages := s.agent.client.StatsReporter().TaskGroupStats(namespace, jobID)
+	if usages == nil {
+		usages = make([]*cstructs.TaskGroupResourceUsage, 0)
+	}
+	return usages, nil
+}
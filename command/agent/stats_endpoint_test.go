@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	cstructs "github.com/hashicorp/nomad/client/structs"
 )
 
 func TestClientStatsRequest(t *testing.T) {
@@ -20,3 +22,32 @@ func TestClientStatsRequest(t *testing.T) {
 		}
 	})
 }
+
+func TestClientJobStatsRequest(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		req, err := http.NewRequest("GET", "/v1/client/job/example/stats", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// No allocations of the job are running
+		respW := httptest.NewRecorder()
+		obj, err := s.Server.ClientJobRequest(respW, req)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if usages := obj.([]*cstructs.TaskGroupResourceUsage); len(usages) != 0 {
+			t.Fatalf("bad: %#v", usages)
+		}
+
+		// Unknown paths are not found
+		req, err = http.NewRequest("GET", "/v1/client/job/example/foo", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		if _, err := s.Server.ClientJobRequest(respW, req); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
@@ -0,0 +1,597 @@
+package agent
+
+// The assets of the web UI. The UI is a single page application calling the
+// HTTP API from the browser, so it doesn't need a build step and is kept in
+// the agent binary as is.
+
+// uiAsset is a file of the web UI
+type uiAsset struct {
+	contentType string
+	content     string
+}
+
+// uiAssets are the files of the web UI by path
+var uiAssets = map[string]uiAsset{
+	"index.html": {"text/html; charset=utf-8", uiIndexHTML},
+	"ui.css":     {"text/css; charset=utf-8", uiCSS},
+	"ui.js":      {"application/javascript; charset=utf-8", uiJS},
+}
+
+const uiIndexHTML = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Nomad</title>
+  <link rel="stylesheet" href="/ui/ui.css">
+</head>
+<body>
+  <nav>
+    <span class="brand">Nomad</span>
+    <a href="#/jobs">Jobs</a>
+    <a href="#/allocations">Allocations</a>
+    <a href="#/clients">Clients</a>
+    <a href="#/evaluations">Evaluations</a>
+    <a href="#/settings" class="right">Settings</a>
+  </nav>
+  <main id="content"></main>
+  <script src="/ui/ui.js"></script>
+</body>
+</html>
+`
+
+const uiCSS = `body {
+  margin: 0;
+  font-family: -apple-system, "Helvetica Neue", Helvetica, Arial, sans-serif;
+  font-size: 14px;
+  color: #25292e;
+}
+
+nav {
+  background: #25ba81;
+  padding: 0 20px;
+}
+
+nav a, nav .brand {
+  display: inline-block;
+  padding: 14px 10px;
+  color: #fff;
+  text-decoration: none;
+}
+
+nav .brand {
+  font-weight: bold;
+}
+
+nav .right {
+  float: right;
+}
+
+main {
+  padding: 10px 30px;
+}
+
+a {
+  color: #1563ff;
+}
+
+table {
+  border-collapse: collapse;
+  width: 100%;
+}
+
+th, td {
+  text-align: left;
+  padding: 6px 10px;
+  border-bottom: 1px solid #e1e4e8;
+}
+
+dl {
+  display: grid;
+  grid-template-columns: max-content auto;
+  grid-gap: 4px 20px;
+}
+
+dt {
+  font-weight: bold;
+}
+
+dd {
+  margin: 0;
+}
+
+label, input {
+  display: block;
+  margin-bottom: 10px;
+}
+
+input {
+  width: 400px;
+  padding: 4px;
+}
+
+.status {
+  padding: 1px 6px;
+  border-radius: 3px;
+  background: #e1e4e8;
+}
+
+.status-running, .status-complete, .status-ready {
+  background: #d1f4e6;
+}
+
+.status-failed, .status-lost, .status-dead, .status-down, .status-blocked {
+  background: #fbdada;
+}
+
+.status-pending {
+  background: #fdf2cf;
+}
+
+.logs {
+  background: #25292e;
+  color: #f7f8fa;
+  padding: 10px;
+  min-height: 400px;
+  white-space: pre-wrap;
+}
+
+.empty {
+  color: #6a737d;
+}
+
+.error {
+  color: #c73445;
+}
+`
+
+const uiJS = `(function() {
+  "use strict";
+
+  var content = document.getElementById("content");
+
+  // settings are kept in the local storage of the browser
+  function setting(name) {
+    return window.localStorage.getItem("nomad." + name) || "";
+  }
+
+  function setSetting(name, value) {
+    if (value) {
+      window.localStorage.setItem("nomad." + name, value);
+    } else {
+      window.localStorage.removeItem("nomad." + name);
+    }
+  }
+
+  function headers() {
+    var h = {};
+    if (setting("token")) {
+      h["X-Nomad-Token"] = setting("token");
+    }
+    return h;
+  }
+
+  function queryString(params) {
+    var parts = [];
+    Object.keys(params).forEach(function(key) {
+      parts.push(encodeURIComponent(key) + "=" + encodeURIComponent(params[key]));
+    });
+    return parts.length ? "?" + parts.join("&") : "";
+  }
+
+  // request calls the HTTP API of the agent serving the UI, or of the agent
+  // at the given base address
+  function request(path, params, base) {
+    params = params || {};
+    if (setting("namespace") && !params.namespace) {
+      params.namespace = setting("namespace");
+    }
+    return fetch((base || "") + path + queryString(params), {headers: headers()})
+      .then(function(resp) {
+        if (!resp.ok) {
+          return resp.text().then(function(text) {
+            throw new Error(resp.status + ": " + text);
+          });
+        }
+        return resp;
+      });
+  }
+
+  function get(path, params) {
+    return request(path, params).then(function(resp) {
+      return resp.json();
+    });
+  }
+
+  // el creates an element with the given attributes and children
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function(key) {
+      node.setAttribute(key, attrs[key]);
+    });
+    (children || []).forEach(function(child) {
+      if (child === null || child === undefined) {
+        return;
+      }
+      if (typeof child !== "object") {
+        child = document.createTextNode(String(child));
+      }
+      node.appendChild(child);
+    });
+    return node;
+  }
+
+  function link(href, text) {
+    return el("a", {href: "#" + href}, [text]);
+  }
+
+  function shortID(id) {
+    return id ? id.substr(0, 8) : "";
+  }
+
+  function status(value) {
+    return el("span", {"class": "status status-" + value}, [value]);
+  }
+
+  function time(nanos) {
+    if (!nanos) {
+      return "";
+    }
+    return new Date(nanos / 1e6).toLocaleString();
+  }
+
+  function table(columns, rows) {
+    if (!rows || rows.length === 0) {
+      return el("p", {"class": "empty"}, ["None"]);
+    }
+    var head = el("tr", {}, columns.map(function(c) {
+      return el("th", {}, [c]);
+    }));
+    var body = rows.map(function(row) {
+      return el("tr", {}, row.map(function(cell) {
+        return el("td", {}, [cell]);
+      }));
+    });
+    return el("table", {}, [el("thead", {}, [head]), el("tbody", {}, body)]);
+  }
+
+  function details(pairs) {
+    var items = [];
+    pairs.forEach(function(pair) {
+      items.push(el("dt", {}, [pair[0]]));
+      items.push(el("dd", {}, [pair[1]]));
+    });
+    return el("dl", {}, items);
+  }
+
+  function render() {
+    content.innerHTML = "";
+    for (var i = 0; i < arguments.length; i++) {
+      content.appendChild(arguments[i]);
+    }
+  }
+
+  function showError(err) {
+    render(el("p", {"class": "error"}, [err.message]));
+  }
+
+  // Views
+
+  function jobsView() {
+    return get("/v1/jobs").then(function(jobs) {
+      render(el("h1", {}, ["Jobs"]), table(
+        ["ID", "Namespace", "Type", "Priority", "Status", "Running", "Pending", "Failed"],
+        jobs.map(function(job) {
+          var counts = {Running: 0, Starting: 0, Queued: 0, Failed: 0};
+          var summary = (job.JobSummary && job.JobSummary.Summary) || {};
+          Object.keys(summary).forEach(function(tg) {
+            Object.keys(counts).forEach(function(key) {
+              counts[key] += summary[tg][key] || 0;
+            });
+          });
+          return [
+            link("/jobs/" + encodeURIComponent(job.ID), job.ID),
+            job.Namespace, job.Type, job.Priority, status(job.Status),
+            counts.Running, counts.Starting + counts.Queued, counts.Failed
+          ];
+        })));
+    });
+  }
+
+  function jobView(id) {
+    var path = "/v1/job/" + encodeURIComponent(id);
+    return Promise.all([
+      get(path), get(path + "/allocations"), get(path + "/evaluations")
+    ]).then(function(results) {
+      var job = results[0], allocs = results[1], evals = results[2];
+      render(
+        el("h1", {}, ["Job " + job.ID]),
+        details([
+          ["Name", job.Name], ["Namespace", job.Namespace], ["Type", job.Type],
+          ["Priority", job.Priority], ["Status", status(job.Status)],
+          ["Version", job.Version], ["Datacenters", (job.Datacenters || []).join(", ")]
+        ]),
+        el("h2", {}, ["Task Groups"]),
+        table(["Name", "Count", "Tasks"], (job.TaskGroups || []).map(function(tg) {
+          return [tg.Name, tg.Count, (tg.Tasks || []).map(function(t) {
+            return t.Name + " (" + t.Driver + ")";
+          }).join(", ")];
+        })),
+        el("h2", {}, ["Allocations"]), allocationsTable(allocs),
+        el("h2", {}, ["Evaluations"]), evaluationsTable(evals));
+    });
+  }
+
+  function allocationsTable(allocs) {
+    return table(["ID", "Job", "Task Group", "Node", "Desired", "Status"],
+      allocs.map(function(alloc) {
+        return [
+          link("/allocations/" + alloc.ID, shortID(alloc.ID)),
+          link("/jobs/" + encodeURIComponent(alloc.JobID), alloc.JobID),
+          alloc.TaskGroup, link("/clients/" + alloc.NodeID, shortID(alloc.NodeID)),
+          alloc.DesiredStatus, status(alloc.ClientStatus)
+        ];
+      }));
+  }
+
+  function allocationsView() {
+    return get("/v1/allocations").then(function(allocs) {
+      render(el("h1", {}, ["Allocations"]), allocationsTable(allocs));
+    });
+  }
+
+  function eventMessage(event) {
+    var message = event.Message || event.DriverError || event.RestartReason ||
+      event.KillError || event.DownloadError || event.ValidationError ||
+      event.VaultError || event.PauseReason || event.MemoryPressure || "";
+    if (event.Type === "Terminated") {
+      message = "Exit Code: " + event.ExitCode + (message ? ", " + message : "");
+    }
+    return message;
+  }
+
+  function allocationView(id) {
+    return get("/v1/allocation/" + id).then(function(alloc) {
+      var sections = [
+        el("h1", {}, ["Allocation " + shortID(alloc.ID)]),
+        details([
+          ["ID", alloc.ID], ["Name", alloc.Name],
+          ["Job", link("/jobs/" + encodeURIComponent(alloc.JobID), alloc.JobID)],
+          ["Node", link("/clients/" + alloc.NodeID, alloc.NodeID)],
+          ["Desired Status", alloc.DesiredStatus],
+          ["Client Status", status(alloc.ClientStatus)],
+          ["Client Description", alloc.ClientDescription]
+        ])
+      ];
+      var states = alloc.TaskStates || {};
+      Object.keys(states).sort().forEach(function(task) {
+        var state = states[task];
+        var logs = "/allocations/" + alloc.ID + "/" + encodeURIComponent(task) + "/logs/";
+        sections.push(
+          el("h2", {}, ["Task " + task + " ", status(state.State)]),
+          el("p", {}, [link(logs + "stdout", "stdout"), " | ", link(logs + "stderr", "stderr")]),
+          table(["Time", "Type", "Description"], (state.Events || []).slice().reverse().map(function(event) {
+            return [time(event.Time), event.Type, eventMessage(event)];
+          })));
+      });
+      render.apply(null, sections);
+    });
+  }
+
+  // logsView streams the logs of a task from the client running it, whose
+  // HTTP API must allow requests from the origin of the UI
+  function logsView(id, task, type) {
+    return get("/v1/allocation/" + id).then(function(alloc) {
+      return get("/v1/node/" + alloc.NodeID).then(function(node) {
+        var output = el("pre", {"class": "logs"}, []);
+        render(
+          el("h1", {}, ["Task " + task + " " + type]),
+          el("p", {}, [link("/allocations/" + id, "Allocation " + shortID(id))]),
+          output);
+
+        var base = "";
+        if (node.HTTPAddr !== window.location.host) {
+          base = window.location.protocol + "//" + node.HTTPAddr;
+        }
+        return request("/v1/client/fs/logs/" + id, {
+          task: task, type: type, follow: "true", origin: "end", offset: 50000
+        }, base).then(function(resp) {
+          streamFrames(resp, output);
+        });
+      });
+    });
+  }
+
+  // streamFrames appends the data of the JSON frames of a stream to the
+  // output as they are received, until the route changes
+  function streamFrames(resp, output) {
+    var reader = resp.body.getReader();
+    var decoder = new TextDecoder();
+    var route = window.location.hash;
+    var buffer = "";
+
+    function read() {
+      if (window.location.hash !== route) {
+        reader.cancel();
+        return;
+      }
+      reader.read().then(function(result) {
+        if (result.done) {
+          return;
+        }
+        buffer += decoder.decode(result.value, {stream: true});
+        var frames = splitFrames(buffer);
+        buffer = frames.rest;
+        frames.objects.forEach(function(frame) {
+          if (frame.Data) {
+            output.appendChild(document.createTextNode(decodeData(frame.Data)));
+          }
+        });
+        read();
+      });
+    }
+    read();
+  }
+
+  // splitFrames splits the complete JSON objects from the start of the text
+  function splitFrames(text) {
+    var objects = [], depth = 0, inString = false, start = 0;
+    for (var i = 0; i < text.length; i++) {
+      var c = text[i];
+      if (inString) {
+        if (c === "\\") {
+          i++;
+        } else if (c === "\"") {
+          inString = false;
+        }
+      } else if (c === "\"") {
+        inString = true;
+      } else if (c === "{") {
+        if (depth === 0) {
+          start = i;
+        }
+        depth++;
+      } else if (c === "}") {
+        depth--;
+        if (depth === 0) {
+          objects.push(JSON.parse(text.substring(start, i + 1)));
+          text = text.substring(i + 1);
+          i = -1;
+        }
+      }
+    }
+    return {objects: objects, rest: text};
+  }
+
+  function decodeData(data) {
+    var bytes = window.atob(data);
+    var array = new Uint8Array(bytes.length);
+    for (var i = 0; i < bytes.length; i++) {
+      array[i] = bytes.charCodeAt(i);
+    }
+    return new TextDecoder().decode(array);
+  }
+
+  function clientsView() {
+    return get("/v1/nodes").then(function(nodes) {
+      render(el("h1", {}, ["Clients"]), table(
+        ["ID", "Name", "Datacenter", "Class", "Drain", "Eligibility", "Status"],
+        nodes.map(function(node) {
+          return [
+            link("/clients/" + node.ID, shortID(node.ID)), node.Name,
+            node.Datacenter, node.NodeClass, node.Drain,
+            node.SchedulingEligibility, status(node.Status)
+          ];
+        })));
+    });
+  }
+
+  function clientView(id) {
+    return Promise.all([
+      get("/v1/node/" + id), get("/v1/node/" + id + "/allocations")
+    ]).then(function(results) {
+      var node = results[0], allocs = results[1];
+      var attrs = node.Attributes || {};
+      render(
+        el("h1", {}, ["Client " + node.Name]),
+        details([
+          ["ID", node.ID], ["Datacenter", node.Datacenter],
+          ["Class", node.NodeClass], ["Address", node.HTTPAddr],
+          ["Drain", node.Drain], ["Status", status(node.Status)]
+        ]),
+        el("h2", {}, ["Allocations"]), allocationsTable(allocs),
+        el("h2", {}, ["Attributes"]),
+        table(["Name", "Value"], Object.keys(attrs).sort().map(function(key) {
+          return [key, attrs[key]];
+        })));
+    });
+  }
+
+  function evaluationsTable(evals) {
+    return table(["ID", "Job", "Triggered By", "Priority", "Status"],
+      evals.map(function(ev) {
+        return [
+          link("/evaluations/" + ev.ID, shortID(ev.ID)),
+          link("/jobs/" + encodeURIComponent(ev.JobID), ev.JobID),
+          ev.TriggeredBy, ev.Priority, status(ev.Status)
+        ];
+      }));
+  }
+
+  function evaluationsView() {
+    return get("/v1/evaluations").then(function(evals) {
+      render(el("h1", {}, ["Evaluations"]), evaluationsTable(evals));
+    });
+  }
+
+  function evaluationView(id) {
+    return get("/v1/evaluation/" + id).then(function(ev) {
+      var failed = ev.FailedTGAllocs || {};
+      render(
+        el("h1", {}, ["Evaluation " + shortID(ev.ID)]),
+        details([
+          ["ID", ev.ID], ["Job", link("/jobs/" + encodeURIComponent(ev.JobID), ev.JobID)],
+          ["Type", ev.Type], ["Triggered By", ev.TriggeredBy],
+          ["Trigger Reason", ev.TriggerReason], ["Status", status(ev.Status)],
+          ["Status Description", ev.StatusDescription],
+          ["Blocked Eval", ev.BlockedEval ? link("/evaluations/" + ev.BlockedEval, shortID(ev.BlockedEval)) : ""]
+        ]),
+        el("h2", {}, ["Placement Failures"]),
+        table(["Task Group", "Failures", "Nodes Evaluated", "Nodes Exhausted"],
+          Object.keys(failed).sort().map(function(tg) {
+            var m = failed[tg];
+            return [tg, m.CoalescedFailures + 1, m.NodesEvaluated, m.NodesExhausted];
+          })));
+    });
+  }
+
+  function settingsView() {
+    var token = el("input", {type: "password", id: "token"}, []);
+    var namespace = el("input", {type: "text", id: "namespace"}, []);
+    token.value = setting("token");
+    namespace.value = setting("namespace");
+    var save = el("button", {}, ["Save"]);
+    save.onclick = function() {
+      setSetting("token", token.value);
+      setSetting("namespace", namespace.value);
+      window.location.hash = "/jobs";
+    };
+    render(
+      el("h1", {}, ["Settings"]),
+      el("label", {"for": "token"}, ["ACL Token Secret ID"]), token,
+      el("label", {"for": "namespace"}, ["Namespace"]), namespace,
+      save);
+    return Promise.resolve();
+  }
+
+  // Routing
+
+  var routes = [
+    [/^\/jobs$/, jobsView],
+    [/^\/jobs\/(.+)$/, jobView],
+    [/^\/allocations$/, allocationsView],
+    [/^\/allocations\/([^\/]+)\/([^\/]+)\/logs\/(stdout|stderr)$/, logsView],
+    [/^\/allocations\/([^\/]+)$/, allocationView],
+    [/^\/clients$/, clientsView],
+    [/^\/clients\/([^\/]+)$/, clientView],
+    [/^\/evaluations$/, evaluationsView],
+    [/^\/evaluations\/([^\/]+)$/, evaluationView],
+    [/^\/settings$/, settingsView]
+  ];
+
+  function route() {
+    var path = window.location.hash.replace(/^#/, "");
+    for (var i = 0; i < routes.length; i++) {
+      var match = routes[i][0].exec(path);
+      if (match) {
+        var args = match.slice(1).map(decodeURIComponent);
+        routes[i][1].apply(null, args).catch(showError);
+        return;
+      }
+    }
+    window.location.hash = "/jobs";
+  }
+
+  window.addEventListener("hashchange", route);
+  route();
+})();
+`
@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// UIRequest serves the web UI. The routes of the UI are kept in the fragment
+// of its URLs, so the paths that aren't assets serve the page.
+func (s *HTTPServer) UIRequest(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		resp.WriteHeader(405)
+		resp.Write([]byte(ErrInvalidMethod))
+		return
+	}
+
+	asset, ok := uiAssets[strings.TrimPrefix(req.URL.Path, "/ui/")]
+	if !ok {
+		asset = uiAssets["index.html"]
+	}
+	resp.Header().Set("Content-Type", asset.contentType)
+	io.WriteString(resp, asset.content)
+}
+
+// UIRedirect redirects the root of the HTTP server to the web UI
+func (s *HTTPServer) UIRedirect(resp http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		resp.WriteHeader(404)
+		resp.Write([]byte(resourceNotFoundErr))
+		return
+	}
+	http.Redirect(resp, req, "/ui/", http.StatusTemporaryRedirect)
+}
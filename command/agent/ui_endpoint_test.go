@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTP_UI(t *testing.T) {
+	s := makeHTTPServer(t, func(c *Config) {
+		c.UI = true
+	})
+	defer s.Cleanup()
+
+	cases := []struct {
+		path        string
+		code        int
+		contentType string
+		contains    string
+	}{
+		{"/ui/", 200, "text/html", `<script src="/ui/ui.js">`},
+		{"/ui/jobs", 200, "text/html", `<script src="/ui/ui.js">`},
+		{"/ui/ui.js", 200, "application/javascript", "function route()"},
+		{"/ui/ui.css", 200, "text/css", "nav"},
+		{"/", 307, "", ""},
+		{"/foo", 404, "", ""},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest("GET", c.path, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		s.Server.mux.ServeHTTP(respW, req)
+
+		if respW.Code != c.code {
+			t.Fatalf("%s: bad code: %d", c.path, respW.Code)
+		}
+		if !strings.HasPrefix(respW.HeaderMap.Get("Content-Type"), c.contentType) {
+			t.Fatalf("%s: bad content type: %q", c.path, respW.HeaderMap.Get("Content-Type"))
+		}
+		if !strings.Contains(respW.Body.String(), c.contains) {
+			t.Fatalf("%s: bad body: %s", c.path, respW.Body.String())
+		}
+	}
+}
+
+func TestHTTP_UI_Disabled(t *testing.T) {
+	s := makeHTTPServer(t, nil)
+	defer s.Cleanup()
+
+	req, err := http.NewRequest("GET", "/ui/", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	respW := httptest.NewRecorder()
+	s.Server.mux.ServeHTTP(respW, req)
+	if respW.Code != 404 {
+		t.Fatalf("bad code: %d", respW.Code)
+	}
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func (s *HTTPServer) VariablesListRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.VariablesListRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.VariablesListResponse
+	if err := s.agent.RPC("Variables.List", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Variables == nil {
+		out.Variables = make([]*structs.VariableMetadata, 0)
+	}
+	return out.Variables, nil
+}
+
+func (s *HTTPServer) VariableSpecificRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	path := strings.TrimPrefix(req.URL.Path, "/v1/var/")
+	if len(path) == 0 {
+		return nil, CodedError(400, "Missing Variable Path")
+	}
+
+	switch req.Method {
+	case "GET":
+		return s.variableQuery(resp, req, path)
+	case "PUT", "POST":
+		return s.variableUpdate(resp, req, path)
+	case "DELETE":
+		return s.variableDelete(resp, req, path)
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) variableQuery(resp http.ResponseWriter, req *http.Request,
+	path string) (interface{}, error) {
+	args := structs.VariablesReadRequest{
+		Path: path,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.VariablesReadResponse
+	if err := s.agent.RPC("Variables.Read", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Variable == nil {
+		return nil, CodedError(404, "Variable not found")
+	}
+	return out.Variable, nil
+}
+
+func (s *HTTPServer) variableUpdate(resp http.ResponseWriter, req *http.Request,
+	path string) (interface{}, error) {
+	// Parse the variable
+	var variable structs.VariableDecrypted
+	if err := decodeBody(req, &variable); err != nil {
+		return nil, CodedError(500, err.Error())
+	}
+
+	// Ensure the variable path matches
+	if variable.Path != "" && variable.Path != path {
+		return nil, CodedError(400, "Variable path does not match request path")
+	}
+	variable.Path = path
+
+	args := structs.VariablesUpsertRequest{
+		Variable: &variable,
+	}
+	s.parseWriteRequest(req, &args.WriteRequest)
+	if err := parseCheckIndex(req, &args.CheckIndex); err != nil {
+		return nil, err
+	}
+
+	var out structs.VariablesUpsertResponse
+	if err := s.agent.RPC("Variables.Upsert", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return out.Variable, nil
+}
+
+func (s *HTTPServer) variableDelete(resp http.ResponseWriter, req *http.Request,
+	path string) (interface{}, error) {
+
+	args := structs.VariablesDeleteRequest{
+		Path: path,
+	}
+	s.parseWriteRequest(req, &args.WriteRequest)
+	if err := parseCheckIndex(req, &args.CheckIndex); err != nil {
+		return nil, err
+	}
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("Variables.Delete", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+// parseCheckIndex is used to parse the ?cas query param of check-and-set
+// writes
+func parseCheckIndex(req *http.Request, index **uint64) error {
+	raw := req.URL.Query().Get("cas")
+	if raw == "" {
+		return nil
+	}
+	cas, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return CodedError(400, "Invalid check-and-set index")
+	}
+	*index = &cas
+	return nil
+}
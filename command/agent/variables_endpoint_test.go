@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_VariablesCRUD(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create a variable
+		variable := &structs.VariableDecrypted{
+			Items: structs.VariableItems{"password": "hunter2"},
+		}
+		buf := encodeReq(variable)
+		req, err := http.NewRequest("PUT", "/v1/var/project/db?cas=0", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.VariableSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+		meta := obj.(*structs.VariableMetadata)
+		if meta.Path != "project/db" || meta.Namespace != structs.DefaultNamespace {
+			t.Fatalf("bad: %#v", meta)
+		}
+
+		// Creating it again conflicts
+		req, err = http.NewRequest("PUT", "/v1/var/project/db?cas=0", encodeReq(variable))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		if _, err := s.Server.VariableSpecificRequest(respW, req); err == nil || err.Error() != structs.ErrCASConflict.Error() {
+			t.Fatalf("expected conflict: %v", err)
+		}
+
+		// Query the variable
+		req, err = http.NewRequest("GET", "/v1/var/project/db", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.VariableSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		out := obj.(*structs.VariableDecrypted)
+		if out.Items["password"] != "hunter2" {
+			t.Fatalf("bad: %#v", out)
+		}
+
+		// List the variables
+		req, err = http.NewRequest("GET", "/v1/vars?prefix=project", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.VariablesListRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if list := obj.([]*structs.VariableMetadata); len(list) != 1 || list[0].Path != "project/db" {
+			t.Fatalf("bad: %#v", list)
+		}
+
+		// Delete the variable
+		req, err = http.NewRequest("DELETE", "/v1/var/project/db", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		if _, err := s.Server.VariableSpecificRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		req, err = http.NewRequest("GET", "/v1/var/project/db", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		_, err = s.Server.VariableSpecificRequest(respW, req)
+		if coded, ok := err.(HTTPCodedError); !ok || coded.Code() != 404 {
+			t.Fatalf("expected not found: %v", err)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// JSONWebKeySet is the set of public keys, as defined by RFC 7517, that
+// verify the workload identities signed by the servers of the region
+type JSONWebKeySet struct {
+	Keys []*JSONWebKey `json:"keys"`
+}
+
+// JSONWebKey is an RSA public key as defined by RFC 7517
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+func (s *HTTPServer) JWKSRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.WorkloadIdentityPublicKeysRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.WorkloadIdentityPublicKeysResponse
+	if err := s.agent.RPC("WorkloadIdentity.ListPublicKeys", &args, &out); err != nil {
+		return nil, err
+	}
+	setMeta(resp, &out.QueryMeta)
+
+	set := &JSONWebKeySet{Keys: make([]*JSONWebKey, 0, len(out.Keys))}
+	for _, key := range out.Keys {
+		jwk, err := toJSONWebKey(key)
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// toJSONWebKey converts a workload identity public key to a JSON Web Key
+func toJSONWebKey(key *structs.WorkloadIdentityPublicKey) (*JSONWebKey, error) {
+	raw, err := x509.ParsePKIXPublicKey(key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %q: %v", key.KeyID, err)
+	}
+	pub, ok := raw.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T for public key %q", raw, key.KeyID)
+	}
+
+	return &JSONWebKey{
+		KeyType:   "RSA",
+		KeyID:     key.KeyID,
+		Use:       "sig",
+		Algorithm: key.Algorithm,
+		Modulus:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}, nil
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_JWKS(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Upsert a signing key
+		priv, err := rsa.GenerateKey(rand.Reader, 1024)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		pub, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		key := mock.WorkloadIdentityKey()
+		key.PrivateKey = x509.MarshalPKCS1PrivateKey(priv)
+		key.PublicKey = pub
+		state := s.Agent.server.State()
+		if err := state.UpsertWorkloadIdentityKeys(1000, []*structs.WorkloadIdentityKey{key}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.JWKSRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		set := obj.(*JSONWebKeySet)
+		if len(set.Keys) != 1 {
+			t.Fatalf("bad: %#v", set)
+		}
+		jwk := set.Keys[0]
+		if jwk.KeyType != "RSA" || jwk.KeyID != key.KeyID || jwk.Use != "sig" || jwk.Algorithm != "RS256" {
+			t.Fatalf("bad: %#v", jwk)
+		}
+
+		// The published key matches the signing key
+		n, err := base64.RawURLEncoding.DecodeString(jwk.Modulus)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.Exponent)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if new(big.Int).SetBytes(n).Cmp(priv.N) != 0 || int(new(big.Int).SetBytes(e).Int64()) != priv.E {
+			t.Fatalf("public key mismatch")
+		}
+
+		// Only GET is allowed
+		req, err = http.NewRequest("PUT", "/.well-known/jwks.json", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := s.Server.JWKSRequest(httptest.NewRecorder(), req); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
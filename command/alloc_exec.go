@@ -0,0 +1,184 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mattn/go-isatty"
+)
+
+type AllocExecCommand struct {
+	Meta
+}
+
+func (c *AllocExecCommand) Help() string {
+	helpText := `
+Usage: nomad alloc-exec [options] <alloc-id> <command> [<args>...]
+
+  Run a command inside the isolation context of a running task of the given
+  allocation, such as the container of a Docker task or the chroot of an exec
+  task. The input and output of the command are streamed from and to the
+  terminal, and the exit code of the command is returned.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Exec Options:
+
+  -task=<name>
+    The task to run the command in. May be omitted if the task group of the
+    allocation has a single task.
+
+  -job
+    Use a random allocation from the specified job ID.
+
+  -i
+    Pass stdin to the command. Defaults to true.
+
+  -t
+    Allocate a terminal to the command. Defaults to true if stdin is a
+    terminal.
+
+  -verbose
+    Show full information.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *AllocExecCommand) Synopsis() string {
+	return "Run a command in a running task"
+}
+
+func (c *AllocExecCommand) Run(args []string) int {
+	var verbose, job, stdinOpt, ttyOpt bool
+	var task string
+
+	flags := c.Meta.FlagSet("alloc-exec", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.BoolVar(&job, "job", false, "")
+	flags.StringVar(&task, "task", "", "")
+	flags.BoolVar(&stdinOpt, "i", true, "")
+	flags.BoolVar(&ttyOpt, "t", isatty.IsTerminal(os.Stdin.Fd()), "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got an allocation and a command
+	args = flags.Args()
+	if len(args) < 2 {
+		if job {
+			c.Ui.Error("Job ID and command required. See help:\n")
+		} else {
+			c.Ui.Error("Allocation ID and command required. See help:\n")
+		}
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	if ttyOpt && !isatty.IsTerminal(os.Stdin.Fd()) {
+		c.Ui.Error("A terminal can only be allocated when stdin is a terminal")
+		return 1
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %v", err))
+		return 1
+	}
+
+	// If -job is specified, use random allocation, otherwise use provided allocation
+	allocID := args[0]
+	if job {
+		allocID, err = getRandomJobAlloc(client, args[0])
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error fetching allocations: %v", err))
+			return 1
+		}
+	}
+
+	alloc, code := lookupAllocation(c.Ui, client, allocID, length)
+	if alloc == nil {
+		return code
+	}
+
+	// Try to determine the task name from the allocation
+	if task == "" {
+		var tasks []*api.Task
+		for _, tg := range alloc.Job.TaskGroups {
+			if tg.Name == alloc.TaskGroup {
+				if len(tg.Tasks) == 1 {
+					task = tg.Tasks[0].Name
+				}
+				tasks = tg.Tasks
+				break
+			}
+		}
+		if task == "" {
+			c.Ui.Error(fmt.Sprintf("Allocation %q is running the following tasks:", limit(alloc.ID, length)))
+			for _, t := range tasks {
+				c.Ui.Error(fmt.Sprintf("  * %s", t.Name))
+			}
+			c.Ui.Error("\nPlease specify the task with -task.")
+			return 1
+		}
+	}
+
+	var stdin io.Reader
+	if stdinOpt {
+		stdin = os.Stdin
+	}
+
+	// Pass the input as typed and the size of the terminal to commands
+	// running with a terminal
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	sizeCh := make(chan api.TerminalSize, 1)
+	restore := func() {}
+	if ttyOpt {
+		restore, err = makeRawTerminal(os.Stdin.Fd())
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error setting up terminal: %v", err))
+			return 1
+		}
+		go watchTerminalSize(os.Stdin.Fd(), sizeCh, stopCh)
+	}
+
+	// Kill the command when interrupted. Interrupts typed in a terminal are
+	// passed to the command instead.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-stopCh:
+		}
+	}()
+
+	code, err = client.Allocations().Exec(ctx, alloc, task, ttyOpt, args[1:],
+		stdin, os.Stdout, os.Stderr, sizeCh, nil)
+	restore()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error executing command: %v", err))
+		return 1
+	}
+	return code
+}
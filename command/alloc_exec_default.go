@@ -0,0 +1,29 @@
+// +build !linux
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// makeRawTerminal is only supported on Linux
+func makeRawTerminal(fd uintptr) (func(), error) {
+	return nil, fmt.Errorf("terminals are only supported on Linux")
+}
+
+// makeRawInputTerminal is only supported on Linux
+func makeRawInputTerminal(fd uintptr) (func(), error) {
+	return nil, fmt.Errorf("terminals are only supported on Linux")
+}
+
+// terminalSize is only supported on Linux
+func terminalSize(fd uintptr) (api.TerminalSize, error) {
+	return api.TerminalSize{}, fmt.Errorf("terminals are only supported on Linux")
+}
+
+// watchTerminalSize doesn't send sizes as terminals are only supported on
+// Linux
+func watchTerminalSize(fd uintptr, sizeCh chan<- api.TerminalSize, stopCh <-chan struct{}) {
+}
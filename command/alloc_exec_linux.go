@@ -0,0 +1,99 @@
+package command
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// makeRawTerminal puts the terminal in raw mode, so that input is passed to
+// the remote command as typed, and returns a function restoring the
+// previous mode
+func makeRawTerminal(fd uintptr) (func(), error) {
+	return setTerminalMode(fd, func(raw *syscall.Termios) {
+		raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+			syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+		raw.Oflag &^= syscall.OPOST
+		raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+		raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+		raw.Cflag |= syscall.CS8
+		raw.Cc[syscall.VMIN] = 1
+		raw.Cc[syscall.VTIME] = 0
+	})
+}
+
+// makeRawInputTerminal puts the input of the terminal in raw mode, so that
+// keys are read as typed without being echoed, and returns a function
+// restoring the previous mode. Output processing and the signals sent by keys
+// such as ctrl+c are kept.
+func makeRawInputTerminal(fd uintptr) (func(), error) {
+	return setTerminalMode(fd, func(raw *syscall.Termios) {
+		raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON
+		raw.Cc[syscall.VMIN] = 1
+		raw.Cc[syscall.VTIME] = 0
+	})
+}
+
+// setTerminalMode applies the changes to the mode of the terminal and returns
+// a function restoring the previous mode
+func setTerminalMode(fd uintptr, change func(*syscall.Termios)) (func(), error) {
+	var orig syscall.Termios
+	if err := termIoctl(fd, syscall.TCGETS, unsafe.Pointer(&orig)); err != nil {
+		return nil, err
+	}
+
+	mode := orig
+	change(&mode)
+	if err := termIoctl(fd, syscall.TCSETS, unsafe.Pointer(&mode)); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		termIoctl(fd, syscall.TCSETS, unsafe.Pointer(&orig))
+	}, nil
+}
+
+// terminalSize returns the size of the terminal
+func terminalSize(fd uintptr) (api.TerminalSize, error) {
+	var ws struct {
+		Row, Col, X, Y uint16
+	}
+	if err := termIoctl(fd, syscall.TIOCGWINSZ, unsafe.Pointer(&ws)); err != nil {
+		return api.TerminalSize{}, err
+	}
+	return api.TerminalSize{Height: int(ws.Row), Width: int(ws.Col)}, nil
+}
+
+// watchTerminalSize sends the size of the terminal when it starts and each
+// time the terminal is resized, until the stop channel is closed
+func watchTerminalSize(fd uintptr, sizeCh chan<- api.TerminalSize, stopCh <-chan struct{}) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGWINCH)
+	defer signal.Stop(sigCh)
+
+	for {
+		if size, err := terminalSize(fd); err == nil {
+			select {
+			case sizeCh <- size:
+			case <-stopCh:
+				return
+			}
+		}
+
+		select {
+		case <-sigCh:
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func termIoctl(fd, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type AllocSignalCommand struct {
+	Meta
+}
+
+func (c *AllocSignalCommand) Help() string {
+	helpText := `
+Usage: nomad alloc-signal [options] <alloc-id> [<task>]
+
+  Send a signal to the running tasks of an allocation, such as SIGHUP to make
+  them reload their configuration. If a task is given only that task is
+  signaled. The signal is recorded in the events of the tasks.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Signal Options:
+
+  -s=<signal>
+    The signal to send, such as SIGHUP or HUP. Defaults to SIGKILL.
+
+  -verbose
+    Show full information.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *AllocSignalCommand) Synopsis() string {
+	return "Send a signal to the tasks of an allocation"
+}
+
+func (c *AllocSignalCommand) Run(args []string) int {
+	var verbose bool
+	var sig string
+
+	flags := c.Meta.FlagSet("alloc-signal", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.StringVar(&sig, "s", "SIGKILL", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got an allocation and optionally a task
+	args = flags.Args()
+	if len(args) < 1 || len(args) > 2 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	if sig == "" {
+		c.Ui.Error("Signal must not be empty")
+		return 1
+	}
+	var task string
+	if len(args) == 2 {
+		task = args[1]
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %v", err))
+		return 1
+	}
+
+	alloc, code := lookupAllocation(c.Ui, client, args[0], length)
+	if alloc == nil {
+		return code
+	}
+
+	if err := client.Allocations().Signal(alloc, task, sig, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error signaling allocation: %s", err))
+		return 1
+	}
+	return 0
+}
@@ -216,6 +216,19 @@ func (c *AllocStatusCommand) Run(args []string) int {
 		fmt.Sprintf("Created At|%s", formatUnixNanoTime(alloc.CreateTime)),
 	}
 
+	if alloc.PreviousAllocation != "" {
+		basic = append(basic,
+			fmt.Sprintf("Replaces Allocation|%s", limit(alloc.PreviousAllocation, length)))
+	}
+	if alloc.RescheduleTracker != nil && len(alloc.RescheduleTracker.Events) > 0 {
+		basic = append(basic,
+			fmt.Sprintf("Attempt|%d", len(alloc.RescheduleTracker.Events)+1))
+	}
+	if next := nextRestartTime(alloc); !next.IsZero() {
+		basic = append(basic,
+			fmt.Sprintf("Next Retry|%s", formatTime(next)))
+	}
+
 	if verbose {
 		basic = append(basic,
 			fmt.Sprintf("Evaluated Nodes|%d", alloc.Metrics.NodesEvaluated),
@@ -239,6 +252,12 @@ func (c *AllocStatusCommand) Run(args []string) int {
 		c.outputTaskDetails(alloc, stats, displayStats)
 	}
 
+	// Format the reschedule history
+	if alloc.RescheduleTracker != nil && len(alloc.RescheduleTracker.Events) > 0 {
+		c.Ui.Output(c.Colorize().Color("\n[bold]Reschedule History[reset]"))
+		c.Ui.Output(formatRescheduleEvents(alloc.RescheduleTracker.Events, length))
+	}
+
 	// Format the detailed status
 	if verbose {
 		c.Ui.Output(c.Colorize().Color("\n[bold]Placement Metrics[reset]"))
@@ -248,6 +267,44 @@ func (c *AllocStatusCommand) Run(args []string) int {
 	return 0
 }
 
+// formatRescheduleEvents returns a table of the reschedule attempts that led
+// to an allocation, oldest first.
+func formatRescheduleEvents(events []*api.RescheduleEvent, length int) string {
+	out := make([]string, len(events)+1)
+	out[0] = "Attempt|Time|Previous Alloc|Previous Node|Previous Status|Delay"
+	for i, event := range events {
+		out[i+1] = fmt.Sprintf("%d|%s|%s|%s|%s|%s",
+			i+1,
+			formatUnixNanoTime(event.RescheduleTime),
+			limit(event.PrevAllocID, length),
+			limit(event.PrevNodeID, length),
+			event.PrevStatus,
+			event.Delay)
+	}
+	return formatList(out)
+}
+
+// nextRestartTime returns the time the first of the tasks of the allocation
+// waiting to be restarted restarts at, or the zero time if no task is waiting
+// to be restarted.
+func nextRestartTime(alloc *api.Allocation) time.Time {
+	var next time.Time
+	for _, state := range alloc.TaskStates {
+		if state == nil || len(state.Events) == 0 {
+			continue
+		}
+		event := state.Events[len(state.Events)-1]
+		if event.Type != api.TaskRestarting {
+			continue
+		}
+		at := time.Unix(0, event.Time).Add(time.Duration(event.StartDelay))
+		if next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+	return next
+}
+
 // outputTaskDetails prints task details for each task in the allocation,
 // optionally printing verbose statistics if displayStats is set
 func (c *AllocStatusCommand) outputTaskDetails(alloc *api.Allocation, stats *api.AllocResourceUsage, displayStats bool) {
@@ -298,6 +355,9 @@ func (c *AllocStatusCommand) outputTaskStatus(state *api.TaskState) {
 			} else {
 				desc = "Failed to download artifacts"
 			}
+			if event.DownloadAttempt > 1 {
+				desc = fmt.Sprintf("%s (attempt %d)", desc, event.DownloadAttempt)
+			}
 		case api.TaskKilling:
 			if event.KillTimeout != 0 {
 				desc = fmt.Sprintf("Sent interrupt. Waiting %v before force killing", event.KillTimeout)
@@ -353,6 +413,46 @@ func (c *AllocStatusCommand) outputTaskStatus(state *api.TaskState) {
 			} else {
 				desc = "Task's sibling failed"
 			}
+		case api.TaskPaused:
+			if event.PauseReason != "" {
+				desc = fmt.Sprintf("Task paused - %s", event.PauseReason)
+			} else {
+				desc = "Task paused"
+			}
+		case api.TaskResumed:
+			if event.PauseReason != "" {
+				desc = fmt.Sprintf("Task resumed - %s", event.PauseReason)
+			} else {
+				desc = "Task resumed"
+			}
+		case api.TaskPortConflict:
+			if len(event.PortConflicts) != 0 {
+				desc = fmt.Sprintf("Static ports already in use on the host: %s", strings.Join(event.PortConflicts, ", "))
+			} else {
+				desc = "Static ports already in use on the host"
+			}
+		case api.TaskMemoryPressure:
+			if event.MemoryPressure != "" {
+				desc = fmt.Sprintf("Task killed to relieve host memory pressure - %s", event.MemoryPressure)
+			} else {
+				desc = "Task killed to relieve host memory pressure"
+			}
+		case api.TaskRestartSignal:
+			if event.RestartReason != "" {
+				desc = event.RestartReason
+			} else {
+				desc = "Task signaled to restart"
+			}
+		case api.TaskSignaling:
+			sig := event.TaskSignal
+			if sig == "" {
+				sig = "a signal"
+			}
+			if event.TaskSignalReason != "" {
+				desc = fmt.Sprintf("Task being sent %s - %s", sig, event.TaskSignalReason)
+			} else {
+				desc = fmt.Sprintf("Task being sent %s", sig)
+			}
 		}
 
 		// Reverse order so we are sorted by time
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/nomad/api"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/testutil"
 	"github.com/mitchellh/cli"
@@ -75,6 +77,41 @@ func TestAllocStatusCommand_Fails(t *testing.T) {
 	}
 }
 
+func TestAllocStatusCommand_NextRestartTime(t *testing.T) {
+	alloc := &api.Allocation{
+		TaskStates: map[string]*api.TaskState{
+			"web": &api.TaskState{
+				Events: []*api.TaskEvent{
+					{Type: api.TaskStarted, Time: 10},
+				},
+			},
+		},
+	}
+	if next := nextRestartTime(alloc); !next.IsZero() {
+		t.Fatalf("expected no restart: %v", next)
+	}
+
+	now := time.Now()
+	alloc.TaskStates["web"].Events = append(alloc.TaskStates["web"].Events, &api.TaskEvent{
+		Type:       api.TaskRestarting,
+		Time:       now.UnixNano(),
+		StartDelay: int64(15 * time.Second),
+	})
+	alloc.TaskStates["db"] = &api.TaskState{
+		Events: []*api.TaskEvent{
+			{
+				Type:       api.TaskRestarting,
+				Time:       now.UnixNano(),
+				StartDelay: int64(5 * time.Second),
+			},
+		},
+	}
+	expected := time.Unix(0, now.UnixNano()).Add(5 * time.Second)
+	if next := nextRestartTime(alloc); !next.Equal(expected) {
+		t.Fatalf("bad: %v; want %v", next, expected)
+	}
+}
+
 func TestAllocStatusCommand_Run(t *testing.T) {
 	srv, client, url := testServer(t, func(c *testutil.TestServerConfig) {
 		c.DevMode = true
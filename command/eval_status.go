@@ -215,6 +215,9 @@ func (c *EvalStatusCommand) Run(args []string) int {
 		fmt.Sprintf("Priority|%d", eval.Priority),
 		fmt.Sprintf("Placement Failures|%s", failureString),
 	}
+	if eval.TriggerReason != "" {
+		basic = append(basic, fmt.Sprintf("Trigger Reason|%s", eval.TriggerReason))
+	}
 
 	if verbose {
 		// NextEval, PreviousEval, BlockedEval
@@ -260,7 +263,7 @@ func sortedTaskGroupFromMetrics(groups map[string]*api.AllocationMetric) []strin
 
 func getTriggerDetails(eval *api.Evaluation) (noun, subject string) {
 	switch eval.TriggeredBy {
-	case "job-register", "job-deregister", "periodic-job", "rolling-update":
+	case "job-register", "job-deregister", "periodic-job", "rolling-update", "custom":
 		return "Job ID", eval.JobID
 	case "node-update":
 		return "Node ID", eval.NodeID
@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/nomad/api"
 	"github.com/hashicorp/nomad/jobspec"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/mitchellh/cli"
 
 	"github.com/ryanuber/columnize"
 )
@@ -92,6 +93,56 @@ func getLocalNodeID(client *api.Client) (string, error) {
 	return nodeID, nil
 }
 
+// lookupAllocation returns the allocation matching the ID or prefix. If no
+// single allocation matches, the error or the matching allocations are
+// output and nil is returned with the exit code of the command.
+func lookupAllocation(ui cli.Ui, client *api.Client, allocID string, length int) (*api.Allocation, int) {
+	if len(allocID) == 1 {
+		ui.Error(fmt.Sprintf("Alloc ID must contain at least two characters."))
+		return nil, 1
+	}
+	if len(allocID)%2 == 1 {
+		// Identifiers must be of even length, so we strip off the last byte
+		// to provide a consistent user experience.
+		allocID = allocID[:len(allocID)-1]
+	}
+
+	allocs, _, err := client.Allocations().PrefixList(allocID)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error querying allocation: %v", err))
+		return nil, 1
+	}
+	if len(allocs) == 0 {
+		ui.Error(fmt.Sprintf("No allocation(s) with prefix or id %q found", allocID))
+		return nil, 1
+	}
+	if len(allocs) > 1 {
+		// Format the allocs
+		out := make([]string, len(allocs)+1)
+		out[0] = "ID|Eval ID|Job ID|Task Group|Desired Status|Client Status"
+		for i, alloc := range allocs {
+			out[i+1] = fmt.Sprintf("%s|%s|%s|%s|%s|%s",
+				limit(alloc.ID, length),
+				limit(alloc.EvalID, length),
+				alloc.JobID,
+				alloc.TaskGroup,
+				alloc.DesiredStatus,
+				alloc.ClientStatus,
+			)
+		}
+		ui.Output(fmt.Sprintf("Prefix matched multiple allocations\n\n%s", formatList(out)))
+		return nil, 0
+	}
+
+	// Prefix lookup matched a single allocation
+	alloc, _, err := client.Allocations().Info(allocs[0].ID, nil)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error querying allocation: %s", err))
+		return nil, 1
+	}
+	return alloc, 0
+}
+
 // evalFailureStatus returns whether the evaluation has failures and a string to
 // display when presenting users with whether there are failures for the eval
 func evalFailureStatus(eval *api.Evaluation) (string, bool) {
@@ -236,6 +287,13 @@ type JobGetter struct {
 
 // StructJob returns the Job struct from jobfile.
 func (j *JobGetter) StructJob(jpath string) (*structs.Job, error) {
+	job, _, err := j.StructJobSubmission(jpath)
+	return job, err
+}
+
+// StructJobSubmission returns the Job struct from jobfile along with the
+// source it was parsed from, to be stored by the servers.
+func (j *JobGetter) StructJobSubmission(jpath string) (*structs.Job, *api.JobSubmission, error) {
 	var jobfile io.Reader
 	switch jpath {
 	case "-":
@@ -246,23 +304,23 @@ func (j *JobGetter) StructJob(jpath string) (*structs.Job, error) {
 		}
 	default:
 		if len(jpath) == 0 {
-			return nil, fmt.Errorf("Error jobfile path has to be specified.")
+			return nil, nil, fmt.Errorf("Error jobfile path has to be specified.")
 		}
 
 		job, err := ioutil.TempFile("", "jobfile")
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		defer os.Remove(job.Name())
 
 		if err := job.Close(); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		// Get the pwd
 		pwd, err := os.Getwd()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		client := &gg.Client{
@@ -272,23 +330,40 @@ func (j *JobGetter) StructJob(jpath string) (*structs.Job, error) {
 		}
 
 		if err := client.Get(); err != nil {
-			return nil, fmt.Errorf("Error getting jobfile from %q: %v", jpath, err)
+			return nil, nil, fmt.Errorf("Error getting jobfile from %q: %v", jpath, err)
 		} else {
 			file, err := os.Open(job.Name())
 			defer file.Close()
 			if err != nil {
-				return nil, fmt.Errorf("Error opening file %q: %v", jpath, err)
+				return nil, nil, fmt.Errorf("Error opening file %q: %v", jpath, err)
 			}
 			jobfile = file
 		}
 	}
 
+	// Read the JobFile, keeping its content as the source of the job
+	source, err := ioutil.ReadAll(jobfile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error reading job file from %s: %v", jpath, err)
+	}
+
 	// Parse the JobFile
-	jobStruct, err := jobspec.Parse(jobfile)
+	jobStruct, err := jobspec.Parse(bytes.NewReader(source))
 	if err != nil {
 		fmt.Errorf("Error parsing job file from %s: %v", jpath, err)
-		return nil, err
+		return nil, nil, err
+	}
+
+	// The HCL parser accepts JSON job files too, which are told apart by
+	// their leading brace
+	format := structs.JobSubmissionFormatHCL
+	if bytes.HasPrefix(bytes.TrimSpace(source), []byte("{")) {
+		format = structs.JobSubmissionFormatJSON
+	}
+	submission := &api.JobSubmission{
+		Source: string(source),
+		Format: format,
 	}
 
-	return jobStruct, nil
+	return jobStruct, submission, nil
 }
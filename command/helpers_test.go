@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/mitchellh/cli"
 )
 
@@ -252,3 +253,28 @@ func TestStructJobWithHTTPServer(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 }
+
+// Test StructJobSubmission keeps the source of the jobfile
+func TestStructJobSubmission(t *testing.T) {
+	j := &JobGetter{testStdin: strings.NewReader(job)}
+	sj, sub, err := j.StructJobSubmission("-")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if sj.ID != "job1" {
+		t.Fatalf("bad: %#v", sj)
+	}
+	if sub.Source != job || sub.Format != structs.JobSubmissionFormatHCL {
+		t.Fatalf("bad: %#v", sub)
+	}
+
+	// JSON jobfiles are recognized
+	jsonJob := `{"job": {"job1": {"type": "service"}}}`
+	j = &JobGetter{testStdin: strings.NewReader(jsonJob)}
+	if _, sub, err = j.StructJobSubmission("-"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if sub.Source != jsonJob || sub.Format != structs.JobSubmissionFormatJSON {
+		t.Fatalf("bad: %#v", sub)
+	}
+}
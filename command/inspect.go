@@ -29,6 +29,10 @@ Inspect Options:
 
   -t
     Format and display evaluation using a Go template.
+
+  -original
+    Output the job file the job was last submitted from, exactly as it was
+    sent by "nomad run".
 `
 	return strings.TrimSpace(helpText)
 }
@@ -38,13 +42,14 @@ func (c *InspectCommand) Synopsis() string {
 }
 
 func (c *InspectCommand) Run(args []string) int {
-	var ojson bool
+	var ojson, original bool
 	var tmpl string
 
 	flags := c.Meta.FlagSet("inspect", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
 	flags.BoolVar(&ojson, "json", false, "")
 	flags.StringVar(&tmpl, "t", "", "")
+	flags.BoolVar(&original, "original", false, "")
 
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -126,6 +131,17 @@ func (c *InspectCommand) Run(args []string) int {
 		return 0
 	}
 
+	// Print the source the job was submitted from
+	if original {
+		submission, _, err := client.Jobs().Submission(jobs[0].ID, nil)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error retrieving job source: %s", err))
+			return 1
+		}
+		c.Ui.Output(submission.Source)
+		return 0
+	}
+
 	// Prefix lookup matched a single job
 	job, _, err := client.Jobs().Info(jobs[0].ID, nil)
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/nomad/api"
 	"github.com/mitchellh/cli"
 )
 
@@ -53,3 +54,26 @@ func TestInspectCommand_Fails(t *testing.T) {
 		t.Fatalf("expected getting formatter error, got: %s", out)
 	}
 }
+
+func TestInspectCommand_Original(t *testing.T) {
+	srv, client, url := testServer(t, nil)
+	defer srv.Stop()
+
+	// Register a job along with its source
+	source := `job "job1" {}`
+	opts := &api.RegisterOptions{
+		Submission: &api.JobSubmission{Source: source, Format: "hcl"},
+	}
+	if _, _, err := client.Jobs().RegisterOpts(testJob("job1"), opts, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	cmd := &InspectCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-original", "job1"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d %s", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); strings.TrimSpace(out) != source {
+		t.Fatalf("bad: %q", out)
+	}
+}
@@ -0,0 +1,154 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type JobHistoryCommand struct {
+	Meta
+}
+
+func (c *JobHistoryCommand) Help() string {
+	helpText := `
+Usage: nomad job-history [options] <job>
+
+  Display the versions of a job. Each registration of a job creates a new
+  version of it, and the servers keep the most recent versions of each job.
+  The changes each version made to the previous one can be displayed without
+  scheduling anything.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+History Options:
+
+  -diff
+    Display the changes each version made to the previous version.
+
+  -version <version>
+    Only display the given version of the job.
+
+  -verbose
+    Expand the task groups and tasks added or removed by a version when
+    displaying its changes.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *JobHistoryCommand) Synopsis() string {
+	return "Display the versions of a job"
+}
+
+func (c *JobHistoryCommand) Run(args []string) int {
+	var diff, verbose bool
+	var version int64
+
+	flags := c.Meta.FlagSet("job-history", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&diff, "diff", false, "")
+	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.Int64Var(&version, "version", -1, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one job
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	jobID := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Check if the job exists
+	jobs, _, err := client.Jobs().PrefixList(jobID)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving job versions: %s", err))
+		return 1
+	}
+	if len(jobs) == 0 {
+		c.Ui.Error(fmt.Sprintf("No job(s) with prefix or id %q found", jobID))
+		return 1
+	}
+	if len(jobs) > 1 && strings.TrimSpace(jobID) != jobs[0].ID {
+		out := make([]string, len(jobs)+1)
+		out[0] = "ID|Type|Priority|Status"
+		for i, job := range jobs {
+			out[i+1] = fmt.Sprintf("%s|%s|%d|%s",
+				job.ID,
+				job.Type,
+				job.Priority,
+				job.Status)
+		}
+		c.Ui.Output(fmt.Sprintf("Prefix matched multiple jobs\n\n%s", formatList(out)))
+		return 0
+	}
+
+	// Prefix lookup matched a single job
+	jobID = jobs[0].ID
+	versions, _, err := client.Jobs().Versions(jobID, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving job versions: %s", err))
+		return 1
+	}
+
+	// Filter the versions to display
+	var shown []int
+	for i, v := range versions {
+		if version < 0 || v.Version == uint64(version) {
+			shown = append(shown, i)
+		}
+	}
+	if len(shown) == 0 {
+		c.Ui.Error(fmt.Sprintf("Version %d of job %q is not tracked", version, jobID))
+		return 1
+	}
+
+	if !diff {
+		out := make([]string, len(shown)+1)
+		out[0] = "Version|Job Modify Index|Stop"
+		for i, idx := range shown {
+			v := versions[idx]
+			out[i+1] = fmt.Sprintf("%d|%d|%v", v.Version, v.JobModifyIndex, v.Stop)
+		}
+		c.Ui.Output(formatList(out))
+		return 0
+	}
+
+	// Versions are listed most recent first, so each version is followed by
+	// the one it replaced
+	for n, idx := range shown {
+		v := versions[idx]
+		if n != 0 {
+			c.Ui.Output("")
+		}
+		c.Ui.Output(formatKV([]string{
+			fmt.Sprintf("Version|%d", v.Version),
+			fmt.Sprintf("Job Modify Index|%d", v.JobModifyIndex),
+			fmt.Sprintf("Stop|%v", v.Stop),
+		}))
+
+		// The oldest tracked version has no previous version to compare with
+		if idx == len(versions)-1 {
+			continue
+		}
+		jobDiff, _, err := client.Jobs().VersionDiff(jobID, versions[idx+1].Version, v.Version, false, nil)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error diffing job versions: %s", err))
+			return 1
+		}
+		c.Ui.Output(fmt.Sprintf("\n%s",
+			c.Colorize().Color(strings.TrimSpace(formatJobDiff(jobDiff, verbose)))))
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestJobHistoryCommand_Implements(t *testing.T) {
+	var _ cli.Command = &JobHistoryCommand{}
+}
+
+func TestJobHistoryCommand_Fails(t *testing.T) {
+	srv, _, url := testServer(t, nil)
+	defer srv.Stop()
+
+	ui := new(cli.MockUi)
+	cmd := &JobHistoryCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"some", "bad", "args"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on non-existent job ID
+	if code := cmd.Run([]string{"-address=" + url, "nope"}); code != 1 {
+		t.Fatalf("expect exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "No job(s) with prefix or id") {
+		t.Fatalf("expect not found error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope", "nope"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error retrieving job versions") {
+		t.Fatalf("expected failed query error, got: %s", out)
+	}
+}
+
+func TestJobHistoryCommand_Run(t *testing.T) {
+	srv, client, url := testServer(t, nil)
+	defer srv.Stop()
+
+	// Register the job twice
+	job := testJob("job1_sfx")
+	if _, _, err := client.Jobs().Register(job, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	job.Priority = 90
+	if _, _, err := client.Jobs().Register(job, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	cmd := &JobHistoryCommand{Meta: Meta{Ui: ui}}
+
+	// Lists the versions
+	if code := cmd.Run([]string{"-address=" + url, "job1_sfx"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d %s", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "Version") || strings.Count(out, "false") != 2 {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Displays the changes of a version
+	if code := cmd.Run([]string{"-address=" + url, "-diff", "-version=1", "job1_sfx"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d %s", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "Priority") || !strings.Contains(out, "90") {
+		t.Fatalf("bad: %s", out)
+	}
+
+	// Fails on untracked versions
+	if code := cmd.Run([]string{"-address=" + url, "-version=5", "job1_sfx"}); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "is not tracked") {
+		t.Fatalf("bad: %s", out)
+	}
+}
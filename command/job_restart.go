@@ -0,0 +1,256 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// restartOnErrorFail stops restarting allocations once a restart fails
+	restartOnErrorFail = "fail"
+
+	// restartOnErrorContinue continues restarting the remaining allocations
+	// after a restart fails
+	restartOnErrorContinue = "continue"
+)
+
+type JobRestartCommand struct {
+	Meta
+}
+
+func (c *JobRestartCommand) Help() string {
+	helpText := `
+Usage: nomad job-restart [options] <job>
+
+  Restart the running allocations of a job in place, without changing the job
+  specification. This can be used to pick up changes to external configuration
+  the tasks read at startup. Allocations are restarted in batches, waiting
+  between each batch, so that only part of the job is restarting at a time.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Restart Options:
+
+  -group=<name>
+    Only restart the allocations of the given task group.
+
+  -task=<name>
+    Only restart the given task of the allocations. Allocations whose group
+    doesn't contain the task are skipped.
+
+  -batch-size=<n>
+    Number of allocations to restart at once. Defaults to 1.
+
+  -batch-wait=<duration>
+    Time to wait after restarting a batch before restarting the next one.
+    Defaults to 0s.
+
+  -on-error=<fail|continue>
+    Whether to stop or to continue restarting the remaining allocations when
+    an allocation fails to restart. Defaults to "fail".
+
+  -verbose
+    Display full information.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *JobRestartCommand) Synopsis() string {
+	return "Restart the allocations of a job in batches"
+}
+
+func (c *JobRestartCommand) Run(args []string) int {
+	var verbose bool
+	var group, task, onError string
+	var batchSize int
+	var batchWait time.Duration
+
+	flags := c.Meta.FlagSet("job-restart", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&group, "group", "", "")
+	flags.StringVar(&task, "task", "", "")
+	flags.IntVar(&batchSize, "batch-size", 1, "")
+	flags.DurationVar(&batchWait, "batch-wait", 0, "")
+	flags.StringVar(&onError, "on-error", restartOnErrorFail, "")
+	flags.BoolVar(&verbose, "verbose", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	// Check that we got exactly one job
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	jobID := args[0]
+
+	// Validate the batching options
+	if batchSize < 1 {
+		c.Ui.Error("Batch size must be greater than zero")
+		return 1
+	}
+	if batchWait < 0 {
+		c.Ui.Error("Batch wait must not be negative")
+		return 1
+	}
+	if onError != restartOnErrorFail && onError != restartOnErrorContinue {
+		c.Ui.Error(fmt.Sprintf("Invalid -on-error value %q: must be %q or %q",
+			onError, restartOnErrorFail, restartOnErrorContinue))
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Check if the job exists
+	jobs, _, err := client.Jobs().PrefixList(jobID)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error restarting job: %s", err))
+		return 1
+	}
+	if len(jobs) == 0 {
+		c.Ui.Error(fmt.Sprintf("No job(s) with prefix or id %q found", jobID))
+		return 1
+	}
+	if len(jobs) > 1 && strings.TrimSpace(jobID) != jobs[0].ID {
+		out := make([]string, len(jobs)+1)
+		out[0] = "ID|Type|Priority|Status"
+		for i, job := range jobs {
+			out[i+1] = fmt.Sprintf("%s|%s|%d|%s",
+				job.ID,
+				job.Type,
+				job.Priority,
+				job.Status)
+		}
+		c.Ui.Output(fmt.Sprintf("Prefix matched multiple jobs\n\n%s", formatList(out)))
+		return 0
+	}
+
+	// Prefix lookup matched a single job
+	job, _, err := client.Jobs().Info(jobs[0].ID, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error restarting job: %s", err))
+		return 1
+	}
+
+	// Determine the groups whose allocations are restarted
+	groups := make(map[string]struct{})
+	for _, tg := range job.TaskGroups {
+		if group != "" && tg.Name != group {
+			continue
+		}
+		if task != "" && !taskGroupHasTask(tg, task) {
+			continue
+		}
+		groups[tg.Name] = struct{}{}
+	}
+	if len(groups) == 0 {
+		switch {
+		case group != "" && task != "":
+			c.Ui.Error(fmt.Sprintf("Job %q has no group %q with task %q", job.ID, group, task))
+		case group != "":
+			c.Ui.Error(fmt.Sprintf("Job %q has no group %q", job.ID, group))
+		default:
+			c.Ui.Error(fmt.Sprintf("Job %q has no task %q", job.ID, task))
+		}
+		return 1
+	}
+
+	// Collect the running allocations to restart
+	stubs, _, err := client.Jobs().Allocations(job.ID, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error querying job allocations: %s", err))
+		return 1
+	}
+	var allocs []*api.AllocationListStub
+	for _, alloc := range stubs {
+		if _, ok := groups[alloc.TaskGroup]; !ok {
+			continue
+		}
+		if alloc.DesiredStatus != "run" || alloc.ClientStatus != "running" {
+			continue
+		}
+		allocs = append(allocs, alloc)
+	}
+	if len(allocs) == 0 {
+		c.Ui.Output(fmt.Sprintf("No running allocations to restart for job %q", job.ID))
+		return 0
+	}
+
+	// Restart the allocations in batches
+	batches := (len(allocs) + batchSize - 1) / batchSize
+	failed := 0
+	for i := 0; i < batches; i++ {
+		end := (i + 1) * batchSize
+		if end > len(allocs) {
+			end = len(allocs)
+		}
+		batch := allocs[i*batchSize : end]
+		c.Ui.Output(fmt.Sprintf("==> Restarting batch %d of %d (%d allocation(s))",
+			i+1, batches, len(batch)))
+
+		for _, stub := range batch {
+			if err := c.restartAlloc(client, stub.ID, task); err != nil {
+				failed++
+				c.Ui.Error(fmt.Sprintf("    Failed to restart allocation %q: %s",
+					limit(stub.ID, length), err))
+				if onError == restartOnErrorFail {
+					c.Ui.Error(fmt.Sprintf("==> Stopped restarting job %q after an error", job.ID))
+					return 1
+				}
+				continue
+			}
+			c.Ui.Output(fmt.Sprintf("    Allocation %q restarted: node %q, group %q",
+				limit(stub.ID, length), limit(stub.NodeID, length), stub.TaskGroup))
+		}
+
+		if i+1 < batches && batchWait > 0 {
+			c.Ui.Output(fmt.Sprintf("==> Waiting %v before restarting the next batch", batchWait))
+			time.Sleep(batchWait)
+		}
+	}
+
+	if failed != 0 {
+		c.Ui.Error(fmt.Sprintf("==> Failed to restart %d of %d allocation(s) of job %q",
+			failed, len(allocs), job.ID))
+		return 1
+	}
+	c.Ui.Output(fmt.Sprintf("==> Restarted %d allocation(s) of job %q", len(allocs), job.ID))
+	return 0
+}
+
+// restartAlloc restarts the given allocation in place on its client
+func (c *JobRestartCommand) restartAlloc(client *api.Client, allocID, task string) error {
+	alloc, _, err := client.Allocations().Info(allocID, nil)
+	if err != nil {
+		return err
+	}
+	return client.Allocations().Restart(alloc, task, nil)
+}
+
+// taskGroupHasTask returns whether the task group contains the given task
+func taskGroupHasTask(tg *api.TaskGroup, task string) bool {
+	for _, t := range tg.Tasks {
+		if t.Name == task {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type JobScaleCommand struct {
+	Meta
+}
+
+func (c *JobScaleCommand) Help() string {
+	helpText := `
+Usage: nomad job-scale [options] <job> <group> <count>
+
+  Scale sets the count of a task group of a job. The count must be within the
+  bounds of the scaling policy of the task group, if it has one. Scaling in
+  stops the allocations that are least useful first: allocations on down or
+  draining nodes, then allocations that are not running yet.
+
+  Upon successful scaling, the triggered evaluation will be monitored. This
+  can be disabled by supplying the detach flag.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Scale Options:
+
+  -check-index
+    If set, the task group is only scaled if the passed job modify index
+    matches the server side version. This ensures that the job is scaled from
+    a known state, so retried scaling requests are only applied once.
+
+  -message <message>
+    Message describing the reason of the scaling. It is recorded along with
+    the scaling event of the task group.
+
+  -detach
+    Return immediately instead of entering monitor mode. After scaling, the
+    evaluation ID will be printed to the screen, which can be used to examine
+    the evaluation using the eval-status command.
+
+  -verbose
+    Display full information.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *JobScaleCommand) Synopsis() string {
+	return "Change the count of a task group of a job"
+}
+
+func (c *JobScaleCommand) Run(args []string) int {
+	var detach, verbose bool
+	var message, checkIndexStr string
+
+	flags := c.Meta.FlagSet("job-scale", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&detach, "detach", false, "")
+	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.StringVar(&message, "message", "", "")
+	flags.StringVar(&checkIndexStr, "check-index", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	// Check that we got exactly three arguments
+	args = flags.Args()
+	if len(args) != 3 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	jobID, group := args[0], args[1]
+	count, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil || count < 0 {
+		c.Ui.Error(fmt.Sprintf("Invalid count %q: must be a non-negative integer", args[2]))
+		return 1
+	}
+
+	// Parse the check-index
+	checkIndex, enforce, err := parseCheckIndex(checkIndexStr)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error parsing check-index value %q: %v", checkIndexStr, err))
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Scale the job
+	var resp *api.JobRegisterResponse
+	if enforce {
+		resp, _, err = client.Jobs().EnforceScale(jobID, group, &count, checkIndex, message, false, nil, nil)
+	} else {
+		resp, _, err = client.Jobs().Scale(jobID, group, &count, message, false, nil, nil)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to scale job: %s", err))
+		return 1
+	}
+
+	// Nothing to monitor if the count didn't change or the job is periodic
+	// or parameterized
+	if resp.EvalID == "" {
+		c.Ui.Output(fmt.Sprintf("Task group %q of job %q scaled to %d", group, jobID, count))
+		return 0
+	}
+
+	if detach {
+		c.Ui.Output("Evaluation ID: " + limit(resp.EvalID, length))
+		return 0
+	}
+
+	mon := newMonitor(c.Ui, client, length)
+	return mon.monitor(resp.EvalID, false)
+}
@@ -0,0 +1,135 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type JobStartCommand struct {
+	Meta
+}
+
+func (c *JobStartCommand) Help() string {
+	helpText := `
+Usage: nomad job-start [options] <job>
+
+  Start a job that was previously stopped. The stopped job definition is
+  registered again, causing the scheduler to place its allocations. Upon
+  successful registration, an interactive monitor session will start to
+  display log lines as the job is scheduled. It is safe to exit the monitor
+  early using ctrl+c.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Start Options:
+
+  -detach
+    Return immediately instead of entering monitor mode. After the
+    register command is submitted, a new evaluation ID is printed to the
+    screen, which can be used to examine the evaluation using the eval-status
+    command.
+
+  -verbose
+    Display full information.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *JobStartCommand) Synopsis() string {
+	return "Start a stopped job"
+}
+
+func (c *JobStartCommand) Run(args []string) int {
+	var detach, verbose bool
+
+	flags := c.Meta.FlagSet("job-start", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&detach, "detach", false, "")
+	flags.BoolVar(&verbose, "verbose", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	// Check that we got exactly one job
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	jobID := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Check if the job exists
+	jobs, _, err := client.Jobs().PrefixList(jobID)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error starting job: %s", err))
+		return 1
+	}
+	if len(jobs) == 0 {
+		c.Ui.Error(fmt.Sprintf("No job(s) with prefix or id %q found", jobID))
+		return 1
+	}
+	if len(jobs) > 1 && strings.TrimSpace(jobID) != jobs[0].ID {
+		out := make([]string, len(jobs)+1)
+		out[0] = "ID|Type|Priority|Status"
+		for i, job := range jobs {
+			out[i+1] = fmt.Sprintf("%s|%s|%d|%s",
+				job.ID,
+				job.Type,
+				job.Priority,
+				job.Status)
+		}
+		c.Ui.Output(fmt.Sprintf("Prefix matched multiple jobs\n\n%s", formatList(out)))
+		return 0
+	}
+
+	// Prefix lookup matched a single job
+	job, _, err := client.Jobs().Info(jobs[0].ID, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error starting job: %s", err))
+		return 1
+	}
+	if !job.Stop {
+		c.Ui.Error(fmt.Sprintf("Job %q is not stopped", job.ID))
+		return 1
+	}
+
+	// Register the job again without the stop flag. The modify index is
+	// enforced so that a concurrent update of the job is not overwritten.
+	job.Stop = false
+	evalID, _, err := client.Jobs().EnforceRegister(job, job.JobModifyIndex, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error starting job: %s", err))
+		return 1
+	}
+
+	// If we are starting a periodic job there won't be an evalID.
+	if evalID == "" {
+		c.Ui.Output("Job started successfully. Periodic jobs are not evaluated on start.")
+		return 0
+	}
+
+	if detach {
+		c.Ui.Output(evalID)
+		return 0
+	}
+
+	// Start monitoring the start eval
+	mon := newMonitor(c.Ui, client, length)
+	return mon.monitor(evalID, false)
+}
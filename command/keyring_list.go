@@ -0,0 +1,72 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type KeyringListCommand struct {
+	Meta
+}
+
+func (c *KeyringListCommand) Help() string {
+	helpText := `
+Usage: nomad keyring-list [options]
+
+  List the keys of the servers' keyring. Root keys encrypt variables and
+  workload identity keys sign the identities of tasks. The key material is
+  never displayed.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KeyringListCommand) Synopsis() string {
+	return "List the keys of the keyring"
+}
+
+func (c *KeyringListCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("keyring-list", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	keys, _, err := client.Keyring().List(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving keyring: %s", err))
+		return 1
+	}
+	if len(keys.RootKeys) == 0 && len(keys.WorkloadIdentityKeys) == 0 {
+		c.Ui.Output("No keys found")
+		return 0
+	}
+
+	out := make([]string, 0, len(keys.RootKeys)+len(keys.WorkloadIdentityKeys)+1)
+	out = append(out, "Key ID|Type|Algorithm|Active|Create Time")
+	for _, k := range keys.RootKeys {
+		out = append(out, fmt.Sprintf("%s|root|%s|%v|%s",
+			k.KeyID, k.Algorithm, k.KeyID == keys.ActiveRootKeyID, formatUnixNanoTime(k.CreateTime)))
+	}
+	for _, k := range keys.WorkloadIdentityKeys {
+		out = append(out, fmt.Sprintf("%s|workload-identity|%s|%v|%s",
+			k.KeyID, k.Algorithm, k.KeyID == keys.ActiveWorkloadIdentityKeyID, formatUnixNanoTime(k.CreateTime)))
+	}
+	c.Ui.Output(formatList(out))
+	return 0
+}
@@ -0,0 +1,67 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type KeyringRotateCommand struct {
+	Meta
+}
+
+func (c *KeyringRotateCommand) Help() string {
+	helpText := `
+Usage: nomad keyring-rotate [options]
+
+  Rotate the keys of the servers' keyring. A new root key and workload
+  identity key are generated and become active. Existing variables are
+  re-encrypted with the new root key, while the previous workload identity
+  keys are kept so identities issued before the rotation remain valid.
+
+  When ACLs are enabled, this command requires a token with operator write
+  permissions.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KeyringRotateCommand) Synopsis() string {
+	return "Rotate the keys of the keyring"
+}
+
+func (c *KeyringRotateCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("keyring-rotate", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	rotation, _, err := client.Keyring().Rotate(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error rotating keyring: %s", err))
+		return 1
+	}
+
+	out := []string{
+		fmt.Sprintf("Root Key ID|%s", rotation.RootKey.KeyID),
+		fmt.Sprintf("Workload Identity Key ID|%s", rotation.WorkloadIdentityKey.KeyID),
+		fmt.Sprintf("Re-encrypted Variables|%d", rotation.Rekeyed),
+	}
+	c.Ui.Output(formatKV(out))
+	return 0
+}
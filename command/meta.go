@@ -15,8 +15,10 @@ import (
 const (
 	// Names of environment variables used to supply various
 	// config options to the Nomad CLI.
-	EnvNomadAddress = "NOMAD_ADDR"
-	EnvNomadRegion  = "NOMAD_REGION"
+	EnvNomadAddress   = "NOMAD_ADDR"
+	EnvNomadRegion    = "NOMAD_REGION"
+	EnvNomadNamespace = "NOMAD_NAMESPACE"
+	EnvNomadToken     = "NOMAD_TOKEN"
 
 	// Constants for CLI identifier length
 	shortId = 8
@@ -46,6 +48,19 @@ type Meta struct {
 
 	// The region to send API requests
 	region string
+
+	// The namespace to scope API requests to
+	namespace string
+
+	// The secret ID of the ACL token used to authenticate API requests
+	token string
+
+	// The TLS configuration used to talk to agents serving the HTTP API over
+	// TLS
+	caCert        string
+	clientCert    string
+	clientKey     string
+	tlsSkipVerify bool
 }
 
 // FlagSet returns a FlagSet with the common flags that every
@@ -60,7 +75,13 @@ func (m *Meta) FlagSet(n string, fs FlagSetFlags) *flag.FlagSet {
 	if fs&FlagSetClient != 0 {
 		f.StringVar(&m.flagAddress, "address", "", "")
 		f.StringVar(&m.region, "region", "", "")
+		f.StringVar(&m.namespace, "namespace", "", "")
+		f.StringVar(&m.token, "token", "", "")
 		f.BoolVar(&m.noColor, "no-color", false, "")
+		f.StringVar(&m.caCert, "ca-cert", "", "")
+		f.StringVar(&m.clientCert, "client-cert", "", "")
+		f.StringVar(&m.clientKey, "client-key", "", "")
+		f.BoolVar(&m.tlsSkipVerify, "tls-skip-verify", false, "")
 	}
 
 	// Create an io.Writer that writes to our UI properly for errors.
@@ -95,9 +116,50 @@ func (m *Meta) Client() (*api.Client, error) {
 	if m.region != "" {
 		config.Region = m.region
 	}
+	if v := os.Getenv(EnvNomadNamespace); v != "" {
+		config.Namespace = v
+	}
+	if m.namespace != "" {
+		config.Namespace = m.namespace
+	}
+	if v := os.Getenv(EnvNomadToken); v != "" {
+		config.SecretID = v
+	}
+	if m.token != "" {
+		config.SecretID = m.token
+	}
+
+	// The TLS flags override the environment variables read by the API
+	if m.caCert != "" || m.clientCert != "" || m.clientKey != "" || m.tlsSkipVerify {
+		if config.TLSConfig == nil {
+			config.TLSConfig = &api.TLSConfig{}
+		}
+		if m.caCert != "" {
+			config.TLSConfig.CACert = m.caCert
+		}
+		if m.clientCert != "" {
+			config.TLSConfig.ClientCert = m.clientCert
+		}
+		if m.clientKey != "" {
+			config.TLSConfig.ClientKey = m.clientKey
+		}
+		if m.tlsSkipVerify {
+			config.TLSConfig.Insecure = true
+		}
+	}
 	return api.NewClient(config)
 }
 
+// allNamespaces returns whether the requests are scoped to all namespaces,
+// using the same precedence as Client.
+func (m *Meta) allNamespaces() bool {
+	namespace := os.Getenv(EnvNomadNamespace)
+	if m.namespace != "" {
+		namespace = m.namespace
+	}
+	return namespace == api.AllNamespacesNamespace
+}
+
 func (m *Meta) Colorize() *colorstring.Colorize {
 	return &colorstring.Colorize{
 		Colors:  colorstring.DefaultColors,
@@ -118,9 +180,37 @@ func generalOptionsUsage() string {
     The region of the Nomad servers to forward commands to.
     Overrides the NOMAD_REGION environment variable if set.
     Defaults to the Agent's local region.
-  
+
+  -namespace=<namespace>
+    The target namespace for queries and actions bound to a namespace.
+    Overrides the NOMAD_NAMESPACE environment variable if set.
+    Defaults to the "default" namespace. The namespace "*" lists the
+    objects of every namespace.
+
+  -token=<secret-id>
+    The secret ID of the ACL token used to authenticate requests.
+    Overrides the NOMAD_TOKEN environment variable if set.
+
   -no-color
     Disables colored command output.
+
+  -ca-cert=<path>
+    Path to a PEM encoded CA cert file to use to verify the Nomad agent
+    serving the HTTP API over TLS. Overrides the NOMAD_CACERT environment
+    variable if set.
+
+  -client-cert=<path>
+    Path to a PEM encoded client certificate presented to the Nomad agent
+    when verify_https_client is enabled. Must be given with -client-key.
+    Overrides the NOMAD_CLIENT_CERT environment variable if set.
+
+  -client-key=<path>
+    Path to the unencrypted PEM encoded private key matching the client
+    certificate. Overrides the NOMAD_CLIENT_KEY environment variable if set.
+
+  -tls-skip-verify
+    Do not verify the certificate of the Nomad agent. This is highly not
+    recommended. Overrides the NOMAD_SKIP_VERIFY environment variable if set.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -18,7 +18,8 @@ func TestMeta_FlagSet(t *testing.T) {
 		},
 		{
 			FlagSetClient,
-			[]string{"address", "no-color", "region"},
+			[]string{"address", "ca-cert", "client-cert", "client-key", "namespace",
+				"no-color", "region", "tls-skip-verify", "token"},
 		},
 	}
 
@@ -0,0 +1,108 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper/flag-slice"
+)
+
+type NamespaceApplyCommand struct {
+	Meta
+}
+
+func (c *NamespaceApplyCommand) Help() string {
+	helpText := `
+Usage: nomad namespace-apply [options] <namespace>
+
+  Create or update a namespace. Jobs, allocations and evaluations are
+  scoped to the namespace they are submitted into.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Apply Options:
+
+  -description
+    An optional human readable description for the namespace.
+
+  -quota
+    The name of a quota specification to attach to the namespace. The
+    allocations of the namespace then count against the quota's limits.
+
+  -deny-privileged-ports
+    Deny the jobs of the namespace static ports below 1024.
+
+  -deny-host-network
+    Deny the jobs of the namespace the network of the hosts, either through
+    task group networks in host mode or tasks with a host network_mode.
+
+  -exempt-job
+    A glob pattern of the IDs of the jobs the network policy doesn't apply
+    to. Can be specified multiple times.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *NamespaceApplyCommand) Synopsis() string {
+	return "Create or update a namespace"
+}
+
+func (c *NamespaceApplyCommand) Run(args []string) int {
+	var description, quota string
+	var denyPrivilegedPorts, denyHostNetwork bool
+	var exemptJobs sliceflag.StringFlag
+
+	flags := c.Meta.FlagSet("namespace-apply", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&description, "description", "", "")
+	flags.StringVar(&quota, "quota", "", "")
+	flags.BoolVar(&denyPrivilegedPorts, "deny-privileged-ports", false, "")
+	flags.BoolVar(&denyHostNetwork, "deny-host-network", false, "")
+	flags.Var(&exemptJobs, "exempt-job", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one namespace
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	name := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Create the request
+	ns := &api.Namespace{
+		Name:        name,
+		Description: description,
+		Quota:       quota,
+	}
+	if denyPrivilegedPorts || denyHostNetwork {
+		ns.NetworkPolicy = &api.NamespaceNetworkPolicy{
+			DenyPrivilegedPorts: denyPrivilegedPorts,
+			DenyHostNetwork:     denyHostNetwork,
+			ExemptJobs:          exemptJobs,
+		}
+	} else if len(exemptJobs) != 0 {
+		c.Ui.Error("-exempt-job requires -deny-privileged-ports or -deny-host-network")
+		return 1
+	}
+	if _, err := client.Namespaces().Register(ns, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error applying namespace: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully applied namespace %q!", name))
+	return 0
+}
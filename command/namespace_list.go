@@ -0,0 +1,68 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type NamespaceListCommand struct {
+	Meta
+}
+
+func (c *NamespaceListCommand) Help() string {
+	helpText := `
+Usage: nomad namespace-list [options]
+
+  List the namespaces registered in the region.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *NamespaceListCommand) Synopsis() string {
+	return "List namespaces"
+}
+
+func (c *NamespaceListCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("namespace-list", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if args = flags.Args(); len(args) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	namespaces, _, err := client.Namespaces().List(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving namespaces: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(formatNamespaces(namespaces))
+	return 0
+}
+
+// formatNamespaces formats the namespaces as a list
+func formatNamespaces(namespaces []*api.Namespace) string {
+	out := make([]string, len(namespaces)+1)
+	out[0] = "Name|Description"
+	for i, ns := range namespaces {
+		out[i+1] = fmt.Sprintf("%s|%s", ns.Name, ns.Description)
+	}
+	return formatList(out)
+}
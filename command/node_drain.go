@@ -3,6 +3,9 @@ package command
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
 )
 
 type NodeDrainCommand struct {
@@ -17,6 +20,11 @@ Usage: nomad node-drain [options] <node>
   that either -enable or -disable is specified, but not both.
   The -self flag is useful to drain the local node.
 
+  A draining node is ineligible for scheduling. Its allocations are migrated
+  gradually, as allowed by the migrate block of their task groups, and the
+  remaining ones are stopped once the deadline is reached. The node is
+  eligible for scheduling again once the drain completes or is disabled.
+
 General Options:
 
   ` + generalOptionsUsage() + `
@@ -27,7 +35,28 @@ Node Drain Options:
     Disable draining for the specified node.
 
   -enable
-    Enable draining for the specified node.
+    Enable draining for the specified node. Batch jobs are migrated first,
+    followed by service jobs in ascending priority and finally system jobs.
+    Enabling draining on a draining node updates its deadline.
+
+  -deadline <duration>
+    Set the deadline by which all allocations must be moved off the node.
+    Remaining allocations after the deadline are forced removed from the node.
+    Defaults to 1 hour.
+
+  -force
+    Force remove allocations off the node immediately.
+
+  -no-deadline
+    No deadline allows the allocations to drain off the node without being
+    force stopped after a certain deadline.
+
+  -ignore-system
+    Leave the allocations of system jobs running on the node when draining.
+
+  -monitor
+    Display the drain order and monitor the node until all of the migrated
+    allocations have stopped.
 
   -self
     Query the status of the local node.
@@ -43,7 +72,8 @@ func (c *NodeDrainCommand) Synopsis() string {
 }
 
 func (c *NodeDrainCommand) Run(args []string) int {
-	var enable, disable, self, autoYes bool
+	var enable, disable, self, autoYes, ignoreSystem, monitor, force, noDeadline bool
+	var deadline time.Duration
 
 	flags := c.Meta.FlagSet("node-drain", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
@@ -51,6 +81,11 @@ func (c *NodeDrainCommand) Run(args []string) int {
 	flags.BoolVar(&disable, "disable", false, "Disable drain mode")
 	flags.BoolVar(&self, "self", false, "")
 	flags.BoolVar(&autoYes, "yes", false, "Automatic yes to prompts.")
+	flags.BoolVar(&ignoreSystem, "ignore-system", false, "")
+	flags.BoolVar(&monitor, "monitor", false, "")
+	flags.DurationVar(&deadline, "deadline", time.Hour, "")
+	flags.BoolVar(&force, "force", false, "")
+	flags.BoolVar(&noDeadline, "no-deadline", false, "")
 
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -62,6 +97,28 @@ func (c *NodeDrainCommand) Run(args []string) int {
 		return 1
 	}
 
+	// Check that the deadline options are consistent
+	if force && noDeadline {
+		c.Ui.Error("-force and -no-deadline are mutually exclusive")
+		return 1
+	}
+	if deadline <= 0 {
+		c.Ui.Error("-deadline must be a positive duration")
+		return 1
+	}
+	var spec *api.DrainSpec
+	if enable {
+		spec = &api.DrainSpec{
+			Deadline:         deadline,
+			IgnoreSystemJobs: ignoreSystem,
+		}
+		if force {
+			spec.Deadline = -1 * time.Second
+		} else if noDeadline {
+			spec.Deadline = 0
+		}
+	}
+
 	// Check that we got a node ID
 	args = flags.Args()
 	if l := len(args); self && l != 0 || !self && l != 1 {
@@ -163,9 +220,86 @@ func (c *NodeDrainCommand) Run(args []string) int {
 	}
 
 	// Toggle node draining
-	if _, err := client.Nodes().ToggleDrain(node.ID, enable, nil); err != nil {
+	resp, _, err := client.Nodes().UpdateDrain(node.ID, spec, nil)
+	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error toggling drain mode: %s", err))
 		return 1
 	}
+
+	if !enable || !monitor {
+		return 0
+	}
+
+	if len(resp.DrainOrder) == 0 {
+		c.Ui.Output(fmt.Sprintf("Node %q has no allocations to drain", node.ID))
+		return 0
+	}
+	c.Ui.Output(c.Colorize().Color("[bold]Drain Order[reset]"))
+	c.Ui.Output(formatDrainOrder(resp.DrainOrder))
+	return c.monitorDrain(client, node.ID, resp.DrainOrder)
+}
+
+// formatDrainOrder returns a table describing the order in which jobs are
+// migrated off of a draining node.
+func formatDrainOrder(order []*api.NodeDrainJob) string {
+	out := make([]string, len(order)+1)
+	out[0] = "Wave|Job ID|Type|Priority|Action"
+	for i, entry := range order {
+		action := "migrate"
+		if entry.Skipped {
+			action = "ignore"
+		}
+		out[i+1] = fmt.Sprintf("%d|%s|%s|%d|%s",
+			entry.Rank,
+			entry.JobID,
+			entry.Type,
+			entry.Priority,
+			action)
+	}
+	return formatList(out)
+}
+
+// monitorDrain watches the allocations on the node until every allocation of
+// the migrated jobs has stopped, reporting each job as its allocations finish.
+func (c *NodeDrainCommand) monitorDrain(client *api.Client, nodeID string, order []*api.NodeDrainJob) int {
+	migrating := make(map[string]struct{}, len(order))
+	for _, entry := range order {
+		if !entry.Skipped {
+			migrating[entry.JobID] = struct{}{}
+		}
+	}
+
+	q := &api.QueryOptions{WaitTime: 10 * time.Second}
+	for len(migrating) != 0 {
+		allocs, meta, err := client.Nodes().Allocations(nodeID, q)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error monitoring node drain: %s", err))
+			return 1
+		}
+		q.WaitIndex = meta.LastIndex
+
+		remaining := make(map[string]int)
+		for _, alloc := range allocs {
+			if _, ok := migrating[alloc.JobID]; !ok {
+				continue
+			}
+			if alloc.DesiredStatus == "run" || alloc.ClientStatus == "pending" || alloc.ClientStatus == "running" {
+				remaining[alloc.JobID]++
+			}
+		}
+
+		for _, entry := range order {
+			if _, ok := migrating[entry.JobID]; !ok {
+				continue
+			}
+			if remaining[entry.JobID] == 0 {
+				c.Ui.Output(fmt.Sprintf("%s: Job %q drained from node (wave %d)",
+					formatTime(time.Now()), entry.JobID, entry.Rank))
+				delete(migrating, entry.JobID)
+			}
+		}
+	}
+
+	c.Ui.Output(fmt.Sprintf("%s: Node %q drain complete", formatTime(time.Now()), nodeID))
 	return 0
 }
@@ -0,0 +1,141 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type NodeNetworkRebuildCommand struct {
+	Meta
+}
+
+func (c *NodeNetworkRebuildCommand) Help() string {
+	helpText := `
+Usage: nomad node-network-rebuild [options] <node>
+
+  Rebuilds the port accounting of a specified node from its live
+  allocations. Ports the client reserved because processes on the host bound
+  them are released once no longer bound, so that they are offered to
+  allocations again. Ports claimed more than once by the reserved resources
+  of the node and its allocations are reported. The -self flag is useful to
+  rebuild the port accounting of the local node.
+
+  Clients rebuild their port accounting every five minutes on their own.
+  This command forces a rebuild, for instance when placements fail because
+  a node's ports appear to be exhausted.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Node Network Rebuild Options:
+
+  -self
+    Rebuild the port accounting of the local node.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *NodeNetworkRebuildCommand) Synopsis() string {
+	return "Rebuild the port accounting of a given node"
+}
+
+func (c *NodeNetworkRebuildCommand) Run(args []string) int {
+	var self bool
+
+	flags := c.Meta.FlagSet("node-network-rebuild", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&self, "self", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got a node ID
+	args = flags.Args()
+	if l := len(args); self && l != 0 || !self && l != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// If -self flag is set then determine the current node.
+	nodeID := ""
+	if !self {
+		nodeID = args[0]
+	} else {
+		var err error
+		if nodeID, err = getLocalNodeID(client); err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+	}
+
+	// Check if node exists
+	if len(nodeID) == 1 {
+		c.Ui.Error(fmt.Sprintf("Identifier must contain at least two characters."))
+		return 1
+	}
+	if len(nodeID)%2 == 1 {
+		// Identifiers must be of even length, so we strip off the last byte
+		// to provide a consistent user experience.
+		nodeID = nodeID[:len(nodeID)-1]
+	}
+
+	nodes, _, err := client.Nodes().PrefixList(nodeID)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error rebuilding network index: %s", err))
+		return 1
+	}
+	// Return error if no nodes are found
+	if len(nodes) == 0 {
+		c.Ui.Error(fmt.Sprintf("No node(s) with prefix or id %q found", nodeID))
+		return 1
+	}
+	if len(nodes) > 1 {
+		// Format the nodes list that matches the prefix so that the user
+		// can create a more specific request
+		out := make([]string, len(nodes)+1)
+		out[0] = "ID|Datacenter|Name|Class|Status"
+		for i, node := range nodes {
+			out[i+1] = fmt.Sprintf("%s|%s|%s|%s|%s",
+				node.ID,
+				node.Datacenter,
+				node.Name,
+				node.NodeClass,
+				node.Status)
+		}
+		// Dump the output
+		c.Ui.Output(fmt.Sprintf("Prefix matched multiple nodes\n\n%s", formatList(out)))
+		return 0
+	}
+
+	// Rebuild the network index of the node
+	node := nodes[0]
+	report, err := client.Nodes().RebuildNetworkIndex(node.ID, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error rebuilding network index: %s", err))
+		return 1
+	}
+
+	if len(report.ReleasedPorts) == 0 {
+		c.Ui.Output(fmt.Sprintf("Node %q network index rebuilt: no leaked ports", node.ID))
+	} else {
+		c.Ui.Output(fmt.Sprintf("Node %q network index rebuilt, released ports: %s",
+			node.ID, strings.Join(report.ReleasedPorts, ", ")))
+	}
+
+	if len(report.Collisions) != 0 {
+		c.Ui.Output(c.Colorize().Color("\n[bold]Port Collisions[reset]"))
+		for _, collision := range report.Collisions {
+			c.Ui.Output(collision)
+		}
+	}
+	return 0
+}
@@ -283,6 +283,18 @@ func (c *NodeStatusCommand) Run(args []string) int {
 	return c.formatNode(client, node)
 }
 
+// formatDrain returns whether the node is draining, along with the deadline
+// of its drain
+func formatDrain(node *api.Node) string {
+	if node.DrainStrategy == nil {
+		return fmt.Sprintf("%v", node.Drain)
+	}
+	if node.DrainStrategy.ForceDeadline.IsZero() {
+		return "true; no deadline"
+	}
+	return fmt.Sprintf("true; deadline %s", formatTime(node.DrainStrategy.ForceDeadline))
+}
+
 func (c *NodeStatusCommand) formatNode(client *api.Client, node *api.Node) int {
 	// Format the header output
 	basic := []string{
@@ -290,7 +302,8 @@ func (c *NodeStatusCommand) formatNode(client *api.Client, node *api.Node) int {
 		fmt.Sprintf("Name|%s", node.Name),
 		fmt.Sprintf("Class|%s", node.NodeClass),
 		fmt.Sprintf("DC|%s", node.Datacenter),
-		fmt.Sprintf("Drain|%v", node.Drain),
+		fmt.Sprintf("Drain|%s", formatDrain(node)),
+		fmt.Sprintf("Eligibility|%s", node.SchedulingEligibility),
 		fmt.Sprintf("Status|%s", node.Status),
 	}
 
@@ -309,6 +322,12 @@ func (c *NodeStatusCommand) formatNode(client *api.Client, node *api.Node) int {
 		}
 		c.Ui.Output(c.Colorize().Color(formatKV(basic)))
 
+		// Print the recent events of the node
+		if len(node.Events) != 0 {
+			c.Ui.Output(c.Colorize().Color("\n[bold]Recent Events[reset]"))
+			c.Ui.Output(formatList(formatNodeEvents(node.Events)))
+		}
+
 		// Get list of running allocations on the node
 		runningAllocs, err := getRunningAllocs(client, node.ID)
 		if err != nil {
@@ -358,12 +377,57 @@ func (c *NodeStatusCommand) formatNode(client *api.Client, node *api.Node) int {
 	}
 
 	if c.verbose {
+		if len(node.HostVolumes) != 0 {
+			c.Ui.Output(c.Colorize().Color("\n[bold]Host Volumes[reset]"))
+			c.Ui.Output(formatList(formatHostVolumes(node.HostVolumes)))
+		}
 		c.formatAttributes(node)
 	}
 	return 0
 
 }
 
+// formatNodeEvents formats the events of a node, most recent first
+func formatNodeEvents(events []*api.NodeEvent) []string {
+	out := make([]string, 0, len(events)+1)
+	out = append(out, "Time|Subsystem|Message")
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		msg := e.Message
+		if len(e.Details) != 0 {
+			keys := make([]string, 0, len(e.Details))
+			for k := range e.Details {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			details := make([]string, len(keys))
+			for j, k := range keys {
+				details[j] = fmt.Sprintf("%s: %s", k, e.Details[k])
+			}
+			msg = fmt.Sprintf("%s (%s)", msg, strings.Join(details, ", "))
+		}
+		out = append(out, fmt.Sprintf("%s|%s|%s", formatUnixNanoTime(e.Timestamp), e.Subsystem, msg))
+	}
+	return out
+}
+
+// formatHostVolumes formats the host volumes of a node, sorted by name
+func formatHostVolumes(volumes map[string]*api.HostVolumeInfo) []string {
+	names := make([]string, 0, len(volumes))
+	for name := range volumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]string, 0, len(names)+1)
+	out = append(out, "Name|ReadOnly|Path")
+	for _, name := range names {
+		v := volumes[name]
+		out = append(out, fmt.Sprintf("%s|%v|%s", name, v.ReadOnly, v.Path))
+	}
+	return out
+}
+
 func (c *NodeStatusCommand) formatAttributes(node *api.Node) {
 	// Print the attributes
 	keys := make([]string, len(node.Attributes))
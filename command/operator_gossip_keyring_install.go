@@ -0,0 +1,64 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type OperatorGossipKeyringInstallCommand struct {
+	Meta
+}
+
+func (c *OperatorGossipKeyringInstallCommand) Help() string {
+	helpText := `
+Usage: nomad operator-gossip-keyring-install [options] <key>
+
+  Install a new key on every server to encrypt the gossip traffic. The key
+  is used to decrypt the gossip traffic right away but it is only used to
+  encrypt it once it is made the primary key with the
+  "nomad operator-gossip-keyring-use" command. Keys are 16, 24 or 32 bytes
+  encoded in base64.
+
+  When ACLs are enabled, this command requires a token with the 'agent:write'
+  capability.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorGossipKeyringInstallCommand) Synopsis() string {
+	return "Install a new gossip encryption key"
+}
+
+func (c *OperatorGossipKeyringInstallCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("operator-gossip-keyring-install", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one argument
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	key := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if _, err := client.Agent().InstallGossipKey(key); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error installing gossip key: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Installed gossip key %q", key))
+	return 0
+}
@@ -0,0 +1,72 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type OperatorGossipKeyringListCommand struct {
+	Meta
+}
+
+func (c *OperatorGossipKeyringListCommand) Help() string {
+	helpText := `
+Usage: nomad operator-gossip-keyring-list [options]
+
+  List the keys installed on the servers to encrypt the gossip traffic,
+  along with the number of servers each key is installed on.
+
+  When ACLs are enabled, this command requires a token with the 'agent:write'
+  capability.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorGossipKeyringListCommand) Synopsis() string {
+	return "List the gossip encryption keys"
+}
+
+func (c *OperatorGossipKeyringListCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("operator-gossip-keyring-list", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	resp, err := client.Agent().ListGossipKeys()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error listing gossip keys: %s", err))
+		return 1
+	}
+
+	keys := make([]string, 0, len(resp.Keys))
+	for key := range resp.Keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make([]string, 0, len(keys)+1)
+	out = append(out, "Key|Servers")
+	for _, key := range keys {
+		out = append(out, fmt.Sprintf("%s|%d/%d", key, resp.Keys[key], resp.NumNodes))
+	}
+	c.Ui.Output(formatList(out))
+	return 0
+}
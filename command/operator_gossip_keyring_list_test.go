@@ -0,0 +1,31 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/testutil"
+	"github.com/mitchellh/cli"
+)
+
+func TestOperatorGossipKeyringListCommand_Implements(t *testing.T) {
+	var _ cli.Command = &OperatorGossipKeyringListCommand{}
+}
+
+func TestOperatorGossipKeyringListCommand_Run(t *testing.T) {
+	key := "tbLJg26ZJyJ9pK3qhc9jig=="
+	srv, _, url := testServer(t, func(c *testutil.TestServerConfig) {
+		c.Server.EncryptKey = key
+	})
+	defer srv.Stop()
+
+	ui := new(cli.MockUi)
+	cmd := &OperatorGossipKeyringListCommand{Meta: Meta{Ui: ui}}
+
+	if code := cmd.Run([]string{"-address=" + url}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d %s", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, key) || !strings.Contains(out, "1/1") {
+		t.Fatalf("bad: %s", out)
+	}
+}
@@ -0,0 +1,61 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type OperatorGossipKeyringRemoveCommand struct {
+	Meta
+}
+
+func (c *OperatorGossipKeyringRemoveCommand) Help() string {
+	helpText := `
+Usage: nomad operator-gossip-keyring-remove [options] <key>
+
+  Remove a key from the keyring of every server. The primary key can't be
+  removed, another key must be made the primary key first.
+
+  When ACLs are enabled, this command requires a token with the 'agent:write'
+  capability.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorGossipKeyringRemoveCommand) Synopsis() string {
+	return "Remove a gossip encryption key"
+}
+
+func (c *OperatorGossipKeyringRemoveCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("operator-gossip-keyring-remove", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one argument
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	key := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if _, err := client.Agent().RemoveGossipKey(key); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error removing gossip key: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Removed gossip key %q", key))
+	return 0
+}
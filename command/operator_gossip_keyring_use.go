@@ -0,0 +1,62 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type OperatorGossipKeyringUseCommand struct {
+	Meta
+}
+
+func (c *OperatorGossipKeyringUseCommand) Help() string {
+	helpText := `
+Usage: nomad operator-gossip-keyring-use [options] <key>
+
+  Make an installed key the primary key encrypting the gossip traffic of
+  every server. The key must have been installed with the
+  "nomad operator-gossip-keyring-install" command.
+
+  When ACLs are enabled, this command requires a token with the 'agent:write'
+  capability.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorGossipKeyringUseCommand) Synopsis() string {
+	return "Change the primary gossip encryption key"
+}
+
+func (c *OperatorGossipKeyringUseCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("operator-gossip-keyring-use", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one argument
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	key := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if _, err := client.Agent().UseGossipKey(key); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error changing primary gossip key: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Changed the primary gossip key to %q", key))
+	return 0
+}
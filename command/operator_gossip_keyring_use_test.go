@@ -0,0 +1,34 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestOperatorGossipKeyringUseCommand_Implements(t *testing.T) {
+	var _ cli.Command = &OperatorGossipKeyringUseCommand{}
+}
+
+func TestOperatorGossipKeyringUseCommand_Fails(t *testing.T) {
+	ui := new(cli.MockUi)
+	cmd := &OperatorGossipKeyringUseCommand{Meta: Meta{Ui: ui}}
+
+	// Fails without a key
+	if code := cmd.Run(nil); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Usage") {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope", "tbLJg26ZJyJ9pK3qhc9jig=="}); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "gossip key") {
+		t.Fatalf("bad: %s", out)
+	}
+}
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type OperatorRaftListPeersCommand struct {
+	Meta
+}
+
+func (c *OperatorRaftListPeersCommand) Help() string {
+	helpText := `
+Usage: nomad operator-raft-list-peers [options]
+
+  Display the current Raft peer configuration of the region, naming each
+  server after the member of the gossip pool advertising its address.
+
+  When ACLs are enabled, this command requires a management token.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+List Peers Options:
+
+  -stale
+    Allow any server to answer, rather than only the leader. This is useful
+    to inspect the configuration when the region has lost its leader.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorRaftListPeersCommand) Synopsis() string {
+	return "Display the current Raft peer configuration"
+}
+
+func (c *OperatorRaftListPeersCommand) Run(args []string) int {
+	var stale bool
+
+	flags := c.Meta.FlagSet("operator-raft-list-peers", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&stale, "stale", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if args = flags.Args(); len(args) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	q := &api.QueryOptions{
+		AllowStale: stale,
+	}
+	reply, err := client.Operator().RaftGetConfiguration(q)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error getting peers: %s", err))
+		return 1
+	}
+
+	// Format it as a nice table
+	result := []string{"Node|ID|Address|State|Voter"}
+	for _, s := range reply.Servers {
+		node := s.Node
+		if node == "" {
+			node = "(unknown)"
+		}
+		state := "follower"
+		if s.Leader {
+			state = "leader"
+		}
+		result = append(result, fmt.Sprintf("%s|%s|%s|%s|%v",
+			node, s.ID, s.Address, state, s.Voter))
+	}
+	c.Ui.Output(formatList(result))
+	return 0
+}
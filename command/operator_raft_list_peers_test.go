@@ -0,0 +1,34 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestOperatorRaftListPeersCommand_Implements(t *testing.T) {
+	var _ cli.Command = &OperatorRaftListPeersCommand{}
+}
+
+func TestOperatorRaftListPeersCommand_Run(t *testing.T) {
+	srv, _, url := testServer(t, nil)
+	defer srv.Stop()
+
+	ui := new(cli.MockUi)
+	cmd := &OperatorRaftListPeersCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"-address=" + url, "extra"}); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	ui.ErrorWriter.Reset()
+
+	if code := cmd.Run([]string{"-address=" + url}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d %s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, "Address") || !strings.Contains(out, "leader") {
+		t.Fatalf("bad: %s", out)
+	}
+}
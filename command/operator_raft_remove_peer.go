@@ -0,0 +1,80 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type OperatorRaftRemovePeerCommand struct {
+	Meta
+}
+
+func (c *OperatorRaftRemovePeerCommand) Help() string {
+	helpText := `
+Usage: nomad operator-raft-remove-peer [options]
+
+  Remove the Nomad server with the given -peer-address from the Raft
+  configuration.
+
+  There are rare cases where a peer may be left behind in the Raft quorum
+  even though the server is no longer present and known to the cluster. This
+  command can be used to remove the failed server so that it no longer
+  affects the Raft quorum. If the server still shows in the output of the
+  "nomad server-members" command, it is preferable to clean up by running
+  "nomad server-force-leave" instead of this command.
+
+  When ACLs are enabled, this command requires a management token.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Remove Peer Options:
+
+  -peer-address="IP:port"
+    Remove a Nomad server with the given address from the Raft
+    configuration.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorRaftRemovePeerCommand) Synopsis() string {
+	return "Remove a Nomad server from the Raft configuration"
+}
+
+func (c *OperatorRaftRemovePeerCommand) Run(args []string) int {
+	var address string
+
+	flags := c.Meta.FlagSet("operator-raft-remove-peer", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&address, "peer-address", "", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if args = flags.Args(); len(args) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	if address == "" {
+		c.Ui.Error("Missing peer address")
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if err := client.Operator().RaftRemovePeerByAddress(address, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error removing peer: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Removed peer with address %q", address))
+	return 0
+}
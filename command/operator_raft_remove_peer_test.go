@@ -0,0 +1,38 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestOperatorRaftRemovePeerCommand_Implements(t *testing.T) {
+	var _ cli.Command = &OperatorRaftRemovePeerCommand{}
+}
+
+func TestOperatorRaftRemovePeerCommand_Run(t *testing.T) {
+	srv, _, url := testServer(t, nil)
+	defer srv.Stop()
+
+	ui := new(cli.MockUi)
+	cmd := &OperatorRaftRemovePeerCommand{Meta: Meta{Ui: ui}}
+
+	// Fails without an address
+	if code := cmd.Run([]string{"-address=" + url}); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Missing peer address") {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// If we get this error, it proves we sent the address all the way
+	// through
+	if code := cmd.Run([]string{"-address=" + url, "-peer-address=nope"}); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "address \"nope\" was not found in the Raft configuration") {
+		t.Fatalf("bad: %s", out)
+	}
+}
@@ -0,0 +1,80 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+type OperatorSnapshotRestoreCommand struct {
+	Meta
+}
+
+func (c *OperatorSnapshotRestoreCommand) Help() string {
+	helpText := `
+Usage: nomad operator-snapshot-restore [options] <file>
+
+  Restore the state of the cluster from a snapshot saved with
+  "nomad operator-snapshot-save". The snapshot is verified before being
+  restored, and replaces the whole state of the region on every server.
+
+  This is a destructive operation: every change made since the snapshot was
+  taken is lost.
+
+  When ACLs are enabled, this command requires a management token.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorSnapshotRestoreCommand) Synopsis() string {
+	return "Restore the state of the cluster from a snapshot"
+}
+
+func (c *OperatorSnapshotRestoreCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("operator-snapshot-restore", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one argument
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	path := args[0]
+
+	// Verify the snapshot before sending it to the servers
+	meta, err := verifySnapshot(path)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error verifying snapshot: %s", err))
+		return 1
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error opening snapshot: %s", err))
+		return 1
+	}
+	defer f.Close()
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if _, err := client.Operator().SnapshotRestore(f, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error restoring snapshot: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Restored snapshot of index %d taken at %v",
+		meta.Index, meta.Time))
+	return 0
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestOperatorSnapshotRestoreCommand_Implements(t *testing.T) {
+	var _ cli.Command = &OperatorSnapshotRestoreCommand{}
+}
+
+func TestOperatorSnapshotRestoreCommand_Run(t *testing.T) {
+	srv, _, url := testServer(t, nil)
+	defer srv.Stop()
+
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "backup.snap")
+
+	// Save a snapshot to restore
+	ui := new(cli.MockUi)
+	save := &OperatorSnapshotSaveCommand{Meta: Meta{Ui: ui}}
+	if code := save.Run([]string{"-address=" + url, path}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d %s", code, ui.ErrorWriter.String())
+	}
+
+	ui = new(cli.MockUi)
+	cmd := &OperatorSnapshotRestoreCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, path}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d %s", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "Restored snapshot") {
+		t.Fatalf("bad: %s", out)
+	}
+
+	// Fails on a corrupted snapshot
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := ioutil.WriteFile(path, data[:len(data)/2], 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ui.ErrorWriter.Reset()
+	if code := cmd.Run([]string{"-address=" + url, path}); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error verifying snapshot") {
+		t.Fatalf("bad: %s", out)
+	}
+}
@@ -0,0 +1,127 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/nomad/snapshot"
+)
+
+type OperatorSnapshotSaveCommand struct {
+	Meta
+}
+
+func (c *OperatorSnapshotSaveCommand) Help() string {
+	helpText := `
+Usage: nomad operator-snapshot-save [options] <file>
+
+  Save a snapshot of the state of the cluster to the given file. The snapshot
+  is taken by the leader once every committed change has been applied, and is
+  verified once written. It holds every job, allocation, evaluation, node,
+  ACL token and variable of the region, and should be stored securely.
+
+  When ACLs are enabled, this command requires a management token.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Snapshot Save Options:
+
+  -stale
+    Allow any server to take the snapshot, even when the cluster has no
+    leader. The snapshot may not include the latest changes.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorSnapshotSaveCommand) Synopsis() string {
+	return "Save a snapshot of the state of the cluster"
+}
+
+func (c *OperatorSnapshotSaveCommand) Run(args []string) int {
+	var stale bool
+
+	flags := c.Meta.FlagSet("operator-snapshot-save", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&stale, "stale", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one argument
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	path := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	snap, err := client.Operator().SnapshotSave(&api.QueryOptions{AllowStale: stale})
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error saving snapshot: %s", err))
+		return 1
+	}
+	defer snap.Close()
+
+	// Write the snapshot to a temporary file, only moved in place once
+	// verified so that an interrupted save never leaves a corrupted file
+	tmpPath := path + ".tmp"
+	meta, err := writeSnapshot(tmpPath, snap)
+	if err != nil {
+		os.Remove(tmpPath)
+		c.Ui.Error(fmt.Sprintf("Error saving snapshot: %s", err))
+		return 1
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		c.Ui.Error(fmt.Sprintf("Error saving snapshot: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Saved snapshot of index %d to %q", meta.Index, path))
+	return 0
+}
+
+// writeSnapshot writes the snapshot archive read from r to path and verifies
+// it, returning its metadata.
+func writeSnapshot(path string, r io.Reader) (*snapshot.Metadata, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return verifySnapshot(path)
+}
+
+// verifySnapshot verifies the checksums of the snapshot archive at path and
+// returns its metadata.
+func verifySnapshot(path string) (*snapshot.Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	meta, _, err := snapshot.Read(f)
+	if err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
@@ -108,6 +108,11 @@ func (c *PlanCommand) Run(args []string) int {
 	// Initialize any fields that need to be.
 	job.Canonicalize()
 
+	// A job registered in multiple regions may be submitted to any of them
+	if r := c.Meta.region; r != "" && len(job.Regions) != 0 {
+		job.Region = r
+	}
+
 	// Check that the job is valid
 	if err := job.Validate(); err != nil {
 		c.Ui.Error(fmt.Sprintf("Error validating job: %s", err))
@@ -217,6 +222,17 @@ func formatDryRun(resp *api.JobPlanResponse, job *structs.Job) string {
 		out += fmt.Sprintf("[green]- Rolling update, next evaluation will be in %s.\n", rolling.Wait)
 	}
 
+	if resp.Annotations != nil {
+		for _, window := range resp.Annotations.MaintenanceWindows {
+			effect := "avoided"
+			if window.Mode == api.MaintenanceModeHard {
+				effect = "blocked"
+			}
+			out += fmt.Sprintf("[yellow]- Placements on node class %q are %s by a maintenance window until %s.\n",
+				window.NodeClass, effect, formatTime(window.End))
+		}
+	}
+
 	if next := resp.NextPeriodicLaunch; !next.IsZero() {
 		out += fmt.Sprintf("[green]- If submitted now, next periodic launch would be at %s (%s from now).\n",
 			formatTime(next), formatTimeDifference(time.Now().UTC(), next, time.Second))
@@ -0,0 +1,138 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type PluginStatusCommand struct {
+	Meta
+}
+
+func (c *PluginStatusCommand) Help() string {
+	helpText := `
+Usage: nomad plugin-status [options] [plugin]
+
+  Display the status of CSI plugins. If no plugin is given, all plugins are
+  listed. Otherwise the plugin is displayed along with the health of its
+  controller and node plugins on every node running them.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Status Options:
+
+  -verbose
+    Display full information.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *PluginStatusCommand) Synopsis() string {
+	return "Display the status of CSI plugins"
+}
+
+func (c *PluginStatusCommand) Run(args []string) int {
+	var verbose bool
+	flags := c.Meta.FlagSet("plugin-status", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&verbose, "verbose", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got either one or zero plugins
+	args = flags.Args()
+	if len(args) > 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+	plugins := client.CSIPlugins()
+
+	// List the plugins if none was given
+	if len(args) == 0 {
+		stubs, _, err := plugins.List(nil)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error retrieving plugins: %s", err))
+			return 1
+		}
+		if len(stubs) == 0 {
+			c.Ui.Output("No plugins found")
+			return 0
+		}
+
+		out := make([]string, len(stubs)+1)
+		out[0] = "ID|Provider|Controllers Healthy/Expected|Nodes Healthy/Expected"
+		for i, p := range stubs {
+			out[i+1] = fmt.Sprintf("%s|%s|%d/%d|%d/%d", p.ID, p.Provider,
+				p.ControllersHealthy, p.Controllers, p.NodesHealthy, p.Nodes)
+		}
+		c.Ui.Output(formatList(out))
+		return 0
+	}
+
+	id := args[0]
+	plugin, _, err := plugins.Info(id, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error querying plugin %q: %s", id, err))
+		return 1
+	}
+
+	basic := []string{
+		fmt.Sprintf("ID|%s", plugin.ID),
+		fmt.Sprintf("Provider|%s", plugin.Provider),
+		fmt.Sprintf("Version|%s", plugin.ProviderVersion),
+		fmt.Sprintf("Controller Required|%t", plugin.ControllerRequired),
+		fmt.Sprintf("Controllers Healthy|%d", plugin.ControllersHealthy),
+		fmt.Sprintf("Controllers Expected|%d", len(plugin.Controllers)),
+		fmt.Sprintf("Nodes Healthy|%d", plugin.NodesHealthy),
+		fmt.Sprintf("Nodes Expected|%d", len(plugin.Nodes)),
+	}
+	c.Ui.Output(formatKV(basic))
+
+	if len(plugin.Controllers) != 0 {
+		c.Ui.Output(c.Colorize().Color("\n[bold]Controllers[reset]"))
+		c.Ui.Output(formatCSIPluginInstances(plugin.Controllers, length))
+	}
+	if len(plugin.Nodes) != 0 {
+		c.Ui.Output(c.Colorize().Color("\n[bold]Nodes[reset]"))
+		c.Ui.Output(formatCSIPluginInstances(plugin.Nodes, length))
+	}
+	return 0
+}
+
+// formatCSIPluginInstances formats the plugins running on the nodes, keyed by
+// node ID
+func formatCSIPluginInstances(instances map[string]*api.CSIInfo, length int) string {
+	nodeIDs := make([]string, 0, len(instances))
+	for nodeID := range instances {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+
+	out := make([]string, len(nodeIDs)+1)
+	out[0] = "Node ID|Alloc ID|Healthy|Description"
+	for i, nodeID := range nodeIDs {
+		info := instances[nodeID]
+		out[i+1] = fmt.Sprintf("%s|%s|%t|%s", limit(nodeID, length),
+			limit(info.AllocID, length), info.Healthy, info.HealthDescription)
+	}
+	return formatList(out)
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type QuotaApplyCommand struct {
+	Meta
+}
+
+func (c *QuotaApplyCommand) Help() string {
+	helpText := `
+Usage: nomad quota-apply [options] <path>
+
+  Create or update a quota specification from a JSON file. If the path is
+  "-", the specification is read from stdin. Quotas limit the CPU, memory
+  and number of allocations the namespaces attached to them may consume
+  per region.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *QuotaApplyCommand) Synopsis() string {
+	return "Create or update a quota specification"
+}
+
+func (c *QuotaApplyCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("quota-apply", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one path
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	path := args[0]
+
+	// Read the quota specification
+	var r io.Reader
+	if path == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error opening file %q: %v", path, err))
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var spec api.QuotaSpec
+	if err := json.NewDecoder(r).Decode(&spec); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error parsing quota specification: %s", err))
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if _, err := client.Quotas().Register(&spec, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error applying quota specification: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully applied quota specification %q!", spec.Name))
+	return 0
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type QuotaDeleteCommand struct {
+	Meta
+}
+
+func (c *QuotaDeleteCommand) Help() string {
+	helpText := `
+Usage: nomad quota-delete [options] <quota>
+
+  Delete a quota specification. A quota can only be deleted once it is no
+  longer attached to any namespace.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *QuotaDeleteCommand) Synopsis() string {
+	return "Delete a quota specification"
+}
+
+func (c *QuotaDeleteCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("quota-delete", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one quota
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	name := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if _, err := client.Quotas().Delete(name, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error deleting quota specification: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully deleted quota specification %q!", name))
+	return 0
+}
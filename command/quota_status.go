@@ -0,0 +1,143 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type QuotaStatusCommand struct {
+	Meta
+}
+
+func (c *QuotaStatusCommand) Help() string {
+	helpText := `
+Usage: nomad quota-status [options] [quota]
+
+  Display the status of quota specifications. If no quota is given, all
+  quota specifications are listed. Otherwise the limits of the quota are
+  displayed along with the resources consumed in the region by the
+  namespaces attached to it.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *QuotaStatusCommand) Synopsis() string {
+	return "Display the status and usage of quotas"
+}
+
+func (c *QuotaStatusCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("quota-status", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got either one or zero quotas
+	args = flags.Args()
+	if len(args) > 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+	quotas := client.Quotas()
+
+	// List the quotas if none was given
+	if len(args) == 0 {
+		specs, _, err := quotas.List(nil)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error retrieving quotas: %s", err))
+			return 1
+		}
+		if len(specs) == 0 {
+			c.Ui.Output("No quotas found")
+			return 0
+		}
+		sort.Sort(api.QuotaSpecNameSort(specs))
+
+		out := make([]string, len(specs)+1)
+		out[0] = "Name|Description"
+		for i, spec := range specs {
+			out[i+1] = fmt.Sprintf("%s|%s", spec.Name, spec.Description)
+		}
+		c.Ui.Output(formatList(out))
+		return 0
+	}
+
+	name := args[0]
+	spec, _, err := quotas.Info(name, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error querying quota %q: %s", name, err))
+		return 1
+	}
+	usage, _, err := quotas.Usage(name, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error querying quota usage %q: %s", name, err))
+		return 1
+	}
+
+	namespaces := "<none>"
+	if len(usage.Namespaces) != 0 {
+		namespaces = strings.Join(usage.Namespaces, ",")
+	}
+	basic := []string{
+		fmt.Sprintf("Name|%s", spec.Name),
+		fmt.Sprintf("Description|%s", spec.Description),
+		fmt.Sprintf("Namespaces|%s", namespaces),
+	}
+	c.Ui.Output(formatKV(basic))
+
+	// Display the limits of every region
+	c.Ui.Output(c.Colorize().Color("\n[bold]Quota Limits[reset]"))
+	limits := make([]string, len(spec.Limits)+1)
+	limits[0] = "Region|CPU|Memory MB|Count"
+	for i, l := range spec.Limits {
+		limits[i+1] = fmt.Sprintf("%s|%s|%s|%s", l.Region,
+			formatQuotaLimit(l.CPU), formatQuotaLimit(l.MemoryMB), formatQuotaLimit(l.Count))
+	}
+	c.Ui.Output(formatList(limits))
+
+	// Display the usage against the limit of the queried region
+	c.Ui.Output(c.Colorize().Color("\n[bold]Quota Usage[reset]"))
+	if usage.Limit == nil {
+		c.Ui.Output("Quota does not limit the queried region")
+		return 0
+	}
+	used := usage.Used
+	if used == nil {
+		used = &api.QuotaLimit{}
+	}
+	u := []string{
+		"Region|CPU|Memory MB|Count",
+		fmt.Sprintf("%s|%s|%s|%s", usage.Limit.Region,
+			formatQuotaUsage(used.CPU, usage.Limit.CPU),
+			formatQuotaUsage(used.MemoryMB, usage.Limit.MemoryMB),
+			formatQuotaUsage(used.Count, usage.Limit.Count)),
+	}
+	c.Ui.Output(formatList(u))
+	return 0
+}
+
+// formatQuotaLimit formats a quota limit, where zero is unlimited
+func formatQuotaLimit(limit int) string {
+	if limit == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d", limit)
+}
+
+// formatQuotaUsage formats the used amount of a resource against its limit
+func formatQuotaUsage(used, limit int) string {
+	return fmt.Sprintf("%d / %s", used, formatQuotaLimit(limit))
+}
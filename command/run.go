@@ -51,6 +51,8 @@ Usage: nomad run [options] <path>
 
   If the job has specified the region, the -region flag and NOMAD_REGION
   environment variable are overridden and the the job's region is used.
+  Jobs listing multiple regions are registered in each of them, and may be
+  submitted to any one of them using the -region flag.
 
   The run command will set the vault_token of the job based on the following
   precedence, going from highest to lowest: the -vault-token flag, the
@@ -132,7 +134,7 @@ func (c *RunCommand) Run(args []string) int {
 	}
 
 	// Get Job struct from Jobfile
-	job, err := c.JobGetter.StructJob(args[0])
+	job, submission, err := c.JobGetter.StructJobSubmission(args[0])
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error getting job struct: %s", err))
 		return 1
@@ -141,6 +143,11 @@ func (c *RunCommand) Run(args []string) int {
 	// Initialize any fields that need to be.
 	job.Canonicalize()
 
+	// A job registered in multiple regions may be submitted to any of them
+	if r := c.Meta.region; r != "" && len(job.Regions) != 0 {
+		job.Region = r
+	}
+
 	// Check that the job is valid
 	if err := job.Validate(); err != nil {
 		c.Ui.Error(fmt.Sprintf("Error validating job: %v", err))
@@ -198,13 +205,15 @@ func (c *RunCommand) Run(args []string) int {
 		return 1
 	}
 
-	// Submit the job
-	var evalID string
+	// Submit the job along with its source
+	opts := &api.RegisterOptions{
+		Submission: submission,
+	}
 	if enforce {
-		evalID, _, err = client.Jobs().EnforceRegister(apiJob, checkIndex, nil)
-	} else {
-		evalID, _, err = client.Jobs().Register(apiJob, nil)
+		opts.EnforceIndex = true
+		opts.ModifyIndex = checkIndex
 	}
+	evalID, _, err := client.Jobs().RegisterOpts(apiJob, opts, nil)
 	if err != nil {
 		if strings.Contains(err.Error(), api.RegisterEnforceIndexErrPrefix) {
 			// Format the error specially if the error is due to index
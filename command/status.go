@@ -100,7 +100,7 @@ func (c *StatusCommand) Run(args []string) int {
 			// No output if we have no jobs
 			c.Ui.Output("No running jobs")
 		} else {
-			c.Ui.Output(createStatusListOutput(jobs))
+			c.Ui.Output(createStatusListOutput(jobs, c.allNamespaces()))
 		}
 		return 0
 	}
@@ -117,7 +117,7 @@ func (c *StatusCommand) Run(args []string) int {
 		return 1
 	}
 	if len(jobs) > 1 && strings.TrimSpace(jobID) != jobs[0].ID {
-		c.Ui.Output(fmt.Sprintf("Prefix matched multiple jobs\n\n%s", createStatusListOutput(jobs)))
+		c.Ui.Output(fmt.Sprintf("Prefix matched multiple jobs\n\n%s", createStatusListOutput(jobs, c.allNamespaces())))
 		return 0
 	}
 	// Prefix lookup matched a single job
@@ -142,7 +142,7 @@ func (c *StatusCommand) Run(args []string) int {
 		fmt.Sprintf("Type|%s", job.Type),
 		fmt.Sprintf("Priority|%d", job.Priority),
 		fmt.Sprintf("Datacenters|%s", strings.Join(job.Datacenters, ",")),
-		fmt.Sprintf("Status|%s", job.Status),
+		fmt.Sprintf("Status|%s", formatJobStatus(job.Status, job.Stop)),
 		fmt.Sprintf("Periodic|%v", periodic),
 	}
 
@@ -182,6 +182,27 @@ func (c *StatusCommand) Run(args []string) int {
 // outputPeriodicInfo prints information about the passed periodic job. If a
 // request fails, an error is returned.
 func (c *StatusCommand) outputPeriodicInfo(client *api.Client, job *api.Job) error {
+	// List the next launch of each spec of jobs launched at several specs
+	if len(job.Periodic.Specs) != 0 {
+		launches, _, err := client.Jobs().PeriodicLaunches(job.ID, nil)
+		if err != nil {
+			return fmt.Errorf("Error querying periodic launches: %s", err)
+		}
+
+		now := time.Now().UTC()
+		specs := make([]string, len(launches)+1)
+		specs[0] = "Spec|Enabled|Next Launch"
+		for i, launch := range launches {
+			next := "<none>"
+			if !launch.Next.IsZero() {
+				next = fmt.Sprintf("%s (%s from now)",
+					formatTime(launch.Next), formatTimeDifference(now, launch.Next, time.Second))
+			}
+			specs[i+1] = fmt.Sprintf("%s|%v|%s", launch.Spec, launch.Enabled, next)
+		}
+		c.Ui.Output(fmt.Sprintf("\nPeriodic specs:\n%s", formatList(specs)))
+	}
+
 	// Generate the prefix that matches launched jobs from the periodic job.
 	prefix := fmt.Sprintf("%s%s", job.ID, structs.PeriodicLaunchSuffix)
 	children, _, err := client.Jobs().PrefixList(prefix)
@@ -363,16 +384,39 @@ func convertApiJob(in *api.Job) (*structs.Job, error) {
 	return structJob, nil
 }
 
-// list general information about a list of jobs
-func createStatusListOutput(jobs []*api.JobListStub) string {
+// list general information about a list of jobs, including their namespace
+// when listing across namespaces
+func createStatusListOutput(jobs []*api.JobListStub, allNamespaces bool) string {
 	out := make([]string, len(jobs)+1)
+	if allNamespaces {
+		out[0] = "ID|Namespace|Type|Priority|Status"
+		for i, job := range jobs {
+			out[i+1] = fmt.Sprintf("%s|%s|%s|%d|%s",
+				job.ID,
+				job.Namespace,
+				job.Type,
+				job.Priority,
+				formatJobStatus(job.Status, job.Stop))
+		}
+		return formatList(out)
+	}
+
 	out[0] = "ID|Type|Priority|Status"
 	for i, job := range jobs {
 		out[i+1] = fmt.Sprintf("%s|%s|%d|%s",
 			job.ID,
 			job.Type,
 			job.Priority,
-			job.Status)
+			formatJobStatus(job.Status, job.Stop))
 	}
 	return formatList(out)
 }
+
+// formatJobStatus returns the status of a job, marking jobs that have been
+// stopped by the user.
+func formatJobStatus(status string, stop bool) string {
+	if stop {
+		return fmt.Sprintf("%s (stopped)", status)
+	}
+	return status
+}
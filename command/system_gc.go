@@ -0,0 +1,59 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type SystemGCCommand struct {
+	Meta
+}
+
+func (c *SystemGCCommand) Help() string {
+	helpText := `
+Usage: nomad system-gc [options]
+
+  Force a garbage collection of the terminal evaluations and allocations,
+  dead jobs and down nodes, regardless of how long ago they became eligible
+  for collection.
+
+  When ACLs are enabled, this command requires a management token.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *SystemGCCommand) Synopsis() string {
+	return "Force a garbage collection"
+}
+
+func (c *SystemGCCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("system-gc", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if err := client.System().GarbageCollect(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error running garbage collection: %s", err))
+		return 1
+	}
+
+	c.Ui.Output("Garbage collection started")
+	return 0
+}
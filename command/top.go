@@ -0,0 +1,719 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/mattn/go-isatty"
+)
+
+const (
+	// topViewNodes and topViewJobs are the views the top command can display
+	topViewNodes = "nodes"
+	topViewJobs  = "jobs"
+
+	// The keys rows may be sorted by
+	topSortCPU    = "cpu"
+	topSortMemory = "mem"
+	topSortName   = "name"
+	topSortAllocs = "allocs"
+
+	// topClearScreen moves the cursor home and clears the terminal
+	topClearScreen = "\033[H\033[2J"
+
+	// topStatsConcurrency is the maximum number of allocation stats queried
+	// at once when refreshing the jobs view
+	topStatsConcurrency = 8
+
+	// The control keys handled while running
+	keyCtrlC     = 0x03
+	keyBackspace = 0x08
+	keyEscape    = 0x1b
+	keyDelete    = 0x7f
+)
+
+// topSortKeys is the order the s key cycles through the sort keys
+var topSortKeys = []string{topSortCPU, topSortMemory, topSortName, topSortAllocs}
+
+type TopCommand struct {
+	Meta
+}
+
+func (c *TopCommand) Help() string {
+	helpText := `
+Usage: nomad top [options]
+
+  Display a live updating view of the resource utilization of the cluster,
+  its nodes and its jobs. The utilization is read from the stats APIs of the
+  client nodes, so the nodes must be reachable from where the command is run.
+
+  While running, the view is controlled with the following keys:
+
+    n          Display the nodes view
+    j          Display the jobs view
+    s          Cycle the sort key through "cpu", "mem", "name" and "allocs"
+    f          Edit the filter, only showing rows whose name or ID contains
+               the text. Enter applies the filter, an empty filter clears it
+               and escape cancels the edit.
+    space      Refresh the view immediately
+    q          Quit
+
+  When stdin isn't a terminal, commands are instead read line by line:
+  "n", "j", "s <key>", "f <text>", "f" to clear the filter and "q". An empty
+  line refreshes the view immediately.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Top Options:
+
+  -view=<view>
+    The initial view, either "nodes" or "jobs". Defaults to "nodes".
+
+  -sort=<key>
+    The initial sort key, one of "cpu", "mem", "name" or "allocs". Defaults
+    to "cpu".
+
+  -filter=<text>
+    The initial filter applied to the name and ID of rows.
+
+  -interval=<duration>
+    How often the view is refreshed. Defaults to 2s.
+
+  -once
+    Display the view a single time and exit, without reading commands.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *TopCommand) Synopsis() string {
+	return "Display a live view of cluster resource utilization"
+}
+
+func (c *TopCommand) Run(args []string) int {
+	var once bool
+	var interval time.Duration
+	state := &topState{}
+
+	flags := c.Meta.FlagSet("top", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&state.view, "view", topViewNodes, "")
+	flags.StringVar(&state.sortBy, "sort", topSortCPU, "")
+	flags.StringVar(&state.filter, "filter", "", "")
+	flags.DurationVar(&interval, "interval", 2*time.Second, "")
+	flags.BoolVar(&once, "once", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Validate the options
+	if err := validateTopView(state.view); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if err := validateTopSort(state.sortBy); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if interval <= 0 {
+		c.Ui.Error("Interval must be greater than zero")
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if err := state.refresh(client); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error querying utilization: %s", err))
+		return 1
+	}
+	if once {
+		c.Ui.Output(state.render())
+		return 0
+	}
+
+	// Only clear the screen between refreshes when writing to a terminal
+	clear := isatty.IsTerminal(os.Stdout.Fd())
+	c.draw(state, clear)
+
+	// Read single keys with the input of the terminal in raw mode, falling
+	// back to reading commands line by line when stdin isn't a terminal
+	var keyCh chan byte
+	var cmdCh chan string
+	if restore, err := topRawInput(); err == nil {
+		defer restore()
+		keyCh = make(chan byte)
+		go readTopKeys(os.Stdin, keyCh)
+	} else {
+		cmdCh = make(chan string)
+		go readTopCommands(os.Stdin, cmdCh)
+	}
+
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signalCh)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-signalCh:
+			return 0
+		case <-ticker.C:
+			if err := state.refresh(client); err != nil {
+				state.message = fmt.Sprintf("Error querying utilization: %s", err)
+			}
+		case key, ok := <-keyCh:
+			// Stdin was closed, keep refreshing until interrupted
+			if !ok {
+				keyCh = nil
+				continue
+			}
+			quit, refresh := state.handleKey(key)
+			if quit {
+				return 0
+			}
+			if refresh {
+				if err := state.refresh(client); err != nil {
+					state.message = fmt.Sprintf("Error querying utilization: %s", err)
+				}
+			}
+		case line, ok := <-cmdCh:
+			if !ok {
+				cmdCh = nil
+				continue
+			}
+			quit, refresh := state.handle(line)
+			if quit {
+				return 0
+			}
+			if refresh {
+				if err := state.refresh(client); err != nil {
+					state.message = fmt.Sprintf("Error querying utilization: %s", err)
+				}
+			}
+		}
+		c.draw(state, clear)
+	}
+}
+
+// draw outputs the current view, optionally clearing the screen first
+func (c *TopCommand) draw(state *topState, clear bool) {
+	out := state.render()
+	if clear {
+		out = topClearScreen + out
+	}
+	c.Ui.Output(out)
+}
+
+// topRawInput puts the input of the terminal in raw mode so keys are read as
+// they are typed. It returns an error if stdin isn't a terminal.
+func topRawInput() (func(), error) {
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return nil, fmt.Errorf("stdin is not a terminal")
+	}
+	return makeRawInputTerminal(os.Stdin.Fd())
+}
+
+// readTopKeys sends every key read from the reader to the channel and closes
+// it once the reader is exhausted.
+func readTopKeys(r io.Reader, keyCh chan<- byte) {
+	buf := make([]byte, 32)
+	for {
+		n, err := r.Read(buf)
+		for _, key := range buf[:n] {
+			keyCh <- key
+		}
+		if err != nil {
+			close(keyCh)
+			return
+		}
+	}
+}
+
+// readTopCommands sends every line read from the reader to the channel and
+// closes it once the reader is exhausted.
+func readTopCommands(r io.Reader, cmdCh chan<- string) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		cmdCh <- strings.TrimSpace(scanner.Text())
+	}
+	close(cmdCh)
+}
+
+// topRow is the resource utilization of a single node or job
+type topRow struct {
+	ID     string
+	Name   string
+	Allocs int
+
+	// CPU is the consumed MHz and CPUTotal the MHz available
+	CPU      float64
+	CPUTotal int
+
+	// Memory is the consumed bytes and MemoryTotal the bytes available
+	Memory      uint64
+	MemoryTotal uint64
+
+	// Err is set if the stats of the row could not be retrieved
+	Err error
+}
+
+// topState is the state of the top command which is updated by the commands
+// typed while running.
+type topState struct {
+	view    string
+	sortBy  string
+	filter  string
+	message string
+
+	// editing is set while the filter is being typed into input
+	editing bool
+	input   []byte
+
+	nodes []*topRow
+	jobs  []*topRow
+	at    time.Time
+}
+
+// handle applies the command to the state. It returns whether the command
+// requested to quit and whether the stats should be refreshed.
+func (s *topState) handle(line string) (quit, refresh bool) {
+	s.message = ""
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, true
+	}
+
+	arg := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+	switch fields[0] {
+	case "q", "quit":
+		return true, false
+	case "n", topViewNodes:
+		s.view = topViewNodes
+	case "j", topViewJobs:
+		// The job stats are only queried while displayed
+		s.view = topViewJobs
+		return false, true
+	case "s", "sort":
+		if err := validateTopSort(arg); err != nil {
+			s.message = err.Error()
+		} else {
+			s.sortBy = arg
+		}
+	case "f", "filter":
+		s.filter = arg
+	default:
+		s.message = fmt.Sprintf("Unknown command %q", fields[0])
+	}
+	return false, false
+}
+
+// handleKey applies the key typed in raw mode to the state. It returns
+// whether the key requested to quit and whether the stats should be
+// refreshed.
+func (s *topState) handleKey(key byte) (quit, refresh bool) {
+	if key == keyCtrlC {
+		return true, false
+	}
+	if s.editing {
+		s.editKey(key)
+		return false, false
+	}
+
+	s.message = ""
+	switch key {
+	case 'q':
+		return true, false
+	case 'n':
+		s.view = topViewNodes
+	case 'j':
+		// The job stats are only queried while displayed
+		s.view = topViewJobs
+		return false, true
+	case 's':
+		next := topSortKeys[0]
+		for i, by := range topSortKeys {
+			if by == s.sortBy {
+				next = topSortKeys[(i+1)%len(topSortKeys)]
+				break
+			}
+		}
+		s.sortBy = next
+	case 'f':
+		s.editing = true
+		s.input = []byte(s.filter)
+		s.message = s.filterPrompt()
+	case ' ', '\r', '\n':
+		return false, true
+	default:
+		s.message = fmt.Sprintf("Unknown key %q", key)
+	}
+	return false, false
+}
+
+// editKey applies the key to the filter being edited
+func (s *topState) editKey(key byte) {
+	switch {
+	case key == '\r' || key == '\n':
+		s.filter = string(s.input)
+		s.editing = false
+		s.message = ""
+		return
+	case key == keyEscape:
+		s.editing = false
+		s.message = ""
+		return
+	case key == keyBackspace || key == keyDelete:
+		if len(s.input) != 0 {
+			s.input = s.input[:len(s.input)-1]
+		}
+	case key >= ' ' && key < keyDelete:
+		s.input = append(s.input, key)
+	}
+	s.message = s.filterPrompt()
+}
+
+// filterPrompt returns the message displayed while editing the filter
+func (s *topState) filterPrompt() string {
+	return fmt.Sprintf("Filter: %s_", s.input)
+}
+
+// refresh queries the stats of the nodes and, if displayed, of the jobs
+func (s *topState) refresh(client *api.Client) error {
+	nodes, err := topNodeRows(client)
+	if err != nil {
+		return err
+	}
+	s.nodes = nodes
+
+	// Querying the jobs requires the stats of every running allocation, so
+	// only do so when they are displayed
+	s.jobs = nil
+	if s.view == topViewJobs {
+		jobs, err := topJobRows(client)
+		if err != nil {
+			return err
+		}
+		s.jobs = jobs
+	}
+	s.at = time.Now()
+	return nil
+}
+
+// render returns the cluster summary followed by the table of the view
+func (s *topState) render() string {
+	var b bytes.Buffer
+	b.WriteString(formatKV(topClusterSummary(s.nodes, s.at)))
+	b.WriteString("\n\n")
+
+	rows := s.nodes
+	if s.view == topViewJobs {
+		rows = s.jobs
+	}
+	rows = filterTopRows(rows, s.filter)
+	sortTopRows(rows, s.sortBy)
+
+	filter := ""
+	if s.filter != "" {
+		filter = fmt.Sprintf(", filter %q", s.filter)
+	}
+	b.WriteString(fmt.Sprintf("%s (sorted by %s%s)\n", strings.Title(s.view), s.sortBy, filter))
+	b.WriteString(formatTopRows(s.view, rows))
+
+	if s.message != "" {
+		b.WriteString("\n\n")
+		b.WriteString(s.message)
+	}
+	return b.String()
+}
+
+// topNodeRows returns the utilization of every ready node
+func topNodeRows(client *api.Client) ([]*topRow, error) {
+	stubs, _, err := client.Nodes().List(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []*topRow
+	for _, stub := range stubs {
+		if stub.Status != structs.NodeStatusReady {
+			continue
+		}
+		row := &topRow{
+			ID:   stub.ID,
+			Name: stub.Name,
+		}
+		rows = append(rows, row)
+
+		node, _, err := client.Nodes().Info(stub.ID, nil)
+		if err != nil {
+			row.Err = err
+			continue
+		}
+		if node.Resources != nil {
+			row.CPUTotal = node.Resources.CPU
+		}
+
+		allocs, err := getRunningAllocs(client, stub.ID)
+		if err != nil {
+			row.Err = err
+			continue
+		}
+		row.Allocs = len(allocs)
+
+		hostStats, err := client.Nodes().Stats(stub.ID, nil)
+		if err != nil {
+			row.Err = err
+			continue
+		}
+		row.CPU = hostStats.CPUTicksConsumed
+		if hostStats.Memory != nil {
+			row.Memory = hostStats.Memory.Used
+			row.MemoryTotal = hostStats.Memory.Total
+		}
+	}
+	return rows, nil
+}
+
+// topJobRows returns the utilization of every job with running allocations,
+// summed over the allocations
+func topJobRows(client *api.Client) ([]*topRow, error) {
+	stubs, _, err := client.Allocations().List(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return sumTopJobRows(stubs, func(stub *api.AllocationListStub) (*api.AllocResourceUsage, error) {
+		// Only the allocation and node ID are needed to query the stats
+		alloc := &api.Allocation{ID: stub.ID, NodeID: stub.NodeID}
+		return client.Allocations().Stats(alloc, nil)
+	}), nil
+}
+
+// sumTopJobRows sums the stats of the running allocations per job. The stats
+// are queried concurrently, at most topStatsConcurrency at once, so jobs with
+// many allocations don't stall the refresh.
+func sumTopJobRows(stubs []*api.AllocationListStub,
+	stats func(*api.AllocationListStub) (*api.AllocResourceUsage, error)) []*topRow {
+
+	var running []*api.AllocationListStub
+	for _, stub := range stubs {
+		if stub.ClientStatus == structs.AllocClientStatusRunning {
+			running = append(running, stub)
+		}
+	}
+
+	usages := make([]*api.AllocResourceUsage, len(running))
+	errs := make([]error, len(running))
+	sem := make(chan struct{}, topStatsConcurrency)
+	var wg sync.WaitGroup
+	for i, stub := range running {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, stub *api.AllocationListStub) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			usages[i], errs[i] = stats(stub)
+		}(i, stub)
+	}
+	wg.Wait()
+
+	jobs := make(map[string]*topRow)
+	var rows []*topRow
+	for i, stub := range running {
+		row, ok := jobs[stub.JobID]
+		if !ok {
+			row = &topRow{
+				ID:   stub.JobID,
+				Name: stub.JobID,
+			}
+			jobs[stub.JobID] = row
+			rows = append(rows, row)
+		}
+		row.Allocs++
+
+		if errs[i] != nil {
+			row.Err = errs[i]
+			continue
+		}
+		if usage := usages[i].ResourceUsage; usage != nil {
+			if usage.CpuStats != nil {
+				row.CPU += usage.CpuStats.TotalTicks
+			}
+			if usage.MemoryStats != nil {
+				row.Memory += usage.MemoryStats.RSS
+			}
+		}
+	}
+	return rows
+}
+
+// topClusterSummary sums the utilization of the nodes
+func topClusterSummary(nodes []*topRow, at time.Time) []string {
+	var cpu float64
+	var cpuTotal, allocs int
+	var mem, memTotal uint64
+	for _, n := range nodes {
+		cpu += n.CPU
+		cpuTotal += n.CPUTotal
+		mem += n.Memory
+		memTotal += n.MemoryTotal
+		allocs += n.Allocs
+	}
+
+	return []string{
+		fmt.Sprintf("Updated|%s", at.Format("15:04:05")),
+		fmt.Sprintf("Ready Nodes|%d", len(nodes)),
+		fmt.Sprintf("Running Allocations|%d", allocs),
+		fmt.Sprintf("CPU|%v/%v MHz (%s)", math.Floor(cpu), cpuTotal,
+			formatTopPercent(cpu, float64(cpuTotal))),
+		fmt.Sprintf("Memory|%v/%v (%s)", humanize.IBytes(mem), humanize.IBytes(memTotal),
+			formatTopPercent(float64(mem), float64(memTotal))),
+	}
+}
+
+// formatTopRows formats the rows of the view as a table
+func formatTopRows(view string, rows []*topRow) string {
+	if len(rows) == 0 {
+		return "No results"
+	}
+
+	out := make([]string, len(rows)+1)
+	if view == topViewJobs {
+		out[0] = "Job ID|Allocs|CPU|Memory"
+		for i, r := range rows {
+			if r.Err != nil {
+				out[i+1] = fmt.Sprintf("%s|%d|<error>|%v", r.ID, r.Allocs, r.Err)
+				continue
+			}
+			out[i+1] = fmt.Sprintf("%s|%d|%v MHz|%v",
+				r.ID, r.Allocs, math.Floor(r.CPU), humanize.IBytes(r.Memory))
+		}
+		return formatList(out)
+	}
+
+	out[0] = "ID|Name|Allocs|CPU|CPU %|Memory|Memory %"
+	for i, r := range rows {
+		if r.Err != nil {
+			out[i+1] = fmt.Sprintf("%s|%s|%d|<error>|%v||",
+				limit(r.ID, shortId), r.Name, r.Allocs, r.Err)
+			continue
+		}
+		out[i+1] = fmt.Sprintf("%s|%s|%d|%v/%v MHz|%s|%v/%v|%s",
+			limit(r.ID, shortId), r.Name, r.Allocs,
+			math.Floor(r.CPU), r.CPUTotal, formatTopPercent(r.CPU, float64(r.CPUTotal)),
+			humanize.IBytes(r.Memory), humanize.IBytes(r.MemoryTotal),
+			formatTopPercent(float64(r.Memory), float64(r.MemoryTotal)))
+	}
+	return formatList(out)
+}
+
+// formatTopPercent formats the used amount as a percentage of the total
+func formatTopPercent(used, total float64) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%v%%", humanize.FormatFloat(floatFormat, used/total*100))
+}
+
+// filterTopRows returns the rows whose ID or name contain the filter
+func filterTopRows(rows []*topRow, filter string) []*topRow {
+	if filter == "" {
+		return append([]*topRow(nil), rows...)
+	}
+	var out []*topRow
+	for _, r := range rows {
+		if strings.Contains(r.ID, filter) || strings.Contains(r.Name, filter) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// sortTopRows sorts the rows by the key. Utilization is sorted from the
+// highest to the lowest and names alphabetically.
+func sortTopRows(rows []*topRow, by string) {
+	sort.Sort(topRowSort{rows: rows, by: by})
+}
+
+// topRowSort sorts rows by a key, breaking ties on the name so the order is
+// stable between refreshes
+type topRowSort struct {
+	rows []*topRow
+	by   string
+}
+
+func (t topRowSort) Len() int {
+	return len(t.rows)
+}
+
+func (t topRowSort) Less(i, j int) bool {
+	a, b := t.rows[i], t.rows[j]
+	switch t.by {
+	case topSortMemory:
+		if a.Memory != b.Memory {
+			return a.Memory > b.Memory
+		}
+	case topSortAllocs:
+		if a.Allocs != b.Allocs {
+			return a.Allocs > b.Allocs
+		}
+	case topSortCPU:
+		if a.CPU != b.CPU {
+			return a.CPU > b.CPU
+		}
+	}
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.ID < b.ID
+}
+
+func (t topRowSort) Swap(i, j int) {
+	t.rows[i], t.rows[j] = t.rows[j], t.rows[i]
+}
+
+func validateTopView(view string) error {
+	switch view {
+	case topViewNodes, topViewJobs:
+		return nil
+	default:
+		return fmt.Errorf("Unknown view %q, must be %q or %q", view, topViewNodes, topViewJobs)
+	}
+}
+
+func validateTopSort(by string) error {
+	switch by {
+	case topSortCPU, topSortMemory, topSortName, topSortAllocs:
+		return nil
+	default:
+		return fmt.Errorf("Unknown sort key %q, must be one of %q, %q, %q or %q",
+			by, topSortCPU, topSortMemory, topSortName, topSortAllocs)
+	}
+}
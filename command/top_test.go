@@ -0,0 +1,281 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/mitchellh/cli"
+)
+
+func TestTopCommand_Implements(t *testing.T) {
+	var _ cli.Command = &TopCommand{}
+}
+
+func TestTopCommand_Fails(t *testing.T) {
+	ui := new(cli.MockUi)
+	cmd := &TopCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"some", "bad", "args"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on an unknown view
+	if code := cmd.Run([]string{"-view=foo"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Unknown view") {
+		t.Fatalf("expected unknown view error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on an unknown sort key
+	if code := cmd.Run([]string{"-sort=foo"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Unknown sort key") {
+		t.Fatalf("expected unknown sort key error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope", "-once"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error querying utilization") {
+		t.Fatalf("expected failed query error, got: %s", out)
+	}
+}
+
+func TestTopState_Handle(t *testing.T) {
+	s := &topState{view: topViewNodes, sortBy: topSortCPU}
+
+	if quit, refresh := s.handle(""); quit || !refresh {
+		t.Fatalf("empty line should refresh")
+	}
+	if _, refresh := s.handle("j"); s.view != topViewJobs || !refresh {
+		t.Fatalf("bad view: %q", s.view)
+	}
+	if s.handle("n"); s.view != topViewNodes {
+		t.Fatalf("bad view: %q", s.view)
+	}
+	if s.handle("s mem"); s.sortBy != topSortMemory {
+		t.Fatalf("bad sort: %q", s.sortBy)
+	}
+	if s.handle("s foo"); s.sortBy != topSortMemory || s.message == "" {
+		t.Fatalf("invalid sort key should be rejected")
+	}
+	if s.handle("f web"); s.filter != "web" || s.message != "" {
+		t.Fatalf("bad filter: %q", s.filter)
+	}
+	if s.handle("f"); s.filter != "" {
+		t.Fatalf("filter not cleared: %q", s.filter)
+	}
+	if s.handle("x"); s.message == "" {
+		t.Fatalf("expected unknown command message")
+	}
+	if quit, _ := s.handle("q"); !quit {
+		t.Fatalf("expected quit")
+	}
+}
+
+func TestTopState_HandleKey(t *testing.T) {
+	s := &topState{view: topViewNodes, sortBy: topSortCPU}
+
+	if quit, refresh := s.handleKey(' '); quit || !refresh {
+		t.Fatalf("space should refresh")
+	}
+	if _, refresh := s.handleKey('j'); s.view != topViewJobs || !refresh {
+		t.Fatalf("bad view: %q", s.view)
+	}
+	if s.handleKey('n'); s.view != topViewNodes {
+		t.Fatalf("bad view: %q", s.view)
+	}
+
+	// The sort key cycles through every key
+	for _, expect := range []string{topSortMemory, topSortName, topSortAllocs, topSortCPU} {
+		if s.handleKey('s'); s.sortBy != expect {
+			t.Fatalf("expected sort %q, got %q", expect, s.sortBy)
+		}
+	}
+
+	// Typing the filter, keys are not interpreted as commands
+	for _, key := range []byte("fwebx") {
+		s.handleKey(key)
+	}
+	if !s.editing || s.filter != "" || s.message != "Filter: webx_" {
+		t.Fatalf("bad edit: %q %q", s.filter, s.message)
+	}
+	s.handleKey(keyDelete)
+	if quit, _ := s.handleKey('q'); quit {
+		t.Fatalf("q should be typed into the filter")
+	}
+	s.handleKey(keyBackspace)
+	if s.handleKey('\r'); s.editing || s.filter != "web" || s.message != "" {
+		t.Fatalf("bad filter: %q", s.filter)
+	}
+
+	// Escape cancels the edit
+	for _, key := range []byte("fapi") {
+		s.handleKey(key)
+	}
+	if s.handleKey(keyEscape); s.editing || s.filter != "web" {
+		t.Fatalf("bad filter: %q", s.filter)
+	}
+
+	// Applying an empty filter clears it
+	s.handleKey('f')
+	for range "web" {
+		s.handleKey(keyDelete)
+	}
+	if s.handleKey('\n'); s.editing || s.filter != "" {
+		t.Fatalf("filter not cleared: %q", s.filter)
+	}
+
+	if s.handleKey('x'); s.message == "" {
+		t.Fatalf("expected unknown key message")
+	}
+	if quit, _ := s.handleKey('q'); !quit {
+		t.Fatalf("expected quit")
+	}
+
+	// ctrl+c quits even while editing the filter
+	s.handleKey('f')
+	if quit, _ := s.handleKey(keyCtrlC); !quit {
+		t.Fatalf("expected quit")
+	}
+}
+
+func TestTopRows_SumJobs(t *testing.T) {
+	var stubs []*api.AllocationListStub
+	for i := 0; i < 20; i++ {
+		stubs = append(stubs, &api.AllocationListStub{
+			ID:           fmt.Sprintf("alloc-%d", i),
+			JobID:        fmt.Sprintf("job-%d", i%2),
+			ClientStatus: structs.AllocClientStatusRunning,
+		})
+	}
+	stubs = append(stubs, &api.AllocationListStub{
+		ID:           "complete",
+		JobID:        "job-2",
+		ClientStatus: structs.AllocClientStatusComplete,
+	})
+
+	// Track how many stats are queried at once
+	var l sync.Mutex
+	var inflight, max int
+	stats := func(stub *api.AllocationListStub) (*api.AllocResourceUsage, error) {
+		l.Lock()
+		inflight++
+		if inflight > max {
+			max = inflight
+		}
+		l.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		l.Lock()
+		inflight--
+		l.Unlock()
+
+		if stub.ID == "alloc-3" {
+			return nil, fmt.Errorf("unreachable")
+		}
+		return &api.AllocResourceUsage{
+			ResourceUsage: &api.ResourceUsage{
+				CpuStats:    &api.CpuStats{TotalTicks: 10},
+				MemoryStats: &api.MemoryStats{RSS: 100},
+			},
+		}, nil
+	}
+
+	rows := sumTopJobRows(stubs, stats)
+	if max < 2 || max > topStatsConcurrency {
+		t.Fatalf("expected between 2 and %d concurrent queries, got %d", topStatsConcurrency, max)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(rows))
+	}
+
+	job0, job1 := rows[0], rows[1]
+	if job0.ID != "job-0" || job0.Allocs != 10 || job0.CPU != 100 || job0.Memory != 1000 || job0.Err != nil {
+		t.Fatalf("bad job: %#v", job0)
+	}
+	if job1.ID != "job-1" || job1.Allocs != 10 || job1.Err == nil {
+		t.Fatalf("bad job: %#v", job1)
+	}
+}
+
+func TestTopRows_FilterSort(t *testing.T) {
+	rows := []*topRow{
+		{ID: "1", Name: "web", CPU: 100, Memory: 300, Allocs: 1},
+		{ID: "2", Name: "cache", CPU: 300, Memory: 100, Allocs: 2},
+		{ID: "3", Name: "web-api", CPU: 200, Memory: 200, Allocs: 2},
+	}
+
+	names := func(rows []*topRow) string {
+		var out []string
+		for _, r := range rows {
+			out = append(out, r.Name)
+		}
+		return strings.Join(out, ",")
+	}
+
+	cases := []struct {
+		sortBy string
+		filter string
+		expect string
+	}{
+		{topSortCPU, "", "cache,web-api,web"},
+		{topSortMemory, "", "web,web-api,cache"},
+		{topSortName, "", "cache,web,web-api"},
+		{topSortAllocs, "", "cache,web-api,web"},
+		{topSortCPU, "web", "web-api,web"},
+		{topSortCPU, "2", "cache"},
+	}
+	for _, c := range cases {
+		out := filterTopRows(rows, c.filter)
+		sortTopRows(out, c.sortBy)
+		if actual := names(out); actual != c.expect {
+			t.Fatalf("sort %q filter %q: expected %q, got %q", c.sortBy, c.filter, c.expect, actual)
+		}
+	}
+
+	// Filtering does not modify the input
+	if len(rows) != 3 || rows[0].Name != "web" {
+		t.Fatalf("input modified: %s", names(rows))
+	}
+}
+
+func TestTopState_Render(t *testing.T) {
+	s := &topState{
+		view:   topViewNodes,
+		sortBy: topSortCPU,
+		nodes: []*topRow{
+			{ID: "12345678-abcd", Name: "node1", CPU: 500, CPUTotal: 1000,
+				Memory: 1024 * 1024, MemoryTotal: 4 * 1024 * 1024, Allocs: 2},
+		},
+	}
+
+	out := s.render()
+	for _, expect := range []string{"Ready Nodes", "500/1000 MHz", "50.00%", "node1", "12345678"} {
+		if !strings.Contains(out, expect) {
+			t.Fatalf("expected %q in output:\n%s", expect, out)
+		}
+	}
+
+	// The jobs view without jobs
+	s.view = topViewJobs
+	if out := s.render(); !strings.Contains(out, "No results") {
+		t.Fatalf("expected no results:\n%s", out)
+	}
+}
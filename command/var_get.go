@@ -0,0 +1,94 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type VarGetCommand struct {
+	Meta
+}
+
+func (c *VarGetCommand) Help() string {
+	helpText := `
+Usage: nomad var-get [options] <path> [key]
+
+  Display the variable at the given path. If a key is given, only the value
+  of the item with that key is output.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VarGetCommand) Synopsis() string {
+	return "Display a variable"
+}
+
+func (c *VarGetCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("var-get", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got the path and optionally a key
+	args = flags.Args()
+	if len(args) != 1 && len(args) != 2 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	variable, _, err := client.Variables().Read(args[0], nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving variable: %s", err))
+		return 1
+	}
+
+	if len(args) == 2 {
+		value, ok := variable.Items[args[1]]
+		if !ok {
+			c.Ui.Error(fmt.Sprintf("Variable %q has no item %q", variable.Path, args[1]))
+			return 1
+		}
+		c.Ui.Output(value)
+		return 0
+	}
+
+	c.Ui.Output(formatVariable(variable))
+	return 0
+}
+
+// formatVariable formats the metadata and items of the variable
+func formatVariable(variable *api.Variable) string {
+	basic := []string{
+		fmt.Sprintf("Namespace|%s", variable.Namespace),
+		fmt.Sprintf("Path|%s", variable.Path),
+		fmt.Sprintf("Create Time|%s", formatUnixNanoTime(variable.CreateTime)),
+		fmt.Sprintf("Modify Time|%s", formatUnixNanoTime(variable.ModifyTime)),
+		fmt.Sprintf("Modify Index|%d", variable.ModifyIndex),
+	}
+
+	keys := make([]string, 0, len(variable.Items))
+	for k := range variable.Items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	items := make([]string, len(keys))
+	for i, k := range keys {
+		items[i] = fmt.Sprintf("%s|%s", k, variable.Items[k])
+	}
+	return fmt.Sprintf("%s\n\n[bold]Items[reset]\n%s", formatKV(basic), formatKV(items))
+}
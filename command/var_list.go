@@ -0,0 +1,72 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type VarListCommand struct {
+	Meta
+}
+
+func (c *VarListCommand) Help() string {
+	helpText := `
+Usage: nomad var-list [options] [prefix]
+
+  List the variables of the namespace. If a prefix is given, only the
+  variables whose path has the prefix are listed. The items of the variables
+  are not displayed.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VarListCommand) Synopsis() string {
+	return "List variables"
+}
+
+func (c *VarListCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("var-list", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got either one or zero prefixes
+	args = flags.Args()
+	if len(args) > 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	prefix := ""
+	if len(args) == 1 {
+		prefix = args[0]
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	variables, _, err := client.Variables().PrefixList(prefix, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving variables: %s", err))
+		return 1
+	}
+	if len(variables) == 0 {
+		c.Ui.Output("No variables found")
+		return 0
+	}
+
+	out := make([]string, len(variables)+1)
+	out[0] = "Namespace|Path|Modify Time"
+	for i, v := range variables {
+		out[i+1] = fmt.Sprintf("%s|%s|%s", v.Namespace, v.Path, formatUnixNanoTime(v.ModifyTime))
+	}
+	c.Ui.Output(formatList(out))
+	return 0
+}
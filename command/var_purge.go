@@ -0,0 +1,71 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type VarPurgeCommand struct {
+	Meta
+}
+
+func (c *VarPurgeCommand) Help() string {
+	helpText := `
+Usage: nomad var-purge [options] <path>
+
+  Permanently delete the variable at the given path.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Purge Options:
+
+  -check-index
+    If set, the variable is only deleted if its modify index matches the
+    given index.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VarPurgeCommand) Synopsis() string {
+	return "Delete a variable"
+}
+
+func (c *VarPurgeCommand) Run(args []string) int {
+	var checkIndex int64
+	flags := c.Meta.FlagSet("var-purge", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.Int64Var(&checkIndex, "check-index", -1, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one path
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	path := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if checkIndex >= 0 {
+		_, err = client.Variables().CheckedDelete(path, uint64(checkIndex), nil)
+	} else {
+		_, err = client.Variables().Delete(path, nil)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error deleting variable: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully purged variable %q!", path))
+	return 0
+}
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type VarPutCommand struct {
+	Meta
+}
+
+func (c *VarPutCommand) Help() string {
+	helpText := `
+Usage: nomad var-put [options] <path> <key>=<value> [<key>=<value>...]
+
+  Create or update the variable at the given path. The items of the variable
+  are replaced by the given key/value pairs and are stored encrypted by the
+  servers.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Put Options:
+
+  -check-index
+    If set, the variable is only written if its modify index matches the
+    given index. An index of 0 only creates the variable if it doesn't exist.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VarPutCommand) Synopsis() string {
+	return "Create or update a variable"
+}
+
+func (c *VarPutCommand) Run(args []string) int {
+	var checkIndex int64
+	flags := c.Meta.FlagSet("var-put", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.Int64Var(&checkIndex, "check-index", -1, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got the path and at least one item
+	args = flags.Args()
+	if len(args) < 2 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	variable := &api.Variable{
+		Path:  args[0],
+		Items: make(map[string]string, len(args)-1),
+	}
+	for _, arg := range args[1:] {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			c.Ui.Error(fmt.Sprintf("Invalid item %q, must be of the form <key>=<value>", arg))
+			return 1
+		}
+		variable.Items[parts[0]] = parts[1]
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	var meta *api.VariableMetadata
+	if checkIndex >= 0 {
+		meta, _, err = client.Variables().CheckedPut(variable, uint64(checkIndex), nil)
+	} else {
+		meta, _, err = client.Variables().Put(variable, nil)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error writing variable: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully wrote variable %q at modify index %d!", meta.Path, meta.ModifyIndex))
+	return 0
+}
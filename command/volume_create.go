@@ -0,0 +1,65 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type VolumeCreateCommand struct {
+	Meta
+}
+
+func (c *VolumeCreateCommand) Help() string {
+	helpText := `
+Usage: nomad volume-create [options] <path>
+
+  Create a CSI volume from a JSON file with the controller of its plugin,
+  and register it. If the path is "-", the volume is read from stdin. The
+  ExternalID of the volume is set by the plugin. The volume is created from
+  a snapshot if it specifies a SnapshotID.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VolumeCreateCommand) Synopsis() string {
+	return "Create a CSI volume"
+}
+
+func (c *VolumeCreateCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("volume-create", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one path
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	vol, err := readCSIVolume(args[0])
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	out, _, err := client.CSIVolumes().Create(vol, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error creating volume: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully created volume %q with external ID %q!", out.ID, out.ExternalID))
+	return 0
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type VolumeDeleteCommand struct {
+	Meta
+}
+
+func (c *VolumeDeleteCommand) Help() string {
+	helpText := `
+Usage: nomad volume-delete [options] <volume>
+
+  Delete a CSI volume with the controller of its plugin, and deregister it.
+  The data of the volume is lost. A volume can only be deleted once no
+  allocation claims it.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VolumeDeleteCommand) Synopsis() string {
+	return "Delete a CSI volume"
+}
+
+func (c *VolumeDeleteCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("volume-delete", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one volume
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	id := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if _, err := client.CSIVolumes().Delete(id, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error deleting volume: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully deleted volume %q!", id))
+	return 0
+}
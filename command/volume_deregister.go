@@ -0,0 +1,67 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type VolumeDeregisterCommand struct {
+	Meta
+}
+
+func (c *VolumeDeregisterCommand) Help() string {
+	helpText := `
+Usage: nomad volume-deregister [options] <volume>
+
+  Deregister a CSI volume. The volume is kept in the storage provider. A
+  volume can only be deregistered once no allocation claims it, unless the
+  -force flag is given.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Deregister Options:
+
+  -force
+    Deregister the volume even if allocations still claim it.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VolumeDeregisterCommand) Synopsis() string {
+	return "Deregister a CSI volume"
+}
+
+func (c *VolumeDeregisterCommand) Run(args []string) int {
+	var force bool
+	flags := c.Meta.FlagSet("volume-deregister", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&force, "force", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one volume
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	id := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if _, err := client.CSIVolumes().Deregister(id, force, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error deregistering volume: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully deregistered volume %q!", id))
+	return 0
+}
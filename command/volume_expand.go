@@ -0,0 +1,87 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+type VolumeExpandCommand struct {
+	Meta
+}
+
+func (c *VolumeExpandCommand) Help() string {
+	helpText := `
+Usage: nomad volume-expand [options] <volume>
+
+  Expand a CSI volume with the controller of its plugin. If the plugin
+  requires it, the file system of the volume is also expanded on the nodes
+  it is mounted on. Volumes can't be shrunk.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Expand Options:
+
+  -capacity-min <size>
+    The minimum capacity of the volume, such as "20GiB". Required.
+
+  -capacity-max <size>
+    The maximum capacity of the volume. Defaults to no maximum.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VolumeExpandCommand) Synopsis() string {
+	return "Expand a CSI volume"
+}
+
+func (c *VolumeExpandCommand) Run(args []string) int {
+	var capacityMin, capacityMax string
+	flags := c.Meta.FlagSet("volume-expand", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&capacityMin, "capacity-min", "", "")
+	flags.StringVar(&capacityMax, "capacity-max", "", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one volume and a minimum capacity
+	args = flags.Args()
+	if len(args) != 1 || capacityMin == "" {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	id := args[0]
+
+	min, err := humanize.ParseBytes(capacityMin)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error parsing minimum capacity: %s", err))
+		return 1
+	}
+	var max uint64
+	if capacityMax != "" {
+		if max, err = humanize.ParseBytes(capacityMax); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error parsing maximum capacity: %s", err))
+			return 1
+		}
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	capacity, _, err := client.CSIVolumes().Expand(id, int64(min), int64(max), nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error expanding volume: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully expanded volume %q to %s!", id, formatCSIVolumeCapacity(capacity)))
+	return 0
+}
@@ -0,0 +1,91 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type VolumeRegisterCommand struct {
+	Meta
+}
+
+func (c *VolumeRegisterCommand) Help() string {
+	helpText := `
+Usage: nomad volume-register [options] <path>
+
+  Register or update a CSI volume from a JSON file. If the path is "-", the
+  volume is read from stdin. The volume must already exist in the storage
+  provider, where it is identified by its ExternalID, and be managed by a
+  CSI plugin running in the cluster.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VolumeRegisterCommand) Synopsis() string {
+	return "Register or update a CSI volume"
+}
+
+func (c *VolumeRegisterCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("volume-register", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one path
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	vol, err := readCSIVolume(args[0])
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if _, err := client.CSIVolumes().Register(vol, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error registering volume: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully registered volume %q!", vol.ID))
+	return 0
+}
+
+// readCSIVolume reads a CSI volume from the JSON file at the path, or from
+// stdin if the path is "-"
+func readCSIVolume(path string) (*api.CSIVolume, error) {
+	var r io.Reader
+	if path == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error opening file %q: %v", path, err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var vol api.CSIVolume
+	if err := json.NewDecoder(r).Decode(&vol); err != nil {
+		return nil, fmt.Errorf("Error parsing volume: %s", err)
+	}
+	return &vol, nil
+}
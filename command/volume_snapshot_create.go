@@ -0,0 +1,65 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type VolumeSnapshotCreateCommand struct {
+	Meta
+}
+
+func (c *VolumeSnapshotCreateCommand) Help() string {
+	helpText := `
+Usage: nomad volume-snapshot-create [options] <volume> <name>
+
+  Snapshot a CSI volume with the controller of its plugin. Snapshots are
+  kept by the storage provider, and new volumes can be created from them
+  with volume-create by setting their SnapshotID.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VolumeSnapshotCreateCommand) Synopsis() string {
+	return "Snapshot a CSI volume"
+}
+
+func (c *VolumeSnapshotCreateCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("volume-snapshot-create", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got the volume and the snapshot name
+	args = flags.Args()
+	if len(args) != 2 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	snap := &api.CSISnapshot{
+		SourceVolumeID: args[0],
+		Name:           args[1],
+	}
+	out, _, err := client.CSIVolumes().CreateSnapshot(snap, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error snapshotting volume: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully created snapshot %q of volume %q!", out.ID, out.SourceVolumeID))
+	return 0
+}
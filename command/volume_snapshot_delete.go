@@ -0,0 +1,63 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type VolumeSnapshotDeleteCommand struct {
+	Meta
+}
+
+func (c *VolumeSnapshotDeleteCommand) Help() string {
+	helpText := `
+Usage: nomad volume-snapshot-delete [options] <plugin> <snapshot>
+
+  Delete a snapshot of a CSI volume with the controller of the plugin that
+  took it.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VolumeSnapshotDeleteCommand) Synopsis() string {
+	return "Delete a snapshot of a CSI volume"
+}
+
+func (c *VolumeSnapshotDeleteCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("volume-snapshot-delete", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got the plugin and the snapshot
+	args = flags.Args()
+	if len(args) != 2 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	snap := &api.CSISnapshot{
+		PluginID: args[0],
+		ID:       args[1],
+	}
+	if _, err := client.CSIVolumes().DeleteSnapshot(snap, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error deleting snapshot: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully deleted snapshot %q!", snap.ID))
+	return 0
+}
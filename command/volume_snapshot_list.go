@@ -0,0 +1,94 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+type VolumeSnapshotListCommand struct {
+	Meta
+}
+
+func (c *VolumeSnapshotListCommand) Help() string {
+	helpText := `
+Usage: nomad volume-snapshot-list [options] <plugin>
+
+  List the snapshots of the volumes of a CSI plugin, as kept by the storage
+  provider. Snapshots of volumes not registered in Nomad are listed too.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Snapshot List Options:
+
+  -per-page <n>
+    The number of snapshots to list. Defaults to all of them.
+
+  -page-token <token>
+    The token, returned by a previous call, of the page of snapshots to list.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VolumeSnapshotListCommand) Synopsis() string {
+	return "List the snapshots of a CSI plugin"
+}
+
+func (c *VolumeSnapshotListCommand) Run(args []string) int {
+	var perPage int
+	var pageToken string
+	flags := c.Meta.FlagSet("volume-snapshot-list", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.IntVar(&perPage, "per-page", 0, "")
+	flags.StringVar(&pageToken, "page-token", "", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one plugin
+	args = flags.Args()
+	if len(args) != 1 || perPage < 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	snapshots, nextToken, _, err := client.CSIVolumes().ListSnapshots(args[0], perPage, pageToken, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error listing snapshots: %s", err))
+		return 1
+	}
+	if len(snapshots) == 0 {
+		c.Ui.Output("No snapshots")
+		return 0
+	}
+
+	out := make([]string, len(snapshots)+1)
+	out[0] = "ID|Volume ID|External Volume ID|Size|Created|Ready"
+	for i, s := range snapshots {
+		volumeID := s.SourceVolumeID
+		if volumeID == "" {
+			volumeID = "<none>"
+		}
+		out[i+1] = fmt.Sprintf("%s|%s|%s|%s|%s|%t",
+			s.ID,
+			volumeID,
+			s.ExternalSourceVolumeID,
+			formatCSIVolumeCapacity(s.SizeBytes),
+			formatUnixNanoTime(s.CreateTime),
+			s.IsReady)
+	}
+	c.Ui.Output(formatList(out))
+
+	if nextToken != "" {
+		c.Ui.Output(fmt.Sprintf("\nMore snapshots are available with -page-token=%s", nextToken))
+	}
+	return 0
+}
@@ -0,0 +1,176 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type VolumeStatusCommand struct {
+	Meta
+}
+
+func (c *VolumeStatusCommand) Help() string {
+	helpText := `
+Usage: nomad volume-status [options] [volume]
+
+  Display the status of CSI volumes. If no volume is given, all volumes are
+  listed. Otherwise the volume is displayed along with the allocations
+  claiming it.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Status Options:
+
+  -plugin=""
+    Only list the volumes managed by the given plugin.
+
+  -verbose
+    Display full information.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VolumeStatusCommand) Synopsis() string {
+	return "Display the status of CSI volumes"
+}
+
+func (c *VolumeStatusCommand) Run(args []string) int {
+	var pluginID string
+	var verbose bool
+	flags := c.Meta.FlagSet("volume-status", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&pluginID, "plugin", "", "")
+	flags.BoolVar(&verbose, "verbose", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got either one or zero volumes
+	args = flags.Args()
+	if len(args) > 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+	volumes := client.CSIVolumes()
+
+	// List the volumes if none was given
+	if len(args) == 0 {
+		var stubs []*api.CSIVolumeListStub
+		if pluginID != "" {
+			stubs, _, err = volumes.PluginList(pluginID, nil)
+		} else {
+			stubs, _, err = volumes.List(nil)
+		}
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error retrieving volumes: %s", err))
+			return 1
+		}
+		if len(stubs) == 0 {
+			c.Ui.Output("No volumes found")
+			return 0
+		}
+
+		out := make([]string, len(stubs)+1)
+		out[0] = "ID|Name|Plugin ID|Access Mode|Readers|Writers"
+		for i, v := range stubs {
+			out[i+1] = fmt.Sprintf("%s|%s|%s|%s|%d|%d",
+				v.ID, v.Name, v.PluginID, v.AccessMode, v.Readers, v.Writers)
+		}
+		c.Ui.Output(formatList(out))
+		return 0
+	}
+
+	id := args[0]
+	vol, _, err := volumes.Info(id, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error querying volume %q: %s", id, err))
+		return 1
+	}
+
+	basic := []string{
+		fmt.Sprintf("ID|%s", vol.ID),
+		fmt.Sprintf("Name|%s", vol.Name),
+		fmt.Sprintf("External ID|%s", vol.ExternalID),
+		fmt.Sprintf("Plugin ID|%s", vol.PluginID),
+		fmt.Sprintf("Access Mode|%s", vol.AccessMode),
+		fmt.Sprintf("Attachment Mode|%s", vol.AttachmentMode),
+		fmt.Sprintf("Capacity|%s", formatCSIVolumeCapacity(vol.Capacity)),
+		fmt.Sprintf("Namespace|%s", vol.Namespace),
+	}
+	c.Ui.Output(formatKV(basic))
+
+	// Display the topologies the volume is accessible from
+	if len(vol.Topologies) != 0 {
+		c.Ui.Output(c.Colorize().Color("\n[bold]Topologies[reset]"))
+		topologies := make([]string, len(vol.Topologies))
+		for i, t := range vol.Topologies {
+			topologies[i] = formatCSITopology(t)
+		}
+		c.Ui.Output(strings.Join(topologies, "\n"))
+	}
+
+	// Display the allocations claiming the volume
+	c.Ui.Output(c.Colorize().Color("\n[bold]Claims[reset]"))
+	if len(vol.Claims) == 0 {
+		c.Ui.Output("No allocations claim the volume")
+		return 0
+	}
+	allocIDs := make([]string, 0, len(vol.Claims))
+	for allocID := range vol.Claims {
+		allocIDs = append(allocIDs, allocID)
+	}
+	sort.Strings(allocIDs)
+	claims := make([]string, len(allocIDs)+1)
+	claims[0] = "Alloc ID|Node ID|Mode"
+	for i, allocID := range allocIDs {
+		claim := vol.Claims[allocID]
+		claims[i+1] = fmt.Sprintf("%s|%s|%s",
+			limit(claim.AllocID, length), limit(claim.NodeID, length), claim.Mode)
+	}
+	c.Ui.Output(formatList(claims))
+	return 0
+}
+
+// formatCSIVolumeCapacity formats the capacity of a volume, which is unknown
+// when zero
+func formatCSIVolumeCapacity(capacity int64) string {
+	if capacity == 0 {
+		return "<unknown>"
+	}
+	return fmt.Sprintf("%d MiB", capacity/(1024*1024))
+}
+
+// formatCSITopology formats the segments of a topology in key order
+func formatCSITopology(t *api.CSITopology) string {
+	if t == nil || len(t.Segments) == 0 {
+		return "<none>"
+	}
+	keys := make([]string, 0, len(t.Segments))
+	for k := range t.Segments {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	segments := make([]string, len(keys))
+	for i, k := range keys {
+		segments[i] = fmt.Sprintf("%s=%s", k, t.Segments[k])
+	}
+	return strings.Join(segments, ",")
+}
@@ -25,6 +25,71 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 	}
 
 	return map[string]cli.CommandFactory{
+		"acl-bootstrap": func() (cli.Command, error) {
+			return &command.ACLBootstrapCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl-policy-apply": func() (cli.Command, error) {
+			return &command.ACLPolicyApplyCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl-policy-delete": func() (cli.Command, error) {
+			return &command.ACLPolicyDeleteCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl-policy-info": func() (cli.Command, error) {
+			return &command.ACLPolicyInfoCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl-policy-list": func() (cli.Command, error) {
+			return &command.ACLPolicyListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl-token-create": func() (cli.Command, error) {
+			return &command.ACLTokenCreateCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl-token-delete": func() (cli.Command, error) {
+			return &command.ACLTokenDeleteCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl-token-info": func() (cli.Command, error) {
+			return &command.ACLTokenInfoCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl-token-list": func() (cli.Command, error) {
+			return &command.ACLTokenListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl-token-self": func() (cli.Command, error) {
+			return &command.ACLTokenSelfCommand{
+				Meta: meta,
+			}, nil
+		},
+		"alloc-exec": func() (cli.Command, error) {
+			return &command.AllocExecCommand{
+				Meta: meta,
+			}, nil
+		},
+		"alloc-restart": func() (cli.Command, error) {
+			return &command.AllocRestartCommand{
+				Meta: meta,
+			}, nil
+		},
+		"alloc-signal": func() (cli.Command, error) {
+			return &command.AllocSignalCommand{
+				Meta: meta,
+			}, nil
+		},
 		"alloc-status": func() (cli.Command, error) {
 			return &command.AllocStatusCommand{
 				Meta: meta,
@@ -79,27 +144,147 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 				Meta: meta,
 			}, nil
 		},
+		"job-dispatch": func() (cli.Command, error) {
+			return &command.JobDispatchCommand{
+				Meta: meta,
+			}, nil
+		},
+		"job-history": func() (cli.Command, error) {
+			return &command.JobHistoryCommand{
+				Meta: meta,
+			}, nil
+		},
+		"job-restart": func() (cli.Command, error) {
+			return &command.JobRestartCommand{
+				Meta: meta,
+			}, nil
+		},
+		"job-scale": func() (cli.Command, error) {
+			return &command.JobScaleCommand{
+				Meta: meta,
+			}, nil
+		},
+		"job-start": func() (cli.Command, error) {
+			return &command.JobStartCommand{
+				Meta: meta,
+			}, nil
+		},
+		"keyring-list": func() (cli.Command, error) {
+			return &command.KeyringListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"keyring-rotate": func() (cli.Command, error) {
+			return &command.KeyringRotateCommand{
+				Meta: meta,
+			}, nil
+		},
 		"logs": func() (cli.Command, error) {
 			return &command.LogsCommand{
 				Meta: meta,
 			}, nil
 		},
+		"namespace-apply": func() (cli.Command, error) {
+			return &command.NamespaceApplyCommand{
+				Meta: meta,
+			}, nil
+		},
+		"namespace-delete": func() (cli.Command, error) {
+			return &command.NamespaceDeleteCommand{
+				Meta: meta,
+			}, nil
+		},
+		"namespace-list": func() (cli.Command, error) {
+			return &command.NamespaceListCommand{
+				Meta: meta,
+			}, nil
+		},
 		"node-drain": func() (cli.Command, error) {
 			return &command.NodeDrainCommand{
 				Meta: meta,
 			}, nil
 		},
+		"node-eligibility": func() (cli.Command, error) {
+			return &command.NodeEligibilityCommand{
+				Meta: meta,
+			}, nil
+		},
+		"node-network-rebuild": func() (cli.Command, error) {
+			return &command.NodeNetworkRebuildCommand{
+				Meta: meta,
+			}, nil
+		},
 		"node-status": func() (cli.Command, error) {
 			return &command.NodeStatusCommand{
 				Meta: meta,
 			}, nil
 		},
 
+		"operator-gossip-keyring-install": func() (cli.Command, error) {
+			return &command.OperatorGossipKeyringInstallCommand{
+				Meta: meta,
+			}, nil
+		},
+		"operator-gossip-keyring-list": func() (cli.Command, error) {
+			return &command.OperatorGossipKeyringListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"operator-gossip-keyring-remove": func() (cli.Command, error) {
+			return &command.OperatorGossipKeyringRemoveCommand{
+				Meta: meta,
+			}, nil
+		},
+		"operator-gossip-keyring-use": func() (cli.Command, error) {
+			return &command.OperatorGossipKeyringUseCommand{
+				Meta: meta,
+			}, nil
+		},
+		"operator-raft-list-peers": func() (cli.Command, error) {
+			return &command.OperatorRaftListPeersCommand{
+				Meta: meta,
+			}, nil
+		},
+		"operator-raft-remove-peer": func() (cli.Command, error) {
+			return &command.OperatorRaftRemovePeerCommand{
+				Meta: meta,
+			}, nil
+		},
+		"operator-snapshot-restore": func() (cli.Command, error) {
+			return &command.OperatorSnapshotRestoreCommand{
+				Meta: meta,
+			}, nil
+		},
+		"operator-snapshot-save": func() (cli.Command, error) {
+			return &command.OperatorSnapshotSaveCommand{
+				Meta: meta,
+			}, nil
+		},
 		"plan": func() (cli.Command, error) {
 			return &command.PlanCommand{
 				Meta: meta,
 			}, nil
 		},
+		"plugin-status": func() (cli.Command, error) {
+			return &command.PluginStatusCommand{
+				Meta: meta,
+			}, nil
+		},
+		"quota-apply": func() (cli.Command, error) {
+			return &command.QuotaApplyCommand{
+				Meta: meta,
+			}, nil
+		},
+		"quota-delete": func() (cli.Command, error) {
+			return &command.QuotaDeleteCommand{
+				Meta: meta,
+			}, nil
+		},
+		"quota-status": func() (cli.Command, error) {
+			return &command.QuotaStatusCommand{
+				Meta: meta,
+			}, nil
+		},
 
 		"run": func() (cli.Command, error) {
 			return &command.RunCommand{
@@ -141,6 +326,81 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 				Meta: meta,
 			}, nil
 		},
+		"system-gc": func() (cli.Command, error) {
+			return &command.SystemGCCommand{
+				Meta: meta,
+			}, nil
+		},
+		"top": func() (cli.Command, error) {
+			return &command.TopCommand{
+				Meta: meta,
+			}, nil
+		},
+		"var-get": func() (cli.Command, error) {
+			return &command.VarGetCommand{
+				Meta: meta,
+			}, nil
+		},
+		"var-list": func() (cli.Command, error) {
+			return &command.VarListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"var-purge": func() (cli.Command, error) {
+			return &command.VarPurgeCommand{
+				Meta: meta,
+			}, nil
+		},
+		"var-put": func() (cli.Command, error) {
+			return &command.VarPutCommand{
+				Meta: meta,
+			}, nil
+		},
+		"volume-create": func() (cli.Command, error) {
+			return &command.VolumeCreateCommand{
+				Meta: meta,
+			}, nil
+		},
+		"volume-delete": func() (cli.Command, error) {
+			return &command.VolumeDeleteCommand{
+				Meta: meta,
+			}, nil
+		},
+		"volume-deregister": func() (cli.Command, error) {
+			return &command.VolumeDeregisterCommand{
+				Meta: meta,
+			}, nil
+		},
+		"volume-expand": func() (cli.Command, error) {
+			return &command.VolumeExpandCommand{
+				Meta: meta,
+			}, nil
+		},
+		"volume-register": func() (cli.Command, error) {
+			return &command.VolumeRegisterCommand{
+				Meta: meta,
+			}, nil
+		},
+		"volume-snapshot-create": func() (cli.Command, error) {
+			return &command.VolumeSnapshotCreateCommand{
+				Meta: meta,
+			}, nil
+		},
+		"volume-snapshot-delete": func() (cli.Command, error) {
+			return &command.VolumeSnapshotDeleteCommand{
+				Meta: meta,
+			}, nil
+		},
+		"volume-snapshot-list": func() (cli.Command, error) {
+			return &command.VolumeSnapshotListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"volume-status": func() (cli.Command, error) {
+			return &command.VolumeStatusCommand{
+				Meta: meta,
+			}, nil
+		},
 		"version": func() (cli.Command, error) {
 			ver := Version
 			rel := VersionPrerelease
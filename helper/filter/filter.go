@@ -0,0 +1,330 @@
+// Package filter implements the expression language used to filter the
+// objects returned by list queries. An expression compares the fields of an
+// object to literal values, for example:
+//
+//	Status == "running" and ClientStatus != "complete"
+//
+// Selectors name the fields of the object, with nested fields and the keys
+// of maps separated by dots, such as Meta.rack. The values of the fields are
+// compared to the literals using their text representation, so numbers and
+// booleans may be compared to unquoted literals. Comparisons are combined
+// with "and", "or", "not" and parentheses.
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is a parsed filter expression
+type Expression struct {
+	root node
+}
+
+// Parse parses the filter expression
+func Parse(expr string) (*Expression, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return &Expression{root: root}, nil
+}
+
+// Match returns whether the object matches the expression. An error is
+// returned if a selector doesn't name a field of the object.
+func (e *Expression) Match(obj interface{}) (bool, error) {
+	return e.root.eval(reflect.ValueOf(obj))
+}
+
+// node is a node of the syntax tree of an expression
+type node interface {
+	eval(v reflect.Value) (bool, error)
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n *andNode) eval(v reflect.Value) (bool, error) {
+	ok, err := n.left.eval(v)
+	if err != nil || !ok {
+		return false, err
+	}
+	return n.right.eval(v)
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n *orNode) eval(v reflect.Value) (bool, error) {
+	ok, err := n.left.eval(v)
+	if err != nil || ok {
+		return ok, err
+	}
+	return n.right.eval(v)
+}
+
+type notNode struct {
+	expr node
+}
+
+func (n *notNode) eval(v reflect.Value) (bool, error) {
+	ok, err := n.expr.eval(v)
+	return !ok, err
+}
+
+type compareNode struct {
+	selector []string
+	equal    bool
+	value    string
+}
+
+func (n *compareNode) eval(v reflect.Value) (bool, error) {
+	field, err := lookup(v, n.selector)
+	if err != nil {
+		return false, err
+	}
+	return (field == n.value) == n.equal, nil
+}
+
+// lookup returns the text representation of the field of the value named by
+// the selector. Nil pointers and missing map keys are empty.
+func lookup(v reflect.Value, selector []string) (string, error) {
+	for i, name := range selector {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return "", nil
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := v.Type().FieldByName(name)
+			if !ok || f.PkgPath != "" {
+				return "", fmt.Errorf("unknown selector %q", strings.Join(selector[:i+1], "."))
+			}
+			v = v.FieldByIndex(f.Index)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return "", fmt.Errorf("selector %q can't index a map without string keys", strings.Join(selector[:i+1], "."))
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return "", nil
+			}
+		default:
+			return "", fmt.Errorf("unknown selector %q", strings.Join(selector[:i+1], "."))
+		}
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface()), nil
+	default:
+		return "", fmt.Errorf("selector %q doesn't name a comparable field", strings.Join(selector, "."))
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenEqual
+	tokenNotEqual
+	tokenString
+	tokenWord
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// tokenize splits the expression into tokens
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case strings.HasPrefix(expr[i:], "=="):
+			tokens = append(tokens, token{tokenEqual, "==", i})
+			i += 2
+		case strings.HasPrefix(expr[i:], "!="):
+			tokens = append(tokens, token{tokenNotEqual, "!=", i})
+			i += 2
+		case c == '"':
+			end := i + 1
+			for ; end < len(expr) && expr[end] != '"'; end++ {
+				if expr[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			s, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %v", i, err)
+			}
+			tokens = append(tokens, token{tokenString, s, i})
+			i = end + 1
+		case isWordChar(rune(c)):
+			end := i
+			for end < len(expr) && isWordChar(rune(expr[end])) {
+				end++
+			}
+			tokens = append(tokens, token{tokenWord, expr[i:end], i})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+		}
+	}
+	return append(tokens, token{tokenEOF, "end of expression", len(expr)}), nil
+}
+
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
+
+// parser is a recursive descent parser of the tokens of an expression:
+//
+//	or      = and { "or" and }
+//	and     = unary { "and" unary }
+//	unary   = "not" unary | "(" or ")" | selector ( "==" | "!=" ) literal
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) keyword(word string) bool {
+	tok := p.peek()
+	if tok.kind == tokenWord && tok.text == word {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.keyword("not") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{expr: expr}, nil
+	}
+
+	tok := p.next()
+	switch tok.kind {
+	case tokenLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if end := p.next(); end.kind != tokenRParen {
+			return nil, fmt.Errorf("expected \")\" at position %d, got %q", end.pos, end.text)
+		}
+		return expr, nil
+	case tokenWord:
+		selector := strings.Split(tok.text, ".")
+		for _, part := range selector {
+			if part == "" {
+				return nil, fmt.Errorf("invalid selector %q at position %d", tok.text, tok.pos)
+			}
+		}
+
+		var equal bool
+		switch op := p.next(); op.kind {
+		case tokenEqual:
+			equal = true
+		case tokenNotEqual:
+		default:
+			return nil, fmt.Errorf("expected \"==\" or \"!=\" at position %d, got %q", op.pos, op.text)
+		}
+
+		value := p.next()
+		if value.kind != tokenString && value.kind != tokenWord {
+			return nil, fmt.Errorf("expected a value at position %d, got %q", value.pos, value.text)
+		}
+		return &compareNode{selector: selector, equal: equal, value: value.text}, nil
+	default:
+		return nil, fmt.Errorf("expected a selector at position %d, got %q", tok.pos, tok.text)
+	}
+}
@@ -0,0 +1,117 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+type testResources struct {
+	CPU      int
+	MemoryMB int
+}
+
+type testObject struct {
+	ID           string
+	Status       string
+	ClientStatus string
+	Priority     int
+	Stop         bool
+	Resources    *testResources
+	Meta         map[string]string
+}
+
+func TestExpression_Match(t *testing.T) {
+	obj := &testObject{
+		ID:           "foo",
+		Status:       "running",
+		ClientStatus: "pending",
+		Priority:     50,
+		Resources:    &testResources{CPU: 500, MemoryMB: 256},
+		Meta:         map[string]string{"rack": "r1"},
+	}
+
+	cases := []struct {
+		expr  string
+		match bool
+	}{
+		{`Status == "running"`, true},
+		{`Status != "running"`, false},
+		{`Status == "running" and ClientStatus != "complete"`, true},
+		{`Status == "dead" or ClientStatus == "pending"`, true},
+		{`Status == "dead" or ClientStatus == "complete"`, false},
+		{`not Status == "dead"`, true},
+		{`not (Status == "running" and Priority == 50)`, false},
+		{`Status == "dead" and ClientStatus == "pending" or ID == foo`, true},
+		{`Status == "dead" and (ClientStatus == "pending" or ID == foo)`, false},
+		{`Priority == 50 and Stop == false`, true},
+		{`Resources.MemoryMB == 256`, true},
+		{`Meta.rack == "r1"`, true},
+		{`Meta.zone == ""`, true},
+		{`ID == "fo\"o"`, false},
+	}
+
+	for _, c := range cases {
+		e, err := Parse(c.expr)
+		if err != nil {
+			t.Fatalf("%q: err: %v", c.expr, err)
+		}
+		match, err := e.Match(obj)
+		if err != nil {
+			t.Fatalf("%q: err: %v", c.expr, err)
+		}
+		if match != c.match {
+			t.Fatalf("%q: got %v; want %v", c.expr, match, c.match)
+		}
+	}
+
+	// Nil nested structs are empty
+	e, err := Parse(`Resources.CPU == ""`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if match, err := e.Match(&testObject{}); err != nil || !match {
+		t.Fatalf("bad: %v %v", match, err)
+	}
+}
+
+func TestExpression_Match_UnknownSelector(t *testing.T) {
+	cases := map[string]string{
+		`Foo == "bar"`:          `unknown selector "Foo"`,
+		`Resources.Disk == 10`:  `unknown selector "Resources.Disk"`,
+		`Status.Foo == "bar"`:   `unknown selector "Status.Foo"`,
+		`Resources == "bar"`:    `doesn't name a comparable field`,
+		`Meta == "bar"`:         `doesn't name a comparable field`,
+		`Priority.Foo == "bar"`: `unknown selector "Priority.Foo"`,
+	}
+	obj := &testObject{Resources: &testResources{}}
+	for expr, expected := range cases {
+		e, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("%q: err: %v", expr, err)
+		}
+		if _, err := e.Match(obj); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("%q: bad: %v", expr, err)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	cases := map[string]string{
+		``:                              `expected a selector`,
+		`Status`:                        `expected "==" or "!="`,
+		`Status ==`:                     `expected a value`,
+		`Status = "running"`:            `unexpected '='`,
+		`Status == "running`:            `unterminated string`,
+		`(Status == "running"`:          `expected ")"`,
+		`Status == "running" Priority`:  `unexpected "Priority"`,
+		`Status == "running" and`:       `expected a selector`,
+		`Meta..rack == "r1"`:            `invalid selector`,
+		`Status == "running" or or`:     `expected "==" or "!="`,
+		`Status == "running" and == ""`: `expected a selector`,
+	}
+	for expr, expected := range cases {
+		if _, err := Parse(expr); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("%q: bad: %v", expr, err)
+		}
+	}
+}
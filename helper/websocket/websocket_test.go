@@ -0,0 +1,116 @@
+package websocket
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testEchoServer echoes the messages it reads until the connection is closed
+func testEchoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer conn.Close()
+
+		for {
+			msgType, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(msgType, msg); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestWebsocket_Echo(t *testing.T) {
+	ts := testEchoServer(t)
+	defer ts.Close()
+
+	conn, err := Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer conn.Close()
+
+	// Messages of each payload length encoding
+	for _, size := range []int{0, 10, 200, 70000} {
+		msg := bytes.Repeat([]byte("a"), size)
+		if err := conn.WriteMessage(BinaryMessage, msg); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		msgType, echo, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if msgType != BinaryMessage || !bytes.Equal(echo, msg) {
+			t.Fatalf("bad echo of %d bytes: type %d, %d bytes", size, msgType, len(echo))
+		}
+	}
+
+	// Pings are answered without being returned as messages
+	if err := conn.WriteMessage(PingMessage, []byte("ping")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := conn.WriteMessage(TextMessage, []byte("hello")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	msgType, echo, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if msgType != TextMessage || string(echo) != "hello" {
+		t.Fatalf("bad: %d %q", msgType, echo)
+	}
+}
+
+func TestWebsocket_Close(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		conn.CloseWithReason(4000, "done")
+	}))
+	defer ts.Close()
+
+	conn, err := Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer conn.Close()
+
+	_, _, err = conn.ReadMessage()
+	closeErr, ok := err.(*CloseError)
+	if !ok {
+		t.Fatalf("expected a close error, got: %v", err)
+	}
+	if closeErr.Code != 4000 || closeErr.Reason != "done" {
+		t.Fatalf("bad: %#v", closeErr)
+	}
+}
+
+func TestWebsocket_BadHandshake(t *testing.T) {
+	ts := testEchoServer(t)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad: %d", resp.StatusCode)
+	}
+
+	if _, err := Dial("http"+strings.TrimPrefix(ts.URL, "http"), nil, nil); err == nil {
+		t.Fatalf("expected an error for the http scheme")
+	}
+}
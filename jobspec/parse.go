@@ -100,8 +100,10 @@ func parseJob(result *structs.Job, list *ast.ObjectList) error {
 	delete(m, "constraint")
 	delete(m, "meta")
 	delete(m, "update")
+	delete(m, "multiregion")
 	delete(m, "periodic")
 	delete(m, "vault")
+	delete(m, "parameterized")
 
 	// Set the ID and name to the object key
 	result.ID = obj.Keys[0].Token.Value().(string)
@@ -130,6 +132,9 @@ func parseJob(result *structs.Job, list *ast.ObjectList) error {
 		"id",
 		"name",
 		"region",
+		"regions",
+		"multiregion",
+		"namespace",
 		"all_at_once",
 		"type",
 		"priority",
@@ -142,6 +147,7 @@ func parseJob(result *structs.Job, list *ast.ObjectList) error {
 		"group",
 		"vault",
 		"vault_token",
+		"parameterized",
 	}
 	if err := checkHCLKeys(listVal, valid); err != nil {
 		return multierror.Prefix(err, "job:")
@@ -161,6 +167,13 @@ func parseJob(result *structs.Job, list *ast.ObjectList) error {
 		}
 	}
 
+	// If we have a multi-region strategy, then parse that
+	if o := listVal.Filter("multiregion"); len(o.Items) > 0 {
+		if err := parseMultiregion(&result.Multiregion, o); err != nil {
+			return multierror.Prefix(err, "multiregion ->")
+		}
+	}
+
 	// If we have a periodic definition, then parse that
 	if o := listVal.Filter("periodic"); len(o.Items) > 0 {
 		if err := parsePeriodic(&result.Periodic, o); err != nil {
@@ -168,6 +181,13 @@ func parseJob(result *structs.Job, list *ast.ObjectList) error {
 		}
 	}
 
+	// If we have a parameterized definition, then parse that
+	if o := listVal.Filter("parameterized"); len(o.Items) > 0 {
+		if err := parseParameterizedJob(&result.ParameterizedJob, o); err != nil {
+			return multierror.Prefix(err, "parameterized ->")
+		}
+	}
+
 	// Parse out meta fields. These are in HCL as a list so we need
 	// to iterate over them and merge them.
 	if metaO := listVal.Filter("meta"); len(metaO.Items) > 0 {
@@ -261,7 +281,11 @@ func parseGroups(result *structs.Job, list *ast.ObjectList) error {
 			"meta",
 			"task",
 			"ephemeral_disk",
+			"network",
 			"vault",
+			"scaling",
+			"volume",
+			"migrate",
 		}
 		if err := checkHCLKeys(listVal, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("'%s' ->", n))
@@ -276,7 +300,11 @@ func parseGroups(result *structs.Job, list *ast.ObjectList) error {
 		delete(m, "task")
 		delete(m, "restart")
 		delete(m, "ephemeral_disk")
+		delete(m, "network")
 		delete(m, "vault")
+		delete(m, "scaling")
+		delete(m, "volume")
+		delete(m, "migrate")
 
 		// Default count to 1 if not specified
 		if _, ok := m["count"]; !ok {
@@ -312,6 +340,36 @@ func parseGroups(result *structs.Job, list *ast.ObjectList) error {
 			}
 		}
 
+		// Parse the network shared by the tasks
+		if o := listVal.Filter("network"); len(o.Items) > 0 {
+			network, err := parseNetwork(o, true)
+			if err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s',", n))
+			}
+			g.Networks = []*structs.NetworkResource{network}
+		}
+
+		// Parse the volumes
+		if o := listVal.Filter("volume"); len(o.Items) > 0 {
+			if err := parseVolumes(&g.Volumes, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', volume ->", n))
+			}
+		}
+
+		// Parse the scaling policy
+		if o := listVal.Filter("scaling"); len(o.Items) > 0 {
+			if err := parseScalingPolicy(&g.Scaling, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', scaling ->", n))
+			}
+		}
+
+		// Parse the migrate strategy
+		if o := listVal.Filter("migrate"); len(o.Items) > 0 {
+			if err := parseMigrate(&g.Migrate, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', migrate ->", n))
+			}
+		}
+
 		// Parse out meta fields. These are in HCL as a list so we need
 		// to iterate over them and merge them.
 		if metaO := listVal.Filter("meta"); len(metaO.Items) > 0 {
@@ -477,6 +535,7 @@ func parseEphemeralDisk(result **structs.EphemeralDisk, list *ast.ObjectList) er
 	valid := []string{
 		"sticky",
 		"size",
+		"migrate",
 	}
 	if err := checkHCLKeys(obj.Val, valid); err != nil {
 		return err
@@ -543,16 +602,24 @@ func parseTasks(jobName string, taskGroupName string, result *[]*structs.Task, l
 			"artifact",
 			"config",
 			"constraint",
+			"consul",
+			"csi_plugin",
+			"dispatch_payload",
 			"driver",
 			"env",
 			"kill_timeout",
+			"lifecycle",
 			"logs",
 			"meta",
 			"resources",
+			"restart",
+			"schedule",
 			"service",
+			"shutdown_order",
 			"template",
 			"user",
 			"vault",
+			"volume_mount",
 		}
 		if err := checkHCLKeys(listVal, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("'%s' ->", n))
@@ -565,13 +632,20 @@ func parseTasks(jobName string, taskGroupName string, result *[]*structs.Task, l
 		delete(m, "artifact")
 		delete(m, "config")
 		delete(m, "constraint")
+		delete(m, "consul")
+		delete(m, "csi_plugin")
+		delete(m, "dispatch_payload")
 		delete(m, "env")
+		delete(m, "lifecycle")
+		delete(m, "restart")
 		delete(m, "logs")
 		delete(m, "meta")
 		delete(m, "resources")
+		delete(m, "schedule")
 		delete(m, "service")
 		delete(m, "template")
 		delete(m, "vault")
+		delete(m, "volume_mount")
 
 		// Build the task
 		var t structs.Task
@@ -707,6 +781,13 @@ func parseTasks(jobName string, taskGroupName string, result *[]*structs.Task, l
 			}
 		}
 
+		// Parse volume mounts
+		if o := listVal.Filter("volume_mount"); len(o.Items) > 0 {
+			if err := parseVolumeMounts(&t.VolumeMounts, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', volume_mount ->", n))
+			}
+		}
+
 		// Parse templates
 		if o := listVal.Filter("template"); len(o.Items) > 0 {
 			if err := parseTemplates(&t.Templates, o); err != nil {
@@ -724,12 +805,135 @@ func parseTasks(jobName string, taskGroupName string, result *[]*structs.Task, l
 			t.Vault = &v
 		}
 
+		// If we have a consul block, then parse that
+		if o := listVal.Filter("consul"); len(o.Items) > 0 {
+			var c structs.Consul
+			if err := parseConsul(&c, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', consul ->", n))
+			}
+
+			t.Consul = &c
+		}
+
+		// If we have a schedule block, then parse that
+		if o := listVal.Filter("schedule"); len(o.Items) > 0 {
+			var s structs.TaskSchedule
+			if err := parseTaskSchedule(&s, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', schedule ->", n))
+			}
+
+			t.Schedule = &s
+		}
+
+		// If we have a lifecycle block, then parse that
+		if o := listVal.Filter("lifecycle"); len(o.Items) > 0 {
+			var l structs.TaskLifecycleConfig
+			if err := parseLifecycle(&l, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', lifecycle ->", n))
+			}
+
+			t.Lifecycle = &l
+		}
+
+		// Parse the restart policy overriding the group's
+		if o := listVal.Filter("restart"); len(o.Items) > 0 {
+			if err := parseRestartPolicy(&t.RestartPolicy, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', restart ->", n))
+			}
+		}
+
+		// If we have a csi_plugin block, then parse that
+		if o := listVal.Filter("csi_plugin"); len(o.Items) > 0 {
+			var c structs.TaskCSIPluginConfig
+			if err := parseCSIPluginConfig(&c, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', csi_plugin ->", n))
+			}
+
+			t.CSIPluginConfig = &c
+		}
+
+		// If we have a dispatch_payload block parse that
+		if o := listVal.Filter("dispatch_payload"); len(o.Items) > 0 {
+			if len(o.Items) > 1 {
+				return fmt.Errorf("only one dispatch_payload block is allowed in a task. Number of dispatch_payload blocks found: %d", len(o.Items))
+			}
+			var d structs.DispatchPayloadConfig
+			if err := parseDispatchPayload(&d, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', dispatch_payload ->", n))
+			}
+
+			t.DispatchPayload = &d
+		}
+
 		*result = append(*result, &t)
 	}
 
 	return nil
 }
 
+func parseVolumes(result *map[string]*structs.VolumeRequest, list *ast.ObjectList) error {
+	volumes := make(map[string]*structs.VolumeRequest, len(list.Items))
+	for _, item := range list.Items {
+		if len(item.Keys) != 1 {
+			return fmt.Errorf("volume must be named")
+		}
+		name := item.Keys[0].Token.Value().(string)
+		if _, ok := volumes[name]; ok {
+			return fmt.Errorf("volume '%s' defined more than once", name)
+		}
+
+		// Check for invalid keys
+		valid := []string{
+			"type",
+			"source",
+			"read_only",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("'%s' ->", name))
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return err
+		}
+
+		v := structs.VolumeRequest{Name: name}
+		if err := mapstructure.WeakDecode(m, &v); err != nil {
+			return err
+		}
+		volumes[name] = &v
+	}
+
+	*result = volumes
+	return nil
+}
+
+func parseVolumeMounts(result *[]*structs.VolumeMount, list *ast.ObjectList) error {
+	for _, o := range list.Elem().Items {
+		// Check for invalid keys
+		valid := []string{
+			"volume",
+			"destination",
+			"read_only",
+		}
+		if err := checkHCLKeys(o.Val, valid); err != nil {
+			return err
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, o.Val); err != nil {
+			return err
+		}
+
+		var vm structs.VolumeMount
+		if err := mapstructure.WeakDecode(m, &vm); err != nil {
+			return err
+		}
+		*result = append(*result, &vm)
+	}
+	return nil
+}
+
 func parseArtifacts(result *[]*structs.TaskArtifact, list *ast.ObjectList) error {
 	for _, o := range list.Elem().Items {
 		// Check for invalid keys
@@ -737,6 +941,9 @@ func parseArtifacts(result *[]*structs.TaskArtifact, list *ast.ObjectList) error
 			"source",
 			"options",
 			"destination",
+			"on_error",
+			"retry_attempts",
+			"retry_delay",
 		}
 		if err := checkHCLKeys(o.Val, valid); err != nil {
 			return err
@@ -755,7 +962,15 @@ func parseArtifacts(result *[]*structs.TaskArtifact, list *ast.ObjectList) error
 		}
 
 		var ta structs.TaskArtifact
-		if err := mapstructure.WeakDecode(m, &ta); err != nil {
+		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+			WeaklyTypedInput: true,
+			Result:           &ta,
+		})
+		if err != nil {
+			return err
+		}
+		if err := dec.Decode(m); err != nil {
 			return err
 		}
 
@@ -851,6 +1066,7 @@ func parseServices(jobName string, taskGroupName string, task *structs.Task, ser
 			"tags",
 			"port",
 			"check",
+			"provider",
 		}
 		if err := checkHCLKeys(o.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("service (%d) ->", idx))
@@ -912,6 +1128,9 @@ func parseChecks(service *structs.Service, checkObjs *ast.ObjectList) error {
 			"command",
 			"args",
 			"initial_status",
+			"on_update",
+			"readiness",
+			"check_restart",
 		}
 		if err := checkHCLKeys(co.Val, valid); err != nil {
 			return multierror.Prefix(err, "check ->")
@@ -922,6 +1141,7 @@ func parseChecks(service *structs.Service, checkObjs *ast.ObjectList) error {
 		if err := hcl.DecodeObject(&cm, co.Val); err != nil {
 			return err
 		}
+		delete(cm, "check_restart")
 		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
 			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
 			WeaklyTypedInput: true,
@@ -934,12 +1154,55 @@ func parseChecks(service *structs.Service, checkObjs *ast.ObjectList) error {
 			return err
 		}
 
+		// Parse the check restart
+		if ot, ok := co.Val.(*ast.ObjectType); ok {
+			if o := ot.List.Filter("check_restart"); len(o.Items) > 0 {
+				var cr structs.CheckRestart
+				if err := parseCheckRestart(&cr, o); err != nil {
+					return multierror.Prefix(err, "check_restart ->")
+				}
+				check.CheckRestart = &cr
+			}
+		}
+
 		service.Checks[idx] = &check
 	}
 
 	return nil
 }
 
+func parseCheckRestart(result *structs.CheckRestart, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'check_restart' block allowed per check")
+	}
+
+	// Check for invalid keys
+	o := list.Items[0]
+	valid := []string{
+		"limit",
+		"grace",
+		"ignore_warnings",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           result,
+	})
+	if err != nil {
+		return err
+	}
+	return dec.Decode(m)
+}
+
 func parseResources(result *structs.Resources, list *ast.ObjectList) error {
 	list = list.Elem()
 	if len(list.Items) == 0 {
@@ -963,6 +1226,7 @@ func parseResources(result *structs.Resources, list *ast.ObjectList) error {
 	// Check for invalid keys
 	valid := []string{
 		"cpu",
+		"device",
 		"iops",
 		"memory",
 		"network",
@@ -976,6 +1240,7 @@ func parseResources(result *structs.Resources, list *ast.ObjectList) error {
 		return err
 	}
 	delete(m, "network")
+	delete(m, "device")
 
 	if err := mapstructure.WeakDecode(m, result); err != nil {
 		return err
@@ -983,52 +1248,104 @@ func parseResources(result *structs.Resources, list *ast.ObjectList) error {
 
 	// Parse the network resources
 	if o := listVal.Filter("network"); len(o.Items) > 0 {
-		if len(o.Items) > 1 {
-			return fmt.Errorf("only one 'network' resource allowed")
+		r, err := parseNetwork(o, false)
+		if err != nil {
+			return multierror.Prefix(err, "resources,")
 		}
+		result.Networks = []*structs.NetworkResource{r}
+	}
 
-		// Check for invalid keys
-		valid := []string{
-			"mbits",
-			"port",
+	// Parse the device requests
+	if o := listVal.Filter("device"); len(o.Items) > 0 {
+		devices, err := parseDevices(o)
+		if err != nil {
+			return multierror.Prefix(err, "resources,")
 		}
-		if err := checkHCLKeys(o.Items[0].Val, valid); err != nil {
-			return multierror.Prefix(err, "resources, network ->")
+		result.Devices = devices
+	}
+
+	// Combine the parsed resources with a default resource block.
+	min := structs.DefaultResources()
+	min.Merge(result)
+	*result = *min
+	return nil
+}
+
+// parseDevices parses the device blocks requesting devices of the node. A
+// block requests a single device unless it sets the count.
+func parseDevices(o *ast.ObjectList) ([]*structs.RequestedDevice, error) {
+	var devices []*structs.RequestedDevice
+	for _, item := range o.Items {
+		if len(item.Keys) != 1 {
+			return nil, fmt.Errorf("device block must be named")
 		}
+		name := item.Keys[0].Token.Value().(string)
 
-		var r structs.NetworkResource
-		var m map[string]interface{}
-		if err := hcl.DecodeObject(&m, o.Items[0].Val); err != nil {
-			return err
+		valid := []string{
+			"count",
 		}
-		if err := mapstructure.WeakDecode(m, &r); err != nil {
-			return err
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return nil, multierror.Prefix(err, fmt.Sprintf("device '%s' ->", name))
 		}
 
-		var networkObj *ast.ObjectList
-		if ot, ok := o.Items[0].Val.(*ast.ObjectType); ok {
-			networkObj = ot.List
-		} else {
-			return fmt.Errorf("resource: should be an object")
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return nil, err
 		}
-		if err := parsePorts(networkObj, &r); err != nil {
-			return multierror.Prefix(err, "resources, network, ports ->")
+		d := &structs.RequestedDevice{Name: name, Count: 1}
+		if err := mapstructure.WeakDecode(m, d); err != nil {
+			return nil, err
 		}
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
 
-		result.Networks = []*structs.NetworkResource{&r}
+// parseNetwork parses a network block. Only the networks of task groups may
+// set the networking mode.
+func parseNetwork(o *ast.ObjectList, group bool) (*structs.NetworkResource, error) {
+	if len(o.Items) > 1 {
+		return nil, fmt.Errorf("only one 'network' resource allowed")
 	}
 
-	// Combine the parsed resources with a default resource block.
-	min := structs.DefaultResources()
-	min.Merge(result)
-	*result = *min
-	return nil
+	// Check for invalid keys
+	valid := []string{
+		"mbits",
+		"port",
+	}
+	if group {
+		valid = append(valid, "mode")
+	}
+	if err := checkHCLKeys(o.Items[0].Val, valid); err != nil {
+		return nil, multierror.Prefix(err, "network ->")
+	}
+
+	var r structs.NetworkResource
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Items[0].Val); err != nil {
+		return nil, err
+	}
+	if err := mapstructure.WeakDecode(m, &r); err != nil {
+		return nil, err
+	}
+
+	var networkObj *ast.ObjectList
+	if ot, ok := o.Items[0].Val.(*ast.ObjectType); ok {
+		networkObj = ot.List
+	} else {
+		return nil, fmt.Errorf("network: should be an object")
+	}
+	if err := parsePorts(networkObj, &r); err != nil {
+		return nil, multierror.Prefix(err, "network, ports ->")
+	}
+	return &r, nil
 }
 
 func parsePorts(networkObj *ast.ObjectList, nw *structs.NetworkResource) error {
 	// Check for invalid keys
 	valid := []string{
 		"mbits",
+		"mode",
 		"port",
 	}
 	if err := checkHCLKeys(networkObj, valid); err != nil {
@@ -1102,6 +1419,76 @@ func parseUpdate(result *structs.UpdateStrategy, list *ast.ObjectList) error {
 	return dec.Decode(m)
 }
 
+func parseMultiregion(result **structs.MultiregionStrategy, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'multiregion' block allowed per job")
+	}
+
+	// Get our resource object
+	o := list.Items[0]
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+
+	// Check for invalid keys
+	valid := []string{
+		"max_parallel",
+		"on_failure",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return err
+	}
+
+	var strategy structs.MultiregionStrategy
+	if err := mapstructure.WeakDecode(m, &strategy); err != nil {
+		return err
+	}
+	*result = &strategy
+	return nil
+}
+
+func parseMigrate(result **structs.MigrateStrategy, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'migrate' block allowed per group")
+	}
+
+	// Get our resource object
+	o := list.Items[0]
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+
+	// Check for invalid keys
+	valid := []string{
+		"max_parallel",
+		"min_healthy_time",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return err
+	}
+
+	var strategy structs.MigrateStrategy
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           &strategy,
+	})
+	if err != nil {
+		return err
+	}
+	if err := dec.Decode(m); err != nil {
+		return err
+	}
+	*result = &strategy
+	return nil
+}
+
 func parsePeriodic(result **structs.PeriodicConfig, list *ast.ObjectList) error {
 	list = list.Elem()
 	if len(list.Items) > 1 {
@@ -1115,12 +1502,15 @@ func parsePeriodic(result **structs.PeriodicConfig, list *ast.ObjectList) error
 	if err := hcl.DecodeObject(&m, o.Val); err != nil {
 		return err
 	}
+	delete(m, "spec")
 
 	// Check for invalid keys
 	valid := []string{
 		"enabled",
 		"cron",
 		"prohibit_overlap",
+		"spec",
+		"time_zone",
 	}
 	if err := checkHCLKeys(o.Val, valid); err != nil {
 		return err
@@ -1148,6 +1538,280 @@ func parsePeriodic(result **structs.PeriodicConfig, list *ast.ObjectList) error
 	if err := mapstructure.WeakDecode(m, &p); err != nil {
 		return err
 	}
+
+	// Parse the additional specs
+	if ot, ok := o.Val.(*ast.ObjectType); ok {
+		if specs := ot.List.Filter("spec"); len(specs.Items) > 0 {
+			if err := parsePeriodicSpecs(&p.Specs, specs); err != nil {
+				return multierror.Prefix(err, "spec ->")
+			}
+			p.SpecType = structs.PeriodicSpecCron
+		}
+	}
+
+	*result = &p
+	return nil
+}
+
+func parsePeriodicSpecs(result *[]*structs.PeriodicSpec, list *ast.ObjectList) error {
+	for _, o := range list.Elem().Items {
+		// Check for invalid keys
+		valid := []string{
+			"cron",
+			"enabled",
+		}
+		if err := checkHCLKeys(o.Val, valid); err != nil {
+			return err
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, o.Val); err != nil {
+			return err
+		}
+
+		// Specs are enabled by default
+		spec := structs.PeriodicSpec{Enabled: true}
+		if value, ok := m["enabled"]; ok {
+			enabled, err := parseBool(value)
+			if err != nil {
+				return fmt.Errorf("spec.enabled should be set to true or false; %v", err)
+			}
+			spec.Enabled = enabled
+		}
+		if cron, ok := m["cron"]; ok {
+			spec.Spec = fmt.Sprintf("%v", cron)
+		}
+
+		*result = append(*result, &spec)
+	}
+	return nil
+}
+
+func parseLifecycle(result *structs.TaskLifecycleConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'lifecycle' block allowed per task")
+	}
+
+	// Get our lifecycle object
+	o := list.Items[0]
+
+	// Check for invalid keys
+	valid := []string{
+		"hook",
+		"sidecar",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+	return mapstructure.WeakDecode(m, result)
+}
+
+func parseCSIPluginConfig(result *structs.TaskCSIPluginConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'csi_plugin' block allowed per task")
+	}
+
+	// Get our csi_plugin object
+	o := list.Items[0]
+
+	// Check for invalid keys
+	valid := []string{
+		"id",
+		"type",
+		"mount_dir",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+	return mapstructure.WeakDecode(m, result)
+}
+
+func parseTaskSchedule(result *structs.TaskSchedule, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'schedule' block allowed per task")
+	}
+
+	// Get our schedule object
+	o := list.Items[0]
+
+	// Check for invalid keys
+	valid := []string{
+		"pause",
+		"duration",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           result,
+	})
+	if err != nil {
+		return err
+	}
+	return dec.Decode(m)
+}
+
+func parseConsul(result *structs.Consul, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'consul' block allowed per task")
+	}
+
+	// Get our consul object
+	o := list.Items[0]
+
+	// Check for invalid keys
+	valid := []string{
+		"cluster",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+
+	return mapstructure.WeakDecode(m, result)
+}
+
+func parseDispatchPayload(result *structs.DispatchPayloadConfig, list *ast.ObjectList) error {
+	// Get our dispatch_payload object
+	o := list.Elem().Items[0]
+
+	// Check for invalid keys
+	valid := []string{
+		"file",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+
+	return mapstructure.WeakDecode(m, result)
+}
+
+func parseParameterizedJob(result **structs.ParameterizedJobConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'parameterized' block allowed per job")
+	}
+
+	// Get our parameterized object
+	o := list.Items[0]
+
+	// Check for invalid keys
+	valid := []string{
+		"payload",
+		"meta_required",
+		"meta_optional",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+
+	// Build the parameterized job block
+	var d structs.ParameterizedJobConfig
+	if err := mapstructure.WeakDecode(m, &d); err != nil {
+		return err
+	}
+
+	*result = &d
+	return nil
+}
+
+func parseScalingPolicy(result **structs.ScalingPolicy, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'scaling' block allowed per group")
+	}
+
+	// Get our scaling object
+	o := list.Items[0]
+
+	// We need this later
+	var listVal *ast.ObjectList
+	if ot, ok := o.Val.(*ast.ObjectType); ok {
+		listVal = ot.List
+	} else {
+		return fmt.Errorf("scaling: should be an object")
+	}
+
+	// Check for invalid keys
+	valid := []string{
+		"min",
+		"max",
+		"enabled",
+		"policy",
+	}
+	if err := checkHCLKeys(listVal, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+	delete(m, "policy")
+
+	if _, ok := m["max"]; !ok {
+		return fmt.Errorf("missing required field 'max'")
+	}
+
+	// Default the enabled bool
+	if _, ok := m["enabled"]; !ok {
+		m["enabled"] = true
+	}
+
+	// Build the scaling policy
+	var p structs.ScalingPolicy
+	if err := mapstructure.WeakDecode(m, &p); err != nil {
+		return err
+	}
+
+	// Parse the policy document, which is opaque to Nomad
+	if po := listVal.Filter("policy"); len(po.Items) > 0 {
+		if len(po.Elem().Items) > 1 {
+			return fmt.Errorf("only one 'policy' block allowed per scaling block")
+		}
+		for _, o := range po.Elem().Items {
+			if err := hcl.DecodeObject(&p.Policy, o.Val); err != nil {
+				return err
+			}
+		}
+	}
+
 	*result = &p
 	return nil
 }
@@ -1176,6 +1840,7 @@ func parseVault(result *structs.Vault, list *ast.ObjectList) error {
 	valid := []string{
 		"policies",
 		"env",
+		"cluster",
 	}
 	if err := checkHCLKeys(listVal, valid); err != nil {
 		return multierror.Prefix(err, "vault ->")
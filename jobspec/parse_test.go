@@ -89,8 +89,9 @@ func TestParse(t *testing.T) {
 							Mode:     "delay",
 						},
 						EphemeralDisk: &structs.EphemeralDisk{
-							Sticky: true,
-							SizeMB: 150,
+							Sticky:  true,
+							Migrate: true,
+							SizeMB:  150,
 						},
 						Tasks: []*structs.Task{
 							&structs.Task{
@@ -132,8 +133,8 @@ func TestParse(t *testing.T) {
 									Networks: []*structs.NetworkResource{
 										&structs.NetworkResource{
 											MBits:         100,
-											ReservedPorts: []structs.Port{{"one", 1}, {"two", 2}, {"three", 3}},
-											DynamicPorts:  []structs.Port{{"http", 0}, {"https", 0}, {"admin", 0}},
+											ReservedPorts: []structs.Port{{"one", 1, 0, ""}, {"two", 2, 0, ""}, {"three", 3, 0, ""}},
+											DynamicPorts:  []structs.Port{{"http", 0, 0, ""}, {"https", 0, 0, ""}, {"admin", 0, 0, ""}},
 										},
 									},
 								},
@@ -156,6 +157,9 @@ func TestParse(t *testing.T) {
 										GetterOptions: map[string]string{
 											"checksum": "md5:ff1cc0d3432dad54d607c1505fb7245c",
 										},
+										OnError:       "retry",
+										RetryAttempts: 3,
+										RetryDelay:    10 * time.Second,
 									},
 								},
 								Vault: &structs.Vault{
@@ -192,6 +196,9 @@ func TestParse(t *testing.T) {
 									CPU:      500,
 									MemoryMB: 128,
 									IOPS:     30,
+									Devices: []*structs.RequestedDevice{
+										{Name: "nvidia/gpu", Count: 2},
+									},
 								},
 								Constraints: []*structs.Constraint{
 									&structs.Constraint{
@@ -307,6 +314,45 @@ func TestParse(t *testing.T) {
 					SpecType:        structs.PeriodicSpecCron,
 					Spec:            "*/5 * * *",
 					ProhibitOverlap: true,
+					TimeZone:        "Europe/Berlin",
+				},
+			},
+			false,
+		},
+
+		{
+			"periodic-specs.hcl",
+			&structs.Job{
+				ID:       "foo",
+				Name:     "foo",
+				Priority: 50,
+				Region:   "global",
+				Type:     "service",
+				Periodic: &structs.PeriodicConfig{
+					Enabled:  true,
+					SpecType: structs.PeriodicSpecCron,
+					Spec:     "*/5 * * *",
+					Specs: []*structs.PeriodicSpec{
+						{Spec: "0 9 * * *", Enabled: true},
+						{Spec: "0 17 * * *", Enabled: false},
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"multi-region.hcl",
+			&structs.Job{
+				ID:       "foo",
+				Name:     "foo",
+				Priority: 50,
+				Region:   "us-east",
+				Regions:  []string{"us-east", "us-west", "eu"},
+				Type:     "service",
+				Multiregion: &structs.MultiregionStrategy{
+					MaxParallel: 2,
+					OnFailure:   "fail_local",
 				},
 			},
 			false,
@@ -459,6 +505,97 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"service-check-readiness.hcl",
+			&structs.Job{
+				ID:       "check_readiness",
+				Name:     "check_readiness",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "group",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name: "task",
+								Services: []*structs.Service{
+									{
+										Name:      "check_readiness-group-task",
+										PortLabel: "http",
+										Checks: []*structs.ServiceCheck{
+											{
+												Name:     "alive",
+												Type:     "tcp",
+												Interval: 10 * time.Second,
+												Timeout:  2 * time.Second,
+												OnUpdate: structs.OnUpdateIgnoreWarnings,
+											},
+											{
+												Name:      "ready",
+												Type:      "http",
+												Path:      "/ready",
+												Interval:  5 * time.Second,
+												Timeout:   1 * time.Second,
+												Readiness: true,
+											},
+										},
+									},
+								},
+								LogConfig: structs.DefaultLogConfig(),
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"service-check-restart.hcl",
+			&structs.Job{
+				ID:       "check_restart",
+				Name:     "check_restart",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "group",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name: "task",
+								Services: []*structs.Service{
+									{
+										Name:      "check_restart-group-task",
+										PortLabel: "http",
+										Checks: []*structs.ServiceCheck{
+											{
+												Name:     "alive",
+												Type:     "http",
+												Path:     "/health",
+												Interval: 10 * time.Second,
+												Timeout:  2 * time.Second,
+												CheckRestart: &structs.CheckRestart{
+													Limit:          3,
+													Grace:          90 * time.Second,
+													IgnoreWarnings: true,
+												},
+											},
+										},
+									},
+								},
+								LogConfig: structs.DefaultLogConfig(),
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
 		{
 			"vault_inheritance.hcl",
 			&structs.Job{
@@ -510,6 +647,472 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+
+		{
+			"task-schedule.hcl",
+			&structs.Job{
+				ID:       "example",
+				Name:     "example",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "cache",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:      "redis",
+								LogConfig: structs.DefaultLogConfig(),
+								Schedule: &structs.TaskSchedule{
+									Pause:    "0 2 * * *",
+									Duration: time.Hour,
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"task-lifecycle.hcl",
+			&structs.Job{
+				ID:       "example",
+				Name:     "example",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "cache",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:      "init",
+								LogConfig: structs.DefaultLogConfig(),
+								Lifecycle: &structs.TaskLifecycleConfig{
+									Hook: structs.TaskLifecycleHookPrestart,
+								},
+							},
+							&structs.Task{
+								Name:      "proxy",
+								LogConfig: structs.DefaultLogConfig(),
+								Lifecycle: &structs.TaskLifecycleConfig{
+									Hook:    structs.TaskLifecycleHookPrestart,
+									Sidecar: true,
+								},
+							},
+							&structs.Task{
+								Name:      "redis",
+								Driver:    "docker",
+								LogConfig: structs.DefaultLogConfig(),
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"task-restart.hcl",
+			&structs.Job{
+				ID:       "example",
+				Name:     "example",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "cache",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						RestartPolicy: &structs.RestartPolicy{
+							Attempts: 2,
+							Interval: time.Minute,
+							Delay:    15 * time.Second,
+							Mode:     "delay",
+						},
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:      "redis",
+								Driver:    "docker",
+								LogConfig: structs.DefaultLogConfig(),
+								RestartPolicy: &structs.RestartPolicy{
+									Attempts: 5,
+									Mode:     "fail",
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"task-clusters.hcl",
+			&structs.Job{
+				ID:       "example",
+				Name:     "example",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "cache",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:      "redis",
+								LogConfig: structs.DefaultLogConfig(),
+								Vault: &structs.Vault{
+									Policies: []string{"redis"},
+									Env:      true,
+									Cluster:  "secondary",
+								},
+								Consul: &structs.Consul{
+									Cluster: "secondary",
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"parameterized-job.hcl",
+			&structs.Job{
+				ID:       "parameterized_job",
+				Name:     "parameterized_job",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				ParameterizedJob: &structs.ParameterizedJobConfig{
+					Payload:      "required",
+					MetaRequired: []string{"foo", "bar"},
+					MetaOptional: []string{"baz", "bam"},
+				},
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "foo",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:      "bar",
+								Driver:    "docker",
+								LogConfig: structs.DefaultLogConfig(),
+								DispatchPayload: &structs.DispatchPayloadConfig{
+									File: "foo/bar",
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"scaling-policy.hcl",
+			&structs.Job{
+				ID:       "elastic",
+				Name:     "elastic",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "group",
+						Count:         3,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Scaling: &structs.ScalingPolicy{
+							Min:     1,
+							Max:     10,
+							Enabled: true,
+							Policy: map[string]interface{}{
+								"cooldown": "2m",
+								"target":   70,
+							},
+						},
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:      "task",
+								Driver:    "docker",
+								LogConfig: structs.DefaultLogConfig(),
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"migrate.hcl",
+			&structs.Job{
+				ID:       "example",
+				Name:     "example",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "cache",
+						Count:         3,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Migrate: &structs.MigrateStrategy{
+							MaxParallel:    2,
+							MinHealthyTime: 30 * time.Second,
+						},
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:      "redis",
+								Driver:    "docker",
+								LogConfig: structs.DefaultLogConfig(),
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"service-provider.hcl",
+			&structs.Job{
+				ID:       "example",
+				Name:     "example",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "cache",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:      "redis",
+								LogConfig: structs.DefaultLogConfig(),
+								Services: []*structs.Service{
+									{
+										Name:      "redis-cache",
+										PortLabel: "db",
+										Provider:  structs.ServiceProviderNomad,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"task-shutdown-order.hcl",
+			&structs.Job{
+				ID:       "example",
+				Name:     "example",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "api",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:          "api",
+								LogConfig:     structs.DefaultLogConfig(),
+								ShutdownOrder: 2,
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"group-network.hcl",
+			&structs.Job{
+				ID:       "example",
+				Name:     "example",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "web",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Networks: []*structs.NetworkResource{
+							{
+								Mode:          "bridge",
+								MBits:         20,
+								ReservedPorts: []structs.Port{{"admin", 9000, 0, ""}},
+								DynamicPorts:  []structs.Port{{"http", 0, 8080, ""}},
+							},
+						},
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:      "server",
+								Driver:    "exec",
+								LogConfig: structs.DefaultLogConfig(),
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"host-network.hcl",
+			&structs.Job{
+				ID:       "example",
+				Name:     "example",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "db",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:      "server",
+								Driver:    "exec",
+								LogConfig: structs.DefaultLogConfig(),
+								Resources: &structs.Resources{
+									CPU:      100,
+									MemoryMB: 10,
+									Networks: []*structs.NetworkResource{
+										{
+											MBits:         10,
+											ReservedPorts: []structs.Port{{"db", 5432, 0, "private"}},
+											DynamicPorts:  []structs.Port{{"admin", 0, 0, "private"}},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"host-volume.hcl",
+			&structs.Job{
+				ID:       "example",
+				Name:     "example",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "db",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Volumes: map[string]*structs.VolumeRequest{
+							"data": {
+								Name:   "data",
+								Type:   "host",
+								Source: "shared_data",
+							},
+							"certs": {
+								Name:     "certs",
+								Type:     "host",
+								Source:   "tls",
+								ReadOnly: true,
+							},
+						},
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:      "server",
+								Driver:    "exec",
+								LogConfig: structs.DefaultLogConfig(),
+								VolumeMounts: []*structs.VolumeMount{
+									{
+										Volume:      "data",
+										Destination: "/srv/data",
+									},
+									{
+										Volume:      "certs",
+										Destination: "/etc/tls",
+										ReadOnly:    true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"csi.hcl",
+			&structs.Job{
+				ID:       "example",
+				Name:     "example",
+				Type:     "service",
+				Priority: 50,
+				Region:   "global",
+				TaskGroups: []*structs.TaskGroup{
+					&structs.TaskGroup{
+						Name:          "plugin",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:      "ebs",
+								Driver:    "docker",
+								LogConfig: structs.DefaultLogConfig(),
+								CSIPluginConfig: &structs.TaskCSIPluginConfig{
+									ID:       "aws-ebs",
+									Type:     "monolith",
+									MountDir: "/csi",
+								},
+							},
+						},
+					},
+					&structs.TaskGroup{
+						Name:          "db",
+						Count:         1,
+						EphemeralDisk: structs.DefaultEphemeralDisk(),
+						Volumes: map[string]*structs.VolumeRequest{
+							"data": {
+								Name:   "data",
+								Type:   "csi",
+								Source: "db-data",
+							},
+						},
+						Tasks: []*structs.Task{
+							&structs.Task{
+								Name:      "server",
+								Driver:    "docker",
+								LogConfig: structs.DefaultLogConfig(),
+								VolumeMounts: []*structs.VolumeMount{
+									{
+										Volume:      "data",
+										Destination: "/srv/data",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
 	}
 
 	for _, tc := range cases {
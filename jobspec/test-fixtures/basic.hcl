@@ -44,6 +44,7 @@ job "binstore-storagelocker" {
 
     ephemeral_disk {
         sticky = true
+        migrate = true
         size = 150
     }
 
@@ -123,7 +124,10 @@ job "binstore-storagelocker" {
       }
 
       artifact {
-        source = "http://bar.com/artifact"
+        source         = "http://bar.com/artifact"
+        on_error       = "retry"
+        retry_attempts = 3
+        retry_delay    = "10s"
 
         options {
           checksum = "md5:ff1cc0d3432dad54d607c1505fb7245c"
@@ -160,6 +164,10 @@ job "binstore-storagelocker" {
         cpu    = 500
         memory = 128
         iops   = 30
+
+        device "nvidia/gpu" {
+          count = 2
+        }
       }
 
       constraint {
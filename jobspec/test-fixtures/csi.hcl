@@ -0,0 +1,29 @@
+job "example" {
+  group "plugin" {
+    task "ebs" {
+      driver = "docker"
+
+      csi_plugin {
+        id        = "aws-ebs"
+        type      = "monolith"
+        mount_dir = "/csi"
+      }
+    }
+  }
+
+  group "db" {
+    volume "data" {
+      type   = "csi"
+      source = "db-data"
+    }
+
+    task "server" {
+      driver = "docker"
+
+      volume_mount {
+        volume      = "data"
+        destination = "/srv/data"
+      }
+    }
+  }
+}
@@ -0,0 +1,20 @@
+job "example" {
+  group "web" {
+    network {
+      mode  = "bridge"
+      mbits = 20
+
+      port "http" {
+        to = 8080
+      }
+
+      port "admin" {
+        static = 9000
+      }
+    }
+
+    task "server" {
+      driver = "exec"
+    }
+  }
+}
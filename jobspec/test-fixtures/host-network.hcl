@@ -0,0 +1,22 @@
+job "example" {
+  group "db" {
+    task "server" {
+      driver = "exec"
+
+      resources {
+        network {
+          mbits = 10
+
+          port "db" {
+            static       = 5432
+            host_network = "private"
+          }
+
+          port "admin" {
+            host_network = "private"
+          }
+        }
+      }
+    }
+  }
+}
@@ -0,0 +1,29 @@
+job "example" {
+  group "db" {
+    volume "data" {
+      type   = "host"
+      source = "shared_data"
+    }
+
+    volume "certs" {
+      type      = "host"
+      source    = "tls"
+      read_only = true
+    }
+
+    task "server" {
+      driver = "exec"
+
+      volume_mount {
+        volume      = "data"
+        destination = "/srv/data"
+      }
+
+      volume_mount {
+        volume      = "certs"
+        destination = "/etc/tls"
+        read_only   = true
+      }
+    }
+  }
+}
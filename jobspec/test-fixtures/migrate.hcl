@@ -0,0 +1,14 @@
+job "example" {
+  group "cache" {
+    count = 3
+
+    migrate {
+      max_parallel     = 2
+      min_healthy_time = "30s"
+    }
+
+    task "redis" {
+      driver = "docker"
+    }
+  }
+}
@@ -0,0 +1,9 @@
+job "foo" {
+    region = "us-east"
+    regions = ["us-east", "us-west", "eu"]
+
+    multiregion {
+        max_parallel = 2
+        on_failure = "fail_local"
+    }
+}
@@ -0,0 +1,14 @@
+job "foo" {
+    periodic {
+        cron = "*/5 * * *"
+
+        spec {
+            cron = "0 9 * * *"
+        }
+
+        spec {
+            cron    = "0 17 * * *"
+            enabled = false
+        }
+    }
+}
@@ -0,0 +1,19 @@
+job "elastic" {
+  group "group" {
+    count = 3
+
+    scaling {
+      min = 1
+      max = 10
+
+      policy {
+        cooldown = "2m"
+        target   = 70
+      }
+    }
+
+    task "task" {
+      driver = "docker"
+    }
+  }
+}
@@ -0,0 +1,30 @@
+job "check_readiness" {
+
+    type = "service"
+    group "group" {
+        count = 1
+
+        task "task" {
+          service {
+            port = "http"
+
+            check {
+              name      = "alive"
+              type      = "tcp"
+              interval  = "10s"
+              timeout   = "2s"
+              on_update = "ignore_warnings"
+            }
+
+            check {
+              name      = "ready"
+              type      = "http"
+              path      = "/ready"
+              interval  = "5s"
+              timeout   = "1s"
+              readiness = true
+            }
+          }
+        }
+    }
+}
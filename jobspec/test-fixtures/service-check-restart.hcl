@@ -0,0 +1,27 @@
+job "check_restart" {
+
+    type = "service"
+    group "group" {
+        count = 1
+
+        task "task" {
+          service {
+            port = "http"
+
+            check {
+              name     = "alive"
+              type     = "http"
+              path     = "/health"
+              interval = "10s"
+              timeout  = "2s"
+
+              check_restart {
+                limit           = 3
+                grace           = "90s"
+                ignore_warnings = true
+              }
+            }
+          }
+        }
+    }
+}
@@ -0,0 +1,11 @@
+job "example" {
+  group "cache" {
+    task "redis" {
+      service {
+        name     = "redis-cache"
+        port     = "db"
+        provider = "nomad"
+      }
+    }
+  }
+}
@@ -0,0 +1,14 @@
+job "example" {
+  group "cache" {
+    task "redis" {
+      vault {
+        policies = ["redis"]
+        cluster  = "secondary"
+      }
+
+      consul {
+        cluster = "secondary"
+      }
+    }
+  }
+}
@@ -0,0 +1,20 @@
+job "example" {
+  group "cache" {
+    task "init" {
+      lifecycle {
+        hook = "prestart"
+      }
+    }
+
+    task "proxy" {
+      lifecycle {
+        hook    = "prestart"
+        sidecar = true
+      }
+    }
+
+    task "redis" {
+      driver = "docker"
+    }
+  }
+}
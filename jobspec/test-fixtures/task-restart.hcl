@@ -0,0 +1,19 @@
+job "example" {
+  group "cache" {
+    restart {
+      attempts = 2
+      interval = "1m"
+      delay    = "15s"
+      mode     = "delay"
+    }
+
+    task "redis" {
+      driver = "docker"
+
+      restart {
+        attempts = 5
+        mode     = "fail"
+      }
+    }
+  }
+}
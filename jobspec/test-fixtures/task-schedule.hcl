@@ -0,0 +1,10 @@
+job "example" {
+  group "cache" {
+    task "redis" {
+      schedule {
+        pause    = "0 2 * * *"
+        duration = "1h"
+      }
+    }
+  }
+}
@@ -0,0 +1,7 @@
+job "example" {
+  group "api" {
+    task "api" {
+      shutdown_order = 2
+    }
+  }
+}
@@ -0,0 +1,197 @@
+package nomad
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	lru "github.com/hashicorp/golang-lru/simplelru"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// aclCacheSize is the number of ACL objects to keep cached. ACLs have a
+	// parsing and construction cost, so we optimize to keep the commonly
+	// used policy combinations cached.
+	aclCacheSize = 512
+)
+
+// aclCache caches compiled ACL objects keyed by the hash of the policies
+// they were compiled from
+type aclCache struct {
+	l   sync.Mutex
+	lru *lru.LRU
+}
+
+// newACLCache returns a new cache of compiled ACL objects
+func newACLCache(size int) (*aclCache, error) {
+	l, err := lru.NewLRU(size, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &aclCache{lru: l}, nil
+}
+
+func (c *aclCache) get(key string) (*acl.ACL, bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	raw, ok := c.lru.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return raw.(*acl.ACL), true
+}
+
+func (c *aclCache) add(key string, aclObj *acl.ACL) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	c.lru.Add(key, aclObj)
+}
+
+// ResolveToken is used to translate an ACL Token Secret ID into
+// an ACL object, nil if ACLs are disabled, or an error.
+func (s *Server) ResolveToken(secretID string) (*acl.ACL, error) {
+	// Fast-path if ACLs are disabled
+	if !s.config.ACLEnabled {
+		return nil, nil
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "resolveToken"}, time.Now())
+
+	// Snapshot the state
+	snap, err := s.fsm.State().Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the ACL
+	return resolveTokenFromSnapshotCache(snap, s.aclCache, secretID)
+}
+
+// resolveTokenFromSnapshotCache is used to resolve an ACL object from a snapshot of state,
+// using a cache to avoid parsing and ACL construction when possible. It is analogous
+// to the ResolveToken method.
+func resolveTokenFromSnapshotCache(snap *state.StateSnapshot, cache *aclCache, secretID string) (*acl.ACL, error) {
+	// Lookup the ACL Token
+	var token *structs.ACLToken
+	var err error
+
+	// Handle anonymous requests
+	if secretID == "" {
+		token = structs.AnonymousACLToken
+	} else {
+		token, err = snap.ACLTokenBySecretID(secretID)
+		if err != nil {
+			return nil, err
+		}
+		if token == nil {
+			return nil, structs.ErrTokenNotFound
+		}
+	}
+
+	// Check if this is a management token
+	if token.Type == structs.ACLManagementToken {
+		return acl.ManagementACL, nil
+	}
+
+	// Get all associated policies
+	policies := make([]*structs.ACLPolicy, 0, len(token.Policies))
+	for _, policyName := range token.Policies {
+		policy, err := snap.ACLPolicyByName(policyName)
+		if err != nil {
+			return nil, err
+		}
+		if policy == nil {
+			// Ignore policies that don't exist, since they don't grant any more privilege
+			continue
+		}
+
+		// Save the policy and update the cache key
+		policies = append(policies, policy)
+	}
+
+	// Compile and cache the ACL object
+	return compileACLObject(cache, policies)
+}
+
+// compileACLObject compiles a set of ACL policies into an ACL object with a
+// cache keyed by the hash of the policies
+func compileACLObject(cache *aclCache, policies []*structs.ACLPolicy) (*acl.ACL, error) {
+	// Determine the cache key
+	hash := sha256.New()
+	for _, policy := range policies {
+		hash.Write([]byte(policy.Name))
+		hash.Write(policy.Hash)
+	}
+	cacheKey := fmt.Sprintf("%x", hash.Sum(nil))
+
+	// Check for a cached ACL object
+	if aclObj, ok := cache.get(cacheKey); ok {
+		return aclObj, nil
+	}
+
+	// Parse the policies
+	parsed := make([]*acl.Policy, 0, len(policies))
+	for _, policy := range policies {
+		p, err := acl.Parse(policy.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %v", policy.Name, err)
+		}
+		parsed = append(parsed, p)
+	}
+
+	// Create the ACL object
+	aclObj, err := acl.NewACL(false, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct ACL: %v", err)
+	}
+
+	// Update the cache
+	cache.add(cacheKey, aclObj)
+	return aclObj, nil
+}
+
+// allowedNamespaces returns the set of namespaces in which the ACL object
+// permits the operation, for use by queries across all namespaces. A nil set
+// is returned if every namespace is permitted.
+func allowedNamespaces(aclObj *acl.ACL, snap *state.StateSnapshot, op string) (map[string]bool, error) {
+	// ACLs disabled or a management token
+	if aclObj == nil || aclObj.IsManagement() {
+		return nil, nil
+	}
+
+	iter, err := snap.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+
+	// The default namespace exists implicitly
+	allowed := make(map[string]bool)
+	if aclObj.AllowNamespaceOperation(structs.DefaultNamespace, op) {
+		allowed[structs.DefaultNamespace] = true
+	}
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		ns := raw.(*structs.Namespace)
+		if aclObj.AllowNamespaceOperation(ns.Name, op) {
+			allowed[ns.Name] = true
+		}
+	}
+	return allowed, nil
+}
+
+// namespaceMatches returns whether an object in the namespace is part of the
+// results of a query for the requested namespace. Queries across all
+// namespaces match the allowed namespaces, or every namespace if nil.
+func namespaceMatches(ns, requested string, allowed map[string]bool) bool {
+	if requested != structs.AllNamespacesSentinel {
+		return ns == requested
+	}
+	return allowed == nil || allowed[ns]
+}
@@ -0,0 +1,885 @@
+package nomad
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/watch"
+)
+
+var (
+	// aclDisabled is returned when an ACL endpoint is hit but ACLs are not enabled
+	aclDisabled = fmt.Errorf("ACL support disabled")
+)
+
+const (
+	// aclBootstrapReset is the file name to create in the data dir. Its only
+	// contents should be the reset index
+	aclBootstrapReset = "acl-bootstrap-reset"
+)
+
+// ACL endpoint is used for manipulating ACL tokens and policies
+type ACL struct {
+	srv *Server
+}
+
+// UpsertPolicies is used to create or update a set of policies
+func (a *ACL) UpsertPolicies(args *structs.ACLPolicyUpsertRequest, reply *structs.GenericResponse) error {
+	// Ensure ACLs are enabled, and always flow modification requests to the authoritative region
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+	args.Region = a.srv.config.AuthoritativeRegion
+
+	if done, err := a.srv.forward("ACL.UpsertPolicies", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "upsert_policies"}, time.Now())
+
+	// Check management level permissions
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj == nil || !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate non-zero set of policies
+	if len(args.Policies) == 0 {
+		return fmt.Errorf("must specify at least one policy")
+	}
+
+	// Validate each policy, compute hash
+	for idx, policy := range args.Policies {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("policy %d invalid: %v", idx, err)
+		}
+		if _, err := acl.Parse(policy.Rules); err != nil {
+			return fmt.Errorf("policy %d invalid: %v", idx, err)
+		}
+		policy.SetHash()
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLPolicyUpsertRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// DeletePolicies is used to delete policies
+func (a *ACL) DeletePolicies(args *structs.ACLPolicyDeleteRequest, reply *structs.GenericResponse) error {
+	// Ensure ACLs are enabled, and always flow modification requests to the authoritative region
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+	args.Region = a.srv.config.AuthoritativeRegion
+
+	if done, err := a.srv.forward("ACL.DeletePolicies", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "delete_policies"}, time.Now())
+
+	// Check management level permissions
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj == nil || !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate non-zero set of policies
+	if len(args.Names) == 0 {
+		return fmt.Errorf("must specify at least one policy")
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLPolicyDeleteRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// ListPolicies is used to list the policies
+func (a *ACL) ListPolicies(args *structs.ACLPolicyListRequest, reply *structs.ACLPolicyListResponse) error {
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	if done, err := a.srv.forward("ACL.ListPolicies", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "list_policies"}, time.Now())
+
+	// Check management level permissions
+	aclObj, err := a.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	} else if aclObj == nil {
+		return structs.ErrPermissionDenied
+	}
+
+	// If it is not a management token determine the policies that may be listed
+	mgt := aclObj.IsManagement()
+	var policies map[string]struct{}
+	if !mgt {
+		token, err := a.requestACLToken(args.AuthToken)
+		if err != nil {
+			return err
+		}
+		if token == nil {
+			return structs.ErrTokenNotFound
+		}
+
+		policies = make(map[string]struct{}, len(token.Policies))
+		for _, p := range token.Policies {
+			policies[p] = struct{}{}
+		}
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "acl_policy"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Iterate over all the policies
+			var err error
+			var iter memdb.ResultIterator
+			if prefix := args.QueryOptions.Prefix; prefix != "" {
+				iter, err = snap.ACLPolicyByNamePrefix(prefix)
+			} else {
+				iter, err = snap.ACLPolicies()
+			}
+			if err != nil {
+				return err
+			}
+
+			// Convert all the policies to a list stub
+			reply.Policies = nil
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				policy := raw.(*structs.ACLPolicy)
+				if _, ok := policies[policy.Name]; ok || mgt {
+					reply.Policies = append(reply.Policies, policy.Stub())
+				}
+			}
+
+			// Use the last index that affected the policy table
+			index, err := snap.Index("acl_policy")
+			if err != nil {
+				return err
+			}
+
+			// Ensure we never set the index to zero, otherwise a blocking query cannot be used.
+			// We floor the index at one, since realistically the first write must have a higher index.
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// GetPolicy is used to get a specific policy
+func (a *ACL) GetPolicy(args *structs.ACLPolicySpecificRequest, reply *structs.SingleACLPolicyResponse) error {
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+	if done, err := a.srv.forward("ACL.GetPolicy", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_policy"}, time.Now())
+
+	// Check management level permissions, or that the token is
+	// associated with the policy
+	aclObj, err := a.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	} else if aclObj == nil {
+		return structs.ErrPermissionDenied
+	}
+	if !aclObj.IsManagement() {
+		token, err := a.requestACLToken(args.AuthToken)
+		if err != nil {
+			return err
+		}
+		if token == nil || !token.PolicySubset([]string{args.Name}) {
+			return structs.ErrPermissionDenied
+		}
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "acl_policy"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Look for the policy
+			out, err := snap.ACLPolicyByName(args.Name)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Policy = out
+			if out != nil {
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the policy table
+				index, err := snap.Index("acl_policy")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// requestACLToken returns the ACL token for the given secret, or the
+// anonymous token if no secret is given
+func (a *ACL) requestACLToken(secretID string) (*structs.ACLToken, error) {
+	if secretID == "" {
+		return structs.AnonymousACLToken, nil
+	}
+
+	snap, err := a.srv.fsm.State().Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return snap.ACLTokenBySecretID(secretID)
+}
+
+// GetPolicies is used to get a set of policies
+func (a *ACL) GetPolicies(args *structs.ACLPolicySetRequest, reply *structs.ACLPolicySetResponse) error {
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+	if done, err := a.srv.forward("ACL.GetPolicies", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_policies"}, time.Now())
+
+	// For client typed tokens, allow them to query any policies associated with that token.
+	// This is used by clients which are resolving the policies to enforce. Any associated
+	// policies need to be fetched so that the client can determine what to allow.
+	token, err := a.requestACLToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return structs.ErrTokenNotFound
+	}
+	if token.Type != structs.ACLManagementToken && !token.PolicySubset(args.Names) {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "acl_policy"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Setup the output
+			reply.Policies = make(map[string]*structs.ACLPolicy, len(args.Names))
+
+			// Look for the policy
+			for _, policyName := range args.Names {
+				out, err := snap.ACLPolicyByName(policyName)
+				if err != nil {
+					return err
+				}
+				if out != nil {
+					reply.Policies[policyName] = out
+				}
+			}
+
+			// Use the last index that affected the policy table
+			index, err := snap.Index("acl_policy")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// Bootstrap is used to bootstrap the initial token
+func (a *ACL) Bootstrap(args *structs.ACLTokenBootstrapRequest, reply *structs.ACLTokenUpsertResponse) error {
+	// Ensure ACLs are enabled, and always flow modification requests to the authoritative region
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+	args.Region = a.srv.config.AuthoritativeRegion
+
+	if done, err := a.srv.forward("ACL.Bootstrap", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "bootstrap"}, time.Now())
+
+	// Always ignore the reset index from the arguments
+	args.ResetIndex = 0
+
+	// Snapshot the state
+	state, err := a.srv.State().Snapshot()
+	if err != nil {
+		return err
+	}
+
+	// Verify bootstrap is possible. The state store method re-verifies this,
+	// but we do an early check to avoid raft transactions when possible.
+	ok, resetIdx, err := state.CanBootstrapACLToken()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// Check if there is a reset index specified
+		specifiedIndex := a.fileBootstrapResetIndex()
+		if specifiedIndex == 0 {
+			return fmt.Errorf("ACL bootstrap already done (reset index: %d)", resetIdx)
+		} else if specifiedIndex != resetIdx {
+			return fmt.Errorf("Invalid bootstrap reset index (specified %d, reset index: %d)", specifiedIndex, resetIdx)
+		}
+
+		// Setup the reset index to allow bootstrapping
+		args.ResetIndex = resetIdx
+	}
+
+	// Create a new global management token, override any parameter
+	args.Token = &structs.ACLToken{
+		AccessorID: structs.GenerateUUID(),
+		SecretID:   structs.GenerateUUID(),
+		Name:       "Bootstrap Token",
+		Type:       structs.ACLManagementToken,
+		Global:     true,
+		CreateTime: time.Now().UTC(),
+	}
+	args.Token.SetHash()
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLTokenBootstrapRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Lookup the token
+	state, err = a.srv.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	out, err := state.ACLTokenByAccessorID(args.Token.AccessorID)
+	if err != nil {
+		return err
+	}
+	if out != nil {
+		reply.Tokens = append(reply.Tokens, out)
+	}
+	a.srv.logger.Printf("[INFO] nomad.acl: bootstrapped ACL system with token %q", args.Token.AccessorID)
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// fileBootstrapResetIndex is used to read the reset file from <data-dir>/acl-bootstrap-reset
+func (a *ACL) fileBootstrapResetIndex() uint64 {
+	// Determine the file path to check
+	path := filepath.Join(a.srv.config.DataDir, aclBootstrapReset)
+
+	// Read the file
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			a.srv.logger.Printf("[ERR] nomad.acl: failed to read %q: %v", path, err)
+		}
+		return 0
+	}
+
+	// Attempt to parse the file
+	var resetIdx uint64
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(raw)), "%d", &resetIdx); err != nil {
+		a.srv.logger.Printf("[ERR] nomad.acl: failed to parse %q: %v", path, err)
+		return 0
+	}
+
+	// Return the reset index
+	a.srv.logger.Printf("[WARN] nomad.acl: bootstrap reset index is %d", resetIdx)
+	return resetIdx
+}
+
+// UpsertTokens is used to create or update a set of tokens
+func (a *ACL) UpsertTokens(args *structs.ACLTokenUpsertRequest, reply *structs.ACLTokenUpsertResponse) error {
+	// Ensure ACLs are enabled, and always flow modification requests to the authoritative region
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Validate non-zero set of tokens
+	if len(args.Tokens) == 0 {
+		return fmt.Errorf("must specify at least one token")
+	}
+
+	// Force the request to the authoritative region if we are creating global tokens
+	hasGlobal := false
+	allGlobal := true
+	for _, token := range args.Tokens {
+		if token.Global {
+			hasGlobal = true
+		} else {
+			allGlobal = false
+		}
+	}
+
+	// Disallow mixed requests with global and non-global tokens since we forward
+	// the entire request as a single batch.
+	if hasGlobal {
+		if !allGlobal {
+			return fmt.Errorf("cannot upsert mixed global and non-global tokens")
+		}
+
+		// Force the request to the authoritative region if it has global
+		args.Region = a.srv.config.AuthoritativeRegion
+	}
+
+	if done, err := a.srv.forward("ACL.UpsertTokens", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "upsert_tokens"}, time.Now())
+
+	// Check management level permissions
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj == nil || !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Snapshot the state
+	state, err := a.srv.State().Snapshot()
+	if err != nil {
+		return err
+	}
+
+	// Validate each token
+	for idx, token := range args.Tokens {
+		if err := token.Validate(); err != nil {
+			return fmt.Errorf("token %d invalid: %v", idx, err)
+		}
+
+		// Generate an accessor and secret ID if new
+		if token.AccessorID == "" {
+			token.AccessorID = structs.GenerateUUID()
+			token.SecretID = structs.GenerateUUID()
+			token.CreateTime = time.Now().UTC()
+
+		} else {
+			// Verify the token exists
+			out, err := state.ACLTokenByAccessorID(token.AccessorID)
+			if err != nil {
+				return fmt.Errorf("token lookup failed: %v", err)
+			}
+			if out == nil {
+				return fmt.Errorf("cannot find token %s", token.AccessorID)
+			}
+
+			// Cannot change an existing token's global status
+			if token.Global != out.Global {
+				return fmt.Errorf("cannot change global value of token")
+			}
+
+			// Overwrite the secret ID and create time
+			token.SecretID = out.SecretID
+			token.CreateTime = out.CreateTime
+		}
+
+		// Compute the token hash
+		token.SetHash()
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLTokenUpsertRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Populate the response. We do a lookup against the state to
+	// pickup the proper create / modify times.
+	state, err = a.srv.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	for _, token := range args.Tokens {
+		out, err := state.ACLTokenByAccessorID(token.AccessorID)
+		if err != nil {
+			return fmt.Errorf("token lookup failed: %v", err)
+		}
+		reply.Tokens = append(reply.Tokens, out)
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// DeleteTokens is used to delete tokens
+func (a *ACL) DeleteTokens(args *structs.ACLTokenDeleteRequest, reply *structs.GenericResponse) error {
+	// Ensure ACLs are enabled, and always flow modification requests to the authoritative region
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Validate non-zero set of tokens
+	if len(args.AccessorIDs) == 0 {
+		return fmt.Errorf("must specify at least one token")
+	}
+
+	// Determine if we are deleting local or global tokens
+	state, err := a.srv.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	hasGlobal, hasLocal := false, false
+	for _, accessor := range args.AccessorIDs {
+		token, err := state.ACLTokenByAccessorID(accessor)
+		if err != nil {
+			return fmt.Errorf("token lookup failed: %v", err)
+		}
+		if token == nil {
+			continue
+		}
+		if token.Global {
+			hasGlobal = true
+		} else {
+			hasLocal = true
+		}
+	}
+
+	// Disallow mixed requests with global and non-global tokens since we forward
+	// the entire request as a single batch.
+	if hasGlobal {
+		if hasLocal {
+			return fmt.Errorf("cannot delete mixed global and non-global tokens")
+		}
+
+		// Force the request to the authoritative region if it has global
+		args.Region = a.srv.config.AuthoritativeRegion
+	}
+
+	if done, err := a.srv.forward("ACL.DeleteTokens", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "delete_tokens"}, time.Now())
+
+	// Check management level permissions
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj == nil || !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLTokenDeleteRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// ListTokens is used to list the tokens
+func (a *ACL) ListTokens(args *structs.ACLTokenListRequest, reply *structs.ACLTokenListResponse) error {
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+	if done, err := a.srv.forward("ACL.ListTokens", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "list_tokens"}, time.Now())
+
+	// Check management level permissions
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj == nil || !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "acl_token"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Iterate over all the tokens
+			var err error
+			var iter memdb.ResultIterator
+			if prefix := args.QueryOptions.Prefix; prefix != "" {
+				iter, err = snap.ACLTokenByAccessorIDPrefix(prefix)
+			} else if args.GlobalOnly {
+				iter, err = snap.ACLTokensByGlobal(true)
+			} else {
+				iter, err = snap.ACLTokens()
+			}
+			if err != nil {
+				return err
+			}
+
+			// Convert all the tokens to a list stub
+			reply.Tokens = nil
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				token := raw.(*structs.ACLToken)
+				reply.Tokens = append(reply.Tokens, token.Stub())
+			}
+
+			// Use the last index that affected the token table
+			index, err := snap.Index("acl_token")
+			if err != nil {
+				return err
+			}
+
+			// Ensure we never set the index to zero, otherwise a blocking query cannot be used.
+			// We floor the index at one, since realistically the first write must have a higher index.
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// GetToken is used to get a specific token
+func (a *ACL) GetToken(args *structs.ACLTokenSpecificRequest, reply *structs.SingleACLTokenResponse) error {
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+	if done, err := a.srv.forward("ACL.GetToken", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_token"}, time.Now())
+
+	// Check management level permissions, or that the token is being
+	// looked up by itself
+	aclObj, err := a.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	} else if aclObj == nil {
+		return structs.ErrPermissionDenied
+	}
+	mgt := aclObj.IsManagement()
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "acl_token"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Look for the token
+			out, err := snap.ACLTokenByAccessorID(args.AccessorID)
+			if err != nil {
+				return err
+			}
+
+			// Only management tokens may read other tokens
+			if out != nil && !mgt && out.SecretID != args.AuthToken {
+				return structs.ErrPermissionDenied
+			}
+
+			// Setup the output
+			reply.Token = out
+			if out != nil {
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the token table
+				index, err := snap.Index("acl_token")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// GetTokens is used to get a set of token
+func (a *ACL) GetTokens(args *structs.ACLTokenSetRequest, reply *structs.ACLTokenSetResponse) error {
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+	if done, err := a.srv.forward("ACL.GetTokens", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_tokens"}, time.Now())
+
+	// Check management level permissions
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj == nil || !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "acl_token"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Setup the output
+			reply.Tokens = make(map[string]*structs.ACLToken, len(args.AccessorIDs))
+
+			// Look for the token
+			for _, accessor := range args.AccessorIDs {
+				out, err := snap.ACLTokenByAccessorID(accessor)
+				if err != nil {
+					return err
+				}
+				if out != nil {
+					reply.Tokens[out.AccessorID] = out
+				}
+			}
+
+			// Use the last index that affected the token table
+			index, err := snap.Index("acl_token")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// ResolveToken is used to lookup a specific token by a secret ID. This is used for enforcing ACLs
+// by agents that do not have access to the state.
+func (a *ACL) ResolveToken(args *structs.ResolveACLTokenRequest, reply *structs.ResolveACLTokenResponse) error {
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+	if done, err := a.srv.forward("ACL.ResolveToken", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "resolve_token"}, time.Now())
+
+	// Setup the query meta
+	a.srv.setQueryMeta(&reply.QueryMeta)
+
+	// Snapshot the state
+	state, err := a.srv.State().Snapshot()
+	if err != nil {
+		return err
+	}
+
+	// Look for the token
+	out, err := state.ACLTokenBySecretID(args.SecretID)
+	if err != nil {
+		return err
+	}
+
+	// Setup the output
+	reply.Token = out
+	if out != nil {
+		reply.Index = out.ModifyIndex
+	} else {
+		// Use the last index that affected the token table
+		index, err := state.Index("acl_token")
+		if err != nil {
+			return err
+		}
+		reply.Index = index
+	}
+	return nil
+}
+
+// ReplicationStatus is used to return the status of the replication of the
+// ACL policies and global tokens from the authoritative region. The status is
+// tracked by the leader, so the request is always served by the leader.
+func (a *ACL) ReplicationStatus(args *structs.GenericRequest, reply *structs.ACLReplicationStatusResponse) error {
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+	args.AllowStale = false
+	if done, err := a.srv.forward("ACL.ReplicationStatus", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "replication_status"}, time.Now())
+
+	// Check operator read permissions
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj == nil || !aclObj.AllowOperatorRead() {
+		return structs.ErrPermissionDenied
+	}
+
+	running, progress, _ := a.srv.aclReplication.status()
+	reply.Status = &structs.ACLReplicationStatus{
+		Enabled:  a.srv.config.Region != a.srv.config.AuthoritativeRegion,
+		Running:  running,
+		Policies: progress[aclReplicationPolicies],
+		Tokens:   progress[aclReplicationTokens],
+	}
+	if reply.Status.Enabled {
+		reply.Status.SourceRegion = a.srv.config.AuthoritativeRegion
+	}
+
+	// Use the last index that affected either ACL table
+	index, err := a.srv.fsm.State().Index("acl_policy")
+	if err != nil {
+		return err
+	}
+	if tokenIndex, err := a.srv.fsm.State().Index("acl_token"); err != nil {
+		return err
+	} else if tokenIndex > index {
+		index = tokenIndex
+	}
+	reply.Index = index
+
+	// Set the query response
+	a.srv.setQueryMeta(&reply.QueryMeta)
+	return nil
+}
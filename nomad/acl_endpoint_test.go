@@ -0,0 +1,764 @@
+package nomad
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestACLEndpoint_Disabled(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	req := &structs.ACLPolicyListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.ACLPolicyListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListPolicies", req, &resp); err == nil || err.Error() != aclDisabled.Error() {
+		t.Fatalf("expected ACL disabled error: %v", err)
+	}
+}
+
+func TestACLEndpoint_GetPolicy(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	policy := mock.ACLPolicy()
+	s1.fsm.State().UpsertACLPolicies(1000, []*structs.ACLPolicy{policy})
+
+	// Lookup the policy
+	get := &structs.ACLPolicySpecificRequest{
+		Name: policy.Name,
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.SingleACLPolicyResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetPolicy", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("Bad index: %d", resp.Index)
+	}
+	if resp.Policy == nil || resp.Policy.Name != policy.Name {
+		t.Fatalf("bad: %#v", resp.Policy)
+	}
+
+	// Lookup non-existing policy
+	get.Name = structs.GenerateUUID()
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetPolicy", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Policy != nil {
+		t.Fatalf("bad: %#v", resp.Policy)
+	}
+
+	// Tokens may only read the policies attached to them
+	token := mock.ACLToken()
+	token.Policies = []string{policy.Name}
+	s1.fsm.State().UpsertACLTokens(1001, []*structs.ACLToken{token})
+	get.AuthToken = token.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetPolicy", get, &resp); err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+	get.Name = policy.Name
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetPolicy", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Policy == nil || resp.Policy.Name != policy.Name {
+		t.Fatalf("bad: %#v", resp.Policy)
+	}
+}
+
+func TestACLEndpoint_GetPolicies(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	policy := mock.ACLPolicy()
+	policy2 := mock.ACLPolicy()
+	s1.fsm.State().UpsertACLPolicies(1000, []*structs.ACLPolicy{policy, policy2})
+
+	// Lookup the policy
+	get := &structs.ACLPolicySetRequest{
+		Names: []string{policy.Name, policy2.Name},
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.ACLPolicySetResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetPolicies", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("Bad index: %d", resp.Index)
+	}
+	if len(resp.Policies) != 2 || resp.Policies[policy.Name] == nil || resp.Policies[policy2.Name] == nil {
+		t.Fatalf("bad: %#v", resp.Policies)
+	}
+
+	// Lookup non-existing policy
+	get.Names = []string{structs.GenerateUUID()}
+	resp = structs.ACLPolicySetResponse{}
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetPolicies", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Policies) != 0 {
+		t.Fatalf("bad: %#v", resp.Policies)
+	}
+
+	// Anonymous requests can not fetch policies
+	get.AuthToken = ""
+	get.Names = []string{policy.Name}
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetPolicies", get, &resp); err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+}
+
+func TestACLEndpoint_ListPolicies(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	p1 := mock.ACLPolicy()
+	p2 := mock.ACLPolicy()
+	p1.Name = "aaaaaaaa-3350-4b4b-d185-0e1992ed43e9"
+	p2.Name = "aaaabbbb-3350-4b4b-d185-0e1992ed43e9"
+	s1.fsm.State().UpsertACLPolicies(1000, []*structs.ACLPolicy{p1, p2})
+
+	// Lookup the policies
+	get := &structs.ACLPolicyListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.ACLPolicyListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListPolicies", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("Bad index: %d", resp.Index)
+	}
+	if len(resp.Policies) != 2 {
+		t.Fatalf("bad: %#v", resp.Policies)
+	}
+
+	// Lookup the policies by prefix
+	get.Prefix = "aaaabb"
+	var resp2 structs.ACLPolicyListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListPolicies", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Policies) != 1 || resp2.Policies[0].Name != p2.Name {
+		t.Fatalf("bad: %#v", resp2.Policies)
+	}
+
+	// Client tokens only list the policies attached to them
+	token := mock.ACLToken()
+	token.Policies = []string{p1.Name}
+	s1.fsm.State().UpsertACLTokens(1001, []*structs.ACLToken{token})
+	get.Prefix = ""
+	get.AuthToken = token.SecretID
+	var resp3 structs.ACLPolicyListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListPolicies", get, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp3.Policies) != 1 || resp3.Policies[0].Name != p1.Name {
+		t.Fatalf("bad: %#v", resp3.Policies)
+	}
+}
+
+func TestACLEndpoint_DeletePolicies(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	p1 := mock.ACLPolicy()
+	s1.fsm.State().UpsertACLPolicies(1000, []*structs.ACLPolicy{p1})
+
+	// Lookup the policies
+	req := &structs.ACLPolicyDeleteRequest{
+		Names: []string{p1.Name},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.DeletePolicies", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	// Check we deleted the policy
+	out, err := s1.fsm.State().ACLPolicyByName(p1.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("policy not deleted: %#v", out)
+	}
+}
+
+func TestACLEndpoint_UpsertPolicies(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	p1 := mock.ACLPolicy()
+
+	// Lookup the policies
+	req := &structs.ACLPolicyUpsertRequest{
+		Policies: []*structs.ACLPolicy{p1},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertPolicies", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	// Check we created the policy
+	out, err := s1.fsm.State().ACLPolicyByName(p1.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("policy not created")
+	}
+}
+
+func TestACLEndpoint_UpsertPolicies_Invalid(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	p1 := mock.ACLPolicy()
+	p1.Rules = `namespace "default" { policy = "invalid" }`
+
+	// Lookup the policies
+	req := &structs.ACLPolicyUpsertRequest{
+		Policies: []*structs.ACLPolicy{p1},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertPolicies", req, &resp); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// Client tokens can not create policies
+	token := mock.ACLToken()
+	s1.fsm.State().UpsertACLTokens(1000, []*structs.ACLToken{token})
+	req.Policies = []*structs.ACLPolicy{mock.ACLPolicy()}
+	req.AuthToken = token.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertPolicies", req, &resp); err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+}
+
+func TestACLEndpoint_GetToken(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	token := mock.ACLToken()
+	s1.fsm.State().UpsertACLTokens(1000, []*structs.ACLToken{token})
+
+	// Lookup the token
+	get := &structs.ACLTokenSpecificRequest{
+		AccessorID: token.AccessorID,
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.SingleACLTokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetToken", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("Bad index: %d", resp.Index)
+	}
+	if resp.Token == nil || resp.Token.AccessorID != token.AccessorID {
+		t.Fatalf("bad: %#v", resp.Token)
+	}
+
+	// Lookup non-existing token
+	get.AccessorID = structs.GenerateUUID()
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetToken", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Token != nil {
+		t.Fatalf("bad: %#v", resp.Token)
+	}
+
+	// A client token can look itself up but not other tokens
+	get.AccessorID = token.AccessorID
+	get.AuthToken = token.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetToken", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Token == nil || resp.Token.AccessorID != token.AccessorID {
+		t.Fatalf("bad: %#v", resp.Token)
+	}
+	get.AccessorID = root.AccessorID
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetToken", get, &resp); err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+}
+
+func TestACLEndpoint_GetTokens(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	token := mock.ACLToken()
+	token2 := mock.ACLToken()
+	s1.fsm.State().UpsertACLTokens(1000, []*structs.ACLToken{token, token2})
+
+	// Lookup the token
+	get := &structs.ACLTokenSetRequest{
+		AccessorIDs: []string{token.AccessorID, token2.AccessorID},
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.ACLTokenSetResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetTokens", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("Bad index: %d", resp.Index)
+	}
+	if len(resp.Tokens) != 2 || resp.Tokens[token.AccessorID] == nil || resp.Tokens[token2.AccessorID] == nil {
+		t.Fatalf("bad: %#v", resp.Tokens)
+	}
+
+	// Lookup non-existing token
+	get.AccessorIDs = []string{structs.GenerateUUID()}
+	resp = structs.ACLTokenSetResponse{}
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetTokens", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Tokens) != 0 {
+		t.Fatalf("bad: %#v", resp.Tokens)
+	}
+}
+
+func TestACLEndpoint_ListTokens(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	t1 := mock.ACLToken()
+	t2 := mock.ACLToken()
+	t2.Global = true
+	t1.AccessorID = "aaaaaaaa-3350-4b4b-d185-0e1992ed43e9"
+	t2.AccessorID = "aaaabbbb-3350-4b4b-d185-0e1992ed43e9"
+	s1.fsm.State().UpsertACLTokens(1000, []*structs.ACLToken{t1, t2})
+
+	// Lookup the tokens
+	get := &structs.ACLTokenListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.ACLTokenListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListTokens", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("Bad index: %d", resp.Index)
+	}
+	if len(resp.Tokens) != 3 {
+		t.Fatalf("bad: %#v", resp.Tokens)
+	}
+
+	// Lookup the tokens by prefix
+	get.Prefix = "aaaabb"
+	var resp2 structs.ACLTokenListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListTokens", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Tokens) != 1 || resp2.Tokens[0].AccessorID != t2.AccessorID {
+		t.Fatalf("bad: %#v", resp2.Tokens)
+	}
+
+	// List only the global tokens
+	get.Prefix = ""
+	get.GlobalOnly = true
+	var resp3 structs.ACLTokenListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListTokens", get, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp3.Tokens) != 2 {
+		t.Fatalf("bad: %#v", resp3.Tokens)
+	}
+
+	// Client tokens can not list tokens
+	get.AuthToken = t1.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListTokens", get, &resp3); err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+}
+
+func TestACLEndpoint_DeleteTokens(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	p1 := mock.ACLToken()
+	s1.fsm.State().UpsertACLTokens(1000, []*structs.ACLToken{p1})
+
+	// Lookup the tokens
+	req := &structs.ACLTokenDeleteRequest{
+		AccessorIDs: []string{p1.AccessorID},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.DeleteTokens", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	// Check we deleted the token
+	out, err := s1.fsm.State().ACLTokenByAccessorID(p1.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("token not deleted: %#v", out)
+	}
+}
+
+func TestACLEndpoint_Bootstrap(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.ACLEnabled = true
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Lookup the tokens
+	req := &structs.ACLTokenBootstrapRequest{
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.ACLTokenUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.Bootstrap", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+	if len(resp.Tokens) != 1 {
+		t.Fatalf("bad: %#v", resp.Tokens)
+	}
+	created := resp.Tokens[0]
+	if created.Type != structs.ACLManagementToken || !created.Global || created.SecretID == "" {
+		t.Fatalf("bad: %#v", created)
+	}
+
+	// Check we created the token
+	out, err := s1.fsm.State().ACLTokenByAccessorID(created.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.SecretID != created.SecretID {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// A second bootstrap fails
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.Bootstrap", req, &resp); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestACLEndpoint_Bootstrap_Reset(t *testing.T) {
+	dir := tmpDir(t)
+	s1 := testServer(t, func(c *Config) {
+		c.ACLEnabled = true
+		c.DataDir = dir
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Bootstrap the ACL system
+	req := &structs.ACLTokenBootstrapRequest{
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.ACLTokenUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.Bootstrap", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resetIdx := resp.Tokens[0].CreateIndex
+
+	// Write the reset file and bootstrap again
+	path := filepath.Join(dir, aclBootstrapReset)
+	if err := ioutil.WriteFile(path, []byte(fmt.Sprintf("%d", resetIdx)), 0755); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var resp2 structs.ACLTokenUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.Bootstrap", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Tokens) != 1 || resp2.Tokens[0].AccessorID == resp.Tokens[0].AccessorID {
+		t.Fatalf("bad: %#v", resp2.Tokens)
+	}
+
+	// The reset index is now stale
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.Bootstrap", req, &resp2); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestACLEndpoint_UpsertTokens(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	p1 := mock.ACLToken()
+	p1.AccessorID = "" // Blank to create
+
+	// Lookup the tokens
+	req := &structs.ACLTokenUpsertRequest{
+		Tokens: []*structs.ACLToken{p1},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.ACLTokenUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertTokens", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	// Get the token out from the response
+	created := resp.Tokens[0]
+	if created.AccessorID == "" || created.SecretID == "" || created.Name != p1.Name {
+		t.Fatalf("bad: %#v", created)
+	}
+
+	// Check we created the token
+	out, err := s1.fsm.State().ACLTokenByAccessorID(created.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.SecretID != created.SecretID {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Update the token type
+	update := created.Copy()
+	update.Type = "management"
+	update.Policies = nil
+	req.Tokens = []*structs.ACLToken{update}
+
+	// Upsert again
+	var resp2 structs.ACLTokenUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertTokens", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Check we modified the token
+	out, err = s1.fsm.State().ACLTokenByAccessorID(created.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Type != structs.ACLManagementToken || out.SecretID != created.SecretID {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestACLEndpoint_UpsertTokens_Invalid(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	p1 := mock.ACLToken()
+	p1.Type = "blah blah"
+
+	// Lookup the tokens
+	req := &structs.ACLTokenUpsertRequest{
+		Tokens: []*structs.ACLToken{p1},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertTokens", req, &resp); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// Mixing global and local tokens is rejected
+	p2 := mock.ACLToken()
+	p2.AccessorID = ""
+	p3 := mock.ACLToken()
+	p3.AccessorID = ""
+	p3.Global = true
+	req.Tokens = []*structs.ACLToken{p2, p3}
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertTokens", req, &resp); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestACLEndpoint_ResolveToken(t *testing.T) {
+	s1, _ := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	token := mock.ACLToken()
+	s1.fsm.State().UpsertACLTokens(1000, []*structs.ACLToken{token})
+
+	// Lookup the token
+	get := &structs.ResolveACLTokenRequest{
+		SecretID:     token.SecretID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.ResolveACLTokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ResolveToken", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("Bad index: %d", resp.Index)
+	}
+	if resp.Token == nil || resp.Token.AccessorID != token.AccessorID {
+		t.Fatalf("bad: %#v", resp.Token)
+	}
+
+	// Lookup non-existing token
+	get.SecretID = structs.GenerateUUID()
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ResolveToken", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Token != nil {
+		t.Fatalf("bad: %#v", resp.Token)
+	}
+}
+
+func TestACLEndpoint_ReplicationStatus(t *testing.T) {
+	s1, root := testACLServer(t, func(c *Config) {
+		c.Region = "region1"
+		c.AuthoritativeRegion = "region1"
+	})
+	defer s1.Shutdown()
+	s2, _ := testACLServer(t, func(c *Config) {
+		c.Region = "region2"
+		c.AuthoritativeRegion = "region1"
+		c.ReplicationBackoff = 20 * time.Millisecond
+		c.ReplicationToken = root.SecretID
+	})
+	defer s2.Shutdown()
+	testJoin(t, s1, s2)
+	testutil.WaitForLeader(t, s1.RPC)
+	testutil.WaitForLeader(t, s2.RPC)
+	codec := rpcClient(t, s1)
+
+	// Write a policy to the authoritative region
+	p1 := mock.ACLPolicy()
+	if err := s1.State().UpsertACLPolicies(1000, []*structs.ACLPolicy{p1}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Anonymous requests are denied
+	req := &structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{Region: "region2"},
+	}
+	var resp structs.ACLReplicationStatusResponse
+	err := msgpackrpc.CallWithCodec(codec, "ACL.ReplicationStatus", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// The status of the second region is forwarded to its leader, which
+	// replicates the policy
+	req.AuthToken = root.SecretID
+	testutil.WaitForResult(func() (bool, error) {
+		if err := msgpackrpc.CallWithCodec(codec, "ACL.ReplicationStatus", req, &resp); err != nil {
+			return false, err
+		}
+		status := resp.Status
+		if !status.Enabled || !status.Running || status.SourceRegion != "region1" {
+			return false, fmt.Errorf("bad: %#v", status)
+		}
+		if status.Policies.ReplicatedIndex < 1000 || status.Policies.LastSuccess == 0 {
+			return false, fmt.Errorf("policies not replicated: %#v", status.Policies)
+		}
+		if status.Policies.Lag != 0 || status.Tokens.LastSuccess == 0 {
+			return false, fmt.Errorf("bad: %#v %#v", status.Policies, status.Tokens)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// The authoritative region doesn't replicate
+	req.Region = "region1"
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ReplicationStatus", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Status.Enabled || resp.Status.Running || resp.Status.Policies != nil {
+		t.Fatalf("bad: %#v", resp.Status)
+	}
+}
@@ -0,0 +1,126 @@
+package nomad
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestResolveACLToken(t *testing.T) {
+	// Create mock state store and cache
+	state, err := state.NewStateStore(os.Stderr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	cache, err := newACLCache(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a policy / token
+	policy := mock.ACLPolicy()
+	policy2 := mock.ACLPolicy()
+	token := mock.ACLToken()
+	token.Policies = []string{policy.Name, policy2.Name}
+	token2 := mock.ACLToken()
+	token2.Type = structs.ACLManagementToken
+	token2.Policies = nil
+	if err := state.UpsertACLPolicies(100, []*structs.ACLPolicy{policy, policy2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertACLTokens(110, []*structs.ACLToken{token, token2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	snap, err := state.Snapshot()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Attempt resolution of blank token. Should return anonymous policy
+	aclObj, err := resolveTokenFromSnapshotCache(snap, cache, "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if aclObj == nil {
+		t.Fatalf("missing anonymous ACL")
+	}
+
+	// Attempt resolution of unknown token. Should fail.
+	randID := structs.GenerateUUID()
+	if _, err := resolveTokenFromSnapshotCache(snap, cache, randID); err != structs.ErrTokenNotFound {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Attempt resolution of management token. Should get singleton.
+	aclObj, err = resolveTokenFromSnapshotCache(snap, cache, token2.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if aclObj != acl.ManagementACL {
+		t.Fatalf("expected management ACL: %#v", aclObj)
+	}
+
+	// Attempt resolution of client token
+	aclObj, err = resolveTokenFromSnapshotCache(snap, cache, token.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if aclObj == nil || aclObj.IsManagement() {
+		t.Fatalf("bad: %#v", aclObj)
+	}
+	if !aclObj.AllowNamespaceOperation("default", acl.NamespaceCapabilitySubmitJob) {
+		t.Fatalf("expected submit-job in default namespace")
+	}
+	if aclObj.AllowNodeWrite() {
+		t.Fatalf("unexpected node write")
+	}
+
+	// Check that the ACL object is cached
+	aclObj2, err := resolveTokenFromSnapshotCache(snap, cache, token.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if aclObj != aclObj2 {
+		t.Fatalf("expected cached ACL object: %p %p", aclObj, aclObj2)
+	}
+
+	// Update the policies, which should invalidate the cache
+	updated := &structs.ACLPolicy{
+		Name:  policy.Name,
+		Rules: `namespace "default" { policy = "read" }`,
+	}
+	updated.SetHash()
+	if err := state.UpsertACLPolicies(120, []*structs.ACLPolicy{updated}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	snap, err = state.Snapshot()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	aclObj3, err := resolveTokenFromSnapshotCache(snap, cache, token.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if aclObj3 == aclObj {
+		t.Fatalf("expected a new ACL object")
+	}
+}
+
+func TestServer_ResolveToken_Disabled(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+
+	// ACLs are disabled so no ACL object is returned for any token
+	aclObj, err := s1.ResolveToken(structs.GenerateUUID())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if aclObj != nil {
+		t.Fatalf("unexpected ACL: %#v", aclObj)
+	}
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/state"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/nomad/watch"
 )
@@ -22,37 +24,70 @@ func (a *Alloc) List(args *structs.AllocListRequest, reply *structs.AllocListRes
 	}
 	defer metrics.MeasureSince([]string{"nomad", "alloc", "list"}, time.Now())
 
+	// Check namespace read-job permissions. Queries across all namespaces
+	// are filtered to the namespaces the token may read instead.
+	aclObj, err := a.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	namespace := args.RequestNamespace()
+	allNamespaces := namespace == structs.AllNamespacesSentinel
+	if !allNamespaces && aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityReadJob) {
+		return structs.ErrPermissionDenied
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		watch:     watch.NewItems(watch.Item{Table: "allocs"}),
-		run: func() error {
+		run: func(snap *state.StateSnapshot) error {
 			// Capture all the allocations
-			snap, err := a.srv.fsm.State().Snapshot()
-			if err != nil {
-				return err
+			var err error
+			var allowed map[string]bool
+			if allNamespaces {
+				allowed, err = allowedNamespaces(aclObj, snap, acl.NamespaceCapabilityReadJob)
+				if err != nil {
+					return err
+				}
 			}
+
 			var iter memdb.ResultIterator
 			if prefix := args.QueryOptions.Prefix; prefix != "" {
 				iter, err = snap.AllocsByIDPrefix(prefix)
-			} else {
+			} else if allNamespaces {
 				iter, err = snap.Allocs()
+			} else {
+				iter, err = snap.AllocsByNamespace(namespace)
+			}
+			if err != nil {
+				return err
 			}
+
+			pager, err := newPaginator(&args.QueryOptions)
 			if err != nil {
 				return err
 			}
 
 			var allocs []*structs.AllocListStub
-			for {
+			for !pager.done() {
 				raw := iter.Next()
 				if raw == nil {
 					break
 				}
 				alloc := raw.(*structs.Allocation)
-				allocs = append(allocs, alloc.Stub())
+				if !namespaceMatches(alloc.Namespace, namespace, allowed) {
+					continue
+				}
+				stub := alloc.Stub()
+				if ok, err := pager.accept(alloc.ID, stub); err != nil {
+					return err
+				} else if ok {
+					allocs = append(allocs, stub)
+				}
 			}
 			reply.Allocations = allocs
+			reply.NextToken = pager.nextToken
 
 			// Use the last index that affected the jobs table
 			index, err := snap.Index("allocs")
@@ -76,22 +111,29 @@ func (a *Alloc) GetAlloc(args *structs.AllocSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "alloc", "get_alloc"}, time.Now())
 
+	// Resolve the token, the namespace of the allocation is checked once it is found
+	aclObj, err := a.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		watch:     watch.NewItems(watch.Item{Alloc: args.AllocID}),
-		run: func() error {
+		run: func(snap *state.StateSnapshot) error {
 			// Lookup the allocation
-			snap, err := a.srv.fsm.State().Snapshot()
-			if err != nil {
-				return err
-			}
 			out, err := snap.AllocByID(args.AllocID)
 			if err != nil {
 				return err
 			}
 
+			// Check namespace read-job permissions
+			if out != nil && aclObj != nil && !aclObj.AllowNamespaceOperation(out.Namespace, acl.NamespaceCapabilityReadJob) {
+				return structs.ErrPermissionDenied
+			}
+
 			// Setup the output
 			reply.Alloc = out
 			if out != nil {
@@ -120,30 +162,39 @@ func (a *Alloc) GetAllocs(args *structs.AllocsGetRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "alloc", "get_alloc"}, time.Now())
 
-	// Lookup the allocations
-	snap, err := a.srv.fsm.State().Snapshot()
-	if err != nil {
-		return err
+	// Watch each of the allocations
+	items := watch.NewItems()
+	for _, alloc := range args.AllocIDs {
+		items.Add(watch.Item{Alloc: alloc})
 	}
 
-	allocs := make([]*structs.Allocation, len(args.AllocIDs))
-	for i, alloc := range args.AllocIDs {
-		out, err := snap.AllocByID(alloc)
-		if err != nil {
-			return err
-		}
-		if out == nil {
-			return fmt.Errorf("unknown alloc id %q", alloc)
-		}
-
-		allocs[i] = out
-		if reply.Index < out.ModifyIndex {
-			reply.Index = out.ModifyIndex
-		}
-	}
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     items,
+		run: func(snap *state.StateSnapshot) error {
+			// Lookup the allocations
+			allocs := make([]*structs.Allocation, len(args.AllocIDs))
+			for i, alloc := range args.AllocIDs {
+				out, err := snap.AllocByID(alloc)
+				if err != nil {
+					return err
+				}
+				if out == nil {
+					return fmt.Errorf("unknown alloc id %q", alloc)
+				}
 
-	// Set the response
-	a.srv.setQueryMeta(&reply.QueryMeta)
-	reply.Allocs = allocs
-	return nil
+				allocs[i] = out
+				if reply.Index < out.ModifyIndex {
+					reply.Index = out.ModifyIndex
+				}
+			}
+
+			// Set the response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			reply.Allocs = allocs
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
 }
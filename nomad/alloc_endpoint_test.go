@@ -69,6 +69,118 @@ func TestAllocEndpoint_List(t *testing.T) {
 	}
 }
 
+func TestAllocEndpoint_List_Paginate(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the allocations, one of which is complete
+	state := s1.fsm.State()
+	var allocs []*structs.Allocation
+	ids := []string{
+		"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
+		"bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb",
+		"cccccccc-cccc-cccc-cccc-cccccccccccc",
+	}
+	for _, id := range ids {
+		alloc := mock.Alloc()
+		alloc.ID = id
+		alloc.DesiredStatus = structs.AllocDesiredStatusRun
+		alloc.ClientStatus = structs.AllocClientStatusRunning
+		if id == ids[0] {
+			alloc.ClientStatus = structs.AllocClientStatusComplete
+		}
+		allocs = append(allocs, alloc)
+	}
+	if err := state.UpsertJobSummary(999, mock.JobSummary(allocs[0].JobID)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertAllocs(1000, allocs); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Lookup the first page of the running allocations
+	get := &structs.AllocListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:  "global",
+			PerPage: 1,
+			Filter:  `DesiredStatus == "run" and ClientStatus != "complete"`,
+		},
+	}
+	var resp structs.AllocListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Alloc.List", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Allocations) != 1 || resp.Allocations[0].ID != ids[1] {
+		t.Fatalf("bad: %#v", resp.Allocations)
+	}
+	if resp.NextToken != ids[2] {
+		t.Fatalf("bad: %q", resp.NextToken)
+	}
+
+	// Lookup the last page
+	get.NextToken = resp.NextToken
+	var resp2 structs.AllocListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Alloc.List", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Allocations) != 1 || resp2.Allocations[0].ID != ids[2] {
+		t.Fatalf("bad: %#v", resp2.Allocations)
+	}
+	if resp2.NextToken != "" {
+		t.Fatalf("bad: %q", resp2.NextToken)
+	}
+}
+
+func TestAllocEndpoint_List_AllNamespaces(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create allocations in two namespaces
+	alloc1 := mock.Alloc()
+	alloc2 := mock.Alloc()
+	alloc2.Namespace = "other"
+	state := s1.fsm.State()
+	state.UpsertJobSummary(998, mock.JobSummary(alloc1.JobID))
+	state.UpsertJobSummary(999, mock.JobSummary(alloc2.JobID))
+	if err := state.UpsertAllocs(1000, []*structs.Allocation{alloc1, alloc2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Lookup the allocations of every namespace
+	get := &structs.AllocListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			Namespace: structs.AllNamespacesSentinel,
+		},
+	}
+	var resp structs.AllocListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Alloc.List", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Allocations) != 2 {
+		t.Fatalf("bad: %#v", resp.Allocations)
+	}
+	for _, stub := range resp.Allocations {
+		if stub.ID == alloc2.ID && stub.Namespace != "other" {
+			t.Fatalf("bad namespace: %#v", stub)
+		}
+	}
+
+	// Lookup by prefix across namespaces
+	get.Prefix = alloc2.ID[:8]
+	var resp2 structs.AllocListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Alloc.List", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Allocations) != 1 || resp2.Allocations[0].ID != alloc2.ID {
+		t.Fatalf("bad: %#v", resp2.Allocations)
+	}
+}
+
 func TestAllocEndpoint_List_Blocking(t *testing.T) {
 	s1 := testServer(t, nil)
 	defer s1.Shutdown()
@@ -272,3 +384,127 @@ func TestAllocEndpoint_GetAllocs(t *testing.T) {
 		t.Fatalf("expect error")
 	}
 }
+
+func TestAllocEndpoint_GetAllocs_Blocking(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the allocs
+	alloc := mock.Alloc()
+	alloc2 := mock.Alloc()
+	state := s1.fsm.State()
+	state.UpsertJobSummary(98, mock.JobSummary(alloc.JobID))
+	state.UpsertJobSummary(99, mock.JobSummary(alloc2.JobID))
+	if err := state.UpsertAllocs(100, []*structs.Allocation{alloc, alloc2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Update an unrelated alloc
+	time.AfterFunc(100*time.Millisecond, func() {
+		update := alloc2.Copy()
+		update.ClientStatus = structs.AllocClientStatusRunning
+		if err := state.UpdateAllocsFromClient(200, []*structs.Allocation{update}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	// Update the alloc we are interested in later
+	time.AfterFunc(200*time.Millisecond, func() {
+		update := alloc.Copy()
+		update.ClientStatus = structs.AllocClientStatusRunning
+		if err := state.UpdateAllocsFromClient(300, []*structs.Allocation{update}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	// Lookup the alloc
+	get := &structs.AllocsGetRequest{
+		AllocIDs: []string{alloc.ID},
+		QueryOptions: structs.QueryOptions{
+			Region:        "global",
+			MinQueryIndex: 150,
+		},
+	}
+	var resp structs.AllocsGetResponse
+	start := time.Now()
+	if err := msgpackrpc.CallWithCodec(codec, "Alloc.GetAllocs", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("should block (returned in %s) %#v", elapsed, resp)
+	}
+	if resp.Index != 300 {
+		t.Fatalf("Bad index: %d %d", resp.Index, 300)
+	}
+	if len(resp.Allocs) != 1 || resp.Allocs[0].ClientStatus != structs.AllocClientStatusRunning {
+		t.Fatalf("bad: %#v", resp.Allocs)
+	}
+}
+
+func TestAllocEndpoint_GetAllocs_Blocking_Bounded(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create more allocs than a query watches individually
+	state := s1.fsm.State()
+	var allocs []*structs.Allocation
+	var ids []string
+	for i := 0; i < maxWatchItems+1; i++ {
+		alloc := mock.Alloc()
+		state.UpsertJobSummary(uint64(i+1), mock.JobSummary(alloc.JobID))
+		allocs = append(allocs, alloc)
+		ids = append(ids, alloc.ID)
+	}
+	unrelated := mock.Alloc()
+	state.UpsertJobSummary(99, mock.JobSummary(unrelated.JobID))
+	if err := state.UpsertAllocs(100, append(allocs, unrelated)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Update an unrelated alloc, which wakes the query watching the table
+	time.AfterFunc(100*time.Millisecond, func() {
+		update := unrelated.Copy()
+		update.ClientStatus = structs.AllocClientStatusRunning
+		if err := state.UpsertAllocs(200, []*structs.Allocation{update}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	// Update one of the allocs we are interested in later
+	time.AfterFunc(200*time.Millisecond, func() {
+		update := allocs[maxWatchItems].Copy()
+		update.ClientStatus = structs.AllocClientStatusRunning
+		if err := state.UpsertAllocs(300, []*structs.Allocation{update}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	// Lookup the allocs
+	get := &structs.AllocsGetRequest{
+		AllocIDs: ids,
+		QueryOptions: structs.QueryOptions{
+			Region:        "global",
+			MinQueryIndex: 150,
+		},
+	}
+	var resp structs.AllocsGetResponse
+	start := time.Now()
+	if err := msgpackrpc.CallWithCodec(codec, "Alloc.GetAllocs", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("should block (returned in %s) %#v", elapsed, resp)
+	}
+	if resp.Index != 300 {
+		t.Fatalf("Bad index: %d %d", resp.Index, 300)
+	}
+	if len(resp.Allocs) != maxWatchItems+1 {
+		t.Fatalf("bad: %#v", resp.Allocs)
+	}
+}
@@ -0,0 +1,311 @@
+package audit
+
+// The audit package records the requests made to the HTTP API of the agents
+// and the RPC endpoints of the servers, so that regulated environments can
+// tell who did what and when. Each request is recorded once when it is
+// received and once when it completes, as a JSON object written to the
+// configured sinks.
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// EventVersion is the version of the format of the audit events
+	EventVersion = 1
+
+	// HTTPEvent and RPCEvent are the types of the audit events of requests
+	// to the HTTP API and the RPC endpoints
+	HTTPEvent = "HTTPEvent"
+	RPCEvent  = "RPCEvent"
+
+	// StageOperationReceived and StageOperationComplete are the stages of a
+	// request an event is recorded at
+	StageOperationReceived = "OperationReceived"
+	StageOperationComplete = "OperationComplete"
+
+	// SinkTypeFile writes the events to a file
+	SinkTypeFile = "file"
+
+	// SinkFormatJSON writes one JSON object per line
+	SinkFormatJSON = "json"
+
+	// DeliveryEnforced fails the request when its event can't be written,
+	// while DeliveryBestEffort only logs the failure
+	DeliveryEnforced   = "enforced"
+	DeliveryBestEffort = "best-effort"
+
+	// matchAll matches every type, endpoint, stage or operation in a filter
+	matchAll = "*"
+)
+
+// Event is the record of a stage of a request
+type Event struct {
+	ID        string
+	Type      string
+	Stage     string
+	Timestamp time.Time
+	Version   int
+	Auth      *Auth `json:",omitempty"`
+	Request   *Request
+	Response  *Response `json:",omitempty"`
+}
+
+// Auth is the ACL token a request was made with. It is only set when ACLs
+// are enabled, and never contains the secret of the token.
+type Auth struct {
+	AccessorID string
+	Name       string
+	Type       string
+	Policies   []string
+	Global     bool
+}
+
+// Request describes the request an event is about. The body of the request
+// isn't recorded since it may contain secrets.
+type Request struct {
+	// ID is shared by the events of the stages of the request
+	ID string
+
+	// Operation is the HTTP method, or "read" or "write" for RPCs
+	Operation string
+
+	// Endpoint is the path of the HTTP request or the RPC method
+	Endpoint string
+
+	Region     string `json:",omitempty"`
+	Namespace  string `json:",omitempty"`
+	RemoteAddr string `json:",omitempty"`
+	UserAgent  string `json:",omitempty"`
+
+	// Params are the query parameters of the HTTP request
+	Params map[string][]string `json:",omitempty"`
+}
+
+// Response is the outcome of a request, set once the request completes
+type Response struct {
+	// StatusCode is the HTTP status code of the response. RPCs that fail
+	// have a status code of 500.
+	StatusCode int
+	Error      string `json:",omitempty"`
+}
+
+// TokenResolver resolves the secret ID of the ACL token of a request
+type TokenResolver func(secretID string) (*structs.ACLToken, error)
+
+// NewAuth returns the auth of an event for the secret ID of a token. The
+// anonymous token is used without a secret ID, and nil is returned if the
+// token can't be resolved.
+func NewAuth(secretID string, resolver TokenResolver) *Auth {
+	if resolver == nil {
+		return nil
+	}
+	token := structs.AnonymousACLToken
+	if secretID != "" {
+		var err error
+		if token, err = resolver(secretID); err != nil || token == nil {
+			return nil
+		}
+	}
+	return &Auth{
+		AccessorID: token.AccessorID,
+		Name:       token.Name,
+		Type:       token.Type,
+		Policies:   token.Policies,
+		Global:     token.Global,
+	}
+}
+
+// Config is the configuration of the auditor
+type Config struct {
+	// Sinks are where the events are written to
+	Sinks []*SinkConfig
+
+	// Filters exclude the events they match from every sink
+	Filters []*FilterConfig
+
+	// Logger logs the events that can't be written
+	Logger *log.Logger
+}
+
+// SinkConfig is the configuration of a sink of the events
+type SinkConfig struct {
+	Name string
+
+	// Type is the type of the sink, only "file" is supported
+	Type string
+
+	// Format is the format of the events, only "json" is supported
+	Format string
+
+	// Path is the path of the file the events are written to
+	Path string
+
+	// DeliveryGuarantee is whether a request fails when its event can't
+	// be written to the sink, either "enforced" or "best-effort"
+	DeliveryGuarantee string
+
+	// RotateBytes and RotateDuration are the size and age the file is
+	// rotated at, and RotateMaxFiles is how many rotated files are kept.
+	// Zero values disable rotation or pruning.
+	RotateBytes    int64
+	RotateDuration time.Duration
+	RotateMaxFiles int
+}
+
+// FilterConfig is the configuration of a filter excluding events. An event
+// is excluded if its type, endpoint, stage and operation all match the
+// filter. Endpoints may end with "*" to match a prefix, and "*" or an empty
+// list match everything.
+type FilterConfig struct {
+	Name       string
+	Type       string
+	Endpoints  []string
+	Stages     []string
+	Operations []string
+}
+
+// matches returns whether the filter excludes the event
+func (f *FilterConfig) matches(e *Event) bool {
+	if f.Type != matchAll && f.Type != e.Type {
+		return false
+	}
+	return matchAny(f.Endpoints, e.Request.Endpoint) &&
+		matchAny(f.Stages, e.Stage) &&
+		matchAny(f.Operations, e.Request.Operation)
+}
+
+// matchAny returns whether any of the patterns match the value
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		switch {
+		case p == matchAll, strings.EqualFold(p, value):
+			return true
+		case strings.HasSuffix(p, matchAll) && strings.HasPrefix(value, strings.TrimSuffix(p, matchAll)):
+			return true
+		}
+	}
+	return false
+}
+
+// Auditor writes the audit events of requests to the configured sinks
+type Auditor struct {
+	sinks   []*sink
+	filters []*FilterConfig
+	logger  *log.Logger
+}
+
+// sink is a configured sink of the events
+type sink struct {
+	name     string
+	enforced bool
+	file     *fileSink
+}
+
+// New returns an auditor writing to the sinks of the configuration
+func New(c *Config) (*Auditor, error) {
+	var mErr multierror.Error
+	for _, f := range c.Filters {
+		switch f.Type {
+		case HTTPEvent, RPCEvent, matchAll:
+		default:
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("filter %q: invalid type %q", f.Name, f.Type))
+		}
+		for _, stage := range f.Stages {
+			switch stage {
+			case StageOperationReceived, StageOperationComplete, matchAll:
+			default:
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("filter %q: invalid stage %q", f.Name, stage))
+			}
+		}
+	}
+	if len(c.Sinks) == 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("at least one sink is required"))
+	}
+	for _, s := range c.Sinks {
+		if s.Type != SinkTypeFile {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("sink %q: invalid type %q", s.Name, s.Type))
+		}
+		if s.Format != SinkFormatJSON {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("sink %q: invalid format %q", s.Name, s.Format))
+		}
+		switch s.DeliveryGuarantee {
+		case DeliveryEnforced, DeliveryBestEffort:
+		default:
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("sink %q: invalid delivery guarantee %q", s.Name, s.DeliveryGuarantee))
+		}
+		if s.Path == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("sink %q: missing path", s.Name))
+		}
+	}
+	if err := mErr.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+
+	a := &Auditor{
+		filters: c.Filters,
+		logger:  c.Logger,
+	}
+	for _, s := range c.Sinks {
+		file, err := newFileSink(s.Path, s.RotateBytes, s.RotateDuration, s.RotateMaxFiles)
+		if err != nil {
+			a.Close()
+			return nil, fmt.Errorf("sink %q: %v", s.Name, err)
+		}
+		a.sinks = append(a.sinks, &sink{
+			name:     s.Name,
+			enforced: s.DeliveryGuarantee == DeliveryEnforced,
+			file:     file,
+		})
+	}
+	return a, nil
+}
+
+// Event writes an event to the sinks unless a filter excludes it. An error
+// is returned if the event couldn't be written to a sink enforcing its
+// delivery, in which case the request must fail.
+func (a *Auditor) Event(e *Event) error {
+	for _, f := range a.filters {
+		if f.matches(e) {
+			return nil
+		}
+	}
+	if e.ID == "" {
+		e.ID = structs.GenerateUUID()
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	e.Version = EventVersion
+
+	var mErr multierror.Error
+	for _, s := range a.sinks {
+		if err := s.file.Write(e); err != nil {
+			a.logger.Printf("[ERR] audit: failed to write event %s to sink %q: %v", e.ID, s.name, err)
+			if s.enforced {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("audit sink %q: %v", s.name, err))
+			}
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// Close closes the sinks
+func (a *Auditor) Close() error {
+	var mErr multierror.Error
+	for _, s := range a.sinks {
+		if err := s.file.Close(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+	return mErr.ErrorOrNil()
+}
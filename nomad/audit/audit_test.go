@@ -0,0 +1,160 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testAuditor(t *testing.T, dir string, filters ...*FilterConfig) *Auditor {
+	a, err := New(&Config{
+		Sinks: []*SinkConfig{
+			{
+				Name:              "file",
+				Type:              SinkTypeFile,
+				Format:            SinkFormatJSON,
+				Path:              filepath.Join(dir, "audit.log"),
+				DeliveryGuarantee: DeliveryEnforced,
+			},
+		},
+		Filters: filters,
+		Logger:  log.New(os.Stderr, "", log.LstdFlags),
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return a
+}
+
+func readEvents(t *testing.T, path string) []*Event {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	var events []*Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		events = append(events, &event)
+	}
+	return events
+}
+
+func TestAuditor_New_Invalid(t *testing.T) {
+	_, err := New(&Config{
+		Sinks: []*SinkConfig{
+			{Name: "bad", Type: "syslog", Format: "xml", DeliveryGuarantee: "maybe"},
+		},
+		Filters: []*FilterConfig{
+			{Name: "bad", Type: "Event", Stages: []string{"Done"}},
+		},
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	for _, expected := range []string{"invalid type \"syslog\"", "invalid format", "invalid delivery guarantee",
+		"missing path", "invalid type \"Event\"", "invalid stage"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q in %v", expected, err)
+		}
+	}
+
+	if _, err := New(&Config{}); err == nil {
+		t.Fatalf("expected error without sinks")
+	}
+}
+
+func TestAuditor_Event(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := testAuditor(t, dir, &FilterConfig{
+		Name:       "health",
+		Type:       HTTPEvent,
+		Endpoints:  []string{"/v1/agent/*"},
+		Stages:     []string{"*"},
+		Operations: []string{"get"},
+	})
+	defer a.Close()
+
+	events := []*Event{
+		// Filtered
+		{Type: HTTPEvent, Stage: StageOperationReceived, Request: &Request{Operation: "GET", Endpoint: "/v1/agent/health"}},
+		// Not filtered, since the operation or type differ
+		{Type: HTTPEvent, Stage: StageOperationReceived, Request: &Request{Operation: "PUT", Endpoint: "/v1/agent/join"}},
+		{Type: RPCEvent, Stage: StageOperationReceived, Request: &Request{Operation: "GET", Endpoint: "/v1/agent/health"}},
+	}
+	for _, e := range events {
+		if err := a.Event(e); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	out := readEvents(t, filepath.Join(dir, "audit.log"))
+	if len(out) != 2 {
+		t.Fatalf("bad: %#v", out)
+	}
+	for _, e := range out {
+		if e.ID == "" || e.Timestamp.IsZero() || e.Version != EventVersion {
+			t.Fatalf("bad: %#v", e)
+		}
+	}
+	if out[0].Request.Endpoint != "/v1/agent/join" || out[1].Type != RPCEvent {
+		t.Fatalf("bad: %#v %#v", out[0], out[1])
+	}
+}
+
+func TestAuditor_DeliveryGuarantee(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, err := New(&Config{
+		Sinks: []*SinkConfig{
+			{
+				Name:              "enforced",
+				Type:              SinkTypeFile,
+				Format:            SinkFormatJSON,
+				Path:              filepath.Join(dir, "enforced.log"),
+				DeliveryGuarantee: DeliveryEnforced,
+			},
+			{
+				Name:              "best-effort",
+				Type:              SinkTypeFile,
+				Format:            SinkFormatJSON,
+				Path:              filepath.Join(dir, "best-effort.log"),
+				DeliveryGuarantee: DeliveryBestEffort,
+			},
+		},
+		Logger: log.New(os.Stderr, "", log.LstdFlags),
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	event := &Event{Type: HTTPEvent, Stage: StageOperationReceived, Request: &Request{}}
+
+	// Only failing to write to the enforced sink fails the event
+	a.sinks[1].file.Close()
+	if err := a.Event(event); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	a.sinks[0].file.Close()
+	if err := a.Event(event); err == nil || !strings.Contains(err.Error(), "enforced") {
+		t.Fatalf("expected error: %v", err)
+	}
+}
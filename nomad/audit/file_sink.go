@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileSink writes events as JSON lines to a file, rotating it once it grows
+// past a size or an age. Rotated files are renamed with the time they were
+// rotated at, and the oldest ones are removed past the maximum count.
+type fileSink struct {
+	path           string
+	rotateBytes    int64
+	rotateDuration time.Duration
+	rotateMaxFiles int
+
+	f       *os.File
+	size    int64
+	created time.Time
+	l       sync.Mutex
+}
+
+// newFileSink opens the file of a sink, appending to it if it exists
+func newFileSink(path string, rotateBytes int64, rotateDuration time.Duration,
+	rotateMaxFiles int) (*fileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+	s := &fileSink{
+		path:           path,
+		rotateBytes:    rotateBytes,
+		rotateDuration: rotateDuration,
+		rotateMaxFiles: rotateMaxFiles,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// open opens the file of the sink. The lock must be held.
+func (s *fileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %v", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat file: %v", err)
+	}
+	s.f = f
+	s.size = fi.Size()
+	s.created = time.Now()
+	return nil
+}
+
+// Write writes an event to the file, rotating it first if needed
+func (s *fileSink) Write(e *Event) error {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %v", err)
+	}
+	buf = append(buf, '\n')
+
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.f == nil {
+		return fmt.Errorf("sink closed")
+	}
+	if s.shouldRotate(int64(len(buf))) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.f.Write(buf)
+	s.size += int64(n)
+	return err
+}
+
+// shouldRotate returns whether the file must be rotated before writing the
+// given number of bytes. The lock must be held.
+func (s *fileSink) shouldRotate(n int64) bool {
+	if s.size == 0 {
+		return false
+	}
+	if s.rotateBytes > 0 && s.size+n > s.rotateBytes {
+		return true
+	}
+	return s.rotateDuration > 0 && time.Since(s.created) >= s.rotateDuration
+}
+
+// rotate renames the file, opens a new one and removes the oldest rotated
+// files. The lock must be held.
+func (s *fileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %v", err)
+	}
+	s.f = nil
+
+	ext := filepath.Ext(s.path)
+	base := strings.TrimSuffix(s.path, ext)
+	rotated := fmt.Sprintf("%s-%d%s", base, time.Now().UnixNano(), ext)
+	if err := os.Rename(s.path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate file: %v", err)
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	if s.rotateMaxFiles <= 0 {
+		return nil
+	}
+	matches, err := filepath.Glob(base + "-*" + ext)
+	if err != nil {
+		return fmt.Errorf("failed to list rotated files: %v", err)
+	}
+	sort.Strings(matches)
+	for len(matches) > s.rotateMaxFiles {
+		if err := os.Remove(matches[0]); err != nil {
+			return fmt.Errorf("failed to remove rotated file: %v", err)
+		}
+		matches = matches[1:]
+	}
+	return nil
+}
+
+// Close closes the file of the sink
+func (s *fileSink) Close() error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileSink_RotateBytes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Rotate on every event, keeping two rotated files
+	path := filepath.Join(dir, "audit.log")
+	s, err := newFileSink(path, 1, 0, 2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := s.Write(&Event{ID: "foo", Request: &Request{}}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	rotated, err := filepath.Glob(filepath.Join(dir, "audit-*.log"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(rotated) != 2 {
+		t.Fatalf("bad: %v", rotated)
+	}
+	if events := readEvents(t, path); len(events) != 1 {
+		t.Fatalf("bad: %#v", events)
+	}
+}
+
+func TestFileSink_RotateDuration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	s, err := newFileSink(path, 0, 10*time.Millisecond, 0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer s.Close()
+
+	// The file isn't rotated until it is old enough
+	for i := 0; i < 2; i++ {
+		if err := s.Write(&Event{ID: "foo", Request: &Request{}}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if events := readEvents(t, path); len(events) != 2 {
+		t.Fatalf("bad: %#v", events)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if err := s.Write(&Event{ID: "foo", Request: &Request{}}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if events := readEvents(t, path); len(events) != 1 {
+		t.Fatalf("bad: %#v", events)
+	}
+	rotated, err := filepath.Glob(filepath.Join(dir, "audit-*.log"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(rotated) != 1 {
+		t.Fatalf("bad: %v", rotated)
+	}
+}
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"net/rpc"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// rpcRequest is implemented by the arguments of the RPC endpoints, through
+// the QueryOptions or WriteRequest they embed
+type rpcRequest interface {
+	RequestRegion() string
+	RequestNamespace() string
+	RequestToken() string
+	IsRead() bool
+}
+
+// serverCodec wraps the codec of an RPC connection to record the requests
+// served over it. Requests of a connection are served one at a time, so the
+// request being served is kept between the calls to the codec.
+type serverCodec struct {
+	rpc.ServerCodec
+
+	auditor    *Auditor
+	remoteAddr string
+	resolver   TokenResolver
+
+	request *Request
+	auth    *Auth
+}
+
+// ServerCodec returns a codec recording the requests served over the codec
+// of an RPC connection. The resolver resolves the ACL tokens of the requests,
+// and is nil when ACLs are disabled.
+func (a *Auditor) ServerCodec(codec rpc.ServerCodec, remoteAddr string, resolver TokenResolver) rpc.ServerCodec {
+	return &serverCodec{
+		ServerCodec: codec,
+		auditor:     a,
+		remoteAddr:  remoteAddr,
+		resolver:    resolver,
+	}
+}
+
+// ReadRequestHeader reads the header of the next request
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	c.request, c.auth = nil, nil
+	if err := c.ServerCodec.ReadRequestHeader(r); err != nil {
+		return err
+	}
+	c.request = &Request{
+		ID:         structs.GenerateUUID(),
+		Endpoint:   r.ServiceMethod,
+		RemoteAddr: c.remoteAddr,
+	}
+	return nil
+}
+
+// ReadRequestBody reads the arguments of the request and records that the
+// request was received. Failing to record the request fails it when a sink
+// enforces its delivery.
+func (c *serverCodec) ReadRequestBody(body interface{}) error {
+	if err := c.ServerCodec.ReadRequestBody(body); err != nil {
+		return err
+	}
+
+	// The body is nil when the request is discarded
+	if body == nil || c.request == nil {
+		return nil
+	}
+	if args, ok := body.(rpcRequest); ok {
+		c.request.Region = args.RequestRegion()
+		c.request.Namespace = args.RequestNamespace()
+		c.request.Operation = "write"
+		if args.IsRead() {
+			c.request.Operation = "read"
+		}
+		c.auth = NewAuth(args.RequestToken(), c.resolver)
+	}
+
+	return c.auditor.Event(&Event{
+		Type:    RPCEvent,
+		Stage:   StageOperationReceived,
+		Auth:    c.auth,
+		Request: c.request,
+	})
+}
+
+// WriteResponse writes the response of the request and records that the
+// request completed
+func (c *serverCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	if c.request != nil {
+		resp := &Response{StatusCode: 200, Error: r.Error}
+		if r.Error != "" {
+			resp.StatusCode = 500
+		}
+
+		// The response is written regardless, since the request was served
+		c.auditor.Event(&Event{
+			Type:     RPCEvent,
+			Stage:    StageOperationComplete,
+			Auth:     c.auth,
+			Request:  c.request,
+			Response: resp,
+		})
+		c.request, c.auth = nil, nil
+	}
+	return c.ServerCodec.WriteResponse(r, body)
+}
@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"io/ioutil"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// testServerCodec serves a single request with the given arguments
+type testServerCodec struct {
+	method string
+	args   *structs.JobSpecificRequest
+	resp   *rpc.Response
+}
+
+func (c *testServerCodec) ReadRequestHeader(r *rpc.Request) error {
+	r.ServiceMethod = c.method
+	return nil
+}
+
+func (c *testServerCodec) ReadRequestBody(body interface{}) error {
+	*body.(*structs.JobSpecificRequest) = *c.args
+	return nil
+}
+
+func (c *testServerCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	c.resp = r
+	return nil
+}
+
+func (c *testServerCodec) Close() error {
+	return nil
+}
+
+func TestServerCodec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	a := testAuditor(t, dir)
+	defer a.Close()
+
+	token := &structs.ACLToken{
+		AccessorID: "accessor",
+		SecretID:   "secret",
+		Name:       "ops",
+		Type:       structs.ACLClientToken,
+		Policies:   []string{"ops"},
+	}
+	resolver := func(secretID string) (*structs.ACLToken, error) {
+		if secretID == token.SecretID {
+			return token, nil
+		}
+		return nil, nil
+	}
+
+	inner := &testServerCodec{
+		method: "Job.GetJob",
+		args: &structs.JobSpecificRequest{
+			JobID: "foo",
+			QueryOptions: structs.QueryOptions{
+				Region:    "global",
+				Namespace: "team",
+				AuthToken: token.SecretID,
+			},
+		},
+	}
+	codec := a.ServerCodec(inner, "10.0.0.1:4647", resolver)
+
+	var req rpc.Request
+	if err := codec.ReadRequestHeader(&req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var args structs.JobSpecificRequest
+	if err := codec.ReadRequestBody(&args); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := codec.WriteResponse(&rpc.Response{ServiceMethod: req.ServiceMethod, Error: "Permission denied"}, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if inner.resp == nil || args.JobID != "foo" {
+		t.Fatalf("request not passed through")
+	}
+
+	events := readEvents(t, filepath.Join(dir, "audit.log"))
+	if len(events) != 2 {
+		t.Fatalf("bad: %#v", events)
+	}
+	received, complete := events[0], events[1]
+	if received.Type != RPCEvent || received.Stage != StageOperationReceived || complete.Stage != StageOperationComplete {
+		t.Fatalf("bad: %#v %#v", received, complete)
+	}
+	r := received.Request
+	if r.Endpoint != "Job.GetJob" || r.Operation != "read" || r.Namespace != "team" ||
+		r.Region != "global" || r.RemoteAddr != "10.0.0.1:4647" {
+		t.Fatalf("bad: %#v", r)
+	}
+	if received.Auth == nil || received.Auth.AccessorID != "accessor" || received.Auth.Name != "ops" {
+		t.Fatalf("bad: %#v", received.Auth)
+	}
+	if complete.Response == nil || complete.Response.StatusCode != 500 || complete.Response.Error != "Permission denied" {
+		t.Fatalf("bad: %#v", complete.Response)
+	}
+}
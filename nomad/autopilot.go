@@ -0,0 +1,245 @@
+package nomad
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/serf/serf"
+)
+
+// autopilotLoop periodically updates the health of the servers of the region
+// and cleans up the dead ones, while the server is the leader.
+func (s *Server) autopilotLoop(stopCh chan struct{}) {
+	pruneTicker := time.NewTicker(s.config.AutopilotInterval)
+	defer pruneTicker.Stop()
+	healthTicker := time.NewTicker(s.config.ServerHealthInterval)
+	defer healthTicker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-pruneTicker.C:
+			if err := s.pruneDeadServers(); err != nil {
+				s.logger.Printf("[ERR] nomad.autopilot: failed to prune dead servers: %v", err)
+			}
+		case <-healthTicker.C:
+			if err := s.updateClusterHealth(); err != nil {
+				s.logger.Printf("[ERR] nomad.autopilot: failed to update server health: %v", err)
+			}
+		}
+	}
+}
+
+// getAutopilotConfig returns the configuration of autopilot set by the
+// operators, or the one of the server if none was set
+func (s *Server) getAutopilotConfig() (*structs.AutopilotConfig, error) {
+	_, config, err := s.fsm.State().AutopilotConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = s.config.AutopilotConfig
+	}
+	return config, nil
+}
+
+// pruneDeadServers removes the failed servers of the region from the gossip
+// pool, which removes them from the Raft configuration, as long as fewer than
+// half of the servers failed. Removing more would drop the region below
+// quorum, so they are left for an operator to handle.
+func (s *Server) pruneDeadServers() error {
+	config, err := s.getAutopilotConfig()
+	if err != nil {
+		return err
+	}
+	if !config.CleanupDeadServers {
+		return nil
+	}
+
+	peers, err := s.raftPeers.Peers()
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, member := range s.serf.Members() {
+		valid, parts := isNomadServer(member)
+		if !valid || parts.Region != s.config.Region {
+			continue
+		}
+		if member.Status == serf.StatusFailed {
+			failed = append(failed, member.Name)
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+	if len(failed) >= (len(peers)+1)/2 {
+		s.logger.Printf("[WARN] nomad.autopilot: not removing %d failed servers out of %d, as it would drop the region below quorum",
+			len(failed), len(peers))
+		return nil
+	}
+
+	for _, name := range failed {
+		s.logger.Printf("[INFO] nomad.autopilot: attempting removal of failed server: %v", name)
+		if err := s.serf.RemoveFailedNode(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateClusterHealth fetches the Raft statistics of the servers of the
+// region and updates their health according to the autopilot configuration
+func (s *Server) updateClusterHealth() error {
+	defer metrics.MeasureSince([]string{"nomad", "autopilot", "update_health"}, time.Now())
+
+	config, err := s.getAutopilotConfig()
+	if err != nil {
+		return err
+	}
+
+	peers, err := s.raftPeers.Peers()
+	if err != nil {
+		return err
+	}
+
+	// Find the gossip member of each server of the Raft configuration
+	members := make(map[string]serf.Member)
+	for _, member := range s.serf.Members() {
+		valid, parts := isNomadServer(member)
+		if !valid || parts.Region != s.config.Region {
+			continue
+		}
+		members[parts.Addr.String()] = member
+	}
+
+	// The health of each server is judged against the log of the leader
+	leader := s.raft.Leader()
+	leaderStats := s.raft.Stats()
+	leaderTerm, _ := strconv.ParseUint(leaderStats["last_log_term"], 10, 64)
+	leaderIndex, _ := strconv.ParseUint(leaderStats["last_log_index"], 10, 64)
+
+	s.clusterHealthLock.RLock()
+	previous := make(map[string]structs.ServerHealth, len(s.clusterHealth.Servers))
+	for _, health := range s.clusterHealth.Servers {
+		previous[health.ID] = health
+	}
+	s.clusterHealthLock.RUnlock()
+
+	now := time.Now()
+	cluster := structs.OperatorHealthReply{Healthy: true}
+	healthyVoters := 0
+	for _, peer := range peers {
+		health := structs.ServerHealth{
+			ID:         peer,
+			Address:    peer,
+			SerfStatus: serf.StatusNone.String(),
+			Leader:     peer == leader,
+			Voter:      true,
+		}
+
+		if member, ok := members[peer]; ok {
+			health.Name = member.Name
+			health.SerfStatus = member.Status.String()
+			health.Version = member.Tags["build"]
+
+			if member.Status == serf.StatusAlive {
+				stats, err := s.serverStats(peer, member, health.Leader, leaderStats)
+				if err != nil {
+					s.logger.Printf("[WARN] nomad.autopilot: failed to get the Raft statistics of server %q: %v", member.Name, err)
+				} else {
+					health.LastTerm = stats.LastTerm
+					health.LastIndex = stats.LastIndex
+					if stats.LastContact != "never" {
+						health.LastContact, err = time.ParseDuration(stats.LastContact)
+						if err != nil {
+							s.logger.Printf("[WARN] nomad.autopilot: invalid last contact of server %q: %v", member.Name, err)
+						}
+					}
+					health.Healthy = isServerHealthy(config, &health, stats, leaderTerm, leaderIndex)
+				}
+			}
+		}
+
+		// Track the time the server last changed its health
+		health.StableSince = now
+		if last, ok := previous[peer]; ok && last.Healthy == health.Healthy {
+			health.StableSince = last.StableSince
+		}
+
+		if health.Healthy {
+			healthyVoters++
+		} else {
+			cluster.Healthy = false
+		}
+		cluster.Servers = append(cluster.Servers, health)
+	}
+
+	// The region tolerates the failure of the healthy servers in excess of
+	// its quorum
+	quorum := len(peers)/2 + 1
+	if healthyVoters > quorum {
+		cluster.FailureTolerance = healthyVoters - quorum
+	}
+
+	s.clusterHealthLock.Lock()
+	s.clusterHealth = cluster
+	s.clusterHealthLock.Unlock()
+	return nil
+}
+
+// serverStats returns the Raft statistics of a server of the region
+func (s *Server) serverStats(addr string, member serf.Member, leader bool,
+	leaderStats map[string]string) (*structs.ServerStats, error) {
+	if leader {
+		lastTerm, _ := strconv.ParseUint(leaderStats["last_log_term"], 10, 64)
+		lastIndex, _ := strconv.ParseUint(leaderStats["last_log_index"], 10, 64)
+		return &structs.ServerStats{
+			LastContact: "0",
+			LastTerm:    lastTerm,
+			LastIndex:   lastIndex,
+		}, nil
+	}
+
+	_, parts := isNomadServer(member)
+	args := &structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:     s.config.Region,
+			AllowStale: true,
+		},
+	}
+	var reply structs.ServerStats
+	if err := s.connPool.RPC(s.config.Region, parts.Addr, parts.MajorVersion, "Status.RaftStats", args, &reply); err != nil {
+		return nil, fmt.Errorf("failed to query %v: %v", addr, err)
+	}
+	return &reply, nil
+}
+
+// isServerHealthy returns whether a server is in contact with the leader and
+// keeping up with its log
+func isServerHealthy(config *structs.AutopilotConfig, health *structs.ServerHealth,
+	stats *structs.ServerStats, leaderTerm, leaderIndex uint64) bool {
+	if stats.LastContact == "never" || health.LastContact > config.LastContactThreshold {
+		return false
+	}
+	if health.LastTerm != leaderTerm {
+		return false
+	}
+	if leaderIndex > config.MaxTrailingLogs && health.LastIndex < leaderIndex-config.MaxTrailingLogs {
+		return false
+	}
+	return true
+}
+
+// getClusterHealth returns the health of the servers of the region
+func (s *Server) getClusterHealth() structs.OperatorHealthReply {
+	s.clusterHealthLock.RLock()
+	defer s.clusterHealthLock.RUnlock()
+	return s.clusterHealth
+}
@@ -0,0 +1,164 @@
+package nomad
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func testAutopilotConfig(c *Config) {
+	c.AutopilotInterval = 100 * time.Millisecond
+	c.ServerHealthInterval = 50 * time.Millisecond
+}
+
+func TestAutopilot_CleanupDeadServer(t *testing.T) {
+	s1 := testServer(t, testAutopilotConfig)
+	defer s1.Shutdown()
+
+	s2 := testServer(t, func(c *Config) {
+		c.DevDisableBootstrap = true
+		testAutopilotConfig(c)
+	})
+	defer s2.Shutdown()
+
+	s3 := testServer(t, func(c *Config) {
+		c.DevDisableBootstrap = true
+		testAutopilotConfig(c)
+	})
+	defer s3.Shutdown()
+	servers := []*Server{s1, s2, s3}
+	testJoin(t, s1, s2, s3)
+
+	for _, s := range servers {
+		testutil.WaitForResult(func() (bool, error) {
+			peers, _ := s.raftPeers.Peers()
+			return len(peers) == 3, fmt.Errorf("%v", peers)
+		}, func(err error) {
+			t.Fatalf("should have 3 peers: %v", err)
+		})
+	}
+
+	// Kill a non-leader server
+	s3.Shutdown()
+
+	// Autopilot removes the failed server from the Raft configuration
+	for _, s := range servers[:2] {
+		testutil.WaitForResult(func() (bool, error) {
+			peers, _ := s.raftPeers.Peers()
+			return len(peers) == 2, fmt.Errorf("%v", peers)
+		}, func(err error) {
+			t.Fatalf("should have 2 peers: %v", err)
+		})
+	}
+}
+
+func TestAutopilot_CleanupDeadServer_Disabled(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		testAutopilotConfig(c)
+		c.AutopilotConfig.CleanupDeadServers = false
+	})
+	defer s1.Shutdown()
+
+	s2 := testServer(t, func(c *Config) {
+		c.DevDisableBootstrap = true
+		testAutopilotConfig(c)
+		c.AutopilotConfig.CleanupDeadServers = false
+	})
+	defer s2.Shutdown()
+
+	s3 := testServer(t, func(c *Config) {
+		c.DevDisableBootstrap = true
+		testAutopilotConfig(c)
+		c.AutopilotConfig.CleanupDeadServers = false
+	})
+	defer s3.Shutdown()
+	testJoin(t, s1, s2, s3)
+
+	testutil.WaitForResult(func() (bool, error) {
+		peers, _ := s1.raftPeers.Peers()
+		return len(peers) == 3, fmt.Errorf("%v", peers)
+	}, func(err error) {
+		t.Fatalf("should have 3 peers: %v", err)
+	})
+
+	// The failed server is kept in the configuration
+	s3.Shutdown()
+	time.Sleep(time.Second)
+	if peers, _ := s1.raftPeers.Peers(); len(peers) != 3 {
+		t.Fatalf("bad: %v", peers)
+	}
+}
+
+func TestAutopilot_ClusterHealth(t *testing.T) {
+	s1 := testServer(t, testAutopilotConfig)
+	defer s1.Shutdown()
+
+	s2 := testServer(t, func(c *Config) {
+		c.DevDisableBootstrap = true
+		testAutopilotConfig(c)
+	})
+	defer s2.Shutdown()
+
+	s3 := testServer(t, func(c *Config) {
+		c.DevDisableBootstrap = true
+		testAutopilotConfig(c)
+	})
+	defer s3.Shutdown()
+	testJoin(t, s1, s2, s3)
+
+	// Every server becomes healthy, so the region tolerates one failure
+	testutil.WaitForResult(func() (bool, error) {
+		health := s1.getClusterHealth()
+		if len(health.Servers) != 3 {
+			return false, fmt.Errorf("bad: %#v", health)
+		}
+		if !health.Healthy || health.FailureTolerance != 1 {
+			return false, fmt.Errorf("bad: %#v", health)
+		}
+		for _, server := range health.Servers {
+			if server.Name == "" || server.SerfStatus != "alive" || server.Version != "unittest" {
+				return false, fmt.Errorf("bad: %#v", server)
+			}
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
+
+func TestAutopilot_IsServerHealthy(t *testing.T) {
+	config := structs.DefaultAutopilotConfig()
+	stats := &structs.ServerStats{LastContact: "10ms"}
+	health := &structs.ServerHealth{
+		LastContact: 10 * time.Millisecond,
+		LastTerm:    3,
+		LastIndex:   1000,
+	}
+	if !isServerHealthy(config, health, stats, 3, 1100) {
+		t.Fatalf("expected healthy")
+	}
+
+	// Servers trailing the leader by too many logs are unhealthy
+	if isServerHealthy(config, health, stats, 3, 1000+config.MaxTrailingLogs+1) {
+		t.Fatalf("expected unhealthy")
+	}
+
+	// Servers on another term are unhealthy
+	if isServerHealthy(config, health, stats, 4, 1000) {
+		t.Fatalf("expected unhealthy")
+	}
+
+	// Servers without recent contact from the leader are unhealthy
+	health.LastContact = time.Second
+	if isServerHealthy(config, health, stats, 3, 1000) {
+		t.Fatalf("expected unhealthy")
+	}
+	health.LastContact = 0
+	stats.LastContact = "never"
+	if isServerHealthy(config, health, stats, 3, 1000) {
+		t.Fatalf("expected unhealthy")
+	}
+}
@@ -35,12 +35,16 @@ type BlockedEvals struct {
 	// classes.
 	escaped map[string]wrappedEval
 
+	// quotaLimited is the set of evaluations that are blocked on a quota
+	// being exhausted. They are only unblocked when the quota changes.
+	quotaLimited map[string]wrappedEval
+
 	// unblockCh is used to buffer unblocking of evaluations.
 	capacityChangeCh chan *capacityUpdate
 
 	// jobs is the map of blocked job and is used to ensure that only one
 	// blocked eval exists for each job.
-	jobs map[string]struct{}
+	jobs map[structs.NamespacedID]struct{}
 
 	// unblockIndexes maps computed node classes to the index in which they were
 	// unblocked. This is used to check if an evaluation could have been
@@ -48,6 +52,11 @@ type BlockedEvals struct {
 	// are being blocked.
 	unblockIndexes map[string]uint64
 
+	// unblockQuotaIndexes maps quota specifications to the index in which
+	// their usage dropped or their limits changed. It serves the same
+	// purpose as unblockIndexes for quota limited evaluations.
+	unblockQuotaIndexes map[string]uint64
+
 	// duplicates is the set of evaluations for jobs that had pre-existing
 	// blocked evaluations. These should be marked as cancelled since only one
 	// blocked eval is neeeded per job.
@@ -62,9 +71,11 @@ type BlockedEvals struct {
 	stopCh chan struct{}
 }
 
-// capacityUpdate stores unblock data.
+// capacityUpdate stores unblock data. Either the computed class or the quota
+// is set.
 type capacityUpdate struct {
 	computedClass string
+	quotaChange   string
 	index         uint64
 }
 
@@ -82,21 +93,27 @@ type BlockedStats struct {
 
 	// TotalBlocked is the total number of blocked evaluations.
 	TotalBlocked int
+
+	// TotalQuotaLimit is the total number of blocked evaluations that are
+	// due to a quota being exhausted.
+	TotalQuotaLimit int
 }
 
 // NewBlockedEvals creates a new blocked eval tracker that will enqueue
 // unblocked evals into the passed broker.
 func NewBlockedEvals(evalBroker *EvalBroker) *BlockedEvals {
 	return &BlockedEvals{
-		evalBroker:       evalBroker,
-		captured:         make(map[string]wrappedEval),
-		escaped:          make(map[string]wrappedEval),
-		jobs:             make(map[string]struct{}),
-		unblockIndexes:   make(map[string]uint64),
-		capacityChangeCh: make(chan *capacityUpdate, unblockBuffer),
-		duplicateCh:      make(chan struct{}, 1),
-		stopCh:           make(chan struct{}),
-		stats:            new(BlockedStats),
+		evalBroker:          evalBroker,
+		captured:            make(map[string]wrappedEval),
+		escaped:             make(map[string]wrappedEval),
+		quotaLimited:        make(map[string]wrappedEval),
+		jobs:                make(map[structs.NamespacedID]struct{}),
+		unblockIndexes:      make(map[string]uint64),
+		unblockQuotaIndexes: make(map[string]uint64),
+		capacityChangeCh:    make(chan *capacityUpdate, unblockBuffer),
+		duplicateCh:         make(chan struct{}, 1),
+		stopCh:              make(chan struct{}),
+		stats:               new(BlockedStats),
 	}
 }
 
@@ -157,7 +174,7 @@ func (b *BlockedEvals) processBlock(eval *structs.Evaluation, token string) {
 	// the list of duplicates. We omly ever want one blocked evaluation per job,
 	// otherwise we would create unnecessary work for the scheduler as multiple
 	// evals for the same job would be run, all producing the same outcome.
-	if _, existing := b.jobs[eval.JobID]; existing {
+	if _, existing := b.jobs[structs.NewNamespacedID(eval.JobID, eval.Namespace)]; existing {
 		b.duplicates = append(b.duplicates, eval)
 
 		// Unblock any waiter.
@@ -183,7 +200,7 @@ func (b *BlockedEvals) processBlock(eval *structs.Evaluation, token string) {
 
 	// Mark the job as tracked.
 	b.stats.TotalBlocked++
-	b.jobs[eval.JobID] = struct{}{}
+	b.jobs[structs.NewNamespacedID(eval.JobID, eval.Namespace)] = struct{}{}
 
 	// Wrap the evaluation, capturing its token.
 	wrapped := wrappedEval{
@@ -191,6 +208,15 @@ func (b *BlockedEvals) processBlock(eval *structs.Evaluation, token string) {
 		token: token,
 	}
 
+	// If the eval is blocked on an exhausted quota, no additional node
+	// capacity will allow it to make progress. Store it separately so that
+	// it is only unblocked when the quota changes.
+	if eval.QuotaLimitReached != "" {
+		b.quotaLimited[eval.ID] = wrapped
+		b.stats.TotalQuotaLimit++
+		return
+	}
+
 	// If the eval has escaped, meaning computed node classes could not capture
 	// the constraints of the job, we store the eval separately as we have to
 	// unblock it whenever node capacity changes. This is because we don't know
@@ -212,6 +238,12 @@ func (b *BlockedEvals) processBlock(eval *structs.Evaluation, token string) {
 // complete. This method returns if that is the case and should be called with
 // the lock held.
 func (b *BlockedEvals) missedUnblock(eval *structs.Evaluation) bool {
+	// A quota limited evaluation can only make progress once the quota
+	// changes.
+	if eval.QuotaLimitReached != "" {
+		return eval.SnapshotIndex < b.unblockQuotaIndexes[eval.QuotaLimitReached]
+	}
+
 	var max uint64 = 0
 	for class, index := range b.unblockIndexes {
 		// Calculate the max unblock index
@@ -268,6 +300,28 @@ func (b *BlockedEvals) Unblock(computedClass string, index uint64) {
 	}
 }
 
+// UnblockQuota causes any evaluation that is blocked on the passed quota to
+// be enqueued into the eval broker. It should be called when the usage of the
+// quota drops or its limits change.
+func (b *BlockedEvals) UnblockQuota(quota string, index uint64) {
+	b.l.Lock()
+
+	// Do nothing if not enabled
+	if !b.enabled {
+		b.l.Unlock()
+		return
+	}
+
+	// Store the index in which the unblock happened.
+	b.unblockQuotaIndexes[quota] = index
+	b.l.Unlock()
+
+	b.capacityChangeCh <- &capacityUpdate{
+		quotaChange: quota,
+		index:       index,
+	}
+}
+
 // watchCapacity is a long lived function that watches for capacity changes in
 // nodes and unblocks the correct set of evals.
 func (b *BlockedEvals) watchCapacity() {
@@ -276,7 +330,11 @@ func (b *BlockedEvals) watchCapacity() {
 		case <-b.stopCh:
 			return
 		case update := <-b.capacityChangeCh:
-			b.unblock(update.computedClass, update.index)
+			if update.quotaChange != "" {
+				b.unblockQuota(update.quotaChange)
+			} else {
+				b.unblock(update.computedClass, update.index)
+			}
 		}
 	}
 }
@@ -300,7 +358,7 @@ func (b *BlockedEvals) unblock(computedClass string, index uint64) {
 		for id, wrapped := range b.escaped {
 			unblocked[wrapped.eval] = wrapped.token
 			delete(b.escaped, id)
-			delete(b.jobs, wrapped.eval.JobID)
+			delete(b.jobs, structs.NewNamespacedID(wrapped.eval.JobID, wrapped.eval.Namespace))
 		}
 	}
 
@@ -319,7 +377,7 @@ func (b *BlockedEvals) unblock(computedClass string, index uint64) {
 		// The computed node class has never been seen by the eval so we unblock
 		// it.
 		unblocked[wrapped.eval] = wrapped.token
-		delete(b.jobs, wrapped.eval.JobID)
+		delete(b.jobs, structs.NewNamespacedID(wrapped.eval.JobID, wrapped.eval.Namespace))
 		delete(b.captured, id)
 	}
 
@@ -333,6 +391,38 @@ func (b *BlockedEvals) unblock(computedClass string, index uint64) {
 	}
 }
 
+// unblockQuota unblocks all blocked evals that are limited by the passed
+// quota.
+func (b *BlockedEvals) unblockQuota(quota string) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	// Protect against the case of a flush.
+	if !b.enabled {
+		return
+	}
+
+	unblocked := make(map[*structs.Evaluation]string, 4)
+	for id, wrapped := range b.quotaLimited {
+		if wrapped.eval.QuotaLimitReached != quota {
+			continue
+		}
+
+		unblocked[wrapped.eval] = wrapped.token
+		delete(b.jobs, structs.NewNamespacedID(wrapped.eval.JobID, wrapped.eval.Namespace))
+		delete(b.quotaLimited, id)
+	}
+
+	if l := len(unblocked); l != 0 {
+		// Update the counters
+		b.stats.TotalQuotaLimit -= l
+		b.stats.TotalBlocked -= l
+
+		// Enqueue all the unblocked evals into the broker.
+		b.evalBroker.EnqueueAll(unblocked)
+	}
+}
+
 // UnblockFailed unblocks all blocked evaluation that were due to scheduler
 // failure.
 func (b *BlockedEvals) UnblockFailed() {
@@ -349,7 +439,7 @@ func (b *BlockedEvals) UnblockFailed() {
 		if wrapped.eval.TriggeredBy == structs.EvalTriggerMaxPlans {
 			unblocked[wrapped.eval] = wrapped.token
 			delete(b.captured, id)
-			delete(b.jobs, wrapped.eval.JobID)
+			delete(b.jobs, structs.NewNamespacedID(wrapped.eval.JobID, wrapped.eval.Namespace))
 		}
 	}
 
@@ -357,7 +447,7 @@ func (b *BlockedEvals) UnblockFailed() {
 		if wrapped.eval.TriggeredBy == structs.EvalTriggerMaxPlans {
 			unblocked[wrapped.eval] = wrapped.token
 			delete(b.escaped, id)
-			delete(b.jobs, wrapped.eval.JobID)
+			delete(b.jobs, structs.NewNamespacedID(wrapped.eval.JobID, wrapped.eval.Namespace))
 			b.stats.TotalEscaped -= 1
 		}
 	}
@@ -408,9 +498,11 @@ func (b *BlockedEvals) Flush() {
 	// Reset the blocked eval tracker.
 	b.stats.TotalEscaped = 0
 	b.stats.TotalBlocked = 0
+	b.stats.TotalQuotaLimit = 0
 	b.captured = make(map[string]wrappedEval)
 	b.escaped = make(map[string]wrappedEval)
-	b.jobs = make(map[string]struct{})
+	b.quotaLimited = make(map[string]wrappedEval)
+	b.jobs = make(map[structs.NamespacedID]struct{})
 	b.duplicates = nil
 	b.capacityChangeCh = make(chan *capacityUpdate, unblockBuffer)
 	b.stopCh = make(chan struct{})
@@ -428,6 +520,7 @@ func (b *BlockedEvals) Stats() *BlockedStats {
 	// Copy all the stats
 	stats.TotalEscaped = b.stats.TotalEscaped
 	stats.TotalBlocked = b.stats.TotalBlocked
+	stats.TotalQuotaLimit = b.stats.TotalQuotaLimit
 	return stats
 }
 
@@ -439,6 +532,7 @@ func (b *BlockedEvals) EmitStats(period time.Duration, stopCh chan struct{}) {
 			stats := b.Stats()
 			metrics.SetGauge([]string{"nomad", "blocked_evals", "total_blocked"}, float32(stats.TotalBlocked))
 			metrics.SetGauge([]string{"nomad", "blocked_evals", "total_escaped"}, float32(stats.TotalEscaped))
+			metrics.SetGauge([]string{"nomad", "blocked_evals", "total_quota_limit"}, float32(stats.TotalQuotaLimit))
 		case <-stopCh:
 			return
 		}
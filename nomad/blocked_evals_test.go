@@ -146,6 +146,72 @@ func TestBlockedEvals_UnblockEscaped(t *testing.T) {
 	})
 }
 
+func TestBlockedEvals_UnblockQuota(t *testing.T) {
+	blocked, broker := testBlockedEvals(t)
+
+	// Create an eval blocked on a quota and add it to the blocked tracker.
+	e := mock.Eval()
+	e.Status = structs.EvalStatusBlocked
+	e.EscapedComputedClass = true
+	e.QuotaLimitReached = "foo"
+	blocked.Block(e)
+
+	// Verify block caused the eval to be tracked
+	bStats := blocked.Stats()
+	if bStats.TotalBlocked != 1 || bStats.TotalQuotaLimit != 1 {
+		t.Fatalf("bad: %#v", bStats)
+	}
+
+	// Capacity changes and other quotas should not unblock the eval
+	blocked.Unblock("v1:123", 1000)
+	blocked.UnblockQuota("bar", 1001)
+	time.Sleep(50 * time.Millisecond)
+	if brokerStats := broker.Stats(); brokerStats.TotalReady != 0 {
+		t.Fatalf("bad: %#v", brokerStats)
+	}
+
+	blocked.UnblockQuota("foo", 1002)
+
+	testutil.WaitForResult(func() (bool, error) {
+		// Verify UnblockQuota caused an enqueue
+		brokerStats := broker.Stats()
+		if brokerStats.TotalReady != 1 {
+			return false, fmt.Errorf("bad: %#v", brokerStats)
+		}
+
+		// Verify UnblockQuota updates the stats
+		bStats := blocked.Stats()
+		if bStats.TotalBlocked != 0 || bStats.TotalQuotaLimit != 0 {
+			return false, fmt.Errorf("bad: %#v", bStats)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %s", err)
+	})
+}
+
+func TestBlockedEvals_Block_ImmediateUnblock_Quota(t *testing.T) {
+	blocked, broker := testBlockedEvals(t)
+
+	// Unblock the quota at an index later than the eval's snapshot
+	blocked.UnblockQuota("foo", 1000)
+
+	e := mock.Eval()
+	e.Status = structs.EvalStatusBlocked
+	e.QuotaLimitReached = "foo"
+	e.SnapshotIndex = 900
+	blocked.Block(e)
+
+	// Verify block did not track the eval and instead enqueued it
+	bStats := blocked.Stats()
+	if bStats.TotalBlocked != 0 || bStats.TotalQuotaLimit != 0 {
+		t.Fatalf("bad: %#v", bStats)
+	}
+	if brokerStats := broker.Stats(); brokerStats.TotalReady != 1 {
+		t.Fatalf("bad: %#v", brokerStats)
+	}
+}
+
 func TestBlockedEvals_UnblockEligible(t *testing.T) {
 	blocked, broker := testBlockedEvals(t)
 
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/hashicorp/memberlist"
+	"github.com/hashicorp/nomad/nomad/audit"
+	"github.com/hashicorp/nomad/nomad/features"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/hashicorp/nomad/scheduler"
@@ -120,6 +122,11 @@ type Config struct {
 	// that the workers dequeue for processing.
 	EnabledSchedulers []string
 
+	// SchedulerMapping maps job types to the name of the scheduler that
+	// processes their evaluations. Job types that are not mapped are
+	// processed by the scheduler of the same name.
+	SchedulerMapping scheduler.Mapping
+
 	// ReconcileInterval controls how often we reconcile the strongly
 	// consistent store with the Serf info. This is used to handle nodes
 	// that are force removed, as well as intermittent unavailability during
@@ -148,6 +155,11 @@ type Config struct {
 	// for GC. This gives users some time to view and debug a failed nodes.
 	NodeGCThreshold time.Duration
 
+	// CSIVolumeClaimGCInterval is how often we dispatch a job to release the
+	// claims of stopped allocations on CSI volumes, which their clients
+	// failed to release.
+	CSIVolumeClaimGCInterval time.Duration
+
 	// EvalNackTimeout controls how long we allow a sub-scheduler to
 	// work on an evaluation before we consider it failed and Nack it.
 	// This allows that evaluation to be handed to another sub-scheduler
@@ -179,18 +191,109 @@ type Config struct {
 	// of all the heartbeats.
 	FailoverHeartbeatTTL time.Duration
 
+	// ClockSkewThreshold is the difference between the wall clock of a node,
+	// as sent with its heartbeats, and the clock of the leader above which
+	// the node is reported as having a skewed clock.
+	ClockSkewThreshold time.Duration
+
+	// NodeDrainStagger is the delay between each wave of migrations when the
+	// allocations of a node class under hard maintenance are migrated. Lower
+	// priority and batch jobs are migrated in the earlier waves, while system
+	// jobs are migrated last.
+	NodeDrainStagger time.Duration
+
 	// ConsulConfig is this Agent's Consul configuration
 	ConsulConfig *config.ConsulConfig
 
 	// VaultConfig is this Agent's Vault configuration
 	VaultConfig *config.VaultConfig
 
+	// VaultClusters are the additional Vault clusters tasks may derive their
+	// tokens from, keyed by name
+	VaultClusters map[string]*config.VaultConfig
+
+	// TLSConfig holds the TLS configuration of the RPC and Raft traffic
+	TLSConfig *config.TLSConfig
+
 	// RPCHoldTimeout is how long an RPC can be "held" before it is errored.
 	// This is used to paper over a loss of leadership by instead holding RPCs,
 	// so that the caller experiences a slow response rather than an error.
 	// This period is meant to be long enough for a leader election to take
 	// place, and a small jitter is applied to avoid a thundering herd.
 	RPCHoldTimeout time.Duration
+
+	// AuthoritativeRegion is the region which is treated as the authoritative
+	// source for ACLs and Policies, as well as namespaces and quota
+	// specifications. This provides a single source of truth to resolve
+	// conflicts.
+	AuthoritativeRegion string
+
+	// ACLEnabled controls if ACL enforcement and management is enabled.
+	ACLEnabled bool
+
+	// ReplicationBackoff is how much we backoff when replication errors.
+	// This is a tunable knob for testing primarily.
+	ReplicationBackoff time.Duration
+
+	// ReplicationToken is the ACL Token Secret ID used to fetch from
+	// the Authoritative Region.
+	ReplicationToken string
+
+	// KeyringEncryptionKey is the AES-256 key wrapping the root keys of the
+	// keyring. Only the wrapped root keys are replicated through Raft, so it
+	// must be the same on all the servers of the region. Dev mode servers
+	// generate one when it isn't set.
+	KeyringEncryptionKey []byte
+
+	// ReplicationReconcileInterval is how often the replication of namespaces
+	// and quota specifications reconciles the region with the authoritative
+	// region when nothing changed, retrying the objects that conflicted.
+	// This is a tunable knob for testing primarily.
+	ReplicationReconcileInterval time.Duration
+
+	// MultiregionRolloutInterval is how often the leader checks the health
+	// of the regions of the multi-region rollouts it coordinates.
+	// This is a tunable knob for testing primarily.
+	MultiregionRolloutInterval time.Duration
+
+	// MaintenanceWindowInterval is how often the leader checks for the
+	// maintenance windows of node classes taking or ceasing effect.
+	// This is a tunable knob for testing primarily.
+	MaintenanceWindowInterval time.Duration
+
+	// NodeDrainInterval is how often the leader advances the drains of the
+	// nodes, migrating their next allocations.
+	// This is a tunable knob for testing primarily.
+	NodeDrainInterval time.Duration
+
+	// NodeReliabilityInterval is how often the leader adds the failures seen
+	// on the nodes to their reliability penalties.
+	// This is a tunable knob for testing primarily.
+	NodeReliabilityInterval time.Duration
+
+	// AutopilotConfig is the configuration autopilot runs with until an
+	// operator sets one through the operator API
+	AutopilotConfig *structs.AutopilotConfig
+
+	// AutopilotInterval is how often the leader checks for dead servers to
+	// clean up. This is a tunable knob for testing primarily.
+	AutopilotInterval time.Duration
+
+	// ServerHealthInterval is how often the leader updates the health of
+	// the servers. This is a tunable knob for testing primarily.
+	ServerHealthInterval time.Duration
+
+	// Auditor records the RPC requests served over the network. It is nil
+	// when audit logging is disabled.
+	Auditor *audit.Auditor
+
+	// Features are the optional subsystems enabled on the server. All the
+	// features compiled into the binary are enabled when nil.
+	Features *features.Set
+
+	// EventBufferSize is the number of Raft log entries whose events are
+	// kept for the subscribers of the event stream to resume from.
+	EventBufferSize int
 }
 
 // CheckVersion is used to check if the ProtocolVersion is valid
@@ -205,6 +308,29 @@ func (c *Config) CheckVersion() error {
 	return nil
 }
 
+// CheckSchedulerMapping is used to check that the job types are mapped to
+// known schedulers
+func (c *Config) CheckSchedulerMapping() error {
+	for jobType := range c.SchedulerMapping {
+		if jobType == structs.JobTypeCore {
+			return fmt.Errorf("Job type %q can't be mapped to a scheduler", jobType)
+		}
+		if err := c.SchedulerMapping.Validate(jobType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckKeyringEncryptionKey is used to check the size of the keyring
+// encryption key, if set
+func (c *Config) CheckKeyringEncryptionKey() error {
+	if l := len(c.KeyringEncryptionKey); l != 0 && l != rootKeyBytes {
+		return fmt.Errorf("Keyring encryption key must be %d bytes, got %d", rootKeyBytes, l)
+	}
+	return nil
+}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	hostname, err := os.Hostname()
@@ -213,39 +339,53 @@ func DefaultConfig() *Config {
 	}
 
 	c := &Config{
-		Region:                 DefaultRegion,
-		Datacenter:             DefaultDC,
-		NodeName:               hostname,
-		ProtocolVersion:        ProtocolVersionMax,
-		RaftConfig:             raft.DefaultConfig(),
-		RaftTimeout:            10 * time.Second,
-		LogOutput:              os.Stderr,
-		RPCAddr:                DefaultRPCAddr,
-		SerfConfig:             serf.DefaultConfig(),
-		NumSchedulers:          1,
-		ReconcileInterval:      60 * time.Second,
-		EvalGCInterval:         5 * time.Minute,
-		EvalGCThreshold:        1 * time.Hour,
-		JobGCInterval:          5 * time.Minute,
-		JobGCThreshold:         4 * time.Hour,
-		NodeGCInterval:         5 * time.Minute,
-		NodeGCThreshold:        24 * time.Hour,
-		EvalNackTimeout:        60 * time.Second,
-		EvalDeliveryLimit:      3,
-		MinHeartbeatTTL:        10 * time.Second,
-		MaxHeartbeatsPerSecond: 50.0,
-		HeartbeatGrace:         10 * time.Second,
-		FailoverHeartbeatTTL:   300 * time.Second,
-		ConsulConfig:           config.DefaultConsulConfig(),
-		VaultConfig:            config.DefaultVaultConfig(),
-		RPCHoldTimeout:         5 * time.Second,
+		Region:                   DefaultRegion,
+		AuthoritativeRegion:      DefaultRegion,
+		Datacenter:               DefaultDC,
+		NodeName:                 hostname,
+		ProtocolVersion:          ProtocolVersionMax,
+		RaftConfig:               raft.DefaultConfig(),
+		RaftTimeout:              10 * time.Second,
+		LogOutput:                os.Stderr,
+		RPCAddr:                  DefaultRPCAddr,
+		SerfConfig:               serf.DefaultConfig(),
+		NumSchedulers:            1,
+		ReconcileInterval:        60 * time.Second,
+		EvalGCInterval:           5 * time.Minute,
+		EvalGCThreshold:          1 * time.Hour,
+		JobGCInterval:            5 * time.Minute,
+		JobGCThreshold:           4 * time.Hour,
+		NodeGCInterval:           5 * time.Minute,
+		NodeGCThreshold:          24 * time.Hour,
+		CSIVolumeClaimGCInterval: 5 * time.Minute,
+		EvalNackTimeout:          60 * time.Second,
+		EvalDeliveryLimit:        3,
+		MinHeartbeatTTL:          10 * time.Second,
+		MaxHeartbeatsPerSecond:   50.0,
+		HeartbeatGrace:           10 * time.Second,
+		FailoverHeartbeatTTL:     300 * time.Second,
+		ClockSkewThreshold:       10 * time.Second,
+		NodeDrainStagger:         5 * time.Second,
+		ConsulConfig:             config.DefaultConsulConfig(),
+		VaultConfig:              config.DefaultVaultConfig(),
+		TLSConfig:                &config.TLSConfig{},
+		RPCHoldTimeout:           5 * time.Second,
+		ReplicationBackoff:       30 * time.Second,
+
+		AutopilotConfig:              structs.DefaultAutopilotConfig(),
+		AutopilotInterval:            10 * time.Second,
+		ServerHealthInterval:         2 * time.Second,
+		MultiregionRolloutInterval:   10 * time.Second,
+		MaintenanceWindowInterval:    10 * time.Second,
+		NodeReliabilityInterval:      10 * time.Second,
+		NodeDrainInterval:            5 * time.Second,
+		EventBufferSize:              100,
+		ReplicationReconcileInterval: 5 * time.Minute,
 	}
 
-	// Enable all known schedulers by default
-	c.EnabledSchedulers = make([]string, 0, len(scheduler.BuiltinSchedulers))
-	for name := range scheduler.BuiltinSchedulers {
-		c.EnabledSchedulers = append(c.EnabledSchedulers, name)
-	}
+	// Enable all known schedulers by default, including the ones registered
+	// in addition to the built in schedulers
+	c.EnabledSchedulers = scheduler.Schedulers()
 	c.EnabledSchedulers = append(c.EnabledSchedulers, structs.JobTypeCore)
 
 	// Default the number of schedulers to match the coores
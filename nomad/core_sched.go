@@ -43,6 +43,8 @@ func (c *CoreScheduler) Process(eval *structs.Evaluation) error {
 		return c.nodeGC(eval)
 	case structs.CoreJobJobGC:
 		return c.jobGC(eval)
+	case structs.CoreJobCSIVolumeClaimGC:
+		return c.csiVolumeClaimGC(eval)
 	case structs.CoreJobForceGC:
 		return c.forceGC(eval)
 	default:
@@ -58,6 +60,9 @@ func (c *CoreScheduler) forceGC(eval *structs.Evaluation) error {
 	if err := c.evalGC(eval); err != nil {
 		return err
 	}
+	if err := c.csiVolumeClaimGC(eval); err != nil {
+		return err
+	}
 
 	// Node GC must occur after the others to ensure the allocations are
 	// cleared.
@@ -88,7 +93,8 @@ func (c *CoreScheduler) jobGC(eval *structs.Evaluation) error {
 	}
 
 	// Collect the allocations, evaluations and jobs to GC
-	var gcAlloc, gcEval, gcJob []string
+	var gcAlloc, gcEval []string
+	var gcJob []*structs.Job
 
 OUTER:
 	for i := iter.Next(); i != nil; i = iter.Next() {
@@ -99,7 +105,7 @@ OUTER:
 			continue
 		}
 
-		evals, err := c.snap.EvalsByJob(job.ID)
+		evals, err := c.snap.EvalsByJob(job.Namespace, job.ID)
 		if err != nil {
 			c.srv.logger.Printf("[ERR] sched.core: failed to get evals for job %s: %v", job.ID, err)
 			continue
@@ -124,7 +130,7 @@ OUTER:
 
 		// Job is eligible for garbage collection
 		if allEvalsGC {
-			gcJob = append(gcJob, job.ID)
+			gcJob = append(gcJob, job)
 			gcAlloc = append(gcAlloc, jobAlloc...)
 			gcEval = append(gcEval, jobEval...)
 		}
@@ -145,9 +151,11 @@ OUTER:
 	// Call to the leader to deregister the jobs.
 	for _, job := range gcJob {
 		req := structs.JobDeregisterRequest{
-			JobID: job,
+			JobID: job.ID,
+			Purge: true,
 			WriteRequest: structs.WriteRequest{
-				Region: c.srv.config.Region,
+				Region:    c.srv.config.Region,
+				Namespace: job.Namespace,
 			},
 		}
 		var resp structs.JobDeregisterResponse
@@ -235,7 +243,7 @@ func (c *CoreScheduler) gcEval(eval *structs.Evaluation, thresholdIndex uint64,
 		}
 
 		// Check if the job is running
-		job, err := c.snap.JobByID(eval.JobID)
+		job, err := c.snap.JobByID(eval.Namespace, eval.JobID)
 		if err != nil {
 			return false, nil, err
 		}
@@ -415,3 +423,61 @@ OUTER:
 	}
 	return nil
 }
+
+// csiVolumeClaimGC is used to release the claims of terminal or collected
+// allocations on CSI volumes
+func (c *CoreScheduler) csiVolumeClaimGC(eval *structs.Evaluation) error {
+	iter, err := c.snap.CSIVolumes()
+	if err != nil {
+		return err
+	}
+
+	// Collect the claims to release
+	release := make(map[string][]string)
+	var volIDs []string
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		vol := raw.(*structs.CSIVolume)
+		for allocID := range vol.Claims {
+			alloc, err := c.snap.AllocByID(allocID)
+			if err != nil {
+				c.srv.logger.Printf("[ERR] sched.core: failed to get allocation %q claiming volume %q: %v",
+					allocID, vol.ID, err)
+				continue
+			}
+			if alloc != nil && !alloc.Terminated() {
+				continue
+			}
+			if _, ok := release[vol.ID]; !ok {
+				volIDs = append(volIDs, vol.ID)
+			}
+			release[vol.ID] = append(release[vol.ID], allocID)
+		}
+	}
+
+	// Fast-path the nothing case
+	if len(volIDs) == 0 {
+		return nil
+	}
+	c.srv.logger.Printf("[DEBUG] sched.core: CSI volume claim GC: %d volumes with claims to release", len(volIDs))
+
+	// Call to the leader to release the claims
+	for _, volID := range volIDs {
+		req := structs.CSIVolumeReleaseClaimsRequest{
+			VolumeID: volID,
+			AllocIDs: release[volID],
+			WriteRequest: structs.WriteRequest{
+				Region: c.srv.config.Region,
+			},
+		}
+		var resp structs.GenericResponse
+		if err := c.srv.RPC("CSIVolume.ReleaseClaims", &req, &resp); err != nil {
+			c.srv.logger.Printf("[ERR] sched.core: releasing claims on volume %q failed: %v", volID, err)
+			return err
+		}
+	}
+	return nil
+}
@@ -178,7 +178,7 @@ func TestCoreScheduler_EvalGC_Batch(t *testing.T) {
 		t.Fatalf("bad: %v", outA2)
 	}
 
-	outB, err := state.JobByID(job.ID)
+	outB, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -589,7 +589,7 @@ func TestCoreScheduler_JobGC_OutstandingEvals(t *testing.T) {
 	}
 
 	// Should still exist
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -635,7 +635,7 @@ func TestCoreScheduler_JobGC_OutstandingEvals(t *testing.T) {
 	}
 
 	// Should not still exist
-	out, err = state.JobByID(job.ID)
+	out, err = state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -724,7 +724,7 @@ func TestCoreScheduler_JobGC_OutstandingAllocs(t *testing.T) {
 	}
 
 	// Should still exist
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -770,7 +770,7 @@ func TestCoreScheduler_JobGC_OutstandingAllocs(t *testing.T) {
 	}
 
 	// Should not still exist
-	out, err = state.JobByID(job.ID)
+	out, err = state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -866,7 +866,7 @@ func TestCoreScheduler_JobGC_OneShot(t *testing.T) {
 	}
 
 	// Should still exist
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -948,7 +948,7 @@ func TestCoreScheduler_JobGC_Force(t *testing.T) {
 	}
 
 	// Shouldn't still exist
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -1005,3 +1005,58 @@ func TestCoreScheduler_PartitionReap(t *testing.T) {
 		t.Fatalf("Unexpected third request: %v", third)
 	}
 }
+
+func TestCoreScheduler_CSIVolumeClaimGC(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Insert a running and a terminal allocation, and a volume claimed by
+	// them and by a collected allocation
+	state := s1.fsm.State()
+	running := mock.Alloc()
+	terminal := mock.Alloc()
+	terminal.ClientStatus = structs.AllocClientStatusComplete
+	if err := state.UpsertJobSummary(998, mock.JobSummary(running.JobID)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJobSummary(999, mock.JobSummary(terminal.JobID)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertAllocs(1000, []*structs.Allocation{running, terminal}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	vol := mock.CSIVolume()
+	vol.AccessMode = structs.CSIVolumeAccessModeMultiNodeMultiWriter
+	if err := state.UpsertCSIVolumes(1001, []*structs.CSIVolume{vol}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, allocID := range []string{running.ID, terminal.ID, structs.GenerateUUID()} {
+		claim := &structs.CSIVolumeClaim{AllocID: allocID, NodeID: "n1", Mode: structs.CSIVolumeClaimWrite}
+		if err := state.CSIVolumeClaim(1002, vol.ID, claim); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Create a core scheduler
+	snap, err := state.Snapshot()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	core := NewCoreScheduler(s1, snap)
+
+	// Attempt the GC
+	gc := s1.coreJobEval(structs.CoreJobCSIVolumeClaimGC, 2000)
+	if err := core.Process(gc); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Only the claim of the running allocation is left
+	out, err := state.CSIVolumeByID(vol.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out.Claims) != 1 || out.Claims[running.ID] == nil {
+		t.Fatalf("bad: %#v", out.Claims)
+	}
+}
@@ -0,0 +1,1293 @@
+package nomad
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/tlsutil"
+	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/watch"
+	"github.com/hashicorp/nomad/plugins/csi"
+)
+
+const (
+	// The operations of the controller plugins the servers call through the
+	// HTTP API of the clients running them
+	csiControllerCreateVolume    = "create_volume"
+	csiControllerDeleteVolume    = "delete_volume"
+	csiControllerExpandVolume    = "expand_volume"
+	csiControllerPublishVolume   = "publish_volume"
+	csiControllerUnpublishVolume = "unpublish_volume"
+	csiControllerCreateSnapshot  = "create_snapshot"
+	csiControllerDeleteSnapshot  = "delete_snapshot"
+	csiControllerListSnapshots   = "list_snapshots"
+
+	// The operations of the node plugins the servers call through the HTTP
+	// API of the clients running them
+	csiNodeExpandVolume = "expand_volume"
+
+	// csiControllerTimeout bounds the calls of controller plugins
+	csiControllerTimeout = 2 * time.Minute
+)
+
+// CSIVolume endpoint is used for registering, creating and querying the
+// volumes managed by CSI plugins, and for claiming them for allocations
+type CSIVolume struct {
+	srv *Server
+}
+
+// Register is used to register existing volumes of storage providers, or to
+// update them
+func (v *CSIVolume) Register(args *structs.CSIVolumeRegisterRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := v.srv.forward("CSIVolume.Register", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_volume", "register"}, time.Now())
+
+	// Check for csi-write-volume permissions
+	namespace := args.RequestNamespace()
+	if aclObj, err := v.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityCSIWriteVolume) {
+		return structs.ErrPermissionDenied
+	}
+
+	if len(args.Volumes) == 0 {
+		return fmt.Errorf("must specify at least one volume")
+	}
+	for _, vol := range args.Volumes {
+		vol.Namespace = namespace
+		if err := vol.Validate(true); err != nil {
+			return err
+		}
+	}
+
+	// Update via Raft
+	resp, index, err := v.srv.raftApply(structs.CSIVolumeRegisterRequestType, args)
+	if err != nil {
+		v.srv.logger.Printf("[ERR] nomad.csi_volume: Register failed: %v", err)
+		return err
+	}
+	if err, ok := resp.(error); ok && err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// Deregister is used to deregister volumes. The volumes are kept in the
+// storage provider.
+func (v *CSIVolume) Deregister(args *structs.CSIVolumeDeregisterRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := v.srv.forward("CSIVolume.Deregister", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_volume", "deregister"}, time.Now())
+
+	// Check for csi-write-volume permissions
+	namespace := args.RequestNamespace()
+	if aclObj, err := v.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityCSIWriteVolume) {
+		return structs.ErrPermissionDenied
+	}
+
+	if len(args.VolumeIDs) == 0 {
+		return fmt.Errorf("must specify at least one volume ID")
+	}
+
+	snap, err := v.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	for _, id := range args.VolumeIDs {
+		vol, err := snap.CSIVolumeByID(id)
+		if err != nil {
+			return err
+		}
+		if vol == nil || vol.Namespace != namespace {
+			return fmt.Errorf("Volume %q does not exist", id)
+		}
+		if len(vol.Claims) != 0 && !args.Force {
+			return fmt.Errorf("Volume %q is in use", id)
+		}
+	}
+
+	// Update via Raft
+	_, index, err := v.srv.raftApply(structs.CSIVolumeDeregisterRequestType, args)
+	if err != nil {
+		v.srv.logger.Printf("[ERR] nomad.csi_volume: Deregister failed: %v", err)
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// Create is used to create volumes with the controller of their plugin and
+// register them
+func (v *CSIVolume) Create(args *structs.CSIVolumeCreateRequest,
+	reply *structs.CSIVolumeCreateResponse) error {
+	if done, err := v.srv.forward("CSIVolume.Create", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_volume", "create"}, time.Now())
+
+	// Check for csi-write-volume permissions
+	namespace := args.RequestNamespace()
+	if aclObj, err := v.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityCSIWriteVolume) {
+		return structs.ErrPermissionDenied
+	}
+
+	if len(args.Volumes) == 0 {
+		return fmt.Errorf("must specify at least one volume")
+	}
+
+	snap, err := v.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	for _, vol := range args.Volumes {
+		vol.Namespace = namespace
+		if err := vol.Validate(false); err != nil {
+			return err
+		}
+		if vol.ExternalID != "" {
+			return fmt.Errorf("Volume %q has an external ID and must be registered instead", vol.ID)
+		}
+		if existing, err := snap.CSIVolumeByID(vol.ID); err != nil {
+			return err
+		} else if existing != nil {
+			return fmt.Errorf("Volume %q already exists", vol.ID)
+		}
+	}
+
+	// Create the volumes with their controllers. The volumes created before
+	// a failure are left in the storage providers, since they can't be
+	// deleted by the controllers yet.
+	for _, vol := range args.Volumes {
+		req := &csi.ControllerCreateVolumeRequest{
+			Name:         vol.ID,
+			CapacityMin:  vol.RequestedCapacityMin,
+			CapacityMax:  vol.RequestedCapacityMax,
+			Capabilities: []*csi.VolumeCapability{csiVolumeCapability(vol)},
+			Parameters:   vol.Parameters,
+			Secrets:      vol.Secrets,
+			Topologies:   csiTopologies(vol.Topologies),
+			SnapshotID:   vol.SnapshotID,
+		}
+		var created csi.Volume
+		if err := v.srv.csiControllerCall(snap, vol.PluginID, csiControllerCreateVolume, req, &created); err != nil {
+			return fmt.Errorf("failed to create volume %q: %v", vol.ID, err)
+		}
+
+		vol.ExternalID = created.ExternalID
+		vol.Capacity = created.CapacityBytes
+		if len(created.Context) != 0 {
+			if vol.Context == nil {
+				vol.Context = make(map[string]string, len(created.Context))
+			}
+			for k, v := range created.Context {
+				vol.Context[k] = v
+			}
+		}
+		if len(created.Topologies) != 0 {
+			vol.Topologies = structsCSITopologies(created.Topologies)
+		}
+	}
+
+	// Register the volumes via Raft
+	req := &structs.CSIVolumeRegisterRequest{
+		Volumes:      args.Volumes,
+		WriteRequest: args.WriteRequest,
+	}
+	resp, index, err := v.srv.raftApply(structs.CSIVolumeRegisterRequestType, req)
+	if err != nil {
+		v.srv.logger.Printf("[ERR] nomad.csi_volume: Create failed: %v", err)
+		return err
+	}
+	if err, ok := resp.(error); ok && err != nil {
+		return err
+	}
+
+	reply.Volumes = make([]*structs.CSIVolume, len(args.Volumes))
+	for i, vol := range args.Volumes {
+		reply.Volumes[i] = vol.Sanitize()
+	}
+	reply.Index = index
+	return nil
+}
+
+// Delete is used to delete volumes with the controller of their plugin and
+// deregister them
+func (v *CSIVolume) Delete(args *structs.CSIVolumeDeleteRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := v.srv.forward("CSIVolume.Delete", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_volume", "delete"}, time.Now())
+
+	// Check for csi-write-volume permissions
+	namespace := args.RequestNamespace()
+	if aclObj, err := v.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityCSIWriteVolume) {
+		return structs.ErrPermissionDenied
+	}
+
+	if len(args.VolumeIDs) == 0 {
+		return fmt.Errorf("must specify at least one volume ID")
+	}
+
+	snap, err := v.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	volumes := make([]*structs.CSIVolume, len(args.VolumeIDs))
+	for i, id := range args.VolumeIDs {
+		vol, err := snap.CSIVolumeByID(id)
+		if err != nil {
+			return err
+		}
+		if vol == nil || vol.Namespace != namespace {
+			return fmt.Errorf("Volume %q does not exist", id)
+		}
+		if len(vol.Claims) != 0 {
+			return fmt.Errorf("Volume %q is in use", id)
+		}
+		volumes[i] = vol
+	}
+
+	// Delete the volumes with their controllers. The volumes deleted before
+	// a failure are still deregistered.
+	var deleted []string
+	var deleteErr error
+	for _, vol := range volumes {
+		req := &csi.ControllerDeleteVolumeRequest{
+			ExternalID: vol.ExternalID,
+			Secrets:    vol.Secrets,
+		}
+		if err := v.srv.csiControllerCall(snap, vol.PluginID, csiControllerDeleteVolume, req, nil); err != nil {
+			deleteErr = fmt.Errorf("failed to delete volume %q: %v", vol.ID, err)
+			break
+		}
+		deleted = append(deleted, vol.ID)
+	}
+	if len(deleted) == 0 {
+		return deleteErr
+	}
+
+	// Deregister the deleted volumes via Raft
+	req := &structs.CSIVolumeDeregisterRequest{
+		VolumeIDs:    deleted,
+		WriteRequest: args.WriteRequest,
+	}
+	_, index, err := v.srv.raftApply(structs.CSIVolumeDeregisterRequestType, req)
+	if err != nil {
+		v.srv.logger.Printf("[ERR] nomad.csi_volume: Delete failed: %v", err)
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return deleteErr
+}
+
+// Expand is used to grow a volume with the controller of its plugin. The
+// volume is also expanded by the node plugins of the nodes claiming it if
+// the controller requires it.
+func (v *CSIVolume) Expand(args *structs.CSIVolumeExpandRequest,
+	reply *structs.CSIVolumeExpandResponse) error {
+	if done, err := v.srv.forward("CSIVolume.Expand", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_volume", "expand"}, time.Now())
+
+	// Check for csi-write-volume permissions
+	namespace := args.RequestNamespace()
+	if aclObj, err := v.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityCSIWriteVolume) {
+		return structs.ErrPermissionDenied
+	}
+
+	if args.RequestedCapacityMin <= 0 {
+		return fmt.Errorf("must specify the minimum capacity")
+	}
+	if args.RequestedCapacityMax != 0 && args.RequestedCapacityMax < args.RequestedCapacityMin {
+		return fmt.Errorf("maximum capacity is less than the minimum capacity")
+	}
+
+	snap, err := v.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	vol, err := snap.CSIVolumeByID(args.VolumeID)
+	if err != nil {
+		return err
+	}
+	if vol == nil || vol.Namespace != namespace {
+		return fmt.Errorf("Volume %q does not exist", args.VolumeID)
+	}
+	if args.RequestedCapacityMax != 0 && args.RequestedCapacityMax < vol.Capacity {
+		return fmt.Errorf("Volume %q can't be shrunk below its capacity of %d bytes", vol.ID, vol.Capacity)
+	}
+
+	req := &csi.ControllerExpandVolumeRequest{
+		ExternalID:  vol.ExternalID,
+		CapacityMin: args.RequestedCapacityMin,
+		CapacityMax: args.RequestedCapacityMax,
+		Capability:  csiVolumeCapability(vol),
+		Secrets:     vol.Secrets,
+	}
+	var resp csi.ControllerExpandVolumeResponse
+	if err := v.srv.csiControllerCall(snap, vol.PluginID, csiControllerExpandVolume, req, &resp); err != nil {
+		return fmt.Errorf("failed to expand volume %q: %v", vol.ID, err)
+	}
+
+	// Update the capacity via Raft
+	vol = vol.Copy()
+	vol.Capacity = resp.CapacityBytes
+	vol.RequestedCapacityMin = args.RequestedCapacityMin
+	vol.RequestedCapacityMax = args.RequestedCapacityMax
+	update := &structs.CSIVolumeRegisterRequest{
+		Volumes:      []*structs.CSIVolume{vol},
+		WriteRequest: args.WriteRequest,
+	}
+	out, index, err := v.srv.raftApply(structs.CSIVolumeRegisterRequestType, update)
+	if err != nil {
+		v.srv.logger.Printf("[ERR] nomad.csi_volume: Expand failed: %v", err)
+		return err
+	}
+	if err, ok := out.(error); ok && err != nil {
+		return err
+	}
+	reply.Capacity = resp.CapacityBytes
+	reply.Index = index
+
+	if !resp.NodeExpansionRequired {
+		return nil
+	}
+
+	// Expand the volume on the nodes it is published on. The volumes of the
+	// nodes failing to expand it are expanded once published again.
+	var mErr multierror.Error
+	expanded := make(map[string]struct{})
+	for _, claim := range vol.Claims {
+		if _, ok := expanded[claim.NodeID]; ok {
+			continue
+		}
+		expanded[claim.NodeID] = struct{}{}
+		if err := v.srv.csiNodeCall(snap, claim.NodeID, vol.PluginID, csiNodeExpandVolume, vol, nil); err != nil {
+			mErr.Errors = append(mErr.Errors,
+				fmt.Errorf("failed to expand volume %q on node %q: %v", vol.ID, claim.NodeID, err))
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// CreateSnapshot is used to snapshot volumes with the controller of their
+// plugin
+func (v *CSIVolume) CreateSnapshot(args *structs.CSISnapshotCreateRequest,
+	reply *structs.CSISnapshotCreateResponse) error {
+	if done, err := v.srv.forward("CSIVolume.CreateSnapshot", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_volume", "create_snapshot"}, time.Now())
+
+	// Check for csi-write-volume permissions
+	namespace := args.RequestNamespace()
+	if aclObj, err := v.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityCSIWriteVolume) {
+		return structs.ErrPermissionDenied
+	}
+
+	if len(args.Snapshots) == 0 {
+		return fmt.Errorf("must specify at least one snapshot")
+	}
+
+	snap, err := v.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	volumes := make([]*structs.CSIVolume, len(args.Snapshots))
+	for i, s := range args.Snapshots {
+		if s.SourceVolumeID == "" {
+			return fmt.Errorf("snapshot %q missing source volume ID", s.Name)
+		}
+		vol, err := snap.CSIVolumeByID(s.SourceVolumeID)
+		if err != nil {
+			return err
+		}
+		if vol == nil || vol.Namespace != namespace {
+			return fmt.Errorf("Volume %q does not exist", s.SourceVolumeID)
+		}
+		volumes[i] = vol
+	}
+
+	for i, s := range args.Snapshots {
+		vol := volumes[i]
+		secrets := s.Secrets
+		if secrets == nil {
+			secrets = vol.Secrets
+		}
+		req := &csi.ControllerCreateSnapshotRequest{
+			SourceVolumeID: vol.ExternalID,
+			Name:           s.Name,
+			Parameters:     s.Parameters,
+			Secrets:        secrets,
+		}
+		var created csi.Snapshot
+		if err := v.srv.csiControllerCall(snap, vol.PluginID, csiControllerCreateSnapshot, req, &created); err != nil {
+			return fmt.Errorf("failed to snapshot volume %q: %v", vol.ID, err)
+		}
+
+		out := structsCSISnapshot(&created, vol.PluginID)
+		out.SourceVolumeID = vol.ID
+		out.Name = s.Name
+		out.Parameters = s.Parameters
+		reply.Snapshots = append(reply.Snapshots, out)
+	}
+	reply.Index = v.srv.raft.AppliedIndex()
+	return nil
+}
+
+// DeleteSnapshot is used to delete snapshots with the controller of their
+// plugin
+func (v *CSIVolume) DeleteSnapshot(args *structs.CSISnapshotDeleteRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := v.srv.forward("CSIVolume.DeleteSnapshot", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_volume", "delete_snapshot"}, time.Now())
+
+	// Check for csi-write-volume permissions
+	namespace := args.RequestNamespace()
+	if aclObj, err := v.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityCSIWriteVolume) {
+		return structs.ErrPermissionDenied
+	}
+
+	if len(args.Snapshots) == 0 {
+		return fmt.Errorf("must specify at least one snapshot")
+	}
+	for _, s := range args.Snapshots {
+		if s.ID == "" || s.PluginID == "" {
+			return fmt.Errorf("snapshots must specify their ID and plugin ID")
+		}
+	}
+
+	snap, err := v.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	for _, s := range args.Snapshots {
+		req := &csi.ControllerDeleteSnapshotRequest{
+			SnapshotID: s.ID,
+			Secrets:    s.Secrets,
+		}
+		if err := v.srv.csiControllerCall(snap, s.PluginID, csiControllerDeleteSnapshot, req, nil); err != nil {
+			return fmt.Errorf("failed to delete snapshot %q: %v", s.ID, err)
+		}
+	}
+	reply.Index = v.srv.raft.AppliedIndex()
+	return nil
+}
+
+// ListSnapshots is used to list the snapshots of a plugin, a page at a time
+func (v *CSIVolume) ListSnapshots(args *structs.CSISnapshotListRequest,
+	reply *structs.CSISnapshotListResponse) error {
+	if done, err := v.srv.forward("CSIVolume.ListSnapshots", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_volume", "list_snapshots"}, time.Now())
+
+	// Check for csi-read-volume permissions
+	namespace := args.RequestNamespace()
+	if aclObj, err := v.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityCSIReadVolume) {
+		return structs.ErrPermissionDenied
+	}
+
+	if args.PluginID == "" {
+		return fmt.Errorf("must specify a plugin ID")
+	}
+
+	snap, err := v.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	req := &csi.ControllerListSnapshotsRequest{
+		MaxEntries:    args.PerPage,
+		StartingToken: args.NextToken,
+		Secrets:       args.Secrets,
+	}
+	var resp csi.ControllerListSnapshotsResponse
+	if err := v.srv.csiControllerCall(snap, args.PluginID, csiControllerListSnapshots, req, &resp); err != nil {
+		return fmt.Errorf("failed to list snapshots: %v", err)
+	}
+
+	// Snapshots of the registered volumes of the namespace are given the ID
+	// of their volume
+	volumes := make(map[string]string)
+	iter, err := snap.CSIVolumesByPluginID(args.PluginID)
+	if err != nil {
+		return err
+	}
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		vol := raw.(*structs.CSIVolume)
+		if vol.Namespace == namespace {
+			volumes[vol.ExternalID] = vol.ID
+		}
+	}
+
+	reply.Snapshots = make([]*structs.CSISnapshot, len(resp.Snapshots))
+	for i, s := range resp.Snapshots {
+		reply.Snapshots[i] = structsCSISnapshot(s, args.PluginID)
+		reply.Snapshots[i].SourceVolumeID = volumes[s.SourceVolumeID]
+	}
+	reply.NextToken = resp.NextToken
+	reply.Index = v.srv.raft.AppliedIndex()
+	return nil
+}
+
+// List is used to list the volumes of a namespace
+func (v *CSIVolume) List(args *structs.CSIVolumeListRequest,
+	reply *structs.CSIVolumeListResponse) error {
+	if done, err := v.srv.forward("CSIVolume.List", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_volume", "list"}, time.Now())
+
+	// Check for csi-read-volume permissions. Queries across all namespaces
+	// are filtered to the namespaces the token may read instead.
+	aclObj, err := v.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	namespace := args.RequestNamespace()
+	allNamespaces := namespace == structs.AllNamespacesSentinel
+	if !allNamespaces && aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityCSIReadVolume) {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "csi_volumes"}),
+		run: func(snap *state.StateSnapshot) error {
+			var err error
+			var allowed map[string]bool
+			if allNamespaces {
+				allowed, err = allowedNamespaces(aclObj, snap, acl.NamespaceCapabilityCSIReadVolume)
+				if err != nil {
+					return err
+				}
+			}
+
+			var iter memdb.ResultIterator
+			if args.PluginID != "" {
+				iter, err = snap.CSIVolumesByPluginID(args.PluginID)
+			} else if allNamespaces {
+				iter, err = snap.CSIVolumes()
+			} else {
+				iter, err = snap.CSIVolumesByNamespace(namespace)
+			}
+			if err != nil {
+				return err
+			}
+
+			var vols []*structs.CSIVolumeListStub
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				vol := raw.(*structs.CSIVolume)
+				if !namespaceMatches(vol.Namespace, namespace, allowed) {
+					continue
+				}
+				if prefix := args.QueryOptions.Prefix; prefix != "" && !strings.HasPrefix(vol.ID, prefix) {
+					continue
+				}
+				vols = append(vols, vol.Stub())
+			}
+			reply.Volumes = vols
+
+			// Use the last index that affected the volume table
+			index, err := snap.Index("csi_volumes")
+			if err != nil {
+				return err
+			}
+
+			// Ensure we never set the index to zero, otherwise a blocking query
+			// cannot be used. We floor the index at one, since realistically
+			// the first write must have a higher index.
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			v.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return v.srv.blockingRPC(&opts)
+}
+
+// Get is used to look up a volume
+func (v *CSIVolume) Get(args *structs.CSIVolumeGetRequest,
+	reply *structs.CSIVolumeGetResponse) error {
+	if done, err := v.srv.forward("CSIVolume.Get", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_volume", "get"}, time.Now())
+
+	// Check for csi-read-volume permissions
+	namespace := args.RequestNamespace()
+	if aclObj, err := v.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityCSIReadVolume) {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "csi_volumes"}),
+		run: func(snap *state.StateSnapshot) error {
+			vol, err := snap.CSIVolumeByID(args.ID)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Volume = nil
+			if vol != nil && vol.Namespace == namespace {
+				reply.Volume = vol.Sanitize()
+				reply.Index = vol.ModifyIndex
+			} else {
+				// Use the last index that affected the volume table
+				index, err := snap.Index("csi_volumes")
+				if err != nil {
+					return err
+				}
+
+				// Ensure we never set the index to zero, otherwise a blocking
+				// query cannot be used. We floor the index at one, since
+				// realistically the first write must have a higher index.
+				if index == 0 {
+					index = 1
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			v.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return v.srv.blockingRPC(&opts)
+}
+
+// Claim is used by clients to claim volumes for the allocations they run,
+// and to release the claims once the allocations stopped. Claimed volumes of
+// plugins requiring a controller are published to the node of the claim.
+func (v *CSIVolume) Claim(args *structs.CSIVolumeClaimRequest,
+	reply *structs.CSIVolumeClaimResponse) error {
+	if done, err := v.srv.forward("CSIVolume.Claim", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_volume", "claim"}, time.Now())
+
+	snap, err := v.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	if err := validateNodeSecret(snap, args.NodeID, args.SecretID); err != nil {
+		return err
+	}
+
+	vol, err := snap.CSIVolumeByID(args.VolumeID)
+	if err != nil {
+		return err
+	}
+	if vol == nil {
+		return fmt.Errorf("Volume %q does not exist", args.VolumeID)
+	}
+	claim := &structs.CSIVolumeClaim{
+		AllocID:        args.AllocationID,
+		NodeID:         args.NodeID,
+		Mode:           args.Mode,
+		ExternalNodeID: args.ExternalNodeID,
+	}
+
+	// Releasing a claim unpublishes the volume
+	if args.Mode == structs.CSIVolumeClaimRelease {
+		existing, ok := vol.Claims[args.AllocationID]
+		if !ok {
+			reply.Volume = vol.Sanitize()
+			reply.Index = vol.ModifyIndex
+			return nil
+		}
+		if existing.NodeID != args.NodeID {
+			return fmt.Errorf("Volume %q not claimed by Node %q", args.VolumeID, args.NodeID)
+		}
+		index, err := v.srv.csiReleaseClaim(snap, vol, existing)
+		if err != nil {
+			return err
+		}
+		reply.Index = index
+		return nil
+	}
+
+	// Ensure the volume is claimed for a running allocation of the node
+	alloc, err := snap.AllocByID(args.AllocationID)
+	if err != nil {
+		return err
+	}
+	if alloc == nil {
+		return fmt.Errorf("Allocation %q does not exist", args.AllocationID)
+	}
+	if alloc.NodeID != args.NodeID {
+		return fmt.Errorf("Allocation %q not running on Node %q", args.AllocationID, args.NodeID)
+	}
+	if alloc.TerminalStatus() {
+		return fmt.Errorf("Can't claim volumes for terminal allocation %q", args.AllocationID)
+	}
+	if alloc.Namespace != vol.Namespace {
+		return fmt.Errorf("Volume %q does not exist in namespace %q", args.VolumeID, alloc.Namespace)
+	}
+	switch args.Mode {
+	case structs.CSIVolumeClaimRead, structs.CSIVolumeClaimWrite:
+	default:
+		return fmt.Errorf("unknown claim mode %q", args.Mode)
+	}
+	if err := vol.ClaimAllowed(claim.AllocID, claim.NodeID, claim.Mode); err != nil {
+		return err
+	}
+
+	// Publish the volume to the node if the plugin requires a controller
+	node, err := snap.NodeByID(args.NodeID)
+	if err != nil {
+		return err
+	}
+	info, ok := node.CSINodePlugins[vol.PluginID]
+	if !ok {
+		return fmt.Errorf("CSI plugin %q not running on Node %q", vol.PluginID, args.NodeID)
+	}
+	if claim.ExternalNodeID == "" && info.NodeInfo != nil {
+		claim.ExternalNodeID = info.NodeInfo.ID
+	}
+	if info.RequiresControllerPlugin {
+		req := &csi.ControllerPublishVolumeRequest{
+			ExternalID: vol.ExternalID,
+			NodeID:     claim.ExternalNodeID,
+			Capability: csiVolumeCapability(vol),
+			ReadOnly:   args.Mode == structs.CSIVolumeClaimRead,
+			Secrets:    vol.Secrets,
+			Context:    vol.Context,
+		}
+		var resp csi.ControllerPublishVolumeResponse
+		if err := v.srv.csiControllerCall(snap, vol.PluginID, csiControllerPublishVolume, req, &resp); err != nil {
+			return fmt.Errorf("failed to publish volume %q: %v", vol.ID, err)
+		}
+		reply.PublishContext = resp.PublishContext
+	}
+
+	// Update via Raft
+	update := &structs.CSIVolumeClaimUpdate{
+		VolumeID: vol.ID,
+		Claim:    claim,
+	}
+	resp, index, err := v.srv.raftApply(structs.CSIVolumeClaimRequestType, update)
+	if err != nil {
+		v.srv.logger.Printf("[ERR] nomad.csi_volume: Claim failed: %v", err)
+		return err
+	}
+	if err, ok := resp.(error); ok && err != nil {
+		return err
+	}
+
+	// The secrets are returned to the node, which passes them to its node
+	// plugin
+	out, err := v.srv.fsm.State().CSIVolumeByID(vol.ID)
+	if err != nil {
+		return err
+	}
+	reply.Volume = out.Copy()
+	reply.Index = index
+	return nil
+}
+
+// ReleaseClaims is used by the core scheduler to release the claims of
+// terminal or collected allocations on a volume
+func (v *CSIVolume) ReleaseClaims(args *structs.CSIVolumeReleaseClaimsRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := v.srv.forward("CSIVolume.ReleaseClaims", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_volume", "release_claims"}, time.Now())
+
+	snap, err := v.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	vol, err := snap.CSIVolumeByID(args.VolumeID)
+	if err != nil {
+		return err
+	}
+	if vol == nil {
+		return fmt.Errorf("Volume %q does not exist", args.VolumeID)
+	}
+
+	for _, allocID := range args.AllocIDs {
+		claim, ok := vol.Claims[allocID]
+		if !ok {
+			continue
+		}
+
+		// Only the claims of stopped allocations are released
+		alloc, err := snap.AllocByID(allocID)
+		if err != nil {
+			return err
+		}
+		if alloc != nil && !alloc.Terminated() {
+			return fmt.Errorf("Allocation %q claiming volume %q is running", allocID, vol.ID)
+		}
+
+		index, err := v.srv.csiReleaseClaim(snap, vol, claim)
+		if err != nil {
+			return err
+		}
+		reply.Index = index
+
+		// Unpublishing depends on the remaining claims of the volume
+		vol = vol.Copy()
+		delete(vol.Claims, allocID)
+	}
+	return nil
+}
+
+// csiReleaseClaim releases the claim of an allocation on a volume. The volume
+// is unpublished from the node of the claim by the controller of its plugin
+// if no other allocation of the node claims it, on a best-effort basis since
+// the node may be gone.
+func (s *Server) csiReleaseClaim(snap *state.StateSnapshot, vol *structs.CSIVolume, claim *structs.CSIVolumeClaim) (uint64, error) {
+	unpublish := claim.ExternalNodeID != ""
+	for _, c := range vol.Claims {
+		if c.AllocID != claim.AllocID && c.NodeID == claim.NodeID {
+			unpublish = false
+			break
+		}
+	}
+	if unpublish {
+		plugin, err := csiPluginByID(snap, vol.PluginID)
+		if err != nil {
+			return 0, err
+		}
+		if plugin.ControllerRequired {
+			req := &csi.ControllerUnpublishVolumeRequest{
+				ExternalID: vol.ExternalID,
+				NodeID:     claim.ExternalNodeID,
+				Secrets:    vol.Secrets,
+			}
+			if err := s.csiControllerCall(snap, vol.PluginID, csiControllerUnpublishVolume, req, nil); err != nil {
+				s.logger.Printf("[WARN] nomad.csi_volume: failed to unpublish volume %q from node %q: %v",
+					vol.ID, claim.NodeID, err)
+			}
+		}
+	}
+
+	// Update via Raft
+	update := &structs.CSIVolumeClaimUpdate{
+		VolumeID: vol.ID,
+		Claim: &structs.CSIVolumeClaim{
+			AllocID: claim.AllocID,
+			NodeID:  claim.NodeID,
+			Mode:    structs.CSIVolumeClaimRelease,
+		},
+	}
+	_, index, err := s.raftApply(structs.CSIVolumeClaimRequestType, update)
+	if err != nil {
+		s.logger.Printf("[ERR] nomad.csi_volume: releasing claim failed: %v", err)
+		return 0, err
+	}
+	return index, nil
+}
+
+// CSIPlugin endpoint is used for querying the CSI plugins running on the
+// nodes
+type CSIPlugin struct {
+	srv *Server
+}
+
+// List is used to list the plugins
+func (p *CSIPlugin) List(args *structs.CSIPluginListRequest,
+	reply *structs.CSIPluginListResponse) error {
+	if done, err := p.srv.forward("CSIPlugin.List", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_plugin", "list"}, time.Now())
+
+	// Check node read permissions
+	if aclObj, err := p.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNodeRead() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "nodes"}),
+		run: func(snap *state.StateSnapshot) error {
+			plugins, err := csiPlugins(snap)
+			if err != nil {
+				return err
+			}
+
+			var stubs []*structs.CSIPluginListStub
+			for _, plugin := range plugins {
+				if prefix := args.QueryOptions.Prefix; prefix != "" && !strings.HasPrefix(plugin.ID, prefix) {
+					continue
+				}
+				stubs = append(stubs, plugin.Stub())
+			}
+			reply.Plugins = stubs
+
+			// Use the last index that affected the node table
+			index, err := snap.Index("nodes")
+			if err != nil {
+				return err
+			}
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			p.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return p.srv.blockingRPC(&opts)
+}
+
+// Get is used to look up a plugin
+func (p *CSIPlugin) Get(args *structs.CSIPluginGetRequest,
+	reply *structs.CSIPluginGetResponse) error {
+	if done, err := p.srv.forward("CSIPlugin.Get", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "csi_plugin", "get"}, time.Now())
+
+	// Check node read permissions
+	if aclObj, err := p.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNodeRead() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "nodes"}),
+		run: func(snap *state.StateSnapshot) error {
+			plugin, err := csiPluginByID(snap, args.ID)
+			if err != nil {
+				return err
+			}
+
+			// Plugins without instances don't exist
+			reply.Plugin = nil
+			if len(plugin.Controllers) != 0 || len(plugin.Nodes) != 0 {
+				reply.Plugin = plugin
+			}
+
+			// Use the last index that affected the node table
+			index, err := snap.Index("nodes")
+			if err != nil {
+				return err
+			}
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			p.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return p.srv.blockingRPC(&opts)
+}
+
+// csiPlugins returns the plugins running on the nodes, sorted by ID
+func csiPlugins(snap *state.StateSnapshot) ([]*structs.CSIPlugin, error) {
+	iter, err := snap.Nodes()
+	if err != nil {
+		return nil, err
+	}
+
+	plugins := make(map[string]*structs.CSIPlugin)
+	var ids []string
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		node := raw.(*structs.Node)
+		for id := range node.CSIControllerPlugins {
+			if _, ok := plugins[id]; !ok {
+				plugins[id] = structs.NewCSIPlugin(id)
+				ids = append(ids, id)
+			}
+		}
+		for id := range node.CSINodePlugins {
+			if _, ok := plugins[id]; !ok {
+				plugins[id] = structs.NewCSIPlugin(id)
+				ids = append(ids, id)
+			}
+		}
+		for _, plugin := range plugins {
+			plugin.AddNode(node)
+		}
+	}
+
+	sort.Strings(ids)
+	out := make([]*structs.CSIPlugin, len(ids))
+	for i, id := range ids {
+		out[i] = plugins[id]
+	}
+	return out, nil
+}
+
+// csiPluginByID returns the instances of the plugin running on the nodes
+func csiPluginByID(snap *state.StateSnapshot, id string) (*structs.CSIPlugin, error) {
+	iter, err := snap.Nodes()
+	if err != nil {
+		return nil, err
+	}
+
+	plugin := structs.NewCSIPlugin(id)
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		plugin.AddNode(raw.(*structs.Node))
+	}
+	return plugin, nil
+}
+
+// csiControllerCall calls an operation of the controller plugin through the
+// HTTP API of a client running a healthy instance of it. The clients are
+// tried in random order until one of them can be reached.
+func (s *Server) csiControllerCall(snap *state.StateSnapshot, pluginID, op string, args, reply interface{}) error {
+	plugin, err := csiPluginByID(snap, pluginID)
+	if err != nil {
+		return err
+	}
+	var nodeIDs []string
+	for nodeID, info := range plugin.Controllers {
+		if info.Healthy {
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+	}
+	if len(nodeIDs) == 0 {
+		return fmt.Errorf("no healthy controller of CSI plugin %q", pluginID)
+	}
+	shuffleStrings(nodeIDs)
+
+	var lastErr error
+	for _, nodeID := range nodeIDs {
+		node, err := snap.NodeByID(nodeID)
+		if err != nil {
+			return err
+		}
+		if node == nil || node.Status != structs.NodeStatusReady || node.HTTPAddr == "" {
+			continue
+		}
+
+		path := fmt.Sprintf("/v1/client/csi/controller/%s/%s", pluginID, op)
+		token := structs.GenerateCSIControllerToken(pluginID, node.SecretID)
+		resp, err := s.csiClientCall(node, path, token, args)
+		if err != nil {
+			// Try the controllers of other nodes
+			lastErr = err
+			continue
+		}
+		err = decodeCSIClientResponse("CSI controller", nodeID, resp, reply)
+		resp.Body.Close()
+		return err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no reachable controller of CSI plugin %q", pluginID)
+	}
+	return lastErr
+}
+
+// csiNodeCall calls an operation of the node plugin running on the node
+// through the HTTP API of the node
+func (s *Server) csiNodeCall(snap *state.StateSnapshot, nodeID, pluginID, op string, args, reply interface{}) error {
+	node, err := snap.NodeByID(nodeID)
+	if err != nil {
+		return err
+	}
+	if node == nil || node.Status != structs.NodeStatusReady || node.HTTPAddr == "" {
+		return fmt.Errorf("node %q is not reachable", nodeID)
+	}
+	if info, ok := node.CSINodePlugins[pluginID]; !ok || !info.Healthy {
+		return fmt.Errorf("no healthy node plugin of CSI plugin %q on node %q", pluginID, nodeID)
+	}
+
+	path := fmt.Sprintf("/v1/client/csi/node/%s/%s", pluginID, op)
+	token := structs.GenerateCSINodeToken(pluginID, node.SecretID)
+	resp, err := s.csiClientCall(node, path, token, args)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeCSIClientResponse("CSI node plugin", nodeID, resp, reply)
+}
+
+// csiClientCall sends the arguments of a plugin call to the path of the HTTP
+// API of the node, authenticated with the token
+func (s *Server) csiClientCall(node *structs.Node, path, token string, args interface{}) (*http.Response, error) {
+	body, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, scheme, err := s.csiClientHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s://%s%s", scheme, node.HTTPAddr, path)
+	req, err := http.NewRequest("PUT", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Nomad-Token", token)
+	return httpClient.Do(req)
+}
+
+// decodeCSIClientResponse decodes the reply of a plugin call
+func decodeCSIClientResponse(plugin, nodeID string, resp *http.Response, reply interface{}) error {
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s on node %q failed: %s", plugin, nodeID, strings.TrimSpace(string(msg)))
+	}
+	if reply == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(reply)
+}
+
+// csiClientHTTPClient returns the HTTP client and the scheme used to call
+// the plugins through the HTTP API of the clients, which serve it over TLS
+// when the agents enable TLS for HTTP
+func (s *Server) csiClientHTTPClient() (*http.Client, string, error) {
+	conf := s.config.TLSConfig
+	if conf == nil || !conf.EnableHTTP {
+		return &http.Client{Timeout: csiControllerTimeout}, "http", nil
+	}
+
+	tc := &tlsutil.Config{
+		CAFile:   conf.CAFile,
+		CertFile: conf.CertFile,
+		KeyFile:  conf.KeyFile,
+	}
+	tlsConf := &tls.Config{RootCAs: x509.NewCertPool()}
+	if err := tc.AppendCA(tlsConf.RootCAs); err != nil {
+		return nil, "", err
+	}
+	cert, err := tc.KeyPair()
+	if err != nil {
+		return nil, "", err
+	} else if cert != nil {
+		tlsConf.Certificates = []tls.Certificate{*cert}
+	}
+	return &http.Client{
+		Timeout:   csiControllerTimeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConf},
+	}, "https", nil
+}
+
+// csiVolumeCapability returns the capability plugins use the volume with
+func csiVolumeCapability(vol *structs.CSIVolume) *csi.VolumeCapability {
+	c := &csi.VolumeCapability{
+		AccessMode:     vol.AccessMode,
+		AttachmentMode: vol.AttachmentMode,
+	}
+	if vol.MountOptions != nil {
+		c.FSType = vol.MountOptions.FSType
+		c.MountFlags = vol.MountOptions.MountFlags
+	}
+	return c
+}
+
+// csiTopologies and structsCSITopologies convert topologies between their
+// plugin and struct representations
+func csiTopologies(in []*structs.CSITopology) []*csi.Topology {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]*csi.Topology, len(in))
+	for i, t := range in {
+		out[i] = &csi.Topology{Segments: t.Segments}
+	}
+	return out
+}
+
+func structsCSITopologies(in []*csi.Topology) []*structs.CSITopology {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]*structs.CSITopology, len(in))
+	for i, t := range in {
+		out[i] = &structs.CSITopology{Segments: t.Segments}
+	}
+	return out
+}
+
+// structsCSISnapshot converts a snapshot taken by the plugin
+func structsCSISnapshot(s *csi.Snapshot, pluginID string) *structs.CSISnapshot {
+	return &structs.CSISnapshot{
+		ID:                     s.ID,
+		ExternalSourceVolumeID: s.SourceVolumeID,
+		PluginID:               pluginID,
+		SizeBytes:              s.SizeBytes,
+		CreateTime:             s.CreateTime,
+		IsReady:                s.ReadyToUse,
+	}
+}
@@ -0,0 +1,649 @@
+package nomad
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/plugins/csi"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// testCSIControllerNode makes the node run a controller and node plugin
+// "ebs", with the controller calls served by the test plugin over a test HTTP
+// server emulating the API of the client
+func testCSIControllerNode(t *testing.T, node *structs.Node, plugin *csi.TestPlugin) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var out interface{}
+		var err error
+
+		// The node plugin is asked to expand the volume at the path of its
+		// ID, which fails unless the test published the volume there
+		if strings.HasPrefix(r.URL.Path, "/v1/client/csi/node/ebs/") {
+			if !structs.CompareCSINodeToken("ebs", node.SecretID, r.Header.Get("X-Nomad-Token")) {
+				http.Error(w, "Permission denied", http.StatusForbidden)
+				return
+			}
+			if r.URL.Path != "/v1/client/csi/node/ebs/expand_volume" {
+				http.NotFound(w, r)
+				return
+			}
+			var vol structs.CSIVolume
+			if err := json.NewDecoder(r.Body).Decode(&vol); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			_, err = plugin.NodeExpandVolume(&csi.NodeExpandVolumeRequest{
+				ExternalID:  vol.ExternalID,
+				VolumePath:  vol.ID,
+				CapacityMin: vol.RequestedCapacityMin,
+				CapacityMax: vol.RequestedCapacityMax,
+			})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/v1/client/csi/controller/ebs/")
+		if !structs.CompareCSIControllerToken("ebs", node.SecretID, r.Header.Get("X-Nomad-Token")) {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+
+		switch path {
+		case "create_volume":
+			var req csi.ControllerCreateVolumeRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			out, err = plugin.ControllerCreateVolume(&req)
+		case "publish_volume":
+			var req csi.ControllerPublishVolumeRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			out, err = plugin.ControllerPublishVolume(&req)
+		case "unpublish_volume":
+			var req csi.ControllerUnpublishVolumeRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			err = plugin.ControllerUnpublishVolume(&req)
+		case "delete_volume":
+			var req csi.ControllerDeleteVolumeRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			err = plugin.ControllerDeleteVolume(&req)
+		case "expand_volume":
+			var req csi.ControllerExpandVolumeRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			out, err = plugin.ControllerExpandVolume(&req)
+		case "create_snapshot":
+			var req csi.ControllerCreateSnapshotRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			out, err = plugin.ControllerCreateSnapshot(&req)
+		case "delete_snapshot":
+			var req csi.ControllerDeleteSnapshotRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			err = plugin.ControllerDeleteSnapshot(&req)
+		case "list_snapshots":
+			var req csi.ControllerListSnapshotsRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			out, err = plugin.ControllerListSnapshots(&req)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(out)
+	}))
+
+	node.HTTPAddr = strings.TrimPrefix(srv.URL, "http://")
+	node.CSIControllerPlugins = map[string]*structs.CSIInfo{
+		"ebs": &structs.CSIInfo{
+			PluginID:                 "ebs",
+			Provider:                 "test",
+			Healthy:                  true,
+			RequiresControllerPlugin: true,
+		},
+	}
+	node.CSINodePlugins = map[string]*structs.CSIInfo{
+		"ebs": &structs.CSIInfo{
+			PluginID:                 "ebs",
+			Provider:                 "test",
+			Healthy:                  true,
+			RequiresControllerPlugin: true,
+			NodeInfo: &structs.CSINodeInfo{
+				ID:         "test-node",
+				MaxVolumes: 8,
+			},
+		},
+	}
+	return srv
+}
+
+func TestCSIVolumeEndpoint_RegisterDeregister(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	vol := mock.CSIVolume()
+	vol.Secrets = map[string]string{"password": "secret"}
+	req := &structs.CSIVolumeRegisterRequest{
+		Volumes:      []*structs.CSIVolume{vol},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	// The secrets of the volume aren't returned
+	get := &structs.CSIVolumeGetRequest{
+		ID:           vol.ID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var getResp structs.CSIVolumeGetResponse
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Get", get, &getResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if getResp.Volume == nil || getResp.Volume.ExternalID != vol.ExternalID {
+		t.Fatalf("bad: %#v", getResp.Volume)
+	}
+	if getResp.Volume.Secrets != nil {
+		t.Fatalf("secrets returned: %#v", getResp.Volume.Secrets)
+	}
+
+	// The volume isn't visible from other namespaces
+	get.Namespace = "other"
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Get", get, &getResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if getResp.Volume != nil {
+		t.Fatalf("bad: %#v", getResp.Volume)
+	}
+
+	// The volumes are listed by plugin
+	list := &structs.CSIVolumeListRequest{
+		PluginID:     "ebs",
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var listResp structs.CSIVolumeListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.List", list, &listResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(listResp.Volumes) != 1 || listResp.Volumes[0].ID != vol.ID {
+		t.Fatalf("bad: %#v", listResp.Volumes)
+	}
+	list.PluginID = "other"
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.List", list, &listResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(listResp.Volumes) != 0 {
+		t.Fatalf("bad: %#v", listResp.Volumes)
+	}
+
+	// Volumes in use are only deregistered if forced
+	claim := &structs.CSIVolumeClaim{AllocID: "a1", NodeID: "n1", Mode: structs.CSIVolumeClaimRead}
+	if err := s1.fsm.State().CSIVolumeClaim(2000, vol.ID, claim); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	dereg := &structs.CSIVolumeDeregisterRequest{
+		VolumeIDs:    []string{vol.ID},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Deregister", dereg, &resp)
+	if err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Fatalf("expected error: %v", err)
+	}
+	dereg.Force = true
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Deregister", dereg, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := s1.fsm.State().CSIVolumeByID(vol.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("volume not deregistered: %#v", out)
+	}
+}
+
+func TestCSIVolumeEndpoint_Register_ACL(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Anonymous requests are denied
+	req := &structs.CSIVolumeRegisterRequest{
+		Volumes:      []*structs.CSIVolume{mock.CSIVolume()},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Register", req, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	req.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCSIVolumeEndpoint_Create(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Without a controller the volume can't be created
+	vol := mock.CSIVolume()
+	vol.ExternalID = ""
+	vol.RequestedCapacityMin = 1 << 30
+	req := &structs.CSIVolumeCreateRequest{
+		Volumes:      []*structs.CSIVolume{vol},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.CSIVolumeCreateResponse
+	err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Create", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "no healthy controller") {
+		t.Fatalf("expected error: %v", err)
+	}
+
+	plugin := csi.NewTestPlugin()
+	node := mock.Node()
+	srv := testCSIControllerNode(t, node, plugin)
+	defer srv.Close()
+	if err := s1.fsm.State().UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Create", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Volumes) != 1 || resp.Volumes[0].ExternalID != "vol-"+vol.ID || resp.Volumes[0].Capacity != 1<<30 {
+		t.Fatalf("bad: %#v", resp.Volumes)
+	}
+
+	out, err := s1.fsm.State().CSIVolumeByID(vol.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.ExternalID != "vol-"+vol.ID || out.Context["name"] != vol.ID {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Existing volumes can't be created again
+	err = msgpackrpc.CallWithCodec(codec, "CSIVolume.Create", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected error: %v", err)
+	}
+}
+
+func TestCSIVolumeEndpoint_DeleteExpand(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	state := s1.fsm.State()
+	plugin := csi.NewTestPlugin()
+	node := mock.Node()
+	srv := testCSIControllerNode(t, node, plugin)
+	defer srv.Close()
+	if err := state.UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	vol := mock.CSIVolume()
+	vol.ExternalID = ""
+	vol.RequestedCapacityMin = 1 << 30
+	createReq := &structs.CSIVolumeCreateRequest{
+		Volumes:      []*structs.CSIVolume{vol},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var createResp structs.CSIVolumeCreateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Create", createReq, &createResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	externalID := createResp.Volumes[0].ExternalID
+
+	// Volumes can't be shrunk
+	expandReq := &structs.CSIVolumeExpandRequest{
+		VolumeID:             vol.ID,
+		RequestedCapacityMin: 1 << 20,
+		RequestedCapacityMax: 1 << 20,
+		WriteRequest:         structs.WriteRequest{Region: "global"},
+	}
+	var expandResp structs.CSIVolumeExpandResponse
+	err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Expand", expandReq, &expandResp)
+	if err == nil || !strings.Contains(err.Error(), "can't be shrunk") {
+		t.Fatalf("expected error: %v", err)
+	}
+
+	expandReq.RequestedCapacityMin = 2 << 30
+	expandReq.RequestedCapacityMax = 0
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Expand", expandReq, &expandResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if expandResp.Capacity != 2<<30 || plugin.Volume(externalID).CapacityBytes != 2<<30 {
+		t.Fatalf("bad: %#v", expandResp)
+	}
+	out, err := state.CSIVolumeByID(vol.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Capacity != 2<<30 || out.RequestedCapacityMin != 2<<30 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Claimed volumes are expanded by the node plugins of their nodes, when
+	// the controller requires it
+	claim := &structs.CSIVolumeClaim{
+		AllocID: structs.GenerateUUID(),
+		NodeID:  node.ID,
+		Mode:    structs.CSIVolumeClaimWrite,
+	}
+	if err := state.CSIVolumeClaim(1100, vol.ID, claim); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	plugin.NodeExpansionRequired = true
+	expandReq.RequestedCapacityMin = 3 << 30
+	err = msgpackrpc.CallWithCodec(codec, "CSIVolume.Expand", expandReq, &expandResp)
+	if err == nil || !strings.Contains(err.Error(), "not published at") {
+		t.Fatalf("expected node plugin error: %v", err)
+	}
+
+	// Claimed volumes can't be deleted
+	deleteReq := &structs.CSIVolumeDeleteRequest{
+		VolumeIDs:    []string{vol.ID},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var deleteResp structs.GenericResponse
+	err = msgpackrpc.CallWithCodec(codec, "CSIVolume.Delete", deleteReq, &deleteResp)
+	if err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Fatalf("expected error: %v", err)
+	}
+
+	claim.Mode = structs.CSIVolumeClaimRelease
+	if err := state.CSIVolumeClaim(1200, vol.ID, claim); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Delete", deleteReq, &deleteResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if plugin.Volume(externalID) != nil {
+		t.Fatalf("volume not deleted")
+	}
+	if out, err = state.CSIVolumeByID(vol.ID); err != nil || out != nil {
+		t.Fatalf("volume not deregistered: %#v %v", out, err)
+	}
+}
+
+func TestCSIVolumeEndpoint_Snapshots(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	state := s1.fsm.State()
+	plugin := csi.NewTestPlugin()
+	node := mock.Node()
+	srv := testCSIControllerNode(t, node, plugin)
+	defer srv.Close()
+	if err := state.UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	vol := mock.CSIVolume()
+	if err := state.UpsertCSIVolumes(1001, []*structs.CSIVolume{vol}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	createReq := &structs.CSISnapshotCreateRequest{
+		Snapshots: []*structs.CSISnapshot{
+			{SourceVolumeID: vol.ID, Name: "a"},
+			{SourceVolumeID: vol.ID, Name: "b"},
+		},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var createResp structs.CSISnapshotCreateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.CreateSnapshot", createReq, &createResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(createResp.Snapshots) != 2 {
+		t.Fatalf("bad: %#v", createResp.Snapshots)
+	}
+	if s := createResp.Snapshots[0]; s.ID != "snap-a" || s.SourceVolumeID != vol.ID ||
+		s.ExternalSourceVolumeID != vol.ExternalID || s.PluginID != "ebs" {
+		t.Fatalf("bad: %#v", s)
+	}
+
+	// Snapshots are listed a page at a time
+	listReq := &structs.CSISnapshotListRequest{
+		PluginID:     "ebs",
+		PerPage:      1,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var listResp structs.CSISnapshotListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.ListSnapshots", listReq, &listResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(listResp.Snapshots) != 1 || listResp.Snapshots[0].ID != "snap-a" ||
+		listResp.Snapshots[0].SourceVolumeID != vol.ID || listResp.NextToken == "" {
+		t.Fatalf("bad: %#v", listResp)
+	}
+	listReq.NextToken = listResp.NextToken
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.ListSnapshots", listReq, &listResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(listResp.Snapshots) != 1 || listResp.Snapshots[0].ID != "snap-b" || listResp.NextToken != "" {
+		t.Fatalf("bad: %#v", listResp)
+	}
+
+	deleteReq := &structs.CSISnapshotDeleteRequest{
+		Snapshots:    []*structs.CSISnapshot{{ID: "snap-a", PluginID: "ebs"}},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var deleteResp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.DeleteSnapshot", deleteReq, &deleteResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	listReq.PerPage = 0
+	listReq.NextToken = ""
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.ListSnapshots", listReq, &listResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(listResp.Snapshots) != 1 || listResp.Snapshots[0].ID != "snap-b" {
+		t.Fatalf("bad: %#v", listResp.Snapshots)
+	}
+}
+
+func TestCSIVolumeEndpoint_Claim(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the node running the plugin and two allocations on it
+	state := s1.fsm.State()
+	plugin := csi.NewTestPlugin()
+	node := mock.Node()
+	srv := testCSIControllerNode(t, node, plugin)
+	defer srv.Close()
+	a1 := mock.Alloc()
+	a1.NodeID = node.ID
+	a2 := mock.Alloc()
+	a2.NodeID = node.ID
+	vol := mock.CSIVolume()
+	if err := state.UpsertNode(998, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJobSummary(999, mock.JobSummary(a1.JobID)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJobSummary(999, mock.JobSummary(a2.JobID)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertAllocs(1000, []*structs.Allocation{a1, a2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertCSIVolumes(1001, []*structs.CSIVolume{vol}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.CSIVolumeClaimRequest{
+		VolumeID:       vol.ID,
+		AllocationID:   a1.ID,
+		Mode:           structs.CSIVolumeClaimWrite,
+		ExternalNodeID: "test-node",
+		NodeID:         node.ID,
+		SecretID:       structs.GenerateUUID(),
+		WriteRequest:   structs.WriteRequest{Region: "global"},
+	}
+
+	// The node's SecretID is required
+	var resp structs.CSIVolumeClaimResponse
+	err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Claim", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "SecretID mismatch") {
+		t.Fatalf("expected SecretID mismatch: %v", err)
+	}
+
+	// The volume is published to the node by the controller
+	req.SecretID = node.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Claim", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.PublishContext["device"] != "/dev/"+vol.ExternalID {
+		t.Fatalf("bad publish context: %#v", resp.PublishContext)
+	}
+	if plugin.Published(vol.ExternalID) != "test-node" {
+		t.Fatalf("volume not published")
+	}
+	if resp.Volume == nil || resp.Volume.Writers() != 1 {
+		t.Fatalf("bad: %#v", resp.Volume)
+	}
+
+	// The access mode of the volume only allows a single writer
+	req.AllocationID = a2.ID
+	err = msgpackrpc.CallWithCodec(codec, "CSIVolume.Claim", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "already has a writer") {
+		t.Fatalf("expected error: %v", err)
+	}
+
+	// Reading is allowed on the same node
+	req.Mode = structs.CSIVolumeClaimRead
+	var readResp structs.CSIVolumeClaimResponse
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Claim", req, &readResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if readResp.Volume == nil || readResp.Volume.Readers() != 1 {
+		t.Fatalf("bad: %#v", readResp.Volume)
+	}
+
+	// The volume stays published while another allocation of the node
+	// claims it
+	req.AllocationID = a1.ID
+	req.Mode = structs.CSIVolumeClaimRelease
+	var releaseResp structs.CSIVolumeClaimResponse
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Claim", req, &releaseResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if plugin.Published(vol.ExternalID) != "test-node" {
+		t.Fatalf("volume unpublished")
+	}
+
+	req.AllocationID = a2.ID
+	if err := msgpackrpc.CallWithCodec(codec, "CSIVolume.Claim", req, &releaseResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if plugin.Published(vol.ExternalID) != "" {
+		t.Fatalf("volume not unpublished")
+	}
+	out, err := state.CSIVolumeByID(vol.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out.Claims) != 0 {
+		t.Fatalf("claims not released: %#v", out.Claims)
+	}
+}
+
+func TestCSIPluginEndpoint_ListGet(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	node := mock.Node()
+	srv := testCSIControllerNode(t, node, csi.NewTestPlugin())
+	defer srv.Close()
+	node2 := mock.Node()
+	node2.CSINodePlugins = map[string]*structs.CSIInfo{
+		"ebs": &structs.CSIInfo{PluginID: "ebs", Healthy: false},
+	}
+	if err := s1.fsm.State().UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := s1.fsm.State().UpsertNode(1001, node2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	list := &structs.CSIPluginListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var listResp structs.CSIPluginListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "CSIPlugin.List", list, &listResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(listResp.Plugins) != 1 {
+		t.Fatalf("bad: %#v", listResp.Plugins)
+	}
+	stub := listResp.Plugins[0]
+	if stub.ID != "ebs" || stub.Provider != "test" || !stub.ControllerRequired ||
+		stub.Controllers != 1 || stub.ControllersHealthy != 1 || stub.Nodes != 2 || stub.NodesHealthy != 1 {
+		t.Fatalf("bad: %#v", stub)
+	}
+
+	get := &structs.CSIPluginGetRequest{
+		ID:           "ebs",
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var getResp structs.CSIPluginGetResponse
+	if err := msgpackrpc.CallWithCodec(codec, "CSIPlugin.Get", get, &getResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if getResp.Plugin == nil || getResp.Plugin.Nodes[node2.ID] == nil || getResp.Plugin.Controllers[node.ID] == nil {
+		t.Fatalf("bad: %#v", getResp.Plugin)
+	}
+
+	get.ID = "other"
+	if err := msgpackrpc.CallWithCodec(codec, "CSIPlugin.Get", get, &getResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if getResp.Plugin != nil {
+		t.Fatalf("bad: %#v", getResp.Plugin)
+	}
+}
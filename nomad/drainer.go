@@ -0,0 +1,280 @@
+package nomad
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// watchNodeDrains periodically advances the drains of the nodes. The
+// allocations of a draining node are migrated wave by wave in the drain
+// order, each task group migrating as many allocations at a time as its
+// migrate strategy allows. Once the deadline of a drain passes the remaining
+// allocations are all migrated, and once the node is empty the drain
+// completes and the node is eligible for scheduling again.
+func (s *Server) watchNodeDrains(stopCh chan struct{}) {
+	ticker := time.NewTicker(s.config.NodeDrainInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			s.reconcileNodeDrains(time.Now())
+		}
+	}
+}
+
+// reconcileNodeDrains advances the drain of each draining node as of the
+// given time
+func (s *Server) reconcileNodeDrains(now time.Time) {
+	snap, err := s.fsm.State().Snapshot()
+	if err != nil {
+		s.logger.Printf("[ERR] nomad: failed to snapshot state: %v", err)
+		return
+	}
+	iter, err := snap.Nodes()
+	if err != nil {
+		s.logger.Printf("[ERR] nomad: failed to list nodes: %v", err)
+		return
+	}
+
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		node := raw.(*structs.Node)
+		if node.DrainStrategy == nil {
+			continue
+		}
+		if err := s.advanceNodeDrain(snap, node, now); err != nil {
+			// Retry on the next check
+			s.logger.Printf("[ERR] nomad: failed to advance drain of node %q: %v", node.ID, err)
+		}
+	}
+}
+
+// advanceNodeDrain marks the next allocations of the draining node to be
+// migrated, or completes the drain once no allocation is left to migrate
+func (s *Server) advanceNodeDrain(snap *state.StateSnapshot, node *structs.Node, now time.Time) error {
+	allocs, err := snap.AllocsByNode(node.ID)
+	if err != nil {
+		return fmt.Errorf("failed to find allocs for '%s': %v", node.ID, err)
+	}
+
+	// Find the allocations left to migrate
+	drain := node.DrainStrategy
+	var remaining []*structs.Allocation
+	for _, alloc := range allocs {
+		if alloc.TerminalStatus() || alloc.Job == nil {
+			continue
+		}
+		if drain.IgnoreSystemJobs && alloc.Job.Type == structs.JobTypeSystem {
+			continue
+		}
+		remaining = append(remaining, alloc)
+	}
+	if len(remaining) == 0 {
+		return s.completeNodeDrain(node)
+	}
+
+	var migrate []*structs.Allocation
+	if infinite, deadline := drain.DeadlineTime(); !infinite && !now.Before(deadline) {
+		// Past the deadline, every remaining allocation is migrated
+		for _, alloc := range remaining {
+			if !alloc.DesiredTransition.ShouldMigrate() {
+				migrate = append(migrate, alloc)
+			}
+		}
+	} else {
+		migrate, err = nextDrainMigrations(snap, remaining, now)
+		if err != nil {
+			return err
+		}
+	}
+	if len(migrate) == 0 {
+		return nil
+	}
+	return s.migrateAllocs(node, migrate)
+}
+
+// nextDrainMigrations returns the allocations of a draining node to migrate
+// next, given the allocations left to migrate. Only the jobs of the earliest
+// drain wave are migrated, and each of their task groups has at most the max
+// parallel of its migrate strategy allocations migrating or unhealthy at a
+// time. The allocations of system jobs can't be replaced elsewhere, so they
+// are all migrated at once.
+func nextDrainMigrations(snap *state.StateSnapshot, remaining []*structs.Allocation,
+	now time.Time) ([]*structs.Allocation, error) {
+	jobs := make(map[structs.NamespacedID]*structs.Job)
+	for _, alloc := range remaining {
+		id := structs.NewNamespacedID(alloc.JobID, alloc.Namespace)
+		if _, ok := jobs[id]; !ok {
+			jobs[id] = alloc.Job
+		}
+	}
+	order := drainOrder(jobs)
+	wave := make(map[structs.NamespacedID]struct{})
+	for _, entry := range order {
+		if entry.Rank == order[0].Rank {
+			wave[structs.NewNamespacedID(entry.JobID, entry.Namespace)] = struct{}{}
+		}
+	}
+
+	// Group the unmarked allocations of the wave by job and task group
+	type groupKey struct {
+		job       structs.NamespacedID
+		taskGroup string
+	}
+	unmarked := make(map[groupKey][]*structs.Allocation)
+	for _, alloc := range remaining {
+		id := structs.NewNamespacedID(alloc.JobID, alloc.Namespace)
+		if _, ok := wave[id]; !ok || alloc.DesiredTransition.ShouldMigrate() {
+			continue
+		}
+		key := groupKey{id, alloc.TaskGroup}
+		unmarked[key] = append(unmarked[key], alloc)
+	}
+
+	var migrate []*structs.Allocation
+	for key, allocs := range unmarked {
+		sort.Sort(allocsByName(allocs))
+
+		// Prefer the latest version of the job for its migrate strategy
+		job, err := snap.JobByID(key.job.Namespace, key.job.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup job %q: %v", key.job, err)
+		}
+		if job == nil {
+			job = allocs[0].Job
+		}
+		if job.Type == structs.JobTypeSystem {
+			migrate = append(migrate, allocs...)
+			continue
+		}
+
+		strategy := structs.DefaultMigrateStrategy()
+		if tg := job.LookupTaskGroup(key.taskGroup); tg != nil && tg.Migrate != nil {
+			strategy = tg.Migrate
+		}
+
+		// Count the allocations of the group migrating or not yet healthy,
+		// across all of the nodes
+		jobAllocs, err := snap.AllocsByJob(key.job.Namespace, key.job.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find allocs of job %q: %v", key.job, err)
+		}
+		inFlight := 0
+		for _, alloc := range jobAllocs {
+			if alloc.TaskGroup != key.taskGroup || alloc.TerminalStatus() {
+				continue
+			}
+			if alloc.DesiredTransition.ShouldMigrate() || !migrationHealthy(alloc, strategy.MinHealthyTime, now) {
+				inFlight++
+			}
+		}
+
+		n := strategy.MaxParallel - inFlight
+		if n <= 0 {
+			continue
+		}
+		if n > len(allocs) {
+			n = len(allocs)
+		}
+		migrate = append(migrate, allocs[:n]...)
+	}
+	return migrate, nil
+}
+
+// migrationHealthy returns whether the allocation has had its tasks running
+// for at least the given time, so that another allocation of its task group
+// may be migrated
+func migrationHealthy(alloc *structs.Allocation, minHealthyTime time.Duration, now time.Time) bool {
+	if alloc.ClientStatus != structs.AllocClientStatusRunning {
+		return false
+	}
+	for _, state := range alloc.TaskStates {
+		if state.Failed() {
+			return false
+		}
+		if state.State != structs.TaskStateRunning {
+			continue
+		}
+
+		var started int64
+		for _, event := range state.Events {
+			if event.Type == structs.TaskStarted {
+				started = event.Time
+			}
+		}
+		if started == 0 || now.Sub(time.Unix(0, started)) < minHealthyTime {
+			return false
+		}
+	}
+	return true
+}
+
+// migrateAllocs marks the allocations of the draining node to be migrated
+// and creates the evaluations of their jobs to migrate them
+func (s *Server) migrateAllocs(node *structs.Node, allocs []*structs.Allocation) error {
+	req := &structs.AllocUpdateDesiredTransitionRequest{
+		Allocs:       make(map[string]*structs.DesiredTransition, len(allocs)),
+		WriteRequest: structs.WriteRequest{Region: s.config.Region},
+	}
+
+	jobs := make(map[structs.NamespacedID]struct{})
+	for _, alloc := range allocs {
+		req.Allocs[alloc.ID] = &structs.DesiredTransition{Migrate: true}
+		id := structs.NewNamespacedID(alloc.JobID, alloc.Namespace)
+		if _, ok := jobs[id]; ok {
+			continue
+		}
+		jobs[id] = struct{}{}
+		req.Evals = append(req.Evals, &structs.Evaluation{
+			ID:              structs.GenerateUUID(),
+			Namespace:       alloc.Namespace,
+			Priority:        alloc.Job.Priority,
+			Type:            alloc.Job.Type,
+			TriggeredBy:     structs.EvalTriggerNodeDrain,
+			JobID:           alloc.JobID,
+			NodeID:          node.ID,
+			NodeModifyIndex: node.ModifyIndex,
+			Status:          structs.EvalStatusPending,
+		})
+	}
+
+	s.logger.Printf("[DEBUG] nomad: migrating %d allocation(s) off of draining node %q", len(allocs), node.ID)
+	_, _, err := s.raftApply(structs.AllocUpdateDesiredTransitionRequestType, req)
+	return err
+}
+
+// completeNodeDrain stops the drain of a node that has no allocation left to
+// migrate, so that it is eligible for scheduling again
+func (s *Server) completeNodeDrain(node *structs.Node) error {
+	req := &structs.NodeUpdateDrainRequest{
+		NodeID:       node.ID,
+		NodeEvent:    structs.NewNodeEvent(structs.NodeEventSubsystemDrain, "Node drain complete"),
+		WriteRequest: structs.WriteRequest{Region: s.config.Region},
+	}
+	_, index, err := s.raftApply(structs.NodeUpdateDrainRequestType, req)
+	if err != nil {
+		return err
+	}
+	s.logger.Printf("[INFO] nomad: drain of node %q complete", node.ID)
+
+	// Evaluate the node so that system jobs and blocked evaluations may use
+	// it again
+	if _, _, err := s.endpoints.Node.createNodeEvals(node.ID, index); err != nil {
+		return fmt.Errorf("failed to create evaluations for node %q: %v", node.ID, err)
+	}
+	return nil
+}
+
+// allocsByName sorts allocations by name, so that the allocations of a task
+// group are migrated in a stable order
+type allocsByName []*structs.Allocation
+
+func (a allocsByName) Len() int           { return len(a) }
+func (a allocsByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a allocsByName) Less(i, j int) bool { return a[i].Name < a[j].Name }
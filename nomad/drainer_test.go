@@ -0,0 +1,262 @@
+package nomad
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// drainTestAlloc returns an allocation of the job on the node whose task has
+// been running since the given time
+func drainTestAlloc(job *structs.Job, nodeID string, idx int, started time.Time) *structs.Allocation {
+	alloc := mock.Alloc()
+	alloc.Job = job
+	alloc.JobID = job.ID
+	alloc.NodeID = nodeID
+	alloc.Name = fmt.Sprintf("%s.web[%d]", job.Name, idx)
+	alloc.ClientStatus = structs.AllocClientStatusRunning
+	alloc.TaskStates = map[string]*structs.TaskState{
+		"web": &structs.TaskState{
+			State: structs.TaskStateRunning,
+			Events: []*structs.TaskEvent{
+				{Type: structs.TaskStarted, Time: started.UnixNano()},
+			},
+		},
+	}
+	return alloc
+}
+
+// markedAllocs returns the allocations of the job marked to be migrated
+func markedAllocs(t *testing.T, s *Server, jobID string) []*structs.Allocation {
+	allocs, err := s.fsm.State().AllocsByJob(structs.DefaultNamespace, jobID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var out []*structs.Allocation
+	for _, alloc := range allocs {
+		if alloc.DesiredTransition.ShouldMigrate() {
+			out = append(out, alloc)
+		}
+	}
+	return out
+}
+
+func TestServer_ReconcileNodeDrains_MaxParallel(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0
+		c.NodeDrainInterval = time.Hour
+	})
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	state := s1.fsm.State()
+	node := mock.Node()
+	other := mock.Node()
+	if err := state.UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertNode(1001, other); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Run three healthy allocations of a job migrating one at a time
+	now := time.Now()
+	job := mock.Job()
+	job.TaskGroups[0].Count = 3
+	job.TaskGroups[0].Migrate = &structs.MigrateStrategy{
+		MaxParallel:    1,
+		MinHealthyTime: time.Minute,
+	}
+	if err := state.UpsertJob(1002, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var allocs []*structs.Allocation
+	for i := 0; i < 3; i++ {
+		allocs = append(allocs, drainTestAlloc(job, node.ID, i, now.Add(-time.Hour)))
+	}
+	if err := state.UpsertAllocs(1003, allocs); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	drain := structs.NewDrainStrategy(structs.DrainSpec{}, now)
+	if err := state.UpdateNodeDrain(1004, node.ID, drain, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A single allocation is migrated
+	s1.reconcileNodeDrains(now)
+	marked := markedAllocs(t, s1, job.ID)
+	if len(marked) != 1 || marked[0].ID != allocs[0].ID {
+		t.Fatalf("bad: %#v", marked)
+	}
+	evals, err := state.EvalsByJob(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(evals) != 1 || evals[0].NodeID != node.ID || evals[0].TriggeredBy != structs.EvalTriggerNodeDrain {
+		t.Fatalf("bad: %#v", evals)
+	}
+
+	// Nothing more is migrated while it is in flight
+	s1.reconcileNodeDrains(now)
+	if marked := markedAllocs(t, s1, job.ID); len(marked) != 1 {
+		t.Fatalf("bad: %#v", marked)
+	}
+
+	// Nor while its replacement is not yet healthy
+	stopped := marked[0].Copy()
+	stopped.DesiredStatus = structs.AllocDesiredStatusStop
+	replacement := drainTestAlloc(job, other.ID, 0, now)
+	if err := state.UpsertAllocs(1005, []*structs.Allocation{stopped, replacement}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	s1.reconcileNodeDrains(now.Add(30 * time.Second))
+	if marked := markedAllocs(t, s1, job.ID); len(marked) != 1 {
+		t.Fatalf("bad: %#v", marked)
+	}
+
+	// The next allocation is migrated once the replacement is healthy
+	s1.reconcileNodeDrains(now.Add(2 * time.Minute))
+	marked = markedAllocs(t, s1, job.ID)
+	if len(marked) != 2 {
+		t.Fatalf("bad: %#v", marked)
+	}
+
+	out, err := state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.DrainStrategy == nil || out.SchedulingEligible() {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestServer_ReconcileNodeDrains_Waves(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0
+		c.NodeDrainInterval = time.Hour
+	})
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	state := s1.fsm.State()
+	node := mock.Node()
+	if err := state.UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Run a batch, a service and a system job on the node
+	now := time.Now()
+	batch := mock.Job()
+	batch.Type = structs.JobTypeBatch
+	service := mock.Job()
+	system := mock.SystemJob()
+	var allocs []*structs.Allocation
+	for i, job := range []*structs.Job{batch, service, system} {
+		if err := state.UpsertJob(uint64(1001+i), job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		allocs = append(allocs, drainTestAlloc(job, node.ID, 0, now.Add(-time.Hour)))
+	}
+	if err := state.UpsertAllocs(1004, allocs); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	drain := structs.NewDrainStrategy(structs.DrainSpec{IgnoreSystemJobs: true}, now)
+	if err := state.UpdateNodeDrain(1005, node.ID, drain, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The batch job is migrated first
+	s1.reconcileNodeDrains(now)
+	if marked := markedAllocs(t, s1, batch.ID); len(marked) != 1 {
+		t.Fatalf("bad: %#v", marked)
+	}
+	if marked := markedAllocs(t, s1, service.ID); len(marked) != 0 {
+		t.Fatalf("bad: %#v", marked)
+	}
+
+	// The service job follows once the batch allocation stopped
+	stopped := allocs[0].Copy()
+	stopped.DesiredStatus = structs.AllocDesiredStatusStop
+	if err := state.UpsertAllocs(1006, []*structs.Allocation{stopped}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	s1.reconcileNodeDrains(now)
+	if marked := markedAllocs(t, s1, service.ID); len(marked) != 1 {
+		t.Fatalf("bad: %#v", marked)
+	}
+
+	// The drain completes once the service allocation stopped, leaving the
+	// system job running
+	stopped = allocs[1].Copy()
+	stopped.DesiredStatus = structs.AllocDesiredStatusStop
+	if err := state.UpsertAllocs(1007, []*structs.Allocation{stopped}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	s1.reconcileNodeDrains(now)
+	if marked := markedAllocs(t, s1, system.ID); len(marked) != 0 {
+		t.Fatalf("bad: %#v", marked)
+	}
+
+	out, err := state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Drain || out.DrainStrategy != nil || !out.SchedulingEligible() {
+		t.Fatalf("bad: %#v", out)
+	}
+	if l := len(out.Events); l == 0 || out.Events[l-1].Message != "Node drain complete" {
+		t.Fatalf("bad: %#v", out.Events)
+	}
+}
+
+func TestServer_ReconcileNodeDrains_Deadline(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0
+		c.NodeDrainInterval = time.Hour
+	})
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	state := s1.fsm.State()
+	node := mock.Node()
+	if err := state.UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	now := time.Now()
+	job := mock.Job()
+	job.TaskGroups[0].Count = 3
+	if err := state.UpsertJob(1001, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var allocs []*structs.Allocation
+	for i := 0; i < 3; i++ {
+		allocs = append(allocs, drainTestAlloc(job, node.ID, i, now.Add(-time.Hour)))
+	}
+	if err := state.UpsertAllocs(1002, allocs); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	drain := structs.NewDrainStrategy(structs.DrainSpec{Deadline: time.Hour}, now)
+	if err := state.UpdateNodeDrain(1003, node.ID, drain, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Before the deadline, the default migrate strategy applies
+	s1.reconcileNodeDrains(now)
+	if marked := markedAllocs(t, s1, job.ID); len(marked) != 1 {
+		t.Fatalf("bad: %#v", marked)
+	}
+
+	// Past the deadline, all of the allocations are migrated
+	s1.reconcileNodeDrains(now.Add(time.Hour))
+	if marked := markedAllocs(t, s1, job.ID); len(marked) != 3 {
+		t.Fatalf("bad: %#v", marked)
+	}
+}
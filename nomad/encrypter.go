@@ -0,0 +1,312 @@
+package nomad
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// rootKeyBytes is the size of the AES-256 root keys of the keyring
+	rootKeyBytes = 32
+
+	// rekeyBatchSize is the number of variables re-encrypted per Raft
+	// request when the root key is rotated
+	rekeyBatchSize = 64
+)
+
+// errNoKeyringEncryptionKey is returned when the root keys can't be wrapped
+// or unwrapped as the server has no keyring encryption key
+var errNoKeyringEncryptionKey = errors.New("keyring encryption key is not configured")
+
+// generateKeyBytes returns the material of a new AES-256 key
+func generateKeyBytes() ([]byte, error) {
+	key := make([]byte, rootKeyBytes)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+	return key, nil
+}
+
+// generateRootKey generates a new key for the keyring, wrapped with the
+// keyring encryption key
+func generateRootKey(kek []byte) (*structs.RootKey, error) {
+	material, err := generateKeyBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &structs.RootKey{
+		KeyID:      structs.GenerateUUID(),
+		Algorithm:  structs.RootKeyAlgorithm,
+		CreateTime: time.Now().UTC().UnixNano(),
+	}
+	key.WrappedKey, err = wrapRootKey(kek, key.KeyID, material)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// newKEKAEAD returns the authenticated cipher of the keyring encryption key
+func newKEKAEAD(kek []byte) (cipher.AEAD, error) {
+	if len(kek) == 0 {
+		return nil, errNoKeyringEncryptionKey
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// wrapRootKey encrypts the material of a root key with the keyring
+// encryption key. The key ID is authenticated along with it, so that the
+// material of a key can't be moved to another. The random nonce is prepended
+// to the ciphertext.
+func wrapRootKey(kek []byte, keyID string, material []byte) ([]byte, error) {
+	aead, err := newKEKAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	return aead.Seal(nonce, nonce, material, []byte(keyID)), nil
+}
+
+// unwrapRootKey decrypts the material of a root key with the keyring
+// encryption key
+func unwrapRootKey(kek []byte, key *structs.RootKey) ([]byte, error) {
+	aead, err := newKEKAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(key.WrappedKey) < aead.NonceSize() {
+		return nil, fmt.Errorf("root key %q is malformed", key.KeyID)
+	}
+	nonce, ciphertext := key.WrappedKey[:aead.NonceSize()], key.WrappedKey[aead.NonceSize():]
+	material, err := aead.Open(nil, nonce, ciphertext, []byte(key.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap root key %q: %v", key.KeyID, err)
+	}
+	return material, nil
+}
+
+// activeRootKey returns the key new variables are encrypted with. If the
+// keyring is empty, a key is generated and committed via Raft, so this must
+// only be called by the leader.
+func (s *Server) activeRootKey() (*structs.RootKey, error) {
+	s.rootKeyLock.Lock()
+	defer s.rootKeyLock.Unlock()
+
+	key, err := s.fsm.State().ActiveRootKey()
+	if err != nil {
+		return nil, err
+	}
+	if key != nil {
+		return key, nil
+	}
+	return s.newRootKey()
+}
+
+// rotateRootKey generates a new root key, which becomes the active key, and
+// re-encrypts the existing variables with it. The previous keys are kept in
+// the keyring. It returns the new key and the number of variables that were
+// re-encrypted, and must only be called by the leader.
+func (s *Server) rotateRootKey() (*structs.RootKey, int, error) {
+	s.rootKeyLock.Lock()
+	defer s.rootKeyLock.Unlock()
+
+	key, err := s.newRootKey()
+	if err != nil {
+		return nil, 0, err
+	}
+	rekeyed, err := s.rekeyVariables(key)
+	if err != nil {
+		return key, rekeyed, fmt.Errorf("failed to re-encrypt variables with root key %q: %v", key.KeyID, err)
+	}
+	return key, rekeyed, nil
+}
+
+// newRootKey generates a key and commits it to the keyring via Raft, which
+// replicates it to all the servers of the region. The caller must hold the
+// root key lock.
+func (s *Server) newRootKey() (*structs.RootKey, error) {
+	key, err := generateRootKey(s.config.KeyringEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	req := structs.RootKeyUpsertRequest{
+		Keys:         []*structs.RootKey{key},
+		WriteRequest: structs.WriteRequest{Region: s.config.Region},
+	}
+	resp, _, err := s.raftApply(structs.RootKeyUpsertRequestType, &req)
+	if err != nil {
+		return nil, err
+	}
+	if err, ok := resp.(error); ok && err != nil {
+		return nil, err
+	}
+	s.logger.Printf("[INFO] nomad: generated root key %q", key.KeyID)
+	return key, nil
+}
+
+// rekeyVariables re-encrypts the variables that aren't encrypted with the
+// given key, committing them in batches via Raft. Variables written while
+// they are re-encrypted keep the data of the write.
+func (s *Server) rekeyVariables(key *structs.RootKey) (int, error) {
+	snap, err := s.fsm.State().Snapshot()
+	if err != nil {
+		return 0, err
+	}
+	iter, err := snap.Variables()
+	if err != nil {
+		return 0, err
+	}
+
+	rekeyed := 0
+	var batch []*structs.VariableEncrypted
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		req := structs.VariablesRekeyRequest{
+			Variables:    batch,
+			WriteRequest: structs.WriteRequest{Region: s.config.Region},
+		}
+		resp, _, err := s.raftApply(structs.VariablesRekeyRequestType, &req)
+		if err != nil {
+			return err
+		}
+		if err, ok := resp.(error); ok && err != nil {
+			return err
+		}
+		rekeyed += len(batch)
+		batch = nil
+		return nil
+	}
+
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		variable := raw.(*structs.VariableEncrypted)
+		if variable.KeyID == key.KeyID {
+			continue
+		}
+
+		decrypted, err := decryptVariable(s.config.KeyringEncryptionKey, snap, variable)
+		if err != nil {
+			return rekeyed, err
+		}
+		encrypted, err := encryptVariable(s.config.KeyringEncryptionKey, key, decrypted)
+		if err != nil {
+			return rekeyed, err
+		}
+		batch = append(batch, encrypted)
+		if len(batch) == rekeyBatchSize {
+			if err := flush(); err != nil {
+				return rekeyed, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return rekeyed, err
+	}
+
+	s.logger.Printf("[INFO] nomad: re-encrypted %d variables with root key %q", rekeyed, key.KeyID)
+	return rekeyed, nil
+}
+
+// newAEAD returns the authenticated cipher of a root key, unwrapping it with
+// the keyring encryption key
+func newAEAD(kek []byte, key *structs.RootKey) (cipher.AEAD, error) {
+	if key.Algorithm != structs.RootKeyAlgorithm {
+		return nil, fmt.Errorf("root key %q has unsupported algorithm %q", key.KeyID, key.Algorithm)
+	}
+	material, err := unwrapRootKey(kek, key)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(material)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// variableAdditionalData binds the ciphertext of a variable to its namespace
+// and path, so that the data can't be moved to another variable
+func variableAdditionalData(meta *structs.VariableMetadata) []byte {
+	return []byte(meta.Namespace + "\x00" + meta.Path)
+}
+
+// encryptVariable encrypts the items of the variable with the root key. The
+// random nonce is prepended to the ciphertext.
+func encryptVariable(kek []byte, key *structs.RootKey, variable *structs.VariableDecrypted) (*structs.VariableEncrypted, error) {
+	aead, err := newAEAD(kek, key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(variable.Items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode variable items: %v", err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	return &structs.VariableEncrypted{
+		VariableMetadata: variable.VariableMetadata,
+		KeyID:            key.KeyID,
+		Data:             aead.Seal(nonce, nonce, plaintext, variableAdditionalData(&variable.VariableMetadata)),
+	}, nil
+}
+
+// decryptVariable decrypts the items of the variable with the root key it
+// was encrypted with
+func decryptVariable(kek []byte, snap *state.StateSnapshot, variable *structs.VariableEncrypted) (*structs.VariableDecrypted, error) {
+	key, err := snap.RootKeyByID(variable.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("root key %q of variable %q not found", variable.KeyID, variable.Path)
+	}
+
+	aead, err := newAEAD(kek, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(variable.Data) < aead.NonceSize() {
+		return nil, fmt.Errorf("variable %q has malformed data", variable.Path)
+	}
+	nonce, ciphertext := variable.Data[:aead.NonceSize()], variable.Data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, variableAdditionalData(&variable.VariableMetadata))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt variable %q: %v", variable.Path, err)
+	}
+
+	decrypted := &structs.VariableDecrypted{
+		VariableMetadata: variable.VariableMetadata,
+	}
+	if err := json.Unmarshal(plaintext, &decrypted.Items); err != nil {
+		return nil, fmt.Errorf("failed to decode items of variable %q: %v", variable.Path, err)
+	}
+	return decrypted, nil
+}
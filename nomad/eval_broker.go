@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"strconv"
 	"sync"
 	"time"
 
@@ -52,11 +53,12 @@ type EvalBroker struct {
 	// and is used to eventually fail an evaluation.
 	evals map[string]int
 
-	// jobEvals tracks queued evaluations by JobID to serialize them
-	jobEvals map[string]string
+	// jobEvals tracks queued evaluations by namespaced job ID to serialize them
+	jobEvals map[structs.NamespacedID]string
 
-	// blocked tracks the blocked evaluations by JobID in a priority queue
-	blocked map[string]PendingEvaluations
+	// blocked tracks the blocked evaluations by namespaced job ID in a
+	// priority queue
+	blocked map[structs.NamespacedID]PendingEvaluations
 
 	// ready tracks the ready jobs by scheduler in a priority queue
 	ready map[string]PendingEvaluations
@@ -76,6 +78,10 @@ type EvalBroker struct {
 	// timeWait has evaluations that are waiting for time to elapse
 	timeWait map[string]*time.Timer
 
+	// enqueued tracks when evaluations were first made available to be
+	// dequeued, to measure how long they wait in the broker
+	enqueued map[string]time.Time
+
 	l sync.RWMutex
 }
 
@@ -84,6 +90,7 @@ type unackEval struct {
 	Eval      *structs.Evaluation
 	Token     string
 	NackTimer *time.Timer
+	QueueWait time.Duration
 }
 
 // PendingEvaluations is a list of waiting evaluations.
@@ -105,13 +112,14 @@ func NewEvalBroker(timeout time.Duration, deliveryLimit int) (*EvalBroker, error
 		enabled:       false,
 		stats:         new(BrokerStats),
 		evals:         make(map[string]int),
-		jobEvals:      make(map[string]string),
-		blocked:       make(map[string]PendingEvaluations),
+		jobEvals:      make(map[structs.NamespacedID]string),
+		blocked:       make(map[structs.NamespacedID]PendingEvaluations),
 		ready:         make(map[string]PendingEvaluations),
 		unack:         make(map[string]*unackEval),
 		waiting:       make(map[string]chan struct{}),
 		requeue:       make(map[string]*structs.Evaluation),
 		timeWait:      make(map[string]*time.Timer),
+		enqueued:      make(map[string]time.Time),
 	}
 	b.stats.ByScheduler = make(map[string]*SchedulerStats)
 	return b, nil
@@ -214,14 +222,21 @@ func (b *EvalBroker) enqueueLocked(eval *structs.Evaluation, queue string) {
 		return
 	}
 
-	// Check if there is an evaluation for this JobID pending
-	pendingEval := b.jobEvals[eval.JobID]
+	// Track when the evaluation was first made available. Evaluations that
+	// are blocked behind another evaluation of the job keep waiting.
+	if _, ok := b.enqueued[eval.ID]; !ok {
+		b.enqueued[eval.ID] = time.Now()
+	}
+
+	// Check if there is an evaluation for this job pending
+	jobID := structs.NewNamespacedID(eval.JobID, eval.Namespace)
+	pendingEval := b.jobEvals[jobID]
 	if pendingEval == "" {
-		b.jobEvals[eval.JobID] = eval.ID
+		b.jobEvals[jobID] = eval.ID
 	} else if pendingEval != eval.ID {
-		blocked := b.blocked[eval.JobID]
+		blocked := b.blocked[jobID]
 		heap.Push(&blocked, eval)
-		b.blocked[eval.JobID] = blocked
+		b.blocked[jobID] = blocked
 		b.stats.TotalBlocked += 1
 		return
 	}
@@ -366,11 +381,19 @@ func (b *EvalBroker) dequeueForSched(sched string) (*structs.Evaluation, string,
 		b.Nack(eval.ID, token)
 	})
 
+	// Determine how long the evaluation waited to be dequeued
+	var wait time.Duration
+	if enqueued, ok := b.enqueued[eval.ID]; ok {
+		wait = time.Since(enqueued)
+		delete(b.enqueued, eval.ID)
+	}
+
 	// Add to the unack queue
 	b.unack[eval.ID] = &unackEval{
 		Eval:      eval,
 		Token:     token,
 		NackTimer: nackTimer,
+		QueueWait: wait,
 	}
 
 	// Increment the dequeue count
@@ -439,6 +462,18 @@ func (b *EvalBroker) Outstanding(evalID string) (string, bool) {
 	return unack.Token, true
 }
 
+// QueueWait returns how long an outstanding evaluation waited in the broker
+// before being dequeued.
+func (b *EvalBroker) QueueWait(evalID string) (time.Duration, bool) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+	unack, ok := b.unack[evalID]
+	if !ok {
+		return 0, false
+	}
+	return unack.QueueWait, true
+}
+
 // OutstandingReset resets the Nack timer for the EvalID if the
 // token matches and the eval is outstanding
 func (b *EvalBroker) OutstandingReset(evalID, token string) error {
@@ -474,7 +509,7 @@ func (b *EvalBroker) Ack(evalID, token string) error {
 	if unack.Token != token {
 		return fmt.Errorf("Token does not match for Evaluation ID")
 	}
-	jobID := unack.Eval.JobID
+	jobID := structs.NewNamespacedID(unack.Eval.JobID, unack.Eval.Namespace)
 
 	// Ensure we were able to stop the timer
 	if !unack.NackTimer.Stop() {
@@ -617,11 +652,12 @@ func (b *EvalBroker) Flush() {
 	b.stats.TotalWaiting = 0
 	b.stats.ByScheduler = make(map[string]*SchedulerStats)
 	b.evals = make(map[string]int)
-	b.jobEvals = make(map[string]string)
-	b.blocked = make(map[string]PendingEvaluations)
+	b.jobEvals = make(map[structs.NamespacedID]string)
+	b.blocked = make(map[structs.NamespacedID]PendingEvaluations)
 	b.ready = make(map[string]PendingEvaluations)
 	b.unack = make(map[string]*unackEval)
 	b.timeWait = make(map[string]*time.Timer)
+	b.enqueued = make(map[string]time.Time)
 }
 
 // Stats is used to query the state of the broker
@@ -641,6 +677,10 @@ func (b *EvalBroker) Stats() *BrokerStats {
 	for sched, subStat := range b.stats.ByScheduler {
 		subStatCopy := new(SchedulerStats)
 		*subStatCopy = *subStat
+		subStatCopy.ReadyByPriority = make(map[int]int)
+		for _, eval := range b.ready[sched] {
+			subStatCopy.ReadyByPriority[eval.Priority]++
+		}
 		stats.ByScheduler[sched] = subStatCopy
 	}
 	return stats
@@ -659,6 +699,9 @@ func (b *EvalBroker) EmitStats(period time.Duration, stopCh chan struct{}) {
 			for sched, schedStats := range stats.ByScheduler {
 				metrics.SetGauge([]string{"nomad", "broker", sched, "ready"}, float32(schedStats.Ready))
 				metrics.SetGauge([]string{"nomad", "broker", sched, "unacked"}, float32(schedStats.Unacked))
+				for priority, ready := range schedStats.ReadyByPriority {
+					metrics.SetGauge([]string{"nomad", "broker", sched, "priority", strconv.Itoa(priority), "ready"}, float32(ready))
+				}
 			}
 
 		case <-stopCh:
@@ -680,6 +723,9 @@ type BrokerStats struct {
 type SchedulerStats struct {
 	Ready   int
 	Unacked int
+
+	// ReadyByPriority is the number of ready evaluations by job priority
+	ReadyByPriority map[int]int
 }
 
 // Len is for the sorting interface
@@ -62,6 +62,9 @@ func TestEvalBroker_Enqueue_Dequeue_Nack_Ack(t *testing.T) {
 	if stats.ByScheduler[eval.Type].Ready != 1 {
 		t.Fatalf("bad: %#v", stats)
 	}
+	if stats.ByScheduler[eval.Type].ReadyByPriority[eval.Priority] != 1 {
+		t.Fatalf("bad: %#v", stats.ByScheduler[eval.Type])
+	}
 
 	// Dequeue should work
 	out, token, err := b.Dequeue(defaultSched, time.Second)
@@ -841,6 +844,56 @@ func TestEvalBroker_Wait(t *testing.T) {
 	}
 }
 
+func TestEvalBroker_QueueWait(t *testing.T) {
+	b := testBroker(t, 0)
+	b.SetEnabled(true)
+
+	// Enqueue two evals of the same job so the second is blocked
+	eval := mock.Eval()
+	eval2 := mock.Eval()
+	eval2.JobID = eval.JobID
+	b.Enqueue(eval)
+	b.Enqueue(eval2)
+
+	// Not outstanding yet
+	if _, ok := b.QueueWait(eval.ID); ok {
+		t.Fatalf("should not be outstanding")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	out, token, err := b.Dequeue(defaultSched, time.Second)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != eval {
+		t.Fatalf("bad : %#v", out)
+	}
+	wait, ok := b.QueueWait(eval.ID)
+	if !ok || wait < 10*time.Millisecond {
+		t.Fatalf("bad: %v %v", wait, ok)
+	}
+
+	// The blocked eval waits until the first is acked
+	time.Sleep(10 * time.Millisecond)
+	if err := b.Ack(eval.ID, token); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := b.QueueWait(eval.ID); ok {
+		t.Fatalf("should not be outstanding")
+	}
+
+	out, _, err = b.Dequeue(defaultSched, time.Second)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != eval2 {
+		t.Fatalf("bad : %#v", out)
+	}
+	if wait2, ok := b.QueueWait(eval2.ID); !ok || wait2 < 20*time.Millisecond {
+		t.Fatalf("bad: %v %v", wait2, ok)
+	}
+}
+
 // Ensure that priority is taken into account when enqueueing many evaluations.
 func TestEvalBroker_EnqueueAll_Dequeue_Fair(t *testing.T) {
 	b := testBroker(t, 0)
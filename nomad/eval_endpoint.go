@@ -6,6 +6,8 @@ import (
 
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/state"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/nomad/watch"
 )
@@ -28,22 +30,29 @@ func (e *Eval) GetEval(args *structs.EvalSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "eval", "get_eval"}, time.Now())
 
+	// Resolve the token, the namespace of the evaluation is checked once it is found
+	aclObj, err := e.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		watch:     watch.NewItems(watch.Item{Eval: args.EvalID}),
-		run: func() error {
+		run: func(snap *state.StateSnapshot) error {
 			// Look for the job
-			snap, err := e.srv.fsm.State().Snapshot()
-			if err != nil {
-				return err
-			}
 			out, err := snap.EvalByID(args.EvalID)
 			if err != nil {
 				return err
 			}
 
+			// Check namespace read-job permissions
+			if out != nil && aclObj != nil && !aclObj.AllowNamespaceOperation(out.Namespace, acl.NamespaceCapabilityReadJob) {
+				return structs.ErrPermissionDenied
+			}
+
 			// Setup the output
 			reply.Eval = out
 			if out != nil {
@@ -92,6 +101,7 @@ func (e *Eval) Dequeue(args *structs.EvalDequeueRequest,
 	if eval != nil {
 		reply.Eval = eval
 		reply.Token = token
+		reply.QueueWait, _ = e.srv.evalBroker.QueueWait(eval.ID)
 	}
 
 	// Set the query response
@@ -269,22 +279,41 @@ func (e *Eval) List(args *structs.EvalListRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "eval", "list"}, time.Now())
 
+	// Check namespace read-job permissions. Queries across all namespaces
+	// are filtered to the namespaces the token may read instead.
+	aclObj, err := e.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	namespace := args.RequestNamespace()
+	allNamespaces := namespace == structs.AllNamespacesSentinel
+	if !allNamespaces && aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityReadJob) {
+		return structs.ErrPermissionDenied
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		watch:     watch.NewItems(watch.Item{Table: "evals"}),
-		run: func() error {
+		run: func(snap *state.StateSnapshot) error {
 			// Scan all the evaluations
-			snap, err := e.srv.fsm.State().Snapshot()
-			if err != nil {
-				return err
+			var err error
+			var allowed map[string]bool
+			if allNamespaces {
+				allowed, err = allowedNamespaces(aclObj, snap, acl.NamespaceCapabilityReadJob)
+				if err != nil {
+					return err
+				}
 			}
+
 			var iter memdb.ResultIterator
 			if prefix := args.QueryOptions.Prefix; prefix != "" {
 				iter, err = snap.EvalsByIDPrefix(prefix)
-			} else {
+			} else if allNamespaces {
 				iter, err = snap.Evals()
+			} else {
+				iter, err = snap.EvalsByNamespace(namespace)
 			}
 			if err != nil {
 				return err
@@ -297,6 +326,9 @@ func (e *Eval) List(args *structs.EvalListRequest,
 					break
 				}
 				eval := raw.(*structs.Evaluation)
+				if !namespaceMatches(eval.Namespace, namespace, allowed) {
+					continue
+				}
 				evals = append(evals, eval)
 			}
 			reply.Evaluations = evals
@@ -323,28 +355,32 @@ func (e *Eval) Allocations(args *structs.EvalSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "eval", "allocations"}, time.Now())
 
+	// Resolve the token, the namespace of the allocations is checked once it is found
+	aclObj, err := e.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		watch:     watch.NewItems(watch.Item{AllocEval: args.EvalID}),
-		run: func() error {
+		run: func(snap *state.StateSnapshot) error {
 			// Capture the allocations
-			snap, err := e.srv.fsm.State().Snapshot()
-			if err != nil {
-				return err
-			}
 			allocs, err := snap.AllocsByEval(args.EvalID)
 			if err != nil {
 				return err
 			}
 
-			// Convert to a stub
-			if len(allocs) > 0 {
-				reply.Allocations = make([]*structs.AllocListStub, 0, len(allocs))
-				for _, alloc := range allocs {
-					reply.Allocations = append(reply.Allocations, alloc.Stub())
+			// Convert to a stub, skipping allocations in namespaces the
+			// token can not read
+			reply.Allocations = nil
+			for _, alloc := range allocs {
+				if aclObj != nil && !aclObj.AllowNamespaceOperation(alloc.Namespace, acl.NamespaceCapabilityReadJob) {
+					continue
 				}
+				reply.Allocations = append(reply.Allocations, alloc.Stub())
 			}
 
 			// Use the last index that affected the allocs table
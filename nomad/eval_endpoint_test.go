@@ -425,6 +425,44 @@ func TestEvalEndpoint_List(t *testing.T) {
 
 }
 
+func TestEvalEndpoint_List_AllNamespaces(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create evaluations in two namespaces
+	eval1 := mock.Eval()
+	eval2 := mock.Eval()
+	eval2.Namespace = "other"
+	s1.fsm.State().UpsertEvals(1000, []*structs.Evaluation{eval1, eval2})
+
+	// Lookup the evals of every namespace
+	get := &structs.EvalListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			Namespace: structs.AllNamespacesSentinel,
+		},
+	}
+	var resp structs.EvalListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Eval.List", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Evaluations) != 2 {
+		t.Fatalf("bad: %#v", resp.Evaluations)
+	}
+
+	// The default namespace only contains the first eval
+	get.Namespace = ""
+	var resp2 structs.EvalListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Eval.List", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Evaluations) != 1 || resp2.Evaluations[0].ID != eval1.ID {
+		t.Fatalf("bad: %#v", resp2.Evaluations)
+	}
+}
+
 func TestEvalEndpoint_List_Blocking(t *testing.T) {
 	s1 := testServer(t, nil)
 	defer s1.Shutdown()
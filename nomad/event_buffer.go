@@ -0,0 +1,102 @@
+package nomad
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// EventBuffer holds the events published by the most recent log entries
+// applied by the FSM, so that subscribers can follow the changes to the state
+// and resume from the index they last saw. The buffer is in memory only, so
+// each server has its own buffer of the entries it applied since it started.
+type EventBuffer struct {
+	size   int
+	events []*structs.Events
+
+	// oldest is the index the buffer holds every event published after.
+	// Events published at or before it may have been evicted.
+	oldest uint64
+
+	// notifyCh is closed when events are published, to wake up the
+	// subscribers waiting on them
+	notifyCh chan struct{}
+	l        sync.RWMutex
+}
+
+// NewEventBuffer returns a buffer keeping the events of up to size log
+// entries
+func NewEventBuffer(size int) *EventBuffer {
+	if size < 1 {
+		size = 1
+	}
+	return &EventBuffer{
+		size:     size,
+		events:   make([]*structs.Events, 0, size),
+		notifyCh: make(chan struct{}),
+	}
+}
+
+// Publish adds the events of a log entry to the buffer, evicting the events
+// of the oldest entry once the buffer is full
+func (b *EventBuffer) Publish(events *structs.Events) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if len(b.events) == b.size {
+		b.oldest = b.events[0].Index
+		copy(b.events, b.events[1:])
+		b.events = b.events[:b.size-1]
+	}
+	b.events = append(b.events, events)
+
+	close(b.notifyCh)
+	b.notifyCh = make(chan struct{})
+}
+
+// Reset drops the buffered events, as the state was replaced up to the index
+// without publishing the events of the changes
+func (b *EventBuffer) Reset(index uint64) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	b.events = b.events[:0]
+	b.oldest = index
+
+	close(b.notifyCh)
+	b.notifyCh = make(chan struct{})
+}
+
+// Since returns the buffered events published after the index, along with a
+// channel that is closed once more events are published. An index of 0
+// returns every buffered event. It returns an error if events published after
+// the index are no longer buffered, rather than skipping them.
+func (b *EventBuffer) Since(index uint64) ([]*structs.Events, <-chan struct{}, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	if index != 0 && index < b.oldest {
+		return nil, nil, fmt.Errorf("%v: index %d is older than index %d",
+			structs.ErrEventsEvicted, index, b.oldest)
+	}
+
+	var out []*structs.Events
+	for i := len(b.events) - 1; i >= 0 && b.events[i].Index > index; i-- {
+		out = append(out, b.events[i])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, b.notifyCh, nil
+}
+
+// Index returns the index of the last events published
+func (b *EventBuffer) Index() uint64 {
+	b.l.RLock()
+	defer b.l.RUnlock()
+	if len(b.events) == 0 {
+		return 0
+	}
+	return b.events[len(b.events)-1].Index
+}
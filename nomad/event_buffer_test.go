@@ -0,0 +1,72 @@
+package nomad
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestEventBuffer(t *testing.T) {
+	buffer := NewEventBuffer(2)
+	events, notifyCh, err := buffer.Since(0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(events) != 0 || buffer.Index() != 0 {
+		t.Fatalf("bad: %#v", events)
+	}
+
+	// Publishing wakes up the subscribers
+	buffer.Publish(&structs.Events{Index: 10})
+	select {
+	case <-notifyCh:
+	default:
+		t.Fatalf("expected notification")
+	}
+
+	// The oldest events are evicted once the buffer is full
+	buffer.Publish(&structs.Events{Index: 20})
+	buffer.Publish(&structs.Events{Index: 30})
+	events, _, err = buffer.Since(0)
+	if len(events) != 2 || events[0].Index != 20 || events[1].Index != 30 {
+		t.Fatalf("bad: %#v", events)
+	}
+	if buffer.Index() != 30 {
+		t.Fatalf("bad: %d", buffer.Index())
+	}
+
+	// Only the events after the index are returned
+	events, _, err = buffer.Since(25)
+	if len(events) != 1 || events[0].Index != 30 {
+		t.Fatalf("bad: %#v", events)
+	}
+	events, _, err = buffer.Since(30)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("bad: %#v %v", events, err)
+	}
+
+	// Resuming from before the evicted events fails rather than skip them
+	if _, _, err = buffer.Since(5); err == nil || !strings.Contains(err.Error(), structs.ErrEventsEvicted.Error()) {
+		t.Fatalf("expected evicted error: %v", err)
+	}
+	if _, _, err = buffer.Since(10); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Resetting drops the events and wakes up the subscribers
+	_, notifyCh, _ = buffer.Since(30)
+	buffer.Reset(50)
+	select {
+	case <-notifyCh:
+	default:
+		t.Fatalf("expected notification")
+	}
+	if _, _, err = buffer.Since(30); err == nil {
+		t.Fatalf("expected evicted error")
+	}
+	events, _, err = buffer.Since(50)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("bad: %#v %v", events, err)
+	}
+}
@@ -0,0 +1,106 @@
+package nomad
+
+import (
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// Event endpoint is used to follow the changes to the state
+type Event struct {
+	srv *Server
+}
+
+// List is used to list the events published after the MinQueryIndex that
+// match the topics of the request and that the token may read. The request
+// blocks until matching events are published or the query times out. The
+// returned index is the index of the last event examined, to resume from. An
+// error is returned if events published after the MinQueryIndex were evicted.
+func (e *Event) List(args *structs.EventListRequest,
+	reply *structs.EventListResponse) error {
+	if done, err := e.srv.forward("Event.List", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "event", "list"}, time.Now())
+
+	// Check for read-job permissions on the namespace. Events of jobs,
+	// allocations and evaluations across all namespaces are filtered to the
+	// namespaces the token may read instead, and node events to tokens that
+	// may read nodes.
+	aclObj, err := e.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	namespace := args.RequestNamespace()
+	allNamespaces := namespace == structs.AllNamespacesSentinel
+	if !allNamespaces && aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityReadJob) {
+		return structs.ErrPermissionDenied
+	}
+
+	allowed := func(event *structs.Event) bool {
+		if !args.Matches(event) {
+			return false
+		}
+		if event.Topic == structs.TopicNode {
+			return aclObj == nil || aclObj.AllowNodeRead()
+		}
+		ns := event.Namespace
+		if ns == "" {
+			ns = structs.DefaultNamespace
+		}
+		if !allNamespaces && ns != namespace {
+			return false
+		}
+		return aclObj == nil || aclObj.AllowNamespaceOperation(ns, acl.NamespaceCapabilityReadJob)
+	}
+
+	// Restrict the max query time, and ensure there is always one
+	if args.MaxQueryTime > maxQueryTime {
+		args.MaxQueryTime = maxQueryTime
+	} else if args.MaxQueryTime <= 0 {
+		args.MaxQueryTime = defaultQueryTime
+	}
+	args.MaxQueryTime += lib.RandomStagger(args.MaxQueryTime / jitterFraction)
+	timeout := time.NewTimer(args.MaxQueryTime)
+	defer timeout.Stop()
+
+	index := args.MinQueryIndex
+	for {
+		metrics.IncrCounter([]string{"nomad", "rpc", "query"}, 1)
+		published, notifyCh, err := e.srv.fsm.Events().Since(index)
+		if err != nil {
+			return err
+		}
+
+		var matched []*structs.Events
+		for _, events := range published {
+			index = events.Index
+
+			var filtered []*structs.Event
+			for _, event := range events.Events {
+				if allowed(event) {
+					filtered = append(filtered, event)
+				}
+			}
+			if len(filtered) != 0 {
+				matched = append(matched, &structs.Events{Index: events.Index, Events: filtered})
+			}
+		}
+
+		e.srv.setQueryMeta(&reply.QueryMeta)
+		reply.Events = matched
+		reply.Index = index
+		if len(matched) != 0 {
+			return nil
+		}
+
+		select {
+		case <-notifyCh:
+		case <-timeout.C:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package nomad
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestEventEndpoint_List(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Register a job
+	job := mock.Job()
+	reg := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var regResp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &regResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Only the events of the job are returned
+	req := &structs.EventListRequest{
+		Topics: map[structs.Topic][]string{
+			structs.TopicJob: []string{job.ID},
+		},
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.EventListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Event.List", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Events) != 1 || len(resp.Events[0].Events) != 1 {
+		t.Fatalf("bad: %#v", resp.Events)
+	}
+	event := resp.Events[0].Events[0]
+	if event.Type != structs.EventTypeJobRegistered || event.Key != job.ID || event.Payload.Job == nil {
+		t.Fatalf("bad: %#v", event)
+	}
+	if resp.Index < regResp.JobModifyIndex {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	// Resuming from the index blocks until the job is deregistered
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		dereg := &structs.JobDeregisterRequest{
+			JobID:        job.ID,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var deregResp structs.JobDeregisterResponse
+		msgpackrpc.CallWithCodec(rpcClient(t, s1), "Job.Deregister", dereg, &deregResp)
+	}()
+
+	req.MinQueryIndex = resp.Index
+	start := time.Now()
+	var resp2 structs.EventListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Event.List", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("should block (returned in %s)", elapsed)
+	}
+	if len(resp2.Events) != 1 || resp2.Events[0].Events[0].Type != structs.EventTypeJobDeregistered {
+		t.Fatalf("bad: %#v", resp2.Events)
+	}
+	if resp2.Index <= resp.Index {
+		t.Fatalf("bad index: %d", resp2.Index)
+	}
+}
+
+func TestEventEndpoint_List_Evicted(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Drop the events up to an index, as done when restoring a snapshot
+	s1.fsm.Events().Reset(1000)
+
+	// Resuming from an older index fails
+	req := &structs.EventListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:        "global",
+			MinQueryIndex: 10,
+		},
+	}
+	var resp structs.EventListResponse
+	err := msgpackrpc.CallWithCodec(codec, "Event.List", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrEventsEvicted.Error()) {
+		t.Fatalf("expected evicted error: %v", err)
+	}
+}
+
+func TestEventEndpoint_List_ACL(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Publish the events of a job and a node
+	state := s1.fsm.State()
+	node := mock.Node()
+	if err := state.UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	job := mock.Job()
+	reg := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global", AuthToken: root.SecretID},
+	}
+	var regResp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &regResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	drain := &structs.NodeUpdateDrainRequest{
+		NodeID:        node.ID,
+		DrainStrategy: &structs.DrainStrategy{},
+		WriteRequest:  structs.WriteRequest{Region: "global", AuthToken: root.SecretID},
+	}
+	var drainResp structs.NodeDrainUpdateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.UpdateDrain", drain, &drainResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Anonymous requests are denied
+	req := &structs.EventListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.EventListResponse
+	err := msgpackrpc.CallWithCodec(codec, "Event.List", req, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// Tokens that may only read jobs don't see node events
+	policy := mock.ACLPolicy()
+	policy.Rules = `namespace "default" { policy = "read" }`
+	policy.SetHash()
+	token := mock.ACLToken()
+	token.Policies = []string{policy.Name}
+	if err := state.UpsertACLPolicies(2000, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertACLTokens(2001, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req.AuthToken = token.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Event.List", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, events := range resp.Events {
+		for _, event := range events.Events {
+			if event.Topic == structs.TopicNode {
+				t.Fatalf("bad: %#v", event)
+			}
+		}
+	}
+
+	// Management tokens see the drain
+	req.AuthToken = root.SecretID
+	req.Topics = map[structs.Topic][]string{structs.TopicNode: []string{"*"}}
+	var resp2 structs.EventListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Event.List", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Events) != 1 || resp2.Events[0].Events[0].Type != structs.EventTypeNodeDrain {
+		t.Fatalf("bad: %#v", resp2.Events)
+	}
+	if !resp2.Events[0].Events[0].Payload.Node.Drain {
+		t.Fatalf("bad: %#v", resp2.Events[0].Events[0].Payload.Node)
+	}
+}
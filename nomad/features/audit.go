@@ -0,0 +1,7 @@
+// +build !noaudit
+
+package features
+
+func init() {
+	register(Audit)
+}
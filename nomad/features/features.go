@@ -0,0 +1,127 @@
+package features
+
+// The features package gates the optional subsystems of Nomad. A feature is
+// compiled into the binary unless the build tag excluding it is set, such as
+// "noquotas", and a compiled feature is enabled unless it is disabled in the
+// agent configuration. Features that aren't part of this build are known so
+// that configurations naming them remain valid.
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// Feature is the name of an optional subsystem
+type Feature string
+
+const (
+	// Audit is the audit logging of requests
+	Audit Feature = "audit"
+
+	// MultiConsul is the integration with more than one Consul cluster
+	MultiConsul Feature = "multi_consul"
+
+	// MultiVault is the integration with more than one Vault cluster
+	MultiVault Feature = "multi_vault"
+
+	// Quotas is the enforcement of quota specifications on namespaces
+	Quotas Feature = "quotas"
+
+	// Sentinel is the enforcement of Sentinel policies on jobs
+	Sentinel Feature = "sentinel"
+)
+
+var (
+	// known are all the features, whether compiled in or not
+	known = []Feature{Audit, MultiConsul, MultiVault, Quotas, Sentinel}
+
+	// compiled are the features compiled into the binary. Features are
+	// registered by the files of the package their build tag selects.
+	compiled = make(map[Feature]struct{})
+)
+
+// register marks a feature as compiled into the binary
+func register(f Feature) {
+	compiled[f] = struct{}{}
+}
+
+// Known returns all the features, sorted by name
+func Known() []Feature {
+	return known
+}
+
+// Compiled returns whether a feature is compiled into the binary
+func Compiled(f Feature) bool {
+	_, ok := compiled[f]
+	return ok
+}
+
+// Set is the set of features enabled in an agent. The nil set enables every
+// feature compiled into the binary.
+type Set struct {
+	enabled map[Feature]struct{}
+}
+
+// NewSet returns the set of the features compiled into the binary, minus the
+// disabled ones. An error is returned if a disabled feature isn't known.
+func NewSet(disabled []string) (*Set, error) {
+	s := &Set{enabled: make(map[Feature]struct{})}
+	for f := range compiled {
+		s.enabled[f] = struct{}{}
+	}
+	for _, name := range disabled {
+		f := Feature(name)
+		if !isKnown(f) {
+			return nil, fmt.Errorf("unknown feature %q", name)
+		}
+		delete(s.enabled, f)
+	}
+	return s, nil
+}
+
+// isKnown returns whether a feature is known
+func isKnown(f Feature) bool {
+	for _, k := range known {
+		if k == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled returns whether a feature is enabled
+func (s *Set) Enabled(f Feature) bool {
+	if s == nil {
+		return Compiled(f)
+	}
+	_, ok := s.enabled[f]
+	return ok
+}
+
+// Check returns an error if a feature isn't enabled. The error starts with
+// structs.ErrFeatureDisabled, since errors are returned by the servers as
+// strings.
+func (s *Set) Check(f Feature) error {
+	if s.Enabled(f) {
+		return nil
+	}
+	return fmt.Errorf("%v: %s", structs.ErrFeatureDisabled, f)
+}
+
+// Status returns the status of every known feature, either "enabled",
+// "disabled" or "unavailable" when not compiled into the binary
+func (s *Set) Status() map[string]string {
+	status := make(map[string]string, len(known))
+	for _, f := range known {
+		switch {
+		case s.Enabled(f):
+			status[string(f)] = "enabled"
+		case Compiled(f):
+			status[string(f)] = "disabled"
+		default:
+			status[string(f)] = "unavailable"
+		}
+	}
+	return status
+}
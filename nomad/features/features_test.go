@@ -0,0 +1,55 @@
+package features
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestSet_Enabled(t *testing.T) {
+	// The nil set enables the compiled features
+	var s *Set
+	if !s.Enabled(Quotas) {
+		t.Fatalf("expected quotas to be enabled")
+	}
+	if s.Enabled(Sentinel) {
+		t.Fatalf("expected sentinel to be unavailable")
+	}
+
+	s, err := NewSet([]string{"quotas", "sentinel"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if s.Enabled(Quotas) {
+		t.Fatalf("expected quotas to be disabled")
+	}
+	if !s.Enabled(Audit) {
+		t.Fatalf("expected audit to be enabled")
+	}
+	if err := s.Check(Audit); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	err = s.Check(Quotas)
+	if err == nil || !strings.HasPrefix(err.Error(), structs.ErrFeatureDisabled.Error()) {
+		t.Fatalf("expected feature disabled error, got: %v", err)
+	}
+
+	expected := map[string]string{
+		"audit":        "enabled",
+		"multi_consul": "enabled",
+		"multi_vault":  "enabled",
+		"quotas":       "disabled",
+		"sentinel":     "unavailable",
+	}
+	if status := s.Status(); !reflect.DeepEqual(status, expected) {
+		t.Fatalf("bad: %#v", status)
+	}
+}
+
+func TestNewSet_Unknown(t *testing.T) {
+	if _, err := NewSet([]string{"foo"}); err == nil || !strings.Contains(err.Error(), "unknown feature") {
+		t.Fatalf("expected unknown feature error, got: %v", err)
+	}
+}
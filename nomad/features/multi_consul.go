@@ -0,0 +1,7 @@
+// +build !nomulticonsul
+
+package features
+
+func init() {
+	register(MultiConsul)
+}
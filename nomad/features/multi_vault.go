@@ -0,0 +1,7 @@
+// +build !nomultivault
+
+package features
+
+func init() {
+	register(MultiVault)
+}
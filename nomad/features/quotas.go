@@ -0,0 +1,7 @@
+// +build !noquotas
+
+package features
+
+func init() {
+	register(Quotas)
+}
@@ -1,12 +1,15 @@
 package nomad
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad/nomad/snapshot"
 	"github.com/hashicorp/nomad/nomad/state"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/scheduler"
@@ -36,6 +39,22 @@ const (
 	PeriodicLaunchSnapshot
 	JobSummarySnapshot
 	VaultAccessorSnapshot
+	NamespaceSnapshot
+	QuotaSpecSnapshot
+	ACLPolicySnapshot
+	ACLTokenSnapshot
+	WorkloadIdentityKeySnapshot
+	ServiceRegistrationSnapshot
+	RootKeySnapshot
+	VariableSnapshot
+	ScalingEventSnapshot
+	MultiregionRolloutSnapshot
+	JobSubmissionSnapshot
+	AutopilotConfigSnapshot
+	SchedulerConfigSnapshot
+	MaintenanceWindowSnapshot
+	JobVersionsSnapshot
+	CSIVolumeSnapshot
 )
 
 // nomadFSM implements a finite state machine that is used
@@ -49,6 +68,13 @@ type nomadFSM struct {
 	logger             *log.Logger
 	state              *state.StateStore
 	timetable          *TimeTable
+
+	// events holds the events published as log entries are applied
+	events *EventBuffer
+
+	// schedulerMapping maps job types to the schedulers used to compute the
+	// queued allocations of their jobs on restore
+	schedulerMapping scheduler.Mapping
 }
 
 // nomadSnapshot is used to provide a snapshot of the current
@@ -65,7 +91,7 @@ type snapshotHeader struct {
 
 // NewFSMPath is used to construct a new FSM with a blank state
 func NewFSM(evalBroker *EvalBroker, periodic *PeriodicDispatch,
-	blocked *BlockedEvals, logOutput io.Writer) (*nomadFSM, error) {
+	blocked *BlockedEvals, eventBufferSize int, logOutput io.Writer) (*nomadFSM, error) {
 	// Create a state store
 	state, err := state.NewStateStore(logOutput)
 	if err != nil {
@@ -80,6 +106,7 @@ func NewFSM(evalBroker *EvalBroker, periodic *PeriodicDispatch,
 		logger:             log.New(logOutput, "", log.LstdFlags),
 		state:              state,
 		timetable:          NewTimeTable(timeTableGranularity, timeTableLimit),
+		events:             NewEventBuffer(eventBufferSize),
 	}
 	return fsm, nil
 }
@@ -99,6 +126,42 @@ func (n *nomadFSM) TimeTable() *TimeTable {
 	return n.timetable
 }
 
+// Events returns the buffer of the events published by applying log entries
+func (n *nomadFSM) Events() *EventBuffer {
+	return n.events
+}
+
+// allocEvents returns the events of the updated allocations, as stored after
+// the update
+func (n *nomadFSM) allocEvents(allocs []*structs.Allocation) []*structs.Event {
+	events := make([]*structs.Event, 0, len(allocs))
+	for _, update := range allocs {
+		alloc, err := n.state.AllocByID(update.ID)
+		if err != nil || alloc == nil {
+			continue
+		}
+		events = append(events, &structs.Event{
+			Topic:     structs.TopicAllocation,
+			Type:      structs.EventTypeAllocationUpdated,
+			Key:       alloc.ID,
+			Namespace: alloc.Namespace,
+			Payload:   &structs.EventPayload{Allocation: alloc.Stub()},
+		})
+	}
+	return events
+}
+
+// publishEvents publishes the events of the log entry applied at the index
+func (n *nomadFSM) publishEvents(index uint64, events []*structs.Event) {
+	if len(events) == 0 {
+		return
+	}
+	for _, event := range events {
+		event.Index = index
+	}
+	n.events.Publish(&structs.Events{Index: index, Events: events})
+}
+
 func (n *nomadFSM) Apply(log *raft.Log) interface{} {
 	buf := log.Data
 	msgType := structs.MessageType(buf[0])
@@ -142,6 +205,64 @@ func (n *nomadFSM) Apply(log *raft.Log) interface{} {
 		return n.applyUpsertVaultAccessor(buf[1:], log.Index)
 	case structs.VaultAccessorDegisterRequestType:
 		return n.applyDeregisterVaultAccessor(buf[1:], log.Index)
+	case structs.NamespaceUpsertRequestType:
+		return n.applyUpsertNamespaces(buf[1:], log.Index)
+	case structs.NamespaceDeleteRequestType:
+		return n.applyDeleteNamespaces(buf[1:], log.Index)
+	case structs.QuotaSpecUpsertRequestType:
+		return n.applyUpsertQuotaSpecs(buf[1:], log.Index)
+	case structs.QuotaSpecDeleteRequestType:
+		return n.applyDeleteQuotaSpecs(buf[1:], log.Index)
+	case structs.ACLPolicyUpsertRequestType:
+		return n.applyACLPolicyUpsert(buf[1:], log.Index)
+	case structs.ACLPolicyDeleteRequestType:
+		return n.applyACLPolicyDelete(buf[1:], log.Index)
+	case structs.ACLTokenUpsertRequestType:
+		return n.applyACLTokenUpsert(buf[1:], log.Index)
+	case structs.ACLTokenDeleteRequestType:
+		return n.applyACLTokenDelete(buf[1:], log.Index)
+	case structs.ACLTokenBootstrapRequestType:
+		return n.applyACLTokenBootstrap(buf[1:], log.Index)
+	case structs.WorkloadIdentityKeyUpsertRequestType:
+		return n.applyWorkloadIdentityKeyUpsert(buf[1:], log.Index)
+	case structs.ServiceRegistrationUpsertRequestType:
+		return n.applyServiceRegistrationUpsert(buf[1:], log.Index)
+	case structs.ServiceRegistrationDeleteRequestType:
+		return n.applyServiceRegistrationDelete(buf[1:], log.Index)
+	case structs.RootKeyUpsertRequestType:
+		return n.applyRootKeyUpsert(buf[1:], log.Index)
+	case structs.VariablesUpsertRequestType:
+		return n.applyVariableUpsert(buf[1:], log.Index)
+	case structs.VariablesDeleteRequestType:
+		return n.applyVariableDelete(buf[1:], log.Index)
+	case structs.VariablesRekeyRequestType:
+		return n.applyVariablesRekey(buf[1:], log.Index)
+	case structs.ScalingEventRegisterRequestType:
+		return n.applyUpsertScalingEvent(buf[1:], log.Index)
+	case structs.NodeUpdateEligibilityRequestType:
+		return n.applyEligibilityUpdate(buf[1:], log.Index)
+	case structs.MultiregionRolloutUpsertRequestType:
+		return n.applyUpsertMultiregionRollout(buf[1:], log.Index)
+	case structs.SnapshotRestoreRequestType:
+		return n.applySnapshotRestore(buf[1:], log.Index)
+	case structs.AutopilotSetConfigRequestType:
+		return n.applyAutopilotSetConfig(buf[1:], log.Index)
+	case structs.SchedulerSetConfigRequestType:
+		return n.applySchedulerSetConfig(buf[1:], log.Index)
+	case structs.MaintenanceWindowUpsertRequestType:
+		return n.applyUpsertMaintenanceWindows(buf[1:], log.Index)
+	case structs.MaintenanceWindowDeleteRequestType:
+		return n.applyDeleteMaintenanceWindows(buf[1:], log.Index)
+	case structs.NodeReliabilityUpdateRequestType:
+		return n.applyNodeReliabilityUpdate(buf[1:], log.Index)
+	case structs.CSIVolumeRegisterRequestType:
+		return n.applyCSIVolumeRegister(buf[1:], log.Index)
+	case structs.CSIVolumeDeregisterRequestType:
+		return n.applyCSIVolumeDeregister(buf[1:], log.Index)
+	case structs.CSIVolumeClaimRequestType:
+		return n.applyCSIVolumeClaim(buf[1:], log.Index)
+	case structs.AllocUpdateDesiredTransitionRequestType:
+		return n.applyAllocUpdateDesiredTransition(buf[1:], log.Index)
 	default:
 		if ignoreUnknown {
 			n.logger.Printf("[WARN] nomad.fsm: ignoring unknown message type (%d), upgrade to newer version", msgType)
@@ -194,7 +315,7 @@ func (n *nomadFSM) applyStatusUpdate(buf []byte, index uint64) interface{} {
 		panic(fmt.Errorf("failed to decode request: %v", err))
 	}
 
-	if err := n.state.UpdateNodeStatus(index, req.NodeID, req.Status); err != nil {
+	if err := n.state.UpdateNodeStatus(index, req.NodeID, req.Status, req.NodeEvent); err != nil {
 		n.logger.Printf("[ERR] nomad.fsm: UpdateNodeStatus failed: %v", err)
 		return err
 	}
@@ -221,10 +342,47 @@ func (n *nomadFSM) applyDrainUpdate(buf []byte, index uint64) interface{} {
 		panic(fmt.Errorf("failed to decode request: %v", err))
 	}
 
-	if err := n.state.UpdateNodeDrain(index, req.NodeID, req.Drain); err != nil {
+	if err := n.state.UpdateNodeDrain(index, req.NodeID, req.DrainStrategy, req.NodeEvent); err != nil {
 		n.logger.Printf("[ERR] nomad.fsm: UpdateNodeDrain failed: %v", err)
 		return err
 	}
+
+	if node, err := n.state.NodeByID(req.NodeID); err == nil && node != nil {
+		n.publishEvents(index, []*structs.Event{{
+			Topic:   structs.TopicNode,
+			Type:    structs.EventTypeNodeDrain,
+			Key:     node.ID,
+			Payload: &structs.EventPayload{Node: node.Stub()},
+		}})
+	}
+	return nil
+}
+
+func (n *nomadFSM) applyEligibilityUpdate(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "node_eligibility_update"}, time.Now())
+	var req structs.NodeUpdateEligibilityRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpdateNodeEligibility(index, req.NodeID, req.Eligibility, req.NodeEvent); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpdateNodeEligibility failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (n *nomadFSM) applyNodeReliabilityUpdate(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "node_reliability_update"}, time.Now())
+	var req structs.NodeReliabilityUpdateRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpdateNodeReliability(index, req.Penalties, req.UpdateTime, req.HalfLife); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpdateNodeReliability failed: %v", err)
+		return err
+	}
 	return nil
 }
 
@@ -247,6 +405,22 @@ func (n *nomadFSM) applyUpsertJob(buf []byte, index uint64) interface{} {
 		return err
 	}
 
+	// Keep the source the job was submitted from
+	if req.Submission != nil {
+		if err := n.state.UpsertJobSubmission(index, req.Submission); err != nil {
+			n.logger.Printf("[ERR] nomad.fsm: UpsertJobSubmission failed: %v", err)
+			return err
+		}
+	}
+
+	n.publishEvents(index, []*structs.Event{{
+		Topic:     structs.TopicJob,
+		Type:      structs.EventTypeJobRegistered,
+		Key:       req.Job.ID,
+		Namespace: req.Job.Namespace,
+		Payload:   &structs.EventPayload{Job: req.Job},
+	}})
+
 	// We always add the job to the periodic dispatcher because there is the
 	// possibility that the periodic spec was removed and then we should stop
 	// tracking it.
@@ -261,7 +435,7 @@ func (n *nomadFSM) applyUpsertJob(buf []byte, index uint64) interface{} {
 	// job was not launched. In this case, we use the insertion time to
 	// determine if a launch was missed.
 	if req.Job.IsPeriodic() {
-		prevLaunch, err := n.state.PeriodicLaunchByID(req.Job.ID)
+		prevLaunch, err := n.state.PeriodicLaunchByID(req.Job.Namespace, req.Job.ID)
 		if err != nil {
 			n.logger.Printf("[ERR] nomad.fsm: PeriodicLaunchByID failed: %v", err)
 			return err
@@ -270,7 +444,11 @@ func (n *nomadFSM) applyUpsertJob(buf []byte, index uint64) interface{} {
 		// Record the insertion time as a launch. We overload the launch table
 		// such that the first entry is the insertion time.
 		if prevLaunch == nil {
-			launch := &structs.PeriodicLaunch{ID: req.Job.ID, Launch: time.Now()}
+			launch := &structs.PeriodicLaunch{
+				ID:        req.Job.ID,
+				Namespace: req.Job.Namespace,
+				Launch:    time.Now(),
+			}
 			if err := n.state.UpsertPeriodicLaunch(index, launch); err != nil {
 				n.logger.Printf("[ERR] nomad.fsm: UpsertPeriodicLaunch failed: %v", err)
 				return err
@@ -281,7 +459,7 @@ func (n *nomadFSM) applyUpsertJob(buf []byte, index uint64) interface{} {
 	// Check if the parent job is periodic and mark the launch time.
 	parentID := req.Job.ParentID
 	if parentID != "" {
-		parent, err := n.state.JobByID(parentID)
+		parent, err := n.state.JobByID(req.Job.Namespace, parentID)
 		if err != nil {
 			n.logger.Printf("[ERR] nomad.fsm: JobByID(%v) lookup for parent failed: %v", parentID, err)
 			return err
@@ -297,7 +475,11 @@ func (n *nomadFSM) applyUpsertJob(buf []byte, index uint64) interface{} {
 				return err
 			}
 
-			launch := &structs.PeriodicLaunch{ID: parentID, Launch: t}
+			launch := &structs.PeriodicLaunch{
+				ID:        parentID,
+				Namespace: req.Job.Namespace,
+				Launch:    t,
+			}
 			if err := n.state.UpsertPeriodicLaunch(index, launch); err != nil {
 				n.logger.Printf("[ERR] nomad.fsm: UpsertPeriodicLaunch failed: %v", err)
 				return err
@@ -315,12 +497,42 @@ func (n *nomadFSM) applyDeregisterJob(buf []byte, index uint64) interface{} {
 		panic(fmt.Errorf("failed to decode request: %v", err))
 	}
 
-	if err := n.state.DeleteJob(index, req.JobID); err != nil {
-		n.logger.Printf("[ERR] nomad.fsm: DeleteJob failed: %v", err)
-		return err
+	// If it is not a purge, mark the job as stopped so that it is still
+	// queryable and can be started again
+	namespace := req.RequestNamespace()
+	event := &structs.Event{
+		Topic:     structs.TopicJob,
+		Type:      structs.EventTypeJobDeregistered,
+		Key:       req.JobID,
+		Namespace: namespace,
+		Payload:   &structs.EventPayload{},
+	}
+	if req.Purge {
+		if err := n.state.DeleteJob(index, namespace, req.JobID); err != nil {
+			n.logger.Printf("[ERR] nomad.fsm: DeleteJob failed: %v", err)
+			return err
+		}
+	} else {
+		current, err := n.state.JobByID(namespace, req.JobID)
+		if err != nil {
+			n.logger.Printf("[ERR] nomad.fsm: JobByID lookup failed: %v", err)
+			return err
+		}
+		if current == nil {
+			return fmt.Errorf("job %q doesn't exist to be deregistered", req.JobID)
+		}
+
+		stopped := current.Copy()
+		stopped.Stop = true
+		if err := n.state.UpsertJob(index, stopped); err != nil {
+			n.logger.Printf("[ERR] nomad.fsm: UpsertJob failed: %v", err)
+			return err
+		}
+		event.Payload.Job = stopped
 	}
+	n.publishEvents(index, []*structs.Event{event})
 
-	if err := n.periodicDispatcher.Remove(req.JobID); err != nil {
+	if err := n.periodicDispatcher.Remove(namespace, req.JobID); err != nil {
 		n.logger.Printf("[ERR] nomad.fsm: periodicDispatcher.Remove failed: %v", err)
 		return err
 	}
@@ -328,7 +540,7 @@ func (n *nomadFSM) applyDeregisterJob(buf []byte, index uint64) interface{} {
 	// We always delete from the periodic launch table because it is possible that
 	// the job was updated to be non-perioidic, thus checking if it is periodic
 	// doesn't ensure we clean it up properly.
-	n.state.DeletePeriodicLaunch(index, req.JobID)
+	n.state.DeletePeriodicLaunch(index, namespace, req.JobID)
 
 	return nil
 }
@@ -345,6 +557,40 @@ func (n *nomadFSM) applyUpdateEval(buf []byte, index uint64) interface{} {
 		return err
 	}
 
+	events := make([]*structs.Event, 0, len(req.Evals))
+	for _, eval := range req.Evals {
+		events = append(events, &structs.Event{
+			Topic:     structs.TopicEvaluation,
+			Type:      structs.EventTypeEvaluationUpdated,
+			Key:       eval.ID,
+			Namespace: eval.Namespace,
+			Payload:   &structs.EventPayload{Evaluation: eval},
+		})
+	}
+	n.publishEvents(index, events)
+
+	for _, eval := range req.Evals {
+		if eval.ShouldEnqueue() {
+			n.evalBroker.Enqueue(eval)
+		} else if eval.ShouldBlock() {
+			n.blockedEvals.Block(eval)
+		}
+	}
+	return nil
+}
+
+func (n *nomadFSM) applyAllocUpdateDesiredTransition(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "alloc_update_desired_transition"}, time.Now())
+	var req structs.AllocUpdateDesiredTransitionRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpdateAllocsDesiredTransitions(index, req.Allocs, req.Evals); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpdateAllocsDesiredTransitions failed: %v", err)
+		return err
+	}
+
 	for _, eval := range req.Evals {
 		if eval.ShouldEnqueue() {
 			n.evalBroker.Enqueue(eval)
@@ -416,6 +662,12 @@ func (n *nomadFSM) applyAllocUpdate(buf []byte, index uint64) interface{} {
 		n.logger.Printf("[ERR] nomad.fsm: UpsertAllocs failed: %v", err)
 		return err
 	}
+	n.publishEvents(index, n.allocEvents(req.Alloc))
+
+	// Unblock evals limited by the quotas whose usage dropped
+	if err := n.unblockQuotas(req.Alloc, index); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -442,6 +694,7 @@ func (n *nomadFSM) applyAllocClientUpdate(buf []byte, index uint64) interface{}
 		n.logger.Printf("[ERR] nomad.fsm: UpdateAllocFromClient failed: %v", err)
 		return err
 	}
+	n.publishEvents(index, n.allocEvents(req.Alloc))
 
 	// Unblock evals for the nodes computed node class if the client has
 	// finished running an allocation.
@@ -459,6 +712,41 @@ func (n *nomadFSM) applyAllocClientUpdate(buf []byte, index uint64) interface{}
 		}
 	}
 
+	// Unblock evals limited by the quotas whose usage dropped
+	if err := n.unblockQuotas(req.Alloc, index); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// unblockQuotas unblocks the evals limited by the quotas of the namespaces
+// that the passed allocations became terminal in.
+func (n *nomadFSM) unblockQuotas(allocs []*structs.Allocation, index uint64) error {
+	quotas := make(map[string]struct{})
+	for _, update := range allocs {
+		alloc, err := n.state.AllocByID(update.ID)
+		if err != nil {
+			n.logger.Printf("[ERR] nomad.fsm: looking up alloc %q failed: %v", update.ID, err)
+			return err
+		}
+		if alloc == nil || !alloc.TerminalStatus() {
+			continue
+		}
+
+		ns, err := n.state.NamespaceByName(alloc.Namespace)
+		if err != nil {
+			n.logger.Printf("[ERR] nomad.fsm: looking up namespace %q failed: %v", alloc.Namespace, err)
+			return err
+		}
+		if ns != nil && ns.Quota != "" {
+			quotas[ns.Quota] = struct{}{}
+		}
+	}
+
+	for quota := range quotas {
+		n.blockedEvals.UnblockQuota(quota, index)
+	}
 	return nil
 }
 
@@ -503,150 +791,792 @@ func (n *nomadFSM) applyDeregisterVaultAccessor(buf []byte, index uint64) interf
 	return nil
 }
 
-func (n *nomadFSM) Snapshot() (raft.FSMSnapshot, error) {
-	// Create a new snapshot
-	snap, err := n.state.Snapshot()
-	if err != nil {
-		return nil, err
+// applyUpsertNamespaces creates or updates a set of namespaces
+func (n *nomadFSM) applyUpsertNamespaces(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "upsert_namespaces"}, time.Now())
+	var req structs.NamespaceUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
 	}
 
-	ns := &nomadSnapshot{
-		snap:      snap,
-		timetable: n.timetable,
+	// Capture the quotas the namespaces are currently attached to
+	var detached []string
+	for _, ns := range req.Namespaces {
+		existing, err := n.state.NamespaceByName(ns.Name)
+		if err != nil {
+			n.logger.Printf("[ERR] nomad.fsm: looking up namespace %q failed: %v", ns.Name, err)
+			return err
+		}
+		if existing != nil && existing.Quota != "" && existing.Quota != ns.Quota {
+			detached = append(detached, existing.Quota)
+		}
 	}
-	return ns, nil
-}
 
-func (n *nomadFSM) Restore(old io.ReadCloser) error {
-	defer old.Close()
-
-	// Create a new state store
-	newState, err := state.NewStateStore(n.logOutput)
-	if err != nil {
+	if err := n.state.UpsertNamespaces(index, req.Namespaces); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertNamespaces failed: %v", err)
 		return err
 	}
-	n.state = newState
 
-	// Start the state restore
-	restore, err := newState.Restore()
-	if err != nil {
-		return err
+	// Unblock evals limited by quotas a namespace was detached from
+	for _, quota := range detached {
+		n.blockedEvals.UnblockQuota(quota, index)
 	}
-	defer restore.Abort()
 
-	// Create a decoder
-	dec := codec.NewDecoder(old, structs.MsgpackHandle)
+	return nil
+}
 
-	// Read in the header
-	var header snapshotHeader
-	if err := dec.Decode(&header); err != nil {
+// applyDeleteNamespaces deletes a set of namespaces
+func (n *nomadFSM) applyDeleteNamespaces(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "delete_namespaces"}, time.Now())
+	var req structs.NamespaceDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteNamespaces(index, req.Namespaces); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteNamespaces failed: %v", err)
 		return err
 	}
 
-	// Populate the new state
-	msgType := make([]byte, 1)
-	for {
-		// Read the message type
-		_, err := old.Read(msgType)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return err
-		}
+	return nil
+}
 
-		// Decode
-		switch SnapshotType(msgType[0]) {
-		case TimeTableSnapshot:
-			if err := n.timetable.Deserialize(dec); err != nil {
-				return fmt.Errorf("time table deserialize failed: %v", err)
-			}
+// applyUpsertQuotaSpecs creates or updates a set of quota specifications
+func (n *nomadFSM) applyUpsertQuotaSpecs(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "upsert_quota_specs"}, time.Now())
+	var req structs.QuotaSpecUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
 
-		case NodeSnapshot:
-			node := new(structs.Node)
-			if err := dec.Decode(node); err != nil {
-				return err
-			}
-			if err := restore.NodeRestore(node); err != nil {
-				return err
-			}
+	if err := n.state.UpsertQuotaSpecs(index, req.Quotas); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertQuotaSpecs failed: %v", err)
+		return err
+	}
 
-		case JobSnapshot:
-			job := new(structs.Job)
-			if err := dec.Decode(job); err != nil {
-				return err
-			}
+	// The limits may have been raised so unblock any limited evals
+	for _, quota := range req.Quotas {
+		n.blockedEvals.UnblockQuota(quota.Name, index)
+	}
 
-			// COMPAT: Remove in 0.5
-			// Empty maps and slices should be treated as nil to avoid
-			// un-intended destructive updates in scheduler since we use
-			// reflect.DeepEqual. Starting Nomad 0.4.1, job submission sanatizes
-			// the incoming job.
-			job.Canonicalize()
+	return nil
+}
 
-			if err := restore.JobRestore(job); err != nil {
-				return err
-			}
+// applyDeleteQuotaSpecs deletes a set of quota specifications
+func (n *nomadFSM) applyDeleteQuotaSpecs(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "delete_quota_specs"}, time.Now())
+	var req structs.QuotaSpecDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
 
-		case EvalSnapshot:
-			eval := new(structs.Evaluation)
-			if err := dec.Decode(eval); err != nil {
-				return err
-			}
-			if err := restore.EvalRestore(eval); err != nil {
-				return err
-			}
+	if err := n.state.DeleteQuotaSpecs(index, req.Names); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteQuotaSpecs failed: %v", err)
+		return err
+	}
 
-		case AllocSnapshot:
-			alloc := new(structs.Allocation)
-			if err := dec.Decode(alloc); err != nil {
-				return err
-			}
-			if err := restore.AllocRestore(alloc); err != nil {
-				return err
-			}
+	return nil
+}
 
-		case IndexSnapshot:
-			idx := new(state.IndexEntry)
-			if err := dec.Decode(idx); err != nil {
-				return err
-			}
-			if err := restore.IndexRestore(idx); err != nil {
-				return err
-			}
+// applyACLPolicyUpsert is used to upsert a set of policies
+func (n *nomadFSM) applyACLPolicyUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_policy_upsert"}, time.Now())
+	var req structs.ACLPolicyUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
 
-		case PeriodicLaunchSnapshot:
-			launch := new(structs.PeriodicLaunch)
-			if err := dec.Decode(launch); err != nil {
-				return err
-			}
-			if err := restore.PeriodicLaunchRestore(launch); err != nil {
-				return err
-			}
+	if err := n.state.UpsertACLPolicies(index, req.Policies); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertACLPolicies failed: %v", err)
+		return err
+	}
+	return nil
+}
 
-		case JobSummarySnapshot:
-			summary := new(structs.JobSummary)
-			if err := dec.Decode(summary); err != nil {
-				return err
-			}
-			if err := restore.JobSummaryRestore(summary); err != nil {
-				return err
-			}
+// applyACLPolicyDelete is used to delete a set of policies
+func (n *nomadFSM) applyACLPolicyDelete(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_policy_delete"}, time.Now())
+	var req structs.ACLPolicyDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
 
-		case VaultAccessorSnapshot:
-			accessor := new(structs.VaultAccessor)
-			if err := dec.Decode(accessor); err != nil {
-				return err
-			}
-			if err := restore.VaultAccessorRestore(accessor); err != nil {
-				return err
-			}
+	if err := n.state.DeleteACLPolicies(index, req.Names); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteACLPolicies failed: %v", err)
+		return err
+	}
+	return nil
+}
 
-		default:
-			return fmt.Errorf("Unrecognized snapshot type: %v", msgType)
-		}
+// applyACLTokenUpsert is used to upsert a set of tokens
+func (n *nomadFSM) applyACLTokenUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_token_upsert"}, time.Now())
+	var req structs.ACLTokenUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
 	}
 
-	restore.Commit()
+	if err := n.state.UpsertACLTokens(index, req.Tokens); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertACLTokens failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyACLTokenDelete is used to delete a set of tokens
+func (n *nomadFSM) applyACLTokenDelete(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_token_delete"}, time.Now())
+	var req structs.ACLTokenDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteACLTokens(index, req.AccessorIDs); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteACLTokens failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyACLTokenBootstrap is used to bootstrap an ACL token
+func (n *nomadFSM) applyACLTokenBootstrap(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_token_bootstrap"}, time.Now())
+	var req structs.ACLTokenBootstrapRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.BootstrapACLTokens(index, req.ResetIndex, req.Token); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: BootstrapACLToken failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyWorkloadIdentityKeyUpsert is used to upsert a set of workload identity
+// signing keys
+func (n *nomadFSM) applyWorkloadIdentityKeyUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_workload_identity_key_upsert"}, time.Now())
+	var req structs.WorkloadIdentityKeyUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertWorkloadIdentityKeys(index, req.Keys); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertWorkloadIdentityKeys failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyServiceRegistrationUpsert is used to upsert a set of service
+// registrations
+func (n *nomadFSM) applyServiceRegistrationUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_service_registration_upsert"}, time.Now())
+	var req structs.ServiceRegistrationUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertServiceRegistrations(index, req.Services); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertServiceRegistrations failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyServiceRegistrationDelete is used to delete a set of service
+// registrations
+func (n *nomadFSM) applyServiceRegistrationDelete(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_service_registration_delete"}, time.Now())
+	var req structs.ServiceRegistrationDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteServiceRegistrations(index, req.IDs); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteServiceRegistrations failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyRootKeyUpsert is used to upsert a set of keys of the keyring
+func (n *nomadFSM) applyRootKeyUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_root_key_upsert"}, time.Now())
+	var req structs.RootKeyUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertRootKeys(index, req.Keys); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertRootKeys failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyUpsertScalingEvent is used to record a scaling event of a task group
+func (n *nomadFSM) applyUpsertScalingEvent(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "upsert_scaling_event"}, time.Now())
+	var req structs.ScalingEventRegisterRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertScalingEvent(index, &req); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertScalingEvent failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyUpsertMultiregionRollout is used to create or update the rollout of a
+// multi-region job
+func (n *nomadFSM) applyUpsertMultiregionRollout(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "upsert_multiregion_rollout"}, time.Now())
+	var req structs.MultiregionRolloutUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertMultiregionRollout(index, req.Rollout); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertMultiregionRollout failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyVariableUpsert is used to upsert an encrypted variable
+func (n *nomadFSM) applyVariableUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_variable_upsert"}, time.Now())
+	var req structs.VariablesEncryptedUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertVariable(index, req.Variable, req.CheckIndex); err != nil {
+		// Check-and-set conflicts are expected and returned to the caller
+		if err != structs.ErrCASConflict {
+			n.logger.Printf("[ERR] nomad.fsm: UpsertVariable failed: %v", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// applyVariableDelete is used to delete a variable
+func (n *nomadFSM) applyVariableDelete(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_variable_delete"}, time.Now())
+	var req structs.VariablesDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteVariable(index, req.RequestNamespace(), req.Path, req.CheckIndex); err != nil {
+		if err != structs.ErrCASConflict {
+			n.logger.Printf("[ERR] nomad.fsm: DeleteVariable failed: %v", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// applyVariablesRekey is used to replace the encrypted data of variables
+// re-encrypted with a new root key
+func (n *nomadFSM) applyVariablesRekey(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_variables_rekey"}, time.Now())
+	var req structs.VariablesRekeyRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.RekeyVariables(index, req.Variables); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: RekeyVariables failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyAutopilotSetConfig is used to set the configuration of autopilot. A
+// check-and-set request returns whether the configuration was set.
+func (n *nomadFSM) applyAutopilotSetConfig(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "autopilot"}, time.Now())
+	var req structs.AutopilotSetConfigRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if req.CAS {
+		updated, err := n.state.AutopilotCASConfig(index, req.Config.ModifyIndex, &req.Config)
+		if err != nil {
+			n.logger.Printf("[ERR] nomad.fsm: AutopilotCASConfig failed: %v", err)
+			return err
+		}
+		return updated
+	}
+
+	if err := n.state.AutopilotSetConfig(index, &req.Config); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: AutopilotSetConfig failed: %v", err)
+		return err
+	}
+	return true
+}
+
+// applySchedulerSetConfig is used to set the configuration of the
+// schedulers. A check-and-set request returns whether the configuration was
+// set.
+func (n *nomadFSM) applySchedulerSetConfig(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "scheduler_config"}, time.Now())
+	var req structs.SchedulerSetConfigRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if req.CAS {
+		updated, err := n.state.SchedulerCASConfig(index, req.Config.ModifyIndex, &req.Config)
+		if err != nil {
+			n.logger.Printf("[ERR] nomad.fsm: SchedulerCASConfig failed: %v", err)
+			return err
+		}
+		return updated
+	}
+
+	if err := n.state.SchedulerSetConfig(index, &req.Config); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: SchedulerSetConfig failed: %v", err)
+		return err
+	}
+	return true
+}
+
+// applyUpsertMaintenanceWindows creates or updates a set of maintenance
+// windows
+func (n *nomadFSM) applyUpsertMaintenanceWindows(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "upsert_maintenance_windows"}, time.Now())
+	var req structs.MaintenanceWindowUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertMaintenanceWindows(index, req.Windows); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertMaintenanceWindows failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyDeleteMaintenanceWindows deletes a set of maintenance windows
+func (n *nomadFSM) applyDeleteMaintenanceWindows(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "delete_maintenance_windows"}, time.Now())
+	var req structs.MaintenanceWindowDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteMaintenanceWindows(index, req.WindowIDs); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteMaintenanceWindows failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyCSIVolumeRegister registers or updates a set of CSI volumes
+func (n *nomadFSM) applyCSIVolumeRegister(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "csi_volume_register"}, time.Now())
+	var req structs.CSIVolumeRegisterRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertCSIVolumes(index, req.Volumes); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertCSIVolumes failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyCSIVolumeDeregister deregisters a set of CSI volumes
+func (n *nomadFSM) applyCSIVolumeDeregister(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "csi_volume_deregister"}, time.Now())
+	var req structs.CSIVolumeDeregisterRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteCSIVolumes(index, req.VolumeIDs); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteCSIVolumes failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyCSIVolumeClaim records or releases the claim of an allocation on a
+// CSI volume
+func (n *nomadFSM) applyCSIVolumeClaim(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "csi_volume_claim"}, time.Now())
+	var req structs.CSIVolumeClaimUpdate
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.CSIVolumeClaim(index, req.VolumeID, req.Claim); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: CSIVolumeClaim failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applySnapshotRestore replaces the state of the FSM with the one held by a
+// snapshot archive. Since the restore goes through Raft, every server restores
+// the same state at the same index.
+func (n *nomadFSM) applySnapshotRestore(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "snapshot_restore"}, time.Now())
+	var req structs.SnapshotRestoreRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	_, data, err := snapshot.Read(bytes.NewReader(req.Snapshot))
+	if err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: SnapshotRestore failed: %v", err)
+		return err
+	}
+	if err := n.Restore(ioutil.NopCloser(bytes.NewReader(data))); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: SnapshotRestore failed: %v", err)
+		return err
+	}
+
+	// The restored indexes are older than the index of the restore. Advance
+	// them so that blocking queries observe the restore as a change.
+	if err := n.advanceIndexes(index); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: SnapshotRestore failed: %v", err)
+		return err
+	}
+	n.events.Reset(index)
+	return nil
+}
+
+// advanceIndexes sets the index of every table of the state store to index.
+func (n *nomadFSM) advanceIndexes(index uint64) error {
+	iter, err := n.state.Indexes()
+	if err != nil {
+		return err
+	}
+
+	var tables []string
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		tables = append(tables, raw.(*state.IndexEntry).Key)
+	}
+
+	restore, err := n.state.Restore()
+	if err != nil {
+		return err
+	}
+	defer restore.Abort()
+	for _, table := range tables {
+		if err := restore.IndexRestore(&state.IndexEntry{Key: table, Value: index}); err != nil {
+			return err
+		}
+	}
+	restore.Commit()
+	return nil
+}
+
+func (n *nomadFSM) Snapshot() (raft.FSMSnapshot, error) {
+	// Create a new snapshot
+	snap, err := n.state.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	ns := &nomadSnapshot{
+		snap:      snap,
+		timetable: n.timetable,
+	}
+	return ns, nil
+}
+
+func (n *nomadFSM) Restore(old io.ReadCloser) error {
+	defer old.Close()
+
+	// Create a new state store and time table. They only replace the current
+	// ones once the whole snapshot has been decoded, so that a snapshot that
+	// fails to decode leaves the current state untouched.
+	newState, err := state.NewStateStore(n.logOutput)
+	if err != nil {
+		return err
+	}
+	timetable := NewTimeTable(timeTableGranularity, timeTableLimit)
+
+	// Start the state restore
+	restore, err := newState.Restore()
+	if err != nil {
+		return err
+	}
+	defer restore.Abort()
+
+	// Create a decoder
+	dec := codec.NewDecoder(old, structs.MsgpackHandle)
+
+	// Read in the header
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return err
+	}
+
+	// Populate the new state
+	msgType := make([]byte, 1)
+	for {
+		// Read the message type
+		_, err := old.Read(msgType)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		// Decode
+		switch SnapshotType(msgType[0]) {
+		case TimeTableSnapshot:
+			if err := timetable.Deserialize(dec); err != nil {
+				return fmt.Errorf("time table deserialize failed: %v", err)
+			}
+
+		case NodeSnapshot:
+			node := new(structs.Node)
+			if err := dec.Decode(node); err != nil {
+				return err
+			}
+			if err := restore.NodeRestore(node); err != nil {
+				return err
+			}
+
+		case JobSnapshot:
+			job := new(structs.Job)
+			if err := dec.Decode(job); err != nil {
+				return err
+			}
+
+			// COMPAT: Remove in 0.5
+			// Empty maps and slices should be treated as nil to avoid
+			// un-intended destructive updates in scheduler since we use
+			// reflect.DeepEqual. Starting Nomad 0.4.1, job submission sanatizes
+			// the incoming job.
+			job.Canonicalize()
+
+			if err := restore.JobRestore(job); err != nil {
+				return err
+			}
+
+		case EvalSnapshot:
+			eval := new(structs.Evaluation)
+			if err := dec.Decode(eval); err != nil {
+				return err
+			}
+			if err := restore.EvalRestore(eval); err != nil {
+				return err
+			}
+
+		case AllocSnapshot:
+			alloc := new(structs.Allocation)
+			if err := dec.Decode(alloc); err != nil {
+				return err
+			}
+			if err := restore.AllocRestore(alloc); err != nil {
+				return err
+			}
+
+		case IndexSnapshot:
+			idx := new(state.IndexEntry)
+			if err := dec.Decode(idx); err != nil {
+				return err
+			}
+			if err := restore.IndexRestore(idx); err != nil {
+				return err
+			}
+
+		case PeriodicLaunchSnapshot:
+			launch := new(structs.PeriodicLaunch)
+			if err := dec.Decode(launch); err != nil {
+				return err
+			}
+			if err := restore.PeriodicLaunchRestore(launch); err != nil {
+				return err
+			}
+
+		case JobSummarySnapshot:
+			summary := new(structs.JobSummary)
+			if err := dec.Decode(summary); err != nil {
+				return err
+			}
+			if err := restore.JobSummaryRestore(summary); err != nil {
+				return err
+			}
+
+		case VaultAccessorSnapshot:
+			accessor := new(structs.VaultAccessor)
+			if err := dec.Decode(accessor); err != nil {
+				return err
+			}
+			if err := restore.VaultAccessorRestore(accessor); err != nil {
+				return err
+			}
+
+		case NamespaceSnapshot:
+			ns := new(structs.Namespace)
+			if err := dec.Decode(ns); err != nil {
+				return err
+			}
+			if err := restore.NamespaceRestore(ns); err != nil {
+				return err
+			}
+
+		case QuotaSpecSnapshot:
+			quota := new(structs.QuotaSpec)
+			if err := dec.Decode(quota); err != nil {
+				return err
+			}
+			if err := restore.QuotaSpecRestore(quota); err != nil {
+				return err
+			}
+
+		case ACLPolicySnapshot:
+			policy := new(structs.ACLPolicy)
+			if err := dec.Decode(policy); err != nil {
+				return err
+			}
+			if err := restore.ACLPolicyRestore(policy); err != nil {
+				return err
+			}
+
+		case ACLTokenSnapshot:
+			token := new(structs.ACLToken)
+			if err := dec.Decode(token); err != nil {
+				return err
+			}
+			if err := restore.ACLTokenRestore(token); err != nil {
+				return err
+			}
+
+		case WorkloadIdentityKeySnapshot:
+			key := new(structs.WorkloadIdentityKey)
+			if err := dec.Decode(key); err != nil {
+				return err
+			}
+			if err := restore.WorkloadIdentityKeyRestore(key); err != nil {
+				return err
+			}
+
+		case ServiceRegistrationSnapshot:
+			service := new(structs.ServiceRegistration)
+			if err := dec.Decode(service); err != nil {
+				return err
+			}
+			if err := restore.ServiceRegistrationRestore(service); err != nil {
+				return err
+			}
+
+		case RootKeySnapshot:
+			key := new(structs.RootKey)
+			if err := dec.Decode(key); err != nil {
+				return err
+			}
+			if err := restore.RootKeyRestore(key); err != nil {
+				return err
+			}
+
+		case VariableSnapshot:
+			variable := new(structs.VariableEncrypted)
+			if err := dec.Decode(variable); err != nil {
+				return err
+			}
+			if err := restore.VariableRestore(variable); err != nil {
+				return err
+			}
+
+		case ScalingEventSnapshot:
+			events := new(structs.JobScalingEvents)
+			if err := dec.Decode(events); err != nil {
+				return err
+			}
+			if err := restore.ScalingEventsRestore(events); err != nil {
+				return err
+			}
+
+		case MultiregionRolloutSnapshot:
+			rollout := new(structs.MultiregionRollout)
+			if err := dec.Decode(rollout); err != nil {
+				return err
+			}
+			if err := restore.MultiregionRolloutRestore(rollout); err != nil {
+				return err
+			}
+
+		case JobSubmissionSnapshot:
+			submission := new(structs.JobSubmission)
+			if err := dec.Decode(submission); err != nil {
+				return err
+			}
+			if err := restore.JobSubmissionRestore(submission); err != nil {
+				return err
+			}
+
+		case AutopilotConfigSnapshot:
+			config := new(structs.AutopilotConfig)
+			if err := dec.Decode(config); err != nil {
+				return err
+			}
+			if err := restore.AutopilotConfigRestore(config); err != nil {
+				return err
+			}
+
+		case SchedulerConfigSnapshot:
+			config := new(structs.SchedulerConfiguration)
+			if err := dec.Decode(config); err != nil {
+				return err
+			}
+			if err := restore.SchedulerConfigRestore(config); err != nil {
+				return err
+			}
+
+		case MaintenanceWindowSnapshot:
+			window := new(structs.MaintenanceWindow)
+			if err := dec.Decode(window); err != nil {
+				return err
+			}
+			if err := restore.MaintenanceWindowRestore(window); err != nil {
+				return err
+			}
+
+		case JobVersionsSnapshot:
+			versions := new(structs.JobVersions)
+			if err := dec.Decode(versions); err != nil {
+				return err
+			}
+			if err := restore.JobVersionsRestore(versions); err != nil {
+				return err
+			}
+
+		case CSIVolumeSnapshot:
+			vol := new(structs.CSIVolume)
+			if err := dec.Decode(vol); err != nil {
+				return err
+			}
+			if err := restore.CSIVolumeRestore(vol); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("Unrecognized snapshot type: %v", msgType)
+		}
+	}
+
+	restore.Commit()
 
 	// Create Job Summaries
 	// COMPAT 0.4 -> 0.4.1
@@ -654,377 +1584,840 @@ func (n *nomadFSM) Restore(old io.ReadCloser) error {
 	// summaries if they were not present previously. When users upgrade to 0.5
 	// from 0.4.1, the snapshot will contain job summaries so it will be safe to
 	// remove this block.
-	index, err := n.state.Index("job_summary")
+	index, err := newState.Index("job_summary")
+	if err != nil {
+		return fmt.Errorf("couldn't fetch index of job summary table: %v", err)
+	}
+
+	// If the index is 0 that means there is no job summary in the snapshot so
+	// we will have to create them
+	if index == 0 {
+		// query the latest index
+		latestIndex, err := newState.LatestIndex()
+		if err != nil {
+			return fmt.Errorf("unable to query latest index: %v", index)
+		}
+		if err := newState.ReconcileJobSummaries(latestIndex); err != nil {
+			return fmt.Errorf("error reconciling summaries: %v", err)
+		}
+	}
+
+	// The events published before the restore don't match the new state
+	latestIndex, err := newState.LatestIndex()
+	if err != nil {
+		return fmt.Errorf("unable to query latest index: %v", err)
+	}
+
+	n.state = newState
+	n.timetable = timetable
+	n.events.Reset(latestIndex)
+	return nil
+}
+
+// reconcileSummaries re-calculates the queued allocations for every job that we
+// created a Job Summary during the snap shot restore
+func (n *nomadFSM) reconcileQueuedAllocations(index uint64) error {
+	// Get all the jobs
+	iter, err := n.state.Jobs()
+	if err != nil {
+		return err
+	}
+
+	snap, err := n.state.Snapshot()
+	if err != nil {
+		return fmt.Errorf("unable to create snapshot: %v", err)
+	}
+
+	// Invoking the scheduler for every job so that we can populate the number
+	// of queued allocations for every job
+	for {
+		rawJob := iter.Next()
+		if rawJob == nil {
+			break
+		}
+		job := rawJob.(*structs.Job)
+		planner := &scheduler.Harness{
+			State: &snap.StateStore,
+		}
+		// Create an eval and mark it as requiring annotations and insert that as well
+		eval := &structs.Evaluation{
+			ID:             structs.GenerateUUID(),
+			Namespace:      job.Namespace,
+			Priority:       job.Priority,
+			Type:           job.Type,
+			TriggeredBy:    structs.EvalTriggerJobRegister,
+			JobID:          job.ID,
+			JobModifyIndex: job.JobModifyIndex + 1,
+			Status:         structs.EvalStatusPending,
+			AnnotatePlan:   true,
+		}
+
+		// Create the scheduler and run it
+		sched, err := n.schedulerMapping.NewScheduler(eval.Type, n.logger, snap, planner)
+		if err != nil {
+			return err
+		}
+
+		if err := sched.Process(eval); err != nil {
+			return err
+		}
+
+		// Get the job summary from the fsm state store
+		summary, err := n.state.JobSummaryByID(job.Namespace, job.ID)
+		if err != nil {
+			return err
+		}
+
+		// Add the allocations scheduler has made to queued since these
+		// allocations are never getting placed until the scheduler is invoked
+		// with a real planner
+		if l := len(planner.Plans); l != 1 {
+			return fmt.Errorf("unexpected number of plans during restore %d. Please file an issue including the logs", l)
+		}
+		for _, allocations := range planner.Plans[0].NodeAllocation {
+			for _, allocation := range allocations {
+				tgSummary, ok := summary.Summary[allocation.TaskGroup]
+				if !ok {
+					return fmt.Errorf("task group %q not found while updating queued count", allocation.TaskGroup)
+				}
+				tgSummary.Queued += 1
+				summary.Summary[allocation.TaskGroup] = tgSummary
+			}
+		}
+
+		// Add the queued allocations attached to the evaluation to the queued
+		// counter of the job summary
+		if l := len(planner.Evals); l != 1 {
+			return fmt.Errorf("unexpected number of evals during restore %d. Please file an issue including the logs", l)
+		}
+		for tg, queued := range planner.Evals[0].QueuedAllocations {
+			tgSummary, ok := summary.Summary[tg]
+			if !ok {
+				return fmt.Errorf("task group %q not found while updating queued count", tg)
+			}
+			tgSummary.Queued += queued
+			summary.Summary[tg] = tgSummary
+		}
+
+		if err := n.state.UpsertJobSummary(index, summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) Persist(sink raft.SnapshotSink) error {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "persist"}, time.Now())
+	// Register the nodes
+	encoder := codec.NewEncoder(sink, structs.MsgpackHandle)
+
+	// Write the header
+	header := snapshotHeader{}
+	if err := encoder.Encode(&header); err != nil {
+		sink.Cancel()
+		return err
+	}
+
+	// Write the time table
+	sink.Write([]byte{byte(TimeTableSnapshot)})
+	if err := s.timetable.Serialize(encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+
+	// Write all the data out
+	if err := s.persistIndexes(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistNodes(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistJobs(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistEvals(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistAllocs(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistPeriodicLaunches(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistJobSummaries(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistVaultAccessors(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistNamespaces(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistQuotaSpecs(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistACLPolicies(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistACLTokens(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistWorkloadIdentityKeys(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistServiceRegistrations(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistRootKeys(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistVariables(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistScalingEvents(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistMultiregionRollouts(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistJobSubmissions(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistAutopilotConfig(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistSchedulerConfig(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistMaintenanceWindows(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistJobVersions(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistCSIVolumes(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistIndexes(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the indexes
+	iter, err := s.snap.Indexes()
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+
+		// Prepare the request struct
+		idx := raw.(*state.IndexEntry)
+
+		// Write out a node registration
+		sink.Write([]byte{byte(IndexSnapshot)})
+		if err := encoder.Encode(idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistNodes(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the nodes
+	nodes, err := s.snap.Nodes()
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := nodes.Next()
+		if raw == nil {
+			break
+		}
+
+		// Prepare the request struct
+		node := raw.(*structs.Node)
+
+		// Write out a node registration
+		sink.Write([]byte{byte(NodeSnapshot)})
+		if err := encoder.Encode(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistJobs(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the jobs
+	jobs, err := s.snap.Jobs()
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := jobs.Next()
+		if raw == nil {
+			break
+		}
+
+		// Prepare the request struct
+		job := raw.(*structs.Job)
+
+		// Write out a job registration
+		sink.Write([]byte{byte(JobSnapshot)})
+		if err := encoder.Encode(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistEvals(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the evaluations
+	evals, err := s.snap.Evals()
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := evals.Next()
+		if raw == nil {
+			break
+		}
+
+		// Prepare the request struct
+		eval := raw.(*structs.Evaluation)
+
+		// Write out the evaluation
+		sink.Write([]byte{byte(EvalSnapshot)})
+		if err := encoder.Encode(eval); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistAllocs(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the allocations
+	allocs, err := s.snap.Allocs()
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := allocs.Next()
+		if raw == nil {
+			break
+		}
+
+		// Prepare the request struct
+		alloc := raw.(*structs.Allocation)
+
+		// Write out the evaluation
+		sink.Write([]byte{byte(AllocSnapshot)})
+		if err := encoder.Encode(alloc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistPeriodicLaunches(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the jobs
+	launches, err := s.snap.PeriodicLaunches()
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := launches.Next()
+		if raw == nil {
+			break
+		}
+
+		// Prepare the request struct
+		launch := raw.(*structs.PeriodicLaunch)
+
+		// Write out a job registration
+		sink.Write([]byte{byte(PeriodicLaunchSnapshot)})
+		if err := encoder.Encode(launch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistJobSummaries(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+
+	summaries, err := s.snap.JobSummaries()
+	if err != nil {
+		return err
+	}
+
+	for {
+		raw := summaries.Next()
+		if raw == nil {
+			break
+		}
+
+		jobSummary := raw.(structs.JobSummary)
+
+		sink.Write([]byte{byte(JobSummarySnapshot)})
+		if err := encoder.Encode(jobSummary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistVaultAccessors(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+
+	accessors, err := s.snap.VaultAccessors()
+	if err != nil {
+		return err
+	}
+
+	for {
+		raw := accessors.Next()
+		if raw == nil {
+			break
+		}
+
+		accessor := raw.(*structs.VaultAccessor)
+
+		sink.Write([]byte{byte(VaultAccessorSnapshot)})
+		if err := encoder.Encode(accessor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistNamespaces(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+
+	namespaces, err := s.snap.Namespaces()
+	if err != nil {
+		return err
+	}
+
+	for {
+		raw := namespaces.Next()
+		if raw == nil {
+			break
+		}
+
+		ns := raw.(*structs.Namespace)
+
+		sink.Write([]byte{byte(NamespaceSnapshot)})
+		if err := encoder.Encode(ns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistQuotaSpecs(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+
+	quotas, err := s.snap.QuotaSpecs()
+	if err != nil {
+		return err
+	}
+
+	for {
+		raw := quotas.Next()
+		if raw == nil {
+			break
+		}
+
+		quota := raw.(*structs.QuotaSpec)
+
+		sink.Write([]byte{byte(QuotaSpecSnapshot)})
+		if err := encoder.Encode(quota); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// persistACLPolicies is used to persist ACL policies
+func (s *nomadSnapshot) persistACLPolicies(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+
+	policies, err := s.snap.ACLPolicies()
 	if err != nil {
-		return fmt.Errorf("couldn't fetch index of job summary table: %v", err)
+		return err
 	}
 
-	// If the index is 0 that means there is no job summary in the snapshot so
-	// we will have to create them
-	if index == 0 {
-		// query the latest index
-		latestIndex, err := n.state.LatestIndex()
-		if err != nil {
-			return fmt.Errorf("unable to query latest index: %v", index)
+	for {
+		raw := policies.Next()
+		if raw == nil {
+			break
 		}
-		if err := n.state.ReconcileJobSummaries(latestIndex); err != nil {
-			return fmt.Errorf("error reconciling summaries: %v", err)
+
+		policy := raw.(*structs.ACLPolicy)
+
+		sink.Write([]byte{byte(ACLPolicySnapshot)})
+		if err := encoder.Encode(policy); err != nil {
+			return err
 		}
 	}
-
 	return nil
 }
 
-// reconcileSummaries re-calculates the queued allocations for every job that we
-// created a Job Summary during the snap shot restore
-func (n *nomadFSM) reconcileQueuedAllocations(index uint64) error {
-	// Get all the jobs
-	iter, err := n.state.Jobs()
-	if err != nil {
-		return err
-	}
+// persistACLTokens is used to persist ACL tokens
+func (s *nomadSnapshot) persistACLTokens(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
 
-	snap, err := n.state.Snapshot()
+	tokens, err := s.snap.ACLTokens()
 	if err != nil {
-		return fmt.Errorf("unable to create snapshot: %v", err)
+		return err
 	}
 
-	// Invoking the scheduler for every job so that we can populate the number
-	// of queued allocations for every job
 	for {
-		rawJob := iter.Next()
-		if rawJob == nil {
+		raw := tokens.Next()
+		if raw == nil {
 			break
 		}
-		job := rawJob.(*structs.Job)
-		planner := &scheduler.Harness{
-			State: &snap.StateStore,
-		}
-		// Create an eval and mark it as requiring annotations and insert that as well
-		eval := &structs.Evaluation{
-			ID:             structs.GenerateUUID(),
-			Priority:       job.Priority,
-			Type:           job.Type,
-			TriggeredBy:    structs.EvalTriggerJobRegister,
-			JobID:          job.ID,
-			JobModifyIndex: job.JobModifyIndex + 1,
-			Status:         structs.EvalStatusPending,
-			AnnotatePlan:   true,
-		}
 
-		// Create the scheduler and run it
-		sched, err := scheduler.NewScheduler(eval.Type, n.logger, snap, planner)
-		if err != nil {
-			return err
-		}
+		token := raw.(*structs.ACLToken)
 
-		if err := sched.Process(eval); err != nil {
+		sink.Write([]byte{byte(ACLTokenSnapshot)})
+		if err := encoder.Encode(token); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		// Get the job summary from the fsm state store
-		summary, err := n.state.JobSummaryByID(job.ID)
-		if err != nil {
-			return err
-		}
+// persistWorkloadIdentityKeys is used to persist workload identity keys
+func (s *nomadSnapshot) persistWorkloadIdentityKeys(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
 
-		// Add the allocations scheduler has made to queued since these
-		// allocations are never getting placed until the scheduler is invoked
-		// with a real planner
-		if l := len(planner.Plans); l != 1 {
-			return fmt.Errorf("unexpected number of plans during restore %d. Please file an issue including the logs", l)
-		}
-		for _, allocations := range planner.Plans[0].NodeAllocation {
-			for _, allocation := range allocations {
-				tgSummary, ok := summary.Summary[allocation.TaskGroup]
-				if !ok {
-					return fmt.Errorf("task group %q not found while updating queued count", allocation.TaskGroup)
-				}
-				tgSummary.Queued += 1
-				summary.Summary[allocation.TaskGroup] = tgSummary
-			}
-		}
+	keys, err := s.snap.WorkloadIdentityKeys()
+	if err != nil {
+		return err
+	}
 
-		// Add the queued allocations attached to the evaluation to the queued
-		// counter of the job summary
-		if l := len(planner.Evals); l != 1 {
-			return fmt.Errorf("unexpected number of evals during restore %d. Please file an issue including the logs", l)
-		}
-		for tg, queued := range planner.Evals[0].QueuedAllocations {
-			tgSummary, ok := summary.Summary[tg]
-			if !ok {
-				return fmt.Errorf("task group %q not found while updating queued count", tg)
-			}
-			tgSummary.Queued += queued
-			summary.Summary[tg] = tgSummary
+	for {
+		raw := keys.Next()
+		if raw == nil {
+			break
 		}
 
-		if err := n.state.UpsertJobSummary(index, summary); err != nil {
+		key := raw.(*structs.WorkloadIdentityKey)
+
+		sink.Write([]byte{byte(WorkloadIdentityKeySnapshot)})
+		if err := encoder.Encode(key); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (s *nomadSnapshot) Persist(sink raft.SnapshotSink) error {
-	defer metrics.MeasureSince([]string{"nomad", "fsm", "persist"}, time.Now())
-	// Register the nodes
-	encoder := codec.NewEncoder(sink, structs.MsgpackHandle)
+// persistServiceRegistrations is used to persist service registrations
+func (s *nomadSnapshot) persistServiceRegistrations(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
 
-	// Write the header
-	header := snapshotHeader{}
-	if err := encoder.Encode(&header); err != nil {
-		sink.Cancel()
+	services, err := s.snap.ServiceRegistrations()
+	if err != nil {
 		return err
 	}
 
-	// Write the time table
-	sink.Write([]byte{byte(TimeTableSnapshot)})
-	if err := s.timetable.Serialize(encoder); err != nil {
-		sink.Cancel()
-		return err
-	}
+	for {
+		raw := services.Next()
+		if raw == nil {
+			break
+		}
 
-	// Write all the data out
-	if err := s.persistIndexes(sink, encoder); err != nil {
-		sink.Cancel()
-		return err
-	}
-	if err := s.persistNodes(sink, encoder); err != nil {
-		sink.Cancel()
-		return err
-	}
-	if err := s.persistJobs(sink, encoder); err != nil {
-		sink.Cancel()
-		return err
-	}
-	if err := s.persistEvals(sink, encoder); err != nil {
-		sink.Cancel()
-		return err
-	}
-	if err := s.persistAllocs(sink, encoder); err != nil {
-		sink.Cancel()
-		return err
-	}
-	if err := s.persistPeriodicLaunches(sink, encoder); err != nil {
-		sink.Cancel()
-		return err
-	}
-	if err := s.persistJobSummaries(sink, encoder); err != nil {
-		sink.Cancel()
-		return err
-	}
-	if err := s.persistVaultAccessors(sink, encoder); err != nil {
-		sink.Cancel()
-		return err
+		service := raw.(*structs.ServiceRegistration)
+
+		sink.Write([]byte{byte(ServiceRegistrationSnapshot)})
+		if err := encoder.Encode(service); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (s *nomadSnapshot) persistIndexes(sink raft.SnapshotSink,
+// persistRootKeys is used to persist the keys of the keyring
+func (s *nomadSnapshot) persistRootKeys(sink raft.SnapshotSink,
 	encoder *codec.Encoder) error {
-	// Get all the indexes
-	iter, err := s.snap.Indexes()
+
+	keys, err := s.snap.RootKeys()
 	if err != nil {
 		return err
 	}
 
 	for {
-		// Get the next item
-		raw := iter.Next()
+		raw := keys.Next()
 		if raw == nil {
 			break
 		}
 
-		// Prepare the request struct
-		idx := raw.(*state.IndexEntry)
+		key := raw.(*structs.RootKey)
 
-		// Write out a node registration
-		sink.Write([]byte{byte(IndexSnapshot)})
-		if err := encoder.Encode(idx); err != nil {
+		sink.Write([]byte{byte(RootKeySnapshot)})
+		if err := encoder.Encode(key); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (s *nomadSnapshot) persistNodes(sink raft.SnapshotSink,
+// persistVariables is used to persist the encrypted variables
+func (s *nomadSnapshot) persistVariables(sink raft.SnapshotSink,
 	encoder *codec.Encoder) error {
-	// Get all the nodes
-	nodes, err := s.snap.Nodes()
+
+	variables, err := s.snap.Variables()
 	if err != nil {
 		return err
 	}
 
 	for {
-		// Get the next item
-		raw := nodes.Next()
+		raw := variables.Next()
 		if raw == nil {
 			break
 		}
 
-		// Prepare the request struct
-		node := raw.(*structs.Node)
+		variable := raw.(*structs.VariableEncrypted)
 
-		// Write out a node registration
-		sink.Write([]byte{byte(NodeSnapshot)})
-		if err := encoder.Encode(node); err != nil {
+		sink.Write([]byte{byte(VariableSnapshot)})
+		if err := encoder.Encode(variable); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (s *nomadSnapshot) persistJobs(sink raft.SnapshotSink,
+// persistScalingEvents is used to persist the scaling events of the jobs
+func (s *nomadSnapshot) persistScalingEvents(sink raft.SnapshotSink,
 	encoder *codec.Encoder) error {
-	// Get all the jobs
-	jobs, err := s.snap.Jobs()
+
+	events, err := s.snap.ScalingEvents()
 	if err != nil {
 		return err
 	}
 
 	for {
-		// Get the next item
-		raw := jobs.Next()
+		raw := events.Next()
 		if raw == nil {
 			break
 		}
 
-		// Prepare the request struct
-		job := raw.(*structs.Job)
+		jobEvents := raw.(*structs.JobScalingEvents)
 
-		// Write out a job registration
-		sink.Write([]byte{byte(JobSnapshot)})
-		if err := encoder.Encode(job); err != nil {
+		sink.Write([]byte{byte(ScalingEventSnapshot)})
+		if err := encoder.Encode(jobEvents); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (s *nomadSnapshot) persistEvals(sink raft.SnapshotSink,
+// persistMultiregionRollouts is used to persist the multi-region rollouts of
+// the jobs
+func (s *nomadSnapshot) persistMultiregionRollouts(sink raft.SnapshotSink,
 	encoder *codec.Encoder) error {
-	// Get all the evaluations
-	evals, err := s.snap.Evals()
+
+	rollouts, err := s.snap.MultiregionRollouts()
 	if err != nil {
 		return err
 	}
 
 	for {
-		// Get the next item
-		raw := evals.Next()
+		raw := rollouts.Next()
 		if raw == nil {
 			break
 		}
 
-		// Prepare the request struct
-		eval := raw.(*structs.Evaluation)
+		rollout := raw.(*structs.MultiregionRollout)
 
-		// Write out the evaluation
-		sink.Write([]byte{byte(EvalSnapshot)})
-		if err := encoder.Encode(eval); err != nil {
+		sink.Write([]byte{byte(MultiregionRolloutSnapshot)})
+		if err := encoder.Encode(rollout); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (s *nomadSnapshot) persistAllocs(sink raft.SnapshotSink,
+// persistJobSubmissions is used to persist the sources of the latest
+// submission of the jobs
+func (s *nomadSnapshot) persistJobSubmissions(sink raft.SnapshotSink,
 	encoder *codec.Encoder) error {
-	// Get all the allocations
-	allocs, err := s.snap.Allocs()
+
+	submissions, err := s.snap.JobSubmissions()
 	if err != nil {
 		return err
 	}
 
 	for {
-		// Get the next item
-		raw := allocs.Next()
+		raw := submissions.Next()
 		if raw == nil {
 			break
 		}
 
-		// Prepare the request struct
-		alloc := raw.(*structs.Allocation)
+		submission := raw.(*structs.JobSubmission)
 
-		// Write out the evaluation
-		sink.Write([]byte{byte(AllocSnapshot)})
-		if err := encoder.Encode(alloc); err != nil {
+		sink.Write([]byte{byte(JobSubmissionSnapshot)})
+		if err := encoder.Encode(submission); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (s *nomadSnapshot) persistPeriodicLaunches(sink raft.SnapshotSink,
+// persistAutopilotConfig is used to persist the configuration of autopilot,
+// if it was set
+func (s *nomadSnapshot) persistAutopilotConfig(sink raft.SnapshotSink,
 	encoder *codec.Encoder) error {
-	// Get all the jobs
-	launches, err := s.snap.PeriodicLaunches()
+
+	_, config, err := s.snap.AutopilotConfig()
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return nil
+	}
+
+	sink.Write([]byte{byte(AutopilotConfigSnapshot)})
+	if err := encoder.Encode(config); err != nil {
+		return err
+	}
+	return nil
+}
+
+// persistSchedulerConfig is used to persist the configuration of the
+// schedulers, if it was set
+func (s *nomadSnapshot) persistSchedulerConfig(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+
+	_, config, err := s.snap.SchedulerConfig()
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return nil
+	}
+
+	sink.Write([]byte{byte(SchedulerConfigSnapshot)})
+	if err := encoder.Encode(config); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistMaintenanceWindows(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+
+	windows, err := s.snap.MaintenanceWindows()
 	if err != nil {
 		return err
 	}
 
 	for {
-		// Get the next item
-		raw := launches.Next()
+		raw := windows.Next()
 		if raw == nil {
 			break
 		}
 
-		// Prepare the request struct
-		launch := raw.(*structs.PeriodicLaunch)
+		window := raw.(*structs.MaintenanceWindow)
 
-		// Write out a job registration
-		sink.Write([]byte{byte(PeriodicLaunchSnapshot)})
-		if err := encoder.Encode(launch); err != nil {
+		sink.Write([]byte{byte(MaintenanceWindowSnapshot)})
+		if err := encoder.Encode(window); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (s *nomadSnapshot) persistJobSummaries(sink raft.SnapshotSink,
+// persistJobVersions is used to persist the tracked versions of the jobs
+func (s *nomadSnapshot) persistJobVersions(sink raft.SnapshotSink,
 	encoder *codec.Encoder) error {
 
-	summaries, err := s.snap.JobSummaries()
+	versions, err := s.snap.JobVersions()
 	if err != nil {
 		return err
 	}
 
 	for {
-		raw := summaries.Next()
+		raw := versions.Next()
 		if raw == nil {
 			break
 		}
 
-		jobSummary := raw.(structs.JobSummary)
+		jobVersions := raw.(*structs.JobVersions)
 
-		sink.Write([]byte{byte(JobSummarySnapshot)})
-		if err := encoder.Encode(jobSummary); err != nil {
+		sink.Write([]byte{byte(JobVersionsSnapshot)})
+		if err := encoder.Encode(jobVersions); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (s *nomadSnapshot) persistVaultAccessors(sink raft.SnapshotSink,
+// persistCSIVolumes is used to persist the CSI volumes and their claims
+func (s *nomadSnapshot) persistCSIVolumes(sink raft.SnapshotSink,
 	encoder *codec.Encoder) error {
 
-	accessors, err := s.snap.VaultAccessors()
+	vols, err := s.snap.CSIVolumes()
 	if err != nil {
 		return err
 	}
 
 	for {
-		raw := accessors.Next()
+		raw := vols.Next()
 		if raw == nil {
 			break
 		}
 
-		accessor := raw.(*structs.VaultAccessor)
+		vol := raw.(*structs.CSIVolume)
 
-		sink.Write([]byte{byte(VaultAccessorSnapshot)})
-		if err := encoder.Encode(accessor); err != nil {
+		sink.Write([]byte{byte(CSIVolumeSnapshot)})
+		if err := encoder.Encode(vol); err != nil {
 			return err
 		}
 	}
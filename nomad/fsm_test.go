@@ -48,7 +48,7 @@ func testFSM(t *testing.T) *nomadFSM {
 	p, _ := testPeriodicDispatcher()
 	broker := testBroker(t, 0)
 	blocked := NewBlockedEvals(broker)
-	fsm, err := NewFSM(broker, p, blocked, os.Stderr)
+	fsm, err := NewFSM(broker, p, blocked, 10, os.Stderr)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -239,7 +239,9 @@ func TestFSM_UpdateNodeDrain(t *testing.T) {
 
 	req2 := structs.NodeUpdateDrainRequest{
 		NodeID: node.ID,
-		Drain:  true,
+		DrainStrategy: &structs.DrainStrategy{
+			DrainSpec: structs.DrainSpec{Deadline: 10 * time.Second},
+		},
 	}
 	buf, err = structs.Encode(structs.NodeUpdateDrainRequestType, req2)
 	if err != nil {
@@ -256,11 +258,147 @@ func TestFSM_UpdateNodeDrain(t *testing.T) {
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if !node.Drain {
+	if !node.Drain || node.DrainStrategy == nil || node.DrainStrategy.Deadline != 10*time.Second {
+		t.Fatalf("bad node: %#v", node)
+	}
+	if node.SchedulingEligibility != structs.NodeSchedulingIneligible {
+		t.Fatalf("bad node: %#v", node)
+	}
+}
+
+func TestFSM_UpdateAllocDesiredTransition(t *testing.T) {
+	fsm := testFSM(t)
+	fsm.evalBroker.SetEnabled(true)
+	state := fsm.State()
+
+	alloc := mock.Alloc()
+	state.UpsertJobSummary(9, mock.JobSummary(alloc.JobID))
+	if err := state.UpsertAllocs(10, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	eval := &structs.Evaluation{
+		ID:          structs.GenerateUUID(),
+		Namespace:   alloc.Namespace,
+		Priority:    alloc.Job.Priority,
+		Type:        alloc.Job.Type,
+		TriggeredBy: structs.EvalTriggerNodeDrain,
+		JobID:       alloc.JobID,
+		NodeID:      alloc.NodeID,
+		Status:      structs.EvalStatusPending,
+	}
+	req := structs.AllocUpdateDesiredTransitionRequest{
+		Allocs: map[string]*structs.DesiredTransition{
+			alloc.ID: &structs.DesiredTransition{Migrate: true},
+		},
+		Evals: []*structs.Evaluation{eval},
+	}
+	buf, err := structs.Encode(structs.AllocUpdateDesiredTransitionRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	out, err := state.AllocByID(alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !out.DesiredTransition.ShouldMigrate() {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	evalOut, err := state.EvalByID(eval.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if evalOut == nil || evalOut.CreateIndex != 1 {
+		t.Fatalf("bad: %#v", evalOut)
+	}
+
+	// The evaluation is enqueued
+	if stats := fsm.evalBroker.Stats(); stats.TotalReady != 1 {
+		t.Fatalf("bad: %#v", stats)
+	}
+}
+
+func TestFSM_UpdateNodeEligibility(t *testing.T) {
+	fsm := testFSM(t)
+
+	node := mock.Node()
+	req := structs.NodeRegisterRequest{
+		Node: node,
+	}
+	buf, err := structs.Encode(structs.NodeRegisterRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	req2 := structs.NodeUpdateEligibilityRequest{
+		NodeID:      node.ID,
+		Eligibility: structs.NodeSchedulingIneligible,
+	}
+	buf, err = structs.Encode(structs.NodeUpdateEligibilityRequestType, req2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp = fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify the node is ineligible
+	node, err = fsm.State().NodeByID(req.Node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if node.SchedulingEligibility != structs.NodeSchedulingIneligible {
 		t.Fatalf("bad node: %#v", node)
 	}
 }
 
+func TestFSM_UpdateNodeReliability(t *testing.T) {
+	fsm := testFSM(t)
+
+	node := mock.Node()
+	if err := fsm.State().UpsertNode(1, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := structs.NodeReliabilityUpdateRequest{
+		Penalties:  map[string]float64{node.ID: 5},
+		UpdateTime: time.Now().UnixNano(),
+		HalfLife:   time.Hour,
+	}
+	buf, err := structs.Encode(structs.NodeReliabilityUpdateRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify the node is penalized
+	out, err := fsm.State().NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Reliability.Penalty != 5.0 || out.Reliability.UpdateTime != req.UpdateTime {
+		t.Fatalf("bad node: %#v", out)
+	}
+}
+
 func TestFSM_RegisterJob(t *testing.T) {
 	fsm := testFSM(t)
 
@@ -279,7 +417,7 @@ func TestFSM_RegisterJob(t *testing.T) {
 	}
 
 	// Verify we are registered
-	jobOut, err := fsm.State().JobByID(req.Job.ID)
+	jobOut, err := fsm.State().JobByID(req.Job.Namespace, req.Job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -291,12 +429,22 @@ func TestFSM_RegisterJob(t *testing.T) {
 	}
 
 	// Verify it was added to the periodic runner.
-	if _, ok := fsm.periodicDispatcher.tracked[job.ID]; !ok {
+	if _, ok := fsm.periodicDispatcher.tracked[structs.NewNamespacedID(job.ID, job.Namespace)]; !ok {
 		t.Fatal("job not added to periodic runner")
 	}
 
+	// Verify the event was published
+	events, _, _ := fsm.Events().Since(0)
+	if len(events) != 1 || len(events[0].Events) != 1 {
+		t.Fatalf("bad: %#v", events)
+	}
+	if event := events[0].Events[0]; event.Type != structs.EventTypeJobRegistered ||
+		event.Key != job.ID || event.Index != 1 || event.Payload.Job == nil {
+		t.Fatalf("bad: %#v", event)
+	}
+
 	// Verify the launch time was tracked.
-	launchOut, err := fsm.State().PeriodicLaunchByID(req.Job.ID)
+	launchOut, err := fsm.State().PeriodicLaunchByID(req.Job.Namespace, req.Job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -327,6 +475,7 @@ func TestFSM_DeregisterJob(t *testing.T) {
 
 	req2 := structs.JobDeregisterRequest{
 		JobID: job.ID,
+		Purge: true,
 	}
 	buf, err = structs.Encode(structs.JobDeregisterRequestType, req2)
 	if err != nil {
@@ -339,7 +488,7 @@ func TestFSM_DeregisterJob(t *testing.T) {
 	}
 
 	// Verify we are NOT registered
-	jobOut, err := fsm.State().JobByID(req.Job.ID)
+	jobOut, err := fsm.State().JobByID(req.Job.Namespace, req.Job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -348,12 +497,12 @@ func TestFSM_DeregisterJob(t *testing.T) {
 	}
 
 	// Verify it was removed from the periodic runner.
-	if _, ok := fsm.periodicDispatcher.tracked[job.ID]; ok {
+	if _, ok := fsm.periodicDispatcher.tracked[structs.NewNamespacedID(job.ID, job.Namespace)]; ok {
 		t.Fatal("job not removed from periodic runner")
 	}
 
 	// Verify it was removed from the periodic launch table.
-	launchOut, err := fsm.State().PeriodicLaunchByID(req.Job.ID)
+	launchOut, err := fsm.State().PeriodicLaunchByID(req.Job.Namespace, req.Job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -362,6 +511,57 @@ func TestFSM_DeregisterJob(t *testing.T) {
 	}
 }
 
+func TestFSM_DeregisterJob_NoPurge(t *testing.T) {
+	fsm := testFSM(t)
+
+	job := mock.PeriodicJob()
+	req := structs.JobRegisterRequest{
+		Job: job,
+	}
+	buf, err := structs.Encode(structs.JobRegisterRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	req2 := structs.JobDeregisterRequest{
+		JobID: job.ID,
+	}
+	buf, err = structs.Encode(structs.JobDeregisterRequestType, req2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp = fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are still registered but stopped
+	jobOut, err := fsm.State().JobByID(req.Job.Namespace, req.Job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if jobOut == nil {
+		t.Fatalf("job not found!")
+	}
+	if !jobOut.Stop {
+		t.Fatalf("job not stopped")
+	}
+	if jobOut.Status != structs.JobStatusDead {
+		t.Fatalf("bad status: %q", jobOut.Status)
+	}
+
+	// Verify it was removed from the periodic runner.
+	if _, ok := fsm.periodicDispatcher.tracked[structs.NewNamespacedID(job.ID, job.Namespace)]; ok {
+		t.Fatal("job not removed from periodic runner")
+	}
+}
+
 func TestFSM_UpdateEval(t *testing.T) {
 	fsm := testFSM(t)
 	fsm.evalBroker.SetEnabled(true)
@@ -734,6 +934,67 @@ func TestFSM_UpdateAllocFromClient_Unblock(t *testing.T) {
 	})
 }
 
+func TestFSM_UpdateAllocFromClient_UnblockQuota(t *testing.T) {
+	fsm := testFSM(t)
+	fsm.blockedEvals.SetEnabled(true)
+	state := fsm.State()
+
+	node := mock.Node()
+	state.UpsertNode(1, node)
+
+	quota := mock.QuotaSpec()
+	state.UpsertQuotaSpecs(2, []*structs.QuotaSpec{quota})
+	ns := mock.Namespace()
+	ns.Quota = quota.Name
+	state.UpsertNamespaces(3, []*structs.Namespace{ns})
+
+	// Mark an eval as blocked on the quota
+	eval := mock.Eval()
+	eval.Namespace = ns.Name
+	eval.QuotaLimitReached = quota.Name
+	fsm.blockedEvals.Block(eval)
+
+	bStats := fsm.blockedEvals.Stats()
+	if bStats.TotalBlocked != 1 || bStats.TotalQuotaLimit != 1 {
+		t.Fatalf("bad: %#v", bStats)
+	}
+
+	// Create an alloc in the namespace
+	alloc := mock.Alloc()
+	alloc.Namespace = ns.Name
+	alloc.NodeID = node.ID
+	state.UpsertJobSummary(8, mock.JobSummary(alloc.JobID))
+	state.UpsertAllocs(10, []*structs.Allocation{alloc})
+
+	clientAlloc := new(structs.Allocation)
+	*clientAlloc = *alloc
+	clientAlloc.ClientStatus = structs.AllocClientStatusComplete
+
+	req := structs.AllocUpdateRequest{
+		Alloc: []*structs.Allocation{clientAlloc},
+	}
+	buf, err := structs.Encode(structs.AllocClientUpdateRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify the eval was unblocked.
+	testutil.WaitForResult(func() (bool, error) {
+		bStats = fsm.blockedEvals.Stats()
+		if bStats.TotalBlocked != 0 || bStats.TotalQuotaLimit != 0 {
+			return false, fmt.Errorf("bad: %#v", bStats)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %s", err)
+	})
+}
+
 func TestFSM_UpdateAllocFromClient(t *testing.T) {
 	fsm := testFSM(t)
 	state := fsm.State()
@@ -860,71 +1121,713 @@ func TestFSM_DeregisterVaultAccessor(t *testing.T) {
 	}
 }
 
-func testSnapshotRestore(t *testing.T, fsm *nomadFSM) *nomadFSM {
-	// Snapshot
-	snap, err := fsm.Snapshot()
+func TestFSM_UpsertMaintenanceWindows(t *testing.T) {
+	fsm := testFSM(t)
+
+	w1 := mock.MaintenanceWindow()
+	w2 := mock.MaintenanceWindow()
+	req := structs.MaintenanceWindowUpsertRequest{
+		Windows: []*structs.MaintenanceWindow{w1, w2},
+	}
+	buf, err := structs.Encode(structs.MaintenanceWindowUpsertRequestType, req)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	defer snap.Release()
 
-	// Persist
-	buf := bytes.NewBuffer(nil)
-	sink := &MockSink{buf, false}
-	if err := snap.Persist(sink); err != nil {
-		t.Fatalf("err: %v", err)
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
 	}
 
-	// Try to restore on a new FSM
-	fsm2 := testFSM(t)
+	// Verify we are registered
+	out, err := fsm.State().MaintenanceWindowByID(w1.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("not found!")
+	}
+	if out.CreateIndex != 1 {
+		t.Fatalf("bad index: %d", out.CreateIndex)
+	}
 
-	// Do a restore
-	if err := fsm2.Restore(sink); err != nil {
+	// Delete the first window
+	dreq := structs.MaintenanceWindowDeleteRequest{
+		WindowIDs: []string{w1.ID},
+	}
+	buf, err = structs.Encode(structs.MaintenanceWindowDeleteRequestType, dreq)
+	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	return fsm2
-}
 
-func TestFSM_SnapshotRestore_Nodes(t *testing.T) {
-	// Add some state
-	fsm := testFSM(t)
-	state := fsm.State()
-	node1 := mock.Node()
-	state.UpsertNode(1000, node1)
-	node2 := mock.Node()
-	state.UpsertNode(1001, node2)
+	resp = fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
 
-	// Verify the contents
-	fsm2 := testSnapshotRestore(t, fsm)
-	state2 := fsm2.State()
-	out1, _ := state2.NodeByID(node1.ID)
-	out2, _ := state2.NodeByID(node2.ID)
-	if !reflect.DeepEqual(node1, out1) {
-		t.Fatalf("bad: \n%#v\n%#v", out1, node1)
+	out, err = fsm.State().MaintenanceWindowByID(w1.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
-	if !reflect.DeepEqual(node2, out2) {
-		t.Fatalf("bad: \n%#v\n%#v", out2, node2)
+	if out != nil {
+		t.Fatalf("window not deleted: %#v", out)
 	}
 }
 
-func TestFSM_SnapshotRestore_Jobs(t *testing.T) {
-	// Add some state
+func TestFSM_CSIVolumes(t *testing.T) {
 	fsm := testFSM(t)
-	state := fsm.State()
-	job1 := mock.Job()
-	state.UpsertJob(1000, job1)
-	job2 := mock.Job()
-	state.UpsertJob(1001, job2)
 
-	// Verify the contents
-	fsm2 := testSnapshotRestore(t, fsm)
-	state2 := fsm2.State()
-	out1, _ := state2.JobByID(job1.ID)
-	out2, _ := state2.JobByID(job2.ID)
-	if !reflect.DeepEqual(job1, out1) {
-		t.Fatalf("bad: \n%#v\n%#v", out1, job1)
+	vol := mock.CSIVolume()
+	req := structs.CSIVolumeRegisterRequest{
+		Volumes: []*structs.CSIVolume{vol},
 	}
-	if !reflect.DeepEqual(job2, out2) {
+	buf, err := structs.Encode(structs.CSIVolumeRegisterRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().CSIVolumeByID(vol.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("not found!")
+	}
+	if out.CreateIndex != 1 {
+		t.Fatalf("bad index: %d", out.CreateIndex)
+	}
+
+	// Claim the volume
+	creq := structs.CSIVolumeClaimUpdate{
+		VolumeID: vol.ID,
+		Claim:    &structs.CSIVolumeClaim{AllocID: "a1", NodeID: "n1", Mode: structs.CSIVolumeClaimWrite},
+	}
+	buf, err = structs.Encode(structs.CSIVolumeClaimRequestType, creq)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp = fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	out, err = fsm.State().CSIVolumeByID(vol.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Writers() != 1 {
+		t.Fatalf("claim not recorded: %#v", out.Claims)
+	}
+
+	// Deregister the volume
+	dreq := structs.CSIVolumeDeregisterRequest{
+		VolumeIDs: []string{vol.ID},
+	}
+	buf, err = structs.Encode(structs.CSIVolumeDeregisterRequestType, dreq)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp = fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	out, err = fsm.State().CSIVolumeByID(vol.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("volume not deregistered: %#v", out)
+	}
+}
+
+func TestFSM_UpsertNamespaces(t *testing.T) {
+	fsm := testFSM(t)
+
+	ns1 := mock.Namespace()
+	ns2 := mock.Namespace()
+	req := structs.NamespaceUpsertRequest{
+		Namespaces: []*structs.Namespace{ns1, ns2},
+	}
+	buf, err := structs.Encode(structs.NamespaceUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().NamespaceByName(ns1.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("not found!")
+	}
+	if out.CreateIndex != 1 {
+		t.Fatalf("bad index: %d", out.CreateIndex)
+	}
+
+	// Delete the first namespace
+	dreq := structs.NamespaceDeleteRequest{
+		Namespaces: []string{ns1.Name},
+	}
+	buf, err = structs.Encode(structs.NamespaceDeleteRequestType, dreq)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp = fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	out, err = fsm.State().NamespaceByName(ns1.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("namespace not deleted: %#v", out)
+	}
+	out, err = fsm.State().NamespaceByName(ns2.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("not found!")
+	}
+}
+
+func TestFSM_UpsertQuotaSpecs(t *testing.T) {
+	fsm := testFSM(t)
+
+	quota := mock.QuotaSpec()
+	req := structs.QuotaSpecUpsertRequest{
+		Quotas: []*structs.QuotaSpec{quota},
+	}
+	buf, err := structs.Encode(structs.QuotaSpecUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().QuotaSpecByName(quota.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("not found!")
+	}
+	if out.CreateIndex != 1 {
+		t.Fatalf("bad index: %d", out.CreateIndex)
+	}
+
+	// Delete the quota
+	dreq := structs.QuotaSpecDeleteRequest{
+		Names: []string{quota.Name},
+	}
+	buf, err = structs.Encode(structs.QuotaSpecDeleteRequestType, dreq)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp = fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	out, err = fsm.State().QuotaSpecByName(quota.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("quota not deleted: %#v", out)
+	}
+}
+
+func TestFSM_UpsertACLPolicies(t *testing.T) {
+	fsm := testFSM(t)
+
+	policy := mock.ACLPolicy()
+	req := structs.ACLPolicyUpsertRequest{
+		Policies: []*structs.ACLPolicy{policy},
+	}
+	buf, err := structs.Encode(structs.ACLPolicyUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().ACLPolicyByName(policy.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("not found!")
+	}
+	if out.CreateIndex != 1 {
+		t.Fatalf("bad index: %d", out.CreateIndex)
+	}
+}
+
+func TestFSM_DeleteACLPolicies(t *testing.T) {
+	fsm := testFSM(t)
+
+	policy := mock.ACLPolicy()
+	err := fsm.State().UpsertACLPolicies(1000, []*structs.ACLPolicy{policy})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := structs.ACLPolicyDeleteRequest{
+		Names: []string{policy.Name},
+	}
+	buf, err := structs.Encode(structs.ACLPolicyDeleteRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are NOT registered
+	out, err := fsm.State().ACLPolicyByName(policy.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("policy found!")
+	}
+}
+
+func TestFSM_BootstrapACLTokens(t *testing.T) {
+	fsm := testFSM(t)
+
+	token := mock.ACLToken()
+	req := structs.ACLTokenBootstrapRequest{
+		Token: token,
+	}
+	buf, err := structs.Encode(structs.ACLTokenBootstrapRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().ACLTokenByAccessorID(token.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("not found!")
+	}
+
+	// Test with reset
+	token2 := mock.ACLToken()
+	req = structs.ACLTokenBootstrapRequest{
+		Token:      token2,
+		ResetIndex: out.CreateIndex,
+	}
+	buf, err = structs.Encode(structs.ACLTokenBootstrapRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp = fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	out2, err := fsm.State().ACLTokenByAccessorID(token2.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out2 == nil {
+		t.Fatalf("not found!")
+	}
+}
+
+func TestFSM_UpsertACLTokens(t *testing.T) {
+	fsm := testFSM(t)
+
+	token := mock.ACLToken()
+	req := structs.ACLTokenUpsertRequest{
+		Tokens: []*structs.ACLToken{token},
+	}
+	buf, err := structs.Encode(structs.ACLTokenUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().ACLTokenByAccessorID(token.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("not found!")
+	}
+	if out.CreateIndex != 1 {
+		t.Fatalf("bad index: %d", out.CreateIndex)
+	}
+}
+
+func TestFSM_UpsertWorkloadIdentityKeys(t *testing.T) {
+	fsm := testFSM(t)
+
+	key := mock.WorkloadIdentityKey()
+	req := structs.WorkloadIdentityKeyUpsertRequest{
+		Keys: []*structs.WorkloadIdentityKey{key},
+	}
+	buf, err := structs.Encode(structs.WorkloadIdentityKeyUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().WorkloadIdentityKeyByID(key.KeyID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("not found!")
+	}
+	if out.CreateIndex != 1 {
+		t.Fatalf("bad index: %d", out.CreateIndex)
+	}
+}
+
+func TestFSM_UpsertDeleteVariables(t *testing.T) {
+	fsm := testFSM(t)
+
+	key := mock.RootKey()
+	keyReq := structs.RootKeyUpsertRequest{
+		Keys: []*structs.RootKey{key},
+	}
+	buf, err := structs.Encode(structs.RootKeyUpsertRequestType, keyReq)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp := fsm.Apply(makeLog(buf)); resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	variable := mock.VariableEncrypted(key.KeyID)
+	req := structs.VariablesEncryptedUpsertRequest{
+		Variable: variable,
+	}
+	buf, err = structs.Encode(structs.VariablesUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp := fsm.Apply(makeLog(buf)); resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().VariableByPath(variable.Namespace, variable.Path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("not found!")
+	}
+	if out.CreateIndex != 1 {
+		t.Fatalf("bad index: %d", out.CreateIndex)
+	}
+
+	// A stale check-and-set index is rejected
+	stale := uint64(0)
+	req2 := structs.VariablesDeleteRequest{
+		Path:         variable.Path,
+		CheckIndex:   &stale,
+		WriteRequest: structs.WriteRequest{Namespace: variable.Namespace},
+	}
+	buf, err = structs.Encode(structs.VariablesDeleteRequestType, req2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp := fsm.Apply(makeLog(buf)); resp != structs.ErrCASConflict {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	req2.CheckIndex = nil
+	buf, err = structs.Encode(structs.VariablesDeleteRequestType, req2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp := fsm.Apply(makeLog(buf)); resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are deleted
+	out, err = fsm.State().VariableByPath(variable.Namespace, variable.Path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("variable not deleted")
+	}
+}
+
+func TestFSM_UpsertScalingEvent(t *testing.T) {
+	fsm := testFSM(t)
+	job := mock.Job()
+	if err := fsm.State().UpsertJob(1, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	count := int64(5)
+	req := structs.ScalingEventRegisterRequest{
+		JobID:     job.ID,
+		TaskGroup: "web",
+		ScalingEvent: &structs.ScalingEvent{
+			Count:   &count,
+			Message: "scaled in",
+		},
+	}
+	buf, err := structs.Encode(structs.ScalingEventRegisterRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp := fsm.Apply(makeLog(buf)); resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify the event was recorded
+	out, err := fsm.State().ScalingEventsByJob(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || len(out.ScalingEvents["web"]) != 1 {
+		t.Fatalf("bad: %#v", out)
+	}
+	if event := out.ScalingEvents["web"][0]; event.Message != "scaled in" || *event.Count != 5 || event.CreateIndex != 1 {
+		t.Fatalf("bad: %#v", event)
+	}
+}
+
+func TestFSM_UpsertMultiregionRollout(t *testing.T) {
+	fsm := testFSM(t)
+	job := mock.Job()
+	if err := fsm.State().UpsertJob(1, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := structs.MultiregionRolloutUpsertRequest{
+		Rollout: &structs.MultiregionRollout{
+			JobID:    job.ID,
+			Strategy: &structs.MultiregionStrategy{MaxParallel: 1},
+			Status:   structs.MultiregionRolloutStatusRunning,
+			Regions: []*structs.MultiregionRolloutRegion{
+				&structs.MultiregionRolloutRegion{Region: "global", Status: structs.MultiregionRegionStatusRunning},
+			},
+		},
+	}
+	buf, err := structs.Encode(structs.MultiregionRolloutUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp := fsm.Apply(makeLog(buf)); resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify the rollout was created
+	out, err := fsm.State().MultiregionRolloutByJob(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.CreateIndex != 1 || len(out.Regions) != 1 {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestFSM_UpsertDeleteServiceRegistrations(t *testing.T) {
+	fsm := testFSM(t)
+
+	service := mock.ServiceRegistration()
+	req := structs.ServiceRegistrationUpsertRequest{
+		Services: []*structs.ServiceRegistration{service},
+	}
+	buf, err := structs.Encode(structs.ServiceRegistrationUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().ServiceRegistrationByID(service.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("not found!")
+	}
+	if out.CreateIndex != 1 {
+		t.Fatalf("bad index: %d", out.CreateIndex)
+	}
+
+	req2 := structs.ServiceRegistrationDeleteRequest{
+		IDs: []string{service.ID},
+	}
+	buf, err = structs.Encode(structs.ServiceRegistrationDeleteRequestType, req2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp = fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are deregistered
+	out, err = fsm.State().ServiceRegistrationByID(service.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("service registration not deleted")
+	}
+}
+
+func TestFSM_DeleteACLTokens(t *testing.T) {
+	fsm := testFSM(t)
+
+	token := mock.ACLToken()
+	err := fsm.State().UpsertACLTokens(1000, []*structs.ACLToken{token})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := structs.ACLTokenDeleteRequest{
+		AccessorIDs: []string{token.AccessorID},
+	}
+	buf, err := structs.Encode(structs.ACLTokenDeleteRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are NOT registered
+	out, err := fsm.State().ACLTokenByAccessorID(token.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("token found!")
+	}
+}
+
+func testSnapshotRestore(t *testing.T, fsm *nomadFSM) *nomadFSM {
+	// Snapshot
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer snap.Release()
+
+	// Persist
+	buf := bytes.NewBuffer(nil)
+	sink := &MockSink{buf, false}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Try to restore on a new FSM
+	fsm2 := testFSM(t)
+
+	// Do a restore
+	if err := fsm2.Restore(sink); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return fsm2
+}
+
+func TestFSM_SnapshotRestore_Nodes(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	node1 := mock.Node()
+	state.UpsertNode(1000, node1)
+	node2 := mock.Node()
+	state.UpsertNode(1001, node2)
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out1, _ := state2.NodeByID(node1.ID)
+	out2, _ := state2.NodeByID(node2.ID)
+	if !reflect.DeepEqual(node1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, node1)
+	}
+	if !reflect.DeepEqual(node2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, node2)
+	}
+}
+
+func TestFSM_SnapshotRestore_Jobs(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	job1 := mock.Job()
+	state.UpsertJob(1000, job1)
+	job2 := mock.Job()
+	state.UpsertJob(1001, job2)
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out1, _ := state2.JobByID(job1.Namespace, job1.ID)
+	out2, _ := state2.JobByID(job2.Namespace, job2.ID)
+	if !reflect.DeepEqual(job1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, job1)
+	}
+	if !reflect.DeepEqual(job2, out2) {
 		t.Fatalf("bad: \n%#v\n%#v", out2, job2)
 	}
 }
@@ -1059,8 +1962,8 @@ func TestFSM_SnapshotRestore_PeriodicLaunches(t *testing.T) {
 	// Verify the contents
 	fsm2 := testSnapshotRestore(t, fsm)
 	state2 := fsm2.State()
-	out1, _ := state2.PeriodicLaunchByID(launch1.ID)
-	out2, _ := state2.PeriodicLaunchByID(launch2.ID)
+	out1, _ := state2.PeriodicLaunchByID(launch1.Namespace, launch1.ID)
+	out2, _ := state2.PeriodicLaunchByID(launch2.Namespace, launch2.ID)
 	if !reflect.DeepEqual(launch1, out1) {
 		t.Fatalf("bad: \n%#v\n%#v", out1, job1)
 	}
@@ -1076,17 +1979,17 @@ func TestFSM_SnapshotRestore_JobSummary(t *testing.T) {
 
 	job1 := mock.Job()
 	state.UpsertJob(1000, job1)
-	js1, _ := state.JobSummaryByID(job1.ID)
+	js1, _ := state.JobSummaryByID(job1.Namespace, job1.ID)
 
 	job2 := mock.Job()
 	state.UpsertJob(1001, job2)
-	js2, _ := state.JobSummaryByID(job2.ID)
+	js2, _ := state.JobSummaryByID(job2.Namespace, job2.ID)
 
 	// Verify the contents
 	fsm2 := testSnapshotRestore(t, fsm)
 	state2 := fsm2.State()
-	out1, _ := state2.JobSummaryByID(job1.ID)
-	out2, _ := state2.JobSummaryByID(job2.ID)
+	out1, _ := state2.JobSummaryByID(job1.Namespace, job1.ID)
+	out2, _ := state2.JobSummaryByID(job2.Namespace, job2.ID)
 	if !reflect.DeepEqual(js1, out1) {
 		t.Fatalf("bad: \n%#v\n%#v", js1, out1)
 	}
@@ -1116,6 +2019,334 @@ func TestFSM_SnapshotRestore_VaultAccessors(t *testing.T) {
 	}
 }
 
+func TestFSM_SnapshotRestore_Namespaces(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	ns1 := mock.Namespace()
+	ns2 := mock.Namespace()
+	state.UpsertNamespaces(1000, []*structs.Namespace{ns1, ns2})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out1, _ := state2.NamespaceByName(ns1.Name)
+	out2, _ := state2.NamespaceByName(ns2.Name)
+	if !reflect.DeepEqual(ns1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, ns1)
+	}
+	if !reflect.DeepEqual(ns2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, ns2)
+	}
+}
+
+func TestFSM_SnapshotRestore_MaintenanceWindows(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	w1 := mock.MaintenanceWindow()
+	w2 := mock.MaintenanceWindow()
+	state.UpsertMaintenanceWindows(1000, []*structs.MaintenanceWindow{w1, w2})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	for _, w := range []*structs.MaintenanceWindow{w1, w2} {
+		out, _ := state2.MaintenanceWindowByID(w.ID)
+		if out == nil || out.NodeClass != w.NodeClass || out.Mode != w.Mode ||
+			!out.Start.Equal(w.Start) || !out.End.Equal(w.End) {
+			t.Fatalf("bad: \n%#v\n%#v", out, w)
+		}
+	}
+}
+
+func TestFSM_SnapshotRestore_CSIVolumes(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	vol := mock.CSIVolume()
+	state.UpsertCSIVolumes(1000, []*structs.CSIVolume{vol})
+	claim := &structs.CSIVolumeClaim{AllocID: "a1", NodeID: "n1", Mode: structs.CSIVolumeClaimWrite}
+	state.CSIVolumeClaim(1001, vol.ID, claim)
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out, _ := state2.CSIVolumeByID(vol.ID)
+	if out == nil || out.ExternalID != vol.ExternalID || !reflect.DeepEqual(out.Claims["a1"], claim) {
+		t.Fatalf("bad: \n%#v\n%#v", out, vol)
+	}
+}
+
+func TestFSM_SnapshotRestore_QuotaSpecs(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	quota1 := mock.QuotaSpec()
+	quota2 := mock.QuotaSpec()
+	state.UpsertQuotaSpecs(1000, []*structs.QuotaSpec{quota1, quota2})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out1, _ := state2.QuotaSpecByName(quota1.Name)
+	out2, _ := state2.QuotaSpecByName(quota2.Name)
+	if !reflect.DeepEqual(quota1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, quota1)
+	}
+	if !reflect.DeepEqual(quota2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, quota2)
+	}
+}
+
+func TestFSM_SnapshotRestore_ACLPolicy(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	p1 := mock.ACLPolicy()
+	p2 := mock.ACLPolicy()
+	state.UpsertACLPolicies(1000, []*structs.ACLPolicy{p1, p2})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out1, _ := state2.ACLPolicyByName(p1.Name)
+	out2, _ := state2.ACLPolicyByName(p2.Name)
+	if !reflect.DeepEqual(p1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, p1)
+	}
+	if !reflect.DeepEqual(p2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, p2)
+	}
+}
+
+func TestFSM_SnapshotRestore_ACLTokens(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	tk1 := mock.ACLToken()
+	tk2 := mock.ACLToken()
+	state.UpsertACLTokens(1000, []*structs.ACLToken{tk1, tk2})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out1, _ := state2.ACLTokenByAccessorID(tk1.AccessorID)
+	out2, _ := state2.ACLTokenByAccessorID(tk2.AccessorID)
+	if !reflect.DeepEqual(tk1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, tk1)
+	}
+	if !reflect.DeepEqual(tk2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, tk2)
+	}
+}
+
+func TestFSM_SnapshotRestore_WorkloadIdentityKeys(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	k1 := mock.WorkloadIdentityKey()
+	k2 := mock.WorkloadIdentityKey()
+	state.UpsertWorkloadIdentityKeys(1000, []*structs.WorkloadIdentityKey{k1, k2})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out1, _ := state2.WorkloadIdentityKeyByID(k1.KeyID)
+	out2, _ := state2.WorkloadIdentityKeyByID(k2.KeyID)
+	if !reflect.DeepEqual(k1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, k1)
+	}
+	if !reflect.DeepEqual(k2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, k2)
+	}
+}
+
+func TestFSM_SnapshotRestore_ServiceRegistrations(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	s1 := mock.ServiceRegistration()
+	s2 := mock.ServiceRegistration()
+	state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{s1, s2})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out1, _ := state2.ServiceRegistrationByID(s1.ID)
+	out2, _ := state2.ServiceRegistrationByID(s2.ID)
+	if !reflect.DeepEqual(s1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, s1)
+	}
+	if !reflect.DeepEqual(s2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, s2)
+	}
+}
+
+func TestFSM_SnapshotRestore_Variables(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	key := mock.RootKey()
+	state.UpsertRootKeys(1000, []*structs.RootKey{key})
+	v1 := mock.VariableEncrypted(key.KeyID)
+	v2 := mock.VariableEncrypted(key.KeyID)
+	state.UpsertVariable(1001, v1, nil)
+	state.UpsertVariable(1002, v2, nil)
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	outKey, _ := state2.RootKeyByID(key.KeyID)
+	if !reflect.DeepEqual(key, outKey) {
+		t.Fatalf("bad: \n%#v\n%#v", outKey, key)
+	}
+	out1, _ := state2.VariableByPath(v1.Namespace, v1.Path)
+	out2, _ := state2.VariableByPath(v2.Namespace, v2.Path)
+	if !reflect.DeepEqual(v1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, v1)
+	}
+	if !reflect.DeepEqual(v2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, v2)
+	}
+}
+
+func TestFSM_SnapshotRestore_ScalingEvents(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	job := mock.Job()
+	state.UpsertJob(1000, job)
+	count := int64(3)
+	state.UpsertScalingEvent(1001, &structs.ScalingEventRegisterRequest{
+		JobID:     job.ID,
+		TaskGroup: "web",
+		ScalingEvent: &structs.ScalingEvent{
+			Time:          time.Now().UnixNano(),
+			Count:         &count,
+			PreviousCount: 10,
+			Message:       "scaled in",
+		},
+	})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	events, _ := state.ScalingEventsByJob(job.Namespace, job.ID)
+	out, _ := state2.ScalingEventsByJob(job.Namespace, job.ID)
+	if !reflect.DeepEqual(events, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, events)
+	}
+}
+
+func TestFSM_SnapshotRestore_MultiregionRollouts(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	job := mock.Job()
+	state.UpsertJob(1000, job)
+	state.UpsertMultiregionRollout(1001, &structs.MultiregionRollout{
+		JobID:     job.ID,
+		Namespace: job.Namespace,
+		Strategy:  &structs.MultiregionStrategy{MaxParallel: 1},
+		Status:    structs.MultiregionRolloutStatusRunning,
+		Regions: []*structs.MultiregionRolloutRegion{
+			&structs.MultiregionRolloutRegion{Region: "region1", Status: structs.MultiregionRegionStatusRunning, EvalID: "foo"},
+			&structs.MultiregionRolloutRegion{Region: "region2", Status: structs.MultiregionRegionStatusPending},
+		},
+	})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	rollout, _ := state.MultiregionRolloutByJob(job.Namespace, job.ID)
+	out, _ := state2.MultiregionRolloutByJob(job.Namespace, job.ID)
+	if !reflect.DeepEqual(rollout, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, rollout)
+	}
+}
+
+func TestFSM_SnapshotRestore_JobVersions(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	job := mock.Job()
+	state.UpsertJob(1000, job)
+	job2 := job.Copy()
+	job2.Priority = 90
+	state.UpsertJob(1001, job2)
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	versions, _ := state.JobVersionsByID(job.Namespace, job.ID)
+	out, _ := state2.JobVersionsByID(job.Namespace, job.ID)
+	if !reflect.DeepEqual(versions, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, versions)
+	}
+	if len(out.Versions) != 2 || out.Versions[0].Version != 1 {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestFSM_SnapshotRestore_JobSubmissions(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	job := mock.Job()
+	state.UpsertJob(1000, job)
+	state.UpsertJobSubmission(1000, &structs.JobSubmission{
+		JobID:  job.ID,
+		Source: `job "example" {}`,
+		Format: structs.JobSubmissionFormatHCL,
+	})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	sub, _ := state.JobSubmissionByJob(job.Namespace, job.ID)
+	out, _ := state2.JobSubmissionByJob(job.Namespace, job.ID)
+	if !reflect.DeepEqual(sub, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, sub)
+	}
+}
+
+func TestFSM_SnapshotRestore_AutopilotConfig(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	config := structs.DefaultAutopilotConfig()
+	config.MaxTrailingLogs = 10
+	state.AutopilotSetConfig(1000, config)
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	_, expected, _ := state.AutopilotConfig()
+	_, out, _ := state2.AutopilotConfig()
+	if !reflect.DeepEqual(expected, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, expected)
+	}
+}
+
+func TestFSM_SnapshotRestore_SchedulerConfig(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	config := structs.DefaultSchedulerConfiguration()
+	config.SchedulingPaused = true
+	state.SchedulerSetConfig(1000, config)
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	_, expected, _ := state.SchedulerConfig()
+	_, out, _ := state2.SchedulerConfig()
+	if !reflect.DeepEqual(expected, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, expected)
+	}
+}
+
 func TestFSM_SnapshotRestore_AddMissingSummary(t *testing.T) {
 	// Add some state
 	fsm := testFSM(t)
@@ -1127,7 +2358,7 @@ func TestFSM_SnapshotRestore_AddMissingSummary(t *testing.T) {
 	state.UpsertAllocs(1011, []*structs.Allocation{alloc})
 
 	// Delete the summary
-	state.DeleteJobSummary(1040, alloc.Job.ID)
+	state.DeleteJobSummary(1040, alloc.Job.Namespace, alloc.Job.ID)
 
 	// Delete the index
 	if err := state.RemoveIndex("job_summary"); err != nil {
@@ -1138,9 +2369,10 @@ func TestFSM_SnapshotRestore_AddMissingSummary(t *testing.T) {
 	state2 := fsm2.State()
 	latestIndex, _ := state.LatestIndex()
 
-	out, _ := state2.JobSummaryByID(alloc.Job.ID)
+	out, _ := state2.JobSummaryByID(alloc.Job.Namespace, alloc.Job.ID)
 	expected := structs.JobSummary{
-		JobID: alloc.Job.ID,
+		JobID:     alloc.Job.ID,
+		Namespace: alloc.Job.Namespace,
 		Summary: map[string]structs.TaskGroupSummary{
 			"web": structs.TaskGroupSummary{
 				Starting: 1,
@@ -1154,6 +2386,57 @@ func TestFSM_SnapshotRestore_AddMissingSummary(t *testing.T) {
 	}
 }
 
+func TestFSM_SnapshotRestore_Invalid(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	node := mock.Node()
+	state.UpsertNode(1000, node)
+
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer snap.Release()
+
+	// Persist the snapshot followed by a record of an unknown type
+	buf := bytes.NewBuffer(nil)
+	sink := &MockSink{buf, false}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	buf.WriteByte(255)
+
+	// Restore it on a FSM holding another node
+	fsm2 := testFSM(t)
+	state2 := fsm2.State()
+	other := mock.Node()
+	state2.UpsertNode(1001, other)
+	timetable := fsm2.TimeTable()
+	if err := fsm2.Restore(sink); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// The state of the FSM is untouched
+	if fsm2.State() != state2 || fsm2.TimeTable() != timetable {
+		t.Fatalf("state replaced")
+	}
+	out, err := fsm2.State().NodeByID(other.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("node missing")
+	}
+	out, err = fsm2.State().NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
 func TestFSM_ReconcileSummaries(t *testing.T) {
 	// Add some state
 	fsm := testFSM(t)
@@ -1175,8 +2458,8 @@ func TestFSM_ReconcileSummaries(t *testing.T) {
 	state.UpsertAllocs(1011, []*structs.Allocation{alloc})
 
 	// Delete the summaries
-	state.DeleteJobSummary(1030, job1.ID)
-	state.DeleteJobSummary(1040, alloc.Job.ID)
+	state.DeleteJobSummary(1030, job1.Namespace, job1.ID)
+	state.DeleteJobSummary(1040, alloc.Job.Namespace, alloc.Job.ID)
 
 	req := structs.GenericRequest{}
 	buf, err := structs.Encode(structs.ReconcileJobSummariesRequestType, req)
@@ -1189,9 +2472,10 @@ func TestFSM_ReconcileSummaries(t *testing.T) {
 		t.Fatalf("resp: %v", resp)
 	}
 
-	out1, _ := state.JobSummaryByID(job1.ID)
+	out1, _ := state.JobSummaryByID(job1.Namespace, job1.ID)
 	expected := structs.JobSummary{
-		JobID: job1.ID,
+		JobID:     job1.ID,
+		Namespace: job1.Namespace,
 		Summary: map[string]structs.TaskGroupSummary{
 			"web": structs.TaskGroupSummary{
 				Queued: 10,
@@ -1207,9 +2491,10 @@ func TestFSM_ReconcileSummaries(t *testing.T) {
 	// This exercises the code path which adds the allocations made by the
 	// planner and the number of unplaced allocations in the reconcile summaries
 	// codepath
-	out2, _ := state.JobSummaryByID(alloc.Job.ID)
+	out2, _ := state.JobSummaryByID(alloc.Job.Namespace, alloc.Job.ID)
 	expected = structs.JobSummary{
-		JobID: alloc.Job.ID,
+		JobID:     alloc.Job.ID,
+		Namespace: alloc.Job.Namespace,
 		Summary: map[string]structs.TaskGroupSummary{
 			"web": structs.TaskGroupSummary{
 				Queued:   10,
@@ -1223,3 +2508,73 @@ func TestFSM_ReconcileSummaries(t *testing.T) {
 		t.Fatalf("expected: %#v, actual: %#v", &expected, out2)
 	}
 }
+
+func TestFSM_AutopilotSetConfig(t *testing.T) {
+	fsm := testFSM(t)
+
+	req := structs.AutopilotSetConfigRequest{
+		Config: *structs.DefaultAutopilotConfig(),
+	}
+	buf, err := structs.Encode(structs.AutopilotSetConfigRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp := fsm.Apply(makeLog(buf)); resp != true {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// A check-and-set with a stale index isn't applied
+	req.CAS = true
+	req.Config.ModifyIndex = 0
+	req.Config.MaxTrailingLogs = 10
+	buf, err = structs.Encode(structs.AutopilotSetConfigRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp := fsm.Apply(makeLog(buf)); resp != false {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	_, config, err := fsm.State().AutopilotConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if config.MaxTrailingLogs != structs.DefaultAutopilotConfig().MaxTrailingLogs {
+		t.Fatalf("bad: %#v", config)
+	}
+}
+
+func TestFSM_SchedulerSetConfig(t *testing.T) {
+	fsm := testFSM(t)
+
+	req := structs.SchedulerSetConfigRequest{
+		Config: *structs.DefaultSchedulerConfiguration(),
+	}
+	buf, err := structs.Encode(structs.SchedulerSetConfigRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp := fsm.Apply(makeLog(buf)); resp != true {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// A check-and-set with a stale index isn't applied
+	req.CAS = true
+	req.Config.ModifyIndex = 0
+	req.Config.SchedulingPaused = true
+	buf, err = structs.Encode(structs.SchedulerSetConfigRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp := fsm.Apply(makeLog(buf)); resp != false {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	_, config, err := fsm.State().SchedulerConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if config.SchedulingPaused {
+		t.Fatalf("bad: %#v", config)
+	}
+}
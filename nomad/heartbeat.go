@@ -65,6 +65,10 @@ func (s *Server) resetHeartbeatTimerLocked(id string, ttl time.Duration) {
 	if s.heartbeatTimers == nil {
 		s.heartbeatTimers = make(map[string]*time.Timer)
 	}
+	if s.heartbeatDeadlines == nil {
+		s.heartbeatDeadlines = make(map[string]time.Time)
+	}
+	s.heartbeatDeadlines[id] = time.Now().Add(ttl)
 
 	// Renew the heartbeat timer if it exists
 	if timer, ok := s.heartbeatTimers[id]; ok {
@@ -83,16 +87,33 @@ func (s *Server) resetHeartbeatTimerLocked(id string, ttl time.Duration) {
 // need to invalidate the heartbeat.
 func (s *Server) invalidateHeartbeat(id string) {
 	defer metrics.MeasureSince([]string{"nomad", "heartbeat", "invalidate"}, time.Now())
-	// Clear the heartbeat timer
 	s.heartbeatTimersLock.Lock()
+
+	// Don't expire the heartbeat before its TTL has elapsed on the monotonic
+	// clock, rearming the timer for the remaining time instead
+	if deadline, ok := s.heartbeatDeadlines[id]; ok {
+		if remaining := deadline.Sub(time.Now()); remaining > 0 {
+			if timer, ok := s.heartbeatTimers[id]; ok {
+				timer.Reset(remaining)
+				s.heartbeatTimersLock.Unlock()
+				return
+			}
+		}
+	}
+
+	// Clear the heartbeat timer
 	delete(s.heartbeatTimers, id)
+	delete(s.heartbeatDeadlines, id)
+	s.clearClockSkew(id)
 	s.heartbeatTimersLock.Unlock()
 	s.logger.Printf("[WARN] nomad.heartbeat: node '%s' TTL expired", id)
+	s.nodeReliability.MissedHeartbeat(id)
 
 	// Make a request to update the node status
 	req := structs.NodeUpdateStatusRequest{
-		NodeID: id,
-		Status: structs.NodeStatusDown,
+		NodeID:    id,
+		Status:    structs.NodeStatusDown,
+		NodeEvent: structs.NewNodeEvent(structs.NodeEventSubsystemHeartbeat, "Node heartbeat missed"),
 		WriteRequest: structs.WriteRequest{
 			Region: s.config.Region,
 		},
@@ -114,6 +135,8 @@ func (s *Server) clearHeartbeatTimer(id string) error {
 		timer.Stop()
 		delete(s.heartbeatTimers, id)
 	}
+	delete(s.heartbeatDeadlines, id)
+	s.clearClockSkew(id)
 	return nil
 }
 
@@ -127,9 +150,63 @@ func (s *Server) clearAllHeartbeatTimers() error {
 		t.Stop()
 	}
 	s.heartbeatTimers = nil
+	s.heartbeatDeadlines = nil
+
+	s.clockSkewedLock.Lock()
+	s.clockSkewed = nil
+	s.clockSkewedLock.Unlock()
 	return nil
 }
 
+// checkClockSkew compares the wall clock time a node sent with its heartbeat
+// against ours. The skew is published as a gauge and the node is reported
+// when it starts or stops exceeding ClockSkewThreshold, along with the event
+// to record on the node. The skew is returned if it exceeds the threshold,
+// otherwise zero is returned.
+func (s *Server) checkClockSkew(nodeID string, clientTime time.Time) (time.Duration, *structs.NodeEvent) {
+	skew := time.Now().Sub(clientTime)
+	metrics.SetGauge([]string{"nomad", "heartbeat", nodeID, "clock_skew"}, float32(skew.Seconds()))
+
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	skewed := abs > s.config.ClockSkewThreshold
+
+	s.clockSkewedLock.Lock()
+	defer s.clockSkewedLock.Unlock()
+	if s.clockSkewed == nil {
+		s.clockSkewed = make(map[string]struct{})
+	}
+	_, wasSkewed := s.clockSkewed[nodeID]
+	var event *structs.NodeEvent
+	switch {
+	case skewed && !wasSkewed:
+		s.clockSkewed[nodeID] = struct{}{}
+		metrics.IncrCounter([]string{"nomad", "heartbeat", "clock_skewed"}, 1)
+		s.logger.Printf("[WARN] nomad.heartbeat: clock of node '%s' is skewed by %v", nodeID, skew)
+		event = structs.NewNodeEvent(structs.NodeEventSubsystemHeartbeat, "Node clock skewed").
+			SetDetail("skew", skew.String())
+	case !skewed && wasSkewed:
+		delete(s.clockSkewed, nodeID)
+		s.logger.Printf("[INFO] nomad.heartbeat: clock of node '%s' is no longer skewed", nodeID)
+		event = structs.NewNodeEvent(structs.NodeEventSubsystemHeartbeat, "Node clock no longer skewed")
+	}
+
+	if !skewed {
+		return 0, event
+	}
+	return skew, event
+}
+
+// clearClockSkew forgets whether the clock of the node was skewed, so that the
+// node is reported again if its clock is still skewed once it heartbeats
+func (s *Server) clearClockSkew(nodeID string) {
+	s.clockSkewedLock.Lock()
+	defer s.clockSkewedLock.Unlock()
+	delete(s.clockSkewed, nodeID)
+}
+
 // heartbeatStats is a long running routine used to capture
 // the number of active heartbeats being tracked
 func (s *Server) heartbeatStats() {
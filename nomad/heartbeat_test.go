@@ -269,3 +269,81 @@ func TestServer_HeartbeatTTL_Failover(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	})
 }
+
+func TestInvalidateHeartbeat_Early(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create a node
+	node := mock.Node()
+	state := s1.fsm.State()
+	if err := state.UpsertNode(1, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A timer firing before the TTL elapsed doesn't expire the heartbeat
+	s1.heartbeatTimersLock.Lock()
+	s1.resetHeartbeatTimerLocked(node.ID, time.Hour)
+	s1.heartbeatTimersLock.Unlock()
+	s1.invalidateHeartbeat(node.ID)
+
+	out, err := state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.TerminalStatus() {
+		t.Fatalf("should not update node: %#v", out)
+	}
+	if _, ok := s1.heartbeatTimers[node.ID]; !ok {
+		t.Fatalf("missing heartbeat timer")
+	}
+}
+
+func TestCheckClockSkew(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.ClockSkewThreshold = 10 * time.Second
+	})
+	defer s1.Shutdown()
+
+	// A small skew isn't reported
+	if skew, event := s1.checkClockSkew("foo", time.Now().Add(-time.Second)); skew != 0 || event != nil {
+		t.Fatalf("bad: %v %#v", skew, event)
+	}
+	if _, ok := s1.clockSkewed["foo"]; ok {
+		t.Fatalf("should not be skewed")
+	}
+
+	// A client clock running ahead is reported with a negative skew
+	skew, event := s1.checkClockSkew("foo", time.Now().Add(time.Minute))
+	if skew > -50*time.Second {
+		t.Fatalf("bad: %v", skew)
+	}
+	if event == nil || event.Subsystem != structs.NodeEventSubsystemHeartbeat || event.Details["skew"] == "" {
+		t.Fatalf("bad: %#v", event)
+	}
+	if _, ok := s1.clockSkewed["foo"]; !ok {
+		t.Fatalf("should be skewed")
+	}
+
+	// The node is only reported once while its clock stays skewed
+	if _, event := s1.checkClockSkew("foo", time.Now().Add(time.Minute)); event != nil {
+		t.Fatalf("bad: %#v", event)
+	}
+
+	// The node recovers once its clock is fixed
+	if _, event := s1.checkClockSkew("foo", time.Now()); event == nil {
+		t.Fatalf("expected event")
+	}
+	if _, ok := s1.clockSkewed["foo"]; ok {
+		t.Fatalf("should not be skewed")
+	}
+
+	// Clearing the heartbeat timer of a node forgets its skew
+	s1.checkClockSkew("foo", time.Now().Add(time.Minute))
+	s1.resetHeartbeatTimer("foo")
+	s1.clearHeartbeatTimer("foo")
+	if _, ok := s1.clockSkewed["foo"]; ok {
+		t.Fatalf("should not be tracked")
+	}
+}
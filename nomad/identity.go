@@ -0,0 +1,274 @@
+package nomad
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// workloadIdentityKeyBits is the size of the RSA keys generated to sign
+	// workload identities
+	workloadIdentityKeyBits = 2048
+)
+
+// identitySigner signs workload identities with a parsed signing key
+type identitySigner struct {
+	keyID string
+	key   *rsa.PrivateKey
+}
+
+// jwtHeader is the JOSE header of a workload identity
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Type      string `json:"typ"`
+}
+
+// Sign returns the compact serialized JWT for the given claims
+func (i *identitySigner) Sign(claims *structs.WorkloadIdentityClaims) (string, error) {
+	header, err := json.Marshal(&jwtHeader{
+		Algorithm: structs.WorkloadIdentityAlgorithm,
+		KeyID:     i.keyID,
+		Type:      "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	hashed := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// generateWorkloadIdentityKey generates a new key to sign workload identities
+func generateWorkloadIdentityKey() (*structs.WorkloadIdentityKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, workloadIdentityKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %v", err)
+	}
+
+	return &structs.WorkloadIdentityKey{
+		KeyID:      structs.GenerateUUID(),
+		Algorithm:  structs.WorkloadIdentityAlgorithm,
+		PrivateKey: x509.MarshalPKCS1PrivateKey(key),
+		PublicKey:  pub,
+		CreateTime: time.Now().UTC().UnixNano(),
+	}, nil
+}
+
+// workloadIdentitySigner returns the signer for the active workload identity
+// key. If no key exists yet, one is generated and committed via Raft, so this
+// must only be called by the leader. The key is generated lazily when the
+// first identity is signed, since generating it is expensive.
+func (s *Server) workloadIdentitySigner() (*identitySigner, error) {
+	s.identitySignerLock.Lock()
+	defer s.identitySignerLock.Unlock()
+
+	key, err := s.fsm.State().ActiveWorkloadIdentityKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		key, err = s.newWorkloadIdentityKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Use the cached signer if the active key hasn't changed
+	if s.identitySigner != nil && s.identitySigner.keyID == key.KeyID {
+		return s.identitySigner, nil
+	}
+
+	priv, err := x509.ParsePKCS1PrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse workload identity key %q: %v", key.KeyID, err)
+	}
+	s.identitySigner = &identitySigner{keyID: key.KeyID, key: priv}
+	return s.identitySigner, nil
+}
+
+// rotateWorkloadIdentityKey generates a new workload identity key, which
+// signs all new identities. The previous keys are kept, so that the
+// identities they signed can still be verified. This must only be called by
+// the leader.
+func (s *Server) rotateWorkloadIdentityKey() (*structs.WorkloadIdentityKey, error) {
+	s.identitySignerLock.Lock()
+	defer s.identitySignerLock.Unlock()
+	return s.newWorkloadIdentityKey()
+}
+
+// newWorkloadIdentityKey generates a workload identity key and commits it
+// via Raft. The caller must hold the identity signer lock.
+func (s *Server) newWorkloadIdentityKey() (*structs.WorkloadIdentityKey, error) {
+	key, err := generateWorkloadIdentityKey()
+	if err != nil {
+		return nil, err
+	}
+
+	req := structs.WorkloadIdentityKeyUpsertRequest{
+		Keys:         []*structs.WorkloadIdentityKey{key},
+		WriteRequest: structs.WriteRequest{Region: s.config.Region},
+	}
+	resp, _, err := s.raftApply(structs.WorkloadIdentityKeyUpsertRequestType, &req)
+	if err != nil {
+		return nil, err
+	}
+	if err, ok := resp.(error); ok && err != nil {
+		return nil, err
+	}
+	s.logger.Printf("[INFO] nomad: generated workload identity key %q", key.KeyID)
+	return key, nil
+}
+
+// signAllocIdentities signs a workload identity for each task of the
+// allocation that doesn't have one yet. The job is used if the allocation
+// was normalized and doesn't reference its job.
+func (s *Server) signAllocIdentities(job *structs.Job, alloc *structs.Allocation, now time.Time) error {
+	if alloc.Job != nil {
+		job = alloc.Job
+	}
+	if job == nil {
+		return nil
+	}
+	tg := job.LookupTaskGroup(alloc.TaskGroup)
+	if tg == nil {
+		return nil
+	}
+
+	// Fast-path if all the tasks already have an identity
+	missing := false
+	for _, task := range tg.Tasks {
+		if _, ok := alloc.SignedIdentities[task.Name]; !ok {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	signer, err := s.workloadIdentitySigner()
+	if err != nil {
+		return err
+	}
+
+	// Build a new map since the allocation may share it with the version in
+	// the state store
+	identities := make(map[string]string, len(tg.Tasks))
+	for _, task := range tg.Tasks {
+		if identity, ok := alloc.SignedIdentities[task.Name]; ok {
+			identities[task.Name] = identity
+			continue
+		}
+
+		claims := structs.NewWorkloadIdentityClaims(alloc, task.Name, now)
+		identity, err := signer.Sign(claims)
+		if err != nil {
+			return fmt.Errorf("failed to sign identity for task %q of alloc %q: %v",
+				task.Name, alloc.ID, err)
+		}
+		identities[task.Name] = identity
+	}
+	alloc.SignedIdentities = identities
+	return nil
+}
+
+// isWorkloadIdentity returns whether the token looks like a workload identity
+// rather than the secret ID of an ACL token
+func isWorkloadIdentity(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// verifyWorkloadIdentity verifies the signature of a workload identity and
+// returns its claims. Identities of allocations that are unknown or terminal
+// are rejected.
+func (s *Server) verifyWorkloadIdentity(token string) (*structs.WorkloadIdentityClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed workload identity")
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed workload identity header: %v", err)
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, fmt.Errorf("malformed workload identity header: %v", err)
+	}
+	if header.Algorithm != structs.WorkloadIdentityAlgorithm {
+		return nil, fmt.Errorf("unsupported workload identity algorithm %q", header.Algorithm)
+	}
+
+	snap, err := s.fsm.State().Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	key, err := snap.WorkloadIdentityKeyByID(header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("unknown workload identity key %q", header.KeyID)
+	}
+	pub, err := x509.ParsePKIXPublicKey(key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse workload identity key %q: %v", key.KeyID, err)
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("workload identity key %q is not an RSA key", key.KeyID)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed workload identity signature: %v", err)
+	}
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(rsaPub, crypto.SHA256, hashed[:], sig); err != nil {
+		return nil, fmt.Errorf("invalid workload identity signature")
+	}
+
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed workload identity claims: %v", err)
+	}
+	var claims structs.WorkloadIdentityClaims
+	if err := json.Unmarshal(rawClaims, &claims); err != nil {
+		return nil, fmt.Errorf("malformed workload identity claims: %v", err)
+	}
+
+	alloc, err := snap.AllocByID(claims.AllocationID)
+	if err != nil {
+		return nil, err
+	}
+	if alloc == nil || alloc.TerminalStatus() {
+		return nil, fmt.Errorf("workload identity of unknown or terminal allocation %q", claims.AllocationID)
+	}
+	return &claims, nil
+}
@@ -0,0 +1,149 @@
+package nomad
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// verifyWorkloadIdentity verifies the signature of the identity with the
+// given key and returns its header and claims
+func verifyWorkloadIdentity(t *testing.T, identity string, key *structs.WorkloadIdentityKey) (*jwtHeader, *structs.WorkloadIdentityClaims) {
+	parts := strings.Split(identity, ".")
+	if len(parts) != 3 {
+		t.Fatalf("bad identity: %q", identity)
+	}
+
+	raw, err := x509.ParsePKIXPublicKey(key.PublicKey)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(raw.(*rsa.PublicKey), crypto.SHA256, hashed[:], sig); err != nil {
+		t.Fatalf("bad signature: %v", err)
+	}
+
+	var header jwtHeader
+	var claims structs.WorkloadIdentityClaims
+	for i, out := range []interface{}{&header, &claims} {
+		buf, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if err := json.Unmarshal(buf, out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	return &header, &claims
+}
+
+func TestWorkloadIdentity_GeneratesKey(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// No key is generated until an identity is signed
+	key, err := s1.fsm.State().ActiveWorkloadIdentityKey()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if key != nil {
+		t.Fatalf("unexpected key: %#v", key)
+	}
+
+	signer, err := s1.workloadIdentitySigner()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	key, err = s1.fsm.State().ActiveWorkloadIdentityKey()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if key == nil || key.KeyID != signer.keyID {
+		t.Fatalf("bad: %#v", key)
+	}
+	if key.Algorithm != structs.WorkloadIdentityAlgorithm {
+		t.Fatalf("bad: %#v", key)
+	}
+	if _, err := x509.ParsePKCS1PrivateKey(key.PrivateKey); err != nil {
+		t.Fatalf("bad private key: %v", err)
+	}
+
+	// The signer uses the existing key rather than generating a new one
+	signer, err = s1.workloadIdentitySigner()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if signer.keyID != key.KeyID {
+		t.Fatalf("bad key ID: %q; want %q", signer.keyID, key.KeyID)
+	}
+}
+
+func TestWorkloadIdentity_SignAllocIdentities(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	signer, err := s1.workloadIdentitySigner()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	key, err := s1.fsm.State().WorkloadIdentityKeyByID(signer.keyID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Sign the identities of a normalized allocation
+	alloc := mock.Alloc()
+	job := alloc.Job
+	alloc.Job = nil
+	now := time.Now()
+	if err := s1.signAllocIdentities(job, alloc, now); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	identity, ok := alloc.SignedIdentities["web"]
+	if !ok || len(alloc.SignedIdentities) != 1 {
+		t.Fatalf("bad: %#v", alloc.SignedIdentities)
+	}
+
+	header, claims := verifyWorkloadIdentity(t, identity, key)
+	if header.Algorithm != "RS256" || header.KeyID != key.KeyID || header.Type != "JWT" {
+		t.Fatalf("bad header: %#v", header)
+	}
+	expected := &structs.WorkloadIdentityClaims{
+		Issuer:       structs.WorkloadIdentityIssuer,
+		Subject:      "default:" + alloc.JobID + ":web:web",
+		IssuedAt:     now.Unix(),
+		NotBefore:    now.Unix(),
+		Namespace:    structs.DefaultNamespace,
+		JobID:        alloc.JobID,
+		AllocationID: alloc.ID,
+		TaskGroup:    "web",
+		Task:         "web",
+	}
+	if *claims != *expected {
+		t.Fatalf("bad claims: %#v; want %#v", claims, expected)
+	}
+
+	// Existing identities are kept
+	if err := s1.signAllocIdentities(job, alloc, now.Add(time.Hour)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if alloc.SignedIdentities["web"] != identity {
+		t.Fatalf("identity was resigned")
+	}
+}
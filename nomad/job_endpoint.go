@@ -10,7 +10,9 @@ import (
 	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/acl"
 	"github.com/hashicorp/nomad/client/driver"
+	"github.com/hashicorp/nomad/nomad/state"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/nomad/watch"
 	"github.com/hashicorp/nomad/scheduler"
@@ -32,6 +34,40 @@ var (
 	}
 )
 
+// vaultClusterConstraint returns the implicit constraint added to jobs
+// requesting a Vault token from the given cluster
+func vaultClusterConstraint(cluster string) *structs.Constraint {
+	if cluster == structs.DefaultCluster {
+		return vaultConstraint
+	}
+	return &structs.Constraint{
+		LTarget: fmt.Sprintf("${attr.vault.%s.version}", cluster),
+		RTarget: vaultConstraint.RTarget,
+		Operand: structs.ConstraintVersion,
+	}
+}
+
+// consulClusterConstraint returns the implicit constraint added to jobs
+// registering services with a named Consul cluster. It is satisfied by the
+// Nodes the cluster is fingerprinted on.
+func consulClusterConstraint(cluster string) *structs.Constraint {
+	return &structs.Constraint{
+		LTarget: fmt.Sprintf("${attr.consul.%s.version}", cluster),
+		RTarget: ">= 0.0.0",
+		Operand: structs.ConstraintVersion,
+	}
+}
+
+// addImplicitConstraint appends the constraint unless it is already present
+func addImplicitConstraint(constraints []*structs.Constraint, c *structs.Constraint) []*structs.Constraint {
+	for _, existing := range constraints {
+		if existing.Equal(c) {
+			return constraints
+		}
+	}
+	return append(constraints, c)
+}
+
 // Job endpoint is used for job interactions
 type Job struct {
 	srv *Server
@@ -48,6 +84,25 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 	if args.Job == nil {
 		return fmt.Errorf("missing job for registration")
 	}
+	if args.Job.Dispatched {
+		return fmt.Errorf("job can't be submitted with 'Dispatched' set")
+	}
+
+	// Jobs without a namespace are submitted into the request's namespace
+	if args.Job.Namespace == "" {
+		args.Job.Namespace = args.RequestNamespace()
+	}
+
+	// Check job submission permissions
+	if aclObj, err := j.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNamespaceOperation(args.Job.Namespace, acl.NamespaceCapabilitySubmitJob) {
+		return structs.ErrPermissionDenied
+	}
+
+	// Keep the job as submitted to register it in its other regions, since
+	// the registration below modifies it
+	submitted := args.Job.Copy()
 
 	// Initialize the job fields (sets defaults and any necessary init work).
 	args.Job.Canonicalize()
@@ -57,13 +112,24 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 		return err
 	}
 
+	// Ensure a scheduler processes the job's type
+	if err := j.srv.config.SchedulerMapping.Validate(args.Job.Type); err != nil {
+		return err
+	}
+
+	snap, err := j.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+
+	// Ensure the job is admitted in its namespace
+	if err := admitJob(snap, args.Job); err != nil {
+		return err
+	}
+
 	if args.EnforceIndex {
 		// Lookup the job
-		snap, err := j.srv.fsm.State().Snapshot()
-		if err != nil {
-			return err
-		}
-		job, err := snap.JobByID(args.Job.ID)
+		job, err := snap.JobByID(args.Job.Namespace, args.Job.ID)
 		if err != nil {
 			return err
 		}
@@ -80,61 +146,44 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 		}
 	}
 
-	// Ensure that the job has permissions for the requested Vault tokens
+	// Ensure that the job has permissions for the requested Vault tokens in
+	// each of the Vault clusters they are derived from
 	policies := args.Job.VaultPolicies()
-	if len(policies) != 0 {
-		vconf := j.srv.config.VaultConfig
-		if !vconf.Enabled {
-			return fmt.Errorf("Vault not enabled and Vault policies requested")
+	for cluster, clusterPolicies := range structs.VaultPoliciesByCluster(policies) {
+		if err := j.checkVaultPolicies(cluster, clusterPolicies, args.Job.VaultToken); err != nil {
+			return err
 		}
+	}
 
-		// Have to check if the user has permissions
-		if !vconf.AllowUnauthenticated {
-			if args.Job.VaultToken == "" {
-				return fmt.Errorf("Vault policies requested but missing Vault Token")
-			}
-
-			vault := j.srv.vault
-			s, err := vault.LookupToken(context.Background(), args.Job.VaultToken)
-			if err != nil {
-				return err
-			}
-
-			allowedPolicies, err := PoliciesFrom(s)
-			if err != nil {
-				return err
+	// Add implicit constraints that the task groups are run on a Node with
+	// the Vault and Consul clusters their tasks select
+	for _, tg := range args.Job.TaskGroups {
+		for _, task := range tg.Tasks {
+			if task.Vault != nil {
+				tg.Constraints = addImplicitConstraint(tg.Constraints, vaultClusterConstraint(task.Vault.ClusterName()))
 			}
-
-			// If we are given a root token it can access all policies
-			if !lib.StrContains(allowedPolicies, "root") {
-				flatPolicies := structs.VaultPoliciesSet(policies)
-				subset, offending := structs.SliceStringIsSubset(allowedPolicies, flatPolicies)
-				if !subset {
-					return fmt.Errorf("Passed Vault Token doesn't allow access to the following policies: %s",
-						strings.Join(offending, ", "))
-				}
+			if task.Consul != nil && len(task.Services) != 0 && task.Consul.ClusterName() != structs.DefaultCluster {
+				tg.Constraints = addImplicitConstraint(tg.Constraints, consulClusterConstraint(task.Consul.Cluster))
 			}
 		}
+	}
 
-		// Add implicit constraints that the task groups are run on a Node with
-		// Vault
-		for _, tg := range args.Job.TaskGroups {
-			_, ok := policies[tg.Name]
-			if !ok {
-				// Not requesting Vault
-				continue
-			}
-
-			found := false
-			for _, c := range tg.Constraints {
-				if c.Equal(vaultConstraint) {
-					found = true
-					break
-				}
-			}
-
-			if !found {
-				tg.Constraints = append(tg.Constraints, vaultConstraint)
+	// Keep the source of the job unless it is too large to be stored. The
+	// Vault token is redacted from it as it is cleared from the job.
+	if args.Submission != nil {
+		if err := args.Submission.Validate(); err != nil {
+			return err
+		}
+		if len(args.Submission.Source) > structs.MaxJobSubmissionSize {
+			j.srv.logger.Printf("[WARN] nomad.job: discarding the source of job %q as it exceeds %d bytes",
+				args.Job.ID, structs.MaxJobSubmissionSize)
+			args.Submission = nil
+		} else {
+			args.Submission.JobID = args.Job.ID
+			args.Submission.SubmitTime = time.Now().UTC().UnixNano()
+			if args.Job.VaultToken != "" {
+				args.Submission.Source = strings.Replace(args.Submission.Source,
+					args.Job.VaultToken, "<redacted>", -1)
 			}
 		}
 	}
@@ -152,14 +201,18 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 	// Populate the reply with job information
 	reply.JobModifyIndex = index
 
-	// If the job is periodic, we don't create an eval.
-	if args.Job.IsPeriodic() {
-		return nil
+	// If the job is periodic or parameterized, we don't create an eval. Nor
+	// do we while the multi-region rollout of the job has yet to reach the
+	// region; the rollout evaluates the job once it does.
+	if args.Job.IsPeriodic() || args.Job.IsParameterized() || args.DeferEval ||
+		(!args.RegionForwarded && args.Job.MultiregionDeferred(j.srv.config.Region)) {
+		return j.registerRegions(args, submitted, reply)
 	}
 
 	// Create a new evaluation
 	eval := &structs.Evaluation{
 		ID:             structs.GenerateUUID(),
+		Namespace:      args.Job.Namespace,
 		Priority:       args.Job.Priority,
 		Type:           args.Job.Type,
 		TriggeredBy:    structs.EvalTriggerJobRegister,
@@ -185,6 +238,144 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 	reply.EvalID = eval.ID
 	reply.EvalCreateIndex = evalIndex
 	reply.Index = evalIndex
+	return j.registerRegions(args, submitted, reply)
+}
+
+// registerRegions registers a multi-region job in each of its regions other
+// than the local one by forwarding the submitted job to them. A failure to
+// register the job in one region doesn't stop it being registered in the
+// others; the failures are returned together. If the job configures a
+// multi-region rollout, the regions the rollout has yet to reach register the
+// job without evaluating it and the rollout is created for the leader to
+// advance.
+func (j *Job) registerRegions(args *structs.JobRegisterRequest, submitted *structs.Job,
+	reply *structs.JobRegisterResponse) error {
+	if args.RegionForwarded || len(submitted.Regions) == 0 {
+		return nil
+	}
+
+	var rollout *structs.MultiregionRollout
+	if args.Job.Multiregion != nil {
+		rollout = &structs.MultiregionRollout{
+			JobID:     args.Job.ID,
+			Namespace: args.Job.Namespace,
+			Strategy:  args.Job.Multiregion.Copy(),
+			Status:    structs.MultiregionRolloutStatusRunning,
+		}
+	}
+
+	var mErr multierror.Error
+	for _, region := range submitted.Regions {
+		deferEval := args.Job.MultiregionDeferred(region)
+		rolloutRegion := &structs.MultiregionRolloutRegion{
+			Region: region,
+			Status: structs.MultiregionRegionStatusRunning,
+		}
+		if deferEval {
+			rolloutRegion.Status = structs.MultiregionRegionStatusPending
+		}
+		if rollout != nil {
+			rollout.Regions = append(rollout.Regions, rolloutRegion)
+		}
+
+		if region == j.srv.config.Region {
+			rolloutRegion.JobModifyIndex = reply.JobModifyIndex
+			rolloutRegion.EvalID = reply.EvalID
+			continue
+		}
+
+		job := submitted.Copy()
+		job.Region = region
+		req := &structs.JobRegisterRequest{
+			Job:             job,
+			RegionForwarded: true,
+			DeferEval:       deferEval,
+			Submission:      args.Submission.Copy(),
+			WriteRequest: structs.WriteRequest{
+				Region:    region,
+				Namespace: args.Namespace,
+				AuthToken: args.AuthToken,
+			},
+		}
+		var resp structs.JobRegisterResponse
+		if err := j.srv.forwardRegion(region, "Job.Register", req, &resp); err != nil {
+			j.srv.logger.Printf("[ERR] nomad.job: Register in region %q failed: %v", region, err)
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("failed to register job in region %q: %v", region, err))
+			rolloutRegion.Status = structs.MultiregionRegionStatusFailed
+			rolloutRegion.StatusDescription = fmt.Sprintf("failed to register job: %v", err)
+			continue
+		}
+		rolloutRegion.JobModifyIndex = resp.JobModifyIndex
+		rolloutRegion.EvalID = resp.EvalID
+
+		if resp.EvalID != "" {
+			if reply.RegionEvalIDs == nil {
+				reply.RegionEvalIDs = make(map[string]string)
+			}
+			reply.RegionEvalIDs[region] = resp.EvalID
+		}
+	}
+
+	// Commit the rollout for the leader to advance
+	if rollout != nil {
+		req := &structs.MultiregionRolloutUpsertRequest{
+			Rollout:      rollout,
+			WriteRequest: structs.WriteRequest{Region: args.Region},
+		}
+		if _, _, err := j.srv.raftApply(structs.MultiregionRolloutUpsertRequestType, req); err != nil {
+			j.srv.logger.Printf("[ERR] nomad.job: Multi-region rollout create failed: %v", err)
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// checkVaultPolicies ensures that the Vault cluster is enabled and, unless it
+// allows unauthenticated access, that the Vault token of the job has access
+// to the policies requested from it
+func (j *Job) checkVaultPolicies(cluster string, policies map[string]map[string]*structs.Vault, token string) error {
+	vconf, err := j.srv.vaultConfig(cluster)
+	if err != nil {
+		return err
+	}
+	if !vconf.Enabled {
+		if cluster == structs.DefaultCluster {
+			return fmt.Errorf("Vault not enabled and Vault policies requested")
+		}
+		return fmt.Errorf("Vault cluster %q not enabled and Vault policies requested", cluster)
+	}
+
+	// Have to check if the user has permissions
+	if vconf.AllowUnauthenticated {
+		return nil
+	}
+	if token == "" {
+		return fmt.Errorf("Vault policies requested but missing Vault Token")
+	}
+
+	vault, err := j.srv.vaultClient(cluster)
+	if err != nil {
+		return err
+	}
+	s, err := vault.LookupToken(context.Background(), token)
+	if err != nil {
+		return err
+	}
+
+	allowedPolicies, err := PoliciesFrom(s)
+	if err != nil {
+		return err
+	}
+
+	// If we are given a root token it can access all policies
+	if !lib.StrContains(allowedPolicies, "root") {
+		flatPolicies := structs.VaultPoliciesSet(policies)
+		subset, offending := structs.SliceStringIsSubset(allowedPolicies, flatPolicies)
+		if !subset {
+			return fmt.Errorf("Passed Vault Token doesn't allow access to the following policies: %s",
+				strings.Join(offending, ", "))
+		}
+	}
 	return nil
 }
 
@@ -195,19 +386,20 @@ func (j *Job) Summary(args *structs.JobSummaryRequest,
 		return err
 	}
 	defer metrics.MeasureSince([]string{"nomad", "job_summary", "get_job_summary"}, time.Now())
+
+	// Check for read-job permissions
+	if err := j.checkJobCapability(args.AuthToken, args.RequestNamespace(), acl.NamespaceCapabilityReadJob); err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		watch:     watch.NewItems(watch.Item{JobSummary: args.JobID}),
-		run: func() error {
-			snap, err := j.srv.fsm.State().Snapshot()
-			if err != nil {
-				return err
-			}
-
+		run: func(snap *state.StateSnapshot) error {
 			// Look for job summary
-			out, err := snap.JobSummaryByID(args.JobID)
+			out, err := snap.JobSummaryByID(args.RequestNamespace(), args.JobID)
 			if err != nil {
 				return err
 			}
@@ -244,12 +436,53 @@ func (j *Job) Evaluate(args *structs.JobEvaluateRequest, reply *structs.JobRegis
 		return fmt.Errorf("missing job ID for evaluation")
 	}
 
+	eval := &structs.Evaluation{
+		TriggeredBy: structs.EvalTriggerJobRegister,
+	}
+	return j.createJobEval(args.RequestNamespace(), args.JobID, args.AuthToken, args.Region, eval, reply)
+}
+
+// Trigger is used by external systems to create an evaluation of a job,
+// such as when the data it processes becomes available. The reason given is
+// recorded on the evaluation so that it can be audited.
+func (j *Job) Trigger(args *structs.JobTriggerRequest, reply *structs.JobRegisterResponse) error {
+	if done, err := j.srv.forward("Job.Trigger", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "trigger"}, time.Now())
+
+	// Validate the arguments
+	if args.JobID == "" {
+		return fmt.Errorf("missing job ID for evaluation")
+	}
+	if args.Reason == "" {
+		return fmt.Errorf("missing reason for evaluation")
+	}
+	if len(args.Reason) > structs.MaxTriggerReasonLength {
+		return fmt.Errorf("reason for evaluation exceeds maximum length of %d", structs.MaxTriggerReasonLength)
+	}
+
+	eval := &structs.Evaluation{
+		TriggeredBy:   structs.EvalTriggerCustom,
+		TriggerReason: args.Reason,
+	}
+	if err := j.createJobEval(args.RequestNamespace(), args.JobID, args.AuthToken, args.Region, eval, reply); err != nil {
+		return err
+	}
+	j.srv.logger.Printf("[INFO] nomad.job: evaluation %q of job %q triggered: %s", eval.ID, args.JobID, args.Reason)
+	return nil
+}
+
+// createJobEval creates the given evaluation of an existing job, once the
+// token is checked for submit-job permissions
+func (j *Job) createJobEval(namespace, jobID, token, region string, eval *structs.Evaluation,
+	reply *structs.JobRegisterResponse) error {
 	// Lookup the job
 	snap, err := j.srv.fsm.State().Snapshot()
 	if err != nil {
 		return err
 	}
-	job, err := snap.JobByID(args.JobID)
+	job, err := snap.JobByID(namespace, jobID)
 	if err != nil {
 		return err
 	}
@@ -257,23 +490,28 @@ func (j *Job) Evaluate(args *structs.JobEvaluateRequest, reply *structs.JobRegis
 		return fmt.Errorf("job not found")
 	}
 
+	// Check for submit-job permissions
+	if err := j.checkJobCapability(token, job.Namespace, acl.NamespaceCapabilitySubmitJob); err != nil {
+		return err
+	}
+
 	if job.IsPeriodic() {
 		return fmt.Errorf("can't evaluate periodic job")
+	} else if job.IsParameterized() {
+		return fmt.Errorf("can't evaluate parameterized job")
 	}
 
 	// Create a new evaluation
-	eval := &structs.Evaluation{
-		ID:             structs.GenerateUUID(),
-		Priority:       job.Priority,
-		Type:           job.Type,
-		TriggeredBy:    structs.EvalTriggerJobRegister,
-		JobID:          job.ID,
-		JobModifyIndex: job.ModifyIndex,
-		Status:         structs.EvalStatusPending,
-	}
+	eval.ID = structs.GenerateUUID()
+	eval.Namespace = job.Namespace
+	eval.Priority = job.Priority
+	eval.Type = job.Type
+	eval.JobID = job.ID
+	eval.JobModifyIndex = job.ModifyIndex
+	eval.Status = structs.EvalStatusPending
 	update := &structs.EvalUpdateRequest{
 		Evals:        []*structs.Evaluation{eval},
-		WriteRequest: structs.WriteRequest{Region: args.Region},
+		WriteRequest: structs.WriteRequest{Region: region},
 	}
 
 	// Commit this evaluation via Raft
@@ -291,7 +529,8 @@ func (j *Job) Evaluate(args *structs.JobEvaluateRequest, reply *structs.JobRegis
 	return nil
 }
 
-// Deregister is used to remove a job the cluster.
+// Deregister is used to stop a job in the cluster. If the request sets Purge,
+// the job is removed from the cluster entirely.
 func (j *Job) Deregister(args *structs.JobDeregisterRequest, reply *structs.JobDeregisterResponse) error {
 	if done, err := j.srv.forward("Job.Deregister", args, args, reply); done {
 		return err
@@ -308,11 +547,16 @@ func (j *Job) Deregister(args *structs.JobDeregisterRequest, reply *structs.JobD
 	if err != nil {
 		return err
 	}
-	job, err := snap.JobByID(args.JobID)
+	job, err := snap.JobByID(args.RequestNamespace(), args.JobID)
 	if err != nil {
 		return err
 	}
 
+	// Check for submit-job permissions
+	if err := j.checkJobCapability(args.AuthToken, args.RequestNamespace(), acl.NamespaceCapabilitySubmitJob); err != nil {
+		return err
+	}
+
 	// Commit this update via Raft
 	_, index, err := j.srv.raftApply(structs.JobDeregisterRequestType, args)
 	if err != nil {
@@ -323,8 +567,8 @@ func (j *Job) Deregister(args *structs.JobDeregisterRequest, reply *structs.JobD
 	// Populate the reply with job information
 	reply.JobModifyIndex = index
 
-	// If the job is periodic, we don't create an eval.
-	if job != nil && job.IsPeriodic() {
+	// If the job is periodic or parameterized, we don't create an eval.
+	if job != nil && (job.IsPeriodic() || job.IsParameterized()) {
 		return nil
 	}
 
@@ -332,8 +576,13 @@ func (j *Job) Deregister(args *structs.JobDeregisterRequest, reply *structs.JobD
 	// XXX: The job priority / type is strange for this, since it's not a high
 	// priority even if the job was. The scheduler itself also doesn't matter,
 	// since all should be able to handle deregistration in the same way.
+	namespace := args.RequestNamespace()
+	if job != nil {
+		namespace = job.Namespace
+	}
 	eval := &structs.Evaluation{
 		ID:             structs.GenerateUUID(),
+		Namespace:      namespace,
 		Priority:       structs.JobDefaultPriority,
 		Type:           structs.JobTypeService,
 		TriggeredBy:    structs.EvalTriggerJobDeregister,
@@ -368,19 +617,19 @@ func (j *Job) GetJob(args *structs.JobSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "job", "get_job"}, time.Now())
 
+	// Check for read-job permissions
+	if err := j.checkJobCapability(args.AuthToken, args.RequestNamespace(), acl.NamespaceCapabilityReadJob); err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		watch:     watch.NewItems(watch.Item{Job: args.JobID}),
-		run: func() error {
-
+		run: func(snap *state.StateSnapshot) error {
 			// Look for the job
-			snap, err := j.srv.fsm.State().Snapshot()
-			if err != nil {
-				return err
-			}
-			out, err := snap.JobByID(args.JobID)
+			out, err := snap.JobByID(args.RequestNamespace(), args.JobID)
 			if err != nil {
 				return err
 			}
@@ -405,6 +654,52 @@ func (j *Job) GetJob(args *structs.JobSpecificRequest,
 	return j.srv.blockingRPC(&opts)
 }
 
+// GetJobSubmission is used to request the source of the latest submission of
+// a job
+func (j *Job) GetJobSubmission(args *structs.JobSpecificRequest,
+	reply *structs.JobSubmissionResponse) error {
+	if done, err := j.srv.forward("Job.GetJobSubmission", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "get_job_submission"}, time.Now())
+
+	// Check for read-job permissions
+	if err := j.checkJobCapability(args.AuthToken, args.RequestNamespace(), acl.NamespaceCapabilityReadJob); err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "job_submission"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Look for the source of the job
+			out, err := snap.JobSubmissionByJob(args.RequestNamespace(), args.JobID)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Submission = out
+			if out != nil {
+				reply.Index = out.JobModifyIndex
+			} else {
+				// Use the last index that affected the job submission table
+				index, err := snap.Index("job_submission")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			j.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return j.srv.blockingRPC(&opts)
+}
+
 // List is used to list the jobs registered in the system
 func (j *Job) List(args *structs.JobListRequest,
 	reply *structs.JobListResponse) error {
@@ -413,41 +708,74 @@ func (j *Job) List(args *structs.JobListRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "job", "list"}, time.Now())
 
+	// Check for list-job permissions. Queries across all namespaces are
+	// filtered to the namespaces the token may list instead.
+	aclObj, err := j.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	namespace := args.RequestNamespace()
+	allNamespaces := namespace == structs.AllNamespacesSentinel
+	if !allNamespaces && aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityListJobs) {
+		return structs.ErrPermissionDenied
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		watch:     watch.NewItems(watch.Item{Table: "jobs"}),
-		run: func() error {
+		run: func(snap *state.StateSnapshot) error {
 			// Capture all the jobs
-			snap, err := j.srv.fsm.State().Snapshot()
-			if err != nil {
-				return err
+			var err error
+			var allowed map[string]bool
+			if allNamespaces {
+				allowed, err = allowedNamespaces(aclObj, snap, acl.NamespaceCapabilityListJobs)
+				if err != nil {
+					return err
+				}
 			}
+
 			var iter memdb.ResultIterator
 			if prefix := args.QueryOptions.Prefix; prefix != "" {
-				iter, err = snap.JobsByIDPrefix(prefix)
-			} else {
+				iter, err = snap.JobsByIDPrefix(namespace, prefix)
+			} else if allNamespaces {
 				iter, err = snap.Jobs()
+			} else {
+				iter, err = snap.JobsByNamespace(namespace)
+			}
+			if err != nil {
+				return err
 			}
+
+			pager, err := newPaginator(&args.QueryOptions)
 			if err != nil {
 				return err
 			}
 
 			var jobs []*structs.JobListStub
-			for {
+			for !pager.done() {
 				raw := iter.Next()
 				if raw == nil {
 					break
 				}
 				job := raw.(*structs.Job)
-				summary, err := snap.JobSummaryByID(job.ID)
+				if !namespaceMatches(job.Namespace, namespace, allowed) {
+					continue
+				}
+				summary, err := snap.JobSummaryByID(job.Namespace, job.ID)
 				if err != nil {
 					return fmt.Errorf("unable to look up summary for job: %v", job.ID)
 				}
-				jobs = append(jobs, job.Stub(summary))
+				stub := job.Stub(summary)
+				if ok, err := pager.accept(job.ID, stub); err != nil {
+					return err
+				} else if ok {
+					jobs = append(jobs, stub)
+				}
 			}
 			reply.Jobs = jobs
+			reply.NextToken = pager.nextToken
 
 			// Use the last index that affected the jobs table
 			index, err := snap.Index("jobs")
@@ -463,83 +791,184 @@ func (j *Job) List(args *structs.JobListRequest,
 	return j.srv.blockingRPC(&opts)
 }
 
-// Allocations is used to list the allocations for a job
-func (j *Job) Allocations(args *structs.JobSpecificRequest,
-	reply *structs.JobAllocationsResponse) error {
-	if done, err := j.srv.forward("Job.Allocations", args, args, reply); done {
+// GetJobVersions is used to retrieve the tracked versions of a job, most
+// recent first
+func (j *Job) GetJobVersions(args *structs.JobSpecificRequest,
+	reply *structs.JobVersionsResponse) error {
+	if done, err := j.srv.forward("Job.GetJobVersions", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "get_job_versions"}, time.Now())
+
+	// Check for read-job permissions
+	if err := j.checkJobCapability(args.AuthToken, args.RequestNamespace(), acl.NamespaceCapabilityReadJob); err != nil {
 		return err
 	}
-	defer metrics.MeasureSince([]string{"nomad", "job", "allocations"}, time.Now())
 
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
-		watch:     watch.NewItems(watch.Item{AllocJob: args.JobID}),
-		run: func() error {
-			// Capture the allocations
-			snap, err := j.srv.fsm.State().Snapshot()
-			if err != nil {
-				return err
-			}
-			allocs, err := snap.AllocsByJob(args.JobID)
+		watch:     watch.NewItems(watch.Item{Job: args.JobID}),
+		run: func(snap *state.StateSnapshot) error {
+			// Look for the versions of the job
+			out, err := snap.JobVersionsByID(args.RequestNamespace(), args.JobID)
 			if err != nil {
 				return err
 			}
 
-			// Convert to stubs
-			if len(allocs) > 0 {
-				reply.Allocations = make([]*structs.AllocListStub, 0, len(allocs))
-				for _, alloc := range allocs {
-					reply.Allocations = append(reply.Allocations, alloc.Stub())
+			// Setup the output
+			if out != nil {
+				reply.Versions = out.Versions
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the job version table
+				index, err := snap.Index("job_version")
+				if err != nil {
+					return err
 				}
+				reply.Index = index
 			}
 
-			// Use the last index that affected the allocs table
-			index, err := snap.Index("allocs")
-			if err != nil {
-				return err
-			}
-			reply.Index = index
-
 			// Set the query response
 			j.srv.setQueryMeta(&reply.QueryMeta)
 			return nil
-
 		}}
 	return j.srv.blockingRPC(&opts)
 }
 
-// Evaluations is used to list the evaluations for a job
-func (j *Job) Evaluations(args *structs.JobSpecificRequest,
-	reply *structs.JobEvaluationsResponse) error {
-	if done, err := j.srv.forward("Job.Evaluations", args, args, reply); done {
+// VersionDiff is used to compute the diff between two tracked versions of a
+// job without scheduling anything
+func (j *Job) VersionDiff(args *structs.JobVersionDiffRequest,
+	reply *structs.JobVersionDiffResponse) error {
+	if done, err := j.srv.forward("Job.VersionDiff", args, args, reply); done {
 		return err
 	}
-	defer metrics.MeasureSince([]string{"nomad", "job", "evaluations"}, time.Now())
+	defer metrics.MeasureSince([]string{"nomad", "job", "version_diff"}, time.Now())
 
-	// Capture the evaluations
-	snap, err := j.srv.fsm.State().Snapshot()
-	if err != nil {
+	// Check for read-job permissions
+	if err := j.checkJobCapability(args.AuthToken, args.RequestNamespace(), acl.NamespaceCapabilityReadJob); err != nil {
 		return err
 	}
-	reply.Evaluations, err = snap.EvalsByJob(args.JobID)
+
+	snap, err := j.srv.fsm.State().Snapshot()
 	if err != nil {
 		return err
 	}
-
-	// Use the last index that affected the evals table
-	index, err := snap.Index("evals")
+	versions, err := snap.JobVersionsByID(args.RequestNamespace(), args.JobID)
 	if err != nil {
 		return err
 	}
-	reply.Index = index
 
-	// Set the query response
+	// Diff the versions if both are tracked
+	from, to := versions.Version(args.FromVersion), versions.Version(args.ToVersion)
+	if from != nil && to != nil {
+		reply.Diff, err = from.Diff(to, args.Contextual)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Use the last index that affected the job version table
+	index, err := snap.Index("job_version")
+	if err != nil {
+		return err
+	}
+	reply.Index = index
+
+	// Set the query response
 	j.srv.setQueryMeta(&reply.QueryMeta)
 	return nil
 }
 
+// Allocations is used to list the allocations for a job
+func (j *Job) Allocations(args *structs.JobSpecificRequest,
+	reply *structs.JobAllocationsResponse) error {
+	if done, err := j.srv.forward("Job.Allocations", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "allocations"}, time.Now())
+
+	// Check for read-job permissions
+	if err := j.checkJobCapability(args.AuthToken, args.RequestNamespace(), acl.NamespaceCapabilityReadJob); err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{AllocJob: args.JobID}),
+		run: func(snap *state.StateSnapshot) error {
+			// Capture the allocations
+			allocs, err := snap.AllocsByJob(args.RequestNamespace(), args.JobID)
+			if err != nil {
+				return err
+			}
+
+			// Convert to stubs
+			if len(allocs) > 0 {
+				reply.Allocations = make([]*structs.AllocListStub, 0, len(allocs))
+				for _, alloc := range allocs {
+					reply.Allocations = append(reply.Allocations, alloc.Stub())
+				}
+			}
+
+			// Use the last index that affected the allocs table
+			index, err := snap.Index("allocs")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			j.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+
+		}}
+	return j.srv.blockingRPC(&opts)
+}
+
+// Evaluations is used to list the evaluations for a job
+func (j *Job) Evaluations(args *structs.JobSpecificRequest,
+	reply *structs.JobEvaluationsResponse) error {
+	if done, err := j.srv.forward("Job.Evaluations", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "evaluations"}, time.Now())
+
+	// Check for read-job permissions
+	if err := j.checkJobCapability(args.AuthToken, args.RequestNamespace(), acl.NamespaceCapabilityReadJob); err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{EvalJob: args.JobID}),
+		run: func(snap *state.StateSnapshot) error {
+			// Capture the evaluations
+			var err error
+			reply.Evaluations, err = snap.EvalsByJob(args.RequestNamespace(), args.JobID)
+			if err != nil {
+				return err
+			}
+
+			// Use the last index that affected the evals table
+			index, err := snap.Index("evals")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			j.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return j.srv.blockingRPC(&opts)
+}
+
 // Plan is used to cause a dry-run evaluation of the Job and return the results
 // with a potential diff containing annotations.
 func (j *Job) Plan(args *structs.JobPlanRequest, reply *structs.JobPlanResponse) error {
@@ -553,6 +982,18 @@ func (j *Job) Plan(args *structs.JobPlanRequest, reply *structs.JobPlanResponse)
 		return fmt.Errorf("Job required for plan")
 	}
 
+	// Jobs without a namespace are planned in the request's namespace
+	if args.Job.Namespace == "" {
+		args.Job.Namespace = args.RequestNamespace()
+	}
+
+	// Check job submission permissions
+	if aclObj, err := j.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNamespaceOperation(args.Job.Namespace, acl.NamespaceCapabilitySubmitJob) {
+		return structs.ErrPermissionDenied
+	}
+
 	// Initialize the job fields (sets defaults and any necessary init work).
 	args.Job.Canonicalize()
 
@@ -567,8 +1008,13 @@ func (j *Job) Plan(args *structs.JobPlanRequest, reply *structs.JobPlanResponse)
 		return err
 	}
 
+	// Ensure the job is admitted in its namespace
+	if err := admitJob(snap, args.Job); err != nil {
+		return err
+	}
+
 	// Get the original job
-	oldJob, err := snap.JobByID(args.Job.ID)
+	oldJob, err := snap.JobByID(args.Job.Namespace, args.Job.ID)
 	if err != nil {
 		return err
 	}
@@ -586,6 +1032,7 @@ func (j *Job) Plan(args *structs.JobPlanRequest, reply *structs.JobPlanResponse)
 	// Create an eval and mark it as requiring annotations and insert that as well
 	eval := &structs.Evaluation{
 		ID:             structs.GenerateUUID(),
+		Namespace:      args.Job.Namespace,
 		Priority:       args.Job.Priority,
 		Type:           args.Job.Type,
 		TriggeredBy:    structs.EvalTriggerJobRegister,
@@ -602,7 +1049,7 @@ func (j *Job) Plan(args *structs.JobPlanRequest, reply *structs.JobPlanResponse)
 	}
 
 	// Create the scheduler and run it
-	sched, err := scheduler.NewScheduler(eval.Type, j.srv.logger, snap, planner)
+	sched, err := j.srv.config.SchedulerMapping.NewScheduler(eval.Type, j.srv.logger, snap, planner)
 	if err != nil {
 		return err
 	}
@@ -647,6 +1094,470 @@ func (j *Job) Plan(args *structs.JobPlanRequest, reply *structs.JobPlanResponse)
 	return nil
 }
 
+// Dispatch a parameterized job.
+func (j *Job) Dispatch(args *structs.JobDispatchRequest, reply *structs.JobDispatchResponse) error {
+	if done, err := j.srv.forward("Job.Dispatch", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "dispatch"}, time.Now())
+
+	// Lookup the parameterized job
+	if args.JobID == "" {
+		return fmt.Errorf("missing parameterized job ID")
+	}
+
+	snap, err := j.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	parameterizedJob, err := snap.JobByID(args.RequestNamespace(), args.JobID)
+	if err != nil {
+		return err
+	}
+	if parameterizedJob == nil {
+		return fmt.Errorf("parameterized job not found")
+	}
+
+	// Check for submit-job permissions
+	if err := j.checkJobCapability(args.AuthToken, parameterizedJob.Namespace, acl.NamespaceCapabilitySubmitJob); err != nil {
+		return err
+	}
+
+	if !parameterizedJob.IsParameterized() {
+		return fmt.Errorf("Specified job %q is not a parameterized job", args.JobID)
+	}
+
+	if parameterizedJob.Stop {
+		return fmt.Errorf("Specified job %q is stopped", args.JobID)
+	}
+
+	// Validate the arguments
+	if err := validateDispatchRequest(args, parameterizedJob); err != nil {
+		return err
+	}
+
+	// Derive the child job and commit it via Raft
+	dispatchJob := parameterizedJob.Copy()
+	dispatchJob.ID = structs.DispatchedID(parameterizedJob.ID, time.Now())
+	dispatchJob.ParentID = parameterizedJob.ID
+	dispatchJob.Name = dispatchJob.ID
+	dispatchJob.Dispatched = true
+	dispatchJob.Status = ""
+	dispatchJob.StatusDescription = ""
+
+	// Merge in the meta data
+	for k, v := range args.Meta {
+		if dispatchJob.Meta == nil {
+			dispatchJob.Meta = make(map[string]string, len(args.Meta))
+		}
+		dispatchJob.Meta[k] = v
+	}
+
+	// Attach the payload
+	dispatchJob.Payload = args.Payload
+
+	regReq := &structs.JobRegisterRequest{
+		Job:          dispatchJob,
+		WriteRequest: args.WriteRequest,
+	}
+
+	// Commit this update via Raft
+	_, jobCreateIndex, err := j.srv.raftApply(structs.JobRegisterRequestType, regReq)
+	if err != nil {
+		j.srv.logger.Printf("[ERR] nomad.job: Dispatched job register failed: %v", err)
+		return err
+	}
+
+	reply.JobCreateIndex = jobCreateIndex
+	reply.DispatchedJobID = dispatchJob.ID
+	reply.Index = jobCreateIndex
+
+	// If the job is periodic, we don't create an eval.
+	if dispatchJob.IsPeriodic() {
+		return nil
+	}
+
+	// Create a new evaluation
+	eval := &structs.Evaluation{
+		ID:             structs.GenerateUUID(),
+		Namespace:      dispatchJob.Namespace,
+		Priority:       dispatchJob.Priority,
+		Type:           dispatchJob.Type,
+		TriggeredBy:    structs.EvalTriggerJobRegister,
+		JobID:          dispatchJob.ID,
+		JobModifyIndex: jobCreateIndex,
+		Status:         structs.EvalStatusPending,
+	}
+	update := &structs.EvalUpdateRequest{
+		Evals:        []*structs.Evaluation{eval},
+		WriteRequest: structs.WriteRequest{Region: args.Region},
+	}
+
+	// Commit this evaluation via Raft
+	_, evalIndex, err := j.srv.raftApply(structs.EvalUpdateRequestType, update)
+	if err != nil {
+		j.srv.logger.Printf("[ERR] nomad.job: Eval create failed: %v", err)
+		return err
+	}
+
+	// Setup the reply
+	reply.EvalID = eval.ID
+	reply.EvalCreateIndex = evalIndex
+	reply.Index = evalIndex
+	return nil
+}
+
+// Scale is used to change the count of a task group of a job. A scaling event
+// is recorded for the task group whether or not its count is changed, so
+// autoscalers can also report the decisions and errors that didn't lead to a
+// change.
+func (j *Job) Scale(args *structs.JobScaleRequest, reply *structs.JobRegisterResponse) error {
+	if done, err := j.srv.forward("Job.Scale", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "scale"}, time.Now())
+
+	// Validate the arguments
+	if args.JobID == "" {
+		return fmt.Errorf("missing job ID for scaling")
+	}
+	groupName := args.Target[structs.ScalingTargetGroup]
+	if groupName == "" {
+		return fmt.Errorf("missing task group name for scaling")
+	}
+	if args.Count != nil && *args.Count < 0 {
+		return fmt.Errorf("scaling count can't be negative")
+	}
+
+	// Lookup the job
+	snap, err := j.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	job, err := snap.JobByID(args.RequestNamespace(), args.JobID)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return fmt.Errorf("job not found")
+	}
+
+	// Check for scale-job permissions, which tokens that can submit the job
+	// have as well
+	if err := j.checkJobCapability(args.AuthToken, job.Namespace, acl.NamespaceCapabilityScaleJob); err != nil {
+		if err := j.checkJobCapability(args.AuthToken, job.Namespace, acl.NamespaceCapabilitySubmitJob); err != nil {
+			return err
+		}
+	}
+
+	// Enforce the job modify index, so that scaling decisions based on an
+	// outdated version of the job are rejected rather than applied twice
+	if args.EnforceIndex && args.JobModifyIndex != job.JobModifyIndex {
+		return fmt.Errorf("%s %d: job exists with conflicting job modify index: %d",
+			RegisterEnforceIndexErrPrefix, args.JobModifyIndex, job.JobModifyIndex)
+	}
+
+	group := job.LookupTaskGroup(groupName)
+	if group == nil {
+		return fmt.Errorf("task group %q specified for scaling does not exist in job", groupName)
+	}
+
+	event := &structs.ScalingEvent{
+		Time:          time.Now().UnixNano(),
+		Count:         args.Count,
+		PreviousCount: int64(group.Count),
+		Message:       args.Message,
+		Error:         args.Error,
+		Meta:          args.Meta,
+	}
+	reply.JobModifyIndex = job.JobModifyIndex
+
+	if args.Count != nil {
+		if job.Type == structs.JobTypeSystem {
+			return fmt.Errorf("can't scale system job")
+		}
+		if job.Stop {
+			return fmt.Errorf("can't scale stopped job")
+		}
+
+		// Enforce the bounds of the scaling policy
+		count := *args.Count
+		if policy := group.Scaling; policy != nil && !args.PolicyOverride {
+			if count > policy.Max {
+				return fmt.Errorf("task group count %d is greater than the scaling policy maximum %d", count, policy.Max)
+			} else if count < policy.Min {
+				return fmt.Errorf("task group count %d is less than the scaling policy minimum %d", count, policy.Min)
+			}
+		}
+
+		if count != int64(group.Count) {
+			// Commit the scaled job via Raft
+			scaled := job.Copy()
+			scaled.LookupTaskGroup(groupName).Count = int(count)
+			regReq := &structs.JobRegisterRequest{
+				Job:          scaled,
+				WriteRequest: args.WriteRequest,
+			}
+			_, index, err := j.srv.raftApply(structs.JobRegisterRequestType, regReq)
+			if err != nil {
+				j.srv.logger.Printf("[ERR] nomad.job: Scale failed: %v", err)
+				return err
+			}
+			reply.JobModifyIndex = index
+
+			// Periodic and parameterized jobs only scale the jobs they launch
+			if !scaled.IsPeriodic() && !scaled.IsParameterized() {
+				eval := &structs.Evaluation{
+					ID:             structs.GenerateUUID(),
+					Namespace:      scaled.Namespace,
+					Priority:       scaled.Priority,
+					Type:           scaled.Type,
+					TriggeredBy:    structs.EvalTriggerJobRegister,
+					JobID:          scaled.ID,
+					JobModifyIndex: index,
+					Status:         structs.EvalStatusPending,
+				}
+				update := &structs.EvalUpdateRequest{
+					Evals:        []*structs.Evaluation{eval},
+					WriteRequest: structs.WriteRequest{Region: args.Region},
+				}
+
+				// Commit this evaluation via Raft
+				_, evalIndex, err := j.srv.raftApply(structs.EvalUpdateRequestType, update)
+				if err != nil {
+					j.srv.logger.Printf("[ERR] nomad.job: Eval create failed: %v", err)
+					return err
+				}
+				reply.EvalID = eval.ID
+				reply.EvalCreateIndex = evalIndex
+				event.EvalID = eval.ID
+			}
+		}
+	}
+
+	// Record the scaling event
+	eventReq := &structs.ScalingEventRegisterRequest{
+		JobID:        job.ID,
+		TaskGroup:    groupName,
+		ScalingEvent: event,
+		WriteRequest: structs.WriteRequest{Region: args.Region},
+	}
+	_, index, err := j.srv.raftApply(structs.ScalingEventRegisterRequestType, eventReq)
+	if err != nil {
+		j.srv.logger.Printf("[ERR] nomad.job: Scaling event register failed: %v", err)
+		return err
+	}
+	reply.Index = index
+	return nil
+}
+
+// ScaleStatus is used to get the scaling status of the task groups of a job
+func (j *Job) ScaleStatus(args *structs.JobSpecificRequest,
+	reply *structs.JobScaleStatusResponse) error {
+	if done, err := j.srv.forward("Job.ScaleStatus", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "scale_status"}, time.Now())
+
+	// Check for read-job permissions
+	if err := j.checkJobCapability(args.AuthToken, args.RequestNamespace(), acl.NamespaceCapabilityReadJob); err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch: watch.NewItems(
+			watch.Item{Job: args.JobID},
+			watch.Item{AllocJob: args.JobID},
+			watch.Item{Table: "scaling_event"},
+		),
+		run: func(snap *state.StateSnapshot) error {
+			job, err := snap.JobByID(args.RequestNamespace(), args.JobID)
+			if err != nil {
+				return err
+			}
+			if job == nil {
+				reply.JobScaleStatus = nil
+
+				// Use the last index that affected the jobs table
+				index, err := snap.Index("jobs")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+				j.srv.setQueryMeta(&reply.QueryMeta)
+				return nil
+			}
+
+			events, err := snap.ScalingEventsByJob(args.RequestNamespace(), args.JobID)
+			if err != nil {
+				return err
+			}
+			allocs, err := snap.AllocsByJob(args.RequestNamespace(), args.JobID)
+			if err != nil {
+				return err
+			}
+
+			status := &structs.JobScaleStatus{
+				JobID:          job.ID,
+				JobCreateIndex: job.CreateIndex,
+				JobModifyIndex: job.ModifyIndex,
+				JobStopped:     job.Stop,
+				TaskGroups:     make(map[string]*structs.TaskGroupScaleStatus, len(job.TaskGroups)),
+			}
+			for _, tg := range job.TaskGroups {
+				tgStatus := &structs.TaskGroupScaleStatus{Desired: tg.Count}
+				if events != nil {
+					tgStatus.Events = events.ScalingEvents[tg.Name]
+				}
+				status.TaskGroups[tg.Name] = tgStatus
+			}
+			for _, alloc := range allocs {
+				tgStatus, ok := status.TaskGroups[alloc.TaskGroup]
+				if !ok || alloc.TerminalStatus() {
+					continue
+				}
+				tgStatus.Placed++
+				if alloc.ClientStatus == structs.AllocClientStatusRunning {
+					tgStatus.Running++
+				}
+			}
+			reply.JobScaleStatus = status
+
+			// Use the last index that affected the job, its allocations or
+			// its scaling events
+			reply.Index = job.ModifyIndex
+			for _, alloc := range allocs {
+				if alloc.ModifyIndex > reply.Index {
+					reply.Index = alloc.ModifyIndex
+				}
+			}
+			if events != nil && events.ModifyIndex > reply.Index {
+				reply.Index = events.ModifyIndex
+			}
+
+			// Set the query response
+			j.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return j.srv.blockingRPC(&opts)
+}
+
+// Rollout is used to retrieve the multi-region rollout of a job. Rollouts
+// are tracked by the region the job was submitted to.
+func (j *Job) Rollout(args *structs.JobSpecificRequest,
+	reply *structs.JobRolloutResponse) error {
+	if done, err := j.srv.forward("Job.Rollout", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "rollout"}, time.Now())
+
+	// Check for read-job permissions
+	if err := j.checkJobCapability(args.AuthToken, args.RequestNamespace(), acl.NamespaceCapabilityReadJob); err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "multiregion_rollout"}),
+		run: func(snap *state.StateSnapshot) error {
+			rollout, err := snap.MultiregionRolloutByJob(args.RequestNamespace(), args.JobID)
+			if err != nil {
+				return err
+			}
+			reply.Rollout = rollout
+
+			// Use the last index that affected the rollout table
+			index, err := snap.Index("multiregion_rollout")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			j.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return j.srv.blockingRPC(&opts)
+}
+
+// validateDispatchRequest returns whether the request is valid given the
+// parameterized job.
+func validateDispatchRequest(req *structs.JobDispatchRequest, job *structs.Job) error {
+	// Check the payload constraint is met
+	hasInputData := len(req.Payload) != 0
+	if job.ParameterizedJob.Payload == structs.DispatchPayloadRequired && !hasInputData {
+		return fmt.Errorf("Payload is not provided but required by parameterized job")
+	} else if job.ParameterizedJob.Payload == structs.DispatchPayloadForbidden && hasInputData {
+		return fmt.Errorf("Payload provided but forbidden by parameterized job")
+	}
+
+	// Check the payload doesn't exceed the size limit
+	if l := len(req.Payload); l > structs.DispatchPayloadSizeLimit {
+		return fmt.Errorf("Payload exceeds maximum size; %d > %d", l, structs.DispatchPayloadSizeLimit)
+	}
+
+	required := structs.SliceStringToSet(job.ParameterizedJob.MetaRequired)
+	optional := structs.SliceStringToSet(job.ParameterizedJob.MetaOptional)
+
+	// Check the metadata key constraints are met
+	unpermitted := make(map[string]struct{})
+	for k := range req.Meta {
+		_, req := required[k]
+		_, opt := optional[k]
+		if !req && !opt {
+			unpermitted[k] = struct{}{}
+		}
+	}
+
+	if len(unpermitted) != 0 {
+		flat := make([]string, 0, len(unpermitted))
+		for k := range unpermitted {
+			flat = append(flat, k)
+		}
+
+		return fmt.Errorf("Dispatch request included unpermitted metadata keys: %v", flat)
+	}
+
+	missing := make(map[string]struct{})
+	for _, k := range job.ParameterizedJob.MetaRequired {
+		if _, ok := req.Meta[k]; !ok {
+			missing[k] = struct{}{}
+		}
+	}
+
+	if len(missing) != 0 {
+		flat := make([]string, 0, len(missing))
+		for k := range missing {
+			flat = append(flat, k)
+		}
+
+		return fmt.Errorf("Dispatch did not provide required meta keys: %v", flat)
+	}
+
+	return nil
+}
+
+// checkJobCapability returns a permission denied error if the given token is
+// not allowed to perform the operation on the jobs of the namespace
+func (j *Job) checkJobCapability(secretID, namespace, op string) error {
+	aclObj, err := j.srv.ResolveToken(secretID)
+	if err != nil {
+		return err
+	} else if aclObj == nil {
+		return nil
+	}
+
+	if !aclObj.AllowNamespaceOperation(namespace, op) {
+		return structs.ErrPermissionDenied
+	}
+	return nil
+}
+
 // validateJob validates a Job and task drivers and returns an error if there is
 // a validation problem or if the Job is of a type a user is not allowed to
 // submit.
@@ -682,3 +1593,25 @@ func validateJob(job *structs.Job) error {
 
 	return validationErrors.ErrorOrNil()
 }
+
+// jobAdmissionValidator validates a job against the state, such as the
+// namespace it is submitted to, returning an error if it isn't admitted
+type jobAdmissionValidator func(snap *state.StateSnapshot, job *structs.Job) error
+
+// jobAdmissionValidators are the validators a job must pass to be registered
+// or planned, in order
+var jobAdmissionValidators = []jobAdmissionValidator{
+	validateJobNamespace,
+	validateJobNetworkPolicy,
+}
+
+// admitJob runs the job through the admission validators, returning the
+// error of the first one denying it
+func admitJob(snap *state.StateSnapshot, job *structs.Job) error {
+	for _, validator := range jobAdmissionValidators {
+		if err := validator(snap, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/net-rpc-msgpackrpc"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/hashicorp/nomad/testutil"
 )
 
@@ -39,7 +40,7 @@ func TestJobEndpoint_Register(t *testing.T) {
 
 	// Check for the node in the FSM
 	state := s1.fsm.State()
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -87,6 +88,163 @@ func TestJobEndpoint_Register(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_Register_MultiRegion(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.Region = "region1"
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	s2 := testServer(t, func(c *Config) {
+		c.Region = "region2"
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s2.Shutdown()
+	testJoin(t, s1, s2)
+	testutil.WaitForLeader(t, s1.RPC)
+	testutil.WaitForLeader(t, s2.RPC)
+	codec := rpcClient(t, s1)
+
+	// Create the register request for a job in both regions
+	job := mock.Job()
+	job.Region = "region1"
+	job.Regions = []string{"region1", "region2"}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "region1"},
+	}
+
+	// Fetch the response
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.EvalID == "" || resp.RegionEvalIDs["region2"] == "" || len(resp.RegionEvalIDs) != 1 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Check the job is registered in each region with its region set
+	for region, srv := range map[string]*Server{"region1": s1, "region2": s2} {
+		out, err := srv.fsm.State().JobByID(job.Namespace, job.ID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out == nil {
+			t.Fatalf("expected job in %q", region)
+		}
+		if out.Region != region || len(out.Regions) != 2 {
+			t.Fatalf("bad: %#v", out)
+		}
+	}
+
+	// Check the evaluation of the other region
+	eval, err := s2.fsm.State().EvalByID(resp.RegionEvalIDs["region2"])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if eval == nil || eval.JobID != job.ID {
+		t.Fatalf("bad: %#v", eval)
+	}
+}
+
+func TestJobEndpoint_Register_MultiRegion_Invalid(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// The job's region must be one of its regions
+	job := mock.Job()
+	job.Regions = []string{"region1", "region2"}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "must be one of the job's regions") {
+		t.Fatalf("expected error: %v", err)
+	}
+}
+
+func TestJobEndpoint_Register_ACL(t *testing.T) {
+	s1, root := testACLServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create a token that can only read jobs
+	state := s1.fsm.State()
+	policy := mock.ACLPolicy()
+	policy.Rules = `namespace "default" { policy = "read" }`
+	policy.SetHash()
+	token := mock.ACLToken()
+	token.Policies = []string{policy.Name}
+	if err := state.UpsertACLPolicies(100, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertACLTokens(101, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create the register request
+	job := mock.Job()
+	req := &structs.JobRegisterRequest{
+		Job: job,
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: token.SecretID,
+		},
+	}
+
+	// Registering with the read token is denied
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// An anonymous request is denied
+	req.AuthToken = ""
+	err = msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// The management token may register the job
+	req.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The read token can now fetch the job
+	get := &structs.JobSpecificRequest{
+		JobID: job.ID,
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: token.SecretID,
+		},
+	}
+	var resp2 structs.SingleJobResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.GetJob", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.Job == nil || resp2.Job.ID != job.ID {
+		t.Fatalf("bad: %#v", resp2.Job)
+	}
+
+	// But an unknown token is rejected
+	get.AuthToken = structs.GenerateUUID()
+	err = msgpackrpc.CallWithCodec(codec, "Job.GetJob", get, &resp2)
+	if err == nil || err.Error() != structs.ErrTokenNotFound.Error() {
+		t.Fatalf("expected token not found: %v", err)
+	}
+}
+
 func TestJobEndpoint_Register_InvalidDriverConfig(t *testing.T) {
 	s1 := testServer(t, func(c *Config) {
 		c.NumSchedulers = 0 // Prevent automatic dequeue
@@ -156,7 +314,7 @@ func TestJobEndpoint_Register_Existing(t *testing.T) {
 
 	// Check for the node in the FSM
 	state := s1.fsm.State()
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -228,7 +386,7 @@ func TestJobEndpoint_Register_Periodic(t *testing.T) {
 
 	// Check for the node in the FSM
 	state := s1.fsm.State()
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -249,6 +407,226 @@ func TestJobEndpoint_Register_Periodic(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_Register_Parameterized(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request for a parameterized job.
+	job := mock.Job()
+	job.Type = structs.JobTypeBatch
+	job.ParameterizedJob = &structs.ParameterizedJobConfig{}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	// Fetch the response
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.JobModifyIndex == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	// Check for the job in the FSM
+	state := s1.fsm.State()
+	out, err := state.JobByID(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("expected job")
+	}
+	if out.CreateIndex != resp.JobModifyIndex {
+		t.Fatalf("index mis-match")
+	}
+	if out.ParameterizedJob.Payload != structs.DispatchPayloadOptional {
+		t.Fatalf("bad: %#v", out.ParameterizedJob)
+	}
+	if resp.EvalID != "" {
+		t.Fatalf("Register created an eval for a parameterized job")
+	}
+
+	// Jobs can't be submitted as dispatched
+	job = mock.Job()
+	job.Dispatched = true
+	req.Job = job
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err == nil {
+		t.Fatalf("expected error registering a dispatched job")
+	}
+}
+
+func TestJobEndpoint_Register_SchedulerMapping(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+		c.SchedulerMapping = map[string]string{"gpu": structs.JobTypeBatch}
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Jobs of a mapped type are accepted
+	job := mock.Job()
+	job.Type = "gpu"
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The evaluation is created for the job's type
+	eval, err := s1.fsm.State().EvalByID(resp.EvalID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if eval == nil || eval.Type != "gpu" {
+		t.Fatalf("bad: %#v", eval)
+	}
+
+	// Jobs of types without a scheduler are rejected
+	job = mock.Job()
+	job.Type = "unknown"
+	req.Job = job
+	err = msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "unknown job type") {
+		t.Fatalf("expected unknown job type error: %v", err)
+	}
+}
+
+func TestJobEndpoint_Register_Namespace(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Registering into a namespace that doesn't exist fails
+	job := mock.Job()
+	job.Namespace = ""
+	req := &structs.JobRegisterRequest{
+		Job: job,
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			Namespace: "engineering",
+		},
+	}
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "nonexistent namespace") {
+		t.Fatalf("expected namespace error: %v", err)
+	}
+
+	// Create the namespace and register again
+	ns := &structs.Namespace{Name: "engineering"}
+	state := s1.fsm.State()
+	if err := state.UpsertNamespaces(1000, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The job and its evaluation inherit the request namespace
+	out, err := state.JobByID("engineering", job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.Namespace != "engineering" {
+		t.Fatalf("bad: %#v", out)
+	}
+	eval, err := state.EvalByID(resp.EvalID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if eval == nil || eval.Namespace != "engineering" {
+		t.Fatalf("bad: %#v", eval)
+	}
+
+	// The same job ID can be registered in another namespace
+	job2 := mock.Job()
+	job2.ID = job.ID
+	job2.Namespace = ""
+	req.Job = job2
+	req.Namespace = ""
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, namespace := range []string{"engineering", structs.DefaultNamespace} {
+		out, err := state.JobByID(namespace, job.ID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out == nil || out.Namespace != namespace {
+			t.Fatalf("bad: %#v", out)
+		}
+	}
+}
+
+func TestJobEndpoint_Register_NetworkPolicy(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create a namespace denying privileged ports
+	ns := &structs.Namespace{
+		Name: "engineering",
+		NetworkPolicy: &structs.NamespaceNetworkPolicy{
+			DenyPrivilegedPorts: true,
+			ExemptJobs:          []string{"ingress-*"},
+		},
+	}
+	state := s1.fsm.State()
+	if err := state.UpsertNamespaces(1000, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A job with a privileged static port is denied
+	job := mock.Job()
+	job.Namespace = "engineering"
+	job.TaskGroups[0].Tasks[0].Resources.Networks[0].ReservedPorts = []structs.Port{{Label: "http", Value: 443}}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "denied by the network policy") {
+		t.Fatalf("expected network policy error: %v", err)
+	}
+
+	// Planning it is denied too
+	planReq := &structs.JobPlanRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var planResp structs.JobPlanResponse
+	err = msgpackrpc.CallWithCodec(codec, "Job.Plan", planReq, &planResp)
+	if err == nil || !strings.Contains(err.Error(), "denied by the network policy") {
+		t.Fatalf("expected network policy error: %v", err)
+	}
+
+	// Exempt jobs are admitted
+	job.ID = "ingress-" + job.ID
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+}
+
 func TestJobEndpoint_Register_EnforceIndex(t *testing.T) {
 	s1 := testServer(t, func(c *Config) {
 		c.NumSchedulers = 0 // Prevent automatic dequeue
@@ -293,7 +671,7 @@ func TestJobEndpoint_Register_EnforceIndex(t *testing.T) {
 
 	// Check for the node in the FSM
 	state := s1.fsm.State()
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -349,7 +727,7 @@ func TestJobEndpoint_Register_EnforceIndex(t *testing.T) {
 		t.Fatalf("bad index: %d", resp.Index)
 	}
 
-	out, err = state.JobByID(job.ID)
+	out, err = state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -418,7 +796,7 @@ func TestJobEndpoint_Register_Vault_AllowUnauthenticated(t *testing.T) {
 
 	// Check for the job in the FSM
 	state := s1.fsm.State()
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -533,7 +911,7 @@ func TestJobEndpoint_Register_Vault_Policies(t *testing.T) {
 
 	// Check for the job in the FSM
 	state := s1.fsm.State()
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -573,7 +951,7 @@ func TestJobEndpoint_Register_Vault_Policies(t *testing.T) {
 	}
 
 	// Check for the job in the FSM
-	out, err = state.JobByID(job2.ID)
+	out, err = state.JobByID(job2.Namespace, job2.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -588,7 +966,7 @@ func TestJobEndpoint_Register_Vault_Policies(t *testing.T) {
 	}
 }
 
-func TestJobEndpoint_Evaluate(t *testing.T) {
+func TestJobEndpoint_Register_Clusters(t *testing.T) {
 	s1 := testServer(t, func(c *Config) {
 		c.NumSchedulers = 0 // Prevent automatic dequeue
 	})
@@ -596,7 +974,68 @@ func TestJobEndpoint_Evaluate(t *testing.T) {
 	codec := rpcClient(t, s1)
 	testutil.WaitForLeader(t, s1.RPC)
 
-	// Create the register request
+	// Only enable a named Vault cluster
+	s1.config.VaultClusters = map[string]*config.VaultConfig{
+		"secondary": &config.VaultConfig{Name: "secondary", Enabled: true},
+	}
+	tvc := &TestVaultClient{}
+	s1.vaultClusters = map[string]VaultClient{"secondary": tvc}
+
+	goodToken := structs.GenerateUUID()
+	tvc.SetLookupTokenAllowedPolicies(goodToken, []string{"foo"})
+
+	// Asking for a policy of an unknown cluster fails
+	job := mock.Job()
+	job.VaultToken = goodToken
+	job.TaskGroups[0].Tasks[0].Vault = &structs.Vault{Policies: []string{"foo"}, Cluster: "other"}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), `Vault cluster "other" not configured`) {
+		t.Fatalf("expected unknown cluster error: %v", err)
+	}
+
+	// The token is checked against the selected cluster
+	job.TaskGroups[0].Tasks[0].Vault.Cluster = "secondary"
+	job.TaskGroups[0].Tasks[0].Consul = &structs.Consul{Cluster: "secondary"}
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	out, err := s1.fsm.State().JobByID(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("expected job")
+	}
+
+	// Check that implicit constraints on both clusters were created
+	expected := []*structs.Constraint{
+		vaultClusterConstraint("secondary"),
+		consulClusterConstraint("secondary"),
+	}
+	constraints := out.TaskGroups[0].Constraints
+	if !reflect.DeepEqual(constraints, expected) {
+		t.Fatalf("bad: %#v", constraints)
+	}
+	if constraints[0].LTarget != "${attr.vault.secondary.version}" {
+		t.Fatalf("bad: %#v", constraints[0])
+	}
+}
+
+func TestJobEndpoint_Evaluate(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
 	job := mock.Job()
 	req := &structs.JobRegisterRequest{
 		Job:          job,
@@ -659,6 +1098,68 @@ func TestJobEndpoint_Evaluate(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_Trigger(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	job := mock.Job()
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A reason is required
+	trigger := &structs.JobTriggerRequest{
+		JobID:        job.ID,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	err := msgpackrpc.CallWithCodec(codec, "Job.Trigger", trigger, &resp)
+	if err == nil || !strings.Contains(err.Error(), "missing reason") {
+		t.Fatalf("expected reason error: %v", err)
+	}
+
+	// Trigger an evaluation
+	trigger.Reason = "new data"
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Trigger", trigger, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	// The evaluation records the reason
+	state := s1.fsm.State()
+	eval, err := state.EvalByID(resp.EvalID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if eval == nil {
+		t.Fatalf("expected eval")
+	}
+	if eval.TriggeredBy != structs.EvalTriggerCustom || eval.TriggerReason != "new data" {
+		t.Fatalf("bad: %#v", eval)
+	}
+	if eval.JobID != job.ID || eval.Namespace != job.Namespace || eval.Status != structs.EvalStatusPending {
+		t.Fatalf("bad: %#v", eval)
+	}
+
+	// Unknown jobs can't be triggered
+	trigger.JobID = "foo"
+	err = msgpackrpc.CallWithCodec(codec, "Job.Trigger", trigger, &resp)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error: %v", err)
+	}
+}
+
 func TestJobEndpoint_Evaluate_Periodic(t *testing.T) {
 	s1 := testServer(t, func(c *Config) {
 		c.NumSchedulers = 0 // Prevent automatic dequeue
@@ -729,14 +1230,17 @@ func TestJobEndpoint_Deregister(t *testing.T) {
 		t.Fatalf("bad index: %d", resp2.Index)
 	}
 
-	// Check for the node in the FSM
+	// Check the job is stopped but not purged
 	state := s1.fsm.State()
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if out != nil {
-		t.Fatalf("unexpected job")
+	if out == nil {
+		t.Fatalf("job purged")
+	}
+	if !out.Stop {
+		t.Fatalf("job not stopped")
 	}
 
 	// Lookup the evaluation
@@ -769,6 +1273,41 @@ func TestJobEndpoint_Deregister(t *testing.T) {
 	if eval.Status != structs.EvalStatusPending {
 		t.Fatalf("bad: %#v", eval)
 	}
+
+	// Deregister and purge
+	dereg2 := &structs.JobDeregisterRequest{
+		JobID:        job.ID,
+		Purge:        true,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp3 structs.JobDeregisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Deregister", dereg2, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp3.Index == 0 {
+		t.Fatalf("bad index: %d", resp3.Index)
+	}
+
+	// Check the job is purged
+	out, err = state.JobByID(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("unexpected job")
+	}
+
+	// Lookup the evaluation
+	eval, err = state.EvalByID(resp3.EvalID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if eval == nil {
+		t.Fatalf("expected eval")
+	}
+	if eval.TriggeredBy != structs.EvalTriggerJobDeregister {
+		t.Fatalf("bad: %#v", eval)
+	}
 }
 
 func TestJobEndpoint_Deregister_NonExistent(t *testing.T) {
@@ -860,14 +1399,14 @@ func TestJobEndpoint_Deregister_Periodic(t *testing.T) {
 		t.Fatalf("bad index: %d", resp2.Index)
 	}
 
-	// Check for the node in the FSM
+	// Check the job is stopped but not purged
 	state := s1.fsm.State()
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if out != nil {
-		t.Fatalf("unexpected job")
+	if out == nil || !out.Stop {
+		t.Fatalf("bad: %#v", out)
 	}
 
 	if resp.EvalID != "" {
@@ -941,6 +1480,220 @@ func TestJobEndpoint_GetJob(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_GetJobSubmission(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Register the job along with its source
+	job := mock.Job()
+	job.VaultToken = "a5bf3a2f-c6cb-4a8f-aa4c-3a6e18dba2ae"
+	source := `job "example" { vault_token = "a5bf3a2f-c6cb-4a8f-aa4c-3a6e18dba2ae" }`
+	reg := &structs.JobRegisterRequest{
+		Job: job,
+		Submission: &structs.JobSubmission{
+			Source: source,
+			Format: structs.JobSubmissionFormatHCL,
+		},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Lookup the source
+	get := &structs.JobSpecificRequest{
+		JobID:        job.ID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp2 structs.JobSubmissionResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.GetJobSubmission", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.Index != resp.JobModifyIndex {
+		t.Fatalf("Bad index: %d %d", resp2.Index, resp.JobModifyIndex)
+	}
+	sub := resp2.Submission
+	if sub == nil || sub.JobID != job.ID || sub.JobModifyIndex != resp.JobModifyIndex || sub.SubmitTime == 0 {
+		t.Fatalf("bad: %#v", sub)
+	}
+
+	// The Vault token is redacted from the source
+	if strings.Contains(sub.Source, job.VaultToken) || !strings.Contains(sub.Source, "<redacted>") {
+		t.Fatalf("bad: %q", sub.Source)
+	}
+
+	// Registering the job without its source keeps the previous one
+	reg.Job = mock.Job()
+	reg.Job.ID = job.ID
+	reg.Submission = nil
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := msgpackrpc.CallWithCodec(codec, "Job.GetJobSubmission", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.Submission == nil || resp2.Submission.JobModifyIndex == resp.JobModifyIndex {
+		t.Fatalf("bad: %#v", resp2.Submission)
+	}
+
+	// Lookup non-existing job
+	get.JobID = "foobarbaz"
+	if err := msgpackrpc.CallWithCodec(codec, "Job.GetJobSubmission", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.Submission != nil {
+		t.Fatalf("unexpected submission")
+	}
+}
+
+func TestJobEndpoint_GetJobVersions(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Register the job twice
+	job := mock.Job()
+	reg := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	job2 := job.Copy()
+	job2.Priority = 90
+	reg.Job = job2
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Lookup the versions
+	get := &structs.JobSpecificRequest{
+		JobID:        job.ID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp2 structs.JobVersionsResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.GetJobVersions", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.Index != resp.JobModifyIndex {
+		t.Fatalf("Bad index: %d %d", resp2.Index, resp.JobModifyIndex)
+	}
+	versions := resp2.Versions
+	if len(versions) != 2 || versions[0].Version != 1 || versions[0].Priority != 90 || versions[1].Version != 0 {
+		t.Fatalf("bad: %#v", versions)
+	}
+
+	// Lookup non-existing job
+	get.JobID = "foobarbaz"
+	var resp3 structs.JobVersionsResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.GetJobVersions", get, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp3.Versions) != 0 {
+		t.Fatalf("unexpected versions: %#v", resp3.Versions)
+	}
+}
+
+func TestJobEndpoint_VersionDiff(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Register the job twice
+	job := mock.Job()
+	reg := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	job2 := job.Copy()
+	job2.Priority = 90
+	job2.TaskGroups[0].Count = 3
+	reg.Job = job2
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Diff the versions
+	get := &structs.JobVersionDiffRequest{
+		JobID:        job.ID,
+		FromVersion:  0,
+		ToVersion:    1,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp2 structs.JobVersionDiffResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.VersionDiff", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	diff := resp2.Diff
+	if diff == nil || diff.Type != structs.DiffTypeEdited {
+		t.Fatalf("bad: %#v", diff)
+	}
+	if len(diff.Fields) != 1 || diff.Fields[0].Name != "Priority" || diff.Fields[0].Old != "50" || diff.Fields[0].New != "90" {
+		t.Fatalf("bad: %#v", diff.Fields)
+	}
+	if len(diff.TaskGroups) != 1 || diff.TaskGroups[0].Type != structs.DiffTypeEdited {
+		t.Fatalf("bad: %#v", diff.TaskGroups)
+	}
+
+	// Untracked versions have no diff
+	get.ToVersion = 5
+	var resp3 structs.JobVersionDiffResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.VersionDiff", get, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp3.Diff != nil {
+		t.Fatalf("unexpected diff: %#v", resp3.Diff)
+	}
+}
+
+func TestJobEndpoint_Register_SubmissionInvalid(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	reg := &structs.JobRegisterRequest{
+		Job: mock.Job(),
+		Submission: &structs.JobSubmission{
+			Source: "job",
+			Format: "yaml",
+		},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &resp)
+	if err == nil || !strings.Contains(err.Error(), "invalid job submission format") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Oversized sources are discarded without failing the registration
+	reg.Submission = &structs.JobSubmission{
+		Source: strings.Repeat("#", structs.MaxJobSubmissionSize+1),
+		Format: structs.JobSubmissionFormatHCL,
+	}
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := s1.fsm.State().JobSubmissionByJob(reg.Job.Namespace, reg.Job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
 func TestJobEndpoint_GetJobSummary(t *testing.T) {
 	s1 := testServer(t, func(c *Config) {
 		c.NumSchedulers = 0 // Prevent automatic dequeue
@@ -980,7 +1733,8 @@ func TestJobEndpoint_GetJobSummary(t *testing.T) {
 	}
 
 	expectedJobSummary := structs.JobSummary{
-		JobID: job.ID,
+		JobID:     job.ID,
+		Namespace: job.Namespace,
 		Summary: map[string]structs.TaskGroupSummary{
 			"web": structs.TaskGroupSummary{},
 		},
@@ -1060,7 +1814,7 @@ func TestJobEndpoint_GetJobSummary_Blocking(t *testing.T) {
 
 	// Job delete fires watches
 	time.AfterFunc(100*time.Millisecond, func() {
-		if err := state.DeleteJob(300, job1.ID); err != nil {
+		if err := state.DeleteJob(300, job1.Namespace, job1.ID); err != nil {
 			t.Fatalf("err: %v", err)
 		}
 	})
@@ -1134,7 +1888,7 @@ func TestJobEndpoint_GetJob_Blocking(t *testing.T) {
 
 	// Job delete fires watches
 	time.AfterFunc(100*time.Millisecond, func() {
-		if err := state.DeleteJob(300, job2.ID); err != nil {
+		if err := state.DeleteJob(300, job2.Namespace, job2.ID); err != nil {
 			t.Fatalf("err: %v", err)
 		}
 	})
@@ -1211,19 +1965,256 @@ func TestJobEndpoint_ListJobs(t *testing.T) {
 	}
 }
 
-func TestJobEndpoint_ListJobs_Blocking(t *testing.T) {
+func TestJobEndpoint_ListJobs_Paginate(t *testing.T) {
 	s1 := testServer(t, nil)
 	defer s1.Shutdown()
-	state := s1.fsm.State()
 	codec := rpcClient(t, s1)
 	testutil.WaitForLeader(t, s1.RPC)
 
-	// Create the job
-	job := mock.Job()
-
-	// Upsert job triggers watches
-	time.AfterFunc(100*time.Millisecond, func() {
-		if err := state.UpsertJob(100, job); err != nil {
+	// Create the jobs, one of which doesn't match the filter
+	state := s1.fsm.State()
+	for i, id := range []string{"job-a", "job-b", "job-c", "job-d"} {
+		job := mock.Job()
+		job.ID = id
+		if id == "job-b" {
+			job.Priority = 60
+		}
+		if err := state.UpsertJob(uint64(1000+i), job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Page through the matching jobs
+	var ids []string
+	var pages int
+	token := ""
+	for {
+		get := &structs.JobListRequest{
+			QueryOptions: structs.QueryOptions{
+				Region:    "global",
+				PerPage:   2,
+				NextToken: token,
+				Filter:    `Priority == 50 and Status != "dead"`,
+			},
+		}
+		var resp structs.JobListResponse
+		if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		pages++
+		for _, job := range resp.Jobs {
+			ids = append(ids, job.ID)
+		}
+		if token = resp.NextToken; token == "" {
+			break
+		}
+	}
+	if pages != 2 {
+		t.Fatalf("bad: %d", pages)
+	}
+	if !reflect.DeepEqual(ids, []string{"job-a", "job-c", "job-d"}) {
+		t.Fatalf("bad: %#v", ids)
+	}
+
+	// Invalid filters are rejected
+	get := &structs.JobListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global", Filter: `Foo == "bar"`},
+	}
+	var resp structs.JobListResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrInvalidFilter.Error()) {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestJobEndpoint_ListJobs_Namespace(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create jobs in two namespaces
+	state := s1.fsm.State()
+	job1 := mock.Job()
+	job2 := mock.Job()
+	job2.Namespace = "other"
+	if err := state.UpsertJob(1000, job1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJob(1001, job2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Listing without a namespace returns the default namespace
+	get := &structs.JobListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Jobs) != 1 || resp.Jobs[0].ID != job1.ID {
+		t.Fatalf("bad: %#v", resp.Jobs)
+	}
+
+	// Listing the other namespace, with and without a prefix
+	get.Namespace = "other"
+	var resp2 structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Jobs) != 1 || resp2.Jobs[0].ID != job2.ID || resp2.Jobs[0].Namespace != "other" {
+		t.Fatalf("bad: %#v", resp2.Jobs)
+	}
+
+	get.Prefix = job1.ID[:4]
+	var resp3 structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, stub := range resp3.Jobs {
+		if stub.ID == job1.ID {
+			t.Fatalf("job from default namespace listed: %#v", stub)
+		}
+	}
+}
+
+func TestJobEndpoint_ListJobs_AllNamespaces(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create jobs in two namespaces
+	state := s1.fsm.State()
+	job1 := mock.Job()
+	job2 := mock.Job()
+	job2.Namespace = "other"
+	if err := state.UpsertJob(1000, job1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJob(1001, job2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Listing all namespaces returns both jobs with their namespace
+	get := &structs.JobListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			Namespace: structs.AllNamespacesSentinel,
+		},
+	}
+	var resp structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Jobs) != 2 {
+		t.Fatalf("bad: %#v", resp.Jobs)
+	}
+	for _, stub := range resp.Jobs {
+		if (stub.ID == job1.ID && stub.Namespace != job1.Namespace) ||
+			(stub.ID == job2.ID && stub.Namespace != job2.Namespace) {
+			t.Fatalf("bad namespace: %#v", stub)
+		}
+	}
+
+	// The prefix applies across namespaces
+	get.Prefix = job2.ID[:4]
+	var resp2 structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Jobs) != 1 || resp2.Jobs[0].ID != job2.ID {
+		t.Fatalf("bad: %#v", resp2.Jobs)
+	}
+}
+
+func TestJobEndpoint_ListJobs_AllNamespaces_ACL(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create jobs in two namespaces
+	state := s1.fsm.State()
+	job1 := mock.Job()
+	job2 := mock.Job()
+	job2.Namespace = "other"
+	if err := state.UpsertJob(1000, job1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJob(1001, job2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ns := mock.Namespace()
+	ns.Name = "other"
+	if err := state.UpsertNamespaces(1002, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a token that may only read the other namespace
+	policy := mock.ACLPolicy()
+	policy.Rules = `namespace "other" { policy = "read" }`
+	policy.SetHash()
+	token := mock.ACLToken()
+	token.Policies = []string{policy.Name}
+	if err := state.UpsertACLPolicies(1003, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertACLTokens(1004, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The token only sees the jobs of the other namespace
+	get := &structs.JobListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			Namespace: structs.AllNamespacesSentinel,
+			AuthToken: token.SecretID,
+		},
+	}
+	var resp structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Jobs) != 1 || resp.Jobs[0].ID != job2.ID {
+		t.Fatalf("bad: %#v", resp.Jobs)
+	}
+
+	// Anonymous requests see nothing
+	get.AuthToken = ""
+	var resp2 structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Jobs) != 0 {
+		t.Fatalf("bad: %#v", resp2.Jobs)
+	}
+
+	// The management token sees every job
+	get.AuthToken = root.SecretID
+	var resp3 structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp3.Jobs) != 2 {
+		t.Fatalf("bad: %#v", resp3.Jobs)
+	}
+}
+
+func TestJobEndpoint_ListJobs_Blocking(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	state := s1.fsm.State()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the job
+	job := mock.Job()
+
+	// Upsert job triggers watches
+	time.AfterFunc(100*time.Millisecond, func() {
+		if err := state.UpsertJob(100, job); err != nil {
 			t.Fatalf("err: %v", err)
 		}
 	})
@@ -1252,7 +2243,7 @@ func TestJobEndpoint_ListJobs_Blocking(t *testing.T) {
 
 	// Job deletion triggers watches
 	time.AfterFunc(100*time.Millisecond, func() {
-		if err := state.DeleteJob(200, job.ID); err != nil {
+		if err := state.DeleteJob(200, job.Namespace, job.ID); err != nil {
 			t.Fatalf("err: %v", err)
 		}
 	})
@@ -1402,6 +2393,59 @@ func TestJobEndpoint_Evaluations(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_Evaluations_Blocking(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	eval1 := mock.Eval()
+	eval2 := mock.Eval()
+	eval2.JobID = "job1"
+	state := s1.fsm.State()
+
+	// First upsert an unrelated eval
+	time.AfterFunc(100*time.Millisecond, func() {
+		err := state.UpsertEvals(100, []*structs.Evaluation{eval1})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	// Upsert an eval for the job we are interested in later
+	time.AfterFunc(200*time.Millisecond, func() {
+		err := state.UpsertEvals(200, []*structs.Evaluation{eval2})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	// Lookup the evaluations
+	get := &structs.JobSpecificRequest{
+		JobID: "job1",
+		QueryOptions: structs.QueryOptions{
+			Region:        "global",
+			MinQueryIndex: 50,
+		},
+	}
+	var resp structs.JobEvaluationsResponse
+	start := time.Now()
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Evaluations", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("should block (returned in %s) %#v", elapsed, resp)
+	}
+	if resp.Index != 200 {
+		t.Fatalf("Bad index: %d %d", resp.Index, 200)
+	}
+	if len(resp.Evaluations) != 1 || resp.Evaluations[0].JobID != "job1" {
+		t.Fatalf("bad: %#v", resp.Evaluations)
+	}
+}
+
 func TestJobEndpoint_Plan_WithDiff(t *testing.T) {
 	s1 := testServer(t, func(c *Config) {
 		c.NumSchedulers = 0 // Prevent automatic dequeue
@@ -1505,3 +2549,479 @@ func TestJobEndpoint_Plan_NoDiff(t *testing.T) {
 		t.Fatalf("no failed task group alloc metrics")
 	}
 }
+
+func TestJobEndpoint_Dispatch(t *testing.T) {
+	// No requirements
+	d1 := mock.Job()
+	d1.Type = structs.JobTypeBatch
+	d1.ParameterizedJob = &structs.ParameterizedJobConfig{}
+
+	// Require input data
+	d2 := mock.Job()
+	d2.Type = structs.JobTypeBatch
+	d2.ParameterizedJob = &structs.ParameterizedJobConfig{
+		Payload: structs.DispatchPayloadRequired,
+	}
+
+	// Disallow input data
+	d3 := mock.Job()
+	d3.Type = structs.JobTypeBatch
+	d3.ParameterizedJob = &structs.ParameterizedJobConfig{
+		Payload: structs.DispatchPayloadForbidden,
+	}
+
+	// Require meta
+	d4 := mock.Job()
+	d4.Type = structs.JobTypeBatch
+	d4.ParameterizedJob = &structs.ParameterizedJobConfig{
+		MetaRequired: []string{"foo", "bar"},
+	}
+
+	// Optional meta
+	d5 := mock.Job()
+	d5.Type = structs.JobTypeBatch
+	d5.ParameterizedJob = &structs.ParameterizedJobConfig{
+		MetaOptional: []string{"foo", "bar"},
+	}
+
+	// Stopped parameterized job
+	d6 := mock.Job()
+	d6.Type = structs.JobTypeBatch
+	d6.ParameterizedJob = &structs.ParameterizedJobConfig{}
+	d6.Stop = true
+
+	reqNoInputNoMeta := &structs.JobDispatchRequest{}
+	reqInputDataNoMeta := &structs.JobDispatchRequest{
+		Payload: []byte("hello world"),
+	}
+	reqNoInputDataMeta := &structs.JobDispatchRequest{
+		Meta: map[string]string{
+			"foo": "f1",
+			"bar": "f2",
+		},
+	}
+	reqInputDataMeta := &structs.JobDispatchRequest{
+		Payload: []byte("hello world"),
+		Meta: map[string]string{
+			"foo": "f1",
+			"bar": "f2",
+		},
+	}
+	reqBadMeta := &structs.JobDispatchRequest{
+		Payload: []byte("hello world"),
+		Meta: map[string]string{
+			"foo": "f1",
+			"bar": "f2",
+			"baz": "f3",
+		},
+	}
+	reqInputDataTooLarge := &structs.JobDispatchRequest{
+		Payload: make([]byte, structs.DispatchPayloadSizeLimit+100),
+	}
+
+	type testCase struct {
+		name             string
+		parameterizedJob *structs.Job
+		dispatchReq      *structs.JobDispatchRequest
+		err              bool
+		errStr           string
+	}
+	cases := []testCase{
+		{
+			name:             "optional input data w/ data",
+			parameterizedJob: d1,
+			dispatchReq:      reqInputDataNoMeta,
+			err:              false,
+		},
+		{
+			name:             "optional input data w/o data",
+			parameterizedJob: d1,
+			dispatchReq:      reqNoInputNoMeta,
+			err:              false,
+		},
+		{
+			name:             "require input data w/ data",
+			parameterizedJob: d2,
+			dispatchReq:      reqInputDataNoMeta,
+			err:              false,
+		},
+		{
+			name:             "require input data w/o data",
+			parameterizedJob: d2,
+			dispatchReq:      reqNoInputNoMeta,
+			err:              true,
+			errStr:           "not provided but required",
+		},
+		{
+			name:             "disallow input data w/o data",
+			parameterizedJob: d3,
+			dispatchReq:      reqNoInputNoMeta,
+			err:              false,
+		},
+		{
+			name:             "disallow input data w/ data",
+			parameterizedJob: d3,
+			dispatchReq:      reqInputDataNoMeta,
+			err:              true,
+			errStr:           "provided but forbidden",
+		},
+		{
+			name:             "require meta w/ meta",
+			parameterizedJob: d4,
+			dispatchReq:      reqInputDataMeta,
+			err:              false,
+		},
+		{
+			name:             "require meta w/o meta",
+			parameterizedJob: d4,
+			dispatchReq:      reqNoInputNoMeta,
+			err:              true,
+			errStr:           "did not provide required meta keys",
+		},
+		{
+			name:             "optional meta w/ meta",
+			parameterizedJob: d5,
+			dispatchReq:      reqNoInputDataMeta,
+			err:              false,
+		},
+		{
+			name:             "optional meta w/o meta",
+			parameterizedJob: d5,
+			dispatchReq:      reqNoInputNoMeta,
+			err:              false,
+		},
+		{
+			name:             "optional meta w/ bad meta",
+			parameterizedJob: d5,
+			dispatchReq:      reqBadMeta,
+			err:              true,
+			errStr:           "unpermitted metadata keys",
+		},
+		{
+			name:             "optional input w/ too big of input",
+			parameterizedJob: d1,
+			dispatchReq:      reqInputDataTooLarge,
+			err:              true,
+			errStr:           "Payload exceeds maximum size",
+		},
+		{
+			name:             "stopped parameterized job",
+			parameterizedJob: d6,
+			dispatchReq:      reqNoInputNoMeta,
+			err:              true,
+			errStr:           "is stopped",
+		},
+	}
+
+	for _, tc := range cases {
+		func() {
+			s1 := testServer(t, func(c *Config) {
+				c.NumSchedulers = 0 // Prevent automatic dequeue
+			})
+			defer s1.Shutdown()
+			codec := rpcClient(t, s1)
+			testutil.WaitForLeader(t, s1.RPC)
+
+			// Create the register request
+			regReq := &structs.JobRegisterRequest{
+				Job:          tc.parameterizedJob,
+				WriteRequest: structs.WriteRequest{Region: "global"},
+			}
+
+			// Fetch the response
+			var regResp structs.JobRegisterResponse
+			if err := msgpackrpc.CallWithCodec(codec, "Job.Register", regReq, &regResp); err != nil {
+				t.Fatalf("%s: err: %v", tc.name, err)
+			}
+
+			// Now try to dispatch
+			tc.dispatchReq.JobID = tc.parameterizedJob.ID
+			tc.dispatchReq.WriteRequest = structs.WriteRequest{Region: "global"}
+
+			var dispatchResp structs.JobDispatchResponse
+			dispatchErr := msgpackrpc.CallWithCodec(codec, "Job.Dispatch", tc.dispatchReq, &dispatchResp)
+
+			if dispatchErr == nil {
+				if tc.err {
+					t.Fatalf("%s: Expected error: %v", tc.name, dispatchErr)
+				}
+
+				// Check that we got an eval and job id back
+				if dispatchResp.EvalID == "" || dispatchResp.DispatchedJobID == "" {
+					t.Fatalf("%s: Bad response: %#v", tc.name, dispatchResp)
+				}
+
+				state := s1.fsm.State()
+				out, err := state.JobByID(structs.DefaultNamespace, dispatchResp.DispatchedJobID)
+				if err != nil {
+					t.Fatalf("%s: err: %v", tc.name, err)
+				}
+				if out == nil {
+					t.Fatalf("%s: expected job", tc.name)
+				}
+				if out.CreateIndex != dispatchResp.JobCreateIndex {
+					t.Fatalf("%s: index mis-match", tc.name)
+				}
+				if out.ParentID != tc.parameterizedJob.ID || !out.Dispatched {
+					t.Fatalf("%s: bad dispatched job: %#v", tc.name, out)
+				}
+				if string(out.Payload) != string(tc.dispatchReq.Payload) {
+					t.Fatalf("%s: bad payload: %q", tc.name, out.Payload)
+				}
+				for k, v := range tc.dispatchReq.Meta {
+					if out.Meta[k] != v {
+						t.Fatalf("%s: bad meta: %#v", tc.name, out.Meta)
+					}
+				}
+
+				// Lookup the evaluation
+				eval, err := state.EvalByID(dispatchResp.EvalID)
+				if err != nil {
+					t.Fatalf("%s: err: %v", tc.name, err)
+				}
+				if eval == nil {
+					t.Fatalf("%s: expected eval", tc.name)
+				}
+				if eval.CreateIndex != dispatchResp.EvalCreateIndex {
+					t.Fatalf("%s: index mis-match", tc.name)
+				}
+				if eval.JobID != out.ID {
+					t.Fatalf("%s: bad eval: %#v", tc.name, eval)
+				}
+			} else {
+				if !tc.err {
+					t.Fatalf("%s: Got unexpected error: %v", tc.name, dispatchErr)
+				} else if !strings.Contains(dispatchErr.Error(), tc.errStr) {
+					t.Fatalf("%s: Expected err to include %q; got %v", tc.name, tc.errStr, dispatchErr)
+				}
+			}
+		}()
+	}
+}
+
+func TestJobEndpoint_Scale(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	job := mock.Job()
+	job.TaskGroups[0].Scaling = &structs.ScalingPolicy{
+		Min:     2,
+		Max:     12,
+		Enabled: true,
+	}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Scale the task group
+	count := int64(4)
+	scale := &structs.JobScaleRequest{
+		JobID:        job.ID,
+		Target:       map[string]string{structs.ScalingTargetGroup: "web"},
+		Count:        &count,
+		Message:      "scaling in",
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var scaleResp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Scale", scale, &scaleResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if scaleResp.EvalID == "" || scaleResp.JobModifyIndex <= resp.JobModifyIndex {
+		t.Fatalf("bad: %#v", scaleResp)
+	}
+
+	// Check the job, eval and scaling event
+	state := s1.fsm.State()
+	out, err := state.JobByID(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.TaskGroups[0].Count != 4 || out.JobModifyIndex != scaleResp.JobModifyIndex {
+		t.Fatalf("bad: %#v", out)
+	}
+	eval, err := state.EvalByID(scaleResp.EvalID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if eval == nil || eval.JobModifyIndex != scaleResp.JobModifyIndex {
+		t.Fatalf("bad: %#v", eval)
+	}
+	events, err := state.ScalingEventsByJob(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if events == nil || len(events.ScalingEvents["web"]) != 1 {
+		t.Fatalf("bad: %#v", events)
+	}
+	event := events.ScalingEvents["web"][0]
+	if *event.Count != 4 || event.PreviousCount != 10 || event.EvalID != scaleResp.EvalID || event.Message != "scaling in" {
+		t.Fatalf("bad: %#v", event)
+	}
+
+	// Counts outside of the policy are rejected unless overridden
+	count = 20
+	err = msgpackrpc.CallWithCodec(codec, "Job.Scale", scale, &scaleResp)
+	if err == nil || !strings.Contains(err.Error(), "greater than the scaling policy maximum") {
+		t.Fatalf("expected policy error: %v", err)
+	}
+	scale.PolicyOverride = true
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Scale", scale, &scaleResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Requests without a count only record an event
+	errEvent := &structs.JobScaleRequest{
+		JobID:        job.ID,
+		Target:       map[string]string{structs.ScalingTargetGroup: "web"},
+		Message:      "metrics unavailable",
+		Error:        true,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var errResp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Scale", errEvent, &errResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if errResp.EvalID != "" {
+		t.Fatalf("bad: %#v", errResp)
+	}
+	out, _ = state.JobByID(job.Namespace, job.ID)
+	if out.TaskGroups[0].Count != 20 {
+		t.Fatalf("bad: %#v", out)
+	}
+	events, _ = state.ScalingEventsByJob(job.Namespace, job.ID)
+	if len(events.ScalingEvents["web"]) != 3 || !events.ScalingEvents["web"][0].Error {
+		t.Fatalf("bad: %#v", events)
+	}
+
+	// Unknown task groups are rejected
+	errEvent.Target[structs.ScalingTargetGroup] = "foo"
+	err = msgpackrpc.CallWithCodec(codec, "Job.Scale", errEvent, &errResp)
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected missing group error: %v", err)
+	}
+}
+
+func TestJobEndpoint_Scale_EnforceIndex(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the job
+	job := mock.Job()
+	reg := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var regResp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &regResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Scale the job enforcing its current modify index
+	count := int64(5)
+	req := &structs.JobScaleRequest{
+		JobID:          job.ID,
+		Target:         map[string]string{structs.ScalingTargetGroup: "web"},
+		Count:          &count,
+		EnforceIndex:   true,
+		JobModifyIndex: regResp.JobModifyIndex,
+		WriteRequest:   structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Scale", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.EvalID == "" || resp.JobModifyIndex <= regResp.JobModifyIndex {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Retrying the request is rejected since the job has changed, and no
+	// scaling event is recorded
+	var resp2 structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Scale", req, &resp2)
+	if err == nil || !strings.Contains(err.Error(), RegisterEnforceIndexErrPrefix) {
+		t.Fatalf("expected enforcement error: %v", err)
+	}
+	events, err := s1.fsm.State().ScalingEventsByJob(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(events.ScalingEvents["web"]) != 1 {
+		t.Fatalf("bad: %#v", events)
+	}
+
+	// The new modify index is accepted
+	req.JobModifyIndex = resp.JobModifyIndex
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Scale", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestJobEndpoint_ScaleStatus(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the job, an allocation and a scaling event
+	state := s1.fsm.State()
+	job := mock.Job()
+	if err := state.UpsertJob(1000, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	alloc := mock.Alloc()
+	alloc.Job = job
+	alloc.JobID = job.ID
+	alloc.ClientStatus = structs.AllocClientStatusRunning
+	if err := state.UpsertAllocs(1001, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	count := int64(10)
+	if err := state.UpsertScalingEvent(1002, &structs.ScalingEventRegisterRequest{
+		JobID:        job.ID,
+		TaskGroup:    "web",
+		ScalingEvent: &structs.ScalingEvent{Count: &count},
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	get := &structs.JobSpecificRequest{
+		JobID:        job.ID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.JobScaleStatusResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.ScaleStatus", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1002 {
+		t.Fatalf("Bad index: %d %d", resp.Index, 1002)
+	}
+	status := resp.JobScaleStatus
+	if status == nil || status.JobID != job.ID || status.JobStopped {
+		t.Fatalf("bad: %#v", status)
+	}
+	tgStatus := status.TaskGroups["web"]
+	if tgStatus == nil || tgStatus.Desired != 10 || tgStatus.Placed != 1 || tgStatus.Running != 1 || len(tgStatus.Events) != 1 {
+		t.Fatalf("bad: %#v", tgStatus)
+	}
+
+	// Unknown jobs have no status
+	get.JobID = structs.GenerateUUID()
+	if err := msgpackrpc.CallWithCodec(codec, "Job.ScaleStatus", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.JobScaleStatus != nil {
+		t.Fatalf("bad: %#v", resp.JobScaleStatus)
+	}
+}
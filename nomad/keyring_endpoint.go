@@ -0,0 +1,162 @@
+package nomad
+
+import (
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/watch"
+)
+
+// Keyring endpoint is used for managing the keys the servers encrypt
+// variables and sign workload identities with
+type Keyring struct {
+	srv *Server
+}
+
+// Rotate is used to generate a new root key and workload identity key. The
+// existing variables are re-encrypted with the new root key, while the
+// previous workload identity keys are kept to verify existing identities.
+func (k *Keyring) Rotate(args *structs.KeyringRotateRequest,
+	reply *structs.KeyringRotateResponse) error {
+	if done, err := k.srv.forward("Keyring.Rotate", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "keyring", "rotate"}, time.Now())
+
+	// Check operator write permissions
+	if aclObj, err := k.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowOperatorWrite() {
+		return structs.ErrPermissionDenied
+	}
+
+	identityKey, err := k.srv.rotateWorkloadIdentityKey()
+	if err != nil {
+		k.srv.logger.Printf("[ERR] nomad.keyring: Rotate failed: %v", err)
+		return err
+	}
+	rootKey, rekeyed, err := k.srv.rotateRootKey()
+	if err != nil {
+		k.srv.logger.Printf("[ERR] nomad.keyring: Rotate failed: %v", err)
+		return err
+	}
+
+	// Return the keys as committed
+	snap, err := k.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	if key, err := snap.RootKeyByID(rootKey.KeyID); err != nil {
+		return err
+	} else if key != nil {
+		reply.RootKey = key.Stub()
+	}
+	if key, err := snap.WorkloadIdentityKeyByID(identityKey.KeyID); err != nil {
+		return err
+	} else if key != nil {
+		reply.WorkloadIdentityKey = key.PublicKeyStub()
+	}
+	reply.Rekeyed = rekeyed
+
+	index, err := snap.Index("variables")
+	if err != nil {
+		return err
+	}
+	if keyIndex, err := snap.Index("root_keys"); err != nil {
+		return err
+	} else if keyIndex > index {
+		index = keyIndex
+	}
+	reply.Index = index
+	return nil
+}
+
+// List is used to list the metadata of the keys of the keyring. The key
+// material is never returned.
+func (k *Keyring) List(args *structs.KeyringListRequest,
+	reply *structs.KeyringListResponse) error {
+	if done, err := k.srv.forward("Keyring.List", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "keyring", "list"}, time.Now())
+
+	// Check operator read permissions
+	if aclObj, err := k.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowOperatorRead() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch: watch.NewItems(
+			watch.Item{Table: "root_keys"},
+			watch.Item{Table: "identity_keys"},
+		),
+		run: func(snap *state.StateSnapshot) error {
+			iter, err := snap.RootKeys()
+			if err != nil {
+				return err
+			}
+			var rootKeys []*structs.RootKeyStub
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				rootKeys = append(rootKeys, raw.(*structs.RootKey).Stub())
+			}
+			reply.RootKeys = rootKeys
+
+			iter, err = snap.WorkloadIdentityKeys()
+			if err != nil {
+				return err
+			}
+			var identityKeys []*structs.WorkloadIdentityPublicKey
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				identityKeys = append(identityKeys, raw.(*structs.WorkloadIdentityKey).PublicKeyStub())
+			}
+			reply.WorkloadIdentityKeys = identityKeys
+
+			reply.ActiveRootKeyID = ""
+			if active, err := snap.ActiveRootKey(); err != nil {
+				return err
+			} else if active != nil {
+				reply.ActiveRootKeyID = active.KeyID
+			}
+			reply.ActiveWorkloadIdentityKeyID = ""
+			if active, err := snap.ActiveWorkloadIdentityKey(); err != nil {
+				return err
+			} else if active != nil {
+				reply.ActiveWorkloadIdentityKeyID = active.KeyID
+			}
+
+			// Use the last index that affected either key table
+			index, err := snap.Index("root_keys")
+			if err != nil {
+				return err
+			}
+			if identityIndex, err := snap.Index("identity_keys"); err != nil {
+				return err
+			} else if identityIndex > index {
+				index = identityIndex
+			}
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			k.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return k.srv.blockingRPC(&opts)
+}
@@ -0,0 +1,124 @@
+package nomad
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestKeyringEndpoint_Rotate(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Write a variable, which generates the first root key
+	variable := mock.Variable()
+	upsert := &structs.VariablesUpsertRequest{
+		Variable:     variable,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var upsertResp structs.VariablesUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Upsert", upsert, &upsertResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	oldKey, err := s1.fsm.State().ActiveRootKey()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Rotate the keyring
+	req := &structs.KeyringRotateRequest{
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.KeyringRotateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Keyring.Rotate", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.RootKey == nil || resp.RootKey.KeyID == oldKey.KeyID {
+		t.Fatalf("bad: %#v", resp.RootKey)
+	}
+	if resp.WorkloadIdentityKey == nil || resp.Rekeyed != 1 || resp.Index == 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The variable is re-encrypted with the new key and keeps its indexes
+	stored, err := s1.fsm.State().VariableByPath(structs.DefaultNamespace, variable.Path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if stored.KeyID != resp.RootKey.KeyID || stored.ModifyIndex != upsertResp.Variable.ModifyIndex {
+		t.Fatalf("bad: %#v", stored)
+	}
+
+	get := &structs.VariablesReadRequest{
+		Path:         variable.Path,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var getResp structs.VariablesReadResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Read", get, &getResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if getResp.Variable == nil || !reflect.DeepEqual(getResp.Variable.Items, variable.Items) {
+		t.Fatalf("bad: %#v", getResp.Variable)
+	}
+
+	// The previous keys are kept while the new keys are active
+	list := &structs.KeyringListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var listResp structs.KeyringListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Keyring.List", list, &listResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(listResp.RootKeys) != 2 || listResp.ActiveRootKeyID != resp.RootKey.KeyID {
+		t.Fatalf("bad: %#v", listResp)
+	}
+	if len(listResp.WorkloadIdentityKeys) != 1 ||
+		listResp.ActiveWorkloadIdentityKeyID != resp.WorkloadIdentityKey.KeyID {
+		t.Fatalf("bad: %#v", listResp)
+	}
+}
+
+func TestKeyringEndpoint_ACL(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Anonymous requests are denied
+	req := &structs.KeyringRotateRequest{
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.KeyringRotateResponse
+	err := msgpackrpc.CallWithCodec(codec, "Keyring.Rotate", req, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	list := &structs.KeyringListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var listResp structs.KeyringListResponse
+	err = msgpackrpc.CallWithCodec(codec, "Keyring.List", list, &listResp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// Management tokens may rotate the keyring
+	req.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Keyring.Rotate", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	list.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Keyring.List", list, &listResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(listResp.RootKeys) != 1 || listResp.ActiveRootKeyID != resp.RootKey.KeyID {
+		t.Fatalf("bad: %#v", listResp)
+	}
+}
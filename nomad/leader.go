@@ -1,12 +1,17 @@
 package nomad
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad/nomad/features"
+	"github.com/hashicorp/nomad/nomad/state"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/raft"
 	"github.com/hashicorp/serf/serf"
@@ -133,8 +138,8 @@ func (s *Server) establishLeadership(stopCh chan struct{}) error {
 		return err
 	}
 
-	// Activate the vault client
-	s.vault.SetActive(true)
+	// Activate the vault clients
+	s.setVaultActive(true)
 	if err := s.restoreRevokingAccessors(); err != nil {
 		return err
 	}
@@ -160,6 +165,38 @@ func (s *Server) establishLeadership(stopCh chan struct{}) error {
 	// Periodically unblock failed allocations
 	go s.periodicUnblockFailedEvals(stopCh)
 
+	// Advance the multi-region rollouts of the jobs submitted to the region
+	go s.watchMultiregionRollouts(stopCh)
+
+	// Track the health of the servers and clean up the dead ones
+	go s.autopilotLoop(stopCh)
+
+	// Migrate allocations off the node classes under maintenance
+	go s.watchMaintenanceWindows(stopCh)
+
+	// Penalize the nodes for the failures seen on them
+	go s.flushNodeReliability(stopCh)
+
+	// Migrate the allocations off of the draining nodes
+	go s.watchNodeDrains(stopCh)
+
+	// Replicate ACL policies and tokens from the authoritative region
+	if s.config.ACLEnabled && s.config.Region != s.config.AuthoritativeRegion {
+		s.aclReplication.start()
+		go s.replicateACLPolicies(stopCh)
+		go s.replicateACLTokens(stopCh)
+	}
+
+	// Replicate namespaces and quota specifications from the authoritative
+	// region, so that every region enforces the same tenancy configuration
+	if s.config.Region != s.config.AuthoritativeRegion {
+		s.namespaceReplication.start()
+		if s.config.Features.Enabled(features.Quotas) {
+			go s.replicateQuotaSpecs(stopCh)
+		}
+		go s.replicateNamespaces(stopCh)
+	}
+
 	// Setup the heartbeat timers. This is done both when starting up or when
 	// a leader fail over happens. Since the timers are maintained by the leader
 	// node, effectively this means all the timers are renewed at the time of failover.
@@ -255,7 +292,7 @@ func (s *Server) restoreRevokingAccessors() error {
 	}
 
 	if len(revoke) != 0 {
-		if err := s.vault.RevokeTokens(context.Background(), revoke, true); err != nil {
+		if err := s.revokeVaultTokens(context.Background(), revoke, true); err != nil {
 			return fmt.Errorf("failed to revoke tokens: %v", err)
 		}
 	}
@@ -282,7 +319,7 @@ func (s *Server) restorePeriodicDispatcher() error {
 		// If the periodic job has never been launched before, launch will hold
 		// the time the periodic job was added. Otherwise it has the last launch
 		// time of the periodic job.
-		launch, err := s.fsm.State().PeriodicLaunchByID(job.ID)
+		launch, err := s.fsm.State().PeriodicLaunchByID(job.Namespace, job.ID)
 		if err != nil || launch == nil {
 			return fmt.Errorf("failed to get periodic launch time: %v", err)
 		}
@@ -297,7 +334,7 @@ func (s *Server) restorePeriodicDispatcher() error {
 			continue
 		}
 
-		if _, err := s.periodicDispatcher.ForceRun(job.ID); err != nil {
+		if _, err := s.periodicDispatcher.ForceRun(job.Namespace, job.ID); err != nil {
 			msg := fmt.Sprintf("force run of periodic job %q failed: %v", job.ID, err)
 			s.logger.Printf("[ERR] nomad.periodic: %s", msg)
 			return errors.New(msg)
@@ -317,6 +354,8 @@ func (s *Server) schedulePeriodic(stopCh chan struct{}) {
 	defer nodeGC.Stop()
 	jobGC := time.NewTicker(s.config.JobGCInterval)
 	defer jobGC.Stop()
+	csiClaimGC := time.NewTicker(s.config.CSIVolumeClaimGCInterval)
+	defer csiClaimGC.Stop()
 
 	// getLatest grabs the latest index from the state store. It returns true if
 	// the index was retrieved successfully.
@@ -345,6 +384,10 @@ func (s *Server) schedulePeriodic(stopCh chan struct{}) {
 			if index, ok := getLatest(); ok {
 				s.evalBroker.Enqueue(s.coreJobEval(structs.CoreJobJobGC, index))
 			}
+		case <-csiClaimGC.C:
+			if index, ok := getLatest(); ok {
+				s.evalBroker.Enqueue(s.coreJobEval(structs.CoreJobCSIVolumeClaimGC, index))
+			}
 		case <-stopCh:
 			return
 		}
@@ -452,6 +495,591 @@ func (s *Server) periodicUnblockFailedEvals(stopCh chan struct{}) {
 	}
 }
 
+// replicateACLPolicies is used to replicate ACL policies from
+// the authoritative region to this region.
+func (s *Server) replicateACLPolicies(stopCh chan struct{}) {
+	req := structs.ACLPolicyListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:     s.config.AuthoritativeRegion,
+			AllowStale: true,
+		},
+	}
+	s.logger.Printf("[DEBUG] nomad: starting ACL policy replication from authoritative region %q", req.Region)
+
+START:
+	for {
+		select {
+		case <-stopCh:
+			return
+		default:
+			// Fetch the list of policies
+			var resp structs.ACLPolicyListResponse
+			req.AuthToken = s.config.ReplicationToken
+			err := s.forwardRegion(s.config.AuthoritativeRegion,
+				"ACL.ListPolicies", &req, &resp)
+			if err != nil {
+				s.logger.Printf("[ERR] nomad: failed to fetch policies from authoritative region: %v", err)
+				s.aclReplication.failure(aclReplicationPolicies, err)
+				goto ERR_WAIT
+			}
+
+			// Perform a two-way diff
+			delete, update := diffACLPolicies(s.State(), req.MinQueryIndex, resp.Policies)
+
+			// Delete policies that should not exist
+			if len(delete) > 0 {
+				args := &structs.ACLPolicyDeleteRequest{
+					Names: delete,
+				}
+				_, _, err := s.raftApply(structs.ACLPolicyDeleteRequestType, args)
+				if err != nil {
+					s.logger.Printf("[ERR] nomad: failed to delete policies: %v", err)
+					s.aclReplication.failure(aclReplicationPolicies, err)
+					goto ERR_WAIT
+				}
+			}
+
+			// Fetch any outdated policies
+			var fetched []*structs.ACLPolicy
+			if len(update) > 0 {
+				req := structs.ACLPolicySetRequest{
+					Names: update,
+					QueryOptions: structs.QueryOptions{
+						Region:        s.config.AuthoritativeRegion,
+						AuthToken:     s.config.ReplicationToken,
+						AllowStale:    true,
+						MinQueryIndex: resp.Index - 1,
+					},
+				}
+				var reply structs.ACLPolicySetResponse
+				if err := s.forwardRegion(s.config.AuthoritativeRegion,
+					"ACL.GetPolicies", &req, &reply); err != nil {
+					s.logger.Printf("[ERR] nomad: failed to fetch policies from authoritative region: %v", err)
+					s.aclReplication.failure(aclReplicationPolicies, err)
+					goto ERR_WAIT
+				}
+				for _, policy := range reply.Policies {
+					fetched = append(fetched, policy)
+				}
+			}
+
+			// Update local policies
+			if len(fetched) > 0 {
+				args := &structs.ACLPolicyUpsertRequest{
+					Policies: fetched,
+				}
+				_, _, err := s.raftApply(structs.ACLPolicyUpsertRequestType, args)
+				if err != nil {
+					s.logger.Printf("[ERR] nomad: failed to update policies: %v", err)
+					s.aclReplication.failure(aclReplicationPolicies, err)
+					goto ERR_WAIT
+				}
+			}
+
+			s.aclReplication.success(aclReplicationPolicies, resp.Index, len(fetched), len(delete))
+
+			// Update the minimum query index, blocks until there
+			// is a change.
+			req.MinQueryIndex = resp.Index
+		}
+	}
+
+ERR_WAIT:
+	select {
+	case <-time.After(s.config.ReplicationBackoff):
+		goto START
+	case <-stopCh:
+		return
+	}
+}
+
+// diffACLPolicies is used to perform a two-way diff between the local
+// policies and the remote policies to determine which policies need to
+// be deleted or updated.
+func diffACLPolicies(state *state.StateStore, minIndex uint64, remoteList []*structs.ACLPolicyListStub) (delete []string, update []string) {
+	// Construct a set of the local and remote policies
+	local := make(map[string][]byte)
+	remote := make(map[string]struct{})
+
+	// Add all the local policies
+	iter, err := state.ACLPolicies()
+	if err != nil {
+		panic("failed to iterate local policies")
+	}
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		policy := raw.(*structs.ACLPolicy)
+		local[policy.Name] = policy.Hash
+	}
+
+	// Iterate over the remote policies
+	for _, rp := range remoteList {
+		remote[rp.Name] = struct{}{}
+
+		// Check if the policy is missing locally
+		if localHash, ok := local[rp.Name]; !ok {
+			update = append(update, rp.Name)
+
+			// Check if policy is newer remotely and there is a hash mis-match.
+		} else if rp.ModifyIndex > minIndex && !bytes.Equal(localHash, rp.Hash) {
+			update = append(update, rp.Name)
+		}
+	}
+
+	// Check if policy should be deleted
+	for lp := range local {
+		if _, ok := remote[lp]; !ok {
+			delete = append(delete, lp)
+		}
+	}
+	return
+}
+
+// replicateACLTokens is used to replicate global ACL tokens from
+// the authoritative region to this region.
+func (s *Server) replicateACLTokens(stopCh chan struct{}) {
+	req := structs.ACLTokenListRequest{
+		GlobalOnly: true,
+		QueryOptions: structs.QueryOptions{
+			Region:     s.config.AuthoritativeRegion,
+			AllowStale: true,
+		},
+	}
+	s.logger.Printf("[DEBUG] nomad: starting ACL token replication from authoritative region %q", req.Region)
+
+START:
+	for {
+		select {
+		case <-stopCh:
+			return
+		default:
+			// Fetch the list of tokens
+			var resp structs.ACLTokenListResponse
+			req.AuthToken = s.config.ReplicationToken
+			err := s.forwardRegion(s.config.AuthoritativeRegion,
+				"ACL.ListTokens", &req, &resp)
+			if err != nil {
+				s.logger.Printf("[ERR] nomad: failed to fetch tokens from authoritative region: %v", err)
+				s.aclReplication.failure(aclReplicationTokens, err)
+				goto ERR_WAIT
+			}
+
+			// Perform a two-way diff
+			delete, update := diffACLTokens(s.State(), req.MinQueryIndex, resp.Tokens)
+
+			// Delete tokens that should not exist
+			if len(delete) > 0 {
+				args := &structs.ACLTokenDeleteRequest{
+					AccessorIDs: delete,
+				}
+				_, _, err := s.raftApply(structs.ACLTokenDeleteRequestType, args)
+				if err != nil {
+					s.logger.Printf("[ERR] nomad: failed to delete tokens: %v", err)
+					s.aclReplication.failure(aclReplicationTokens, err)
+					goto ERR_WAIT
+				}
+			}
+
+			// Fetch any outdated tokens
+			var fetched []*structs.ACLToken
+			if len(update) > 0 {
+				req := structs.ACLTokenSetRequest{
+					AccessorIDs: update,
+					QueryOptions: structs.QueryOptions{
+						Region:        s.config.AuthoritativeRegion,
+						AuthToken:     s.config.ReplicationToken,
+						AllowStale:    true,
+						MinQueryIndex: resp.Index - 1,
+					},
+				}
+				var reply structs.ACLTokenSetResponse
+				if err := s.forwardRegion(s.config.AuthoritativeRegion,
+					"ACL.GetTokens", &req, &reply); err != nil {
+					s.logger.Printf("[ERR] nomad: failed to fetch tokens from authoritative region: %v", err)
+					s.aclReplication.failure(aclReplicationTokens, err)
+					goto ERR_WAIT
+				}
+				for _, token := range reply.Tokens {
+					fetched = append(fetched, token)
+				}
+			}
+
+			// Update local tokens
+			if len(fetched) > 0 {
+				args := &structs.ACLTokenUpsertRequest{
+					Tokens: fetched,
+				}
+				_, _, err := s.raftApply(structs.ACLTokenUpsertRequestType, args)
+				if err != nil {
+					s.logger.Printf("[ERR] nomad: failed to update tokens: %v", err)
+					s.aclReplication.failure(aclReplicationTokens, err)
+					goto ERR_WAIT
+				}
+			}
+
+			s.aclReplication.success(aclReplicationTokens, resp.Index, len(fetched), len(delete))
+
+			// Update the minimum query index, blocks until there
+			// is a change.
+			req.MinQueryIndex = resp.Index
+		}
+	}
+
+ERR_WAIT:
+	select {
+	case <-time.After(s.config.ReplicationBackoff):
+		goto START
+	case <-stopCh:
+		return
+	}
+}
+
+// diffACLTokens is used to perform a two-way diff between the local
+// global tokens and the remote tokens to determine which tokens need to
+// be deleted or updated.
+func diffACLTokens(state *state.StateStore, minIndex uint64, remoteList []*structs.ACLTokenListStub) (delete []string, update []string) {
+	// Construct a set of the local and remote tokens
+	local := make(map[string][]byte)
+	remote := make(map[string]struct{})
+
+	// Add all the local global tokens
+	iter, err := state.ACLTokensByGlobal(true)
+	if err != nil {
+		panic("failed to iterate local tokens")
+	}
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		token := raw.(*structs.ACLToken)
+		local[token.AccessorID] = token.Hash
+	}
+
+	// Iterate over the remote tokens
+	for _, rp := range remoteList {
+		remote[rp.AccessorID] = struct{}{}
+
+		// Check if the token is missing locally
+		if localHash, ok := local[rp.AccessorID]; !ok {
+			update = append(update, rp.AccessorID)
+
+			// Check if token is newer remotely and there is a hash mis-match.
+		} else if rp.ModifyIndex > minIndex && !bytes.Equal(localHash, rp.Hash) {
+			update = append(update, rp.AccessorID)
+		}
+	}
+
+	// Check if local token should be deleted
+	for lp := range local {
+		if _, ok := remote[lp]; !ok {
+			delete = append(delete, lp)
+		}
+	}
+	return
+}
+
+// replicateNamespaces is used to replicate namespaces from the
+// authoritative region to this region. Each namespace is applied on its own,
+// so that a namespace that can't be replicated, such as one that still
+// contains jobs, is reported as a conflict without blocking the others.
+func (s *Server) replicateNamespaces(stopCh chan struct{}) {
+	req := structs.NamespaceListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:     s.config.AuthoritativeRegion,
+			AllowStale: true,
+		},
+	}
+	s.logger.Printf("[DEBUG] nomad: starting namespace replication from authoritative region %q", req.Region)
+
+START:
+	for {
+		select {
+		case <-stopCh:
+			return
+		default:
+			// Fetch the namespaces. The query returns after the reconcile
+			// interval even when nothing changed, to retry the conflicts.
+			var resp structs.NamespaceListResponse
+			req.AuthToken = s.config.ReplicationToken
+			req.MaxQueryTime = s.config.ReplicationReconcileInterval
+			err := s.forwardRegion(s.config.AuthoritativeRegion,
+				"Namespace.ListNamespaces", &req, &resp)
+			if err != nil {
+				s.logger.Printf("[ERR] nomad: failed to fetch namespaces from authoritative region: %v", err)
+				s.namespaceReplication.failure(namespaceReplicationNamespaces, err)
+				goto ERR_WAIT
+			}
+
+			// Perform a two-way diff
+			delete, update := diffNamespaces(s.State(), resp.Namespaces)
+
+			// Update local namespaces
+			var conflicts []*structs.ReplicationConflict
+			upserted := 0
+			for _, ns := range update {
+				args := &structs.NamespaceUpsertRequest{
+					Namespaces: []*structs.Namespace{ns},
+				}
+				applyResp, _, err := s.raftApply(structs.NamespaceUpsertRequestType, args)
+				if err != nil {
+					s.logger.Printf("[ERR] nomad: failed to update namespace %q: %v", ns.Name, err)
+					s.namespaceReplication.failure(namespaceReplicationNamespaces, err)
+					goto ERR_WAIT
+				}
+				if err, ok := applyResp.(error); ok && err != nil {
+					s.logger.Printf("[WARN] nomad: failed to replicate namespace %q: %v", ns.Name, err)
+					conflicts = append(conflicts, &structs.ReplicationConflict{
+						Type:   namespaceReplicationNamespaces,
+						Name:   ns.Name,
+						Reason: err.Error(),
+					})
+					continue
+				}
+				upserted++
+			}
+
+			// Delete namespaces that should not exist
+			deleted := 0
+			for _, name := range delete {
+				args := &structs.NamespaceDeleteRequest{
+					Namespaces: []string{name},
+				}
+				applyResp, _, err := s.raftApply(structs.NamespaceDeleteRequestType, args)
+				if err != nil {
+					s.logger.Printf("[ERR] nomad: failed to delete namespace %q: %v", name, err)
+					s.namespaceReplication.failure(namespaceReplicationNamespaces, err)
+					goto ERR_WAIT
+				}
+				if err, ok := applyResp.(error); ok && err != nil {
+					s.logger.Printf("[WARN] nomad: failed to replicate deletion of namespace %q: %v", name, err)
+					conflicts = append(conflicts, &structs.ReplicationConflict{
+						Type:   namespaceReplicationNamespaces,
+						Name:   name,
+						Reason: err.Error(),
+					})
+					continue
+				}
+				deleted++
+			}
+
+			s.namespaceReplication.setConflicts(namespaceReplicationNamespaces, conflicts)
+			s.namespaceReplication.success(namespaceReplicationNamespaces, resp.Index, upserted, deleted)
+
+			// Update the minimum query index, blocks until there
+			// is a change or the reconcile interval elapses.
+			req.MinQueryIndex = resp.Index
+		}
+	}
+
+ERR_WAIT:
+	select {
+	case <-time.After(s.config.ReplicationBackoff):
+		goto START
+	case <-stopCh:
+		return
+	}
+}
+
+// diffNamespaces is used to perform a two-way diff between the local
+// namespaces and the remote namespaces to determine which namespaces need to
+// be deleted or updated. The default namespace is never deleted, and the
+// implicit default namespace of the remote region is ignored.
+func diffNamespaces(state *state.StateStore, remoteList []*structs.Namespace) (delete []string, update []*structs.Namespace) {
+	// Construct a set of the local and remote namespaces
+	local := make(map[string]*structs.Namespace)
+	remote := make(map[string]struct{})
+
+	// Add all the local namespaces
+	iter, err := state.Namespaces()
+	if err != nil {
+		panic("failed to iterate local namespaces")
+	}
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		ns := raw.(*structs.Namespace)
+		local[ns.Name] = ns
+	}
+
+	// Iterate over the remote namespaces
+	for _, rn := range remoteList {
+		// The implicit default namespace has never been written
+		if rn.Name == structs.DefaultNamespace && rn.ModifyIndex == 0 {
+			continue
+		}
+		remote[rn.Name] = struct{}{}
+
+		// Check if the namespace is missing locally or differs
+		if ln, ok := local[rn.Name]; !ok {
+			update = append(update, rn)
+		} else if ln.Description != rn.Description || ln.Quota != rn.Quota {
+			update = append(update, rn)
+		}
+	}
+
+	// Check if namespace should be deleted
+	for ln := range local {
+		if _, ok := remote[ln]; !ok && ln != structs.DefaultNamespace {
+			delete = append(delete, ln)
+		}
+	}
+	sort.Strings(delete)
+	return
+}
+
+// replicateQuotaSpecs is used to replicate quota specifications from the
+// authoritative region to this region. Each quota specification is applied
+// on its own, so that one that can't be replicated, such as one still used
+// by a namespace, is reported as a conflict without blocking the others.
+func (s *Server) replicateQuotaSpecs(stopCh chan struct{}) {
+	req := structs.QuotaSpecListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:     s.config.AuthoritativeRegion,
+			AllowStale: true,
+		},
+	}
+	s.logger.Printf("[DEBUG] nomad: starting quota specification replication from authoritative region %q", req.Region)
+
+START:
+	for {
+		select {
+		case <-stopCh:
+			return
+		default:
+			// Fetch the quota specifications. The query returns after the
+			// reconcile interval even when nothing changed, to retry the
+			// conflicts.
+			var resp structs.QuotaSpecListResponse
+			req.AuthToken = s.config.ReplicationToken
+			req.MaxQueryTime = s.config.ReplicationReconcileInterval
+			err := s.forwardRegion(s.config.AuthoritativeRegion,
+				"Quota.ListQuotaSpecs", &req, &resp)
+			if err != nil {
+				s.logger.Printf("[ERR] nomad: failed to fetch quota specifications from authoritative region: %v", err)
+				s.namespaceReplication.failure(namespaceReplicationQuotas, err)
+				goto ERR_WAIT
+			}
+
+			// Perform a two-way diff
+			delete, update := diffQuotaSpecs(s.State(), resp.Quotas)
+
+			// Update local quota specifications
+			var conflicts []*structs.ReplicationConflict
+			upserted := 0
+			for _, quota := range update {
+				args := &structs.QuotaSpecUpsertRequest{
+					Quotas: []*structs.QuotaSpec{quota},
+				}
+				applyResp, _, err := s.raftApply(structs.QuotaSpecUpsertRequestType, args)
+				if err != nil {
+					s.logger.Printf("[ERR] nomad: failed to update quota specification %q: %v", quota.Name, err)
+					s.namespaceReplication.failure(namespaceReplicationQuotas, err)
+					goto ERR_WAIT
+				}
+				if err, ok := applyResp.(error); ok && err != nil {
+					s.logger.Printf("[WARN] nomad: failed to replicate quota specification %q: %v", quota.Name, err)
+					conflicts = append(conflicts, &structs.ReplicationConflict{
+						Type:   namespaceReplicationQuotas,
+						Name:   quota.Name,
+						Reason: err.Error(),
+					})
+					continue
+				}
+				upserted++
+			}
+
+			// Delete quota specifications that should not exist
+			deleted := 0
+			for _, name := range delete {
+				args := &structs.QuotaSpecDeleteRequest{
+					Names: []string{name},
+				}
+				applyResp, _, err := s.raftApply(structs.QuotaSpecDeleteRequestType, args)
+				if err != nil {
+					s.logger.Printf("[ERR] nomad: failed to delete quota specification %q: %v", name, err)
+					s.namespaceReplication.failure(namespaceReplicationQuotas, err)
+					goto ERR_WAIT
+				}
+				if err, ok := applyResp.(error); ok && err != nil {
+					s.logger.Printf("[WARN] nomad: failed to replicate deletion of quota specification %q: %v", name, err)
+					conflicts = append(conflicts, &structs.ReplicationConflict{
+						Type:   namespaceReplicationQuotas,
+						Name:   name,
+						Reason: err.Error(),
+					})
+					continue
+				}
+				deleted++
+			}
+
+			s.namespaceReplication.setConflicts(namespaceReplicationQuotas, conflicts)
+			s.namespaceReplication.success(namespaceReplicationQuotas, resp.Index, upserted, deleted)
+
+			// Update the minimum query index, blocks until there
+			// is a change or the reconcile interval elapses.
+			req.MinQueryIndex = resp.Index
+		}
+	}
+
+ERR_WAIT:
+	select {
+	case <-time.After(s.config.ReplicationBackoff):
+		goto START
+	case <-stopCh:
+		return
+	}
+}
+
+// diffQuotaSpecs is used to perform a two-way diff between the local quota
+// specifications and the remote ones to determine which quota specifications
+// need to be deleted or updated.
+func diffQuotaSpecs(state *state.StateStore, remoteList []*structs.QuotaSpec) (delete []string, update []*structs.QuotaSpec) {
+	// Construct a set of the local and remote quota specifications
+	local := make(map[string]*structs.QuotaSpec)
+	remote := make(map[string]struct{})
+
+	// Add all the local quota specifications
+	iter, err := state.QuotaSpecs()
+	if err != nil {
+		panic("failed to iterate local quota specifications")
+	}
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		quota := raw.(*structs.QuotaSpec)
+		local[quota.Name] = quota
+	}
+
+	// Iterate over the remote quota specifications
+	for _, rq := range remoteList {
+		remote[rq.Name] = struct{}{}
+
+		// Check if the quota specification is missing locally or differs
+		if lq, ok := local[rq.Name]; !ok {
+			update = append(update, rq)
+		} else if lq.Description != rq.Description || !reflect.DeepEqual(lq.Limits, rq.Limits) {
+			update = append(update, rq)
+		}
+	}
+
+	// Check if quota specification should be deleted
+	for lq := range local {
+		if _, ok := remote[lq]; !ok {
+			delete = append(delete, lq)
+		}
+	}
+	sort.Strings(delete)
+	return
+}
+
 // revokeLeadership is invoked once we step down as leader.
 // This is used to cleanup any state that may be specific to a leader.
 func (s *Server) revokeLeadership() error {
@@ -467,8 +1095,17 @@ func (s *Server) revokeLeadership() error {
 	// Disable the periodic dispatcher, since it is only useful as a leader
 	s.periodicDispatcher.SetEnabled(false)
 
-	// Disable the Vault client as it is only useful as a leader.
-	s.vault.SetActive(false)
+	// Disable the Vault clients as they are only useful as a leader.
+	s.setVaultActive(false)
+
+	// Stop tracking the replication, since only the leader replicates
+	s.aclReplication.stop()
+	s.namespaceReplication.stop()
+
+	// Forget the health of the servers, since only the leader tracks it
+	s.clusterHealthLock.Lock()
+	s.clusterHealth = structs.OperatorHealthReply{}
+	s.clusterHealthLock.Unlock()
 
 	// Clear the heartbeat timers on either shutdown or step down,
 	// since we are no longer responsible for TTL expirations.
@@ -486,6 +1123,32 @@ func (s *Server) revokeLeadership() error {
 	return nil
 }
 
+// restoreLeaderState is used to rebuild the state the leader maintains in
+// memory after the state store was replaced by a snapshot restore. The eval
+// broker, blocked eval tracker, periodic dispatcher and heartbeat timers are
+// reset and restored as during a leadership transition.
+func (s *Server) restoreLeaderState() error {
+	s.evalBroker.SetEnabled(false)
+	s.evalBroker.SetEnabled(true)
+	s.blockedEvals.SetEnabled(false)
+	s.blockedEvals.SetEnabled(true)
+	if err := s.restoreEvals(); err != nil {
+		return err
+	}
+
+	s.periodicDispatcher.SetEnabled(false)
+	s.periodicDispatcher.SetEnabled(true)
+	s.periodicDispatcher.Start()
+	if err := s.restorePeriodicDispatcher(); err != nil {
+		return err
+	}
+
+	if err := s.clearAllHeartbeatTimers(); err != nil {
+		return err
+	}
+	return s.initializeHeartbeatTimers()
+}
+
 // reconcile is used to reconcile the differences between Serf
 // membership and what is reflected in our strongly consistent store.
 func (s *Server) reconcile() error {
@@ -568,6 +1231,11 @@ func (s *Server) addRaftPeer(m serf.Member, parts *serverParts) error {
 		return err
 	} else if err == nil {
 		s.logger.Printf("[INFO] nomad: added raft peer: %v", parts)
+
+		// The new server may be replacing a dead one
+		if err := s.pruneDeadServers(); err != nil {
+			s.logger.Printf("[ERR] nomad.autopilot: failed to prune dead servers: %v", err)
+		}
 	}
 	return nil
 }
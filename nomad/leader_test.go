@@ -3,10 +3,13 @@ package nomad
 import (
 	"errors"
 	"fmt"
+	"os"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/state"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/testutil"
 )
@@ -360,7 +363,7 @@ func TestLeader_PeriodicDispatcher_Restore_Adds(t *testing.T) {
 
 	// Check that the new leader is tracking the periodic job.
 	testutil.WaitForResult(func() (bool, error) {
-		_, tracked := leader.periodicDispatcher.tracked[periodic.ID]
+		_, tracked := leader.periodicDispatcher.tracked[structs.NewNamespacedID(periodic.ID, periodic.Namespace)]
 		return tracked, nil
 	}, func(err error) {
 		t.Fatalf("periodic job not tracked")
@@ -401,12 +404,12 @@ func TestLeader_PeriodicDispatcher_Restore_NoEvals(t *testing.T) {
 	s1.restorePeriodicDispatcher()
 
 	// Ensure the job is tracked.
-	if _, tracked := s1.periodicDispatcher.tracked[job.ID]; !tracked {
+	if _, tracked := s1.periodicDispatcher.tracked[structs.NewNamespacedID(job.ID, job.Namespace)]; !tracked {
 		t.Fatalf("periodic job not restored")
 	}
 
 	// Check that an eval was made.
-	last, err := s1.fsm.State().PeriodicLaunchByID(job.ID)
+	last, err := s1.fsm.State().PeriodicLaunchByID(job.Namespace, job.ID)
 	if err != nil || last == nil {
 		t.Fatalf("failed to get periodic launch time: %v", err)
 	}
@@ -452,12 +455,12 @@ func TestLeader_PeriodicDispatcher_Restore_Evals(t *testing.T) {
 	s1.restorePeriodicDispatcher()
 
 	// Ensure the job is tracked.
-	if _, tracked := s1.periodicDispatcher.tracked[job.ID]; !tracked {
+	if _, tracked := s1.periodicDispatcher.tracked[structs.NewNamespacedID(job.ID, job.Namespace)]; !tracked {
 		t.Fatalf("periodic job not restored")
 	}
 
 	// Check that an eval was made.
-	last, err := s1.fsm.State().PeriodicLaunchByID(job.ID)
+	last, err := s1.fsm.State().PeriodicLaunchByID(job.Namespace, job.ID)
 	if err != nil || last == nil {
 		t.Fatalf("failed to get periodic launch time: %v", err)
 	}
@@ -572,3 +575,346 @@ func TestLeader_RestoreVaultAccessors(t *testing.T) {
 		t.Fatalf("Bad revoked accessors: %v", tvc.RevokedTokens)
 	}
 }
+
+func TestLeader_ReplicateACLPolicies(t *testing.T) {
+	s1, root := testACLServer(t, func(c *Config) {
+		c.Region = "region1"
+		c.AuthoritativeRegion = "region1"
+		c.ACLEnabled = true
+	})
+	defer s1.Shutdown()
+	s2, _ := testACLServer(t, func(c *Config) {
+		c.Region = "region2"
+		c.AuthoritativeRegion = "region1"
+		c.ACLEnabled = true
+		c.ReplicationBackoff = 20 * time.Millisecond
+		c.ReplicationToken = root.SecretID
+	})
+	defer s2.Shutdown()
+	testJoin(t, s1, s2)
+	testutil.WaitForLeader(t, s1.RPC)
+	testutil.WaitForLeader(t, s2.RPC)
+
+	// Write a policy to the authoritative region
+	p1 := mock.ACLPolicy()
+	if err := s1.State().UpsertACLPolicies(100, []*structs.ACLPolicy{p1}); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Wait for the policy to replicate
+	testutil.WaitForResult(func() (bool, error) {
+		state := s2.State()
+		out, err := state.ACLPolicyByName(p1.Name)
+		return out != nil, err
+	}, func(err error) {
+		t.Fatalf("should replicate policy")
+	})
+}
+
+func TestLeader_DiffACLPolicies(t *testing.T) {
+	state, err := state.NewStateStore(os.Stderr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Populate the local state
+	p1 := mock.ACLPolicy()
+	p2 := mock.ACLPolicy()
+	p3 := mock.ACLPolicy()
+	if err := state.UpsertACLPolicies(100, []*structs.ACLPolicy{p1, p2, p3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Simulate a remote list
+	p2Stub := p2.Stub()
+	p2Stub.ModifyIndex = 50 // Ignored, same index
+	p3Stub := p3.Stub()
+	p3Stub.ModifyIndex = 100 // Updated, higher index
+	p3Stub.Hash = []byte{0, 1, 2, 3}
+	p4 := mock.ACLPolicy()
+	remoteList := []*structs.ACLPolicyListStub{
+		p2Stub,
+		p3Stub,
+		p4.Stub(),
+	}
+	delete, update := diffACLPolicies(state, 50, remoteList)
+
+	// P1 does not exist on the remote side, should delete
+	if len(delete) != 1 || delete[0] != p1.Name {
+		t.Fatalf("bad: %v", delete)
+	}
+
+	// P2 is un-modified - ignore. P3 modified, P4 new.
+	sort.Strings(update)
+	expected := []string{p3.Name, p4.Name}
+	sort.Strings(expected)
+	if len(update) != 2 || update[0] != expected[0] || update[1] != expected[1] {
+		t.Fatalf("bad: %v", update)
+	}
+}
+
+func TestLeader_ReplicateACLTokens(t *testing.T) {
+	s1, root := testACLServer(t, func(c *Config) {
+		c.Region = "region1"
+		c.AuthoritativeRegion = "region1"
+		c.ACLEnabled = true
+	})
+	defer s1.Shutdown()
+	s2, _ := testACLServer(t, func(c *Config) {
+		c.Region = "region2"
+		c.AuthoritativeRegion = "region1"
+		c.ACLEnabled = true
+		c.ReplicationBackoff = 20 * time.Millisecond
+		c.ReplicationToken = root.SecretID
+	})
+	defer s2.Shutdown()
+	testJoin(t, s1, s2)
+	testutil.WaitForLeader(t, s1.RPC)
+	testutil.WaitForLeader(t, s2.RPC)
+
+	// Write a token to the authoritative region
+	p1 := mock.ACLToken()
+	p1.Global = true
+	if err := s1.State().UpsertACLTokens(100, []*structs.ACLToken{p1}); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Wait for the token to replicate
+	testutil.WaitForResult(func() (bool, error) {
+		state := s2.State()
+		out, err := state.ACLTokenByAccessorID(p1.AccessorID)
+		return out != nil, err
+	}, func(err error) {
+		t.Fatalf("should replicate token")
+	})
+}
+
+func TestLeader_DiffACLTokens(t *testing.T) {
+	state, err := state.NewStateStore(os.Stderr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Populate the local state
+	p0 := mock.ACLToken()
+	p1 := mock.ACLToken()
+	p1.Global = true
+	p2 := mock.ACLToken()
+	p2.Global = true
+	p3 := mock.ACLToken()
+	p3.Global = true
+	if err := state.UpsertACLTokens(100, []*structs.ACLToken{p0, p1, p2, p3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Simulate a remote list
+	p2Stub := p2.Stub()
+	p2Stub.ModifyIndex = 50 // Ignored, same index
+	p3Stub := p3.Stub()
+	p3Stub.ModifyIndex = 100 // Updated, higher index
+	p3Stub.Hash = []byte{0, 1, 2, 3}
+	p4 := mock.ACLToken()
+	p4.Global = true
+	remoteList := []*structs.ACLTokenListStub{
+		p2Stub,
+		p3Stub,
+		p4.Stub(),
+	}
+	delete, update := diffACLTokens(state, 50, remoteList)
+
+	// P0 is local and should be ignored
+	// P1 does not exist on the remote side, should delete
+	if len(delete) != 1 || delete[0] != p1.AccessorID {
+		t.Fatalf("bad: %v", delete)
+	}
+
+	// P2 is un-modified - ignore. P3 modified, P4 new.
+	sort.Strings(update)
+	expected := []string{p3.AccessorID, p4.AccessorID}
+	sort.Strings(expected)
+	if len(update) != 2 || update[0] != expected[0] || update[1] != expected[1] {
+		t.Fatalf("bad: %v", update)
+	}
+}
+
+func TestLeader_ReplicateNamespaces(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.Region = "region1"
+		c.AuthoritativeRegion = "region1"
+	})
+	defer s1.Shutdown()
+	s2 := testServer(t, func(c *Config) {
+		c.Region = "region2"
+		c.AuthoritativeRegion = "region1"
+		c.ReplicationBackoff = 20 * time.Millisecond
+		c.ReplicationReconcileInterval = 100 * time.Millisecond
+	})
+	defer s2.Shutdown()
+	testJoin(t, s1, s2)
+	testutil.WaitForLeader(t, s1.RPC)
+	testutil.WaitForLeader(t, s2.RPC)
+
+	// Write a quota and a namespace using it to the authoritative region
+	q1 := mock.QuotaSpec()
+	if err := s1.State().UpsertQuotaSpecs(1000, []*structs.QuotaSpec{q1}); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	ns1 := mock.Namespace()
+	ns1.Quota = q1.Name
+	if err := s1.State().UpsertNamespaces(1001, []*structs.Namespace{ns1}); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Wait for both to replicate
+	testutil.WaitForResult(func() (bool, error) {
+		state := s2.State()
+		quota, err := state.QuotaSpecByName(q1.Name)
+		if err != nil || quota == nil {
+			return false, fmt.Errorf("quota not replicated: %v", err)
+		}
+		ns, err := state.NamespaceByName(ns1.Name)
+		if err != nil || ns == nil {
+			return false, fmt.Errorf("namespace not replicated: %v", err)
+		}
+		if ns.Quota != q1.Name {
+			return false, fmt.Errorf("bad: %#v", ns)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// Register a job in the namespace in the federated region
+	job := mock.Job()
+	job.Namespace = ns1.Name
+	if err := s2.State().UpsertJob(2000, job); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Delete the namespace from the authoritative region. The namespace
+	// still contains a job in the federated region, so it is a conflict.
+	if err := s1.State().DeleteNamespaces(1002, []string{ns1.Name}); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	testutil.WaitForResult(func() (bool, error) {
+		_, _, conflicts := s2.namespaceReplication.status()
+		for _, c := range conflicts {
+			if c.Type == namespaceReplicationNamespaces && c.Name == ns1.Name {
+				return true, nil
+			}
+		}
+		return false, fmt.Errorf("bad: %#v", conflicts)
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+	if ns, err := s2.State().NamespaceByName(ns1.Name); err != nil || ns == nil {
+		t.Fatalf("namespace should not be deleted: %v %v", ns, err)
+	}
+
+	// Removing the job resolves the conflict on the next reconcile
+	if err := s2.State().DeleteJob(2001, job.Namespace, job.ID); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	testutil.WaitForResult(func() (bool, error) {
+		ns, err := s2.State().NamespaceByName(ns1.Name)
+		if err != nil || ns != nil {
+			return false, fmt.Errorf("namespace not deleted: %v %v", ns, err)
+		}
+		_, _, conflicts := s2.namespaceReplication.status()
+		for _, c := range conflicts {
+			if c.Type == namespaceReplicationNamespaces {
+				return false, fmt.Errorf("bad: %#v", conflicts)
+			}
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
+
+func TestLeader_DiffNamespaces(t *testing.T) {
+	state, err := state.NewStateStore(os.Stderr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Populate the local state
+	p1 := mock.Namespace()
+	p2 := mock.Namespace()
+	p3 := mock.Namespace()
+	if err := state.UpsertNamespaces(100, []*structs.Namespace{p1, p2, p3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Simulate a remote list, including the implicit default namespace
+	p3Remote := p3.Copy()
+	p3Remote.Description = "updated"
+	p4 := mock.Namespace()
+	remoteList := []*structs.Namespace{
+		defaultNamespace,
+		p2.Copy(),
+		p3Remote,
+		p4,
+	}
+	delete, update := diffNamespaces(state, remoteList)
+
+	// P1 does not exist on the remote side, should delete
+	if len(delete) != 1 || delete[0] != p1.Name {
+		t.Fatalf("bad: %v", delete)
+	}
+
+	// P2 is un-modified - ignore. P3 modified, P4 new.
+	var names []string
+	for _, ns := range update {
+		names = append(names, ns.Name)
+	}
+	sort.Strings(names)
+	expected := []string{p3.Name, p4.Name}
+	sort.Strings(expected)
+	if len(names) != 2 || names[0] != expected[0] || names[1] != expected[1] {
+		t.Fatalf("bad: %v", names)
+	}
+}
+
+func TestLeader_DiffQuotaSpecs(t *testing.T) {
+	state, err := state.NewStateStore(os.Stderr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Populate the local state
+	p1 := mock.QuotaSpec()
+	p2 := mock.QuotaSpec()
+	p3 := mock.QuotaSpec()
+	if err := state.UpsertQuotaSpecs(100, []*structs.QuotaSpec{p1, p2, p3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Simulate a remote list
+	p3Remote := p3.Copy()
+	p3Remote.Limits[0].CPU = 4000
+	p4 := mock.QuotaSpec()
+	remoteList := []*structs.QuotaSpec{
+		p2.Copy(),
+		p3Remote,
+		p4,
+	}
+	delete, update := diffQuotaSpecs(state, remoteList)
+
+	// P1 does not exist on the remote side, should delete
+	if len(delete) != 1 || delete[0] != p1.Name {
+		t.Fatalf("bad: %v", delete)
+	}
+
+	// P2 is un-modified - ignore. P3 modified, P4 new.
+	var names []string
+	for _, quota := range update {
+		names = append(names, quota.Name)
+	}
+	sort.Strings(names)
+	expected := []string{p3.Name, p4.Name}
+	sort.Strings(expected)
+	if len(names) != 2 || names[0] != expected[0] || names[1] != expected[1] {
+		t.Fatalf("bad: %v", names)
+	}
+}
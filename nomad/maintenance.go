@@ -0,0 +1,135 @@
+package nomad
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// watchMaintenanceWindows periodically checks for hard maintenance windows
+// taking or ceasing effect. The allocations on the nodes of a window taking
+// effect are migrated away, and the nodes of a window ceasing effect are
+// evaluated again so that system jobs and blocked evaluations may use them.
+func (s *Server) watchMaintenanceWindows(stopCh chan struct{}) {
+	ticker := time.NewTicker(s.config.MaintenanceWindowInterval)
+	defer ticker.Stop()
+
+	// The windows in effect are only tracked by the leader, so a new leader
+	// migrates the allocations of the windows in effect once more, which is
+	// a no-op for the nodes that were already emptied.
+	inEffect := make(map[string]*structs.MaintenanceWindow)
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			inEffect = s.reconcileMaintenanceWindows(inEffect, time.Now())
+		}
+	}
+}
+
+// reconcileMaintenanceWindows acts on the hard maintenance windows that took
+// or ceased effect since the previous check, given the windows that were in
+// effect then, and returns the windows in effect now.
+func (s *Server) reconcileMaintenanceWindows(prev map[string]*structs.MaintenanceWindow,
+	now time.Time) map[string]*structs.MaintenanceWindow {
+	iter, err := s.fsm.State().MaintenanceWindows()
+	if err != nil {
+		s.logger.Printf("[ERR] nomad: failed to list maintenance windows: %v", err)
+		return prev
+	}
+
+	current := make(map[string]*structs.MaintenanceWindow)
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		window := raw.(*structs.MaintenanceWindow)
+		if window.Mode != structs.MaintenanceModeHard || !window.InEffect(now) {
+			continue
+		}
+		current[window.ID] = window
+		if old, ok := prev[window.ID]; ok && old.NodeClass == window.NodeClass {
+			continue
+		}
+
+		s.logger.Printf("[INFO] nomad: maintenance window %q of node class %q in effect, migrating allocations",
+			window.ID, window.NodeClass)
+		if err := s.migrateNodeClass(window.NodeClass); err != nil {
+			// Retry on the next check
+			s.logger.Printf("[ERR] nomad: failed to migrate allocations of node class %q: %v", window.NodeClass, err)
+			delete(current, window.ID)
+		}
+	}
+
+	for id, window := range prev {
+		if cur, ok := current[id]; ok && cur.NodeClass == window.NodeClass {
+			continue
+		}
+
+		s.logger.Printf("[INFO] nomad: maintenance window %q of node class %q no longer in effect",
+			window.ID, window.NodeClass)
+		if err := s.evaluateNodeClass(window.NodeClass); err != nil {
+			// Retry on the next check
+			s.logger.Printf("[ERR] nomad: failed to evaluate nodes of node class %q: %v", window.NodeClass, err)
+			if _, ok := current[id]; !ok {
+				current[id] = window
+			}
+		}
+	}
+	return current
+}
+
+// nodesOfClass returns the nodes of the given node class
+func (s *Server) nodesOfClass(class string) ([]*structs.Node, error) {
+	iter, err := s.fsm.State().Nodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+
+	var nodes []*structs.Node
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		node := raw.(*structs.Node)
+		if node.NodeClass == class {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+// migrateNodeClass migrates the allocations off the nodes of the given node
+// class, in the same order as when the nodes are drained but without waiting
+// for the replacements to be healthy
+func (s *Server) migrateNodeClass(class string) error {
+	nodes, err := s.nodesOfClass(class)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if _, _, err := s.endpoints.Node.createDrainEvals(node.ID, node.ModifyIndex); err != nil {
+			return fmt.Errorf("failed to create evaluations for node %q: %v", node.ID, err)
+		}
+	}
+	return nil
+}
+
+// evaluateNodeClass evaluates the nodes of the given node class once they
+// may be used again, and unblocks the evaluations waiting for capacity
+func (s *Server) evaluateNodeClass(class string) error {
+	nodes, err := s.nodesOfClass(class)
+	if err != nil {
+		return err
+	}
+
+	index, err := s.fsm.State().LatestIndex()
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if _, _, err := s.endpoints.Node.createNodeEvals(node.ID, node.ModifyIndex); err != nil {
+			return fmt.Errorf("failed to create evaluations for node %q: %v", node.ID, err)
+		}
+		if node.Ready() {
+			s.blockedEvals.Unblock(node.ComputedClass, index)
+		}
+	}
+	return nil
+}
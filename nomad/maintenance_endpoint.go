@@ -0,0 +1,205 @@
+package nomad
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/watch"
+)
+
+// Maintenance endpoint is used for manipulating the maintenance windows of
+// node classes
+type Maintenance struct {
+	srv *Server
+}
+
+// UpsertWindows is used to create or update a set of maintenance windows
+func (m *Maintenance) UpsertWindows(args *structs.MaintenanceWindowUpsertRequest,
+	reply *structs.MaintenanceWindowUpsertResponse) error {
+	if done, err := m.srv.forward("Maintenance.UpsertWindows", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "maintenance", "upsert_windows"}, time.Now())
+
+	// Check node write permissions
+	if aclObj, err := m.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNodeWrite() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.Windows) == 0 {
+		return fmt.Errorf("must specify at least one maintenance window")
+	}
+	ids := make([]string, 0, len(args.Windows))
+	for _, window := range args.Windows {
+		if window.ID == "" {
+			window.ID = structs.GenerateUUID()
+		}
+		if err := window.Validate(); err != nil {
+			return fmt.Errorf("Invalid maintenance window %q: %v", window.ID, err)
+		}
+		ids = append(ids, window.ID)
+	}
+
+	// Commit this update via Raft
+	_, index, err := m.srv.raftApply(structs.MaintenanceWindowUpsertRequestType, args)
+	if err != nil {
+		m.srv.logger.Printf("[ERR] nomad.maintenance: UpsertWindows failed: %v", err)
+		return err
+	}
+
+	reply.WindowIDs = ids
+	reply.Index = index
+	return nil
+}
+
+// DeleteWindows is used to delete a set of maintenance windows
+func (m *Maintenance) DeleteWindows(args *structs.MaintenanceWindowDeleteRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := m.srv.forward("Maintenance.DeleteWindows", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "maintenance", "delete_windows"}, time.Now())
+
+	// Check node write permissions
+	if aclObj, err := m.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNodeWrite() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.WindowIDs) == 0 {
+		return fmt.Errorf("must specify at least one maintenance window to delete")
+	}
+
+	// Commit this update via Raft
+	_, index, err := m.srv.raftApply(structs.MaintenanceWindowDeleteRequestType, args)
+	if err != nil {
+		m.srv.logger.Printf("[ERR] nomad.maintenance: DeleteWindows failed: %v", err)
+		return err
+	}
+
+	reply.Index = index
+	return nil
+}
+
+// ListWindows is used to list the maintenance windows
+func (m *Maintenance) ListWindows(args *structs.MaintenanceWindowListRequest,
+	reply *structs.MaintenanceWindowListResponse) error {
+	if done, err := m.srv.forward("Maintenance.ListWindows", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "maintenance", "list_windows"}, time.Now())
+
+	// Check node read permissions
+	if aclObj, err := m.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNodeRead() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "maintenance_windows"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Capture all the maintenance windows
+			var err error
+			var iter memdb.ResultIterator
+			if prefix := args.QueryOptions.Prefix; prefix != "" {
+				iter, err = snap.MaintenanceWindowsByIDPrefix(prefix)
+			} else {
+				iter, err = snap.MaintenanceWindows()
+			}
+			if err != nil {
+				return err
+			}
+
+			var windows []*structs.MaintenanceWindow
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				windows = append(windows, raw.(*structs.MaintenanceWindow))
+			}
+			reply.Windows = windows
+
+			// Use the last index that affected the maintenance window table
+			index, err := snap.Index("maintenance_windows")
+			if err != nil {
+				return err
+			}
+
+			// Ensure we never set the index to zero, otherwise a blocking query
+			// cannot be used. We floor the index at one, since realistically
+			// the first write must have a higher index.
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			m.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return m.srv.blockingRPC(&opts)
+}
+
+// GetWindow is used to get a specific maintenance window
+func (m *Maintenance) GetWindow(args *structs.MaintenanceWindowSpecificRequest,
+	reply *structs.SingleMaintenanceWindowResponse) error {
+	if done, err := m.srv.forward("Maintenance.GetWindow", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "maintenance", "get_window"}, time.Now())
+
+	// Check node read permissions
+	if aclObj, err := m.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNodeRead() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "maintenance_windows"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Look for the maintenance window
+			out, err := snap.MaintenanceWindowByID(args.WindowID)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Window = out
+			if out != nil {
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the maintenance window table
+				index, err := snap.Index("maintenance_windows")
+				if err != nil {
+					return err
+				}
+				if index == 0 {
+					index = 1
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			m.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return m.srv.blockingRPC(&opts)
+}
@@ -0,0 +1,199 @@
+package nomad
+
+import (
+	"testing"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestMaintenanceEndpoint_UpsertWindows(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Windows submitted without an ID get one
+	w1 := mock.MaintenanceWindow()
+	w2 := mock.MaintenanceWindow()
+	w2.ID = ""
+	req := &structs.MaintenanceWindowUpsertRequest{
+		Windows:      []*structs.MaintenanceWindow{w1, w2},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.MaintenanceWindowUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Maintenance.UpsertWindows", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+	if len(resp.WindowIDs) != 2 || resp.WindowIDs[0] != w1.ID || resp.WindowIDs[1] == "" {
+		t.Fatalf("bad: %#v", resp.WindowIDs)
+	}
+
+	// Check we created the windows
+	for _, id := range resp.WindowIDs {
+		out, err := s1.fsm.State().MaintenanceWindowByID(id)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out == nil {
+			t.Fatalf("window not found")
+		}
+	}
+
+	// Invalid windows are rejected
+	bad := mock.MaintenanceWindow()
+	bad.End = bad.Start
+	req.Windows = []*structs.MaintenanceWindow{bad}
+	if err := msgpackrpc.CallWithCodec(codec, "Maintenance.UpsertWindows", req, &resp); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestMaintenanceEndpoint_DeleteWindows(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	w := mock.MaintenanceWindow()
+	state := s1.fsm.State()
+	if err := state.UpsertMaintenanceWindows(1000, []*structs.MaintenanceWindow{w}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.MaintenanceWindowDeleteRequest{
+		WindowIDs:    []string{w.ID},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Maintenance.DeleteWindows", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	out, err := state.MaintenanceWindowByID(w.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("window not deleted: %#v", out)
+	}
+}
+
+func TestMaintenanceEndpoint_ListWindows(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	w1 := mock.MaintenanceWindow()
+	w2 := mock.MaintenanceWindow()
+	state := s1.fsm.State()
+	if err := state.UpsertMaintenanceWindows(1000, []*structs.MaintenanceWindow{w1, w2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	get := &structs.MaintenanceWindowListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.MaintenanceWindowListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Maintenance.ListWindows", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 || len(resp.Windows) != 2 {
+		t.Fatalf("bad: %d %#v", resp.Index, resp.Windows)
+	}
+
+	// Lookup the windows by prefix
+	get.Prefix = w1.ID[:8]
+	var resp2 structs.MaintenanceWindowListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Maintenance.ListWindows", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Windows) != 1 || resp2.Windows[0].ID != w1.ID {
+		t.Fatalf("bad: %#v", resp2.Windows)
+	}
+
+	// Lookup a single window
+	single := &structs.MaintenanceWindowSpecificRequest{
+		WindowID:     w2.ID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var singleResp structs.SingleMaintenanceWindowResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Maintenance.GetWindow", single, &singleResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if singleResp.Window == nil || singleResp.Window.ID != w2.ID || singleResp.Index != 1000 {
+		t.Fatalf("bad: %#v", singleResp)
+	}
+}
+
+func TestMaintenanceEndpoint_ACL(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create a token that can only read the nodes
+	state := s1.fsm.State()
+	policy := mock.ACLPolicy()
+	policy.Rules = `node { policy = "read" }`
+	policy.SetHash()
+	token := mock.ACLToken()
+	token.Policies = []string{policy.Name}
+	if err := state.UpsertACLPolicies(100, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertACLTokens(101, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Upserting windows is denied without node write
+	req := &structs.MaintenanceWindowUpsertRequest{
+		Windows: []*structs.MaintenanceWindow{mock.MaintenanceWindow()},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: token.SecretID,
+		},
+	}
+	var resp structs.MaintenanceWindowUpsertResponse
+	err := msgpackrpc.CallWithCodec(codec, "Maintenance.UpsertWindows", req, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// The management token may upsert windows
+	req.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Maintenance.UpsertWindows", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Listing the windows is allowed with node read
+	get := &structs.MaintenanceWindowListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: token.SecretID,
+		},
+	}
+	var listResp structs.MaintenanceWindowListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Maintenance.ListWindows", get, &listResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(listResp.Windows) != 1 {
+		t.Fatalf("bad: %#v", listResp.Windows)
+	}
+
+	// Anonymous requests are denied
+	get.AuthToken = ""
+	err = msgpackrpc.CallWithCodec(codec, "Maintenance.ListWindows", get, &listResp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package nomad
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestServer_ReconcileMaintenanceWindows(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0
+		c.MaintenanceWindowInterval = time.Hour
+	})
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create a node running an allocation
+	state := s1.fsm.State()
+	node := mock.Node()
+	if err := state.UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	alloc := mock.Alloc()
+	alloc.NodeID = node.ID
+	state.UpsertJobSummary(1001, mock.JobSummary(alloc.JobID))
+	if err := state.UpsertAllocs(1002, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Windows not yet in effect are ignored
+	window := mock.MaintenanceWindow()
+	window.Start = time.Now().Add(time.Hour)
+	window.End = window.Start.Add(time.Hour)
+	window.MigrateBefore = 30 * time.Minute
+	if err := state.UpsertMaintenanceWindows(1003, []*structs.MaintenanceWindow{window}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	inEffect := s1.reconcileMaintenanceWindows(nil, time.Now())
+	if len(inEffect) != 0 {
+		t.Fatalf("bad: %#v", inEffect)
+	}
+
+	// The allocations are migrated once the window takes effect, ahead of
+	// its start
+	now := window.Start.Add(-10 * time.Minute)
+	inEffect = s1.reconcileMaintenanceWindows(inEffect, now)
+	if _, ok := inEffect[window.ID]; !ok || len(inEffect) != 1 {
+		t.Fatalf("bad: %#v", inEffect)
+	}
+	evals, err := state.EvalsByJob(alloc.Namespace, alloc.JobID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(evals) != 1 || evals[0].NodeID != node.ID || evals[0].TriggeredBy != structs.EvalTriggerNodeUpdate {
+		t.Fatalf("bad: %#v", evals)
+	}
+
+	// Nothing is done while the window stays in effect
+	inEffect = s1.reconcileMaintenanceWindows(inEffect, now)
+	if len(inEffect) != 1 {
+		t.Fatalf("bad: %#v", inEffect)
+	}
+	evals, err = state.EvalsByJob(alloc.Namespace, alloc.JobID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(evals) != 1 {
+		t.Fatalf("bad: %#v", evals)
+	}
+
+	// The node is evaluated again once the window ends
+	inEffect = s1.reconcileMaintenanceWindows(inEffect, window.End)
+	if len(inEffect) != 0 {
+		t.Fatalf("bad: %#v", inEffect)
+	}
+	evals, err = state.EvalsByJob(alloc.Namespace, alloc.JobID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(evals) != 2 {
+		t.Fatalf("bad: %#v", evals)
+	}
+}
@@ -1,6 +1,7 @@
 package mock
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -17,6 +18,12 @@ func Node() *structs.Node {
 			"arch":          "x86",
 			"nomad.version": "0.5.0",
 			"driver.exec":   "1",
+
+			"driver.exec.capability.signals":           "false",
+			"driver.exec.capability.exec":              "true",
+			"driver.exec.capability.pause":             "true",
+			"driver.exec.capability.volumes":           "true",
+			"driver.exec.capability.network_isolation": "host",
 		},
 		Resources: &structs.Resources{
 			CPU:      4000,
@@ -52,8 +59,9 @@ func Node() *structs.Node {
 			"database": "mysql",
 			"version":  "5.6",
 		},
-		NodeClass: "linux-medium-pci",
-		Status:    structs.NodeStatusReady,
+		NodeClass:             "linux-medium-pci",
+		SchedulingEligibility: structs.NodeSchedulingEligible,
+		Status:                structs.NodeStatusReady,
 	}
 	node.ComputeClass()
 	return node
@@ -63,6 +71,7 @@ func Job() *structs.Job {
 	job := &structs.Job{
 		Region:      "global",
 		ID:          structs.GenerateUUID(),
+		Namespace:   structs.DefaultNamespace,
 		Name:        "my-job",
 		Type:        structs.JobTypeService,
 		Priority:    50,
@@ -158,6 +167,7 @@ func SystemJob() *structs.Job {
 	job := &structs.Job{
 		Region:      "global",
 		ID:          structs.GenerateUUID(),
+		Namespace:   structs.DefaultNamespace,
 		Name:        "my-job",
 		Type:        structs.JobTypeSystem,
 		Priority:    100,
@@ -227,11 +237,12 @@ func PeriodicJob() *structs.Job {
 
 func Eval() *structs.Evaluation {
 	eval := &structs.Evaluation{
-		ID:       structs.GenerateUUID(),
-		Priority: 50,
-		Type:     structs.JobTypeService,
-		JobID:    structs.GenerateUUID(),
-		Status:   structs.EvalStatusPending,
+		ID:        structs.GenerateUUID(),
+		Namespace: structs.DefaultNamespace,
+		Priority:  50,
+		Type:      structs.JobTypeService,
+		JobID:     structs.GenerateUUID(),
+		Status:    structs.EvalStatusPending,
 	}
 	return eval
 }
@@ -252,6 +263,7 @@ func JobSummary(jobID string) *structs.JobSummary {
 func Alloc() *structs.Allocation {
 	alloc := &structs.Allocation{
 		ID:        structs.GenerateUUID(),
+		Namespace: structs.DefaultNamespace,
 		EvalID:    structs.GenerateUUID(),
 		NodeID:    "12345678-abcd-efab-cdef-123456789abc",
 		TaskGroup: "web",
@@ -314,3 +326,174 @@ func Plan() *structs.Plan {
 func PlanResult() *structs.PlanResult {
 	return &structs.PlanResult{}
 }
+
+func Namespace() *structs.Namespace {
+	return &structs.Namespace{
+		Name:        fmt.Sprintf("team-%s", structs.GenerateUUID()[:8]),
+		Description: "mock namespace",
+	}
+}
+
+// MaintenanceWindow returns a hard maintenance window in effect for the node
+// class of the mock nodes
+func MaintenanceWindow() *structs.MaintenanceWindow {
+	now := time.Now().UTC()
+	return &structs.MaintenanceWindow{
+		ID:          structs.GenerateUUID(),
+		NodeClass:   "linux-medium-pci",
+		Mode:        structs.MaintenanceModeHard,
+		Start:       now.Add(-time.Hour),
+		End:         now.Add(time.Hour),
+		Description: "mock maintenance",
+	}
+}
+
+func CSIVolume() *structs.CSIVolume {
+	return &structs.CSIVolume{
+		ID:             fmt.Sprintf("vol-%s", structs.GenerateUUID()[:8]),
+		Name:           "mock volume",
+		Namespace:      structs.DefaultNamespace,
+		ExternalID:     structs.GenerateUUID(),
+		PluginID:       "ebs",
+		AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+		AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+	}
+}
+
+func QuotaSpec() *structs.QuotaSpec {
+	return &structs.QuotaSpec{
+		Name:        fmt.Sprintf("quota-%s", structs.GenerateUUID()[:8]),
+		Description: "mock quota",
+		Limits: []*structs.QuotaLimit{
+			&structs.QuotaLimit{
+				Region:   "global",
+				CPU:      2000,
+				MemoryMB: 2000,
+			},
+		},
+	}
+}
+
+func ACLPolicy() *structs.ACLPolicy {
+	ap := &structs.ACLPolicy{
+		Name:        fmt.Sprintf("policy-%s", structs.GenerateUUID()),
+		Description: "Super cool policy!",
+		Rules: `
+		namespace "default" {
+			policy = "write"
+		}
+		node {
+			policy = "read"
+		}
+		agent {
+			policy = "read"
+		}
+		`,
+		CreateIndex: 10,
+		ModifyIndex: 20,
+	}
+	ap.SetHash()
+	return ap
+}
+
+func ACLToken() *structs.ACLToken {
+	tk := &structs.ACLToken{
+		AccessorID:  structs.GenerateUUID(),
+		SecretID:    structs.GenerateUUID(),
+		Name:        "my cool token " + structs.GenerateUUID(),
+		Type:        "client",
+		Policies:    []string{"foo", "bar"},
+		Global:      false,
+		CreateTime:  time.Now().UTC(),
+		CreateIndex: 10,
+		ModifyIndex: 20,
+	}
+	tk.SetHash()
+	return tk
+}
+
+func ACLManagementToken() *structs.ACLToken {
+	return &structs.ACLToken{
+		AccessorID:  structs.GenerateUUID(),
+		SecretID:    structs.GenerateUUID(),
+		Name:        "management " + structs.GenerateUUID(),
+		Type:        "management",
+		Global:      true,
+		CreateTime:  time.Now().UTC(),
+		CreateIndex: 10,
+		ModifyIndex: 20,
+	}
+}
+
+// WorkloadIdentityKey returns a workload identity key whose key material is
+// not a valid RSA key, which is sufficient for testing its storage.
+func WorkloadIdentityKey() *structs.WorkloadIdentityKey {
+	return &structs.WorkloadIdentityKey{
+		KeyID:       structs.GenerateUUID(),
+		Algorithm:   structs.WorkloadIdentityAlgorithm,
+		PrivateKey:  []byte(structs.GenerateUUID()),
+		PublicKey:   []byte(structs.GenerateUUID()),
+		CreateTime:  time.Now().UTC().UnixNano(),
+		CreateIndex: 10,
+		ModifyIndex: 20,
+	}
+}
+
+func ServiceRegistration() *structs.ServiceRegistration {
+	allocID := structs.GenerateUUID()
+	return &structs.ServiceRegistration{
+		ID:          fmt.Sprintf("_nomad-task-%s-web-web-frontend-http", allocID),
+		ServiceName: "web-frontend",
+		Namespace:   structs.DefaultNamespace,
+		NodeID:      structs.GenerateUUID(),
+		Datacenter:  "dc1",
+		JobID:       "example",
+		AllocID:     allocID,
+		Task:        "web",
+		Tags:        []string{"pci:true"},
+		Address:     "192.168.0.100",
+		Port:        5000,
+		Health:      structs.ServiceRegistrationHealthPassing,
+		CreateIndex: 10,
+		ModifyIndex: 20,
+	}
+}
+
+func Variable() *structs.VariableDecrypted {
+	return &structs.VariableDecrypted{
+		VariableMetadata: structs.VariableMetadata{
+			Namespace: structs.DefaultNamespace,
+			Path:      "project/" + structs.GenerateUUID(),
+		},
+		Items: structs.VariableItems{
+			"username": "admin",
+			"password": "hunter2",
+		},
+	}
+}
+
+func RootKey() *structs.RootKey {
+	return &structs.RootKey{
+		KeyID:       structs.GenerateUUID(),
+		Algorithm:   structs.RootKeyAlgorithm,
+		WrappedKey:  []byte(structs.GenerateUUID()),
+		CreateTime:  time.Now().UTC().UnixNano(),
+		CreateIndex: 10,
+		ModifyIndex: 20,
+	}
+}
+
+func VariableEncrypted(keyID string) *structs.VariableEncrypted {
+	return &structs.VariableEncrypted{
+		VariableMetadata: structs.VariableMetadata{
+			Namespace:   structs.DefaultNamespace,
+			Path:        "project/" + structs.GenerateUUID(),
+			CreateTime:  time.Now().UTC().UnixNano(),
+			ModifyTime:  time.Now().UTC().UnixNano(),
+			CreateIndex: 10,
+			ModifyIndex: 20,
+		},
+		KeyID: keyID,
+		Data:  []byte(structs.GenerateUUID()),
+	}
+}
@@ -0,0 +1,283 @@
+package nomad
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// watchMultiregionRollouts periodically advances the multi-region rollouts of
+// the jobs submitted to the region. The health of the regions being rolled
+// out is checked, and the next regions are started once earlier ones are
+// healthy.
+func (s *Server) watchMultiregionRollouts(stopCh chan struct{}) {
+	ticker := time.NewTicker(s.config.MultiregionRolloutInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			s.advanceMultiregionRollouts()
+		}
+	}
+}
+
+// advanceMultiregionRollouts advances each of the active multi-region
+// rollouts
+func (s *Server) advanceMultiregionRollouts() {
+	iter, err := s.fsm.State().MultiregionRollouts()
+	if err != nil {
+		s.logger.Printf("[ERR] nomad: failed to list multi-region rollouts: %v", err)
+		return
+	}
+
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		rollout := raw.(*structs.MultiregionRollout)
+		if !rollout.Active() {
+			continue
+		}
+		if err := s.advanceMultiregionRollout(rollout); err != nil {
+			s.logger.Printf("[ERR] nomad: failed to advance multi-region rollout of job %q: %v", rollout.JobID, err)
+		}
+	}
+}
+
+// advanceMultiregionRollout updates the status of the regions of the rollout
+// being rolled out, starts the next regions the strategy allows and commits
+// the rollout if it changed
+func (s *Server) advanceMultiregionRollout(rollout *structs.MultiregionRollout) error {
+	rollout = rollout.Copy()
+	changed := false
+
+	// Check the health of the regions being rolled out. Regions that can't
+	// be reached are checked again on the next pass.
+	for _, region := range rollout.Regions {
+		if region.Status != structs.MultiregionRegionStatusRunning {
+			continue
+		}
+		status, desc, err := s.multiregionRegionStatus(rollout, region)
+		if err != nil {
+			s.logger.Printf("[WARN] nomad: failed to check health of job %q in region %q: %v",
+				rollout.JobID, region.Region, err)
+			continue
+		}
+		if status != region.Status {
+			region.Status = status
+			region.StatusDescription = desc
+			changed = true
+		}
+	}
+
+	start, stepped := multiregionRolloutStep(rollout)
+	changed = changed || stepped
+
+	// Start the rollout of the next regions by evaluating the job in them
+	for _, region := range start {
+		changed = true
+		req := &structs.JobEvaluateRequest{
+			JobID: rollout.JobID,
+			WriteRequest: structs.WriteRequest{
+				Region:    region.Region,
+				Namespace: rollout.Namespace,
+				AuthToken: s.config.ReplicationToken,
+			},
+		}
+		var resp structs.JobRegisterResponse
+		if err := s.RPC("Job.Evaluate", req, &resp); err != nil {
+			region.Status = structs.MultiregionRegionStatusFailed
+			region.StatusDescription = fmt.Sprintf("failed to evaluate job: %v", err)
+			continue
+		}
+		region.Status = structs.MultiregionRegionStatusRunning
+		region.EvalID = resp.EvalID
+	}
+
+	if !changed {
+		return nil
+	}
+
+	req := &structs.MultiregionRolloutUpsertRequest{
+		Rollout:      rollout,
+		WriteRequest: structs.WriteRequest{Region: s.config.Region},
+	}
+	resp, _, err := s.raftApply(structs.MultiregionRolloutUpsertRequestType, req)
+	if err != nil {
+		return err
+	}
+	if err, ok := resp.(error); ok && err != nil {
+		return err
+	}
+	return nil
+}
+
+// multiregionRolloutStep applies the strategy of the rollout to the status of
+// its regions. Once a region failed, the pending regions are cancelled unless
+// the strategy only fails the local region. Otherwise the pending regions to
+// start are returned, up to MaxParallel regions being rolled out at once. The
+// rollout completes once no region is left to roll out. The returned bool is
+// whether the rollout changed.
+func multiregionRolloutStep(rollout *structs.MultiregionRollout) ([]*structs.MultiregionRolloutRegion, bool) {
+	changed := false
+	running := 0
+	var failed []string
+	for _, region := range rollout.Regions {
+		switch region.Status {
+		case structs.MultiregionRegionStatusRunning:
+			running++
+		case structs.MultiregionRegionStatusFailed:
+			failed = append(failed, region.Region)
+		}
+	}
+
+	var start []*structs.MultiregionRolloutRegion
+	if len(failed) != 0 && rollout.Strategy.OnFailure != structs.MultiregionOnFailureFailLocal {
+		for _, region := range rollout.Regions {
+			if region.Status == structs.MultiregionRegionStatusPending {
+				region.Status = structs.MultiregionRegionStatusCancelled
+				region.StatusDescription = fmt.Sprintf("Cancelled after region %q failed", failed[0])
+				changed = true
+			}
+		}
+	} else {
+		for _, region := range rollout.Regions {
+			if running >= rollout.Strategy.MaxParallel {
+				break
+			}
+			if region.Status == structs.MultiregionRegionStatusPending {
+				start = append(start, region)
+				running++
+			}
+		}
+	}
+
+	if running != 0 {
+		return start, changed
+	}
+	if len(failed) != 0 {
+		rollout.Status = structs.MultiregionRolloutStatusFailed
+		rollout.StatusDescription = fmt.Sprintf("Failed in regions %v", failed)
+	} else {
+		rollout.Status = structs.MultiregionRolloutStatusSuccessful
+		rollout.StatusDescription = "All regions are healthy"
+	}
+	return start, true
+}
+
+// multiregionRegionStatus looks up the job, the evaluation that started its
+// rollout and its allocations in the region to return the rollout status of
+// the region
+func (s *Server) multiregionRegionStatus(rollout *structs.MultiregionRollout,
+	region *structs.MultiregionRolloutRegion) (string, string, error) {
+	opts := structs.QueryOptions{
+		Region:     region.Region,
+		Namespace:  rollout.Namespace,
+		AuthToken:  s.config.ReplicationToken,
+		AllowStale: true,
+	}
+
+	jobReq := &structs.JobSpecificRequest{JobID: rollout.JobID, QueryOptions: opts}
+	var jobResp structs.SingleJobResponse
+	if err := s.RPC("Job.GetJob", jobReq, &jobResp); err != nil {
+		return "", "", err
+	}
+	if jobResp.Job == nil {
+		return structs.MultiregionRegionStatusFailed, "Job was deregistered", nil
+	}
+	if jobResp.Job.JobModifyIndex != region.JobModifyIndex {
+		return structs.MultiregionRegionStatusFailed, "Job was updated during the rollout", nil
+	}
+
+	var eval *structs.Evaluation
+	if region.EvalID != "" {
+		evalReq := &structs.EvalSpecificRequest{EvalID: region.EvalID, QueryOptions: opts}
+		var evalResp structs.SingleEvalResponse
+		if err := s.RPC("Eval.GetEval", evalReq, &evalResp); err != nil {
+			return "", "", err
+		}
+		eval = evalResp.Eval
+	}
+
+	allocReq := &structs.JobSpecificRequest{JobID: rollout.JobID, QueryOptions: opts}
+	var allocResp structs.JobAllocationsResponse
+	if err := s.RPC("Job.Allocations", allocReq, &allocResp); err != nil {
+		return "", "", err
+	}
+
+	status, desc := multiregionHealth(jobResp.Job, eval, allocResp.Allocations)
+	return status, desc, nil
+}
+
+// multiregionHealth returns the rollout status of a region given the job
+// registered in it, the evaluation that started its rollout and the
+// allocations of the job. The region is healthy once the evaluation is
+// complete, the task groups have their count of allocations of the job
+// version running, or complete for batch jobs, and no allocation of an
+// earlier version is left to be replaced. The evaluation is skipped if it was
+// garbage collected.
+func multiregionHealth(job *structs.Job, eval *structs.Evaluation,
+	allocs []*structs.AllocListStub) (string, string) {
+	if eval != nil {
+		switch eval.Status {
+		case structs.EvalStatusComplete:
+		case structs.EvalStatusFailed, structs.EvalStatusCancelled:
+			return structs.MultiregionRegionStatusFailed,
+				fmt.Sprintf("Evaluation %q %s: %s", eval.ID, eval.Status, eval.StatusDescription)
+		default:
+			return structs.MultiregionRegionStatusRunning, ""
+		}
+	}
+
+	healthy := make(map[string]int)
+	for _, alloc := range allocs {
+		if alloc.DesiredStatus != structs.AllocDesiredStatusRun {
+			continue
+		}
+
+		terminal := alloc.ClientStatus == structs.AllocClientStatusComplete ||
+			alloc.ClientStatus == structs.AllocClientStatusFailed ||
+			alloc.ClientStatus == structs.AllocClientStatusLost
+		if alloc.JobModifyIndex != job.JobModifyIndex {
+			if terminal {
+				continue
+			}
+			return structs.MultiregionRegionStatusRunning, ""
+		}
+
+		switch alloc.ClientStatus {
+		case structs.AllocClientStatusFailed:
+			return structs.MultiregionRegionStatusFailed, fmt.Sprintf("Allocation %q failed", alloc.ID)
+		case structs.AllocClientStatusRunning:
+			healthy[alloc.TaskGroup]++
+		case structs.AllocClientStatusComplete:
+			if job.Type == structs.JobTypeBatch {
+				healthy[alloc.TaskGroup]++
+			}
+		}
+	}
+
+	// System jobs are placed on each feasible node rather than by count
+	if job.Type == structs.JobTypeSystem {
+		for _, alloc := range allocs {
+			if alloc.DesiredStatus == structs.AllocDesiredStatusRun &&
+				alloc.JobModifyIndex == job.JobModifyIndex &&
+				alloc.ClientStatus != structs.AllocClientStatusRunning {
+				return structs.MultiregionRegionStatusRunning, ""
+			}
+		}
+		return structs.MultiregionRegionStatusHealthy, ""
+	}
+
+	for _, tg := range job.TaskGroups {
+		if healthy[tg.Name] < tg.Count {
+			return structs.MultiregionRegionStatusRunning, ""
+		}
+	}
+	return structs.MultiregionRegionStatusHealthy, ""
+}
@@ -0,0 +1,224 @@
+package nomad
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func testMultiregionRollout(onFailure string, statuses ...string) *structs.MultiregionRollout {
+	rollout := &structs.MultiregionRollout{
+		JobID:  "example",
+		Status: structs.MultiregionRolloutStatusRunning,
+		Strategy: &structs.MultiregionStrategy{
+			MaxParallel: 1,
+			OnFailure:   onFailure,
+		},
+	}
+	for i, status := range statuses {
+		rollout.Regions = append(rollout.Regions, &structs.MultiregionRolloutRegion{
+			Region: string("abc"[i]),
+			Status: status,
+		})
+	}
+	return rollout
+}
+
+func TestMultiregionRolloutStep(t *testing.T) {
+	// The next region starts once the previous one is healthy
+	rollout := testMultiregionRollout(structs.MultiregionOnFailureFailAll,
+		structs.MultiregionRegionStatusHealthy,
+		structs.MultiregionRegionStatusPending,
+		structs.MultiregionRegionStatusPending)
+	start, changed := multiregionRolloutStep(rollout)
+	if len(start) != 1 || start[0].Region != "b" || changed {
+		t.Fatalf("bad: %v %v", start, changed)
+	}
+
+	// Nothing starts while a region is being rolled out
+	rollout.Regions[1].Status = structs.MultiregionRegionStatusRunning
+	start, changed = multiregionRolloutStep(rollout)
+	if len(start) != 0 || changed || !rollout.Active() {
+		t.Fatalf("bad: %v %v %#v", start, changed, rollout)
+	}
+
+	// The rollout succeeds once each region is healthy
+	for _, region := range rollout.Regions {
+		region.Status = structs.MultiregionRegionStatusHealthy
+	}
+	start, changed = multiregionRolloutStep(rollout)
+	if len(start) != 0 || !changed || rollout.Status != structs.MultiregionRolloutStatusSuccessful {
+		t.Fatalf("bad: %v %v %#v", start, changed, rollout)
+	}
+}
+
+func TestMultiregionRolloutStep_FailAll(t *testing.T) {
+	rollout := testMultiregionRollout(structs.MultiregionOnFailureFailAll,
+		structs.MultiregionRegionStatusFailed,
+		structs.MultiregionRegionStatusPending,
+		structs.MultiregionRegionStatusPending)
+	start, changed := multiregionRolloutStep(rollout)
+	if len(start) != 0 || !changed {
+		t.Fatalf("bad: %v %v", start, changed)
+	}
+	for _, region := range rollout.Regions[1:] {
+		if region.Status != structs.MultiregionRegionStatusCancelled {
+			t.Fatalf("bad: %#v", region)
+		}
+	}
+	if rollout.Status != structs.MultiregionRolloutStatusFailed {
+		t.Fatalf("bad: %#v", rollout)
+	}
+}
+
+func TestMultiregionRolloutStep_FailLocal(t *testing.T) {
+	rollout := testMultiregionRollout(structs.MultiregionOnFailureFailLocal,
+		structs.MultiregionRegionStatusFailed,
+		structs.MultiregionRegionStatusPending)
+	start, changed := multiregionRolloutStep(rollout)
+	if len(start) != 1 || start[0].Region != "b" || changed {
+		t.Fatalf("bad: %v %v", start, changed)
+	}
+
+	// The rollout fails once the remaining regions are rolled out
+	rollout.Regions[1].Status = structs.MultiregionRegionStatusHealthy
+	if _, changed = multiregionRolloutStep(rollout); !changed {
+		t.Fatalf("expected change")
+	}
+	if rollout.Status != structs.MultiregionRolloutStatusFailed ||
+		!strings.Contains(rollout.StatusDescription, "[a]") {
+		t.Fatalf("bad: %#v", rollout)
+	}
+}
+
+func TestMultiregionHealth(t *testing.T) {
+	job := mock.Job()
+	job.JobModifyIndex = 10
+	job.TaskGroups[0].Count = 2
+	eval := mock.Eval()
+
+	alloc := func(jobModifyIndex uint64, clientStatus string) *structs.AllocListStub {
+		return &structs.AllocListStub{
+			ID:             structs.GenerateUUID(),
+			TaskGroup:      job.TaskGroups[0].Name,
+			DesiredStatus:  structs.AllocDesiredStatusRun,
+			ClientStatus:   clientStatus,
+			JobModifyIndex: jobModifyIndex,
+		}
+	}
+
+	// The region is rolling out while its evaluation is pending
+	if status, _ := multiregionHealth(job, eval, nil); status != structs.MultiregionRegionStatusRunning {
+		t.Fatalf("bad: %v", status)
+	}
+
+	// A failed evaluation fails the region
+	eval.Status = structs.EvalStatusFailed
+	if status, _ := multiregionHealth(job, eval, nil); status != structs.MultiregionRegionStatusFailed {
+		t.Fatalf("bad: %v", status)
+	}
+
+	// Allocations of an earlier version are yet to be replaced
+	eval.Status = structs.EvalStatusComplete
+	allocs := []*structs.AllocListStub{
+		alloc(10, structs.AllocClientStatusRunning),
+		alloc(5, structs.AllocClientStatusRunning),
+	}
+	if status, _ := multiregionHealth(job, eval, allocs); status != structs.MultiregionRegionStatusRunning {
+		t.Fatalf("bad: %v", status)
+	}
+
+	// The region is healthy once the count of the new version is running
+	allocs[1] = alloc(10, structs.AllocClientStatusRunning)
+	allocs = append(allocs, alloc(5, structs.AllocClientStatusComplete))
+	if status, _ := multiregionHealth(job, eval, allocs); status != structs.MultiregionRegionStatusHealthy {
+		t.Fatalf("bad: %v", status)
+	}
+
+	// A failed allocation of the new version fails the region
+	allocs = append(allocs, alloc(10, structs.AllocClientStatusFailed))
+	if status, _ := multiregionHealth(job, eval, allocs); status != structs.MultiregionRegionStatusFailed {
+		t.Fatalf("bad: %v", status)
+	}
+}
+
+func TestServer_MultiregionRollout(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.Region = "region1"
+		c.MultiregionRolloutInterval = 50 * time.Millisecond
+	})
+	defer s1.Shutdown()
+	s2 := testServer(t, func(c *Config) {
+		c.Region = "region2"
+	})
+	defer s2.Shutdown()
+	testJoin(t, s1, s2)
+	testutil.WaitForLeader(t, s1.RPC)
+	testutil.WaitForLeader(t, s2.RPC)
+	codec := rpcClient(t, s1)
+
+	// Register a job rolled out to one region at a time. The task group
+	// has no allocations to place so each region is healthy once the job
+	// is evaluated.
+	job := mock.Job()
+	job.Region = "region1"
+	job.Regions = []string{"region1", "region2"}
+	job.Multiregion = &structs.MultiregionStrategy{MaxParallel: 1}
+	job.TaskGroups[0].Count = 0
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "region1"},
+	}
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The second region registers the job without evaluating it
+	if resp.EvalID == "" || len(resp.RegionEvalIDs) != 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+	out, err := s2.fsm.State().JobByID(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("expected job")
+	}
+
+	// The rollout reaches the second region once the first is healthy
+	testutil.WaitForResult(func() (bool, error) {
+		rollout, err := s1.fsm.State().MultiregionRolloutByJob(job.Namespace, job.ID)
+		if err != nil {
+			return false, err
+		}
+		if rollout == nil || rollout.Status != structs.MultiregionRolloutStatusSuccessful {
+			return false, fmt.Errorf("rollout not successful: %#v", rollout)
+		}
+		if rollout.Regions[1].EvalID == "" {
+			return false, fmt.Errorf("second region not evaluated: %#v", rollout.Regions[1])
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// The rollout is readable from either region
+	get := &structs.JobSpecificRequest{
+		JobID:        job.ID,
+		QueryOptions: structs.QueryOptions{Region: "region1"},
+	}
+	var getResp structs.JobRolloutResponse
+	if err := msgpackrpc.CallWithCodec(rpcClient(t, s2), "Job.Rollout", get, &getResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if getResp.Rollout == nil || len(getResp.Rollout.Regions) != 2 {
+		t.Fatalf("bad: %#v", getResp.Rollout)
+	}
+}
@@ -0,0 +1,320 @@
+package nomad
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/watch"
+)
+
+var (
+	// defaultNamespace is returned when the default namespace has not been
+	// explicitly registered. It always exists.
+	defaultNamespace = &structs.Namespace{
+		Name:        structs.DefaultNamespace,
+		Description: "Default shared namespace",
+	}
+)
+
+// Namespace endpoint is used for manipulating namespaces
+type Namespace struct {
+	srv *Server
+}
+
+// UpsertNamespaces is used to create or update a set of namespaces
+func (n *Namespace) UpsertNamespaces(args *structs.NamespaceUpsertRequest,
+	reply *structs.GenericResponse) error {
+	// Always flow modification requests to the authoritative region, which
+	// replicates them to the other regions
+	args.Region = n.srv.config.AuthoritativeRegion
+
+	if done, err := n.srv.forward("Namespace.UpsertNamespaces", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "namespace", "upsert_namespaces"}, time.Now())
+
+	// Check management level permissions
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.Namespaces) == 0 {
+		return fmt.Errorf("must specify at least one namespace")
+	}
+	for _, ns := range args.Namespaces {
+		if err := ns.Validate(); err != nil {
+			return fmt.Errorf("Invalid namespace %q: %v", ns.Name, err)
+		}
+	}
+
+	// Commit this update via Raft
+	_, index, err := n.srv.raftApply(structs.NamespaceUpsertRequestType, args)
+	if err != nil {
+		n.srv.logger.Printf("[ERR] nomad.namespace: UpsertNamespaces failed: %v", err)
+		return err
+	}
+
+	reply.Index = index
+	return nil
+}
+
+// DeleteNamespaces is used to delete a set of namespaces
+func (n *Namespace) DeleteNamespaces(args *structs.NamespaceDeleteRequest,
+	reply *structs.GenericResponse) error {
+	// Always flow modification requests to the authoritative region, which
+	// replicates them to the other regions
+	args.Region = n.srv.config.AuthoritativeRegion
+
+	if done, err := n.srv.forward("Namespace.DeleteNamespaces", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "namespace", "delete_namespaces"}, time.Now())
+
+	// Check management level permissions
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.Namespaces) == 0 {
+		return fmt.Errorf("must specify at least one namespace to delete")
+	}
+	for _, name := range args.Namespaces {
+		if name == structs.DefaultNamespace {
+			return fmt.Errorf("can not delete default namespace")
+		}
+	}
+
+	// Commit this update via Raft
+	_, index, err := n.srv.raftApply(structs.NamespaceDeleteRequestType, args)
+	if err != nil {
+		n.srv.logger.Printf("[ERR] nomad.namespace: DeleteNamespaces failed: %v", err)
+		return err
+	}
+
+	reply.Index = index
+	return nil
+}
+
+// ListNamespaces is used to list the namespaces
+func (n *Namespace) ListNamespaces(args *structs.NamespaceListRequest,
+	reply *structs.NamespaceListResponse) error {
+	if done, err := n.srv.forward("Namespace.ListNamespaces", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "namespace", "list_namespace"}, time.Now())
+
+	// Resolve the token, namespaces the token has no access to are filtered
+	aclObj, err := n.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "namespaces"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Capture all the namespaces
+			var err error
+			var iter memdb.ResultIterator
+			if prefix := args.QueryOptions.Prefix; prefix != "" {
+				iter, err = snap.NamespacesByNamePrefix(prefix)
+			} else {
+				iter, err = snap.Namespaces()
+			}
+			if err != nil {
+				return err
+			}
+
+			var namespaces []*structs.Namespace
+			foundDefault := false
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				ns := raw.(*structs.Namespace)
+				if ns.Name == structs.DefaultNamespace {
+					foundDefault = true
+				}
+				if aclObj != nil && !aclObj.AllowNamespace(ns.Name) {
+					continue
+				}
+				namespaces = append(namespaces, ns)
+			}
+
+			// Include the implicit default namespace
+			if !foundDefault && strings.HasPrefix(structs.DefaultNamespace, args.QueryOptions.Prefix) &&
+				(aclObj == nil || aclObj.AllowNamespace(structs.DefaultNamespace)) {
+				namespaces = append([]*structs.Namespace{defaultNamespace}, namespaces...)
+			}
+			reply.Namespaces = namespaces
+
+			// Use the last index that affected the namespace table
+			index, err := snap.Index("namespaces")
+			if err != nil {
+				return err
+			}
+
+			// Ensure we never set the index to zero, otherwise a blocking query
+			// cannot be used. We floor the index at one, since realistically
+			// the first write must have a higher index.
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			n.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return n.srv.blockingRPC(&opts)
+}
+
+// GetNamespace is used to get a specific namespace
+func (n *Namespace) GetNamespace(args *structs.NamespaceSpecificRequest,
+	reply *structs.SingleNamespaceResponse) error {
+	if done, err := n.srv.forward("Namespace.GetNamespace", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "namespace", "get_namespace"}, time.Now())
+
+	// Check namespace permissions
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNamespace(args.Name) {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "namespaces"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Look for the namespace
+			out, err := lookupNamespace(snap, args.Name)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Namespace = out
+			if out != nil && out.ModifyIndex != 0 {
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the namespace table
+				index, err := snap.Index("namespaces")
+				if err != nil {
+					return err
+				}
+				if index == 0 {
+					index = 1
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			n.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return n.srv.blockingRPC(&opts)
+}
+
+// ReplicationStatus is used to return the status of the replication of the
+// namespaces and quota specifications from the authoritative region. The
+// status is tracked by the leader, so the request is always served by the
+// leader.
+func (n *Namespace) ReplicationStatus(args *structs.GenericRequest,
+	reply *structs.NamespaceReplicationStatusResponse) error {
+	args.AllowStale = false
+	if done, err := n.srv.forward("Namespace.ReplicationStatus", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "namespace", "replication_status"}, time.Now())
+
+	// Check operator read permissions
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowOperatorRead() {
+		return structs.ErrPermissionDenied
+	}
+
+	running, progress, conflicts := n.srv.namespaceReplication.status()
+	reply.Status = &structs.NamespaceReplicationStatus{
+		Enabled:    n.srv.config.Region != n.srv.config.AuthoritativeRegion,
+		Running:    running,
+		Namespaces: progress[namespaceReplicationNamespaces],
+		QuotaSpecs: progress[namespaceReplicationQuotas],
+		Conflicts:  conflicts,
+	}
+	if reply.Status.Enabled {
+		reply.Status.SourceRegion = n.srv.config.AuthoritativeRegion
+	}
+
+	// Use the last index that affected either table
+	index, err := n.srv.fsm.State().Index("namespaces")
+	if err != nil {
+		return err
+	}
+	if quotaIndex, err := n.srv.fsm.State().Index("quota_specs"); err != nil {
+		return err
+	} else if quotaIndex > index {
+		index = quotaIndex
+	}
+	reply.Index = index
+
+	// Set the query response
+	n.srv.setQueryMeta(&reply.QueryMeta)
+	return nil
+}
+
+// lookupNamespace returns the namespace with the given name, taking into
+// account that the default namespace always exists.
+func lookupNamespace(snap *state.StateSnapshot, name string) (*structs.Namespace, error) {
+	ns, err := snap.NamespaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if ns == nil && name == structs.DefaultNamespace {
+		return defaultNamespace, nil
+	}
+	return ns, nil
+}
+
+// validateJobNamespace ensures the job's namespace exists.
+func validateJobNamespace(snap *state.StateSnapshot, job *structs.Job) error {
+	ns, err := lookupNamespace(snap, job.Namespace)
+	if err != nil {
+		return err
+	}
+	if ns == nil {
+		return fmt.Errorf("job %q is in nonexistent namespace %q", job.ID, job.Namespace)
+	}
+	return nil
+}
+
+// validateJobNetworkPolicy ensures the job only requests the networking the
+// policy of its namespace allows. It must run after validateJobNamespace.
+func validateJobNetworkPolicy(snap *state.StateSnapshot, job *structs.Job) error {
+	ns, err := lookupNamespace(snap, job.Namespace)
+	if err != nil || ns == nil {
+		return err
+	}
+	if err := ns.NetworkPolicy.Admit(job); err != nil {
+		return fmt.Errorf("job %q denied by the network policy of namespace %q: %v", job.ID, ns.Name, err)
+	}
+	return nil
+}
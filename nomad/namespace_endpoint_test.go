@@ -0,0 +1,203 @@
+package nomad
+
+import (
+	"testing"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestNamespaceEndpoint_UpsertNamespaces(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	ns1 := mock.Namespace()
+	ns2 := mock.Namespace()
+	req := &structs.NamespaceUpsertRequest{
+		Namespaces:   []*structs.Namespace{ns1, ns2},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Namespace.UpsertNamespaces", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	// Check we created the namespaces
+	out, err := s1.fsm.State().NamespaceByName(ns1.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("namespace not found")
+	}
+	out, err = s1.fsm.State().NamespaceByName(ns2.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("namespace not found")
+	}
+
+	// Invalid names are rejected
+	req.Namespaces = []*structs.Namespace{{Name: "bad name"}}
+	if err := msgpackrpc.CallWithCodec(codec, "Namespace.UpsertNamespaces", req, &resp); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestNamespaceEndpoint_DeleteNamespaces(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	ns := mock.Namespace()
+	state := s1.fsm.State()
+	if err := state.UpsertNamespaces(1000, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The default namespace can't be deleted
+	req := &structs.NamespaceDeleteRequest{
+		Namespaces:   []string{structs.DefaultNamespace},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Namespace.DeleteNamespaces", req, &resp); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	req.Namespaces = []string{ns.Name}
+	if err := msgpackrpc.CallWithCodec(codec, "Namespace.DeleteNamespaces", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	out, err := state.NamespaceByName(ns.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("namespace not deleted: %#v", out)
+	}
+}
+
+func TestNamespaceEndpoint_ListNamespaces(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	ns := mock.Namespace()
+	ns.Name = "engineering"
+	if err := s1.fsm.State().UpsertNamespaces(1000, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The implicit default namespace is always listed
+	req := &structs.NamespaceListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.NamespaceListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Namespace.ListNamespaces", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("Bad index: %d %d", resp.Index, 1000)
+	}
+	if len(resp.Namespaces) != 2 {
+		t.Fatalf("bad: %#v", resp.Namespaces)
+	}
+
+	// Lookup the namespaces by prefix
+	req.Prefix = "eng"
+	var resp2 structs.NamespaceListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Namespace.ListNamespaces", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Namespaces) != 1 || resp2.Namespaces[0].Name != ns.Name {
+		t.Fatalf("bad: %#v", resp2.Namespaces)
+	}
+}
+
+func TestNamespaceEndpoint_GetNamespace(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	ns := mock.Namespace()
+	if err := s1.fsm.State().UpsertNamespaces(1000, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.NamespaceSpecificRequest{
+		Name:         ns.Name,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.SingleNamespaceResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Namespace.GetNamespace", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("Bad index: %d %d", resp.Index, 1000)
+	}
+	if resp.Namespace == nil || resp.Namespace.Name != ns.Name {
+		t.Fatalf("bad: %#v", resp.Namespace)
+	}
+
+	// The default namespace exists even if not registered
+	req.Name = structs.DefaultNamespace
+	if err := msgpackrpc.CallWithCodec(codec, "Namespace.GetNamespace", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Namespace == nil || resp.Namespace.Name != structs.DefaultNamespace {
+		t.Fatalf("bad: %#v", resp.Namespace)
+	}
+
+	// Missing namespace
+	req.Name = "missing"
+	if err := msgpackrpc.CallWithCodec(codec, "Namespace.GetNamespace", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Namespace != nil {
+		t.Fatalf("bad: %#v", resp.Namespace)
+	}
+}
+
+func TestNamespaceEndpoint_ReplicationStatus(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Anonymous requests are denied
+	req := &structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.NamespaceReplicationStatusResponse
+	err := msgpackrpc.CallWithCodec(codec, "Namespace.ReplicationStatus", req, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// The authoritative region doesn't replicate
+	req.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Namespace.ReplicationStatus", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	status := resp.Status
+	if status.Enabled || status.Running || status.SourceRegion != "" || len(status.Conflicts) != 0 {
+		t.Fatalf("bad: %#v", status)
+	}
+}
@@ -3,6 +3,8 @@ package nomad
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -114,6 +116,17 @@ func (n *Node) Register(args *structs.NodeRegisterRequest, reply *structs.NodeUp
 		}
 	}
 
+	// Record the registration and the changes of the drivers' health
+	args.Node.Events = nil
+	if originalNode == nil {
+		args.Node.Events = append(args.Node.Events, structs.NewNodeEvent(structs.NodeEventSubsystemCluster, "Node registered"))
+	} else {
+		if originalNode.Status == structs.NodeStatusDown && args.Node.Status != structs.NodeStatusDown {
+			args.Node.Events = append(args.Node.Events, structs.NewNodeEvent(structs.NodeEventSubsystemCluster, "Node re-registered"))
+		}
+		args.Node.Events = append(args.Node.Events, driverHealthEvents(originalNode, args.Node)...)
+	}
+
 	// Commit this update via Raft
 	_, index, err := n.srv.raftApply(structs.NodeRegisterRequestType, args)
 	if err != nil {
@@ -128,7 +141,8 @@ func (n *Node) Register(args *structs.NodeRegisterRequest, reply *structs.NodeUp
 		originalStatus = originalNode.Status
 	}
 	transitionToReady := transitionedToReady(args.Node.Status, originalStatus)
-	if structs.ShouldDrainNode(args.Node.Status) || transitionToReady {
+	if structs.ShouldDrainNode(args.Node.Status) || transitionToReady ||
+		nodeConstraintsChanged(originalNode, args.Node) {
 		evalIDs, evalIndex, err := n.createNodeEvals(args.Node.ID, index)
 		if err != nil {
 			n.srv.logger.Printf("[ERR] nomad.client: eval creation failed: %v", err)
@@ -165,6 +179,17 @@ func (n *Node) Register(args *structs.NodeRegisterRequest, reply *structs.NodeUp
 	return nil
 }
 
+// nodeConstraintsChanged returns whether a ready node changed the meta or
+// attributes that constraints are checked against, in which case its
+// allocations and the system jobs must be evaluated again
+func nodeConstraintsChanged(original, updated *structs.Node) bool {
+	if original == nil || updated.Status != structs.NodeStatusReady {
+		return false
+	}
+	return !reflect.DeepEqual(original.Attributes, updated.Attributes) ||
+		!reflect.DeepEqual(original.Meta, updated.Meta)
+}
+
 // nodePreSecretID is a helper that returns whether the node is on a version
 // that is before SecretIDs were introduced
 func nodePreSecretID(node *structs.Node) (bool, error) {
@@ -224,6 +249,13 @@ func (n *Node) Deregister(args *structs.NodeDeregisterRequest, reply *structs.No
 	}
 	defer metrics.MeasureSince([]string{"nomad", "client", "deregister"}, time.Now())
 
+	// Check node write permissions
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNodeWrite() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Verify the arguments
 	if args.NodeID == "" {
 		return fmt.Errorf("missing node ID for client deregistration")
@@ -254,7 +286,7 @@ func (n *Node) Deregister(args *structs.NodeDeregisterRequest, reply *structs.No
 	}
 
 	if len(accessors) != 0 {
-		if err := n.srv.vault.RevokeTokens(context.Background(), accessors, true); err != nil {
+		if err := n.srv.revokeVaultTokens(context.Background(), accessors, true); err != nil {
 			n.srv.logger.Printf("[ERR] nomad.client: revoking accessors for node %q failed: %v", args.NodeID, err)
 			return err
 		}
@@ -268,6 +300,37 @@ func (n *Node) Deregister(args *structs.NodeDeregisterRequest, reply *structs.No
 	return nil
 }
 
+// driverHealthEvents returns the events for the drivers that became healthy
+// or unhealthy between the two registrations of a node. A driver is healthy
+// while the node fingerprints it.
+func driverHealthEvents(original, updated *structs.Node) []*structs.NodeEvent {
+	drivers := func(node *structs.Node) map[string]struct{} {
+		set := make(map[string]struct{})
+		for k := range node.Attributes {
+			if strings.HasPrefix(k, "driver.") && strings.Count(k, ".") == 1 {
+				set[strings.TrimPrefix(k, "driver.")] = struct{}{}
+			}
+		}
+		return set
+	}
+	before, after := drivers(original), drivers(updated)
+
+	var events []*structs.NodeEvent
+	for driver := range before {
+		if _, ok := after[driver]; !ok {
+			events = append(events, structs.NewNodeEvent(structs.NodeEventSubsystemDriver, "Driver became unhealthy").
+				SetDetail("driver", driver))
+		}
+	}
+	for driver := range after {
+		if _, ok := before[driver]; !ok {
+			events = append(events, structs.NewNodeEvent(structs.NodeEventSubsystemDriver, "Driver became healthy").
+				SetDetail("driver", driver))
+		}
+	}
+	return events
+}
+
 // UpdateStatus is used to update the status of a client node
 func (n *Node) UpdateStatus(args *structs.NodeUpdateStatusRequest, reply *structs.NodeUpdateResponse) error {
 	if done, err := n.srv.forward("Node.UpdateStatus", args, args, reply); done {
@@ -305,6 +368,9 @@ func (n *Node) UpdateStatus(args *structs.NodeUpdateStatusRequest, reply *struct
 	// Commit this update via Raft
 	var index uint64
 	if node.Status != args.Status {
+		if args.NodeEvent == nil && node.Status == structs.NodeStatusDown {
+			args.NodeEvent = structs.NewNodeEvent(structs.NodeEventSubsystemHeartbeat, "Node heartbeat received after being down")
+		}
 		_, index, err = n.srv.raftApply(structs.NodeUpdateStatusRequestType, args)
 		if err != nil {
 			n.srv.logger.Printf("[ERR] nomad.client: status update failed: %v", err)
@@ -336,7 +402,7 @@ func (n *Node) UpdateStatus(args *structs.NodeUpdateStatusRequest, reply *struct
 		}
 
 		if len(accessors) != 0 {
-			if err := n.srv.vault.RevokeTokens(context.Background(), accessors, true); err != nil {
+			if err := n.srv.revokeVaultTokens(context.Background(), accessors, true); err != nil {
 				n.srv.logger.Printf("[ERR] nomad.client: revoking accessors for node %q failed: %v", args.NodeID, err)
 				return err
 			}
@@ -348,6 +414,24 @@ func (n *Node) UpdateStatus(args *structs.NodeUpdateStatusRequest, reply *struct
 			return err
 		}
 		reply.HeartbeatTTL = ttl
+
+		// Check the clock of the node against ours, recording on the node
+		// when it starts or stops being skewed
+		if args.ClientTime != 0 {
+			var event *structs.NodeEvent
+			reply.ClockSkew, event = n.srv.checkClockSkew(args.NodeID, time.Unix(0, args.ClientTime))
+			if event != nil {
+				req := structs.NodeUpdateStatusRequest{
+					NodeID:       args.NodeID,
+					Status:       args.Status,
+					NodeEvent:    event,
+					WriteRequest: structs.WriteRequest{Region: n.srv.config.Region},
+				}
+				if _, _, err := n.srv.raftApply(structs.NodeUpdateStatusRequestType, &req); err != nil {
+					n.srv.logger.Printf("[ERR] nomad.client: recording clock skew of node %q failed: %v", args.NodeID, err)
+				}
+			}
+		}
 	}
 
 	// Set the reply index and leader
@@ -378,6 +462,13 @@ func (n *Node) UpdateDrain(args *structs.NodeUpdateDrainRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "client", "update_drain"}, time.Now())
 
+	// Check node write permissions
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNodeWrite() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Verify the arguments
 	if args.NodeID == "" {
 		return fmt.Errorf("missing node ID for drain update")
@@ -397,11 +488,29 @@ func (n *Node) UpdateDrain(args *structs.NodeUpdateDrainRequest,
 	}
 
 	// Update the timestamp to
-	node.StatusUpdatedAt = time.Now().Unix()
+	now := time.Now()
+	node.StatusUpdatedAt = now.Unix()
+
+	// The deadline is counted from the request, while updating the drain of
+	// a draining node keeps the start of its drain
+	if args.DrainStrategy != nil {
+		args.DrainStrategy = structs.NewDrainStrategy(args.DrainStrategy.DrainSpec, now)
+		if node.DrainStrategy != nil {
+			args.DrainStrategy.StartedAt = node.DrainStrategy.StartedAt
+		}
+	}
 
 	// Commit this update via Raft
 	var index uint64
-	if node.Drain != args.Drain {
+	if node.DrainStrategy != nil || args.DrainStrategy != nil {
+		switch {
+		case node.DrainStrategy == nil:
+			args.NodeEvent = structs.NewNodeEvent(structs.NodeEventSubsystemDrain, "Node drain started")
+		case args.DrainStrategy == nil:
+			args.NodeEvent = structs.NewNodeEvent(structs.NodeEventSubsystemDrain, "Node drain stopped")
+		default:
+			args.NodeEvent = structs.NewNodeEvent(structs.NodeEventSubsystemDrain, "Node drain updated")
+		}
 		_, index, err = n.srv.raftApply(structs.NodeUpdateDrainRequestType, args)
 		if err != nil {
 			n.srv.logger.Printf("[ERR] nomad.client: drain update failed: %v", err)
@@ -410,15 +519,121 @@ func (n *Node) UpdateDrain(args *structs.NodeUpdateDrainRequest,
 		reply.NodeModifyIndex = index
 	}
 
-	// Always attempt to create Node evaluations because there may be a System
-	// job registered that should be evaluated.
-	evalIDs, evalIndex, err := n.createNodeEvals(args.NodeID, index)
+	// The allocations of a draining node are migrated by the drainer of the
+	// leader, so only the order they will be migrated in is returned.
+	// Otherwise always attempt to create Node evaluations because there may
+	// be a System job registered that should be evaluated.
+	if args.DrainStrategy != nil {
+		reply.DrainOrder, err = n.nodeDrainOrder(snap, args.NodeID, args.DrainStrategy.IgnoreSystemJobs)
+		if err != nil {
+			return err
+		}
+	} else {
+		evalIDs, evalIndex, err := n.createNodeEvals(args.NodeID, index)
+		if err != nil {
+			n.srv.logger.Printf("[ERR] nomad.client: eval creation failed: %v", err)
+			return err
+		}
+		reply.EvalIDs = evalIDs
+		reply.EvalCreateIndex = evalIndex
+	}
+
+	// Set the reply index
+	reply.Index = index
+	return nil
+}
+
+// nodeDrainOrder returns the order in which the jobs with allocations on the
+// node are migrated off of it when it is drained. If ignoreSystem is set,
+// system jobs are marked as skipped since their allocations keep running.
+func (n *Node) nodeDrainOrder(snap *state.StateSnapshot, nodeID string, ignoreSystem bool) ([]*structs.NodeDrainJob, error) {
+	allocs, err := snap.AllocsByNode(nodeID)
 	if err != nil {
-		n.srv.logger.Printf("[ERR] nomad.client: eval creation failed: %v", err)
+		return nil, fmt.Errorf("failed to find allocs for '%s': %v", nodeID, err)
+	}
+
+	jobs := make(map[structs.NamespacedID]*structs.Job)
+	for _, alloc := range allocs {
+		id := structs.NewNamespacedID(alloc.JobID, alloc.Namespace)
+		if _, ok := jobs[id]; !ok && alloc.Job != nil && !alloc.TerminalStatus() {
+			jobs[id] = alloc.Job
+		}
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+
+	order := drainOrder(jobs)
+	for _, entry := range order {
+		if entry.Type == structs.JobTypeSystem && ignoreSystem {
+			entry.Skipped = true
+		}
+	}
+	return order, nil
+}
+
+// UpdateEligibility is used to update the scheduling eligibility of a client
+// node. Ineligible nodes keep running their allocations, so they can be
+// cordoned before their allocations are stopped.
+func (n *Node) UpdateEligibility(args *structs.NodeUpdateEligibilityRequest,
+	reply *structs.NodeEligibilityUpdateResponse) error {
+	if done, err := n.srv.forward("Node.UpdateEligibility", args, args, reply); done {
 		return err
 	}
-	reply.EvalIDs = evalIDs
-	reply.EvalCreateIndex = evalIndex
+	defer metrics.MeasureSince([]string{"nomad", "client", "update_eligibility"}, time.Now())
+
+	// Check node write permissions
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNodeWrite() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Verify the arguments
+	if args.NodeID == "" {
+		return fmt.Errorf("missing node ID for eligibility update")
+	}
+	if !structs.ValidNodeSchedulingEligibility(args.Eligibility) {
+		return fmt.Errorf("invalid scheduling eligibility %q", args.Eligibility)
+	}
+
+	// Look for the node
+	snap, err := n.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	node, err := snap.NodeByID(args.NodeID)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return fmt.Errorf("node not found")
+	}
+
+	// Commit this update via Raft
+	var index uint64
+	if node.SchedulingEligibility != args.Eligibility {
+		args.NodeEvent = structs.NewNodeEvent(structs.NodeEventSubsystemScheduler,
+			fmt.Sprintf("Node marked as %s for scheduling", args.Eligibility))
+		_, index, err = n.srv.raftApply(structs.NodeUpdateEligibilityRequestType, args)
+		if err != nil {
+			n.srv.logger.Printf("[ERR] nomad.client: eligibility update failed: %v", err)
+			return err
+		}
+		reply.NodeModifyIndex = index
+	}
+
+	// Create Node evaluations once the node is eligible again, since there
+	// may be System jobs that should be placed on it
+	if args.Eligibility == structs.NodeSchedulingEligible {
+		evalIDs, evalIndex, err := n.createNodeEvals(args.NodeID, index)
+		if err != nil {
+			n.srv.logger.Printf("[ERR] nomad.client: eval creation failed: %v", err)
+			return err
+		}
+		reply.EvalIDs = evalIDs
+		reply.EvalCreateIndex = evalIndex
+	}
 
 	// Set the reply index
 	reply.Index = index
@@ -432,6 +647,13 @@ func (n *Node) Evaluate(args *structs.NodeEvaluateRequest, reply *structs.NodeUp
 	}
 	defer metrics.MeasureSince([]string{"nomad", "client", "evaluate"}, time.Now())
 
+	// Check node write permissions
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNodeWrite() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Verify the arguments
 	if args.NodeID == "" {
 		return fmt.Errorf("missing node ID for evaluation")
@@ -479,22 +701,33 @@ func (n *Node) GetNode(args *structs.NodeSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "client", "get_node"}, time.Now())
 
+	// Check node read permissions. Clients have no ACL token and use their
+	// secret ID to look up the nodes they migrate allocation data from.
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		if err != structs.ErrTokenNotFound {
+			return err
+		}
+		if ok, err := n.isNodeSecretID(args.AuthToken); err != nil {
+			return err
+		} else if !ok {
+			return structs.ErrTokenNotFound
+		}
+	} else if aclObj != nil && !aclObj.AllowNodeRead() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		watch:     watch.NewItems(watch.Item{Node: args.NodeID}),
-		run: func() error {
+		run: func(snap *state.StateSnapshot) error {
 			// Verify the arguments
 			if args.NodeID == "" {
 				return fmt.Errorf("missing node ID")
 			}
 
 			// Look for the node
-			snap, err := n.srv.fsm.State().Snapshot()
-			if err != nil {
-				return err
-			}
 			out, err := snap.NodeByID(args.NodeID)
 			if err != nil {
 				return err
@@ -523,6 +756,20 @@ func (n *Node) GetNode(args *structs.NodeSpecificRequest,
 	return n.srv.blockingRPC(&opts)
 }
 
+// isNodeSecretID returns whether the secret ID belongs to a registered node
+func (n *Node) isNodeSecretID(secretID string) (bool, error) {
+	iter, err := n.srv.fsm.State().Nodes()
+	if err != nil {
+		return false, err
+	}
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		if raw.(*structs.Node).SecretID == secretID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // GetAllocs is used to request allocations for a specific node
 func (n *Node) GetAllocs(args *structs.NodeSpecificRequest,
 	reply *structs.NodeAllocsResponse) error {
@@ -531,6 +778,13 @@ func (n *Node) GetAllocs(args *structs.NodeSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "client", "get_allocs"}, time.Now())
 
+	// Check node read permissions
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNodeRead() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Verify the arguments
 	if args.NodeID == "" {
 		return fmt.Errorf("missing node ID")
@@ -541,12 +795,8 @@ func (n *Node) GetAllocs(args *structs.NodeSpecificRequest,
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		watch:     watch.NewItems(watch.Item{AllocNode: args.NodeID}),
-		run: func() error {
+		run: func(snap *state.StateSnapshot) error {
 			// Look for the node
-			snap, err := n.srv.fsm.State().Snapshot()
-			if err != nil {
-				return err
-			}
 			allocs, err := snap.AllocsByNode(args.NodeID)
 			if err != nil {
 				return err
@@ -599,13 +849,7 @@ func (n *Node) GetClientAllocs(args *structs.NodeSpecificRequest,
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		watch:     watch.NewItems(watch.Item{AllocNode: args.NodeID}),
-		run: func() error {
-			// Look for the node
-			snap, err := n.srv.fsm.State().Snapshot()
-			if err != nil {
-				return err
-			}
-
+		run: func(snap *state.StateSnapshot) error {
 			// Look for the node
 			node, err := snap.NodeByID(args.NodeID)
 			if err != nil {
@@ -634,11 +878,24 @@ func (n *Node) GetClientAllocs(args *structs.NodeSpecificRequest,
 			}
 
 			reply.Allocs = make(map[string]uint64)
+			reply.MigrateTokens = make(map[string]string)
 			// Setup the output
 			if len(allocs) != 0 {
 				for _, alloc := range allocs {
 					reply.Allocs[alloc.ID] = alloc.AllocModifyIndex
 					reply.Index = maxUint64(reply.Index, alloc.ModifyIndex)
+
+					// Authorize the migration of the data of the previous
+					// allocation when it ran on another node
+					if n.srv.config.ACLEnabled {
+						token, err := migrateToken(snap, alloc)
+						if err != nil {
+							return err
+						}
+						if token != "" {
+							reply.MigrateTokens[alloc.ID] = token
+						}
+					}
 				}
 			} else {
 				// Use the last index that affected the nodes table
@@ -660,6 +917,35 @@ func (n *Node) GetClientAllocs(args *structs.NodeSpecificRequest,
 	return n.srv.blockingRPC(&opts)
 }
 
+// migrateToken returns the token authorizing the migration of the data of the
+// previous allocation of the allocation from the node it ran on. It is empty
+// if the data isn't migrated across nodes.
+func migrateToken(snap *state.StateSnapshot, alloc *structs.Allocation) (string, error) {
+	if alloc.PreviousAllocation == "" || alloc.Job == nil {
+		return "", nil
+	}
+	tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+	if tg == nil || tg.EphemeralDisk == nil || !tg.EphemeralDisk.Sticky || !tg.EphemeralDisk.Migrate {
+		return "", nil
+	}
+
+	prev, err := snap.AllocByID(alloc.PreviousAllocation)
+	if err != nil {
+		return "", err
+	}
+	if prev == nil || prev.NodeID == alloc.NodeID {
+		return "", nil
+	}
+	prevNode, err := snap.NodeByID(prev.NodeID)
+	if err != nil {
+		return "", err
+	}
+	if prevNode == nil {
+		return "", nil
+	}
+	return structs.GenerateMigrateToken(prev.ID, prevNode.SecretID), nil
+}
+
 // UpdateAlloc is used to update the client status of an allocation
 func (n *Node) UpdateAlloc(args *structs.AllocUpdateRequest, reply *structs.GenericResponse) error {
 	if done, err := n.srv.forward("Node.UpdateAlloc", args, args, reply); done {
@@ -715,9 +1001,17 @@ func (n *Node) batchUpdate(future *batchFuture, updates []*structs.Allocation) {
 		WriteRequest: structs.WriteRequest{Region: n.srv.config.Region},
 	}
 
+	// Find the allocations failing with this update before committing it
+	failed := n.failedAllocNodes(updates)
+
 	// Commit this update via Raft
 	var mErr multierror.Error
 	_, index, err := n.srv.raftApply(structs.AllocClientUpdateRequestType, batch)
+	if err == nil {
+		for _, nodeID := range failed {
+			n.srv.nodeReliability.FailedAlloc(nodeID)
+		}
+	}
 	if err != nil {
 		n.srv.logger.Printf("[ERR] nomad.client: alloc update failed: %v", err)
 		mErr.Errors = append(mErr.Errors, err)
@@ -743,7 +1037,7 @@ func (n *Node) batchUpdate(future *batchFuture, updates []*structs.Allocation) {
 	}
 
 	if len(revoke) != 0 {
-		if err := n.srv.vault.RevokeTokens(context.Background(), revoke, true); err != nil {
+		if err := n.srv.revokeVaultTokens(context.Background(), revoke, true); err != nil {
 			n.srv.logger.Printf("[ERR] nomad.client: batched accessor revocation failed: %v", err)
 			mErr.Errors = append(mErr.Errors, err)
 		}
@@ -753,6 +1047,23 @@ func (n *Node) batchUpdate(future *batchFuture, updates []*structs.Allocation) {
 	future.Respond(index, mErr.ErrorOrNil())
 }
 
+// failedAllocNodes returns the nodes of the allocations the updates mark as
+// failed, once per allocation that wasn't failed already
+func (n *Node) failedAllocNodes(updates []*structs.Allocation) []string {
+	var nodes []string
+	for _, update := range updates {
+		if update.ClientStatus != structs.AllocClientStatusFailed {
+			continue
+		}
+		existing, err := n.srv.State().AllocByID(update.ID)
+		if err != nil || existing == nil || existing.ClientStatus == structs.AllocClientStatusFailed {
+			continue
+		}
+		nodes = append(nodes, existing.NodeID)
+	}
+	return nodes
+}
+
 // List is used to list the available nodes
 func (n *Node) List(args *structs.NodeListRequest,
 	reply *structs.NodeListResponse) error {
@@ -761,17 +1072,21 @@ func (n *Node) List(args *structs.NodeListRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "client", "list"}, time.Now())
 
+	// Check node read permissions
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNodeRead() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		watch:     watch.NewItems(watch.Item{Table: "nodes"}),
-		run: func() error {
+		run: func(snap *state.StateSnapshot) error {
 			// Capture all the nodes
-			snap, err := n.srv.fsm.State().Snapshot()
-			if err != nil {
-				return err
-			}
+			var err error
 			var iter memdb.ResultIterator
 			if prefix := args.QueryOptions.Prefix; prefix != "" {
 				iter, err = snap.NodesByIDPrefix(prefix)
@@ -840,18 +1155,20 @@ func (n *Node) createNodeEvals(nodeID string, nodeIndex uint64) ([]string, uint6
 	// Create an eval for each JobID affected
 	var evals []*structs.Evaluation
 	var evalIDs []string
-	jobIDs := make(map[string]struct{})
+	jobIDs := make(map[structs.NamespacedID]struct{})
 
 	for _, alloc := range allocs {
-		// Deduplicate on JobID
-		if _, ok := jobIDs[alloc.JobID]; ok {
+		// Deduplicate on the namespaced JobID
+		id := structs.NewNamespacedID(alloc.JobID, alloc.Namespace)
+		if _, ok := jobIDs[id]; ok {
 			continue
 		}
-		jobIDs[alloc.JobID] = struct{}{}
+		jobIDs[id] = struct{}{}
 
 		// Create a new eval
 		eval := &structs.Evaluation{
 			ID:              structs.GenerateUUID(),
+			Namespace:       alloc.Namespace,
 			Priority:        alloc.Job.Priority,
 			Type:            alloc.Job.Type,
 			TriggeredBy:     structs.EvalTriggerNodeUpdate,
@@ -867,14 +1184,16 @@ func (n *Node) createNodeEvals(nodeID string, nodeIndex uint64) ([]string, uint6
 	// Create an evaluation for each system job.
 	for _, job := range sysJobs {
 		// Still dedup on JobID as the node may already have the system job.
-		if _, ok := jobIDs[job.ID]; ok {
+		id := structs.NewNamespacedID(job.ID, job.Namespace)
+		if _, ok := jobIDs[id]; ok {
 			continue
 		}
-		jobIDs[job.ID] = struct{}{}
+		jobIDs[id] = struct{}{}
 
 		// Create a new eval
 		eval := &structs.Evaluation{
 			ID:              structs.GenerateUUID(),
+			Namespace:       job.Namespace,
 			Priority:        job.Priority,
 			Type:            job.Type,
 			TriggeredBy:     structs.EvalTriggerNodeUpdate,
@@ -903,6 +1222,142 @@ func (n *Node) createNodeEvals(nodeID string, nodeIndex uint64) ([]string, uint6
 	return evalIDs, evalIndex, nil
 }
 
+// createDrainEvals is used to create evaluations that migrate all of the
+// allocations off of a node at once, as when its node class is under hard
+// maintenance. Jobs are ordered so that batch jobs are migrated first,
+// followed by service jobs in ascending priority and finally system jobs.
+// Each wave of jobs is delayed by the configured drain stagger.
+func (n *Node) createDrainEvals(nodeID string, nodeIndex uint64) ([]string, uint64, error) {
+	// Snapshot the state
+	snap, err := n.srv.fsm.State().Snapshot()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to snapshot state: %v", err)
+	}
+
+	// Find all the allocations for this node
+	allocs, err := snap.AllocsByNode(nodeID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find allocs for '%s': %v", nodeID, err)
+	}
+
+	sysJobsIter, err := snap.JobsByScheduler(structs.JobTypeSystem)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find system jobs for '%s': %v", nodeID, err)
+	}
+
+	// Collect the set of affected jobs, deduplicating on the namespaced
+	// job ID
+	jobs := make(map[structs.NamespacedID]*structs.Job)
+	for _, alloc := range allocs {
+		id := structs.NewNamespacedID(alloc.JobID, alloc.Namespace)
+		if _, ok := jobs[id]; !ok && alloc.Job != nil {
+			jobs[id] = alloc.Job
+		}
+	}
+	for raw := sysJobsIter.Next(); raw != nil; raw = sysJobsIter.Next() {
+		job := raw.(*structs.Job)
+		id := structs.NewNamespacedID(job.ID, job.Namespace)
+		if _, ok := jobs[id]; !ok {
+			jobs[id] = job
+		}
+	}
+
+	// Fast-path if nothing to do
+	if len(jobs) == 0 {
+		return nil, 0, nil
+	}
+
+	var evals []*structs.Evaluation
+	var evalIDs []string
+	for _, entry := range drainOrder(jobs) {
+		eval := &structs.Evaluation{
+			ID:              structs.GenerateUUID(),
+			Namespace:       entry.Namespace,
+			Priority:        entry.Priority,
+			Type:            entry.Type,
+			TriggeredBy:     structs.EvalTriggerNodeUpdate,
+			JobID:           entry.JobID,
+			NodeID:          nodeID,
+			NodeModifyIndex: nodeIndex,
+			Status:          structs.EvalStatusPending,
+			Wait:            time.Duration(entry.Rank) * n.srv.config.NodeDrainStagger,
+		}
+		evals = append(evals, eval)
+		evalIDs = append(evalIDs, eval.ID)
+	}
+
+	// Create the Raft transaction
+	update := &structs.EvalUpdateRequest{
+		Evals:        evals,
+		WriteRequest: structs.WriteRequest{Region: n.srv.config.Region},
+	}
+
+	// Commit this evaluation via Raft
+	_, evalIndex, err := n.srv.raftApply(structs.EvalUpdateRequestType, update)
+	if err != nil {
+		return nil, 0, err
+	}
+	return evalIDs, evalIndex, nil
+}
+
+// drainOrder returns the order in which the passed jobs should be migrated off
+// of a draining node. Batch jobs form the first wave, service jobs are grouped
+// into waves by ascending priority, and system jobs form the last wave.
+func drainOrder(jobs map[structs.NamespacedID]*structs.Job) []*structs.NodeDrainJob {
+	order := make([]*structs.NodeDrainJob, 0, len(jobs))
+	for _, job := range jobs {
+		order = append(order, &structs.NodeDrainJob{
+			JobID:     job.ID,
+			Namespace: job.Namespace,
+			Type:      job.Type,
+			Priority:  job.Priority,
+		})
+	}
+
+	sort.Sort(drainJobs(order))
+
+	// Assign a rank to each wave of jobs that share a drain class and priority
+	rank := 0
+	for i, entry := range order {
+		if i > 0 && drainWave(order[i-1]) != drainWave(entry) {
+			rank++
+		}
+		entry.Rank = rank
+	}
+	return order
+}
+
+// drainJobs sorts jobs into their drain order. Jobs within the same drain
+// wave are sorted by ID and namespace to give a stable ordering.
+type drainJobs []*structs.NodeDrainJob
+
+func (d drainJobs) Len() int      { return len(d) }
+func (d drainJobs) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d drainJobs) Less(i, j int) bool {
+	wi, wj := drainWave(d[i]), drainWave(d[j])
+	if wi != wj {
+		return wi < wj
+	}
+	if d[i].JobID != d[j].JobID {
+		return d[i].JobID < d[j].JobID
+	}
+	return d[i].Namespace < d[j].Namespace
+}
+
+// drainWave returns a sortable key for the migration wave of a job. Batch and
+// system jobs are each drained as a single wave, while service jobs are
+// grouped by priority.
+func drainWave(job *structs.NodeDrainJob) int {
+	switch job.Type {
+	case structs.JobTypeBatch:
+		return 0
+	case structs.JobTypeSystem:
+		return structs.JobMaxPriority + 1
+	default:
+		return job.Priority
+	}
+}
+
 // batchFuture is used to wait on a batch update to complete
 type batchFuture struct {
 	doneCh chan struct{}
@@ -1015,6 +1470,16 @@ func (n *Node) DeriveVaultToken(args *structs.DeriveVaultTokenRequest,
 			strings.Join(unneeded, ", "))
 	}
 
+	// Lookup the client of the Vault cluster each task derives its token from
+	vaultClients := make(map[string]VaultClient, len(args.Tasks))
+	for _, task := range args.Tasks {
+		v, err := n.srv.vaultClient(tg[task].Cluster)
+		if err != nil {
+			return fmt.Errorf("failed to create token for task %q: %v", task, err)
+		}
+		vaultClients[task] = v
+	}
+
 	// At this point the request is valid and we should contact Vault for
 	// tokens.
 
@@ -1041,7 +1506,7 @@ func (n *Node) DeriveVaultToken(args *structs.DeriveVaultTokenRequest,
 						return nil
 					}
 
-					secret, err := n.srv.vault.CreateToken(ctx, alloc, task)
+					secret, err := vaultClients[task].CreateToken(ctx, alloc, task)
 					if err != nil {
 						return fmt.Errorf("failed to create token for task %q: %v", task, err)
 					}
@@ -1086,6 +1551,7 @@ func (n *Node) DeriveVaultToken(args *structs.DeriveVaultTokenRequest,
 			NodeID:      alloc.NodeID,
 			AllocID:     alloc.ID,
 			CreationTTL: w.TTL,
+			Cluster:     tg[task].Cluster,
 		}
 
 		accessors = append(accessors, accessor)
@@ -1095,7 +1561,7 @@ func (n *Node) DeriveVaultToken(args *structs.DeriveVaultTokenRequest,
 	if err != nil {
 		var mErr multierror.Error
 		mErr.Errors = append(mErr.Errors, err)
-		if err := n.srv.vault.RevokeTokens(context.Background(), accessors, false); err != nil {
+		if err := n.srv.revokeVaultTokens(context.Background(), accessors, false); err != nil {
 			mErr.Errors = append(mErr.Errors, err)
 		}
 		return mErr.ErrorOrNil()
@@ -53,6 +53,45 @@ func TestClientEndpoint_Register(t *testing.T) {
 	}
 }
 
+func TestClientEndpoint_Register_NodeEvents(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Register the node with a driver
+	node := mock.Node()
+	node.Attributes["driver.docker"] = "1"
+	req := &structs.NodeRegisterRequest{
+		Node:         node,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Re-register it once the driver is no longer detected
+	delete(node.Attributes, "driver.docker")
+	if err := msgpackrpc.CallWithCodec(codec, "Node.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := s1.fsm.State().NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out.Events) != 2 {
+		t.Fatalf("bad: %#v", out.Events)
+	}
+	if out.Events[0].Message != "Node registered" {
+		t.Fatalf("bad: %#v", out.Events[0])
+	}
+	if e := out.Events[1]; e.Subsystem != structs.NodeEventSubsystemDriver || e.Details["driver"] != "docker" {
+		t.Fatalf("bad: %#v", e)
+	}
+}
+
 func TestClientEndpoint_Register_NoSecret(t *testing.T) {
 	s1 := testServer(t, nil)
 	defer s1.Shutdown()
@@ -288,6 +327,67 @@ func TestClientEndpoint_UpdateStatus(t *testing.T) {
 	}
 }
 
+func TestClientEndpoint_UpdateStatus_ClockSkew(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.ClockSkewThreshold = 10 * time.Second
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	node := mock.Node()
+	reg := &structs.NodeRegisterRequest{
+		Node:         node,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.NodeUpdateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.Register", reg, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Heartbeat with a clock running a minute behind
+	req := &structs.NodeUpdateStatusRequest{
+		NodeID:       node.ID,
+		Status:       node.Status,
+		ClientTime:   time.Now().Add(-time.Minute).UnixNano(),
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp2 structs.NodeUpdateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.UpdateStatus", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.ClockSkew < 50*time.Second {
+		t.Fatalf("bad: %v", resp2.ClockSkew)
+	}
+
+	// Heartbeat once the clock was fixed
+	req.ClientTime = time.Now().UnixNano()
+	var resp3 structs.NodeUpdateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.UpdateStatus", req, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp3.ClockSkew != 0 {
+		t.Fatalf("bad: %v", resp3.ClockSkew)
+	}
+
+	// Check both transitions were recorded on the node
+	out, err := s1.fsm.State().NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var messages []string
+	for _, event := range out.Events {
+		if event.Subsystem == structs.NodeEventSubsystemHeartbeat {
+			messages = append(messages, event.Message)
+		}
+	}
+	expected := []string{"Node clock skewed", "Node clock no longer skewed"}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Fatalf("bad: %#v", out.Events)
+	}
+}
+
 func TestClientEndpoint_UpdateStatus_Vault(t *testing.T) {
 	s1 := testServer(t, nil)
 	defer s1.Shutdown()
@@ -437,6 +537,58 @@ func TestClientEndpoint_Register_GetEvals(t *testing.T) {
 	}
 }
 
+func TestClientEndpoint_Register_MetaChangeGetEvals(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Register a system job.
+	job := mock.SystemJob()
+	state := s1.fsm.State()
+	if err := state.UpsertJob(1, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Register the node as ready
+	node := mock.Node()
+	node.Status = structs.NodeStatusReady
+	reg := &structs.NodeRegisterRequest{
+		Node:         node,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.NodeUpdateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.Register", reg, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Registering it again unchanged doesn't create evals
+	var resp2 structs.NodeUpdateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.Register", reg, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.EvalIDs) != 0 {
+		t.Fatalf("expected no evals; got %#v", resp2.EvalIDs)
+	}
+
+	// Changing its meta creates an eval for the system job
+	node.Meta["rack"] = "r2"
+	var resp3 structs.NodeUpdateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.Register", reg, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp3.EvalIDs) != 1 {
+		t.Fatalf("expected one eval; got %#v", resp3.EvalIDs)
+	}
+	eval, err := state.EvalByID(resp3.EvalIDs[0])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if eval.TriggeredBy != structs.EvalTriggerNodeUpdate || eval.NodeID != node.ID {
+		t.Fatalf("bad: %#v", eval)
+	}
+}
+
 func TestClientEndpoint_UpdateStatus_GetEvals(t *testing.T) {
 	s1 := testServer(t, nil)
 	defer s1.Shutdown()
@@ -613,8 +765,10 @@ func TestClientEndpoint_UpdateDrain(t *testing.T) {
 
 	// Update the status
 	dereg := &structs.NodeUpdateDrainRequest{
-		NodeID:       node.ID,
-		Drain:        true,
+		NodeID: node.ID,
+		DrainStrategy: &structs.DrainStrategy{
+			DrainSpec: structs.DrainSpec{Deadline: 10 * time.Second},
+		},
 		WriteRequest: structs.WriteRequest{Region: "global"},
 	}
 	var resp2 structs.NodeDrainUpdateResponse
@@ -631,9 +785,116 @@ func TestClientEndpoint_UpdateDrain(t *testing.T) {
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if !out.Drain {
+	if !out.Drain || out.SchedulingEligibility != structs.NodeSchedulingIneligible {
 		t.Fatalf("bad: %#v", out)
 	}
+	drain := out.DrainStrategy
+	if drain == nil || drain.StartedAt.IsZero() || drain.ForceDeadline.Sub(drain.StartedAt) != 10*time.Second {
+		t.Fatalf("bad: %#v", drain)
+	}
+
+	// Updating the drain keeps its start
+	dereg.DrainStrategy = &structs.DrainStrategy{}
+	if err := msgpackrpc.CallWithCodec(codec, "Node.UpdateDrain", dereg, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.DrainStrategy == nil || !out.DrainStrategy.StartedAt.Equal(drain.StartedAt) || !out.DrainStrategy.ForceDeadline.IsZero() {
+		t.Fatalf("bad: %#v", out.DrainStrategy)
+	}
+
+	// Stopping the drain makes the node eligible again
+	dereg.DrainStrategy = nil
+	if err := msgpackrpc.CallWithCodec(codec, "Node.UpdateDrain", dereg, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Drain || out.DrainStrategy != nil || out.SchedulingEligibility != structs.NodeSchedulingEligible {
+		t.Fatalf("bad: %#v", out)
+	}
+	if len(out.Events) == 0 || out.Events[len(out.Events)-1].Message != "Node drain stopped" {
+		t.Fatalf("bad: %#v", out.Events)
+	}
+}
+
+func TestClientEndpoint_UpdateEligibility(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	node := mock.Node()
+	reg := &structs.NodeRegisterRequest{
+		Node:         node,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	// Fetch the response
+	var resp structs.NodeUpdateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.Register", reg, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Register a system job
+	state := s1.fsm.State()
+	job := mock.SystemJob()
+	if err := state.UpsertJob(resp.Index+1, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Mark the node as ineligible
+	update := &structs.NodeUpdateEligibilityRequest{
+		NodeID:       node.ID,
+		Eligibility:  structs.NodeSchedulingIneligible,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp2 structs.NodeEligibilityUpdateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.UpdateEligibility", update, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.Index == 0 || len(resp2.EvalIDs) != 0 {
+		t.Fatalf("bad: %#v", resp2)
+	}
+
+	// Check for the node in the FSM
+	out, err := state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.SchedulingEligibility != structs.NodeSchedulingIneligible || out.Drain {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Marking the node as eligible again evaluates the system job
+	update.Eligibility = structs.NodeSchedulingEligible
+	var resp3 structs.NodeEligibilityUpdateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.UpdateEligibility", update, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp3.Index == 0 || len(resp3.EvalIDs) != 1 {
+		t.Fatalf("bad: %#v", resp3)
+	}
+	eval, err := state.EvalByID(resp3.EvalIDs[0])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if eval == nil || eval.JobID != job.ID || eval.NodeID != node.ID {
+		t.Fatalf("bad: %#v", eval)
+	}
+
+	// Invalid eligibilities are rejected
+	update.Eligibility = "bogus"
+	err = msgpackrpc.CallWithCodec(codec, "Node.UpdateEligibility", update, &resp3)
+	if err == nil || !strings.Contains(err.Error(), "invalid scheduling eligibility") {
+		t.Fatalf("expected error: %v", err)
+	}
 }
 
 // This test ensures that Nomad marks client state of allocations which are in
@@ -683,11 +944,11 @@ func TestClientEndpoint_Drain_Down(t *testing.T) {
 
 	// Wait for the scheduler to create an allocation
 	testutil.WaitForResult(func() (bool, error) {
-		allocs, err := s1.fsm.state.AllocsByJob(job.ID)
+		allocs, err := s1.fsm.state.AllocsByJob(job.Namespace, job.ID)
 		if err != nil {
 			return false, err
 		}
-		allocs1, err := s1.fsm.state.AllocsByJob(job1.ID)
+		allocs1, err := s1.fsm.state.AllocsByJob(job1.Namespace, job1.ID)
 		if err != nil {
 			return false, err
 		}
@@ -698,9 +959,9 @@ func TestClientEndpoint_Drain_Down(t *testing.T) {
 
 	// Drain the node
 	dereg := &structs.NodeUpdateDrainRequest{
-		NodeID:       node.ID,
-		Drain:        true,
-		WriteRequest: structs.WriteRequest{Region: "global"},
+		NodeID:        node.ID,
+		DrainStrategy: &structs.DrainStrategy{},
+		WriteRequest:  structs.WriteRequest{Region: "global"},
 	}
 	var resp2 structs.NodeDrainUpdateResponse
 	if err := msgpackrpc.CallWithCodec(codec, "Node.UpdateDrain", dereg, &resp2); err != nil {
@@ -719,12 +980,13 @@ func TestClientEndpoint_Drain_Down(t *testing.T) {
 
 	// Ensure that the allocation has transitioned to lost
 	testutil.WaitForResult(func() (bool, error) {
-		summary, err := s1.fsm.state.JobSummaryByID(job.ID)
+		summary, err := s1.fsm.state.JobSummaryByID(job.Namespace, job.ID)
 		if err != nil {
 			return false, err
 		}
 		expectedSummary := &structs.JobSummary{
-			JobID: job.ID,
+			JobID:     job.ID,
+			Namespace: job.Namespace,
 			Summary: map[string]structs.TaskGroupSummary{
 				"web": structs.TaskGroupSummary{
 					Queued: 1,
@@ -738,12 +1000,13 @@ func TestClientEndpoint_Drain_Down(t *testing.T) {
 			return false, fmt.Errorf("expected: %#v, actual: %#v", expectedSummary, summary)
 		}
 
-		summary1, err := s1.fsm.state.JobSummaryByID(job1.ID)
+		summary1, err := s1.fsm.state.JobSummaryByID(job1.Namespace, job1.ID)
 		if err != nil {
 			return false, err
 		}
 		expectedSummary1 := &structs.JobSummary{
-			JobID: job1.ID,
+			JobID:     job1.ID,
+			Namespace: job1.Namespace,
 			Summary: map[string]structs.TaskGroupSummary{
 				"web": structs.TaskGroupSummary{
 					Lost: 1,
@@ -761,6 +1024,85 @@ func TestClientEndpoint_Drain_Down(t *testing.T) {
 	})
 }
 
+func TestClientEndpoint_UpdateDrain_Order(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+		c.NodeDrainInterval = time.Hour
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	node := mock.Node()
+	state := s1.fsm.State()
+	if err := state.UpsertNode(90, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Place allocations for a batch, two service and a system job
+	batch := mock.Job()
+	batch.Type = structs.JobTypeBatch
+	batch.Priority = 80
+	low := mock.Job()
+	low.Priority = 20
+	high := mock.Job()
+	high.Priority = 70
+	system := mock.SystemJob()
+
+	var allocs []*structs.Allocation
+	for i, job := range []*structs.Job{batch, low, high, system} {
+		if err := state.UpsertJob(uint64(91+i), job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		alloc := mock.Alloc()
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.NodeID = node.ID
+		allocs = append(allocs, alloc)
+	}
+	if err := state.UpsertAllocs(100, allocs); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Drain the node, leaving the system job running
+	req := &structs.NodeUpdateDrainRequest{
+		NodeID: node.ID,
+		DrainStrategy: &structs.DrainStrategy{
+			DrainSpec: structs.DrainSpec{IgnoreSystemJobs: true},
+		},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.NodeDrainUpdateResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.UpdateDrain", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if len(resp.DrainOrder) != 4 {
+		t.Fatalf("bad: %#v", resp.DrainOrder)
+	}
+	expected := []struct {
+		JobID   string
+		Rank    int
+		Skipped bool
+	}{
+		{batch.ID, 0, false},
+		{low.ID, 1, false},
+		{high.ID, 2, false},
+		{system.ID, 3, true},
+	}
+	for i, e := range expected {
+		entry := resp.DrainOrder[i]
+		if entry.JobID != e.JobID || entry.Rank != e.Rank || entry.Skipped != e.Skipped {
+			t.Fatalf("bad entry %d: %#v", i, entry)
+		}
+	}
+
+	// The allocations are left to the drainer to migrate
+	if len(resp.EvalIDs) != 0 {
+		t.Fatalf("bad: %#v", resp.EvalIDs)
+	}
+}
+
 func TestClientEndpoint_GetNode(t *testing.T) {
 	s1 := testServer(t, nil)
 	defer s1.Shutdown()
@@ -799,8 +1141,9 @@ func TestClientEndpoint_GetNode(t *testing.T) {
 		t.Fatalf("bad ComputedClass: %#v", resp2.Node)
 	}
 
-	// Update the status updated at value
+	// Update the status updated at value and the events of the registration
 	node.StatusUpdatedAt = resp2.Node.StatusUpdatedAt
+	node.Events = resp2.Node.Events
 	node.SecretID = ""
 	if !reflect.DeepEqual(node, resp2.Node) {
 		t.Fatalf("bad: %#v \n %#v", node, resp2.Node)
@@ -1052,6 +1395,83 @@ func TestClientEndpoint_GetClientAllocs(t *testing.T) {
 	}
 }
 
+func TestClientEndpoint_GetClientAllocs_MigrateTokens(t *testing.T) {
+	s1, _ := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the nodes the previous and the new allocation run on
+	state := s1.fsm.State()
+	prevNode := mock.Node()
+	node := mock.Node()
+	if err := state.UpsertNode(98, prevNode); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertNode(99, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a sticky allocation replacing one on the other node
+	prev := mock.Alloc()
+	prev.NodeID = prevNode.ID
+	prev.Job.TaskGroups[0].EphemeralDisk = &structs.EphemeralDisk{
+		Sticky:  true,
+		Migrate: true,
+		SizeMB:  150,
+	}
+	alloc := mock.Alloc()
+	alloc.NodeID = node.ID
+	alloc.JobID = prev.JobID
+	alloc.Job = prev.Job
+	alloc.PreviousAllocation = prev.ID
+	state.UpsertJobSummary(99, mock.JobSummary(alloc.JobID))
+	if err := state.UpsertAllocs(100, []*structs.Allocation{prev, alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Lookup the allocs
+	get := &structs.NodeSpecificRequest{
+		NodeID:       node.ID,
+		SecretID:     node.SecretID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.NodeClientAllocsResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.GetClientAllocs", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.MigrateTokens) != 1 {
+		t.Fatalf("bad: %#v", resp.MigrateTokens)
+	}
+	if !structs.CompareMigrateToken(prev.ID, prevNode.SecretID, resp.MigrateTokens[alloc.ID]) {
+		t.Fatalf("bad token: %q", resp.MigrateTokens[alloc.ID])
+	}
+
+	// The client looks up the previous node with its secret ID
+	getNode := &structs.NodeSpecificRequest{
+		NodeID: prevNode.ID,
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: node.SecretID,
+		},
+	}
+	var resp2 structs.SingleNodeResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.GetNode", getNode, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.Node == nil || resp2.Node.ID != prevNode.ID || resp2.Node.SecretID != "" {
+		t.Fatalf("bad: %#v", resp2.Node)
+	}
+
+	// Unknown secrets are rejected
+	getNode.AuthToken = structs.GenerateUUID()
+	var resp3 structs.SingleNodeResponse
+	err := msgpackrpc.CallWithCodec(codec, "Node.GetNode", getNode, &resp3)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrTokenNotFound.Error()) {
+		t.Fatalf("expected token not found error, got: %v", err)
+	}
+}
+
 func TestClientEndpoint_GetClientAllocs_Blocking(t *testing.T) {
 	s1 := testServer(t, nil)
 	defer s1.Shutdown()
@@ -1651,6 +2071,55 @@ func TestClientEndpoint_ListNodes(t *testing.T) {
 	}
 }
 
+func TestClientEndpoint_ListNodes_ACL(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the node
+	state := s1.fsm.State()
+	node := mock.Node()
+	if err := state.UpsertNode(1, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a token that can only access jobs
+	policy := mock.ACLPolicy()
+	policy.Rules = `namespace "default" { policy = "write" }`
+	policy.SetHash()
+	token := mock.ACLToken()
+	token.Policies = []string{policy.Name}
+	if err := state.UpsertACLPolicies(100, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertACLTokens(101, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Listing the nodes is denied without node read
+	get := &structs.NodeListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: token.SecretID,
+		},
+	}
+	var resp structs.NodeListResponse
+	err := msgpackrpc.CallWithCodec(codec, "Node.List", get, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// The management token may list the nodes
+	get.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Node.List", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Nodes) != 1 || resp.Nodes[0].ID != node.ID {
+		t.Fatalf("bad: %#v", resp.Nodes)
+	}
+}
+
 func TestClientEndpoint_ListNodes_Blocking(t *testing.T) {
 	s1 := testServer(t, nil)
 	defer s1.Shutdown()
@@ -1692,7 +2161,7 @@ func TestClientEndpoint_ListNodes_Blocking(t *testing.T) {
 
 	// Node drain updates trigger watches.
 	time.AfterFunc(100*time.Millisecond, func() {
-		if err := state.UpdateNodeDrain(3, node.ID, true); err != nil {
+		if err := state.UpdateNodeDrain(3, node.ID, &structs.DrainStrategy{}, nil); err != nil {
 			t.Fatalf("err: %v", err)
 		}
 	})
@@ -1716,7 +2185,7 @@ func TestClientEndpoint_ListNodes_Blocking(t *testing.T) {
 
 	// Node status update triggers watches
 	time.AfterFunc(100*time.Millisecond, func() {
-		if err := state.UpdateNodeStatus(4, node.ID, structs.NodeStatusDown); err != nil {
+		if err := state.UpdateNodeStatus(4, node.ID, structs.NodeStatusDown, nil); err != nil {
 			t.Fatalf("err: %v", err)
 		}
 	})
@@ -1946,3 +2415,69 @@ func TestClientEndpoint_DeriveVaultToken(t *testing.T) {
 		t.Fatalf("Got %#v; want %#v", va, expected)
 	}
 }
+
+func TestClientEndpoint_DeriveVaultToken_Cluster(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	state := s1.fsm.State()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Replace the Vault Clients on the server. The token must be created in
+	// the named cluster the task selects.
+	s1.vault = &TestVaultClient{}
+	tvc := &TestVaultClient{}
+	s1.vaultClusters = map[string]VaultClient{"secondary": tvc}
+
+	// Create the node
+	node := mock.Node()
+	if err := state.UpsertNode(2, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create an allocation with a task requiring a token of the named cluster
+	alloc := mock.Alloc()
+	alloc.NodeID = node.ID
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Vault = &structs.Vault{Policies: []string{"a"}, Cluster: "secondary"}
+	if err := state.UpsertAllocs(3, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	accessor := structs.GenerateUUID()
+	secret := &vapi.Secret{
+		WrapInfo: &vapi.SecretWrapInfo{
+			Token:           structs.GenerateUUID(),
+			WrappedAccessor: accessor,
+			TTL:             10,
+		},
+	}
+	tvc.SetCreateTokenSecret(alloc.ID, task.Name, secret)
+
+	req := &structs.DeriveVaultTokenRequest{
+		NodeID:   node.ID,
+		SecretID: node.SecretID,
+		AllocID:  alloc.ID,
+		Tasks:    []string{task.Name},
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+
+	var resp structs.DeriveVaultTokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveVaultToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Tasks[task.Name] != secret.WrapInfo.Token {
+		t.Fatalf("bad: %#v", resp.Tasks)
+	}
+
+	// The accessor records the cluster the token was created in
+	va, err := state.VaultAccessor(accessor)
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if va == nil || va.Cluster != "secondary" {
+		t.Fatalf("bad: %#v", va)
+	}
+}
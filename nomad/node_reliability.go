@@ -0,0 +1,133 @@
+package nomad
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// nodeFailures counts the failures seen on a node since the last flush
+type nodeFailures struct {
+	failedAllocs     int
+	planRejections   int
+	missedHeartbeats int
+}
+
+// nodeReliabilityTracker collects the failures seen on the nodes by the
+// leader, so that they are added to the reliability penalties of the nodes
+// in batches rather than with a Raft write per failure.
+type nodeReliabilityTracker struct {
+	failures map[string]*nodeFailures
+	l        sync.Mutex
+}
+
+// newNodeReliabilityTracker returns a tracker with no failures
+func newNodeReliabilityTracker() *nodeReliabilityTracker {
+	return &nodeReliabilityTracker{
+		failures: make(map[string]*nodeFailures),
+	}
+}
+
+// record counts a failure on the given node
+func (t *nodeReliabilityTracker) record(nodeID string, count func(*nodeFailures)) {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	f, ok := t.failures[nodeID]
+	if !ok {
+		f = &nodeFailures{}
+		t.failures[nodeID] = f
+	}
+	count(f)
+}
+
+// FailedAlloc records an allocation failing on the node
+func (t *nodeReliabilityTracker) FailedAlloc(nodeID string) {
+	t.record(nodeID, func(f *nodeFailures) { f.failedAllocs++ })
+}
+
+// PlanRejected records a plan being rejected for the node
+func (t *nodeReliabilityTracker) PlanRejected(nodeID string) {
+	t.record(nodeID, func(f *nodeFailures) { f.planRejections++ })
+}
+
+// MissedHeartbeat records the node missing its heartbeats
+func (t *nodeReliabilityTracker) MissedHeartbeat(nodeID string) {
+	t.record(nodeID, func(f *nodeFailures) { f.missedHeartbeats++ })
+}
+
+// drain returns the failures recorded since the last drain
+func (t *nodeReliabilityTracker) drain() map[string]*nodeFailures {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	failures := t.failures
+	t.failures = make(map[string]*nodeFailures)
+	return failures
+}
+
+// penalties weighs the failures of the nodes with the given configuration
+func (t *nodeReliabilityTracker) penalties(config *structs.NodeReliabilityConfig) map[string]float64 {
+	penalties := make(map[string]float64)
+	for nodeID, f := range t.drain() {
+		penalty := float64(f.failedAllocs)*config.FailedAllocWeight +
+			float64(f.planRejections)*config.PlanRejectionWeight +
+			float64(f.missedHeartbeats)*config.MissedHeartbeatWeight
+		if penalty > 0 {
+			penalties[nodeID] = penalty
+		}
+	}
+	return penalties
+}
+
+// flushNodeReliability periodically adds the failures seen on the nodes to
+// their reliability penalties. The failures are tracked by the leader only,
+// so those not yet flushed are lost on a leadership change.
+func (s *Server) flushNodeReliability(stopCh chan struct{}) {
+	ticker := time.NewTicker(s.config.NodeReliabilityInterval)
+	defer ticker.Stop()
+
+	// Drop the failures recorded during a previous leadership
+	s.nodeReliability.drain()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			if err := s.applyNodeReliability(time.Now()); err != nil {
+				s.logger.Printf("[ERR] nomad: failed to update node reliability: %v", err)
+			}
+		}
+	}
+}
+
+// applyNodeReliability adds the failures recorded since the last flush to
+// the reliability penalties of the nodes, as of the given time
+func (s *Server) applyNodeReliability(now time.Time) error {
+	_, config, err := s.fsm.State().SchedulerConfig()
+	if err != nil {
+		return err
+	}
+	if config == nil || !config.NodeReliability.Enabled {
+		// Drop the failures while the tracking is disabled
+		s.nodeReliability.drain()
+		return nil
+	}
+
+	penalties := s.nodeReliability.penalties(&config.NodeReliability)
+	if len(penalties) == 0 {
+		return nil
+	}
+
+	req := structs.NodeReliabilityUpdateRequest{
+		Penalties:  penalties,
+		UpdateTime: now.UnixNano(),
+		HalfLife:   config.NodeReliability.HalfLife,
+		WriteRequest: structs.WriteRequest{
+			Region: s.config.Region,
+		},
+	}
+	_, _, err = s.raftApply(structs.NodeReliabilityUpdateRequestType, &req)
+	return err
+}
@@ -0,0 +1,422 @@
+package nomad
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad/nomad/snapshot"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/watch"
+)
+
+// Operator endpoint is used to perform maintenance operations on the cluster
+type Operator struct {
+	srv *Server
+}
+
+// SnapshotSave is used to take a snapshot of the state of the cluster. Unless
+// stale reads are allowed, the snapshot is taken by the leader once every
+// committed entry has been applied.
+func (o *Operator) SnapshotSave(args *structs.SnapshotSaveRequest,
+	reply *structs.SnapshotSaveResponse) error {
+	if done, err := o.srv.forward("Operator.SnapshotSave", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "operator", "snapshot_save"}, time.Now())
+
+	// The snapshot holds every secret of the cluster, so management
+	// permissions are required
+	if aclObj, err := o.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	if !args.AllowStale {
+		if err := o.srv.raft.Barrier(0).Error(); err != nil {
+			return fmt.Errorf("failed to wait for barrier: %v", err)
+		}
+	}
+
+	snap, err := o.srv.fsm.Snapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+
+	// Persist the snapshot in memory, then wrap it in an archive
+	sink := &snapshotBuffer{}
+	if err := snap.Persist(sink); err != nil {
+		return fmt.Errorf("failed to persist snapshot: %v", err)
+	}
+	index, err := snap.(*nomadSnapshot).snap.LatestIndex()
+	if err != nil {
+		return err
+	}
+
+	var archive bytes.Buffer
+	meta := &snapshot.Metadata{
+		Region: o.srv.config.Region,
+		Index:  index,
+		Time:   time.Now().UTC(),
+	}
+	if err := snapshot.Write(&archive, meta, sink.Bytes()); err != nil {
+		return err
+	}
+
+	reply.Snapshot = archive.Bytes()
+	reply.Index = index
+	o.srv.setQueryMeta(&reply.QueryMeta)
+	return nil
+}
+
+// SnapshotRestore is used to replace the state of the cluster with the one
+// held by a snapshot. The snapshot is verified before being committed through
+// Raft, so that every server restores the same state.
+func (o *Operator) SnapshotRestore(args *structs.SnapshotRestoreRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := o.srv.forward("Operator.SnapshotRestore", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "operator", "snapshot_restore"}, time.Now())
+
+	// Check management level permissions
+	if aclObj, err := o.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	meta, _, err := snapshot.Read(bytes.NewReader(args.Snapshot))
+	if err != nil {
+		return fmt.Errorf("invalid snapshot: %v", err)
+	}
+	if meta.Region != o.srv.config.Region {
+		return fmt.Errorf("snapshot was taken in region %q, not %q", meta.Region, o.srv.config.Region)
+	}
+
+	resp, index, err := o.srv.raftApply(structs.SnapshotRestoreRequestType, args)
+	if err != nil {
+		o.srv.logger.Printf("[ERR] nomad.operator: SnapshotRestore failed: %v", err)
+		return err
+	}
+	if err, ok := resp.(error); ok && err != nil {
+		o.srv.logger.Printf("[ERR] nomad.operator: SnapshotRestore failed: %v", err)
+		return err
+	}
+	o.srv.logger.Printf("[INFO] nomad.operator: restored snapshot of index %d taken at %v",
+		meta.Index, meta.Time)
+
+	// The leader tracks evaluations, periodic jobs and heartbeats in memory,
+	// rebuild them from the restored state
+	if err := o.srv.restoreLeaderState(); err != nil {
+		o.srv.logger.Printf("[ERR] nomad.operator: failed to restore leader state: %v", err)
+		return err
+	}
+
+	reply.Index = index
+	return nil
+}
+
+// RaftGetConfiguration is used to list the servers of the Raft configuration
+// of the region, as known by the server handling the request.
+func (o *Operator) RaftGetConfiguration(args *structs.GenericRequest,
+	reply *structs.RaftConfigurationResponse) error {
+	if done, err := o.srv.forward("Operator.RaftGetConfiguration", args, args, reply); done {
+		return err
+	}
+
+	// Check management level permissions
+	if aclObj, err := o.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	peers, err := o.srv.raftPeers.Peers()
+	if err != nil {
+		return err
+	}
+
+	// Name the servers after the gossip members advertising their address
+	leader := o.srv.raft.Leader()
+	o.srv.peerLock.RLock()
+	defer o.srv.peerLock.RUnlock()
+	reply.Servers = make([]*structs.RaftServer, 0, len(peers))
+	for _, peer := range peers {
+		server := &structs.RaftServer{
+			ID:      peer,
+			Address: peer,
+			Leader:  peer == leader,
+			Voter:   true,
+		}
+		if parts, ok := o.srv.localPeers[peer]; ok {
+			server.Node = parts.Name
+		}
+		reply.Servers = append(reply.Servers, server)
+	}
+
+	reply.Index = o.srv.raft.LastIndex()
+	o.srv.setQueryMeta(&reply.QueryMeta)
+	return nil
+}
+
+// RaftRemovePeerByAddress is used to remove a server from the Raft
+// configuration. It is meant to recover from servers that failed without
+// leaving the cluster; servers that are still alive rejoin the
+// configuration.
+func (o *Operator) RaftRemovePeerByAddress(args *structs.RaftPeerByAddressRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := o.srv.forward("Operator.RaftRemovePeerByAddress", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "operator", "raft_remove_peer"}, time.Now())
+
+	// Check management level permissions
+	if aclObj, err := o.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Only remove peers of the configuration, so that a typo in the address
+	// isn't silently accepted
+	peers, err := o.srv.raftPeers.Peers()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, peer := range peers {
+		if peer == args.Address {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("address %q was not found in the Raft configuration", args.Address)
+	}
+
+	future := o.srv.raft.RemovePeer(args.Address)
+	if err := future.Error(); err != nil {
+		o.srv.logger.Printf("[ERR] nomad.operator: failed to remove Raft peer %q: %v", args.Address, err)
+		return err
+	}
+	o.srv.logger.Printf("[WARN] nomad.operator: removed Raft peer %q", args.Address)
+
+	reply.Index = o.srv.raft.LastIndex()
+	return nil
+}
+
+// AutopilotGetConfiguration is used to retrieve the configuration of
+// autopilot
+func (o *Operator) AutopilotGetConfiguration(args *structs.GenericRequest,
+	reply *structs.AutopilotConfigResponse) error {
+	if done, err := o.srv.forward("Operator.AutopilotGetConfiguration", args, args, reply); done {
+		return err
+	}
+
+	// Check management level permissions
+	if aclObj, err := o.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "autopilot_config"}),
+		run: func(snap *state.StateSnapshot) error {
+			_, config, err := snap.AutopilotConfig()
+			if err != nil {
+				return err
+			}
+
+			// Servers run with their own configuration until one is set
+			if config == nil {
+				config = o.srv.config.AutopilotConfig
+			}
+			reply.Config = config
+
+			index, err := snap.Index("autopilot_config")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+			o.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return o.srv.blockingRPC(&opts)
+}
+
+// AutopilotSetConfiguration is used to set the configuration of autopilot
+func (o *Operator) AutopilotSetConfiguration(args *structs.AutopilotSetConfigRequest,
+	reply *structs.AutopilotSetConfigResponse) error {
+	if done, err := o.srv.forward("Operator.AutopilotSetConfiguration", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "operator", "autopilot_set_config"}, time.Now())
+
+	// Check management level permissions
+	if aclObj, err := o.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	if err := args.Config.Validate(); err != nil {
+		return err
+	}
+
+	resp, index, err := o.srv.raftApply(structs.AutopilotSetConfigRequestType, args)
+	if err != nil {
+		o.srv.logger.Printf("[ERR] nomad.operator: AutopilotSetConfiguration failed: %v", err)
+		return err
+	}
+	if err, ok := resp.(error); ok && err != nil {
+		o.srv.logger.Printf("[ERR] nomad.operator: AutopilotSetConfiguration failed: %v", err)
+		return err
+	}
+
+	reply.Updated, _ = resp.(bool)
+	reply.Index = index
+	return nil
+}
+
+// ServerHealth is used to get the health of the servers of the region, as
+// tracked by autopilot on the leader
+func (o *Operator) ServerHealth(args *structs.GenericRequest,
+	reply *structs.OperatorHealthReply) error {
+	// Only the leader tracks the health of the servers
+	args.AllowStale = false
+	if done, err := o.srv.forward("Operator.ServerHealth", args, args, reply); done {
+		return err
+	}
+
+	// Check management level permissions
+	if aclObj, err := o.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	health := o.srv.getClusterHealth()
+	if len(health.Servers) == 0 {
+		return fmt.Errorf("server health is not yet known")
+	}
+
+	reply.Healthy = health.Healthy
+	reply.FailureTolerance = health.FailureTolerance
+	reply.Servers = health.Servers
+	reply.Index = o.srv.raft.LastIndex()
+	o.srv.setQueryMeta(&reply.QueryMeta)
+	return nil
+}
+
+// SchedulerGetConfiguration is used to retrieve the configuration of the
+// schedulers
+func (o *Operator) SchedulerGetConfiguration(args *structs.GenericRequest,
+	reply *structs.SchedulerConfigurationResponse) error {
+	if done, err := o.srv.forward("Operator.SchedulerGetConfiguration", args, args, reply); done {
+		return err
+	}
+
+	// Check management level permissions
+	if aclObj, err := o.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "scheduler_config"}),
+		run: func(snap *state.StateSnapshot) error {
+			_, config, err := snap.SchedulerConfig()
+			if err != nil {
+				return err
+			}
+
+			// The schedulers run with the default configuration until one
+			// is set
+			if config == nil {
+				config = structs.DefaultSchedulerConfiguration()
+			}
+			reply.SchedulerConfig = config
+
+			index, err := snap.Index("scheduler_config")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+			o.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return o.srv.blockingRPC(&opts)
+}
+
+// SchedulerSetConfiguration is used to set the configuration of the
+// schedulers
+func (o *Operator) SchedulerSetConfiguration(args *structs.SchedulerSetConfigRequest,
+	reply *structs.SchedulerSetConfigurationResponse) error {
+	if done, err := o.srv.forward("Operator.SchedulerSetConfiguration", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "operator", "scheduler_set_config"}, time.Now())
+
+	// Check management level permissions
+	if aclObj, err := o.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	if err := args.Config.Validate(); err != nil {
+		return err
+	}
+
+	resp, index, err := o.srv.raftApply(structs.SchedulerSetConfigRequestType, args)
+	if err != nil {
+		o.srv.logger.Printf("[ERR] nomad.operator: SchedulerSetConfiguration failed: %v", err)
+		return err
+	}
+	if err, ok := resp.(error); ok && err != nil {
+		o.srv.logger.Printf("[ERR] nomad.operator: SchedulerSetConfiguration failed: %v", err)
+		return err
+	}
+
+	reply.Updated, _ = resp.(bool)
+	if reply.Updated {
+		if args.Config.SchedulingPaused {
+			o.srv.logger.Printf("[WARN] nomad.operator: scheduling of jobs paused")
+		} else {
+			o.srv.logger.Printf("[INFO] nomad.operator: scheduler configuration set")
+		}
+	}
+	reply.Index = index
+	return nil
+}
+
+// snapshotBuffer is a raft.SnapshotSink that persists a snapshot in memory.
+type snapshotBuffer struct {
+	bytes.Buffer
+}
+
+func (s *snapshotBuffer) ID() string {
+	return "snapshot-save"
+}
+
+func (s *snapshotBuffer) Cancel() error {
+	s.Reset()
+	return nil
+}
+
+func (s *snapshotBuffer) Close() error {
+	return nil
+}
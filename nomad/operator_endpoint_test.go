@@ -0,0 +1,492 @@
+package nomad
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/snapshot"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestOperatorEndpoint_SnapshotSaveRestore(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Register a job to be captured by the snapshot
+	job := mock.Job()
+	register := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var registerResp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", register, &registerResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Save a snapshot
+	save := &structs.SnapshotSaveRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var saveResp structs.SnapshotSaveResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SnapshotSave", save, &saveResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	meta, _, err := snapshot.Read(bytes.NewReader(saveResp.Snapshot))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if meta.Region != "global" || meta.Index < registerResp.JobModifyIndex {
+		t.Fatalf("bad: %#v", meta)
+	}
+
+	// Deregister the job after the snapshot was taken
+	deregister := &structs.JobDeregisterRequest{
+		JobID:        job.ID,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var deregisterResp structs.JobDeregisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Deregister", deregister, &deregisterResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Restoring the snapshot brings the job back
+	restore := &structs.SnapshotRestoreRequest{
+		Snapshot:     saveResp.Snapshot,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var restoreResp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SnapshotRestore", restore, &restoreResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if restoreResp.Index <= deregisterResp.JobModifyIndex {
+		t.Fatalf("bad: %d", restoreResp.Index)
+	}
+
+	state := s1.fsm.State()
+	out, err := state.JobByID(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("job not restored")
+	}
+
+	// The indexes are advanced to the index of the restore
+	index, err := state.Index("jobs")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != restoreResp.Index {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestOperatorEndpoint_SnapshotRestore_Invalid(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	save := &structs.SnapshotSaveRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var saveResp structs.SnapshotSaveResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SnapshotSave", save, &saveResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A truncated snapshot is rejected before being applied
+	restore := &structs.SnapshotRestoreRequest{
+		Snapshot:     saveResp.Snapshot[:len(saveResp.Snapshot)/2],
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var restoreResp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SnapshotRestore", restore, &restoreResp); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestOperatorEndpoint_SnapshotRestore_OtherRegion(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	save := &structs.SnapshotSaveRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var saveResp structs.SnapshotSaveResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SnapshotSave", save, &saveResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Rewrite the archive as if it was taken in another region
+	meta, data, err := snapshot.Read(bytes.NewReader(saveResp.Snapshot))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	meta.Region = "other"
+	var archive bytes.Buffer
+	if err := snapshot.Write(&archive, meta, data); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	restore := &structs.SnapshotRestoreRequest{
+		Snapshot:     archive.Bytes(),
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var restoreResp structs.GenericResponse
+	err = msgpackrpc.CallWithCodec(codec, "Operator.SnapshotRestore", restore, &restoreResp)
+	if err == nil || !strings.Contains(err.Error(), "region") {
+		t.Fatalf("expected region error: %v", err)
+	}
+}
+
+func TestOperatorEndpoint_Snapshot_ACL(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Anonymous requests are denied
+	save := &structs.SnapshotSaveRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var saveResp structs.SnapshotSaveResponse
+	err := msgpackrpc.CallWithCodec(codec, "Operator.SnapshotSave", save, &saveResp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	restore := &structs.SnapshotRestoreRequest{
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var restoreResp structs.GenericResponse
+	err = msgpackrpc.CallWithCodec(codec, "Operator.SnapshotRestore", restore, &restoreResp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// Management tokens may save snapshots
+	save.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SnapshotSave", save, &saveResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(saveResp.Snapshot) == 0 {
+		t.Fatalf("empty snapshot")
+	}
+}
+
+func TestOperatorEndpoint_RaftGetConfiguration(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	arg := &structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var reply structs.RaftConfigurationResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.RaftGetConfiguration", arg, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(reply.Servers) != 1 {
+		t.Fatalf("bad: %#v", reply.Servers)
+	}
+	me := s1.raftTransport.LocalAddr()
+	server := reply.Servers[0]
+	if server.ID != me || server.Address != me || !server.Leader || !server.Voter {
+		t.Fatalf("bad: %#v", server)
+	}
+	if reply.Index == 0 {
+		t.Fatalf("bad index")
+	}
+}
+
+func TestOperatorEndpoint_RaftRemovePeerByAddress(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Addresses outside of the configuration are rejected
+	arg := &structs.RaftPeerByAddressRequest{
+		Address:      "127.0.0.1:1",
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var reply structs.GenericResponse
+	err := msgpackrpc.CallWithCodec(codec, "Operator.RaftRemovePeerByAddress", arg, &reply)
+	if err == nil || !strings.Contains(err.Error(), "not found in the Raft configuration") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestOperatorEndpoint_Raft_ACL(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Anonymous requests are denied
+	get := &structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var getReply structs.RaftConfigurationResponse
+	err := msgpackrpc.CallWithCodec(codec, "Operator.RaftGetConfiguration", get, &getReply)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	remove := &structs.RaftPeerByAddressRequest{
+		Address:      s1.raftTransport.LocalAddr(),
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var removeReply structs.GenericResponse
+	err = msgpackrpc.CallWithCodec(codec, "Operator.RaftRemovePeerByAddress", remove, &removeReply)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// Management tokens may list the configuration
+	get.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.RaftGetConfiguration", get, &getReply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(getReply.Servers) != 1 {
+		t.Fatalf("bad: %#v", getReply.Servers)
+	}
+}
+
+func TestOperatorEndpoint_AutopilotConfiguration(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// The configuration of the server is returned until one is set
+	get := &structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var getReply structs.AutopilotConfigResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.AutopilotGetConfiguration", get, &getReply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !getReply.Config.CleanupDeadServers || getReply.Config.MaxTrailingLogs != 250 {
+		t.Fatalf("bad: %#v", getReply.Config)
+	}
+
+	// Set the configuration
+	set := &structs.AutopilotSetConfigRequest{
+		Config: structs.AutopilotConfig{
+			CleanupDeadServers:   false,
+			LastContactThreshold: time.Second,
+			MaxTrailingLogs:      100,
+		},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var setReply structs.AutopilotSetConfigResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.AutopilotSetConfiguration", set, &setReply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !setReply.Updated || setReply.Index == 0 {
+		t.Fatalf("bad: %#v", setReply)
+	}
+
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.AutopilotGetConfiguration", get, &getReply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	config := getReply.Config
+	if config.CleanupDeadServers || config.MaxTrailingLogs != 100 || config.ModifyIndex != setReply.Index {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	// A check-and-set with a stale index isn't applied
+	set.CAS = true
+	set.Config.ModifyIndex = setReply.Index - 1
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.AutopilotSetConfiguration", set, &setReply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if setReply.Updated {
+		t.Fatalf("bad: %#v", setReply)
+	}
+
+	// Invalid configurations are rejected
+	set.CAS = false
+	set.Config.MaxTrailingLogs = 0
+	err := msgpackrpc.CallWithCodec(codec, "Operator.AutopilotSetConfiguration", set, &setReply)
+	if err == nil || !strings.Contains(err.Error(), "MaxTrailingLogs") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestOperatorEndpoint_ServerHealth(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.ServerHealthInterval = 50 * time.Millisecond
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	arg := &structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	testutil.WaitForResult(func() (bool, error) {
+		var reply structs.OperatorHealthReply
+		if err := msgpackrpc.CallWithCodec(codec, "Operator.ServerHealth", arg, &reply); err != nil {
+			return false, err
+		}
+		if !reply.Healthy || reply.FailureTolerance != 0 || len(reply.Servers) != 1 {
+			return false, fmt.Errorf("bad: %#v", reply)
+		}
+		server := reply.Servers[0]
+		if !server.Leader || !server.Healthy || server.SerfStatus != "alive" {
+			return false, fmt.Errorf("bad: %#v", server)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
+
+func TestOperatorEndpoint_Autopilot_ACL(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Anonymous requests are denied
+	get := &structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var getReply structs.AutopilotConfigResponse
+	err := msgpackrpc.CallWithCodec(codec, "Operator.AutopilotGetConfiguration", get, &getReply)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	set := &structs.AutopilotSetConfigRequest{
+		Config:       *structs.DefaultAutopilotConfig(),
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var setReply structs.AutopilotSetConfigResponse
+	err = msgpackrpc.CallWithCodec(codec, "Operator.AutopilotSetConfiguration", set, &setReply)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	var healthReply structs.OperatorHealthReply
+	err = msgpackrpc.CallWithCodec(codec, "Operator.ServerHealth", get, &healthReply)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// Management tokens may set the configuration
+	set.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.AutopilotSetConfiguration", set, &setReply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestOperatorEndpoint_SchedulerConfiguration(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// The default configuration is returned until one is set
+	get := &structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var getReply structs.SchedulerConfigurationResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SchedulerGetConfiguration", get, &getReply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(getReply.SchedulerConfig, structs.DefaultSchedulerConfiguration()) {
+		t.Fatalf("bad: %#v", getReply.SchedulerConfig)
+	}
+
+	// Set the configuration
+	set := &structs.SchedulerSetConfigRequest{
+		Config: structs.SchedulerConfiguration{
+			SchedulerAlgorithm: structs.SchedulerAlgorithmSpread,
+			SchedulingPaused:   true,
+		},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var setReply structs.SchedulerSetConfigurationResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SchedulerSetConfiguration", set, &setReply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !setReply.Updated || setReply.Index == 0 {
+		t.Fatalf("bad: %#v", setReply)
+	}
+
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SchedulerGetConfiguration", get, &getReply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	config := getReply.SchedulerConfig
+	if config.SchedulerAlgorithm != structs.SchedulerAlgorithmSpread || !config.SchedulingPaused ||
+		config.PreemptionConfig.ServiceSchedulerEnabled || config.ModifyIndex != setReply.Index {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	// A check-and-set with a stale index isn't applied
+	set.CAS = true
+	set.Config.ModifyIndex = setReply.Index - 1
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SchedulerSetConfiguration", set, &setReply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if setReply.Updated {
+		t.Fatalf("bad: %#v", setReply)
+	}
+
+	// Invalid configurations are rejected
+	set.CAS = false
+	set.Config.SchedulerAlgorithm = "random"
+	err := msgpackrpc.CallWithCodec(codec, "Operator.SchedulerSetConfiguration", set, &setReply)
+	if err == nil || !strings.Contains(err.Error(), "invalid scheduler algorithm") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestOperatorEndpoint_Scheduler_ACL(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Anonymous requests are denied
+	get := &structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var getReply structs.SchedulerConfigurationResponse
+	err := msgpackrpc.CallWithCodec(codec, "Operator.SchedulerGetConfiguration", get, &getReply)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	set := &structs.SchedulerSetConfigRequest{
+		Config:       *structs.DefaultSchedulerConfiguration(),
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var setReply structs.SchedulerSetConfigurationResponse
+	err = msgpackrpc.CallWithCodec(codec, "Operator.SchedulerSetConfiguration", set, &setReply)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// Management tokens may set the configuration
+	set.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SchedulerSetConfiguration", set, &setReply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package nomad
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/nomad/helper/filter"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// paginator pages through the objects of a list query, which are iterated in
+// the order of their IDs. The objects before the next token of the query and
+// those not matching its filter are skipped. Once the page is full, the ID of
+// the next matching object is the token of the next page.
+type paginator struct {
+	perPage   int32
+	token     string
+	filter    *filter.Expression
+	count     int32
+	nextToken string
+}
+
+// newPaginator returns a paginator for the query, failing if its filter is
+// invalid
+func newPaginator(opts *structs.QueryOptions) (*paginator, error) {
+	p := &paginator{
+		perPage: opts.PerPage,
+		token:   opts.NextToken,
+	}
+	if opts.Filter != "" {
+		expr, err := filter.Parse(opts.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", structs.ErrInvalidFilter, err)
+		}
+		p.filter = expr
+	}
+	return p, nil
+}
+
+// accept returns whether the object with the given ID is part of the page
+func (p *paginator) accept(id string, obj interface{}) (bool, error) {
+	if p.done() || id < p.token {
+		return false, nil
+	}
+	if p.filter != nil {
+		match, err := p.filter.Match(obj)
+		if err != nil {
+			return false, fmt.Errorf("%v: %v", structs.ErrInvalidFilter, err)
+		}
+		if !match {
+			return false, nil
+		}
+	}
+	if p.perPage > 0 && p.count == p.perPage {
+		p.nextToken = id
+		return false, nil
+	}
+	p.count++
+	return true, nil
+}
+
+// done returns whether the page is full and no more objects are accepted
+func (p *paginator) done() bool {
+	return p.nextToken != ""
+}
@@ -20,7 +20,7 @@ type PeriodicDispatch struct {
 	enabled    bool
 	running    bool
 
-	tracked map[string]*structs.Job
+	tracked map[structs.NamespacedID]*structs.Job
 	heap    *periodicHeap
 
 	updateCh chan struct{}
@@ -54,6 +54,7 @@ func (s *Server) DispatchJob(job *structs.Job) (*structs.Evaluation, error) {
 	// Create a new evaluation
 	eval := &structs.Evaluation{
 		ID:             structs.GenerateUUID(),
+		Namespace:      job.Namespace,
 		Priority:       job.Priority,
 		Type:           job.Type,
 		TriggeredBy:    structs.EvalTriggerPeriodicJob,
@@ -87,7 +88,7 @@ func (s *Server) RunningChildren(job *structs.Job) (bool, error) {
 	}
 
 	prefix := fmt.Sprintf("%s%s", job.ID, structs.PeriodicLaunchSuffix)
-	iter, err := state.JobsByIDPrefix(prefix)
+	iter, err := state.JobsByIDPrefix(job.Namespace, prefix)
 	if err != nil {
 		return false, err
 	}
@@ -102,7 +103,7 @@ func (s *Server) RunningChildren(job *structs.Job) (bool, error) {
 		}
 
 		// Get the childs evaluations.
-		evals, err := state.EvalsByJob(child.ID)
+		evals, err := state.EvalsByJob(child.Namespace, child.ID)
 		if err != nil {
 			return false, err
 		}
@@ -135,7 +136,7 @@ func (s *Server) RunningChildren(job *structs.Job) (bool, error) {
 func NewPeriodicDispatch(logger *log.Logger, dispatcher JobEvalDispatcher) *PeriodicDispatch {
 	return &PeriodicDispatch{
 		dispatcher: dispatcher,
-		tracked:    make(map[string]*structs.Job),
+		tracked:    make(map[structs.NamespacedID]*structs.Job),
 		heap:       NewPeriodicHeap(),
 		updateCh:   make(chan struct{}, 1),
 		stopCh:     make(chan struct{}),
@@ -193,12 +194,14 @@ func (p *PeriodicDispatch) Add(job *structs.Job) error {
 		return nil
 	}
 
-	// If we were tracking a job and it has been disabled or made non-periodic remove it.
-	disabled := !job.IsPeriodic() || !job.Periodic.Enabled
-	_, tracked := p.tracked[job.ID]
+	// If we were tracking a job and it has been disabled, made non-periodic or
+	// stopped remove it.
+	disabled := !job.IsPeriodic() || !job.Periodic.Enabled || job.Stopped()
+	id := structs.NewNamespacedID(job.ID, job.Namespace)
+	_, tracked := p.tracked[id]
 	if disabled {
 		if tracked {
-			p.removeLocked(job.ID)
+			p.removeLocked(id)
 		}
 
 		// If the job is disabled and we aren't tracking it, do nothing.
@@ -206,7 +209,7 @@ func (p *PeriodicDispatch) Add(job *structs.Job) error {
 	}
 
 	// Add or update the job.
-	p.tracked[job.ID] = job
+	p.tracked[id] = job
 	next := job.Periodic.Next(time.Now().UTC())
 	if tracked {
 		if err := p.heap.Update(job, next); err != nil {
@@ -233,15 +236,15 @@ func (p *PeriodicDispatch) Add(job *structs.Job) error {
 
 // Remove stops tracking the passed job. If the job is not tracked, it is a
 // no-op.
-func (p *PeriodicDispatch) Remove(jobID string) error {
+func (p *PeriodicDispatch) Remove(namespace, jobID string) error {
 	p.l.Lock()
 	defer p.l.Unlock()
-	return p.removeLocked(jobID)
+	return p.removeLocked(structs.NewNamespacedID(jobID, namespace))
 }
 
 // Remove stops tracking the passed job. If the job is not tracked, it is a
 // no-op. It assumes this is called while a lock is held.
-func (p *PeriodicDispatch) removeLocked(jobID string) error {
+func (p *PeriodicDispatch) removeLocked(jobID structs.NamespacedID) error {
 	// Do nothing if not enabled
 	if !p.enabled {
 		return nil
@@ -271,7 +274,7 @@ func (p *PeriodicDispatch) removeLocked(jobID string) error {
 
 // ForceRun causes the periodic job to be evaluated immediately and returns the
 // subsequent eval.
-func (p *PeriodicDispatch) ForceRun(jobID string) (*structs.Evaluation, error) {
+func (p *PeriodicDispatch) ForceRun(namespace, jobID string) (*structs.Evaluation, error) {
 	p.l.Lock()
 
 	// Do nothing if not enabled
@@ -280,7 +283,7 @@ func (p *PeriodicDispatch) ForceRun(jobID string) (*structs.Evaluation, error) {
 		return nil, fmt.Errorf("periodic dispatch disabled")
 	}
 
-	job, tracked := p.tracked[jobID]
+	job, tracked := p.tracked[structs.NewNamespacedID(jobID, namespace)]
 	if !tracked {
 		p.l.Unlock()
 		return nil, fmt.Errorf("can't force run non-tracked job %v", jobID)
@@ -406,7 +409,7 @@ func (p *PeriodicDispatch) deriveJob(periodicJob *structs.Job, time time.Time) (
 			p.logger.Printf("[ERR] nomad.periodic: deriving job from"+
 				" periodic job %v failed; deregistering from periodic runner: %v",
 				periodicJob.ID, r)
-			p.Remove(periodicJob.ID)
+			p.Remove(periodicJob.Namespace, periodicJob.ID)
 			derived = nil
 			err = fmt.Errorf("Failed to create a copy of the periodic job %v: %v", periodicJob.ID, r)
 		}
@@ -451,13 +454,13 @@ func (p *PeriodicDispatch) Flush() {
 	p.stopCh = make(chan struct{})
 	p.updateCh = make(chan struct{}, 1)
 	p.waitCh = make(chan struct{})
-	p.tracked = make(map[string]*structs.Job)
+	p.tracked = make(map[structs.NamespacedID]*structs.Job)
 	p.heap = NewPeriodicHeap()
 }
 
 // periodicHeap wraps a heap and gives operations other than Push/Pop.
 type periodicHeap struct {
-	index map[string]*periodicJob
+	index map[structs.NamespacedID]*periodicJob
 	heap  periodicHeapImp
 }
 
@@ -469,18 +472,19 @@ type periodicJob struct {
 
 func NewPeriodicHeap() *periodicHeap {
 	return &periodicHeap{
-		index: make(map[string]*periodicJob),
+		index: make(map[structs.NamespacedID]*periodicJob),
 		heap:  make(periodicHeapImp, 0),
 	}
 }
 
 func (p *periodicHeap) Push(job *structs.Job, next time.Time) error {
-	if _, ok := p.index[job.ID]; ok {
-		return fmt.Errorf("job %v already exists", job.ID)
+	id := structs.NewNamespacedID(job.ID, job.Namespace)
+	if _, ok := p.index[id]; ok {
+		return fmt.Errorf("job %v already exists", id)
 	}
 
 	pJob := &periodicJob{job, next, 0}
-	p.index[job.ID] = pJob
+	p.index[id] = pJob
 	heap.Push(&p.heap, pJob)
 	return nil
 }
@@ -491,7 +495,7 @@ func (p *periodicHeap) Pop() *periodicJob {
 	}
 
 	pJob := heap.Pop(&p.heap).(*periodicJob)
-	delete(p.index, pJob.job.ID)
+	delete(p.index, structs.NewNamespacedID(pJob.job.ID, pJob.job.Namespace))
 	return pJob
 }
 
@@ -504,12 +508,12 @@ func (p *periodicHeap) Peek() *periodicJob {
 }
 
 func (p *periodicHeap) Contains(job *structs.Job) bool {
-	_, ok := p.index[job.ID]
+	_, ok := p.index[structs.NewNamespacedID(job.ID, job.Namespace)]
 	return ok
 }
 
 func (p *periodicHeap) Update(job *structs.Job, next time.Time) error {
-	if pJob, ok := p.index[job.ID]; ok {
+	if pJob, ok := p.index[structs.NewNamespacedID(job.ID, job.Namespace)]; ok {
 		// Need to update the job as well because its spec can change.
 		pJob.job = job
 		pJob.next = next
@@ -521,9 +525,10 @@ func (p *periodicHeap) Update(job *structs.Job, next time.Time) error {
 }
 
 func (p *periodicHeap) Remove(job *structs.Job) error {
-	if pJob, ok := p.index[job.ID]; ok {
+	id := structs.NewNamespacedID(job.ID, job.Namespace)
+	if pJob, ok := p.index[id]; ok {
 		heap.Remove(&p.heap, pJob.index)
-		delete(p.index, job.ID)
+		delete(p.index, id)
 		return nil
 	}
 
@@ -5,7 +5,10 @@ import (
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/state"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/watch"
 )
 
 // Periodic endpoint is used for periodic job interactions
@@ -30,7 +33,7 @@ func (p *Periodic) Force(args *structs.PeriodicForceRequest, reply *structs.Peri
 	if err != nil {
 		return err
 	}
-	job, err := snap.JobByID(args.JobID)
+	job, err := snap.JobByID(args.RequestNamespace(), args.JobID)
 	if err != nil {
 		return err
 	}
@@ -38,12 +41,19 @@ func (p *Periodic) Force(args *structs.PeriodicForceRequest, reply *structs.Peri
 		return fmt.Errorf("job not found")
 	}
 
+	// Check for submit-job permissions
+	if aclObj, err := p.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNamespaceOperation(job.Namespace, acl.NamespaceCapabilitySubmitJob) {
+		return structs.ErrPermissionDenied
+	}
+
 	if !job.IsPeriodic() {
 		return fmt.Errorf("can't force launch non-periodic job")
 	}
 
 	// Force run the job.
-	eval, err := p.srv.periodicDispatcher.ForceRun(job.ID)
+	eval, err := p.srv.periodicDispatcher.ForceRun(job.Namespace, job.ID)
 	if err != nil {
 		return fmt.Errorf("force launch for job %q failed: %v", job.ID, err)
 	}
@@ -53,3 +63,50 @@ func (p *Periodic) Force(args *structs.PeriodicForceRequest, reply *structs.Peri
 	reply.Index = eval.CreateIndex
 	return nil
 }
+
+// NextLaunches is used to list the next launch time of each of the specs of a
+// periodic job
+func (p *Periodic) NextLaunches(args *structs.JobSpecificRequest, reply *structs.PeriodicNextLaunchesResponse) error {
+	if done, err := p.srv.forward("Periodic.NextLaunches", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "periodic", "next_launches"}, time.Now())
+
+	// Resolve the token used to check read-job permissions
+	aclObj, err := p.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Job: args.JobID}),
+		run: func(snap *state.StateSnapshot) error {
+			// Lookup the job
+			job, err := snap.JobByID(args.RequestNamespace(), args.JobID)
+			if err != nil {
+				return err
+			}
+			if job == nil {
+				return fmt.Errorf("job not found")
+			}
+
+			// Check namespace read-job permissions
+			if aclObj != nil && !aclObj.AllowNamespaceOperation(job.Namespace, acl.NamespaceCapabilityReadJob) {
+				return structs.ErrPermissionDenied
+			}
+
+			if !job.IsPeriodic() {
+				return fmt.Errorf("job %q is not periodic", job.ID)
+			}
+
+			// Setup the output
+			reply.Launches = job.Periodic.NextLaunches(time.Now().UTC())
+			reply.Index = job.ModifyIndex
+			p.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return p.srv.blockingRPC(&opts)
+}
@@ -81,3 +81,57 @@ func TestPeriodicEndpoint_Force_NonPeriodic(t *testing.T) {
 		t.Fatalf("Force on non-perodic job should err")
 	}
 }
+
+func TestPeriodicEndpoint_NextLaunches(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	state := s1.fsm.State()
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create and insert a periodic job with additional specs.
+	job := mock.PeriodicJob()
+	job.Periodic.Specs = []*structs.PeriodicSpec{
+		{Spec: "0 9 * * *", Enabled: true},
+		{Spec: "0 17 * * *", Enabled: false},
+	}
+	if err := state.UpsertJob(100, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.JobSpecificRequest{
+		JobID:        job.ID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.PeriodicNextLaunchesResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Periodic.NextLaunches", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 100 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	if len(resp.Launches) != 3 {
+		t.Fatalf("bad: %#v", resp.Launches)
+	}
+	for i, spec := range []string{"*/30 * * * *", "0 9 * * *", "0 17 * * *"} {
+		if resp.Launches[i].Spec != spec {
+			t.Fatalf("launch %d: bad spec: %#v", i, resp.Launches[i])
+		}
+	}
+	if resp.Launches[0].Next.IsZero() || resp.Launches[1].Next.IsZero() || !resp.Launches[2].Next.IsZero() {
+		t.Fatalf("bad: %#v", resp.Launches)
+	}
+
+	// Non-periodic jobs have no launches
+	other := mock.Job()
+	if err := state.UpsertJob(200, other); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req.JobID = other.ID
+	if err := msgpackrpc.CallWithCodec(codec, "Periodic.NextLaunches", req, &resp); err == nil {
+		t.Fatalf("NextLaunches on non-periodic job should err")
+	}
+}
@@ -202,7 +202,7 @@ func TestPeriodicDispatch_Add_TriggersUpdate(t *testing.T) {
 
 func TestPeriodicDispatch_Remove_Untracked(t *testing.T) {
 	p, _ := testPeriodicDispatcher()
-	if err := p.Remove("foo"); err != nil {
+	if err := p.Remove(structs.DefaultNamespace, "foo"); err != nil {
 		t.Fatalf("Remove failed %v; expected a no-op", err)
 	}
 }
@@ -220,7 +220,7 @@ func TestPeriodicDispatch_Remove_Tracked(t *testing.T) {
 		t.Fatalf("Add didn't track the job: %v", tracked)
 	}
 
-	if err := p.Remove(job.ID); err != nil {
+	if err := p.Remove(job.Namespace, job.ID); err != nil {
 		t.Fatalf("Remove failed %v", err)
 	}
 
@@ -242,7 +242,7 @@ func TestPeriodicDispatch_Remove_TriggersUpdate(t *testing.T) {
 	}
 
 	// Remove the job.
-	if err := p.Remove(job.ID); err != nil {
+	if err := p.Remove(job.Namespace, job.ID); err != nil {
 		t.Fatalf("Add failed %v", err)
 	}
 
@@ -258,7 +258,7 @@ func TestPeriodicDispatch_Remove_TriggersUpdate(t *testing.T) {
 func TestPeriodicDispatch_ForceRun_Untracked(t *testing.T) {
 	p, _ := testPeriodicDispatcher()
 
-	if _, err := p.ForceRun("foo"); err == nil {
+	if _, err := p.ForceRun(structs.DefaultNamespace, "foo"); err == nil {
 		t.Fatal("ForceRun of untracked job should fail")
 	}
 }
@@ -275,7 +275,7 @@ func TestPeriodicDispatch_ForceRun_Tracked(t *testing.T) {
 	}
 
 	// ForceRun the job
-	if _, err := p.ForceRun(job.ID); err != nil {
+	if _, err := p.ForceRun(job.Namespace, job.ID); err != nil {
 		t.Fatalf("ForceRun failed %v", err)
 	}
 
@@ -441,7 +441,7 @@ func TestPeriodicDispatch_Complex(t *testing.T) {
 	}
 
 	for _, job := range toDelete {
-		if err := p.Remove(job.ID); err != nil {
+		if err := p.Remove(job.Namespace, job.ID); err != nil {
 			t.Fatalf("Remove failed %v", err)
 		}
 	}
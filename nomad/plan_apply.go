@@ -3,6 +3,7 @@ package nomad
 import (
 	"fmt"
 	"runtime"
+	"sort"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -78,13 +79,39 @@ func (s *Server) planApply() {
 			}
 		}
 
+		// Reject the placements that would exceed the namespace's quota
+		plan, quota, err := applyQuota(snap, pending.plan, s.config.Region)
+		if err != nil {
+			s.logger.Printf("[ERR] nomad: failed to evaluate plan quota: %v", err)
+			pending.respond(nil, err)
+			continue
+		}
+
 		// Evaluate the plan
-		result, err := evaluatePlan(pool, snap, pending.plan)
+		result, err := evaluatePlan(pool, snap, plan)
 		if err != nil {
 			s.logger.Printf("[ERR] nomad: failed to evaluate plan: %v", err)
 			pending.respond(nil, err)
 			continue
 		}
+		for _, nodeID := range result.RejectedNodes {
+			s.nodeReliability.PlanRejected(nodeID)
+		}
+
+		// Mark the result as limited by the quota and force the scheduler to
+		// refresh, as for any other partial commit
+		if quota != "" {
+			s.logger.Printf("[DEBUG] nomad: plan for eval %q limited by quota %q", plan.EvalID, quota)
+			result.QuotaLimitReached = quota
+			if result.RefreshIndex == 0 {
+				allocIndex, err := snap.Index("allocs")
+				if err != nil {
+					pending.respond(nil, err)
+					continue
+				}
+				result.RefreshIndex = maxUint64(allocIndex, 1)
+			}
+		}
 
 		// Fast-path the response if there is nothing to do
 		if result.IsNoOp() {
@@ -139,10 +166,19 @@ func (s *Server) applyPlan(job *structs.Job, result *structs.PlanResult, snap *s
 
 	// Set the time the alloc was applied for the first time. This can be used
 	// to approximate the scheduling time.
-	now := time.Now().UTC().UnixNano()
+	now := time.Now().UTC()
 	for _, alloc := range req.Alloc {
 		if alloc.CreateTime == 0 {
-			alloc.CreateTime = now
+			alloc.CreateTime = now.UnixNano()
+		}
+	}
+
+	// Sign the workload identities of the placed allocations' tasks
+	for _, allocList := range result.NodeAllocation {
+		for _, alloc := range allocList {
+			if err := s.signAllocIdentities(job, alloc, now); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -165,7 +201,8 @@ func (s *Server) applyPlan(job *structs.Job, result *structs.PlanResult, snap *s
 // asyncPlanWait is used to apply and respond to a plan async
 func (s *Server) asyncPlanWait(waitCh chan struct{}, future raft.ApplyFuture,
 	result *structs.PlanResult, pending *pendingPlan) {
-	defer metrics.MeasureSince([]string{"nomad", "plan", "apply"}, time.Now())
+	start := time.Now()
+	defer metrics.MeasureSince([]string{"nomad", "plan", "apply"}, start)
 	defer close(waitCh)
 
 	// Wait for the plan to apply
@@ -177,6 +214,7 @@ func (s *Server) asyncPlanWait(waitCh chan struct{}, future raft.ApplyFuture,
 
 	// Respond to the plan
 	result.AllocIndex = future.Index()
+	result.RaftApply = time.Since(start)
 
 	// If this is a partial plan application, we need to ensure the scheduler
 	// at least has visibility into any placements it made to avoid double placement.
@@ -231,6 +269,8 @@ func evaluatePlan(pool *EvaluatePool, snap *state.StateSnapshot, plan *structs.P
 		if !fit {
 			// Set that this is a partial commit
 			partialCommit = true
+			metrics.IncrCounter([]string{"nomad", "plan", "node_rejected"}, 1)
+			result.RejectedNodes = append(result.RejectedNodes, nodeID)
 
 			// If we require all-at-once scheduling, there is no point
 			// to continue the evaluation, as we've already failed.
@@ -295,6 +335,7 @@ func evaluatePlan(pool *EvaluatePool, snap *state.StateSnapshot, plan *structs.P
 	// a minimum refresh index to force the scheduler to work on a more
 	// up-to-date state to avoid the failures.
 	if partialCommit {
+		metrics.IncrCounter([]string{"nomad", "plan", "partial_commit"}, 1)
 		allocIndex, err := snap.Index("allocs")
 		if err != nil {
 			mErr.Errors = append(mErr.Errors, err)
@@ -313,6 +354,105 @@ func evaluatePlan(pool *EvaluatePool, snap *state.StateSnapshot, plan *structs.P
 	return result, mErr.ErrorOrNil()
 }
 
+// applyQuota removes the placements from a plan that would exceed the quota
+// attached to the job's namespace in the given region. Placements are
+// admitted in node order until the quota is exhausted. It returns the plan to
+// evaluate and, if any placements were removed, the name of the quota.
+func applyQuota(snap *state.StateSnapshot, plan *structs.Plan, region string) (*structs.Plan, string, error) {
+	if plan.Job == nil || len(plan.NodeAllocation) == 0 {
+		return plan, "", nil
+	}
+
+	ns, err := snap.NamespaceByName(plan.Job.Namespace)
+	if err != nil {
+		return nil, "", err
+	}
+	if ns == nil || ns.Quota == "" {
+		return plan, "", nil
+	}
+
+	usage, err := snap.QuotaUsage(region, ns.Quota)
+	if err != nil {
+		return nil, "", err
+	}
+	if usage == nil || usage.Limit == nil {
+		return plan, "", nil
+	}
+	used := usage.Used
+
+	// Release the resources of the allocations the plan stops
+	for _, updateList := range plan.NodeUpdate {
+		for _, alloc := range updateList {
+			existing, err := snap.AllocByID(alloc.ID)
+			if err != nil {
+				return nil, "", err
+			}
+			if existing != nil && !existing.TerminalStatus() {
+				used.Subtract(existing)
+			}
+		}
+	}
+
+	// Admit placements while they fit in the quota
+	nodeIDs := make([]string, 0, len(plan.NodeAllocation))
+	for nodeID := range plan.NodeAllocation {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+
+	limited := false
+	admitted := make(map[string][]*structs.Allocation, len(plan.NodeAllocation))
+	for _, nodeID := range nodeIDs {
+		for _, alloc := range plan.NodeAllocation[nodeID] {
+			proposed := used.Copy()
+			existing, err := snap.AllocByID(alloc.ID)
+			if err != nil {
+				return nil, "", err
+			}
+			if existing != nil && !existing.TerminalStatus() {
+				proposed.Subtract(existing)
+			}
+			if !alloc.TerminalStatus() {
+				proposed.Add(alloc)
+			}
+
+			if quotaExceeded(used, proposed, usage.Limit) {
+				limited = true
+				continue
+			}
+			used = proposed
+			admitted[nodeID] = append(admitted[nodeID], alloc)
+		}
+	}
+
+	if !limited {
+		return plan, "", nil
+	}
+
+	// An all-at-once plan can not be partially applied
+	trimmed := new(structs.Plan)
+	*trimmed = *plan
+	trimmed.NodeAllocation = admitted
+	if plan.AllAtOnce {
+		trimmed.NodeUpdate = make(map[string][]*structs.Allocation)
+		trimmed.NodeAllocation = make(map[string][]*structs.Allocation)
+	}
+	return trimmed, ns.Quota, nil
+}
+
+// quotaExceeded returns whether moving from the current to the proposed usage
+// grows any resource beyond the limit. Usage that does not grow is allowed
+// even if it is over the limit, so that lowering a quota does not prevent
+// in-place updates.
+func quotaExceeded(current, proposed, limit *structs.QuotaLimit) bool {
+	exceeds := func(cur, prop, max int) bool {
+		return max != 0 && prop > cur && prop > max
+	}
+	return exceeds(current.CPU, proposed.CPU, limit.CPU) ||
+		exceeds(current.MemoryMB, proposed.MemoryMB, limit.MemoryMB) ||
+		exceeds(current.Count, proposed.Count, limit.Count)
+}
+
 // evaluateNodePlan is used to evalute the plan for a single node,
 // returning if the plan is valid or if an error is encountered
 func evaluateNodePlan(snap *state.StateSnapshot, plan *structs.Plan, nodeID string) (bool, error) {
@@ -334,12 +474,34 @@ func evaluateNodePlan(snap *state.StateSnapshot, plan *structs.Plan, nodeID stri
 		return false, nil
 	}
 
+	// If the node changed since the scheduler checked the feasibility of the
+	// placements against it, they may no longer be feasible. Rejecting them
+	// forces the scheduler to refresh its state and retry.
+	if index, ok := plan.NodeModifyIndex[nodeID]; ok && index != node.ModifyIndex {
+		return false, nil
+	}
+
 	// Get the existing allocations that are non-terminal
 	existingAlloc, err := snap.AllocsByNodeTerminal(nodeID, false)
 	if err != nil {
 		return false, fmt.Errorf("failed to get existing allocations for '%s': %v", nodeID, err)
 	}
 
+	// Nodes that are ineligible for scheduling keep running their existing
+	// allocations, which may still be updated in place, but no new
+	// allocations may be placed on them
+	if !node.SchedulingEligible() {
+		existing := make(map[string]struct{}, len(existingAlloc))
+		for _, alloc := range existingAlloc {
+			existing[alloc.ID] = struct{}{}
+		}
+		for _, alloc := range plan.NodeAllocation[nodeID] {
+			if _, ok := existing[alloc.ID]; !ok {
+				return false, nil
+			}
+		}
+	}
+
 	// Determine the proposed allocation by first removing allocations
 	// that are planned evictions and adding the new allocations.
 	proposed := existingAlloc
@@ -110,6 +110,11 @@ func TestPlanApply_applyPlan(t *testing.T) {
 		t.Fatalf("missing alloc")
 	}
 
+	// The placed allocation has a workload identity for its task
+	if _, ok := out.SignedIdentities["web"]; !ok {
+		t.Fatalf("missing workload identity: %#v", out.SignedIdentities)
+	}
+
 	// Evict alloc, Register alloc2
 	allocEvict := new(structs.Allocation)
 	*allocEvict = *alloc
@@ -285,6 +290,81 @@ func TestPlanApply_EvalPlan_Partial_AllAtOnce(t *testing.T) {
 	}
 }
 
+func TestPlanApply_applyQuota(t *testing.T) {
+	state := testStateStore(t)
+	node := mock.Node()
+	state.UpsertNode(1000, node)
+
+	// Create a quota that allows two allocations
+	quota := mock.QuotaSpec()
+	quota.Limits[0].Count = 2
+	state.UpsertQuotaSpecs(1001, []*structs.QuotaSpec{quota})
+	ns := mock.Namespace()
+	ns.Quota = quota.Name
+	state.UpsertNamespaces(1002, []*structs.Namespace{ns})
+
+	// Use up half of the quota
+	job := mock.Job()
+	job.Namespace = ns.Name
+	alloc := mock.Alloc()
+	alloc.Namespace = ns.Name
+	alloc.NodeID = node.ID
+	state.UpsertJobSummary(1003, mock.JobSummary(alloc.JobID))
+	state.UpsertAllocs(1004, []*structs.Allocation{alloc})
+	snap, _ := state.Snapshot()
+
+	// Only one of the placements fits in the quota
+	alloc2 := mock.Alloc()
+	alloc2.Resources = nil
+	alloc3 := mock.Alloc()
+	alloc3.Resources = nil
+	plan := &structs.Plan{
+		Job: job,
+		NodeAllocation: map[string][]*structs.Allocation{
+			node.ID: []*structs.Allocation{alloc2, alloc3},
+		},
+	}
+
+	out, limited, err := applyQuota(snap, plan, "global")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if limited != quota.Name {
+		t.Fatalf("bad: %q", limited)
+	}
+	if allocs := out.NodeAllocation[node.ID]; len(allocs) != 1 || allocs[0] != alloc2 {
+		t.Fatalf("bad: %#v", out.NodeAllocation)
+	}
+
+	// Stopping the existing allocation makes room for both placements
+	stop := alloc.Copy()
+	stop.DesiredStatus = structs.AllocDesiredStatusStop
+	plan.NodeUpdate = map[string][]*structs.Allocation{
+		node.ID: []*structs.Allocation{stop},
+	}
+
+	out, limited, err = applyQuota(snap, plan, "global")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if limited != "" {
+		t.Fatalf("bad: %q", limited)
+	}
+	if out != plan {
+		t.Fatalf("plan should not be modified")
+	}
+
+	// Quotas are only enforced in the regions they limit
+	plan.NodeUpdate = nil
+	out, limited, err = applyQuota(snap, plan, "other")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if limited != "" || out != plan {
+		t.Fatalf("bad: %q", limited)
+	}
+}
+
 func TestPlanApply_EvalNodePlan_Simple(t *testing.T) {
 	state := testStateStore(t)
 	node := mock.Node()
@@ -353,6 +433,83 @@ func TestPlanApply_EvalNodePlan_NodeDrain(t *testing.T) {
 	}
 }
 
+func TestPlanApply_EvalNodePlan_NodeIneligible(t *testing.T) {
+	state := testStateStore(t)
+	node := mock.Node()
+	node.SchedulingEligibility = structs.NodeSchedulingIneligible
+	state.UpsertNode(1000, node)
+
+	existing := mock.Alloc()
+	existing.NodeID = node.ID
+	state.UpsertAllocs(1001, []*structs.Allocation{existing})
+	snap, _ := state.Snapshot()
+
+	// Existing allocations may be updated in place
+	plan := &structs.Plan{
+		NodeAllocation: map[string][]*structs.Allocation{
+			node.ID: []*structs.Allocation{existing},
+		},
+	}
+	fit, err := evaluateNodePlan(snap, plan, node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !fit {
+		t.Fatalf("bad")
+	}
+
+	// New allocations are rejected
+	alloc := mock.Alloc()
+	plan.NodeAllocation[node.ID] = []*structs.Allocation{existing, alloc}
+	fit, err = evaluateNodePlan(snap, plan, node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if fit {
+		t.Fatalf("bad")
+	}
+}
+
+func TestPlanApply_EvalNodePlan_NodeModified(t *testing.T) {
+	state := testStateStore(t)
+	node := mock.Node()
+	state.UpsertNode(1000, node)
+
+	alloc := mock.Alloc()
+	plan := &structs.Plan{
+		NodeAllocation: map[string][]*structs.Allocation{
+			node.ID: []*structs.Allocation{alloc},
+		},
+		NodeModifyIndex: map[string]uint64{
+			node.ID: 1000,
+		},
+	}
+
+	// The node is unchanged since it was scheduled against
+	snap, _ := state.Snapshot()
+	fit, err := evaluateNodePlan(snap, plan, node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !fit {
+		t.Fatalf("bad")
+	}
+
+	// Modify the node
+	node = node.Copy()
+	node.Attributes["kernel.name"] = "windows"
+	state.UpsertNode(1001, node)
+
+	snap, _ = state.Snapshot()
+	fit, err = evaluateNodePlan(snap, plan, node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if fit {
+		t.Fatalf("bad")
+	}
+}
+
 func TestPlanApply_EvalNodePlan_NodeNotExist(t *testing.T) {
 	state := testStateStore(t)
 	snap, _ := state.Snapshot()
@@ -176,6 +176,19 @@ func (p *ConnPool) Shutdown() error {
 	return nil
 }
 
+// ReloadTLS swaps the TLS wrapper of the pool. The pooled connections are
+// closed so that the new connections use the new certificates.
+func (p *ConnPool) ReloadTLS(tlsWrap tlsutil.DCWrapper) {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, conn := range p.pool {
+		conn.Close()
+	}
+	p.pool = make(map[string]*Conn)
+	p.tlsWrap = tlsWrap
+}
+
 // Acquire is used to get a connection that is
 // pooled or to return a new connection
 func (p *ConnPool) acquire(region string, addr net.Addr, version int) (*Conn, error) {
@@ -254,7 +267,10 @@ func (p *ConnPool) getNewConn(region string, addr net.Addr, version int) (*Conn,
 	}
 
 	// Check if TLS is enabled
-	if p.tlsWrap != nil {
+	p.Lock()
+	tlsWrap := p.tlsWrap
+	p.Unlock()
+	if tlsWrap != nil {
 		// Switch the connection into TLS mode
 		if _, err := conn.Write([]byte{byte(rpcTLS)}); err != nil {
 			conn.Close()
@@ -262,7 +278,7 @@ func (p *ConnPool) getNewConn(region string, addr net.Addr, version int) (*Conn,
 		}
 
 		// Wrap the connection in a TLS client
-		tlsConn, err := p.tlsWrap(region, conn)
+		tlsConn, err := tlsWrap(region, conn)
 		if err != nil {
 			conn.Close()
 			return nil, err
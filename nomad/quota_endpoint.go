@@ -0,0 +1,287 @@
+package nomad
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/features"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/watch"
+)
+
+// Quota endpoint is used for manipulating quota specifications and querying
+// their usage
+type Quota struct {
+	srv *Server
+}
+
+// UpsertQuotaSpecs is used to create or update a set of quota specifications
+func (q *Quota) UpsertQuotaSpecs(args *structs.QuotaSpecUpsertRequest,
+	reply *structs.GenericResponse) error {
+	// Always flow modification requests to the authoritative region, which
+	// replicates them to the other regions
+	args.Region = q.srv.config.AuthoritativeRegion
+
+	if done, err := q.srv.forward("Quota.UpsertQuotaSpecs", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "quota", "upsert_quota_specs"}, time.Now())
+
+	// Check that quotas are enabled
+	if err := q.srv.config.Features.Check(features.Quotas); err != nil {
+		return err
+	}
+
+	// Check quota write permissions
+	if aclObj, err := q.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowQuotaWrite() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.Quotas) == 0 {
+		return fmt.Errorf("must specify at least one quota specification")
+	}
+	for _, quota := range args.Quotas {
+		if err := quota.Validate(); err != nil {
+			return fmt.Errorf("Invalid quota %q: %v", quota.Name, err)
+		}
+	}
+
+	// Commit this update via Raft
+	_, index, err := q.srv.raftApply(structs.QuotaSpecUpsertRequestType, args)
+	if err != nil {
+		q.srv.logger.Printf("[ERR] nomad.quota: UpsertQuotaSpecs failed: %v", err)
+		return err
+	}
+
+	reply.Index = index
+	return nil
+}
+
+// DeleteQuotaSpecs is used to delete a set of quota specifications
+func (q *Quota) DeleteQuotaSpecs(args *structs.QuotaSpecDeleteRequest,
+	reply *structs.GenericResponse) error {
+	// Always flow modification requests to the authoritative region, which
+	// replicates them to the other regions
+	args.Region = q.srv.config.AuthoritativeRegion
+
+	if done, err := q.srv.forward("Quota.DeleteQuotaSpecs", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "quota", "delete_quota_specs"}, time.Now())
+
+	// Check that quotas are enabled
+	if err := q.srv.config.Features.Check(features.Quotas); err != nil {
+		return err
+	}
+
+	// Check quota write permissions
+	if aclObj, err := q.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowQuotaWrite() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.Names) == 0 {
+		return fmt.Errorf("must specify at least one quota specification to delete")
+	}
+
+	// Commit this update via Raft
+	_, index, err := q.srv.raftApply(structs.QuotaSpecDeleteRequestType, args)
+	if err != nil {
+		q.srv.logger.Printf("[ERR] nomad.quota: DeleteQuotaSpecs failed: %v", err)
+		return err
+	}
+
+	reply.Index = index
+	return nil
+}
+
+// ListQuotaSpecs is used to list the quota specifications
+func (q *Quota) ListQuotaSpecs(args *structs.QuotaSpecListRequest,
+	reply *structs.QuotaSpecListResponse) error {
+	if done, err := q.srv.forward("Quota.ListQuotaSpecs", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "quota", "list_quota_specs"}, time.Now())
+
+	// Check that quotas are enabled
+	if err := q.srv.config.Features.Check(features.Quotas); err != nil {
+		return err
+	}
+
+	// Check quota read permissions
+	if aclObj, err := q.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowQuotaRead() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "quota_specs"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Capture all the quota specifications
+			var err error
+			var iter memdb.ResultIterator
+			if prefix := args.QueryOptions.Prefix; prefix != "" {
+				iter, err = snap.QuotaSpecsByNamePrefix(prefix)
+			} else {
+				iter, err = snap.QuotaSpecs()
+			}
+			if err != nil {
+				return err
+			}
+
+			var quotas []*structs.QuotaSpec
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				quotas = append(quotas, raw.(*structs.QuotaSpec))
+			}
+			reply.Quotas = quotas
+
+			// Use the last index that affected the quota table
+			index, err := snap.Index("quota_specs")
+			if err != nil {
+				return err
+			}
+
+			// Ensure we never set the index to zero, otherwise a blocking query
+			// cannot be used. We floor the index at one, since realistically
+			// the first write must have a higher index.
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			q.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return q.srv.blockingRPC(&opts)
+}
+
+// GetQuotaSpec is used to get a specific quota specification
+func (q *Quota) GetQuotaSpec(args *structs.QuotaSpecificRequest,
+	reply *structs.SingleQuotaSpecResponse) error {
+	if done, err := q.srv.forward("Quota.GetQuotaSpec", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "quota", "get_quota_spec"}, time.Now())
+
+	// Check that quotas are enabled
+	if err := q.srv.config.Features.Check(features.Quotas); err != nil {
+		return err
+	}
+
+	// Check quota read permissions
+	if aclObj, err := q.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowQuotaRead() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "quota_specs"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Look for the quota specification
+			out, err := snap.QuotaSpecByName(args.Name)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Quota = out
+			if out != nil {
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the quota table
+				index, err := snap.Index("quota_specs")
+				if err != nil {
+					return err
+				}
+				if index == 0 {
+					index = 1
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			q.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return q.srv.blockingRPC(&opts)
+}
+
+// GetQuotaUsage is used to get the usage of a quota specification in the
+// region of the server
+func (q *Quota) GetQuotaUsage(args *structs.QuotaSpecificRequest,
+	reply *structs.SingleQuotaUsageResponse) error {
+	if done, err := q.srv.forward("Quota.GetQuotaUsage", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "quota", "get_quota_usage"}, time.Now())
+
+	// Check that quotas are enabled
+	if err := q.srv.config.Features.Check(features.Quotas); err != nil {
+		return err
+	}
+
+	// Check quota read permissions
+	if aclObj, err := q.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowQuotaRead() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch: watch.NewItems(
+			watch.Item{Table: "quota_specs"},
+			watch.Item{Table: "namespaces"},
+			watch.Item{Table: "allocs"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Compute the usage
+			out, err := snap.QuotaUsage(q.srv.config.Region, args.Name)
+			if err != nil {
+				return err
+			}
+			reply.Usage = out
+
+			// The usage depends on the quota, its namespaces and their
+			// allocations, so use the latest index of those tables
+			var index uint64
+			for _, table := range []string{"quota_specs", "namespaces", "allocs"} {
+				tableIndex, err := snap.Index(table)
+				if err != nil {
+					return err
+				}
+				index = maxUint64(index, tableIndex)
+			}
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			q.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return q.srv.blockingRPC(&opts)
+}
@@ -0,0 +1,230 @@
+package nomad
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/features"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestQuotaEndpoint_UpsertQuotaSpecs(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	quota := mock.QuotaSpec()
+	req := &structs.QuotaSpecUpsertRequest{
+		Quotas:       []*structs.QuotaSpec{quota},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Quota.UpsertQuotaSpecs", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	// Check we created the quota
+	out, err := s1.fsm.State().QuotaSpecByName(quota.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("quota not found")
+	}
+
+	// Quotas without limits are rejected
+	req.Quotas = []*structs.QuotaSpec{{Name: "empty"}}
+	if err := msgpackrpc.CallWithCodec(codec, "Quota.UpsertQuotaSpecs", req, &resp); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestQuotaEndpoint_DeleteQuotaSpecs(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	quota := mock.QuotaSpec()
+	state := s1.fsm.State()
+	if err := state.UpsertQuotaSpecs(1000, []*structs.QuotaSpec{quota}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.QuotaSpecDeleteRequest{
+		Names:        []string{quota.Name},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Quota.DeleteQuotaSpecs", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	out, err := state.QuotaSpecByName(quota.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("quota not deleted: %#v", out)
+	}
+}
+
+func TestQuotaEndpoint_ListQuotaSpecs(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	quota1 := mock.QuotaSpec()
+	quota1.Name = "engineering"
+	quota2 := mock.QuotaSpec()
+	if err := s1.fsm.State().UpsertQuotaSpecs(1000, []*structs.QuotaSpec{quota1, quota2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.QuotaSpecListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.QuotaSpecListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Quota.ListQuotaSpecs", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("Bad index: %d %d", resp.Index, 1000)
+	}
+	if len(resp.Quotas) != 2 {
+		t.Fatalf("bad: %#v", resp.Quotas)
+	}
+
+	// Lookup the quotas by prefix
+	req.Prefix = "eng"
+	var resp2 structs.QuotaSpecListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Quota.ListQuotaSpecs", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Quotas) != 1 || resp2.Quotas[0].Name != quota1.Name {
+		t.Fatalf("bad: %#v", resp2.Quotas)
+	}
+}
+
+func TestQuotaEndpoint_GetQuotaSpec(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	quota := mock.QuotaSpec()
+	if err := s1.fsm.State().UpsertQuotaSpecs(1000, []*structs.QuotaSpec{quota}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.QuotaSpecificRequest{
+		Name:         quota.Name,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.SingleQuotaSpecResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Quota.GetQuotaSpec", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("Bad index: %d %d", resp.Index, 1000)
+	}
+	if resp.Quota == nil || resp.Quota.Name != quota.Name {
+		t.Fatalf("bad: %#v", resp.Quota)
+	}
+
+	// Missing quota
+	req.Name = "missing"
+	if err := msgpackrpc.CallWithCodec(codec, "Quota.GetQuotaSpec", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Quota != nil {
+		t.Fatalf("bad: %#v", resp.Quota)
+	}
+}
+
+func TestQuotaEndpoint_GetQuotaUsage(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	state := s1.fsm.State()
+	quota := mock.QuotaSpec()
+	if err := state.UpsertQuotaSpecs(1000, []*structs.QuotaSpec{quota}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ns := mock.Namespace()
+	ns.Quota = quota.Name
+	if err := state.UpsertNamespaces(1001, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	alloc := mock.Alloc()
+	alloc.Namespace = ns.Name
+	state.UpsertJobSummary(1002, mock.JobSummary(alloc.JobID))
+	if err := state.UpsertAllocs(1003, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.QuotaSpecificRequest{
+		Name:         quota.Name,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.SingleQuotaUsageResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Quota.GetQuotaUsage", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1003 {
+		t.Fatalf("Bad index: %d %d", resp.Index, 1003)
+	}
+	usage := resp.Usage
+	if usage == nil || usage.Used.Count != 1 || usage.Used.CPU != alloc.Resources.CPU {
+		t.Fatalf("bad: %#v", usage)
+	}
+	if usage.Limit == nil || usage.Limit.CPU != quota.Limits[0].CPU {
+		t.Fatalf("bad: %#v", usage.Limit)
+	}
+}
+
+func TestQuotaEndpoint_FeatureDisabled(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		set, err := features.NewSet([]string{string(features.Quotas)})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		c.Features = set
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	req := &structs.QuotaSpecUpsertRequest{
+		Quotas:       []*structs.QuotaSpec{mock.QuotaSpec()},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	err := msgpackrpc.CallWithCodec(codec, "Quota.UpsertQuotaSpecs", req, &resp)
+	if err == nil || !strings.HasPrefix(err.Error(), structs.ErrFeatureDisabled.Error()) {
+		t.Fatalf("expected feature disabled error, got: %v", err)
+	}
+
+	list := &structs.QuotaSpecListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var listResp structs.QuotaSpecListResponse
+	err = msgpackrpc.CallWithCodec(codec, "Quota.ListQuotaSpecs", list, &listResp)
+	if err == nil || !strings.HasPrefix(err.Error(), structs.ErrFeatureDisabled.Error()) {
+		t.Fatalf("expected feature disabled error, got: %v", err)
+	}
+}
@@ -19,7 +19,8 @@ type RaftLayer struct {
 	connCh chan net.Conn
 
 	// TLS wrapper
-	tlsWrap tlsutil.Wrapper
+	tlsWrap     tlsutil.Wrapper
+	tlsWrapLock sync.RWMutex
 
 	// Tracks if we are closed
 	closed    bool
@@ -74,6 +75,13 @@ func (l *RaftLayer) Close() error {
 	return nil
 }
 
+// ReloadTLS swaps the TLS wrapper used for new outgoing connections
+func (l *RaftLayer) ReloadTLS(tlsWrap tlsutil.Wrapper) {
+	l.tlsWrapLock.Lock()
+	defer l.tlsWrapLock.Unlock()
+	l.tlsWrap = tlsWrap
+}
+
 // Addr is used to return the address of the listener
 func (l *RaftLayer) Addr() net.Addr {
 	return l.addr
@@ -87,7 +95,10 @@ func (l *RaftLayer) Dial(address string, timeout time.Duration) (net.Conn, error
 	}
 
 	// Check for tls mode
-	if l.tlsWrap != nil {
+	l.tlsWrapLock.RLock()
+	tlsWrap := l.tlsWrap
+	l.tlsWrapLock.RUnlock()
+	if tlsWrap != nil {
 		// Switch the connection into TLS mode
 		if _, err := conn.Write([]byte{byte(rpcTLS)}); err != nil {
 			conn.Close()
@@ -95,7 +106,7 @@ func (l *RaftLayer) Dial(address string, timeout time.Duration) (net.Conn, error
 		}
 
 		// Wrap the connection in a TLS client
-		conn, err = l.tlsWrap(conn)
+		conn, err = tlsWrap(conn)
 		if err != nil {
 			return nil, err
 		}
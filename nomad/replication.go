@@ -0,0 +1,183 @@
+package nomad
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// aclReplicationPolicies and aclReplicationTokens are the types of ACL
+	// objects replicated from the authoritative region
+	aclReplicationPolicies = "policies"
+	aclReplicationTokens   = "tokens"
+
+	// namespaceReplicationNamespaces and namespaceReplicationQuotas are the
+	// types of tenancy objects replicated from the authoritative region
+	namespaceReplicationNamespaces = "namespaces"
+	namespaceReplicationQuotas     = "quota_specs"
+)
+
+// replicationTracker tracks the progress of the replication of objects from
+// the authoritative region by the leader
+type replicationTracker struct {
+	// metricsPrefix is the prefix of the replication metrics, which are
+	// emitted per type of object
+	metricsPrefix []string
+	types         []string
+
+	l         sync.RWMutex
+	running   bool
+	started   time.Time
+	progress  map[string]*structs.ReplicationProgress
+	conflicts map[string][]*structs.ReplicationConflict
+}
+
+// newReplicationTracker returns a tracker of the replication of the types of
+// objects that isn't running
+func newReplicationTracker(metricsPrefix []string, types ...string) *replicationTracker {
+	return &replicationTracker{
+		metricsPrefix: metricsPrefix,
+		types:         types,
+		progress:      make(map[string]*structs.ReplicationProgress),
+		conflicts:     make(map[string][]*structs.ReplicationConflict),
+	}
+}
+
+// metric returns the name of a replication metric of a type of object
+func (r *replicationTracker) metric(typ, name string) []string {
+	key := make([]string, 0, len(r.metricsPrefix)+2)
+	key = append(key, r.metricsPrefix...)
+	return append(key, typ, name)
+}
+
+// start resets the progress of the replication as the server starts
+// replicating on gaining leadership
+func (r *replicationTracker) start() {
+	r.l.Lock()
+	defer r.l.Unlock()
+	r.running = true
+	r.started = time.Now()
+	r.progress = make(map[string]*structs.ReplicationProgress, len(r.types))
+	r.conflicts = make(map[string][]*structs.ReplicationConflict, len(r.types))
+	for _, typ := range r.types {
+		r.progress[typ] = &structs.ReplicationProgress{}
+	}
+}
+
+// stop marks the replication as stopped as the server loses leadership
+func (r *replicationTracker) stop() {
+	r.l.Lock()
+	defer r.l.Unlock()
+	r.running = false
+}
+
+// success records that the objects of the type were replicated up to the
+// index of the authoritative region
+func (r *replicationTracker) success(typ string, index uint64, upserted, deleted int) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	if p, ok := r.progress[typ]; ok {
+		p.ReplicatedIndex = index
+		p.LastSuccess = time.Now().UnixNano()
+	}
+
+	metrics.SetGauge(r.metric(typ, "index"), float32(index))
+	if upserted > 0 {
+		metrics.IncrCounter(r.metric(typ, "upserted"), float32(upserted))
+	}
+	if deleted > 0 {
+		metrics.IncrCounter(r.metric(typ, "deleted"), float32(deleted))
+	}
+}
+
+// failure records that replicating the objects of the type failed
+func (r *replicationTracker) failure(typ string, err error) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	if p, ok := r.progress[typ]; ok {
+		p.LastError = time.Now().UnixNano()
+		p.LastErrorMessage = err.Error()
+	}
+
+	metrics.IncrCounter(r.metric(typ, "errors"), 1)
+}
+
+// setConflicts records the objects of the type that can't be made to match
+// the authoritative region, replacing the conflicts previously recorded
+func (r *replicationTracker) setConflicts(typ string, conflicts []*structs.ReplicationConflict) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	if _, ok := r.progress[typ]; !ok {
+		return
+	}
+	r.conflicts[typ] = conflicts
+
+	metrics.SetGauge(r.metric(typ, "conflicts"), float32(len(conflicts)))
+}
+
+// lag returns how long the replication of the objects has been failing to
+// keep up. Until the first success, the replication lags since it started.
+// The lock must be held.
+func (r *replicationTracker) lag(p *structs.ReplicationProgress, now time.Time) time.Duration {
+	if p.LastSuccess == 0 {
+		return now.Sub(r.started)
+	}
+	if p.LastError > p.LastSuccess {
+		return now.Sub(time.Unix(0, p.LastSuccess))
+	}
+	return 0
+}
+
+// status returns whether the replication is running, a copy of the progress
+// of the replication of each type and the conflicts sorted by type and name
+func (r *replicationTracker) status() (bool, map[string]*structs.ReplicationProgress, []*structs.ReplicationConflict) {
+	r.l.RLock()
+	defer r.l.RUnlock()
+
+	now := time.Now()
+	progress := make(map[string]*structs.ReplicationProgress, len(r.progress))
+	for typ, p := range r.progress {
+		p = p.Copy()
+		if r.running {
+			p.Lag = r.lag(p, now)
+		}
+		progress[typ] = p
+	}
+
+	var conflicts []*structs.ReplicationConflict
+	for _, typ := range r.types {
+		conflicts = append(conflicts, r.conflicts[typ]...)
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		if conflicts[i].Type != conflicts[j].Type {
+			return conflicts[i].Type < conflicts[j].Type
+		}
+		return conflicts[i].Name < conflicts[j].Name
+	})
+	return r.running, progress, conflicts
+}
+
+// EmitStats is used to export the replication lag while replicating
+func (r *replicationTracker) EmitStats(period time.Duration, stopCh chan struct{}) {
+	for {
+		select {
+		case <-time.After(period):
+			r.l.RLock()
+			if r.running {
+				now := time.Now()
+				for typ, p := range r.progress {
+					lag := r.lag(p, now)
+					metrics.SetGauge(r.metric(typ, "lag"), float32(lag/time.Millisecond))
+				}
+			}
+			r.l.RUnlock()
+
+		case <-stopCh:
+			return
+		}
+	}
+}
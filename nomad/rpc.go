@@ -13,6 +13,7 @@ import (
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/audit"
 	"github.com/hashicorp/nomad/nomad/state"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/nomad/watch"
@@ -37,6 +38,10 @@ const (
 	// if no time is specified. Previously we would wait the maxQueryTime.
 	defaultQueryTime = 300 * time.Second
 
+	// maxWatchItems bounds the number of items a blocking query watches.
+	// Queries looking up more objects watch their tables instead.
+	maxWatchItems = 64
+
 	// jitterFraction is a the limit to the amount of jitter we apply
 	// to a user specified MaxQueryTime. We divide the specified time by
 	// the fraction. So 16 == 6.25% limit of jitter. This jitter is also
@@ -117,12 +122,15 @@ func (s *Server) handleConn(conn net.Conn, isTLS bool) {
 		s.handleMultiplex(conn)
 
 	case rpcTLS:
-		if s.rpcTLS == nil {
+		s.tlsLock.RLock()
+		tlsConf := s.rpcTLS
+		s.tlsLock.RUnlock()
+		if tlsConf == nil {
 			s.logger.Printf("[WARN] nomad.rpc: TLS connection attempted, server not configured for TLS")
 			conn.Close()
 			return
 		}
-		conn = tls.Server(conn, s.rpcTLS)
+		conn = tls.Server(conn, tlsConf)
 		s.handleConn(conn, true)
 
 	default:
@@ -155,6 +163,9 @@ func (s *Server) handleMultiplex(conn net.Conn) {
 func (s *Server) handleNomadConn(conn net.Conn) {
 	defer conn.Close()
 	rpcCodec := NewServerCodec(conn)
+	if s.config.Auditor != nil {
+		rpcCodec = s.config.Auditor.ServerCodec(rpcCodec, conn.RemoteAddr().String(), s.auditTokenResolver())
+	}
 	for {
 		select {
 		case <-s.shutdownCh:
@@ -173,6 +184,17 @@ func (s *Server) handleNomadConn(conn net.Conn) {
 	}
 }
 
+// auditTokenResolver returns the resolver of the ACL tokens recorded by the
+// audit log, or nil when ACLs are disabled
+func (s *Server) auditTokenResolver() audit.TokenResolver {
+	if !s.config.ACLEnabled {
+		return nil
+	}
+	return func(secretID string) (*structs.ACLToken, error) {
+		return s.fsm.State().ACLTokenBySecretID(secretID)
+	}
+}
+
 // forward is used to forward to a remote region or to forward to the local leader
 // Returns a bool of if forwarding was performed, as well as any error
 func (s *Server) forward(method string, info structs.RPCInfo, args interface{}, reply interface{}) (bool, error) {
@@ -321,12 +343,14 @@ func (s *Server) setQueryMeta(m *structs.QueryMeta) {
 	}
 }
 
-// blockingOptions is used to parameterize blockingRPC
+// blockingOptions is used to parameterize blockingRPC. The run function
+// is given a snapshot of the state taken for that run, so that all the
+// lookups of a run share a single read transaction and are consistent.
 type blockingOptions struct {
 	queryOpts *structs.QueryOptions
 	queryMeta *structs.QueryMeta
 	watch     watch.Items
-	run       func() error
+	run       func(snap *state.StateSnapshot) error
 }
 
 // blockingRPC is used for queries that need to wait for a
@@ -334,7 +358,9 @@ type blockingOptions struct {
 func (s *Server) blockingRPC(opts *blockingOptions) error {
 	var timeout *time.Timer
 	var notifyCh chan struct{}
-	var state *state.StateStore
+	var store *state.StateStore
+	var snap *state.StateSnapshot
+	var err error
 
 	// Fast path non-blocking
 	if opts.queryOpts.MinQueryIndex == 0 {
@@ -354,28 +380,36 @@ func (s *Server) blockingRPC(opts *blockingOptions) error {
 	// Setup a query timeout
 	timeout = time.NewTimer(opts.queryOpts.MaxQueryTime)
 
-	// Setup the notify channel
+	// Setup the notify channel. A single channel is used however many items
+	// are watched, which are bounded so that popular queries looking up
+	// many objects don't subscribe to as many notify groups.
 	notifyCh = make(chan struct{}, 1)
+	opts.watch = opts.watch.Bound(maxWatchItems)
 
 	// Ensure we tear down any watchers on return
-	state = s.fsm.State()
+	store = s.fsm.State()
 	defer func() {
 		timeout.Stop()
-		state.StopWatch(opts.watch, notifyCh)
+		store.StopWatch(opts.watch, notifyCh)
 	}()
 
 REGISTER_NOTIFY:
 	// Register the notification channel. This may be done
 	// multiple times if we have not reached the target wait index.
-	state.Watch(opts.watch, notifyCh)
+	store.Watch(opts.watch, notifyCh)
 
 RUN_QUERY:
 	// Update the query meta data
 	s.setQueryMeta(opts.queryMeta)
 
-	// Run the query function
+	// Run the query function against a snapshot taken after registering
+	// the watch, so that no change is missed between the two
 	metrics.IncrCounter([]string{"nomad", "rpc", "query"}, 1)
-	err := opts.run()
+	snap, err = s.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	err = opts.run(snap)
 
 	// Check for minimum query time
 	if err == nil && opts.queryOpts.MinQueryIndex > 0 && opts.queryMeta.Index <= opts.queryOpts.MinQueryIndex {
@@ -0,0 +1,174 @@
+package nomad
+
+import (
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/watch"
+)
+
+// Scaling endpoint is used for listing the scaling policies of task groups,
+// which external autoscalers act on
+type Scaling struct {
+	srv *Server
+}
+
+// ListPolicies is used to list the scaling policies of the task groups of the
+// jobs in a namespace
+func (s *Scaling) ListPolicies(args *structs.ScalingPolicyListRequest,
+	reply *structs.ScalingPolicyListResponse) error {
+	if done, err := s.srv.forward("Scaling.ListPolicies", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "scaling", "list_policies"}, time.Now())
+
+	// Check for list-job permissions. Queries across all namespaces are
+	// filtered to the namespaces the token may list instead.
+	aclObj, err := s.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	namespace := args.RequestNamespace()
+	allNamespaces := namespace == structs.AllNamespacesSentinel
+	if !allNamespaces && aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityListJobs) {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "jobs"}),
+		run: func(snap *state.StateSnapshot) error {
+			var err error
+			var allowed map[string]bool
+			if allNamespaces {
+				allowed, err = allowedNamespaces(aclObj, snap, acl.NamespaceCapabilityListJobs)
+				if err != nil {
+					return err
+				}
+			}
+
+			var iter memdb.ResultIterator
+			if prefix := args.QueryOptions.Prefix; prefix != "" {
+				iter, err = snap.JobsByIDPrefix(namespace, prefix)
+			} else if allNamespaces {
+				iter, err = snap.Jobs()
+			} else {
+				iter, err = snap.JobsByNamespace(namespace)
+			}
+			if err != nil {
+				return err
+			}
+
+			var policies []*structs.ScalingPolicyListStub
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				job := raw.(*structs.Job)
+				if !namespaceMatches(job.Namespace, namespace, allowed) {
+					continue
+				}
+				for _, tg := range job.TaskGroups {
+					if tg.Scaling == nil {
+						continue
+					}
+					policies = append(policies, &structs.ScalingPolicyListStub{
+						Namespace:      job.Namespace,
+						JobID:          job.ID,
+						Group:          tg.Name,
+						Min:            tg.Scaling.Min,
+						Max:            tg.Scaling.Max,
+						Enabled:        tg.Scaling.Enabled,
+						JobModifyIndex: job.JobModifyIndex,
+					})
+				}
+			}
+			reply.Policies = policies
+
+			// Use the last index that affected the jobs table
+			index, err := snap.Index("jobs")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			s.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return s.srv.blockingRPC(&opts)
+}
+
+// GetPolicy is used to get the scaling policy of a task group
+func (s *Scaling) GetPolicy(args *structs.ScalingPolicySpecificRequest,
+	reply *structs.SingleScalingPolicyResponse) error {
+	if done, err := s.srv.forward("Scaling.GetPolicy", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "scaling", "get_policy"}, time.Now())
+
+	// Check for read-job permissions in the namespace of the job
+	aclObj, err := s.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	if aclObj != nil {
+		snap, err := s.srv.fsm.State().Snapshot()
+		if err != nil {
+			return err
+		}
+		namespace := args.RequestNamespace()
+		job, err := snap.JobByID(args.RequestNamespace(), args.JobID)
+		if err != nil {
+			return err
+		}
+		if job != nil {
+			namespace = job.Namespace
+		}
+		if !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityReadJob) {
+			return structs.ErrPermissionDenied
+		}
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Job: args.JobID}),
+		run: func(snap *state.StateSnapshot) error {
+			job, err := snap.JobByID(args.RequestNamespace(), args.JobID)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Policy = nil
+			reply.JobModifyIndex = 0
+			if job != nil {
+				if tg := job.LookupTaskGroup(args.Group); tg != nil {
+					reply.Policy = tg.Scaling
+				}
+				reply.JobModifyIndex = job.JobModifyIndex
+				reply.Index = job.ModifyIndex
+			} else {
+				// Use the last index that affected the jobs table
+				index, err := snap.Index("jobs")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			s.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return s.srv.blockingRPC(&opts)
+}
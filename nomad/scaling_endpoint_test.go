@@ -0,0 +1,98 @@
+package nomad
+
+import (
+	"testing"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestScalingEndpoint_ListPolicies(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create a job with a scaling policy and one without
+	state := s1.fsm.State()
+	job := mock.Job()
+	job.TaskGroups[0].Scaling = &structs.ScalingPolicy{
+		Min:     1,
+		Max:     20,
+		Enabled: true,
+	}
+	if err := state.UpsertJob(1000, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJob(1001, mock.Job()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	get := &structs.ScalingPolicyListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.ScalingPolicyListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Scaling.ListPolicies", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1001 {
+		t.Fatalf("Bad index: %d %d", resp.Index, 1001)
+	}
+	if len(resp.Policies) != 1 {
+		t.Fatalf("bad: %#v", resp.Policies)
+	}
+	policy := resp.Policies[0]
+	if policy.JobID != job.ID || policy.Group != "web" || policy.Min != 1 || policy.Max != 20 || !policy.Enabled {
+		t.Fatalf("bad: %#v", policy)
+	}
+	if policy.Namespace != structs.DefaultNamespace || policy.JobModifyIndex != 1000 {
+		t.Fatalf("bad: %#v", policy)
+	}
+}
+
+func TestScalingEndpoint_GetPolicy(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create a job with a scaling policy
+	state := s1.fsm.State()
+	job := mock.Job()
+	job.TaskGroups[0].Scaling = &structs.ScalingPolicy{
+		Min:     1,
+		Max:     20,
+		Policy:  map[string]interface{}{"cooldown": "1m"},
+		Enabled: true,
+	}
+	if err := state.UpsertJob(1000, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	get := &structs.ScalingPolicySpecificRequest{
+		JobID:        job.ID,
+		Group:        "web",
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.SingleScalingPolicyResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Scaling.GetPolicy", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 || resp.JobModifyIndex != 1000 {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.Policy == nil || resp.Policy.Max != 20 || resp.Policy.Policy["cooldown"] != "1m" {
+		t.Fatalf("bad: %#v", resp.Policy)
+	}
+
+	// Unknown task groups have no policy
+	get.Group = "foo"
+	if err := msgpackrpc.CallWithCodec(codec, "Scaling.GetPolicy", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Policy != nil {
+		t.Fatalf("bad: %#v", resp.Policy)
+	}
+}
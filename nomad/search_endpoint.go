@@ -0,0 +1,342 @@
+package nomad
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// Search endpoint is used to look up the objects matching a prefix or a text
+// across several contexts in a single round trip, so that short IDs can be
+// resolved and names completed
+type Search struct {
+	srv *Server
+}
+
+// PrefixSearch is used to list the IDs of the objects starting with a prefix
+func (s *Search) PrefixSearch(args *structs.SearchRequest,
+	reply *structs.SearchResponse) error {
+	if done, err := s.srv.forward("Search.PrefixSearch", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "search", "prefix_search"}, time.Now())
+
+	aclObj, err := s.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	namespace := args.RequestNamespace()
+	contexts, err := searchContexts(aclObj, namespace, args.Context)
+	if err != nil {
+		return err
+	}
+
+	snap, err := s.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	var allowed map[string]bool
+	if namespace == structs.AllNamespacesSentinel {
+		allowed, err = allowedNamespaces(aclObj, snap, acl.NamespaceCapabilityReadJob)
+		if err != nil {
+			return err
+		}
+	}
+
+	reply.Matches = make(map[structs.SearchContext][]string, len(contexts))
+	reply.Truncations = make(map[structs.SearchContext]bool, len(contexts))
+	uuidPrefix, uuidOK := roundUUIDPrefix(args.Prefix)
+	for _, context := range contexts {
+		ids := make([]string, 0)
+		if context != structs.SearchContextJobs && !uuidOK {
+			// The prefix can't match the UUID of the object
+			reply.Matches[context] = ids
+			continue
+		}
+
+		var iter memdb.ResultIterator
+		switch context {
+		case structs.SearchContextJobs:
+			iter, err = snap.JobsByIDPrefix(namespace, args.Prefix)
+		case structs.SearchContextAllocs:
+			iter, err = snap.AllocsByIDPrefix(uuidPrefix)
+		case structs.SearchContextNodes:
+			iter, err = snap.NodesByIDPrefix(uuidPrefix)
+		case structs.SearchContextEvals:
+			iter, err = snap.EvalsByIDPrefix(uuidPrefix)
+		}
+		if err != nil {
+			return err
+		}
+
+		for {
+			raw := iter.Next()
+			if raw == nil {
+				break
+			}
+
+			var id string
+			switch obj := raw.(type) {
+			case *structs.Job:
+				if !namespaceMatches(obj.Namespace, namespace, allowed) {
+					continue
+				}
+				id = obj.ID
+			case *structs.Allocation:
+				if !namespaceMatches(obj.Namespace, namespace, allowed) {
+					continue
+				}
+				id = obj.ID
+			case *structs.Evaluation:
+				if !namespaceMatches(obj.Namespace, namespace, allowed) {
+					continue
+				}
+				id = obj.ID
+			case *structs.Node:
+				id = obj.ID
+			}
+			if !strings.HasPrefix(id, args.Prefix) {
+				continue
+			}
+
+			if len(ids) == structs.MaxSearchResults {
+				reply.Truncations[context] = true
+				break
+			}
+			ids = append(ids, id)
+		}
+		reply.Matches[context] = ids
+	}
+
+	return s.setSearchIndex(snap, contexts, &reply.QueryMeta)
+}
+
+// FuzzySearch is used to list the jobs, allocations and nodes whose name or
+// ID contains a text, best matches first
+func (s *Search) FuzzySearch(args *structs.FuzzySearchRequest,
+	reply *structs.FuzzySearchResponse) error {
+	if done, err := s.srv.forward("Search.FuzzySearch", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "search", "fuzzy_search"}, time.Now())
+
+	if len(args.Text) < structs.MinFuzzySearchTextLength {
+		return fmt.Errorf("fuzzy search text must be at least %d characters", structs.MinFuzzySearchTextLength)
+	}
+
+	aclObj, err := s.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	namespace := args.RequestNamespace()
+	contexts, err := searchContexts(aclObj, namespace, args.Context)
+	if err != nil {
+		return err
+	}
+
+	snap, err := s.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	allNamespaces := namespace == structs.AllNamespacesSentinel
+	var allowed map[string]bool
+	if allNamespaces {
+		allowed, err = allowedNamespaces(aclObj, snap, acl.NamespaceCapabilityReadJob)
+		if err != nil {
+			return err
+		}
+	}
+
+	text := strings.ToLower(args.Text)
+	reply.Matches = make(map[structs.SearchContext][]structs.FuzzyMatch, len(contexts))
+	reply.Truncations = make(map[structs.SearchContext]bool, len(contexts))
+	for _, context := range contexts {
+		var iter memdb.ResultIterator
+		switch context {
+		case structs.SearchContextJobs:
+			if allNamespaces {
+				iter, err = snap.Jobs()
+			} else {
+				iter, err = snap.JobsByNamespace(namespace)
+			}
+		case structs.SearchContextAllocs:
+			if allNamespaces {
+				iter, err = snap.Allocs()
+			} else {
+				iter, err = snap.AllocsByNamespace(namespace)
+			}
+		case structs.SearchContextNodes:
+			iter, err = snap.Nodes()
+		default:
+			// Evaluations have no name to match
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var matches fuzzyMatches
+		for {
+			raw := iter.Next()
+			if raw == nil {
+				break
+			}
+
+			var name, id string
+			var scope []string
+			switch obj := raw.(type) {
+			case *structs.Job:
+				if !namespaceMatches(obj.Namespace, namespace, allowed) {
+					continue
+				}
+				name, id, scope = obj.Name, obj.ID, []string{obj.Namespace, obj.ID}
+			case *structs.Allocation:
+				if !namespaceMatches(obj.Namespace, namespace, allowed) {
+					continue
+				}
+				name, id, scope = obj.Name, obj.ID, []string{obj.Namespace, obj.ID}
+			case *structs.Node:
+				name, id, scope = obj.Name, obj.ID, []string{obj.ID}
+			}
+
+			if score, ok := fuzzyScore(text, name, id); ok {
+				matches = append(matches, fuzzyMatch{
+					FuzzyMatch: structs.FuzzyMatch{ID: name, Scope: scope},
+					score:      score,
+				})
+			}
+		}
+
+		sort.Sort(matches)
+		if len(matches) > structs.MaxSearchResults {
+			matches = matches[:structs.MaxSearchResults]
+			reply.Truncations[context] = true
+		}
+		reply.Matches[context] = make([]structs.FuzzyMatch, 0, len(matches))
+		for _, m := range matches {
+			reply.Matches[context] = append(reply.Matches[context], m.FuzzyMatch)
+		}
+	}
+
+	return s.setSearchIndex(snap, contexts, &reply.QueryMeta)
+}
+
+// setSearchIndex sets the query meta of a search, whose index is the last
+// index that affected the tables of the searched contexts
+func (s *Search) setSearchIndex(snap *state.StateSnapshot, contexts []structs.SearchContext,
+	meta *structs.QueryMeta) error {
+	for _, context := range contexts {
+		// The contexts are named after their tables
+		index, err := snap.Index(string(context))
+		if err != nil {
+			return err
+		}
+		if index > meta.Index {
+			meta.Index = index
+		}
+	}
+	s.srv.setQueryMeta(meta)
+	return nil
+}
+
+// roundUUIDPrefix returns the prefix to look up in the UUID indexes, which
+// only accept an even number of hex characters, and whether the prefix may
+// match a UUID at all. Odd prefixes are rounded down, so the matches must be
+// filtered with the original prefix.
+func roundUUIDPrefix(prefix string) (string, bool) {
+	if len(prefix) > 36 {
+		return "", false
+	}
+	for _, c := range prefix {
+		if c != '-' && !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return "", false
+		}
+	}
+
+	prefix = strings.TrimRight(prefix, "-")
+	if len(strings.Replace(prefix, "-", "", -1))%2 != 0 {
+		prefix = strings.TrimRight(prefix[:len(prefix)-1], "-")
+	}
+	return prefix, true
+}
+
+// searchContexts returns the contexts a search looks in. Searching all the
+// contexts skips those the token isn't permitted to read, while searching a
+// context it isn't permitted to read is denied.
+func searchContexts(aclObj *acl.ACL, namespace string, context structs.SearchContext) ([]structs.SearchContext, error) {
+	permitted := func(c structs.SearchContext) bool {
+		switch {
+		case aclObj == nil:
+			return true
+		case c == structs.SearchContextNodes:
+			return aclObj.AllowNodeRead()
+		case namespace == structs.AllNamespacesSentinel:
+			// Filtered to the namespaces the token may read
+			return true
+		default:
+			return aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityReadJob)
+		}
+	}
+
+	switch context {
+	case "", structs.SearchContextAll:
+		var contexts []structs.SearchContext
+		for _, c := range structs.SearchContexts {
+			if permitted(c) {
+				contexts = append(contexts, c)
+			}
+		}
+		return contexts, nil
+	case structs.SearchContextJobs, structs.SearchContextAllocs,
+		structs.SearchContextNodes, structs.SearchContextEvals:
+		if !permitted(context) {
+			return nil, structs.ErrPermissionDenied
+		}
+		return []structs.SearchContext{context}, nil
+	default:
+		return nil, fmt.Errorf("unknown search context %q", context)
+	}
+}
+
+// fuzzyScore returns whether the name or the ID of an object contains the
+// lower case text, and the score of the match, lower being better. Matches
+// at the start of the name rank first, then matches further in the name and
+// finally matches of the ID only.
+func fuzzyScore(text, name, id string) (int, bool) {
+	if i := strings.Index(strings.ToLower(name), text); i >= 0 {
+		return i, true
+	}
+	if strings.Contains(strings.ToLower(id), text) {
+		return math.MaxInt32, true
+	}
+	return 0, false
+}
+
+// fuzzyMatch is a match of a fuzzy search with its score
+type fuzzyMatch struct {
+	structs.FuzzyMatch
+	score int
+}
+
+// fuzzyMatches sorts the matches of a fuzzy search by score, then by name
+type fuzzyMatches []fuzzyMatch
+
+func (f fuzzyMatches) Len() int      { return len(f) }
+func (f fuzzyMatches) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f fuzzyMatches) Less(i, j int) bool {
+	if f[i].score != f[j].score {
+		return f[i].score < f[j].score
+	}
+	if f[i].ID != f[j].ID {
+		return f[i].ID < f[j].ID
+	}
+	return strings.Join(f[i].Scope, "/") < strings.Join(f[j].Scope, "/")
+}
@@ -0,0 +1,231 @@
+package nomad
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestSearch_PrefixSearch(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create objects sharing a prefix
+	state := s1.fsm.State()
+	job := mock.Job()
+	job.ID = "aaaa-job"
+	if err := state.UpsertJob(1000, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	eval := mock.Eval()
+	eval.ID = "aaaaaaaa-0000-0000-0000-000000000000"
+	if err := state.UpsertEvals(1001, []*structs.Evaluation{eval}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	alloc := mock.Alloc()
+	alloc.ID = "bbbbbbbb-0000-0000-0000-000000000000"
+	if err := state.UpsertJobSummary(1002, mock.JobSummary(alloc.JobID)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertAllocs(1003, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Search all the contexts
+	req := &structs.SearchRequest{
+		Prefix:       "aaaa",
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.SearchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Search.PrefixSearch", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Matches) != len(structs.SearchContexts) {
+		t.Fatalf("bad: %#v", resp.Matches)
+	}
+	if !reflect.DeepEqual(resp.Matches[structs.SearchContextJobs], []string{"aaaa-job"}) {
+		t.Fatalf("bad: %#v", resp.Matches)
+	}
+	if !reflect.DeepEqual(resp.Matches[structs.SearchContextEvals], []string{eval.ID}) {
+		t.Fatalf("bad: %#v", resp.Matches)
+	}
+	if len(resp.Matches[structs.SearchContextAllocs]) != 0 || len(resp.Matches[structs.SearchContextNodes]) != 0 {
+		t.Fatalf("bad: %#v", resp.Matches)
+	}
+	if resp.Index != 1003 {
+		t.Fatalf("bad: %d", resp.Index)
+	}
+
+	// Search a single context
+	req.Prefix = "bbbb"
+	req.Context = structs.SearchContextAllocs
+	var resp2 structs.SearchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Search.PrefixSearch", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := map[structs.SearchContext][]string{
+		structs.SearchContextAllocs: {alloc.ID},
+	}
+	if !reflect.DeepEqual(resp2.Matches, expected) {
+		t.Fatalf("bad: %#v", resp2.Matches)
+	}
+
+	// Unknown contexts are rejected
+	req.Context = "foo"
+	var resp3 structs.SearchResponse
+	err := msgpackrpc.CallWithCodec(codec, "Search.PrefixSearch", req, &resp3)
+	if err == nil || !strings.Contains(err.Error(), "unknown search context") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestSearch_PrefixSearch_Truncate(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create more jobs than returned
+	state := s1.fsm.State()
+	for i := 0; i < structs.MaxSearchResults+5; i++ {
+		if err := state.UpsertJob(uint64(1000+i), mock.Job()); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	req := &structs.SearchRequest{
+		Context:      structs.SearchContextJobs,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.SearchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Search.PrefixSearch", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Matches[structs.SearchContextJobs]) != structs.MaxSearchResults {
+		t.Fatalf("bad: %#v", resp.Matches)
+	}
+	if !resp.Truncations[structs.SearchContextJobs] {
+		t.Fatalf("bad: %#v", resp.Truncations)
+	}
+}
+
+func TestSearch_FuzzySearch(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create jobs whose names contain the text at different positions
+	state := s1.fsm.State()
+	for i, name := range []string{"my-web", "web-frontend", "cache"} {
+		job := mock.Job()
+		job.Name = name
+		if err := state.UpsertJob(uint64(1000+i), job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	node := mock.Node()
+	node.Name = "WEB-node"
+	if err := state.UpsertNode(1003, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.FuzzySearchRequest{
+		Text:         "web",
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.FuzzySearchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Search.FuzzySearch", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Matches at the start of the name rank first
+	jobs := resp.Matches[structs.SearchContextJobs]
+	if len(jobs) != 2 || jobs[0].ID != "web-frontend" || jobs[1].ID != "my-web" {
+		t.Fatalf("bad: %#v", jobs)
+	}
+	if jobs[0].Scope[0] != structs.DefaultNamespace {
+		t.Fatalf("bad: %#v", jobs[0])
+	}
+	nodes := resp.Matches[structs.SearchContextNodes]
+	if len(nodes) != 1 || !reflect.DeepEqual(nodes[0], structs.FuzzyMatch{ID: "WEB-node", Scope: []string{node.ID}}) {
+		t.Fatalf("bad: %#v", nodes)
+	}
+
+	// The text must be long enough
+	req.Text = "w"
+	var resp2 structs.FuzzySearchResponse
+	err := msgpackrpc.CallWithCodec(codec, "Search.FuzzySearch", req, &resp2)
+	if err == nil || !strings.Contains(err.Error(), "at least") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestSearch_PrefixSearch_ACL(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	state := s1.fsm.State()
+	job := mock.Job()
+	if err := state.UpsertJob(1000, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertNode(1001, mock.Node()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A token that may only read jobs doesn't see the nodes
+	policy := mock.ACLPolicy()
+	policy.Rules = `namespace "default" { policy = "read" }`
+	policy.SetHash()
+	token := mock.ACLToken()
+	token.Policies = []string{policy.Name}
+	if err := state.UpsertACLPolicies(1002, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertACLTokens(1003, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.SearchRequest{
+		QueryOptions: structs.QueryOptions{Region: "global", AuthToken: token.SecretID},
+	}
+	var resp structs.SearchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Search.PrefixSearch", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := resp.Matches[structs.SearchContextNodes]; ok {
+		t.Fatalf("bad: %#v", resp.Matches)
+	}
+	if !reflect.DeepEqual(resp.Matches[structs.SearchContextJobs], []string{job.ID}) {
+		t.Fatalf("bad: %#v", resp.Matches)
+	}
+
+	// Searching the nodes explicitly is denied
+	req.Context = structs.SearchContextNodes
+	var resp2 structs.SearchResponse
+	err := msgpackrpc.CallWithCodec(codec, "Search.PrefixSearch", req, &resp2)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// The management token sees everything
+	req.Context = structs.SearchContextAll
+	req.AuthToken = root.SecretID
+	var resp3 structs.SearchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Search.PrefixSearch", req, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp3.Matches[structs.SearchContextNodes]) != 1 {
+		t.Fatalf("bad: %#v", resp3.Matches)
+	}
+}
@@ -1,6 +1,7 @@
 package nomad
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -23,6 +24,7 @@ import (
 	"github.com/hashicorp/nomad/command/agent/consul"
 	"github.com/hashicorp/nomad/nomad/state"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/hashicorp/raft"
 	"github.com/hashicorp/raft-boltdb"
 	"github.com/hashicorp/serf/serf"
@@ -94,8 +96,10 @@ type Server struct {
 	rpcServer    *rpc.Server
 	rpcAdvertise net.Addr
 
-	// rpcTLS is the TLS config for incoming TLS requests
-	rpcTLS *tls.Config
+	// rpcTLS is the TLS config for incoming TLS requests. It is swapped
+	// when the certificates are reloaded.
+	rpcTLS  *tls.Config
+	tlsLock sync.RWMutex
 
 	// peers is used to track the known Nomad servers. This is
 	// used for region forwarding and clustering.
@@ -136,15 +140,55 @@ type Server struct {
 	heartbeatTimers     map[string]*time.Timer
 	heartbeatTimersLock sync.Mutex
 
+	// heartbeatDeadlines are the expiration times of the heartbeats. They
+	// carry a monotonic clock reading, so a heartbeat is only expired once
+	// its TTL has elapsed regardless of jumps of the wall clock.
+	heartbeatDeadlines map[string]time.Time
+
+	// clockSkewed is the set of nodes whose clock was last found to be
+	// skewed from the servers' clock beyond ClockSkewThreshold
+	clockSkewed     map[string]struct{}
+	clockSkewedLock sync.Mutex
+
+	// nodeReliability collects the failures seen on the nodes while leader
+	nodeReliability *nodeReliabilityTracker
+
 	// consulSyncer advertises this Nomad Agent with Consul
 	consulSyncer *consul.Syncer
 
-	// vault is the client for communicating with Vault.
+	// vault is the client for communicating with the default Vault cluster.
 	vault VaultClient
 
+	// vaultClusters are the clients of the additional, named Vault clusters
+	vaultClusters map[string]VaultClient
+
 	// Worker used for processing
 	workers []*Worker
 
+	// aclCache is used to maintain the parsed ACL objects
+	aclCache *aclCache
+
+	// aclReplication tracks the replication of ACL policies and tokens from
+	// the authoritative region
+	aclReplication *replicationTracker
+
+	// namespaceReplication tracks the replication of namespaces and quota
+	// specifications from the authoritative region
+	namespaceReplication *replicationTracker
+
+	// identitySigner caches the parsed key workload identities are signed
+	// with by the plan applier
+	identitySigner     *identitySigner
+	identitySignerLock sync.Mutex
+
+	// rootKeyLock serializes the generation of the root key of the keyring
+	rootKeyLock sync.Mutex
+
+	// clusterHealth is the health of the servers of the region, as last
+	// updated by autopilot while the server is the leader
+	clusterHealth     structs.OperatorHealthReply
+	clusterHealthLock sync.RWMutex
+
 	left         bool
 	shutdown     bool
 	shutdownCh   chan struct{}
@@ -153,15 +197,30 @@ type Server struct {
 
 // Holds the RPC endpoints
 type endpoints struct {
-	Status   *Status
-	Node     *Node
-	Job      *Job
-	Eval     *Eval
-	Plan     *Plan
-	Alloc    *Alloc
-	Region   *Region
-	Periodic *Periodic
-	System   *System
+	Status    *Status
+	Node      *Node
+	Job       *Job
+	Eval      *Eval
+	Plan      *Plan
+	Alloc     *Alloc
+	Region    *Region
+	Periodic  *Periodic
+	System    *System
+	Namespace *Namespace
+	Quota     *Quota
+	ACL       *ACL
+
+	WorkloadIdentity    *WorkloadIdentity
+	ServiceRegistration *ServiceRegistration
+	Variables           *Variables
+	Keyring             *Keyring
+	Scaling             *Scaling
+	Event               *Event
+	Operator            *Operator
+	Maintenance         *Maintenance
+	Search              *Search
+	CSIVolume           *CSIVolume
+	CSIPlugin           *CSIPlugin
 }
 
 // NewServer is used to construct a new Nomad server from the
@@ -171,6 +230,22 @@ func NewServer(config *Config, consulSyncer *consul.Syncer, logger *log.Logger)
 	if err := config.CheckVersion(); err != nil {
 		return nil, err
 	}
+	if err := config.CheckSchedulerMapping(); err != nil {
+		return nil, err
+	}
+	if err := config.CheckKeyringEncryptionKey(); err != nil {
+		return nil, err
+	}
+
+	// Dev mode servers run alone and keep their state in memory, so they can
+	// wrap the root keys with a key of their own
+	if config.DevMode && len(config.KeyringEncryptionKey) == 0 {
+		kek, err := generateKeyBytes()
+		if err != nil {
+			return nil, err
+		}
+		config.KeyringEncryptionKey = kek
+	}
 
 	// Create an eval broker
 	evalBroker, err := NewEvalBroker(config.EvalNackTimeout, config.EvalDeliveryLimit)
@@ -187,23 +262,49 @@ func NewServer(config *Config, consulSyncer *consul.Syncer, logger *log.Logger)
 		return nil, err
 	}
 
+	// Create the ACL object cache
+	aclCache, err := newACLCache(aclCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Configure TLS for RPC
+	tlsWrap, err := NewTLSWrapper(config.TLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to setup TLS: %v", err)
+	}
+	incomingTLS, err := newIncomingTLSConfig(config.TLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to setup TLS: %v", err)
+	}
+
 	// Create the server
 	s := &Server{
-		config:       config,
-		consulSyncer: consulSyncer,
-		connPool:     NewPool(config.LogOutput, serverRPCCache, serverMaxStreams, nil),
-		logger:       logger,
-		rpcServer:    rpc.NewServer(),
-		peers:        make(map[string][]*serverParts),
-		localPeers:   make(map[string]*serverParts),
-		reconcileCh:  make(chan serf.Member, 32),
-		eventCh:      make(chan serf.Event, 256),
-		evalBroker:   evalBroker,
-		blockedEvals: blockedEvals,
-		planQueue:    planQueue,
-		shutdownCh:   make(chan struct{}),
+		config:          config,
+		consulSyncer:    consulSyncer,
+		connPool:        NewPool(config.LogOutput, serverRPCCache, serverMaxStreams, tlsWrap),
+		rpcTLS:          incomingTLS,
+		logger:          logger,
+		rpcServer:       rpc.NewServer(),
+		peers:           make(map[string][]*serverParts),
+		localPeers:      make(map[string]*serverParts),
+		reconcileCh:     make(chan serf.Member, 32),
+		eventCh:         make(chan serf.Event, 256),
+		evalBroker:      evalBroker,
+		blockedEvals:    blockedEvals,
+		planQueue:       planQueue,
+		aclCache:        aclCache,
+		nodeReliability: newNodeReliabilityTracker(),
+		shutdownCh:      make(chan struct{}),
 	}
 
+	// Track the replication of ACLs and tenancy configuration from the
+	// authoritative region
+	s.aclReplication = newReplicationTracker([]string{"nomad", "acl", "replication"},
+		aclReplicationPolicies, aclReplicationTokens)
+	s.namespaceReplication = newReplicationTracker([]string{"nomad", "replication"},
+		namespaceReplicationNamespaces, namespaceReplicationQuotas)
+
 	// Create the periodic dispatcher for launching periodic jobs.
 	s.periodicDispatcher = NewPeriodicDispatch(s.logger, s)
 
@@ -215,8 +316,7 @@ func NewServer(config *Config, consulSyncer *consul.Syncer, logger *log.Logger)
 	}
 
 	// Initialize the RPC layer
-	// TODO: TLS...
-	if err := s.setupRPC(nil); err != nil {
+	if err := s.setupRPC(tlsWrap); err != nil {
 		s.Shutdown()
 		s.logger.Printf("[ERR] nomad: failed to start RPC layer: %s", err)
 		return nil, fmt.Errorf("Failed to start RPC layer: %v", err)
@@ -267,6 +367,10 @@ func NewServer(config *Config, consulSyncer *consul.Syncer, logger *log.Logger)
 	// Emit metrics for the blocked eval tracker.
 	go blockedEvals.EmitStats(time.Second, s.shutdownCh)
 
+	// Emit metrics for the replication from the authoritative region
+	go s.aclReplication.EmitStats(time.Second, s.shutdownCh)
+	go s.namespaceReplication.EmitStats(time.Second, s.shutdownCh)
+
 	// Emit metrics
 	go s.heartbeatStats()
 
@@ -320,6 +424,9 @@ func (s *Server) Shutdown() error {
 	if s.vault != nil {
 		s.vault.Stop()
 	}
+	for _, v := range s.vaultClusters {
+		v.Stop()
+	}
 
 	return nil
 }
@@ -334,6 +441,51 @@ func (s *Server) IsShutdown() bool {
 	}
 }
 
+// ReloadTLS reloads the certificates used for RPC and Raft from the given
+// TLS configuration. Established connections are closed so that new ones
+// use the new certificates. Enabling or disabling TLS requires a restart.
+func (s *Server) ReloadTLS(newConf *config.TLSConfig) error {
+	s.tlsLock.Lock()
+	defer s.tlsLock.Unlock()
+
+	if rpcTLSEnabled(newConf) != rpcTLSEnabled(s.config.TLSConfig) {
+		return fmt.Errorf("enabling or disabling TLS for RPC requires a restart")
+	}
+	if !rpcTLSEnabled(newConf) {
+		s.config.TLSConfig = newConf
+		return nil
+	}
+
+	tlsWrap, err := NewTLSWrapper(newConf)
+	if err != nil {
+		return err
+	}
+	incomingTLS, err := newIncomingTLSConfig(newConf)
+	if err != nil {
+		return err
+	}
+
+	s.config.TLSConfig = newConf
+	s.rpcTLS = incomingTLS
+	s.connPool.ReloadTLS(tlsWrap)
+	s.raftLayer.ReloadTLS(tlsutil.SpecificDC(s.config.Region, tlsWrap))
+	s.logger.Printf("[INFO] nomad: reloaded TLS certificates")
+	return nil
+}
+
+// ReloadACL enables or disables the enforcement of ACLs and updates the token
+// used to replicate them, without restarting the server. The replication of
+// ACLs from the authoritative region is only started or stopped at the next
+// leader election.
+func (s *Server) ReloadACL(enabled bool, replicationToken string) {
+	if enabled != s.config.ACLEnabled && s.config.Region != s.config.AuthoritativeRegion && s.IsLeader() {
+		s.logger.Printf("[WARN] nomad: ACL replication is updated at the next leader election")
+	}
+	s.config.ACLEnabled = enabled
+	s.config.ReplicationToken = replicationToken
+	s.logger.Printf("[INFO] nomad: reloaded ACL configuration (enabled: %v)", enabled)
+}
+
 // Leave is used to prepare for a graceful shutdown of the server
 func (s *Server) Leave() error {
 	s.logger.Printf("[INFO] nomad: server starting leave")
@@ -565,16 +717,85 @@ func (s *Server) setupConsulSyncer() error {
 	return nil
 }
 
-// setupVaultClient is used to set up the Vault API client.
+// setupVaultClient is used to set up the Vault API clients of the default
+// cluster and of the named ones.
 func (s *Server) setupVaultClient() error {
 	v, err := NewVaultClient(s.config.VaultConfig, s.logger, s.purgeVaultAccessors)
 	if err != nil {
 		return err
 	}
 	s.vault = v
+
+	s.vaultClusters = make(map[string]VaultClient, len(s.config.VaultClusters))
+	for name, conf := range s.config.VaultClusters {
+		v, err := NewVaultClient(conf, s.logger, s.purgeVaultAccessors)
+		if err != nil {
+			return fmt.Errorf("Vault cluster %q: %v", name, err)
+		}
+		s.vaultClusters[name] = v
+	}
 	return nil
 }
 
+// vaultClient returns the client of the named Vault cluster. The default
+// cluster is returned for the empty name.
+func (s *Server) vaultClient(cluster string) (VaultClient, error) {
+	if cluster == "" || cluster == structs.DefaultCluster {
+		return s.vault, nil
+	}
+	v, ok := s.vaultClusters[cluster]
+	if !ok {
+		return nil, fmt.Errorf("Vault cluster %q not configured", cluster)
+	}
+	return v, nil
+}
+
+// vaultConfig returns the configuration of the named Vault cluster. The
+// default cluster is returned for the empty name.
+func (s *Server) vaultConfig(cluster string) (*config.VaultConfig, error) {
+	if cluster == "" || cluster == structs.DefaultCluster {
+		return s.config.VaultConfig, nil
+	}
+	conf, ok := s.config.VaultClusters[cluster]
+	if !ok {
+		return nil, fmt.Errorf("Vault cluster %q not configured", cluster)
+	}
+	return conf, nil
+}
+
+// setVaultActive activates or de-activates the clients of every Vault
+// cluster
+func (s *Server) setVaultActive(active bool) {
+	s.vault.SetActive(active)
+	for _, v := range s.vaultClusters {
+		v.SetActive(active)
+	}
+}
+
+// revokeVaultTokens revokes the tokens of the accessors in the Vault cluster
+// each was created in
+func (s *Server) revokeVaultTokens(ctx context.Context, accessors []*structs.VaultAccessor, committed bool) error {
+	byCluster := make(map[string][]*structs.VaultAccessor)
+	for _, va := range accessors {
+		byCluster[va.Cluster] = append(byCluster[va.Cluster], va)
+	}
+
+	var mErr multierror.Error
+	for cluster, clusterAccessors := range byCluster {
+		v, err := s.vaultClient(cluster)
+		if err != nil {
+			// The cluster was removed from the configuration, so its tokens
+			// can't be revoked anymore
+			s.logger.Printf("[WARN] nomad: not revoking %d Vault tokens: %v", len(clusterAccessors), err)
+			continue
+		}
+		if err := v.RevokeTokens(ctx, clusterAccessors, committed); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
 // setupRPC is used to setup the RPC listener
 func (s *Server) setupRPC(tlsWrap tlsutil.DCWrapper) error {
 	// Create endpoints
@@ -587,6 +808,20 @@ func (s *Server) setupRPC(tlsWrap tlsutil.DCWrapper) error {
 	s.endpoints.Region = &Region{s}
 	s.endpoints.Periodic = &Periodic{s}
 	s.endpoints.System = &System{s}
+	s.endpoints.Namespace = &Namespace{s}
+	s.endpoints.Quota = &Quota{s}
+	s.endpoints.ACL = &ACL{s}
+	s.endpoints.WorkloadIdentity = &WorkloadIdentity{s}
+	s.endpoints.ServiceRegistration = &ServiceRegistration{s}
+	s.endpoints.Variables = &Variables{s}
+	s.endpoints.Keyring = &Keyring{s}
+	s.endpoints.Scaling = &Scaling{s}
+	s.endpoints.Event = &Event{s}
+	s.endpoints.Operator = &Operator{s}
+	s.endpoints.Maintenance = &Maintenance{s}
+	s.endpoints.Search = &Search{s}
+	s.endpoints.CSIVolume = &CSIVolume{s}
+	s.endpoints.CSIPlugin = &CSIPlugin{s}
 
 	// Register the handlers
 	s.rpcServer.Register(s.endpoints.Status)
@@ -598,6 +833,20 @@ func (s *Server) setupRPC(tlsWrap tlsutil.DCWrapper) error {
 	s.rpcServer.Register(s.endpoints.Region)
 	s.rpcServer.Register(s.endpoints.Periodic)
 	s.rpcServer.Register(s.endpoints.System)
+	s.rpcServer.Register(s.endpoints.Namespace)
+	s.rpcServer.Register(s.endpoints.Quota)
+	s.rpcServer.Register(s.endpoints.ACL)
+	s.rpcServer.Register(s.endpoints.WorkloadIdentity)
+	s.rpcServer.Register(s.endpoints.ServiceRegistration)
+	s.rpcServer.Register(s.endpoints.Variables)
+	s.rpcServer.Register(s.endpoints.Keyring)
+	s.rpcServer.Register(s.endpoints.Scaling)
+	s.rpcServer.Register(s.endpoints.Event)
+	s.rpcServer.Register(s.endpoints.Operator)
+	s.rpcServer.Register(s.endpoints.Maintenance)
+	s.rpcServer.Register(s.endpoints.Search)
+	s.rpcServer.Register(s.endpoints.CSIVolume)
+	s.rpcServer.Register(s.endpoints.CSIPlugin)
 
 	list, err := net.ListenTCP("tcp", s.config.RPCAddr)
 	if err != nil {
@@ -622,11 +871,10 @@ func (s *Server) setupRPC(tlsWrap tlsutil.DCWrapper) error {
 		return fmt.Errorf("RPC advertise address is not advertisable: %v", addr)
 	}
 
-	// Provide a DC specific wrapper. Raft replication is only
-	// ever done in the same datacenter, so we can provide it as a constant.
-	// wrapper := tlsutil.SpecificDC(s.config.Datacenter, tlsWrap)
-	// TODO: TLS...
-	s.raftLayer = NewRaftLayer(s.rpcAdvertise, nil)
+	// Provide a region specific wrapper. Raft replication is only
+	// ever done in the same region, so we can provide it as a constant.
+	wrapper := tlsutil.SpecificDC(s.config.Region, tlsWrap)
+	s.raftLayer = NewRaftLayer(s.rpcAdvertise, wrapper)
 	return nil
 }
 
@@ -639,10 +887,12 @@ func (s *Server) setupRaft() error {
 
 	// Create the FSM
 	var err error
-	s.fsm, err = NewFSM(s.evalBroker, s.periodicDispatcher, s.blockedEvals, s.config.LogOutput)
+	s.fsm, err = NewFSM(s.evalBroker, s.periodicDispatcher, s.blockedEvals,
+		s.config.EventBufferSize, s.config.LogOutput)
 	if err != nil {
 		return err
 	}
+	s.fsm.schedulerMapping = s.config.SchedulerMapping
 
 	// Create a transport layer
 	trans := raft.NewNetworkTransport(s.raftLayer, 3, s.config.RaftTimeout,
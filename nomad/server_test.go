@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/hashicorp/nomad/command/agent/consul"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/testutil"
 )
 
@@ -30,6 +32,22 @@ func tmpDir(t *testing.T) string {
 	return dir
 }
 
+// testACLServer returns a server with ACLs enabled along with a management
+// token that can be used to authenticate requests against it
+func testACLServer(t *testing.T, cb func(*Config)) (*Server, *structs.ACLToken) {
+	server := testServer(t, func(c *Config) {
+		c.ACLEnabled = true
+		if cb != nil {
+			cb(c)
+		}
+	})
+	token := mock.ACLManagementToken()
+	if err := server.State().BootstrapACLTokens(1, 0, token); err != nil {
+		t.Fatalf("failed to bootstrap ACL token: %v", err)
+	}
+	return server, token
+}
+
 func testServer(t *testing.T, cb func(*Config)) *Server {
 	// Setup the default settings
 	config := DefaultConfig()
@@ -135,3 +153,29 @@ func TestServer_Regions(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	})
 }
+
+func TestServer_ReloadACL(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Tokens aren't resolved while ACLs are disabled
+	secret := structs.GenerateUUID()
+	if aclObj, err := s1.ResolveToken(secret); err != nil || aclObj != nil {
+		t.Fatalf("bad: %v %v", aclObj, err)
+	}
+
+	// Enabling ACLs rejects unknown tokens
+	s1.ReloadACL(true, "bar")
+	if s1.config.ReplicationToken != "bar" {
+		t.Fatalf("bad: %q", s1.config.ReplicationToken)
+	}
+	if _, err := s1.ResolveToken(secret); err != structs.ErrTokenNotFound {
+		t.Fatalf("expected token not found, got: %v", err)
+	}
+
+	s1.ReloadACL(false, "")
+	if aclObj, err := s1.ResolveToken(secret); err != nil || aclObj != nil {
+		t.Fatalf("bad: %v %v", aclObj, err)
+	}
+}
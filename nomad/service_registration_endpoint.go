@@ -0,0 +1,329 @@
+package nomad
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/watch"
+)
+
+// ServiceRegistration endpoint is used for registering and querying the
+// services in Nomad's built-in service catalog
+type ServiceRegistration struct {
+	srv *Server
+}
+
+// Upsert is used by clients to upsert the service registrations of the tasks
+// they run
+func (s *ServiceRegistration) Upsert(args *structs.ServiceRegistrationUpsertRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := s.srv.forward("ServiceRegistration.Upsert", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "service_registration", "upsert"}, time.Now())
+
+	if len(args.Services) == 0 {
+		return fmt.Errorf("must specify at least one service registration")
+	}
+
+	snap, err := s.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	if err := validateNodeSecret(snap, args.NodeID, args.SecretID); err != nil {
+		return err
+	}
+
+	// Ensure the services are registered for running allocations of the node
+	for _, service := range args.Services {
+		if service.ID == "" || service.ServiceName == "" {
+			return fmt.Errorf("service registration missing ID or service name")
+		}
+		alloc, err := snap.AllocByID(service.AllocID)
+		if err != nil {
+			return err
+		}
+		if alloc == nil {
+			return fmt.Errorf("Allocation %q does not exist", service.AllocID)
+		}
+		if alloc.NodeID != args.NodeID {
+			return fmt.Errorf("Allocation %q not running on Node %q", service.AllocID, args.NodeID)
+		}
+		if alloc.TerminalStatus() {
+			return fmt.Errorf("Can't register services of terminal allocation %q", service.AllocID)
+		}
+		if existing, err := snap.ServiceRegistrationByID(service.ID); err != nil {
+			return err
+		} else if existing != nil && existing.AllocID != service.AllocID {
+			return fmt.Errorf("Service registration %q belongs to another allocation", service.ID)
+		}
+
+		// The server is the authority on where the allocation runs
+		service.NodeID = alloc.NodeID
+		service.Namespace = alloc.Namespace
+		service.JobID = alloc.JobID
+	}
+
+	// Update via Raft
+	_, index, err := s.srv.raftApply(structs.ServiceRegistrationUpsertRequestType, args)
+	if err != nil {
+		s.srv.logger.Printf("[ERR] nomad.service_registration: Upsert failed: %v", err)
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// Delete is used by clients to delete the service registrations of the tasks
+// they run
+func (s *ServiceRegistration) Delete(args *structs.ServiceRegistrationDeleteRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := s.srv.forward("ServiceRegistration.Delete", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "service_registration", "delete"}, time.Now())
+
+	if len(args.IDs) == 0 {
+		return fmt.Errorf("must specify at least one service registration ID")
+	}
+
+	snap, err := s.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	if err := validateNodeSecret(snap, args.NodeID, args.SecretID); err != nil {
+		return err
+	}
+
+	// Nodes may only delete their own registrations
+	for _, id := range args.IDs {
+		existing, err := snap.ServiceRegistrationByID(id)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.NodeID != args.NodeID {
+			return fmt.Errorf("Service registration %q not registered by Node %q", id, args.NodeID)
+		}
+	}
+
+	// Update via Raft
+	_, index, err := s.srv.raftApply(structs.ServiceRegistrationDeleteRequestType, args)
+	if err != nil {
+		s.srv.logger.Printf("[ERR] nomad.service_registration: Delete failed: %v", err)
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// List is used to list the registered services
+func (s *ServiceRegistration) List(args *structs.ServiceRegistrationListRequest,
+	reply *structs.ServiceRegistrationListResponse) error {
+	if done, err := s.srv.forward("ServiceRegistration.List", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "service_registration", "list"}, time.Now())
+
+	// Check for read-job permissions. Queries across all namespaces are
+	// filtered to the namespaces the token may read instead.
+	aclObj, err := s.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	namespace := args.RequestNamespace()
+	allNamespaces := namespace == structs.AllNamespacesSentinel
+	if !allNamespaces && aclObj != nil && !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityReadJob) {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "service_registrations"}),
+		run: func(snap *state.StateSnapshot) error {
+			var err error
+			var allowed map[string]bool
+			var iter memdb.ResultIterator
+			if allNamespaces {
+				allowed, err = allowedNamespaces(aclObj, snap, acl.NamespaceCapabilityReadJob)
+				if err != nil {
+					return err
+				}
+				iter, err = snap.ServiceRegistrations()
+			} else {
+				iter, err = snap.ServiceRegistrationsByNamespace(namespace)
+			}
+			if err != nil {
+				return err
+			}
+
+			// Group the registrations by namespace and service name
+			stubs := make(map[string]*structs.ServiceRegistrationListStub)
+			tags := make(map[string]map[string]struct{})
+			var keys []string
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				service := raw.(*structs.ServiceRegistration)
+				if !namespaceMatches(service.Namespace, namespace, allowed) {
+					continue
+				}
+
+				key := service.Namespace + "\x00" + service.ServiceName
+				stub, ok := stubs[key]
+				if !ok {
+					stub = &structs.ServiceRegistrationListStub{
+						Namespace:   service.Namespace,
+						ServiceName: service.ServiceName,
+					}
+					stubs[key] = stub
+					tags[key] = make(map[string]struct{})
+					keys = append(keys, key)
+				}
+				for _, tag := range service.Tags {
+					if _, ok := tags[key][tag]; !ok {
+						tags[key][tag] = struct{}{}
+						stub.Tags = append(stub.Tags, tag)
+					}
+				}
+			}
+
+			sort.Strings(keys)
+			services := make([]*structs.ServiceRegistrationListStub, 0, len(keys))
+			for _, key := range keys {
+				services = append(services, stubs[key])
+			}
+			reply.Services = services
+
+			// Use the last index that affected the registration table
+			index, err := snap.Index("service_registrations")
+			if err != nil {
+				return err
+			}
+
+			// Ensure we never set the index to zero, otherwise a blocking query
+			// cannot be used. We floor the index at one, since realistically
+			// the first write must have a higher index.
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			s.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return s.srv.blockingRPC(&opts)
+}
+
+// GetService is used to query the registrations of a service
+func (s *ServiceRegistration) GetService(args *structs.ServiceRegistrationByNameRequest,
+	reply *structs.ServiceRegistrationByNameResponse) error {
+	if done, err := s.srv.forward("ServiceRegistration.GetService", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "service_registration", "get_service"}, time.Now())
+
+	// Check for read-job permissions, or a workload identity of the namespace
+	if err := s.checkGetServiceACL(args.AuthToken, args.RequestNamespace()); err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "service_registrations"}),
+		run: func(snap *state.StateSnapshot) error {
+			services, err := snap.ServiceRegistrationsByName(args.RequestNamespace(), args.ServiceName)
+			if err != nil {
+				return err
+			}
+			reply.Services = services
+
+			// Use the last index that affected the registration table
+			index, err := snap.Index("service_registrations")
+			if err != nil {
+				return err
+			}
+
+			// Ensure we never set the index to zero, otherwise a blocking query
+			// cannot be used. We floor the index at one, since realistically
+			// the first write must have a higher index.
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			s.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return s.srv.blockingRPC(&opts)
+}
+
+// checkGetServiceACL ensures the token of a request may query the services of
+// the namespace. The token is either the secret ID of an ACL token with
+// read-job permissions or the workload identity of a task, which may query the
+// services of its own namespace so that its templates can discover them.
+func (s *ServiceRegistration) checkGetServiceACL(token, namespace string) error {
+	// Fast-path if ACLs are disabled
+	if !s.srv.config.ACLEnabled {
+		return nil
+	}
+
+	if isWorkloadIdentity(token) {
+		claims, err := s.srv.verifyWorkloadIdentity(token)
+		if err != nil {
+			return err
+		}
+		if claims.Namespace != namespace {
+			return structs.ErrPermissionDenied
+		}
+		return nil
+	}
+
+	aclObj, err := s.srv.ResolveToken(token)
+	if err != nil {
+		return err
+	}
+	if !aclObj.AllowNamespaceOperation(namespace, acl.NamespaceCapabilityReadJob) {
+		return structs.ErrPermissionDenied
+	}
+	return nil
+}
+
+// validateNodeSecret ensures the node exists and has the given SecretID
+func validateNodeSecret(snap *state.StateSnapshot, nodeID, secretID string) error {
+	if nodeID == "" {
+		return fmt.Errorf("missing node ID")
+	}
+	if secretID == "" {
+		return fmt.Errorf("missing node SecretID")
+	}
+
+	node, err := snap.NodeByID(nodeID)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return fmt.Errorf("Node %q does not exist", nodeID)
+	}
+	if node.SecretID != secretID {
+		return fmt.Errorf("SecretID mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package nomad
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestServiceRegistrationEndpoint_UpsertDelete(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the node and an allocation running on it
+	state := s1.fsm.State()
+	node := mock.Node()
+	alloc := mock.Alloc()
+	alloc.NodeID = node.ID
+	if err := state.UpsertNode(998, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertAllocs(1000, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	service := mock.ServiceRegistration()
+	service.AllocID = alloc.ID
+	service.NodeID = node.ID
+	service.JobID = ""
+	req := &structs.ServiceRegistrationUpsertRequest{
+		Services:     []*structs.ServiceRegistration{service},
+		NodeID:       node.ID,
+		SecretID:     structs.GenerateUUID(),
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	// The node's SecretID is required
+	var resp structs.GenericResponse
+	err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.Upsert", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "SecretID mismatch") {
+		t.Fatalf("expected SecretID mismatch: %v", err)
+	}
+
+	req.SecretID = node.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.Upsert", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	// The server fills in the fields it is the authority on
+	out, err := state.ServiceRegistrationByID(service.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.JobID != alloc.JobID || out.Namespace != alloc.Namespace {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Nodes may only register the services of their own allocations
+	node2 := mock.Node()
+	if err := state.UpsertNode(1001, node2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req.NodeID = node2.ID
+	req.SecretID = node2.SecretID
+	err = msgpackrpc.CallWithCodec(codec, "ServiceRegistration.Upsert", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "not running on Node") {
+		t.Fatalf("expected error: %v", err)
+	}
+
+	// Nor delete the registrations of other nodes
+	del := &structs.ServiceRegistrationDeleteRequest{
+		IDs:          []string{service.ID},
+		NodeID:       node2.ID,
+		SecretID:     node2.SecretID,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	err = msgpackrpc.CallWithCodec(codec, "ServiceRegistration.Delete", del, &resp)
+	if err == nil || !strings.Contains(err.Error(), "not registered by Node") {
+		t.Fatalf("expected error: %v", err)
+	}
+
+	del.NodeID = node.ID
+	del.SecretID = node.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.Delete", del, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.ServiceRegistrationByID(service.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestServiceRegistrationEndpoint_ListGetService(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Register two instances of a service and one in another namespace
+	s2 := mock.ServiceRegistration()
+	s2.Tags = []string{"pci:true", "v2"}
+	s3 := mock.ServiceRegistration()
+	s3.ServiceName = "other"
+	s3.Namespace = "other"
+	services := []*structs.ServiceRegistration{mock.ServiceRegistration(), s2, s3}
+	if err := s1.fsm.State().UpsertServiceRegistrations(1000, services); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	list := &structs.ServiceRegistrationListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var listResp structs.ServiceRegistrationListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.List", list, &listResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if listResp.Index != 1000 {
+		t.Fatalf("Bad index: %d", listResp.Index)
+	}
+	if len(listResp.Services) != 1 {
+		t.Fatalf("bad: %#v", listResp.Services)
+	}
+	stub := listResp.Services[0]
+	if stub.ServiceName != "web-frontend" || len(stub.Tags) != 2 {
+		t.Fatalf("bad: %#v", stub)
+	}
+
+	// Services in all namespaces can be listed
+	list.Namespace = structs.AllNamespacesSentinel
+	if err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.List", list, &listResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(listResp.Services) != 2 {
+		t.Fatalf("bad: %#v", listResp.Services)
+	}
+
+	get := &structs.ServiceRegistrationByNameRequest{
+		ServiceName:  "web-frontend",
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var getResp structs.ServiceRegistrationByNameResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.GetService", get, &getResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if getResp.Index != 1000 {
+		t.Fatalf("Bad index: %d", getResp.Index)
+	}
+	if len(getResp.Services) != 2 {
+		t.Fatalf("bad: %#v", getResp.Services)
+	}
+}
+
+func TestServiceRegistrationEndpoint_GetService_ACL(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	service := mock.ServiceRegistration()
+	if err := s1.fsm.State().UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{service}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Anonymous requests are denied
+	get := &structs.ServiceRegistrationByNameRequest{
+		ServiceName:  service.ServiceName,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.ServiceRegistrationByNameResponse
+	err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.GetService", get, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	get.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.GetService", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Services) != 1 {
+		t.Fatalf("bad: %#v", resp.Services)
+	}
+
+	// Tasks may query the services of their namespace with their workload
+	// identity
+	alloc := mock.Alloc()
+	if err := s1.signAllocIdentities(alloc.Job, alloc, time.Now()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	state := s1.fsm.State()
+	if err := state.UpsertJobSummary(1001, mock.JobSummary(alloc.JobID)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertAllocs(1002, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	get.AuthToken = alloc.SignedIdentities["web"]
+	var identityResp structs.ServiceRegistrationByNameResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.GetService", get, &identityResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(identityResp.Services) != 1 {
+		t.Fatalf("bad: %#v", identityResp.Services)
+	}
+
+	// But not the services of other namespaces
+	get.Namespace = "other"
+	err = msgpackrpc.CallWithCodec(codec, "ServiceRegistration.GetService", get, &identityResp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+// Package snapshot implements the archive format used to save and restore
+// the state of a Nomad cluster. An archive is a gzip compressed tar file
+// holding the metadata of the snapshot, the serialized state of the servers'
+// FSM and the SHA-256 checksums of both, so that a snapshot that was
+// truncated or tampered with is detected before it is restored.
+package snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+const (
+	// metaFile, stateFile and sumsFile are the names of the files of the
+	// archive
+	metaFile  = "meta.json"
+	stateFile = "state.bin"
+	sumsFile  = "SHA256SUMS"
+
+	// formatVersion is the version of the archive format, bumped on
+	// incompatible changes
+	formatVersion = 1
+)
+
+// Metadata describes the state held by a snapshot.
+type Metadata struct {
+	// Version is the version of the archive format
+	Version int
+
+	// Region is the region the snapshot was taken in
+	Region string
+
+	// Index is the Raft index of the state held by the snapshot
+	Index uint64
+
+	// Time is when the snapshot was taken
+	Time time.Time
+}
+
+// Write writes an archive holding the given metadata and FSM state to w.
+func Write(w io.Writer, meta *Metadata, state []byte) error {
+	meta.Version = formatVersion
+	metaBuf, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot metadata: %v", err)
+	}
+
+	var sums bytes.Buffer
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{metaFile, metaBuf}, {stateFile, state}} {
+		sum := sha256.Sum256(f.data)
+		fmt.Fprintf(&sums, "%s  %s\n", hex.EncodeToString(sum[:]), f.name)
+	}
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	now := time.Now()
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{metaFile, metaBuf}, {stateFile, state}, {sumsFile, sums.Bytes()}} {
+		header := &tar.Header{
+			Name:    f.name,
+			Mode:    0600,
+			Size:    int64(len(f.data)),
+			ModTime: now,
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write snapshot archive: %v", err)
+		}
+		if _, err := archive.Write(f.data); err != nil {
+			return fmt.Errorf("failed to write snapshot archive: %v", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot archive: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot archive: %v", err)
+	}
+	return nil
+}
+
+// Read reads an archive from r, verifies the checksums of its files and
+// returns its metadata and FSM state.
+func Read(r io.Reader) (*Metadata, []byte, error) {
+	gz, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read snapshot archive: %v", err)
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte, 3)
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to read snapshot archive: %v", err)
+		}
+
+		switch header.Name {
+		case metaFile, stateFile, sumsFile:
+		default:
+			return nil, nil, fmt.Errorf("unexpected file %q in snapshot archive", header.Name)
+		}
+		data, err := ioutil.ReadAll(archive)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read snapshot archive: %v", err)
+		}
+		files[header.Name] = data
+	}
+
+	if err := verify(files); err != nil {
+		return nil, nil, err
+	}
+
+	var meta Metadata
+	if err := json.Unmarshal(files[metaFile], &meta); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode snapshot metadata: %v", err)
+	}
+	if meta.Version != formatVersion {
+		return nil, nil, fmt.Errorf("unsupported snapshot version %d", meta.Version)
+	}
+	return &meta, files[stateFile], nil
+}
+
+// verify checks that the archive holds every file and that their checksums
+// match the ones recorded when the archive was written.
+func verify(files map[string][]byte) error {
+	sums, ok := files[sumsFile]
+	if !ok {
+		return fmt.Errorf("snapshot archive is missing %s", sumsFile)
+	}
+
+	verified := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(sums)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("malformed %s in snapshot archive", sumsFile)
+		}
+		data, ok := files[fields[1]]
+		if !ok {
+			return fmt.Errorf("snapshot archive is missing %s", fields[1])
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != fields[0] {
+			return fmt.Errorf("checksum mismatch for %s in snapshot archive", fields[1])
+		}
+		verified++
+	}
+
+	if verified != len(files)-1 {
+		return fmt.Errorf("snapshot archive has files without a checksum")
+	}
+	for _, name := range []string{metaFile, stateFile} {
+		if _, ok := files[name]; !ok {
+			return fmt.Errorf("snapshot archive is missing %s", name)
+		}
+	}
+	return nil
+}
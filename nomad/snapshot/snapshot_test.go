@@ -0,0 +1,91 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshot_WriteRead(t *testing.T) {
+	meta := &Metadata{
+		Region: "global",
+		Index:  1000,
+		Time:   time.Unix(1500000000, 0).UTC(),
+	}
+	state := []byte("the state of the FSM")
+
+	var buf bytes.Buffer
+	if err := Write(&buf, meta, state); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, outState, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out, meta) {
+		t.Fatalf("bad: %#v", out)
+	}
+	if !bytes.Equal(outState, state) {
+		t.Fatalf("bad: %q", outState)
+	}
+}
+
+func TestSnapshot_Read_Truncated(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, &Metadata{Index: 1}, []byte("state")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()/2])
+	if _, _, err := Read(truncated); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestSnapshot_Read_ChecksumMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, &Metadata{Index: 1}, []byte("state")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Rewrite the archive with a tampered state
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var tampered bytes.Buffer
+	gzOut := gzip.NewWriter(&tampered)
+	in, out := tar.NewReader(gz), tar.NewWriter(gzOut)
+	for {
+		header, err := in.Next()
+		if err != nil {
+			break
+		}
+		data, err := ioutil.ReadAll(in)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if header.Name == stateFile {
+			data = []byte("STATE")
+		}
+		if err := out.WriteHeader(header); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := out.Write(data); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	out.Close()
+	gzOut.Close()
+
+	_, _, err = Read(&tampered)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("bad: %v", err)
+	}
+}
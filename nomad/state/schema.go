@@ -24,6 +24,22 @@ func stateStoreSchema() *memdb.DBSchema {
 		evalTableSchema,
 		allocTableSchema,
 		vaultAccessorTableSchema,
+		namespaceTableSchema,
+		quotaSpecTableSchema,
+		aclPolicyTableSchema,
+		aclTokenTableSchema,
+		workloadIdentityKeyTableSchema,
+		serviceRegistrationTableSchema,
+		rootKeyTableSchema,
+		variablesTableSchema,
+		scalingEventTableSchema,
+		multiregionRolloutTableSchema,
+		jobSubmissionTableSchema,
+		jobVersionTableSchema,
+		autopilotConfigTableSchema,
+		schedulerConfigTableSchema,
+		maintenanceWindowTableSchema,
+		csiVolumeTableSchema,
 	}
 
 	// Add each of the tables
@@ -72,6 +88,29 @@ func nodeTableSchema() *memdb.TableSchema {
 					Field: "ID",
 				},
 			},
+
+			// The dc_status_class index shards the nodes by datacenter,
+			// status and node class, so that schedulers only scan the
+			// nodes they may place on. The node class is optional.
+			"dc_status_class": &memdb.IndexSchema{
+				Name:         "dc_status_class",
+				AllowMissing: true,
+				Unique:       false,
+				Indexer: &memdb.CompoundIndex{
+					AllowMissing: true,
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Datacenter",
+						},
+						&memdb.StringFieldIndex{
+							Field: "Status",
+						},
+						&memdb.StringFieldIndex{
+							Field: "NodeClass",
+						},
+					},
+				},
+			},
 		},
 	}
 }
@@ -84,14 +123,30 @@ func jobTableSchema() *memdb.TableSchema {
 		Indexes: map[string]*memdb.IndexSchema{
 			// Primary index is used for job management
 			// and simple direct lookup. ID is required to be
-			// unique.
+			// unique within a namespace.
 			"id": &memdb.IndexSchema{
 				Name:         "id",
 				AllowMissing: false,
 				Unique:       true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Namespace",
+						},
+						&memdb.StringFieldIndex{
+							Field:     "ID",
+							Lowercase: true,
+						},
+					},
+				},
+			},
+			// Namespace index is used to lookup jobs by namespace
+			"namespace": &memdb.IndexSchema{
+				Name:         "namespace",
+				AllowMissing: false,
+				Unique:       false,
 				Indexer: &memdb.StringFieldIndex{
-					Field:     "ID",
-					Lowercase: true,
+					Field: "Namespace",
 				},
 			},
 			"type": &memdb.IndexSchema{
@@ -132,9 +187,16 @@ func jobSummarySchema() *memdb.TableSchema {
 				Name:         "id",
 				AllowMissing: false,
 				Unique:       true,
-				Indexer: &memdb.StringFieldIndex{
-					Field:     "JobID",
-					Lowercase: true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Namespace",
+						},
+						&memdb.StringFieldIndex{
+							Field:     "JobID",
+							Lowercase: true,
+						},
+					},
 				},
 			},
 		},
@@ -149,9 +211,15 @@ func jobIsGCable(obj interface{}) (bool, error) {
 		return false, fmt.Errorf("Unexpected type: %v", obj)
 	}
 
-	// The job is GCable if it is batch and it is not periodic
+	// If the job is stopped by the user it is GCable, regardless of type
+	if j.Stopped() {
+		return true, nil
+	}
+
+	// The job is GCable if it is batch and it is not periodic or
+	// parameterized
 	periodic := j.Periodic != nil && j.Periodic.Enabled
-	gcable := j.Type == structs.JobTypeBatch && !periodic
+	gcable := j.Type == structs.JobTypeBatch && !periodic && !j.IsParameterized()
 	return gcable, nil
 }
 
@@ -178,14 +246,21 @@ func periodicLaunchTableSchema() *memdb.TableSchema {
 		Indexes: map[string]*memdb.IndexSchema{
 			// Primary index is used for job management
 			// and simple direct lookup. ID is required to be
-			// unique.
+			// unique within a namespace.
 			"id": &memdb.IndexSchema{
 				Name:         "id",
 				AllowMissing: false,
 				Unique:       true,
-				Indexer: &memdb.StringFieldIndex{
-					Field:     "ID",
-					Lowercase: true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Namespace",
+						},
+						&memdb.StringFieldIndex{
+							Field:     "ID",
+							Lowercase: true,
+						},
+					},
 				},
 			},
 		},
@@ -214,9 +289,26 @@ func evalTableSchema() *memdb.TableSchema {
 				Name:         "job",
 				AllowMissing: false,
 				Unique:       false,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Namespace",
+						},
+						&memdb.StringFieldIndex{
+							Field:     "JobID",
+							Lowercase: true,
+						},
+					},
+				},
+			},
+
+			// Namespace index is used to lookup evaluations by namespace
+			"namespace": &memdb.IndexSchema{
+				Name:         "namespace",
+				AllowMissing: false,
+				Unique:       false,
 				Indexer: &memdb.StringFieldIndex{
-					Field:     "JobID",
-					Lowercase: true,
+					Field: "Namespace",
 				},
 			},
 		},
@@ -274,9 +366,16 @@ func allocTableSchema() *memdb.TableSchema {
 				Name:         "job",
 				AllowMissing: false,
 				Unique:       false,
-				Indexer: &memdb.StringFieldIndex{
-					Field:     "JobID",
-					Lowercase: true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Namespace",
+						},
+						&memdb.StringFieldIndex{
+							Field:     "JobID",
+							Lowercase: true,
+						},
+					},
 				},
 			},
 
@@ -289,6 +388,16 @@ func allocTableSchema() *memdb.TableSchema {
 					Field: "EvalID",
 				},
 			},
+
+			// Namespace index is used to lookup allocations by namespace
+			"namespace": &memdb.IndexSchema{
+				Name:         "namespace",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "Namespace",
+				},
+			},
 		},
 	}
 }
@@ -330,3 +439,459 @@ func vaultAccessorTableSchema() *memdb.TableSchema {
 		},
 	}
 }
+
+// namespaceTableSchema returns the MemDB schema for the namespace table.
+// This table is used to store the namespaces jobs can be submitted into.
+func namespaceTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "namespaces",
+		Indexes: map[string]*memdb.IndexSchema{
+			// The primary index is the namespace name
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "Name",
+				},
+			},
+
+			// Quota index is used to lookup the namespaces attached to a
+			// quota specification
+			"quota": &memdb.IndexSchema{
+				Name:         "quota",
+				AllowMissing: true,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "Quota",
+				},
+			},
+		},
+	}
+}
+
+// quotaSpecTableSchema returns the MemDB schema for the quota specification
+// table. This table is used to store the resource limits of namespaces.
+func quotaSpecTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "quota_specs",
+		Indexes: map[string]*memdb.IndexSchema{
+			// The primary index is the quota name
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "Name",
+				},
+			},
+		},
+	}
+}
+
+// aclPolicyTableSchema returns the MemDB schema for the policy table.
+// This table is used to store the policies which are referenced by tokens
+func aclPolicyTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "acl_policy",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "Name",
+				},
+			},
+		},
+	}
+}
+
+// aclTokenTableSchema returns the MemDB schema for the tokens table.
+// This table is used to store the bearer tokens which are used to authenticate
+func aclTokenTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "acl_token",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.UUIDFieldIndex{
+					Field: "AccessorID",
+				},
+			},
+			"secret": &memdb.IndexSchema{
+				Name:         "secret",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.UUIDFieldIndex{
+					Field: "SecretID",
+				},
+			},
+			"global": &memdb.IndexSchema{
+				Name:         "global",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.ConditionalIndex{
+					Conditional: func(obj interface{}) (bool, error) {
+						token, ok := obj.(*structs.ACLToken)
+						if !ok {
+							return false, fmt.Errorf("invalid type %T for global index", obj)
+						}
+						return token.Global, nil
+					},
+				},
+			},
+		},
+	}
+}
+
+// workloadIdentityKeyTableSchema returns the MemDB schema for the workload
+// identity key table. This table is used to store the keys the leader signs
+// workload identities with.
+func workloadIdentityKeyTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "identity_keys",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.UUIDFieldIndex{
+					Field: "KeyID",
+				},
+			},
+		},
+	}
+}
+
+// serviceRegistrationTableSchema returns the MemDB schema for the service
+// registration table. This table is used to store the services of tasks
+// registered in Nomad's built-in service catalog.
+func serviceRegistrationTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "service_registrations",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "ID",
+				},
+			},
+
+			// Service name index is used to lookup the registrations of a
+			// service in a namespace
+			"service_name": &memdb.IndexSchema{
+				Name:         "service_name",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Namespace",
+						},
+						&memdb.StringFieldIndex{
+							Field: "ServiceName",
+						},
+					},
+				},
+			},
+
+			// Namespace index is used to lookup registrations by namespace
+			"namespace": &memdb.IndexSchema{
+				Name:         "namespace",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "Namespace",
+				},
+			},
+
+			// Alloc index is used to lookup the registrations of an
+			// allocation
+			"alloc_id": &memdb.IndexSchema{
+				Name:         "alloc_id",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "AllocID",
+				},
+			},
+
+			// Node index is used to lookup the registrations of a node
+			"node_id": &memdb.IndexSchema{
+				Name:         "node_id",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "NodeID",
+				},
+			},
+		},
+	}
+}
+
+// rootKeyTableSchema returns the MemDB schema for the root key table. This
+// table is used to store the keyring that encrypts variables.
+func rootKeyTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "root_keys",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.UUIDFieldIndex{
+					Field: "KeyID",
+				},
+			},
+		},
+	}
+}
+
+// variablesTableSchema returns the MemDB schema for the variables table.
+// This table is used to store the encrypted variables of namespaces.
+func variablesTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "variables",
+		Indexes: map[string]*memdb.IndexSchema{
+			// The primary index is the namespace and path of the variable,
+			// allowing prefix lookups of the paths in a namespace
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Namespace",
+						},
+						&memdb.StringFieldIndex{
+							Field: "Path",
+						},
+					},
+				},
+			},
+
+			// Key index is used to lookup the variables encrypted with a
+			// root key
+			"key_id": &memdb.IndexSchema{
+				Name:         "key_id",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "KeyID",
+				},
+			},
+		},
+	}
+}
+
+// scalingEventTableSchema returns the MemDB schema for the scaling event
+// table. This table is used to store the recent scaling events of the task
+// groups of each job.
+func scalingEventTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "scaling_event",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Namespace",
+						},
+						&memdb.StringFieldIndex{
+							Field: "JobID",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// multiregionRolloutTableSchema returns the MemDB schema for the multi-region
+// rollout table. This table is used to track the rollout of the multi-region
+// jobs submitted to the region.
+func multiregionRolloutTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "multiregion_rollout",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Namespace",
+						},
+						&memdb.StringFieldIndex{
+							Field: "JobID",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// jobSubmissionTableSchema returns the MemDB schema for the table holding the
+// source of the latest submission of each job
+func jobSubmissionTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "job_submission",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Namespace",
+						},
+						&memdb.StringFieldIndex{
+							Field: "JobID",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// autopilotConfigTableSchema returns the MemDB schema for the table holding
+// the configuration of autopilot. The table holds a single entry.
+func autopilotConfigTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "autopilot_config",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: true,
+				Unique:       true,
+				Indexer: &memdb.ConditionalIndex{
+					Conditional: func(obj interface{}) (bool, error) { return true, nil },
+				},
+			},
+		},
+	}
+}
+
+// schedulerConfigTableSchema returns the MemDB schema for the table holding
+// the configuration of the schedulers. The table holds a single entry.
+func schedulerConfigTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "scheduler_config",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: true,
+				Unique:       true,
+				Indexer: &memdb.ConditionalIndex{
+					Conditional: func(obj interface{}) (bool, error) { return true, nil },
+				},
+			},
+		},
+	}
+}
+
+// maintenanceWindowTableSchema returns the MemDB schema for the maintenance
+// window table. This table is used to store the windows during which node
+// classes undergo maintenance.
+func maintenanceWindowTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "maintenance_windows",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.UUIDFieldIndex{
+					Field: "ID",
+				},
+			},
+
+			// Node class index is used to lookup the windows of a node class
+			"node_class": &memdb.IndexSchema{
+				Name:         "node_class",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "NodeClass",
+				},
+			},
+		},
+	}
+}
+
+// jobVersionTableSchema returns the MemDB schema for the job version table.
+// This table is used to store the most recent versions of each job.
+func jobVersionTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "job_version",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Namespace",
+						},
+						&memdb.StringFieldIndex{
+							Field: "JobID",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// csiVolumeTableSchema returns the MemDB schema for the CSI volume table.
+// This table is used to store the CSI volumes registered or created by
+// Nomad, along with their claims.
+func csiVolumeTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "csi_volumes",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "ID",
+				},
+			},
+
+			// Namespace index is used to lookup volumes by namespace
+			"namespace": &memdb.IndexSchema{
+				Name:         "namespace",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "Namespace",
+				},
+			},
+
+			// Plugin index is used to lookup the volumes of a plugin
+			"plugin_id": &memdb.IndexSchema{
+				Name:         "plugin_id",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "PluginID",
+				},
+			},
+		},
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -90,8 +92,14 @@ func (s *StateStore) UpsertJobSummary(index uint64, jobSummary *structs.JobSumma
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
+	// Job summaries without a namespace belong to the default namespace
+	summary := *jobSummary
+	if summary.Namespace == "" {
+		summary.Namespace = structs.DefaultNamespace
+	}
+
 	// Update the index
-	if err := txn.Insert("job_summary", *jobSummary); err != nil {
+	if err := txn.Insert("job_summary", summary); err != nil {
 		return err
 	}
 
@@ -104,14 +112,14 @@ func (s *StateStore) UpsertJobSummary(index uint64, jobSummary *structs.JobSumma
 	return nil
 }
 
-// DeleteJobSummary deletes the job summary with the given ID. This is for
-// testing purposes only.
-func (s *StateStore) DeleteJobSummary(index uint64, id string) error {
+// DeleteJobSummary deletes the job summary with the given namespace and ID.
+// This is for testing purposes only.
+func (s *StateStore) DeleteJobSummary(index uint64, namespace, id string) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
 	// Delete the job summary
-	if _, err := txn.DeleteAll("job_summary", "id", id); err != nil {
+	if _, err := txn.DeleteAll("job_summary", "id", namespace, id); err != nil {
 		return fmt.Errorf("deleting job summary failed: %v", err)
 	}
 	if err := txn.Insert("index", &IndexEntry{"job_summary", index}); err != nil {
@@ -144,9 +152,17 @@ func (s *StateStore) UpsertNode(index uint64, node *structs.Node) error {
 		node.CreateIndex = exist.CreateIndex
 		node.ModifyIndex = index
 		node.Drain = exist.Drain // Retain the drain mode
+		node.DrainStrategy = exist.DrainStrategy
+		node.SchedulingEligibility = exist.SchedulingEligibility
+		node.Reliability = exist.Reliability
+		node.Events = appendNodeEvents(exist.Events, index, node.Events...)
 	} else {
 		node.CreateIndex = index
 		node.ModifyIndex = index
+		if node.SchedulingEligibility == "" {
+			node.SchedulingEligibility = structs.NodeSchedulingEligible
+		}
+		node.Events = appendNodeEvents(nil, index, node.Events...)
 	}
 
 	// Insert the node
@@ -188,13 +204,19 @@ func (s *StateStore) DeleteNode(index uint64, nodeID string) error {
 		return fmt.Errorf("index update failed: %v", err)
 	}
 
+	// Remove the services registered by the node
+	if err := s.deleteServiceRegistrations(index, watcher, txn, "node_id", nodeID); err != nil {
+		return err
+	}
+
 	txn.Defer(func() { s.watch.notify(watcher) })
 	txn.Commit()
 	return nil
 }
 
-// UpdateNodeStatus is used to update the status of a node
-func (s *StateStore) UpdateNodeStatus(index uint64, nodeID, status string) error {
+// UpdateNodeStatus is used to update the status of a node, recording the
+// event if one is given
+func (s *StateStore) UpdateNodeStatus(index uint64, nodeID, status string, event *structs.NodeEvent) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
@@ -219,6 +241,9 @@ func (s *StateStore) UpdateNodeStatus(index uint64, nodeID, status string) error
 	// Update the status in the copy
 	copyNode.Status = status
 	copyNode.ModifyIndex = index
+	if event != nil {
+		copyNode.Events = appendNodeEvents(copyNode.Events, index, event)
+	}
 
 	// Insert the node
 	if err := txn.Insert("nodes", copyNode); err != nil {
@@ -228,13 +253,22 @@ func (s *StateStore) UpdateNodeStatus(index uint64, nodeID, status string) error
 		return fmt.Errorf("index update failed: %v", err)
 	}
 
+	// The services registered by a down node are no longer reachable
+	if status == structs.NodeStatusDown {
+		if err := s.deleteServiceRegistrations(index, watcher, txn, "node_id", nodeID); err != nil {
+			return err
+		}
+	}
+
 	txn.Defer(func() { s.watch.notify(watcher) })
 	txn.Commit()
 	return nil
 }
 
-// UpdateNodeDrain is used to update the drain of a node
-func (s *StateStore) UpdateNodeDrain(index uint64, nodeID string, drain bool) error {
+// UpdateNodeDrain is used to update the drain of a node, recording the event
+// if one is given. Draining nodes are ineligible for scheduling, and nodes
+// that stop draining are eligible again.
+func (s *StateStore) UpdateNodeDrain(index uint64, nodeID string, drain *structs.DrainStrategy, event *structs.NodeEvent) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
@@ -257,8 +291,61 @@ func (s *StateStore) UpdateNodeDrain(index uint64, nodeID string, drain bool) er
 	*copyNode = *existingNode
 
 	// Update the drain in the copy
-	copyNode.Drain = drain
+	copyNode.Drain = drain != nil
+	copyNode.DrainStrategy = drain
+	if drain != nil {
+		copyNode.SchedulingEligibility = structs.NodeSchedulingIneligible
+	} else if existingNode.Drain {
+		copyNode.SchedulingEligibility = structs.NodeSchedulingEligible
+	}
+	copyNode.ModifyIndex = index
+	if event != nil {
+		copyNode.Events = appendNodeEvents(copyNode.Events, index, event)
+	}
+
+	// Insert the node
+	if err := txn.Insert("nodes", copyNode); err != nil {
+		return fmt.Errorf("node update failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"nodes", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// UpdateNodeEligibility is used to update the scheduling eligibility of a
+// node, recording the event if one is given
+func (s *StateStore) UpdateNodeEligibility(index uint64, nodeID, eligibility string, event *structs.NodeEvent) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "nodes"})
+	watcher.Add(watch.Item{Node: nodeID})
+
+	// Lookup the node
+	existing, err := txn.First("nodes", "id", nodeID)
+	if err != nil {
+		return fmt.Errorf("node lookup failed: %v", err)
+	}
+	if existing == nil {
+		return fmt.Errorf("node not found")
+	}
+
+	// Copy the existing node
+	existingNode := existing.(*structs.Node)
+	copyNode := new(structs.Node)
+	*copyNode = *existingNode
+
+	// Update the eligibility in the copy
+	copyNode.SchedulingEligibility = eligibility
 	copyNode.ModifyIndex = index
+	if event != nil {
+		copyNode.Events = appendNodeEvents(copyNode.Events, index, event)
+	}
 
 	// Insert the node
 	if err := txn.Insert("nodes", copyNode); err != nil {
@@ -273,6 +360,72 @@ func (s *StateStore) UpdateNodeDrain(index uint64, nodeID string, drain bool) er
 	return nil
 }
 
+// appendNodeEvents returns the events of a node with the given events
+// appended at the index, keeping only the most recent events. The existing
+// events are not modified.
+func appendNodeEvents(events []*structs.NodeEvent, index uint64, added ...*structs.NodeEvent) []*structs.NodeEvent {
+	if len(added) == 0 {
+		return events
+	}
+
+	out := make([]*structs.NodeEvent, 0, len(events)+len(added))
+	out = append(out, events...)
+	for _, e := range added {
+		e = e.Copy()
+		e.CreateIndex = index
+		out = append(out, e)
+	}
+	if len(out) > structs.MaxRetainedNodeEvents {
+		out = out[len(out)-structs.MaxRetainedNodeEvents:]
+	}
+	return out
+}
+
+// UpdateNodeReliability is used to add to the reliability penalties of
+// nodes, decaying their existing penalties to the update time. Nodes that
+// no longer exist are skipped.
+func (s *StateStore) UpdateNodeReliability(index uint64, penalties map[string]float64,
+	updateTime int64, halfLife time.Duration) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "nodes"})
+
+	now := time.Unix(0, updateTime)
+	for nodeID, penalty := range penalties {
+		existing, err := txn.First("nodes", "id", nodeID)
+		if err != nil {
+			return fmt.Errorf("node lookup failed: %v", err)
+		}
+		if existing == nil {
+			continue
+		}
+		watcher.Add(watch.Item{Node: nodeID})
+
+		// Copy the existing node
+		existingNode := existing.(*structs.Node)
+		copyNode := new(structs.Node)
+		*copyNode = *existingNode
+
+		// Update the reliability in the copy
+		copyNode.Reliability = existingNode.Reliability.Add(penalty, now, halfLife)
+		copyNode.ModifyIndex = index
+
+		// Insert the node
+		if err := txn.Insert("nodes", copyNode); err != nil {
+			return fmt.Errorf("node update failed: %v", err)
+		}
+	}
+	if err := txn.Insert("index", &IndexEntry{"nodes", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
 // NodeByID is used to lookup a node by ID
 func (s *StateStore) NodeByID(nodeID string) (*structs.Node, error) {
 	txn := s.db.Txn(false)
@@ -312,6 +465,30 @@ func (s *StateStore) Nodes() (memdb.ResultIterator, error) {
 	return iter, nil
 }
 
+// NodesByDatacenterStatus returns an iterator over the nodes of the
+// datacenter with the given status, across all node classes
+func (s *StateStore) NodesByDatacenterStatus(dc, status string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("nodes", "dc_status_class_prefix", dc, status)
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// NodesByDatacenterStatusClass returns an iterator over the nodes of the
+// datacenter with the given status and node class
+func (s *StateStore) NodesByDatacenterStatusClass(dc, status, class string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("nodes", "dc_status_class", dc, status, class)
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
 // UpsertJob is used to register a job or update a job definition
 func (s *StateStore) UpsertJob(index uint64, job *structs.Job) error {
 	txn := s.db.Txn(true)
@@ -321,8 +498,13 @@ func (s *StateStore) UpsertJob(index uint64, job *structs.Job) error {
 	watcher.Add(watch.Item{Table: "jobs"})
 	watcher.Add(watch.Item{Job: job.ID})
 
+	// Jobs without a namespace belong to the default namespace
+	if job.Namespace == "" {
+		job.Namespace = structs.DefaultNamespace
+	}
+
 	// Check if the job already exists
-	existing, err := txn.First("jobs", "id", job.ID)
+	existing, err := txn.First("jobs", "id", job.Namespace, job.ID)
 	if err != nil {
 		return fmt.Errorf("job lookup failed: %v", err)
 	}
@@ -332,6 +514,7 @@ func (s *StateStore) UpsertJob(index uint64, job *structs.Job) error {
 		job.CreateIndex = existing.(*structs.Job).CreateIndex
 		job.ModifyIndex = index
 		job.JobModifyIndex = index
+		job.Version = existing.(*structs.Job).Version + 1
 
 		// Compute the job status
 		var err error
@@ -343,10 +526,11 @@ func (s *StateStore) UpsertJob(index uint64, job *structs.Job) error {
 		job.CreateIndex = index
 		job.ModifyIndex = index
 		job.JobModifyIndex = index
+		job.Version = 0
 
 		// If we are inserting the job for the first time, we don't need to
 		// calculate the jobs status as it is known.
-		if job.IsPeriodic() {
+		if job.IsPeriodic() || job.IsParameterized() {
 			job.Status = structs.JobStatusRunning
 		} else {
 			job.Status = structs.JobStatusPending
@@ -369,18 +553,53 @@ func (s *StateStore) UpsertJob(index uint64, job *structs.Job) error {
 		return fmt.Errorf("index update failed: %v", err)
 	}
 
+	if err := s.upsertJobVersion(index, job, watcher, txn); err != nil {
+		return err
+	}
+
 	txn.Defer(func() { s.watch.notify(watcher) })
 	txn.Commit()
 	return nil
 }
 
+// upsertJobVersion records a new version of a job, dropping the oldest
+// version once more than JobTrackedVersions are tracked
+func (s *StateStore) upsertJobVersion(index uint64, job *structs.Job, watcher watch.Items, txn *memdb.Txn) error {
+	existing, err := txn.First("job_version", "id", job.Namespace, job.ID)
+	if err != nil {
+		return fmt.Errorf("job version lookup failed: %v", err)
+	}
+
+	var versions *structs.JobVersions
+	if existing != nil {
+		versions = existing.(*structs.JobVersions).Copy()
+	} else {
+		versions = &structs.JobVersions{JobID: job.ID, Namespace: job.Namespace}
+	}
+
+	versions.Versions = append([]*structs.Job{job}, versions.Versions...)
+	if len(versions.Versions) > structs.JobTrackedVersions {
+		versions.Versions = versions.Versions[:structs.JobTrackedVersions]
+	}
+	versions.ModifyIndex = index
+
+	if err := txn.Insert("job_version", versions); err != nil {
+		return fmt.Errorf("job version insert failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"job_version", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+	watcher.Add(watch.Item{Table: "job_version"})
+	return nil
+}
+
 // DeleteJob is used to deregister a job
-func (s *StateStore) DeleteJob(index uint64, jobID string) error {
+func (s *StateStore) DeleteJob(index uint64, namespace, jobID string) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
 	// Lookup the node
-	existing, err := txn.First("jobs", "id", jobID)
+	existing, err := txn.First("jobs", "id", namespace, jobID)
 	if err != nil {
 		return fmt.Errorf("job lookup failed: %v", err)
 	}
@@ -403,23 +622,63 @@ func (s *StateStore) DeleteJob(index uint64, jobID string) error {
 	}
 
 	// Delete the job summary
-	if _, err = txn.DeleteAll("job_summary", "id", jobID); err != nil {
+	if _, err = txn.DeleteAll("job_summary", "id", namespace, jobID); err != nil {
 		return fmt.Errorf("deleing job summary failed: %v", err)
 	}
 	if err := txn.Insert("index", &IndexEntry{"job_summary", index}); err != nil {
 		return fmt.Errorf("index update failed: %v", err)
 	}
 
+	// Delete the scaling events
+	if num, err := txn.DeleteAll("scaling_event", "id", namespace, jobID); err != nil {
+		return fmt.Errorf("deleting scaling events failed: %v", err)
+	} else if num != 0 {
+		watcher.Add(watch.Item{Table: "scaling_event"})
+		if err := txn.Insert("index", &IndexEntry{"scaling_event", index}); err != nil {
+			return fmt.Errorf("index update failed: %v", err)
+		}
+	}
+
+	// Delete the multi-region rollout
+	if num, err := txn.DeleteAll("multiregion_rollout", "id", namespace, jobID); err != nil {
+		return fmt.Errorf("deleting multi-region rollout failed: %v", err)
+	} else if num != 0 {
+		watcher.Add(watch.Item{Table: "multiregion_rollout"})
+		if err := txn.Insert("index", &IndexEntry{"multiregion_rollout", index}); err != nil {
+			return fmt.Errorf("index update failed: %v", err)
+		}
+	}
+
+	// Delete the source of the job
+	if num, err := txn.DeleteAll("job_submission", "id", namespace, jobID); err != nil {
+		return fmt.Errorf("deleting job submission failed: %v", err)
+	} else if num != 0 {
+		watcher.Add(watch.Item{Table: "job_submission"})
+		if err := txn.Insert("index", &IndexEntry{"job_submission", index}); err != nil {
+			return fmt.Errorf("index update failed: %v", err)
+		}
+	}
+
+	// Delete the versions of the job
+	if num, err := txn.DeleteAll("job_version", "id", namespace, jobID); err != nil {
+		return fmt.Errorf("deleting job versions failed: %v", err)
+	} else if num != 0 {
+		watcher.Add(watch.Item{Table: "job_version"})
+		if err := txn.Insert("index", &IndexEntry{"job_version", index}); err != nil {
+			return fmt.Errorf("index update failed: %v", err)
+		}
+	}
+
 	txn.Defer(func() { s.watch.notify(watcher) })
 	txn.Commit()
 	return nil
 }
 
-// JobByID is used to lookup a job by its ID
-func (s *StateStore) JobByID(id string) (*structs.Job, error) {
+// JobByID is used to lookup a job by its namespace and ID
+func (s *StateStore) JobByID(namespace, id string) (*structs.Job, error) {
 	txn := s.db.Txn(false)
 
-	existing, err := txn.First("jobs", "id", id)
+	existing, err := txn.First("jobs", "id", namespace, id)
 	if err != nil {
 		return nil, fmt.Errorf("job lookup failed: %v", err)
 	}
@@ -430,11 +689,21 @@ func (s *StateStore) JobByID(id string) (*structs.Job, error) {
 	return nil, nil
 }
 
-// JobsByIDPrefix is used to lookup a job by prefix
-func (s *StateStore) JobsByIDPrefix(id string) (memdb.ResultIterator, error) {
+// JobsByIDPrefix is used to lookup the jobs of a namespace by ID prefix
+func (s *StateStore) JobsByIDPrefix(namespace, id string) (memdb.ResultIterator, error) {
 	txn := s.db.Txn(false)
 
-	iter, err := txn.Get("jobs", "id_prefix", id)
+	// The prefix index is scoped to a single namespace, so a search across
+	// all namespaces walks the table and filters on the ID instead.
+	if namespace == structs.AllNamespacesSentinel {
+		iter, err := txn.Get("jobs", "id")
+		if err != nil {
+			return nil, fmt.Errorf("job lookup failed: %v", err)
+		}
+		return &jobIDPrefixIterator{iter: iter, prefix: strings.ToLower(id)}, nil
+	}
+
+	iter, err := txn.Get("jobs", "id_prefix", namespace, id)
 	if err != nil {
 		return nil, fmt.Errorf("job lookup failed: %v", err)
 	}
@@ -442,6 +711,25 @@ func (s *StateStore) JobsByIDPrefix(id string) (memdb.ResultIterator, error) {
 	return iter, nil
 }
 
+// jobIDPrefixIterator filters a jobs iterator to the jobs whose ID starts
+// with the given lowercase prefix.
+type jobIDPrefixIterator struct {
+	iter   memdb.ResultIterator
+	prefix string
+}
+
+func (i *jobIDPrefixIterator) Next() interface{} {
+	for {
+		raw := i.iter.Next()
+		if raw == nil {
+			return nil
+		}
+		if strings.HasPrefix(strings.ToLower(raw.(*structs.Job).ID), i.prefix) {
+			return raw
+		}
+	}
+}
+
 // Jobs returns an iterator over all the jobs
 func (s *StateStore) Jobs() (memdb.ResultIterator, error) {
 	txn := s.db.Txn(false)
@@ -454,6 +742,18 @@ func (s *StateStore) Jobs() (memdb.ResultIterator, error) {
 	return iter, nil
 }
 
+// JobsByNamespace returns an iterator over all the jobs in the given
+// namespace.
+func (s *StateStore) JobsByNamespace(namespace string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("jobs", "namespace", namespace)
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
 // JobsByPeriodic returns an iterator over all the periodic or non-periodic jobs.
 func (s *StateStore) JobsByPeriodic(periodic bool) (memdb.ResultIterator, error) {
 	txn := s.db.Txn(false)
@@ -490,11 +790,12 @@ func (s *StateStore) JobsByGC(gc bool) (memdb.ResultIterator, error) {
 	return iter, nil
 }
 
-// JobSummary returns a job summary object which matches a specific id.
-func (s *StateStore) JobSummaryByID(jobID string) (*structs.JobSummary, error) {
+// JobSummary returns a job summary object which matches a specific namespace
+// and id.
+func (s *StateStore) JobSummaryByID(namespace, jobID string) (*structs.JobSummary, error) {
 	txn := s.db.Txn(false)
 
-	existing, err := txn.First("job_summary", "id", jobID)
+	existing, err := txn.First("job_summary", "id", namespace, jobID)
 	if err != nil {
 		return nil, err
 	}
@@ -518,11 +819,12 @@ func (s *StateStore) JobSummaries() (memdb.ResultIterator, error) {
 	return iter, nil
 }
 
-// JobSummaryByPrefix is used to look up Job Summary by id prefix
-func (s *StateStore) JobSummaryByPrefix(id string) (memdb.ResultIterator, error) {
+// JobSummaryByPrefix is used to look up the Job Summaries of a namespace by
+// id prefix
+func (s *StateStore) JobSummaryByPrefix(namespace, id string) (memdb.ResultIterator, error) {
 	txn := s.db.Txn(false)
 
-	iter, err := txn.Get("job_summary", "id_prefix", id)
+	iter, err := txn.Get("job_summary", "id_prefix", namespace, id)
 	if err != nil {
 		return nil, fmt.Errorf("eval lookup failed: %v", err)
 	}
@@ -539,8 +841,13 @@ func (s *StateStore) UpsertPeriodicLaunch(index uint64, launch *structs.Periodic
 	watcher.Add(watch.Item{Table: "periodic_launch"})
 	watcher.Add(watch.Item{Job: launch.ID})
 
+	// Launches without a namespace belong to the default namespace
+	if launch.Namespace == "" {
+		launch.Namespace = structs.DefaultNamespace
+	}
+
 	// Check if the job already exists
-	existing, err := txn.First("periodic_launch", "id", launch.ID)
+	existing, err := txn.First("periodic_launch", "id", launch.Namespace, launch.ID)
 	if err != nil {
 		return fmt.Errorf("periodic launch lookup failed: %v", err)
 	}
@@ -568,12 +875,12 @@ func (s *StateStore) UpsertPeriodicLaunch(index uint64, launch *structs.Periodic
 }
 
 // DeletePeriodicLaunch is used to delete the periodic launch
-func (s *StateStore) DeletePeriodicLaunch(index uint64, jobID string) error {
+func (s *StateStore) DeletePeriodicLaunch(index uint64, namespace, jobID string) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
 	// Lookup the launch
-	existing, err := txn.First("periodic_launch", "id", jobID)
+	existing, err := txn.First("periodic_launch", "id", namespace, jobID)
 	if err != nil {
 		return fmt.Errorf("launch lookup failed: %v", err)
 	}
@@ -599,11 +906,11 @@ func (s *StateStore) DeletePeriodicLaunch(index uint64, jobID string) error {
 }
 
 // PeriodicLaunchByID is used to lookup a periodic launch by the periodic job
-// ID.
-func (s *StateStore) PeriodicLaunchByID(id string) (*structs.PeriodicLaunch, error) {
+// namespace and ID.
+func (s *StateStore) PeriodicLaunchByID(namespace, id string) (*structs.PeriodicLaunch, error) {
 	txn := s.db.Txn(false)
 
-	existing, err := txn.First("periodic_launch", "id", id)
+	existing, err := txn.First("periodic_launch", "id", namespace, id)
 	if err != nil {
 		return nil, fmt.Errorf("periodic launch lookup failed: %v", err)
 	}
@@ -635,14 +942,15 @@ func (s *StateStore) UpsertEvals(index uint64, evals []*structs.Evaluation) erro
 	watcher.Add(watch.Item{Table: "evals"})
 
 	// Do a nested upsert
-	jobs := make(map[string]string, len(evals))
+	jobs := make(map[structs.NamespacedID]string, len(evals))
 	for _, eval := range evals {
 		watcher.Add(watch.Item{Eval: eval.ID})
+		watcher.Add(watch.Item{EvalJob: eval.JobID})
 		if err := s.nestedUpsertEval(txn, index, eval); err != nil {
 			return err
 		}
 
-		jobs[eval.JobID] = ""
+		jobs[structs.NewNamespacedID(eval.JobID, eval.Namespace)] = ""
 	}
 
 	// Set the job's status
@@ -672,8 +980,13 @@ func (s *StateStore) nestedUpsertEval(txn *memdb.Txn, index uint64, eval *struct
 		eval.ModifyIndex = index
 	}
 
+	// Evaluations without a namespace belong to the default namespace
+	if eval.Namespace == "" {
+		eval.Namespace = structs.DefaultNamespace
+	}
+
 	// Update the job summary
-	summaryRaw, err := txn.First("job_summary", "id", eval.JobID)
+	summaryRaw, err := txn.First("job_summary", "id", eval.Namespace, eval.JobID)
 	if err != nil {
 		return fmt.Errorf("job summary lookup failed: %v", err)
 	}
@@ -722,7 +1035,7 @@ func (s *StateStore) DeleteEval(index uint64, evals []string, allocs []string) e
 	watcher.Add(watch.Item{Table: "evals"})
 	watcher.Add(watch.Item{Table: "allocs"})
 
-	jobs := make(map[string]string, len(evals))
+	jobs := make(map[structs.NamespacedID]string, len(evals))
 	for _, eval := range evals {
 		existing, err := txn.First("evals", "id", eval)
 		if err != nil {
@@ -735,7 +1048,9 @@ func (s *StateStore) DeleteEval(index uint64, evals []string, allocs []string) e
 			return fmt.Errorf("eval delete failed: %v", err)
 		}
 		watcher.Add(watch.Item{Eval: eval})
-		jobs[existing.(*structs.Evaluation).JobID] = ""
+		realEval := existing.(*structs.Evaluation)
+		watcher.Add(watch.Item{EvalJob: realEval.JobID})
+		jobs[structs.NewNamespacedID(realEval.JobID, realEval.Namespace)] = ""
 	}
 
 	for _, alloc := range allocs {
@@ -754,6 +1069,10 @@ func (s *StateStore) DeleteEval(index uint64, evals []string, allocs []string) e
 		watcher.Add(watch.Item{AllocEval: realAlloc.EvalID})
 		watcher.Add(watch.Item{AllocJob: realAlloc.JobID})
 		watcher.Add(watch.Item{AllocNode: realAlloc.NodeID})
+
+		if err := s.deleteServiceRegistrations(index, watcher, txn, "alloc_id", alloc); err != nil {
+			return err
+		}
 	}
 
 	// Update the indexes
@@ -801,12 +1120,12 @@ func (s *StateStore) EvalsByIDPrefix(id string) (memdb.ResultIterator, error) {
 	return iter, nil
 }
 
-// EvalsByJob returns all the evaluations by job id
-func (s *StateStore) EvalsByJob(jobID string) ([]*structs.Evaluation, error) {
+// EvalsByJob returns all the evaluations by job namespace and id
+func (s *StateStore) EvalsByJob(namespace, jobID string) ([]*structs.Evaluation, error) {
 	txn := s.db.Txn(false)
 
 	// Get an iterator over the node allocations
-	iter, err := txn.Get("evals", "job", jobID)
+	iter, err := txn.Get("evals", "job", namespace, jobID)
 	if err != nil {
 		return nil, err
 	}
@@ -822,6 +1141,18 @@ func (s *StateStore) EvalsByJob(jobID string) ([]*structs.Evaluation, error) {
 	return out, nil
 }
 
+// EvalsByNamespace returns an iterator over all the evaluations in the given
+// namespace.
+func (s *StateStore) EvalsByNamespace(namespace string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("evals", "namespace", namespace)
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
 // Evals returns an iterator over all the evaluations
 func (s *StateStore) Evals() (memdb.ResultIterator, error) {
 	txn := s.db.Txn(false)
@@ -905,18 +1236,80 @@ func (s *StateStore) nestedUpdateAllocFromClient(txn *memdb.Txn, watcher watch.I
 		return fmt.Errorf("alloc insert failed: %v", err)
 	}
 
+	// Remove the services registered by tasks of a terminal allocation
+	if copyAlloc.Terminated() {
+		if err := s.deleteServiceRegistrations(index, watcher, txn, "alloc_id", alloc.ID); err != nil {
+			return err
+		}
+	}
+
 	// Set the job's status
 	forceStatus := ""
 	if !copyAlloc.TerminalStatus() {
 		forceStatus = structs.JobStatusRunning
 	}
-	jobs := map[string]string{exist.JobID: forceStatus}
+	jobs := map[structs.NamespacedID]string{
+		structs.NewNamespacedID(exist.JobID, exist.Namespace): forceStatus,
+	}
 	if err := s.setJobStatuses(index, watcher, txn, jobs, false); err != nil {
 		return fmt.Errorf("setting job status failed: %v", err)
 	}
 	return nil
 }
 
+// UpdateAllocsDesiredTransitions is used to update the desired transitions of
+// allocations, creating the evaluations that act on them in the same
+// transaction
+func (s *StateStore) UpdateAllocsDesiredTransitions(index uint64, allocs map[string]*structs.DesiredTransition,
+	evals []*structs.Evaluation) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "allocs"})
+
+	for id, transition := range allocs {
+		existing, err := txn.First("allocs", "id", id)
+		if err != nil {
+			return fmt.Errorf("alloc lookup failed: %v", err)
+		}
+		if existing == nil {
+			continue
+		}
+		exist := existing.(*structs.Allocation)
+		watcher.Add(watch.Item{Alloc: id})
+		watcher.Add(watch.Item{AllocEval: exist.EvalID})
+		watcher.Add(watch.Item{AllocJob: exist.JobID})
+		watcher.Add(watch.Item{AllocNode: exist.NodeID})
+
+		copyAlloc := exist.Copy()
+		copyAlloc.DesiredTransition.Merge(transition)
+		copyAlloc.ModifyIndex = index
+		if err := txn.Insert("allocs", copyAlloc); err != nil {
+			return fmt.Errorf("alloc insert failed: %v", err)
+		}
+	}
+
+	if len(evals) != 0 {
+		watcher.Add(watch.Item{Table: "evals"})
+		for _, eval := range evals {
+			watcher.Add(watch.Item{Eval: eval.ID})
+			watcher.Add(watch.Item{EvalJob: eval.JobID})
+			if err := s.nestedUpsertEval(txn, index, eval); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"allocs", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
 // UpsertAllocs is used to evict a set of allocations
 // and allocate new ones at the same time.
 func (s *StateStore) UpsertAllocs(index uint64, allocs []*structs.Allocation) error {
@@ -927,7 +1320,7 @@ func (s *StateStore) UpsertAllocs(index uint64, allocs []*structs.Allocation) er
 	watcher.Add(watch.Item{Table: "allocs"})
 
 	// Handle the allocations
-	jobs := make(map[string]string, 1)
+	jobs := make(map[structs.NamespacedID]string, 1)
 	for _, alloc := range allocs {
 		existing, err := txn.First("allocs", "id", alloc.ID)
 		if err != nil {
@@ -957,6 +1350,11 @@ func (s *StateStore) UpsertAllocs(index uint64, allocs []*structs.Allocation) er
 			}
 		}
 
+		// Allocations inherit the namespace of their job
+		if alloc.Namespace == "" {
+			alloc.Namespace = allocNamespace(alloc, exist)
+		}
+
 		if err := s.updateSummaryWithAlloc(index, alloc, exist, watcher, txn); err != nil {
 			return fmt.Errorf("error updating job summary: %v", err)
 		}
@@ -976,7 +1374,7 @@ func (s *StateStore) UpsertAllocs(index uint64, allocs []*structs.Allocation) er
 		if !alloc.TerminalStatus() {
 			forceStatus = structs.JobStatusRunning
 		}
-		jobs[alloc.JobID] = forceStatus
+		jobs[structs.NewNamespacedID(alloc.JobID, alloc.Namespace)] = forceStatus
 
 		watcher.Add(watch.Item{Alloc: alloc.ID})
 		watcher.Add(watch.Item{AllocEval: alloc.EvalID})
@@ -1069,12 +1467,12 @@ func (s *StateStore) AllocsByNodeTerminal(node string, terminal bool) ([]*struct
 	return out, nil
 }
 
-// AllocsByJob returns all the allocations by job id
-func (s *StateStore) AllocsByJob(jobID string) ([]*structs.Allocation, error) {
+// AllocsByJob returns all the allocations by job namespace and id
+func (s *StateStore) AllocsByJob(namespace, jobID string) ([]*structs.Allocation, error) {
 	txn := s.db.Txn(false)
 
 	// Get an iterator over the node allocations
-	iter, err := txn.Get("allocs", "job", jobID)
+	iter, err := txn.Get("allocs", "job", namespace, jobID)
 	if err != nil {
 		return nil, err
 	}
@@ -1111,6 +1509,18 @@ func (s *StateStore) AllocsByEval(evalID string) ([]*structs.Allocation, error)
 	return out, nil
 }
 
+// AllocsByNamespace returns an iterator over all the allocations in the
+// given namespace.
+func (s *StateStore) AllocsByNamespace(namespace string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("allocs", "namespace", namespace)
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
 // Allocs returns an iterator over all the evaluations
 func (s *StateStore) Allocs() (memdb.ResultIterator, error) {
 	txn := s.db.Txn(false)
@@ -1236,39 +1646,1862 @@ func (s *StateStore) VaultAccessorsByNode(nodeID string) ([]*structs.VaultAccess
 	return out, nil
 }
 
-// LastIndex returns the greatest index value for all indexes
-func (s *StateStore) LatestIndex() (uint64, error) {
-	indexes, err := s.Indexes()
-	if err != nil {
-		return 0, err
-	}
+// UpsertNamespaces is used to register or update a set of namespaces
+func (s *StateStore) UpsertNamespaces(index uint64, namespaces []*structs.Namespace) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
 
-	var max uint64 = 0
-	for {
-		raw := indexes.Next()
-		if raw == nil {
-			break
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "namespaces"})
+
+	for _, ns := range namespaces {
+		existing, err := txn.First("namespaces", "id", ns.Name)
+		if err != nil {
+			return fmt.Errorf("namespace lookup failed: %v", err)
 		}
 
-		// Prepare the request struct
-		idx := raw.(*IndexEntry)
+		// Ensure the quota specification exists
+		if ns.Quota != "" {
+			quota, err := txn.First("quota_specs", "id", ns.Quota)
+			if err != nil {
+				return fmt.Errorf("quota lookup failed: %v", err)
+			}
+			if quota == nil {
+				return fmt.Errorf("namespace %q references unknown quota %q", ns.Name, ns.Quota)
+			}
+		}
 
-		// Determine the max
-		if idx.Value > max {
-			max = idx.Value
+		if existing != nil {
+			ns.CreateIndex = existing.(*structs.Namespace).CreateIndex
+			ns.ModifyIndex = index
+		} else {
+			ns.CreateIndex = index
+			ns.ModifyIndex = index
+		}
+
+		if err := txn.Insert("namespaces", ns); err != nil {
+			return fmt.Errorf("namespace insert failed: %v", err)
 		}
 	}
 
-	return max, nil
+	if err := txn.Insert("index", &IndexEntry{"namespaces", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
 }
 
-// Index finds the matching index value
-func (s *StateStore) Index(name string) (uint64, error) {
-	txn := s.db.Txn(false)
+// DeleteNamespaces is used to delete a set of namespaces. A namespace can
+// only be deleted once it no longer contains any jobs.
+func (s *StateStore) DeleteNamespaces(index uint64, names []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
 
-	// Lookup the first matching index
-	out, err := txn.First("index", "id", name)
-	if err != nil {
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "namespaces"})
+
+	for _, name := range names {
+		if name == structs.DefaultNamespace {
+			return fmt.Errorf("default namespace can not be deleted")
+		}
+
+		existing, err := txn.First("namespaces", "id", name)
+		if err != nil {
+			return fmt.Errorf("namespace lookup failed: %v", err)
+		}
+		if existing == nil {
+			return fmt.Errorf("namespace %q not found", name)
+		}
+
+		// Ensure the namespace is empty
+		job, err := txn.First("jobs", "namespace", name)
+		if err != nil {
+			return fmt.Errorf("job lookup failed: %v", err)
+		}
+		if job != nil {
+			return fmt.Errorf("namespace %q contains jobs", name)
+		}
+		variable, err := txn.First("variables", "id_prefix", name, "")
+		if err != nil {
+			return fmt.Errorf("variable lookup failed: %v", err)
+		}
+		if variable != nil {
+			return fmt.Errorf("namespace %q contains variables", name)
+		}
+
+		if err := txn.Delete("namespaces", existing); err != nil {
+			return fmt.Errorf("namespace delete failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"namespaces", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// NamespaceByName is used to lookup a namespace by name
+func (s *StateStore) NamespaceByName(name string) (*structs.Namespace, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("namespaces", "id", name)
+	if err != nil {
+		return nil, fmt.Errorf("namespace lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.Namespace), nil
+	}
+	return nil, nil
+}
+
+// NamespacesByNamePrefix is used to lookup namespaces by prefix
+func (s *StateStore) NamespacesByNamePrefix(prefix string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("namespaces", "id_prefix", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("namespace lookup failed: %v", err)
+	}
+	return iter, nil
+}
+
+// Namespaces returns an iterator over all the namespaces
+func (s *StateStore) Namespaces() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("namespaces", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// NamespacesByQuota returns an iterator over the namespaces attached to the
+// given quota specification
+func (s *StateStore) NamespacesByQuota(quota string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("namespaces", "quota", quota)
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// UpsertQuotaSpecs is used to register or update a set of quota
+// specifications
+func (s *StateStore) UpsertQuotaSpecs(index uint64, quotas []*structs.QuotaSpec) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "quota_specs"})
+
+	for _, quota := range quotas {
+		existing, err := txn.First("quota_specs", "id", quota.Name)
+		if err != nil {
+			return fmt.Errorf("quota lookup failed: %v", err)
+		}
+
+		if existing != nil {
+			quota.CreateIndex = existing.(*structs.QuotaSpec).CreateIndex
+			quota.ModifyIndex = index
+		} else {
+			quota.CreateIndex = index
+			quota.ModifyIndex = index
+		}
+
+		if err := txn.Insert("quota_specs", quota); err != nil {
+			return fmt.Errorf("quota insert failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"quota_specs", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// DeleteQuotaSpecs is used to delete a set of quota specifications. A quota
+// can only be deleted once no namespace is attached to it.
+func (s *StateStore) DeleteQuotaSpecs(index uint64, names []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "quota_specs"})
+
+	for _, name := range names {
+		existing, err := txn.First("quota_specs", "id", name)
+		if err != nil {
+			return fmt.Errorf("quota lookup failed: %v", err)
+		}
+		if existing == nil {
+			return fmt.Errorf("quota %q not found", name)
+		}
+
+		// Ensure the quota is not in use
+		ns, err := txn.First("namespaces", "quota", name)
+		if err != nil {
+			return fmt.Errorf("namespace lookup failed: %v", err)
+		}
+		if ns != nil {
+			return fmt.Errorf("quota %q is in use by namespace %q", name, ns.(*structs.Namespace).Name)
+		}
+
+		if err := txn.Delete("quota_specs", existing); err != nil {
+			return fmt.Errorf("quota delete failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"quota_specs", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// QuotaSpecByName is used to lookup a quota specification by name
+func (s *StateStore) QuotaSpecByName(name string) (*structs.QuotaSpec, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("quota_specs", "id", name)
+	if err != nil {
+		return nil, fmt.Errorf("quota lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.QuotaSpec), nil
+	}
+	return nil, nil
+}
+
+// QuotaSpecsByNamePrefix is used to lookup quota specifications by prefix
+func (s *StateStore) QuotaSpecsByNamePrefix(prefix string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("quota_specs", "id_prefix", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("quota lookup failed: %v", err)
+	}
+	return iter, nil
+}
+
+// QuotaSpecs returns an iterator over all the quota specifications
+func (s *StateStore) QuotaSpecs() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("quota_specs", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// QuotaUsage computes the resources consumed in the given region by the
+// non-terminal allocations of the namespaces attached to a quota
+// specification. It returns nil if the quota does not exist.
+func (s *StateStore) QuotaUsage(region, name string) (*structs.QuotaUsage, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("quota_specs", "id", name)
+	if err != nil {
+		return nil, fmt.Errorf("quota lookup failed: %v", err)
+	}
+	if existing == nil {
+		return nil, nil
+	}
+	quota := existing.(*structs.QuotaSpec)
+
+	usage := &structs.QuotaUsage{
+		Name:  name,
+		Used:  &structs.QuotaLimit{Region: region},
+		Limit: quota.RegionLimit(region),
+	}
+
+	namespaces, err := txn.Get("namespaces", "quota", name)
+	if err != nil {
+		return nil, fmt.Errorf("namespace lookup failed: %v", err)
+	}
+	for {
+		raw := namespaces.Next()
+		if raw == nil {
+			break
+		}
+		ns := raw.(*structs.Namespace)
+		usage.Namespaces = append(usage.Namespaces, ns.Name)
+
+		allocs, err := txn.Get("allocs", "namespace", ns.Name)
+		if err != nil {
+			return nil, fmt.Errorf("alloc lookup failed: %v", err)
+		}
+		for {
+			raw := allocs.Next()
+			if raw == nil {
+				break
+			}
+			alloc := raw.(*structs.Allocation)
+			if alloc.TerminalStatus() {
+				continue
+			}
+			usage.Used.Add(alloc)
+		}
+	}
+	return usage, nil
+}
+
+// UpsertACLPolicies is used to create or update a set of ACL policies
+func (s *StateStore) UpsertACLPolicies(index uint64, policies []*structs.ACLPolicy) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "acl_policy"})
+
+	for _, policy := range policies {
+		// Ensure the policy hash is non-nil. This should be done outside the state store
+		// for performance reasons, but we check here for defense in depth.
+		if len(policy.Hash) == 0 {
+			policy.SetHash()
+		}
+
+		// Check if the policy already exists
+		existing, err := txn.First("acl_policy", "id", policy.Name)
+		if err != nil {
+			return fmt.Errorf("policy lookup failed: %v", err)
+		}
+
+		// Update all the indexes
+		if existing != nil {
+			policy.CreateIndex = existing.(*structs.ACLPolicy).CreateIndex
+			policy.ModifyIndex = index
+		} else {
+			policy.CreateIndex = index
+			policy.ModifyIndex = index
+		}
+
+		// Update the policy
+		if err := txn.Insert("acl_policy", policy); err != nil {
+			return fmt.Errorf("upserting policy failed: %v", err)
+		}
+	}
+
+	// Update the indexes table
+	if err := txn.Insert("index", &IndexEntry{"acl_policy", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// DeleteACLPolicies deletes the policies with the given names
+func (s *StateStore) DeleteACLPolicies(index uint64, names []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "acl_policy"})
+
+	// Delete the policy
+	for _, name := range names {
+		if _, err := txn.DeleteAll("acl_policy", "id", name); err != nil {
+			return fmt.Errorf("deleting acl policy failed: %v", err)
+		}
+	}
+	if err := txn.Insert("index", &IndexEntry{"acl_policy", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// ACLPolicyByName is used to lookup a policy by name
+func (s *StateStore) ACLPolicyByName(name string) (*structs.ACLPolicy, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("acl_policy", "id", name)
+	if err != nil {
+		return nil, fmt.Errorf("acl policy lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.ACLPolicy), nil
+	}
+	return nil, nil
+}
+
+// ACLPolicyByNamePrefix is used to lookup policies by prefix
+func (s *StateStore) ACLPolicyByNamePrefix(prefix string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("acl_policy", "id_prefix", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("acl policy lookup failed: %v", err)
+	}
+	return iter, nil
+}
+
+// ACLPolicies returns an iterator over all the acl policies
+func (s *StateStore) ACLPolicies() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("acl_policy", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// UpsertACLTokens is used to create or update a set of ACL tokens
+func (s *StateStore) UpsertACLTokens(index uint64, tokens []*structs.ACLToken) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	if err := s.upsertACLTokensTxn(index, txn, tokens); err != nil {
+		return err
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "acl_token"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// upsertACLTokensTxn is used to create or update a set of ACL tokens within
+// an existing transaction
+func (s *StateStore) upsertACLTokensTxn(index uint64, txn *memdb.Txn, tokens []*structs.ACLToken) error {
+	for _, token := range tokens {
+		// Ensure the token hash is non-nil. This should be done outside the state store
+		// for performance reasons, but we check here for defense in depth.
+		if len(token.Hash) == 0 {
+			token.SetHash()
+		}
+
+		// Check if the token already exists
+		existing, err := txn.First("acl_token", "id", token.AccessorID)
+		if err != nil {
+			return fmt.Errorf("token lookup failed: %v", err)
+		}
+
+		// Update all the indexes
+		if existing != nil {
+			existTK := existing.(*structs.ACLToken)
+			token.CreateIndex = existTK.CreateIndex
+			token.ModifyIndex = index
+
+			// Do not allow SecretID or create time to change
+			token.SecretID = existTK.SecretID
+			token.CreateTime = existTK.CreateTime
+
+		} else {
+			token.CreateIndex = index
+			token.ModifyIndex = index
+		}
+
+		// Update the token
+		if err := txn.Insert("acl_token", token); err != nil {
+			return fmt.Errorf("upserting token failed: %v", err)
+		}
+	}
+
+	// Update the indexes table
+	if err := txn.Insert("index", &IndexEntry{"acl_token", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+	return nil
+}
+
+// DeleteACLTokens deletes the tokens with the given accessor ids
+func (s *StateStore) DeleteACLTokens(index uint64, ids []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "acl_token"})
+
+	// Delete the tokens
+	for _, id := range ids {
+		if _, err := txn.DeleteAll("acl_token", "id", id); err != nil {
+			return fmt.Errorf("deleting acl token failed: %v", err)
+		}
+	}
+	if err := txn.Insert("index", &IndexEntry{"acl_token", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// ACLTokenByAccessorID is used to lookup a token by accessor ID
+func (s *StateStore) ACLTokenByAccessorID(id string) (*structs.ACLToken, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("acl_token", "id", id)
+	if err != nil {
+		return nil, fmt.Errorf("acl token lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.ACLToken), nil
+	}
+	return nil, nil
+}
+
+// ACLTokenBySecretID is used to lookup a token by secret ID
+func (s *StateStore) ACLTokenBySecretID(secretID string) (*structs.ACLToken, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("acl_token", "secret", secretID)
+	if err != nil {
+		return nil, fmt.Errorf("acl token lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.ACLToken), nil
+	}
+	return nil, nil
+}
+
+// ACLTokenByAccessorIDPrefix is used to lookup tokens by prefix
+func (s *StateStore) ACLTokenByAccessorIDPrefix(prefix string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("acl_token", "id_prefix", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("acl token lookup failed: %v", err)
+	}
+	return iter, nil
+}
+
+// ACLTokens returns an iterator over all the tokens
+func (s *StateStore) ACLTokens() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("acl_token", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// ACLTokensByGlobal returns an iterator over all the tokens filtered by global value
+func (s *StateStore) ACLTokensByGlobal(globalVal bool) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("acl_token", "global", globalVal)
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// CanBootstrapACLToken checks if bootstrapping is possible and returns the
+// index of the previous bootstrap, if any
+func (s *StateStore) CanBootstrapACLToken() (bool, uint64, error) {
+	txn := s.db.Txn(false)
+
+	// Lookup the bootstrap sentinel
+	out, err := txn.First("index", "id", "acl_token_bootstrap")
+	if err != nil {
+		return false, 0, err
+	}
+
+	// No entry, we haven't bootstrapped yet
+	if out == nil {
+		return true, 0, nil
+	}
+
+	// Return the reset index if we've already bootstrapped
+	return false, out.(*IndexEntry).Value, nil
+}
+
+// BootstrapACLTokens is used to create an initial ACL token. A reset index
+// matching the index of the previous bootstrap allows bootstrapping again.
+func (s *StateStore) BootstrapACLTokens(index, resetIndex uint64, token *structs.ACLToken) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	// Check if we have already done a bootstrap
+	existing, err := txn.First("index", "id", "acl_token_bootstrap")
+	if err != nil {
+		return fmt.Errorf("bootstrap check failed: %v", err)
+	}
+	if existing != nil {
+		if resetIndex == 0 {
+			return fmt.Errorf("ACL bootstrap already done")
+		} else if resetIndex != existing.(*IndexEntry).Value {
+			return fmt.Errorf("Invalid reset index for ACL bootstrap")
+		}
+	}
+
+	// Update the Create/Modify time
+	token.CreateIndex = index
+	token.ModifyIndex = index
+
+	// Insert the token
+	if err := txn.Insert("acl_token", token); err != nil {
+		return fmt.Errorf("upserting token failed: %v", err)
+	}
+
+	// Update the indexes table, prevents future bootstrap until reset
+	if err := txn.Insert("index", &IndexEntry{"acl_token", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"acl_token_bootstrap", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "acl_token"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// UpsertWorkloadIdentityKeys is used to create or update a set of workload
+// identity signing keys
+func (s *StateStore) UpsertWorkloadIdentityKeys(index uint64, keys []*structs.WorkloadIdentityKey) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, key := range keys {
+		// Check if the key already exists
+		existing, err := txn.First("identity_keys", "id", key.KeyID)
+		if err != nil {
+			return fmt.Errorf("identity key lookup failed: %v", err)
+		}
+
+		// Update all the indexes
+		if existing != nil {
+			key.CreateIndex = existing.(*structs.WorkloadIdentityKey).CreateIndex
+			key.ModifyIndex = index
+		} else {
+			key.CreateIndex = index
+			key.ModifyIndex = index
+		}
+
+		// Update the key
+		if err := txn.Insert("identity_keys", key); err != nil {
+			return fmt.Errorf("upserting identity key failed: %v", err)
+		}
+	}
+
+	// Update the indexes table
+	if err := txn.Insert("index", &IndexEntry{"identity_keys", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "identity_keys"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// WorkloadIdentityKeyByID is used to lookup a workload identity key by its ID
+func (s *StateStore) WorkloadIdentityKeyByID(id string) (*structs.WorkloadIdentityKey, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("identity_keys", "id", id)
+	if err != nil {
+		return nil, fmt.Errorf("identity key lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.WorkloadIdentityKey), nil
+	}
+	return nil, nil
+}
+
+// WorkloadIdentityKeys returns an iterator over all the workload identity keys
+func (s *StateStore) WorkloadIdentityKeys() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("identity_keys", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// ActiveWorkloadIdentityKey returns the key new workload identities are
+// signed with, which is the most recently created key. If no key exists, nil
+// is returned.
+func (s *StateStore) ActiveWorkloadIdentityKey() (*structs.WorkloadIdentityKey, error) {
+	iter, err := s.WorkloadIdentityKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var active *structs.WorkloadIdentityKey
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+
+		key := raw.(*structs.WorkloadIdentityKey)
+		if active == nil || key.CreateIndex > active.CreateIndex {
+			active = key
+		}
+	}
+	return active, nil
+}
+
+// UpsertServiceRegistrations is used to create or update a set of service
+// registrations
+func (s *StateStore) UpsertServiceRegistrations(index uint64, services []*structs.ServiceRegistration) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, service := range services {
+		// Check if the registration already exists
+		existing, err := txn.First("service_registrations", "id", service.ID)
+		if err != nil {
+			return fmt.Errorf("service registration lookup failed: %v", err)
+		}
+
+		// Update all the indexes
+		if existing != nil {
+			service.CreateIndex = existing.(*structs.ServiceRegistration).CreateIndex
+			service.ModifyIndex = index
+		} else {
+			service.CreateIndex = index
+			service.ModifyIndex = index
+		}
+
+		// Update the registration
+		if err := txn.Insert("service_registrations", service); err != nil {
+			return fmt.Errorf("upserting service registration failed: %v", err)
+		}
+	}
+
+	// Update the indexes table
+	if err := txn.Insert("index", &IndexEntry{"service_registrations", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "service_registrations"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// DeleteServiceRegistrations is used to delete a set of service registrations
+// by ID. Registrations that don't exist are ignored.
+func (s *StateStore) DeleteServiceRegistrations(index uint64, ids []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, id := range ids {
+		existing, err := txn.First("service_registrations", "id", id)
+		if err != nil {
+			return fmt.Errorf("service registration lookup failed: %v", err)
+		}
+		if existing == nil {
+			continue
+		}
+		if err := txn.Delete("service_registrations", existing); err != nil {
+			return fmt.Errorf("service registration deletion failed: %v", err)
+		}
+	}
+
+	// Update the indexes table
+	if err := txn.Insert("index", &IndexEntry{"service_registrations", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "service_registrations"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// deleteServiceRegistrations is used to delete the service registrations
+// matching the given index within a transaction
+func (s *StateStore) deleteServiceRegistrations(index uint64, watcher watch.Items,
+	txn *memdb.Txn, indexName, value string) error {
+	num, err := txn.DeleteAll("service_registrations", indexName, value)
+	if err != nil {
+		return fmt.Errorf("service registration deletion failed: %v", err)
+	}
+	if num == 0 {
+		return nil
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"service_registrations", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+	watcher.Add(watch.Item{Table: "service_registrations"})
+	return nil
+}
+
+// ServiceRegistrationByID is used to lookup a service registration by its ID
+func (s *StateStore) ServiceRegistrationByID(id string) (*structs.ServiceRegistration, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("service_registrations", "id", id)
+	if err != nil {
+		return nil, fmt.Errorf("service registration lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.ServiceRegistration), nil
+	}
+	return nil, nil
+}
+
+// ServiceRegistrations returns an iterator over all the service registrations
+func (s *StateStore) ServiceRegistrations() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("service_registrations", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// ServiceRegistrationsByNamespace returns an iterator over the service
+// registrations of a namespace
+func (s *StateStore) ServiceRegistrationsByNamespace(namespace string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("service_registrations", "namespace", namespace)
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// ServiceRegistrationsByName returns the registrations of the service with
+// the given name in a namespace
+func (s *StateStore) ServiceRegistrationsByName(namespace, name string) ([]*structs.ServiceRegistration, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("service_registrations", "service_name", namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*structs.ServiceRegistration
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		out = append(out, raw.(*structs.ServiceRegistration))
+	}
+	return out, nil
+}
+
+// ServiceRegistrationsByAllocID returns the service registrations of the
+// tasks of an allocation
+func (s *StateStore) ServiceRegistrationsByAllocID(allocID string) ([]*structs.ServiceRegistration, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("service_registrations", "alloc_id", allocID)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*structs.ServiceRegistration
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		out = append(out, raw.(*structs.ServiceRegistration))
+	}
+	return out, nil
+}
+
+// UpsertRootKeys is used to create or update a set of keys of the keyring
+func (s *StateStore) UpsertRootKeys(index uint64, keys []*structs.RootKey) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, key := range keys {
+		// Check if the key already exists
+		existing, err := txn.First("root_keys", "id", key.KeyID)
+		if err != nil {
+			return fmt.Errorf("root key lookup failed: %v", err)
+		}
+
+		// Update all the indexes
+		if existing != nil {
+			key.CreateIndex = existing.(*structs.RootKey).CreateIndex
+			key.ModifyIndex = index
+		} else {
+			key.CreateIndex = index
+			key.ModifyIndex = index
+		}
+
+		// Update the key
+		if err := txn.Insert("root_keys", key); err != nil {
+			return fmt.Errorf("upserting root key failed: %v", err)
+		}
+	}
+
+	// Update the indexes table
+	if err := txn.Insert("index", &IndexEntry{"root_keys", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "root_keys"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// UpsertScalingEvent is used to record a scaling event of a task group. Only
+// the most recent JobTrackedScalingEvents events of each task group are kept.
+func (s *StateStore) UpsertScalingEvent(index uint64, req *structs.ScalingEventRegisterRequest) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	namespace := req.RequestNamespace()
+	existing, err := txn.First("scaling_event", "id", namespace, req.JobID)
+	if err != nil {
+		return fmt.Errorf("scaling event lookup failed: %v", err)
+	}
+
+	var events *structs.JobScalingEvents
+	if existing != nil {
+		events = existing.(*structs.JobScalingEvents).Copy()
+	} else {
+		events = &structs.JobScalingEvents{
+			JobID:         req.JobID,
+			Namespace:     namespace,
+			ScalingEvents: make(map[string][]*structs.ScalingEvent),
+		}
+	}
+
+	req.ScalingEvent.CreateIndex = index
+	groupEvents := append([]*structs.ScalingEvent{req.ScalingEvent}, events.ScalingEvents[req.TaskGroup]...)
+	if len(groupEvents) > structs.JobTrackedScalingEvents {
+		groupEvents = groupEvents[:structs.JobTrackedScalingEvents]
+	}
+	events.ScalingEvents[req.TaskGroup] = groupEvents
+	events.ModifyIndex = index
+
+	if err := txn.Insert("scaling_event", events); err != nil {
+		return fmt.Errorf("scaling event insert failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"scaling_event", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "scaling_event"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// ScalingEventsByJob is used to lookup the scaling events of a job
+func (s *StateStore) ScalingEventsByJob(namespace, jobID string) (*structs.JobScalingEvents, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("scaling_event", "id", namespace, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("scaling event lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.JobScalingEvents), nil
+	}
+	return nil, nil
+}
+
+// ScalingEvents returns an iterator over the scaling events of all jobs
+func (s *StateStore) ScalingEvents() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("scaling_event", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// UpsertMultiregionRollout is used to create or update the rollout of a
+// multi-region job. A job has a single rollout, that of its latest version.
+func (s *StateStore) UpsertMultiregionRollout(index uint64, rollout *structs.MultiregionRollout) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	rollout = rollout.Copy()
+	if rollout.Namespace == "" {
+		rollout.Namespace = structs.DefaultNamespace
+	}
+
+	existing, err := txn.First("multiregion_rollout", "id", rollout.Namespace, rollout.JobID)
+	if err != nil {
+		return fmt.Errorf("multi-region rollout lookup failed: %v", err)
+	}
+
+	// A rollout without a create index is that of a new job version and
+	// replaces the existing one. Updates of a replaced rollout are rejected.
+	if rollout.CreateIndex == 0 {
+		rollout.CreateIndex = index
+	} else if existing == nil || existing.(*structs.MultiregionRollout).CreateIndex != rollout.CreateIndex {
+		return fmt.Errorf("multi-region rollout of job %q was replaced", rollout.JobID)
+	}
+	rollout.ModifyIndex = index
+
+	if err := txn.Insert("multiregion_rollout", rollout); err != nil {
+		return fmt.Errorf("multi-region rollout insert failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"multiregion_rollout", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "multiregion_rollout"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// MultiregionRolloutByJob is used to lookup the multi-region rollout of a job
+func (s *StateStore) MultiregionRolloutByJob(namespace, jobID string) (*structs.MultiregionRollout, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("multiregion_rollout", "id", namespace, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("multi-region rollout lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.MultiregionRollout), nil
+	}
+	return nil, nil
+}
+
+// MultiregionRollouts returns an iterator over the multi-region rollouts of
+// all jobs
+func (s *StateStore) MultiregionRollouts() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("multiregion_rollout", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// UpsertJobSubmission is used to store the source of the latest submission of
+// a job, replacing that of the previous submission.
+func (s *StateStore) UpsertJobSubmission(index uint64, submission *structs.JobSubmission) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	submission = submission.Copy()
+	submission.JobModifyIndex = index
+	if submission.Namespace == "" {
+		submission.Namespace = structs.DefaultNamespace
+	}
+
+	if err := txn.Insert("job_submission", submission); err != nil {
+		return fmt.Errorf("job submission insert failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"job_submission", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "job_submission"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// JobSubmissionByJob is used to lookup the source of the latest submission
+// of a job
+func (s *StateStore) JobSubmissionByJob(namespace, jobID string) (*structs.JobSubmission, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("job_submission", "id", namespace, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("job submission lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.JobSubmission), nil
+	}
+	return nil, nil
+}
+
+// JobSubmissions returns an iterator over the sources of the latest
+// submission of all jobs
+func (s *StateStore) JobSubmissions() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("job_submission", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// JobVersionsByID is used to lookup the tracked versions of a job
+func (s *StateStore) JobVersionsByID(namespace, jobID string) (*structs.JobVersions, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("job_version", "id", namespace, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("job version lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.JobVersions), nil
+	}
+	return nil, nil
+}
+
+// JobVersions returns an iterator over the tracked versions of all jobs
+func (s *StateStore) JobVersions() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("job_version", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// AutopilotConfig is used to get the configuration of autopilot, nil if it
+// was never set
+func (s *StateStore) AutopilotConfig() (uint64, *structs.AutopilotConfig, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("autopilot_config", "id")
+	if err != nil {
+		return 0, nil, fmt.Errorf("autopilot config lookup failed: %v", err)
+	}
+
+	config, ok := existing.(*structs.AutopilotConfig)
+	if !ok {
+		return 0, nil, nil
+	}
+	return config.ModifyIndex, config, nil
+}
+
+// AutopilotSetConfig is used to set the configuration of autopilot
+func (s *StateStore) AutopilotSetConfig(index uint64, config *structs.AutopilotConfig) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	if err := s.autopilotSetConfigTxn(index, txn, config); err != nil {
+		return err
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// AutopilotCASConfig is used to set the configuration of autopilot only if
+// its current ModifyIndex is cidx. It returns false, without an error, when
+// the configuration was modified since.
+func (s *StateStore) AutopilotCASConfig(index, cidx uint64, config *structs.AutopilotConfig) (bool, error) {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	existing, err := txn.First("autopilot_config", "id")
+	if err != nil {
+		return false, fmt.Errorf("autopilot config lookup failed: %v", err)
+	}
+
+	// A zero index only matches a configuration that was never set
+	e, ok := existing.(*structs.AutopilotConfig)
+	if (ok && e.ModifyIndex != cidx) || (!ok && cidx != 0) {
+		return false, nil
+	}
+
+	if err := s.autopilotSetConfigTxn(index, txn, config); err != nil {
+		return false, err
+	}
+
+	txn.Commit()
+	return true, nil
+}
+
+func (s *StateStore) autopilotSetConfigTxn(index uint64, txn *memdb.Txn, config *structs.AutopilotConfig) error {
+	existing, err := txn.First("autopilot_config", "id")
+	if err != nil {
+		return fmt.Errorf("autopilot config lookup failed: %v", err)
+	}
+
+	config = config.Copy()
+	if existing != nil {
+		config.CreateIndex = existing.(*structs.AutopilotConfig).CreateIndex
+	} else {
+		config.CreateIndex = index
+	}
+	config.ModifyIndex = index
+
+	if err := txn.Insert("autopilot_config", config); err != nil {
+		return fmt.Errorf("autopilot config insert failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"autopilot_config", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "autopilot_config"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	return nil
+}
+
+// SchedulerConfig is used to get the configuration of the schedulers, nil if
+// it was never set
+func (s *StateStore) SchedulerConfig() (uint64, *structs.SchedulerConfiguration, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("scheduler_config", "id")
+	if err != nil {
+		return 0, nil, fmt.Errorf("scheduler config lookup failed: %v", err)
+	}
+
+	config, ok := existing.(*structs.SchedulerConfiguration)
+	if !ok {
+		return 0, nil, nil
+	}
+	return config.ModifyIndex, config, nil
+}
+
+// SchedulerSetConfig is used to set the configuration of the schedulers
+func (s *StateStore) SchedulerSetConfig(index uint64, config *structs.SchedulerConfiguration) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	if err := s.schedulerSetConfigTxn(index, txn, config); err != nil {
+		return err
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// SchedulerCASConfig is used to set the configuration of the schedulers only
+// if its current ModifyIndex is cidx. It returns false, without an error,
+// when the configuration was modified since.
+func (s *StateStore) SchedulerCASConfig(index, cidx uint64, config *structs.SchedulerConfiguration) (bool, error) {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	existing, err := txn.First("scheduler_config", "id")
+	if err != nil {
+		return false, fmt.Errorf("scheduler config lookup failed: %v", err)
+	}
+
+	// A zero index only matches a configuration that was never set
+	e, ok := existing.(*structs.SchedulerConfiguration)
+	if (ok && e.ModifyIndex != cidx) || (!ok && cidx != 0) {
+		return false, nil
+	}
+
+	if err := s.schedulerSetConfigTxn(index, txn, config); err != nil {
+		return false, err
+	}
+
+	txn.Commit()
+	return true, nil
+}
+
+func (s *StateStore) schedulerSetConfigTxn(index uint64, txn *memdb.Txn, config *structs.SchedulerConfiguration) error {
+	existing, err := txn.First("scheduler_config", "id")
+	if err != nil {
+		return fmt.Errorf("scheduler config lookup failed: %v", err)
+	}
+
+	config = config.Copy()
+	if existing != nil {
+		config.CreateIndex = existing.(*structs.SchedulerConfiguration).CreateIndex
+	} else {
+		config.CreateIndex = index
+	}
+	config.ModifyIndex = index
+
+	if err := txn.Insert("scheduler_config", config); err != nil {
+		return fmt.Errorf("scheduler config insert failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"scheduler_config", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "scheduler_config"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	return nil
+}
+
+// UpsertMaintenanceWindows is used to register or update a set of
+// maintenance windows
+func (s *StateStore) UpsertMaintenanceWindows(index uint64, windows []*structs.MaintenanceWindow) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "maintenance_windows"})
+
+	for _, window := range windows {
+		existing, err := txn.First("maintenance_windows", "id", window.ID)
+		if err != nil {
+			return fmt.Errorf("maintenance window lookup failed: %v", err)
+		}
+
+		if existing != nil {
+			window.CreateIndex = existing.(*structs.MaintenanceWindow).CreateIndex
+			window.ModifyIndex = index
+		} else {
+			window.CreateIndex = index
+			window.ModifyIndex = index
+		}
+
+		if err := txn.Insert("maintenance_windows", window); err != nil {
+			return fmt.Errorf("maintenance window insert failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"maintenance_windows", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// DeleteMaintenanceWindows is used to delete a set of maintenance windows
+func (s *StateStore) DeleteMaintenanceWindows(index uint64, ids []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "maintenance_windows"})
+
+	for _, id := range ids {
+		existing, err := txn.First("maintenance_windows", "id", id)
+		if err != nil {
+			return fmt.Errorf("maintenance window lookup failed: %v", err)
+		}
+		if existing == nil {
+			return fmt.Errorf("maintenance window %q not found", id)
+		}
+
+		if err := txn.Delete("maintenance_windows", existing); err != nil {
+			return fmt.Errorf("maintenance window delete failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"maintenance_windows", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// MaintenanceWindowByID is used to lookup a maintenance window by ID
+func (s *StateStore) MaintenanceWindowByID(id string) (*structs.MaintenanceWindow, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("maintenance_windows", "id", id)
+	if err != nil {
+		return nil, fmt.Errorf("maintenance window lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.MaintenanceWindow), nil
+	}
+	return nil, nil
+}
+
+// MaintenanceWindowsByIDPrefix is used to lookup maintenance windows by
+// prefix
+func (s *StateStore) MaintenanceWindowsByIDPrefix(prefix string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("maintenance_windows", "id_prefix", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("maintenance window lookup failed: %v", err)
+	}
+	return iter, nil
+}
+
+// MaintenanceWindowsByNodeClass returns an iterator over the maintenance
+// windows of a node class
+func (s *StateStore) MaintenanceWindowsByNodeClass(class string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("maintenance_windows", "node_class", class)
+	if err != nil {
+		return nil, fmt.Errorf("maintenance window lookup failed: %v", err)
+	}
+	return iter, nil
+}
+
+// MaintenanceWindows returns an iterator over all the maintenance windows
+func (s *StateStore) MaintenanceWindows() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("maintenance_windows", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// UpsertCSIVolumes is used to register or update a set of CSI volumes. The
+// claims of existing volumes are kept.
+func (s *StateStore) UpsertCSIVolumes(index uint64, volumes []*structs.CSIVolume) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, vol := range volumes {
+		existing, err := txn.First("csi_volumes", "id", vol.ID)
+		if err != nil {
+			return fmt.Errorf("csi volume lookup failed: %v", err)
+		}
+
+		if existing != nil {
+			old := existing.(*structs.CSIVolume)
+			if old.Namespace != vol.Namespace {
+				return fmt.Errorf("csi volume %q exists in namespace %q", vol.ID, old.Namespace)
+			}
+			vol.Claims = old.Claims
+			vol.CreateIndex = old.CreateIndex
+			vol.ModifyIndex = index
+		} else {
+			vol.Claims = nil
+			vol.CreateIndex = index
+			vol.ModifyIndex = index
+		}
+
+		if err := txn.Insert("csi_volumes", vol); err != nil {
+			return fmt.Errorf("csi volume insert failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"csi_volumes", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "csi_volumes"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// DeleteCSIVolumes is used to deregister a set of CSI volumes
+func (s *StateStore) DeleteCSIVolumes(index uint64, ids []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, id := range ids {
+		existing, err := txn.First("csi_volumes", "id", id)
+		if err != nil {
+			return fmt.Errorf("csi volume lookup failed: %v", err)
+		}
+		if existing == nil {
+			return fmt.Errorf("csi volume %q not found", id)
+		}
+
+		if err := txn.Delete("csi_volumes", existing); err != nil {
+			return fmt.Errorf("csi volume delete failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"csi_volumes", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "csi_volumes"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// CSIVolumeClaim is used to record or release the claim of an allocation on
+// a CSI volume
+func (s *StateStore) CSIVolumeClaim(index uint64, volumeID string, claim *structs.CSIVolumeClaim) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	existing, err := txn.First("csi_volumes", "id", volumeID)
+	if err != nil {
+		return fmt.Errorf("csi volume lookup failed: %v", err)
+	}
+	if existing == nil {
+		return fmt.Errorf("csi volume %q not found", volumeID)
+	}
+
+	// Copy the volume since the existing one may be in use by readers
+	vol := existing.(*structs.CSIVolume).Copy()
+	if err := vol.Claim(claim); err != nil {
+		return err
+	}
+	vol.ModifyIndex = index
+
+	if err := txn.Insert("csi_volumes", vol); err != nil {
+		return fmt.Errorf("csi volume insert failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"csi_volumes", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "csi_volumes"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// CSIVolumeByID is used to lookup a CSI volume by ID
+func (s *StateStore) CSIVolumeByID(id string) (*structs.CSIVolume, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("csi_volumes", "id", id)
+	if err != nil {
+		return nil, fmt.Errorf("csi volume lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.CSIVolume), nil
+	}
+	return nil, nil
+}
+
+// CSIVolumes returns an iterator over all the CSI volumes
+func (s *StateStore) CSIVolumes() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("csi_volumes", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// CSIVolumesByNamespace returns an iterator over the CSI volumes of a
+// namespace
+func (s *StateStore) CSIVolumesByNamespace(namespace string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("csi_volumes", "namespace", namespace)
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// CSIVolumesByPluginID returns an iterator over the CSI volumes managed by a
+// plugin
+func (s *StateStore) CSIVolumesByPluginID(pluginID string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("csi_volumes", "plugin_id", pluginID)
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// RootKeyByID is used to lookup a key of the keyring by its ID
+func (s *StateStore) RootKeyByID(id string) (*structs.RootKey, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("root_keys", "id", id)
+	if err != nil {
+		return nil, fmt.Errorf("root key lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.RootKey), nil
+	}
+	return nil, nil
+}
+
+// RootKeys returns an iterator over all the keys of the keyring
+func (s *StateStore) RootKeys() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("root_keys", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// ActiveRootKey returns the key new variables are encrypted with, which is
+// the most recently created key. If no key exists, nil is returned.
+func (s *StateStore) ActiveRootKey() (*structs.RootKey, error) {
+	iter, err := s.RootKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var active *structs.RootKey
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+
+		key := raw.(*structs.RootKey)
+		if active == nil || key.CreateIndex > active.CreateIndex {
+			active = key
+		}
+	}
+	return active, nil
+}
+
+// UpsertVariable is used to create or update an encrypted variable. If
+// checkIndex is set, ErrCASConflict is returned unless it matches the
+// ModifyIndex of the existing variable, or is zero and the variable doesn't
+// exist.
+func (s *StateStore) UpsertVariable(index uint64, variable *structs.VariableEncrypted, checkIndex *uint64) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	existing, err := txn.First("variables", "id", variable.Namespace, variable.Path)
+	if err != nil {
+		return fmt.Errorf("variable lookup failed: %v", err)
+	}
+	if err := checkVariableIndex(existing, checkIndex); err != nil {
+		return err
+	}
+
+	// Ensure the root key exists
+	key, err := txn.First("root_keys", "id", variable.KeyID)
+	if err != nil {
+		return fmt.Errorf("root key lookup failed: %v", err)
+	}
+	if key == nil {
+		return fmt.Errorf("variable %q is encrypted with unknown root key %q", variable.Path, variable.KeyID)
+	}
+
+	// Update all the indexes
+	if existing != nil {
+		exist := existing.(*structs.VariableEncrypted)
+		variable.CreateIndex = exist.CreateIndex
+		variable.CreateTime = exist.CreateTime
+		variable.ModifyIndex = index
+	} else {
+		variable.CreateIndex = index
+		variable.ModifyIndex = index
+	}
+
+	if err := txn.Insert("variables", variable); err != nil {
+		return fmt.Errorf("variable insert failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"variables", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "variables"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// DeleteVariable is used to delete a variable, with the same check-and-set
+// semantics as UpsertVariable. Deleting a missing variable is a no-op.
+func (s *StateStore) DeleteVariable(index uint64, namespace, path string, checkIndex *uint64) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	existing, err := txn.First("variables", "id", namespace, path)
+	if err != nil {
+		return fmt.Errorf("variable lookup failed: %v", err)
+	}
+	if err := checkVariableIndex(existing, checkIndex); err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+
+	if err := txn.Delete("variables", existing); err != nil {
+		return fmt.Errorf("variable delete failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"variables", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "variables"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// RekeyVariables is used to replace the encrypted data of variables that were
+// re-encrypted with another root key. The indexes of the variables are left
+// unchanged since their items are. Variables that were modified or deleted
+// since they were re-encrypted are skipped.
+func (s *StateStore) RekeyVariables(index uint64, variables []*structs.VariableEncrypted) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, variable := range variables {
+		existing, err := txn.First("variables", "id", variable.Namespace, variable.Path)
+		if err != nil {
+			return fmt.Errorf("variable lookup failed: %v", err)
+		}
+		if existing == nil {
+			continue
+		}
+		exist := existing.(*structs.VariableEncrypted)
+		if exist.ModifyIndex != variable.ModifyIndex {
+			continue
+		}
+
+		// Ensure the root key exists
+		key, err := txn.First("root_keys", "id", variable.KeyID)
+		if err != nil {
+			return fmt.Errorf("root key lookup failed: %v", err)
+		}
+		if key == nil {
+			return fmt.Errorf("variable %q is encrypted with unknown root key %q", variable.Path, variable.KeyID)
+		}
+
+		rekeyed := new(structs.VariableEncrypted)
+		*rekeyed = *exist
+		rekeyed.KeyID = variable.KeyID
+		rekeyed.Data = variable.Data
+		if err := txn.Insert("variables", rekeyed); err != nil {
+			return fmt.Errorf("variable insert failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"variables", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	watcher := watch.NewItems()
+	watcher.Add(watch.Item{Table: "variables"})
+	txn.Defer(func() { s.watch.notify(watcher) })
+	txn.Commit()
+	return nil
+}
+
+// checkVariableIndex checks the check-and-set index of a write against the
+// existing variable
+func checkVariableIndex(existing interface{}, checkIndex *uint64) error {
+	if checkIndex == nil {
+		return nil
+	}
+	if existing == nil {
+		if *checkIndex != 0 {
+			return structs.ErrCASConflict
+		}
+		return nil
+	}
+	if existing.(*structs.VariableEncrypted).ModifyIndex != *checkIndex {
+		return structs.ErrCASConflict
+	}
+	return nil
+}
+
+// VariableByPath is used to lookup the variable of a namespace by its path
+func (s *StateStore) VariableByPath(namespace, path string) (*structs.VariableEncrypted, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("variables", "id", namespace, path)
+	if err != nil {
+		return nil, fmt.Errorf("variable lookup failed: %v", err)
+	}
+
+	if existing != nil {
+		return existing.(*structs.VariableEncrypted), nil
+	}
+	return nil, nil
+}
+
+// VariablesByPathPrefix returns an iterator over the variables of a
+// namespace whose path has the given prefix
+func (s *StateStore) VariablesByPathPrefix(namespace, prefix string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("variables", "id_prefix", namespace, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("variable lookup failed: %v", err)
+	}
+	return iter, nil
+}
+
+// Variables returns an iterator over all the variables
+func (s *StateStore) Variables() (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("variables", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// LastIndex returns the greatest index value for all indexes
+func (s *StateStore) LatestIndex() (uint64, error) {
+	indexes, err := s.Indexes()
+	if err != nil {
+		return 0, err
+	}
+
+	var max uint64 = 0
+	for {
+		raw := indexes.Next()
+		if raw == nil {
+			break
+		}
+
+		// Prepare the request struct
+		idx := raw.(*IndexEntry)
+
+		// Determine the max
+		if idx.Value > max {
+			max = idx.Value
+		}
+	}
+
+	return max, nil
+}
+
+// Index finds the matching index value
+func (s *StateStore) Index(name string) (uint64, error) {
+	txn := s.db.Txn(false)
+
+	// Lookup the first matching index
+	out, err := txn.First("index", "id", name)
+	if err != nil {
 		return 0, err
 	}
 	if out == nil {
@@ -1322,15 +3555,16 @@ func (s *StateStore) ReconcileJobSummaries(index uint64) error {
 
 		// Create a job summary for the job
 		summary := structs.JobSummary{
-			JobID:   job.ID,
-			Summary: make(map[string]structs.TaskGroupSummary),
+			JobID:     job.ID,
+			Namespace: job.Namespace,
+			Summary:   make(map[string]structs.TaskGroupSummary),
 		}
 		for _, tg := range job.TaskGroups {
 			summary.Summary[tg.Name] = structs.TaskGroupSummary{}
 		}
 
 		// Find all the allocations for the jobs
-		iterAllocs, err := txn.Get("allocs", "job", job.ID)
+		iterAllocs, err := txn.Get("allocs", "job", job.Namespace, job.ID)
 		if err != nil {
 			return err
 		}
@@ -1387,12 +3621,12 @@ func (s *StateStore) ReconcileJobSummaries(index uint64) error {
 }
 
 // setJobStatuses is a helper for calling setJobStatus on multiple jobs by ID.
-// It takes a map of job IDs to an optional forceStatus string. It returns an
-// error if the job doesn't exist or setJobStatus fails.
+// It takes a map of namespaced job IDs to an optional forceStatus string. It
+// returns an error if the job doesn't exist or setJobStatus fails.
 func (s *StateStore) setJobStatuses(index uint64, watcher watch.Items, txn *memdb.Txn,
-	jobs map[string]string, evalDelete bool) error {
+	jobs map[structs.NamespacedID]string, evalDelete bool) error {
 	for job, forceStatus := range jobs {
-		existing, err := txn.First("jobs", "id", job)
+		existing, err := txn.First("jobs", "id", job.Namespace, job.ID)
 		if err != nil {
 			return fmt.Errorf("job lookup failed: %v", err)
 		}
@@ -1455,7 +3689,7 @@ func (s *StateStore) setJobStatus(index uint64, watcher watch.Items, txn *memdb.
 }
 
 func (s *StateStore) getJobStatus(txn *memdb.Txn, job *structs.Job, evalDelete bool) (string, error) {
-	allocs, err := txn.Get("allocs", "job", job.ID)
+	allocs, err := txn.Get("allocs", "job", job.Namespace, job.ID)
 	if err != nil {
 		return "", err
 	}
@@ -1469,7 +3703,7 @@ func (s *StateStore) getJobStatus(txn *memdb.Txn, job *structs.Job, evalDelete b
 		}
 	}
 
-	evals, err := txn.Get("evals", "job", job.ID)
+	evals, err := txn.Get("evals", "job", job.Namespace, job.ID)
 	if err != nil {
 		return "", err
 	}
@@ -1488,10 +3722,16 @@ func (s *StateStore) getJobStatus(txn *memdb.Txn, job *structs.Job, evalDelete b
 		return structs.JobStatusDead, nil
 	}
 
+	// A stopped job without any outstanding work is dead, even if it never had
+	// an allocation or evaluation.
+	if job.Stopped() {
+		return structs.JobStatusDead, nil
+	}
+
 	// If there are no allocations or evaluations it is a new job. If the job is
-	// periodic, we mark it as running as it will never have an
-	// allocation/evaluation against it.
-	if job.IsPeriodic() {
+	// periodic or parameterized, we mark it as running as it will never have
+	// an allocation/evaluation against it.
+	if job.IsPeriodic() || job.IsParameterized() {
 		return structs.JobStatusRunning, nil
 	}
 	return structs.JobStatusPending, nil
@@ -1502,7 +3742,7 @@ func (s *StateStore) getJobStatus(txn *memdb.Txn, job *structs.Job, evalDelete b
 func (s *StateStore) updateSummaryWithJob(index uint64, job *structs.Job,
 	watcher watch.Items, txn *memdb.Txn) error {
 
-	existing, err := s.JobSummaryByID(job.ID)
+	existing, err := s.JobSummaryByID(job.Namespace, job.ID)
 	if err != nil {
 		return fmt.Errorf("unable to retrieve summary for job: %v", err)
 	}
@@ -1510,6 +3750,7 @@ func (s *StateStore) updateSummaryWithJob(index uint64, job *structs.Job,
 	if existing == nil {
 		existing = &structs.JobSummary{
 			JobID:       job.ID,
+			Namespace:   job.Namespace,
 			Summary:     make(map[string]structs.TaskGroupSummary),
 			CreateIndex: index,
 		}
@@ -1557,13 +3798,13 @@ func (s *StateStore) updateSummaryWithAlloc(index uint64, alloc *structs.Allocat
 		return nil
 	}
 
-	summaryRaw, err := txn.First("job_summary", "id", alloc.JobID)
+	summaryRaw, err := txn.First("job_summary", "id", alloc.Namespace, alloc.JobID)
 	if err != nil {
 		return fmt.Errorf("unable to lookup job summary for job id %q: %v", err)
 	}
 	if summaryRaw == nil {
 		// Check if the job is de-registered
-		rawJob, err := txn.First("jobs", "id", alloc.JobID)
+		rawJob, err := txn.First("jobs", "id", alloc.Namespace, alloc.JobID)
 		if err != nil {
 			return fmt.Errorf("unable to query job: %v", err)
 		}
@@ -1719,9 +3960,28 @@ func (r *StateRestore) JobRestore(job *structs.Job) error {
 	// COMPAT 0.4.1 -> 0.5
 	r.addEphemeralDiskToTaskGroups(job)
 
+	// Place jobs from snapshots that predate namespaces in the default
+	// namespace
+	if job.Namespace == "" {
+		job.Namespace = structs.DefaultNamespace
+	}
+
 	if err := r.txn.Insert("jobs", job); err != nil {
 		return fmt.Errorf("job insert failed: %v", err)
 	}
+
+	// Track the job as its only version for snapshots that predate job
+	// versions. The versions of the snapshot are restored after the jobs and
+	// replace it otherwise.
+	versions := &structs.JobVersions{
+		JobID:       job.ID,
+		Namespace:   job.Namespace,
+		Versions:    []*structs.Job{job},
+		ModifyIndex: job.JobModifyIndex,
+	}
+	if err := r.txn.Insert("job_version", versions); err != nil {
+		return fmt.Errorf("inserting job versions failed: %v", err)
+	}
 	return nil
 }
 
@@ -1729,6 +3989,10 @@ func (r *StateRestore) JobRestore(job *structs.Job) error {
 func (r *StateRestore) EvalRestore(eval *structs.Evaluation) error {
 	r.items.Add(watch.Item{Table: "evals"})
 	r.items.Add(watch.Item{Eval: eval.ID})
+	r.items.Add(watch.Item{EvalJob: eval.JobID})
+	if eval.Namespace == "" {
+		eval.Namespace = structs.DefaultNamespace
+	}
 	if err := r.txn.Insert("evals", eval); err != nil {
 		return fmt.Errorf("eval insert failed: %v", err)
 	}
@@ -1756,6 +4020,10 @@ func (r *StateRestore) AllocRestore(alloc *structs.Allocation) error {
 		r.addEphemeralDiskToTaskGroups(alloc.Job)
 	}
 
+	if alloc.Namespace == "" {
+		alloc.Namespace = allocNamespace(alloc, nil)
+	}
+
 	if err := r.txn.Insert("allocs", alloc); err != nil {
 		return fmt.Errorf("alloc insert failed: %v", err)
 	}
@@ -1774,6 +4042,9 @@ func (r *StateRestore) IndexRestore(idx *IndexEntry) error {
 func (r *StateRestore) PeriodicLaunchRestore(launch *structs.PeriodicLaunch) error {
 	r.items.Add(watch.Item{Table: "periodic_launch"})
 	r.items.Add(watch.Item{Job: launch.ID})
+	if launch.Namespace == "" {
+		launch.Namespace = structs.DefaultNamespace
+	}
 	if err := r.txn.Insert("periodic_launch", launch); err != nil {
 		return fmt.Errorf("periodic launch insert failed: %v", err)
 	}
@@ -1782,6 +4053,9 @@ func (r *StateRestore) PeriodicLaunchRestore(launch *structs.PeriodicLaunch) err
 
 // JobSummaryRestore is used to restore a job summary
 func (r *StateRestore) JobSummaryRestore(jobSummary *structs.JobSummary) error {
+	if jobSummary.Namespace == "" {
+		jobSummary.Namespace = structs.DefaultNamespace
+	}
 	if err := r.txn.Insert("job_summary", *jobSummary); err != nil {
 		return fmt.Errorf("job summary insert failed: %v", err)
 	}
@@ -1796,6 +4070,178 @@ func (r *StateRestore) VaultAccessorRestore(accessor *structs.VaultAccessor) err
 	return nil
 }
 
+// NamespaceRestore is used to restore a namespace
+func (r *StateRestore) NamespaceRestore(ns *structs.Namespace) error {
+	r.items.Add(watch.Item{Table: "namespaces"})
+	if err := r.txn.Insert("namespaces", ns); err != nil {
+		return fmt.Errorf("namespace insert failed: %v", err)
+	}
+	return nil
+}
+
+// QuotaSpecRestore is used to restore a quota specification
+func (r *StateRestore) QuotaSpecRestore(quota *structs.QuotaSpec) error {
+	r.items.Add(watch.Item{Table: "quota_specs"})
+	if err := r.txn.Insert("quota_specs", quota); err != nil {
+		return fmt.Errorf("quota insert failed: %v", err)
+	}
+	return nil
+}
+
+// ACLPolicyRestore is used to restore an ACL policy
+func (r *StateRestore) ACLPolicyRestore(policy *structs.ACLPolicy) error {
+	r.items.Add(watch.Item{Table: "acl_policy"})
+	if err := r.txn.Insert("acl_policy", policy); err != nil {
+		return fmt.Errorf("inserting acl policy failed: %v", err)
+	}
+	return nil
+}
+
+// ACLTokenRestore is used to restore an ACL token
+func (r *StateRestore) ACLTokenRestore(token *structs.ACLToken) error {
+	r.items.Add(watch.Item{Table: "acl_token"})
+	if err := r.txn.Insert("acl_token", token); err != nil {
+		return fmt.Errorf("inserting acl token failed: %v", err)
+	}
+	return nil
+}
+
+// WorkloadIdentityKeyRestore is used to restore a workload identity key
+func (r *StateRestore) WorkloadIdentityKeyRestore(key *structs.WorkloadIdentityKey) error {
+	r.items.Add(watch.Item{Table: "identity_keys"})
+	if err := r.txn.Insert("identity_keys", key); err != nil {
+		return fmt.Errorf("inserting identity key failed: %v", err)
+	}
+	return nil
+}
+
+// ServiceRegistrationRestore is used to restore a service registration
+func (r *StateRestore) ServiceRegistrationRestore(service *structs.ServiceRegistration) error {
+	r.items.Add(watch.Item{Table: "service_registrations"})
+	if err := r.txn.Insert("service_registrations", service); err != nil {
+		return fmt.Errorf("inserting service registration failed: %v", err)
+	}
+	return nil
+}
+
+// ScalingEventsRestore is used to restore the scaling events of a job
+func (r *StateRestore) ScalingEventsRestore(events *structs.JobScalingEvents) error {
+	r.items.Add(watch.Item{Table: "scaling_event"})
+	if events.Namespace == "" {
+		events.Namespace = structs.DefaultNamespace
+	}
+	if err := r.txn.Insert("scaling_event", events); err != nil {
+		return fmt.Errorf("inserting scaling events failed: %v", err)
+	}
+	return nil
+}
+
+// MultiregionRolloutRestore is used to restore the multi-region rollout of a
+// job
+func (r *StateRestore) MultiregionRolloutRestore(rollout *structs.MultiregionRollout) error {
+	r.items.Add(watch.Item{Table: "multiregion_rollout"})
+	if rollout.Namespace == "" {
+		rollout.Namespace = structs.DefaultNamespace
+	}
+	if err := r.txn.Insert("multiregion_rollout", rollout); err != nil {
+		return fmt.Errorf("inserting multi-region rollout failed: %v", err)
+	}
+	return nil
+}
+
+// JobVersionsRestore is used to restore the tracked versions of a job
+func (r *StateRestore) JobVersionsRestore(versions *structs.JobVersions) error {
+	r.items.Add(watch.Item{Table: "job_version"})
+	if versions.Namespace == "" {
+		versions.Namespace = structs.DefaultNamespace
+	}
+	if err := r.txn.Insert("job_version", versions); err != nil {
+		return fmt.Errorf("inserting job versions failed: %v", err)
+	}
+	return nil
+}
+
+// JobSubmissionRestore is used to restore the source of the latest
+// submission of a job
+func (r *StateRestore) JobSubmissionRestore(submission *structs.JobSubmission) error {
+	r.items.Add(watch.Item{Table: "job_submission"})
+	if submission.Namespace == "" {
+		submission.Namespace = structs.DefaultNamespace
+	}
+	if err := r.txn.Insert("job_submission", submission); err != nil {
+		return fmt.Errorf("inserting job submission failed: %v", err)
+	}
+	return nil
+}
+
+// AutopilotConfigRestore is used to restore the configuration of autopilot
+func (r *StateRestore) AutopilotConfigRestore(config *structs.AutopilotConfig) error {
+	r.items.Add(watch.Item{Table: "autopilot_config"})
+	if err := r.txn.Insert("autopilot_config", config); err != nil {
+		return fmt.Errorf("inserting autopilot config failed: %v", err)
+	}
+	return nil
+}
+
+// SchedulerConfigRestore is used to restore the configuration of the
+// schedulers
+func (r *StateRestore) SchedulerConfigRestore(config *structs.SchedulerConfiguration) error {
+	r.items.Add(watch.Item{Table: "scheduler_config"})
+	if err := r.txn.Insert("scheduler_config", config); err != nil {
+		return fmt.Errorf("inserting scheduler config failed: %v", err)
+	}
+	return nil
+}
+
+// MaintenanceWindowRestore is used to restore a maintenance window
+func (r *StateRestore) MaintenanceWindowRestore(window *structs.MaintenanceWindow) error {
+	r.items.Add(watch.Item{Table: "maintenance_windows"})
+	if err := r.txn.Insert("maintenance_windows", window); err != nil {
+		return fmt.Errorf("maintenance window insert failed: %v", err)
+	}
+	return nil
+}
+
+// CSIVolumeRestore is used to restore a CSI volume
+func (r *StateRestore) CSIVolumeRestore(vol *structs.CSIVolume) error {
+	r.items.Add(watch.Item{Table: "csi_volumes"})
+	if err := r.txn.Insert("csi_volumes", vol); err != nil {
+		return fmt.Errorf("csi volume insert failed: %v", err)
+	}
+	return nil
+}
+
+// RootKeyRestore is used to restore a key of the keyring
+func (r *StateRestore) RootKeyRestore(key *structs.RootKey) error {
+	r.items.Add(watch.Item{Table: "root_keys"})
+	if err := r.txn.Insert("root_keys", key); err != nil {
+		return fmt.Errorf("inserting root key failed: %v", err)
+	}
+	return nil
+}
+
+// VariableRestore is used to restore an encrypted variable
+func (r *StateRestore) VariableRestore(variable *structs.VariableEncrypted) error {
+	r.items.Add(watch.Item{Table: "variables"})
+	if err := r.txn.Insert("variables", variable); err != nil {
+		return fmt.Errorf("inserting variable failed: %v", err)
+	}
+	return nil
+}
+
+// allocNamespace returns the namespace an allocation without one belongs to.
+// It is derived from the allocation's job, falling back to the existing
+// allocation and finally the default namespace.
+func allocNamespace(alloc, existing *structs.Allocation) string {
+	if alloc.Job != nil && alloc.Job.Namespace != "" {
+		return alloc.Job.Namespace
+	}
+	if existing != nil && existing.Namespace != "" {
+		return existing.Namespace
+	}
+	return structs.DefaultNamespace
+}
+
 // addEphemeralDiskToTaskGroups adds missing EphemeralDisk objects to TaskGroups
 func (r *StateRestore) addEphemeralDiskToTaskGroups(job *structs.Job) {
 	for _, tg := range job.TaskGroups {
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"fmt"
 	"os"
 	"reflect"
 	"sort"
@@ -111,7 +112,7 @@ func TestStateStore_UpdateNodeStatus_Node(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	err = state.UpdateNodeStatus(801, node.ID, structs.NodeStatusReady)
+	err = state.UpdateNodeStatus(801, node.ID, structs.NodeStatusReady, nil)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -153,7 +154,8 @@ func TestStateStore_UpdateNodeDrain_Node(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	err = state.UpdateNodeDrain(1001, node.ID, true)
+	drain := structs.NewDrainStrategy(structs.DrainSpec{Deadline: time.Hour}, time.Now())
+	err = state.UpdateNodeDrain(1001, node.ID, drain, nil)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -163,7 +165,129 @@ func TestStateStore_UpdateNodeDrain_Node(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	if !out.Drain {
+	if !out.Drain || !reflect.DeepEqual(out.DrainStrategy, drain) {
+		t.Fatalf("bad: %#v", out)
+	}
+	if out.SchedulingEligibility != structs.NodeSchedulingIneligible {
+		t.Fatalf("bad: %#v", out)
+	}
+	if out.ModifyIndex != 1001 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("nodes")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+
+	notify.verify(t)
+
+	// Re-registering the node keeps its drain
+	if err := state.UpsertNode(1002, node.Copy()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !out.Drain || !reflect.DeepEqual(out.DrainStrategy, drain) {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Stopping the drain makes the node eligible again
+	if err := state.UpdateNodeDrain(1003, node.ID, nil, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Drain || out.DrainStrategy != nil || out.SchedulingEligibility != structs.NodeSchedulingEligible {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestStateStore_NodeEvents(t *testing.T) {
+	state := testStateStore(t)
+	node := mock.Node()
+	node.Events = []*structs.NodeEvent{structs.NewNodeEvent(structs.NodeEventSubsystemCluster, "Node registered")}
+
+	if err := state.UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Record more events than are retained
+	for i := 0; i < structs.MaxRetainedNodeEvents; i++ {
+		event := structs.NewNodeEvent(structs.NodeEventSubsystemDrain, fmt.Sprintf("event %d", i))
+		var drain *structs.DrainStrategy
+		if i%2 == 0 {
+			drain = &structs.DrainStrategy{}
+		}
+		if err := state.UpdateNodeDrain(uint64(1001+i), node.ID, drain, event); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Re-registering the node keeps its events
+	node2 := node.Copy()
+	node2.Events = nil
+	if err := state.UpsertNode(2001, node2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out.Events) != structs.MaxRetainedNodeEvents {
+		t.Fatalf("bad: %d", len(out.Events))
+	}
+	first, last := out.Events[0], out.Events[len(out.Events)-1]
+	if first.Message != "event 0" || first.CreateIndex != 1001 {
+		t.Fatalf("bad: %#v", first)
+	}
+	if last.Message != fmt.Sprintf("event %d", structs.MaxRetainedNodeEvents-1) {
+		t.Fatalf("bad: %#v", last)
+	}
+}
+
+func TestStateStore_UpdateNodeEligibility_Node(t *testing.T) {
+	state := testStateStore(t)
+	node := mock.Node()
+	node.SchedulingEligibility = ""
+
+	err := state.UpsertNode(1000, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// New nodes are eligible for scheduling
+	out, err := state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.SchedulingEligibility != structs.NodeSchedulingEligible {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "nodes"},
+		watch.Item{Node: node.ID})
+
+	err = state.UpdateNodeEligibility(1001, node.ID, structs.NodeSchedulingIneligible, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err = state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.SchedulingEligibility != structs.NodeSchedulingIneligible || out.Ready() {
 		t.Fatalf("bad: %#v", out)
 	}
 	if out.ModifyIndex != 1001 {
@@ -179,6 +303,88 @@ func TestStateStore_UpdateNodeDrain_Node(t *testing.T) {
 	}
 
 	notify.verify(t)
+
+	// Re-registering the node retains its eligibility
+	node2 := mock.Node()
+	node2.ID = node.ID
+	node2.SchedulingEligibility = ""
+	if err := state.UpsertNode(1002, node2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.SchedulingEligibility != structs.NodeSchedulingIneligible {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestStateStore_UpdateNodeReliability(t *testing.T) {
+	state := testStateStore(t)
+	node := mock.Node()
+	if err := state.UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "nodes"},
+		watch.Item{Node: node.ID})
+
+	// Penalize the node and one that doesn't exist
+	now := time.Now()
+	penalties := map[string]float64{node.ID: 10, structs.GenerateUUID(): 5}
+	if err := state.UpdateNodeReliability(1001, penalties, now.UnixNano(), time.Hour); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := structs.NodeReliability{Penalty: 10, UpdateTime: now.UnixNano()}
+	if out.Reliability != expected || out.ModifyIndex != 1001 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("nodes")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+
+	notify.verify(t)
+
+	// The existing penalty decays before the new one is added
+	later := now.Add(time.Hour)
+	penalties = map[string]float64{node.ID: 5}
+	if err := state.UpdateNodeReliability(1002, penalties, later.UnixNano(), time.Hour); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Reliability.Penalty != 10.0 || out.Reliability.UpdateTime != later.UnixNano() {
+		t.Fatalf("bad: %#v", out.Reliability)
+	}
+
+	// Re-registering the node retains its reliability
+	node2 := mock.Node()
+	node2.ID = node.ID
+	if err := state.UpsertNode(1003, node2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Reliability.Penalty != 10.0 {
+		t.Fatalf("bad: %#v", out.Reliability)
+	}
 }
 
 func TestStateStore_Nodes(t *testing.T) {
@@ -217,6 +423,57 @@ func TestStateStore_Nodes(t *testing.T) {
 	}
 }
 
+func TestStateStore_NodesByDatacenterStatus(t *testing.T) {
+	state := testStateStore(t)
+
+	node1 := mock.Node()
+	node1.NodeClass = ""
+	node2 := mock.Node()
+	node2.NodeClass = "large"
+	node3 := mock.Node()
+	node3.Datacenter = "dc10"
+	node4 := mock.Node()
+	for i, node := range []*structs.Node{node1, node2, node3, node4} {
+		if err := state.UpsertNode(1000+uint64(i), node); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if err := state.UpdateNodeStatus(1004, node4.ID, structs.NodeStatusDown, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	collect := func(iter memdb.ResultIterator, err error) []*structs.Node {
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		var out []*structs.Node
+		for raw := iter.Next(); raw != nil; raw = iter.Next() {
+			out = append(out, raw.(*structs.Node))
+		}
+		sort.Sort(NodeIDSort(out))
+		return out
+	}
+
+	// Nodes of all classes are returned, excluding other datacenters and
+	// statuses
+	expected := []*structs.Node{node1, node2}
+	sort.Sort(NodeIDSort(expected))
+	out := collect(state.NodesByDatacenterStatus("dc1", structs.NodeStatusReady))
+	if !reflect.DeepEqual(out, expected) {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	out = collect(state.NodesByDatacenterStatus("dc1", structs.NodeStatusDown))
+	if len(out) != 1 || out[0].ID != node4.ID {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	out = collect(state.NodesByDatacenterStatusClass("dc1", structs.NodeStatusReady, "large"))
+	if len(out) != 1 || out[0].ID != node2.ID {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
 func TestStateStore_NodesByIDPrefix(t *testing.T) {
 	state := testStateStore(t)
 	node := mock.Node()
@@ -334,7 +591,7 @@ func TestStateStore_UpsertJob_Job(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -351,7 +608,7 @@ func TestStateStore_UpsertJob_Job(t *testing.T) {
 		t.Fatalf("bad: %d", index)
 	}
 
-	summary, err := state.JobSummaryByID(job.ID)
+	summary, err := state.JobSummaryByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -389,7 +646,7 @@ func TestStateStore_UpdateUpsertJob_Job(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -415,7 +672,7 @@ func TestStateStore_UpdateUpsertJob_Job(t *testing.T) {
 
 	// Test that the job summary remains the same if the job is updated but
 	// count remains same
-	summary, err := state.JobSummaryByID(job.ID)
+	summary, err := state.JobSummaryByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -449,7 +706,7 @@ func TestStateStore_UpsertJob_NoEphemeralDisk(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -481,12 +738,12 @@ func TestStateStore_DeleteJob_Job(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	err = state.DeleteJob(1001, job.ID)
+	err = state.DeleteJob(1001, job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.JobByID(job.ID)
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -503,7 +760,7 @@ func TestStateStore_DeleteJob_Job(t *testing.T) {
 		t.Fatalf("bad: %d", index)
 	}
 
-	summary, err := state.JobSummaryByID(job.ID)
+	summary, err := state.JobSummaryByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -514,736 +771,829 @@ func TestStateStore_DeleteJob_Job(t *testing.T) {
 	notify.verify(t)
 }
 
-func TestStateStore_Jobs(t *testing.T) {
+func TestStateStore_Job_SameIDNamespaces(t *testing.T) {
 	state := testStateStore(t)
-	var jobs []*structs.Job
+	job1 := mock.Job()
+	job2 := mock.Job()
+	job2.ID = job1.ID
+	job2.Namespace = "engineering"
 
-	for i := 0; i < 10; i++ {
-		job := mock.Job()
-		jobs = append(jobs, job)
+	if err := state.UpsertJob(1000, job1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJob(1001, job2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-		err := state.UpsertJob(1000+uint64(i), job)
+	eval := mock.Eval()
+	eval.Namespace = job2.Namespace
+	eval.JobID = job2.ID
+	if err := state.UpsertEvals(1002, []*structs.Evaluation{eval}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	alloc := mock.Alloc()
+	alloc.Namespace = job2.Namespace
+	alloc.Job = job2
+	alloc.JobID = job2.ID
+	if err := state.UpsertAllocs(1003, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Each namespace sees its own job
+	for _, job := range []*structs.Job{job1, job2} {
+		out, err := state.JobByID(job.Namespace, job.ID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out == nil || out.Namespace != job.Namespace || out.CreateIndex != job.CreateIndex {
+			t.Fatalf("bad: %#v", out)
+		}
+		summary, err := state.JobSummaryByID(job.Namespace, job.ID)
 		if err != nil {
 			t.Fatalf("err: %v", err)
 		}
+		if summary == nil {
+			t.Fatalf("expected summary for job in namespace %q", job.Namespace)
+		}
 	}
 
-	iter, err := state.Jobs()
+	// Evals and allocs are only found through their own namespace
+	evals, err := state.EvalsByJob(job1.Namespace, job1.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	var out []*structs.Job
-	for {
-		raw := iter.Next()
-		if raw == nil {
-			break
-		}
-		out = append(out, raw.(*structs.Job))
+	if len(evals) != 0 {
+		t.Fatalf("bad: %#v", evals)
 	}
-
-	sort.Sort(JobIDSort(jobs))
-	sort.Sort(JobIDSort(out))
-
-	if !reflect.DeepEqual(jobs, out) {
-		t.Fatalf("bad: %#v %#v", jobs, out)
+	evals, err = state.EvalsByJob(job2.Namespace, job2.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
-}
-
-func TestStateStore_JobsByIDPrefix(t *testing.T) {
-	state := testStateStore(t)
-	job := mock.Job()
-
-	job.ID = "redis"
-	err := state.UpsertJob(1000, job)
+	if len(evals) != 1 || evals[0].ID != eval.ID {
+		t.Fatalf("bad: %#v", evals)
+	}
+	allocs, err := state.AllocsByJob(job1.Namespace, job1.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	iter, err := state.JobsByIDPrefix(job.ID)
+	if len(allocs) != 0 {
+		t.Fatalf("bad: %#v", allocs)
+	}
+	allocs, err = state.AllocsByJob(job2.Namespace, job2.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	gatherJobs := func(iter memdb.ResultIterator) []*structs.Job {
-		var jobs []*structs.Job
-		for {
-			raw := iter.Next()
-			if raw == nil {
-				break
-			}
-			jobs = append(jobs, raw.(*structs.Job))
-		}
-		return jobs
+	if len(allocs) != 1 || allocs[0].ID != alloc.ID {
+		t.Fatalf("bad: %#v", allocs)
 	}
 
-	jobs := gatherJobs(iter)
-	if len(jobs) != 1 {
+	// Deleting one job leaves the other in place
+	if err := state.DeleteJob(1004, job2.Namespace, job2.ID); err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	iter, err = state.JobsByIDPrefix("re")
+	out, err := state.JobByID(job1.Namespace, job1.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	jobs = gatherJobs(iter)
-	if len(jobs) != 1 {
-		t.Fatalf("err: %v", err)
+	if out == nil {
+		t.Fatalf("expected job in namespace %q", job1.Namespace)
 	}
-
-	job = mock.Job()
-	job.ID = "riak"
-	err = state.UpsertJob(1001, job)
+	out, err = state.JobByID(job2.Namespace, job2.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
 
-	iter, err = state.JobsByIDPrefix("r")
-	if err != nil {
+func TestStateStore_UpsertJobSubmission(t *testing.T) {
+	state := testStateStore(t)
+	job := mock.Job()
+
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "job_submission"})
+
+	if err := state.UpsertJob(1000, job); err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	jobs = gatherJobs(iter)
-	if len(jobs) != 2 {
+	sub := &structs.JobSubmission{
+		JobID:  job.ID,
+		Source: `job "example" {}`,
+		Format: structs.JobSubmissionFormatHCL,
+	}
+	if err := state.UpsertJobSubmission(1000, sub); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	iter, err = state.JobsByIDPrefix("ri")
+	out, err := state.JobSubmissionByJob(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if out == nil || out.Source != sub.Source || out.JobModifyIndex != 1000 {
+		t.Fatalf("bad: %#v", out)
+	}
 
-	jobs = gatherJobs(iter)
-	if len(jobs) != 1 {
+	index, err := state.Index("job_submission")
+	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-}
-
-func TestStateStore_JobsByPeriodic(t *testing.T) {
-	state := testStateStore(t)
-	var periodic, nonPeriodic []*structs.Job
-
-	for i := 0; i < 10; i++ {
-		job := mock.Job()
-		nonPeriodic = append(nonPeriodic, job)
-
-		err := state.UpsertJob(1000+uint64(i), job)
-		if err != nil {
-			t.Fatalf("err: %v", err)
-		}
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
 	}
 
-	for i := 0; i < 10; i++ {
-		job := mock.PeriodicJob()
-		periodic = append(periodic, job)
-
-		err := state.UpsertJob(2000+uint64(i), job)
-		if err != nil {
-			t.Fatalf("err: %v", err)
-		}
+	// Purging the job deletes its source
+	if err := state.DeleteJob(1001, job.Namespace, job.ID); err != nil {
+		t.Fatalf("err: %v", err)
 	}
-
-	iter, err := state.JobsByPeriodic(true)
+	out, err = state.JobSubmissionByJob(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	var outPeriodic []*structs.Job
-	for {
-		raw := iter.Next()
-		if raw == nil {
-			break
-		}
-		outPeriodic = append(outPeriodic, raw.(*structs.Job))
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
 	}
 
-	iter, err = state.JobsByPeriodic(false)
+	index, err = state.Index("job_submission")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	var outNonPeriodic []*structs.Job
-	for {
-		raw := iter.Next()
-		if raw == nil {
-			break
-		}
-		outNonPeriodic = append(outNonPeriodic, raw.(*structs.Job))
-	}
-
-	sort.Sort(JobIDSort(periodic))
-	sort.Sort(JobIDSort(nonPeriodic))
-	sort.Sort(JobIDSort(outPeriodic))
-	sort.Sort(JobIDSort(outNonPeriodic))
-
-	if !reflect.DeepEqual(periodic, outPeriodic) {
-		t.Fatalf("bad: %#v %#v", periodic, outPeriodic)
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
 	}
 
-	if !reflect.DeepEqual(nonPeriodic, outNonPeriodic) {
-		t.Fatalf("bad: %#v %#v", nonPeriodic, outNonPeriodic)
-	}
+	notify.verify(t)
 }
 
-func TestStateStore_JobsByScheduler(t *testing.T) {
+func TestStateStore_UpsertJob_Versions(t *testing.T) {
 	state := testStateStore(t)
-	var serviceJobs []*structs.Job
-	var sysJobs []*structs.Job
+	job := mock.Job()
 
-	for i := 0; i < 10; i++ {
-		job := mock.Job()
-		serviceJobs = append(serviceJobs, job)
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "job_version"})
 
-		err := state.UpsertJob(1000+uint64(i), job)
-		if err != nil {
+	// Register more versions than are tracked
+	for i := 0; i < structs.JobTrackedVersions+2; i++ {
+		update := job.Copy()
+		update.Priority = 10 + i
+		if err := state.UpsertJob(uint64(1000+i), update); err != nil {
 			t.Fatalf("err: %v", err)
 		}
 	}
 
-	for i := 0; i < 10; i++ {
-		job := mock.SystemJob()
-		sysJobs = append(sysJobs, job)
-
-		err := state.UpsertJob(2000+uint64(i), job)
-		if err != nil {
-			t.Fatalf("err: %v", err)
-		}
+	out, err := state.JobByID(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	latest := uint64(structs.JobTrackedVersions + 1)
+	if out.Version != latest {
+		t.Fatalf("bad: %d", out.Version)
 	}
 
-	iter, err := state.JobsByScheduler("service")
+	// Only the most recent versions are kept, most recent first
+	versions, err := state.JobVersionsByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	var outService []*structs.Job
-	for {
-		raw := iter.Next()
-		if raw == nil {
-			break
+	if len(versions.Versions) != structs.JobTrackedVersions {
+		t.Fatalf("bad: %#v", versions)
+	}
+	for i, v := range versions.Versions {
+		if v.Version != latest-uint64(i) || v.Priority != 10+int(v.Version) {
+			t.Fatalf("bad version %d: %#v", i, v)
 		}
-		outService = append(outService, raw.(*structs.Job))
+	}
+	if versions.Version(latest) != out || versions.Version(0) != nil {
+		t.Fatalf("bad: %#v", versions)
 	}
 
-	iter, err = state.JobsByScheduler("system")
+	// Purging the job deletes its versions
+	if err := state.DeleteJob(2000, job.Namespace, job.ID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	versions, err = state.JobVersionsByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	var outSystem []*structs.Job
-	for {
-		raw := iter.Next()
-		if raw == nil {
-			break
-		}
-		outSystem = append(outSystem, raw.(*structs.Job))
+	if versions != nil {
+		t.Fatalf("bad: %#v", versions)
 	}
 
-	sort.Sort(JobIDSort(serviceJobs))
-	sort.Sort(JobIDSort(sysJobs))
-	sort.Sort(JobIDSort(outService))
-	sort.Sort(JobIDSort(outSystem))
-
-	if !reflect.DeepEqual(serviceJobs, outService) {
-		t.Fatalf("bad: %#v %#v", serviceJobs, outService)
+	index, err := state.Index("job_version")
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
-
-	if !reflect.DeepEqual(sysJobs, outSystem) {
-		t.Fatalf("bad: %#v %#v", sysJobs, outSystem)
+	if index != 2000 {
+		t.Fatalf("bad: %d", index)
 	}
+
+	notify.verify(t)
 }
 
-func TestStateStore_JobsByGC(t *testing.T) {
+func TestStateStore_UpsertScalingEvent(t *testing.T) {
 	state := testStateStore(t)
-	var gc, nonGc []*structs.Job
+	job := mock.Job()
 
-	for i := 0; i < 20; i++ {
-		var job *structs.Job
-		if i%2 == 0 {
-			job = mock.Job()
-		} else {
-			job = mock.PeriodicJob()
-		}
-		nonGc = append(nonGc, job)
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "scaling_event"})
 
-		if err := state.UpsertJob(1000+uint64(i), job); err != nil {
-			t.Fatalf("err: %v", err)
-		}
+	if err := state.UpsertJob(1000, job); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	for i := 0; i < 10; i++ {
-		job := mock.Job()
-		job.Type = structs.JobTypeBatch
-		gc = append(gc, job)
-
-		if err := state.UpsertJob(2000+uint64(i), job); err != nil {
+	// Only the most recent events of the task group are kept
+	total := structs.JobTrackedScalingEvents + 5
+	for i := 0; i < total; i++ {
+		count := int64(i)
+		req := &structs.ScalingEventRegisterRequest{
+			JobID:     job.ID,
+			TaskGroup: "web",
+			ScalingEvent: &structs.ScalingEvent{
+				Count:   &count,
+				Message: fmt.Sprintf("scale %d", i),
+			},
+		}
+		if err := state.UpsertScalingEvent(1001+uint64(i), req); err != nil {
 			t.Fatalf("err: %v", err)
 		}
 	}
 
-	iter, err := state.JobsByGC(true)
+	out, err := state.ScalingEventsByJob(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	var outGc []*structs.Job
-	for i := iter.Next(); i != nil; i = iter.Next() {
-		outGc = append(outGc, i.(*structs.Job))
+	events := out.ScalingEvents["web"]
+	if len(events) != structs.JobTrackedScalingEvents {
+		t.Fatalf("bad: %#v", events)
+	}
+	last := uint64(1000 + total)
+	if events[0].Message != fmt.Sprintf("scale %d", total-1) || events[0].CreateIndex != last {
+		t.Fatalf("bad: %#v", events[0])
+	}
+	if out.ModifyIndex != last {
+		t.Fatalf("bad: %#v", out)
 	}
 
-	iter, err = state.JobsByGC(false)
+	index, err := state.Index("scaling_event")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	var outNonGc []*structs.Job
-	for i := iter.Next(); i != nil; i = iter.Next() {
-		outNonGc = append(outNonGc, i.(*structs.Job))
+	if index != last {
+		t.Fatalf("bad: %d", index)
 	}
 
-	sort.Sort(JobIDSort(gc))
-	sort.Sort(JobIDSort(nonGc))
-	sort.Sort(JobIDSort(outGc))
-	sort.Sort(JobIDSort(outNonGc))
-
-	if !reflect.DeepEqual(gc, outGc) {
-		t.Fatalf("bad: %#v %#v", gc, outGc)
+	// The events are deleted along with the job
+	if err := state.DeleteJob(last+1, job.Namespace, job.ID); err != nil {
+		t.Fatalf("err: %v", err)
 	}
-
-	if !reflect.DeepEqual(nonGc, outNonGc) {
-		t.Fatalf("bad: %#v %#v", nonGc, outNonGc)
+	out, err = state.ScalingEventsByJob(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
 	}
+
+	notify.verify(t)
 }
 
-func TestStateStore_RestoreJob(t *testing.T) {
+func TestStateStore_UpsertMultiregionRollout(t *testing.T) {
 	state := testStateStore(t)
 	job := mock.Job()
 
 	notify := setupNotifyTest(
 		state,
-		watch.Item{Table: "jobs"},
-		watch.Item{Job: job.ID})
+		watch.Item{Table: "multiregion_rollout"})
 
-	restore, err := state.Restore()
-	if err != nil {
+	if err := state.UpsertJob(1000, job); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	err = restore.JobRestore(job)
-	if err != nil {
+	rollout := &structs.MultiregionRollout{
+		JobID:    job.ID,
+		Strategy: &structs.MultiregionStrategy{MaxParallel: 1},
+		Status:   structs.MultiregionRolloutStatusRunning,
+		Regions: []*structs.MultiregionRolloutRegion{
+			&structs.MultiregionRolloutRegion{Region: "region1", Status: structs.MultiregionRegionStatusRunning},
+			&structs.MultiregionRolloutRegion{Region: "region2", Status: structs.MultiregionRegionStatusPending},
+		},
+	}
+	if err := state.UpsertMultiregionRollout(1001, rollout); err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	restore.Commit()
 
-	out, err := state.JobByID(job.ID)
+	out, err := state.MultiregionRolloutByJob(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	if !reflect.DeepEqual(out, job) {
-		t.Fatalf("Bad: %#v %#v", out, job)
+	if out == nil || out.CreateIndex != 1001 || out.ModifyIndex != 1001 || len(out.Regions) != 2 {
+		t.Fatalf("bad: %#v", out)
 	}
 
-	notify.verify(t)
-}
-
-// This test ensures that the state restore creates the EphemeralDisk for a job if
-// it doesn't have one
-// COMPAT 0.4.1 -> 0.5
-func TestStateStore_Jobs_NoEphemeralDisk(t *testing.T) {
-	state := testStateStore(t)
-	job := mock.Job()
-
-	// Set EphemeralDisk to nil and set the DiskMB to 150
-	job.TaskGroups[0].EphemeralDisk = nil
-	job.TaskGroups[0].Tasks[0].Resources.DiskMB = 150
-
-	notify := setupNotifyTest(
-		state,
-		watch.Item{Table: "jobs"},
-		watch.Item{Job: job.ID})
-
-	restore, err := state.Restore()
+	// Updates keep the create index of the rollout
+	update := out.Copy()
+	update.Regions[0].Status = structs.MultiregionRegionStatusHealthy
+	if err := state.UpsertMultiregionRollout(1002, update); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.MultiregionRolloutByJob(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if out.CreateIndex != 1001 || out.ModifyIndex != 1002 ||
+		out.Regions[0].Status != structs.MultiregionRegionStatusHealthy {
+		t.Fatalf("bad: %#v", out)
+	}
 
-	err = restore.JobRestore(job)
-	if err != nil {
+	// The rollout of a new job version replaces the rollout, after which
+	// updates of the replaced rollout are rejected
+	if err := state.UpsertMultiregionRollout(1003, rollout); err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	restore.Commit()
+	if err := state.UpsertMultiregionRollout(1004, update); err == nil {
+		t.Fatalf("expected error")
+	}
 
-	out, err := state.JobByID(job.ID)
+	index, err := state.Index("multiregion_rollout")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if index != 1003 {
+		t.Fatalf("bad: %d", index)
+	}
 
-	// Expect job to have local disk and clear out the task's disk resource ask
-	expected := job.Copy()
-	expected.TaskGroups[0].EphemeralDisk = &structs.EphemeralDisk{
-		SizeMB: 150,
+	// The rollout is deleted along with the job
+	if err := state.DeleteJob(1005, job.Namespace, job.ID); err != nil {
+		t.Fatalf("err: %v", err)
 	}
-	expected.TaskGroups[0].Tasks[0].Resources.DiskMB = 0
-	if !reflect.DeepEqual(out, expected) {
-		t.Fatalf("Bad: %#v %#v", out, job)
+	out, err = state.MultiregionRolloutByJob(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
 	}
 
 	notify.verify(t)
 }
 
-func TestStateStore_UpsertPeriodicLaunch(t *testing.T) {
+func TestStateStore_Jobs(t *testing.T) {
 	state := testStateStore(t)
-	job := mock.Job()
-	launch := &structs.PeriodicLaunch{ID: job.ID, Launch: time.Now()}
+	var jobs []*structs.Job
 
-	notify := setupNotifyTest(
-		state,
-		watch.Item{Table: "periodic_launch"},
-		watch.Item{Job: job.ID})
+	for i := 0; i < 10; i++ {
+		job := mock.Job()
+		jobs = append(jobs, job)
 
-	err := state.UpsertPeriodicLaunch(1000, launch)
-	if err != nil {
-		t.Fatalf("err: %v", err)
+		err := state.UpsertJob(1000+uint64(i), job)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
 	}
 
-	out, err := state.PeriodicLaunchByID(job.ID)
+	iter, err := state.Jobs()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if out.CreateIndex != 1000 {
-		t.Fatalf("bad: %#v", out)
-	}
-	if out.ModifyIndex != 1000 {
-		t.Fatalf("bad: %#v", out)
-	}
 
-	if !reflect.DeepEqual(launch, out) {
-		t.Fatalf("bad: %#v %#v", job, out)
+	var out []*structs.Job
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		out = append(out, raw.(*structs.Job))
 	}
 
-	index, err := state.Index("periodic_launch")
-	if err != nil {
-		t.Fatalf("err: %v", err)
-	}
-	if index != 1000 {
-		t.Fatalf("bad: %d", index)
-	}
+	sort.Sort(JobIDSort(jobs))
+	sort.Sort(JobIDSort(out))
 
-	notify.verify(t)
+	if !reflect.DeepEqual(jobs, out) {
+		t.Fatalf("bad: %#v %#v", jobs, out)
+	}
 }
 
-func TestStateStore_UpdateUpsertPeriodicLaunch(t *testing.T) {
+func TestStateStore_JobsByIDPrefix(t *testing.T) {
 	state := testStateStore(t)
 	job := mock.Job()
-	launch := &structs.PeriodicLaunch{ID: job.ID, Launch: time.Now()}
 
-	notify := setupNotifyTest(
-		state,
-		watch.Item{Table: "periodic_launch"},
-		watch.Item{Job: job.ID})
+	job.ID = "redis"
+	err := state.UpsertJob(1000, job)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-	err := state.UpsertPeriodicLaunch(1000, launch)
+	iter, err := state.JobsByIDPrefix(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	launch2 := &structs.PeriodicLaunch{
-		ID:     job.ID,
-		Launch: launch.Launch.Add(1 * time.Second),
+	gatherJobs := func(iter memdb.ResultIterator) []*structs.Job {
+		var jobs []*structs.Job
+		for {
+			raw := iter.Next()
+			if raw == nil {
+				break
+			}
+			jobs = append(jobs, raw.(*structs.Job))
+		}
+		return jobs
 	}
-	err = state.UpsertPeriodicLaunch(1001, launch2)
-	if err != nil {
+
+	jobs := gatherJobs(iter)
+	if len(jobs) != 1 {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.PeriodicLaunchByID(job.ID)
+	iter, err = state.JobsByIDPrefix(structs.DefaultNamespace, "re")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if out.CreateIndex != 1000 {
-		t.Fatalf("bad: %#v", out)
-	}
-	if out.ModifyIndex != 1001 {
-		t.Fatalf("bad: %#v", out)
-	}
 
-	if !reflect.DeepEqual(launch2, out) {
-		t.Fatalf("bad: %#v %#v", launch2, out)
+	jobs = gatherJobs(iter)
+	if len(jobs) != 1 {
+		t.Fatalf("err: %v", err)
 	}
 
-	index, err := state.Index("periodic_launch")
+	job = mock.Job()
+	job.ID = "riak"
+	err = state.UpsertJob(1001, job)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if index != 1001 {
-		t.Fatalf("bad: %d", index)
-	}
 
-	notify.verify(t)
-}
-
-func TestStateStore_DeletePeriodicLaunch(t *testing.T) {
-	state := testStateStore(t)
-	job := mock.Job()
-	launch := &structs.PeriodicLaunch{ID: job.ID, Launch: time.Now()}
+	iter, err = state.JobsByIDPrefix(structs.DefaultNamespace, "r")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-	notify := setupNotifyTest(
-		state,
-		watch.Item{Table: "periodic_launch"},
-		watch.Item{Job: job.ID})
+	jobs = gatherJobs(iter)
+	if len(jobs) != 2 {
+		t.Fatalf("err: %v", err)
+	}
 
-	err := state.UpsertPeriodicLaunch(1000, launch)
+	iter, err = state.JobsByIDPrefix(structs.DefaultNamespace, "ri")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	err = state.DeletePeriodicLaunch(1001, job.ID)
+	jobs = gatherJobs(iter)
+	if len(jobs) != 1 {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Prefixes are matched within the given namespace, or across all of them
+	job = mock.Job()
+	job.ID = "riak"
+	job.Namespace = "engineering"
+	err = state.UpsertJob(1002, job)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.PeriodicLaunchByID(job.ID)
+	iter, err = state.JobsByIDPrefix(structs.DefaultNamespace, "ri")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	if out != nil {
-		t.Fatalf("bad: %#v %#v", job, out)
+	jobs = gatherJobs(iter)
+	if len(jobs) != 1 {
+		t.Fatalf("bad: %d", len(jobs))
 	}
 
-	index, err := state.Index("periodic_launch")
+	iter, err = state.JobsByIDPrefix(structs.AllNamespacesSentinel, "ri")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if index != 1001 {
-		t.Fatalf("bad: %d", index)
-	}
 
-	notify.verify(t)
+	jobs = gatherJobs(iter)
+	if len(jobs) != 2 {
+		t.Fatalf("bad: %d", len(jobs))
+	}
 }
 
-func TestStateStore_PeriodicLaunches(t *testing.T) {
+func TestStateStore_JobsByPeriodic(t *testing.T) {
 	state := testStateStore(t)
-	var launches []*structs.PeriodicLaunch
+	var periodic, nonPeriodic []*structs.Job
 
 	for i := 0; i < 10; i++ {
 		job := mock.Job()
-		launch := &structs.PeriodicLaunch{ID: job.ID, Launch: time.Now()}
-		launches = append(launches, launch)
+		nonPeriodic = append(nonPeriodic, job)
 
-		err := state.UpsertPeriodicLaunch(1000+uint64(i), launch)
+		err := state.UpsertJob(1000+uint64(i), job)
 		if err != nil {
 			t.Fatalf("err: %v", err)
 		}
 	}
 
-	iter, err := state.PeriodicLaunches()
+	for i := 0; i < 10; i++ {
+		job := mock.PeriodicJob()
+		periodic = append(periodic, job)
+
+		err := state.UpsertJob(2000+uint64(i), job)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	iter, err := state.JobsByPeriodic(true)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	out := make(map[string]*structs.PeriodicLaunch, 10)
+	var outPeriodic []*structs.Job
 	for {
 		raw := iter.Next()
 		if raw == nil {
 			break
 		}
-		launch := raw.(*structs.PeriodicLaunch)
-		if _, ok := out[launch.ID]; ok {
-			t.Fatalf("duplicate: %v", launch.ID)
-		}
+		outPeriodic = append(outPeriodic, raw.(*structs.Job))
+	}
 
-		out[launch.ID] = launch
+	iter, err = state.JobsByPeriodic(false)
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	for _, launch := range launches {
-		l, ok := out[launch.ID]
-		if !ok {
-			t.Fatalf("bad %v", launch.ID)
+	var outNonPeriodic []*structs.Job
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
 		}
+		outNonPeriodic = append(outNonPeriodic, raw.(*structs.Job))
+	}
 
-		if !reflect.DeepEqual(launch, l) {
-			t.Fatalf("bad: %#v %#v", launch, l)
-		}
+	sort.Sort(JobIDSort(periodic))
+	sort.Sort(JobIDSort(nonPeriodic))
+	sort.Sort(JobIDSort(outPeriodic))
+	sort.Sort(JobIDSort(outNonPeriodic))
 
-		delete(out, launch.ID)
+	if !reflect.DeepEqual(periodic, outPeriodic) {
+		t.Fatalf("bad: %#v %#v", periodic, outPeriodic)
 	}
 
-	if len(out) != 0 {
-		t.Fatalf("leftover: %#v", out)
+	if !reflect.DeepEqual(nonPeriodic, outNonPeriodic) {
+		t.Fatalf("bad: %#v %#v", nonPeriodic, outNonPeriodic)
 	}
 }
 
-func TestStateStore_RestorePeriodicLaunch(t *testing.T) {
+func TestStateStore_JobsByScheduler(t *testing.T) {
 	state := testStateStore(t)
-	job := mock.Job()
-	launch := &structs.PeriodicLaunch{ID: job.ID, Launch: time.Now()}
+	var serviceJobs []*structs.Job
+	var sysJobs []*structs.Job
 
-	notify := setupNotifyTest(
-		state,
-		watch.Item{Table: "periodic_launch"},
-		watch.Item{Job: job.ID})
+	for i := 0; i < 10; i++ {
+		job := mock.Job()
+		serviceJobs = append(serviceJobs, job)
 
-	restore, err := state.Restore()
-	if err != nil {
-		t.Fatalf("err: %v", err)
+		err := state.UpsertJob(1000+uint64(i), job)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
 	}
 
-	err = restore.PeriodicLaunchRestore(launch)
-	if err != nil {
-		t.Fatalf("err: %v", err)
+	for i := 0; i < 10; i++ {
+		job := mock.SystemJob()
+		sysJobs = append(sysJobs, job)
+
+		err := state.UpsertJob(2000+uint64(i), job)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
 	}
-	restore.Commit()
 
-	out, err := state.PeriodicLaunchByID(job.ID)
+	iter, err := state.JobsByScheduler("service")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	if !reflect.DeepEqual(out, launch) {
-		t.Fatalf("Bad: %#v %#v", out, job)
+	var outService []*structs.Job
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		outService = append(outService, raw.(*structs.Job))
 	}
 
-	notify.verify(t)
-}
-
-func TestStateStore_RestoreJobSummary(t *testing.T) {
-	state := testStateStore(t)
-	job := mock.Job()
-	jobSummary := &structs.JobSummary{
-		JobID: job.ID,
-		Summary: map[string]structs.TaskGroupSummary{
-			"web": structs.TaskGroupSummary{
-				Starting: 10,
-			},
-		},
-	}
-	restore, err := state.Restore()
+	iter, err = state.JobsByScheduler("system")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	err = restore.JobSummaryRestore(jobSummary)
-	if err != nil {
-		t.Fatalf("err: %v", err)
+	var outSystem []*structs.Job
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		outSystem = append(outSystem, raw.(*structs.Job))
 	}
-	restore.Commit()
 
-	out, err := state.JobSummaryByID(job.ID)
-	if err != nil {
-		t.Fatalf("err: %v", err)
+	sort.Sort(JobIDSort(serviceJobs))
+	sort.Sort(JobIDSort(sysJobs))
+	sort.Sort(JobIDSort(outService))
+	sort.Sort(JobIDSort(outSystem))
+
+	if !reflect.DeepEqual(serviceJobs, outService) {
+		t.Fatalf("bad: %#v %#v", serviceJobs, outService)
 	}
 
-	if !reflect.DeepEqual(out, jobSummary) {
-		t.Fatalf("Bad: %#v %#v", out, jobSummary)
+	if !reflect.DeepEqual(sysJobs, outSystem) {
+		t.Fatalf("bad: %#v %#v", sysJobs, outSystem)
 	}
 }
 
-func TestStateStore_Indexes(t *testing.T) {
+func TestStateStore_JobsByGC(t *testing.T) {
 	state := testStateStore(t)
-	node := mock.Node()
+	var gc, nonGc []*structs.Job
 
-	err := state.UpsertNode(1000, node)
+	for i := 0; i < 20; i++ {
+		var job *structs.Job
+		if i%2 == 0 {
+			job = mock.Job()
+		} else {
+			job = mock.PeriodicJob()
+		}
+		nonGc = append(nonGc, job)
+
+		if err := state.UpsertJob(1000+uint64(i), job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		job := mock.Job()
+		job.Type = structs.JobTypeBatch
+		gc = append(gc, job)
+
+		if err := state.UpsertJob(2000+uint64(i), job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Stopped jobs are GCable regardless of their type
+	for i := 0; i < 10; i++ {
+		var job *structs.Job
+		if i%2 == 0 {
+			job = mock.Job()
+		} else {
+			job = mock.PeriodicJob()
+		}
+		job.Stop = true
+		gc = append(gc, job)
+
+		if err := state.UpsertJob(3000+uint64(i), job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	iter, err := state.JobsByGC(true)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	iter, err := state.Indexes()
+	var outGc []*structs.Job
+	for i := iter.Next(); i != nil; i = iter.Next() {
+		outGc = append(outGc, i.(*structs.Job))
+	}
+
+	iter, err = state.JobsByGC(false)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	var out []*IndexEntry
-	for {
-		raw := iter.Next()
-		if raw == nil {
-			break
-		}
-		out = append(out, raw.(*IndexEntry))
+	var outNonGc []*structs.Job
+	for i := iter.Next(); i != nil; i = iter.Next() {
+		outNonGc = append(outNonGc, i.(*structs.Job))
 	}
 
-	expect := []*IndexEntry{
-		&IndexEntry{"nodes", 1000},
+	sort.Sort(JobIDSort(gc))
+	sort.Sort(JobIDSort(nonGc))
+	sort.Sort(JobIDSort(outGc))
+	sort.Sort(JobIDSort(outNonGc))
+
+	if !reflect.DeepEqual(gc, outGc) {
+		t.Fatalf("bad: %#v %#v", gc, outGc)
 	}
 
-	if !reflect.DeepEqual(expect, out) {
-		t.Fatalf("bad: %#v %#v", expect, out)
+	if !reflect.DeepEqual(nonGc, outNonGc) {
+		t.Fatalf("bad: %#v %#v", nonGc, outNonGc)
 	}
 }
 
-func TestStateStore_LatestIndex(t *testing.T) {
+func TestStateStore_RestoreJob(t *testing.T) {
 	state := testStateStore(t)
+	job := mock.Job()
 
-	if err := state.UpsertNode(1000, mock.Node()); err != nil {
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "jobs"},
+		watch.Item{Job: job.ID})
+
+	restore, err := state.Restore()
+	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	exp := uint64(2000)
-	if err := state.UpsertJob(exp, mock.Job()); err != nil {
+	err = restore.JobRestore(job)
+	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	restore.Commit()
 
-	latest, err := state.LatestIndex()
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	if latest != exp {
-		t.Fatalf("LatestIndex() returned %d; want %d", latest, exp)
+	if !reflect.DeepEqual(out, job) {
+		t.Fatalf("Bad: %#v %#v", out, job)
 	}
+
+	notify.verify(t)
 }
 
-func TestStateStore_RestoreIndex(t *testing.T) {
+// This test ensures that the state restore creates the EphemeralDisk for a job if
+// it doesn't have one
+// COMPAT 0.4.1 -> 0.5
+func TestStateStore_Jobs_NoEphemeralDisk(t *testing.T) {
 	state := testStateStore(t)
+	job := mock.Job()
+
+	// Set EphemeralDisk to nil and set the DiskMB to 150
+	job.TaskGroups[0].EphemeralDisk = nil
+	job.TaskGroups[0].Tasks[0].Resources.DiskMB = 150
+
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "jobs"},
+		watch.Item{Job: job.ID})
 
 	restore, err := state.Restore()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	index := &IndexEntry{"jobs", 1000}
-	err = restore.IndexRestore(index)
+	err = restore.JobRestore(job)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
 	restore.Commit()
 
-	out, err := state.Index("jobs")
+	out, err := state.JobByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	if out != 1000 {
-		t.Fatalf("Bad: %#v %#v", out, 1000)
+	// Expect job to have local disk and clear out the task's disk resource ask
+	expected := job.Copy()
+	expected.TaskGroups[0].EphemeralDisk = &structs.EphemeralDisk{
+		SizeMB: 150,
+	}
+	expected.TaskGroups[0].Tasks[0].Resources.DiskMB = 0
+	if !reflect.DeepEqual(out, expected) {
+		t.Fatalf("Bad: %#v %#v", out, job)
 	}
+
+	notify.verify(t)
 }
 
-func TestStateStore_UpsertEvals_Eval(t *testing.T) {
+func TestStateStore_UpsertPeriodicLaunch(t *testing.T) {
 	state := testStateStore(t)
-	eval := mock.Eval()
+	job := mock.Job()
+	launch := &structs.PeriodicLaunch{ID: job.ID, Launch: time.Now()}
 
 	notify := setupNotifyTest(
 		state,
-		watch.Item{Table: "evals"},
-		watch.Item{Eval: eval.ID})
+		watch.Item{Table: "periodic_launch"},
+		watch.Item{Job: job.ID})
 
-	err := state.UpsertEvals(1000, []*structs.Evaluation{eval})
+	err := state.UpsertPeriodicLaunch(1000, launch)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.EvalByID(eval.ID)
+	out, err := state.PeriodicLaunchByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if out.CreateIndex != 1000 {
+		t.Fatalf("bad: %#v", out)
+	}
+	if out.ModifyIndex != 1000 {
+		t.Fatalf("bad: %#v", out)
+	}
 
-	if !reflect.DeepEqual(eval, out) {
-		t.Fatalf("bad: %#v %#v", eval, out)
+	if !reflect.DeepEqual(launch, out) {
+		t.Fatalf("bad: %#v %#v", job, out)
 	}
 
-	index, err := state.Index("evals")
+	index, err := state.Index("periodic_launch")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -1254,36 +1604,34 @@ func TestStateStore_UpsertEvals_Eval(t *testing.T) {
 	notify.verify(t)
 }
 
-func TestStateStore_Update_UpsertEvals_Eval(t *testing.T) {
+func TestStateStore_UpdateUpsertPeriodicLaunch(t *testing.T) {
 	state := testStateStore(t)
-	eval := mock.Eval()
-
-	err := state.UpsertEvals(1000, []*structs.Evaluation{eval})
-	if err != nil {
-		t.Fatalf("err: %v", err)
-	}
+	job := mock.Job()
+	launch := &structs.PeriodicLaunch{ID: job.ID, Launch: time.Now()}
 
 	notify := setupNotifyTest(
 		state,
-		watch.Item{Table: "evals"},
-		watch.Item{Eval: eval.ID})
+		watch.Item{Table: "periodic_launch"},
+		watch.Item{Job: job.ID})
 
-	eval2 := mock.Eval()
-	eval2.ID = eval.ID
-	err = state.UpsertEvals(1001, []*structs.Evaluation{eval2})
+	err := state.UpsertPeriodicLaunch(1000, launch)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.EvalByID(eval.ID)
+	launch2 := &structs.PeriodicLaunch{
+		ID:     job.ID,
+		Launch: launch.Launch.Add(1 * time.Second),
+	}
+	err = state.UpsertPeriodicLaunch(1001, launch2)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	if !reflect.DeepEqual(eval2, out) {
-		t.Fatalf("bad: %#v %#v", eval2, out)
+	out, err := state.PeriodicLaunchByID(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
-
 	if out.CreateIndex != 1000 {
 		t.Fatalf("bad: %#v", out)
 	}
@@ -1291,7 +1639,11 @@ func TestStateStore_Update_UpsertEvals_Eval(t *testing.T) {
 		t.Fatalf("bad: %#v", out)
 	}
 
-	index, err := state.Index("evals")
+	if !reflect.DeepEqual(launch2, out) {
+		t.Fatalf("bad: %#v %#v", launch2, out)
+	}
+
+	index, err := state.Index("periodic_launch")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -1302,347 +1654,319 @@ func TestStateStore_Update_UpsertEvals_Eval(t *testing.T) {
 	notify.verify(t)
 }
 
-func TestStateStore_DeleteEval_Eval(t *testing.T) {
+func TestStateStore_DeletePeriodicLaunch(t *testing.T) {
 	state := testStateStore(t)
-	eval1 := mock.Eval()
-	eval2 := mock.Eval()
-	alloc1 := mock.Alloc()
-	alloc2 := mock.Alloc()
+	job := mock.Job()
+	launch := &structs.PeriodicLaunch{ID: job.ID, Launch: time.Now()}
 
 	notify := setupNotifyTest(
 		state,
-		watch.Item{Table: "evals"},
-		watch.Item{Table: "allocs"},
-		watch.Item{Eval: eval1.ID},
-		watch.Item{Eval: eval2.ID},
-		watch.Item{Alloc: alloc1.ID},
-		watch.Item{Alloc: alloc2.ID},
-		watch.Item{AllocEval: alloc1.EvalID},
-		watch.Item{AllocEval: alloc2.EvalID},
-		watch.Item{AllocJob: alloc1.JobID},
-		watch.Item{AllocJob: alloc2.JobID},
-		watch.Item{AllocNode: alloc1.NodeID},
-		watch.Item{AllocNode: alloc2.NodeID})
-
-	state.UpsertJobSummary(900, mock.JobSummary(eval1.JobID))
-	state.UpsertJobSummary(901, mock.JobSummary(eval2.JobID))
-	state.UpsertJobSummary(902, mock.JobSummary(alloc1.JobID))
-	state.UpsertJobSummary(903, mock.JobSummary(alloc2.JobID))
-	err := state.UpsertEvals(1000, []*structs.Evaluation{eval1, eval2})
-	if err != nil {
-		t.Fatalf("err: %v", err)
-	}
+		watch.Item{Table: "periodic_launch"},
+		watch.Item{Job: job.ID})
 
-	err = state.UpsertAllocs(1001, []*structs.Allocation{alloc1, alloc2})
+	err := state.UpsertPeriodicLaunch(1000, launch)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	err = state.DeleteEval(1002, []string{eval1.ID, eval2.ID}, []string{alloc1.ID, alloc2.ID})
+	err = state.DeletePeriodicLaunch(1001, job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.EvalByID(eval1.ID)
+	out, err := state.PeriodicLaunchByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
 	if out != nil {
-		t.Fatalf("bad: %#v %#v", eval1, out)
+		t.Fatalf("bad: %#v %#v", job, out)
 	}
 
-	out, err = state.EvalByID(eval2.ID)
+	index, err := state.Index("periodic_launch")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	if out != nil {
-		t.Fatalf("bad: %#v %#v", eval1, out)
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
 	}
 
-	outA, err := state.AllocByID(alloc1.ID)
-	if err != nil {
-		t.Fatalf("err: %v", err)
-	}
+	notify.verify(t)
+}
 
-	if out != nil {
-		t.Fatalf("bad: %#v %#v", alloc1, outA)
-	}
+func TestStateStore_PeriodicLaunches(t *testing.T) {
+	state := testStateStore(t)
+	var launches []*structs.PeriodicLaunch
 
-	outA, err = state.AllocByID(alloc2.ID)
-	if err != nil {
-		t.Fatalf("err: %v", err)
-	}
+	for i := 0; i < 10; i++ {
+		job := mock.Job()
+		launch := &structs.PeriodicLaunch{ID: job.ID, Launch: time.Now()}
+		launches = append(launches, launch)
 
-	if out != nil {
-		t.Fatalf("bad: %#v %#v", alloc1, outA)
+		err := state.UpsertPeriodicLaunch(1000+uint64(i), launch)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
 	}
 
-	index, err := state.Index("evals")
+	iter, err := state.PeriodicLaunches()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if index != 1002 {
-		t.Fatalf("bad: %d", index)
+
+	out := make(map[string]*structs.PeriodicLaunch, 10)
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		launch := raw.(*structs.PeriodicLaunch)
+		if _, ok := out[launch.ID]; ok {
+			t.Fatalf("duplicate: %v", launch.ID)
+		}
+
+		out[launch.ID] = launch
 	}
 
-	index, err = state.Index("allocs")
+	for _, launch := range launches {
+		l, ok := out[launch.ID]
+		if !ok {
+			t.Fatalf("bad %v", launch.ID)
+		}
+
+		if !reflect.DeepEqual(launch, l) {
+			t.Fatalf("bad: %#v %#v", launch, l)
+		}
+
+		delete(out, launch.ID)
+	}
+
+	if len(out) != 0 {
+		t.Fatalf("leftover: %#v", out)
+	}
+}
+
+func TestStateStore_RestorePeriodicLaunch(t *testing.T) {
+	state := testStateStore(t)
+	job := mock.Job()
+	launch := &structs.PeriodicLaunch{ID: job.ID, Launch: time.Now()}
+
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "periodic_launch"},
+		watch.Item{Job: job.ID})
+
+	restore, err := state.Restore()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if index != 1002 {
-		t.Fatalf("bad: %d", index)
+
+	err = restore.PeriodicLaunchRestore(launch)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	restore.Commit()
+
+	out, err := state.PeriodicLaunchByID(job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !reflect.DeepEqual(out, launch) {
+		t.Fatalf("Bad: %#v %#v", out, job)
 	}
 
 	notify.verify(t)
 }
 
-func TestStateStore_EvalsByJob(t *testing.T) {
+func TestStateStore_RestoreJobSummary(t *testing.T) {
 	state := testStateStore(t)
-
-	eval1 := mock.Eval()
-	eval2 := mock.Eval()
-	eval2.JobID = eval1.JobID
-	eval3 := mock.Eval()
-	evals := []*structs.Evaluation{eval1, eval2}
-
-	err := state.UpsertEvals(1000, evals)
+	job := mock.Job()
+	jobSummary := &structs.JobSummary{
+		JobID:     job.ID,
+		Namespace: job.Namespace,
+		Summary: map[string]structs.TaskGroupSummary{
+			"web": structs.TaskGroupSummary{
+				Starting: 10,
+			},
+		},
+	}
+	restore, err := state.Restore()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	err = state.UpsertEvals(1001, []*structs.Evaluation{eval3})
+
+	err = restore.JobSummaryRestore(jobSummary)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	restore.Commit()
 
-	out, err := state.EvalsByJob(eval1.JobID)
+	out, err := state.JobSummaryByID(job.Namespace, job.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	sort.Sort(EvalIDSort(evals))
-	sort.Sort(EvalIDSort(out))
-
-	if !reflect.DeepEqual(evals, out) {
-		t.Fatalf("bad: %#v %#v", evals, out)
+	if !reflect.DeepEqual(out, jobSummary) {
+		t.Fatalf("Bad: %#v %#v", out, jobSummary)
 	}
 }
 
-func TestStateStore_Evals(t *testing.T) {
+func TestStateStore_Indexes(t *testing.T) {
 	state := testStateStore(t)
-	var evals []*structs.Evaluation
-
-	for i := 0; i < 10; i++ {
-		eval := mock.Eval()
-		evals = append(evals, eval)
+	node := mock.Node()
 
-		err := state.UpsertEvals(1000+uint64(i), []*structs.Evaluation{eval})
-		if err != nil {
-			t.Fatalf("err: %v", err)
-		}
+	err := state.UpsertNode(1000, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	iter, err := state.Evals()
+	iter, err := state.Indexes()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	var out []*structs.Evaluation
+	var out []*IndexEntry
 	for {
 		raw := iter.Next()
 		if raw == nil {
 			break
 		}
-		out = append(out, raw.(*structs.Evaluation))
+		out = append(out, raw.(*IndexEntry))
 	}
 
-	sort.Sort(EvalIDSort(evals))
-	sort.Sort(EvalIDSort(out))
+	expect := []*IndexEntry{
+		&IndexEntry{"nodes", 1000},
+	}
 
-	if !reflect.DeepEqual(evals, out) {
-		t.Fatalf("bad: %#v %#v", evals, out)
+	if !reflect.DeepEqual(expect, out) {
+		t.Fatalf("bad: %#v %#v", expect, out)
 	}
 }
 
-func TestStateStore_EvalsByIDPrefix(t *testing.T) {
+func TestStateStore_LatestIndex(t *testing.T) {
 	state := testStateStore(t)
-	var evals []*structs.Evaluation
 
-	ids := []string{
-		"aaaaaaaa-7bfb-395d-eb95-0685af2176b2",
-		"aaaaaaab-7bfb-395d-eb95-0685af2176b2",
-		"aaaaaabb-7bfb-395d-eb95-0685af2176b2",
-		"aaaaabbb-7bfb-395d-eb95-0685af2176b2",
-		"aaaabbbb-7bfb-395d-eb95-0685af2176b2",
-		"aaabbbbb-7bfb-395d-eb95-0685af2176b2",
-		"aabbbbbb-7bfb-395d-eb95-0685af2176b2",
-		"abbbbbbb-7bfb-395d-eb95-0685af2176b2",
-		"bbbbbbbb-7bfb-395d-eb95-0685af2176b2",
-	}
-	for i := 0; i < 9; i++ {
-		eval := mock.Eval()
-		eval.ID = ids[i]
-		evals = append(evals, eval)
+	if err := state.UpsertNode(1000, mock.Node()); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	err := state.UpsertEvals(1000, evals)
-	if err != nil {
+	exp := uint64(2000)
+	if err := state.UpsertJob(exp, mock.Job()); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	iter, err := state.EvalsByIDPrefix("aaaa")
+	latest, err := state.LatestIndex()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	gatherEvals := func(iter memdb.ResultIterator) []*structs.Evaluation {
-		var evals []*structs.Evaluation
-		for {
-			raw := iter.Next()
-			if raw == nil {
-				break
-			}
-			evals = append(evals, raw.(*structs.Evaluation))
-		}
-		return evals
-	}
-
-	out := gatherEvals(iter)
-	if len(out) != 5 {
-		t.Fatalf("bad: expected five evaluations, got: %#v", out)
+	if latest != exp {
+		t.Fatalf("LatestIndex() returned %d; want %d", latest, exp)
 	}
+}
 
-	sort.Sort(EvalIDSort(evals))
+func TestStateStore_RestoreIndex(t *testing.T) {
+	state := testStateStore(t)
 
-	for index, eval := range out {
-		if ids[index] != eval.ID {
-			t.Fatalf("bad: got unexpected id: %s", eval.ID)
-		}
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	iter, err = state.EvalsByIDPrefix("b-a7bfb")
+	index := &IndexEntry{"jobs", 1000}
+	err = restore.IndexRestore(index)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	out = gatherEvals(iter)
-	if len(out) != 0 {
-		t.Fatalf("bad: unexpected zero evaluations, got: %#v", out)
+	restore.Commit()
+
+	out, err := state.Index("jobs")
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
+	if out != 1000 {
+		t.Fatalf("Bad: %#v %#v", out, 1000)
+	}
 }
 
-func TestStateStore_RestoreEval(t *testing.T) {
+func TestStateStore_UpsertEvals_Eval(t *testing.T) {
 	state := testStateStore(t)
 	eval := mock.Eval()
 
 	notify := setupNotifyTest(
 		state,
 		watch.Item{Table: "evals"},
-		watch.Item{Eval: eval.ID})
+		watch.Item{Eval: eval.ID},
+		watch.Item{EvalJob: eval.JobID})
 
-	restore, err := state.Restore()
+	err := state.UpsertEvals(1000, []*structs.Evaluation{eval})
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	err = restore.EvalRestore(eval)
+	out, err := state.EvalByID(eval.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	restore.Commit()
 
-	out, err := state.EvalByID(eval.ID)
+	if !reflect.DeepEqual(eval, out) {
+		t.Fatalf("bad: %#v %#v", eval, out)
+	}
+
+	index, err := state.Index("evals")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	if !reflect.DeepEqual(out, eval) {
-		t.Fatalf("Bad: %#v %#v", out, eval)
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
 	}
 
 	notify.verify(t)
 }
 
-func TestStateStore_UpdateAllocsFromClient(t *testing.T) {
+func TestStateStore_Update_UpsertEvals_Eval(t *testing.T) {
 	state := testStateStore(t)
-	alloc := mock.Alloc()
-	alloc2 := mock.Alloc()
-
-	notify := setupNotifyTest(
-		state,
-		watch.Item{Table: "allocs"},
-		watch.Item{Alloc: alloc.ID},
-		watch.Item{AllocEval: alloc.EvalID},
-		watch.Item{AllocJob: alloc.JobID},
-		watch.Item{AllocNode: alloc.NodeID},
-		watch.Item{Alloc: alloc2.ID},
-		watch.Item{AllocEval: alloc2.EvalID},
-		watch.Item{AllocJob: alloc2.JobID},
-		watch.Item{AllocNode: alloc2.NodeID})
-
-	if err := state.UpsertJob(999, alloc.Job); err != nil {
-		t.Fatalf("err: %v", err)
-	}
-	if err := state.UpsertJob(999, alloc2.Job); err != nil {
-		t.Fatalf("err: %v", err)
-	}
+	eval := mock.Eval()
 
-	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc, alloc2})
+	err := state.UpsertEvals(1000, []*structs.Evaluation{eval})
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	// Create the delta updates
-	ts := map[string]*structs.TaskState{"web": &structs.TaskState{State: structs.TaskStatePending}}
-	update := &structs.Allocation{
-		ID:           alloc.ID,
-		ClientStatus: structs.AllocClientStatusFailed,
-		TaskStates:   ts,
-		JobID:        alloc.JobID,
-		TaskGroup:    alloc.TaskGroup,
-	}
-	update2 := &structs.Allocation{
-		ID:           alloc2.ID,
-		ClientStatus: structs.AllocClientStatusRunning,
-		TaskStates:   ts,
-		JobID:        alloc2.JobID,
-		TaskGroup:    alloc2.TaskGroup,
-	}
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "evals"},
+		watch.Item{Eval: eval.ID},
+		watch.Item{EvalJob: eval.JobID})
 
-	err = state.UpdateAllocsFromClient(1001, []*structs.Allocation{update, update2})
+	eval2 := mock.Eval()
+	eval2.ID = eval.ID
+	eval2.JobID = eval.JobID
+	err = state.UpsertEvals(1001, []*structs.Evaluation{eval2})
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.AllocByID(alloc.ID)
+	out, err := state.EvalByID(eval.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	alloc.CreateIndex = 1000
-	alloc.ModifyIndex = 1001
-	alloc.TaskStates = ts
-	alloc.ClientStatus = structs.AllocClientStatusFailed
-	if !reflect.DeepEqual(alloc, out) {
-		t.Fatalf("bad: %#v %#v", alloc, out)
+	if !reflect.DeepEqual(eval2, out) {
+		t.Fatalf("bad: %#v %#v", eval2, out)
 	}
 
-	out, err = state.AllocByID(alloc2.ID)
-	if err != nil {
-		t.Fatalf("err: %v", err)
+	if out.CreateIndex != 1000 {
+		t.Fatalf("bad: %#v", out)
 	}
-
-	alloc2.ModifyIndex = 1000
-	alloc2.ModifyIndex = 1001
-	alloc2.ClientStatus = structs.AllocClientStatusRunning
-	alloc2.TaskStates = ts
-	if !reflect.DeepEqual(alloc2, out) {
-		t.Fatalf("bad: %#v %#v", alloc2, out)
+	if out.ModifyIndex != 1001 {
+		t.Fatalf("bad: %#v", out)
 	}
 
-	index, err := state.Index("allocs")
+	index, err := state.Index("evals")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -1650,1621 +1974,3448 @@ func TestStateStore_UpdateAllocsFromClient(t *testing.T) {
 		t.Fatalf("bad: %d", index)
 	}
 
-	// Ensure summaries have been updated
-	summary, err := state.JobSummaryByID(alloc.JobID)
-	if err != nil {
-		t.Fatalf("err: %v", err)
-	}
-	tgSummary := summary.Summary["web"]
-	if tgSummary.Failed != 1 {
-		t.Fatalf("expected failed: %v, actual: %v, summary: %#v", 1, tgSummary.Failed, tgSummary)
-	}
-
-	summary2, err := state.JobSummaryByID(alloc2.JobID)
-	if err != nil {
-		t.Fatalf("err: %v", err)
-	}
-	tgSummary2 := summary2.Summary["web"]
-	if tgSummary2.Running != 1 {
-		t.Fatalf("expected running: %v, actual: %v", 1, tgSummary2.Running)
-	}
-
 	notify.verify(t)
 }
 
-func TestStateStore_UpdateMultipleAllocsFromClient(t *testing.T) {
+func TestStateStore_DeleteEval_Eval(t *testing.T) {
 	state := testStateStore(t)
-	alloc := mock.Alloc()
+	eval1 := mock.Eval()
+	eval2 := mock.Eval()
+	alloc1 := mock.Alloc()
+	alloc2 := mock.Alloc()
 
-	if err := state.UpsertJob(999, alloc.Job); err != nil {
-		t.Fatalf("err: %v", err)
-	}
-	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "evals"},
+		watch.Item{Table: "allocs"},
+		watch.Item{Eval: eval1.ID},
+		watch.Item{Eval: eval2.ID},
+		watch.Item{EvalJob: eval1.JobID},
+		watch.Item{EvalJob: eval2.JobID},
+		watch.Item{Alloc: alloc1.ID},
+		watch.Item{Alloc: alloc2.ID},
+		watch.Item{AllocEval: alloc1.EvalID},
+		watch.Item{AllocEval: alloc2.EvalID},
+		watch.Item{AllocJob: alloc1.JobID},
+		watch.Item{AllocJob: alloc2.JobID},
+		watch.Item{AllocNode: alloc1.NodeID},
+		watch.Item{AllocNode: alloc2.NodeID})
+
+	state.UpsertJobSummary(900, mock.JobSummary(eval1.JobID))
+	state.UpsertJobSummary(901, mock.JobSummary(eval2.JobID))
+	state.UpsertJobSummary(902, mock.JobSummary(alloc1.JobID))
+	state.UpsertJobSummary(903, mock.JobSummary(alloc2.JobID))
+	err := state.UpsertEvals(1000, []*structs.Evaluation{eval1, eval2})
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	// Create the delta updates
-	ts := map[string]*structs.TaskState{"web": &structs.TaskState{State: structs.TaskStatePending}}
-	update := &structs.Allocation{
-		ID:           alloc.ID,
-		ClientStatus: structs.AllocClientStatusRunning,
-		TaskStates:   ts,
-		JobID:        alloc.JobID,
-		TaskGroup:    alloc.TaskGroup,
-	}
-	update2 := &structs.Allocation{
-		ID:           alloc.ID,
-		ClientStatus: structs.AllocClientStatusPending,
-		TaskStates:   ts,
-		JobID:        alloc.JobID,
-		TaskGroup:    alloc.TaskGroup,
+	err = state.UpsertAllocs(1001, []*structs.Allocation{alloc1, alloc2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	err = state.UpdateAllocsFromClient(1001, []*structs.Allocation{update, update2})
+	err = state.DeleteEval(1002, []string{eval1.ID, eval2.ID}, []string{alloc1.ID, alloc2.ID})
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.AllocByID(alloc.ID)
+	out, err := state.EvalByID(eval1.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	alloc.CreateIndex = 1000
-	alloc.ModifyIndex = 1001
-	alloc.TaskStates = ts
-	alloc.ClientStatus = structs.AllocClientStatusPending
-	if !reflect.DeepEqual(alloc, out) {
-		t.Fatalf("bad: %#v , actual:%#v", alloc, out)
+	if out != nil {
+		t.Fatalf("bad: %#v %#v", eval1, out)
 	}
 
-	summary, err := state.JobSummaryByID(alloc.JobID)
-	expectedSummary := &structs.JobSummary{
-		JobID: alloc.JobID,
-		Summary: map[string]structs.TaskGroupSummary{
-			"web": structs.TaskGroupSummary{
-				Starting: 1,
-			},
-		},
-		CreateIndex: 999,
-		ModifyIndex: 1001,
-	}
+	out, err = state.EvalByID(eval2.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if !reflect.DeepEqual(summary, expectedSummary) {
-		t.Fatalf("expected: %#v, actual: %#v", expectedSummary, summary)
-	}
-}
-
-func TestStateStore_UpsertAlloc_Alloc(t *testing.T) {
-	state := testStateStore(t)
-	alloc := mock.Alloc()
-
-	notify := setupNotifyTest(
-		state,
-		watch.Item{Table: "allocs"},
-		watch.Item{Alloc: alloc.ID},
-		watch.Item{AllocEval: alloc.EvalID},
-		watch.Item{AllocJob: alloc.JobID},
-		watch.Item{AllocNode: alloc.NodeID})
 
-	if err := state.UpsertJob(999, alloc.Job); err != nil {
-		t.Fatalf("err: %v", err)
+	if out != nil {
+		t.Fatalf("bad: %#v %#v", eval1, out)
 	}
 
-	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	outA, err := state.AllocByID(alloc1.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.AllocByID(alloc.ID)
+	if out != nil {
+		t.Fatalf("bad: %#v %#v", alloc1, outA)
+	}
+
+	outA, err = state.AllocByID(alloc2.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	if !reflect.DeepEqual(alloc, out) {
-		t.Fatalf("bad: %#v %#v", alloc, out)
+	if out != nil {
+		t.Fatalf("bad: %#v %#v", alloc1, outA)
 	}
 
-	index, err := state.Index("allocs")
+	index, err := state.Index("evals")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if index != 1000 {
+	if index != 1002 {
 		t.Fatalf("bad: %d", index)
 	}
 
-	summary, err := state.JobSummaryByID(alloc.JobID)
+	index, err = state.Index("allocs")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	tgSummary, ok := summary.Summary["web"]
-	if !ok {
-		t.Fatalf("no summary for task group web")
-	}
-	if tgSummary.Starting != 1 {
-		t.Fatalf("expected queued: %v, actual: %v", 1, tgSummary.Starting)
+	if index != 1002 {
+		t.Fatalf("bad: %d", index)
 	}
 
 	notify.verify(t)
 }
 
-func TestStateStore_UpsertAlloc_NoEphemeralDisk(t *testing.T) {
+func TestStateStore_EvalsByJob(t *testing.T) {
 	state := testStateStore(t)
-	alloc := mock.Alloc()
-	alloc.Job.TaskGroups[0].EphemeralDisk = nil
-	alloc.Job.TaskGroups[0].Tasks[0].Resources.DiskMB = 120
 
-	if err := state.UpsertJob(999, alloc.Job); err != nil {
+	eval1 := mock.Eval()
+	eval2 := mock.Eval()
+	eval2.JobID = eval1.JobID
+	eval3 := mock.Eval()
+	evals := []*structs.Evaluation{eval1, eval2}
+
+	err := state.UpsertEvals(1000, evals)
+	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	err = state.UpsertEvals(1001, []*structs.Evaluation{eval3})
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.AllocByID(alloc.ID)
+	out, err := state.EvalsByJob(eval1.Namespace, eval1.JobID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	expected := alloc.Copy()
-	expected.Job.TaskGroups[0].EphemeralDisk = &structs.EphemeralDisk{SizeMB: 120}
-	if !reflect.DeepEqual(expected, out) {
-		t.Fatalf("bad: %#v %#v", expected, out)
+	sort.Sort(EvalIDSort(evals))
+	sort.Sort(EvalIDSort(out))
+
+	if !reflect.DeepEqual(evals, out) {
+		t.Fatalf("bad: %#v %#v", evals, out)
 	}
 }
 
-func TestStateStore_UpdateAlloc_Alloc(t *testing.T) {
+func TestStateStore_Evals(t *testing.T) {
 	state := testStateStore(t)
-	alloc := mock.Alloc()
+	var evals []*structs.Evaluation
 
-	if err := state.UpsertJob(999, alloc.Job); err != nil {
-		t.Fatalf("err: %v", err)
+	for i := 0; i < 10; i++ {
+		eval := mock.Eval()
+		evals = append(evals, eval)
+
+		err := state.UpsertEvals(1000+uint64(i), []*structs.Evaluation{eval})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
 	}
 
-	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	iter, err := state.Evals()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	summary, err := state.JobSummaryByID(alloc.JobID)
-	if err != nil {
-		t.Fatalf("err: %v", err)
+	var out []*structs.Evaluation
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		out = append(out, raw.(*structs.Evaluation))
 	}
-	tgSummary := summary.Summary["web"]
-	if tgSummary.Starting != 1 {
-		t.Fatalf("expected starting: %v, actual: %v", 1, tgSummary.Starting)
+
+	sort.Sort(EvalIDSort(evals))
+	sort.Sort(EvalIDSort(out))
+
+	if !reflect.DeepEqual(evals, out) {
+		t.Fatalf("bad: %#v %#v", evals, out)
 	}
+}
 
-	alloc2 := mock.Alloc()
-	alloc2.ID = alloc.ID
-	alloc2.NodeID = alloc.NodeID + ".new"
-	state.UpsertJobSummary(1001, mock.JobSummary(alloc2.JobID))
+func TestStateStore_EvalsByIDPrefix(t *testing.T) {
+	state := testStateStore(t)
+	var evals []*structs.Evaluation
 
-	notify := setupNotifyTest(
-		state,
-		watch.Item{Table: "allocs"},
-		watch.Item{Alloc: alloc2.ID},
-		watch.Item{AllocEval: alloc2.EvalID},
-		watch.Item{AllocJob: alloc2.JobID},
-		watch.Item{AllocNode: alloc2.NodeID})
+	ids := []string{
+		"aaaaaaaa-7bfb-395d-eb95-0685af2176b2",
+		"aaaaaaab-7bfb-395d-eb95-0685af2176b2",
+		"aaaaaabb-7bfb-395d-eb95-0685af2176b2",
+		"aaaaabbb-7bfb-395d-eb95-0685af2176b2",
+		"aaaabbbb-7bfb-395d-eb95-0685af2176b2",
+		"aaabbbbb-7bfb-395d-eb95-0685af2176b2",
+		"aabbbbbb-7bfb-395d-eb95-0685af2176b2",
+		"abbbbbbb-7bfb-395d-eb95-0685af2176b2",
+		"bbbbbbbb-7bfb-395d-eb95-0685af2176b2",
+	}
+	for i := 0; i < 9; i++ {
+		eval := mock.Eval()
+		eval.ID = ids[i]
+		evals = append(evals, eval)
+	}
 
-	err = state.UpsertAllocs(1002, []*structs.Allocation{alloc2})
+	err := state.UpsertEvals(1000, evals)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	out, err := state.AllocByID(alloc.ID)
+	iter, err := state.EvalsByIDPrefix("aaaa")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	if !reflect.DeepEqual(alloc2, out) {
-		t.Fatalf("bad: %#v %#v", alloc2, out)
-	}
+	gatherEvals := func(iter memdb.ResultIterator) []*structs.Evaluation {
+		var evals []*structs.Evaluation
+		for {
+			raw := iter.Next()
+			if raw == nil {
+				break
+			}
+			evals = append(evals, raw.(*structs.Evaluation))
+		}
+		return evals
+	}
+
+	out := gatherEvals(iter)
+	if len(out) != 5 {
+		t.Fatalf("bad: expected five evaluations, got: %#v", out)
+	}
+
+	sort.Sort(EvalIDSort(evals))
+
+	for index, eval := range out {
+		if ids[index] != eval.ID {
+			t.Fatalf("bad: got unexpected id: %s", eval.ID)
+		}
+	}
+
+	iter, err = state.EvalsByIDPrefix("b-a7bfb")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out = gatherEvals(iter)
+	if len(out) != 0 {
+		t.Fatalf("bad: unexpected zero evaluations, got: %#v", out)
+	}
+
+}
+
+func TestStateStore_RestoreEval(t *testing.T) {
+	state := testStateStore(t)
+	eval := mock.Eval()
+
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "evals"},
+		watch.Item{Eval: eval.ID},
+		watch.Item{EvalJob: eval.JobID})
+
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err = restore.EvalRestore(eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	restore.Commit()
+
+	out, err := state.EvalByID(eval.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !reflect.DeepEqual(out, eval) {
+		t.Fatalf("Bad: %#v %#v", out, eval)
+	}
+
+	notify.verify(t)
+}
+
+func TestStateStore_UpdateAllocsDesiredTransitions(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+	alloc2 := mock.Alloc()
+
+	state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID))
+	state.UpsertJobSummary(999, mock.JobSummary(alloc2.JobID))
+	if err := state.UpsertAllocs(1000, []*structs.Allocation{alloc, alloc2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "allocs"},
+		watch.Item{Table: "evals"},
+		watch.Item{Alloc: alloc.ID},
+		watch.Item{AllocJob: alloc.JobID},
+		watch.Item{AllocNode: alloc.NodeID})
+
+	eval := mock.Eval()
+	eval.JobID = alloc.JobID
+	transitions := map[string]*structs.DesiredTransition{
+		alloc.ID: &structs.DesiredTransition{Migrate: true},
+	}
+	if err := state.UpdateAllocsDesiredTransitions(1001, transitions, []*structs.Evaluation{eval}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.AllocByID(alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !out.DesiredTransition.ShouldMigrate() || out.ModifyIndex != 1001 {
+		t.Fatalf("bad: %#v", out)
+	}
+	out, err = state.AllocByID(alloc2.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.DesiredTransition.ShouldMigrate() || out.ModifyIndex != 1000 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	evalOut, err := state.EvalByID(eval.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if evalOut == nil || evalOut.CreateIndex != 1001 {
+		t.Fatalf("bad: %#v", evalOut)
+	}
+
+	for _, table := range []string{"allocs", "evals"} {
+		index, err := state.Index(table)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if index != 1001 {
+			t.Fatalf("bad %s index: %d", table, index)
+		}
+	}
+
+	notify.verify(t)
+}
+
+func TestStateStore_UpdateAllocsFromClient(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+	alloc2 := mock.Alloc()
+
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "allocs"},
+		watch.Item{Alloc: alloc.ID},
+		watch.Item{AllocEval: alloc.EvalID},
+		watch.Item{AllocJob: alloc.JobID},
+		watch.Item{AllocNode: alloc.NodeID},
+		watch.Item{Alloc: alloc2.ID},
+		watch.Item{AllocEval: alloc2.EvalID},
+		watch.Item{AllocJob: alloc2.JobID},
+		watch.Item{AllocNode: alloc2.NodeID})
+
+	if err := state.UpsertJob(999, alloc.Job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJob(999, alloc2.Job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc, alloc2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create the delta updates
+	ts := map[string]*structs.TaskState{"web": &structs.TaskState{State: structs.TaskStatePending}}
+	update := &structs.Allocation{
+		ID:           alloc.ID,
+		ClientStatus: structs.AllocClientStatusFailed,
+		TaskStates:   ts,
+		JobID:        alloc.JobID,
+		TaskGroup:    alloc.TaskGroup,
+	}
+	update2 := &structs.Allocation{
+		ID:           alloc2.ID,
+		ClientStatus: structs.AllocClientStatusRunning,
+		TaskStates:   ts,
+		JobID:        alloc2.JobID,
+		TaskGroup:    alloc2.TaskGroup,
+	}
+
+	err = state.UpdateAllocsFromClient(1001, []*structs.Allocation{update, update2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.AllocByID(alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	alloc.CreateIndex = 1000
+	alloc.ModifyIndex = 1001
+	alloc.TaskStates = ts
+	alloc.ClientStatus = structs.AllocClientStatusFailed
+	if !reflect.DeepEqual(alloc, out) {
+		t.Fatalf("bad: %#v %#v", alloc, out)
+	}
+
+	out, err = state.AllocByID(alloc2.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	alloc2.ModifyIndex = 1000
+	alloc2.ModifyIndex = 1001
+	alloc2.ClientStatus = structs.AllocClientStatusRunning
+	alloc2.TaskStates = ts
+	if !reflect.DeepEqual(alloc2, out) {
+		t.Fatalf("bad: %#v %#v", alloc2, out)
+	}
+
+	index, err := state.Index("allocs")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+
+	// Ensure summaries have been updated
+	summary, err := state.JobSummaryByID(alloc.Namespace, alloc.JobID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	tgSummary := summary.Summary["web"]
+	if tgSummary.Failed != 1 {
+		t.Fatalf("expected failed: %v, actual: %v, summary: %#v", 1, tgSummary.Failed, tgSummary)
+	}
+
+	summary2, err := state.JobSummaryByID(alloc2.Namespace, alloc2.JobID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	tgSummary2 := summary2.Summary["web"]
+	if tgSummary2.Running != 1 {
+		t.Fatalf("expected running: %v, actual: %v", 1, tgSummary2.Running)
+	}
+
+	notify.verify(t)
+}
+
+func TestStateStore_UpdateMultipleAllocsFromClient(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+
+	if err := state.UpsertJob(999, alloc.Job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create the delta updates
+	ts := map[string]*structs.TaskState{"web": &structs.TaskState{State: structs.TaskStatePending}}
+	update := &structs.Allocation{
+		ID:           alloc.ID,
+		ClientStatus: structs.AllocClientStatusRunning,
+		TaskStates:   ts,
+		JobID:        alloc.JobID,
+		TaskGroup:    alloc.TaskGroup,
+	}
+	update2 := &structs.Allocation{
+		ID:           alloc.ID,
+		ClientStatus: structs.AllocClientStatusPending,
+		TaskStates:   ts,
+		JobID:        alloc.JobID,
+		TaskGroup:    alloc.TaskGroup,
+	}
+
+	err = state.UpdateAllocsFromClient(1001, []*structs.Allocation{update, update2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.AllocByID(alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	alloc.CreateIndex = 1000
+	alloc.ModifyIndex = 1001
+	alloc.TaskStates = ts
+	alloc.ClientStatus = structs.AllocClientStatusPending
+	if !reflect.DeepEqual(alloc, out) {
+		t.Fatalf("bad: %#v , actual:%#v", alloc, out)
+	}
+
+	summary, err := state.JobSummaryByID(alloc.Namespace, alloc.JobID)
+	expectedSummary := &structs.JobSummary{
+		JobID:     alloc.JobID,
+		Namespace: alloc.Namespace,
+		Summary: map[string]structs.TaskGroupSummary{
+			"web": structs.TaskGroupSummary{
+				Starting: 1,
+			},
+		},
+		CreateIndex: 999,
+		ModifyIndex: 1001,
+	}
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(summary, expectedSummary) {
+		t.Fatalf("expected: %#v, actual: %#v", expectedSummary, summary)
+	}
+}
+
+func TestStateStore_UpsertAlloc_Alloc(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "allocs"},
+		watch.Item{Alloc: alloc.ID},
+		watch.Item{AllocEval: alloc.EvalID},
+		watch.Item{AllocJob: alloc.JobID},
+		watch.Item{AllocNode: alloc.NodeID})
+
+	if err := state.UpsertJob(999, alloc.Job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.AllocByID(alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !reflect.DeepEqual(alloc, out) {
+		t.Fatalf("bad: %#v %#v", alloc, out)
+	}
+
+	index, err := state.Index("allocs")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
+	}
+
+	summary, err := state.JobSummaryByID(alloc.Namespace, alloc.JobID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	tgSummary, ok := summary.Summary["web"]
+	if !ok {
+		t.Fatalf("no summary for task group web")
+	}
+	if tgSummary.Starting != 1 {
+		t.Fatalf("expected queued: %v, actual: %v", 1, tgSummary.Starting)
+	}
+
+	notify.verify(t)
+}
+
+func TestStateStore_UpsertAlloc_NoEphemeralDisk(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+	alloc.Job.TaskGroups[0].EphemeralDisk = nil
+	alloc.Job.TaskGroups[0].Tasks[0].Resources.DiskMB = 120
+
+	if err := state.UpsertJob(999, alloc.Job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.AllocByID(alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	expected := alloc.Copy()
+	expected.Job.TaskGroups[0].EphemeralDisk = &structs.EphemeralDisk{SizeMB: 120}
+	if !reflect.DeepEqual(expected, out) {
+		t.Fatalf("bad: %#v %#v", expected, out)
+	}
+}
+
+func TestStateStore_UpdateAlloc_Alloc(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+
+	if err := state.UpsertJob(999, alloc.Job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	summary, err := state.JobSummaryByID(alloc.Namespace, alloc.JobID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	tgSummary := summary.Summary["web"]
+	if tgSummary.Starting != 1 {
+		t.Fatalf("expected starting: %v, actual: %v", 1, tgSummary.Starting)
+	}
+
+	alloc2 := mock.Alloc()
+	alloc2.ID = alloc.ID
+	alloc2.NodeID = alloc.NodeID + ".new"
+	state.UpsertJobSummary(1001, mock.JobSummary(alloc2.JobID))
+
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "allocs"},
+		watch.Item{Alloc: alloc2.ID},
+		watch.Item{AllocEval: alloc2.EvalID},
+		watch.Item{AllocJob: alloc2.JobID},
+		watch.Item{AllocNode: alloc2.NodeID})
+
+	err = state.UpsertAllocs(1002, []*structs.Allocation{alloc2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.AllocByID(alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !reflect.DeepEqual(alloc2, out) {
+		t.Fatalf("bad: %#v %#v", alloc2, out)
+	}
+
+	if out.CreateIndex != 1000 {
+		t.Fatalf("bad: %#v", out)
+	}
+	if out.ModifyIndex != 1002 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("allocs")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1002 {
+		t.Fatalf("bad: %d", index)
+	}
+
+	// Ensure that summary hasb't changed
+	summary, err = state.JobSummaryByID(alloc.Namespace, alloc.JobID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	tgSummary = summary.Summary["web"]
+	if tgSummary.Starting != 1 {
+		t.Fatalf("expected starting: %v, actual: %v", 1, tgSummary.Starting)
+	}
+
+	notify.verify(t)
+}
+
+// This test ensures that the state store will mark the clients status as lost
+// when set rather than preferring the existing status.
+func TestStateStore_UpdateAlloc_Lost(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+	alloc.ClientStatus = "foo"
+
+	if err := state.UpsertJob(999, alloc.Job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	alloc2 := new(structs.Allocation)
+	*alloc2 = *alloc
+	alloc2.ClientStatus = structs.AllocClientStatusLost
+	if err := state.UpsertAllocs(1001, []*structs.Allocation{alloc2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.AllocByID(alloc2.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if out.ClientStatus != structs.AllocClientStatusLost {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+// This test ensures an allocation can be updated when there is no job
+// associated with it. This will happen when a job is stopped by an user which
+// has non-terminal allocations on clients
+func TestStateStore_UpdateAlloc_NoJob(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+
+	// Upsert a job
+	state.UpsertJobSummary(998, mock.JobSummary(alloc.JobID))
+	if err := state.UpsertJob(999, alloc.Job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.DeleteJob(1001, alloc.Namespace, alloc.JobID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Update the desired state of the allocation to stop
+	allocCopy := alloc.Copy()
+	allocCopy.DesiredStatus = structs.AllocDesiredStatusStop
+	if err := state.UpsertAllocs(1002, []*structs.Allocation{allocCopy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Update the client state of the allocation to complete
+	allocCopy1 := allocCopy.Copy()
+	allocCopy1.ClientStatus = structs.AllocClientStatusComplete
+	if err := state.UpdateAllocsFromClient(1003, []*structs.Allocation{allocCopy1}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, _ := state.AllocByID(alloc.ID)
+	// Update the modify index of the alloc before comparing
+	allocCopy1.ModifyIndex = 1003
+	if !reflect.DeepEqual(out, allocCopy1) {
+		t.Fatalf("expected: %#v \n actual: %#v", allocCopy1, out)
+	}
+}
+
+func TestStateStore_JobSummary(t *testing.T) {
+	state := testStateStore(t)
+
+	// Add a job
+	job := mock.Job()
+	state.UpsertJob(900, job)
+
+	// Get the job back
+	outJob, _ := state.JobByID(job.Namespace, job.ID)
+	if outJob.CreateIndex != 900 {
+		t.Fatalf("bad create index: %v", outJob.CreateIndex)
+	}
+	summary, _ := state.JobSummaryByID(job.Namespace, job.ID)
+	if summary.CreateIndex != 900 {
+		t.Fatalf("bad create index: %v", summary.CreateIndex)
+	}
+
+	// Upser an allocation
+	alloc := mock.Alloc()
+	alloc.JobID = job.ID
+	alloc.Job = job
+	state.UpsertAllocs(910, []*structs.Allocation{alloc})
+
+	// Update the alloc from client
+	alloc1 := alloc.Copy()
+	alloc1.ClientStatus = structs.AllocClientStatusPending
+	alloc1.DesiredStatus = ""
+	state.UpdateAllocsFromClient(920, []*structs.Allocation{alloc})
+
+	alloc3 := alloc.Copy()
+	alloc3.ClientStatus = structs.AllocClientStatusRunning
+	alloc3.DesiredStatus = ""
+	state.UpdateAllocsFromClient(930, []*structs.Allocation{alloc3})
+
+	// Upsert the alloc
+	alloc4 := alloc.Copy()
+	alloc4.ClientStatus = structs.AllocClientStatusPending
+	alloc4.DesiredStatus = structs.AllocDesiredStatusRun
+	state.UpsertAllocs(950, []*structs.Allocation{alloc4})
+
+	// Again upsert the alloc
+	alloc5 := alloc.Copy()
+	alloc5.ClientStatus = structs.AllocClientStatusPending
+	alloc5.DesiredStatus = structs.AllocDesiredStatusRun
+	state.UpsertAllocs(970, []*structs.Allocation{alloc5})
+
+	expectedSummary := structs.JobSummary{
+		JobID:     job.ID,
+		Namespace: job.Namespace,
+		Summary: map[string]structs.TaskGroupSummary{
+			"web": structs.TaskGroupSummary{
+				Running: 1,
+			},
+		},
+		CreateIndex: 900,
+		ModifyIndex: 930,
+	}
+
+	summary, _ = state.JobSummaryByID(job.Namespace, job.ID)
+	if !reflect.DeepEqual(&expectedSummary, summary) {
+		t.Fatalf("expected: %#v, actual: %v", expectedSummary, summary)
+	}
+
+	// De-register the job.
+	state.DeleteJob(980, job.Namespace, job.ID)
+
+	// Shouldn't have any effect on the summary
+	alloc6 := alloc.Copy()
+	alloc6.ClientStatus = structs.AllocClientStatusRunning
+	alloc6.DesiredStatus = ""
+	state.UpdateAllocsFromClient(990, []*structs.Allocation{alloc6})
+
+	// We shouldn't have any summary at this point
+	summary, _ = state.JobSummaryByID(job.Namespace, job.ID)
+	if summary != nil {
+		t.Fatalf("expected nil, actual: %#v", summary)
+	}
+
+	// Re-register the same job
+	job1 := mock.Job()
+	job1.ID = job.ID
+	state.UpsertJob(1000, job1)
+	outJob2, _ := state.JobByID(job1.Namespace, job1.ID)
+	if outJob2.CreateIndex != 1000 {
+		t.Fatalf("bad create index: %v", outJob2.CreateIndex)
+	}
+	summary, _ = state.JobSummaryByID(job1.Namespace, job1.ID)
+	if summary.CreateIndex != 1000 {
+		t.Fatalf("bad create index: %v", summary.CreateIndex)
+	}
+
+	// Upsert an allocation
+	alloc7 := alloc.Copy()
+	alloc7.JobID = outJob.ID
+	alloc7.Job = outJob
+	alloc7.ClientStatus = structs.AllocClientStatusComplete
+	alloc7.DesiredStatus = structs.AllocDesiredStatusRun
+	state.UpdateAllocsFromClient(1020, []*structs.Allocation{alloc7})
+
+	expectedSummary = structs.JobSummary{
+		JobID:     job.ID,
+		Namespace: job.Namespace,
+		Summary: map[string]structs.TaskGroupSummary{
+			"web": structs.TaskGroupSummary{},
+		},
+		CreateIndex: 1000,
+		ModifyIndex: 1000,
+	}
+
+	summary, _ = state.JobSummaryByID(job1.Namespace, job1.ID)
+	if !reflect.DeepEqual(&expectedSummary, summary) {
+		t.Fatalf("expected: %#v, actual: %#v", expectedSummary, summary)
+	}
+}
+
+func TestStateStore_ReconcileJobSummary(t *testing.T) {
+	state := testStateStore(t)
+
+	// Create an alloc
+	alloc := mock.Alloc()
+
+	// Add another task group to the job
+	tg2 := alloc.Job.TaskGroups[0].Copy()
+	tg2.Name = "db"
+	alloc.Job.TaskGroups = append(alloc.Job.TaskGroups, tg2)
+	state.UpsertJob(100, alloc.Job)
+
+	// Create one more alloc for the db task group
+	alloc2 := mock.Alloc()
+	alloc2.TaskGroup = "db"
+	alloc2.JobID = alloc.JobID
+	alloc2.Job = alloc.Job
+
+	// Upserts the alloc
+	state.UpsertAllocs(110, []*structs.Allocation{alloc, alloc2})
+
+	// Change the state of the first alloc to running
+	alloc3 := alloc.Copy()
+	alloc3.ClientStatus = structs.AllocClientStatusRunning
+	state.UpdateAllocsFromClient(120, []*structs.Allocation{alloc3})
+
+	//Add some more allocs to the second tg
+	alloc4 := mock.Alloc()
+	alloc4.JobID = alloc.JobID
+	alloc4.Job = alloc.Job
+	alloc4.TaskGroup = "db"
+	alloc5 := alloc4.Copy()
+	alloc5.ClientStatus = structs.AllocClientStatusRunning
+
+	alloc6 := mock.Alloc()
+	alloc6.JobID = alloc.JobID
+	alloc6.Job = alloc.Job
+	alloc6.TaskGroup = "db"
+	alloc7 := alloc6.Copy()
+	alloc7.ClientStatus = structs.AllocClientStatusComplete
+
+	alloc8 := mock.Alloc()
+	alloc8.JobID = alloc.JobID
+	alloc8.Job = alloc.Job
+	alloc8.TaskGroup = "db"
+	alloc9 := alloc8.Copy()
+	alloc9.ClientStatus = structs.AllocClientStatusFailed
+
+	alloc10 := mock.Alloc()
+	alloc10.JobID = alloc.JobID
+	alloc10.Job = alloc.Job
+	alloc10.TaskGroup = "db"
+	alloc11 := alloc10.Copy()
+	alloc11.ClientStatus = structs.AllocClientStatusLost
+
+	state.UpsertAllocs(130, []*structs.Allocation{alloc4, alloc6, alloc8, alloc10})
+
+	state.UpdateAllocsFromClient(150, []*structs.Allocation{alloc5, alloc7, alloc9, alloc11})
+
+	// DeleteJobSummary is a helper method and doesn't modify the indexes table
+	state.DeleteJobSummary(130, alloc.Job.Namespace, alloc.Job.ID)
+
+	state.ReconcileJobSummaries(120)
+
+	summary, _ := state.JobSummaryByID(alloc.Job.Namespace, alloc.Job.ID)
+	expectedSummary := structs.JobSummary{
+		JobID:     alloc.Job.ID,
+		Namespace: alloc.Job.Namespace,
+		Summary: map[string]structs.TaskGroupSummary{
+			"web": structs.TaskGroupSummary{
+				Running: 1,
+			},
+			"db": structs.TaskGroupSummary{
+				Starting: 1,
+				Running:  1,
+				Failed:   1,
+				Complete: 1,
+				Lost:     1,
+			},
+		},
+		CreateIndex: 100,
+		ModifyIndex: 120,
+	}
+	if !reflect.DeepEqual(&expectedSummary, summary) {
+		t.Fatalf("expected: %v, actual: %v", expectedSummary, summary)
+	}
+}
+
+func TestStateStore_UpdateAlloc_JobNotPresent(t *testing.T) {
+	state := testStateStore(t)
+
+	alloc := mock.Alloc()
+	state.UpsertJob(100, alloc.Job)
+	state.UpsertAllocs(200, []*structs.Allocation{alloc})
+
+	// Delete the job
+	state.DeleteJob(300, alloc.Job.Namespace, alloc.Job.ID)
+
+	// Update the alloc
+	alloc1 := alloc.Copy()
+	alloc1.ClientStatus = structs.AllocClientStatusRunning
+
+	// Updating allocation should not throw any error
+	if err := state.UpdateAllocsFromClient(400, []*structs.Allocation{alloc1}); err != nil {
+		t.Fatalf("expect err: %v", err)
+	}
+
+	// Re-Register the job
+	state.UpsertJob(500, alloc.Job)
+
+	// Update the alloc again
+	alloc2 := alloc.Copy()
+	alloc2.ClientStatus = structs.AllocClientStatusComplete
+	if err := state.UpdateAllocsFromClient(400, []*structs.Allocation{alloc1}); err != nil {
+		t.Fatalf("expect err: %v", err)
+	}
+
+	// Job Summary of the newly registered job shouldn't account for the
+	// allocation update for the older job
+	expectedSummary := structs.JobSummary{
+		JobID:     alloc1.JobID,
+		Namespace: alloc1.Namespace,
+		Summary: map[string]structs.TaskGroupSummary{
+			"web": structs.TaskGroupSummary{},
+		},
+		CreateIndex: 500,
+		ModifyIndex: 500,
+	}
+	summary, _ := state.JobSummaryByID(alloc.Job.Namespace, alloc.Job.ID)
+	if !reflect.DeepEqual(&expectedSummary, summary) {
+		t.Fatalf("expected: %v, actual: %v", expectedSummary, summary)
+	}
+}
+
+func TestStateStore_EvictAlloc_Alloc(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+
+	state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID))
+	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	alloc2 := new(structs.Allocation)
+	*alloc2 = *alloc
+	alloc2.DesiredStatus = structs.AllocDesiredStatusEvict
+	err = state.UpsertAllocs(1001, []*structs.Allocation{alloc2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.AllocByID(alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if out.DesiredStatus != structs.AllocDesiredStatusEvict {
+		t.Fatalf("bad: %#v %#v", alloc, out)
+	}
+
+	index, err := state.Index("allocs")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_AllocsByNode(t *testing.T) {
+	state := testStateStore(t)
+	var allocs []*structs.Allocation
+
+	for i := 0; i < 10; i++ {
+		alloc := mock.Alloc()
+		alloc.NodeID = "foo"
+		allocs = append(allocs, alloc)
+	}
+
+	for idx, alloc := range allocs {
+		state.UpsertJobSummary(uint64(900+idx), mock.JobSummary(alloc.JobID))
+	}
+
+	err := state.UpsertAllocs(1000, allocs)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.AllocsByNode("foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	sort.Sort(AllocIDSort(allocs))
+	sort.Sort(AllocIDSort(out))
+
+	if !reflect.DeepEqual(allocs, out) {
+		t.Fatalf("bad: %#v %#v", allocs, out)
+	}
+}
+
+func TestStateStore_AllocsByNodeTerminal(t *testing.T) {
+	state := testStateStore(t)
+	var allocs, term, nonterm []*structs.Allocation
+
+	for i := 0; i < 10; i++ {
+		alloc := mock.Alloc()
+		alloc.NodeID = "foo"
+		if i%2 == 0 {
+			alloc.DesiredStatus = structs.AllocDesiredStatusStop
+			term = append(term, alloc)
+		} else {
+			nonterm = append(nonterm, alloc)
+		}
+		allocs = append(allocs, alloc)
+	}
+
+	for idx, alloc := range allocs {
+		state.UpsertJobSummary(uint64(900+idx), mock.JobSummary(alloc.JobID))
+	}
+
+	err := state.UpsertAllocs(1000, allocs)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Verify the terminal allocs
+	out, err := state.AllocsByNodeTerminal("foo", true)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	sort.Sort(AllocIDSort(term))
+	sort.Sort(AllocIDSort(out))
+
+	if !reflect.DeepEqual(term, out) {
+		t.Fatalf("bad: %#v %#v", term, out)
+	}
+
+	// Verify the non-terminal allocs
+	out, err = state.AllocsByNodeTerminal("foo", false)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	sort.Sort(AllocIDSort(nonterm))
+	sort.Sort(AllocIDSort(out))
+
+	if !reflect.DeepEqual(nonterm, out) {
+		t.Fatalf("bad: %#v %#v", nonterm, out)
+	}
+}
+
+func TestStateStore_AllocsByJob(t *testing.T) {
+	state := testStateStore(t)
+	var allocs []*structs.Allocation
+
+	for i := 0; i < 10; i++ {
+		alloc := mock.Alloc()
+		alloc.JobID = "foo"
+		allocs = append(allocs, alloc)
+	}
+
+	for i, alloc := range allocs {
+		state.UpsertJobSummary(uint64(900+i), mock.JobSummary(alloc.JobID))
+	}
+
+	err := state.UpsertAllocs(1000, allocs)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.AllocsByJob(structs.DefaultNamespace, "foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	sort.Sort(AllocIDSort(allocs))
+	sort.Sort(AllocIDSort(out))
+
+	if !reflect.DeepEqual(allocs, out) {
+		t.Fatalf("bad: %#v %#v", allocs, out)
+	}
+}
+
+func TestStateStore_AllocsByIDPrefix(t *testing.T) {
+	state := testStateStore(t)
+	var allocs []*structs.Allocation
+
+	ids := []string{
+		"aaaaaaaa-7bfb-395d-eb95-0685af2176b2",
+		"aaaaaaab-7bfb-395d-eb95-0685af2176b2",
+		"aaaaaabb-7bfb-395d-eb95-0685af2176b2",
+		"aaaaabbb-7bfb-395d-eb95-0685af2176b2",
+		"aaaabbbb-7bfb-395d-eb95-0685af2176b2",
+		"aaabbbbb-7bfb-395d-eb95-0685af2176b2",
+		"aabbbbbb-7bfb-395d-eb95-0685af2176b2",
+		"abbbbbbb-7bfb-395d-eb95-0685af2176b2",
+		"bbbbbbbb-7bfb-395d-eb95-0685af2176b2",
+	}
+	for i := 0; i < 9; i++ {
+		alloc := mock.Alloc()
+		alloc.ID = ids[i]
+		allocs = append(allocs, alloc)
+	}
+
+	for i, alloc := range allocs {
+		state.UpsertJobSummary(uint64(900+i), mock.JobSummary(alloc.JobID))
+	}
+
+	err := state.UpsertAllocs(1000, allocs)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	iter, err := state.AllocsByIDPrefix("aaaa")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	gatherAllocs := func(iter memdb.ResultIterator) []*structs.Allocation {
+		var allocs []*structs.Allocation
+		for {
+			raw := iter.Next()
+			if raw == nil {
+				break
+			}
+			allocs = append(allocs, raw.(*structs.Allocation))
+		}
+		return allocs
+	}
+
+	out := gatherAllocs(iter)
+	if len(out) != 5 {
+		t.Fatalf("bad: expected five allocations, got: %#v", out)
+	}
+
+	sort.Sort(AllocIDSort(allocs))
+
+	for index, alloc := range out {
+		if ids[index] != alloc.ID {
+			t.Fatalf("bad: got unexpected id: %s", alloc.ID)
+		}
+	}
+
+	iter, err = state.AllocsByIDPrefix("b-a7bfb")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out = gatherAllocs(iter)
+	if len(out) != 0 {
+		t.Fatalf("bad: unexpected zero allocations, got: %#v", out)
+	}
+}
+
+func TestStateStore_Allocs(t *testing.T) {
+	state := testStateStore(t)
+	var allocs []*structs.Allocation
+
+	for i := 0; i < 10; i++ {
+		alloc := mock.Alloc()
+		allocs = append(allocs, alloc)
+	}
+	for i, alloc := range allocs {
+		state.UpsertJobSummary(uint64(900+i), mock.JobSummary(alloc.JobID))
+	}
+
+	err := state.UpsertAllocs(1000, allocs)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	iter, err := state.Allocs()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var out []*structs.Allocation
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		out = append(out, raw.(*structs.Allocation))
+	}
+
+	sort.Sort(AllocIDSort(allocs))
+	sort.Sort(AllocIDSort(out))
+
+	if !reflect.DeepEqual(allocs, out) {
+		t.Fatalf("bad: %#v %#v", allocs, out)
+	}
+}
+
+func TestStateStore_RestoreAlloc(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "allocs"},
+		watch.Item{Alloc: alloc.ID},
+		watch.Item{AllocEval: alloc.EvalID},
+		watch.Item{AllocJob: alloc.JobID},
+		watch.Item{AllocNode: alloc.NodeID})
+
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err = restore.AllocRestore(alloc)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	restore.Commit()
+
+	out, err := state.AllocByID(alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !reflect.DeepEqual(out, alloc) {
+		t.Fatalf("Bad: %#v %#v", out, alloc)
+	}
+
+	notify.verify(t)
+}
+
+func TestStateStore_RestoreAlloc_NoEphemeralDisk(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+	alloc.Job.TaskGroups[0].EphemeralDisk = nil
+	alloc.Job.TaskGroups[0].Tasks[0].Resources.DiskMB = 120
+
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err = restore.AllocRestore(alloc)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	restore.Commit()
+
+	out, err := state.AllocByID(alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	expected := alloc.Copy()
+	expected.Job.TaskGroups[0].EphemeralDisk = &structs.EphemeralDisk{SizeMB: 120}
+	expected.Job.TaskGroups[0].Tasks[0].Resources.DiskMB = 0
+
+	if !reflect.DeepEqual(out, expected) {
+		t.Fatalf("Bad: %#v %#v", out, expected)
+	}
+}
+
+func TestStateStore_SetJobStatus_ForceStatus(t *testing.T) {
+	state := testStateStore(t)
+	watcher := watch.NewItems()
+	txn := state.db.Txn(true)
+
+	// Create and insert a mock job.
+	job := mock.Job()
+	job.Status = ""
+	job.ModifyIndex = 0
+	if err := txn.Insert("jobs", job); err != nil {
+		t.Fatalf("job insert failed: %v", err)
+	}
+
+	exp := "foobar"
+	index := uint64(1000)
+	if err := state.setJobStatus(index, watcher, txn, job, false, exp); err != nil {
+		t.Fatalf("setJobStatus() failed: %v", err)
+	}
+
+	i, err := txn.First("jobs", "id", job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("job lookup failed: %v", err)
+	}
+	updated := i.(*structs.Job)
+
+	if updated.Status != exp {
+		t.Fatalf("setJobStatus() set %v; expected %v", updated.Status, exp)
+	}
+
+	if updated.ModifyIndex != index {
+		t.Fatalf("setJobStatus() set %d; expected %d", updated.ModifyIndex, index)
+	}
+}
+
+func TestStateStore_SetJobStatus_NoOp(t *testing.T) {
+	state := testStateStore(t)
+	watcher := watch.NewItems()
+	txn := state.db.Txn(true)
+
+	// Create and insert a mock job that should be pending.
+	job := mock.Job()
+	job.Status = structs.JobStatusPending
+	job.ModifyIndex = 10
+	if err := txn.Insert("jobs", job); err != nil {
+		t.Fatalf("job insert failed: %v", err)
+	}
+
+	index := uint64(1000)
+	if err := state.setJobStatus(index, watcher, txn, job, false, ""); err != nil {
+		t.Fatalf("setJobStatus() failed: %v", err)
+	}
+
+	i, err := txn.First("jobs", "id", job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("job lookup failed: %v", err)
+	}
+	updated := i.(*structs.Job)
+
+	if updated.ModifyIndex == index {
+		t.Fatalf("setJobStatus() should have been a no-op")
+	}
+}
+
+func TestStateStore_SetJobStatus(t *testing.T) {
+	state := testStateStore(t)
+	watcher := watch.NewItems()
+	txn := state.db.Txn(true)
+
+	// Create and insert a mock job that should be pending but has an incorrect
+	// status.
+	job := mock.Job()
+	job.Status = "foobar"
+	job.ModifyIndex = 10
+	if err := txn.Insert("jobs", job); err != nil {
+		t.Fatalf("job insert failed: %v", err)
+	}
+
+	index := uint64(1000)
+	if err := state.setJobStatus(index, watcher, txn, job, false, ""); err != nil {
+		t.Fatalf("setJobStatus() failed: %v", err)
+	}
+
+	i, err := txn.First("jobs", "id", job.Namespace, job.ID)
+	if err != nil {
+		t.Fatalf("job lookup failed: %v", err)
+	}
+	updated := i.(*structs.Job)
+
+	if updated.Status != structs.JobStatusPending {
+		t.Fatalf("setJobStatus() set %v; expected %v", updated.Status, structs.JobStatusPending)
+	}
+
+	if updated.ModifyIndex != index {
+		t.Fatalf("setJobStatus() set %d; expected %d", updated.ModifyIndex, index)
+	}
+}
+
+func TestStateStore_GetJobStatus_NoEvalsOrAllocs(t *testing.T) {
+	job := mock.Job()
+	state := testStateStore(t)
+	txn := state.db.Txn(false)
+	status, err := state.getJobStatus(txn, job, false)
+	if err != nil {
+		t.Fatalf("getJobStatus() failed: %v", err)
+	}
+
+	if status != structs.JobStatusPending {
+		t.Fatalf("getJobStatus() returned %v; expected %v", status, structs.JobStatusPending)
+	}
+}
+
+func TestStateStore_GetJobStatus_NoEvalsOrAllocs_Periodic(t *testing.T) {
+	job := mock.PeriodicJob()
+	state := testStateStore(t)
+	txn := state.db.Txn(false)
+	status, err := state.getJobStatus(txn, job, false)
+	if err != nil {
+		t.Fatalf("getJobStatus() failed: %v", err)
+	}
+
+	if status != structs.JobStatusRunning {
+		t.Fatalf("getJobStatus() returned %v; expected %v", status, structs.JobStatusRunning)
+	}
+}
+
+func TestStateStore_GetJobStatus_NoEvalsOrAllocs_EvalDelete(t *testing.T) {
+	job := mock.Job()
+	state := testStateStore(t)
+	txn := state.db.Txn(false)
+	status, err := state.getJobStatus(txn, job, true)
+	if err != nil {
+		t.Fatalf("getJobStatus() failed: %v", err)
+	}
+
+	if status != structs.JobStatusDead {
+		t.Fatalf("getJobStatus() returned %v; expected %v", status, structs.JobStatusDead)
+	}
+}
+
+func TestStateStore_GetJobStatus_DeadEvalsAndAllocs(t *testing.T) {
+	state := testStateStore(t)
+	job := mock.Job()
+
+	// Create a mock alloc that is dead.
+	alloc := mock.Alloc()
+	alloc.JobID = job.ID
+	alloc.DesiredStatus = structs.AllocDesiredStatusStop
+	state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID))
+	if err := state.UpsertAllocs(1000, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a mock eval that is complete
+	eval := mock.Eval()
+	eval.JobID = job.ID
+	eval.Status = structs.EvalStatusComplete
+	if err := state.UpsertEvals(1001, []*structs.Evaluation{eval}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	txn := state.db.Txn(false)
+	status, err := state.getJobStatus(txn, job, false)
+	if err != nil {
+		t.Fatalf("getJobStatus() failed: %v", err)
+	}
+
+	if status != structs.JobStatusDead {
+		t.Fatalf("getJobStatus() returned %v; expected %v", status, structs.JobStatusDead)
+	}
+}
+
+func TestStateStore_GetJobStatus_RunningAlloc(t *testing.T) {
+	state := testStateStore(t)
+	job := mock.Job()
+
+	// Create a mock alloc that is running.
+	alloc := mock.Alloc()
+	alloc.JobID = job.ID
+	alloc.DesiredStatus = structs.AllocDesiredStatusRun
+	state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID))
+	if err := state.UpsertAllocs(1000, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	txn := state.db.Txn(false)
+	status, err := state.getJobStatus(txn, job, true)
+	if err != nil {
+		t.Fatalf("getJobStatus() failed: %v", err)
+	}
+
+	if status != structs.JobStatusRunning {
+		t.Fatalf("getJobStatus() returned %v; expected %v", status, structs.JobStatusRunning)
+	}
+}
+
+func TestStateStore_SetJobStatus_PendingEval(t *testing.T) {
+	state := testStateStore(t)
+	job := mock.Job()
+
+	// Create a mock eval that is pending.
+	eval := mock.Eval()
+	eval.JobID = job.ID
+	eval.Status = structs.EvalStatusPending
+	if err := state.UpsertEvals(1000, []*structs.Evaluation{eval}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	txn := state.db.Txn(false)
+	status, err := state.getJobStatus(txn, job, true)
+	if err != nil {
+		t.Fatalf("getJobStatus() failed: %v", err)
+	}
+
+	if status != structs.JobStatusPending {
+		t.Fatalf("getJobStatus() returned %v; expected %v", status, structs.JobStatusPending)
+	}
+}
+
+func TestStateWatch_watch(t *testing.T) {
+	sw := newStateWatch()
+	notify1 := make(chan struct{}, 1)
+	notify2 := make(chan struct{}, 1)
+	notify3 := make(chan struct{}, 1)
+
+	// Notifications trigger subscribed channels
+	sw.watch(watch.NewItems(watch.Item{Table: "foo"}), notify1)
+	sw.watch(watch.NewItems(watch.Item{Table: "bar"}), notify2)
+	sw.watch(watch.NewItems(watch.Item{Table: "baz"}), notify3)
+
+	items := watch.NewItems()
+	items.Add(watch.Item{Table: "foo"})
+	items.Add(watch.Item{Table: "bar"})
+
+	sw.notify(items)
+	if len(notify1) != 1 {
+		t.Fatalf("should notify")
+	}
+	if len(notify2) != 1 {
+		t.Fatalf("should notify")
+	}
+	if len(notify3) != 0 {
+		t.Fatalf("should not notify")
+	}
+}
+
+func TestStateWatch_stopWatch(t *testing.T) {
+	sw := newStateWatch()
+	notify := make(chan struct{})
+
+	// First subscribe
+	sw.watch(watch.NewItems(watch.Item{Table: "foo"}), notify)
+
+	// Unsubscribe stop notifications
+	sw.stopWatch(watch.NewItems(watch.Item{Table: "foo"}), notify)
+
+	// Check that the group was removed
+	if _, ok := sw.items[watch.Item{Table: "foo"}]; ok {
+		t.Fatalf("should remove group")
+	}
+
+	// Check that we are not notified
+	sw.notify(watch.NewItems(watch.Item{Table: "foo"}))
+	if len(notify) != 0 {
+		t.Fatalf("should not notify")
+	}
+}
+
+func TestStateJobSummary_UpdateJobCount(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+	job := alloc.Job
+	job.TaskGroups[0].Count = 3
+	err := state.UpsertJob(1000, job)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.UpsertAllocs(1001, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	summary, _ := state.JobSummaryByID(job.Namespace, job.ID)
+	expectedSummary := structs.JobSummary{
+		JobID:     job.ID,
+		Namespace: job.Namespace,
+		Summary: map[string]structs.TaskGroupSummary{
+			"web": {
+				Starting: 1,
+			},
+		},
+		CreateIndex: 1000,
+		ModifyIndex: 1001,
+	}
+	if !reflect.DeepEqual(summary, &expectedSummary) {
+		t.Fatalf("expected: %v, actual: %v", expectedSummary, summary)
+	}
+
+	alloc2 := mock.Alloc()
+	alloc2.Job = job
+	alloc2.JobID = job.ID
+
+	alloc3 := mock.Alloc()
+	alloc3.Job = job
+	alloc3.JobID = job.ID
+
+	if err := state.UpsertAllocs(1002, []*structs.Allocation{alloc2, alloc3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	outA, _ := state.AllocByID(alloc3.ID)
+
+	summary, _ = state.JobSummaryByID(job.Namespace, job.ID)
+	expectedSummary = structs.JobSummary{
+		JobID:     job.ID,
+		Namespace: job.Namespace,
+		Summary: map[string]structs.TaskGroupSummary{
+			"web": {
+				Starting: 3,
+			},
+		},
+		CreateIndex: job.CreateIndex,
+		ModifyIndex: outA.ModifyIndex,
+	}
+	if !reflect.DeepEqual(summary, &expectedSummary) {
+		t.Fatalf("expected summary: %v, actual: %v", expectedSummary, summary)
+	}
+
+	alloc4 := mock.Alloc()
+	alloc4.ID = alloc2.ID
+	alloc4.Job = alloc2.Job
+	alloc4.JobID = alloc2.JobID
+	alloc4.ClientStatus = structs.AllocClientStatusComplete
+
+	alloc5 := mock.Alloc()
+	alloc5.ID = alloc3.ID
+	alloc5.Job = alloc3.Job
+	alloc5.JobID = alloc3.JobID
+	alloc5.ClientStatus = structs.AllocClientStatusComplete
+
+	if err := state.UpdateAllocsFromClient(1004, []*structs.Allocation{alloc4, alloc5}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	outA, _ = state.AllocByID(alloc5.ID)
+	summary, _ = state.JobSummaryByID(job.Namespace, job.ID)
+	expectedSummary = structs.JobSummary{
+		JobID:     job.ID,
+		Namespace: job.Namespace,
+		Summary: map[string]structs.TaskGroupSummary{
+			"web": {
+				Complete: 2,
+				Starting: 1,
+			},
+		},
+		CreateIndex: job.CreateIndex,
+		ModifyIndex: outA.ModifyIndex,
+	}
+	if !reflect.DeepEqual(summary, &expectedSummary) {
+		t.Fatalf("expected: %v, actual: %v", expectedSummary, summary)
+	}
+}
+
+func TestJobSummary_UpdateClientStatus(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+	job := alloc.Job
+	job.TaskGroups[0].Count = 3
+
+	alloc2 := mock.Alloc()
+	alloc2.Job = job
+	alloc2.JobID = job.ID
+
+	alloc3 := mock.Alloc()
+	alloc3.Job = job
+	alloc3.JobID = job.ID
+
+	err := state.UpsertJob(1000, job)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.UpsertAllocs(1001, []*structs.Allocation{alloc, alloc2, alloc3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	summary, _ := state.JobSummaryByID(job.Namespace, job.ID)
+	if summary.Summary["web"].Starting != 3 {
+		t.Fatalf("bad job summary: %v", summary)
+	}
+
+	alloc4 := mock.Alloc()
+	alloc4.ID = alloc2.ID
+	alloc4.Job = alloc2.Job
+	alloc4.JobID = alloc2.JobID
+	alloc4.ClientStatus = structs.AllocClientStatusComplete
+
+	alloc5 := mock.Alloc()
+	alloc5.ID = alloc3.ID
+	alloc5.Job = alloc3.Job
+	alloc5.JobID = alloc3.JobID
+	alloc5.ClientStatus = structs.AllocClientStatusFailed
+
+	alloc6 := mock.Alloc()
+	alloc6.ID = alloc.ID
+	alloc6.Job = alloc.Job
+	alloc6.JobID = alloc.JobID
+	alloc6.ClientStatus = structs.AllocClientStatusRunning
+
+	if err := state.UpdateAllocsFromClient(1002, []*structs.Allocation{alloc4, alloc5, alloc6}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	summary, _ = state.JobSummaryByID(job.Namespace, job.ID)
+	if summary.Summary["web"].Running != 1 || summary.Summary["web"].Failed != 1 || summary.Summary["web"].Complete != 1 {
+		t.Fatalf("bad job summary: %v", summary)
+	}
+
+	alloc7 := mock.Alloc()
+	alloc7.Job = alloc.Job
+	alloc7.JobID = alloc.JobID
+
+	if err := state.UpsertAllocs(1003, []*structs.Allocation{alloc7}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	summary, _ = state.JobSummaryByID(job.Namespace, job.ID)
+	if summary.Summary["web"].Starting != 1 || summary.Summary["web"].Running != 1 || summary.Summary["web"].Failed != 1 || summary.Summary["web"].Complete != 1 {
+		t.Fatalf("bad job summary: %v", summary)
+	}
+}
+
+func TestStateStore_UpsertVaultAccessors(t *testing.T) {
+	state := testStateStore(t)
+	a := mock.VaultAccessor()
+	a2 := mock.VaultAccessor()
+
+	err := state.UpsertVaultAccessor(1000, []*structs.VaultAccessor{a, a2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.VaultAccessor(a.Accessor)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !reflect.DeepEqual(a, out) {
+		t.Fatalf("bad: %#v %#v", a, out)
+	}
+
+	out, err = state.VaultAccessor(a2.Accessor)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !reflect.DeepEqual(a2, out) {
+		t.Fatalf("bad: %#v %#v", a2, out)
+	}
+
+	iter, err := state.VaultAccessors()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	count := 0
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+
+		count++
+		accessor := raw.(*structs.VaultAccessor)
+
+		if !reflect.DeepEqual(accessor, a) && !reflect.DeepEqual(accessor, a2) {
+			t.Fatalf("bad: %#v", accessor)
+		}
+	}
+
+	if count != 2 {
+		t.Fatalf("bad: %d", count)
+	}
+
+	index, err := state.Index("vault_accessors")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_DeleteVaultAccessors(t *testing.T) {
+	state := testStateStore(t)
+	a1 := mock.VaultAccessor()
+	a2 := mock.VaultAccessor()
+	accessors := []*structs.VaultAccessor{a1, a2}
+
+	err := state.UpsertVaultAccessor(1000, accessors)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err = state.DeleteVaultAccessors(1001, accessors)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.VaultAccessor(a1.Accessor)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v %#v", a1, out)
+	}
+	out, err = state.VaultAccessor(a2.Accessor)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v %#v", a2, out)
+	}
+
+	index, err := state.Index("vault_accessors")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_VaultAccessorsByAlloc(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+	var accessors []*structs.VaultAccessor
+	var expected []*structs.VaultAccessor
+
+	for i := 0; i < 5; i++ {
+		accessor := mock.VaultAccessor()
+		accessor.AllocID = alloc.ID
+		expected = append(expected, accessor)
+		accessors = append(accessors, accessor)
+	}
+
+	for i := 0; i < 10; i++ {
+		accessor := mock.VaultAccessor()
+		accessors = append(accessors, accessor)
+	}
+
+	err := state.UpsertVaultAccessor(1000, accessors)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.VaultAccessorsByAlloc(alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if len(expected) != len(out) {
+		t.Fatalf("bad: %#v %#v", len(expected), len(out))
+	}
+
+	index, err := state.Index("vault_accessors")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_VaultAccessorsByNode(t *testing.T) {
+	state := testStateStore(t)
+	node := mock.Node()
+	var accessors []*structs.VaultAccessor
+	var expected []*structs.VaultAccessor
+
+	for i := 0; i < 5; i++ {
+		accessor := mock.VaultAccessor()
+		accessor.NodeID = node.ID
+		expected = append(expected, accessor)
+		accessors = append(accessors, accessor)
+	}
+
+	for i := 0; i < 10; i++ {
+		accessor := mock.VaultAccessor()
+		accessors = append(accessors, accessor)
+	}
+
+	err := state.UpsertVaultAccessor(1000, accessors)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.VaultAccessorsByNode(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if len(expected) != len(out) {
+		t.Fatalf("bad: %#v %#v", len(expected), len(out))
+	}
+
+	index, err := state.Index("vault_accessors")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_RestoreVaultAccessor(t *testing.T) {
+	state := testStateStore(t)
+	a := mock.VaultAccessor()
+
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err = restore.VaultAccessorRestore(a)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	restore.Commit()
+
+	out, err := state.VaultAccessor(a.Accessor)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !reflect.DeepEqual(out, a) {
+		t.Fatalf("Bad: %#v %#v", out, a)
+	}
+}
+
+// setupNotifyTest takes a state store and a set of watch items, then creates
+// and subscribes a notification channel for each item.
+func setupNotifyTest(state *StateStore, items ...watch.Item) notifyTest {
+	var n notifyTest
+	for _, item := range items {
+		ch := make(chan struct{}, 1)
+		state.Watch(watch.NewItems(item), ch)
+		n = append(n, &notifyTestCase{item, ch})
+	}
+	return n
+}
+
+// notifyTestCase is used to set up and verify watch triggers.
+type notifyTestCase struct {
+	item watch.Item
+	ch   chan struct{}
+}
+
+// notifyTest is a suite of notifyTestCases.
+type notifyTest []*notifyTestCase
+
+// verify ensures that each channel received a notification.
+func (n notifyTest) verify(t *testing.T) {
+	for _, tcase := range n {
+		if len(tcase.ch) != 1 {
+			t.Fatalf("should notify %#v", tcase.item)
+		}
+	}
+}
+
+// NodeIDSort is used to sort nodes by ID
+type NodeIDSort []*structs.Node
+
+func (n NodeIDSort) Len() int {
+	return len(n)
+}
+
+func (n NodeIDSort) Less(i, j int) bool {
+	return n[i].ID < n[j].ID
+}
+
+func (n NodeIDSort) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
+
+// JobIDis used to sort jobs by id
+type JobIDSort []*structs.Job
+
+func (n JobIDSort) Len() int {
+	return len(n)
+}
+
+func (n JobIDSort) Less(i, j int) bool {
+	return n[i].ID < n[j].ID
+}
+
+func (n JobIDSort) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
+
+// EvalIDis used to sort evals by id
+type EvalIDSort []*structs.Evaluation
+
+func (n EvalIDSort) Len() int {
+	return len(n)
+}
+
+func (n EvalIDSort) Less(i, j int) bool {
+	return n[i].ID < n[j].ID
+}
+
+func (n EvalIDSort) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
+
+// AllocIDsort used to sort allocations by id
+type AllocIDSort []*structs.Allocation
+
+func (n AllocIDSort) Len() int {
+	return len(n)
+}
+
+func (n AllocIDSort) Less(i, j int) bool {
+	return n[i].ID < n[j].ID
+}
+
+func (n AllocIDSort) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
 
-	if out.CreateIndex != 1000 {
-		t.Fatalf("bad: %#v", out)
+func TestStateStore_UpsertMaintenanceWindows(t *testing.T) {
+	state := testStateStore(t)
+	w1 := mock.MaintenanceWindow()
+	w2 := mock.MaintenanceWindow()
+	w2.NodeClass = "other"
+
+	if err := state.UpsertMaintenanceWindows(1000, []*structs.MaintenanceWindow{w1, w2}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
-	if out.ModifyIndex != 1002 {
-		t.Fatalf("bad: %#v", out)
+
+	out, err := state.MaintenanceWindowByID(w1.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(w1, out) {
+		t.Fatalf("bad: %#v %#v", w1, out)
 	}
 
-	index, err := state.Index("allocs")
+	// Update the first window and ensure the create index is retained
+	update := w1.Copy()
+	update.Mode = structs.MaintenanceModeSoft
+	if err := state.UpsertMaintenanceWindows(1001, []*structs.MaintenanceWindow{update}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.MaintenanceWindowByID(w1.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if index != 1002 {
-		t.Fatalf("bad: %d", index)
+	if out.CreateIndex != 1000 || out.ModifyIndex != 1001 || out.Mode != structs.MaintenanceModeSoft {
+		t.Fatalf("bad: %#v", out)
 	}
 
-	// Ensure that summary hasb't changed
-	summary, err = state.JobSummaryByID(alloc.JobID)
+	// The windows are looked up by node class
+	iter, err := state.MaintenanceWindowsByNodeClass("other")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	tgSummary = summary.Summary["web"]
-	if tgSummary.Starting != 1 {
-		t.Fatalf("expected starting: %v, actual: %v", 1, tgSummary.Starting)
+	raw := iter.Next()
+	if raw == nil || raw.(*structs.MaintenanceWindow).ID != w2.ID || iter.Next() != nil {
+		t.Fatalf("bad: %#v", raw)
 	}
 
-	notify.verify(t)
+	index, err := state.Index("maintenance_windows")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
 }
 
-// This test ensures that the state store will mark the clients status as lost
-// when set rather than preferring the existing status.
-func TestStateStore_UpdateAlloc_Lost(t *testing.T) {
+func TestStateStore_DeleteMaintenanceWindows(t *testing.T) {
 	state := testStateStore(t)
-	alloc := mock.Alloc()
-	alloc.ClientStatus = "foo"
-
-	if err := state.UpsertJob(999, alloc.Job); err != nil {
+	w1 := mock.MaintenanceWindow()
+	w2 := mock.MaintenanceWindow()
+	if err := state.UpsertMaintenanceWindows(1000, []*structs.MaintenanceWindow{w1, w2}); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
-	if err != nil {
+	if err := state.DeleteMaintenanceWindows(1001, []string{w1.ID}); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	alloc2 := new(structs.Allocation)
-	*alloc2 = *alloc
-	alloc2.ClientStatus = structs.AllocClientStatusLost
-	if err := state.UpsertAllocs(1001, []*structs.Allocation{alloc2}); err != nil {
-		t.Fatalf("err: %v", err)
+	// Deleting an unknown window fails
+	if err := state.DeleteMaintenanceWindows(1002, []string{w1.ID}); err == nil {
+		t.Fatalf("expected error")
 	}
 
-	out, err := state.AllocByID(alloc2.ID)
+	iter, err := state.MaintenanceWindows()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	raw := iter.Next()
+	if raw == nil || raw.(*structs.MaintenanceWindow).ID != w2.ID || iter.Next() != nil {
+		t.Fatalf("bad: %#v", raw)
+	}
 
-	if out.ClientStatus != structs.AllocClientStatusLost {
-		t.Fatalf("bad: %#v", out)
+	index, err := state.Index("maintenance_windows")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
 	}
 }
 
-// This test ensures an allocation can be updated when there is no job
-// associated with it. This will happen when a job is stopped by an user which
-// has non-terminal allocations on clients
-func TestStateStore_UpdateAlloc_NoJob(t *testing.T) {
+func TestStateStore_UpsertNamespaces(t *testing.T) {
 	state := testStateStore(t)
-	alloc := mock.Alloc()
+	ns1 := mock.Namespace()
+	ns2 := mock.Namespace()
 
-	// Upsert a job
-	state.UpsertJobSummary(998, mock.JobSummary(alloc.JobID))
-	if err := state.UpsertJob(999, alloc.Job); err != nil {
+	if err := state.UpsertNamespaces(1000, []*structs.Namespace{ns1, ns2}); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	out, err := state.NamespaceByName(ns1.Name)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if !reflect.DeepEqual(ns1, out) {
+		t.Fatalf("bad: %#v %#v", ns1, out)
+	}
 
-	if err := state.DeleteJob(1001, alloc.JobID); err != nil {
+	// Update the first namespace and ensure the create index is retained
+	update := ns1.Copy()
+	update.Description = "updated"
+	if err := state.UpsertNamespaces(1001, []*structs.Namespace{update}); err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	// Update the desired state of the allocation to stop
-	allocCopy := alloc.Copy()
-	allocCopy.DesiredStatus = structs.AllocDesiredStatusStop
-	if err := state.UpsertAllocs(1002, []*structs.Allocation{allocCopy}); err != nil {
+	out, err = state.NamespaceByName(ns1.Name)
+	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if out.CreateIndex != 1000 || out.ModifyIndex != 1001 || out.Description != "updated" {
+		t.Fatalf("bad: %#v", out)
+	}
 
-	// Update the client state of the allocation to complete
-	allocCopy1 := allocCopy.Copy()
-	allocCopy1.ClientStatus = structs.AllocClientStatusComplete
-	if err := state.UpdateAllocsFromClient(1003, []*structs.Allocation{allocCopy1}); err != nil {
+	iter, err := state.Namespaces()
+	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	count := 0
+	for iter.Next() != nil {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("bad: %d", count)
+	}
 
-	out, _ := state.AllocByID(alloc.ID)
-	// Update the modify index of the alloc before comparing
-	allocCopy1.ModifyIndex = 1003
-	if !reflect.DeepEqual(out, allocCopy1) {
-		t.Fatalf("expected: %#v \n actual: %#v", allocCopy1, out)
+	index, err := state.Index("namespaces")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
 	}
 }
 
-func TestStateStore_JobSummary(t *testing.T) {
+func TestStateStore_DeleteNamespaces(t *testing.T) {
 	state := testStateStore(t)
+	ns := mock.Namespace()
+	if err := state.UpsertNamespaces(1000, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-	// Add a job
+	// A namespace containing jobs can not be deleted
 	job := mock.Job()
-	state.UpsertJob(900, job)
-
-	// Get the job back
-	outJob, _ := state.JobByID(job.ID)
-	if outJob.CreateIndex != 900 {
-		t.Fatalf("bad create index: %v", outJob.CreateIndex)
+	job.Namespace = ns.Name
+	if err := state.UpsertJob(1001, job); err != nil {
+		t.Fatalf("err: %v", err)
 	}
-	summary, _ := state.JobSummaryByID(job.ID)
-	if summary.CreateIndex != 900 {
-		t.Fatalf("bad create index: %v", summary.CreateIndex)
+	if err := state.DeleteNamespaces(1002, []string{ns.Name}); err == nil {
+		t.Fatalf("expected error deleting non-empty namespace")
 	}
 
-	// Upser an allocation
-	alloc := mock.Alloc()
-	alloc.JobID = job.ID
-	alloc.Job = job
-	state.UpsertAllocs(910, []*structs.Allocation{alloc})
-
-	// Update the alloc from client
-	alloc1 := alloc.Copy()
-	alloc1.ClientStatus = structs.AllocClientStatusPending
-	alloc1.DesiredStatus = ""
-	state.UpdateAllocsFromClient(920, []*structs.Allocation{alloc})
-
-	alloc3 := alloc.Copy()
-	alloc3.ClientStatus = structs.AllocClientStatusRunning
-	alloc3.DesiredStatus = ""
-	state.UpdateAllocsFromClient(930, []*structs.Allocation{alloc3})
-
-	// Upsert the alloc
-	alloc4 := alloc.Copy()
-	alloc4.ClientStatus = structs.AllocClientStatusPending
-	alloc4.DesiredStatus = structs.AllocDesiredStatusRun
-	state.UpsertAllocs(950, []*structs.Allocation{alloc4})
-
-	// Again upsert the alloc
-	alloc5 := alloc.Copy()
-	alloc5.ClientStatus = structs.AllocClientStatusPending
-	alloc5.DesiredStatus = structs.AllocDesiredStatusRun
-	state.UpsertAllocs(970, []*structs.Allocation{alloc5})
-
-	expectedSummary := structs.JobSummary{
-		JobID: job.ID,
-		Summary: map[string]structs.TaskGroupSummary{
-			"web": structs.TaskGroupSummary{
-				Running: 1,
-			},
-		},
-		CreateIndex: 900,
-		ModifyIndex: 930,
+	// The default namespace can never be deleted
+	if err := state.DeleteNamespaces(1002, []string{structs.DefaultNamespace}); err == nil {
+		t.Fatalf("expected error deleting default namespace")
 	}
 
-	summary, _ = state.JobSummaryByID(job.ID)
-	if !reflect.DeepEqual(&expectedSummary, summary) {
-		t.Fatalf("expected: %#v, actual: %v", expectedSummary, summary)
+	if err := state.DeleteJob(1003, job.Namespace, job.ID); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	// De-register the job.
-	state.DeleteJob(980, job.ID)
-
-	// Shouldn't have any effect on the summary
-	alloc6 := alloc.Copy()
-	alloc6.ClientStatus = structs.AllocClientStatusRunning
-	alloc6.DesiredStatus = ""
-	state.UpdateAllocsFromClient(990, []*structs.Allocation{alloc6})
-
-	// We shouldn't have any summary at this point
-	summary, _ = state.JobSummaryByID(job.ID)
-	if summary != nil {
-		t.Fatalf("expected nil, actual: %#v", summary)
+	// A namespace containing variables can not be deleted
+	key := mock.RootKey()
+	variable := mock.VariableEncrypted(key.KeyID)
+	variable.Namespace = ns.Name
+	if err := state.UpsertRootKeys(1004, []*structs.RootKey{key}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
-
-	// Re-register the same job
-	job1 := mock.Job()
-	job1.ID = job.ID
-	state.UpsertJob(1000, job1)
-	outJob2, _ := state.JobByID(job1.ID)
-	if outJob2.CreateIndex != 1000 {
-		t.Fatalf("bad create index: %v", outJob2.CreateIndex)
+	if err := state.UpsertVariable(1005, variable, nil); err != nil {
+		t.Fatalf("err: %v", err)
 	}
-	summary, _ = state.JobSummaryByID(job1.ID)
-	if summary.CreateIndex != 1000 {
-		t.Fatalf("bad create index: %v", summary.CreateIndex)
+	if err := state.DeleteNamespaces(1006, []string{ns.Name}); err == nil {
+		t.Fatalf("expected error deleting namespace with variables")
+	}
+	if err := state.DeleteVariable(1007, ns.Name, variable.Path, nil); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	// Upsert an allocation
-	alloc7 := alloc.Copy()
-	alloc7.JobID = outJob.ID
-	alloc7.Job = outJob
-	alloc7.ClientStatus = structs.AllocClientStatusComplete
-	alloc7.DesiredStatus = structs.AllocDesiredStatusRun
-	state.UpdateAllocsFromClient(1020, []*structs.Allocation{alloc7})
-
-	expectedSummary = structs.JobSummary{
-		JobID: job.ID,
-		Summary: map[string]structs.TaskGroupSummary{
-			"web": structs.TaskGroupSummary{},
-		},
-		CreateIndex: 1000,
-		ModifyIndex: 1000,
+	if err := state.DeleteNamespaces(1008, []string{ns.Name}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	summary, _ = state.JobSummaryByID(job1.ID)
-	if !reflect.DeepEqual(&expectedSummary, summary) {
-		t.Fatalf("expected: %#v, actual: %#v", expectedSummary, summary)
+	out, err := state.NamespaceByName(ns.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
 	}
 }
 
-func TestStateStore_ReconcileJobSummary(t *testing.T) {
+func TestStateStore_UpsertQuotaSpecs(t *testing.T) {
 	state := testStateStore(t)
+	quota := mock.QuotaSpec()
 
-	// Create an alloc
-	alloc := mock.Alloc()
-
-	// Add another task group to the job
-	tg2 := alloc.Job.TaskGroups[0].Copy()
-	tg2.Name = "db"
-	alloc.Job.TaskGroups = append(alloc.Job.TaskGroups, tg2)
-	state.UpsertJob(100, alloc.Job)
-
-	// Create one more alloc for the db task group
-	alloc2 := mock.Alloc()
-	alloc2.TaskGroup = "db"
-	alloc2.JobID = alloc.JobID
-	alloc2.Job = alloc.Job
-
-	// Upserts the alloc
-	state.UpsertAllocs(110, []*structs.Allocation{alloc, alloc2})
-
-	// Change the state of the first alloc to running
-	alloc3 := alloc.Copy()
-	alloc3.ClientStatus = structs.AllocClientStatusRunning
-	state.UpdateAllocsFromClient(120, []*structs.Allocation{alloc3})
-
-	//Add some more allocs to the second tg
-	alloc4 := mock.Alloc()
-	alloc4.JobID = alloc.JobID
-	alloc4.Job = alloc.Job
-	alloc4.TaskGroup = "db"
-	alloc5 := alloc4.Copy()
-	alloc5.ClientStatus = structs.AllocClientStatusRunning
-
-	alloc6 := mock.Alloc()
-	alloc6.JobID = alloc.JobID
-	alloc6.Job = alloc.Job
-	alloc6.TaskGroup = "db"
-	alloc7 := alloc6.Copy()
-	alloc7.ClientStatus = structs.AllocClientStatusComplete
-
-	alloc8 := mock.Alloc()
-	alloc8.JobID = alloc.JobID
-	alloc8.Job = alloc.Job
-	alloc8.TaskGroup = "db"
-	alloc9 := alloc8.Copy()
-	alloc9.ClientStatus = structs.AllocClientStatusFailed
-
-	alloc10 := mock.Alloc()
-	alloc10.JobID = alloc.JobID
-	alloc10.Job = alloc.Job
-	alloc10.TaskGroup = "db"
-	alloc11 := alloc10.Copy()
-	alloc11.ClientStatus = structs.AllocClientStatusLost
-
-	state.UpsertAllocs(130, []*structs.Allocation{alloc4, alloc6, alloc8, alloc10})
+	// A namespace can not reference an unknown quota
+	ns := mock.Namespace()
+	ns.Quota = quota.Name
+	if err := state.UpsertNamespaces(999, []*structs.Namespace{ns}); err == nil {
+		t.Fatalf("expected error referencing unknown quota")
+	}
 
-	state.UpdateAllocsFromClient(150, []*structs.Allocation{alloc5, alloc7, alloc9, alloc11})
+	if err := state.UpsertQuotaSpecs(1000, []*structs.QuotaSpec{quota}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertNamespaces(1001, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-	// DeleteJobSummary is a helper method and doesn't modify the indexes table
-	state.DeleteJobSummary(130, alloc.Job.ID)
+	out, err := state.QuotaSpecByName(quota.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(quota, out) {
+		t.Fatalf("bad: %#v %#v", quota, out)
+	}
 
-	state.ReconcileJobSummaries(120)
+	iter, err := state.NamespacesByQuota(quota.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	raw := iter.Next()
+	if raw == nil || raw.(*structs.Namespace).Name != ns.Name {
+		t.Fatalf("bad: %#v", raw)
+	}
 
-	summary, _ := state.JobSummaryByID(alloc.Job.ID)
-	expectedSummary := structs.JobSummary{
-		JobID: alloc.Job.ID,
-		Summary: map[string]structs.TaskGroupSummary{
-			"web": structs.TaskGroupSummary{
-				Running: 1,
-			},
-			"db": structs.TaskGroupSummary{
-				Starting: 1,
-				Running:  1,
-				Failed:   1,
-				Complete: 1,
-				Lost:     1,
-			},
-		},
-		CreateIndex: 100,
-		ModifyIndex: 120,
+	index, err := state.Index("quota_specs")
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
-	if !reflect.DeepEqual(&expectedSummary, summary) {
-		t.Fatalf("expected: %v, actual: %v", expectedSummary, summary)
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
 	}
 }
 
-func TestStateStore_UpdateAlloc_JobNotPresent(t *testing.T) {
+func TestStateStore_DeleteQuotaSpecs(t *testing.T) {
 	state := testStateStore(t)
+	quota := mock.QuotaSpec()
+	if err := state.UpsertQuotaSpecs(1000, []*structs.QuotaSpec{quota}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-	alloc := mock.Alloc()
-	state.UpsertJob(100, alloc.Job)
-	state.UpsertAllocs(200, []*structs.Allocation{alloc})
+	// A quota attached to a namespace can not be deleted
+	ns := mock.Namespace()
+	ns.Quota = quota.Name
+	if err := state.UpsertNamespaces(1001, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.DeleteQuotaSpecs(1002, []string{quota.Name}); err == nil {
+		t.Fatalf("expected error deleting quota in use")
+	}
 
-	// Delete the job
-	state.DeleteJob(300, alloc.Job.ID)
+	ns = ns.Copy()
+	ns.Quota = ""
+	if err := state.UpsertNamespaces(1003, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.DeleteQuotaSpecs(1004, []string{quota.Name}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-	// Update the alloc
-	alloc1 := alloc.Copy()
-	alloc1.ClientStatus = structs.AllocClientStatusRunning
+	out, err := state.QuotaSpecByName(quota.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
 
-	// Updating allocation should not throw any error
-	if err := state.UpdateAllocsFromClient(400, []*structs.Allocation{alloc1}); err != nil {
-		t.Fatalf("expect err: %v", err)
+func TestStateStore_QuotaUsage(t *testing.T) {
+	state := testStateStore(t)
+	quota := mock.QuotaSpec()
+	if err := state.UpsertQuotaSpecs(1000, []*structs.QuotaSpec{quota}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ns := mock.Namespace()
+	ns.Quota = quota.Name
+	if err := state.UpsertNamespaces(1001, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	// Re-Register the job
-	state.UpsertJob(500, alloc.Job)
+	// Only the non-terminal allocations of the namespace count
+	alloc1 := mock.Alloc()
+	alloc1.Namespace = ns.Name
+	alloc2 := mock.Alloc()
+	alloc2.Namespace = ns.Name
+	alloc2.DesiredStatus = structs.AllocDesiredStatusStop
+	alloc3 := mock.Alloc()
+	if err := state.UpsertAllocs(1002, []*structs.Allocation{alloc1, alloc2, alloc3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-	// Update the alloc again
-	alloc2 := alloc.Copy()
-	alloc2.ClientStatus = structs.AllocClientStatusComplete
-	if err := state.UpdateAllocsFromClient(400, []*structs.Allocation{alloc1}); err != nil {
-		t.Fatalf("expect err: %v", err)
+	usage, err := state.QuotaUsage("global", quota.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := &structs.QuotaLimit{
+		Region:   "global",
+		CPU:      alloc1.Resources.CPU,
+		MemoryMB: alloc1.Resources.MemoryMB,
+		Count:    1,
+	}
+	if !reflect.DeepEqual(usage.Used, expected) {
+		t.Fatalf("bad: %#v", usage.Used)
+	}
+	if usage.Limit != quota.Limits[0] {
+		t.Fatalf("bad: %#v", usage.Limit)
+	}
+	if len(usage.Namespaces) != 1 || usage.Namespaces[0] != ns.Name {
+		t.Fatalf("bad: %#v", usage.Namespaces)
 	}
 
-	// Job Summary of the newly registered job shouldn't account for the
-	// allocation update for the older job
-	expectedSummary := structs.JobSummary{
-		JobID: alloc1.JobID,
-		Summary: map[string]structs.TaskGroupSummary{
-			"web": structs.TaskGroupSummary{},
-		},
-		CreateIndex: 500,
-		ModifyIndex: 500,
+	// Unknown quotas have no usage
+	usage, err = state.QuotaUsage("global", "unknown")
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
-	summary, _ := state.JobSummaryByID(alloc.Job.ID)
-	if !reflect.DeepEqual(&expectedSummary, summary) {
-		t.Fatalf("expected: %v, actual: %v", expectedSummary, summary)
+	if usage != nil {
+		t.Fatalf("bad: %#v", usage)
 	}
 }
 
-func TestStateStore_EvictAlloc_Alloc(t *testing.T) {
+func TestStateStore_UpsertACLPolicy(t *testing.T) {
 	state := testStateStore(t)
-	alloc := mock.Alloc()
+	policy := mock.ACLPolicy()
+	policy2 := mock.ACLPolicy()
 
-	state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID))
-	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	if err := state.UpsertACLPolicies(1000, []*structs.ACLPolicy{policy, policy2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.ACLPolicyByName(policy.Name)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if !reflect.DeepEqual(policy, out) {
+		t.Fatalf("bad: %#v %#v", policy, out)
+	}
 
-	alloc2 := new(structs.Allocation)
-	*alloc2 = *alloc
-	alloc2.DesiredStatus = structs.AllocDesiredStatusEvict
-	err = state.UpsertAllocs(1001, []*structs.Allocation{alloc2})
+	out, err = state.ACLPolicyByName(policy2.Name)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if !reflect.DeepEqual(policy2, out) {
+		t.Fatalf("bad: %#v %#v", policy2, out)
+	}
 
-	out, err := state.AllocByID(alloc.ID)
+	iter, err := state.ACLPolicies()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	if out.DesiredStatus != structs.AllocDesiredStatusEvict {
-		t.Fatalf("bad: %#v %#v", alloc, out)
+	// Ensure we see both policies
+	count := 0
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("bad: %d", count)
 	}
 
-	index, err := state.Index("allocs")
+	index, err := state.Index("acl_policy")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if index != 1001 {
+	if index != 1000 {
 		t.Fatalf("bad: %d", index)
 	}
 }
 
-func TestStateStore_AllocsByNode(t *testing.T) {
+func TestStateStore_DeleteACLPolicy(t *testing.T) {
 	state := testStateStore(t)
-	var allocs []*structs.Allocation
+	policy := mock.ACLPolicy()
+	policy2 := mock.ACLPolicy()
 
-	for i := 0; i < 10; i++ {
-		alloc := mock.Alloc()
-		alloc.NodeID = "foo"
-		allocs = append(allocs, alloc)
+	// Create the policy
+	if err := state.UpsertACLPolicies(1000, []*structs.ACLPolicy{policy, policy2}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	for idx, alloc := range allocs {
-		state.UpsertJobSummary(uint64(900+idx), mock.JobSummary(alloc.JobID))
+	// Delete the policy
+	if err := state.DeleteACLPolicies(1001, []string{policy.Name, policy2.Name}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	err := state.UpsertAllocs(1000, allocs)
+	// Ensure the policy is gone
+	out, err := state.ACLPolicyByName(policy.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	iter, err := state.ACLPolicies()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if raw := iter.Next(); raw != nil {
+		t.Fatalf("bad: %#v", raw)
+	}
 
-	out, err := state.AllocsByNode("foo")
+	index, err := state.Index("acl_policy")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
 
-	sort.Sort(AllocIDSort(allocs))
-	sort.Sort(AllocIDSort(out))
+func TestStateStore_ACLPolicyByNamePrefix(t *testing.T) {
+	state := testStateStore(t)
+	names := []string{
+		"foo",
+		"bar",
+		"foobar",
+		"foozip",
+		"zip",
+	}
+
+	// Create the policies
+	var baseIndex uint64 = 1000
+	for _, name := range names {
+		p := mock.ACLPolicy()
+		p.Name = name
+		if err := state.UpsertACLPolicies(baseIndex, []*structs.ACLPolicy{p}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		baseIndex++
+	}
 
-	if !reflect.DeepEqual(allocs, out) {
-		t.Fatalf("bad: %#v %#v", allocs, out)
+	// Scan by prefix
+	iter, err := state.ACLPolicyByNamePrefix("foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure we see both policies
+	count := 0
+	out := []string{}
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		count++
+		out = append(out, raw.(*structs.ACLPolicy).Name)
+	}
+	if count != 3 {
+		t.Fatalf("bad: %d %v", count, out)
+	}
+	sort.Strings(out)
+
+	expect := []string{"foo", "foobar", "foozip"}
+	if !reflect.DeepEqual(out, expect) {
+		t.Fatalf("bad: %#v", out)
 	}
 }
 
-func TestStateStore_AllocsByNodeTerminal(t *testing.T) {
+func TestStateStore_BootstrapACLTokens(t *testing.T) {
 	state := testStateStore(t)
-	var allocs, term, nonterm []*structs.Allocation
+	tk1 := mock.ACLToken()
+	tk2 := mock.ACLToken()
 
-	for i := 0; i < 10; i++ {
-		alloc := mock.Alloc()
-		alloc.NodeID = "foo"
-		if i%2 == 0 {
-			alloc.DesiredStatus = structs.AllocDesiredStatusStop
-			term = append(term, alloc)
-		} else {
-			nonterm = append(nonterm, alloc)
-		}
-		allocs = append(allocs, alloc)
+	ok, resetIdx, err := state.CanBootstrapACLToken()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !ok || resetIdx != 0 {
+		t.Fatalf("bad: %v %d", ok, resetIdx)
 	}
 
-	for idx, alloc := range allocs {
-		state.UpsertJobSummary(uint64(900+idx), mock.JobSummary(alloc.JobID))
+	if err := state.BootstrapACLTokens(1000, 0, tk1); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	err := state.UpsertAllocs(1000, allocs)
+	out, err := state.ACLTokenByAccessorID(tk1.AccessorID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if !reflect.DeepEqual(tk1, out) {
+		t.Fatalf("bad: %#v %#v", tk1, out)
+	}
 
-	// Verify the terminal allocs
-	out, err := state.AllocsByNodeTerminal("foo", true)
+	ok, resetIdx, err = state.CanBootstrapACLToken()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if ok || resetIdx != 1000 {
+		t.Fatalf("bad: %v %d", ok, resetIdx)
+	}
 
-	sort.Sort(AllocIDSort(term))
-	sort.Sort(AllocIDSort(out))
+	// A second bootstrap is rejected
+	if err := state.BootstrapACLTokens(1001, 0, tk2); err == nil {
+		t.Fatalf("expected error")
+	}
 
-	if !reflect.DeepEqual(term, out) {
-		t.Fatalf("bad: %#v %#v", term, out)
+	// A bad reset index is rejected
+	if err := state.BootstrapACLTokens(1001, 999, tk2); err == nil {
+		t.Fatalf("expected error")
 	}
 
-	// Verify the non-terminal allocs
-	out, err = state.AllocsByNodeTerminal("foo", false)
+	// The correct reset index allows bootstrapping again
+	if err := state.BootstrapACLTokens(1001, resetIdx, tk2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	index, err := state.Index("acl_token")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+	index, err = state.Index("acl_token_bootstrap")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
 
-	sort.Sort(AllocIDSort(nonterm))
-	sort.Sort(AllocIDSort(out))
+func TestStateStore_UpsertACLTokens(t *testing.T) {
+	state := testStateStore(t)
+	tk1 := mock.ACLToken()
+	tk2 := mock.ACLToken()
 
-	if !reflect.DeepEqual(nonterm, out) {
-		t.Fatalf("bad: %#v %#v", nonterm, out)
+	if err := state.UpsertACLTokens(1000, []*structs.ACLToken{tk1, tk2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.ACLTokenByAccessorID(tk1.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(tk1, out) {
+		t.Fatalf("bad: %#v %#v", tk1, out)
+	}
+
+	out, err = state.ACLTokenByAccessorID(tk2.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(tk2, out) {
+		t.Fatalf("bad: %#v %#v", tk2, out)
+	}
+
+	out, err = state.ACLTokenBySecretID(tk1.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(tk1, out) {
+		t.Fatalf("bad: %#v %#v", tk1, out)
+	}
+
+	// Updating a token may not change its secret
+	tk3 := tk1.Copy()
+	tk3.SecretID = structs.GenerateUUID()
+	tk3.Name = "updated"
+	if err := state.UpsertACLTokens(1001, []*structs.ACLToken{tk3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.ACLTokenByAccessorID(tk1.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.SecretID != tk1.SecretID || out.Name != "updated" || out.CreateIndex != 1000 || out.ModifyIndex != 1001 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("acl_token")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
 	}
 }
 
-func TestStateStore_AllocsByJob(t *testing.T) {
+func TestStateStore_DeleteACLTokens(t *testing.T) {
 	state := testStateStore(t)
-	var allocs []*structs.Allocation
+	tk1 := mock.ACLToken()
+	tk2 := mock.ACLToken()
 
-	for i := 0; i < 10; i++ {
-		alloc := mock.Alloc()
-		alloc.JobID = "foo"
-		allocs = append(allocs, alloc)
+	// Create the tokens
+	if err := state.UpsertACLTokens(1000, []*structs.ACLToken{tk1, tk2}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	for i, alloc := range allocs {
-		state.UpsertJobSummary(uint64(900+i), mock.JobSummary(alloc.JobID))
+	// Delete the tokens
+	if err := state.DeleteACLTokens(1001, []string{tk1.AccessorID, tk2.AccessorID}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure the token is gone
+	out, err := state.ACLTokenByAccessorID(tk1.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
 	}
 
-	err := state.UpsertAllocs(1000, allocs)
+	iter, err := state.ACLTokens()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if raw := iter.Next(); raw != nil {
+		t.Fatalf("bad: %#v", raw)
+	}
 
-	out, err := state.AllocsByJob("foo")
+	index, err := state.Index("acl_token")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	sort.Sort(AllocIDSort(allocs))
-	sort.Sort(AllocIDSort(out))
-
-	if !reflect.DeepEqual(allocs, out) {
-		t.Fatalf("bad: %#v %#v", allocs, out)
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
 	}
 }
 
-func TestStateStore_AllocsByIDPrefix(t *testing.T) {
+func TestStateStore_UpsertWorkloadIdentityKeys(t *testing.T) {
 	state := testStateStore(t)
-	var allocs []*structs.Allocation
+	k1 := mock.WorkloadIdentityKey()
+	k2 := mock.WorkloadIdentityKey()
 
-	ids := []string{
-		"aaaaaaaa-7bfb-395d-eb95-0685af2176b2",
-		"aaaaaaab-7bfb-395d-eb95-0685af2176b2",
-		"aaaaaabb-7bfb-395d-eb95-0685af2176b2",
-		"aaaaabbb-7bfb-395d-eb95-0685af2176b2",
-		"aaaabbbb-7bfb-395d-eb95-0685af2176b2",
-		"aaabbbbb-7bfb-395d-eb95-0685af2176b2",
-		"aabbbbbb-7bfb-395d-eb95-0685af2176b2",
-		"abbbbbbb-7bfb-395d-eb95-0685af2176b2",
-		"bbbbbbbb-7bfb-395d-eb95-0685af2176b2",
+	// No key is active before any is created
+	active, err := state.ActiveWorkloadIdentityKey()
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
-	for i := 0; i < 9; i++ {
-		alloc := mock.Alloc()
-		alloc.ID = ids[i]
-		allocs = append(allocs, alloc)
+	if active != nil {
+		t.Fatalf("bad: %#v", active)
 	}
 
-	for i, alloc := range allocs {
-		state.UpsertJobSummary(uint64(900+i), mock.JobSummary(alloc.JobID))
+	if err := state.UpsertWorkloadIdentityKeys(1000, []*structs.WorkloadIdentityKey{k1}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertWorkloadIdentityKeys(1001, []*structs.WorkloadIdentityKey{k2}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	err := state.UpsertAllocs(1000, allocs)
+	out, err := state.WorkloadIdentityKeyByID(k1.KeyID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if !reflect.DeepEqual(k1, out) {
+		t.Fatalf("bad: %#v %#v", k1, out)
+	}
 
-	iter, err := state.AllocsByIDPrefix("aaaa")
+	// The most recently created key is active
+	active, err = state.ActiveWorkloadIdentityKey()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	gatherAllocs := func(iter memdb.ResultIterator) []*structs.Allocation {
-		var allocs []*structs.Allocation
-		for {
-			raw := iter.Next()
-			if raw == nil {
-				break
-			}
-			allocs = append(allocs, raw.(*structs.Allocation))
-		}
-		return allocs
+	if active == nil || active.KeyID != k2.KeyID {
+		t.Fatalf("bad: %#v", active)
 	}
 
-	out := gatherAllocs(iter)
-	if len(out) != 5 {
-		t.Fatalf("bad: expected five allocations, got: %#v", out)
+	iter, err := state.WorkloadIdentityKeys()
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
-
-	sort.Sort(AllocIDSort(allocs))
-
-	for index, alloc := range out {
-		if ids[index] != alloc.ID {
-			t.Fatalf("bad: got unexpected id: %s", alloc.ID)
-		}
+	count := 0
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("bad: %d", count)
 	}
 
-	iter, err = state.AllocsByIDPrefix("b-a7bfb")
+	index, err := state.Index("identity_keys")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	out = gatherAllocs(iter)
-	if len(out) != 0 {
-		t.Fatalf("bad: unexpected zero allocations, got: %#v", out)
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
 	}
 }
 
-func TestStateStore_Allocs(t *testing.T) {
+func TestStateStore_UpsertVariable(t *testing.T) {
 	state := testStateStore(t)
-	var allocs []*structs.Allocation
+	key := mock.RootKey()
+	v1 := mock.VariableEncrypted(key.KeyID)
 
-	for i := 0; i < 10; i++ {
-		alloc := mock.Alloc()
-		allocs = append(allocs, alloc)
+	// The root key of the variable must exist
+	if err := state.UpsertVariable(999, v1, nil); err == nil {
+		t.Fatalf("expected error for unknown root key")
 	}
-	for i, alloc := range allocs {
-		state.UpsertJobSummary(uint64(900+i), mock.JobSummary(alloc.JobID))
+	if err := state.UpsertRootKeys(1000, []*structs.RootKey{key}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	err := state.UpsertAllocs(1000, allocs)
-	if err != nil {
+	// Creating with a non-zero check index conflicts
+	check := uint64(5)
+	if err := state.UpsertVariable(1001, v1, &check); err != structs.ErrCASConflict {
+		t.Fatalf("expected conflict: %v", err)
+	}
+	check = 0
+	if err := state.UpsertVariable(1001, v1, &check); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	iter, err := state.Allocs()
+	out, err := state.VariableByPath(v1.Namespace, v1.Path)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	var out []*structs.Allocation
-	for {
-		raw := iter.Next()
-		if raw == nil {
-			break
-		}
-		out = append(out, raw.(*structs.Allocation))
+	if out == nil || out.CreateIndex != 1001 || out.ModifyIndex != 1001 {
+		t.Fatalf("bad: %#v", out)
 	}
 
-	sort.Sort(AllocIDSort(allocs))
-	sort.Sort(AllocIDSort(out))
-
-	if !reflect.DeepEqual(allocs, out) {
-		t.Fatalf("bad: %#v %#v", allocs, out)
+	// Updates must match the modify index
+	update := mock.VariableEncrypted(key.KeyID)
+	update.Path = v1.Path
+	if err := state.UpsertVariable(1002, update, &check); err != structs.ErrCASConflict {
+		t.Fatalf("expected conflict: %v", err)
 	}
-}
-
-func TestStateStore_RestoreAlloc(t *testing.T) {
-	state := testStateStore(t)
-	alloc := mock.Alloc()
-
-	notify := setupNotifyTest(
-		state,
-		watch.Item{Table: "allocs"},
-		watch.Item{Alloc: alloc.ID},
-		watch.Item{AllocEval: alloc.EvalID},
-		watch.Item{AllocJob: alloc.JobID},
-		watch.Item{AllocNode: alloc.NodeID})
-
-	restore, err := state.Restore()
-	if err != nil {
+	check = 1001
+	if err := state.UpsertVariable(1002, update, &check); err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	err = restore.AllocRestore(alloc)
+	out, err = state.VariableByPath(v1.Namespace, v1.Path)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if out.CreateIndex != 1001 || out.ModifyIndex != 1002 || out.CreateTime != v1.CreateTime {
+		t.Fatalf("bad: %#v", out)
+	}
 
-	restore.Commit()
-
-	out, err := state.AllocByID(alloc.ID)
+	// Lookup by prefix
+	v2 := mock.VariableEncrypted(key.KeyID)
+	v2.Path = "other"
+	if err := state.UpsertVariable(1003, v2, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	iter, err := state.VariablesByPathPrefix(structs.DefaultNamespace, "project/")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	if !reflect.DeepEqual(out, alloc) {
-		t.Fatalf("Bad: %#v %#v", out, alloc)
+	count := 0
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		count++
+	}
+	if count != 1 {
+		t.Fatalf("bad: %d", count)
 	}
 
-	notify.verify(t)
-}
-
-func TestStateStore_RestoreAlloc_NoEphemeralDisk(t *testing.T) {
-	state := testStateStore(t)
-	alloc := mock.Alloc()
-	alloc.Job.TaskGroups[0].EphemeralDisk = nil
-	alloc.Job.TaskGroups[0].Tasks[0].Resources.DiskMB = 120
-
-	restore, err := state.Restore()
-	if err != nil {
+	// Delete with a stale index conflicts
+	if err := state.DeleteVariable(1004, v1.Namespace, v1.Path, &check); err != structs.ErrCASConflict {
+		t.Fatalf("expected conflict: %v", err)
+	}
+	if err := state.DeleteVariable(1004, v1.Namespace, v1.Path, nil); err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	err = restore.AllocRestore(alloc)
+	out, err = state.VariableByPath(v1.Namespace, v1.Path)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
 
-	restore.Commit()
-
-	out, err := state.AllocByID(alloc.ID)
+	index, err := state.Index("variables")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	expected := alloc.Copy()
-	expected.Job.TaskGroups[0].EphemeralDisk = &structs.EphemeralDisk{SizeMB: 120}
-	expected.Job.TaskGroups[0].Tasks[0].Resources.DiskMB = 0
-
-	if !reflect.DeepEqual(out, expected) {
-		t.Fatalf("Bad: %#v %#v", out, expected)
+	if index != 1004 {
+		t.Fatalf("bad: %d", index)
 	}
 }
 
-func TestStateStore_SetJobStatus_ForceStatus(t *testing.T) {
+func TestStateStore_RekeyVariables(t *testing.T) {
 	state := testStateStore(t)
-	watcher := watch.NewItems()
-	txn := state.db.Txn(true)
-
-	// Create and insert a mock job.
-	job := mock.Job()
-	job.Status = ""
-	job.ModifyIndex = 0
-	if err := txn.Insert("jobs", job); err != nil {
-		t.Fatalf("job insert failed: %v", err)
+	oldKey := mock.RootKey()
+	newKey := mock.RootKey()
+	if err := state.UpsertRootKeys(1000, []*structs.RootKey{oldKey, newKey}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	exp := "foobar"
-	index := uint64(1000)
-	if err := state.setJobStatus(index, watcher, txn, job, false, exp); err != nil {
-		t.Fatalf("setJobStatus() failed: %v", err)
+	v1 := mock.VariableEncrypted(oldKey.KeyID)
+	v2 := mock.VariableEncrypted(oldKey.KeyID)
+	if err := state.UpsertVariable(1001, v1, nil); err != nil {
+		t.Fatalf("err: %v", err)
 	}
-
-	i, err := txn.First("jobs", "id", job.ID)
-	if err != nil {
-		t.Fatalf("job lookup failed: %v", err)
+	if err := state.UpsertVariable(1002, v2, nil); err != nil {
+		t.Fatalf("err: %v", err)
 	}
-	updated := i.(*structs.Job)
 
-	if updated.Status != exp {
-		t.Fatalf("setJobStatus() set %v; expected %v", updated.Status, exp)
+	// Re-encrypt both variables, while v2 was modified in the meantime
+	r1 := v1.Copy()
+	r1.KeyID = newKey.KeyID
+	r1.Data = []byte("rekeyed")
+	r2 := v2.Copy()
+	r2.KeyID = newKey.KeyID
+	r2.Data = []byte("rekeyed")
+	r2.ModifyIndex = 999
+	if err := state.RekeyVariables(1003, []*structs.VariableEncrypted{r1, r2}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	if updated.ModifyIndex != index {
-		t.Fatalf("setJobStatus() set %d; expected %d", updated.ModifyIndex, index)
+	out, err := state.VariableByPath(v1.Namespace, v1.Path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
-}
-
-func TestStateStore_SetJobStatus_NoOp(t *testing.T) {
-	state := testStateStore(t)
-	watcher := watch.NewItems()
-	txn := state.db.Txn(true)
-
-	// Create and insert a mock job that should be pending.
-	job := mock.Job()
-	job.Status = structs.JobStatusPending
-	job.ModifyIndex = 10
-	if err := txn.Insert("jobs", job); err != nil {
-		t.Fatalf("job insert failed: %v", err)
+	if out.KeyID != newKey.KeyID || string(out.Data) != "rekeyed" || out.ModifyIndex != 1001 {
+		t.Fatalf("bad: %#v", out)
 	}
-
-	index := uint64(1000)
-	if err := state.setJobStatus(index, watcher, txn, job, false, ""); err != nil {
-		t.Fatalf("setJobStatus() failed: %v", err)
+	out, err = state.VariableByPath(v2.Namespace, v2.Path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.KeyID != oldKey.KeyID {
+		t.Fatalf("bad: %#v", out)
 	}
 
-	i, err := txn.First("jobs", "id", job.ID)
+	index, err := state.Index("variables")
 	if err != nil {
-		t.Fatalf("job lookup failed: %v", err)
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1003 {
+		t.Fatalf("bad: %d", index)
 	}
-	updated := i.(*structs.Job)
 
-	if updated.ModifyIndex == index {
-		t.Fatalf("setJobStatus() should have been a no-op")
+	// The new root key must exist
+	r1.KeyID = structs.GenerateUUID()
+	r1.ModifyIndex = 1001
+	if err := state.RekeyVariables(1004, []*structs.VariableEncrypted{r1}); err == nil {
+		t.Fatalf("expected error for unknown root key")
 	}
 }
 
-func TestStateStore_SetJobStatus(t *testing.T) {
+func TestStateStore_UpsertServiceRegistrations(t *testing.T) {
 	state := testStateStore(t)
-	watcher := watch.NewItems()
-	txn := state.db.Txn(true)
+	s1 := mock.ServiceRegistration()
+	s2 := mock.ServiceRegistration()
+	s2.Namespace = "other"
 
-	// Create and insert a mock job that should be pending but has an incorrect
-	// status.
-	job := mock.Job()
-	job.Status = "foobar"
-	job.ModifyIndex = 10
-	if err := txn.Insert("jobs", job); err != nil {
-		t.Fatalf("job insert failed: %v", err)
+	if err := state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{s1, s2}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	index := uint64(1000)
-	if err := state.setJobStatus(index, watcher, txn, job, false, ""); err != nil {
-		t.Fatalf("setJobStatus() failed: %v", err)
+	out, err := state.ServiceRegistrationByID(s1.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(s1, out) {
+		t.Fatalf("bad: %#v %#v", s1, out)
 	}
 
-	i, err := txn.First("jobs", "id", job.ID)
+	// Lookups by name are scoped to the namespace
+	services, err := state.ServiceRegistrationsByName(structs.DefaultNamespace, s1.ServiceName)
 	if err != nil {
-		t.Fatalf("job lookup failed: %v", err)
+		t.Fatalf("err: %v", err)
 	}
-	updated := i.(*structs.Job)
-
-	if updated.Status != structs.JobStatusPending {
-		t.Fatalf("setJobStatus() set %v; expected %v", updated.Status, structs.JobStatusPending)
+	if len(services) != 1 || services[0].ID != s1.ID {
+		t.Fatalf("bad: %#v", services)
 	}
 
-	if updated.ModifyIndex != index {
-		t.Fatalf("setJobStatus() set %d; expected %d", updated.ModifyIndex, index)
+	services, err = state.ServiceRegistrationsByAllocID(s2.AllocID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(services) != 1 || services[0].ID != s2.ID {
+		t.Fatalf("bad: %#v", services)
 	}
-}
 
-func TestStateStore_GetJobStatus_NoEvalsOrAllocs(t *testing.T) {
-	job := mock.Job()
-	state := testStateStore(t)
-	txn := state.db.Txn(false)
-	status, err := state.getJobStatus(txn, job, false)
+	// Updating a registration keeps its create index
+	s1 = s1.Copy()
+	s1.Health = structs.ServiceRegistrationHealthCritical
+	if err := state.UpsertServiceRegistrations(1001, []*structs.ServiceRegistration{s1}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.ServiceRegistrationByID(s1.ID)
 	if err != nil {
-		t.Fatalf("getJobStatus() failed: %v", err)
+		t.Fatalf("err: %v", err)
+	}
+	if out.CreateIndex != 1000 || out.ModifyIndex != 1001 || out.Health != structs.ServiceRegistrationHealthCritical {
+		t.Fatalf("bad: %#v", out)
 	}
 
-	if status != structs.JobStatusPending {
-		t.Fatalf("getJobStatus() returned %v; expected %v", status, structs.JobStatusPending)
+	if err := state.DeleteServiceRegistrations(1002, []string{s1.ID, "unknown"}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
-}
-
-func TestStateStore_GetJobStatus_NoEvalsOrAllocs_Periodic(t *testing.T) {
-	job := mock.PeriodicJob()
-	state := testStateStore(t)
-	txn := state.db.Txn(false)
-	status, err := state.getJobStatus(txn, job, false)
+	out, err = state.ServiceRegistrationByID(s1.ID)
 	if err != nil {
-		t.Fatalf("getJobStatus() failed: %v", err)
+		t.Fatalf("err: %v", err)
 	}
-
-	if status != structs.JobStatusRunning {
-		t.Fatalf("getJobStatus() returned %v; expected %v", status, structs.JobStatusRunning)
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
 	}
-}
 
-func TestStateStore_GetJobStatus_NoEvalsOrAllocs_EvalDelete(t *testing.T) {
-	job := mock.Job()
-	state := testStateStore(t)
-	txn := state.db.Txn(false)
-	status, err := state.getJobStatus(txn, job, true)
+	index, err := state.Index("service_registrations")
 	if err != nil {
-		t.Fatalf("getJobStatus() failed: %v", err)
+		t.Fatalf("err: %v", err)
 	}
-
-	if status != structs.JobStatusDead {
-		t.Fatalf("getJobStatus() returned %v; expected %v", status, structs.JobStatusDead)
+	if index != 1002 {
+		t.Fatalf("bad: %d", index)
 	}
 }
 
-func TestStateStore_GetJobStatus_DeadEvalsAndAllocs(t *testing.T) {
+func TestStateStore_UpdateAllocsFromClient_DeletesServiceRegistrations(t *testing.T) {
 	state := testStateStore(t)
-	job := mock.Job()
-
-	// Create a mock alloc that is dead.
 	alloc := mock.Alloc()
-	alloc.JobID = job.ID
-	alloc.DesiredStatus = structs.AllocDesiredStatusStop
-	state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID))
-	if err := state.UpsertAllocs(1000, []*structs.Allocation{alloc}); err != nil {
+	if err := state.UpsertJobSummary(998, mock.JobSummary(alloc.JobID)); err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	// Create a mock eval that is complete
-	eval := mock.Eval()
-	eval.JobID = job.ID
-	eval.Status = structs.EvalStatusComplete
-	if err := state.UpsertEvals(1001, []*structs.Evaluation{eval}); err != nil {
+	if err := state.UpsertAllocs(999, []*structs.Allocation{alloc}); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	txn := state.db.Txn(false)
-	status, err := state.getJobStatus(txn, job, false)
-	if err != nil {
-		t.Fatalf("getJobStatus() failed: %v", err)
+	service := mock.ServiceRegistration()
+	service.AllocID = alloc.ID
+	service.NodeID = alloc.NodeID
+	if err := state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{service}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	if status != structs.JobStatusDead {
-		t.Fatalf("getJobStatus() returned %v; expected %v", status, structs.JobStatusDead)
+	// A running allocation keeps its registrations
+	update := alloc.Copy()
+	update.ClientStatus = structs.AllocClientStatusRunning
+	if err := state.UpdateAllocsFromClient(1001, []*structs.Allocation{update}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out, _ := state.ServiceRegistrationByID(service.ID); out == nil {
+		t.Fatalf("registration removed")
 	}
-}
-
-func TestStateStore_GetJobStatus_RunningAlloc(t *testing.T) {
-	state := testStateStore(t)
-	job := mock.Job()
 
-	// Create a mock alloc that is running.
-	alloc := mock.Alloc()
-	alloc.JobID = job.ID
-	alloc.DesiredStatus = structs.AllocDesiredStatusRun
-	state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID))
-	if err := state.UpsertAllocs(1000, []*structs.Allocation{alloc}); err != nil {
+	// A terminal allocation has its registrations removed
+	update = update.Copy()
+	update.ClientStatus = structs.AllocClientStatusComplete
+	if err := state.UpdateAllocsFromClient(1002, []*structs.Allocation{update}); err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	txn := state.db.Txn(false)
-	status, err := state.getJobStatus(txn, job, true)
+	if out, _ := state.ServiceRegistrationByID(service.ID); out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+	index, err := state.Index("service_registrations")
 	if err != nil {
-		t.Fatalf("getJobStatus() failed: %v", err)
+		t.Fatalf("err: %v", err)
 	}
-
-	if status != structs.JobStatusRunning {
-		t.Fatalf("getJobStatus() returned %v; expected %v", status, structs.JobStatusRunning)
+	if index != 1002 {
+		t.Fatalf("bad: %d", index)
 	}
 }
 
-func TestStateStore_SetJobStatus_PendingEval(t *testing.T) {
+func TestStateStore_UpdateNodeStatus_DeletesServiceRegistrations(t *testing.T) {
 	state := testStateStore(t)
-	job := mock.Job()
-
-	// Create a mock eval that is pending.
-	eval := mock.Eval()
-	eval.JobID = job.ID
-	eval.Status = structs.EvalStatusPending
-	if err := state.UpsertEvals(1000, []*structs.Evaluation{eval}); err != nil {
+	node := mock.Node()
+	if err := state.UpsertNode(999, node); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	txn := state.db.Txn(false)
-	status, err := state.getJobStatus(txn, job, true)
-	if err != nil {
-		t.Fatalf("getJobStatus() failed: %v", err)
+	service := mock.ServiceRegistration()
+	service.NodeID = node.ID
+	if err := state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{service}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	if status != structs.JobStatusPending {
-		t.Fatalf("getJobStatus() returned %v; expected %v", status, structs.JobStatusPending)
+	if err := state.UpdateNodeStatus(1001, node.ID, structs.NodeStatusDown, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out, _ := state.ServiceRegistrationByID(service.ID); out != nil {
+		t.Fatalf("bad: %#v", out)
 	}
 }
 
-func TestStateWatch_watch(t *testing.T) {
-	sw := newStateWatch()
-	notify1 := make(chan struct{}, 1)
-	notify2 := make(chan struct{}, 1)
-	notify3 := make(chan struct{}, 1)
-
-	// Notifications trigger subscribed channels
-	sw.watch(watch.NewItems(watch.Item{Table: "foo"}), notify1)
-	sw.watch(watch.NewItems(watch.Item{Table: "bar"}), notify2)
-	sw.watch(watch.NewItems(watch.Item{Table: "baz"}), notify3)
+func TestStateStore_ACLTokensByGlobal(t *testing.T) {
+	state := testStateStore(t)
+	tk1 := mock.ACLToken()
+	tk2 := mock.ACLToken()
+	tk3 := mock.ACLToken()
+	tk3.Global = true
 
-	items := watch.NewItems()
-	items.Add(watch.Item{Table: "foo"})
-	items.Add(watch.Item{Table: "bar"})
+	if err := state.UpsertACLTokens(1000, []*structs.ACLToken{tk1, tk2, tk3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-	sw.notify(items)
-	if len(notify1) != 1 {
-		t.Fatalf("should notify")
+	iter, err := state.ACLTokensByGlobal(true)
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
-	if len(notify2) != 1 {
-		t.Fatalf("should notify")
+
+	// Ensure we see only the global token
+	var out []*structs.ACLToken
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		out = append(out, raw.(*structs.ACLToken))
 	}
-	if len(notify3) != 0 {
-		t.Fatalf("should not notify")
+	if len(out) != 1 || out[0].AccessorID != tk3.AccessorID {
+		t.Fatalf("bad: %#v", out)
 	}
 }
 
-func TestStateWatch_stopWatch(t *testing.T) {
-	sw := newStateWatch()
-	notify := make(chan struct{})
+func TestStateStore_RestoreACLPolicy(t *testing.T) {
+	state := testStateStore(t)
+	policy := mock.ACLPolicy()
 
-	// First subscribe
-	sw.watch(watch.NewItems(watch.Item{Table: "foo"}), notify)
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-	// Unsubscribe stop notifications
-	sw.stopWatch(watch.NewItems(watch.Item{Table: "foo"}), notify)
+	err = restore.ACLPolicyRestore(policy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	restore.Commit()
 
-	// Check that the group was removed
-	if _, ok := sw.items[watch.Item{Table: "foo"}]; ok {
-		t.Fatalf("should remove group")
+	out, err := state.ACLPolicyByName(policy.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	// Check that we are not notified
-	sw.notify(watch.NewItems(watch.Item{Table: "foo"}))
-	if len(notify) != 0 {
-		t.Fatalf("should not notify")
+	if !reflect.DeepEqual(out, policy) {
+		t.Fatalf("Bad: %#v %#v", out, policy)
 	}
 }
 
-func TestStateJobSummary_UpdateJobCount(t *testing.T) {
+func TestStateStore_RestoreACLToken(t *testing.T) {
 	state := testStateStore(t)
-	alloc := mock.Alloc()
-	job := alloc.Job
-	job.TaskGroups[0].Count = 3
-	err := state.UpsertJob(1000, job)
+	token := mock.ACLToken()
+
+	restore, err := state.Restore()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	if err := state.UpsertAllocs(1001, []*structs.Allocation{alloc}); err != nil {
+	err = restore.ACLTokenRestore(token)
+	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	summary, _ := state.JobSummaryByID(job.ID)
-	expectedSummary := structs.JobSummary{
-		JobID: job.ID,
-		Summary: map[string]structs.TaskGroupSummary{
-			"web": {
-				Starting: 1,
-			},
-		},
-		CreateIndex: 1000,
-		ModifyIndex: 1001,
+	restore.Commit()
+
+	out, err := state.ACLTokenByAccessorID(token.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
-	if !reflect.DeepEqual(summary, &expectedSummary) {
-		t.Fatalf("expected: %v, actual: %v", expectedSummary, summary)
+
+	if !reflect.DeepEqual(out, token) {
+		t.Fatalf("Bad: %#v %#v", out, token)
 	}
+}
 
-	alloc2 := mock.Alloc()
-	alloc2.Job = job
-	alloc2.JobID = job.ID
+func TestStateStore_ByNamespace(t *testing.T) {
+	state := testStateStore(t)
 
-	alloc3 := mock.Alloc()
-	alloc3.Job = job
-	alloc3.JobID = job.ID
+	// Objects without a namespace are placed in the default namespace
+	job1 := mock.Job()
+	job1.Namespace = ""
+	job2 := mock.Job()
+	job2.Namespace = "other"
+	for i, job := range []*structs.Job{job1, job2} {
+		if err := state.UpsertJob(uint64(1000+i), job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
 
-	if err := state.UpsertAllocs(1002, []*structs.Allocation{alloc2, alloc3}); err != nil {
+	eval := mock.Eval()
+	eval.Namespace = ""
+	eval.JobID = job2.ID
+	if err := state.UpsertEvals(1002, []*structs.Evaluation{eval}); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	outA, _ := state.AllocByID(alloc3.ID)
-
-	summary, _ = state.JobSummaryByID(job.ID)
-	expectedSummary = structs.JobSummary{
-		JobID: job.ID,
-		Summary: map[string]structs.TaskGroupSummary{
-			"web": {
-				Starting: 3,
-			},
-		},
-		CreateIndex: job.CreateIndex,
-		ModifyIndex: outA.ModifyIndex,
-	}
-	if !reflect.DeepEqual(summary, &expectedSummary) {
-		t.Fatalf("expected summary: %v, actual: %v", expectedSummary, summary)
+	alloc := mock.Alloc()
+	alloc.Namespace = ""
+	alloc.Job = job2
+	alloc.JobID = job2.ID
+	if err := state.UpsertAllocs(1003, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	alloc4 := mock.Alloc()
-	alloc4.ID = alloc2.ID
-	alloc4.Job = alloc2.Job
-	alloc4.JobID = alloc2.JobID
-	alloc4.ClientStatus = structs.AllocClientStatusComplete
-
-	alloc5 := mock.Alloc()
-	alloc5.ID = alloc3.ID
-	alloc5.Job = alloc3.Job
-	alloc5.JobID = alloc3.JobID
-	alloc5.ClientStatus = structs.AllocClientStatusComplete
+	count := func(iter memdb.ResultIterator, err error) int {
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		n := 0
+		for iter.Next() != nil {
+			n++
+		}
+		return n
+	}
 
-	if err := state.UpdateAllocsFromClient(1004, []*structs.Allocation{alloc4, alloc5}); err != nil {
-		t.Fatalf("err: %v", err)
+	if n := count(state.JobsByNamespace(structs.DefaultNamespace)); n != 1 {
+		t.Fatalf("bad default jobs: %d", n)
 	}
-	outA, _ = state.AllocByID(alloc5.ID)
-	summary, _ = state.JobSummaryByID(job.ID)
-	expectedSummary = structs.JobSummary{
-		JobID: job.ID,
-		Summary: map[string]structs.TaskGroupSummary{
-			"web": {
-				Complete: 2,
-				Starting: 1,
-			},
-		},
-		CreateIndex: job.CreateIndex,
-		ModifyIndex: outA.ModifyIndex,
+	if n := count(state.JobsByNamespace("other")); n != 1 {
+		t.Fatalf("bad other jobs: %d", n)
 	}
-	if !reflect.DeepEqual(summary, &expectedSummary) {
-		t.Fatalf("expected: %v, actual: %v", expectedSummary, summary)
+	if n := count(state.EvalsByNamespace(structs.DefaultNamespace)); n != 1 {
+		t.Fatalf("bad default evals: %d", n)
+	}
+	if n := count(state.AllocsByNamespace("other")); n != 1 {
+		t.Fatalf("bad other allocs: %d", n)
+	}
+	if n := count(state.AllocsByNamespace(structs.DefaultNamespace)); n != 0 {
+		t.Fatalf("bad default allocs: %d", n)
 	}
 }
 
-func TestJobSummary_UpdateClientStatus(t *testing.T) {
+func TestStateStore_AutopilotConfig(t *testing.T) {
 	state := testStateStore(t)
-	alloc := mock.Alloc()
-	job := alloc.Job
-	job.TaskGroups[0].Count = 3
-
-	alloc2 := mock.Alloc()
-	alloc2.Job = job
-	alloc2.JobID = job.ID
 
-	alloc3 := mock.Alloc()
-	alloc3.Job = job
-	alloc3.JobID = job.ID
+	notify := setupNotifyTest(
+		state,
+		watch.Item{Table: "autopilot_config"})
 
-	err := state.UpsertJob(1000, job)
+	// Nothing is set initially
+	idx, config, err := state.AutopilotConfig()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if idx != 0 || config != nil {
+		t.Fatalf("bad: %d %#v", idx, config)
+	}
+
+	expected := &structs.AutopilotConfig{
+		CleanupDeadServers:   true,
+		LastContactThreshold: 5 * time.Second,
+		MaxTrailingLogs:      500,
+	}
+	if err := state.AutopilotSetConfig(1000, expected); err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-	if err := state.UpsertAllocs(1001, []*structs.Allocation{alloc, alloc2, alloc3}); err != nil {
+	idx, config, err = state.AutopilotConfig()
+	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	summary, _ := state.JobSummaryByID(job.ID)
-	if summary.Summary["web"].Starting != 3 {
-		t.Fatalf("bad job summary: %v", summary)
+	if idx != 1000 || config.CreateIndex != 1000 || config.ModifyIndex != 1000 {
+		t.Fatalf("bad: %d %#v", idx, config)
+	}
+	if config.MaxTrailingLogs != 500 || config.LastContactThreshold != 5*time.Second {
+		t.Fatalf("bad: %#v", config)
 	}
 
-	alloc4 := mock.Alloc()
-	alloc4.ID = alloc2.ID
-	alloc4.Job = alloc2.Job
-	alloc4.JobID = alloc2.JobID
-	alloc4.ClientStatus = structs.AllocClientStatusComplete
-
-	alloc5 := mock.Alloc()
-	alloc5.ID = alloc3.ID
-	alloc5.Job = alloc3.Job
-	alloc5.JobID = alloc3.JobID
-	alloc5.ClientStatus = structs.AllocClientStatusFailed
+	notify.verify(t)
+}
 
-	alloc6 := mock.Alloc()
-	alloc6.ID = alloc.ID
-	alloc6.Job = alloc.Job
-	alloc6.JobID = alloc.JobID
-	alloc6.ClientStatus = structs.AllocClientStatusRunning
+func TestStateStore_AutopilotCASConfig(t *testing.T) {
+	state := testStateStore(t)
 
-	if err := state.UpdateAllocsFromClient(1002, []*structs.Allocation{alloc4, alloc5, alloc6}); err != nil {
-		t.Fatalf("err: %v", err)
+	// A zero index only sets a configuration that was never set
+	config := structs.DefaultAutopilotConfig()
+	ok, err := state.AutopilotCASConfig(1000, 0, config)
+	if err != nil || !ok {
+		t.Fatalf("bad: %v %v", ok, err)
 	}
-	summary, _ = state.JobSummaryByID(job.ID)
-	if summary.Summary["web"].Running != 1 || summary.Summary["web"].Failed != 1 || summary.Summary["web"].Complete != 1 {
-		t.Fatalf("bad job summary: %v", summary)
+	ok, err = state.AutopilotCASConfig(1001, 0, config)
+	if err != nil || ok {
+		t.Fatalf("bad: %v %v", ok, err)
 	}
 
-	alloc7 := mock.Alloc()
-	alloc7.Job = alloc.Job
-	alloc7.JobID = alloc.JobID
+	// A stale index doesn't set the configuration
+	config.MaxTrailingLogs = 10
+	ok, err = state.AutopilotCASConfig(1002, 999, config)
+	if err != nil || ok {
+		t.Fatalf("bad: %v %v", ok, err)
+	}
 
-	if err := state.UpsertAllocs(1003, []*structs.Allocation{alloc7}); err != nil {
+	ok, err = state.AutopilotCASConfig(1003, 1000, config)
+	if err != nil || !ok {
+		t.Fatalf("bad: %v %v", ok, err)
+	}
+
+	_, out, err := state.AutopilotConfig()
+	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	summary, _ = state.JobSummaryByID(job.ID)
-	if summary.Summary["web"].Starting != 1 || summary.Summary["web"].Running != 1 || summary.Summary["web"].Failed != 1 || summary.Summary["web"].Complete != 1 {
-		t.Fatalf("bad job summary: %v", summary)
+	if out.MaxTrailingLogs != 10 || out.CreateIndex != 1000 || out.ModifyIndex != 1003 {
+		t.Fatalf("bad: %#v", out)
 	}
 }
 
-func TestStateStore_UpsertVaultAccessors(t *testing.T) {
+func TestStateStore_SchedulerConfig(t *testing.T) {
 	state := testStateStore(t)
-	a := mock.VaultAccessor()
-	a2 := mock.VaultAccessor()
 
-	err := state.UpsertVaultAccessor(1000, []*structs.VaultAccessor{a, a2})
+	// Unset configurations are nil
+	index, config, err := state.SchedulerConfig()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if index != 0 || config != nil {
+		t.Fatalf("bad: %d %#v", index, config)
+	}
 
-	out, err := state.VaultAccessor(a.Accessor)
-	if err != nil {
+	config = structs.DefaultSchedulerConfiguration()
+	config.SchedulerAlgorithm = structs.SchedulerAlgorithmSpread
+	if err := state.SchedulerSetConfig(1000, config); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	if !reflect.DeepEqual(a, out) {
-		t.Fatalf("bad: %#v %#v", a, out)
+	config.SchedulingPaused = true
+	if err := state.SchedulerSetConfig(1001, config); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	out, err = state.VaultAccessor(a2.Accessor)
+	index, out, err := state.SchedulerConfig()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	if !reflect.DeepEqual(a2, out) {
-		t.Fatalf("bad: %#v %#v", a2, out)
+	if index != 1001 || out.CreateIndex != 1000 || out.ModifyIndex != 1001 {
+		t.Fatalf("bad: %d %#v", index, out)
+	}
+	if out.SchedulerAlgorithm != structs.SchedulerAlgorithmSpread || !out.SchedulingPaused {
+		t.Fatalf("bad: %#v", out)
 	}
 
-	iter, err := state.VaultAccessors()
+	index, err = state.Index("scheduler_config")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
 
-	count := 0
-	for {
-		raw := iter.Next()
-		if raw == nil {
-			break
-		}
+func TestStateStore_SchedulerCASConfig(t *testing.T) {
+	state := testStateStore(t)
 
-		count++
-		accessor := raw.(*structs.VaultAccessor)
+	// A zero index only sets a configuration that was never set
+	config := structs.DefaultSchedulerConfiguration()
+	ok, err := state.SchedulerCASConfig(1000, 0, config)
+	if err != nil || !ok {
+		t.Fatalf("bad: %v %v", ok, err)
+	}
+	ok, err = state.SchedulerCASConfig(1001, 0, config)
+	if err != nil || ok {
+		t.Fatalf("bad: %v %v", ok, err)
+	}
 
-		if !reflect.DeepEqual(accessor, a) && !reflect.DeepEqual(accessor, a2) {
-			t.Fatalf("bad: %#v", accessor)
-		}
+	// A stale index doesn't set the configuration
+	config.SchedulingPaused = true
+	ok, err = state.SchedulerCASConfig(1002, 999, config)
+	if err != nil || ok {
+		t.Fatalf("bad: %v %v", ok, err)
 	}
 
-	if count != 2 {
-		t.Fatalf("bad: %d", count)
+	ok, err = state.SchedulerCASConfig(1003, 1000, config)
+	if err != nil || !ok {
+		t.Fatalf("bad: %v %v", ok, err)
 	}
 
-	index, err := state.Index("vault_accessors")
+	_, out, err := state.SchedulerConfig()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if index != 1000 {
-		t.Fatalf("bad: %d", index)
+	if !out.SchedulingPaused || out.CreateIndex != 1000 || out.ModifyIndex != 1003 {
+		t.Fatalf("bad: %#v", out)
 	}
 }
 
-func TestStateStore_DeleteVaultAccessors(t *testing.T) {
+func TestStateStore_UpsertCSIVolumes(t *testing.T) {
 	state := testStateStore(t)
-	a1 := mock.VaultAccessor()
-	a2 := mock.VaultAccessor()
-	accessors := []*structs.VaultAccessor{a1, a2}
+	v1 := mock.CSIVolume()
+	v2 := mock.CSIVolume()
+	v2.PluginID = "other"
 
-	err := state.UpsertVaultAccessor(1000, accessors)
-	if err != nil {
+	if err := state.UpsertCSIVolumes(1000, []*structs.CSIVolume{v1, v2}); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	err = state.DeleteVaultAccessors(1001, accessors)
+	out, err := state.CSIVolumeByID(v1.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if !reflect.DeepEqual(v1, out) {
+		t.Fatalf("bad: %#v %#v", v1, out)
+	}
 
-	out, err := state.VaultAccessor(a1.Accessor)
-	if err != nil {
+	// Claim the first volume
+	claim := &structs.CSIVolumeClaim{AllocID: "a1", NodeID: "n1", Mode: structs.CSIVolumeClaimWrite}
+	if err := state.CSIVolumeClaim(1001, v1.ID, claim); err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if out != nil {
-		t.Fatalf("bad: %#v %#v", a1, out)
+
+	// A second writer isn't allowed by the access mode
+	claim2 := &structs.CSIVolumeClaim{AllocID: "a2", NodeID: "n1", Mode: structs.CSIVolumeClaimWrite}
+	if err := state.CSIVolumeClaim(1002, v1.ID, claim2); err == nil {
+		t.Fatalf("expected error")
 	}
-	out, err = state.VaultAccessor(a2.Accessor)
-	if err != nil {
+
+	// Update the first volume and ensure the claims and create index are
+	// retained
+	update := v1.Copy()
+	update.Name = "updated"
+	if err := state.UpsertCSIVolumes(1003, []*structs.CSIVolume{update}); err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if out != nil {
-		t.Fatalf("bad: %#v %#v", a2, out)
-	}
-
-	index, err := state.Index("vault_accessors")
+	out, err = state.CSIVolumeByID(v1.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if index != 1001 {
-		t.Fatalf("bad: %d", index)
+	if out.CreateIndex != 1000 || out.ModifyIndex != 1003 || out.Name != "updated" {
+		t.Fatalf("bad: %#v", out)
 	}
-}
-
-func TestStateStore_VaultAccessorsByAlloc(t *testing.T) {
-	state := testStateStore(t)
-	alloc := mock.Alloc()
-	var accessors []*structs.VaultAccessor
-	var expected []*structs.VaultAccessor
-
-	for i := 0; i < 5; i++ {
-		accessor := mock.VaultAccessor()
-		accessor.AllocID = alloc.ID
-		expected = append(expected, accessor)
-		accessors = append(accessors, accessor)
+	if out.Writers() != 1 || out.Claims["a1"] == nil {
+		t.Fatalf("bad: %#v", out.Claims)
 	}
 
-	for i := 0; i < 10; i++ {
-		accessor := mock.VaultAccessor()
-		accessors = append(accessors, accessor)
+	// Release the claim
+	release := &structs.CSIVolumeClaim{AllocID: "a1", NodeID: "n1", Mode: structs.CSIVolumeClaimRelease}
+	if err := state.CSIVolumeClaim(1004, v1.ID, release); err != nil {
+		t.Fatalf("err: %v", err)
 	}
-
-	err := state.UpsertVaultAccessor(1000, accessors)
+	out, err = state.CSIVolumeByID(v1.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if len(out.Claims) != 0 || out.ModifyIndex != 1004 {
+		t.Fatalf("bad: %#v", out)
+	}
 
-	out, err := state.VaultAccessorsByAlloc(alloc.ID)
+	// The volumes are looked up by plugin
+	iter, err := state.CSIVolumesByPluginID("other")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	if len(expected) != len(out) {
-		t.Fatalf("bad: %#v %#v", len(expected), len(out))
+	raw := iter.Next()
+	if raw == nil || raw.(*structs.CSIVolume).ID != v2.ID || iter.Next() != nil {
+		t.Fatalf("bad: %#v", raw)
 	}
 
-	index, err := state.Index("vault_accessors")
+	index, err := state.Index("csi_volumes")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if index != 1000 {
+	if index != 1004 {
 		t.Fatalf("bad: %d", index)
 	}
 }
 
-func TestStateStore_VaultAccessorsByNode(t *testing.T) {
+func TestStateStore_DeleteCSIVolumes(t *testing.T) {
 	state := testStateStore(t)
-	node := mock.Node()
-	var accessors []*structs.VaultAccessor
-	var expected []*structs.VaultAccessor
-
-	for i := 0; i < 5; i++ {
-		accessor := mock.VaultAccessor()
-		accessor.NodeID = node.ID
-		expected = append(expected, accessor)
-		accessors = append(accessors, accessor)
+	v1 := mock.CSIVolume()
+	v2 := mock.CSIVolume()
+	if err := state.UpsertCSIVolumes(1000, []*structs.CSIVolume{v1, v2}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	for i := 0; i < 10; i++ {
-		accessor := mock.VaultAccessor()
-		accessors = append(accessors, accessor)
+	if err := state.DeleteCSIVolumes(1001, []string{v1.ID}); err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
-	err := state.UpsertVaultAccessor(1000, accessors)
-	if err != nil {
-		t.Fatalf("err: %v", err)
+	// Deleting an unknown volume fails
+	if err := state.DeleteCSIVolumes(1002, []string{v1.ID}); err == nil {
+		t.Fatalf("expected error")
 	}
 
-	out, err := state.VaultAccessorsByNode(node.ID)
+	iter, err := state.CSIVolumesByNamespace(structs.DefaultNamespace)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	if len(expected) != len(out) {
-		t.Fatalf("bad: %#v %#v", len(expected), len(out))
+	raw := iter.Next()
+	if raw == nil || raw.(*structs.CSIVolume).ID != v2.ID || iter.Next() != nil {
+		t.Fatalf("bad: %#v", raw)
 	}
 
-	index, err := state.Index("vault_accessors")
+	index, err := state.Index("csi_volumes")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if index != 1000 {
+	if index != 1001 {
 		t.Fatalf("bad: %d", index)
 	}
 }
 
-func TestStateStore_RestoreVaultAccessor(t *testing.T) {
+func TestStateStore_RestoreCSIVolume(t *testing.T) {
 	state := testStateStore(t)
-	a := mock.VaultAccessor()
+	vol := mock.CSIVolume()
 
 	restore, err := state.Restore()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	err = restore.VaultAccessorRestore(a)
-	if err != nil {
+	if err := restore.CSIVolumeRestore(vol); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 	restore.Commit()
 
-	out, err := state.VaultAccessor(a.Accessor)
+	out, err := state.CSIVolumeByID(vol.ID)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	if !reflect.DeepEqual(out, a) {
-		t.Fatalf("Bad: %#v %#v", out, a)
-	}
-}
-
-// setupNotifyTest takes a state store and a set of watch items, then creates
-// and subscribes a notification channel for each item.
-func setupNotifyTest(state *StateStore, items ...watch.Item) notifyTest {
-	var n notifyTest
-	for _, item := range items {
-		ch := make(chan struct{}, 1)
-		state.Watch(watch.NewItems(item), ch)
-		n = append(n, &notifyTestCase{item, ch})
-	}
-	return n
-}
-
-// notifyTestCase is used to set up and verify watch triggers.
-type notifyTestCase struct {
-	item watch.Item
-	ch   chan struct{}
-}
-
-// notifyTest is a suite of notifyTestCases.
-type notifyTest []*notifyTestCase
-
-// verify ensures that each channel received a notification.
-func (n notifyTest) verify(t *testing.T) {
-	for _, tcase := range n {
-		if len(tcase.ch) != 1 {
-			t.Fatalf("should notify %#v", tcase.item)
-		}
+	if !reflect.DeepEqual(out, vol) {
+		t.Fatalf("Bad: %#v %#v", out, vol)
 	}
 }
-
-// NodeIDSort is used to sort nodes by ID
-type NodeIDSort []*structs.Node
-
-func (n NodeIDSort) Len() int {
-	return len(n)
-}
-
-func (n NodeIDSort) Less(i, j int) bool {
-	return n[i].ID < n[j].ID
-}
-
-func (n NodeIDSort) Swap(i, j int) {
-	n[i], n[j] = n[j], n[i]
-}
-
-// JobIDis used to sort jobs by id
-type JobIDSort []*structs.Job
-
-func (n JobIDSort) Len() int {
-	return len(n)
-}
-
-func (n JobIDSort) Less(i, j int) bool {
-	return n[i].ID < n[j].ID
-}
-
-func (n JobIDSort) Swap(i, j int) {
-	n[i], n[j] = n[j], n[i]
-}
-
-// EvalIDis used to sort evals by id
-type EvalIDSort []*structs.Evaluation
-
-func (n EvalIDSort) Len() int {
-	return len(n)
-}
-
-func (n EvalIDSort) Less(i, j int) bool {
-	return n[i].ID < n[j].ID
-}
-
-func (n EvalIDSort) Swap(i, j int) {
-	n[i], n[j] = n[j], n[i]
-}
-
-// AllocIDsort used to sort allocations by id
-type AllocIDSort []*structs.Allocation
-
-func (n AllocIDSort) Len() int {
-	return len(n)
-}
-
-func (n AllocIDSort) Less(i, j int) bool {
-	return n[i].ID < n[j].ID
-}
-
-func (n AllocIDSort) Swap(i, j int) {
-	n[i], n[j] = n[j], n[i]
-}
@@ -1,6 +1,10 @@
 package nomad
 
-import "github.com/hashicorp/nomad/nomad/structs"
+import (
+	"strconv"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
 
 // Status endpoint is used to check on server status
 type Status struct {
@@ -61,3 +65,13 @@ func (s *Status) Peers(args *structs.GenericRequest, reply *[]string) error {
 	*reply = peers
 	return nil
 }
+
+// RaftStats is used by the leader to get the Raft statistics of the server,
+// to track its health. It is always answered by the server itself.
+func (s *Status) RaftStats(args *structs.GenericRequest, reply *structs.ServerStats) error {
+	stats := s.srv.raft.Stats()
+	reply.LastContact = stats["last_contact"]
+	reply.LastIndex, _ = strconv.ParseUint(stats["last_log_index"], 10, 64)
+	reply.LastTerm, _ = strconv.ParseUint(stats["last_log_term"], 10, 64)
+	return nil
+}
@@ -20,6 +20,11 @@ import (
 //
 // Both the Agent and the executor need to be able to import ConsulConfig.
 type ConsulConfig struct {
+	// Name is the name of the Consul cluster tasks select to register their
+	// services with. The cluster configured without a name is the default
+	// one.
+	Name string `mapstructure:"name"`
+
 	// ServerServiceName is the name of the service that Nomad uses to register
 	// servers with Consul
 	ServerServiceName string `mapstructure:"server_service_name"`
@@ -88,6 +93,9 @@ func DefaultConsulConfig() *ConsulConfig {
 func (a *ConsulConfig) Merge(b *ConsulConfig) *ConsulConfig {
 	result := *a
 
+	if b.Name != "" {
+		result.Name = b.Name
+	}
 	if b.ServerServiceName != "" {
 		result.ServerServiceName = b.ServerServiceName
 	}
@@ -0,0 +1,75 @@
+package config
+
+// TLSConfig provides TLS related configuration
+type TLSConfig struct {
+
+	// EnableHTTP enables TLS for the HTTP API of the agent
+	EnableHTTP bool `mapstructure:"http"`
+
+	// EnableRPC enables TLS for the RPC and Raft traffic between servers and
+	// for the RPC traffic between clients and servers. Connections are
+	// mutually authenticated: both ends must present a certificate signed by
+	// the CA.
+	EnableRPC bool `mapstructure:"rpc"`
+
+	// VerifyServerHostname is used to enable hostname verification of
+	// servers. This ensures that the certificate presented is valid for
+	// server.<region>.nomad, which prevents a compromised client from being
+	// restarted as a server and intercepting RPC traffic.
+	VerifyServerHostname bool `mapstructure:"verify_server_hostname"`
+
+	// VerifyHTTPSClient requires the clients of the HTTP API to present a
+	// certificate signed by the CA.
+	VerifyHTTPSClient bool `mapstructure:"verify_https_client"`
+
+	// CAFile is a path to a certificate authority file. This is used to
+	// verify the certificates presented by the other end of a connection.
+	CAFile string `mapstructure:"ca_file"`
+
+	// CertFile is used to provide a TLS certificate that is used for serving
+	// TLS connections and authenticating to servers.
+	CertFile string `mapstructure:"cert_file"`
+
+	// KeyFile is used to provide a TLS key that is used for serving TLS
+	// connections and authenticating to servers.
+	KeyFile string `mapstructure:"key_file"`
+}
+
+// Merge merges two TLS configurations together.
+func (t *TLSConfig) Merge(b *TLSConfig) *TLSConfig {
+	result := *t
+
+	if b.EnableHTTP {
+		result.EnableHTTP = true
+	}
+	if b.EnableRPC {
+		result.EnableRPC = true
+	}
+	if b.VerifyServerHostname {
+		result.VerifyServerHostname = true
+	}
+	if b.VerifyHTTPSClient {
+		result.VerifyHTTPSClient = true
+	}
+	if b.CAFile != "" {
+		result.CAFile = b.CAFile
+	}
+	if b.CertFile != "" {
+		result.CertFile = b.CertFile
+	}
+	if b.KeyFile != "" {
+		result.KeyFile = b.KeyFile
+	}
+	return &result
+}
+
+// Copy returns a copy of this TLS config.
+func (t *TLSConfig) Copy() *TLSConfig {
+	if t == nil {
+		return nil
+	}
+
+	nt := new(TLSConfig)
+	*nt = *t
+	return nt
+}
@@ -22,6 +22,10 @@ const (
 // - Create child tokens with policy subsets of the Server's token.
 type VaultConfig struct {
 
+	// Name is the name of the Vault cluster tasks select to derive their
+	// tokens from. The cluster configured without a name is the default one.
+	Name string `mapstructure:"name"`
+
 	// Enabled enables or disables Vault support.
 	Enabled bool `mapstructure:"enabled"`
 
@@ -83,6 +87,9 @@ func DefaultVaultConfig() *VaultConfig {
 func (a *VaultConfig) Merge(b *VaultConfig) *VaultConfig {
 	result := *a
 
+	if b.Name != "" {
+		result.Name = b.Name
+	}
 	if b.Token != "" {
 		result.Token = b.Token
 	}
@@ -0,0 +1,772 @@
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+const (
+	// The types of CSI plugins. Controller plugins provision volumes and
+	// attach them to nodes, node plugins mount them on the nodes, and
+	// monolith plugins do both.
+	CSIPluginTypeController = "controller"
+	CSIPluginTypeNode       = "node"
+	CSIPluginTypeMonolith   = "monolith"
+
+	// The access modes of CSI volumes, which limit the number of nodes and
+	// writers claiming them
+	CSIVolumeAccessModeSingleNodeReader      = "single-node-reader-only"
+	CSIVolumeAccessModeSingleNodeWriter      = "single-node-writer"
+	CSIVolumeAccessModeMultiNodeReader       = "multi-node-reader-only"
+	CSIVolumeAccessModeMultiNodeSingleWriter = "multi-node-single-writer"
+	CSIVolumeAccessModeMultiNodeMultiWriter  = "multi-node-multi-writer"
+
+	// The attachment modes of CSI volumes
+	CSIVolumeAttachmentModeBlockDevice = "block-device"
+	CSIVolumeAttachmentModeFilesystem  = "file-system"
+
+	// The modes of volume claims. Allocations claim volumes for reading or
+	// writing before using them, and release their claims once their tasks
+	// have stopped.
+	CSIVolumeClaimRead    = "read"
+	CSIVolumeClaimWrite   = "write"
+	CSIVolumeClaimRelease = "release"
+)
+
+// TaskCSIPluginConfig marks a task as a CSI plugin. The plugin serves on a
+// socket in the mount directory, which the client mounts into the task.
+type TaskCSIPluginConfig struct {
+	// ID is the ID volumes refer to the plugin with
+	ID string
+
+	// Type is one of controller, node or monolith
+	Type string
+
+	// MountDir is the directory of the task the client mounts its plugin
+	// directory at. Volumes are staged and published below it.
+	MountDir string `mapstructure:"mount_dir"`
+}
+
+func (c *TaskCSIPluginConfig) Copy() *TaskCSIPluginConfig {
+	if c == nil {
+		return nil
+	}
+	nc := new(TaskCSIPluginConfig)
+	*nc = *c
+	return nc
+}
+
+// Validate checks the plugin configuration is well formed
+func (c *TaskCSIPluginConfig) Validate() error {
+	var mErr multierror.Error
+	if c.ID == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("CSI plugin missing ID"))
+	} else if strings.Contains(c.ID, "/") {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("CSI plugin ID %q must not contain '/'", c.ID))
+	}
+	switch c.Type {
+	case CSIPluginTypeController, CSIPluginTypeNode, CSIPluginTypeMonolith:
+	case "":
+		mErr.Errors = append(mErr.Errors, errors.New("CSI plugin missing type"))
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("CSI plugin has unsupported type %q", c.Type))
+	}
+	if c.MountDir == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("CSI plugin missing mount dir"))
+	} else if !path.IsAbs(c.MountDir) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("CSI plugin mount dir %q must be an absolute path", c.MountDir))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// CSITopology is a set of segments, such as a zone or a rack, identifying
+// where nodes are and where volumes are accessible from
+type CSITopology struct {
+	Segments map[string]string
+}
+
+func (t *CSITopology) Copy() *CSITopology {
+	if t == nil {
+		return nil
+	}
+	return &CSITopology{Segments: CopyMapStringString(t.Segments)}
+}
+
+// Contains returns whether all the segments of the other topology are
+// segments of the topology
+func (t *CSITopology) Contains(other *CSITopology) bool {
+	if other == nil {
+		return true
+	}
+	if t == nil {
+		return len(other.Segments) == 0
+	}
+	for k, v := range other.Segments {
+		if t.Segments[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// CopySliceCSITopology copies a list of topologies
+func CopySliceCSITopology(s []*CSITopology) []*CSITopology {
+	if s == nil {
+		return nil
+	}
+	ns := make([]*CSITopology, len(s))
+	for i, t := range s {
+		ns[i] = t.Copy()
+	}
+	return ns
+}
+
+// CSIInfo is the fingerprint of a CSI plugin running on a node
+type CSIInfo struct {
+	PluginID string
+
+	// AllocID is the allocation running the plugin
+	AllocID string
+
+	// Provider and ProviderVersion are the name and version the plugin
+	// reports
+	Provider        string
+	ProviderVersion string
+
+	// Healthy is set if the plugin responds to probes, with
+	// HealthDescription describing why it doesn't
+	Healthy           bool
+	HealthDescription string
+
+	// RequiresControllerPlugin is set if volumes must be published by a
+	// controller plugin before they are used on a node
+	RequiresControllerPlugin bool
+
+	// RequiresTopologies is set if volumes are only accessible from the
+	// nodes of some topologies
+	RequiresTopologies bool
+
+	// NodeInfo is set for the node plugins
+	NodeInfo *CSINodeInfo
+}
+
+func (c *CSIInfo) Copy() *CSIInfo {
+	if c == nil {
+		return nil
+	}
+	nc := new(CSIInfo)
+	*nc = *c
+	nc.NodeInfo = c.NodeInfo.Copy()
+	return nc
+}
+
+// CopyMapStringCSIInfo copies the CSI plugins of a node
+func CopyMapStringCSIInfo(m map[string]*CSIInfo) map[string]*CSIInfo {
+	if m == nil {
+		return nil
+	}
+	nm := make(map[string]*CSIInfo, len(m))
+	for k, v := range m {
+		nm[k] = v.Copy()
+	}
+	return nm
+}
+
+// CSINodeInfo describes the node of a node plugin
+type CSINodeInfo struct {
+	// ID is the ID of the node in the storage provider, which controller
+	// plugins publish volumes to
+	ID string
+
+	// MaxVolumes is the maximum number of volumes that can be claimed on the
+	// node, zero if unlimited
+	MaxVolumes int64
+
+	// AccessibleTopology is the topology of the node
+	AccessibleTopology *CSITopology
+
+	// RequiresNodeStageVolume is set if volumes are staged on the node
+	// before they are published
+	RequiresNodeStageVolume bool
+}
+
+func (n *CSINodeInfo) Copy() *CSINodeInfo {
+	if n == nil {
+		return nil
+	}
+	nn := new(CSINodeInfo)
+	*nn = *n
+	nn.AccessibleTopology = n.AccessibleTopology.Copy()
+	return nn
+}
+
+// CSIMountOptions are the options of the file-system mounts of a volume
+type CSIMountOptions struct {
+	FSType     string
+	MountFlags []string
+}
+
+func (o *CSIMountOptions) Copy() *CSIMountOptions {
+	if o == nil {
+		return nil
+	}
+	return &CSIMountOptions{
+		FSType:     o.FSType,
+		MountFlags: CopySliceString(o.MountFlags),
+	}
+}
+
+// CSIVolume is a volume of a storage provider managed by a CSI plugin, which
+// task groups request by ID
+type CSIVolume struct {
+	ID        string
+	Name      string
+	Namespace string
+
+	// ExternalID is the ID of the volume in the storage provider
+	ExternalID string
+
+	// PluginID is the ID of the plugin managing the volume
+	PluginID string
+
+	AccessMode     string
+	AttachmentMode string
+	MountOptions   *CSIMountOptions
+
+	// Capacity is the size of the volume in bytes, as reported by the
+	// plugin creating it
+	Capacity int64
+
+	// RequestedCapacityMin and RequestedCapacityMax bound the size of the
+	// volumes created by Nomad
+	RequestedCapacityMin int64
+	RequestedCapacityMax int64
+
+	// Parameters are passed to the plugin creating the volume
+	Parameters map[string]string
+
+	// SnapshotID is the ID of the snapshot, in the storage provider, the
+	// volume is created from, if any
+	SnapshotID string
+
+	// Context is passed to the plugin calls of the volume
+	Context map[string]string
+
+	// Secrets are passed to the plugin calls of the volume. They are never
+	// returned by the API.
+	Secrets map[string]string
+
+	// Topologies are the topologies the volume is accessible from. The
+	// volume is accessible from all the nodes if there are none.
+	Topologies []*CSITopology
+
+	// Claims are the claims of the allocations using the volume, keyed by
+	// allocation ID
+	Claims map[string]*CSIVolumeClaim
+
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// CSIVolumeClaim is the claim of an allocation on a volume
+type CSIVolumeClaim struct {
+	AllocID string
+	NodeID  string
+	Mode    string
+
+	// ExternalNodeID is the ID of the node in the storage provider, which
+	// the volume is unpublished from when the claim is released
+	ExternalNodeID string
+}
+
+func (c *CSIVolumeClaim) Copy() *CSIVolumeClaim {
+	if c == nil {
+		return nil
+	}
+	nc := new(CSIVolumeClaim)
+	*nc = *c
+	return nc
+}
+
+func (v *CSIVolume) Copy() *CSIVolume {
+	if v == nil {
+		return nil
+	}
+	nv := new(CSIVolume)
+	*nv = *v
+	nv.MountOptions = v.MountOptions.Copy()
+	nv.Parameters = CopyMapStringString(v.Parameters)
+	nv.Context = CopyMapStringString(v.Context)
+	nv.Secrets = CopyMapStringString(v.Secrets)
+	nv.Topologies = CopySliceCSITopology(v.Topologies)
+	if v.Claims != nil {
+		nv.Claims = make(map[string]*CSIVolumeClaim, len(v.Claims))
+		for k, c := range v.Claims {
+			nv.Claims[k] = c.Copy()
+		}
+	}
+	return nv
+}
+
+// Sanitize returns a copy of the volume without its secrets
+func (v *CSIVolume) Sanitize() *CSIVolume {
+	if v == nil {
+		return nil
+	}
+	nv := v.Copy()
+	nv.Secrets = nil
+	return nv
+}
+
+// Validate checks the volume is well formed. The external ID is only
+// required for volumes registered rather than created by Nomad.
+func (v *CSIVolume) Validate(requireExternalID bool) error {
+	var mErr multierror.Error
+	if v.ID == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("Volume missing ID"))
+	} else if strings.Contains(v.ID, "/") {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume ID %q must not contain '/'", v.ID))
+	}
+	if v.PluginID == "" {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume %q missing plugin ID", v.ID))
+	}
+	if requireExternalID && v.ExternalID == "" {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume %q missing external ID", v.ID))
+	}
+	switch v.AccessMode {
+	case CSIVolumeAccessModeSingleNodeReader, CSIVolumeAccessModeSingleNodeWriter,
+		CSIVolumeAccessModeMultiNodeReader, CSIVolumeAccessModeMultiNodeSingleWriter,
+		CSIVolumeAccessModeMultiNodeMultiWriter:
+	case "":
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume %q missing access mode", v.ID))
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume %q has unsupported access mode %q", v.ID, v.AccessMode))
+	}
+	switch v.AttachmentMode {
+	case CSIVolumeAttachmentModeBlockDevice, CSIVolumeAttachmentModeFilesystem:
+	case "":
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume %q missing attachment mode", v.ID))
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume %q has unsupported attachment mode %q", v.ID, v.AttachmentMode))
+	}
+	if v.RequestedCapacityMax != 0 && v.RequestedCapacityMax < v.RequestedCapacityMin {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume %q maximum capacity is less than its minimum capacity", v.ID))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// WriteAllowed returns whether the access mode of the volume allows writes
+func (v *CSIVolume) WriteAllowed() bool {
+	switch v.AccessMode {
+	case CSIVolumeAccessModeSingleNodeReader, CSIVolumeAccessModeMultiNodeReader:
+		return false
+	default:
+		return true
+	}
+}
+
+// singleNode returns whether the access mode of the volume restricts its
+// claims to a single node
+func (v *CSIVolume) singleNode() bool {
+	switch v.AccessMode {
+	case CSIVolumeAccessModeSingleNodeReader, CSIVolumeAccessModeSingleNodeWriter:
+		return true
+	default:
+		return false
+	}
+}
+
+// singleWriter returns whether the access mode of the volume restricts it to
+// a single writer
+func (v *CSIVolume) singleWriter() bool {
+	switch v.AccessMode {
+	case CSIVolumeAccessModeSingleNodeWriter, CSIVolumeAccessModeMultiNodeSingleWriter:
+		return true
+	default:
+		return false
+	}
+}
+
+// Readers and Writers return the number of read and write claims of the
+// volume
+func (v *CSIVolume) Readers() int { return v.countClaims(CSIVolumeClaimRead) }
+func (v *CSIVolume) Writers() int { return v.countClaims(CSIVolumeClaimWrite) }
+
+func (v *CSIVolume) countClaims(mode string) int {
+	n := 0
+	for _, c := range v.Claims {
+		if c.Mode == mode {
+			n++
+		}
+	}
+	return n
+}
+
+// ClaimAllowed returns an error if an allocation on the node can't claim the
+// volume in the mode because of the access mode of the volume or its other
+// claims
+func (v *CSIVolume) ClaimAllowed(allocID, nodeID, mode string) error {
+	if mode == CSIVolumeClaimWrite && !v.WriteAllowed() {
+		return fmt.Errorf("volume %q is read-only", v.ID)
+	}
+	for _, c := range v.Claims {
+		if c.AllocID == allocID {
+			continue
+		}
+		if v.singleNode() && c.NodeID != nodeID {
+			return fmt.Errorf("volume %q is already claimed on node %q", v.ID, c.NodeID)
+		}
+		if mode == CSIVolumeClaimWrite && c.Mode == CSIVolumeClaimWrite && v.singleWriter() {
+			return fmt.Errorf("volume %q already has a writer", v.ID)
+		}
+	}
+	return nil
+}
+
+// Claim records the claim of an allocation on the volume, or releases it if
+// its mode is release
+func (v *CSIVolume) Claim(claim *CSIVolumeClaim) error {
+	switch claim.Mode {
+	case CSIVolumeClaimRelease:
+		delete(v.Claims, claim.AllocID)
+		return nil
+	case CSIVolumeClaimRead, CSIVolumeClaimWrite:
+	default:
+		return fmt.Errorf("unknown claim mode %q", claim.Mode)
+	}
+	if err := v.ClaimAllowed(claim.AllocID, claim.NodeID, claim.Mode); err != nil {
+		return err
+	}
+	if v.Claims == nil {
+		v.Claims = make(map[string]*CSIVolumeClaim)
+	}
+	v.Claims[claim.AllocID] = claim
+	return nil
+}
+
+// Stub returns a summary of the volume
+func (v *CSIVolume) Stub() *CSIVolumeListStub {
+	return &CSIVolumeListStub{
+		ID:             v.ID,
+		Namespace:      v.Namespace,
+		Name:           v.Name,
+		ExternalID:     v.ExternalID,
+		PluginID:       v.PluginID,
+		AccessMode:     v.AccessMode,
+		AttachmentMode: v.AttachmentMode,
+		Capacity:       v.Capacity,
+		Readers:        v.Readers(),
+		Writers:        v.Writers(),
+		CreateIndex:    v.CreateIndex,
+		ModifyIndex:    v.ModifyIndex,
+	}
+}
+
+// CSIVolumeListStub is a summary of a volume
+type CSIVolumeListStub struct {
+	ID             string
+	Namespace      string
+	Name           string
+	ExternalID     string
+	PluginID       string
+	AccessMode     string
+	AttachmentMode string
+	Capacity       int64
+	Readers        int
+	Writers        int
+	CreateIndex    uint64
+	ModifyIndex    uint64
+}
+
+// CSIPlugin is a CSI plugin and the instances of it running on the nodes.
+// Plugins aren't stored but derived from the fingerprints of the nodes.
+type CSIPlugin struct {
+	ID              string
+	Provider        string
+	ProviderVersion string
+
+	// ControllerRequired is set if volumes must be published by a
+	// controller plugin before they are used on a node
+	ControllerRequired bool
+
+	// Controllers and Nodes are the controller and node plugins, keyed by
+	// node ID
+	Controllers map[string]*CSIInfo
+	Nodes       map[string]*CSIInfo
+
+	ControllersHealthy int
+	NodesHealthy       int
+}
+
+// NewCSIPlugin returns a plugin without instances
+func NewCSIPlugin(id string) *CSIPlugin {
+	return &CSIPlugin{
+		ID:          id,
+		Controllers: make(map[string]*CSIInfo),
+		Nodes:       make(map[string]*CSIInfo),
+	}
+}
+
+// AddNode adds the instances of the plugin running on the node. Only the
+// instances of ready nodes count as healthy.
+func (p *CSIPlugin) AddNode(node *Node) {
+	ready := node.Status == NodeStatusReady
+	if info, ok := node.CSIControllerPlugins[p.ID]; ok {
+		p.Controllers[node.ID] = info
+		if info.Healthy && ready {
+			p.ControllersHealthy++
+		}
+		p.addInfo(info)
+	}
+	if info, ok := node.CSINodePlugins[p.ID]; ok {
+		p.Nodes[node.ID] = info
+		if info.Healthy && ready {
+			p.NodesHealthy++
+		}
+		p.addInfo(info)
+	}
+}
+
+func (p *CSIPlugin) addInfo(info *CSIInfo) {
+	if info.Provider != "" {
+		p.Provider = info.Provider
+		p.ProviderVersion = info.ProviderVersion
+	}
+	if info.RequiresControllerPlugin {
+		p.ControllerRequired = true
+	}
+}
+
+// Stub returns a summary of the plugin
+func (p *CSIPlugin) Stub() *CSIPluginListStub {
+	return &CSIPluginListStub{
+		ID:                 p.ID,
+		Provider:           p.Provider,
+		ControllerRequired: p.ControllerRequired,
+		ControllersHealthy: p.ControllersHealthy,
+		Controllers:        len(p.Controllers),
+		NodesHealthy:       p.NodesHealthy,
+		Nodes:              len(p.Nodes),
+	}
+}
+
+// CSIPluginListStub is a summary of a plugin
+type CSIPluginListStub struct {
+	ID                 string
+	Provider           string
+	ControllerRequired bool
+	ControllersHealthy int
+	Controllers        int
+	NodesHealthy       int
+	Nodes              int
+}
+
+// CSIVolumeRegisterRequest is used to register existing volumes of storage
+// providers, or to update them
+type CSIVolumeRegisterRequest struct {
+	Volumes []*CSIVolume
+	WriteRequest
+}
+
+// CSIVolumeDeregisterRequest is used to deregister volumes. Volumes in use
+// are only deregistered if forced.
+type CSIVolumeDeregisterRequest struct {
+	VolumeIDs []string
+	Force     bool
+	WriteRequest
+}
+
+// CSIVolumeCreateRequest is used to create volumes with the controller of
+// their plugin and register them
+type CSIVolumeCreateRequest struct {
+	Volumes []*CSIVolume
+	WriteRequest
+}
+
+// CSIVolumeCreateResponse is the response to a volume creation
+type CSIVolumeCreateResponse struct {
+	Volumes []*CSIVolume
+	WriteMeta
+}
+
+// CSIVolumeDeleteRequest is used to delete volumes with the controller of
+// their plugin and deregister them. Volumes in use can't be deleted.
+type CSIVolumeDeleteRequest struct {
+	VolumeIDs []string
+	WriteRequest
+}
+
+// CSIVolumeExpandRequest is used to grow a volume to a capacity between the
+// bounds, in bytes. A zero maximum is unbounded.
+type CSIVolumeExpandRequest struct {
+	VolumeID             string
+	RequestedCapacityMin int64
+	RequestedCapacityMax int64
+	WriteRequest
+}
+
+// CSIVolumeExpandResponse is the response to a volume expansion
+type CSIVolumeExpandResponse struct {
+	Capacity int64
+	WriteMeta
+}
+
+// CSISnapshot is a snapshot of a volume taken by the controller of its
+// plugin. Snapshots are only tracked by the storage providers.
+type CSISnapshot struct {
+	// ID is the ID of the snapshot in the storage provider
+	ID string
+
+	// SourceVolumeID is the ID of the volume the snapshot is taken of, and
+	// ExternalSourceVolumeID its ID in the storage provider
+	SourceVolumeID         string
+	ExternalSourceVolumeID string
+
+	PluginID string
+	Name     string
+
+	SizeBytes int64
+
+	// CreateTime is when the snapshot was taken, in nanoseconds since the
+	// Unix epoch
+	CreateTime int64
+
+	// IsReady is set once volumes can be created from the snapshot
+	IsReady bool
+
+	// Parameters are passed to the plugin taking the snapshot
+	Parameters map[string]string
+
+	// Secrets are passed to the plugin calls of the snapshot. They are never
+	// returned by the API.
+	Secrets map[string]string
+}
+
+// CSISnapshotCreateRequest is used to snapshot volumes
+type CSISnapshotCreateRequest struct {
+	Snapshots []*CSISnapshot
+	WriteRequest
+}
+
+// CSISnapshotCreateResponse is the response to a snapshot creation
+type CSISnapshotCreateResponse struct {
+	Snapshots []*CSISnapshot
+	WriteMeta
+}
+
+// CSISnapshotDeleteRequest is used to delete snapshots, identified by their
+// ID and plugin ID
+type CSISnapshotDeleteRequest struct {
+	Snapshots []*CSISnapshot
+	WriteRequest
+}
+
+// CSISnapshotListRequest is used to list the snapshots of a plugin. At most
+// PerPage snapshots are returned if set, starting from the NextToken of a
+// previous response.
+type CSISnapshotListRequest struct {
+	PluginID  string
+	Secrets   map[string]string
+	PerPage   int
+	NextToken string
+	QueryOptions
+}
+
+// CSISnapshotListResponse is used for a list request. NextToken is empty on
+// the last page.
+type CSISnapshotListResponse struct {
+	Snapshots []*CSISnapshot
+	NextToken string
+	QueryMeta
+}
+
+// CSIVolumeListRequest is used to list the volumes of a namespace,
+// optionally only the ones of a plugin
+type CSIVolumeListRequest struct {
+	PluginID string
+	QueryOptions
+}
+
+// CSIVolumeListResponse is used for a list request
+type CSIVolumeListResponse struct {
+	Volumes []*CSIVolumeListStub
+	QueryMeta
+}
+
+// CSIVolumeGetRequest is used to look up a volume
+type CSIVolumeGetRequest struct {
+	ID string
+	QueryOptions
+}
+
+// CSIVolumeGetResponse is used for a get request
+type CSIVolumeGetResponse struct {
+	Volume *CSIVolume
+	QueryMeta
+}
+
+// CSIVolumeClaimRequest is used by clients to claim volumes for their
+// allocations, and to release the claims
+type CSIVolumeClaimRequest struct {
+	VolumeID     string
+	AllocationID string
+	Mode         string
+
+	// ExternalNodeID is the ID of the node in the storage provider, which
+	// the volume is published to. It defaults to the ID fingerprinted by the
+	// node plugin.
+	ExternalNodeID string
+
+	NodeID   string
+	SecretID string
+	WriteRequest
+}
+
+// CSIVolumeClaimResponse is the response to a claim. The publish context
+// returned by the controller plugin is passed to the node plugin.
+type CSIVolumeClaimResponse struct {
+	Volume         *CSIVolume
+	PublishContext map[string]string
+	WriteMeta
+}
+
+// CSIVolumeReleaseClaimsRequest is used by the core scheduler to release the
+// claims of terminal or collected allocations on a volume
+type CSIVolumeReleaseClaimsRequest struct {
+	VolumeID string
+	AllocIDs []string
+	WriteRequest
+}
+
+// CSIVolumeClaimUpdate is the raft request updating the claim of a volume
+type CSIVolumeClaimUpdate struct {
+	VolumeID string
+	Claim    *CSIVolumeClaim
+}
+
+// CSIPluginListRequest is used to list the plugins
+type CSIPluginListRequest struct {
+	QueryOptions
+}
+
+// CSIPluginListResponse is used for a list request
+type CSIPluginListResponse struct {
+	Plugins []*CSIPluginListStub
+	QueryMeta
+}
+
+// CSIPluginGetRequest is used to look up a plugin
+type CSIPluginGetRequest struct {
+	ID string
+	QueryOptions
+}
+
+// CSIPluginGetResponse is used for a get request
+type CSIPluginGetResponse struct {
+	Plugin *CSIPlugin
+	QueryMeta
+}
@@ -0,0 +1,165 @@
+package structs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NodeDeviceResource is a group of identical devices of a node, such as GPUs
+// of the same model. Devices are reported by the device plugins of the
+// client.
+type NodeDeviceResource struct {
+	// Vendor, Type and Name identify the group, such as nvidia, gpu and
+	// 1080ti
+	Vendor string
+	Type   string
+	Name   string
+
+	// Instances are the devices of the group
+	Instances []*NodeDevice
+
+	// Attributes describe the devices of the group, such as their memory
+	Attributes map[string]string
+}
+
+// NodeDevice is a device of a node
+type NodeDevice struct {
+	// ID identifies the device within its group
+	ID string
+
+	// Healthy marks whether the device can be used, with HealthDescription
+	// describing why it can't
+	Healthy           bool
+	HealthDescription string
+}
+
+// ID returns the identifier of the group, as <vendor>/<type>/<name>
+func (r *NodeDeviceResource) ID() string {
+	return fmt.Sprintf("%s/%s/%s", r.Vendor, r.Type, r.Name)
+}
+
+// Matches returns whether the devices of the group are selected by the name
+// of a device request, which is either <type>, <vendor>/<type> or
+// <vendor>/<type>/<name>
+func (r *NodeDeviceResource) Matches(name string) bool {
+	parts := strings.Split(name, "/")
+	switch len(parts) {
+	case 1:
+		return parts[0] == r.Type
+	case 2:
+		return parts[0] == r.Vendor && parts[1] == r.Type
+	case 3:
+		return parts[0] == r.Vendor && parts[1] == r.Type && parts[2] == r.Name
+	default:
+		return false
+	}
+}
+
+// HealthyInstances returns the number of healthy devices of the group
+func (r *NodeDeviceResource) HealthyInstances() int {
+	healthy := 0
+	for _, d := range r.Instances {
+		if d.Healthy {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+// Copy returns a deep copy of the device group
+func (r *NodeDeviceResource) Copy() *NodeDeviceResource {
+	if r == nil {
+		return nil
+	}
+	nr := new(NodeDeviceResource)
+	*nr = *r
+	if r.Instances != nil {
+		nr.Instances = make([]*NodeDevice, len(r.Instances))
+		for i, d := range r.Instances {
+			nd := *d
+			nr.Instances[i] = &nd
+		}
+	}
+	nr.Attributes = CopyMapStringString(r.Attributes)
+	return nr
+}
+
+// RequestedDevice is a request of a task for devices of the node
+type RequestedDevice struct {
+	// Name selects the devices, either by <type>, <vendor>/<type> or
+	// <vendor>/<type>/<name>
+	Name string
+
+	// Count is the number of devices requested
+	Count int
+}
+
+// Copy returns a copy of the device request
+func (r *RequestedDevice) Copy() *RequestedDevice {
+	if r == nil {
+		return nil
+	}
+	nr := *r
+	return &nr
+}
+
+// Validate returns an error if the device request is invalid
+func (r *RequestedDevice) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("device name must be set")
+	}
+	if parts := strings.Split(r.Name, "/"); len(parts) > 3 {
+		return fmt.Errorf("device name %q must be <type>, <vendor>/<type> or <vendor>/<type>/<name>", r.Name)
+	}
+	if r.Count < 1 {
+		return fmt.Errorf("device %q count must be at least 1; got %d", r.Name, r.Count)
+	}
+	return nil
+}
+
+// DevicesFit returns whether the healthy devices of the node satisfy the
+// requests. Each request is satisfied by the devices of a single group, and
+// the requests are assigned greedily in order. If they don't fit, the
+// dimension that was exhausted is returned.
+func DevicesFit(devices []*NodeDeviceResource, requests []*RequestedDevice) (bool, string) {
+	if len(requests) == 0 {
+		return true, ""
+	}
+
+	available := make([]int, len(devices))
+	for i, d := range devices {
+		available[i] = d.HealthyInstances()
+	}
+
+REQUESTS:
+	for _, req := range requests {
+		for i, d := range devices {
+			if d.Matches(req.Name) && available[i] >= req.Count {
+				available[i] -= req.Count
+				continue REQUESTS
+			}
+		}
+		return false, fmt.Sprintf("devices %q exhausted", req.Name)
+	}
+	return true, ""
+}
+
+// allocsRequestedDevices returns the devices requested by the tasks of the
+// allocations, ordered by allocation and task name
+func allocsRequestedDevices(allocs []*Allocation) []*RequestedDevice {
+	var requests []*RequestedDevice
+	for _, alloc := range allocs {
+		tasks := make([]string, 0, len(alloc.TaskResources))
+		for task, r := range alloc.TaskResources {
+			if len(r.Devices) != 0 {
+				tasks = append(tasks, task)
+			}
+		}
+		sort.Strings(tasks)
+		for _, task := range tasks {
+			requests = append(requests, alloc.TaskResources[task].Devices...)
+		}
+	}
+	return requests
+}
@@ -59,7 +59,7 @@ type JobDiff struct {
 func (j *Job) Diff(other *Job, contextual bool) (*JobDiff, error) {
 	diff := &JobDiff{Type: DiffTypeNone}
 	var oldPrimitiveFlat, newPrimitiveFlat map[string]string
-	filter := []string{"ID", "Status", "StatusDescription", "CreateIndex", "ModifyIndex", "JobModifyIndex"}
+	filter := []string{"ID", "Status", "StatusDescription", "CreateIndex", "ModifyIndex", "JobModifyIndex", "Dispatched", "Version"}
 
 	// Have to treat this special since it is a struct literal, not a pointer
 	var jUpdate, otherUpdate *UpdateStrategy
@@ -97,6 +97,11 @@ func (j *Job) Diff(other *Job, contextual bool) (*JobDiff, error) {
 	// Diff the primitive fields.
 	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, false)
 
+	// Regions diff
+	if setDiff := stringSetDiff(j.Regions, other.Regions, "Regions", contextual); setDiff != nil {
+		diff.Objects = append(diff.Objects, setDiff)
+	}
+
 	// Datacenters diff
 	if setDiff := stringSetDiff(j.Datacenters, other.Datacenters, "Datacenters", contextual); setDiff != nil {
 		diff.Objects = append(diff.Objects, setDiff)
@@ -125,14 +130,101 @@ func (j *Job) Diff(other *Job, contextual bool) (*JobDiff, error) {
 		diff.Objects = append(diff.Objects, uDiff)
 	}
 
+	// Multiregion diff
+	if mDiff := primitiveObjectDiff(j.Multiregion, other.Multiregion, nil, "Multiregion", contextual); mDiff != nil {
+		diff.Objects = append(diff.Objects, mDiff)
+	}
+
 	// Periodic diff
-	if pDiff := primitiveObjectDiff(j.Periodic, other.Periodic, nil, "Periodic", contextual); pDiff != nil {
+	if pDiff := periodicDiff(j.Periodic, other.Periodic, contextual); pDiff != nil {
 		diff.Objects = append(diff.Objects, pDiff)
 	}
 
+	// ParameterizedJob diff
+	if cDiff := parameterizedJobDiff(j.ParameterizedJob, other.ParameterizedJob, contextual); cDiff != nil {
+		diff.Objects = append(diff.Objects, cDiff)
+	}
+
 	return diff, nil
 }
 
+// periodicDiff returns the diff of two periodic configs. If contextual diff is
+// enabled, all fields will be returned, even if no diff occurred.
+func periodicDiff(old, new *PeriodicConfig, contextual bool) *ObjectDiff {
+	diff := &ObjectDiff{Type: DiffTypeNone, Name: "Periodic"}
+	var oldPrimitiveFlat, newPrimitiveFlat map[string]string
+
+	if reflect.DeepEqual(old, new) {
+		return nil
+	} else if old == nil {
+		old = &PeriodicConfig{}
+		diff.Type = DiffTypeAdded
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	} else if new == nil {
+		new = &PeriodicConfig{}
+		diff.Type = DiffTypeDeleted
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+	} else {
+		diff.Type = DiffTypeEdited
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	}
+
+	// Diff the primitive fields.
+	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, contextual)
+
+	// Specs diff
+	specDiffs := primitiveObjectSetDiff(
+		interfaceSlice(old.Specs),
+		interfaceSlice(new.Specs),
+		nil,
+		"Spec",
+		contextual)
+	if specDiffs != nil {
+		diff.Objects = append(diff.Objects, specDiffs...)
+	}
+
+	return diff
+}
+
+// parameterizedJobDiff returns the diff of two parameterized job configs. If
+// contextual diff is enabled, all fields will be returned, even if no diff
+// occurred.
+func parameterizedJobDiff(old, new *ParameterizedJobConfig, contextual bool) *ObjectDiff {
+	diff := &ObjectDiff{Type: DiffTypeNone, Name: "ParameterizedJob"}
+	var oldPrimitiveFlat, newPrimitiveFlat map[string]string
+
+	if reflect.DeepEqual(old, new) {
+		return nil
+	} else if old == nil {
+		old = &ParameterizedJobConfig{}
+		diff.Type = DiffTypeAdded
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	} else if new == nil {
+		new = &ParameterizedJobConfig{}
+		diff.Type = DiffTypeDeleted
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+	} else {
+		diff.Type = DiffTypeEdited
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	}
+
+	// Diff the primitive fields.
+	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, contextual)
+
+	// Meta diffs
+	if optionalDiff := stringSetDiff(old.MetaOptional, new.MetaOptional, "MetaOptional", contextual); optionalDiff != nil {
+		diff.Objects = append(diff.Objects, optionalDiff)
+	}
+
+	if requiredDiff := stringSetDiff(old.MetaRequired, new.MetaRequired, "MetaRequired", contextual); requiredDiff != nil {
+		diff.Objects = append(diff.Objects, requiredDiff)
+	}
+
+	return diff
+}
+
 func (j *JobDiff) GoString() string {
 	out := fmt.Sprintf("Job %q (%s):\n", j.ID, j.Type)
 
@@ -219,6 +311,33 @@ func (tg *TaskGroup) Diff(other *TaskGroup, contextual bool) (*TaskGroupDiff, er
 		diff.Objects = append(diff.Objects, diskDiff)
 	}
 
+	// Migrate strategy diff
+	mDiff := primitiveObjectDiff(tg.Migrate, other.Migrate, nil, "Migrate", contextual)
+	if mDiff != nil {
+		diff.Objects = append(diff.Objects, mDiff)
+	}
+
+	// Network Resources diff
+	if nDiffs := networkResourceDiffs(tg.Networks, other.Networks, contextual); nDiffs != nil {
+		diff.Objects = append(diff.Objects, nDiffs...)
+	}
+
+	// Volumes diff
+	volDiffs := primitiveObjectSetDiff(
+		volumeRequestSlice(tg.Volumes),
+		volumeRequestSlice(other.Volumes),
+		nil,
+		"Volume",
+		contextual)
+	if volDiffs != nil {
+		diff.Objects = append(diff.Objects, volDiffs...)
+	}
+
+	// Scaling policy diff
+	if sDiff := scalingPolicyDiff(tg.Scaling, other.Scaling, contextual); sDiff != nil {
+		diff.Objects = append(diff.Objects, sDiff)
+	}
+
 	// Tasks diff
 	tasks, err := taskDiffs(tg.Tasks, other.Tasks, contextual)
 	if err != nil {
@@ -229,6 +348,40 @@ func (tg *TaskGroup) Diff(other *TaskGroup, contextual bool) (*TaskGroupDiff, er
 	return diff, nil
 }
 
+// scalingPolicyDiff returns the diff of two scaling policies. If contextual
+// diff is enabled, all fields will be returned, even if no diff occurred.
+func scalingPolicyDiff(old, new *ScalingPolicy, contextual bool) *ObjectDiff {
+	diff := &ObjectDiff{Type: DiffTypeNone, Name: "Scaling"}
+	var oldPrimitiveFlat, newPrimitiveFlat map[string]string
+
+	if reflect.DeepEqual(old, new) {
+		return nil
+	} else if old == nil {
+		old = &ScalingPolicy{}
+		diff.Type = DiffTypeAdded
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	} else if new == nil {
+		new = &ScalingPolicy{}
+		diff.Type = DiffTypeDeleted
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+	} else {
+		diff.Type = DiffTypeEdited
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	}
+
+	// Diff the primitive fields.
+	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, contextual)
+
+	// Policy document diff
+	if policyDiff := configDiff(old.Policy, new.Policy, contextual); policyDiff != nil {
+		policyDiff.Name = "Policy"
+		diff.Objects = append(diff.Objects, policyDiff)
+	}
+
+	return diff
+}
+
 func (tg *TaskGroupDiff) GoString() string {
 	out := fmt.Sprintf("Group %q (%s):\n", tg.Name, tg.Type)
 
@@ -370,6 +523,36 @@ func (t *Task) Diff(other *Task, contextual bool) (*TaskDiff, error) {
 		diff.Objects = append(diff.Objects, lDiff)
 	}
 
+	// Schedule diff
+	schedDiff := primitiveObjectDiff(t.Schedule, other.Schedule, nil, "Schedule", contextual)
+	if schedDiff != nil {
+		diff.Objects = append(diff.Objects, schedDiff)
+	}
+
+	// Lifecycle diff
+	lifecycleDiff := primitiveObjectDiff(t.Lifecycle, other.Lifecycle, nil, "Lifecycle", contextual)
+	if lifecycleDiff != nil {
+		diff.Objects = append(diff.Objects, lifecycleDiff)
+	}
+
+	// Restart policy diff
+	rDiff := primitiveObjectDiff(t.RestartPolicy, other.RestartPolicy, nil, "RestartPolicy", contextual)
+	if rDiff != nil {
+		diff.Objects = append(diff.Objects, rDiff)
+	}
+
+	// Dispatch payload diff
+	dispatchDiff := primitiveObjectDiff(t.DispatchPayload, other.DispatchPayload, nil, "DispatchPayload", contextual)
+	if dispatchDiff != nil {
+		diff.Objects = append(diff.Objects, dispatchDiff)
+	}
+
+	// CSI plugin diff
+	csiDiff := primitiveObjectDiff(t.CSIPluginConfig, other.CSIPluginConfig, nil, "CSIPluginConfig", contextual)
+	if csiDiff != nil {
+		diff.Objects = append(diff.Objects, csiDiff)
+	}
+
 	// Artifacts diff
 	diffs := primitiveObjectSetDiff(
 		interfaceSlice(t.Artifacts),
@@ -392,6 +575,12 @@ func (t *Task) Diff(other *Task, contextual bool) (*TaskDiff, error) {
 		diff.Objects = append(diff.Objects, vDiff)
 	}
 
+	// Consul diff
+	cDiff := primitiveObjectDiff(t.Consul, other.Consul, nil, "Consul", contextual)
+	if cDiff != nil {
+		diff.Objects = append(diff.Objects, cDiff)
+	}
+
 	// Artifacts diff
 	tmplDiffs := primitiveObjectSetDiff(
 		interfaceSlice(t.Templates),
@@ -403,6 +592,17 @@ func (t *Task) Diff(other *Task, contextual bool) (*TaskDiff, error) {
 		diff.Objects = append(diff.Objects, tmplDiffs...)
 	}
 
+	// Volume mounts diff
+	mountDiffs := primitiveObjectSetDiff(
+		interfaceSlice(t.VolumeMounts),
+		interfaceSlice(other.VolumeMounts),
+		nil,
+		"VolumeMount",
+		contextual)
+	if mountDiffs != nil {
+		diff.Objects = append(diff.Objects, mountDiffs...)
+	}
+
 	return diff, nil
 }
 
@@ -559,6 +759,11 @@ func serviceCheckDiff(old, new *ServiceCheck, contextual bool) *ObjectDiff {
 
 	// Diff the primitive fields.
 	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, contextual)
+
+	// Diff the check restart
+	if crDiff := primitiveObjectDiff(old.CheckRestart, new.CheckRestart, nil, "CheckRestart", contextual); crDiff != nil {
+		diff.Objects = append(diff.Objects, crDiff)
+	}
 	return diff
 }
 
@@ -659,6 +864,17 @@ func (r *Resources) Diff(other *Resources, contextual bool) *ObjectDiff {
 		diff.Objects = append(diff.Objects, nDiffs...)
 	}
 
+	// Requested devices diff
+	devDiffs := primitiveObjectSetDiff(
+		interfaceSlice(r.Devices),
+		interfaceSlice(other.Devices),
+		nil,
+		"Device",
+		contextual)
+	if devDiffs != nil {
+		diff.Objects = append(diff.Objects, devDiffs...)
+	}
+
 	return diff
 }
 
@@ -667,7 +883,7 @@ func (r *Resources) Diff(other *Resources, contextual bool) *ObjectDiff {
 func (r *NetworkResource) Diff(other *NetworkResource, contextual bool) *ObjectDiff {
 	diff := &ObjectDiff{Type: DiffTypeNone, Name: "Network"}
 	var oldPrimitiveFlat, newPrimitiveFlat map[string]string
-	filter := []string{"Device", "CIDR", "IP"}
+	filter := []string{"Device", "CIDR", "IP", "HostNetwork"}
 
 	if reflect.DeepEqual(r, other) {
 		return nil
@@ -1118,6 +1334,22 @@ func primitiveObjectSetDiff(old, new []interface{}, filter []string, name string
 // interfaceSlice is a helper method that takes a slice of typed elements and
 // returns a slice of interface. This method will panic if given a non-slice
 // input.
+// volumeRequestSlice returns the volume requests of a task group sorted by
+// name, to diff them as a set
+func volumeRequestSlice(volumes map[string]*VolumeRequest) []interface{} {
+	names := make([]string, 0, len(volumes))
+	for name := range volumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ret := make([]interface{}, len(names))
+	for i, name := range names {
+		ret[i] = volumes[name]
+	}
+	return ret
+}
+
 func interfaceSlice(slice interface{}) []interface{} {
 	s := reflect.ValueOf(slice)
 	if s.Kind() != reflect.Slice {
@@ -174,6 +174,12 @@ func TestJobDiff(t *testing.T) {
 						Old:  "foo",
 						New:  "",
 					},
+					{
+						Type: DiffTypeDeleted,
+						Name: "Stop",
+						Old:  "false",
+						New:  "",
+					},
 					{
 						Type: DiffTypeDeleted,
 						Name: "Type",
@@ -251,6 +257,12 @@ func TestJobDiff(t *testing.T) {
 						Old:  "",
 						New:  "foo",
 					},
+					{
+						Type: DiffTypeAdded,
+						Name: "Stop",
+						Old:  "",
+						New:  "false",
+					},
 					{
 						Type: DiffTypeAdded,
 						Name: "Type",
@@ -506,6 +518,47 @@ func TestJobDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			// ParameterizedJob added
+			Old: &Job{},
+			New: &Job{
+				ParameterizedJob: &ParameterizedJobConfig{
+					Payload:      DispatchPayloadRequired,
+					MetaRequired: []string{"foo"},
+				},
+			},
+			Expected: &JobDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeAdded,
+						Name: "ParameterizedJob",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeAdded,
+								Name: "Payload",
+								Old:  "",
+								New:  DispatchPayloadRequired,
+							},
+						},
+						Objects: []*ObjectDiff{
+							{
+								Type: DiffTypeAdded,
+								Name: "MetaRequired",
+								Fields: []*FieldDiff{
+									{
+										Type: DiffTypeAdded,
+										Name: "MetaRequired",
+										Old:  "",
+										New:  "foo",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			// Periodic added
 			Old: &Job{},
@@ -704,6 +757,82 @@ func TestJobDiff(t *testing.T) {
 								Old:  "foo",
 								New:  "foo",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "TimeZone",
+								Old:  "",
+								New:  "",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			// Periodic specs edited
+			Old: &Job{
+				Periodic: &PeriodicConfig{
+					Enabled:  true,
+					Spec:     "*/15 * * * * *",
+					SpecType: "foo",
+					Specs: []*PeriodicSpec{
+						{Spec: "0 9 * * *", Enabled: true},
+					},
+				},
+			},
+			New: &Job{
+				Periodic: &PeriodicConfig{
+					Enabled:  true,
+					Spec:     "*/15 * * * * *",
+					SpecType: "foo",
+					Specs: []*PeriodicSpec{
+						{Spec: "0 17 * * *", Enabled: false},
+					},
+				},
+			},
+			Expected: &JobDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "Periodic",
+						Objects: []*ObjectDiff{
+							{
+								Type: DiffTypeAdded,
+								Name: "Spec",
+								Fields: []*FieldDiff{
+									{
+										Type: DiffTypeAdded,
+										Name: "Enabled",
+										Old:  "",
+										New:  "false",
+									},
+									{
+										Type: DiffTypeAdded,
+										Name: "Spec",
+										Old:  "",
+										New:  "0 17 * * *",
+									},
+								},
+							},
+							{
+								Type: DiffTypeDeleted,
+								Name: "Spec",
+								Fields: []*FieldDiff{
+									{
+										Type: DiffTypeDeleted,
+										Name: "Enabled",
+										Old:  "true",
+										New:  "",
+									},
+									{
+										Type: DiffTypeDeleted,
+										Name: "Spec",
+										Old:  "0 9 * * *",
+										New:  "",
+									},
+								},
+							},
 						},
 					},
 				},
@@ -1309,13 +1438,70 @@ func TestTaskGroupDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			// Network added
+			Old: &TaskGroup{},
+			New: &TaskGroup{
+				Networks: []*NetworkResource{
+					{
+						Mode:         "bridge",
+						MBits:        10,
+						DynamicPorts: []Port{{"http", 0, 8080, ""}},
+					},
+				},
+			},
+			Expected: &TaskGroupDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeAdded,
+						Name: "Network",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeAdded,
+								Name: "MBits",
+								Old:  "",
+								New:  "10",
+							},
+							{
+								Type: DiffTypeAdded,
+								Name: "Mode",
+								Old:  "",
+								New:  "bridge",
+							},
+						},
+						Objects: []*ObjectDiff{
+							{
+								Type: DiffTypeAdded,
+								Name: "Dynamic Port",
+								Fields: []*FieldDiff{
+									{
+										Type: DiffTypeAdded,
+										Name: "Label",
+										Old:  "",
+										New:  "http",
+									},
+									{
+										Type: DiffTypeAdded,
+										Name: "To",
+										Old:  "",
+										New:  "8080",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			// EphemeralDisk added
 			Old: &TaskGroup{},
 			New: &TaskGroup{
 				EphemeralDisk: &EphemeralDisk{
-					Sticky: true,
-					SizeMB: 100,
+					Sticky:  true,
+					Migrate: true,
+					SizeMB:  100,
 				},
 			},
 			Expected: &TaskGroupDiff{
@@ -1325,6 +1511,12 @@ func TestTaskGroupDiff(t *testing.T) {
 						Type: DiffTypeAdded,
 						Name: "EphemeralDisk",
 						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeAdded,
+								Name: "Migrate",
+								Old:  "",
+								New:  "true",
+							},
 							{
 								Type: DiffTypeAdded,
 								Name: "SizeMB",
@@ -1346,8 +1538,9 @@ func TestTaskGroupDiff(t *testing.T) {
 			// EphemeralDisk deleted
 			Old: &TaskGroup{
 				EphemeralDisk: &EphemeralDisk{
-					Sticky: true,
-					SizeMB: 100,
+					Sticky:  true,
+					Migrate: true,
+					SizeMB:  100,
 				},
 			},
 			New: &TaskGroup{},
@@ -1358,6 +1551,12 @@ func TestTaskGroupDiff(t *testing.T) {
 						Type: DiffTypeDeleted,
 						Name: "EphemeralDisk",
 						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeDeleted,
+								Name: "Migrate",
+								Old:  "true",
+								New:  "",
+							},
 							{
 								Type: DiffTypeDeleted,
 								Name: "SizeMB",
@@ -1375,18 +1574,152 @@ func TestTaskGroupDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			// Volumes edited
+			Old: &TaskGroup{
+				Volumes: map[string]*VolumeRequest{
+					"data": &VolumeRequest{Name: "data", Type: "host", Source: "shared_data"},
+				},
+			},
+			New: &TaskGroup{
+				Volumes: map[string]*VolumeRequest{
+					"data": &VolumeRequest{Name: "data", Type: "host", Source: "shared_data", ReadOnly: true},
+				},
+			},
+			Expected: &TaskGroupDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeAdded,
+						Name: "Volume",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeAdded,
+								Name: "Name",
+								Old:  "",
+								New:  "data",
+							},
+							{
+								Type: DiffTypeAdded,
+								Name: "ReadOnly",
+								Old:  "",
+								New:  "true",
+							},
+							{
+								Type: DiffTypeAdded,
+								Name: "Source",
+								Old:  "",
+								New:  "shared_data",
+							},
+							{
+								Type: DiffTypeAdded,
+								Name: "Type",
+								Old:  "",
+								New:  "host",
+							},
+						},
+					},
+					{
+						Type: DiffTypeDeleted,
+						Name: "Volume",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeDeleted,
+								Name: "Name",
+								Old:  "data",
+								New:  "",
+							},
+							{
+								Type: DiffTypeDeleted,
+								Name: "ReadOnly",
+								Old:  "false",
+								New:  "",
+							},
+							{
+								Type: DiffTypeDeleted,
+								Name: "Source",
+								Old:  "shared_data",
+								New:  "",
+							},
+							{
+								Type: DiffTypeDeleted,
+								Name: "Type",
+								Old:  "host",
+								New:  "",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			// Scaling policy edited
+			Old: &TaskGroup{
+				Scaling: &ScalingPolicy{
+					Min:     1,
+					Max:     5,
+					Enabled: true,
+					Policy: map[string]interface{}{
+						"cooldown": "1m",
+					},
+				},
+			},
+			New: &TaskGroup{
+				Scaling: &ScalingPolicy{
+					Min:     1,
+					Max:     10,
+					Enabled: true,
+					Policy: map[string]interface{}{
+						"cooldown": "2m",
+					},
+				},
+			},
+			Expected: &TaskGroupDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "Scaling",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "Max",
+								Old:  "5",
+								New:  "10",
+							},
+						},
+						Objects: []*ObjectDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "Policy",
+								Fields: []*FieldDiff{
+									{
+										Type: DiffTypeEdited,
+										Name: "cooldown",
+										Old:  "1m",
+										New:  "2m",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			// EphemeralDisk edited
 			Old: &TaskGroup{
 				EphemeralDisk: &EphemeralDisk{
-					Sticky: true,
-					SizeMB: 150,
+					Sticky:  true,
+					Migrate: true,
+					SizeMB:  150,
 				},
 			},
 			New: &TaskGroup{
 				EphemeralDisk: &EphemeralDisk{
-					Sticky: false,
-					SizeMB: 90,
+					Sticky:  false,
+					Migrate: false,
+					SizeMB:  90,
 				},
 			},
 			Expected: &TaskGroupDiff{
@@ -1396,6 +1729,12 @@ func TestTaskGroupDiff(t *testing.T) {
 						Type: DiffTypeEdited,
 						Name: "EphemeralDisk",
 						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "Migrate",
+								Old:  "true",
+								New:  "false",
+							},
 							{
 								Type: DiffTypeEdited,
 								Name: "SizeMB",
@@ -1414,6 +1753,38 @@ func TestTaskGroupDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			// Migrate strategy edited
+			Old: &TaskGroup{
+				Migrate: &MigrateStrategy{
+					MaxParallel:    1,
+					MinHealthyTime: 10 * time.Second,
+				},
+			},
+			New: &TaskGroup{
+				Migrate: &MigrateStrategy{
+					MaxParallel:    2,
+					MinHealthyTime: 10 * time.Second,
+				},
+			},
+			Expected: &TaskGroupDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "Migrate",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "MaxParallel",
+								Old:  "1",
+								New:  "2",
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			// EphemeralDisk edited with context
 			Contextual: true,
@@ -1436,6 +1807,12 @@ func TestTaskGroupDiff(t *testing.T) {
 						Type: DiffTypeEdited,
 						Name: "EphemeralDisk",
 						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeNone,
+								Name: "Migrate",
+								Old:  "false",
+								New:  "false",
+							},
 							{
 								Type: DiffTypeEdited,
 								Name: "SizeMB",
@@ -1507,6 +1884,12 @@ func TestTaskGroupDiff(t *testing.T) {
 								Old:  "",
 								New:  "0",
 							},
+							{
+								Type: DiffTypeAdded,
+								Name: "ShutdownOrder",
+								Old:  "",
+								New:  "0",
+							},
 						},
 					},
 					{
@@ -1541,6 +1924,12 @@ func TestTaskGroupDiff(t *testing.T) {
 								Old:  "0",
 								New:  "",
 							},
+							{
+								Type: DiffTypeDeleted,
+								Name: "ShutdownOrder",
+								Old:  "0",
+								New:  "",
+							},
 						},
 					},
 				},
@@ -1974,6 +2363,99 @@ func TestTaskDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			// Schedule edited
+			Old: &Task{
+				Schedule: &TaskSchedule{
+					Pause:    "0 2 * * *",
+					Duration: 1 * time.Hour,
+				},
+			},
+			New: &Task{
+				Schedule: &TaskSchedule{
+					Pause:    "0 3 * * *",
+					Duration: 1 * time.Hour,
+				},
+			},
+			Expected: &TaskDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "Schedule",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "Pause",
+								Old:  "0 2 * * *",
+								New:  "0 3 * * *",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			// Lifecycle edited
+			Old: &Task{
+				Lifecycle: &TaskLifecycleConfig{
+					Hook: TaskLifecycleHookPrestart,
+				},
+			},
+			New: &Task{
+				Lifecycle: &TaskLifecycleConfig{
+					Hook:    TaskLifecycleHookPrestart,
+					Sidecar: true,
+				},
+			},
+			Expected: &TaskDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "Lifecycle",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "Sidecar",
+								Old:  "false",
+								New:  "true",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			// Consul cluster edited
+			Old: &Task{
+				Consul: &Consul{
+					Cluster: "primary",
+				},
+			},
+			New: &Task{
+				Consul: &Consul{
+					Cluster: "secondary",
+				},
+			},
+			Expected: &TaskDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "Consul",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "Cluster",
+								Old:  "primary",
+								New:  "secondary",
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			// Artifacts edited
 			Old: &Task{
@@ -2037,6 +2519,18 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "",
 								New:  "bam",
 							},
+							{
+								Type: DiffTypeAdded,
+								Name: "RetryAttempts",
+								Old:  "",
+								New:  "0",
+							},
+							{
+								Type: DiffTypeAdded,
+								Name: "RetryDelay",
+								Old:  "",
+								New:  "0",
+							},
 						},
 					},
 					{
@@ -2061,6 +2555,18 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "bar",
 								New:  "",
 							},
+							{
+								Type: DiffTypeDeleted,
+								Name: "RetryAttempts",
+								Old:  "0",
+								New:  "",
+							},
+							{
+								Type: DiffTypeDeleted,
+								Name: "RetryDelay",
+								Old:  "0",
+								New:  "",
+							},
 						},
 					},
 				},
@@ -2252,6 +2758,12 @@ func TestTaskDiff(t *testing.T) {
 												Old:  "",
 												New:  "foo",
 											},
+											{
+												Type: DiffTypeAdded,
+												Name: "To",
+												Old:  "",
+												New:  "0",
+											},
 											{
 												Type: DiffTypeAdded,
 												Name: "Value",
@@ -2270,6 +2782,12 @@ func TestTaskDiff(t *testing.T) {
 												Old:  "",
 												New:  "baz",
 											},
+											{
+												Type: DiffTypeAdded,
+												Name: "To",
+												Old:  "",
+												New:  "0",
+											},
 										},
 									},
 								},
@@ -2296,6 +2814,12 @@ func TestTaskDiff(t *testing.T) {
 												Old:  "foo",
 												New:  "",
 											},
+											{
+												Type: DiffTypeDeleted,
+												Name: "To",
+												Old:  "0",
+												New:  "",
+											},
 											{
 												Type: DiffTypeDeleted,
 												Name: "Value",
@@ -2314,6 +2838,12 @@ func TestTaskDiff(t *testing.T) {
 												Old:  "bar",
 												New:  "",
 											},
+											{
+												Type: DiffTypeDeleted,
+												Name: "To",
+												Old:  "0",
+												New:  "",
+											},
 										},
 									},
 								},
@@ -2515,12 +3045,24 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "2",
 								New:  "2",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "boom.HostNetwork",
+								Old:  "",
+								New:  "",
+							},
 							{
 								Type: DiffTypeNone,
 								Name: "boom.Label",
 								Old:  "boom_port",
 								New:  "boom_port",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "boom.To",
+								Old:  "0",
+								New:  "0",
+							},
 							{
 								Type: DiffTypeNone,
 								Name: "boom.Value",
@@ -2664,6 +3206,12 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "foo",
 								New:  "bar",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "Provider",
+								Old:  "",
+								New:  "",
+							},
 						},
 					},
 				},
@@ -2802,6 +3350,12 @@ func TestTaskDiff(t *testing.T) {
 										Old:  "",
 										New:  "http",
 									},
+									{
+										Type: DiffTypeAdded,
+										Name: "Readiness",
+										Old:  "",
+										New:  "false",
+									},
 									{
 										Type: DiffTypeAdded,
 										Name: "Timeout",
@@ -2850,6 +3404,12 @@ func TestTaskDiff(t *testing.T) {
 										Old:  "http",
 										New:  "",
 									},
+									{
+										Type: DiffTypeDeleted,
+										Name: "Readiness",
+										Old:  "false",
+										New:  "",
+									},
 									{
 										Type: DiffTypeDeleted,
 										Name: "Timeout",
@@ -2931,6 +3491,12 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "",
 								New:  "",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "Provider",
+								Old:  "",
+								New:  "",
+							},
 						},
 						Objects: []*ObjectDiff{
 							{
@@ -2961,6 +3527,12 @@ func TestTaskDiff(t *testing.T) {
 										Old:  "foo",
 										New:  "foo",
 									},
+									{
+										Type: DiffTypeNone,
+										Name: "OnUpdate",
+										Old:  "",
+										New:  "",
+									},
 									{
 										Type: DiffTypeNone,
 										Name: "Path",
@@ -2979,6 +3551,12 @@ func TestTaskDiff(t *testing.T) {
 										Old:  "http",
 										New:  "http",
 									},
+									{
+										Type: DiffTypeNone,
+										Name: "Readiness",
+										Old:  "false",
+										New:  "false",
+									},
 									{
 										Type: DiffTypeNone,
 										Name: "Timeout",
@@ -3166,6 +3744,12 @@ func TestTaskDiff(t *testing.T) {
 						Type: DiffTypeEdited,
 						Name: "Vault",
 						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeNone,
+								Name: "Cluster",
+								Old:  "",
+								New:  "",
+							},
 							{
 								Type: DiffTypeNone,
 								Name: "Env",
@@ -1,9 +1,14 @@
 package structs
 
 import (
+	"crypto/hmac"
 	crand "crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
 	"fmt"
 	"math"
+	"path"
+	"strings"
 )
 
 // RemoveAllocs is used to remove any allocs with the given IDs
@@ -91,6 +96,9 @@ func AllocsFit(node *Node, allocs []*Allocation, netIdx *NetworkIndex) (bool, st
 		} else {
 			return false, "", nil, fmt.Errorf("allocation %q has no resources set", alloc.ID)
 		}
+
+		// Add the memory backing the tmpfs mounts of the tasks
+		used.MemoryMB += len(alloc.TaskResources) * node.TaskTmpfsMB
 	}
 
 	// Check that the node resources are a super set of those
@@ -113,6 +121,11 @@ func AllocsFit(node *Node, allocs []*Allocation, netIdx *NetworkIndex) (bool, st
 		return false, "bandwidth exceeded", used, nil
 	}
 
+	// Check that the devices requested by the tasks are available
+	if fit, dimension := DevicesFit(node.Devices, allocsRequestedDevices(allocs)); !fit {
+		return false, dimension, used, nil
+	}
+
 	// Allocations fit!
 	return true, "", used, nil
 }
@@ -154,6 +167,13 @@ func ScoreFit(node *Node, util *Resources) float64 {
 	return score
 }
 
+// ScoreFitSpread is used to score the fit of the allocations on a node when
+// spreading them across the cluster. It inverts ScoreFit, so that the emptier
+// the node is the higher it scores, within the same 0 to 18 range.
+func ScoreFitSpread(node *Node, util *Resources) float64 {
+	return 18.0 - ScoreFit(node, util)
+}
+
 // GenerateUUID is used to generate a random UUID
 func GenerateUUID() string {
 	buf := make([]byte, 16)
@@ -269,6 +289,41 @@ func SliceStringIsSubset(larger, smaller []string) (bool, []string) {
 	return subset, offending
 }
 
+// SliceStringToSet returns the set of the given strings
+func SliceStringToSet(s []string) map[string]struct{} {
+	m := make(map[string]struct{}, len(s))
+	for _, k := range s {
+		m[k] = struct{}{}
+	}
+	return m
+}
+
+// SliceSetDisjoint returns whether the two sets of strings are disjoint. If
+// they are not, the offending elements are returned.
+func SliceSetDisjoint(first, second []string) (bool, []string) {
+	contained := make(map[string]struct{}, len(first))
+	for _, k := range first {
+		contained[k] = struct{}{}
+	}
+
+	offending := make(map[string]struct{})
+	for _, k := range second {
+		if _, ok := contained[k]; ok {
+			offending[k] = struct{}{}
+		}
+	}
+
+	if len(offending) == 0 {
+		return true, nil
+	}
+
+	flattened := make([]string, 0, len(offending))
+	for k := range offending {
+		flattened = append(flattened, k)
+	}
+	return false, flattened
+}
+
 // VaultPoliciesSet takes the structure returned by VaultPolicies and returns
 // the set of required policies
 func VaultPoliciesSet(policies map[string]map[string]*Vault) []string {
@@ -288,3 +343,88 @@ func VaultPoliciesSet(policies map[string]map[string]*Vault) []string {
 	}
 	return flattened
 }
+
+// VaultPoliciesByCluster takes the structure returned by VaultPolicies and
+// splits it by the name of the Vault cluster the tasks derive their tokens
+// from
+func VaultPoliciesByCluster(policies map[string]map[string]*Vault) map[string]map[string]map[string]*Vault {
+	clusters := make(map[string]map[string]map[string]*Vault)
+
+	for tg, tgp := range policies {
+		for task, tp := range tgp {
+			cluster := tp.ClusterName()
+			if _, ok := clusters[cluster]; !ok {
+				clusters[cluster] = make(map[string]map[string]*Vault)
+			}
+			if _, ok := clusters[cluster][tg]; !ok {
+				clusters[cluster][tg] = make(map[string]*Vault)
+			}
+			clusters[cluster][tg][task] = tp
+		}
+	}
+	return clusters
+}
+
+// IsDatacenterPattern returns whether the datacenter given in a job is a glob
+// pattern rather than an exact datacenter name.
+func IsDatacenterPattern(dc string) bool {
+	return strings.ContainsAny(dc, "*?[")
+}
+
+// DatacenterMatches returns whether the datacenter matches the given
+// datacenter or glob pattern. Malformed patterns never match.
+func DatacenterMatches(pattern, dc string) bool {
+	if !IsDatacenterPattern(pattern) {
+		return pattern == dc
+	}
+	matched, err := path.Match(pattern, dc)
+	return err == nil && matched
+}
+
+// GenerateMigrateToken returns a token authorizing the migration of the data
+// of the allocation off the node with the secret ID
+func GenerateMigrateToken(allocID, nodeSecretID string) string {
+	h := hmac.New(sha512.New, []byte(nodeSecretID))
+	h.Write([]byte(allocID))
+	return base64.URLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// CompareMigrateToken returns whether the token authorizes the migration of
+// the data of the allocation off the node with the secret ID
+func CompareMigrateToken(allocID, nodeSecretID, token string) bool {
+	expected := GenerateMigrateToken(allocID, nodeSecretID)
+	return hmac.Equal([]byte(expected), []byte(token))
+}
+
+// GenerateCSIControllerToken returns a token authorizing the servers to call
+// the controller plugin with the ID running on the node with the secret ID
+func GenerateCSIControllerToken(pluginID, nodeSecretID string) string {
+	return generateCSIPluginToken("csi-controller:"+pluginID, nodeSecretID)
+}
+
+// CompareCSIControllerToken returns whether the token authorizes calls of the
+// controller plugin with the ID running on the node with the secret ID
+func CompareCSIControllerToken(pluginID, nodeSecretID, token string) bool {
+	expected := GenerateCSIControllerToken(pluginID, nodeSecretID)
+	return hmac.Equal([]byte(expected), []byte(token))
+}
+
+// GenerateCSINodeToken returns a token authorizing the servers to call the
+// node plugin with the ID running on the node with the secret ID
+func GenerateCSINodeToken(pluginID, nodeSecretID string) string {
+	return generateCSIPluginToken("csi-node:"+pluginID, nodeSecretID)
+}
+
+// CompareCSINodeToken returns whether the token authorizes calls of the node
+// plugin with the ID running on the node with the secret ID
+func CompareCSINodeToken(pluginID, nodeSecretID, token string) bool {
+	expected := GenerateCSINodeToken(pluginID, nodeSecretID)
+	return hmac.Equal([]byte(expected), []byte(token))
+}
+
+// generateCSIPluginToken signs the plugin with the secret ID of the node
+func generateCSIPluginToken(plugin, nodeSecretID string) string {
+	h := hmac.New(sha512.New, []byte(nodeSecretID))
+	h.Write([]byte(plugin))
+	return base64.URLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -102,7 +102,7 @@ func TestAllocsFit_PortsOvercommitted(t *testing.T) {
 						Device:        "eth0",
 						IP:            "10.0.0.1",
 						MBits:         50,
-						ReservedPorts: []Port{{"main", 8000}},
+						ReservedPorts: []Port{{"main", 8000, 0, ""}},
 					},
 				},
 			},
@@ -153,7 +153,7 @@ func TestAllocsFit(t *testing.T) {
 					Device:        "eth0",
 					IP:            "10.0.0.1",
 					MBits:         50,
-					ReservedPorts: []Port{{"main", 80}},
+					ReservedPorts: []Port{{"main", 80, 0, ""}},
 				},
 			},
 		},
@@ -170,7 +170,7 @@ func TestAllocsFit(t *testing.T) {
 					Device:        "eth0",
 					IP:            "10.0.0.1",
 					MBits:         50,
-					ReservedPorts: []Port{{"main", 8000}},
+					ReservedPorts: []Port{{"main", 8000, 0, ""}},
 				},
 			},
 		},
@@ -212,6 +212,120 @@ func TestAllocsFit(t *testing.T) {
 
 }
 
+func TestAllocsFit_TaskTmpfs(t *testing.T) {
+	n := &Node{
+		Resources: &Resources{
+			CPU:      2000,
+			MemoryMB: 2000,
+		},
+		TaskTmpfsMB: 64,
+	}
+
+	a1 := &Allocation{
+		Resources: &Resources{
+			CPU:      1000,
+			MemoryMB: 896,
+		},
+		TaskResources: map[string]*Resources{
+			"web": &Resources{
+				CPU:      500,
+				MemoryMB: 448,
+			},
+			"sidecar": &Resources{
+				CPU:      500,
+				MemoryMB: 448,
+			},
+		},
+	}
+
+	// Should fit one allocation with the tmpfs of its two tasks
+	fit, _, used, err := AllocsFit(n, []*Allocation{a1}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !fit {
+		t.Fatalf("Bad")
+	}
+	if used.MemoryMB != 1024 {
+		t.Fatalf("bad: %#v", used)
+	}
+
+	// Should not fit the second allocation once the tmpfs is accounted
+	fit, dim, used, err := AllocsFit(n, []*Allocation{a1, a1}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if fit || dim != "memory exhausted" {
+		t.Fatalf("bad: %v %q", fit, dim)
+	}
+	if used.MemoryMB != 2048 {
+		t.Fatalf("bad: %#v", used)
+	}
+}
+
+func TestAllocsFit_Devices(t *testing.T) {
+	n := &Node{
+		Resources: &Resources{
+			CPU:      2000,
+			MemoryMB: 2000,
+		},
+		Devices: []*NodeDeviceResource{
+			{
+				Vendor: "nvidia",
+				Type:   "gpu",
+				Name:   "1080ti",
+				Instances: []*NodeDevice{
+					{ID: "gpu-0", Healthy: true},
+					{ID: "gpu-1", Healthy: true},
+					{ID: "gpu-2", Healthy: false},
+				},
+			},
+		},
+	}
+
+	a1 := &Allocation{
+		Resources: &Resources{
+			CPU:      500,
+			MemoryMB: 500,
+		},
+		TaskResources: map[string]*Resources{
+			"web": &Resources{
+				CPU:      500,
+				MemoryMB: 500,
+				Devices:  []*RequestedDevice{{Name: "nvidia/gpu", Count: 1}},
+			},
+		},
+	}
+
+	// Should fit two allocations on the healthy devices
+	fit, _, _, err := AllocsFit(n, []*Allocation{a1, a1}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !fit {
+		t.Fatalf("Bad")
+	}
+
+	// Should not fit a third allocation as the last device is unhealthy
+	fit, dim, _, err := AllocsFit(n, []*Allocation{a1, a1, a1}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if fit || dim != `devices "nvidia/gpu" exhausted` {
+		t.Fatalf("bad: %v %q", fit, dim)
+	}
+
+	// Should not fit devices the node doesn't have
+	a1.TaskResources["web"].Devices[0].Name = "amd/gpu"
+	fit, _, _, err = AllocsFit(n, []*Allocation{a1}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if fit {
+		t.Fatalf("Bad")
+	}
+}
+
 func TestScoreFit(t *testing.T) {
 	node := &Node{}
 	node.Resources = &Resources{
@@ -284,3 +398,74 @@ func TestSliceStringIsSubset(t *testing.T) {
 		t.Fatalf("bad %v %v", sub, offending)
 	}
 }
+
+func TestDatacenterMatches(t *testing.T) {
+	cases := []struct {
+		Pattern string
+		DC      string
+		Match   bool
+	}{
+		{"dc1", "dc1", true},
+		{"dc1", "dc2", false},
+		{"*", "dc1", true},
+		{"us-east-*", "us-east-1", true},
+		{"us-east-*", "us-west-1", false},
+		{"dc?", "dc3", true},
+		{"dc[", "dc[", false},
+	}
+
+	for _, c := range cases {
+		if m := DatacenterMatches(c.Pattern, c.DC); m != c.Match {
+			t.Fatalf("DatacenterMatches(%q, %q) returned %v; want %v", c.Pattern, c.DC, m, c.Match)
+		}
+	}
+}
+
+func TestMigrateToken(t *testing.T) {
+	allocID := GenerateUUID()
+	nodeSecret := GenerateUUID()
+	token := GenerateMigrateToken(allocID, nodeSecret)
+
+	if !CompareMigrateToken(allocID, nodeSecret, token) {
+		t.Fatalf("token doesn't match")
+	}
+	if CompareMigrateToken(GenerateUUID(), nodeSecret, token) {
+		t.Fatalf("token matches another allocation")
+	}
+	if CompareMigrateToken(allocID, GenerateUUID(), token) {
+		t.Fatalf("token matches another node")
+	}
+}
+
+func TestCSIControllerToken(t *testing.T) {
+	nodeSecret := GenerateUUID()
+	token := GenerateCSIControllerToken("ebs", nodeSecret)
+
+	if !CompareCSIControllerToken("ebs", nodeSecret, token) {
+		t.Fatalf("token doesn't match")
+	}
+	if CompareCSIControllerToken("other", nodeSecret, token) {
+		t.Fatalf("token matches another plugin")
+	}
+	if CompareCSIControllerToken("ebs", GenerateUUID(), token) {
+		t.Fatalf("token matches another node")
+	}
+	if CompareMigrateToken("ebs", nodeSecret, token) {
+		t.Fatalf("token is a migrate token")
+	}
+}
+
+func TestCSINodeToken(t *testing.T) {
+	nodeSecret := GenerateUUID()
+	token := GenerateCSINodeToken("ebs", nodeSecret)
+
+	if !CompareCSINodeToken("ebs", nodeSecret, token) {
+		t.Fatalf("token doesn't match")
+	}
+	if CompareCSINodeToken("ebs", GenerateUUID(), token) {
+		t.Fatalf("token matches another node")
+	}
+	if CompareCSIControllerToken("ebs", nodeSecret, token) {
+		t.Fatalf("token is a controller token")
+	}
+}
@@ -102,6 +102,15 @@ func (idx *NetworkIndex) AddAllocs(allocs []*Allocation) (collide bool) {
 				collide = true
 			}
 		}
+
+		// Add the network shared by the tasks of the allocation
+		if alloc.SharedResources != nil {
+			for _, n := range alloc.SharedResources.Networks {
+				if idx.AddReserved(n) {
+					collide = true
+				}
+			}
+		}
 	}
 	return
 }
@@ -170,8 +179,21 @@ func (idx *NetworkIndex) yieldIP(cb func(net *NetworkResource, ip net.IP) bool)
 // AssignNetwork is used to assign network resources given an ask.
 // If the ask cannot be satisfied, returns nil
 func (idx *NetworkIndex) AssignNetwork(ask *NetworkResource) (out *NetworkResource, err error) {
+	hostNetwork, err := ask.PortsHostNetwork()
+	if err != nil {
+		return nil, err
+	}
+
 	err = fmt.Errorf("no networks available")
+	if hostNetwork != "" {
+		err = fmt.Errorf("no networks available in host network %q", hostNetwork)
+	}
 	idx.yieldIP(func(n *NetworkResource, ip net.IP) (stop bool) {
+		// Only use addresses of the host network the ports bind to
+		if n.HostNetwork != hostNetwork {
+			return
+		}
+
 		// Convert the IP to a string
 		ipStr := ip.String()
 
@@ -202,11 +224,13 @@ func (idx *NetworkIndex) AssignNetwork(ask *NetworkResource) (out *NetworkResour
 
 		// Create the offer
 		offer := &NetworkResource{
+			Mode:          ask.Mode,
 			Device:        n.Device,
 			IP:            ipStr,
 			MBits:         ask.MBits,
 			ReservedPorts: ask.ReservedPorts,
 			DynamicPorts:  ask.DynamicPorts,
+			HostNetwork:   n.HostNetwork,
 		}
 
 		// Try to stochastically pick the dynamic ports as it is faster and
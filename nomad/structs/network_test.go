@@ -14,7 +14,7 @@ func TestNetworkIndex_Overcommitted(t *testing.T) {
 		Device:        "eth0",
 		IP:            "192.168.0.100",
 		MBits:         505,
-		ReservedPorts: []Port{{"one", 8000}, {"two", 9000}},
+		ReservedPorts: []Port{{"one", 8000, 0, ""}, {"two", 9000, 0, ""}},
 	}
 	collide := idx.AddReserved(reserved)
 	if collide {
@@ -65,7 +65,7 @@ func TestNetworkIndex_SetNode(t *testing.T) {
 				&NetworkResource{
 					Device:        "eth0",
 					IP:            "192.168.0.100",
-					ReservedPorts: []Port{{"ssh", 22}},
+					ReservedPorts: []Port{{"ssh", 22, 0, ""}},
 					MBits:         1,
 				},
 			},
@@ -101,7 +101,7 @@ func TestNetworkIndex_AddAllocs(t *testing.T) {
 							Device:        "eth0",
 							IP:            "192.168.0.100",
 							MBits:         20,
-							ReservedPorts: []Port{{"one", 8000}, {"two", 9000}},
+							ReservedPorts: []Port{{"one", 8000, 0, ""}, {"two", 9000, 0, ""}},
 						},
 					},
 				},
@@ -115,7 +115,7 @@ func TestNetworkIndex_AddAllocs(t *testing.T) {
 							Device:        "eth0",
 							IP:            "192.168.0.100",
 							MBits:         50,
-							ReservedPorts: []Port{{"one", 10000}},
+							ReservedPorts: []Port{{"one", 10000, 0, ""}},
 						},
 					},
 				},
@@ -148,7 +148,7 @@ func TestNetworkIndex_AddReserved(t *testing.T) {
 		Device:        "eth0",
 		IP:            "192.168.0.100",
 		MBits:         20,
-		ReservedPorts: []Port{{"one", 8000}, {"two", 9000}},
+		ReservedPorts: []Port{{"one", 8000, 0, ""}, {"two", 9000, 0, ""}},
 	}
 	collide := idx.AddReserved(reserved)
 	if collide {
@@ -189,7 +189,7 @@ func TestNetworkIndex_yieldIP(t *testing.T) {
 				&NetworkResource{
 					Device:        "eth0",
 					IP:            "192.168.0.100",
-					ReservedPorts: []Port{{"ssh", 22}},
+					ReservedPorts: []Port{{"ssh", 22, 0, ""}},
 					MBits:         1,
 				},
 			},
@@ -227,7 +227,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 				&NetworkResource{
 					Device:        "eth0",
 					IP:            "192.168.0.100",
-					ReservedPorts: []Port{{"ssh", 22}},
+					ReservedPorts: []Port{{"ssh", 22, 0, ""}},
 					MBits:         1,
 				},
 			},
@@ -244,7 +244,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 							Device:        "eth0",
 							IP:            "192.168.0.100",
 							MBits:         20,
-							ReservedPorts: []Port{{"one", 8000}, {"two", 9000}},
+							ReservedPorts: []Port{{"one", 8000, 0, ""}, {"two", 9000, 0, ""}},
 						},
 					},
 				},
@@ -258,7 +258,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 							Device:        "eth0",
 							IP:            "192.168.0.100",
 							MBits:         50,
-							ReservedPorts: []Port{{"main", 10000}},
+							ReservedPorts: []Port{{"main", 10000, 0, ""}},
 						},
 					},
 				},
@@ -269,7 +269,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 
 	// Ask for a reserved port
 	ask := &NetworkResource{
-		ReservedPorts: []Port{{"main", 8000}},
+		ReservedPorts: []Port{{"main", 8000, 0, ""}},
 	}
 	offer, err := idx.AssignNetwork(ask)
 	if err != nil {
@@ -281,14 +281,14 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 	if offer.IP != "192.168.0.101" {
 		t.Fatalf("bad: %#v", offer)
 	}
-	rp := Port{"main", 8000}
+	rp := Port{"main", 8000, 0, ""}
 	if len(offer.ReservedPorts) != 1 || offer.ReservedPorts[0] != rp {
 		t.Fatalf("bad: %#v", offer)
 	}
 
 	// Ask for dynamic ports
 	ask = &NetworkResource{
-		DynamicPorts: []Port{{"http", 0}, {"https", 0}, {"admin", 0}},
+		DynamicPorts: []Port{{"http", 0, 0, ""}, {"https", 0, 0, ""}, {"admin", 0, 0, ""}},
 	}
 	offer, err = idx.AssignNetwork(ask)
 	if err != nil {
@@ -311,8 +311,8 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 
 	// Ask for reserved + dynamic ports
 	ask = &NetworkResource{
-		ReservedPorts: []Port{{"main", 2345}},
-		DynamicPorts:  []Port{{"http", 0}, {"https", 0}, {"admin", 0}},
+		ReservedPorts: []Port{{"main", 2345, 0, ""}},
+		DynamicPorts:  []Port{{"http", 0, 0, ""}, {"https", 0, 0, ""}, {"admin", 0, 0, ""}},
 	}
 	offer, err = idx.AssignNetwork(ask)
 	if err != nil {
@@ -325,7 +325,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 		t.Fatalf("bad: %#v", offer)
 	}
 
-	rp = Port{"main", 2345}
+	rp = Port{"main", 2345, 0, ""}
 	if len(offer.ReservedPorts) != 1 || offer.ReservedPorts[0] != rp {
 		t.Fatalf("bad: %#v", offer)
 	}
@@ -377,7 +377,7 @@ func TestNetworkIndex_AssignNetwork_Dynamic_Contention(t *testing.T) {
 
 	// Ask for dynamic ports
 	ask := &NetworkResource{
-		DynamicPorts: []Port{{"http", 0}},
+		DynamicPorts: []Port{{"http", 0, 0, ""}},
 	}
 	offer, err := idx.AssignNetwork(ask)
 	if err != nil {
@@ -397,6 +397,71 @@ func TestNetworkIndex_AssignNetwork_Dynamic_Contention(t *testing.T) {
 	}
 }
 
+func TestNetworkIndex_AssignNetwork_HostNetwork(t *testing.T) {
+	idx := NewNetworkIndex()
+	n := &Node{
+		Resources: &Resources{
+			Networks: []*NetworkResource{
+				&NetworkResource{
+					Device: "eth0",
+					CIDR:   "192.168.0.100/32",
+					MBits:  1000,
+				},
+				&NetworkResource{
+					Device:      "eth1",
+					CIDR:        "10.0.0.5/32",
+					MBits:       1000,
+					HostNetwork: "private",
+				},
+			},
+		},
+	}
+	idx.SetNode(n)
+
+	// Ports without a host network use the default network
+	ask := &NetworkResource{
+		DynamicPorts: []Port{{"http", 0, 0, ""}},
+	}
+	offer, err := idx.AssignNetwork(ask)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if offer.IP != "192.168.0.100" || offer.Device != "eth0" || offer.HostNetwork != "" {
+		t.Fatalf("bad: %#v", offer)
+	}
+
+	// Ports binding to a host network use its address
+	ask = &NetworkResource{
+		ReservedPorts: []Port{{"db", 5432, 0, "private"}},
+		DynamicPorts:  []Port{{"admin", 0, 0, "private"}},
+	}
+	offer, err = idx.AssignNetwork(ask)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if offer.IP != "10.0.0.5" || offer.Device != "eth1" || offer.HostNetwork != "private" {
+		t.Fatalf("bad: %#v", offer)
+	}
+
+	// Ports binding to an unknown host network can not be placed
+	ask = &NetworkResource{
+		DynamicPorts: []Port{{"http", 0, 0, "public"}},
+	}
+	offer, err = idx.AssignNetwork(ask)
+	if err == nil || err.Error() != `no networks available in host network "public"` || offer != nil {
+		t.Fatalf("bad: %#v %v", offer, err)
+	}
+
+	// Ports of a network can not bind to different host networks
+	ask = &NetworkResource{
+		ReservedPorts: []Port{{"db", 5432, 0, "private"}},
+		DynamicPorts:  []Port{{"http", 0, 0, ""}},
+	}
+	if _, err := idx.AssignNetwork(ask); err == nil {
+		t.Fatalf("expected error for mixed host networks")
+	}
+}
+
 func TestIntContains(t *testing.T) {
 	l := []int{1, 2, 10, 20}
 	if isPortReserved(l, 50) {
@@ -42,7 +42,7 @@ func (n *Node) ComputeClass() error {
 // included in the computed node class.
 func (n Node) HashInclude(field string, v interface{}) (bool, error) {
 	switch field {
-	case "Datacenter", "Attributes", "Meta", "NodeClass":
+	case "Datacenter", "Attributes", "Meta", "NodeClass", "HostVolumes":
 		return true, nil
 	default:
 		return false, nil
@@ -60,6 +60,8 @@ func (n Node) HashIncludeMap(field string, k, v interface{}) (bool, error) {
 	switch field {
 	case "Meta", "Attributes":
 		return !IsUniqueNamespace(key), nil
+	case "HostVolumes":
+		return true, nil
 	default:
 		return false, fmt.Errorf("unexpected map field: %v", field)
 	}
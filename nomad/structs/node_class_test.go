@@ -181,6 +181,36 @@ func TestNode_ComputedClass_Meta(t *testing.T) {
 	}
 }
 
+func TestNode_ComputedClass_HostVolumes(t *testing.T) {
+	// Create a node and gets it computed class
+	n := testNode()
+	if err := n.ComputeClass(); err != nil {
+		t.Fatalf("ComputeClass() failed: %v", err)
+	}
+	old := n.ComputedClass
+
+	// Add a host volume and compute the class again.
+	n.HostVolumes = map[string]*ClientHostVolumeConfig{
+		"data": &ClientHostVolumeConfig{Name: "data", Path: "/srv/data"},
+	}
+	if err := n.ComputeClass(); err != nil {
+		t.Fatalf("ComputeClass() failed: %v", err)
+	}
+	if old == n.ComputedClass {
+		t.Fatal("ComputeClass() ignored host volume change")
+	}
+	old = n.ComputedClass
+
+	// Make the host volume read-only and compute the class again.
+	n.HostVolumes["data"].ReadOnly = true
+	if err := n.ComputeClass(); err != nil {
+		t.Fatalf("ComputeClass() failed: %v", err)
+	}
+	if old == n.ComputedClass {
+		t.Fatal("ComputeClass() ignored read-only host volume")
+	}
+}
+
 func TestNode_EscapedConstraints(t *testing.T) {
 	// Non-escaped constraints
 	ne1 := &Constraint{
@@ -10,9 +10,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"path"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -29,8 +32,14 @@ import (
 )
 
 var (
-	ErrNoLeader     = fmt.Errorf("No cluster leader")
-	ErrNoRegionPath = fmt.Errorf("No path to region")
+	ErrNoLeader         = fmt.Errorf("No cluster leader")
+	ErrNoRegionPath     = fmt.Errorf("No path to region")
+	ErrTokenNotFound    = errors.New("ACL token not found")
+	ErrPermissionDenied = errors.New("Permission denied")
+	ErrCASConflict      = errors.New("Check-and-set index conflict")
+	ErrFeatureDisabled  = errors.New("Feature disabled")
+	ErrInvalidFilter    = errors.New("Invalid filter")
+	ErrEventsEvicted    = errors.New("Events evicted")
 )
 
 type MessageType uint8
@@ -49,6 +58,35 @@ const (
 	ReconcileJobSummariesRequestType
 	VaultAccessorRegisterRequestType
 	VaultAccessorDegisterRequestType
+	NamespaceUpsertRequestType
+	NamespaceDeleteRequestType
+	QuotaSpecUpsertRequestType
+	QuotaSpecDeleteRequestType
+	ACLPolicyUpsertRequestType
+	ACLPolicyDeleteRequestType
+	ACLTokenUpsertRequestType
+	ACLTokenDeleteRequestType
+	ACLTokenBootstrapRequestType
+	WorkloadIdentityKeyUpsertRequestType
+	ServiceRegistrationUpsertRequestType
+	ServiceRegistrationDeleteRequestType
+	RootKeyUpsertRequestType
+	VariablesUpsertRequestType
+	VariablesDeleteRequestType
+	VariablesRekeyRequestType
+	ScalingEventRegisterRequestType
+	NodeUpdateEligibilityRequestType
+	MultiregionRolloutUpsertRequestType
+	SnapshotRestoreRequestType
+	AutopilotSetConfigRequestType
+	SchedulerSetConfigRequestType
+	MaintenanceWindowUpsertRequestType
+	MaintenanceWindowDeleteRequestType
+	NodeReliabilityUpdateRequestType
+	CSIVolumeRegisterRequestType
+	CSIVolumeDeregisterRequestType
+	CSIVolumeClaimRequestType
+	AllocUpdateDesiredTransitionRequestType
 )
 
 const (
@@ -100,12 +138,45 @@ type QueryOptions struct {
 
 	// If set, used as prefix for resource list searches
 	Prefix string
+
+	// Namespace is the namespace to scope the query to. If empty the
+	// default namespace is used.
+	Namespace string
+
+	// PerPage is the maximum number of objects returned by list queries. If
+	// zero, all the objects are returned.
+	PerPage int32
+
+	// NextToken is the token returned by a previous page of a list query to
+	// resume the query from
+	NextToken string
+
+	// Filter is the expression the objects returned by list queries must
+	// match
+	Filter string
+
+	// AuthToken is secret portion of the ACL token used for the request
+	AuthToken string
 }
 
 func (q QueryOptions) RequestRegion() string {
 	return q.Region
 }
 
+// RequestNamespace returns the namespace the query is scoped to, falling
+// back to the default namespace.
+func (q QueryOptions) RequestNamespace() string {
+	if q.Namespace == "" {
+		return DefaultNamespace
+	}
+	return q.Namespace
+}
+
+// RequestToken returns the secret ID of the ACL token of the query
+func (q QueryOptions) RequestToken() string {
+	return q.AuthToken
+}
+
 // QueryOption only applies to reads, so always true
 func (q QueryOptions) IsRead() bool {
 	return true
@@ -118,6 +189,13 @@ func (q QueryOptions) AllowStaleRead() bool {
 type WriteRequest struct {
 	// The target region for this write
 	Region string
+
+	// Namespace is the target namespace for this write. If empty the
+	// default namespace is used.
+	Namespace string
+
+	// AuthToken is secret portion of the ACL token used for the request
+	AuthToken string
 }
 
 func (w WriteRequest) RequestRegion() string {
@@ -125,6 +203,20 @@ func (w WriteRequest) RequestRegion() string {
 	return w.Region
 }
 
+// RequestNamespace returns the namespace the write targets, falling back to
+// the default namespace.
+func (w WriteRequest) RequestNamespace() string {
+	if w.Namespace == "" {
+		return DefaultNamespace
+	}
+	return w.Namespace
+}
+
+// RequestToken returns the secret ID of the ACL token of the write
+func (w WriteRequest) RequestToken() string {
+	return w.AuthToken
+}
+
 // WriteRequest only applies to writes, always false
 func (w WriteRequest) IsRead() bool {
 	return false
@@ -147,6 +239,10 @@ type QueryMeta struct {
 
 	// Used to indicate if there is a known leader node
 	KnownLeader bool
+
+	// NextToken is set if a list query has more results than the page, and
+	// is used to request the next page
+	NextToken string
 }
 
 // WriteMeta allows a write response to include potentially
@@ -194,13 +290,55 @@ type NodeServerInfo struct {
 type NodeUpdateStatusRequest struct {
 	NodeID string
 	Status string
+
+	// NodeEvent is the event to record on the node, if any
+	NodeEvent *NodeEvent
+
+	// ClientTime is the wall clock time of the client, in nanoseconds since
+	// the Unix epoch, when it sent the heartbeat. It is used to detect clock
+	// skew between the client and the servers.
+	ClientTime int64
 	WriteRequest
 }
 
 // NodeUpdateDrainRequest is used for updatin the drain status
 type NodeUpdateDrainRequest struct {
 	NodeID string
-	Drain  bool
+
+	// DrainStrategy is the strategy to drain the node with. A nil strategy
+	// stops draining the node, which is then eligible for scheduling again.
+	DrainStrategy *DrainStrategy
+
+	// NodeEvent is the event to record on the node, if any
+	NodeEvent *NodeEvent
+
+	WriteRequest
+}
+
+// NodeUpdateEligibilityRequest is used for updating the scheduling
+// eligibility of a node
+type NodeUpdateEligibilityRequest struct {
+	NodeID      string
+	Eligibility string
+
+	// NodeEvent is the event to record on the node, if any
+	NodeEvent *NodeEvent
+
+	WriteRequest
+}
+
+// NodeReliabilityUpdateRequest is used by the leader to add to the
+// reliability penalties of nodes
+type NodeReliabilityUpdateRequest struct {
+	// Penalties are the penalties to add by node ID
+	Penalties map[string]float64
+
+	// UpdateTime is the time the penalties are added at
+	UpdateTime int64
+
+	// HalfLife is the half-life the existing penalties decay with
+	HalfLife time.Duration
+
 	WriteRequest
 }
 
@@ -228,6 +366,18 @@ type JobRegisterRequest struct {
 	EnforceIndex   bool
 	JobModifyIndex uint64
 
+	// RegionForwarded is set when a multi-region job is registered in its
+	// other regions, so that the registration isn't forwarded again.
+	RegionForwarded bool
+
+	// DeferEval is set to register a job without creating an evaluation for
+	// it, when its multi-region rollout has yet to reach the region
+	DeferEval bool
+
+	// Submission is the source the job was parsed from, if the submitter
+	// sent it
+	Submission *JobSubmission
+
 	WriteRequest
 }
 
@@ -235,6 +385,12 @@ type JobRegisterRequest struct {
 // to deregister a job as being a schedulable entity.
 type JobDeregisterRequest struct {
 	JobID string
+
+	// Purge controls whether the deregister purges the job from the system or
+	// whether the job is just marked as stopped and will be removed by the
+	// garbage collector
+	Purge bool
+
 	WriteRequest
 }
 
@@ -244,12 +400,111 @@ type JobEvaluateRequest struct {
 	WriteRequest
 }
 
+// JobTriggerRequest is used by external systems to create an evaluation of
+// a job, recording the reason they triggered it
+type JobTriggerRequest struct {
+	JobID  string
+	Reason string
+	WriteRequest
+}
+
+// MaxTriggerReasonLength is the maximum length of the reason of a custom
+// evaluation
+const MaxTriggerReasonLength = 256
+
+// JobDispatchRequest is used to dispatch an instance of a parameterized job
+type JobDispatchRequest struct {
+	JobID string
+
+	// Payload is delivered to the tasks of the dispatched job that specify a
+	// dispatch payload file
+	Payload []byte
+
+	// Meta is merged into the meta of the dispatched job
+	Meta map[string]string
+	WriteRequest
+}
+
+// JobScaleRequest is used to change the count of a task group of a job. A
+// request without a count only records a scaling event.
+type JobScaleRequest struct {
+	JobID string
+
+	// Target identifies the task group to scale by the ScalingTargetGroup key
+	Target map[string]string
+
+	// Count is the new count of the task group
+	Count *int64
+
+	// Message, Error and Meta describe the scaling event that is recorded
+	Message string
+	Error   bool
+	Meta    map[string]interface{}
+
+	// PolicyOverride allows the count to be set outside the bounds of the
+	// scaling policy of the task group
+	PolicyOverride bool
+
+	// If EnforceIndex is set then the task group will only be scaled if the
+	// passed JobModifyIndex matches the current job's index. This makes
+	// retried scaling requests idempotent.
+	EnforceIndex   bool
+	JobModifyIndex uint64
+
+	WriteRequest
+}
+
+// ScalingEventRegisterRequest is used to record a scaling event of a task
+// group
+type ScalingEventRegisterRequest struct {
+	JobID        string
+	TaskGroup    string
+	ScalingEvent *ScalingEvent
+	WriteRequest
+}
+
+// MultiregionRolloutUpsertRequest is used to create or update the rollout of
+// a multi-region job
+type MultiregionRolloutUpsertRequest struct {
+	Rollout *MultiregionRollout
+	WriteRequest
+}
+
+// ScalingPolicyListRequest is used to list the scaling policies of the task
+// groups of jobs
+type ScalingPolicyListRequest struct {
+	QueryOptions
+}
+
+// ScalingPolicySpecificRequest is used to get the scaling policy of a task
+// group
+type ScalingPolicySpecificRequest struct {
+	JobID string
+	Group string
+	QueryOptions
+}
+
 // JobSpecificRequest is used when we just need to specify a target job
 type JobSpecificRequest struct {
 	JobID string
 	QueryOptions
 }
 
+// JobVersionDiffRequest is used to compare two versions of a job
+type JobVersionDiffRequest struct {
+	JobID string
+
+	// FromVersion and ToVersion are the versions to compare. The diff
+	// describes the changes going from the former to the latter.
+	FromVersion uint64
+	ToVersion   uint64
+
+	// Contextual includes the unchanged fields of the edited objects
+	Contextual bool
+
+	QueryOptions
+}
+
 // JobListRequest is used to parameterize a list request
 type JobListRequest struct {
 	QueryOptions
@@ -333,6 +588,18 @@ type AllocUpdateRequest struct {
 	WriteRequest
 }
 
+// AllocUpdateDesiredTransitionRequest is used to set the desired transitions
+// of allocations, along with the evaluations that act on them
+type AllocUpdateDesiredTransitionRequest struct {
+	// Allocs maps the IDs of the allocations to their desired transition
+	Allocs map[string]*DesiredTransition
+
+	// Evals are the evaluations to create
+	Evals []*Evaluation
+
+	WriteRequest
+}
+
 // AllocListRequest is used to request a list of allocations
 type AllocListRequest struct {
 	QueryOptions
@@ -380,6 +647,9 @@ type VaultAccessor struct {
 	Accessor    string
 	CreationTTL int
 
+	// Cluster is the name of the Vault cluster the token was created in
+	Cluster string
+
 	// Raft Indexes
 	CreateIndex uint64
 }
@@ -391,6 +661,97 @@ type DeriveVaultTokenResponse struct {
 	QueryMeta
 }
 
+// NamespaceUpsertRequest is used to create or update a set of namespaces
+type NamespaceUpsertRequest struct {
+	Namespaces []*Namespace
+	WriteRequest
+}
+
+// NamespaceDeleteRequest is used to delete a set of namespaces
+type NamespaceDeleteRequest struct {
+	Namespaces []string
+	WriteRequest
+}
+
+// NamespaceSpecificRequest is used to query a specific namespace
+type NamespaceSpecificRequest struct {
+	Name string
+	QueryOptions
+}
+
+// NamespaceListRequest is used to request a list of namespaces
+type NamespaceListRequest struct {
+	QueryOptions
+}
+
+// QuotaSpecUpsertRequest is used to create or update a set of quota
+// specifications
+type QuotaSpecUpsertRequest struct {
+	Quotas []*QuotaSpec
+	WriteRequest
+}
+
+// QuotaSpecDeleteRequest is used to delete a set of quota specifications
+type QuotaSpecDeleteRequest struct {
+	Names []string
+	WriteRequest
+}
+
+// QuotaSpecificRequest is used to query a specific quota specification or
+// its usage
+type QuotaSpecificRequest struct {
+	Name string
+	QueryOptions
+}
+
+// QuotaSpecListRequest is used to request a list of quota specifications
+type QuotaSpecListRequest struct {
+	QueryOptions
+}
+
+// MaintenanceWindowUpsertRequest is used to create or update a set of
+// maintenance windows
+type MaintenanceWindowUpsertRequest struct {
+	Windows []*MaintenanceWindow
+	WriteRequest
+}
+
+// MaintenanceWindowDeleteRequest is used to delete a set of maintenance
+// windows
+type MaintenanceWindowDeleteRequest struct {
+	WindowIDs []string
+	WriteRequest
+}
+
+// MaintenanceWindowSpecificRequest is used to query a specific maintenance
+// window
+type MaintenanceWindowSpecificRequest struct {
+	WindowID string
+	QueryOptions
+}
+
+// MaintenanceWindowListRequest is used to request a list of maintenance
+// windows
+type MaintenanceWindowListRequest struct {
+	QueryOptions
+}
+
+// SearchRequest is used to search for the objects whose ID starts with the
+// prefix, within the context or across all contexts
+type SearchRequest struct {
+	Prefix  string
+	Context SearchContext
+	QueryOptions
+}
+
+// FuzzySearchRequest is used to search for the objects whose name or ID
+// contains the text, within the context or across all contexts
+type FuzzySearchRequest struct {
+	Text    string
+	Context SearchContext
+	QueryOptions
+}
+
 // GenericRequest is used to request where no
 // specific information is needed.
 type GenericRequest struct {
@@ -415,9 +776,23 @@ type JobRegisterResponse struct {
 	EvalID          string
 	EvalCreateIndex uint64
 	JobModifyIndex  uint64
+
+	// RegionEvalIDs are the evaluations created when registering a
+	// multi-region job in its other regions, keyed by region.
+	RegionEvalIDs map[string]string
+
 	QueryMeta
 }
 
+// JobDispatchResponse is used to respond to a job dispatch
+type JobDispatchResponse struct {
+	DispatchedJobID string
+	EvalID          string
+	EvalCreateIndex uint64
+	JobCreateIndex  uint64
+	WriteMeta
+}
+
 // JobDeregisterResponse is used to respond to a job deregistration
 type JobDeregisterResponse struct {
 	EvalID          string
@@ -433,6 +808,11 @@ type NodeUpdateResponse struct {
 	EvalCreateIndex uint64
 	NodeModifyIndex uint64
 
+	// ClockSkew is the difference between the clock of the server and the
+	// client time of the heartbeat. It is only set when it exceeds the
+	// servers' threshold.
+	ClockSkew time.Duration
+
 	// LeaderRPCAddr is the RPC address of the current Raft Leader.  If
 	// empty, the current Nomad Server is in the minority of a partition.
 	LeaderRPCAddr string
@@ -454,9 +834,41 @@ type NodeDrainUpdateResponse struct {
 	EvalIDs         []string
 	EvalCreateIndex uint64
 	NodeModifyIndex uint64
+
+	// DrainOrder is the order in which the jobs on the node will be migrated
+	// off of it. It is only populated when drain is enabled.
+	DrainOrder []*NodeDrainJob
+
+	QueryMeta
+}
+
+// NodeEligibilityUpdateResponse is used to respond to a node eligibility
+// update
+type NodeEligibilityUpdateResponse struct {
+	EvalIDs         []string
+	EvalCreateIndex uint64
+	NodeModifyIndex uint64
 	QueryMeta
 }
 
+// NodeDrainJob describes where a job was placed in the migration order of a
+// draining node. Batch jobs are drained first, followed by service jobs in
+// ascending priority and finally system jobs.
+type NodeDrainJob struct {
+	JobID     string
+	Namespace string
+	Type      string
+	Priority  int
+
+	// Rank is the migration wave the job belongs to. Jobs with a lower rank
+	// are migrated first, once the allocations of the previous waves have
+	// migrated.
+	Rank int
+
+	// Skipped marks a system job that is left running on the node.
+	Skipped bool
+}
+
 // NodeAllocsResponse is used to return allocs for a single node
 type NodeAllocsResponse struct {
 	Allocs []*Allocation
@@ -466,6 +878,11 @@ type NodeAllocsResponse struct {
 // NodeClientAllocsResponse is used to return allocs meta data for a single node
 type NodeClientAllocsResponse struct {
 	Allocs map[string]uint64
+
+	// MigrateTokens authorize the client to fetch the data of the previous
+	// allocations of its allocations from the nodes they ran on. They are
+	// keyed by the ID of the allocation and only set when ACLs are enabled.
+	MigrateTokens map[string]string
 	QueryMeta
 }
 
@@ -487,6 +904,54 @@ type SingleJobResponse struct {
 	QueryMeta
 }
 
+// JobVersionsResponse is used to return the tracked versions of a job
+type JobVersionsResponse struct {
+	// Versions are the versions of the job, most recent first
+	Versions []*Job
+	QueryMeta
+}
+
+// JobVersionDiffResponse is used to return the diff between two versions of
+// a job. The diff is nil if either version isn't tracked.
+type JobVersionDiffResponse struct {
+	Diff *JobDiff
+	QueryMeta
+}
+
+// JobSubmissionResponse is used to return the source a job was submitted from
+type JobSubmissionResponse struct {
+	Submission *JobSubmission
+	QueryMeta
+}
+
+// JobScaleStatusResponse is used to return the scaling status of a job
+type JobScaleStatusResponse struct {
+	JobScaleStatus *JobScaleStatus
+	QueryMeta
+}
+
+// JobRolloutResponse is used to return the multi-region rollout of a job
+type JobRolloutResponse struct {
+	Rollout *MultiregionRollout
+	QueryMeta
+}
+
+// ScalingPolicyListResponse is used to return the scaling policies of the
+// task groups of jobs
+type ScalingPolicyListResponse struct {
+	Policies []*ScalingPolicyListStub
+	QueryMeta
+}
+
+// SingleScalingPolicyResponse is used to return the scaling policy of a task
+// group, along with the modify index of its job which scale requests may
+// enforce
+type SingleScalingPolicyResponse struct {
+	Policy         *ScalingPolicy
+	JobModifyIndex uint64
+	QueryMeta
+}
+
 // JobSummaryResponse is used to return a single job summary
 type JobSummaryResponse struct {
 	JobSummary *JobSummary
@@ -561,6 +1026,9 @@ type SingleEvalResponse struct {
 type EvalDequeueResponse struct {
 	Eval  *Evaluation
 	Token string
+
+	// QueueWait is the time the evaluation waited to be dequeued
+	QueueWait time.Duration
 	QueryMeta
 }
 
@@ -588,6 +1056,76 @@ type EvalAllocationsResponse struct {
 	QueryMeta
 }
 
+// SingleNamespaceResponse is used to return a single namespace
+type SingleNamespaceResponse struct {
+	Namespace *Namespace
+	QueryMeta
+}
+
+// NamespaceListResponse is used for a list request
+type NamespaceListResponse struct {
+	Namespaces []*Namespace
+	QueryMeta
+}
+
+// SingleQuotaSpecResponse is used to return a single quota specification
+type SingleQuotaSpecResponse struct {
+	Quota *QuotaSpec
+	QueryMeta
+}
+
+// QuotaSpecListResponse is used for a quota specification list request
+type QuotaSpecListResponse struct {
+	Quotas []*QuotaSpec
+	QueryMeta
+}
+
+// SingleQuotaUsageResponse is used to return the usage of a quota
+type SingleQuotaUsageResponse struct {
+	Usage *QuotaUsage
+	QueryMeta
+}
+
+// MaintenanceWindowUpsertResponse is used to respond to a maintenance window
+// upsert request
+type MaintenanceWindowUpsertResponse struct {
+	// WindowIDs are the IDs of the upserted windows, in the order of the
+	// request. IDs are generated for the windows submitted without one.
+	WindowIDs []string
+	WriteMeta
+}
+
+// SingleMaintenanceWindowResponse is used to return a single maintenance
+// window
+type SingleMaintenanceWindowResponse struct {
+	Window *MaintenanceWindow
+	QueryMeta
+}
+
+// MaintenanceWindowListResponse is used for a maintenance window list request
+type MaintenanceWindowListResponse struct {
+	Windows []*MaintenanceWindow
+	QueryMeta
+}
+
+// SearchResponse is used to return the IDs of the objects matching a search
+// request, by context. The matches of a context are truncated to
+// MaxSearchResults, in which case its truncation is set.
+type SearchResponse struct {
+	Matches     map[SearchContext][]string
+	Truncations map[SearchContext]bool
+	QueryMeta
+}
+
+// FuzzySearchResponse is used to return the objects matching a fuzzy search
+// request, by context. The matches of a context are truncated to
+// MaxSearchResults, in which case its truncation is set.
+type FuzzySearchResponse struct {
+	Matches     map[SearchContext][]FuzzyMatch
+	Truncations map[SearchContext]bool
+	QueryMeta
+}
+
 // PeriodicForceResponse is used to respond to a periodic job force launch
 type PeriodicForceResponse struct {
 	EvalID          string
@@ -595,2936 +1133,7054 @@ type PeriodicForceResponse struct {
 	WriteMeta
 }
 
+// PeriodicNextLaunchesResponse is used to return the next launch times of a
+// periodic job
+type PeriodicNextLaunchesResponse struct {
+	Launches []*PeriodicLaunchTime
+	QueryMeta
+}
+
 const (
-	NodeStatusInit  = "initializing"
-	NodeStatusReady = "ready"
-	NodeStatusDown  = "down"
+	// DefaultNamespace is the namespace objects are placed in when no
+	// namespace is given. It always exists and can not be deleted.
+	DefaultNamespace = "default"
+
+	// AllNamespacesSentinel is the namespace given to list queries to
+	// return the objects of every namespace the token may access
+	AllNamespacesSentinel = "*"
 )
 
-// ShouldDrainNode checks if a given node status should trigger an
-// evaluation. Some states don't require any further action.
-func ShouldDrainNode(status string) bool {
-	switch status {
-	case NodeStatusInit, NodeStatusReady:
-		return false
-	case NodeStatusDown:
-		return true
-	default:
-		panic(fmt.Sprintf("unhandled node status %s", status))
-	}
+var (
+	// validNamespaceName is used to validate a namespace name
+	validNamespaceName = regexp.MustCompile("^[a-zA-Z0-9-]{1,128}$")
+)
+
+// NamespacedID identifies an object, such as a job, whose ID is only unique
+// within its namespace
+type NamespacedID struct {
+	ID        string
+	Namespace string
 }
 
-// ValidNodeStatus is used to check if a node status is valid
-func ValidNodeStatus(status string) bool {
-	switch status {
-	case NodeStatusInit, NodeStatusReady, NodeStatusDown:
-		return true
-	default:
-		return false
+// NewNamespacedID returns the namespaced ID of the object
+func NewNamespacedID(id, namespace string) NamespacedID {
+	return NamespacedID{
+		ID:        id,
+		Namespace: namespace,
 	}
 }
 
-// Node is a representation of a schedulable client node
-type Node struct {
-	// ID is a unique identifier for the node. It can be constructed
-	// by doing a concatenation of the Name and Datacenter as a simple
-	// approach. Alternatively a UUID may be used.
-	ID string
+func (n NamespacedID) String() string {
+	return fmt.Sprintf("%s/%s", n.Namespace, n.ID)
+}
 
-	// SecretID is an ID that is only known by the Node and the set of Servers.
-	// It is not accessible via the API and is used to authenticate nodes
-	// conducting priviledged activities.
-	SecretID string
+// Namespace allows logically grouping jobs and their associated objects so
+// that multiple teams can share a cluster without colliding.
+type Namespace struct {
+	// Name is the name of the namespace
+	Name string
 
-	// Datacenter for this node
-	Datacenter string
+	// Description is a human readable description of the namespace
+	Description string
 
-	// Node name
-	Name string
+	// Quota is the name of the quota specification limiting the resources
+	// the namespace may consume. It is optional.
+	Quota string
 
-	// HTTPAddr is the address on which the Nomad client is listening for http
-	// requests
-	HTTPAddr string
-
-	// Attributes is an arbitrary set of key/value
-	// data that can be used for constraints. Examples
-	// include "kernel.name=linux", "arch=386", "driver.docker=1",
-	// "docker.runtime=1.8.3"
-	Attributes map[string]string
-
-	// Resources is the available resources on the client.
-	// For example 'cpu=2' 'memory=2048'
-	Resources *Resources
-
-	// Reserved is the set of resources that are reserved,
-	// and should be subtracted from the total resources for
-	// the purposes of scheduling. This may be provide certain
-	// high-watermark tolerances or because of external schedulers
-	// consuming resources.
-	Reserved *Resources
-
-	// Links are used to 'link' this client to external
-	// systems. For example 'consul=foo.dc1' 'aws=i-83212'
-	// 'ami=ami-123'
-	Links map[string]string
-
-	// Meta is used to associate arbitrary metadata with this
-	// client. This is opaque to Nomad.
-	Meta map[string]string
-
-	// NodeClass is an opaque identifier used to group nodes
-	// together for the purpose of determining scheduling pressure.
-	NodeClass string
-
-	// ComputedClass is a unique id that identifies nodes with a common set of
-	// attributes and capabilities.
-	ComputedClass string
-
-	// Drain is controlled by the servers, and not the client.
-	// If true, no jobs will be scheduled to this node, and existing
-	// allocations will be drained.
-	Drain bool
-
-	// Status of this node
-	Status string
-
-	// StatusDescription is meant to provide more human useful information
-	StatusDescription string
-
-	// StatusUpdatedAt is the time stamp at which the state of the node was
-	// updated
-	StatusUpdatedAt int64
+	// NetworkPolicy restricts the networking the jobs of the namespace may
+	// request. It is optional.
+	NetworkPolicy *NamespaceNetworkPolicy
 
 	// Raft Indexes
 	CreateIndex uint64
 	ModifyIndex uint64
 }
 
-// Ready returns if the node is ready for running allocations
-func (n *Node) Ready() bool {
-	return n.Status == NodeStatusReady && !n.Drain
+// Validate validates the namespace
+func (n *Namespace) Validate() error {
+	var mErr multierror.Error
+	if !validNamespaceName.MatchString(n.Name) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid name %q. Must match regex %s", n.Name, validNamespaceName))
+	}
+	if len(n.Description) > maxNamespaceDescriptionLength {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("description longer than %d", maxNamespaceDescriptionLength))
+	}
+	if n.Quota != "" && !validNamespaceName.MatchString(n.Quota) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid quota name %q. Must match regex %s", n.Quota, validNamespaceName))
+	}
+	if n.NetworkPolicy != nil {
+		if err := n.NetworkPolicy.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("network policy: %v", err))
+		}
+	}
+	return mErr.ErrorOrNil()
 }
 
-func (n *Node) Copy() *Node {
+// Copy returns a copy of the namespace
+func (n *Namespace) Copy() *Namespace {
 	if n == nil {
 		return nil
 	}
-	nn := new(Node)
+	nn := new(Namespace)
 	*nn = *n
-	nn.Attributes = CopyMapStringString(nn.Attributes)
-	nn.Resources = nn.Resources.Copy()
-	nn.Reserved = nn.Reserved.Copy()
-	nn.Links = CopyMapStringString(nn.Links)
-	nn.Meta = CopyMapStringString(nn.Meta)
+	nn.NetworkPolicy = n.NetworkPolicy.Copy()
 	return nn
 }
 
-// TerminalStatus returns if the current status is terminal and
-// will no longer transition.
-func (n *Node) TerminalStatus() bool {
-	switch n.Status {
-	case NodeStatusDown:
-		return true
-	default:
-		return false
-	}
-}
+// MaxUnprivilegedPort is the highest port the network policies of
+// namespaces don't consider privileged
+const MaxUnprivilegedPort = 1024
 
-// Stub returns a summarized version of the node
-func (n *Node) Stub() *NodeListStub {
-	return &NodeListStub{
-		ID:                n.ID,
-		Datacenter:        n.Datacenter,
-		Name:              n.Name,
-		NodeClass:         n.NodeClass,
-		Drain:             n.Drain,
-		Status:            n.Status,
-		StatusDescription: n.StatusDescription,
-		CreateIndex:       n.CreateIndex,
-		ModifyIndex:       n.ModifyIndex,
-	}
-}
+// NamespaceNetworkPolicy restricts the networking the jobs of a namespace may
+// request, so that privileged ports and the network of the hosts are only
+// used by the jobs allowed to
+type NamespaceNetworkPolicy struct {
+	// DenyPrivilegedPorts denies static ports below MaxUnprivilegedPort
+	DenyPrivilegedPorts bool
 
-// NodeListStub is used to return a subset of job information
-// for the job list
-type NodeListStub struct {
-	ID                string
-	Datacenter        string
-	Name              string
-	NodeClass         string
-	Drain             bool
-	Status            string
-	StatusDescription string
-	CreateIndex       uint64
-	ModifyIndex       uint64
-}
+	// DenyHostNetwork denies the task group networks in host mode and the
+	// tasks configured with a host network_mode
+	DenyHostNetwork bool
 
-// Resources is used to define the resources available
-// on a client
-type Resources struct {
-	CPU      int
-	MemoryMB int `mapstructure:"memory"`
-	DiskMB   int `mapstructure:"disk"`
-	IOPS     int
-	Networks []*NetworkResource
+	// ExemptJobs are glob patterns of the IDs of the jobs the policy doesn't
+	// apply to
+	ExemptJobs []string
 }
 
-const (
-	BytesInMegabyte = 1024 * 1024
-)
-
-// DefaultResources returns the default resources for a task.
-func DefaultResources() *Resources {
-	return &Resources{
-		CPU:      100,
-		MemoryMB: 10,
-		IOPS:     0,
+// Copy returns a copy of the network policy
+func (p *NamespaceNetworkPolicy) Copy() *NamespaceNetworkPolicy {
+	if p == nil {
+		return nil
 	}
+	np := new(NamespaceNetworkPolicy)
+	*np = *p
+	np.ExemptJobs = CopySliceString(p.ExemptJobs)
+	return np
 }
 
-// DiskInBytes returns the amount of disk resources in bytes.
-func (r *Resources) DiskInBytes() int64 {
-	return int64(r.DiskMB * BytesInMegabyte)
+// Validate validates the network policy
+func (p *NamespaceNetworkPolicy) Validate() error {
+	var mErr multierror.Error
+	for _, pattern := range p.ExemptJobs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid exempt job pattern %q: %v", pattern, err))
+		}
+	}
+	return mErr.ErrorOrNil()
 }
 
-// Merge merges this resource with another resource.
-func (r *Resources) Merge(other *Resources) {
-	if other.CPU != 0 {
-		r.CPU = other.CPU
-	}
-	if other.MemoryMB != 0 {
-		r.MemoryMB = other.MemoryMB
+// Admit returns an error listing the networking requests of the job denied
+// by the policy, if any
+func (p *NamespaceNetworkPolicy) Admit(job *Job) error {
+	if p == nil {
+		return nil
 	}
-	if other.DiskMB != 0 {
-		r.DiskMB = other.DiskMB
+	for _, pattern := range p.ExemptJobs {
+		if ok, _ := path.Match(pattern, job.ID); ok {
+			return nil
+		}
 	}
-	if other.IOPS != 0 {
-		r.IOPS = other.IOPS
+
+	var mErr multierror.Error
+	checkPorts := func(where string, networks []*NetworkResource) {
+		if !p.DenyPrivilegedPorts {
+			return
+		}
+		for _, n := range networks {
+			for _, port := range n.ReservedPorts {
+				if port.Value < MaxUnprivilegedPort {
+					mErr.Errors = append(mErr.Errors, fmt.Errorf("%s: static port %q uses privileged port %d", where, port.Label, port.Value))
+				}
+			}
+		}
 	}
-	if len(other.Networks) != 0 {
-		r.Networks = other.Networks
+	for _, tg := range job.TaskGroups {
+		where := fmt.Sprintf("group %q", tg.Name)
+		checkPorts(where, tg.Networks)
+		if p.DenyHostNetwork {
+			for _, n := range tg.Networks {
+				if !n.Isolated() {
+					mErr.Errors = append(mErr.Errors, fmt.Errorf("%s: host network mode is not allowed", where))
+				}
+			}
+		}
+
+		for _, task := range tg.Tasks {
+			where := fmt.Sprintf("group %q -> task %q", tg.Name, task.Name)
+			if task.Resources != nil {
+				checkPorts(where, task.Resources.Networks)
+			}
+			if mode, ok := task.Config["network_mode"].(string); ok && mode == NetworkModeHost && p.DenyHostNetwork {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("%s: host network_mode is not allowed", where))
+			}
+		}
 	}
+	return mErr.ErrorOrNil()
 }
 
-func (r *Resources) Canonicalize() {
-	// Ensure that an empty and nil slices are treated the same to avoid scheduling
-	// problems since we use reflect DeepEquals.
-	if len(r.Networks) == 0 {
-		r.Networks = nil
-	}
+// maxNamespaceDescriptionLength is the maximum length of a namespace's
+// description
+const maxNamespaceDescriptionLength = 256
 
-	for _, n := range r.Networks {
-		n.Canonicalize()
-	}
+// QuotaSpec is used to limit the resources that the namespaces attached to
+// it may consume. Limits are given per region and usage is enforced by each
+// region individually.
+type QuotaSpec struct {
+	// Name is the name of the quota specification
+	Name string
+
+	// Description is a human readable description of the quota
+	Description string
+
+	// Limits is the set of per region limits
+	Limits []*QuotaLimit
+
+	// Raft Indexes
+	CreateIndex uint64
+	ModifyIndex uint64
 }
 
-// MeetsMinResources returns an error if the resources specified are less than
-// the minimum allowed.
-func (r *Resources) MeetsMinResources() error {
+// Validate validates the quota specification
+func (q *QuotaSpec) Validate() error {
 	var mErr multierror.Error
-	if r.CPU < 20 {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum CPU value is 20; got %d", r.CPU))
+	if !validNamespaceName.MatchString(q.Name) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid name %q. Must match regex %s", q.Name, validNamespaceName))
 	}
-	if r.MemoryMB < 10 {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum MemoryMB value is 10; got %d", r.MemoryMB))
+	if len(q.Description) > maxNamespaceDescriptionLength {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("description longer than %d", maxNamespaceDescriptionLength))
 	}
-	if r.IOPS < 0 {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum IOPS value is 0; got %d", r.IOPS))
+	if len(q.Limits) == 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("must provide at least one quota limit"))
 	}
-	for i, n := range r.Networks {
-		if err := n.MeetsMinResources(); err != nil {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("network resource at index %d failed: %v", i, err))
+	regions := make(map[string]struct{}, len(q.Limits))
+	for idx, limit := range q.Limits {
+		if limit == nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("quota limit %d is empty", idx+1))
+			continue
+		}
+		if _, ok := regions[limit.Region]; ok {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("duplicate quota limit for region %q", limit.Region))
+		}
+		regions[limit.Region] = struct{}{}
+		if err := limit.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("quota limit %d validation failed: %v", idx+1, err))
 		}
 	}
-
 	return mErr.ErrorOrNil()
 }
 
-// Copy returns a deep copy of the resources
-func (r *Resources) Copy() *Resources {
-	if r == nil {
+// Copy returns a copy of the quota specification
+func (q *QuotaSpec) Copy() *QuotaSpec {
+	if q == nil {
 		return nil
 	}
-	newR := new(Resources)
-	*newR = *r
-	if r.Networks != nil {
-		n := len(r.Networks)
-		newR.Networks = make([]*NetworkResource, n)
-		for i := 0; i < n; i++ {
-			newR.Networks[i] = r.Networks[i].Copy()
+	nq := new(QuotaSpec)
+	*nq = *q
+	if q.Limits != nil {
+		nq.Limits = make([]*QuotaLimit, len(q.Limits))
+		for i, l := range q.Limits {
+			nq.Limits[i] = l.Copy()
 		}
 	}
-	return newR
+	return nq
 }
 
-// NetIndex finds the matching net index using device name
-func (r *Resources) NetIndex(n *NetworkResource) int {
-	for idx, net := range r.Networks {
-		if net.Device == n.Device {
-			return idx
+// RegionLimit returns the limit for the given region or nil if the region is
+// not limited by the quota.
+func (q *QuotaSpec) RegionLimit(region string) *QuotaLimit {
+	for _, limit := range q.Limits {
+		if limit.Region == region {
+			return limit
 		}
 	}
-	return -1
+	return nil
 }
 
-// Superset checks if one set of resources is a superset
-// of another. This ignores network resources, and the NetworkIndex
-// should be used for that.
-func (r *Resources) Superset(other *Resources) (bool, string) {
-	if r.CPU < other.CPU {
-		return false, "cpu exhausted"
+// QuotaLimit is the resources that may be consumed in a single region. A
+// value of zero means the resource is not limited.
+type QuotaLimit struct {
+	// Region is the region the limit applies to
+	Region string
+
+	// CPU is the total CPU in MHz that may be allocated
+	CPU int
+
+	// MemoryMB is the total memory in MB that may be allocated
+	MemoryMB int
+
+	// Count is the number of allocations that may be running
+	Count int
+}
+
+// Validate validates the quota limit
+func (l *QuotaLimit) Validate() error {
+	var mErr multierror.Error
+	if l.Region == "" {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("missing region"))
 	}
-	if r.MemoryMB < other.MemoryMB {
-		return false, "memory exhausted"
+	if l.CPU < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("cpu limit must be non-negative"))
 	}
-	if r.DiskMB < other.DiskMB {
-		return false, "disk exhausted"
+	if l.MemoryMB < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("memory limit must be non-negative"))
 	}
-	if r.IOPS < other.IOPS {
-		return false, "iops exhausted"
+	if l.Count < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("count limit must be non-negative"))
 	}
-	return true, ""
+	return mErr.ErrorOrNil()
 }
 
-// Add adds the resources of the delta to this, potentially
-// returning an error if not possible.
-func (r *Resources) Add(delta *Resources) error {
-	if delta == nil {
+// Copy returns a copy of the quota limit
+func (l *QuotaLimit) Copy() *QuotaLimit {
+	if l == nil {
 		return nil
 	}
-	r.CPU += delta.CPU
-	r.MemoryMB += delta.MemoryMB
-	r.DiskMB += delta.DiskMB
-	r.IOPS += delta.IOPS
-
-	for _, n := range delta.Networks {
-		// Find the matching interface by IP or CIDR
-		idx := r.NetIndex(n)
-		if idx == -1 {
-			r.Networks = append(r.Networks, n.Copy())
-		} else {
-			r.Networks[idx].Add(n)
-		}
-	}
-	return nil
+	nl := new(QuotaLimit)
+	*nl = *l
+	return nl
 }
 
-func (r *Resources) GoString() string {
-	return fmt.Sprintf("*%#v", *r)
+// Add adds the resources of an allocation to the quota limit.
+func (l *QuotaLimit) Add(alloc *Allocation) {
+	cpu, mem := quotaResources(alloc)
+	l.CPU += cpu
+	l.MemoryMB += mem
+	l.Count++
 }
 
-type Port struct {
-	Label string
-	Value int `mapstructure:"static"`
+// Subtract removes the resources of an allocation from the quota limit.
+func (l *QuotaLimit) Subtract(alloc *Allocation) {
+	cpu, mem := quotaResources(alloc)
+	l.CPU -= cpu
+	l.MemoryMB -= mem
+	l.Count--
 }
 
-// NetworkResource is used to represent available network
-// resources
-type NetworkResource struct {
-	Device        string // Name of the device
-	CIDR          string // CIDR block of addresses
-	IP            string // IP address
-	MBits         int    // Throughput
-	ReservedPorts []Port // Reserved ports
-	DynamicPorts  []Port // Dynamically assigned ports
+// quotaResources returns the CPU and memory an allocation counts against a
+// quota. Allocations that have not been committed yet only carry their task
+// resources, so the total is computed from those.
+func quotaResources(alloc *Allocation) (int, int) {
+	if alloc.Resources != nil {
+		return alloc.Resources.CPU, alloc.Resources.MemoryMB
+	}
+
+	var cpu, mem int
+	for _, r := range alloc.TaskResources {
+		cpu += r.CPU
+		mem += r.MemoryMB
+	}
+	if r := alloc.SharedResources; r != nil {
+		cpu += r.CPU
+		mem += r.MemoryMB
+	}
+	return cpu, mem
 }
 
-func (n *NetworkResource) Canonicalize() {
-	// Ensure that an empty and nil slices are treated the same to avoid scheduling
-	// problems since we use reflect DeepEquals.
-	if len(n.ReservedPorts) == 0 {
-		n.ReservedPorts = nil
+// Exceeds returns a description of each dimension in which the usage
+// exceeds the given limit.
+func (l *QuotaLimit) Exceeds(limit *QuotaLimit) []string {
+	var exceeded []string
+	if limit.CPU != 0 && l.CPU > limit.CPU {
+		exceeded = append(exceeded, fmt.Sprintf("cpu exhausted (%d > %d)", l.CPU, limit.CPU))
 	}
-	if len(n.DynamicPorts) == 0 {
-		n.DynamicPorts = nil
+	if limit.MemoryMB != 0 && l.MemoryMB > limit.MemoryMB {
+		exceeded = append(exceeded, fmt.Sprintf("memory exhausted (%d > %d)", l.MemoryMB, limit.MemoryMB))
 	}
-}
-
-// MeetsMinResources returns an error if the resources specified are less than
-// the minimum allowed.
-func (n *NetworkResource) MeetsMinResources() error {
-	var mErr multierror.Error
-	if n.MBits < 1 {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum MBits value is 1; got %d", n.MBits))
+	if limit.Count != 0 && l.Count > limit.Count {
+		exceeded = append(exceeded, fmt.Sprintf("count exhausted (%d > %d)", l.Count, limit.Count))
 	}
-	return mErr.ErrorOrNil()
+	return exceeded
 }
 
-// Copy returns a deep copy of the network resource
-func (n *NetworkResource) Copy() *NetworkResource {
-	if n == nil {
-		return nil
-	}
-	newR := new(NetworkResource)
-	*newR = *n
-	if n.ReservedPorts != nil {
-		newR.ReservedPorts = make([]Port, len(n.ReservedPorts))
-		copy(newR.ReservedPorts, n.ReservedPorts)
-	}
-	if n.DynamicPorts != nil {
-		newR.DynamicPorts = make([]Port, len(n.DynamicPorts))
-		copy(newR.DynamicPorts, n.DynamicPorts)
-	}
-	return newR
-}
+// QuotaUsage is the resources currently consumed in a region by the
+// namespaces attached to a quota specification.
+type QuotaUsage struct {
+	// Name is the name of the quota specification
+	Name string
 
-// Add adds the resources of the delta to this, potentially
-// returning an error if not possible.
-func (n *NetworkResource) Add(delta *NetworkResource) {
-	if len(delta.ReservedPorts) > 0 {
-		n.ReservedPorts = append(n.ReservedPorts, delta.ReservedPorts...)
-	}
-	n.MBits += delta.MBits
-	n.DynamicPorts = append(n.DynamicPorts, delta.DynamicPorts...)
-}
+	// Namespaces is the set of namespaces attached to the quota
+	Namespaces []string
 
-func (n *NetworkResource) GoString() string {
-	return fmt.Sprintf("*%#v", *n)
-}
+	// Used is the resources consumed in the region by non-terminal
+	// allocations. Its region is the region the usage was computed in.
+	Used *QuotaLimit
 
-func (n *NetworkResource) MapLabelToValues(port_map map[string]int) map[string]int {
-	labelValues := make(map[string]int)
-	ports := append(n.ReservedPorts, n.DynamicPorts...)
-	for _, port := range ports {
-		if mapping, ok := port_map[port.Label]; ok {
-			labelValues[port.Label] = mapping
-		} else {
-			labelValues[port.Label] = port.Value
-		}
-	}
-	return labelValues
+	// Limit is the limit of the quota in the region, or nil if the region is
+	// not limited.
+	Limit *QuotaLimit
 }
 
 const (
-	// JobTypeNomad is reserved for internal system tasks and is
-	// always handled by the CoreScheduler.
-	JobTypeCore    = "_core"
-	JobTypeService = "service"
-	JobTypeBatch   = "batch"
-	JobTypeSystem  = "system"
+	NodeStatusInit  = "initializing"
+	NodeStatusReady = "ready"
+	NodeStatusDown  = "down"
 )
 
-const (
-	JobStatusPending = "pending" // Pending means the job is waiting on scheduling
-	JobStatusRunning = "running" // Running means the job has non-terminal allocations
-	JobStatusDead    = "dead"    // Dead means all evaluation's and allocations are terminal
-)
+// ShouldDrainNode checks if a given node status should trigger an
+// evaluation. Some states don't require any further action.
+func ShouldDrainNode(status string) bool {
+	switch status {
+	case NodeStatusInit, NodeStatusReady:
+		return false
+	case NodeStatusDown:
+		return true
+	default:
+		panic(fmt.Sprintf("unhandled node status %s", status))
+	}
+}
 
 const (
-	// JobMinPriority is the minimum allowed priority
-	JobMinPriority = 1
-
-	// JobDefaultPriority is the default priority if not
-	// not specified.
-	JobDefaultPriority = 50
-
-	// JobMaxPriority is the maximum allowed priority
-	JobMaxPriority = 100
-
-	// Ensure CoreJobPriority is higher than any user
-	// specified job so that it gets priority. This is important
-	// for the system to remain healthy.
-	CoreJobPriority = JobMaxPriority * 2
+	// NodeSchedulingEligible and NodeSchedulingIneligible are the scheduling
+	// eligibilities of a node. Ineligible nodes keep running their existing
+	// allocations but no new allocations are placed on them.
+	NodeSchedulingEligible   = "eligible"
+	NodeSchedulingIneligible = "ineligible"
 )
 
-// JobSummary summarizes the state of the allocations of a job
-type JobSummary struct {
-	JobID   string
-	Summary map[string]TaskGroupSummary
-
-	// Raft Indexes
-	CreateIndex uint64
-	ModifyIndex uint64
-}
-
-// Copy returns a new copy of JobSummary
-func (js *JobSummary) Copy() *JobSummary {
-	newJobSummary := new(JobSummary)
-	*newJobSummary = *js
-	newTGSummary := make(map[string]TaskGroupSummary, len(js.Summary))
-	for k, v := range js.Summary {
-		newTGSummary[k] = v
+// ValidNodeSchedulingEligibility is used to check if a node scheduling
+// eligibility is valid
+func ValidNodeSchedulingEligibility(eligibility string) bool {
+	switch eligibility {
+	case NodeSchedulingEligible, NodeSchedulingIneligible:
+		return true
+	default:
+		return false
 	}
-	newJobSummary.Summary = newTGSummary
-	return newJobSummary
 }
 
-// TaskGroup summarizes the state of all the allocations of a particular
-// TaskGroup
-type TaskGroupSummary struct {
-	Queued   int
-	Complete int
-	Failed   int
-	Running  int
-	Starting int
-	Lost     int
+// ValidNodeStatus is used to check if a node status is valid
+func ValidNodeStatus(status string) bool {
+	switch status {
+	case NodeStatusInit, NodeStatusReady, NodeStatusDown:
+		return true
+	default:
+		return false
+	}
 }
 
-// Job is the scope of a scheduling request to Nomad. It is the largest
-// scoped object, and is a named collection of task groups. Each task group
-// is further composed of tasks. A task group (TG) is the unit of scheduling
-// however.
-type Job struct {
-	// Region is the Nomad region that handles scheduling this job
-	Region string
-
-	// ID is a unique identifier for the job per region. It can be
-	// specified hierarchically like LineOfBiz/OrgName/Team/Project
+// Node is a representation of a schedulable client node
+type Node struct {
+	// ID is a unique identifier for the node. It can be constructed
+	// by doing a concatenation of the Name and Datacenter as a simple
+	// approach. Alternatively a UUID may be used.
 	ID string
 
-	// ParentID is the unique identifier of the job that spawned this job.
-	ParentID string
-
-	// Name is the logical name of the job used to refer to it. This is unique
-	// per region, but not unique globally.
-	Name string
+	// SecretID is an ID that is only known by the Node and the set of Servers.
+	// It is not accessible via the API and is used to authenticate nodes
+	// conducting priviledged activities.
+	SecretID string
 
-	// Type is used to control various behaviors about the job. Most jobs
-	// are service jobs, meaning they are expected to be long lived.
-	// Some jobs are batch oriented meaning they run and then terminate.
-	// This can be extended in the future to support custom schedulers.
-	Type string
+	// Datacenter for this node
+	Datacenter string
 
-	// Priority is used to control scheduling importance and if this job
-	// can preempt other jobs.
-	Priority int
+	// Node name
+	Name string
 
-	// AllAtOnce is used to control if incremental scheduling of task groups
-	// is allowed or if we must do a gang scheduling of the entire job. This
-	// can slow down larger jobs if resources are not available.
-	AllAtOnce bool `mapstructure:"all_at_once"`
+	// HTTPAddr is the address on which the Nomad client is listening for http
+	// requests
+	HTTPAddr string
 
-	// Datacenters contains all the datacenters this job is allowed to span
-	Datacenters []string
+	// Attributes is an arbitrary set of key/value
+	// data that can be used for constraints. Examples
+	// include "kernel.name=linux", "arch=386", "driver.docker=1",
+	// "docker.runtime=1.8.3"
+	Attributes map[string]string
 
-	// Constraints can be specified at a job level and apply to
-	// all the task groups and tasks.
-	Constraints []*Constraint
+	// Resources is the available resources on the client.
+	// For example 'cpu=2' 'memory=2048'
+	Resources *Resources
 
-	// TaskGroups are the collections of task groups that this job needs
-	// to run. Each task group is an atomic unit of scheduling and placement.
-	TaskGroups []*TaskGroup
+	// Reserved is the set of resources that are reserved,
+	// and should be subtracted from the total resources for
+	// the purposes of scheduling. This may be provide certain
+	// high-watermark tolerances or because of external schedulers
+	// consuming resources.
+	Reserved *Resources
 
-	// Update is used to control the update strategy
-	Update UpdateStrategy
+	// TaskTmpfsMB is the memory in megabytes consumed by the tmpfs mounts of
+	// the task dirs of each task placed on the client, in addition to the
+	// memory of its resources.
+	TaskTmpfsMB int
 
-	// Periodic is used to define the interval the job is run at.
-	Periodic *PeriodicConfig
+	// Links are used to 'link' this client to external
+	// systems. For example 'consul=foo.dc1' 'aws=i-83212'
+	// 'ami=ami-123'
+	Links map[string]string
 
 	// Meta is used to associate arbitrary metadata with this
-	// job. This is opaque to Nomad.
+	// client. This is opaque to Nomad.
 	Meta map[string]string
 
-	// VaultToken is the Vault token that proves the submitter of the job has
-	// access to the specified Vault policies. This field is only used to
-	// transfer the token and is not stored after Job submission.
-	VaultToken string `mapstructure:"vault_token"`
+	// NodeClass is an opaque identifier used to group nodes
+	// together for the purpose of determining scheduling pressure.
+	NodeClass string
 
-	// Job status
+	// ComputedClass is a unique id that identifies nodes with a common set of
+	// attributes and capabilities.
+	ComputedClass string
+
+	// Drain is controlled by the servers, and not the client.
+	// If true, no jobs will be scheduled to this node, and existing
+	// allocations will be drained. It is set whenever DrainStrategy is.
+	Drain bool
+
+	// DrainStrategy is controlled by the servers, and not the client. It is
+	// how the allocations of a draining node are migrated away.
+	DrainStrategy *DrainStrategy
+
+	// SchedulingEligibility is controlled by the servers, and not the client.
+	// If ineligible, no jobs will be scheduled to this node but existing
+	// allocations keep running.
+	SchedulingEligibility string
+
+	// Status of this node
 	Status string
 
 	// StatusDescription is meant to provide more human useful information
 	StatusDescription string
 
+	// StatusUpdatedAt is the time stamp at which the state of the node was
+	// updated
+	StatusUpdatedAt int64
+
+	// Devices are the groups of devices of the node, such as GPUs, reported
+	// by the device plugins of the client
+	Devices []*NodeDeviceResource
+
+	// HostVolumes are the directories of the host that tasks can mount, keyed
+	// by the name task groups request them with
+	HostVolumes map[string]*ClientHostVolumeConfig
+
+	// CSIControllerPlugins and CSINodePlugins are the CSI plugins running on
+	// the node, keyed by plugin ID
+	CSIControllerPlugins map[string]*CSIInfo
+	CSINodePlugins       map[string]*CSIInfo
+
+	// Reliability is controlled by the servers, and not the client. It
+	// tracks the failures seen on the node.
+	Reliability NodeReliability
+
+	// Events are the most recent significant events of the node, oldest
+	// first. They are controlled by the servers, and not the client.
+	Events []*NodeEvent
+
 	// Raft Indexes
-	CreateIndex    uint64
-	ModifyIndex    uint64
-	JobModifyIndex uint64
+	CreateIndex uint64
+	ModifyIndex uint64
 }
 
-// Canonicalize is used to canonicalize fields in the Job. This should be called
-// when registering a Job.
-func (j *Job) Canonicalize() {
-	// Ensure that an empty and nil map are treated the same to avoid scheduling
-	// problems since we use reflect DeepEquals.
-	if len(j.Meta) == 0 {
-		j.Meta = nil
-	}
+const (
+	// MaxRetainedNodeEvents is the maximum number of events kept per node
+	MaxRetainedNodeEvents = 10
+
+	// The subsystems node events are recorded by
+	NodeEventSubsystemCluster   = "Cluster"
+	NodeEventSubsystemDrain     = "Drain"
+	NodeEventSubsystemDriver    = "Driver"
+	NodeEventSubsystemHeartbeat = "Heartbeat"
+	NodeEventSubsystemScheduler = "Scheduler"
+)
 
-	for _, tg := range j.TaskGroups {
-		tg.Canonicalize(j)
+// NodeEvent is a significant event in the lifecycle of a node, such as its
+// registration or it missing its heartbeats
+type NodeEvent struct {
+	Message   string
+	Subsystem string
+	Details   map[string]string
+
+	// Timestamp is the time of the event in nanoseconds since the Unix
+	// epoch. It is set by the server creating the event, so that all the
+	// servers store the same time.
+	Timestamp   int64
+	CreateIndex uint64
+}
+
+// NewNodeEvent returns an event of the given subsystem happening now
+func NewNodeEvent(subsystem, message string) *NodeEvent {
+	return &NodeEvent{
+		Subsystem: subsystem,
+		Message:   message,
+		Timestamp: time.Now().UnixNano(),
 	}
 }
 
-// Copy returns a deep copy of the Job. It is expected that callers use recover.
-// This job can panic if the deep copy failed as it uses reflection.
-func (j *Job) Copy() *Job {
-	if j == nil {
-		return nil
+// SetDetail sets a detail of the event
+func (e *NodeEvent) SetDetail(key, value string) *NodeEvent {
+	if e.Details == nil {
+		e.Details = make(map[string]string)
 	}
-	nj := new(Job)
-	*nj = *j
-	nj.Datacenters = CopySliceString(nj.Datacenters)
-	nj.Constraints = CopySliceConstraints(nj.Constraints)
+	e.Details[key] = value
+	return e
+}
 
-	if j.TaskGroups != nil {
-		tgs := make([]*TaskGroup, len(nj.TaskGroups))
-		for i, tg := range nj.TaskGroups {
-			tgs[i] = tg.Copy()
-		}
-		nj.TaskGroups = tgs
+func (e *NodeEvent) Copy() *NodeEvent {
+	if e == nil {
+		return nil
 	}
+	ne := new(NodeEvent)
+	*ne = *e
+	ne.Details = CopyMapStringString(ne.Details)
+	return ne
+}
 
-	nj.Periodic = nj.Periodic.Copy()
-	nj.Meta = CopyMapStringString(nj.Meta)
-	return nj
+// DrainSpec is how an operator asks for a node to be drained
+type DrainSpec struct {
+	// Deadline is how long the allocations of the node may take to migrate
+	// before the remaining ones are stopped. A zero deadline waits for the
+	// allocations to migrate however long it takes, while a negative one
+	// stops them all right away.
+	Deadline time.Duration
+
+	// IgnoreSystemJobs leaves the allocations of system jobs running on the
+	// node when it is drained.
+	IgnoreSystemJobs bool
 }
 
-// Validate is used to sanity check a job input
-func (j *Job) Validate() error {
-	var mErr multierror.Error
-	if j.Region == "" {
-		mErr.Errors = append(mErr.Errors, errors.New("Missing job region"))
-	}
-	if j.ID == "" {
-		mErr.Errors = append(mErr.Errors, errors.New("Missing job ID"))
-	} else if strings.Contains(j.ID, " ") {
-		mErr.Errors = append(mErr.Errors, errors.New("Job ID contains a space"))
+// DrainStrategy is the drain of a node as tracked by the servers
+type DrainStrategy struct {
+	DrainSpec
+
+	// ForceDeadline is the time at which the remaining allocations of the
+	// node are stopped. It is zero when the drain has no deadline.
+	ForceDeadline time.Time
+
+	// StartedAt is the time at which the drain started
+	StartedAt time.Time
+}
+
+// NewDrainStrategy returns the strategy of a drain of the given spec
+// starting at the given time
+func NewDrainStrategy(spec DrainSpec, now time.Time) *DrainStrategy {
+	d := &DrainStrategy{
+		DrainSpec: spec,
+		StartedAt: now,
 	}
-	if j.Name == "" {
-		mErr.Errors = append(mErr.Errors, errors.New("Missing job name"))
+	switch {
+	case spec.Deadline > 0:
+		d.ForceDeadline = now.Add(spec.Deadline)
+	case spec.Deadline < 0:
+		d.ForceDeadline = now
 	}
-	if j.Type == "" {
-		mErr.Errors = append(mErr.Errors, errors.New("Missing job type"))
+	return d
+}
+
+func (d *DrainStrategy) Copy() *DrainStrategy {
+	if d == nil {
+		return nil
 	}
-	if j.Priority < JobMinPriority || j.Priority > JobMaxPriority {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("Job priority must be between [%d, %d]", JobMinPriority, JobMaxPriority))
+	nd := new(DrainStrategy)
+	*nd = *d
+	return nd
+}
+
+// DeadlineTime returns whether the drain has no deadline and otherwise the
+// time at which the remaining allocations are stopped
+func (d *DrainStrategy) DeadlineTime() (infinite bool, deadline time.Time) {
+	if d == nil || d.ForceDeadline.IsZero() {
+		return true, time.Time{}
 	}
-	if len(j.Datacenters) == 0 {
-		mErr.Errors = append(mErr.Errors, errors.New("Missing job datacenters"))
+	return false, d.ForceDeadline
+}
+
+// NodeReliability is the penalty of a node for the failures seen on it,
+// such as failed allocations, rejected plans and missed heartbeats. The
+// penalty decays exponentially so that nodes recover from past failures.
+type NodeReliability struct {
+	// Penalty is the penalty as of UpdateTime
+	Penalty float64
+
+	// UpdateTime is the time stamp at which the penalty was last updated
+	UpdateTime int64
+}
+
+// Decayed returns the penalty at the given time, halved every half-life
+func (r NodeReliability) Decayed(now time.Time, halfLife time.Duration) float64 {
+	if r.Penalty == 0 || halfLife <= 0 {
+		return r.Penalty
 	}
-	if len(j.TaskGroups) == 0 {
-		mErr.Errors = append(mErr.Errors, errors.New("Missing job task groups"))
+	elapsed := now.UnixNano() - r.UpdateTime
+	if elapsed <= 0 {
+		return r.Penalty
 	}
-	for idx, constr := range j.Constraints {
-		if err := constr.Validate(); err != nil {
-			outer := fmt.Errorf("Constraint %d validation failed: %s", idx+1, err)
-			mErr.Errors = append(mErr.Errors, outer)
-		}
+	return r.Penalty * math.Pow(0.5, float64(elapsed)/float64(halfLife))
+}
+
+// Add returns the reliability once the given penalty is added at the given
+// time
+func (r NodeReliability) Add(penalty float64, now time.Time, halfLife time.Duration) NodeReliability {
+	return NodeReliability{
+		Penalty:    r.Decayed(now, halfLife) + penalty,
+		UpdateTime: now.UnixNano(),
 	}
+}
 
-	// Check for duplicate task groups
-	taskGroups := make(map[string]int)
-	for idx, tg := range j.TaskGroups {
-		if tg.Name == "" {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("Job task group %d missing name", idx+1))
-		} else if existing, ok := taskGroups[tg.Name]; ok {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("Job task group %d redefines '%s' from group %d", idx+1, tg.Name, existing+1))
-		} else {
-			taskGroups[tg.Name] = idx
-		}
+// Ready returns if the node is ready for running allocations
+func (n *Node) Ready() bool {
+	return n.Status == NodeStatusReady && !n.Drain && n.SchedulingEligible()
+}
 
-		if j.Type == "system" && tg.Count > 1 {
-			mErr.Errors = append(mErr.Errors,
-				fmt.Errorf("Job task group %s has count %d. Count cannot exceed 1 with system scheduler",
-					tg.Name, tg.Count))
-		}
-	}
+// SchedulingEligible returns if new allocations may be placed on the node.
+// Nodes registered before the scheduling eligibility was tracked are eligible.
+func (n *Node) SchedulingEligible() bool {
+	return n.SchedulingEligibility != NodeSchedulingIneligible
+}
 
-	// Validate the task group
-	for _, tg := range j.TaskGroups {
-		if err := tg.Validate(); err != nil {
-			outer := fmt.Errorf("Task group %s validation failed: %s", tg.Name, err)
-			mErr.Errors = append(mErr.Errors, outer)
-		}
+func (n *Node) Copy() *Node {
+	if n == nil {
+		return nil
 	}
-
-	// Validate periodic is only used with batch jobs.
-	if j.IsPeriodic() && j.Periodic.Enabled {
-		if j.Type != JobTypeBatch {
-			mErr.Errors = append(mErr.Errors,
-				fmt.Errorf("Periodic can only be used with %q scheduler", JobTypeBatch))
+	nn := new(Node)
+	*nn = *n
+	nn.Attributes = CopyMapStringString(nn.Attributes)
+	nn.Resources = nn.Resources.Copy()
+	nn.Reserved = nn.Reserved.Copy()
+	nn.Links = CopyMapStringString(nn.Links)
+	nn.Meta = CopyMapStringString(nn.Meta)
+	nn.DrainStrategy = nn.DrainStrategy.Copy()
+	nn.HostVolumes = CopyMapStringClientHostVolumeConfig(n.HostVolumes)
+	nn.CSIControllerPlugins = CopyMapStringCSIInfo(n.CSIControllerPlugins)
+	nn.CSINodePlugins = CopyMapStringCSIInfo(n.CSINodePlugins)
+	if n.Devices != nil {
+		nn.Devices = make([]*NodeDeviceResource, len(n.Devices))
+		for i, d := range n.Devices {
+			nn.Devices[i] = d.Copy()
 		}
-
-		if err := j.Periodic.Validate(); err != nil {
-			mErr.Errors = append(mErr.Errors, err)
+	}
+	if n.Events != nil {
+		nn.Events = make([]*NodeEvent, len(n.Events))
+		for i, e := range n.Events {
+			nn.Events[i] = e.Copy()
 		}
 	}
-
-	return mErr.ErrorOrNil()
+	return nn
 }
 
-// LookupTaskGroup finds a task group by name
-func (j *Job) LookupTaskGroup(name string) *TaskGroup {
-	for _, tg := range j.TaskGroups {
-		if tg.Name == name {
-			return tg
-		}
+// TerminalStatus returns if the current status is terminal and
+// will no longer transition.
+func (n *Node) TerminalStatus() bool {
+	switch n.Status {
+	case NodeStatusDown:
+		return true
+	default:
+		return false
 	}
-	return nil
 }
 
-// Stub is used to return a summary of the job
-func (j *Job) Stub(summary *JobSummary) *JobListStub {
-	return &JobListStub{
-		ID:                j.ID,
-		ParentID:          j.ParentID,
-		Name:              j.Name,
-		Type:              j.Type,
-		Priority:          j.Priority,
-		Status:            j.Status,
-		StatusDescription: j.StatusDescription,
-		CreateIndex:       j.CreateIndex,
-		ModifyIndex:       j.ModifyIndex,
-		JobModifyIndex:    j.JobModifyIndex,
-		JobSummary:        summary,
+// Stub returns a summarized version of the node
+func (n *Node) Stub() *NodeListStub {
+	return &NodeListStub{
+		ID:                    n.ID,
+		Datacenter:            n.Datacenter,
+		Name:                  n.Name,
+		NodeClass:             n.NodeClass,
+		Drain:                 n.Drain,
+		SchedulingEligibility: n.SchedulingEligibility,
+		Status:                n.Status,
+		StatusDescription:     n.StatusDescription,
+		CreateIndex:           n.CreateIndex,
+		ModifyIndex:           n.ModifyIndex,
 	}
 }
 
-// IsPeriodic returns whether a job is periodic.
-func (j *Job) IsPeriodic() bool {
-	return j.Periodic != nil
+// NodeListStub is used to return a subset of job information
+// for the job list
+type NodeListStub struct {
+	ID                    string
+	Datacenter            string
+	Name                  string
+	NodeClass             string
+	Drain                 bool
+	SchedulingEligibility string
+	Status                string
+	StatusDescription     string
+	CreateIndex           uint64
+	ModifyIndex           uint64
 }
 
-// VaultPolicies returns the set of Vault policies per task group, per task
-func (j *Job) VaultPolicies() map[string]map[string]*Vault {
-	policies := make(map[string]map[string]*Vault, len(j.TaskGroups))
+const (
+	// MaintenanceModeSoft avoids placing allocations on the nodes of the
+	// class, while still using them when no other node fits.
+	MaintenanceModeSoft = "soft"
 
-	for _, tg := range j.TaskGroups {
-		tgPolicies := make(map[string]*Vault, len(tg.Tasks))
+	// MaintenanceModeHard blocks placements on the nodes of the class and
+	// migrates their allocations away.
+	MaintenanceModeHard = "hard"
+)
 
-		for _, task := range tg.Tasks {
-			if task.Vault == nil {
-				continue
-			}
+// SearchContext is the type of the objects a search looks for
+type SearchContext string
 
-			tgPolicies[task.Name] = task.Vault
-		}
+const (
+	SearchContextAll    SearchContext = "all"
+	SearchContextJobs   SearchContext = "jobs"
+	SearchContextAllocs SearchContext = "allocs"
+	SearchContextNodes  SearchContext = "nodes"
+	SearchContextEvals  SearchContext = "evals"
+)
 
-		if len(tgPolicies) != 0 {
-			policies[tg.Name] = tgPolicies
-		}
-	}
+const (
+	// MaxSearchResults is the maximum number of matches returned per
+	// context by a search
+	MaxSearchResults = 20
 
-	return policies
-}
+	// MinFuzzySearchTextLength is the minimum length of the text of a fuzzy
+	// search
+	MinFuzzySearchTextLength = 2
+)
 
-// JobListStub is used to return a subset of job information
-// for the job list
-type JobListStub struct {
-	ID                string
-	ParentID          string
-	Name              string
-	Type              string
-	Priority          int
-	Status            string
-	StatusDescription string
-	JobSummary        *JobSummary
-	CreateIndex       uint64
-	ModifyIndex       uint64
-	JobModifyIndex    uint64
+// SearchContexts is the set of contexts searched when searching all of them
+var SearchContexts = []SearchContext{
+	SearchContextJobs,
+	SearchContextAllocs,
+	SearchContextNodes,
+	SearchContextEvals,
 }
 
-// UpdateStrategy is used to modify how updates are done
-type UpdateStrategy struct {
-	// Stagger is the amount of time between the updates
-	Stagger time.Duration
-
-	// MaxParallel is how many updates can be done in parallel
-	MaxParallel int `mapstructure:"max_parallel"`
+// FuzzyMatch is an object matching a fuzzy search. ID is the name of the
+// object and Scope the path of IDs identifying it: the namespace and ID of a
+// job or an allocation, and the ID of a node.
+type FuzzyMatch struct {
+	ID    string
+	Scope []string
 }
 
-// Rolling returns if a rolling strategy should be used
-func (u *UpdateStrategy) Rolling() bool {
-	return u.Stagger > 0 && u.MaxParallel > 0
-}
+// MaintenanceWindow is a period of time during which the nodes of a node
+// class undergo maintenance, so the schedulers avoid or stop using them.
+type MaintenanceWindow struct {
+	ID string
 
-const (
-	// PeriodicSpecCron is used for a cron spec.
-	PeriodicSpecCron = "cron"
+	// NodeClass is the class of the nodes under maintenance
+	NodeClass string
 
-	// PeriodicSpecTest is only used by unit tests. It is a sorted, comma
-	// separated list of unix timestamps at which to launch.
-	PeriodicSpecTest = "_internal_test"
-)
+	// Mode is either soft or hard. Placements on the nodes of a soft window
+	// are penalized, while those on the nodes of a hard window are blocked
+	// and existing allocations are migrated off the nodes.
+	Mode string
 
-// Periodic defines the interval a job should be run at.
-type PeriodicConfig struct {
-	// Enabled determines if the job should be run periodically.
-	Enabled bool
+	// Start and End bound the window
+	Start time.Time
+	End   time.Time
 
-	// Spec specifies the interval the job should be run as. It is parsed based
-	// on the SpecType.
-	Spec string
+	// MigrateBefore is how long before the start of a hard window that its
+	// nodes stop receiving placements and their allocations are migrated,
+	// so that the nodes are empty once the maintenance starts.
+	MigrateBefore time.Duration
 
-	// SpecType defines the format of the spec.
-	SpecType string
+	// Description is a human readable reason for the maintenance
+	Description string
 
-	// ProhibitOverlap enforces that spawned jobs do not run in parallel.
-	ProhibitOverlap bool `mapstructure:"prohibit_overlap"`
+	// Raft Indexes
+	CreateIndex uint64
+	ModifyIndex uint64
 }
 
-func (p *PeriodicConfig) Copy() *PeriodicConfig {
-	if p == nil {
-		return nil
+// Validate validates the maintenance window
+func (w *MaintenanceWindow) Validate() error {
+	var mErr multierror.Error
+	if w.NodeClass == "" {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("missing node class"))
 	}
-	np := new(PeriodicConfig)
-	*np = *p
-	return np
-}
-
-func (p *PeriodicConfig) Validate() error {
-	if !p.Enabled {
-		return nil
+	switch w.Mode {
+	case MaintenanceModeSoft, MaintenanceModeHard:
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid mode %q, must be %q or %q",
+			w.Mode, MaintenanceModeSoft, MaintenanceModeHard))
 	}
-
-	if p.Spec == "" {
-		return fmt.Errorf("Must specify a spec")
+	if w.Start.IsZero() || w.End.IsZero() {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("start and end must be set"))
+	} else if !w.End.After(w.Start) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("end must be after start"))
 	}
-
-	switch p.SpecType {
-	case PeriodicSpecCron:
-		// Validate the cron spec
-		if _, err := cronexpr.Parse(p.Spec); err != nil {
-			return fmt.Errorf("Invalid cron spec %q: %v", p.Spec, err)
-		}
-	case PeriodicSpecTest:
-		// No-op
-	default:
-		return fmt.Errorf("Unknown periodic specification type %q", p.SpecType)
+	if w.MigrateBefore < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("migrate_before must not be negative"))
+	} else if w.MigrateBefore > 0 && w.Mode != MaintenanceModeHard {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("migrate_before is only supported by hard windows"))
 	}
+	if len(w.Description) > maxNamespaceDescriptionLength {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("description longer than %d", maxNamespaceDescriptionLength))
+	}
+	return mErr.ErrorOrNil()
+}
 
-	return nil
+// InEffect returns if the window affects the scheduling of its nodes at the
+// given time. Hard windows take effect MigrateBefore ahead of their start.
+func (w *MaintenanceWindow) InEffect(now time.Time) bool {
+	start := w.Start
+	if w.Mode == MaintenanceModeHard {
+		start = start.Add(-w.MigrateBefore)
+	}
+	return !now.Before(start) && now.Before(w.End)
 }
 
-// Next returns the closest time instant matching the spec that is after the
-// passed time. If no matching instance exists, the zero value of time.Time is
-// returned. The `time.Location` of the returned value matches that of the
-// passed time.
-func (p *PeriodicConfig) Next(fromTime time.Time) time.Time {
-	switch p.SpecType {
-	case PeriodicSpecCron:
-		if e, err := cronexpr.Parse(p.Spec); err == nil {
-			return e.Next(fromTime)
-		}
-	case PeriodicSpecTest:
-		split := strings.Split(p.Spec, ",")
-		if len(split) == 1 && split[0] == "" {
-			return time.Time{}
-		}
-
-		// Parse the times
-		times := make([]time.Time, len(split))
-		for i, s := range split {
-			unix, err := strconv.Atoi(s)
-			if err != nil {
-				return time.Time{}
-			}
-
-			times[i] = time.Unix(int64(unix), 0)
-		}
-
-		// Find the next match
-		for _, next := range times {
-			if fromTime.Before(next) {
-				return next
-			}
-		}
+// Copy returns a copy of the maintenance window
+func (w *MaintenanceWindow) Copy() *MaintenanceWindow {
+	if w == nil {
+		return nil
 	}
+	nw := new(MaintenanceWindow)
+	*nw = *w
+	return nw
+}
 
-	return time.Time{}
+// Resources is used to define the resources available
+// on a client
+type Resources struct {
+	CPU      int
+	MemoryMB int `mapstructure:"memory"`
+	DiskMB   int `mapstructure:"disk"`
+	IOPS     int
+	Networks []*NetworkResource
+
+	// Devices are the devices of the node requested by a task
+	Devices []*RequestedDevice
 }
 
 const (
-	// PeriodicLaunchSuffix is the string appended to the periodic jobs ID
-	// when launching derived instances of it.
-	PeriodicLaunchSuffix = "/periodic-"
+	BytesInMegabyte = 1024 * 1024
 )
 
-// PeriodicLaunch tracks the last launch time of a periodic job.
-type PeriodicLaunch struct {
-	ID     string    // ID of the periodic job.
-	Launch time.Time // The last launch time.
+// DefaultResources returns the default resources for a task.
+func DefaultResources() *Resources {
+	return &Resources{
+		CPU:      100,
+		MemoryMB: 10,
+		IOPS:     0,
+	}
+}
 
-	// Raft Indexes
-	CreateIndex uint64
-	ModifyIndex uint64
+// DiskInBytes returns the amount of disk resources in bytes.
+func (r *Resources) DiskInBytes() int64 {
+	return int64(r.DiskMB * BytesInMegabyte)
 }
 
-var (
-	defaultServiceJobRestartPolicy = RestartPolicy{
-		Delay:    15 * time.Second,
-		Attempts: 2,
-		Interval: 1 * time.Minute,
-		Mode:     RestartPolicyModeDelay,
+// Merge merges this resource with another resource.
+func (r *Resources) Merge(other *Resources) {
+	if other.CPU != 0 {
+		r.CPU = other.CPU
 	}
-	defaultBatchJobRestartPolicy = RestartPolicy{
-		Delay:    15 * time.Second,
-		Attempts: 15,
-		Interval: 7 * 24 * time.Hour,
-		Mode:     RestartPolicyModeDelay,
+	if other.MemoryMB != 0 {
+		r.MemoryMB = other.MemoryMB
 	}
-)
-
-const (
-	// RestartPolicyModeDelay causes an artificial delay till the next interval is
-	// reached when the specified attempts have been reached in the interval.
-	RestartPolicyModeDelay = "delay"
-
-	// RestartPolicyModeFail causes a job to fail if the specified number of
-	// attempts are reached within an interval.
-	RestartPolicyModeFail = "fail"
-)
+	if other.DiskMB != 0 {
+		r.DiskMB = other.DiskMB
+	}
+	if other.IOPS != 0 {
+		r.IOPS = other.IOPS
+	}
+	if len(other.Networks) != 0 {
+		r.Networks = other.Networks
+	}
+	if len(other.Devices) != 0 {
+		r.Devices = other.Devices
+	}
+}
 
-// RestartPolicy configures how Tasks are restarted when they crash or fail.
-type RestartPolicy struct {
-	// Attempts is the number of restart that will occur in an interval.
-	Attempts int
+func (r *Resources) Canonicalize() {
+	// Ensure that an empty and nil slices are treated the same to avoid scheduling
+	// problems since we use reflect DeepEquals.
+	if len(r.Networks) == 0 {
+		r.Networks = nil
+	}
+	if len(r.Devices) == 0 {
+		r.Devices = nil
+	}
 
-	// Interval is a duration in which we can limit the number of restarts
-	// within.
-	Interval time.Duration
+	for _, n := range r.Networks {
+		n.Canonicalize()
+	}
+}
 
-	// Delay is the time between a failure and a restart.
-	Delay time.Duration
+// MeetsMinResources returns an error if the resources specified are less than
+// the minimum allowed.
+func (r *Resources) MeetsMinResources() error {
+	var mErr multierror.Error
+	if r.CPU < 20 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum CPU value is 20; got %d", r.CPU))
+	}
+	if r.MemoryMB < 10 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum MemoryMB value is 10; got %d", r.MemoryMB))
+	}
+	if r.IOPS < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum IOPS value is 0; got %d", r.IOPS))
+	}
+	for i, n := range r.Networks {
+		if err := n.MeetsMinResources(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("network resource at index %d failed: %v", i, err))
+		}
+	}
+	for _, d := range r.Devices {
+		if err := d.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
 
-	// Mode controls what happens when the task restarts more than attempt times
-	// in an interval.
-	Mode string
+	return mErr.ErrorOrNil()
 }
 
-func (r *RestartPolicy) Copy() *RestartPolicy {
+// Copy returns a deep copy of the resources
+func (r *Resources) Copy() *Resources {
 	if r == nil {
 		return nil
 	}
-	nrp := new(RestartPolicy)
-	*nrp = *r
-	return nrp
+	newR := new(Resources)
+	*newR = *r
+	if r.Networks != nil {
+		n := len(r.Networks)
+		newR.Networks = make([]*NetworkResource, n)
+		for i := 0; i < n; i++ {
+			newR.Networks[i] = r.Networks[i].Copy()
+		}
+	}
+	if r.Devices != nil {
+		newR.Devices = make([]*RequestedDevice, len(r.Devices))
+		for i, d := range r.Devices {
+			newR.Devices[i] = d.Copy()
+		}
+	}
+	return newR
 }
 
-func (r *RestartPolicy) Validate() error {
-	switch r.Mode {
-	case RestartPolicyModeDelay, RestartPolicyModeFail:
-	default:
-		return fmt.Errorf("Unsupported restart mode: %q", r.Mode)
+// NetIndex finds the matching net index using device name
+func (r *Resources) NetIndex(n *NetworkResource) int {
+	for idx, net := range r.Networks {
+		if net.Device == n.Device {
+			return idx
+		}
 	}
+	return -1
+}
 
-	// Check for ambiguous/confusing settings
-	if r.Attempts == 0 && r.Mode != RestartPolicyModeFail {
-		return fmt.Errorf("Restart policy %q with %d attempts is ambiguous", r.Mode, r.Attempts)
+// Superset checks if one set of resources is a superset
+// of another. This ignores network resources, and the NetworkIndex
+// should be used for that.
+func (r *Resources) Superset(other *Resources) (bool, string) {
+	if r.CPU < other.CPU {
+		return false, "cpu exhausted"
 	}
-
-	if r.Interval == 0 {
-		return nil
+	if r.MemoryMB < other.MemoryMB {
+		return false, "memory exhausted"
 	}
-	if time.Duration(r.Attempts)*r.Delay > r.Interval {
-		return fmt.Errorf("Nomad can't restart the TaskGroup %v times in an interval of %v with a delay of %v", r.Attempts, r.Interval, r.Delay)
+	if r.DiskMB < other.DiskMB {
+		return false, "disk exhausted"
 	}
-	return nil
+	if r.IOPS < other.IOPS {
+		return false, "iops exhausted"
+	}
+	return true, ""
 }
 
-func NewRestartPolicy(jobType string) *RestartPolicy {
-	switch jobType {
-	case JobTypeService, JobTypeSystem:
-		rp := defaultServiceJobRestartPolicy
-		return &rp
-	case JobTypeBatch:
-		rp := defaultBatchJobRestartPolicy
-		return &rp
+// Add adds the resources of the delta to this, potentially
+// returning an error if not possible.
+func (r *Resources) Add(delta *Resources) error {
+	if delta == nil {
+		return nil
+	}
+	r.CPU += delta.CPU
+	r.MemoryMB += delta.MemoryMB
+	r.DiskMB += delta.DiskMB
+	r.IOPS += delta.IOPS
+
+	for _, n := range delta.Networks {
+		// Find the matching interface by IP or CIDR
+		idx := r.NetIndex(n)
+		if idx == -1 {
+			r.Networks = append(r.Networks, n.Copy())
+		} else {
+			r.Networks[idx].Add(n)
+		}
 	}
 	return nil
 }
 
-// TaskGroup is an atomic unit of placement. Each task group belongs to
-// a job and may contain any number of tasks. A task group support running
-// in many replicas using the same configuration..
-type TaskGroup struct {
-	// Name of the task group
-	Name string
+func (r *Resources) GoString() string {
+	return fmt.Sprintf("*%#v", *r)
+}
 
-	// Count is the number of replicas of this task group that should
-	// be scheduled.
-	Count int
+type Port struct {
+	Label string
+	Value int `mapstructure:"static"`
 
-	// Constraints can be specified at a task group level and apply to
-	// all the tasks contained.
-	Constraints []*Constraint
+	// To is the port inside the network namespace of the allocation the host
+	// port is mapped to. It defaults to the host port.
+	To int `mapstructure:"to"`
 
-	//RestartPolicy of a TaskGroup
-	RestartPolicy *RestartPolicy
+	// HostNetwork is the name of the host network the port binds to. The
+	// port uses the default network of the node if it is empty.
+	HostNetwork string `mapstructure:"host_network"`
+}
 
-	// Tasks are the collection of tasks that this task group needs to run
-	Tasks []*Task
+const (
+	// NetworkModeHost shares the network namespace of the host
+	NetworkModeHost = "host"
 
-	// EphemeralDisk is the disk resources that the task group requests
-	EphemeralDisk *EphemeralDisk
+	// NetworkModeBridge places the allocation in a network namespace attached
+	// to a bridge on the host
+	NetworkModeBridge = "bridge"
 
-	// Meta is used to associate arbitrary metadata with this
-	// task group. This is opaque to Nomad.
-	Meta map[string]string
-}
+	// NetworkModeCNIPrefix prefixes the name of the CNI network configuration
+	// the allocation's network namespace is configured with
+	NetworkModeCNIPrefix = "cni/"
+)
 
-func (tg *TaskGroup) Copy() *TaskGroup {
-	if tg == nil {
-		return nil
-	}
-	ntg := new(TaskGroup)
-	*ntg = *tg
-	ntg.Constraints = CopySliceConstraints(ntg.Constraints)
+// NetworkResource is used to represent available network
+// resources
+type NetworkResource struct {
+	Mode          string // Networking mode of a task group network
+	Device        string // Name of the device
+	CIDR          string // CIDR block of addresses
+	IP            string // IP address
+	MBits         int    // Throughput
+	ReservedPorts []Port // Reserved ports
+	DynamicPorts  []Port // Dynamically assigned ports
 
-	ntg.RestartPolicy = ntg.RestartPolicy.Copy()
+	// HostNetwork is the name of the host network the address of the network
+	// belongs to. It is empty for the default network of the node.
+	HostNetwork string
+}
 
-	if tg.Tasks != nil {
-		tasks := make([]*Task, len(ntg.Tasks))
-		for i, t := range ntg.Tasks {
-			tasks[i] = t.Copy()
-		}
-		ntg.Tasks = tasks
+func (n *NetworkResource) Canonicalize() {
+	// Ensure that an empty and nil slices are treated the same to avoid scheduling
+	// problems since we use reflect DeepEquals.
+	if len(n.ReservedPorts) == 0 {
+		n.ReservedPorts = nil
+	}
+	if len(n.DynamicPorts) == 0 {
+		n.DynamicPorts = nil
 	}
+}
 
-	ntg.Meta = CopyMapStringString(ntg.Meta)
+// MeetsMinResources returns an error if the resources specified are less than
+// the minimum allowed.
+func (n *NetworkResource) MeetsMinResources() error {
+	var mErr multierror.Error
+	if n.MBits < 1 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum MBits value is 1; got %d", n.MBits))
+	}
+	return mErr.ErrorOrNil()
+}
 
-	if tg.EphemeralDisk != nil {
-		ntg.EphemeralDisk = tg.EphemeralDisk.Copy()
+// Copy returns a deep copy of the network resource
+func (n *NetworkResource) Copy() *NetworkResource {
+	if n == nil {
+		return nil
 	}
-	return ntg
+	newR := new(NetworkResource)
+	*newR = *n
+	if n.ReservedPorts != nil {
+		newR.ReservedPorts = make([]Port, len(n.ReservedPorts))
+		copy(newR.ReservedPorts, n.ReservedPorts)
+	}
+	if n.DynamicPorts != nil {
+		newR.DynamicPorts = make([]Port, len(n.DynamicPorts))
+		copy(newR.DynamicPorts, n.DynamicPorts)
+	}
+	return newR
 }
 
-// Canonicalize is used to canonicalize fields in the TaskGroup.
-func (tg *TaskGroup) Canonicalize(job *Job) {
-	// Ensure that an empty and nil map are treated the same to avoid scheduling
-	// problems since we use reflect DeepEquals.
-	if len(tg.Meta) == 0 {
-		tg.Meta = nil
+// Add adds the resources of the delta to this, potentially
+// returning an error if not possible.
+func (n *NetworkResource) Add(delta *NetworkResource) {
+	if len(delta.ReservedPorts) > 0 {
+		n.ReservedPorts = append(n.ReservedPorts, delta.ReservedPorts...)
 	}
+	n.MBits += delta.MBits
+	n.DynamicPorts = append(n.DynamicPorts, delta.DynamicPorts...)
+}
 
-	// Set the default restart policy.
-	if tg.RestartPolicy == nil {
-		tg.RestartPolicy = NewRestartPolicy(job.Type)
+func (n *NetworkResource) GoString() string {
+	return fmt.Sprintf("*%#v", *n)
+}
+
+// Isolated returns whether the network uses a network namespace of its own
+func (n *NetworkResource) Isolated() bool {
+	return n.Mode != "" && n.Mode != NetworkModeHost
+}
+
+// CNINetwork returns the name of the CNI network configuration the network
+// uses, if any
+func (n *NetworkResource) CNINetwork() (string, bool) {
+	if !strings.HasPrefix(n.Mode, NetworkModeCNIPrefix) {
+		return "", false
 	}
+	return strings.TrimPrefix(n.Mode, NetworkModeCNIPrefix), true
+}
 
-	for _, task := range tg.Tasks {
-		task.Canonicalize(job, tg)
+// PortMappings returns the ports of the network keyed by their host port,
+// along with the port inside the network namespace they are mapped to
+func (n *NetworkResource) PortMappings() map[int]int {
+	mappings := make(map[int]int)
+	for _, port := range append(n.ReservedPorts, n.DynamicPorts...) {
+		to := port.To
+		if to == 0 {
+			to = port.Value
+		}
+		mappings[port.Value] = to
 	}
+	return mappings
 }
 
-// Validate is used to sanity check a task group
-func (tg *TaskGroup) Validate() error {
+// Validate validates the network of a task group
+func (n *NetworkResource) Validate() error {
 	var mErr multierror.Error
-	if tg.Name == "" {
-		mErr.Errors = append(mErr.Errors, errors.New("Missing task group name"))
-	}
-	if tg.Count < 0 {
-		mErr.Errors = append(mErr.Errors, errors.New("Task group count can't be negative"))
-	}
-	if len(tg.Tasks) == 0 {
-		mErr.Errors = append(mErr.Errors, errors.New("Missing tasks for task group"))
-	}
-	for idx, constr := range tg.Constraints {
-		if err := constr.Validate(); err != nil {
-			outer := fmt.Errorf("Constraint %d validation failed: %s", idx+1, err)
-			mErr.Errors = append(mErr.Errors, outer)
+	switch n.Mode {
+	case "", NetworkModeHost, NetworkModeBridge:
+	default:
+		if name, ok := n.CNINetwork(); !ok || name == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid network mode %q", n.Mode))
 		}
 	}
 
-	if tg.RestartPolicy != nil {
-		if err := tg.RestartPolicy.Validate(); err != nil {
-			mErr.Errors = append(mErr.Errors, err)
-		}
-	} else {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("Task Group %v should have a restart policy", tg.Name))
+	if err := n.MeetsMinResources(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
 	}
 
-	if tg.EphemeralDisk != nil {
-		if err := tg.EphemeralDisk.Validate(); err != nil {
-			mErr.Errors = append(mErr.Errors, err)
+	for _, port := range append(n.ReservedPorts, n.DynamicPorts...) {
+		if port.To < 0 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("port %q mapped to invalid port %d", port.Label, port.To))
 		}
-	} else {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("Task Group %v should have a ephemeral disk object", tg.Name))
 	}
+	if _, err := n.PortsHostNetwork(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+	return mErr.ErrorOrNil()
+}
 
-	// Check for duplicate tasks
-	tasks := make(map[string]int)
-	for idx, task := range tg.Tasks {
-		if task.Name == "" {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("Task %d missing name", idx+1))
-		} else if existing, ok := tasks[task.Name]; ok {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("Task %d redefines '%s' from task %d", idx+1, task.Name, existing+1))
-		} else {
-			tasks[task.Name] = idx
-		}
+// PortsHostNetwork returns the name of the host network the ports of the
+// network bind to. The ports of a network are assigned a single address, so
+// an error is returned if they bind to different host networks.
+func (n *NetworkResource) PortsHostNetwork() (string, error) {
+	ports := append(n.ReservedPorts, n.DynamicPorts...)
+	if len(ports) == 0 {
+		return "", nil
 	}
 
-	// Validate the tasks
-	for _, task := range tg.Tasks {
-		if err := task.Validate(tg.EphemeralDisk); err != nil {
-			outer := fmt.Errorf("Task %s validation failed: %s", task.Name, err)
-			mErr.Errors = append(mErr.Errors, outer)
+	hostNetwork := ports[0].HostNetwork
+	for _, port := range ports[1:] {
+		if port.HostNetwork != hostNetwork {
+			return "", fmt.Errorf("ports %q and %q bind to different host networks %q and %q",
+				ports[0].Label, port.Label, hostNetwork, port.HostNetwork)
 		}
 	}
-	return mErr.ErrorOrNil()
+	return hostNetwork, nil
 }
 
-// LookupTask finds a task by name
-func (tg *TaskGroup) LookupTask(name string) *Task {
-	for _, t := range tg.Tasks {
-		if t.Name == name {
-			return t
+func (n *NetworkResource) MapLabelToValues(port_map map[string]int) map[string]int {
+	labelValues := make(map[string]int)
+	ports := append(n.ReservedPorts, n.DynamicPorts...)
+	for _, port := range ports {
+		if mapping, ok := port_map[port.Label]; ok {
+			labelValues[port.Label] = mapping
+		} else {
+			labelValues[port.Label] = port.Value
 		}
 	}
-	return nil
+	return labelValues
 }
 
-func (tg *TaskGroup) GoString() string {
-	return fmt.Sprintf("*%#v", *tg)
-}
+const (
+	// JobTypeNomad is reserved for internal system tasks and is
+	// always handled by the CoreScheduler.
+	JobTypeCore    = "_core"
+	JobTypeService = "service"
+	JobTypeBatch   = "batch"
+	JobTypeSystem  = "system"
+)
 
 const (
-	// TODO add Consul TTL check
-	ServiceCheckHTTP   = "http"
-	ServiceCheckTCP    = "tcp"
-	ServiceCheckScript = "script"
+	JobStatusPending = "pending" // Pending means the job is waiting on scheduling
+	JobStatusRunning = "running" // Running means the job has non-terminal allocations
+	JobStatusDead    = "dead"    // Dead means all evaluation's and allocations are terminal
+)
 
-	// minCheckInterval is the minimum check interval permitted.  Consul
-	// currently has its MinInterval set to 1s.  Mirror that here for
-	// consistency.
-	minCheckInterval = 1 * time.Second
+const (
+	// JobMinPriority is the minimum allowed priority
+	JobMinPriority = 1
 
-	// minCheckTimeout is the minimum check timeout permitted for Consul
-	// script TTL checks.
-	minCheckTimeout = 1 * time.Second
+	// JobDefaultPriority is the default priority if not
+	// not specified.
+	JobDefaultPriority = 50
+
+	// JobMaxPriority is the maximum allowed priority
+	JobMaxPriority = 100
+
+	// Ensure CoreJobPriority is higher than any user
+	// specified job so that it gets priority. This is important
+	// for the system to remain healthy.
+	CoreJobPriority = JobMaxPriority * 2
 )
 
-// The ServiceCheck data model represents the consul health check that
-// Nomad registers for a Task
-type ServiceCheck struct {
-	Name          string        // Name of the check, defaults to id
-	Type          string        // Type of the check - tcp, http, docker and script
-	Command       string        // Command is the command to run for script checks
-	Args          []string      // Args is a list of argumes for script checks
-	Path          string        // path of the health check url for http type check
-	Protocol      string        // Protocol to use if check is http, defaults to http
-	PortLabel     string        `mapstructure:"port"` // The port to use for tcp/http checks
-	Interval      time.Duration // Interval of the check
-	Timeout       time.Duration // Timeout of the response from the check before consul fails the check
-	InitialStatus string        `mapstructure:"initial_status"` // Initial status of the check
-}
+// JobSummary summarizes the state of the allocations of a job
+type JobSummary struct {
+	JobID     string
+	Namespace string
+	Summary   map[string]TaskGroupSummary
 
-func (sc *ServiceCheck) Copy() *ServiceCheck {
-	if sc == nil {
-		return nil
-	}
-	nsc := new(ServiceCheck)
-	*nsc = *sc
-	return nsc
+	// Raft Indexes
+	CreateIndex uint64
+	ModifyIndex uint64
 }
 
-func (sc *ServiceCheck) Canonicalize(serviceName string) {
-	// Ensure empty slices are treated as null to avoid scheduling issues when
-	// using DeepEquals.
-	if len(sc.Args) == 0 {
-		sc.Args = nil
-	}
-
-	if sc.Name == "" {
-		sc.Name = fmt.Sprintf("service: %q check", serviceName)
+// Copy returns a new copy of JobSummary
+func (js *JobSummary) Copy() *JobSummary {
+	newJobSummary := new(JobSummary)
+	*newJobSummary = *js
+	newTGSummary := make(map[string]TaskGroupSummary, len(js.Summary))
+	for k, v := range js.Summary {
+		newTGSummary[k] = v
 	}
+	newJobSummary.Summary = newTGSummary
+	return newJobSummary
 }
 
-// validate a Service's ServiceCheck
-func (sc *ServiceCheck) validate() error {
-	switch strings.ToLower(sc.Type) {
-	case ServiceCheckTCP:
-		if sc.Timeout < minCheckTimeout {
-			return fmt.Errorf("timeout (%v) is lower than required minimum timeout %v", sc.Timeout, minCheckInterval)
-		}
-	case ServiceCheckHTTP:
-		if sc.Path == "" {
-			return fmt.Errorf("http type must have a valid http path")
-		}
+// TaskGroup summarizes the state of all the allocations of a particular
+// TaskGroup
+type TaskGroupSummary struct {
+	Queued   int
+	Complete int
+	Failed   int
+	Running  int
+	Starting int
+	Lost     int
+}
 
-		if sc.Timeout < minCheckTimeout {
-			return fmt.Errorf("timeout (%v) is lower than required minimum timeout %v", sc.Timeout, minCheckInterval)
-		}
-	case ServiceCheckScript:
-		if sc.Command == "" {
-			return fmt.Errorf("script type must have a valid script path")
-		}
+// Job is the scope of a scheduling request to Nomad. It is the largest
+// scoped object, and is a named collection of task groups. Each task group
+// is further composed of tasks. A task group (TG) is the unit of scheduling
+// however.
+type Job struct {
+	// Region is the Nomad region that handles scheduling this job
+	Region string
 
-		// TODO: enforce timeout on the Client side and reenable
-		// validation.
-	default:
-		return fmt.Errorf(`invalid type (%+q), must be one of "http", "tcp", or "script" type`, sc.Type)
-	}
+	// Regions is the set of federated regions the job is registered in. A job
+	// submitted to any one of them is also registered in each of the others,
+	// with its Region set to that region.
+	Regions []string
 
-	if sc.Interval < minCheckInterval {
-		return fmt.Errorf("interval (%v) can not be lower than %v", sc.Interval, minCheckInterval)
-	}
+	// Multiregion configures the rollout of a job registered in multiple
+	// regions. If set, the regions are rolled out in the order they are
+	// listed, each waiting on the previous ones to be healthy, rather than
+	// all at once.
+	Multiregion *MultiregionStrategy
 
-	switch sc.InitialStatus {
-	case "":
-	case api.HealthUnknown:
-	case api.HealthPassing:
-	case api.HealthWarning:
-	case api.HealthCritical:
-	default:
-		return fmt.Errorf(`invalid initial check state (%s), must be one of %q, %q, %q, %q or empty`, sc.InitialStatus, api.HealthUnknown, api.HealthPassing, api.HealthWarning, api.HealthCritical)
+	// Namespace is the namespace the job is submitted into.
+	Namespace string
 
-	}
+	// ID is a unique identifier for the job per region. It can be
+	// specified hierarchically like LineOfBiz/OrgName/Team/Project
+	ID string
 
-	return nil
-}
+	// ParentID is the unique identifier of the job that spawned this job.
+	ParentID string
 
-// RequiresPort returns whether the service check requires the task has a port.
-func (sc *ServiceCheck) RequiresPort() bool {
-	switch sc.Type {
-	case ServiceCheckHTTP, ServiceCheckTCP:
-		return true
-	default:
-		return false
-	}
-}
+	// Name is the logical name of the job used to refer to it. This is unique
+	// per region, but not unique globally.
+	Name string
 
-func (sc *ServiceCheck) Hash(serviceID string) string {
-	h := sha1.New()
-	io.WriteString(h, serviceID)
-	io.WriteString(h, sc.Name)
-	io.WriteString(h, sc.Type)
-	io.WriteString(h, sc.Command)
-	io.WriteString(h, strings.Join(sc.Args, ""))
-	io.WriteString(h, sc.Path)
-	io.WriteString(h, sc.Protocol)
-	io.WriteString(h, sc.PortLabel)
-	io.WriteString(h, sc.Interval.String())
-	io.WriteString(h, sc.Timeout.String())
-	return fmt.Sprintf("%x", h.Sum(nil))
-}
+	// Type is used to control various behaviors about the job. Most jobs
+	// are service jobs, meaning they are expected to be long lived.
+	// Some jobs are batch oriented meaning they run and then terminate.
+	// This can be extended in the future to support custom schedulers.
+	Type string
 
-// Service represents a Consul service definition in Nomad
-type Service struct {
-	// Name of the service registered with Consul. Consul defaults the
-	// Name to ServiceID if not specified.  The Name if specified is used
-	// as one of the seed values when generating a Consul ServiceID.
-	Name string
+	// Priority is used to control scheduling importance and if this job
+	// can preempt other jobs.
+	Priority int
 
-	// PortLabel is either the numeric port number or the `host:port`.
-	// To specify the port number using the host's Consul Advertise
-	// address, specify an empty host in the PortLabel (e.g. `:port`).
-	PortLabel string          `mapstructure:"port"`
-	Tags      []string        // List of tags for the service
-	Checks    []*ServiceCheck // List of checks associated with the service
-}
+	// AllAtOnce is used to control if incremental scheduling of task groups
+	// is allowed or if we must do a gang scheduling of the entire job. This
+	// can slow down larger jobs if resources are not available.
+	AllAtOnce bool `mapstructure:"all_at_once"`
 
-func (s *Service) Copy() *Service {
-	if s == nil {
-		return nil
-	}
-	ns := new(Service)
-	*ns = *s
-	ns.Tags = CopySliceString(ns.Tags)
+	// Datacenters contains all the datacenters this job is allowed to span.
+	// Entries may be glob patterns such as "us-east-*" or "*".
+	Datacenters []string
 
-	if s.Checks != nil {
-		checks := make([]*ServiceCheck, len(ns.Checks))
-		for i, c := range ns.Checks {
-			checks[i] = c.Copy()
-		}
-		ns.Checks = checks
-	}
+	// Constraints can be specified at a job level and apply to
+	// all the task groups and tasks.
+	Constraints []*Constraint
 
-	return ns
-}
+	// TaskGroups are the collections of task groups that this job needs
+	// to run. Each task group is an atomic unit of scheduling and placement.
+	TaskGroups []*TaskGroup
 
-// Canonicalize interpolates values of Job, Task Group and Task in the Service
-// Name. This also generates check names, service id and check ids.
-func (s *Service) Canonicalize(job string, taskGroup string, task string) {
-	// Ensure empty lists are treated as null to avoid scheduler issues when
-	// using DeepEquals
-	if len(s.Tags) == 0 {
-		s.Tags = nil
-	}
-	if len(s.Checks) == 0 {
-		s.Checks = nil
-	}
+	// Update is used to control the update strategy
+	Update UpdateStrategy
 
-	s.Name = args.ReplaceEnv(s.Name, map[string]string{
-		"JOB":       job,
-		"TASKGROUP": taskGroup,
-		"TASK":      task,
-		"BASE":      fmt.Sprintf("%s-%s-%s", job, taskGroup, task),
-	},
-	)
+	// Periodic is used to define the interval the job is run at.
+	Periodic *PeriodicConfig
 
-	for _, check := range s.Checks {
-		check.Canonicalize(s.Name)
-	}
-}
+	// ParameterizedJob is used to specify the job as a parameterized job
+	// for dispatching.
+	ParameterizedJob *ParameterizedJobConfig `mapstructure:"parameterized"`
 
-// Validate checks if the Check definition is valid
-func (s *Service) Validate() error {
-	var mErr multierror.Error
+	// Dispatched is set for jobs dispatched from a parameterized job.
+	Dispatched bool
 
-	// Ensure the service name is valid per RFC-952 §1
-	// (https://tools.ietf.org/html/rfc952), RFC-1123 §2.1
-	// (https://tools.ietf.org/html/rfc1123), and RFC-2782
-	// (https://tools.ietf.org/html/rfc2782).
-	re := regexp.MustCompile(`^(?i:[a-z0-9]|[a-z0-9][a-z0-9\-]{0,61}[a-z0-9])$`)
-	if !re.MatchString(s.Name) {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("service name must be valid per RFC 1123 and can contain only alphanumeric characters or dashes and must be less than 63 characters long: %q", s.Name))
-	}
+	// Payload is the payload supplied when the job was dispatched.
+	Payload []byte
 
-	for _, c := range s.Checks {
-		if s.PortLabel == "" && c.RequiresPort() {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("check %s invalid: check requires a port but the service %+q has no port", c.Name, s.Name))
-			continue
-		}
+	// Meta is used to associate arbitrary metadata with this
+	// job. This is opaque to Nomad.
+	Meta map[string]string
 
-		if err := c.validate(); err != nil {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("check %s invalid: %v", c.Name, err))
-		}
-	}
-	return mErr.ErrorOrNil()
-}
+	// VaultToken is the Vault token that proves the submitter of the job has
+	// access to the specified Vault policies. This field is only used to
+	// transfer the token and is not stored after Job submission.
+	VaultToken string `mapstructure:"vault_token"`
 
-// Hash calculates the hash of the check based on it's content and the service
-// which owns it
-func (s *Service) Hash() string {
-	h := sha1.New()
-	io.WriteString(h, s.Name)
-	io.WriteString(h, strings.Join(s.Tags, ""))
-	io.WriteString(h, s.PortLabel)
-	return fmt.Sprintf("%x", h.Sum(nil))
-}
+	// Stop marks whether the user has stopped the job. A stopped job will
+	// have all created allocations stopped and acts as a way to stop a job
+	// without purging it from the system. This allows existing allocs to be
+	// queried and the job to be started again.
+	Stop bool
 
-const (
-	// DefaultKillTimeout is the default timeout between signaling a task it
-	// will be killed and killing it.
-	DefaultKillTimeout = 5 * time.Second
-)
+	// Job status
+	Status string
 
-// LogConfig provides configuration for log rotation
-type LogConfig struct {
-	MaxFiles      int `mapstructure:"max_files"`
-	MaxFileSizeMB int `mapstructure:"max_file_size"`
+	// StatusDescription is meant to provide more human useful information
+	StatusDescription string
+
+	// Version is incremented each time the job is registered, starting at 0.
+	// The most recent versions of each job are kept so they can be compared.
+	Version uint64
+
+	// Raft Indexes
+	CreateIndex    uint64
+	ModifyIndex    uint64
+	JobModifyIndex uint64
 }
 
-// DefaultLogConfig returns the default LogConfig values.
-func DefaultLogConfig() *LogConfig {
-	return &LogConfig{
-		MaxFiles:      10,
-		MaxFileSizeMB: 10,
+// Canonicalize is used to canonicalize fields in the Job. This should be called
+// when registering a Job.
+func (j *Job) Canonicalize() {
+	// Ensure that an empty and nil map are treated the same to avoid scheduling
+	// problems since we use reflect DeepEquals.
+	if len(j.Meta) == 0 {
+		j.Meta = nil
 	}
-}
 
-// Validate returns an error if the log config specified are less than
-// the minimum allowed.
-func (l *LogConfig) Validate() error {
-	var mErr multierror.Error
-	if l.MaxFiles < 1 {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum number of files is 1; got %d", l.MaxFiles))
+	// Jobs submitted without a namespace are placed in the default namespace
+	if j.Namespace == "" {
+		j.Namespace = DefaultNamespace
 	}
-	if l.MaxFileSizeMB < 1 {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum file size is 1MB; got %d", l.MaxFileSizeMB))
+
+	for _, tg := range j.TaskGroups {
+		tg.Canonicalize(j)
 	}
-	return mErr.ErrorOrNil()
-}
 
-// Task is a single process typically that is executed as part of a task group.
-type Task struct {
-	// Name of the task
-	Name string
+	if j.ParameterizedJob != nil {
+		j.ParameterizedJob.Canonicalize()
+	}
 
-	// Driver is used to control which driver is used
-	Driver string
+	if j.Multiregion != nil {
+		j.Multiregion.Canonicalize()
+	}
+}
 
-	// User is used to determine which user will run the task. It defaults to
-	// the same user the Nomad client is being run as.
-	User string
+// Copy returns a deep copy of the Job. It is expected that callers use recover.
+// This job can panic if the deep copy failed as it uses reflection.
+func (j *Job) Copy() *Job {
+	if j == nil {
+		return nil
+	}
+	nj := new(Job)
+	*nj = *j
+	nj.Regions = CopySliceString(nj.Regions)
+	nj.Datacenters = CopySliceString(nj.Datacenters)
+	nj.Constraints = CopySliceConstraints(nj.Constraints)
 
-	// Config is provided to the driver to initialize
-	Config map[string]interface{}
+	if j.TaskGroups != nil {
+		tgs := make([]*TaskGroup, len(nj.TaskGroups))
+		for i, tg := range nj.TaskGroups {
+			tgs[i] = tg.Copy()
+		}
+		nj.TaskGroups = tgs
+	}
 
-	// Map of environment variables to be used by the driver
-	Env map[string]string
+	nj.Periodic = nj.Periodic.Copy()
+	nj.Meta = CopyMapStringString(nj.Meta)
+	nj.ParameterizedJob = nj.ParameterizedJob.Copy()
+	nj.Multiregion = nj.Multiregion.Copy()
+	if j.Payload != nil {
+		nj.Payload = make([]byte, len(j.Payload))
+		copy(nj.Payload, j.Payload)
+	}
+	return nj
+}
 
-	// List of service definitions exposed by the Task
-	Services []*Service
-
-	// Vault is used to define the set of Vault policies that this task should
-	// have access to.
-	Vault *Vault
-
-	// Templates are the set of templates to be rendered for the task.
-	Templates []*Template
-
-	// Constraints can be specified at a task level and apply only to
-	// the particular task.
-	Constraints []*Constraint
-
-	// Resources is the resources needed by this task
-	Resources *Resources
-
-	// Meta is used to associate arbitrary metadata with this
-	// task. This is opaque to Nomad.
-	Meta map[string]string
-
-	// KillTimeout is the time between signaling a task that it will be
-	// killed and killing it.
-	KillTimeout time.Duration `mapstructure:"kill_timeout"`
-
-	// LogConfig provides configuration for log rotation
-	LogConfig *LogConfig `mapstructure:"logs"`
-
-	// Artifacts is a list of artifacts to download and extract before running
-	// the task.
-	Artifacts []*TaskArtifact
-}
-
-func (t *Task) Copy() *Task {
-	if t == nil {
-		return nil
+// Validate is used to sanity check a job input
+func (j *Job) Validate() error {
+	var mErr multierror.Error
+	if j.Region == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("Missing job region"))
 	}
-	nt := new(Task)
-	*nt = *t
-	nt.Env = CopyMapStringString(nt.Env)
-
-	if t.Services != nil {
-		services := make([]*Service, len(nt.Services))
-		for i, s := range nt.Services {
-			services[i] = s.Copy()
+	if len(j.Regions) != 0 {
+		regions := make(map[string]struct{}, len(j.Regions))
+		for idx, region := range j.Regions {
+			if region == "" {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("Job region %d is empty", idx+1))
+			} else if _, ok := regions[region]; ok {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("Job region %q is listed more than once", region))
+			}
+			regions[region] = struct{}{}
 		}
-		nt.Services = services
-	}
-
-	nt.Constraints = CopySliceConstraints(nt.Constraints)
-
-	nt.Vault = nt.Vault.Copy()
-	nt.Resources = nt.Resources.Copy()
-	nt.Meta = CopyMapStringString(nt.Meta)
-
-	if t.Artifacts != nil {
-		artifacts := make([]*TaskArtifact, 0, len(t.Artifacts))
-		for _, a := range nt.Artifacts {
-			artifacts = append(artifacts, a.Copy())
+		if _, ok := regions[j.Region]; !ok && j.Region != "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Job region %q must be one of the job's regions", j.Region))
 		}
-		nt.Artifacts = artifacts
 	}
-
-	if i, err := copystructure.Copy(nt.Config); err != nil {
-		nt.Config = i.(map[string]interface{})
-	}
-
-	if t.Templates != nil {
-		templates := make([]*Template, len(t.Templates))
-		for i, tmpl := range nt.Templates {
-			templates[i] = tmpl.Copy()
+	if j.Multiregion != nil {
+		if len(j.Regions) == 0 {
+			mErr.Errors = append(mErr.Errors, errors.New("Multiregion requires the job's regions to be set"))
 		}
-		nt.Templates = templates
-	}
-
-	return nt
-}
-
-// Canonicalize canonicalizes fields in the task.
-func (t *Task) Canonicalize(job *Job, tg *TaskGroup) {
-	// Ensure that an empty and nil map are treated the same to avoid scheduling
-	// problems since we use reflect DeepEquals.
-	if len(t.Meta) == 0 {
-		t.Meta = nil
-	}
-	if len(t.Config) == 0 {
-		t.Config = nil
-	}
-	if len(t.Env) == 0 {
-		t.Env = nil
-	}
-
-	for _, service := range t.Services {
-		service.Canonicalize(job.Name, tg.Name, t.Name)
-	}
-
-	if t.Resources != nil {
-		t.Resources.Canonicalize()
-	}
-
-	// Set the default timeout if it is not specified.
-	if t.KillTimeout == 0 {
-		t.KillTimeout = DefaultKillTimeout
-	}
-}
-
-func (t *Task) GoString() string {
-	return fmt.Sprintf("*%#v", *t)
-}
-
-func (t *Task) FindHostAndPortFor(portLabel string) (string, int) {
-	for _, network := range t.Resources.Networks {
-		if p, ok := network.MapLabelToValues(nil)[portLabel]; ok {
-			return network.IP, p
+		if j.IsPeriodic() || j.IsParameterized() {
+			mErr.Errors = append(mErr.Errors, errors.New("Multiregion can't be used with periodic or parameterized jobs"))
+		}
+		if err := j.Multiregion.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Multiregion validation failed: %v", err))
 		}
 	}
-	return "", 0
-}
-
-// Validate is used to sanity check a task
-func (t *Task) Validate(ephemeralDisk *EphemeralDisk) error {
-	var mErr multierror.Error
-	if t.Name == "" {
-		mErr.Errors = append(mErr.Errors, errors.New("Missing task name"))
+	if j.ID == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("Missing job ID"))
+	} else if strings.Contains(j.ID, " ") {
+		mErr.Errors = append(mErr.Errors, errors.New("Job ID contains a space"))
 	}
-	if strings.ContainsAny(t.Name, `/\`) {
-		// We enforce this so that when creating the directory on disk it will
-		// not have any slashes.
-		mErr.Errors = append(mErr.Errors, errors.New("Task name can not include slashes"))
+	if j.Name == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("Missing job name"))
 	}
-	if t.Driver == "" {
-		mErr.Errors = append(mErr.Errors, errors.New("Missing task driver"))
+	if j.Type == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("Missing job type"))
 	}
-	if t.KillTimeout.Nanoseconds() < 0 {
-		mErr.Errors = append(mErr.Errors, errors.New("KillTimeout must be a positive value"))
+	if j.Priority < JobMinPriority || j.Priority > JobMaxPriority {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Job priority must be between [%d, %d]", JobMinPriority, JobMaxPriority))
 	}
-
-	// Validate the resources.
-	if t.Resources == nil {
-		mErr.Errors = append(mErr.Errors, errors.New("Missing task resources"))
-	} else if err := t.Resources.MeetsMinResources(); err != nil {
-		mErr.Errors = append(mErr.Errors, err)
+	if len(j.Datacenters) == 0 {
+		mErr.Errors = append(mErr.Errors, errors.New("Missing job datacenters"))
 	}
-
-	// Ensure the task isn't asking for disk resources
-	if t.Resources != nil {
-		if t.Resources.DiskMB > 0 {
-			mErr.Errors = append(mErr.Errors, errors.New("Task can't ask for disk resources, they have to be specified at the task group level."))
+	for _, dc := range j.Datacenters {
+		if !IsDatacenterPattern(dc) {
+			continue
+		}
+		if _, err := path.Match(dc, ""); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Invalid datacenter pattern %q: %v", dc, err))
 		}
 	}
-
-	// Validate the log config
-	if t.LogConfig == nil {
-		mErr.Errors = append(mErr.Errors, errors.New("Missing Log Config"))
-	} else if err := t.LogConfig.Validate(); err != nil {
-		mErr.Errors = append(mErr.Errors, err)
+	if len(j.TaskGroups) == 0 {
+		mErr.Errors = append(mErr.Errors, errors.New("Missing job task groups"))
 	}
-
-	for idx, constr := range t.Constraints {
+	for idx, constr := range j.Constraints {
 		if err := constr.Validate(); err != nil {
 			outer := fmt.Errorf("Constraint %d validation failed: %s", idx+1, err)
 			mErr.Errors = append(mErr.Errors, outer)
 		}
 	}
 
-	// Validate Services
-	if err := validateServices(t); err != nil {
-		mErr.Errors = append(mErr.Errors, err)
-	}
+	// Check for duplicate task groups
+	taskGroups := make(map[string]int)
+	for idx, tg := range j.TaskGroups {
+		if tg.Name == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Job task group %d missing name", idx+1))
+		} else if existing, ok := taskGroups[tg.Name]; ok {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Job task group %d redefines '%s' from group %d", idx+1, tg.Name, existing+1))
+		} else {
+			taskGroups[tg.Name] = idx
+		}
 
-	if t.LogConfig != nil && ephemeralDisk != nil {
-		logUsage := (t.LogConfig.MaxFiles * t.LogConfig.MaxFileSizeMB)
-		if ephemeralDisk.SizeMB <= logUsage {
+		if j.Type == "system" && tg.Count > 1 {
 			mErr.Errors = append(mErr.Errors,
-				fmt.Errorf("log storage (%d MB) must be less than requested disk capacity (%d MB)",
-					logUsage, ephemeralDisk.SizeMB))
+				fmt.Errorf("Job task group %s has count %d. Count cannot exceed 1 with system scheduler",
+					tg.Name, tg.Count))
+		}
+		if j.Type == JobTypeSystem && tg.Scaling != nil {
+			mErr.Errors = append(mErr.Errors,
+				fmt.Errorf("Job task group %s can't specify a scaling policy with system scheduler", tg.Name))
 		}
 	}
 
-	for idx, artifact := range t.Artifacts {
-		if err := artifact.Validate(); err != nil {
-			outer := fmt.Errorf("Artifact %d validation failed: %v", idx+1, err)
+	// Validate the task group
+	for _, tg := range j.TaskGroups {
+		if err := tg.Validate(); err != nil {
+			outer := fmt.Errorf("Task group %s validation failed: %s", tg.Name, err)
 			mErr.Errors = append(mErr.Errors, outer)
 		}
 	}
 
-	if t.Vault != nil {
-		if err := t.Vault.Validate(); err != nil {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("Vault validation failed: %v", err))
+	// Validate periodic is only used with batch jobs.
+	if j.IsPeriodic() && j.Periodic.Enabled {
+		if j.Type != JobTypeBatch {
+			mErr.Errors = append(mErr.Errors,
+				fmt.Errorf("Periodic can only be used with %q scheduler", JobTypeBatch))
 		}
-	}
 
-	for idx, tmpl := range t.Templates {
-		if err := tmpl.Validate(); err != nil {
-			outer := fmt.Errorf("Template %d validation failed: %s", idx+1, err)
-			mErr.Errors = append(mErr.Errors, outer)
+		if err := j.Periodic.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
 		}
 	}
 
-	return mErr.ErrorOrNil()
-}
-
-// validateServices takes a task and validates the services within it are valid
-// and reference ports that exist.
-func validateServices(t *Task) error {
-	var mErr multierror.Error
-
-	// Ensure that services don't ask for non-existent ports and their names are
-	// unique.
-	servicePorts := make(map[string][]string)
-	knownServices := make(map[string]struct{})
-	for i, service := range t.Services {
-		if err := service.Validate(); err != nil {
-			outer := fmt.Errorf("service[%d] %+q validation failed: %s", i, service.Name, err)
-			mErr.Errors = append(mErr.Errors, outer)
-		}
-		if _, ok := knownServices[service.Name]; ok {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("service %q is duplicate", service.Name))
+	// Validate parameterized jobs, whose tasks are the only ones that may
+	// receive a dispatch payload
+	if j.IsParameterized() {
+		if j.Type != JobTypeBatch {
+			mErr.Errors = append(mErr.Errors,
+				fmt.Errorf("Parameterized job can only be used with %q scheduler", JobTypeBatch))
 		}
-		knownServices[service.Name] = struct{}{}
 
-		if service.PortLabel != "" {
-			servicePorts[service.PortLabel] = append(servicePorts[service.PortLabel], service.Name)
+		if err := j.ParameterizedJob.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
 		}
-
-		// Ensure that check names are unique.
-		knownChecks := make(map[string]struct{})
-		for _, check := range service.Checks {
-			if _, ok := knownChecks[check.Name]; ok {
-				mErr.Errors = append(mErr.Errors, fmt.Errorf("check %q is duplicate", check.Name))
+	} else if j.ParameterizedJob == nil {
+		for _, tg := range j.TaskGroups {
+			for _, task := range tg.Tasks {
+				if task.DispatchPayload != nil {
+					mErr.Errors = append(mErr.Errors,
+						fmt.Errorf("Task %q in group %q can only specify a dispatch payload in a parameterized job", task.Name, tg.Name))
+				}
 			}
-			knownChecks[check.Name] = struct{}{}
 		}
 	}
 
-	// Get the set of port labels.
-	portLabels := make(map[string]struct{})
-	if t.Resources != nil {
-		for _, network := range t.Resources.Networks {
-			ports := network.MapLabelToValues(nil)
-			for portLabel, _ := range ports {
-				portLabels[portLabel] = struct{}{}
-			}
+	return mErr.ErrorOrNil()
+}
+
+// LookupTaskGroup finds a task group by name
+func (j *Job) LookupTaskGroup(name string) *TaskGroup {
+	for _, tg := range j.TaskGroups {
+		if tg.Name == name {
+			return tg
+		}
+	}
+	return nil
+}
+
+// Stub is used to return a summary of the job
+func (j *Job) Stub(summary *JobSummary) *JobListStub {
+	return &JobListStub{
+		ID:                j.ID,
+		ParentID:          j.ParentID,
+		Namespace:         j.Namespace,
+		Name:              j.Name,
+		Type:              j.Type,
+		Priority:          j.Priority,
+		Stop:              j.Stop,
+		ParameterizedJob:  j.IsParameterized(),
+		Status:            j.Status,
+		StatusDescription: j.StatusDescription,
+		CreateIndex:       j.CreateIndex,
+		ModifyIndex:       j.ModifyIndex,
+		JobModifyIndex:    j.JobModifyIndex,
+		JobSummary:        summary,
+	}
+}
+
+// IsPeriodic returns whether a job is periodic.
+func (j *Job) IsPeriodic() bool {
+	return j.Periodic != nil
+}
+
+// IsParameterized returns whether a job is a parameterized job that is
+// dispatched, rather than an instance dispatched from one.
+func (j *Job) IsParameterized() bool {
+	return j.ParameterizedJob != nil && !j.Dispatched
+}
+
+// MultiregionDeferred returns whether the multi-region rollout of the job
+// reaches the region only once earlier regions are healthy. The rollout
+// starts in the first MaxParallel regions of the job.
+func (j *Job) MultiregionDeferred(region string) bool {
+	if j.Multiregion == nil {
+		return false
+	}
+	for i, r := range j.Regions {
+		if r == region {
+			return i >= j.Multiregion.MaxParallel
+		}
+	}
+	return false
+}
+
+// Stopped returns if a job is stopped.
+func (j *Job) Stopped() bool {
+	return j == nil || j.Stop
+}
+
+// VaultPolicies returns the set of Vault policies per task group, per task
+func (j *Job) VaultPolicies() map[string]map[string]*Vault {
+	policies := make(map[string]map[string]*Vault, len(j.TaskGroups))
+
+	for _, tg := range j.TaskGroups {
+		tgPolicies := make(map[string]*Vault, len(tg.Tasks))
+
+		for _, task := range tg.Tasks {
+			if task.Vault == nil {
+				continue
+			}
+
+			tgPolicies[task.Name] = task.Vault
+		}
+
+		if len(tgPolicies) != 0 {
+			policies[tg.Name] = tgPolicies
+		}
+	}
+
+	return policies
+}
+
+// JobListStub is used to return a subset of job information
+// for the job list
+type JobListStub struct {
+	ID                string
+	ParentID          string
+	Namespace         string
+	Name              string
+	Type              string
+	Priority          int
+	Stop              bool
+	ParameterizedJob  bool
+	Status            string
+	StatusDescription string
+	JobSummary        *JobSummary
+	CreateIndex       uint64
+	ModifyIndex       uint64
+	JobModifyIndex    uint64
+}
+
+// UpdateStrategy is used to modify how updates are done
+type UpdateStrategy struct {
+	// Stagger is the amount of time between the updates
+	Stagger time.Duration
+
+	// MaxParallel is how many updates can be done in parallel
+	MaxParallel int `mapstructure:"max_parallel"`
+}
+
+// Rolling returns if a rolling strategy should be used
+func (u *UpdateStrategy) Rolling() bool {
+	return u.Stagger > 0 && u.MaxParallel > 0
+}
+
+// MigrateStrategy is how the allocations of a task group are migrated off of
+// a draining node
+type MigrateStrategy struct {
+	// MaxParallel is how many allocations of the task group are migrated at
+	// the same time
+	MaxParallel int `mapstructure:"max_parallel"`
+
+	// MinHealthyTime is how long a replacement allocation must have been
+	// running before it is healthy and more allocations are migrated
+	MinHealthyTime time.Duration `mapstructure:"min_healthy_time"`
+}
+
+// DefaultMigrateStrategy returns the migrate strategy of task groups without
+// a migrate block
+func DefaultMigrateStrategy() *MigrateStrategy {
+	return &MigrateStrategy{
+		MaxParallel:    1,
+		MinHealthyTime: 10 * time.Second,
+	}
+}
+
+func (m *MigrateStrategy) Canonicalize() {
+	if m.MaxParallel == 0 {
+		m.MaxParallel = 1
+	}
+}
+
+func (m *MigrateStrategy) Validate() error {
+	var mErr multierror.Error
+	if m.MaxParallel < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Max parallel can not be less than zero: %d", m.MaxParallel))
+	}
+	if m.MinHealthyTime < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Minimum healthy time can not be negative: %v", m.MinHealthyTime))
+	}
+	return mErr.ErrorOrNil()
+}
+
+func (m *MigrateStrategy) Copy() *MigrateStrategy {
+	if m == nil {
+		return nil
+	}
+	nm := new(MigrateStrategy)
+	*nm = *m
+	return nm
+}
+
+const (
+	// MultiregionOnFailureFailAll stops the rollout of a multi-region job
+	// once a region fails. The regions not yet rolled out are cancelled.
+	MultiregionOnFailureFailAll = "fail_all"
+
+	// MultiregionOnFailureFailLocal only fails the region that failed and
+	// continues the rollout with the remaining regions.
+	MultiregionOnFailureFailLocal = "fail_local"
+)
+
+// MultiregionStrategy configures the region-by-region rollout of a job
+// registered in multiple regions
+type MultiregionStrategy struct {
+	// MaxParallel is how many regions are rolled out at the same time
+	MaxParallel int `mapstructure:"max_parallel"`
+
+	// OnFailure is how the rollout proceeds once a region fails
+	OnFailure string `mapstructure:"on_failure"`
+}
+
+func (m *MultiregionStrategy) Canonicalize() {
+	if m.MaxParallel == 0 {
+		m.MaxParallel = 1
+	}
+	if m.OnFailure == "" {
+		m.OnFailure = MultiregionOnFailureFailAll
+	}
+}
+
+func (m *MultiregionStrategy) Validate() error {
+	var mErr multierror.Error
+	if m.MaxParallel < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Max parallel can not be less than zero: %d", m.MaxParallel))
+	}
+	switch m.OnFailure {
+	case "", MultiregionOnFailureFailAll, MultiregionOnFailureFailLocal:
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Unknown on_failure %q, must be one of %q or %q",
+			m.OnFailure, MultiregionOnFailureFailAll, MultiregionOnFailureFailLocal))
+	}
+	return mErr.ErrorOrNil()
+}
+
+func (m *MultiregionStrategy) Copy() *MultiregionStrategy {
+	if m == nil {
+		return nil
+	}
+	nm := new(MultiregionStrategy)
+	*nm = *m
+	return nm
+}
+
+const (
+	MultiregionRolloutStatusRunning    = "running"
+	MultiregionRolloutStatusSuccessful = "successful"
+	MultiregionRolloutStatusFailed     = "failed"
+)
+
+const (
+	MultiregionRegionStatusPending   = "pending"
+	MultiregionRegionStatusRunning   = "running"
+	MultiregionRegionStatusHealthy   = "healthy"
+	MultiregionRegionStatusFailed    = "failed"
+	MultiregionRegionStatusCancelled = "cancelled"
+)
+
+// MultiregionRollout tracks the region-by-region rollout of a version of a
+// multi-region job. It is stored in the region the job was submitted to,
+// whose leader advances the rollout.
+type MultiregionRollout struct {
+	// JobID is the ID of the job being rolled out
+	JobID string
+
+	// Namespace is the namespace of the job
+	Namespace string
+
+	// Strategy is the multi-region strategy of the job version
+	Strategy *MultiregionStrategy
+
+	// Regions are the regions of the job in rollout order
+	Regions []*MultiregionRolloutRegion
+
+	// Status is the status of the rollout
+	Status            string
+	StatusDescription string
+
+	// Raft Indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// MultiregionRolloutRegion is the rollout of a multi-region job in one of its
+// regions
+type MultiregionRolloutRegion struct {
+	// Region is the name of the region
+	Region string
+
+	// JobModifyIndex is the job modify index of the version registered in
+	// the region
+	JobModifyIndex uint64
+
+	// EvalID is the evaluation that started the rollout in the region
+	EvalID string
+
+	// Status is the status of the region's rollout
+	Status            string
+	StatusDescription string
+}
+
+// Active returns whether the rollout is still in progress
+func (r *MultiregionRollout) Active() bool {
+	return r.Status == MultiregionRolloutStatusRunning
+}
+
+func (r *MultiregionRollout) Copy() *MultiregionRollout {
+	if r == nil {
+		return nil
+	}
+	nr := new(MultiregionRollout)
+	*nr = *r
+	nr.Strategy = r.Strategy.Copy()
+	if r.Regions != nil {
+		nr.Regions = make([]*MultiregionRolloutRegion, len(r.Regions))
+		for i, region := range r.Regions {
+			nregion := *region
+			nr.Regions[i] = &nregion
+		}
+	}
+	return nr
+}
+
+const (
+	// JobSubmissionFormatHCL and JobSubmissionFormatJSON are the formats of
+	// the source of a job submission
+	JobSubmissionFormatHCL  = "hcl"
+	JobSubmissionFormatJSON = "json"
+
+	// MaxJobSubmissionSize is the size above which the source of a job
+	// submission is discarded rather than stored
+	MaxJobSubmissionSize = 1024 * 1024
+)
+
+// JobTrackedVersions is the number of versions tracked per job
+const JobTrackedVersions = 6
+
+// JobVersions holds the most recent versions of a job, so that they can be
+// compared with each other
+type JobVersions struct {
+	JobID     string
+	Namespace string
+
+	// Versions are the versions of the job, most recent first
+	Versions []*Job
+
+	ModifyIndex uint64
+}
+
+// Copy returns a copy of the versions. The jobs themselves are immutable and
+// are shared.
+func (v *JobVersions) Copy() *JobVersions {
+	if v == nil {
+		return nil
+	}
+
+	nv := new(JobVersions)
+	*nv = *v
+	nv.Versions = append([]*Job(nil), v.Versions...)
+	return nv
+}
+
+// Version returns the given version of the job or nil if it isn't tracked
+func (v *JobVersions) Version(version uint64) *Job {
+	if v == nil {
+		return nil
+	}
+	for _, job := range v.Versions {
+		if job.Version == version {
+			return job
+		}
+	}
+	return nil
+}
+
+// JobSubmission is the source a job was parsed from, exactly as it was
+// submitted. Only the source of the latest submission of a job is kept, so
+// comparing its JobModifyIndex with that of the job tells whether the job was
+// modified by other means since, such as by scaling it.
+type JobSubmission struct {
+	// JobID is the ID of the job the source was parsed into
+	JobID string
+
+	// Namespace is the namespace of the job
+	Namespace string
+
+	// Source is the content of the job file
+	Source string
+
+	// Format is the format of the source, either hcl or json
+	Format string
+
+	// JobModifyIndex is the job modify index of the job registered from the
+	// source
+	JobModifyIndex uint64
+
+	// SubmitTime is the time the source was submitted at
+	SubmitTime int64
+}
+
+// Copy returns a copy of the submission
+func (s *JobSubmission) Copy() *JobSubmission {
+	if s == nil {
+		return nil
+	}
+	ns := new(JobSubmission)
+	*ns = *s
+	return ns
+}
+
+// Validate validates the submission
+func (s *JobSubmission) Validate() error {
+	switch s.Format {
+	case JobSubmissionFormatHCL, JobSubmissionFormatJSON:
+	default:
+		return fmt.Errorf("invalid job submission format %q", s.Format)
+	}
+	return nil
+}
+
+const (
+	// PeriodicSpecCron is used for a cron spec.
+	PeriodicSpecCron = "cron"
+
+	// PeriodicSpecTest is only used by unit tests. It is a sorted, comma
+	// separated list of unix timestamps at which to launch.
+	PeriodicSpecTest = "_internal_test"
+)
+
+// Periodic defines the interval a job should be run at.
+type PeriodicConfig struct {
+	// Enabled determines if the job should be run periodically.
+	Enabled bool
+
+	// Spec specifies the interval the job should be run as. It is parsed based
+	// on the SpecType.
+	Spec string
+
+	// SpecType defines the format of the spec.
+	SpecType string
+
+	// ProhibitOverlap enforces that spawned jobs do not run in parallel.
+	ProhibitOverlap bool `mapstructure:"prohibit_overlap"`
+
+	// TimeZone is the name of the time zone the cron specs are evaluated in,
+	// such as "America/New_York". Specs are evaluated in UTC if it is unset.
+	TimeZone string `mapstructure:"time_zone"`
+
+	// Specs are additional specifications the job is launched at, parsed
+	// based on the SpecType. They allow a job to run at several distinct
+	// times that a single spec can't express.
+	Specs []*PeriodicSpec
+}
+
+// PeriodicSpec is an additional specification a periodic job is launched at
+type PeriodicSpec struct {
+	// Spec specifies the interval the job should be run at.
+	Spec string
+
+	// Enabled determines if the job is launched at the spec.
+	Enabled bool
+}
+
+// PeriodicLaunchTime is the next launch time of a periodic job for one of its
+// specs.
+type PeriodicLaunchTime struct {
+	// Spec is the specification the launch time is computed from.
+	Spec string
+
+	// Enabled determines if the job is launched at the spec.
+	Enabled bool
+
+	// Next is the next launch time matching the spec. It is the zero value of
+	// time.Time if the spec is disabled or has no future match.
+	Next time.Time
+}
+
+func (p *PeriodicConfig) Copy() *PeriodicConfig {
+	if p == nil {
+		return nil
+	}
+	np := new(PeriodicConfig)
+	*np = *p
+	if p.Specs != nil {
+		np.Specs = make([]*PeriodicSpec, len(p.Specs))
+		for i, spec := range p.Specs {
+			ns := *spec
+			np.Specs[i] = &ns
+		}
+	}
+	return np
+}
+
+func (p *PeriodicConfig) Validate() error {
+	if !p.Enabled {
+		return nil
+	}
+
+	if p.Spec == "" && len(p.Specs) == 0 {
+		return fmt.Errorf("Must specify a spec")
+	}
+
+	var mErr multierror.Error
+	if _, err := time.LoadLocation(p.TimeZone); err != nil {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Invalid time zone %q: %v", p.TimeZone, err))
+	}
+
+	switch p.SpecType {
+	case PeriodicSpecCron:
+		// Validate the cron specs
+		for _, spec := range p.allSpecs() {
+			if spec.Spec == "" {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("Must specify a spec"))
+			} else if _, err := cronexpr.Parse(spec.Spec); err != nil {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("Invalid cron spec %q: %v", spec.Spec, err))
+			}
+		}
+	case PeriodicSpecTest:
+		// No-op
+	default:
+		return fmt.Errorf("Unknown periodic specification type %q", p.SpecType)
+	}
+
+	if len(mErr.Errors) == 1 {
+		return mErr.Errors[0]
+	}
+	return mErr.ErrorOrNil()
+}
+
+// allSpecs returns the spec of the periodic config, which is enabled along
+// with the config, followed by its additional specs
+func (p *PeriodicConfig) allSpecs() []*PeriodicSpec {
+	specs := make([]*PeriodicSpec, 0, len(p.Specs)+1)
+	if p.Spec != "" {
+		specs = append(specs, &PeriodicSpec{Spec: p.Spec, Enabled: true})
+	}
+	return append(specs, p.Specs...)
+}
+
+// Next returns the closest time instant matching one of the enabled specs that
+// is after the passed time. If no matching instance exists, the zero value of
+// time.Time is returned. The `time.Location` of the returned value matches
+// that of the passed time.
+func (p *PeriodicConfig) Next(fromTime time.Time) time.Time {
+	var next time.Time
+	for _, spec := range p.allSpecs() {
+		if !spec.Enabled {
+			continue
+		}
+		if n := p.nextSpec(spec.Spec, fromTime); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
+
+// NextLaunches returns the next launch time after the passed time for each of
+// the specs of the periodic config.
+func (p *PeriodicConfig) NextLaunches(fromTime time.Time) []*PeriodicLaunchTime {
+	specs := p.allSpecs()
+	launches := make([]*PeriodicLaunchTime, len(specs))
+	for i, spec := range specs {
+		launches[i] = &PeriodicLaunchTime{
+			Spec:    spec.Spec,
+			Enabled: spec.Enabled,
+		}
+		if p.Enabled && spec.Enabled {
+			launches[i].Next = p.nextSpec(spec.Spec, fromTime)
+		}
+	}
+	return launches
+}
+
+// GetLocation returns the location of the time zone the specs are evaluated
+// in. UTC is returned if the time zone is unset or invalid.
+func (p *PeriodicConfig) GetLocation() *time.Location {
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// nextSpec returns the closest time instant matching the spec that is after
+// the passed time, or the zero value of time.Time if none exists.
+func (p *PeriodicConfig) nextSpec(spec string, fromTime time.Time) time.Time {
+	switch p.SpecType {
+	case PeriodicSpecCron:
+		// Cron specs are matched against the wall clock of the time zone
+		e, err := cronexpr.Parse(spec)
+		if err != nil {
+			return time.Time{}
+		}
+		next := e.Next(fromTime.In(p.GetLocation()))
+		if next.IsZero() {
+			return next
+		}
+		return next.In(fromTime.Location())
+	case PeriodicSpecTest:
+		split := strings.Split(spec, ",")
+		if len(split) == 1 && split[0] == "" {
+			return time.Time{}
+		}
+
+		// Parse the times
+		times := make([]time.Time, len(split))
+		for i, s := range split {
+			unix, err := strconv.Atoi(s)
+			if err != nil {
+				return time.Time{}
+			}
+
+			times[i] = time.Unix(int64(unix), 0)
+		}
+
+		// Find the next match
+		for _, next := range times {
+			if fromTime.Before(next) {
+				return next
+			}
+		}
+	}
+
+	return time.Time{}
+}
+
+const (
+	// PeriodicLaunchSuffix is the string appended to the periodic jobs ID
+	// when launching derived instances of it.
+	PeriodicLaunchSuffix = "/periodic-"
+)
+
+const (
+	// DispatchPayloadForbidden denotes that a payload can not be passed when
+	// dispatching the job
+	DispatchPayloadForbidden = "forbidden"
+
+	// DispatchPayloadOptional denotes that a payload may be passed when
+	// dispatching the job
+	DispatchPayloadOptional = "optional"
+
+	// DispatchPayloadRequired denotes that a payload must be passed when
+	// dispatching the job
+	DispatchPayloadRequired = "required"
+
+	// DispatchLaunchSuffix is the string appended to the parameterized job's
+	// ID when dispatching instances of it.
+	DispatchLaunchSuffix = "/dispatch-"
+
+	// DispatchPayloadSizeLimit is the maximum size of the payload of a
+	// dispatched job, in bytes.
+	DispatchPayloadSizeLimit = 16 * 1024
+)
+
+// ParameterizedJobConfig is used to configure the parameterized job
+type ParameterizedJobConfig struct {
+	// Payload configures whether a payload is forbidden, optional or
+	// required when dispatching the job
+	Payload string
+
+	// MetaRequired are the meta keys that must be set when dispatching
+	MetaRequired []string `mapstructure:"meta_required"`
+
+	// MetaOptional are the meta keys that may be set when dispatching
+	MetaOptional []string `mapstructure:"meta_optional"`
+}
+
+func (d *ParameterizedJobConfig) Validate() error {
+	var mErr multierror.Error
+	switch d.Payload {
+	case DispatchPayloadOptional, DispatchPayloadRequired, DispatchPayloadForbidden:
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Unknown payload requirement: %q", d.Payload))
+	}
+
+	// Check that the meta configurations are disjoint sets
+	disjoint, offending := SliceSetDisjoint(d.MetaRequired, d.MetaOptional)
+	if !disjoint {
+		mErr.Errors = append(mErr.Errors,
+			fmt.Errorf("Required and optional meta keys should be disjoint. Following keys exist in both: %v", offending))
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+func (d *ParameterizedJobConfig) Canonicalize() {
+	if d.Payload == "" {
+		d.Payload = DispatchPayloadOptional
+	}
+}
+
+func (d *ParameterizedJobConfig) Copy() *ParameterizedJobConfig {
+	if d == nil {
+		return nil
+	}
+	nd := new(ParameterizedJobConfig)
+	*nd = *d
+	nd.MetaOptional = CopySliceString(nd.MetaOptional)
+	nd.MetaRequired = CopySliceString(nd.MetaRequired)
+	return nd
+}
+
+// DispatchedID returns the ID of a job dispatched from the parameterized job
+// with the given ID at the given time
+func DispatchedID(templateID string, t time.Time) string {
+	u := GenerateUUID()[:8]
+	return fmt.Sprintf("%s%s%d-%s", templateID, DispatchLaunchSuffix, t.Unix(), u)
+}
+
+// DispatchPayloadConfig configures how a task gets its input from a job
+// dispatch
+type DispatchPayloadConfig struct {
+	// File specifies a relative path to where the input data should be
+	// written in the task's local directory
+	File string
+}
+
+func (d *DispatchPayloadConfig) Copy() *DispatchPayloadConfig {
+	if d == nil {
+		return nil
+	}
+	nd := new(DispatchPayloadConfig)
+	*nd = *d
+	return nd
+}
+
+func (d *DispatchPayloadConfig) Validate() error {
+	if d.File == "" {
+		return fmt.Errorf("file must be specified")
+	}
+
+	// Verify the destination doesn't escape, with the file being relative to
+	// the task's local directory
+	escaped, err := pathEscapesAllocDir(filepath.Join("local", d.File))
+	if err != nil {
+		return fmt.Errorf("invalid destination path: %v", err)
+	} else if escaped {
+		return fmt.Errorf("destination escapes allocation directory")
+	}
+
+	return nil
+}
+
+// PeriodicLaunch tracks the last launch time of a periodic job.
+type PeriodicLaunch struct {
+	ID        string    // ID of the periodic job.
+	Namespace string    // Namespace of the periodic job.
+	Launch    time.Time // The last launch time.
+
+	// Raft Indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+var (
+	defaultServiceJobRestartPolicy = RestartPolicy{
+		Delay:    15 * time.Second,
+		Attempts: 2,
+		Interval: 1 * time.Minute,
+		Mode:     RestartPolicyModeDelay,
+	}
+	defaultBatchJobRestartPolicy = RestartPolicy{
+		Delay:    15 * time.Second,
+		Attempts: 15,
+		Interval: 7 * 24 * time.Hour,
+		Mode:     RestartPolicyModeDelay,
+	}
+)
+
+const (
+	// RestartPolicyModeDelay causes an artificial delay till the next interval is
+	// reached when the specified attempts have been reached in the interval.
+	RestartPolicyModeDelay = "delay"
+
+	// RestartPolicyModeFail causes a job to fail if the specified number of
+	// attempts are reached within an interval.
+	RestartPolicyModeFail = "fail"
+)
+
+// RestartPolicy configures how Tasks are restarted when they crash or fail.
+type RestartPolicy struct {
+	// Attempts is the number of restart that will occur in an interval.
+	Attempts int
+
+	// Interval is a duration in which we can limit the number of restarts
+	// within.
+	Interval time.Duration
+
+	// Delay is the time between a failure and a restart.
+	Delay time.Duration
+
+	// Mode controls what happens when the task restarts more than attempt times
+	// in an interval.
+	Mode string
+}
+
+func (r *RestartPolicy) Copy() *RestartPolicy {
+	if r == nil {
+		return nil
+	}
+	nrp := new(RestartPolicy)
+	*nrp = *r
+	return nrp
+}
+
+func (r *RestartPolicy) Validate() error {
+	switch r.Mode {
+	case RestartPolicyModeDelay, RestartPolicyModeFail:
+	default:
+		return fmt.Errorf("Unsupported restart mode: %q", r.Mode)
+	}
+
+	// Check for ambiguous/confusing settings
+	if r.Attempts == 0 && r.Mode != RestartPolicyModeFail {
+		return fmt.Errorf("Restart policy %q with %d attempts is ambiguous", r.Mode, r.Attempts)
+	}
+
+	if r.Interval == 0 {
+		return nil
+	}
+	if time.Duration(r.Attempts)*r.Delay > r.Interval {
+		return fmt.Errorf("Nomad can't restart the TaskGroup %v times in an interval of %v with a delay of %v", r.Attempts, r.Interval, r.Delay)
+	}
+	return nil
+}
+
+func NewRestartPolicy(jobType string) *RestartPolicy {
+	switch jobType {
+	case JobTypeService, JobTypeSystem:
+		rp := defaultServiceJobRestartPolicy
+		return &rp
+	case JobTypeBatch:
+		rp := defaultBatchJobRestartPolicy
+		return &rp
+	}
+	return nil
+}
+
+// TaskGroup is an atomic unit of placement. Each task group belongs to
+// a job and may contain any number of tasks. A task group support running
+// in many replicas using the same configuration..
+type TaskGroup struct {
+	// Name of the task group
+	Name string
+
+	// Count is the number of replicas of this task group that should
+	// be scheduled.
+	Count int
+
+	// Constraints can be specified at a task group level and apply to
+	// all the tasks contained.
+	Constraints []*Constraint
+
+	//RestartPolicy of a TaskGroup
+	RestartPolicy *RestartPolicy
+
+	// Tasks are the collection of tasks that this task group needs to run
+	Tasks []*Task
+
+	// EphemeralDisk is the disk resources that the task group requests
+	EphemeralDisk *EphemeralDisk
+
+	// Networks are the networks shared by all the tasks of the task group. At
+	// most one network may be requested.
+	Networks []*NetworkResource
+
+	// Volumes are the volumes the tasks of the task group can mount, keyed by
+	// their name. The task group is only placed on clients providing them.
+	Volumes map[string]*VolumeRequest
+
+	// Meta is used to associate arbitrary metadata with this
+	// task group. This is opaque to Nomad.
+	Meta map[string]string
+
+	// Scaling is the policy external autoscalers use to scale the task group
+	Scaling *ScalingPolicy
+
+	// Migrate is how the allocations of the task group are migrated off of
+	// draining nodes. When nil, the default migrate strategy is used.
+	Migrate *MigrateStrategy
+}
+
+func (tg *TaskGroup) Copy() *TaskGroup {
+	if tg == nil {
+		return nil
+	}
+	ntg := new(TaskGroup)
+	*ntg = *tg
+	ntg.Constraints = CopySliceConstraints(ntg.Constraints)
+
+	ntg.RestartPolicy = ntg.RestartPolicy.Copy()
+
+	if tg.Tasks != nil {
+		tasks := make([]*Task, len(ntg.Tasks))
+		for i, t := range ntg.Tasks {
+			tasks[i] = t.Copy()
+		}
+		ntg.Tasks = tasks
+	}
+
+	ntg.Meta = CopyMapStringString(ntg.Meta)
+
+	if tg.Networks != nil {
+		networks := make([]*NetworkResource, len(tg.Networks))
+		for i, n := range tg.Networks {
+			networks[i] = n.Copy()
+		}
+		ntg.Networks = networks
+	}
+
+	if tg.EphemeralDisk != nil {
+		ntg.EphemeralDisk = tg.EphemeralDisk.Copy()
+	}
+
+	if tg.Volumes != nil {
+		volumes := make(map[string]*VolumeRequest, len(tg.Volumes))
+		for name, v := range tg.Volumes {
+			volumes[name] = v.Copy()
+		}
+		ntg.Volumes = volumes
+	}
+
+	ntg.Scaling = tg.Scaling.Copy()
+	ntg.Migrate = tg.Migrate.Copy()
+	return ntg
+}
+
+// Canonicalize is used to canonicalize fields in the TaskGroup.
+func (tg *TaskGroup) Canonicalize(job *Job) {
+	// Ensure that an empty and nil map are treated the same to avoid scheduling
+	// problems since we use reflect DeepEquals.
+	if len(tg.Meta) == 0 {
+		tg.Meta = nil
+	}
+
+	// Set the default restart policy.
+	if tg.RestartPolicy == nil {
+		tg.RestartPolicy = NewRestartPolicy(job.Type)
+	}
+
+	if len(tg.Networks) == 0 {
+		tg.Networks = nil
+	}
+	if len(tg.Volumes) == 0 {
+		tg.Volumes = nil
+	}
+	for name, v := range tg.Volumes {
+		v.Name = name
+	}
+	if tg.Migrate != nil {
+		tg.Migrate.Canonicalize()
+	}
+	for _, n := range tg.Networks {
+		n.Canonicalize()
+		if n.Mode == "" {
+			n.Mode = NetworkModeHost
+		}
+	}
+
+	for _, task := range tg.Tasks {
+		task.Canonicalize(job, tg)
+	}
+}
+
+// IsolatedNetwork returns the network of the task group if it uses a network
+// namespace of its own
+func (tg *TaskGroup) IsolatedNetwork() *NetworkResource {
+	for _, n := range tg.Networks {
+		if n.Isolated() {
+			return n
+		}
+	}
+	return nil
+}
+
+// Validate is used to sanity check a task group
+func (tg *TaskGroup) Validate() error {
+	var mErr multierror.Error
+	if tg.Name == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("Missing task group name"))
+	}
+	if tg.Count < 0 {
+		mErr.Errors = append(mErr.Errors, errors.New("Task group count can't be negative"))
+	}
+	if tg.Scaling != nil {
+		if err := tg.Scaling.Validate(); err != nil {
+			outer := fmt.Errorf("Scaling policy validation failed: %s", err)
+			mErr.Errors = append(mErr.Errors, outer)
+		} else if count := int64(tg.Count); count < tg.Scaling.Min || count > tg.Scaling.Max {
+			mErr.Errors = append(mErr.Errors,
+				fmt.Errorf("Task group count %d is outside of the scaling policy bounds [%d, %d]", count, tg.Scaling.Min, tg.Scaling.Max))
+		}
+	}
+	if len(tg.Tasks) == 0 {
+		mErr.Errors = append(mErr.Errors, errors.New("Missing tasks for task group"))
+	}
+	for idx, constr := range tg.Constraints {
+		if err := constr.Validate(); err != nil {
+			outer := fmt.Errorf("Constraint %d validation failed: %s", idx+1, err)
+			mErr.Errors = append(mErr.Errors, outer)
+		}
+	}
+
+	if tg.RestartPolicy != nil {
+		if err := tg.RestartPolicy.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	} else {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Task Group %v should have a restart policy", tg.Name))
+	}
+
+	if tg.EphemeralDisk != nil {
+		if err := tg.EphemeralDisk.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	} else {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Task Group %v should have a ephemeral disk object", tg.Name))
+	}
+
+	if tg.Migrate != nil {
+		if err := tg.Migrate.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Migrate strategy validation failed: %v", err))
+		}
+	}
+
+	// Check for duplicate tasks and that the group has a main task
+	tasks := make(map[string]int)
+	var mainTasks int
+	for idx, task := range tg.Tasks {
+		if task.IsMainTask() {
+			mainTasks++
+		}
+		if task.Name == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Task %d missing name", idx+1))
+		} else if existing, ok := tasks[task.Name]; ok {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Task %d redefines '%s' from task %d", idx+1, task.Name, existing+1))
+		} else {
+			tasks[task.Name] = idx
+		}
+	}
+	if len(tg.Tasks) != 0 && mainTasks == 0 {
+		mErr.Errors = append(mErr.Errors, errors.New("Task group must have at least one task without a lifecycle"))
+	}
+
+	// Validate the networks
+	if len(tg.Networks) > 1 {
+		mErr.Errors = append(mErr.Errors, errors.New("Only one network resource may be specified per task group"))
+	}
+	for idx, n := range tg.Networks {
+		if err := n.Validate(); err != nil {
+			outer := fmt.Errorf("Network %d validation failed: %s", idx+1, err)
+			mErr.Errors = append(mErr.Errors, outer)
+		}
+	}
+	if err := tg.validatePortLabels(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+
+	// Tasks joining the network namespace of the allocation can't request
+	// networks of their own
+	if n := tg.IsolatedNetwork(); n != nil {
+		for _, task := range tg.Tasks {
+			if task.Resources != nil && len(task.Resources.Networks) != 0 {
+				mErr.Errors = append(mErr.Errors,
+					fmt.Errorf("Task %s can't request networks when the task group uses %q networking", task.Name, n.Mode))
+			}
+		}
+	}
+
+	// Validate the volumes and that the tasks only mount volumes of the group
+	for _, name := range tg.volumeNames() {
+		if err := tg.Volumes[name].Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+	for _, task := range tg.Tasks {
+		for idx, m := range task.VolumeMounts {
+			if err := m.Validate(tg.Volumes); err != nil {
+				outer := fmt.Errorf("Task %s volume mount %d validation failed: %s", task.Name, idx+1, err)
+				mErr.Errors = append(mErr.Errors, outer)
+			}
+		}
+	}
+
+	// Validate the tasks
+	for _, task := range tg.Tasks {
+		if err := task.Validate(tg.EphemeralDisk); err != nil {
+			outer := fmt.Errorf("Task %s validation failed: %s", task.Name, err)
+			mErr.Errors = append(mErr.Errors, outer)
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// validatePortLabels ensures the port labels of the task group network do not
+// collide with each other or with the port labels of the tasks
+func (tg *TaskGroup) validatePortLabels() error {
+	var mErr multierror.Error
+	labels := make(map[string]struct{})
+	for _, n := range tg.Networks {
+		for _, port := range append(n.ReservedPorts, n.DynamicPorts...) {
+			if _, ok := labels[port.Label]; ok {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("Port label %q is duplicate", port.Label))
+			}
+			labels[port.Label] = struct{}{}
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+
+	for _, task := range tg.Tasks {
+		if task.Resources == nil {
+			continue
+		}
+		for _, n := range task.Resources.Networks {
+			for _, port := range append(n.ReservedPorts, n.DynamicPorts...) {
+				if _, ok := labels[port.Label]; ok {
+					mErr.Errors = append(mErr.Errors,
+						fmt.Errorf("Task %s port label %q collides with the task group network", task.Name, port.Label))
+				}
+			}
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// volumeNames returns the sorted names of the volumes of the task group
+func (tg *TaskGroup) volumeNames() []string {
+	names := make([]string, 0, len(tg.Volumes))
+	for name := range tg.Volumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupTask finds a task by name
+func (tg *TaskGroup) LookupTask(name string) *Task {
+	for _, t := range tg.Tasks {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (tg *TaskGroup) GoString() string {
+	return fmt.Sprintf("*%#v", *tg)
+}
+
+// ScalingPolicy bounds the count of a task group and carries the policy an
+// external autoscaler uses to drive it
+type ScalingPolicy struct {
+	// Min and Max bound the count the task group can be scaled to
+	Min int64
+	Max int64
+
+	// Policy is the policy document of the autoscaler. It is opaque to Nomad.
+	Policy map[string]interface{}
+
+	// Enabled marks whether the autoscaler should act on the policy
+	Enabled bool
+}
+
+// Copy returns a copy of the scaling policy
+func (p *ScalingPolicy) Copy() *ScalingPolicy {
+	if p == nil {
+		return nil
+	}
+
+	np := new(ScalingPolicy)
+	*np = *p
+	if i, err := copystructure.Copy(p.Policy); err == nil {
+		np.Policy = i.(map[string]interface{})
+	}
+	return np
+}
+
+// Validate returns if the scaling policy is valid
+func (p *ScalingPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	var mErr multierror.Error
+	if p.Min < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Minimum count can't be negative: %d", p.Min))
+	}
+	if p.Max < p.Min {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Maximum count %d must be greater than or equal to the minimum count %d", p.Max, p.Min))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ScalingPolicyListStub is used to return a subset of the scaling policy of a
+// task group when listing the policies
+type ScalingPolicyListStub struct {
+	Namespace string
+	JobID     string
+	Group     string
+	Min       int64
+	Max       int64
+	Enabled   bool
+
+	// JobModifyIndex is the modify index of the job the policy belongs to
+	JobModifyIndex uint64
+}
+
+const (
+	// ScalingTargetGroup is the key of the scale request target naming the
+	// task group to scale
+	ScalingTargetGroup = "Group"
+
+	// JobTrackedScalingEvents is the number of scaling events tracked per
+	// task group
+	JobTrackedScalingEvents = 20
+)
+
+// ScalingEvent describes a request to scale a task group, whether or not it
+// changed the count of the task group
+type ScalingEvent struct {
+	// Time is the time of the event in nanoseconds since the Unix epoch
+	Time int64
+
+	// Count is the count requested. It is nil if the event only recorded a
+	// message, such as an autoscaler error.
+	Count *int64
+
+	// PreviousCount is the count of the task group when the event was
+	// recorded
+	PreviousCount int64
+
+	// Message describes the event
+	Message string
+
+	// Error marks whether the event is an error
+	Error bool
+
+	// Meta is opaque metadata attached by the requester
+	Meta map[string]interface{}
+
+	// EvalID is the ID of the evaluation created by the event, if any
+	EvalID string
+
+	CreateIndex uint64
+}
+
+// JobScalingEvents holds the most recent scaling events of the task groups of
+// a job
+type JobScalingEvents struct {
+	JobID     string
+	Namespace string
+
+	// ScalingEvents are the events of each task group, most recent first
+	ScalingEvents map[string][]*ScalingEvent
+
+	ModifyIndex uint64
+}
+
+// Copy returns a copy of the scaling events. The events themselves are
+// immutable and are shared.
+func (e *JobScalingEvents) Copy() *JobScalingEvents {
+	if e == nil {
+		return nil
+	}
+
+	ne := new(JobScalingEvents)
+	*ne = *e
+	ne.ScalingEvents = make(map[string][]*ScalingEvent, len(e.ScalingEvents))
+	for group, events := range e.ScalingEvents {
+		ne.ScalingEvents[group] = append([]*ScalingEvent(nil), events...)
+	}
+	return ne
+}
+
+// JobScaleStatus is the scaling status of the task groups of a job
+type JobScaleStatus struct {
+	JobID          string
+	JobCreateIndex uint64
+	JobModifyIndex uint64
+	JobStopped     bool
+	TaskGroups     map[string]*TaskGroupScaleStatus
+}
+
+// TaskGroupScaleStatus is the scaling status of a task group
+type TaskGroupScaleStatus struct {
+	// Desired is the count of the task group
+	Desired int
+
+	// Placed is the number of non-terminal allocations of the task group
+	Placed int
+
+	// Running is the number of allocations of the task group whose tasks are
+	// running
+	Running int
+
+	// Events are the most recent scaling events of the task group
+	Events []*ScalingEvent
+}
+
+const (
+	// TODO add Consul TTL check
+	ServiceCheckHTTP   = "http"
+	ServiceCheckTCP    = "tcp"
+	ServiceCheckScript = "script"
+
+	// minCheckInterval is the minimum check interval permitted.  Consul
+	// currently has its MinInterval set to 1s.  Mirror that here for
+	// consistency.
+	minCheckInterval = 1 * time.Second
+
+	// minCheckTimeout is the minimum check timeout permitted for Consul
+	// script TTL checks.
+	minCheckTimeout = 1 * time.Second
+)
+
+const (
+	// OnUpdateRequireHealthy is the default check update mode, in which the
+	// check must be passing for the allocation to be healthy
+	OnUpdateRequireHealthy = "require_healthy"
+
+	// OnUpdateIgnoreWarnings treats a check in the warning state as healthy
+	OnUpdateIgnoreWarnings = "ignore_warnings"
+
+	// OnUpdateIgnore ignores the check when determining the allocation's
+	// health
+	OnUpdateIgnore = "ignore"
+)
+
+// The ServiceCheck data model represents the consul health check that
+// Nomad registers for a Task
+type ServiceCheck struct {
+	Name          string        // Name of the check, defaults to id
+	Type          string        // Type of the check - tcp, http, docker and script
+	Command       string        // Command is the command to run for script checks
+	Args          []string      // Args is a list of argumes for script checks
+	Path          string        // path of the health check url for http type check
+	Protocol      string        // Protocol to use if check is http, defaults to http
+	PortLabel     string        `mapstructure:"port"` // The port to use for tcp/http checks
+	Interval      time.Duration // Interval of the check
+	Timeout       time.Duration // Timeout of the response from the check before consul fails the check
+	InitialStatus string        `mapstructure:"initial_status"` // Initial status of the check
+
+	// OnUpdate determines how the status of the check affects the health of
+	// the allocation: one of "require_healthy", the default,
+	// "ignore_warnings" or "ignore".
+	OnUpdate string `mapstructure:"on_update"`
+
+	// Readiness marks checks that only gate whether the instance receives
+	// traffic. A failing readiness check removes the instance from service
+	// discovery but never makes the allocation unhealthy.
+	Readiness bool
+
+	// CheckRestart restarts the task once the check has been unhealthy for
+	// a number of consecutive check intervals.
+	CheckRestart *CheckRestart `mapstructure:"check_restart"`
+}
+
+func (sc *ServiceCheck) Copy() *ServiceCheck {
+	if sc == nil {
+		return nil
+	}
+	nsc := new(ServiceCheck)
+	*nsc = *sc
+	nsc.CheckRestart = nsc.CheckRestart.Copy()
+	return nsc
+}
+
+func (sc *ServiceCheck) Canonicalize(serviceName string) {
+	// Ensure empty slices are treated as null to avoid scheduling issues when
+	// using DeepEquals.
+	if len(sc.Args) == 0 {
+		sc.Args = nil
+	}
+
+	if sc.Name == "" {
+		sc.Name = fmt.Sprintf("service: %q check", serviceName)
+	}
+}
+
+// validate a Service's ServiceCheck
+func (sc *ServiceCheck) validate() error {
+	switch strings.ToLower(sc.Type) {
+	case ServiceCheckTCP:
+		if sc.Timeout < minCheckTimeout {
+			return fmt.Errorf("timeout (%v) is lower than required minimum timeout %v", sc.Timeout, minCheckInterval)
+		}
+	case ServiceCheckHTTP:
+		if sc.Path == "" {
+			return fmt.Errorf("http type must have a valid http path")
+		}
+
+		if sc.Timeout < minCheckTimeout {
+			return fmt.Errorf("timeout (%v) is lower than required minimum timeout %v", sc.Timeout, minCheckInterval)
+		}
+	case ServiceCheckScript:
+		if sc.Command == "" {
+			return fmt.Errorf("script type must have a valid script path")
+		}
+
+		// TODO: enforce timeout on the Client side and reenable
+		// validation.
+	default:
+		return fmt.Errorf(`invalid type (%+q), must be one of "http", "tcp", or "script" type`, sc.Type)
+	}
+
+	if sc.Interval < minCheckInterval {
+		return fmt.Errorf("interval (%v) can not be lower than %v", sc.Interval, minCheckInterval)
+	}
+
+	switch sc.InitialStatus {
+	case "":
+	case api.HealthUnknown:
+	case api.HealthPassing:
+	case api.HealthWarning:
+	case api.HealthCritical:
+	default:
+		return fmt.Errorf(`invalid initial check state (%s), must be one of %q, %q, %q, %q or empty`, sc.InitialStatus, api.HealthUnknown, api.HealthPassing, api.HealthWarning, api.HealthCritical)
+
+	}
+
+	switch sc.OnUpdate {
+	case "", OnUpdateRequireHealthy, OnUpdateIgnoreWarnings, OnUpdateIgnore:
+	default:
+		return fmt.Errorf(`invalid on_update (%s), must be one of %q, %q, %q or empty`, sc.OnUpdate, OnUpdateRequireHealthy, OnUpdateIgnoreWarnings, OnUpdateIgnore)
+	}
+
+	if sc.CheckRestart != nil {
+		if err := sc.CheckRestart.Validate(); err != nil {
+			return fmt.Errorf("invalid check_restart: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// CheckRestart restarts a task once one of its checks has been unhealthy for
+// a number of consecutive check intervals
+type CheckRestart struct {
+	Limit          int           // Consecutive unhealthy intervals before a restart, 0 disables restarts
+	Grace          time.Duration // Time after the task starts during which the check is ignored
+	IgnoreWarnings bool          `mapstructure:"ignore_warnings"` // Whether the warning status counts as healthy
+}
+
+func (c *CheckRestart) Copy() *CheckRestart {
+	if c == nil {
+		return nil
+	}
+	nc := new(CheckRestart)
+	*nc = *c
+	return nc
+}
+
+func (c *CheckRestart) Validate() error {
+	if c.Limit < 0 {
+		return fmt.Errorf("limit must be greater than or equal to 0 but was %d", c.Limit)
+	}
+	if c.Grace < 0 {
+		return fmt.Errorf("grace period must be greater than or equal to 0 but was %v", c.Grace)
+	}
+	return nil
+}
+
+// RequiresPort returns whether the service check requires the task has a port.
+func (sc *ServiceCheck) RequiresPort() bool {
+	switch sc.Type {
+	case ServiceCheckHTTP, ServiceCheckTCP:
+		return true
+	default:
+		return false
+	}
+}
+
+func (sc *ServiceCheck) Hash(serviceID string) string {
+	h := sha1.New()
+	io.WriteString(h, serviceID)
+	io.WriteString(h, sc.Name)
+	io.WriteString(h, sc.Type)
+	io.WriteString(h, sc.Command)
+	io.WriteString(h, strings.Join(sc.Args, ""))
+	io.WriteString(h, sc.Path)
+	io.WriteString(h, sc.Protocol)
+	io.WriteString(h, sc.PortLabel)
+	io.WriteString(h, sc.Interval.String())
+	io.WriteString(h, sc.Timeout.String())
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// Service represents a Consul service definition in Nomad
+type Service struct {
+	// Name of the service registered with Consul. Consul defaults the
+	// Name to ServiceID if not specified.  The Name if specified is used
+	// as one of the seed values when generating a Consul ServiceID.
+	Name string
+
+	// PortLabel is either the numeric port number or the `host:port`.
+	// To specify the port number using the host's Consul Advertise
+	// address, specify an empty host in the PortLabel (e.g. `:port`).
+	PortLabel string          `mapstructure:"port"`
+	Tags      []string        // List of tags for the service
+	Checks    []*ServiceCheck // List of checks associated with the service
+
+	// Provider is the catalog the service is registered in. It defaults to
+	// Consul, while services using the Nomad provider are registered in
+	// Nomad's built-in service catalog.
+	Provider string
+}
+
+const (
+	ServiceProviderConsul = "consul"
+	ServiceProviderNomad  = "nomad"
+)
+
+func (s *Service) Copy() *Service {
+	if s == nil {
+		return nil
+	}
+	ns := new(Service)
+	*ns = *s
+	ns.Tags = CopySliceString(ns.Tags)
+
+	if s.Checks != nil {
+		checks := make([]*ServiceCheck, len(ns.Checks))
+		for i, c := range ns.Checks {
+			checks[i] = c.Copy()
+		}
+		ns.Checks = checks
+	}
+
+	return ns
+}
+
+// Canonicalize interpolates values of Job, Task Group and Task in the Service
+// Name. This also generates check names, service id and check ids.
+func (s *Service) Canonicalize(job string, taskGroup string, task string) {
+	// Ensure empty lists are treated as null to avoid scheduler issues when
+	// using DeepEquals
+	if len(s.Tags) == 0 {
+		s.Tags = nil
+	}
+	if len(s.Checks) == 0 {
+		s.Checks = nil
+	}
+
+	s.Name = args.ReplaceEnv(s.Name, map[string]string{
+		"JOB":       job,
+		"TASKGROUP": taskGroup,
+		"TASK":      task,
+		"BASE":      fmt.Sprintf("%s-%s-%s", job, taskGroup, task),
+	},
+	)
+
+	for _, check := range s.Checks {
+		check.Canonicalize(s.Name)
+	}
+}
+
+// Validate checks if the Check definition is valid
+func (s *Service) Validate() error {
+	var mErr multierror.Error
+
+	// Ensure the service name is valid per RFC-952 §1
+	// (https://tools.ietf.org/html/rfc952), RFC-1123 §2.1
+	// (https://tools.ietf.org/html/rfc1123), and RFC-2782
+	// (https://tools.ietf.org/html/rfc2782).
+	re := regexp.MustCompile(`^(?i:[a-z0-9]|[a-z0-9][a-z0-9\-]{0,61}[a-z0-9])$`)
+	if !re.MatchString(s.Name) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("service name must be valid per RFC 1123 and can contain only alphanumeric characters or dashes and must be less than 63 characters long: %q", s.Name))
+	}
+
+	switch s.Provider {
+	case "", ServiceProviderConsul:
+	case ServiceProviderNomad:
+		if len(s.Checks) != 0 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("service %q using the %q provider can not have checks", s.Name, s.Provider))
+		}
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("service %q has invalid provider %q", s.Name, s.Provider))
+	}
+
+	for _, c := range s.Checks {
+		if s.PortLabel == "" && c.RequiresPort() {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("check %s invalid: check requires a port but the service %+q has no port", c.Name, s.Name))
+			continue
+		}
+
+		if err := c.validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("check %s invalid: %v", c.Name, err))
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// Hash calculates the hash of the check based on it's content and the service
+// which owns it
+func (s *Service) Hash() string {
+	h := sha1.New()
+	io.WriteString(h, s.Name)
+	io.WriteString(h, strings.Join(s.Tags, ""))
+	io.WriteString(h, s.PortLabel)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+const (
+	// DefaultKillTimeout is the default timeout between signaling a task it
+	// will be killed and killing it.
+	DefaultKillTimeout = 5 * time.Second
+)
+
+// LogConfig provides configuration for log rotation
+type LogConfig struct {
+	MaxFiles      int `mapstructure:"max_files"`
+	MaxFileSizeMB int `mapstructure:"max_file_size"`
+}
+
+// DefaultLogConfig returns the default LogConfig values.
+func DefaultLogConfig() *LogConfig {
+	return &LogConfig{
+		MaxFiles:      10,
+		MaxFileSizeMB: 10,
+	}
+}
+
+// Validate returns an error if the log config specified are less than
+// the minimum allowed.
+func (l *LogConfig) Validate() error {
+	var mErr multierror.Error
+	if l.MaxFiles < 1 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum number of files is 1; got %d", l.MaxFiles))
+	}
+	if l.MaxFileSizeMB < 1 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum file size is 1MB; got %d", l.MaxFileSizeMB))
+	}
+	return mErr.ErrorOrNil()
+}
+
+const (
+	// DriverCapabilitySignals marks that a driver can send signals to a task
+	DriverCapabilitySignals = "signals"
+
+	// DriverCapabilityExec marks that a driver can execute commands, such as
+	// script checks, in the context of a task
+	DriverCapabilityExec = "exec"
+
+	// DriverCapabilityPause marks that a driver can pause and resume a task
+	DriverCapabilityPause = "pause"
+
+	// DriverCapabilityMountVolumes marks that a driver can mount volumes into
+	// a task
+	DriverCapabilityMountVolumes = "volumes"
+
+	// DriverCapabilityNetworkIsolation lists the network isolation modes a
+	// driver supports
+	DriverCapabilityNetworkIsolation = "network_isolation"
+
+	// DriverNetworkIsolationGroup is the network isolation mode of drivers
+	// that can start tasks in the network namespace of their allocation
+	DriverNetworkIsolationGroup = "group"
+)
+
+// DriverCapabilityAttribute returns the node attribute a client uses to
+// advertise a capability of a driver, like
+// "driver.docker.capability.pause=true".
+func DriverCapabilityAttribute(driver, capability string) string {
+	return fmt.Sprintf("driver.%s.capability.%s", driver, capability)
+}
+
+// Task is a single process typically that is executed as part of a task group.
+type Task struct {
+	// Name of the task
+	Name string
+
+	// Driver is used to control which driver is used
+	Driver string
+
+	// User is used to determine which user will run the task. It defaults to
+	// the same user the Nomad client is being run as.
+	User string
+
+	// Config is provided to the driver to initialize
+	Config map[string]interface{}
+
+	// Map of environment variables to be used by the driver
+	Env map[string]string
+
+	// List of service definitions exposed by the Task
+	Services []*Service
+
+	// Vault is used to define the set of Vault policies that this task should
+	// have access to.
+	Vault *Vault
+
+	// Consul selects the Consul cluster the task's services are registered
+	// with. The default cluster is used if unset.
+	Consul *Consul
+
+	// Templates are the set of templates to be rendered for the task.
+	Templates []*Template
+
+	// Constraints can be specified at a task level and apply only to
+	// the particular task.
+	Constraints []*Constraint
+
+	// Resources is the resources needed by this task
+	Resources *Resources
+
+	// Meta is used to associate arbitrary metadata with this
+	// task. This is opaque to Nomad.
+	Meta map[string]string
+
+	// KillTimeout is the time between signaling a task that it will be
+	// killed and killing it.
+	KillTimeout time.Duration `mapstructure:"kill_timeout"`
+
+	// LogConfig provides configuration for log rotation
+	LogConfig *LogConfig `mapstructure:"logs"`
+
+	// Artifacts is a list of artifacts to download and extract before running
+	// the task.
+	Artifacts []*TaskArtifact
+
+	// Schedule optionally pauses the task during recurring time windows.
+	Schedule *TaskSchedule
+
+	// DispatchPayload configures how the task retrieves its input from a
+	// dispatch of its parameterized job.
+	DispatchPayload *DispatchPayloadConfig `mapstructure:"dispatch_payload"`
+
+	// ShutdownOrder orders the shutdown of the tasks of a group. Tasks are
+	// stopped in stages of increasing shutdown order, and a stage is only
+	// stopped once the tasks of the previous stages have exited.
+	ShutdownOrder int `mapstructure:"shutdown_order"`
+
+	// Lifecycle runs the task before or after the main tasks of its group
+	// rather than alongside them. Tasks without a lifecycle are main tasks.
+	Lifecycle *TaskLifecycleConfig
+
+	// RestartPolicy overrides the restart policy of the task group for the
+	// task. Its unset fields are inherited from the group's policy.
+	RestartPolicy *RestartPolicy
+
+	// VolumeMounts mount volumes of the task group into the task
+	VolumeMounts []*VolumeMount
+
+	// CSIPluginConfig marks the task as a CSI plugin managing volumes
+	CSIPluginConfig *TaskCSIPluginConfig `mapstructure:"csi_plugin"`
+}
+
+func (t *Task) Copy() *Task {
+	if t == nil {
+		return nil
+	}
+	nt := new(Task)
+	*nt = *t
+	nt.Env = CopyMapStringString(nt.Env)
+
+	if t.Services != nil {
+		services := make([]*Service, len(nt.Services))
+		for i, s := range nt.Services {
+			services[i] = s.Copy()
+		}
+		nt.Services = services
+	}
+
+	nt.Constraints = CopySliceConstraints(nt.Constraints)
+
+	nt.Vault = nt.Vault.Copy()
+	nt.Consul = nt.Consul.Copy()
+	nt.Schedule = nt.Schedule.Copy()
+	nt.Lifecycle = nt.Lifecycle.Copy()
+	nt.RestartPolicy = nt.RestartPolicy.Copy()
+	nt.DispatchPayload = nt.DispatchPayload.Copy()
+	nt.Resources = nt.Resources.Copy()
+	nt.Meta = CopyMapStringString(nt.Meta)
+	nt.VolumeMounts = CopySliceVolumeMount(nt.VolumeMounts)
+	nt.CSIPluginConfig = nt.CSIPluginConfig.Copy()
+
+	if t.Artifacts != nil {
+		artifacts := make([]*TaskArtifact, 0, len(t.Artifacts))
+		for _, a := range nt.Artifacts {
+			artifacts = append(artifacts, a.Copy())
+		}
+		nt.Artifacts = artifacts
+	}
+
+	if i, err := copystructure.Copy(nt.Config); err != nil {
+		nt.Config = i.(map[string]interface{})
+	}
+
+	if t.Templates != nil {
+		templates := make([]*Template, len(t.Templates))
+		for i, tmpl := range nt.Templates {
+			templates[i] = tmpl.Copy()
+		}
+		nt.Templates = templates
+	}
+
+	return nt
+}
+
+// Canonicalize canonicalizes fields in the task.
+func (t *Task) Canonicalize(job *Job, tg *TaskGroup) {
+	// Ensure that an empty and nil map are treated the same to avoid scheduling
+	// problems since we use reflect DeepEquals.
+	if len(t.Meta) == 0 {
+		t.Meta = nil
+	}
+	if len(t.Config) == 0 {
+		t.Config = nil
+	}
+	if len(t.Env) == 0 {
+		t.Env = nil
+	}
+
+	for _, service := range t.Services {
+		service.Canonicalize(job.Name, tg.Name, t.Name)
+	}
+
+	for _, artifact := range t.Artifacts {
+		artifact.Canonicalize()
+	}
+
+	if t.Resources != nil {
+		t.Resources.Canonicalize()
+	}
+
+	// Set the default timeout if it is not specified.
+	if t.KillTimeout == 0 {
+		t.KillTimeout = DefaultKillTimeout
+	}
+
+	// Inherit the unset fields of the restart policy from the group. Zero
+	// attempts are only meaningful with the "fail" mode, so they are only
+	// inherited along with the mode.
+	if t.RestartPolicy != nil && tg.RestartPolicy != nil {
+		if t.RestartPolicy.Attempts == 0 && t.RestartPolicy.Mode == "" {
+			t.RestartPolicy.Attempts = tg.RestartPolicy.Attempts
+		}
+		if t.RestartPolicy.Interval == 0 {
+			t.RestartPolicy.Interval = tg.RestartPolicy.Interval
+		}
+		if t.RestartPolicy.Delay == 0 {
+			t.RestartPolicy.Delay = tg.RestartPolicy.Delay
+		}
+		if t.RestartPolicy.Mode == "" {
+			t.RestartPolicy.Mode = tg.RestartPolicy.Mode
+		}
+	}
+}
+
+func (t *Task) GoString() string {
+	return fmt.Sprintf("*%#v", *t)
+}
+
+func (t *Task) FindHostAndPortFor(portLabel string) (string, int) {
+	for _, network := range t.Resources.Networks {
+		if p, ok := network.MapLabelToValues(nil)[portLabel]; ok {
+			return network.IP, p
+		}
+	}
+	return "", 0
+}
+
+// RequiredDriverCapabilities returns the sorted set of driver capabilities the
+// task relies on
+func (t *Task) RequiredDriverCapabilities() []string {
+	required := make(map[string]struct{})
+	if t.Schedule != nil {
+		required[DriverCapabilityPause] = struct{}{}
+	}
+	for _, tmpl := range t.Templates {
+		if tmpl.ChangeMode == TemplateChangeModeSignal {
+			required[DriverCapabilitySignals] = struct{}{}
+		}
+	}
+	for _, service := range t.Services {
+		for _, check := range service.Checks {
+			if check.Type == ServiceCheckScript {
+				required[DriverCapabilityExec] = struct{}{}
+			}
+		}
+	}
+	if len(t.VolumeMounts) != 0 || t.CSIPluginConfig != nil {
+		required[DriverCapabilityMountVolumes] = struct{}{}
+	}
+
+	capabilities := make([]string, 0, len(required))
+	for capability := range required {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+	return capabilities
+}
+
+// Validate is used to sanity check a task
+func (t *Task) Validate(ephemeralDisk *EphemeralDisk) error {
+	var mErr multierror.Error
+	if t.Name == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("Missing task name"))
+	}
+	if strings.ContainsAny(t.Name, `/\`) {
+		// We enforce this so that when creating the directory on disk it will
+		// not have any slashes.
+		mErr.Errors = append(mErr.Errors, errors.New("Task name can not include slashes"))
+	}
+	if t.Driver == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("Missing task driver"))
+	}
+	if t.KillTimeout.Nanoseconds() < 0 {
+		mErr.Errors = append(mErr.Errors, errors.New("KillTimeout must be a positive value"))
+	}
+
+	// Validate the resources.
+	if t.Resources == nil {
+		mErr.Errors = append(mErr.Errors, errors.New("Missing task resources"))
+	} else if err := t.Resources.MeetsMinResources(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+
+	if t.Resources != nil {
+		for _, n := range t.Resources.Networks {
+			if _, err := n.PortsHostNetwork(); err != nil {
+				mErr.Errors = append(mErr.Errors, err)
+			}
+		}
+	}
+
+	// Ensure the task isn't asking for disk resources
+	if t.Resources != nil {
+		if t.Resources.DiskMB > 0 {
+			mErr.Errors = append(mErr.Errors, errors.New("Task can't ask for disk resources, they have to be specified at the task group level."))
+		}
+	}
+
+	// Validate the log config
+	if t.LogConfig == nil {
+		mErr.Errors = append(mErr.Errors, errors.New("Missing Log Config"))
+	} else if err := t.LogConfig.Validate(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+
+	for idx, constr := range t.Constraints {
+		if err := constr.Validate(); err != nil {
+			outer := fmt.Errorf("Constraint %d validation failed: %s", idx+1, err)
+			mErr.Errors = append(mErr.Errors, outer)
+		}
+	}
+
+	// Validate Services
+	if err := validateServices(t); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+
+	if t.LogConfig != nil && ephemeralDisk != nil {
+		logUsage := (t.LogConfig.MaxFiles * t.LogConfig.MaxFileSizeMB)
+		if ephemeralDisk.SizeMB <= logUsage {
+			mErr.Errors = append(mErr.Errors,
+				fmt.Errorf("log storage (%d MB) must be less than requested disk capacity (%d MB)",
+					logUsage, ephemeralDisk.SizeMB))
+		}
+	}
+
+	for idx, artifact := range t.Artifacts {
+		if err := artifact.Validate(); err != nil {
+			outer := fmt.Errorf("Artifact %d validation failed: %v", idx+1, err)
+			mErr.Errors = append(mErr.Errors, outer)
+		}
+	}
+
+	if t.Schedule != nil {
+		if err := t.Schedule.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Schedule validation failed: %v", err))
+		}
+	}
+
+	if t.Lifecycle != nil {
+		if err := t.Lifecycle.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Lifecycle validation failed: %v", err))
+		}
+	}
+
+	if t.RestartPolicy != nil {
+		if err := t.RestartPolicy.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Restart policy validation failed: %v", err))
+		}
+	}
+
+	if t.Vault != nil {
+		if err := t.Vault.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Vault validation failed: %v", err))
+		}
+	}
+
+	if t.Consul != nil {
+		if err := t.Consul.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Consul validation failed: %v", err))
+		}
+	}
+
+	if t.DispatchPayload != nil {
+		if err := t.DispatchPayload.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Dispatch Payload validation failed: %v", err))
+		}
+	}
+
+	if t.CSIPluginConfig != nil {
+		if err := t.CSIPluginConfig.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("CSI plugin validation failed: %v", err))
+		}
+	}
+
+	for idx, tmpl := range t.Templates {
+		if err := tmpl.Validate(); err != nil {
+			outer := fmt.Errorf("Template %d validation failed: %s", idx+1, err)
+			mErr.Errors = append(mErr.Errors, outer)
+		}
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+// validateServices takes a task and validates the services within it are valid
+// and reference ports that exist.
+func validateServices(t *Task) error {
+	var mErr multierror.Error
+
+	// Ensure that services don't ask for non-existent ports and their names are
+	// unique.
+	servicePorts := make(map[string][]string)
+	knownServices := make(map[string]struct{})
+	for i, service := range t.Services {
+		if err := service.Validate(); err != nil {
+			outer := fmt.Errorf("service[%d] %+q validation failed: %s", i, service.Name, err)
+			mErr.Errors = append(mErr.Errors, outer)
+		}
+		if _, ok := knownServices[service.Name]; ok {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("service %q is duplicate", service.Name))
+		}
+		knownServices[service.Name] = struct{}{}
+
+		if service.PortLabel != "" {
+			servicePorts[service.PortLabel] = append(servicePorts[service.PortLabel], service.Name)
+		}
+
+		// Ensure that check names are unique.
+		knownChecks := make(map[string]struct{})
+		for _, check := range service.Checks {
+			if _, ok := knownChecks[check.Name]; ok {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("check %q is duplicate", check.Name))
+			}
+			knownChecks[check.Name] = struct{}{}
+		}
+	}
+
+	// Get the set of port labels.
+	portLabels := make(map[string]struct{})
+	if t.Resources != nil {
+		for _, network := range t.Resources.Networks {
+			ports := network.MapLabelToValues(nil)
+			for portLabel, _ := range ports {
+				portLabels[portLabel] = struct{}{}
+			}
+		}
+	}
+
+	// Ensure all ports referenced in services exist.
+	for servicePort, services := range servicePorts {
+		_, ok := portLabels[servicePort]
+		if !ok {
+			joined := strings.Join(services, ", ")
+			err := fmt.Errorf("port label %q referenced by services %v does not exist", servicePort, joined)
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+const (
+	// TemplateChangeModeNoop marks that no action should be taken if the
+	// template is re-rendered
+	TemplateChangeModeNoop = "noop"
+
+	// TemplateChangeModeSignal marks that the task should be signaled if the
+	// template is re-rendered
+	TemplateChangeModeSignal = "signal"
+
+	// TemplateChangeModeRestart marks that the task should be restarted if the
+	// template is re-rendered
+	TemplateChangeModeRestart = "restart"
+)
+
+var (
+	// TemplateChangeModeInvalidError is the error for when an invalid change
+	// mode is given
+	TemplateChangeModeInvalidError = errors.New("Invalid change mode. Must be one of the following: noop, signal, restart")
+)
+
+// Template represents a template configuration to be rendered for a given task
+type Template struct {
+	// SourcePath is the the path to the template to be rendered
+	SourcePath string `mapstructure:"source"`
+
+	// DestPath is the path to where the template should be rendered
+	DestPath string `mapstructure:"destination"`
+
+	// EmbededTmpl store the raw template. This is useful for smaller templates
+	// where they are embeded in the job file rather than sent as an artificat
+	EmbededTmpl string `mapstructure:"data"`
+
+	// ChangeMode indicates what should be done if the template is re-rendered
+	ChangeMode string `mapstructure:"change_mode"`
+
+	// RestartSignal is the signal that should be sent if the change mode
+	// requires it.
+	RestartSignal string `mapstructure:"restart_signal"`
+
+	// Splay is used to avoid coordinated restarts of processes by applying a
+	// random wait between 0 and the given splay value before signalling the
+	// application of a change
+	Splay time.Duration `mapstructure:"splay"`
+
+	// Once mode is used to indicate that template should be rendered only once
+	Once bool `mapstructure:"once"`
+}
+
+// DefaultTemplate returns a default template.
+func DefaultTemplate() *Template {
+	return &Template{
+		ChangeMode: TemplateChangeModeRestart,
+		Splay:      5 * time.Second,
+		Once:       false,
+	}
+}
+
+func (t *Template) Copy() *Template {
+	if t == nil {
+		return nil
+	}
+	copy := new(Template)
+	*copy = *t
+	return copy
+}
+
+func (t *Template) Validate() error {
+	var mErr multierror.Error
+
+	// Verify we have something to render
+	if t.SourcePath == "" && t.EmbededTmpl == "" {
+		multierror.Append(&mErr, fmt.Errorf("Must specify a source path or have an embeded template"))
+	}
+
+	// Verify we can render somewhere
+	if t.DestPath == "" {
+		multierror.Append(&mErr, fmt.Errorf("Must specify a destination for the template"))
+	}
+
+	// Verify the destination doesn't escape
+	escaped, err := pathEscapesAllocDir(t.DestPath)
+	if err != nil {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid destination path: %v", err))
+	} else if escaped {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("destination escapes allocation directory"))
+	}
+
+	// Verify a proper change mode
+	switch t.ChangeMode {
+	case TemplateChangeModeNoop, TemplateChangeModeRestart:
+	case TemplateChangeModeSignal:
+		if t.RestartSignal == "" {
+			multierror.Append(&mErr, fmt.Errorf("Must specify signal value when change mode is signal"))
+		}
+	default:
+		multierror.Append(&mErr, TemplateChangeModeInvalidError)
+	}
+
+	// Verify the splay is positive
+	if t.Splay < 0 {
+		multierror.Append(&mErr, fmt.Errorf("Must specify positive splay value"))
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+// Set of possible states for a task.
+const (
+	TaskStatePending = "pending" // The task is waiting to be run.
+	TaskStateRunning = "running" // The task is currently running.
+	TaskStateDead    = "dead"    // Terminal state of task.
+)
+
+// TaskState tracks the current state of a task and events that caused state
+// transitions.
+type TaskState struct {
+	// The current state of the task.
+	State string
+
+	// Series of task events that transition the state of the task.
+	Events []*TaskEvent
+}
+
+func (ts *TaskState) Copy() *TaskState {
+	if ts == nil {
+		return nil
+	}
+	copy := new(TaskState)
+	copy.State = ts.State
+
+	if ts.Events != nil {
+		copy.Events = make([]*TaskEvent, len(ts.Events))
+		for i, e := range ts.Events {
+			copy.Events[i] = e.Copy()
+		}
+	}
+	return copy
+}
+
+// Failed returns true if the task has has failed.
+func (ts *TaskState) Failed() bool {
+	l := len(ts.Events)
+	if ts.State != TaskStateDead || l == 0 {
+		return false
+	}
+
+	switch ts.Events[l-1].Type {
+	case TaskDiskExceeded, TaskNotRestarting, TaskArtifactDownloadFailed,
+		TaskFailedValidation, TaskVaultRenewalFailed, TaskPortConflict:
+		return true
+	default:
+		return false
+	}
+}
+
+// Successful returns whether a task finished successfully.
+func (ts *TaskState) Successful() bool {
+	l := len(ts.Events)
+	if ts.State != TaskStateDead || l == 0 {
+		return false
+	}
+
+	e := ts.Events[l-1]
+	if e.Type != TaskTerminated {
+		return false
+	}
+
+	return e.ExitCode == 0
+}
+
+const (
+	// TaskDriveFailure indicates that the task could not be started due to a
+	// failure in the driver.
+	TaskDriverFailure = "Driver Failure"
+
+	// TaskReceived signals that the task has been pulled by the client at the
+	// given timestamp.
+	TaskReceived = "Received"
+
+	// TaskFailedValidation indicates the task was invalid and as such was not
+	// run.
+	TaskFailedValidation = "Failed Validation"
+
+	// TaskStarted signals that the task was started and its timestamp can be
+	// used to determine the running length of the task.
+	TaskStarted = "Started"
+
+	// TaskTerminated indicates that the task was started and exited.
+	TaskTerminated = "Terminated"
+
+	// TaskKilling indicates a kill signal has been sent to the task.
+	TaskKilling = "Killing"
+
+	// TaskKilled indicates a user has killed the task.
+	TaskKilled = "Killed"
+
+	// TaskRestarting indicates that task terminated and is being restarted.
+	TaskRestarting = "Restarting"
+
+	// TaskNotRestarting indicates that the task has failed and is not being
+	// restarted because it has exceeded its restart policy.
+	TaskNotRestarting = "Not Restarting"
+
+	// TaskDownloadingArtifacts means the task is downloading the artifacts
+	// specified in the task.
+	TaskDownloadingArtifacts = "Downloading Artifacts"
+
+	// TaskArtifactDownloadFailed indicates that downloading the artifacts
+	// failed.
+	TaskArtifactDownloadFailed = "Failed Artifact Download"
+
+	// TaskDiskExceeded indicates that one of the tasks in a taskgroup has
+	// exceeded the requested disk resources.
+	TaskDiskExceeded = "Disk Resources Exceeded"
+
+	// TaskSiblingFailed indicates that a sibling task in the task group has
+	// failed.
+	TaskSiblingFailed = "Sibling task failed"
+
+	// TaskVaultRenewalFailed indicates that Vault token renewal failed
+	TaskVaultRenewalFailed = "Vault token renewal failed"
+
+	// TaskRestartSignal indicates that the task has been signalled to be
+	// restarted
+	TaskRestartSignal = "Restart Signaled"
+
+	// TaskPaused indicates that the running task has been paused.
+	TaskPaused = "Paused"
+
+	// TaskResumed indicates that the paused task has been resumed.
+	TaskResumed = "Resumed"
+
+	// TaskPortConflict indicates that a static port of the allocation is
+	// already bound by a process on the host, so the task was not started.
+	TaskPortConflict = "Port Conflict"
+
+	// TaskMemoryPressure indicates that the task was killed to relieve memory
+	// pressure on the host.
+	TaskMemoryPressure = "Memory Pressure"
+
+	// TaskSignaling indicates that the task is being sent a signal.
+	TaskSignaling = "Signaling"
+)
+
+// TaskEvent is an event that effects the state of a task and contains meta-data
+// appropriate to the events type.
+type TaskEvent struct {
+	Type string
+	Time int64 // Unix Nanosecond timestamp
+
+	// Restart fields.
+	RestartReason string
+
+	// Driver Failure fields.
+	DriverError string // A driver error occurred while starting the task.
+
+	// Task Terminated Fields.
+	ExitCode int    // The exit code of the task.
+	Signal   int    // The signal that terminated the task.
+	Message  string // A possible message explaining the termination of the task.
+
+	// Killing fields
+	KillTimeout time.Duration
+
+	// Task Killed Fields.
+	KillError string // Error killing the task.
+
+	// TaskRestarting fields.
+	StartDelay int64 // The sleep period before restarting the task in unix nanoseconds.
+
+	// Artifact Download fields
+	DownloadError   string // Error downloading artifacts
+	DownloadAttempt int    // The attempt at downloading the artifact that failed
+
+	// Validation fields
+	ValidationError string // Validation error
+
+	// The maximum allowed task disk size.
+	DiskLimit int64
+
+	// The recorded task disk size.
+	DiskSize int64
+
+	// Name of the sibling task that caused termination of the task that
+	// the TaskEvent refers to.
+	FailedSibling string
+
+	// VaultError is the error from token renewal
+	VaultError string
+
+	// PauseReason is why the task was paused or resumed
+	PauseReason string
+
+	// PortConflicts are the addresses of the static ports that are already
+	// bound on the host
+	PortConflicts []string
+
+	// MemoryPressure explains why the task was chosen to relieve memory
+	// pressure on the host
+	MemoryPressure string
+
+	// TaskSignal is the signal sent to the task and TaskSignalReason why it
+	// was sent
+	TaskSignal       string
+	TaskSignalReason string
+}
+
+func (te *TaskEvent) GoString() string {
+	return fmt.Sprintf("%v at %v", te.Type, te.Time)
+}
+
+func (te *TaskEvent) Copy() *TaskEvent {
+	if te == nil {
+		return nil
+	}
+	copy := new(TaskEvent)
+	*copy = *te
+	copy.PortConflicts = CopySliceString(te.PortConflicts)
+	return copy
+}
+
+func NewTaskEvent(event string) *TaskEvent {
+	return &TaskEvent{
+		Type: event,
+		Time: time.Now().UnixNano(),
+	}
+}
+
+func (e *TaskEvent) SetDriverError(err error) *TaskEvent {
+	if err != nil {
+		e.DriverError = err.Error()
+	}
+	return e
+}
+
+func (e *TaskEvent) SetExitCode(c int) *TaskEvent {
+	e.ExitCode = c
+	return e
+}
+
+func (e *TaskEvent) SetSignal(s int) *TaskEvent {
+	e.Signal = s
+	return e
+}
+
+func (e *TaskEvent) SetExitMessage(err error) *TaskEvent {
+	if err != nil {
+		e.Message = err.Error()
+	}
+	return e
+}
+
+func (e *TaskEvent) SetKillError(err error) *TaskEvent {
+	if err != nil {
+		e.KillError = err.Error()
+	}
+	return e
+}
+
+func (e *TaskEvent) SetRestartDelay(delay time.Duration) *TaskEvent {
+	e.StartDelay = int64(delay)
+	return e
+}
+
+func (e *TaskEvent) SetRestartReason(reason string) *TaskEvent {
+	e.RestartReason = reason
+	return e
+}
+
+func (e *TaskEvent) SetTaskSignal(sig string) *TaskEvent {
+	e.TaskSignal = sig
+	return e
+}
+
+func (e *TaskEvent) SetTaskSignalReason(reason string) *TaskEvent {
+	e.TaskSignalReason = reason
+	return e
+}
+
+func (e *TaskEvent) SetDownloadError(err error) *TaskEvent {
+	if err != nil {
+		e.DownloadError = err.Error()
+	}
+	return e
+}
+
+func (e *TaskEvent) SetDownloadAttempt(attempt int) *TaskEvent {
+	e.DownloadAttempt = attempt
+	return e
+}
+
+func (e *TaskEvent) SetValidationError(err error) *TaskEvent {
+	if err != nil {
+		e.ValidationError = err.Error()
+	}
+	return e
+}
+
+func (e *TaskEvent) SetKillTimeout(timeout time.Duration) *TaskEvent {
+	e.KillTimeout = timeout
+	return e
+}
+
+func (e *TaskEvent) SetDiskLimit(limit int64) *TaskEvent {
+	e.DiskLimit = limit
+	return e
+}
+
+func (e *TaskEvent) SetDiskSize(size int64) *TaskEvent {
+	e.DiskSize = size
+	return e
+}
+
+func (e *TaskEvent) SetFailedSibling(sibling string) *TaskEvent {
+	e.FailedSibling = sibling
+	return e
+}
+
+func (e *TaskEvent) SetVaultRenewalError(err error) *TaskEvent {
+	if err != nil {
+		e.VaultError = err.Error()
+	}
+	return e
+}
+
+func (e *TaskEvent) SetPauseReason(reason string) *TaskEvent {
+	e.PauseReason = reason
+	return e
+}
+
+func (e *TaskEvent) SetPortConflicts(addrs []string) *TaskEvent {
+	e.PortConflicts = addrs
+	return e
+}
+
+func (e *TaskEvent) SetMemoryPressure(reason string) *TaskEvent {
+	e.MemoryPressure = reason
+	return e
+}
+
+const (
+	// ArtifactOnErrorRetry retries a failed download in place and then
+	// restarts the task as per its restart policy
+	ArtifactOnErrorRetry = "retry"
+
+	// ArtifactOnErrorFail fails the task without restarting it when the
+	// artifact fails to download
+	ArtifactOnErrorFail = "fail"
+
+	// ArtifactOnErrorContinue skips an artifact that fails to download and
+	// starts the task regardless
+	ArtifactOnErrorContinue = "continue"
+
+	// DefaultArtifactRetryDelay is the default delay before retrying a failed
+	// artifact download
+	DefaultArtifactRetryDelay = 5 * time.Second
+
+	// ArtifactMaxRetryDelay caps the backoff between artifact download retries
+	ArtifactMaxRetryDelay = 5 * time.Minute
+)
+
+var (
+	// ArtifactOnErrorInvalidError is the error for when an invalid on_error
+	// policy is given
+	ArtifactOnErrorInvalidError = errors.New("Invalid on_error policy. Must be one of the following: retry, fail, continue")
+)
+
+// TaskArtifact is an artifact to download before running the task.
+type TaskArtifact struct {
+	// GetterSource is the source to download an artifact using go-getter
+	GetterSource string `mapstructure:"source"`
+
+	// GetterOptions are options to use when downloading the artifact using
+	// go-getter.
+	GetterOptions map[string]string `mapstructure:"options"`
+
+	// RelativeDest is the download destination given relative to the task's
+	// directory.
+	RelativeDest string `mapstructure:"destination"`
+
+	// OnError is the policy applied when the artifact fails to download.
+	OnError string `mapstructure:"on_error"`
+
+	// RetryAttempts is the number of times a failed download is retried in
+	// place before the task is restarted as per its restart policy.
+	RetryAttempts int `mapstructure:"retry_attempts"`
+
+	// RetryDelay is the delay before the first retry of a failed download.
+	// The delay doubles with each retry, up to ArtifactMaxRetryDelay.
+	RetryDelay time.Duration `mapstructure:"retry_delay"`
+}
+
+// Canonicalize sets the defaults of the artifact's failure policy
+func (ta *TaskArtifact) Canonicalize() {
+	if ta.OnError == "" {
+		ta.OnError = ArtifactOnErrorRetry
+	}
+	if ta.RetryDelay == 0 {
+		ta.RetryDelay = DefaultArtifactRetryDelay
+	}
+}
+
+func (ta *TaskArtifact) Copy() *TaskArtifact {
+	if ta == nil {
+		return nil
+	}
+	nta := new(TaskArtifact)
+	*nta = *ta
+	nta.GetterOptions = CopyMapStringString(ta.GetterOptions)
+	return nta
+}
+
+func (ta *TaskArtifact) GoString() string {
+	return fmt.Sprintf("%+v", ta)
+}
+
+// pathEscapesAllocDir returns if the given path escapes the allocation
+// directory
+func pathEscapesAllocDir(path string) (bool, error) {
+	// Verify the destination doesn't escape the tasks directory
+	alloc, err := filepath.Abs(filepath.Join("/", "foo/", "bar/"))
+	if err != nil {
+		return false, err
+	}
+	abs, err := filepath.Abs(filepath.Join(alloc, path))
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(alloc, abs)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.HasPrefix(rel, ".."), nil
+}
+
+func (ta *TaskArtifact) Validate() error {
+	// Verify the source
+	var mErr multierror.Error
+	if ta.GetterSource == "" {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("source must be specified"))
+	}
+
+	// Verify the failure policy
+	switch ta.OnError {
+	case "", ArtifactOnErrorRetry, ArtifactOnErrorFail, ArtifactOnErrorContinue:
+	default:
+		mErr.Errors = append(mErr.Errors, ArtifactOnErrorInvalidError)
+	}
+	if ta.RetryAttempts < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("retry attempts can not be negative"))
+	} else if ta.RetryAttempts > 0 && ta.OnError != "" && ta.OnError != ArtifactOnErrorRetry {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("retry attempts require the %q on_error policy", ArtifactOnErrorRetry))
+	}
+	if ta.RetryDelay < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("retry delay can not be negative"))
+	}
+
+	escaped, err := pathEscapesAllocDir(ta.RelativeDest)
+	if err != nil {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid destination path: %v", err))
+	} else if escaped {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("destination escapes task's directory"))
+	}
+
+	// Verify the checksum
+	if check, ok := ta.GetterOptions["checksum"]; ok {
+		check = strings.TrimSpace(check)
+		if check == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("checksum value can not be empty"))
+			return mErr.ErrorOrNil()
+		}
+
+		parts := strings.Split(check, ":")
+		if l := len(parts); l != 2 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf(`checksum must be given as "type:value"; got %q`, check))
+			return mErr.ErrorOrNil()
+		}
+
+		checksumVal := parts[1]
+		checksumBytes, err := hex.DecodeString(checksumVal)
+		if err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid checksum: %v", err))
+			return mErr.ErrorOrNil()
+		}
+
+		checksumType := parts[0]
+		expectedLength := 0
+		switch checksumType {
+		case "md5":
+			expectedLength = md5.Size
+		case "sha1":
+			expectedLength = sha1.Size
+		case "sha256":
+			expectedLength = sha256.Size
+		case "sha512":
+			expectedLength = sha512.Size
+		default:
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("unsupported checksum type: %s", checksumType))
+			return mErr.ErrorOrNil()
+		}
+
+		if len(checksumBytes) != expectedLength {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid %s checksum: %v", checksumType, checksumVal))
+			return mErr.ErrorOrNil()
+		}
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+const (
+	ConstraintDistinctHosts = "distinct_hosts"
+	ConstraintRegex         = "regexp"
+	ConstraintVersion       = "version"
+)
+
+// Constraints are used to restrict placement options.
+type Constraint struct {
+	LTarget string // Left-hand target
+	RTarget string // Right-hand target
+	Operand string // Constraint operand (<=, <, =, !=, >, >=), contains, near
+	str     string // Memoized string
+}
+
+// Equal checks if two constraints are equal
+func (c *Constraint) Equal(o *Constraint) bool {
+	return c.LTarget == o.LTarget &&
+		c.RTarget == o.RTarget &&
+		c.Operand == o.Operand
+}
+
+func (c *Constraint) Copy() *Constraint {
+	if c == nil {
+		return nil
+	}
+	nc := new(Constraint)
+	*nc = *c
+	return nc
+}
+
+func (c *Constraint) String() string {
+	if c.str != "" {
+		return c.str
+	}
+	c.str = fmt.Sprintf("%s %s %s", c.LTarget, c.Operand, c.RTarget)
+	return c.str
+}
+
+func (c *Constraint) Validate() error {
+	var mErr multierror.Error
+	if c.Operand == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("Missing constraint operand"))
+	}
+
+	// Perform additional validation based on operand
+	switch c.Operand {
+	case ConstraintRegex:
+		if _, err := regexp.Compile(c.RTarget); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Regular expression failed to compile: %v", err))
+		}
+	case ConstraintVersion:
+		if _, err := version.NewConstraint(c.RTarget); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Version constraint is invalid: %v", err))
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// EphemeralDisk is an ephemeral disk object
+type EphemeralDisk struct {
+	// Sticky indicates whether the allocation is sticky to a node
+	Sticky bool
+
+	// SizeMB is the size of the local disk
+	SizeMB int `mapstructure:"size"`
+
+	// Migrate determines if Nomad client should migrate the allocation dir for
+	// sticky allocations
+	Migrate bool
+}
+
+// DefaultEphemeralDisk returns a EphemeralDisk with default configurations
+func DefaultEphemeralDisk() *EphemeralDisk {
+	return &EphemeralDisk{
+		SizeMB: 300,
+	}
+}
+
+// Validate validates EphemeralDisk
+func (d *EphemeralDisk) Validate() error {
+	if d.SizeMB < 10 {
+		return fmt.Errorf("minimum DiskMB value is 10; got %d", d.SizeMB)
+	}
+	return nil
+}
+
+// Copy copies the EphemeralDisk struct and returns a new one
+func (d *EphemeralDisk) Copy() *EphemeralDisk {
+	ld := new(EphemeralDisk)
+	*ld = *d
+	return ld
+}
+
+const (
+	// DefaultCluster is the name of the Vault or Consul cluster configured
+	// in an agent without a name. Tasks use it unless they select another.
+	DefaultCluster = "default"
+)
+
+var (
+	// validClusterName is used to validate the name of a Vault or Consul
+	// cluster
+	validClusterName = regexp.MustCompile("^[a-zA-Z0-9_-]{1,128}$")
+)
+
+// Vault stores the set of premissions a task needs access to from Vault.
+type Vault struct {
+	// Policies is the set of policies that the task needs access to
+	Policies []string
+
+	// Env marks whether the Vault Token should be exposed as an environment
+	// variable
+	Env bool
+
+	// Cluster is the name of the Vault cluster the token is derived from.
+	// The default cluster is used if unset.
+	Cluster string
+}
+
+// Copy returns a copy of this Vault block.
+func (v *Vault) Copy() *Vault {
+	if v == nil {
+		return nil
+	}
+
+	nv := new(Vault)
+	*nv = *v
+	return nv
+}
+
+// Validate returns if the Vault block is valid.
+func (v *Vault) Validate() error {
+	if v == nil {
+		return nil
+	}
+
+	if len(v.Policies) == 0 {
+		return fmt.Errorf("Policy list can not be empty")
+	}
+
+	return validateClusterName(v.Cluster)
+}
+
+// ClusterName returns the name of the Vault cluster the token is derived
+// from
+func (v *Vault) ClusterName() string {
+	if v == nil || v.Cluster == "" {
+		return DefaultCluster
+	}
+	return v.Cluster
+}
+
+// Consul stores the Consul cluster a task's services are registered with.
+type Consul struct {
+	// Cluster is the name of the Consul cluster. The default cluster is used
+	// if unset.
+	Cluster string
+}
+
+// Copy returns a copy of this Consul block.
+func (c *Consul) Copy() *Consul {
+	if c == nil {
+		return nil
+	}
+
+	nc := new(Consul)
+	*nc = *c
+	return nc
+}
+
+// Validate returns if the Consul block is valid.
+func (c *Consul) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	return validateClusterName(c.Cluster)
+}
+
+// ClusterName returns the name of the Consul cluster the task's services are
+// registered with
+func (c *Consul) ClusterName() string {
+	if c == nil || c.Cluster == "" {
+		return DefaultCluster
+	}
+	return c.Cluster
+}
+
+// validateClusterName returns an error if the name of a Vault or Consul
+// cluster can't be used in node attributes
+func validateClusterName(name string) error {
+	if name != "" && !validClusterName.MatchString(name) {
+		return fmt.Errorf("Cluster name %q must match regex %s", name, validClusterName)
+	}
+	return nil
+}
+
+// IsMainTask returns whether the task runs alongside the other main tasks of
+// its group rather than in a lifecycle hook
+func (t *Task) IsMainTask() bool {
+	return t.Lifecycle == nil
+}
+
+const (
+	// TaskLifecycleHookPrestart starts the task before the main tasks, which
+	// are started once it completes or, for sidecars, is running
+	TaskLifecycleHookPrestart = "prestart"
+
+	// TaskLifecycleHookPoststart starts the task once the main tasks are
+	// running
+	TaskLifecycleHookPoststart = "poststart"
+
+	// TaskLifecycleHookPoststop starts the task once the main tasks have
+	// exited
+	TaskLifecycleHookPoststop = "poststop"
+)
+
+// TaskLifecycleConfig orders a task relative to the main tasks of its group
+type TaskLifecycleConfig struct {
+	// Hook is when the task is started: prestart, poststart or poststop
+	Hook string
+
+	// Sidecar marks that the task keeps running alongside the main tasks and
+	// is stopped after them, rather than running to completion
+	Sidecar bool
+}
+
+// Copy returns a copy of this lifecycle.
+func (l *TaskLifecycleConfig) Copy() *TaskLifecycleConfig {
+	if l == nil {
+		return nil
+	}
+
+	nl := new(TaskLifecycleConfig)
+	*nl = *l
+	return nl
+}
+
+// Validate returns if the lifecycle is valid.
+func (l *TaskLifecycleConfig) Validate() error {
+	switch l.Hook {
+	case TaskLifecycleHookPrestart, TaskLifecycleHookPoststart:
+	case TaskLifecycleHookPoststop:
+		if l.Sidecar {
+			return fmt.Errorf("Poststop tasks can't be sidecars")
+		}
+	case "":
+		return fmt.Errorf("Must specify a lifecycle hook")
+	default:
+		return fmt.Errorf("Invalid lifecycle hook %q. Must be one of the following: %s, %s, %s",
+			l.Hook, TaskLifecycleHookPrestart, TaskLifecycleHookPoststart, TaskLifecycleHookPoststop)
+	}
+	return nil
+}
+
+// TaskSchedule pauses a running task during recurring time windows, for
+// example to quiesce it while backups are taken.
+type TaskSchedule struct {
+	// Pause is the cron expression, evaluated in UTC, of when the task is
+	// paused
+	Pause string
+
+	// Duration is how long the task stays paused before being resumed
+	Duration time.Duration
+}
+
+// Copy returns a copy of this schedule.
+func (s *TaskSchedule) Copy() *TaskSchedule {
+	if s == nil {
+		return nil
+	}
+
+	ns := new(TaskSchedule)
+	*ns = *s
+	return ns
+}
+
+// Validate returns if the schedule is valid.
+func (s *TaskSchedule) Validate() error {
+	if s == nil {
+		return nil
+	}
+
+	var mErr multierror.Error
+	if s.Pause == "" {
+		multierror.Append(&mErr, fmt.Errorf("Must specify a pause cron expression"))
+	} else if _, err := cronexpr.Parse(s.Pause); err != nil {
+		multierror.Append(&mErr, fmt.Errorf("Invalid pause cron expression %q: %v", s.Pause, err))
+	}
+	if s.Duration <= 0 {
+		multierror.Append(&mErr, fmt.Errorf("Pause duration must be a positive value"))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// Window returns whether the task is in a pause window at the given time and
+// the time of the next transition, either the end of the current window or
+// the start of the next one. A zero time is returned if the task is never
+// paused again.
+func (s *TaskSchedule) Window(now time.Time) (bool, time.Time) {
+	e, err := cronexpr.Parse(s.Pause)
+	if err != nil {
+		return false, time.Time{}
+	}
+
+	// The first window starting after now minus the duration is either the
+	// window the task is currently in or the next one
+	now = now.UTC()
+	start := e.Next(now.Add(-s.Duration))
+	if start.IsZero() {
+		return false, time.Time{}
+	}
+	if !start.After(now) {
+		return true, start.Add(s.Duration)
+	}
+	return false, start
+}
+
+const (
+	AllocDesiredStatusRun   = "run"   // Allocation should run
+	AllocDesiredStatusStop  = "stop"  // Allocation should stop
+	AllocDesiredStatusEvict = "evict" // Allocation should stop, and was evicted
+)
+
+const (
+	AllocClientStatusPending  = "pending"
+	AllocClientStatusRunning  = "running"
+	AllocClientStatusComplete = "complete"
+	AllocClientStatusFailed   = "failed"
+	AllocClientStatusLost     = "lost"
+)
+
+// Allocation is used to allocate the placement of a task group to a node.
+type Allocation struct {
+	// ID of the allocation (UUID)
+	ID string
+
+	// Namespace is the namespace the allocation is created in
+	Namespace string
+
+	// ID of the evaluation that generated this allocation
+	EvalID string
+
+	// Name is a logical name of the allocation.
+	Name string
+
+	// NodeID is the node this is being placed on
+	NodeID string
+
+	// Job is the parent job of the task group being allocated.
+	// This is copied at allocation time to avoid issues if the job
+	// definition is updated.
+	JobID string
+	Job   *Job
+
+	// TaskGroup is the name of the task group that should be run
+	TaskGroup string
+
+	// Resources is the total set of resources allocated as part
+	// of this allocation of the task group.
+	Resources *Resources
+
+	// SharedResources are the resources that are shared by all the tasks in an
+	// allocation
+	SharedResources *Resources
+
+	// TaskResources is the set of resources allocated to each
+	// task. These should sum to the total Resources.
+	TaskResources map[string]*Resources
+
+	// Metrics associated with this allocation
+	Metrics *AllocMetric
+
+	// Desired Status of the allocation on the client
+	DesiredStatus string
+
+	// DesiredStatusDescription is meant to provide more human useful information
+	DesiredDescription string
+
+	// DesiredTransition is controlled by the servers and marks how the
+	// allocation should transition, such as it being migrated off of a
+	// draining node
+	DesiredTransition DesiredTransition
+
+	// Status of the allocation on the client
+	ClientStatus string
+
+	// ClientStatusDescription is meant to provide more human useful information
+	ClientDescription string
+
+	// TaskStates stores the state of each task,
+	TaskStates map[string]*TaskState
+
+	// PreviousAllocation is the allocation that this allocation is replacing
+	PreviousAllocation string
+
+	// RescheduleTracker captures the chain of reschedule attempts that led
+	// to this allocation after previous allocations failed or were lost.
+	RescheduleTracker *RescheduleTracker
+
+	// SignedIdentities is a map of task names to the workload identity signed
+	// by the leader for the task. It is set by the plan applier and must not
+	// be exposed outside of the servers and the client running the allocation.
+	SignedIdentities map[string]string
+
+	// Raft Indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+
+	// AllocModifyIndex is not updated when the client updates allocations. This
+	// lets the client pull only the allocs updated by the server.
+	AllocModifyIndex uint64
+
+	// CreateTime is the time the allocation has finished scheduling and been
+	// verified by the plan applier.
+	CreateTime int64
+}
+
+func (a *Allocation) Copy() *Allocation {
+	if a == nil {
+		return nil
+	}
+	na := new(Allocation)
+	*na = *a
+
+	na.Job = na.Job.Copy()
+	na.Resources = na.Resources.Copy()
+	na.SharedResources = na.SharedResources.Copy()
+
+	if a.TaskResources != nil {
+		tr := make(map[string]*Resources, len(na.TaskResources))
+		for task, resource := range na.TaskResources {
+			tr[task] = resource.Copy()
+		}
+		na.TaskResources = tr
+	}
+
+	na.Metrics = na.Metrics.Copy()
+	na.RescheduleTracker = na.RescheduleTracker.Copy()
+
+	if a.TaskStates != nil {
+		ts := make(map[string]*TaskState, len(na.TaskStates))
+		for task, state := range na.TaskStates {
+			ts[task] = state.Copy()
+		}
+		na.TaskStates = ts
+	}
+
+	if a.SignedIdentities != nil {
+		si := make(map[string]string, len(na.SignedIdentities))
+		for task, identity := range na.SignedIdentities {
+			si[task] = identity
+		}
+		na.SignedIdentities = si
+	}
+	return na
+}
+
+// DesiredTransition is how the servers want an allocation to transition
+type DesiredTransition struct {
+	// Migrate marks the allocation to be migrated off of its draining node
+	Migrate bool
+}
+
+// Merge sets the transitions marked in the given desired transition
+func (d *DesiredTransition) Merge(o *DesiredTransition) {
+	if o.Migrate {
+		d.Migrate = true
+	}
+}
+
+// ShouldMigrate returns whether the allocation should be migrated
+func (d *DesiredTransition) ShouldMigrate() bool {
+	return d.Migrate
+}
+
+// TerminalStatus returns if the desired or actual status is terminal and
+// will no longer transition.
+func (a *Allocation) TerminalStatus() bool {
+	// First check the desired state and if that isn't terminal, check client
+	// state.
+	switch a.DesiredStatus {
+	case AllocDesiredStatusStop, AllocDesiredStatusEvict:
+		return true
+	default:
+	}
+
+	switch a.ClientStatus {
+	case AllocClientStatusComplete, AllocClientStatusFailed, AllocClientStatusLost:
+		return true
+	default:
+		return false
+	}
+}
+
+// Terminated returns if the allocation is in a terminal state on a client.
+func (a *Allocation) Terminated() bool {
+	if a.ClientStatus == AllocClientStatusFailed ||
+		a.ClientStatus == AllocClientStatusComplete ||
+		a.ClientStatus == AllocClientStatusLost {
+		return true
+	}
+	return false
+}
+
+// RanSuccessfully returns whether the client has ran the allocation and all
+// tasks finished successfully
+func (a *Allocation) RanSuccessfully() bool {
+	// Handle the case the client hasn't started the allocation.
+	if len(a.TaskStates) == 0 {
+		return false
+	}
+
+	// Check to see if all the tasks finised successfully in the allocation
+	allSuccess := true
+	for _, state := range a.TaskStates {
+		allSuccess = allSuccess && state.Successful()
+	}
+
+	return allSuccess
+}
+
+// RescheduleAttempts returns the number of times the allocation has been
+// rescheduled to replace a failed or lost allocation.
+func (a *Allocation) RescheduleAttempts() int {
+	if a.RescheduleTracker == nil {
+		return 0
+	}
+	return len(a.RescheduleTracker.Events)
+}
+
+// LastEventTime returns the time of the most recent event of the tasks of the
+// allocation, which is the time a failed allocation failed at, or the zero
+// time if none of its tasks has any event.
+func (a *Allocation) LastEventTime() time.Time {
+	var last int64
+	for _, state := range a.TaskStates {
+		if state == nil {
+			continue
+		}
+		for _, event := range state.Events {
+			if event != nil && event.Time > last {
+				last = event.Time
+			}
+		}
+	}
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
+
+// NextRescheduleTracker returns the reschedule tracker for an allocation
+// that replaces this one. If this allocation failed or was lost, a reschedule
+// event is appended to the existing history. Otherwise the replacement is not
+// a reschedule attempt and the history is carried over unchanged.
+func (a *Allocation) NextRescheduleTracker(now time.Time, delay time.Duration) *RescheduleTracker {
+	switch a.ClientStatus {
+	case AllocClientStatusFailed, AllocClientStatusLost:
+	default:
+		return a.RescheduleTracker.Copy()
+	}
+
+	tracker := a.RescheduleTracker.Copy()
+	if tracker == nil {
+		tracker = &RescheduleTracker{}
+	}
+	tracker.Events = append(tracker.Events, &RescheduleEvent{
+		RescheduleTime: now.UTC().UnixNano(),
+		PrevAllocID:    a.ID,
+		PrevNodeID:     a.NodeID,
+		PrevStatus:     a.ClientStatus,
+		Delay:          delay,
+	})
+	return tracker
+}
+
+// Stub returns a list stub for the allocation
+func (a *Allocation) Stub() *AllocListStub {
+	var jobModifyIndex uint64
+	if a.Job != nil {
+		jobModifyIndex = a.Job.JobModifyIndex
+	}
+	return &AllocListStub{
+		ID:                 a.ID,
+		Namespace:          a.Namespace,
+		EvalID:             a.EvalID,
+		Name:               a.Name,
+		NodeID:             a.NodeID,
+		JobID:              a.JobID,
+		TaskGroup:          a.TaskGroup,
+		DesiredStatus:      a.DesiredStatus,
+		DesiredDescription: a.DesiredDescription,
+		ClientStatus:       a.ClientStatus,
+		ClientDescription:  a.ClientDescription,
+		TaskStates:         a.TaskStates,
+		PreviousAllocation: a.PreviousAllocation,
+		RescheduleAttempts: a.RescheduleAttempts(),
+		JobModifyIndex:     jobModifyIndex,
+		CreateIndex:        a.CreateIndex,
+		ModifyIndex:        a.ModifyIndex,
+		CreateTime:         a.CreateTime,
+	}
+}
+
+var (
+	// AllocationIndexRegex is a regular expression to find the allocation index.
+	AllocationIndexRegex = regexp.MustCompile(".+\\[(\\d+)\\]$")
+)
+
+// Index returns the index of the allocation. If the allocation is from a task
+// group with count greater than 1, there will be multiple allocations for it.
+func (a *Allocation) Index() int {
+	matches := AllocationIndexRegex.FindStringSubmatch(a.Name)
+	if len(matches) != 2 {
+		return -1
+	}
+
+	index, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return -1
+	}
+
+	return index
+}
+
+// RescheduleTracker encapsulates the history of reschedule attempts of the
+// allocations that were replaced to arrive at the current allocation.
+type RescheduleTracker struct {
+	// Events is the ordered list of reschedule attempts, oldest first.
+	Events []*RescheduleEvent
+}
+
+func (rt *RescheduleTracker) Copy() *RescheduleTracker {
+	if rt == nil {
+		return nil
+	}
+	nt := new(RescheduleTracker)
+	if rt.Events != nil {
+		nt.Events = make([]*RescheduleEvent, len(rt.Events))
+		for i, e := range rt.Events {
+			nt.Events[i] = e.Copy()
+		}
+	}
+	return nt
+}
+
+// RescheduleEvent records a single attempt to replace a failed or lost
+// allocation.
+type RescheduleEvent struct {
+	// RescheduleTime is the Unix nanosecond timestamp at which the
+	// replacement was placed.
+	RescheduleTime int64
+
+	// PrevAllocID is the ID of the allocation that was replaced.
+	PrevAllocID string
+
+	// PrevNodeID is the node the replaced allocation ran on.
+	PrevNodeID string
+
+	// PrevStatus is the client status of the replaced allocation.
+	PrevStatus string
+
+	// Delay is the time waited before the replacement was placed.
+	Delay time.Duration
+}
+
+func (re *RescheduleEvent) Copy() *RescheduleEvent {
+	if re == nil {
+		return nil
+	}
+	copy := new(RescheduleEvent)
+	*copy = *re
+	return copy
+}
+
+// AllocListStub is used to return a subset of alloc information
+type AllocListStub struct {
+	ID                 string
+	Namespace          string
+	EvalID             string
+	Name               string
+	NodeID             string
+	JobID              string
+	TaskGroup          string
+	DesiredStatus      string
+	DesiredDescription string
+	ClientStatus       string
+	ClientDescription  string
+	TaskStates         map[string]*TaskState
+	PreviousAllocation string
+	RescheduleAttempts int
+	JobModifyIndex     uint64
+	CreateIndex        uint64
+	ModifyIndex        uint64
+	CreateTime         int64
+}
+
+// AllocMetric is used to track various metrics while attempting
+// to make an allocation. These are used to debug a job, or to better
+// understand the pressure within the system.
+type AllocMetric struct {
+	// NodesEvaluated is the number of nodes that were evaluated
+	NodesEvaluated int
+
+	// NodesFiltered is the number of nodes filtered due to a constraint
+	NodesFiltered int
+
+	// NodesAvailable is the number of nodes available for evaluation per DC.
+	NodesAvailable map[string]int
+
+	// ClassFiltered is the number of nodes filtered by class
+	ClassFiltered map[string]int
+
+	// ConstraintFiltered is the number of failures caused by constraint
+	ConstraintFiltered map[string]int
+
+	// NodesExhausted is the number of nodes skipped due to being
+	// exhausted of at least one resource
+	NodesExhausted int
+
+	// ClassExhausted is the number of nodes exhausted by class
+	ClassExhausted map[string]int
+
+	// DimensionExhausted provides the count by dimension or reason
+	DimensionExhausted map[string]int
+
+	// Scores is the scores of the final few nodes remaining
+	// for placement. The top score is typically selected.
+	Scores map[string]float64
+
+	// AllocationTime is a measure of how long the allocation
+	// attempt took. This can affect performance and SLAs.
+	AllocationTime time.Duration
+
+	// CoalescedFailures indicates the number of other
+	// allocations that were coalesced into this failed allocation.
+	// This is to prevent creating many failed allocations for a
+	// single task group.
+	CoalescedFailures int
+}
+
+func (a *AllocMetric) Copy() *AllocMetric {
+	if a == nil {
+		return nil
+	}
+	na := new(AllocMetric)
+	*na = *a
+	na.NodesAvailable = CopyMapStringInt(na.NodesAvailable)
+	na.ClassFiltered = CopyMapStringInt(na.ClassFiltered)
+	na.ConstraintFiltered = CopyMapStringInt(na.ConstraintFiltered)
+	na.ClassExhausted = CopyMapStringInt(na.ClassExhausted)
+	na.DimensionExhausted = CopyMapStringInt(na.DimensionExhausted)
+	na.Scores = CopyMapStringFloat64(na.Scores)
+	return na
+}
+
+func (a *AllocMetric) EvaluateNode() {
+	a.NodesEvaluated += 1
+}
+
+func (a *AllocMetric) FilterNode(node *Node, constraint string) {
+	a.NodesFiltered += 1
+	if node != nil && node.NodeClass != "" {
+		if a.ClassFiltered == nil {
+			a.ClassFiltered = make(map[string]int)
+		}
+		a.ClassFiltered[node.NodeClass] += 1
+	}
+	if constraint != "" {
+		if a.ConstraintFiltered == nil {
+			a.ConstraintFiltered = make(map[string]int)
+		}
+		a.ConstraintFiltered[constraint] += 1
+	}
+}
+
+func (a *AllocMetric) ExhaustedNode(node *Node, dimension string) {
+	a.NodesExhausted += 1
+	if node != nil && node.NodeClass != "" {
+		if a.ClassExhausted == nil {
+			a.ClassExhausted = make(map[string]int)
+		}
+		a.ClassExhausted[node.NodeClass] += 1
+	}
+	if dimension != "" {
+		if a.DimensionExhausted == nil {
+			a.DimensionExhausted = make(map[string]int)
+		}
+		a.DimensionExhausted[dimension] += 1
+	}
+}
+
+func (a *AllocMetric) ScoreNode(node *Node, name string, score float64) {
+	if a.Scores == nil {
+		a.Scores = make(map[string]float64)
+	}
+	key := fmt.Sprintf("%s.%s", node.ID, name)
+	a.Scores[key] = score
+}
+
+const (
+	EvalStatusBlocked   = "blocked"
+	EvalStatusPending   = "pending"
+	EvalStatusComplete  = "complete"
+	EvalStatusFailed    = "failed"
+	EvalStatusCancelled = "canceled"
+)
+
+const (
+	EvalTriggerJobRegister   = "job-register"
+	EvalTriggerJobDeregister = "job-deregister"
+	EvalTriggerPeriodicJob   = "periodic-job"
+	EvalTriggerNodeUpdate    = "node-update"
+	EvalTriggerNodeDrain     = "node-drain"
+	EvalTriggerScheduled     = "scheduled"
+	EvalTriggerRollingUpdate = "rolling-update"
+	EvalTriggerMaxPlans      = "max-plan-attempts"
+	EvalTriggerCustom        = "custom"
+)
+
+const (
+	// CoreJobEvalGC is used for the garbage collection of evaluations
+	// and allocations. We periodically scan evaluations in a terminal state,
+	// in which all the corresponding allocations are also terminal. We
+	// delete these out of the system to bound the state.
+	CoreJobEvalGC = "eval-gc"
+
+	// CoreJobNodeGC is used for the garbage collection of failed nodes.
+	// We periodically scan nodes in a terminal state, and if they have no
+	// corresponding allocations we delete these out of the system.
+	CoreJobNodeGC = "node-gc"
+
+	// CoreJobJobGC is used for the garbage collection of eligible jobs. We
+	// periodically scan garbage collectible jobs and check if both their
+	// evaluations and allocations are terminal. If so, we delete these out of
+	// the system.
+	CoreJobJobGC = "job-gc"
+
+	// CoreJobCSIVolumeClaimGC is used to release the claims of terminal or
+	// collected allocations on CSI volumes. Clients release the claims of
+	// their allocations once they stop, so this only catches the claims of
+	// clients that failed to, such as the ones of lost nodes.
+	CoreJobCSIVolumeClaimGC = "csi-volume-claim-gc"
+
+	// CoreJobForceGC is used to force garbage collection of all GCable objects.
+	CoreJobForceGC = "force-gc"
+)
+
+// Evaluation is used anytime we need to apply business logic as a result
+// of a change to our desired state (job specification) or the emergent state
+// (registered nodes). When the inputs change, we need to "evaluate" them,
+// potentially taking action (allocation of work) or doing nothing if the state
+// of the world does not require it.
+type Evaluation struct {
+	// ID is a randonly generated UUID used for this evaluation. This
+	// is assigned upon the creation of the evaluation.
+	ID string
+
+	// Namespace is the namespace the evaluation is created in
+	Namespace string
+
+	// Priority is used to control scheduling importance and if this job
+	// can preempt other jobs.
+	Priority int
+
+	// Type is used to control which schedulers are available to handle
+	// this evaluation.
+	Type string
+
+	// TriggeredBy is used to give some insight into why this Eval
+	// was created. (Job change, node failure, alloc failure, etc).
+	TriggeredBy string
+
+	// TriggerReason is the reason given by the external system that
+	// triggered a custom evaluation
+	TriggerReason string
+
+	// JobID is the job this evaluation is scoped to. Evaluations cannot
+	// be run in parallel for a given JobID, so we serialize on this.
+	JobID string
+
+	// JobModifyIndex is the modify index of the job at the time
+	// the evaluation was created
+	JobModifyIndex uint64
+
+	// NodeID is the node that was affected triggering the evaluation.
+	NodeID string
+
+	// NodeModifyIndex is the modify index of the node at the time
+	// the evaluation was created
+	NodeModifyIndex uint64
+
+	// Status of the evaluation
+	Status string
+
+	// StatusDescription is meant to provide more human useful information
+	StatusDescription string
+
+	// Wait is a minimum wait time for running the eval. This is used to
+	// support a rolling upgrade.
+	Wait time.Duration
+
+	// NextEval is the evaluation ID for the eval created to do a followup.
+	// This is used to support rolling upgrades, where we need a chain of evaluations.
+	NextEval string
+
+	// PreviousEval is the evaluation ID for the eval creating this one to do a followup.
+	// This is used to support rolling upgrades, where we need a chain of evaluations.
+	PreviousEval string
+
+	// BlockedEval is the evaluation ID for a created blocked eval. A
+	// blocked eval will be created if all allocations could not be placed due
+	// to constraints or lacking resources.
+	BlockedEval string
+
+	// FailedTGAllocs are task groups which have allocations that could not be
+	// made, but the metrics are persisted so that the user can use the feedback
+	// to determine the cause.
+	FailedTGAllocs map[string]*AllocMetric
+
+	// ClassEligibility tracks computed node classes that have been explicitly
+	// marked as eligible or ineligible.
+	ClassEligibility map[string]bool
+
+	// EscapedComputedClass marks whether the job has constraints that are not
+	// captured by computed node classes.
+	EscapedComputedClass bool
+
+	// AnnotatePlan triggers the scheduler to provide additional annotations
+	// during the evaluation. This should not be set during normal operations.
+	AnnotatePlan bool
+
+	// SnapshotIndex is the Raft index of the snapshot used to process the
+	// evaluation. As such it will only be set once it has gone through the
+	// scheduler.
+	SnapshotIndex uint64
+
+	// QueuedAllocations is the number of unplaced allocations at the time the
+	// evaluation was processed. The map is keyed by Task Group names.
+	QueuedAllocations map[string]int
+
+	// AttemptedNodes tracks the nodes that placements have already been
+	// attempted on and failed, keyed by allocation name. It is carried over
+	// to follow-up evaluations so that retries are spread across the cluster
+	// rather than repeatedly selecting the same node.
+	AttemptedNodes map[string][]string
+
+	// QuotaLimitReached marks the quota specification that was exhausted
+	// when this evaluation was blocked. Such evaluations are unblocked when
+	// the quota's usage drops or its limits change.
+	QuotaLimitReached string
+
+	// Timing is the breakdown of the time spent processing the evaluation. It
+	// is set once the evaluation has gone through the scheduler.
+	Timing *EvalTiming
+
+	// Raft Indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// TerminalStatus returns if the current status is terminal and
+// will no longer transition.
+func (e *Evaluation) TerminalStatus() bool {
+	switch e.Status {
+	case EvalStatusComplete, EvalStatusFailed, EvalStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+func (e *Evaluation) GoString() string {
+	return fmt.Sprintf("<Eval '%s' JobID: '%s'>", e.ID, e.JobID)
+}
+
+func (e *Evaluation) Copy() *Evaluation {
+	if e == nil {
+		return nil
+	}
+	ne := new(Evaluation)
+	*ne = *e
+
+	// Copy ClassEligibility
+	if e.ClassEligibility != nil {
+		classes := make(map[string]bool, len(e.ClassEligibility))
+		for class, elig := range e.ClassEligibility {
+			classes[class] = elig
 		}
+		ne.ClassEligibility = classes
 	}
 
-	// Ensure all ports referenced in services exist.
-	for servicePort, services := range servicePorts {
-		_, ok := portLabels[servicePort]
-		if !ok {
-			joined := strings.Join(services, ", ")
-			err := fmt.Errorf("port label %q referenced by services %v does not exist", servicePort, joined)
-			mErr.Errors = append(mErr.Errors, err)
+	// Copy FailedTGAllocs
+	if e.FailedTGAllocs != nil {
+		failedTGs := make(map[string]*AllocMetric, len(e.FailedTGAllocs))
+		for tg, metric := range e.FailedTGAllocs {
+			failedTGs[tg] = metric.Copy()
 		}
+		ne.FailedTGAllocs = failedTGs
 	}
-	return mErr.ErrorOrNil()
-}
-
-const (
-	// TemplateChangeModeNoop marks that no action should be taken if the
-	// template is re-rendered
-	TemplateChangeModeNoop = "noop"
-
-	// TemplateChangeModeSignal marks that the task should be signaled if the
-	// template is re-rendered
-	TemplateChangeModeSignal = "signal"
-
-	// TemplateChangeModeRestart marks that the task should be restarted if the
-	// template is re-rendered
-	TemplateChangeModeRestart = "restart"
-)
 
-var (
-	// TemplateChangeModeInvalidError is the error for when an invalid change
-	// mode is given
-	TemplateChangeModeInvalidError = errors.New("Invalid change mode. Must be one of the following: noop, signal, restart")
-)
+	// Copy queued allocations
+	if e.QueuedAllocations != nil {
+		queuedAllocations := make(map[string]int, len(e.QueuedAllocations))
+		for tg, num := range e.QueuedAllocations {
+			queuedAllocations[tg] = num
+		}
+		ne.QueuedAllocations = queuedAllocations
+	}
 
-// Template represents a template configuration to be rendered for a given task
-type Template struct {
-	// SourcePath is the the path to the template to be rendered
-	SourcePath string `mapstructure:"source"`
+	ne.AttemptedNodes = copyAttemptedNodes(e.AttemptedNodes)
+	ne.Timing = e.Timing.Copy()
+	return ne
+}
 
-	// DestPath is the path to where the template should be rendered
-	DestPath string `mapstructure:"destination"`
+// EvalTiming is the breakdown of the time spent in each phase of processing an
+// evaluation. The durations are totals over all the scheduling attempts made
+// for the evaluation.
+type EvalTiming struct {
+	// QueueWait is the time the evaluation waited in the eval broker before
+	// being dequeued by a scheduler worker.
+	QueueWait time.Duration
 
-	// EmbededTmpl store the raw template. This is useful for smaller templates
-	// where they are embeded in the job file rather than sent as an artificat
-	EmbededTmpl string `mapstructure:"data"`
+	// Snapshot is the time spent waiting for the state to catch up to the
+	// evaluation and snapshotting it.
+	Snapshot time.Duration
 
-	// ChangeMode indicates what should be done if the template is re-rendered
-	ChangeMode string `mapstructure:"change_mode"`
+	// Feasibility is the time spent filtering the nodes that are feasible for
+	// the placements.
+	Feasibility time.Duration
 
-	// RestartSignal is the signal that should be sent if the change mode
-	// requires it.
-	RestartSignal string `mapstructure:"restart_signal"`
+	// Ranking is the time spent scoring the feasible nodes and selecting the
+	// best of them.
+	Ranking time.Duration
 
-	// Splay is used to avoid coordinated restarts of processes by applying a
-	// random wait between 0 and the given splay value before signalling the
-	// application of a change
-	Splay time.Duration `mapstructure:"splay"`
+	// PlanSubmit is the time spent submitting plans to the leader. It
+	// includes the time to evaluate and apply the plans.
+	PlanSubmit time.Duration
 
-	// Once mode is used to indicate that template should be rendered only once
-	Once bool `mapstructure:"once"`
+	// RaftApply is the time the leader spent committing the plans to Raft.
+	RaftApply time.Duration
 }
 
-// DefaultTemplate returns a default template.
-func DefaultTemplate() *Template {
-	return &Template{
-		ChangeMode: TemplateChangeModeRestart,
-		Splay:      5 * time.Second,
-		Once:       false,
+func (t *EvalTiming) Copy() *EvalTiming {
+	if t == nil {
+		return nil
 	}
+	nt := new(EvalTiming)
+	*nt = *t
+	return nt
 }
 
-func (t *Template) Copy() *Template {
-	if t == nil {
+// copyAttemptedNodes returns a deep copy of a set of attempted nodes.
+func copyAttemptedNodes(attempted map[string][]string) map[string][]string {
+	if attempted == nil {
 		return nil
 	}
-	copy := new(Template)
-	*copy = *t
-	return copy
+	c := make(map[string][]string, len(attempted))
+	for name, nodes := range attempted {
+		c[name] = append([]string(nil), nodes...)
+	}
+	return c
 }
 
-func (t *Template) Validate() error {
-	var mErr multierror.Error
-
-	// Verify we have something to render
-	if t.SourcePath == "" && t.EmbededTmpl == "" {
-		multierror.Append(&mErr, fmt.Errorf("Must specify a source path or have an embeded template"))
+// ShouldEnqueue checks if a given evaluation should be enqueued into the
+// eval_broker
+func (e *Evaluation) ShouldEnqueue() bool {
+	switch e.Status {
+	case EvalStatusPending:
+		return true
+	case EvalStatusComplete, EvalStatusFailed, EvalStatusBlocked, EvalStatusCancelled:
+		return false
+	default:
+		panic(fmt.Sprintf("unhandled evaluation (%s) status %s", e.ID, e.Status))
 	}
+}
 
-	// Verify we can render somewhere
-	if t.DestPath == "" {
-		multierror.Append(&mErr, fmt.Errorf("Must specify a destination for the template"))
+// ShouldBlock checks if a given evaluation should be entered into the blocked
+// eval tracker.
+func (e *Evaluation) ShouldBlock() bool {
+	switch e.Status {
+	case EvalStatusBlocked:
+		return true
+	case EvalStatusComplete, EvalStatusFailed, EvalStatusPending, EvalStatusCancelled:
+		return false
+	default:
+		panic(fmt.Sprintf("unhandled evaluation (%s) status %s", e.ID, e.Status))
 	}
+}
 
-	// Verify the destination doesn't escape
-	escaped, err := pathEscapesAllocDir(t.DestPath)
-	if err != nil {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid destination path: %v", err))
-	} else if escaped {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("destination escapes allocation directory"))
+// MakePlan is used to make a plan from the given evaluation
+// for a given Job
+func (e *Evaluation) MakePlan(j *Job) *Plan {
+	p := &Plan{
+		EvalID:         e.ID,
+		Priority:       e.Priority,
+		Job:            j,
+		NodeUpdate:     make(map[string][]*Allocation),
+		NodeAllocation: make(map[string][]*Allocation),
 	}
-
-	// Verify a proper change mode
-	switch t.ChangeMode {
-	case TemplateChangeModeNoop, TemplateChangeModeRestart:
-	case TemplateChangeModeSignal:
-		if t.RestartSignal == "" {
-			multierror.Append(&mErr, fmt.Errorf("Must specify signal value when change mode is signal"))
-		}
-	default:
-		multierror.Append(&mErr, TemplateChangeModeInvalidError)
+	if j != nil {
+		p.AllAtOnce = j.AllAtOnce
 	}
+	return p
+}
 
-	// Verify the splay is positive
-	if t.Splay < 0 {
-		multierror.Append(&mErr, fmt.Errorf("Must specify positive splay value"))
+// NextRollingEval creates an evaluation to followup this eval for rolling updates
+func (e *Evaluation) NextRollingEval(wait time.Duration) *Evaluation {
+	return &Evaluation{
+		ID:             GenerateUUID(),
+		Namespace:      e.Namespace,
+		Priority:       e.Priority,
+		Type:           e.Type,
+		TriggeredBy:    EvalTriggerRollingUpdate,
+		JobID:          e.JobID,
+		JobModifyIndex: e.JobModifyIndex,
+		Status:         EvalStatusPending,
+		Wait:           wait,
+		PreviousEval:   e.ID,
+		AttemptedNodes: copyAttemptedNodes(e.AttemptedNodes),
 	}
+}
 
-	return mErr.ErrorOrNil()
+// CreateBlockedEval creates a blocked evaluation to followup this eval to place any
+// failed allocations. It takes the classes marked explicitly eligible or
+// ineligible and whether the job has escaped computed node classes.
+func (e *Evaluation) CreateBlockedEval(classEligibility map[string]bool, escaped bool) *Evaluation {
+	return &Evaluation{
+		ID:                   GenerateUUID(),
+		Namespace:            e.Namespace,
+		Priority:             e.Priority,
+		Type:                 e.Type,
+		TriggeredBy:          e.TriggeredBy,
+		JobID:                e.JobID,
+		JobModifyIndex:       e.JobModifyIndex,
+		Status:               EvalStatusBlocked,
+		PreviousEval:         e.ID,
+		ClassEligibility:     classEligibility,
+		EscapedComputedClass: escaped,
+		AttemptedNodes:       copyAttemptedNodes(e.AttemptedNodes),
+	}
 }
 
-// Set of possible states for a task.
-const (
-	TaskStatePending = "pending" // The task is waiting to be run.
-	TaskStateRunning = "running" // The task is currently running.
-	TaskStateDead    = "dead"    // Terminal state of task.
-)
+// Plan is used to submit a commit plan for task allocations. These
+// are submitted to the leader which verifies that resources have
+// not been overcommitted before admiting the plan.
+type Plan struct {
+	// EvalID is the evaluation ID this plan is associated with
+	EvalID string
 
-// TaskState tracks the current state of a task and events that caused state
-// transitions.
-type TaskState struct {
-	// The current state of the task.
-	State string
+	// EvalToken is used to prevent a split-brain processing of
+	// an evaluation. There should only be a single scheduler running
+	// an Eval at a time, but this could be violated after a leadership
+	// transition. This unique token is used to reject plans that are
+	// being submitted from a different leader.
+	EvalToken string
 
-	// Series of task events that transition the state of the task.
-	Events []*TaskEvent
+	// Priority is the priority of the upstream job
+	Priority int
+
+	// AllAtOnce is used to control if incremental scheduling of task groups
+	// is allowed or if we must do a gang scheduling of the entire job.
+	// If this is false, a plan may be partially applied. Otherwise, the
+	// entire plan must be able to make progress.
+	AllAtOnce bool
+
+	// Job is the parent job of all the allocations in the Plan.
+	// Since a Plan only involves a single Job, we can reduce the size
+	// of the plan by only including it once.
+	Job *Job
+
+	// NodeUpdate contains all the allocations for each node. For each node,
+	// this is a list of the allocations to update to either stop or evict.
+	NodeUpdate map[string][]*Allocation
+
+	// NodeAllocation contains all the allocations for each node.
+	// The evicts must be considered prior to the allocations.
+	NodeAllocation map[string][]*Allocation
+
+	// NodeModifyIndex is the modify index of each node allocations are placed
+	// on, as of the snapshot the scheduler checked their feasibility against.
+	// Placements on nodes that changed since are rejected when the plan is
+	// applied.
+	NodeModifyIndex map[string]uint64
+
+	// Annotations contains annotations by the scheduler to be used by operators
+	// to understand the decisions made by the scheduler.
+	Annotations *PlanAnnotations
 }
 
-func (ts *TaskState) Copy() *TaskState {
-	if ts == nil {
-		return nil
+// AppendUpdate marks the allocation for eviction. The clientStatus of the
+// allocation may be optionally set by passing in a non-empty value.
+func (p *Plan) AppendUpdate(alloc *Allocation, desiredStatus, desiredDesc, clientStatus string) {
+	newAlloc := new(Allocation)
+	*newAlloc = *alloc
+
+	// If the job is not set in the plan we are deregistering a job so we
+	// extract the job from the allocation.
+	if p.Job == nil && newAlloc.Job != nil {
+		p.Job = newAlloc.Job
 	}
-	copy := new(TaskState)
-	copy.State = ts.State
 
-	if ts.Events != nil {
-		copy.Events = make([]*TaskEvent, len(ts.Events))
-		for i, e := range ts.Events {
-			copy.Events[i] = e.Copy()
-		}
+	// Normalize the job
+	newAlloc.Job = nil
+
+	// Strip the resources as it can be rebuilt.
+	newAlloc.Resources = nil
+
+	newAlloc.DesiredStatus = desiredStatus
+	newAlloc.DesiredDescription = desiredDesc
+
+	if clientStatus != "" {
+		newAlloc.ClientStatus = clientStatus
 	}
-	return copy
+
+	node := alloc.NodeID
+	existing := p.NodeUpdate[node]
+	p.NodeUpdate[node] = append(existing, newAlloc)
 }
 
-// Failed returns true if the task has has failed.
-func (ts *TaskState) Failed() bool {
-	l := len(ts.Events)
-	if ts.State != TaskStateDead || l == 0 {
-		return false
+func (p *Plan) PopUpdate(alloc *Allocation) {
+	existing := p.NodeUpdate[alloc.NodeID]
+	n := len(existing)
+	if n > 0 && existing[n-1].ID == alloc.ID {
+		existing = existing[:n-1]
+		if len(existing) > 0 {
+			p.NodeUpdate[alloc.NodeID] = existing
+		} else {
+			delete(p.NodeUpdate, alloc.NodeID)
+		}
 	}
+}
 
-	switch ts.Events[l-1].Type {
-	case TaskDiskExceeded, TaskNotRestarting, TaskArtifactDownloadFailed,
-		TaskFailedValidation, TaskVaultRenewalFailed:
-		return true
-	default:
-		return false
-	}
+func (p *Plan) AppendAlloc(alloc *Allocation) {
+	node := alloc.NodeID
+	existing := p.NodeAllocation[node]
+	p.NodeAllocation[node] = append(existing, alloc)
 }
 
-// Successful returns whether a task finished successfully.
-func (ts *TaskState) Successful() bool {
-	l := len(ts.Events)
-	if ts.State != TaskStateDead || l == 0 {
-		return false
+// AppendNodeIndex records the modify index of the node an allocation is
+// placed on. The index of the first version of the node seen is kept.
+func (p *Plan) AppendNodeIndex(node *Node) {
+	if p.NodeModifyIndex == nil {
+		p.NodeModifyIndex = make(map[string]uint64)
 	}
-
-	e := ts.Events[l-1]
-	if e.Type != TaskTerminated {
-		return false
+	if _, ok := p.NodeModifyIndex[node.ID]; !ok {
+		p.NodeModifyIndex[node.ID] = node.ModifyIndex
 	}
+}
 
-	return e.ExitCode == 0
+// IsNoOp checks if this plan would do nothing
+func (p *Plan) IsNoOp() bool {
+	return len(p.NodeUpdate) == 0 && len(p.NodeAllocation) == 0
 }
 
-const (
-	// TaskDriveFailure indicates that the task could not be started due to a
-	// failure in the driver.
-	TaskDriverFailure = "Driver Failure"
+// PlanResult is the result of a plan submitted to the leader.
+type PlanResult struct {
+	// NodeUpdate contains all the updates that were committed.
+	NodeUpdate map[string][]*Allocation
 
-	// TaskReceived signals that the task has been pulled by the client at the
-	// given timestamp.
-	TaskReceived = "Received"
+	// NodeAllocation contains all the allocations that were committed.
+	NodeAllocation map[string][]*Allocation
 
-	// TaskFailedValidation indicates the task was invalid and as such was not
-	// run.
-	TaskFailedValidation = "Failed Validation"
+	// RefreshIndex is the index the worker should refresh state up to.
+	// This allows all evictions and allocations to be materialized.
+	// If any allocations were rejected due to stale data (node state,
+	// over committed) this can be used to force a worker refresh.
+	RefreshIndex uint64
 
-	// TaskStarted signals that the task was started and its timestamp can be
-	// used to determine the running length of the task.
-	TaskStarted = "Started"
+	// AllocIndex is the Raft index in which the evictions and
+	// allocations took place. This is used for the write index.
+	AllocIndex uint64
 
-	// TaskTerminated indicates that the task was started and exited.
-	TaskTerminated = "Terminated"
+	// QuotaLimitReached is set to the name of the quota specification if the
+	// plan was rejected because it would have exceeded the quota.
+	QuotaLimitReached string
 
-	// TaskKilling indicates a kill signal has been sent to the task.
-	TaskKilling = "Killing"
+	// RejectedNodes are the nodes the plan was rejected for, as they could
+	// not fit the allocations
+	RejectedNodes []string
 
-	// TaskKilled indicates a user has killed the task.
-	TaskKilled = "Killed"
+	// RaftApply is the time spent committing the result to Raft.
+	RaftApply time.Duration
+}
 
-	// TaskRestarting indicates that task terminated and is being restarted.
-	TaskRestarting = "Restarting"
+// IsNoOp checks if this plan result would do nothing
+func (p *PlanResult) IsNoOp() bool {
+	return len(p.NodeUpdate) == 0 && len(p.NodeAllocation) == 0
+}
 
-	// TaskNotRestarting indicates that the task has failed and is not being
-	// restarted because it has exceeded its restart policy.
-	TaskNotRestarting = "Not Restarting"
+// FullCommit is used to check if all the allocations in a plan
+// were committed as part of the result. Returns if there was
+// a match, and the number of expected and actual allocations.
+func (p *PlanResult) FullCommit(plan *Plan) (bool, int, int) {
+	expected := 0
+	actual := 0
+	for name, allocList := range plan.NodeAllocation {
+		didAlloc, _ := p.NodeAllocation[name]
+		expected += len(allocList)
+		actual += len(didAlloc)
+	}
+	return actual == expected, expected, actual
+}
 
-	// TaskDownloadingArtifacts means the task is downloading the artifacts
-	// specified in the task.
-	TaskDownloadingArtifacts = "Downloading Artifacts"
+// PlanAnnotations holds annotations made by the scheduler to give further debug
+// information to operators.
+type PlanAnnotations struct {
+	// DesiredTGUpdates is the set of desired updates per task group.
+	DesiredTGUpdates map[string]*DesiredUpdates
 
-	// TaskArtifactDownloadFailed indicates that downloading the artifacts
-	// failed.
-	TaskArtifactDownloadFailed = "Failed Artifact Download"
+	// MaintenanceWindows are the maintenance windows in effect when the plan
+	// was computed, which avoid or block placements on their node classes.
+	MaintenanceWindows []*MaintenanceWindow
+}
 
-	// TaskDiskExceeded indicates that one of the tasks in a taskgroup has
-	// exceeded the requested disk resources.
-	TaskDiskExceeded = "Disk Resources Exceeded"
+// DesiredUpdates is the set of changes the scheduler would like to make given
+// sufficient resources and cluster capacity.
+type DesiredUpdates struct {
+	Ignore            uint64
+	Place             uint64
+	Migrate           uint64
+	Stop              uint64
+	InPlaceUpdate     uint64
+	DestructiveUpdate uint64
+}
 
-	// TaskSiblingFailed indicates that a sibling task in the task group has
-	// failed.
-	TaskSiblingFailed = "Sibling task failed"
+const (
+	// ACLClientToken and ACLManagementToken are the only types of tokens
+	ACLClientToken     = "client"
+	ACLManagementToken = "management"
 
-	// TaskVaultRenewalFailed indicates that Vault token renewal failed
-	TaskVaultRenewalFailed = "Vault token renewal failed"
-)
+	// maxACLPolicyDescriptionLength limits a policy description length
+	maxACLPolicyDescriptionLength = 256
 
-// TaskEvent is an event that effects the state of a task and contains meta-data
-// appropriate to the events type.
-type TaskEvent struct {
-	Type string
-	Time int64 // Unix Nanosecond timestamp
+	// maxACLTokenNameLength limits a ACL token name length
+	maxACLTokenNameLength = 256
+)
 
-	// Restart fields.
-	RestartReason string
+var (
+	// validACLPolicyName is used to validate a policy name
+	validACLPolicyName = regexp.MustCompile("^[a-zA-Z0-9-]{1,128}$")
+
+	// AnonymousACLToken is used when no SecretID is provided, and the
+	// request is made anonymously.
+	AnonymousACLToken = &ACLToken{
+		AccessorID: "anonymous",
+		Name:       "Anonymous Token",
+		Type:       ACLClientToken,
+		Policies:   []string{"anonymous"},
+		Global:     false,
+	}
+)
 
-	// Driver Failure fields.
-	DriverError string // A driver error occurred while starting the task.
+// ACLPolicy is used to represent an ACL policy
+type ACLPolicy struct {
+	Name        string // Unique name
+	Description string // Human readable
+	Rules       string // HCL or JSON format
+	Hash        []byte
+	CreateIndex uint64
+	ModifyIndex uint64
+}
 
-	// Task Terminated Fields.
-	ExitCode int    // The exit code of the task.
-	Signal   int    // The signal that terminated the task.
-	Message  string // A possible message explaining the termination of the task.
+// SetHash is used to compute and set the hash of the ACL policy
+func (c *ACLPolicy) SetHash() []byte {
+	// Initialize a 256bit hash (32 bytes)
+	hash := sha256.New()
 
-	// Killing fields
-	KillTimeout time.Duration
+	// Write all the user set fields
+	hash.Write([]byte(c.Name))
+	hash.Write([]byte(c.Description))
+	hash.Write([]byte(c.Rules))
 
-	// Task Killed Fields.
-	KillError string // Error killing the task.
+	// Finalize the hash
+	hashVal := hash.Sum(nil)
 
-	// TaskRestarting fields.
-	StartDelay int64 // The sleep period before restarting the task in unix nanoseconds.
+	// Set and return the hash
+	c.Hash = hashVal
+	return hashVal
+}
 
-	// Artifact Download fields
-	DownloadError string // Error downloading artifacts
+func (a *ACLPolicy) Stub() *ACLPolicyListStub {
+	return &ACLPolicyListStub{
+		Name:        a.Name,
+		Description: a.Description,
+		Hash:        a.Hash,
+		CreateIndex: a.CreateIndex,
+		ModifyIndex: a.ModifyIndex,
+	}
+}
 
-	// Validation fields
-	ValidationError string // Validation error
+// Validate is used to sanity check the policy. The rules themselves are
+// validated by the caller, which compiles them.
+func (a *ACLPolicy) Validate() error {
+	var mErr multierror.Error
+	if !validACLPolicyName.MatchString(a.Name) {
+		err := fmt.Errorf("invalid name '%s'", a.Name)
+		mErr.Errors = append(mErr.Errors, err)
+	}
+	if len(a.Description) > maxACLPolicyDescriptionLength {
+		err := fmt.Errorf("description longer than %d", maxACLPolicyDescriptionLength)
+		mErr.Errors = append(mErr.Errors, err)
+	}
+	return mErr.ErrorOrNil()
+}
 
-	// The maximum allowed task disk size.
-	DiskLimit int64
+// ACLPolicyListStub is used to for listing ACL policies
+type ACLPolicyListStub struct {
+	Name        string
+	Description string
+	Hash        []byte
+	CreateIndex uint64
+	ModifyIndex uint64
+}
 
-	// The recorded task disk size.
-	DiskSize int64
+// ACLPolicyListRequest is used to request a list of policies
+type ACLPolicyListRequest struct {
+	QueryOptions
+}
 
-	// Name of the sibling task that caused termination of the task that
-	// the TaskEvent refers to.
-	FailedSibling string
+// ACLPolicySpecificRequest is used to query a specific policy
+type ACLPolicySpecificRequest struct {
+	Name string
+	QueryOptions
+}
 
-	// VaultError is the error from token renewal
-	VaultError string
+// ACLPolicySetRequest is used to query a set of policies
+type ACLPolicySetRequest struct {
+	Names []string
+	QueryOptions
 }
 
-func (te *TaskEvent) GoString() string {
-	return fmt.Sprintf("%v at %v", te.Type, te.Time)
+// ACLPolicyListResponse is used for a list request
+type ACLPolicyListResponse struct {
+	Policies []*ACLPolicyListStub
+	QueryMeta
 }
 
-func (te *TaskEvent) Copy() *TaskEvent {
-	if te == nil {
-		return nil
-	}
-	copy := new(TaskEvent)
-	*copy = *te
-	return copy
+// SingleACLPolicyResponse is used to return a single policy
+type SingleACLPolicyResponse struct {
+	Policy *ACLPolicy
+	QueryMeta
 }
 
-func NewTaskEvent(event string) *TaskEvent {
-	return &TaskEvent{
-		Type: event,
-		Time: time.Now().UnixNano(),
-	}
+// ACLPolicySetResponse is used to return a set of policies
+type ACLPolicySetResponse struct {
+	Policies map[string]*ACLPolicy
+	QueryMeta
 }
 
-func (e *TaskEvent) SetDriverError(err error) *TaskEvent {
-	if err != nil {
-		e.DriverError = err.Error()
-	}
-	return e
+// ACLPolicyDeleteRequest is used to delete a set of policies
+type ACLPolicyDeleteRequest struct {
+	Names []string
+	WriteRequest
 }
 
-func (e *TaskEvent) SetExitCode(c int) *TaskEvent {
-	e.ExitCode = c
-	return e
+// ACLPolicyUpsertRequest is used to upsert a set of policies
+type ACLPolicyUpsertRequest struct {
+	Policies []*ACLPolicy
+	WriteRequest
 }
 
-func (e *TaskEvent) SetSignal(s int) *TaskEvent {
-	e.Signal = s
-	return e
+// ACLToken represents a client token which is used to Authenticate
+type ACLToken struct {
+	AccessorID  string   // Public Accessor ID (UUID)
+	SecretID    string   // Secret ID, private (UUID)
+	Name        string   // Human friendly name
+	Type        string   // Client or Management
+	Policies    []string // Policies this token ties to
+	Global      bool     // Global or Region local
+	Hash        []byte
+	CreateTime  time.Time // Time of creation
+	CreateIndex uint64
+	ModifyIndex uint64
 }
 
-func (e *TaskEvent) SetExitMessage(err error) *TaskEvent {
-	if err != nil {
-		e.Message = err.Error()
+// Copy returns a deep copy of the token
+func (a *ACLToken) Copy() *ACLToken {
+	if a == nil {
+		return nil
 	}
-	return e
+	c := new(ACLToken)
+	*c = *a
+
+	c.Policies = make([]string, len(a.Policies))
+	copy(c.Policies, a.Policies)
+	c.Hash = make([]byte, len(a.Hash))
+	copy(c.Hash, a.Hash)
+
+	return c
 }
 
-func (e *TaskEvent) SetKillError(err error) *TaskEvent {
-	if err != nil {
-		e.KillError = err.Error()
+// SetHash is used to compute and set the hash of the ACL token
+func (a *ACLToken) SetHash() []byte {
+	// Initialize a 256bit hash (32 bytes)
+	hash := sha256.New()
+
+	// Write all the user set fields
+	hash.Write([]byte(a.Name))
+	hash.Write([]byte(a.Type))
+	for _, policyName := range a.Policies {
+		hash.Write([]byte(policyName))
+	}
+	if a.Global {
+		hash.Write([]byte("global"))
+	} else {
+		hash.Write([]byte("local"))
 	}
-	return e
-}
 
-func (e *TaskEvent) SetRestartDelay(delay time.Duration) *TaskEvent {
-	e.StartDelay = int64(delay)
-	return e
+	// Finalize the hash
+	hashVal := hash.Sum(nil)
+
+	// Set and return the hash
+	a.Hash = hashVal
+	return hashVal
 }
 
-func (e *TaskEvent) SetRestartReason(reason string) *TaskEvent {
-	e.RestartReason = reason
-	return e
+func (a *ACLToken) Stub() *ACLTokenListStub {
+	return &ACLTokenListStub{
+		AccessorID:  a.AccessorID,
+		Name:        a.Name,
+		Type:        a.Type,
+		Policies:    a.Policies,
+		Global:      a.Global,
+		Hash:        a.Hash,
+		CreateTime:  a.CreateTime,
+		CreateIndex: a.CreateIndex,
+		ModifyIndex: a.ModifyIndex,
+	}
 }
 
-func (e *TaskEvent) SetDownloadError(err error) *TaskEvent {
-	if err != nil {
-		e.DownloadError = err.Error()
+// Validate is used to sanity check a token
+func (a *ACLToken) Validate() error {
+	var mErr multierror.Error
+	if len(a.Name) > maxACLTokenNameLength {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("token name too long"))
+	}
+	switch a.Type {
+	case ACLClientToken:
+		if len(a.Policies) == 0 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("client token missing policies"))
+		}
+	case ACLManagementToken:
+		if len(a.Policies) != 0 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("management token cannot be associated with policies"))
+		}
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("token type must be client or management"))
 	}
-	return e
+	return mErr.ErrorOrNil()
 }
 
-func (e *TaskEvent) SetValidationError(err error) *TaskEvent {
-	if err != nil {
-		e.ValidationError = err.Error()
+// PolicySubset checks if a given set of policies is a subset of the token
+func (a *ACLToken) PolicySubset(policies []string) bool {
+	// Hot-path the management tokens, superset of all policies.
+	if a.Type == ACLManagementToken {
+		return true
 	}
-	return e
+	associatedPolicies := make(map[string]struct{}, len(a.Policies))
+	for _, policy := range a.Policies {
+		associatedPolicies[policy] = struct{}{}
+	}
+	for _, policy := range policies {
+		if _, ok := associatedPolicies[policy]; !ok {
+			return false
+		}
+	}
+	return true
 }
 
-func (e *TaskEvent) SetKillTimeout(timeout time.Duration) *TaskEvent {
-	e.KillTimeout = timeout
-	return e
+// ACLTokenListStub is used for listing ACL tokens. It omits the SecretID.
+type ACLTokenListStub struct {
+	AccessorID  string
+	Name        string
+	Type        string
+	Policies    []string
+	Global      bool
+	Hash        []byte
+	CreateTime  time.Time
+	CreateIndex uint64
+	ModifyIndex uint64
 }
 
-func (e *TaskEvent) SetDiskLimit(limit int64) *TaskEvent {
-	e.DiskLimit = limit
-	return e
+// ACLTokenListRequest is used to request a list of tokens
+type ACLTokenListRequest struct {
+	GlobalOnly bool
+	QueryOptions
 }
 
-func (e *TaskEvent) SetDiskSize(size int64) *TaskEvent {
-	e.DiskSize = size
-	return e
+// ACLTokenSpecificRequest is used to query a specific token
+type ACLTokenSpecificRequest struct {
+	AccessorID string
+	QueryOptions
 }
 
-func (e *TaskEvent) SetFailedSibling(sibling string) *TaskEvent {
-	e.FailedSibling = sibling
-	return e
+// ACLTokenSetRequest is used to query a set of tokens
+type ACLTokenSetRequest struct {
+	AccessorIDs []string
+	QueryOptions
 }
 
-func (e *TaskEvent) SetVaultRenewalError(err error) *TaskEvent {
-	if err != nil {
-		e.VaultError = err.Error()
-	}
-	return e
+// ACLTokenListResponse is used for a list request
+type ACLTokenListResponse struct {
+	Tokens []*ACLTokenListStub
+	QueryMeta
 }
 
-// TaskArtifact is an artifact to download before running the task.
-type TaskArtifact struct {
-	// GetterSource is the source to download an artifact using go-getter
-	GetterSource string `mapstructure:"source"`
-
-	// GetterOptions are options to use when downloading the artifact using
-	// go-getter.
-	GetterOptions map[string]string `mapstructure:"options"`
-
-	// RelativeDest is the download destination given relative to the task's
-	// directory.
-	RelativeDest string `mapstructure:"destination"`
+// SingleACLTokenResponse is used to return a single token
+type SingleACLTokenResponse struct {
+	Token *ACLToken
+	QueryMeta
 }
 
-func (ta *TaskArtifact) Copy() *TaskArtifact {
-	if ta == nil {
-		return nil
-	}
-	nta := new(TaskArtifact)
-	*nta = *ta
-	nta.GetterOptions = CopyMapStringString(ta.GetterOptions)
-	return nta
+// ACLTokenSetResponse is used to return a set of tokens
+type ACLTokenSetResponse struct {
+	Tokens map[string]*ACLToken // Keyed by Accessor ID
+	QueryMeta
 }
 
-func (ta *TaskArtifact) GoString() string {
-	return fmt.Sprintf("%+v", ta)
+// ResolveACLTokenRequest is used to resolve a specific token
+type ResolveACLTokenRequest struct {
+	SecretID string
+	QueryOptions
 }
 
-// pathEscapesAllocDir returns if the given path escapes the allocation
-// directory
-func pathEscapesAllocDir(path string) (bool, error) {
-	// Verify the destination doesn't escape the tasks directory
-	alloc, err := filepath.Abs(filepath.Join("/", "foo/", "bar/"))
-	if err != nil {
-		return false, err
-	}
-	abs, err := filepath.Abs(filepath.Join(alloc, path))
-	if err != nil {
-		return false, err
-	}
-	rel, err := filepath.Rel(alloc, abs)
-	if err != nil {
-		return false, err
-	}
-
-	return strings.HasPrefix(rel, ".."), nil
+// ResolveACLTokenResponse is used to resolve a single token
+type ResolveACLTokenResponse struct {
+	Token *ACLToken
+	QueryMeta
 }
 
-func (ta *TaskArtifact) Validate() error {
-	// Verify the source
-	var mErr multierror.Error
-	if ta.GetterSource == "" {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("source must be specified"))
-	}
+// ACLTokenDeleteRequest is used to delete a set of tokens
+type ACLTokenDeleteRequest struct {
+	AccessorIDs []string
+	WriteRequest
+}
 
-	escaped, err := pathEscapesAllocDir(ta.RelativeDest)
-	if err != nil {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid destination path: %v", err))
-	} else if escaped {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("destination escapes task's directory"))
-	}
+// ACLTokenBootstrapRequest is used to bootstrap ACLs
+type ACLTokenBootstrapRequest struct {
+	Token      *ACLToken // Not client specifiable
+	ResetIndex uint64    // Reset index is used to clear the bootstrap token
+	WriteRequest
+}
 
-	// Verify the checksum
-	if check, ok := ta.GetterOptions["checksum"]; ok {
-		check = strings.TrimSpace(check)
-		if check == "" {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("checksum value can not be empty"))
-			return mErr.ErrorOrNil()
-		}
+// ACLTokenUpsertRequest is used to upsert a set of tokens
+type ACLTokenUpsertRequest struct {
+	Tokens []*ACLToken
+	WriteRequest
+}
 
-		parts := strings.Split(check, ":")
-		if l := len(parts); l != 2 {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf(`checksum must be given as "type:value"; got %q`, check))
-			return mErr.ErrorOrNil()
-		}
+// ACLTokenUpsertResponse is used to return from an ACLTokenUpsertRequest
+type ACLTokenUpsertResponse struct {
+	Tokens []*ACLToken
+	WriteMeta
+}
 
-		checksumVal := parts[1]
-		checksumBytes, err := hex.DecodeString(checksumVal)
-		if err != nil {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid checksum: %v", err))
-			return mErr.ErrorOrNil()
-		}
+// ACLReplicationStatus is the status of the replication of the ACL policies
+// and global tokens of a region from the authoritative region
+type ACLReplicationStatus struct {
+	// Enabled is whether the region replicates from the authoritative region
+	Enabled bool
 
-		checksumType := parts[0]
-		expectedLength := 0
-		switch checksumType {
-		case "md5":
-			expectedLength = md5.Size
-		case "sha1":
-			expectedLength = sha1.Size
-		case "sha256":
-			expectedLength = sha256.Size
-		case "sha512":
-			expectedLength = sha512.Size
-		default:
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("unsupported checksum type: %s", checksumType))
-			return mErr.ErrorOrNil()
-		}
+	// Running is whether the leader of the region is replicating
+	Running bool
 
-		if len(checksumBytes) != expectedLength {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid %s checksum: %v", checksumType, checksumVal))
-			return mErr.ErrorOrNil()
-		}
-	}
+	// SourceRegion is the authoritative region replicated from
+	SourceRegion string
 
-	return mErr.ErrorOrNil()
+	// Policies and Tokens are the status of the replication of the ACL
+	// policies and global tokens
+	Policies *ReplicationProgress
+	Tokens   *ReplicationProgress
 }
 
-const (
-	ConstraintDistinctHosts = "distinct_hosts"
-	ConstraintRegex         = "regexp"
-	ConstraintVersion       = "version"
-)
+// ReplicationProgress is the progress of the replication of one type of
+// object from the authoritative region
+type ReplicationProgress struct {
+	// ReplicatedIndex is the index of the authoritative region the objects
+	// were last replicated at
+	ReplicatedIndex uint64
 
-// Constraints are used to restrict placement options.
-type Constraint struct {
-	LTarget string // Left-hand target
-	RTarget string // Right-hand target
-	Operand string // Constraint operand (<=, <, =, !=, >, >=), contains, near
-	str     string // Memoized string
-}
+	// LastSuccess is the time the objects were last replicated at, and
+	// LastError the time the replication last failed at
+	LastSuccess int64
+	LastError   int64
 
-// Equal checks if two constraints are equal
-func (c *Constraint) Equal(o *Constraint) bool {
-	return c.LTarget == o.LTarget &&
-		c.RTarget == o.RTarget &&
-		c.Operand == o.Operand
+	// LastErrorMessage is why the replication last failed
+	LastErrorMessage string
+
+	// Lag is how long the replication has been failing to keep up with the
+	// authoritative region. It is zero while the replication is blocked
+	// waiting on changes to the objects, since any change is then replicated
+	// as it happens.
+	Lag time.Duration
 }
 
-func (c *Constraint) Copy() *Constraint {
-	if c == nil {
+// Copy returns a copy of the replication progress
+func (p *ReplicationProgress) Copy() *ReplicationProgress {
+	if p == nil {
 		return nil
 	}
-	nc := new(Constraint)
-	*nc = *c
-	return nc
+	np := new(ReplicationProgress)
+	*np = *p
+	return np
 }
 
-func (c *Constraint) String() string {
-	if c.str != "" {
-		return c.str
-	}
-	c.str = fmt.Sprintf("%s %s %s", c.LTarget, c.Operand, c.RTarget)
-	return c.str
+// ACLReplicationStatusResponse is used to return the status of the ACL
+// replication of a region
+type ACLReplicationStatusResponse struct {
+	Status *ACLReplicationStatus
+	QueryMeta
 }
 
-func (c *Constraint) Validate() error {
-	var mErr multierror.Error
-	if c.Operand == "" {
-		mErr.Errors = append(mErr.Errors, errors.New("Missing constraint operand"))
-	}
+// NamespaceReplicationStatus is the status of the replication of the
+// namespaces and quota specifications of a region from the authoritative
+// region
+type NamespaceReplicationStatus struct {
+	// Enabled is whether the region replicates from the authoritative region
+	Enabled bool
 
-	// Perform additional validation based on operand
-	switch c.Operand {
-	case ConstraintRegex:
-		if _, err := regexp.Compile(c.RTarget); err != nil {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("Regular expression failed to compile: %v", err))
-		}
-	case ConstraintVersion:
-		if _, err := version.NewConstraint(c.RTarget); err != nil {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("Version constraint is invalid: %v", err))
-		}
-	}
-	return mErr.ErrorOrNil()
-}
+	// Running is whether the leader of the region is replicating
+	Running bool
 
-// EphemeralDisk is an ephemeral disk object
-type EphemeralDisk struct {
-	// Sticky indicates whether the allocation is sticky to a node
-	Sticky bool
+	// SourceRegion is the authoritative region replicated from
+	SourceRegion string
 
-	// SizeMB is the size of the local disk
-	SizeMB int `mapstructure:"size"`
-}
+	// Namespaces and QuotaSpecs are the status of the replication of the
+	// namespaces and quota specifications
+	Namespaces *ReplicationProgress
+	QuotaSpecs *ReplicationProgress
 
-// DefaultEphemeralDisk returns a EphemeralDisk with default configurations
-func DefaultEphemeralDisk() *EphemeralDisk {
-	return &EphemeralDisk{
-		SizeMB: 300,
-	}
+	// Conflicts are the objects that could not be made to match the
+	// authoritative region when they were last reconciled
+	Conflicts []*ReplicationConflict
 }
 
-// Validate validates EphemeralDisk
-func (d *EphemeralDisk) Validate() error {
-	if d.SizeMB < 10 {
-		return fmt.Errorf("minimum DiskMB value is 10; got %d", d.SizeMB)
-	}
-	return nil
+// ReplicationConflict is an object of a region that could not be made to
+// match the authoritative region, such as a namespace that can't be deleted
+// because it still contains jobs. Conflicts are retried on every reconcile.
+type ReplicationConflict struct {
+	// Type is the type of the object, such as "namespaces"
+	Type string
+
+	// Name is the name of the object
+	Name string
+
+	// Reason is why the object could not be replicated
+	Reason string
 }
 
-// Copy copies the EphemeralDisk struct and returns a new one
-func (d *EphemeralDisk) Copy() *EphemeralDisk {
-	ld := new(EphemeralDisk)
-	*ld = *d
-	return ld
+// NamespaceReplicationStatusResponse is used to return the status of the
+// namespace replication of a region
+type NamespaceReplicationStatusResponse struct {
+	Status *NamespaceReplicationStatus
+	QueryMeta
 }
 
-// Vault stores the set of premissions a task needs access to from Vault.
-type Vault struct {
-	// Policies is the set of policies that the task needs access to
-	Policies []string
+const (
+	// WorkloadIdentityAlgorithm is the JWS algorithm used to sign workload
+	// identities
+	WorkloadIdentityAlgorithm = "RS256"
 
-	// Env marks whether the Vault Token should be exposed as an environment
-	// variable
-	Env bool
-}
+	// WorkloadIdentityIssuer is the issuer of workload identities
+	WorkloadIdentityIssuer = "nomad"
+)
 
-// Copy returns a copy of this Vault block.
-func (v *Vault) Copy() *Vault {
-	if v == nil {
-		return nil
-	}
+// WorkloadIdentityKey is a key used by the leader to sign the workload
+// identities of allocations. The private key never leaves the servers, while
+// the public key is published so third parties can verify identities.
+type WorkloadIdentityKey struct {
+	// KeyID uniquely identifies the key and is set as the "kid" header of
+	// the identities it signs
+	KeyID string
 
-	nv := new(Vault)
-	*nv = *v
-	return nv
-}
+	// Algorithm is the JWS algorithm the key signs with
+	Algorithm string
 
-// Validate returns if the Vault block is valid.
-func (v *Vault) Validate() error {
-	if v == nil {
-		return nil
-	}
+	// PrivateKey is the PKCS #1, ASN.1 DER encoded RSA private key
+	PrivateKey []byte
 
-	if len(v.Policies) == 0 {
-		return fmt.Errorf("Policy list can not be empty")
+	// PublicKey is the PKIX, ASN.1 DER encoded RSA public key
+	PublicKey []byte
+
+	// CreateTime is the time the key was generated
+	CreateTime int64
+
+	// Raft Indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// PublicKeyStub returns the publishable part of the key
+func (k *WorkloadIdentityKey) PublicKeyStub() *WorkloadIdentityPublicKey {
+	return &WorkloadIdentityPublicKey{
+		KeyID:       k.KeyID,
+		Algorithm:   k.Algorithm,
+		PublicKey:   k.PublicKey,
+		CreateTime:  k.CreateTime,
+		CreateIndex: k.CreateIndex,
+		ModifyIndex: k.ModifyIndex,
 	}
+}
 
-	return nil
+// WorkloadIdentityPublicKey is the public part of a WorkloadIdentityKey
+type WorkloadIdentityPublicKey struct {
+	KeyID       string
+	Algorithm   string
+	PublicKey   []byte
+	CreateTime  int64
+	CreateIndex uint64
+	ModifyIndex uint64
 }
 
-const (
-	AllocDesiredStatusRun   = "run"   // Allocation should run
-	AllocDesiredStatusStop  = "stop"  // Allocation should stop
-	AllocDesiredStatusEvict = "evict" // Allocation should stop, and was evicted
-)
+// WorkloadIdentityClaims are the claims of the identity issued to a task
+type WorkloadIdentityClaims struct {
+	Issuer       string `json:"iss"`
+	Subject      string `json:"sub"`
+	IssuedAt     int64  `json:"iat"`
+	NotBefore    int64  `json:"nbf"`
+	Namespace    string `json:"nomad_namespace"`
+	JobID        string `json:"nomad_job_id"`
+	AllocationID string `json:"nomad_allocation_id"`
+	TaskGroup    string `json:"nomad_task_group"`
+	Task         string `json:"nomad_task"`
+}
+
+// NewWorkloadIdentityClaims returns the claims for the given task of an
+// allocation, issued at the given time
+func NewWorkloadIdentityClaims(alloc *Allocation, task string, now time.Time) *WorkloadIdentityClaims {
+	return &WorkloadIdentityClaims{
+		Issuer:       WorkloadIdentityIssuer,
+		Subject:      strings.Join([]string{alloc.Namespace, alloc.JobID, alloc.TaskGroup, task}, ":"),
+		IssuedAt:     now.Unix(),
+		NotBefore:    now.Unix(),
+		Namespace:    alloc.Namespace,
+		JobID:        alloc.JobID,
+		AllocationID: alloc.ID,
+		TaskGroup:    alloc.TaskGroup,
+		Task:         task,
+	}
+}
+
+// WorkloadIdentityKeyUpsertRequest is used to upsert a set of workload
+// identity signing keys
+type WorkloadIdentityKeyUpsertRequest struct {
+	Keys []*WorkloadIdentityKey
+	WriteRequest
+}
+
+// WorkloadIdentityPublicKeysRequest is used to list the public keys used to
+// verify workload identities
+type WorkloadIdentityPublicKeysRequest struct {
+	QueryOptions
+}
+
+// WorkloadIdentityPublicKeysResponse is used to return the public keys used
+// to verify workload identities
+type WorkloadIdentityPublicKeysResponse struct {
+	Keys []*WorkloadIdentityPublicKey
+	QueryMeta
+}
 
 const (
-	AllocClientStatusPending  = "pending"
-	AllocClientStatusRunning  = "running"
-	AllocClientStatusComplete = "complete"
-	AllocClientStatusFailed   = "failed"
-	AllocClientStatusLost     = "lost"
+	ServiceRegistrationHealthPassing  = "passing"
+	ServiceRegistrationHealthCritical = "critical"
 )
 
-// Allocation is used to allocate the placement of a task group to a node.
-type Allocation struct {
-	// ID of the allocation (UUID)
+// ServiceRegistration is the registration of a task's service in Nomad's
+// built-in service catalog. Registrations are created by the client running
+// the task and removed once the task stops or its allocation is terminal.
+type ServiceRegistration struct {
+	// ID uniquely identifies the registration and is derived from the
+	// allocation, task and service
 	ID string
 
-	// ID of the evaluation that generated this allocation
-	EvalID string
+	// ServiceName is the interpolated name of the service
+	ServiceName string
 
-	// Name is a logical name of the allocation.
-	Name string
+	Namespace  string
+	NodeID     string
+	Datacenter string
+	JobID      string
+	AllocID    string
+	Task       string
 
-	// NodeID is the node this is being placed on
-	NodeID string
+	// Tags are the interpolated tags of the service
+	Tags []string
 
-	// Job is the parent job of the task group being allocated.
-	// This is copied at allocation time to avoid issues if the job
-	// definition is updated.
-	JobID string
-	Job   *Job
+	// Address and Port are where the service can be reached
+	Address string
+	Port    int
 
-	// TaskGroup is the name of the task group that should be run
-	TaskGroup string
+	// Health is the health status of the service as reported by the client
+	Health string
 
-	// Resources is the total set of resources allocated as part
-	// of this allocation of the task group.
-	Resources *Resources
+	// Raft Indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
 
-	// SharedResources are the resources that are shared by all the tasks in an
-	// allocation
-	SharedResources *Resources
+// ServiceRegistrationID returns the ID of the registration of the given
+// service of an allocation's task
+func ServiceRegistrationID(allocID, task string, service *Service) string {
+	return fmt.Sprintf("_nomad-task-%s-%s-%s-%s", allocID, task, service.Name, service.PortLabel)
+}
 
-	// TaskResources is the set of resources allocated to each
-	// task. These should sum to the total Resources.
-	TaskResources map[string]*Resources
+// Copy returns a copy of the service registration
+func (s *ServiceRegistration) Copy() *ServiceRegistration {
+	if s == nil {
+		return nil
+	}
+	ns := new(ServiceRegistration)
+	*ns = *s
+	ns.Tags = CopySliceString(ns.Tags)
+	return ns
+}
 
-	// Metrics associated with this allocation
-	Metrics *AllocMetric
+// ServiceRegistrationListStub summarizes the services registered under a
+// name in a namespace
+type ServiceRegistrationListStub struct {
+	Namespace   string
+	ServiceName string
+	Tags        []string
+}
 
-	// Desired Status of the allocation on the client
-	DesiredStatus string
+// ServiceRegistrationUpsertRequest is used by clients to upsert the service
+// registrations of the tasks they run
+type ServiceRegistrationUpsertRequest struct {
+	Services []*ServiceRegistration
+	NodeID   string
+	SecretID string
+	WriteRequest
+}
 
-	// DesiredStatusDescription is meant to provide more human useful information
-	DesiredDescription string
+// ServiceRegistrationDeleteRequest is used by clients to delete the service
+// registrations of the tasks they run
+type ServiceRegistrationDeleteRequest struct {
+	IDs      []string
+	NodeID   string
+	SecretID string
+	WriteRequest
+}
 
-	// Status of the allocation on the client
-	ClientStatus string
+// ServiceRegistrationListRequest is used to list the registered services
+type ServiceRegistrationListRequest struct {
+	QueryOptions
+}
 
-	// ClientStatusDescription is meant to provide more human useful information
-	ClientDescription string
+// ServiceRegistrationListResponse is used to return the registered services
+type ServiceRegistrationListResponse struct {
+	Services []*ServiceRegistrationListStub
+	QueryMeta
+}
 
-	// TaskStates stores the state of each task,
-	TaskStates map[string]*TaskState
+// ServiceRegistrationByNameRequest is used to query the registrations of a
+// service
+type ServiceRegistrationByNameRequest struct {
+	ServiceName string
+	QueryOptions
+}
 
-	// PreviousAllocation is the allocation that this allocation is replacing
-	PreviousAllocation string
+// ServiceRegistrationByNameResponse is used to return the registrations of a
+// service
+type ServiceRegistrationByNameResponse struct {
+	Services []*ServiceRegistration
+	QueryMeta
+}
 
-	// Raft Indexes
-	CreateIndex uint64
-	ModifyIndex uint64
+const (
+	// RootKeyAlgorithm is the algorithm the root keys of the keyring encrypt
+	// variables with
+	RootKeyAlgorithm = "aes256-gcm"
 
-	// AllocModifyIndex is not updated when the client updates allocations. This
-	// lets the client pull only the allocs updated by the server.
-	AllocModifyIndex uint64
+	// VariablesJobPathPrefix is the path prefix of the variables tasks can
+	// read with their workload identity
+	VariablesJobPathPrefix = "nomad/jobs"
 
-	// CreateTime is the time the allocation has finished scheduling and been
-	// verified by the plan applier.
+	// maxVariablesPathLength is the maximum length of a variable path
+	maxVariablesPathLength = 128
+
+	// maxVariablesItemsSize is the maximum size of the items of a variable
+	maxVariablesItemsSize = 16 * 1024
+)
+
+var (
+	// validVariablePath matches the valid variable paths
+	validVariablePath = regexp.MustCompile("^[a-zA-Z0-9-_~/]+$")
+)
+
+// RootKey is a key of the servers' keyring. The key encrypts the variables
+// at rest and never leaves the servers. Its material is wrapped with the
+// keyring encryption key of the servers, which isn't replicated, so that the
+// Raft state and its snapshots don't hold the material in the clear.
+type RootKey struct {
+	// KeyID uniquely identifies the key and is stored alongside the
+	// variables it encrypted
+	KeyID string
+
+	// Algorithm is the encryption algorithm of the key
+	Algorithm string
+
+	// WrappedKey is the key material encrypted with the keyring encryption
+	// key of the servers
+	WrappedKey []byte
+
+	// CreateTime is the time the key was generated
 	CreateTime int64
+
+	// Raft Indexes
+	CreateIndex uint64
+	ModifyIndex uint64
 }
 
-func (a *Allocation) Copy() *Allocation {
-	if a == nil {
-		return nil
+// Stub returns the metadata of the key, leaving out the key material
+func (k *RootKey) Stub() *RootKeyStub {
+	return &RootKeyStub{
+		KeyID:       k.KeyID,
+		Algorithm:   k.Algorithm,
+		CreateTime:  k.CreateTime,
+		CreateIndex: k.CreateIndex,
+		ModifyIndex: k.ModifyIndex,
 	}
-	na := new(Allocation)
-	*na = *a
-
-	na.Job = na.Job.Copy()
-	na.Resources = na.Resources.Copy()
-	na.SharedResources = na.SharedResources.Copy()
+}
 
-	if a.TaskResources != nil {
-		tr := make(map[string]*Resources, len(na.TaskResources))
-		for task, resource := range na.TaskResources {
-			tr[task] = resource.Copy()
-		}
-		na.TaskResources = tr
-	}
+// RootKeyStub is the metadata of a RootKey
+type RootKeyStub struct {
+	KeyID       string
+	Algorithm   string
+	CreateTime  int64
+	CreateIndex uint64
+	ModifyIndex uint64
+}
 
-	na.Metrics = na.Metrics.Copy()
+// RootKeyUpsertRequest is used to upsert a set of keys of the keyring
+type RootKeyUpsertRequest struct {
+	Keys []*RootKey
+	WriteRequest
+}
 
-	if a.TaskStates != nil {
-		ts := make(map[string]*TaskState, len(na.TaskStates))
-		for task, state := range na.TaskStates {
-			ts[task] = state.Copy()
-		}
-		na.TaskStates = ts
-	}
-	return na
+// KeyringRotateRequest is used to rotate the keys of the keyring. A new root
+// key and a new workload identity key are generated and the existing
+// variables are re-encrypted with the new root key.
+type KeyringRotateRequest struct {
+	WriteRequest
 }
 
-// TerminalStatus returns if the desired or actual status is terminal and
-// will no longer transition.
-func (a *Allocation) TerminalStatus() bool {
-	// First check the desired state and if that isn't terminal, check client
-	// state.
-	switch a.DesiredStatus {
-	case AllocDesiredStatusStop, AllocDesiredStatusEvict:
-		return true
-	default:
-	}
+// KeyringRotation is the result of a rotation of the keyring
+type KeyringRotation struct {
+	// RootKey and WorkloadIdentityKey are the newly active keys
+	RootKey             *RootKeyStub
+	WorkloadIdentityKey *WorkloadIdentityPublicKey
 
-	switch a.ClientStatus {
-	case AllocClientStatusComplete, AllocClientStatusFailed, AllocClientStatusLost:
-		return true
-	default:
-		return false
-	}
+	// Rekeyed is the number of variables re-encrypted with the new root key
+	Rekeyed int
 }
 
-// Terminated returns if the allocation is in a terminal state on a client.
-func (a *Allocation) Terminated() bool {
-	if a.ClientStatus == AllocClientStatusFailed ||
-		a.ClientStatus == AllocClientStatusComplete ||
-		a.ClientStatus == AllocClientStatusLost {
-		return true
-	}
-	return false
+// KeyringRotateResponse is used to return the result of a rotation
+type KeyringRotateResponse struct {
+	KeyringRotation
+	WriteMeta
 }
 
-// RanSuccessfully returns whether the client has ran the allocation and all
-// tasks finished successfully
-func (a *Allocation) RanSuccessfully() bool {
-	// Handle the case the client hasn't started the allocation.
-	if len(a.TaskStates) == 0 {
-		return false
-	}
+// KeyringListRequest is used to list the keys of the keyring
+type KeyringListRequest struct {
+	QueryOptions
+}
 
-	// Check to see if all the tasks finised successfully in the allocation
-	allSuccess := true
-	for _, state := range a.TaskStates {
-		allSuccess = allSuccess && state.Successful()
-	}
+// KeyringKeys is the metadata of the keys of the keyring, along with the IDs
+// of the active keys
+type KeyringKeys struct {
+	RootKeys                    []*RootKeyStub
+	WorkloadIdentityKeys        []*WorkloadIdentityPublicKey
+	ActiveRootKeyID             string
+	ActiveWorkloadIdentityKeyID string
+}
 
-	return allSuccess
+// KeyringListResponse is used to return the keys of the keyring
+type KeyringListResponse struct {
+	KeyringKeys
+	QueryMeta
 }
 
-// Stub returns a list stub for the allocation
-func (a *Allocation) Stub() *AllocListStub {
-	return &AllocListStub{
-		ID:                 a.ID,
-		EvalID:             a.EvalID,
-		Name:               a.Name,
-		NodeID:             a.NodeID,
-		JobID:              a.JobID,
-		TaskGroup:          a.TaskGroup,
-		DesiredStatus:      a.DesiredStatus,
-		DesiredDescription: a.DesiredDescription,
-		ClientStatus:       a.ClientStatus,
-		ClientDescription:  a.ClientDescription,
-		TaskStates:         a.TaskStates,
-		CreateIndex:        a.CreateIndex,
-		ModifyIndex:        a.ModifyIndex,
-		CreateTime:         a.CreateTime,
-	}
+// GossipKeyringRequest is used to install, use or remove a key of the
+// keyring encrypting the gossip traffic between servers
+type GossipKeyringRequest struct {
+	// Key is the base64 encoded key
+	Key string
 }
 
-var (
-	// AllocationIndexRegex is a regular expression to find the allocation index.
-	AllocationIndexRegex = regexp.MustCompile(".+\\[(\\d+)\\]$")
-)
+// GossipKeyringResponse is the result of an operation on the keyring
+// encrypting the gossip traffic between servers
+type GossipKeyringResponse struct {
+	// Messages maps the names of the servers that failed the operation to
+	// their error
+	Messages map[string]string
 
-// Index returns the index of the allocation. If the allocation is from a task
-// group with count greater than 1, there will be multiple allocations for it.
-func (a *Allocation) Index() int {
-	matches := AllocationIndexRegex.FindStringSubmatch(a.Name)
-	if len(matches) != 2 {
-		return -1
-	}
+	// Keys maps the installed keys to the number of servers they are
+	// installed on
+	Keys map[string]int
 
-	index, err := strconv.Atoi(matches[1])
-	if err != nil {
-		return -1
-	}
+	// NumNodes is the number of servers in the gossip pool
+	NumNodes int
+}
 
-	return index
+// SnapshotSaveRequest is used to take a snapshot of the state of the cluster
+type SnapshotSaveRequest struct {
+	QueryOptions
 }
 
-// AllocListStub is used to return a subset of alloc information
-type AllocListStub struct {
-	ID                 string
-	EvalID             string
-	Name               string
-	NodeID             string
-	JobID              string
-	TaskGroup          string
-	DesiredStatus      string
-	DesiredDescription string
-	ClientStatus       string
-	ClientDescription  string
-	TaskStates         map[string]*TaskState
-	CreateIndex        uint64
-	ModifyIndex        uint64
-	CreateTime         int64
+// SnapshotSaveResponse is used to return a snapshot of the state of the
+// cluster. Snapshot is an archive written by the snapshot package.
+type SnapshotSaveResponse struct {
+	Snapshot []byte
+	QueryMeta
 }
 
-// AllocMetric is used to track various metrics while attempting
-// to make an allocation. These are used to debug a job, or to better
-// understand the pressure within the system.
-type AllocMetric struct {
-	// NodesEvaluated is the number of nodes that were evaluated
-	NodesEvaluated int
+// SnapshotRestoreRequest is used to replace the state of the cluster with the
+// one held by a snapshot archive
+type SnapshotRestoreRequest struct {
+	Snapshot []byte
+	WriteRequest
+}
 
-	// NodesFiltered is the number of nodes filtered due to a constraint
-	NodesFiltered int
+// RaftServer is a server of the Raft configuration of the region
+type RaftServer struct {
+	// ID is the ID of the server in the Raft configuration. Raft identifies
+	// servers by their address, so it is the same as Address.
+	ID string
 
-	// NodesAvailable is the number of nodes available for evaluation per DC.
-	NodesAvailable map[string]int
+	// Node is the name of the server, empty when no live member of the
+	// gossip pool advertises the address
+	Node string
 
-	// ClassFiltered is the number of nodes filtered by class
-	ClassFiltered map[string]int
+	// Address is the address Raft reaches the server at
+	Address string
+
+	// Leader is true when the server is the current Raft leader
+	Leader bool
 
-	// ConstraintFiltered is the number of failures caused by constraint
-	ConstraintFiltered map[string]int
+	// Voter is true when the server takes part in leader elections and
+	// commits. Every server of the configuration is a voter.
+	Voter bool
+}
 
-	// NodesExhausted is the number of nodes skipped due to being
-	// exhausted of at least one resource
-	NodesExhausted int
+// RaftConfigurationResponse is used to return the Raft configuration of the
+// region
+type RaftConfigurationResponse struct {
+	Servers []*RaftServer
+	QueryMeta
+}
 
-	// ClassExhausted is the number of nodes exhausted by class
-	ClassExhausted map[string]int
+// RaftPeerByAddressRequest is used to remove a peer from the Raft
+// configuration by its address
+type RaftPeerByAddressRequest struct {
+	Address string
+	WriteRequest
+}
 
-	// DimensionExhausted provides the count by dimension or reason
-	DimensionExhausted map[string]int
+// AutopilotConfig is the configuration of autopilot, which tracks the health
+// of the servers of the region and cleans up the dead ones
+type AutopilotConfig struct {
+	// CleanupDeadServers controls whether failed servers are removed from
+	// the Raft configuration when new servers join, as long as the removal
+	// doesn't drop the region below quorum
+	CleanupDeadServers bool
 
-	// Scores is the scores of the final few nodes remaining
-	// for placement. The top score is typically selected.
-	Scores map[string]float64
+	// LastContactThreshold is the longest a server may go without contact
+	// from the leader before being considered unhealthy
+	LastContactThreshold time.Duration
 
-	// AllocationTime is a measure of how long the allocation
-	// attempt took. This can affect performance and SLAs.
-	AllocationTime time.Duration
+	// MaxTrailingLogs is the number of Raft log entries a server may trail
+	// the leader by before being considered unhealthy
+	MaxTrailingLogs uint64
 
-	// CoalescedFailures indicates the number of other
-	// allocations that were coalesced into this failed allocation.
-	// This is to prevent creating many failed allocations for a
-	// single task group.
-	CoalescedFailures int
+	CreateIndex uint64
+	ModifyIndex uint64
 }
 
-func (a *AllocMetric) Copy() *AllocMetric {
-	if a == nil {
-		return nil
+// DefaultAutopilotConfig returns the configuration autopilot runs with until
+// an operator sets one
+func DefaultAutopilotConfig() *AutopilotConfig {
+	return &AutopilotConfig{
+		CleanupDeadServers:   true,
+		LastContactThreshold: 200 * time.Millisecond,
+		MaxTrailingLogs:      250,
 	}
-	na := new(AllocMetric)
-	*na = *a
-	na.NodesAvailable = CopyMapStringInt(na.NodesAvailable)
-	na.ClassFiltered = CopyMapStringInt(na.ClassFiltered)
-	na.ConstraintFiltered = CopyMapStringInt(na.ConstraintFiltered)
-	na.ClassExhausted = CopyMapStringInt(na.ClassExhausted)
-	na.DimensionExhausted = CopyMapStringInt(na.DimensionExhausted)
-	na.Scores = CopyMapStringFloat64(na.Scores)
-	return na
 }
 
-func (a *AllocMetric) EvaluateNode() {
-	a.NodesEvaluated += 1
+// Copy returns a copy of the configuration
+func (c *AutopilotConfig) Copy() *AutopilotConfig {
+	if c == nil {
+		return nil
+	}
+	nc := new(AutopilotConfig)
+	*nc = *c
+	return nc
 }
 
-func (a *AllocMetric) FilterNode(node *Node, constraint string) {
-	a.NodesFiltered += 1
-	if node != nil && node.NodeClass != "" {
-		if a.ClassFiltered == nil {
-			a.ClassFiltered = make(map[string]int)
-		}
-		a.ClassFiltered[node.NodeClass] += 1
+// Validate validates the autopilot configuration
+func (c *AutopilotConfig) Validate() error {
+	var mErr multierror.Error
+	if c.LastContactThreshold <= 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("LastContactThreshold must be positive"))
 	}
-	if constraint != "" {
-		if a.ConstraintFiltered == nil {
-			a.ConstraintFiltered = make(map[string]int)
-		}
-		a.ConstraintFiltered[constraint] += 1
+	if c.MaxTrailingLogs == 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("MaxTrailingLogs must be positive"))
 	}
+	return mErr.ErrorOrNil()
 }
 
-func (a *AllocMetric) ExhaustedNode(node *Node, dimension string) {
-	a.NodesExhausted += 1
-	if node != nil && node.NodeClass != "" {
-		if a.ClassExhausted == nil {
-			a.ClassExhausted = make(map[string]int)
-		}
-		a.ClassExhausted[node.NodeClass] += 1
-	}
-	if dimension != "" {
-		if a.DimensionExhausted == nil {
-			a.DimensionExhausted = make(map[string]int)
-		}
-		a.DimensionExhausted[dimension] += 1
-	}
+// AutopilotSetConfigRequest is used to set the configuration of autopilot
+type AutopilotSetConfigRequest struct {
+	Config AutopilotConfig
+
+	// CAS controls whether the configuration is only set if its current
+	// ModifyIndex matches the one of Config
+	CAS bool
+
+	WriteRequest
 }
 
-func (a *AllocMetric) ScoreNode(node *Node, name string, score float64) {
-	if a.Scores == nil {
-		a.Scores = make(map[string]float64)
-	}
-	key := fmt.Sprintf("%s.%s", node.ID, name)
-	a.Scores[key] = score
+// AutopilotConfigResponse is used to return the configuration of autopilot
+type AutopilotConfigResponse struct {
+	Config *AutopilotConfig
+	QueryMeta
 }
 
-const (
-	EvalStatusBlocked   = "blocked"
-	EvalStatusPending   = "pending"
-	EvalStatusComplete  = "complete"
-	EvalStatusFailed    = "failed"
-	EvalStatusCancelled = "canceled"
-)
+// AutopilotSetConfigResponse is used to respond to a request to set the
+// configuration of autopilot
+type AutopilotSetConfigResponse struct {
+	// Updated is false when a check-and-set request lost the race with
+	// another update
+	Updated bool
+	WriteMeta
+}
 
-const (
-	EvalTriggerJobRegister   = "job-register"
-	EvalTriggerJobDeregister = "job-deregister"
-	EvalTriggerPeriodicJob   = "periodic-job"
-	EvalTriggerNodeUpdate    = "node-update"
-	EvalTriggerScheduled     = "scheduled"
-	EvalTriggerRollingUpdate = "rolling-update"
-	EvalTriggerMaxPlans      = "max-plan-attempts"
-)
+// ServerStats are the Raft statistics a server reports to the leader for
+// its health to be tracked
+type ServerStats struct {
+	// LastContact is the time since the server last heard from the leader,
+	// or "never"
+	LastContact string
 
-const (
-	// CoreJobEvalGC is used for the garbage collection of evaluations
-	// and allocations. We periodically scan evaluations in a terminal state,
-	// in which all the corresponding allocations are also terminal. We
-	// delete these out of the system to bound the state.
-	CoreJobEvalGC = "eval-gc"
+	// LastTerm is the term of the last Raft log entry of the server
+	LastTerm uint64
 
-	// CoreJobNodeGC is used for the garbage collection of failed nodes.
-	// We periodically scan nodes in a terminal state, and if they have no
-	// corresponding allocations we delete these out of the system.
-	CoreJobNodeGC = "node-gc"
+	// LastIndex is the index of the last Raft log entry of the server
+	LastIndex uint64
+}
 
-	// CoreJobJobGC is used for the garbage collection of eligible jobs. We
-	// periodically scan garbage collectible jobs and check if both their
-	// evaluations and allocations are terminal. If so, we delete these out of
-	// the system.
-	CoreJobJobGC = "job-gc"
+// ServerHealth is the health of a server of the Raft configuration, as
+// tracked by autopilot on the leader
+type ServerHealth struct {
+	// ID is the ID of the server in the Raft configuration, its address
+	ID string
 
-	// CoreJobForceGC is used to force garbage collection of all GCable objects.
-	CoreJobForceGC = "force-gc"
-)
+	// Name is the name of the server in the gossip pool
+	Name string
 
-// Evaluation is used anytime we need to apply business logic as a result
-// of a change to our desired state (job specification) or the emergent state
-// (registered nodes). When the inputs change, we need to "evaluate" them,
-// potentially taking action (allocation of work) or doing nothing if the state
-// of the world does not require it.
-type Evaluation struct {
-	// ID is a randonly generated UUID used for this evaluation. This
-	// is assigned upon the creation of the evaluation.
-	ID string
+	// Address is the address Raft reaches the server at
+	Address string
 
-	// Priority is used to control scheduling importance and if this job
-	// can preempt other jobs.
-	Priority int
+	// SerfStatus is the status of the server in the gossip pool
+	SerfStatus string
 
-	// Type is used to control which schedulers are available to handle
-	// this evaluation.
-	Type string
+	// Version is the Nomad version of the server
+	Version string
 
-	// TriggeredBy is used to give some insight into why this Eval
-	// was created. (Job change, node failure, alloc failure, etc).
-	TriggeredBy string
+	// Leader is true when the server is the current Raft leader
+	Leader bool
 
-	// JobID is the job this evaluation is scoped to. Evaluations cannot
-	// be run in parallel for a given JobID, so we serialize on this.
-	JobID string
+	// LastContact is the time since the server last heard from the leader
+	LastContact time.Duration
 
-	// JobModifyIndex is the modify index of the job at the time
-	// the evaluation was created
-	JobModifyIndex uint64
+	// LastTerm and LastIndex are the term and index of the last Raft log
+	// entry of the server
+	LastTerm  uint64
+	LastIndex uint64
 
-	// NodeID is the node that was affected triggering the evaluation.
-	NodeID string
+	// Healthy is true when the server is alive, in contact with the leader
+	// and keeping up with its log according to the autopilot configuration
+	Healthy bool
 
-	// NodeModifyIndex is the modify index of the node at the time
-	// the evaluation was created
-	NodeModifyIndex uint64
+	// Voter is true when the server takes part in leader elections and
+	// commits. Every server of the configuration is a voter.
+	Voter bool
 
-	// Status of the evaluation
-	Status string
+	// StableSince is the time the server last changed its health
+	StableSince time.Time
+}
 
-	// StatusDescription is meant to provide more human useful information
-	StatusDescription string
+// OperatorHealthReply is the health of the servers of the region
+type OperatorHealthReply struct {
+	// Healthy is true when every server of the region is healthy
+	Healthy bool
 
-	// Wait is a minimum wait time for running the eval. This is used to
-	// support a rolling upgrade.
-	Wait time.Duration
+	// FailureTolerance is the number of servers that can fail without the
+	// region losing quorum
+	FailureTolerance int
 
-	// NextEval is the evaluation ID for the eval created to do a followup.
-	// This is used to support rolling upgrades, where we need a chain of evaluations.
-	NextEval string
+	// Servers is the health of each server of the Raft configuration
+	Servers []ServerHealth
 
-	// PreviousEval is the evaluation ID for the eval creating this one to do a followup.
-	// This is used to support rolling upgrades, where we need a chain of evaluations.
-	PreviousEval string
+	QueryMeta
+}
 
-	// BlockedEval is the evaluation ID for a created blocked eval. A
-	// blocked eval will be created if all allocations could not be placed due
-	// to constraints or lacking resources.
-	BlockedEval string
+const (
+	// SchedulerAlgorithmBinpack places allocations on the nodes they fill
+	// the most, to keep other nodes free for large allocations
+	SchedulerAlgorithmBinpack = "binpack"
 
-	// FailedTGAllocs are task groups which have allocations that could not be
-	// made, but the metrics are persisted so that the user can use the feedback
-	// to determine the cause.
-	FailedTGAllocs map[string]*AllocMetric
+	// SchedulerAlgorithmSpread places allocations on the nodes they fill the
+	// least, to spread the load across the cluster
+	SchedulerAlgorithmSpread = "spread"
+)
 
-	// ClassEligibility tracks computed node classes that have been explicitly
-	// marked as eligible or ineligible.
-	ClassEligibility map[string]bool
+const (
+	// NodeConstraintPolicyIgnore leaves running allocations in place when
+	// their node stops satisfying their constraints
+	NodeConstraintPolicyIgnore = "ignore"
+
+	// NodeConstraintPolicyMigrate reschedules running allocations whose node
+	// no longer satisfies their constraints, for instance after its meta or
+	// attributes changed
+	NodeConstraintPolicyMigrate = "migrate"
+)
 
-	// EscapedComputedClass marks whether the job has constraints that are not
-	// captured by computed node classes.
-	EscapedComputedClass bool
+// SchedulerConfiguration is the configuration of the schedulers that
+// operators can change at runtime
+type SchedulerConfiguration struct {
+	// SchedulerAlgorithm is how nodes are scored for placements, either
+	// binpack or spread
+	SchedulerAlgorithm string
 
-	// AnnotatePlan triggers the scheduler to provide additional annotations
-	// during the evaluation. This should not be set during normal operations.
-	AnnotatePlan bool
+	// PreemptionConfig controls which schedulers may evict lower priority
+	// allocations to make room for a placement
+	PreemptionConfig PreemptionConfig
 
-	// SnapshotIndex is the Raft index of the snapshot used to process the
-	// evaluation. As such it will only be set once it has gone through the
-	// scheduler.
-	SnapshotIndex uint64
+	// SchedulingPaused stops the workers from processing evaluations of
+	// jobs, which queue until scheduling resumes. Evaluations of the core
+	// scheduler, such as garbage collections, are still processed.
+	SchedulingPaused bool
 
-	// QueuedAllocations is the number of unplaced allocations at the time the
-	// evaluation was processed. The map is keyed by Task Group names.
-	QueuedAllocations map[string]int
+	// NodeConstraintPolicy is what happens to running allocations when their
+	// node no longer satisfies their constraints, either ignore or migrate.
+	// An empty policy is treated as ignore.
+	NodeConstraintPolicy string
+
+	// NodeReliability configures the penalty of the nodes failures are seen
+	// on
+	NodeReliability NodeReliabilityConfig
 
-	// Raft Indexes
 	CreateIndex uint64
 	ModifyIndex uint64
 }
 
-// TerminalStatus returns if the current status is terminal and
-// will no longer transition.
-func (e *Evaluation) TerminalStatus() bool {
-	switch e.Status {
-	case EvalStatusComplete, EvalStatusFailed, EvalStatusCancelled:
-		return true
-	default:
-		return false
-	}
+// PreemptionConfig controls preemption for each type of scheduler
+type PreemptionConfig struct {
+	SystemSchedulerEnabled  bool
+	ServiceSchedulerEnabled bool
+	BatchSchedulerEnabled   bool
 }
 
-func (e *Evaluation) GoString() string {
-	return fmt.Sprintf("<Eval '%s' JobID: '%s'>", e.ID, e.JobID)
-}
+// NodeReliabilityConfig configures the reliability penalty of the nodes.
+// Every failure seen on a node adds its weight to the penalty of the node,
+// which decays over time. The ranking of nodes subtracts the penalty from
+// their score.
+type NodeReliabilityConfig struct {
+	// Enabled enables the tracking of the failures and their penalty
+	Enabled bool
 
-func (e *Evaluation) Copy() *Evaluation {
-	if e == nil {
-		return nil
-	}
-	ne := new(Evaluation)
-	*ne = *e
+	// HalfLife is how long it takes for a penalty to halve
+	HalfLife time.Duration
 
-	// Copy ClassEligibility
-	if e.ClassEligibility != nil {
-		classes := make(map[string]bool, len(e.ClassEligibility))
-		for class, elig := range e.ClassEligibility {
-			classes[class] = elig
-		}
-		ne.ClassEligibility = classes
-	}
+	// FailedAllocWeight is the penalty of an allocation failing on a node
+	FailedAllocWeight float64
 
-	// Copy FailedTGAllocs
-	if e.FailedTGAllocs != nil {
-		failedTGs := make(map[string]*AllocMetric, len(e.FailedTGAllocs))
-		for tg, metric := range e.FailedTGAllocs {
-			failedTGs[tg] = metric.Copy()
-		}
-		ne.FailedTGAllocs = failedTGs
-	}
+	// PlanRejectionWeight is the penalty of a plan being rejected for a node
+	PlanRejectionWeight float64
 
-	// Copy queued allocations
-	if e.QueuedAllocations != nil {
-		queuedAllocations := make(map[string]int, len(e.QueuedAllocations))
-		for tg, num := range e.QueuedAllocations {
-			queuedAllocations[tg] = num
-		}
-		ne.QueuedAllocations = queuedAllocations
-	}
+	// MissedHeartbeatWeight is the penalty of a node missing its heartbeats
+	MissedHeartbeatWeight float64
 
-	return ne
+	// MaxPenalty caps the penalty subtracted from the score of a node
+	MaxPenalty float64
 }
 
-// ShouldEnqueue checks if a given evaluation should be enqueued into the
-// eval_broker
-func (e *Evaluation) ShouldEnqueue() bool {
-	switch e.Status {
-	case EvalStatusPending:
-		return true
-	case EvalStatusComplete, EvalStatusFailed, EvalStatusBlocked, EvalStatusCancelled:
-		return false
-	default:
-		panic(fmt.Sprintf("unhandled evaluation (%s) status %s", e.ID, e.Status))
+// Validate validates the node reliability configuration
+func (c *NodeReliabilityConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.HalfLife <= 0 {
+		return fmt.Errorf("node reliability half-life must be positive")
+	}
+	if c.FailedAllocWeight < 0 || c.PlanRejectionWeight < 0 || c.MissedHeartbeatWeight < 0 || c.MaxPenalty < 0 {
+		return fmt.Errorf("node reliability weights and max penalty must not be negative")
 	}
+	return nil
 }
 
-// ShouldBlock checks if a given evaluation should be entered into the blocked
-// eval tracker.
-func (e *Evaluation) ShouldBlock() bool {
-	switch e.Status {
-	case EvalStatusBlocked:
-		return true
-	case EvalStatusComplete, EvalStatusFailed, EvalStatusPending, EvalStatusCancelled:
-		return false
-	default:
-		panic(fmt.Sprintf("unhandled evaluation (%s) status %s", e.ID, e.Status))
+// Penalty returns the penalty subtracted from the score of a node at the
+// given time
+func (c *NodeReliabilityConfig) Penalty(node *Node, now time.Time) float64 {
+	if !c.Enabled {
+		return 0
+	}
+	penalty := node.Reliability.Decayed(now, c.HalfLife)
+	if c.MaxPenalty > 0 && penalty > c.MaxPenalty {
+		penalty = c.MaxPenalty
 	}
+	return penalty
 }
 
-// MakePlan is used to make a plan from the given evaluation
-// for a given Job
-func (e *Evaluation) MakePlan(j *Job) *Plan {
-	p := &Plan{
-		EvalID:         e.ID,
-		Priority:       e.Priority,
-		Job:            j,
-		NodeUpdate:     make(map[string][]*Allocation),
-		NodeAllocation: make(map[string][]*Allocation),
+// DefaultSchedulerConfiguration returns the configuration the schedulers run
+// with until an operator sets one
+func DefaultSchedulerConfiguration() *SchedulerConfiguration {
+	return &SchedulerConfiguration{
+		SchedulerAlgorithm: SchedulerAlgorithmBinpack,
+		PreemptionConfig: PreemptionConfig{
+			SystemSchedulerEnabled:  true,
+			ServiceSchedulerEnabled: true,
+		},
+		NodeConstraintPolicy: NodeConstraintPolicyIgnore,
+		NodeReliability: NodeReliabilityConfig{
+			HalfLife:              1 * time.Hour,
+			FailedAllocWeight:     5,
+			PlanRejectionWeight:   2,
+			MissedHeartbeatWeight: 10,
+			MaxPenalty:            50,
+		},
 	}
-	if j != nil {
-		p.AllAtOnce = j.AllAtOnce
+}
+
+// Copy returns a copy of the configuration
+func (c *SchedulerConfiguration) Copy() *SchedulerConfiguration {
+	if c == nil {
+		return nil
 	}
-	return p
+	nc := new(SchedulerConfiguration)
+	*nc = *c
+	return nc
 }
 
-// NextRollingEval creates an evaluation to followup this eval for rolling updates
-func (e *Evaluation) NextRollingEval(wait time.Duration) *Evaluation {
-	return &Evaluation{
-		ID:             GenerateUUID(),
-		Priority:       e.Priority,
-		Type:           e.Type,
-		TriggeredBy:    EvalTriggerRollingUpdate,
-		JobID:          e.JobID,
-		JobModifyIndex: e.JobModifyIndex,
-		Status:         EvalStatusPending,
-		Wait:           wait,
-		PreviousEval:   e.ID,
+// PreemptionEnabled returns whether the scheduler of the given type may
+// preempt allocations
+func (c *SchedulerConfiguration) PreemptionEnabled(schedulerType string) bool {
+	switch schedulerType {
+	case JobTypeSystem:
+		return c.PreemptionConfig.SystemSchedulerEnabled
+	case JobTypeService:
+		return c.PreemptionConfig.ServiceSchedulerEnabled
+	case JobTypeBatch:
+		return c.PreemptionConfig.BatchSchedulerEnabled
+	default:
+		return false
 	}
 }
 
-// CreateBlockedEval creates a blocked evaluation to followup this eval to place any
-// failed allocations. It takes the classes marked explicitly eligible or
-// ineligible and whether the job has escaped computed node classes.
-func (e *Evaluation) CreateBlockedEval(classEligibility map[string]bool, escaped bool) *Evaluation {
-	return &Evaluation{
-		ID:                   GenerateUUID(),
-		Priority:             e.Priority,
-		Type:                 e.Type,
-		TriggeredBy:          e.TriggeredBy,
-		JobID:                e.JobID,
-		JobModifyIndex:       e.JobModifyIndex,
-		Status:               EvalStatusBlocked,
-		PreviousEval:         e.ID,
-		ClassEligibility:     classEligibility,
-		EscapedComputedClass: escaped,
+// Validate validates the scheduler configuration
+func (c *SchedulerConfiguration) Validate() error {
+	switch c.SchedulerAlgorithm {
+	case SchedulerAlgorithmBinpack, SchedulerAlgorithmSpread:
+	default:
+		return fmt.Errorf("invalid scheduler algorithm %q: must be %q or %q",
+			c.SchedulerAlgorithm, SchedulerAlgorithmBinpack, SchedulerAlgorithmSpread)
+	}
+
+	switch c.NodeConstraintPolicy {
+	case "", NodeConstraintPolicyIgnore, NodeConstraintPolicyMigrate:
+	default:
+		return fmt.Errorf("invalid node constraint policy %q: must be %q or %q",
+			c.NodeConstraintPolicy, NodeConstraintPolicyIgnore, NodeConstraintPolicyMigrate)
 	}
+
+	return c.NodeReliability.Validate()
 }
 
-// Plan is used to submit a commit plan for task allocations. These
-// are submitted to the leader which verifies that resources have
-// not been overcommitted before admiting the plan.
-type Plan struct {
-	// EvalID is the evaluation ID this plan is associated with
-	EvalID string
+// MigrateOnNodeConstraints returns whether allocations whose node no longer
+// satisfies their constraints should be migrated
+func (c *SchedulerConfiguration) MigrateOnNodeConstraints() bool {
+	return c != nil && c.NodeConstraintPolicy == NodeConstraintPolicyMigrate
+}
 
-	// EvalToken is used to prevent a split-brain processing of
-	// an evaluation. There should only be a single scheduler running
-	// an Eval at a time, but this could be violated after a leadership
-	// transition. This unique token is used to reject plans that are
-	// being submitted from a different leader.
-	EvalToken string
+// SchedulerSetConfigRequest is used to set the configuration of the
+// schedulers
+type SchedulerSetConfigRequest struct {
+	Config SchedulerConfiguration
 
-	// Priority is the priority of the upstream job
-	Priority int
+	// CAS controls whether the configuration is only set if its current
+	// ModifyIndex matches the one of Config
+	CAS bool
 
-	// AllAtOnce is used to control if incremental scheduling of task groups
-	// is allowed or if we must do a gang scheduling of the entire job.
-	// If this is false, a plan may be partially applied. Otherwise, the
-	// entire plan must be able to make progress.
-	AllAtOnce bool
+	WriteRequest
+}
 
-	// Job is the parent job of all the allocations in the Plan.
-	// Since a Plan only involves a single Job, we can reduce the size
-	// of the plan by only including it once.
-	Job *Job
+// SchedulerConfigurationResponse is used to return the configuration of the
+// schedulers
+type SchedulerConfigurationResponse struct {
+	SchedulerConfig *SchedulerConfiguration
+	QueryMeta
+}
 
-	// NodeUpdate contains all the allocations for each node. For each node,
-	// this is a list of the allocations to update to either stop or evict.
-	NodeUpdate map[string][]*Allocation
+// SchedulerSetConfigurationResponse is used to respond to a request to set
+// the configuration of the schedulers
+type SchedulerSetConfigurationResponse struct {
+	// Updated is false when a check-and-set request lost the race with
+	// another update
+	Updated bool
+	WriteMeta
+}
 
-	// NodeAllocation contains all the allocations for each node.
-	// The evicts must be considered prior to the allocations.
-	NodeAllocation map[string][]*Allocation
+// VariableMetadata is the unencrypted metadata of a variable
+type VariableMetadata struct {
+	Namespace  string
+	Path       string
+	CreateTime int64
+	ModifyTime int64
 
-	// Annotations contains annotations by the scheduler to be used by operators
-	// to understand the decisions made by the scheduler.
-	Annotations *PlanAnnotations
+	// Raft Indexes
+	CreateIndex uint64
+	ModifyIndex uint64
 }
 
-// AppendUpdate marks the allocation for eviction. The clientStatus of the
-// allocation may be optionally set by passing in a non-empty value.
-func (p *Plan) AppendUpdate(alloc *Allocation, desiredStatus, desiredDesc, clientStatus string) {
-	newAlloc := new(Allocation)
-	*newAlloc = *alloc
+// VariableItems are the key/value pairs of a variable
+type VariableItems map[string]string
 
-	// If the job is not set in the plan we are deregistering a job so we
-	// extract the job from the allocation.
-	if p.Job == nil && newAlloc.Job != nil {
-		p.Job = newAlloc.Job
+// Size returns the size of the keys and values of the items
+func (v VariableItems) Size() int {
+	size := 0
+	for k, val := range v {
+		size += len(k) + len(val)
 	}
+	return size
+}
 
-	// Normalize the job
-	newAlloc.Job = nil
+// VariableEncrypted is a variable as stored in the state store. Its items
+// are encrypted with the root key it references.
+type VariableEncrypted struct {
+	VariableMetadata
 
-	// Strip the resources as it can be rebuilt.
-	newAlloc.Resources = nil
+	// KeyID is the ID of the root key the data is encrypted with
+	KeyID string
 
-	newAlloc.DesiredStatus = desiredStatus
-	newAlloc.DesiredDescription = desiredDesc
+	// Data is the encrypted items of the variable
+	Data []byte
+}
 
-	if clientStatus != "" {
-		newAlloc.ClientStatus = clientStatus
+// Copy returns a copy of the encrypted variable
+func (v *VariableEncrypted) Copy() *VariableEncrypted {
+	if v == nil {
+		return nil
 	}
+	nv := new(VariableEncrypted)
+	*nv = *v
+	nv.Data = append([]byte(nil), v.Data...)
+	return nv
+}
 
-	node := alloc.NodeID
-	existing := p.NodeUpdate[node]
-	p.NodeUpdate[node] = append(existing, newAlloc)
+// VariableDecrypted is a variable with its items in plain text. It is only
+// used outside of Raft and the state store.
+type VariableDecrypted struct {
+	VariableMetadata
+	Items VariableItems
 }
 
-func (p *Plan) PopUpdate(alloc *Allocation) {
-	existing := p.NodeUpdate[alloc.NodeID]
-	n := len(existing)
-	if n > 0 && existing[n-1].ID == alloc.ID {
-		existing = existing[:n-1]
-		if len(existing) > 0 {
-			p.NodeUpdate[alloc.NodeID] = existing
-		} else {
-			delete(p.NodeUpdate, alloc.NodeID)
+// Copy returns a copy of the decrypted variable
+func (v *VariableDecrypted) Copy() *VariableDecrypted {
+	if v == nil {
+		return nil
+	}
+	nv := new(VariableDecrypted)
+	*nv = *v
+	nv.Items = VariableItems(CopyMapStringString(v.Items))
+	return nv
+}
+
+// Validate validates the path and items of the variable
+func (v *VariableDecrypted) Validate() error {
+	var mErr multierror.Error
+	if err := ValidateVariablePath(v.Path); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+	if len(v.Items) == 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Variable must have at least one item"))
+	}
+	for k := range v.Items {
+		if k == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Variable items must have a key"))
+			break
 		}
 	}
+	if size := v.Items.Size(); size > maxVariablesItemsSize {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Variable items are %d bytes, exceeding the maximum of %d bytes", size, maxVariablesItemsSize))
+	}
+	return mErr.ErrorOrNil()
 }
 
-func (p *Plan) AppendAlloc(alloc *Allocation) {
-	node := alloc.NodeID
-	existing := p.NodeAllocation[node]
-	p.NodeAllocation[node] = append(existing, alloc)
+// ValidateVariablePath validates a variable path. Paths below "nomad/" are
+// reserved, except for the paths of jobs.
+func ValidateVariablePath(path string) error {
+	switch {
+	case path == "":
+		return fmt.Errorf("Missing variable path")
+	case len(path) > maxVariablesPathLength:
+		return fmt.Errorf("Variable path %q exceeds the maximum length of %d", path, maxVariablesPathLength)
+	case !validVariablePath.MatchString(path):
+		return fmt.Errorf("Invalid variable path %q, must only contain alphanumeric, '-', '_', '~' and '/' characters", path)
+	case strings.HasPrefix(path, "/") || strings.HasSuffix(path, "/") || strings.Contains(path, "//"):
+		return fmt.Errorf("Invalid variable path %q, must not have empty segments", path)
+	case path == "nomad" || (strings.HasPrefix(path, "nomad/") &&
+		path != VariablesJobPathPrefix && !strings.HasPrefix(path, VariablesJobPathPrefix+"/")):
+		return fmt.Errorf("Variable path %q is reserved", path)
+	}
+	return nil
 }
 
-// IsNoOp checks if this plan would do nothing
-func (p *Plan) IsNoOp() bool {
-	return len(p.NodeUpdate) == 0 && len(p.NodeAllocation) == 0
+// WorkloadVariablePaths returns the variable paths the workload identity of
+// a task grants read access to
+func WorkloadVariablePaths(claims *WorkloadIdentityClaims) []string {
+	job := VariablesJobPathPrefix + "/" + claims.JobID
+	group := job + "/" + claims.TaskGroup
+	return []string{
+		VariablesJobPathPrefix,
+		job,
+		group,
+		group + "/" + claims.Task,
+	}
 }
 
-// PlanResult is the result of a plan submitted to the leader.
-type PlanResult struct {
-	// NodeUpdate contains all the updates that were committed.
-	NodeUpdate map[string][]*Allocation
+// VariablesUpsertRequest is used to create or update a variable. If
+// CheckIndex is set, the write only succeeds if the variable's ModifyIndex
+// matches it, where zero means the variable must not exist.
+type VariablesUpsertRequest struct {
+	Variable   *VariableDecrypted
+	CheckIndex *uint64
+	WriteRequest
+}
 
-	// NodeAllocation contains all the allocations that were committed.
-	NodeAllocation map[string][]*Allocation
+// VariablesEncryptedUpsertRequest is the Raft request upserting an
+// encrypted variable
+type VariablesEncryptedUpsertRequest struct {
+	Variable   *VariableEncrypted
+	CheckIndex *uint64
+	WriteRequest
+}
 
-	// RefreshIndex is the index the worker should refresh state up to.
-	// This allows all evictions and allocations to be materialized.
-	// If any allocations were rejected due to stale data (node state,
-	// over committed) this can be used to force a worker refresh.
-	RefreshIndex uint64
+// VariablesRekeyRequest is the Raft request replacing the encrypted data of
+// variables after they were re-encrypted with a new root key. A variable is
+// only updated if its ModifyIndex still matches, so that concurrent writes
+// aren't overwritten.
+type VariablesRekeyRequest struct {
+	Variables []*VariableEncrypted
+	WriteRequest
+}
 
-	// AllocIndex is the Raft index in which the evictions and
-	// allocations took place. This is used for the write index.
-	AllocIndex uint64
+// VariablesDeleteRequest is used to delete a variable, with the same
+// check-and-set semantics as VariablesUpsertRequest
+type VariablesDeleteRequest struct {
+	Path       string
+	CheckIndex *uint64
+	WriteRequest
 }
 
-// IsNoOp checks if this plan result would do nothing
-func (p *PlanResult) IsNoOp() bool {
-	return len(p.NodeUpdate) == 0 && len(p.NodeAllocation) == 0
+// VariablesUpsertResponse is used to return the upserted variable
+type VariablesUpsertResponse struct {
+	Variable *VariableMetadata
+	WriteMeta
 }
 
-// FullCommit is used to check if all the allocations in a plan
-// were committed as part of the result. Returns if there was
-// a match, and the number of expected and actual allocations.
-func (p *PlanResult) FullCommit(plan *Plan) (bool, int, int) {
-	expected := 0
-	actual := 0
-	for name, allocList := range plan.NodeAllocation {
-		didAlloc, _ := p.NodeAllocation[name]
-		expected += len(allocList)
-		actual += len(didAlloc)
-	}
-	return actual == expected, expected, actual
+// VariablesReadRequest is used to read a variable
+type VariablesReadRequest struct {
+	Path string
+	QueryOptions
 }
 
-// PlanAnnotations holds annotations made by the scheduler to give further debug
-// information to operators.
-type PlanAnnotations struct {
-	// DesiredTGUpdates is the set of desired updates per task group.
-	DesiredTGUpdates map[string]*DesiredUpdates
+// VariablesReadResponse is used to return a decrypted variable
+type VariablesReadResponse struct {
+	Variable *VariableDecrypted
+	QueryMeta
 }
 
-// DesiredUpdates is the set of changes the scheduler would like to make given
-// sufficient resources and cluster capacity.
-type DesiredUpdates struct {
-	Ignore            uint64
-	Place             uint64
-	Migrate           uint64
-	Stop              uint64
-	InPlaceUpdate     uint64
-	DestructiveUpdate uint64
+// VariablesListRequest is used to list the variables whose path has the
+// prefix of the query options
+type VariablesListRequest struct {
+	QueryOptions
+}
+
+// VariablesListResponse is used to return the metadata of variables
+type VariablesListResponse struct {
+	Variables []*VariableMetadata
+	QueryMeta
+}
+
+// Topic is the type of object events are about
+type Topic string
+
+const (
+	TopicJob        Topic = "Job"
+	TopicAllocation Topic = "Allocation"
+	TopicNode       Topic = "Node"
+	TopicEvaluation Topic = "Evaluation"
+
+	// TopicAll subscribes to every topic
+	TopicAll Topic = "*"
+)
+
+const (
+	EventTypeJobRegistered     = "JobRegistered"
+	EventTypeJobDeregistered   = "JobDeregistered"
+	EventTypeAllocationUpdated = "AllocationUpdated"
+	EventTypeNodeDrain         = "NodeDrain"
+	EventTypeEvaluationUpdated = "EvaluationUpdated"
+)
+
+// Event is a change to an object of the state, published as the Raft log
+// entry making the change is applied
+type Event struct {
+	// Topic is the type of the object changed and Type the change
+	Topic Topic
+	Type  string
+
+	// Key is the ID of the object changed, and Namespace its namespace if
+	// the type of object is namespaced
+	Key       string
+	Namespace string
+
+	// Index is the Raft index of the change
+	Index uint64
+
+	// Payload is the object as changed
+	Payload *EventPayload
+}
+
+// EventPayload holds the object an event is about. Only the field of the
+// topic of the event is set.
+type EventPayload struct {
+	Job        *Job           `json:",omitempty"`
+	Allocation *AllocListStub `json:",omitempty"`
+	Node       *NodeListStub  `json:",omitempty"`
+	Evaluation *Evaluation    `json:",omitempty"`
+}
+
+// Events are the events published by applying a Raft log entry
+type Events struct {
+	Index  uint64
+	Events []*Event
+}
+
+// EventListRequest is used to list the events after the MinQueryIndex. The
+// topics map the topics to subscribe to to the keys of the events to return,
+// where "*" matches any topic or key. Without topics, every event is
+// returned.
+type EventListRequest struct {
+	Topics map[Topic][]string
+	QueryOptions
+}
+
+// Matches returns whether the event matches the topics of the request
+func (r *EventListRequest) Matches(event *Event) bool {
+	if len(r.Topics) == 0 {
+		return true
+	}
+	for _, topic := range []Topic{event.Topic, TopicAll} {
+		for _, key := range r.Topics[topic] {
+			if key == string(TopicAll) || key == event.Key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EventListResponse is used to return the events after the MinQueryIndex,
+// grouped by the Raft index they were published at
+type EventListResponse struct {
+	Events []*Events
+	QueryMeta
 }
 
 // msgpackHandle is a shared handle for encoding/decoding of structs
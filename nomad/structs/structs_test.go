@@ -1,6 +1,7 @@
 package structs
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -222,6 +223,97 @@ func TestJob_SystemJob_Validate(t *testing.T) {
 	}
 }
 
+func TestJob_Validate_DatacenterPattern(t *testing.T) {
+	j := testJob()
+	j.Canonicalize()
+	j.Datacenters = []string{"us-east-*", "*"}
+	if err := j.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	j.Datacenters = []string{"us-east-[1"}
+	err := j.Validate()
+	if err == nil || !strings.Contains(err.Error(), "datacenter pattern") {
+		t.Fatalf("expected invalid pattern error: %v", err)
+	}
+}
+
+func TestJob_Validate_Regions(t *testing.T) {
+	j := testJob()
+	j.Canonicalize()
+	j.Regions = []string{"global", "eu"}
+	if err := j.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	j.Regions = []string{"eu", "", "eu"}
+	err := j.Validate()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	for _, expected := range []string{"region 2 is empty", "listed more than once", "must be one of the job's regions"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q: %v", expected, err)
+		}
+	}
+}
+
+func TestJob_Validate_Multiregion(t *testing.T) {
+	j := testJob()
+	j.Periodic = nil
+	j.Regions = []string{"global", "eu"}
+	j.Multiregion = &MultiregionStrategy{}
+	j.Canonicalize()
+	if err := j.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if j.Multiregion.MaxParallel != 1 || j.Multiregion.OnFailure != MultiregionOnFailureFailAll {
+		t.Fatalf("bad: %#v", j.Multiregion)
+	}
+	if j.MultiregionDeferred("global") || !j.MultiregionDeferred("eu") {
+		t.Fatalf("expected only the second region to be deferred")
+	}
+
+	j.Regions = nil
+	j.Multiregion = &MultiregionStrategy{MaxParallel: -1, OnFailure: "foo"}
+	err := j.Validate()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	for _, expected := range []string{"requires the job's regions", "less than zero", "Unknown on_failure"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q: %v", expected, err)
+		}
+	}
+}
+
+func TestJob_Validate_Parameterized(t *testing.T) {
+	j := testJob()
+	j.Canonicalize()
+	j.Type = JobTypeBatch
+	j.ParameterizedJob = &ParameterizedJobConfig{}
+	j.ParameterizedJob.Canonicalize()
+	j.TaskGroups[0].Tasks[0].DispatchPayload = &DispatchPayloadConfig{File: "input.txt"}
+	if err := j.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Only batch jobs may be parameterized
+	j.Type = JobTypeService
+	err := j.Validate()
+	if err == nil || !strings.Contains(err.Error(), "Parameterized job") {
+		t.Fatalf("expected scheduler error: %v", err)
+	}
+
+	// Dispatch payloads require a parameterized job
+	j.Type = JobTypeBatch
+	j.ParameterizedJob = nil
+	err = j.Validate()
+	if err == nil || !strings.Contains(err.Error(), "dispatch payload") {
+		t.Fatalf("expected dispatch payload error: %v", err)
+	}
+}
+
 func TestJob_VaultPolicies(t *testing.T) {
 	j0 := &Job{}
 	e0 := make(map[string]map[string]*Vault, 0)
@@ -359,6 +451,206 @@ func TestTaskGroup_Validate(t *testing.T) {
 	}
 }
 
+func TestTaskGroup_Validate_Scaling(t *testing.T) {
+	tg := &TaskGroup{
+		Name:          "web",
+		Count:         2,
+		RestartPolicy: NewRestartPolicy(JobTypeService),
+		EphemeralDisk: DefaultEphemeralDisk(),
+		Tasks: []*Task{
+			{
+				Name:      "web",
+				Driver:    "exec",
+				Resources: DefaultResources(),
+				LogConfig: DefaultLogConfig(),
+			},
+		},
+		Scaling: &ScalingPolicy{
+			Min:     1,
+			Max:     5,
+			Enabled: true,
+			Policy: map[string]interface{}{
+				"cooldown": "1m",
+			},
+		},
+	}
+	if err := tg.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The policy is deep copied
+	copied := tg.Copy()
+	copied.Scaling.Policy["cooldown"] = "2m"
+	if tg.Scaling.Policy["cooldown"] != "1m" {
+		t.Fatalf("policy not copied: %#v", tg.Scaling)
+	}
+
+	// The count must be within the bounds of the policy
+	invalid := tg.Copy()
+	invalid.Count = 6
+	err := invalid.Validate()
+	if err == nil || !strings.Contains(err.Error(), "outside of the scaling policy bounds") {
+		t.Fatalf("expected bounds error: %v", err)
+	}
+
+	// The bounds must be valid
+	invalid = tg.Copy()
+	invalid.Scaling.Min = -1
+	invalid.Scaling.Max = -2
+	err = invalid.Validate()
+	if err == nil || !strings.Contains(err.Error(), "Minimum count can't be negative") {
+		t.Fatalf("expected minimum error: %v", err)
+	}
+	if !strings.Contains(err.Error(), "Maximum count -2 must be greater") {
+		t.Fatalf("expected maximum error: %v", err)
+	}
+}
+
+func TestTaskGroup_Validate_Migrate(t *testing.T) {
+	tg := &TaskGroup{
+		Name:          "web",
+		Count:         2,
+		RestartPolicy: NewRestartPolicy(JobTypeService),
+		EphemeralDisk: DefaultEphemeralDisk(),
+		Tasks: []*Task{
+			{
+				Name:      "web",
+				Driver:    "exec",
+				Resources: DefaultResources(),
+				LogConfig: DefaultLogConfig(),
+			},
+		},
+		Migrate: &MigrateStrategy{},
+	}
+
+	// The max parallel defaults to one
+	tg.Canonicalize(&Job{Type: JobTypeService})
+	if tg.Migrate.MaxParallel != 1 {
+		t.Fatalf("bad: %#v", tg.Migrate)
+	}
+	if err := tg.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	invalid := tg.Copy()
+	invalid.Migrate.MaxParallel = -1
+	invalid.Migrate.MinHealthyTime = -time.Second
+	err := invalid.Validate()
+	if err == nil || !strings.Contains(err.Error(), "Max parallel can not be less than zero") {
+		t.Fatalf("expected max parallel error: %v", err)
+	}
+	if !strings.Contains(err.Error(), "Minimum healthy time can not be negative") {
+		t.Fatalf("expected minimum healthy time error: %v", err)
+	}
+	if tg.Migrate.MaxParallel != 1 {
+		t.Fatalf("migrate strategy not copied: %#v", tg.Migrate)
+	}
+}
+
+func TestNewDrainStrategy(t *testing.T) {
+	now := time.Now()
+
+	// A positive deadline is counted from the start of the drain
+	d := NewDrainStrategy(DrainSpec{Deadline: time.Hour}, now)
+	if infinite, deadline := d.DeadlineTime(); infinite || !deadline.Equal(now.Add(time.Hour)) {
+		t.Fatalf("bad: %v %v", infinite, deadline)
+	}
+
+	// A zero deadline never forces the allocations off of the node
+	d = NewDrainStrategy(DrainSpec{}, now)
+	if infinite, _ := d.DeadlineTime(); !infinite {
+		t.Fatalf("bad: %#v", d)
+	}
+
+	// A negative deadline forces them right away
+	d = NewDrainStrategy(DrainSpec{Deadline: -1}, now)
+	if infinite, deadline := d.DeadlineTime(); infinite || !deadline.Equal(now) {
+		t.Fatalf("bad: %v %v", infinite, deadline)
+	}
+	if !d.StartedAt.Equal(now) {
+		t.Fatalf("bad: %#v", d)
+	}
+}
+
+func TestTaskGroup_Validate_Networks(t *testing.T) {
+	tg := &TaskGroup{
+		Name:          "web",
+		Count:         1,
+		RestartPolicy: NewRestartPolicy(JobTypeService),
+		EphemeralDisk: DefaultEphemeralDisk(),
+		Networks: []*NetworkResource{
+			{
+				Mode:          NetworkModeBridge,
+				MBits:         10,
+				ReservedPorts: []Port{{"admin", 9000, 0, ""}},
+				DynamicPorts:  []Port{{"http", 0, 8080, ""}},
+			},
+		},
+		Tasks: []*Task{
+			{
+				Name:      "web",
+				Driver:    "exec",
+				Resources: DefaultResources(),
+				LogConfig: DefaultLogConfig(),
+			},
+		},
+	}
+	if err := tg.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Only one network may be requested and its mode must be valid
+	invalid := tg.Copy()
+	invalid.Networks[0].Mode = "cni/"
+	invalid.Networks = append(invalid.Networks, &NetworkResource{MBits: 10})
+	err := invalid.Validate()
+	if err == nil || !strings.Contains(err.Error(), "Only one network") {
+		t.Fatalf("expected multiple networks error: %v", err)
+	}
+	if !strings.Contains(err.Error(), `invalid network mode "cni/"`) {
+		t.Fatalf("expected network mode error: %v", err)
+	}
+
+	// Tasks can't request networks of their own in an isolated network
+	invalid = tg.Copy()
+	invalid.Tasks[0].Resources.Networks = []*NetworkResource{
+		{
+			MBits:        10,
+			DynamicPorts: []Port{{"http", 0, 0, ""}},
+		},
+	}
+	err = invalid.Validate()
+	if err == nil || !strings.Contains(err.Error(), `uses "bridge" networking`) {
+		t.Fatalf("expected task network error: %v", err)
+	}
+	if !strings.Contains(err.Error(), `port label "http" collides`) {
+		t.Fatalf("expected port label collision: %v", err)
+	}
+
+	// Tasks can request networks next to a group network using the host
+	valid := tg.Copy()
+	valid.Networks[0].Mode = NetworkModeHost
+	valid.Tasks[0].Resources.Networks = []*NetworkResource{{MBits: 10}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The ports of a network must bind to the same host network
+	invalid = tg.Copy()
+	invalid.Networks[0].DynamicPorts[0].HostNetwork = "public"
+	err = invalid.Validate()
+	if err == nil || !strings.Contains(err.Error(), `ports "admin" and "http" bind to different host networks`) {
+		t.Fatalf("expected host network error: %v", err)
+	}
+
+	invalid = valid.Copy()
+	invalid.Tasks[0].Resources.Networks[0].DynamicPorts = []Port{{"web", 0, 0, "public"}, {"db", 0, 0, "private"}}
+	err = invalid.Validate()
+	if err == nil || !strings.Contains(err.Error(), `ports "web" and "db" bind to different host networks`) {
+		t.Fatalf("expected host network error: %v", err)
+	}
+}
+
 func TestTask_Validate(t *testing.T) {
 	task := &Task{}
 	ephemeralDisk := DefaultEphemeralDisk()
@@ -398,6 +690,41 @@ func TestTask_Validate(t *testing.T) {
 	}
 }
 
+func TestTask_RequiredDriverCapabilities(t *testing.T) {
+	task := &Task{
+		Name:   "web",
+		Driver: "docker",
+	}
+	if caps := task.RequiredDriverCapabilities(); len(caps) != 0 {
+		t.Fatalf("bad: %v", caps)
+	}
+
+	task.Schedule = &TaskSchedule{Pause: "0 2 * * *", Duration: time.Hour}
+	task.Templates = []*Template{
+		{ChangeMode: TemplateChangeModeRestart},
+		{ChangeMode: TemplateChangeModeSignal},
+	}
+	task.Services = []*Service{
+		{
+			Name: "web",
+			Checks: []*ServiceCheck{
+				{Name: "http", Type: ServiceCheckHTTP},
+				{Name: "script", Type: ServiceCheckScript},
+			},
+		},
+	}
+	expected := []string{DriverCapabilityExec, DriverCapabilityPause, DriverCapabilitySignals}
+	if caps := task.RequiredDriverCapabilities(); !reflect.DeepEqual(caps, expected) {
+		t.Fatalf("bad: %v; want %v", caps, expected)
+	}
+
+	task.VolumeMounts = []*VolumeMount{{Volume: "data", Destination: "/srv/data"}}
+	expected = []string{DriverCapabilityExec, DriverCapabilityPause, DriverCapabilitySignals, DriverCapabilityMountVolumes}
+	if caps := task.RequiredDriverCapabilities(); !reflect.DeepEqual(caps, expected) {
+		t.Fatalf("bad: %v; want %v", caps, expected)
+	}
+}
+
 func TestTask_Validate_Services(t *testing.T) {
 	s1 := &Service{
 		Name:      "service-name",
@@ -495,6 +822,19 @@ func TestTask_Validate_Service_Check(t *testing.T) {
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+
+	check1.OnUpdate = "sometimes"
+	err = check1.validate()
+	if err == nil || !strings.Contains(err.Error(), "invalid on_update (sometimes)") {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, onUpdate := range []string{OnUpdateRequireHealthy, OnUpdateIgnoreWarnings, OnUpdateIgnore} {
+		check1.OnUpdate = onUpdate
+		if err := check1.validate(); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
 }
 
 func TestTask_Validate_LogConfig(t *testing.T) {
@@ -610,6 +950,231 @@ func TestTemplate_Validate(t *testing.T) {
 	}
 }
 
+func TestTaskSchedule_Validate(t *testing.T) {
+	s := &TaskSchedule{}
+	err := s.Validate()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	for _, exp := range []string{"pause cron expression", "duration must be"} {
+		if !strings.Contains(err.Error(), exp) {
+			t.Fatalf("should have contained error %q: %q", exp, err)
+		}
+	}
+
+	s = &TaskSchedule{Pause: "foo", Duration: time.Hour}
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "Invalid pause cron") {
+		t.Fatalf("err: %v", err)
+	}
+
+	s = &TaskSchedule{Pause: "0 2 * * *", Duration: time.Hour}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestTaskLifecycleConfig_Validate(t *testing.T) {
+	l := &TaskLifecycleConfig{}
+	if err := l.Validate(); err == nil || !strings.Contains(err.Error(), "Must specify a lifecycle hook") {
+		t.Fatalf("err: %v", err)
+	}
+
+	l = &TaskLifecycleConfig{Hook: "foo"}
+	if err := l.Validate(); err == nil || !strings.Contains(err.Error(), "Invalid lifecycle hook") {
+		t.Fatalf("err: %v", err)
+	}
+
+	l = &TaskLifecycleConfig{Hook: TaskLifecycleHookPoststop, Sidecar: true}
+	if err := l.Validate(); err == nil || !strings.Contains(err.Error(), "can't be sidecars") {
+		t.Fatalf("err: %v", err)
+	}
+
+	l = &TaskLifecycleConfig{Hook: TaskLifecycleHookPrestart, Sidecar: true}
+	if err := l.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestTaskGroup_Validate_Volumes(t *testing.T) {
+	tg := &TaskGroup{
+		Name:          "web",
+		Count:         1,
+		RestartPolicy: NewRestartPolicy(JobTypeService),
+		EphemeralDisk: DefaultEphemeralDisk(),
+		Volumes: map[string]*VolumeRequest{
+			"data": {Name: "data", Type: VolumeTypeHost, Source: "shared_data"},
+		},
+		Tasks: []*Task{
+			{
+				Name:      "web",
+				Driver:    "exec",
+				Resources: DefaultResources(),
+				LogConfig: DefaultLogConfig(),
+				VolumeMounts: []*VolumeMount{
+					{Volume: "data", Destination: "/srv/data"},
+				},
+			},
+		},
+	}
+	if err := tg.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Volumes must have a supported type and a source
+	invalid := tg.Copy()
+	invalid.Volumes["data"].Type = "nfs"
+	invalid.Volumes["data"].Source = ""
+	err := invalid.Validate()
+	if err == nil || !strings.Contains(err.Error(), `unsupported type "nfs"`) {
+		t.Fatalf("expected volume type error: %v", err)
+	}
+	if !strings.Contains(err.Error(), `Volume "data" missing source`) {
+		t.Fatalf("expected volume source error: %v", err)
+	}
+
+	// Tasks can only mount the volumes of the group at absolute paths
+	invalid = tg.Copy()
+	invalid.Tasks[0].VolumeMounts = append(invalid.Tasks[0].VolumeMounts,
+		&VolumeMount{Volume: "logs", Destination: "/var/log"},
+		&VolumeMount{Volume: "data", Destination: "data"})
+	err = invalid.Validate()
+	if err == nil || !strings.Contains(err.Error(), `unknown volume "logs"`) {
+		t.Fatalf("expected unknown volume error: %v", err)
+	}
+	if !strings.Contains(err.Error(), `destination "data" must be an absolute path`) {
+		t.Fatalf("expected destination error: %v", err)
+	}
+}
+
+func TestTaskGroup_Validate_MainTask(t *testing.T) {
+	tg := &TaskGroup{
+		Name:          "web",
+		Count:         1,
+		RestartPolicy: NewRestartPolicy(JobTypeService),
+		EphemeralDisk: DefaultEphemeralDisk(),
+		Tasks: []*Task{
+			{
+				Name:      "init",
+				Driver:    "exec",
+				Resources: DefaultResources(),
+				LogConfig: DefaultLogConfig(),
+				Lifecycle: &TaskLifecycleConfig{Hook: TaskLifecycleHookPrestart},
+			},
+		},
+	}
+	if err := tg.Validate(); err == nil || !strings.Contains(err.Error(), "at least one task without a lifecycle") {
+		t.Fatalf("err: %v", err)
+	}
+
+	tg.Tasks = append(tg.Tasks, &Task{
+		Name:      "web",
+		Driver:    "exec",
+		Resources: DefaultResources(),
+		LogConfig: DefaultLogConfig(),
+	})
+	if err := tg.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestTask_Canonicalize_RestartPolicy(t *testing.T) {
+	job := testJob()
+	tg := job.TaskGroups[0]
+	tg.RestartPolicy = &RestartPolicy{
+		Attempts: 2,
+		Interval: time.Minute,
+		Delay:    15 * time.Second,
+		Mode:     RestartPolicyModeDelay,
+	}
+
+	task := tg.Tasks[0]
+	task.RestartPolicy = &RestartPolicy{Delay: time.Second}
+	task.Canonicalize(job, tg)
+	expected := &RestartPolicy{
+		Attempts: 2,
+		Interval: time.Minute,
+		Delay:    time.Second,
+		Mode:     RestartPolicyModeDelay,
+	}
+	if !reflect.DeepEqual(task.RestartPolicy, expected) {
+		t.Fatalf("bad: %#v", task.RestartPolicy)
+	}
+
+	// Zero attempts aren't inherited with the "fail" mode
+	task.RestartPolicy = &RestartPolicy{Mode: RestartPolicyModeFail}
+	task.Canonicalize(job, tg)
+	if task.RestartPolicy.Attempts != 0 || task.RestartPolicy.Interval != time.Minute {
+		t.Fatalf("bad: %#v", task.RestartPolicy)
+	}
+	if err := task.RestartPolicy.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestVault_Validate_Cluster(t *testing.T) {
+	v := &Vault{Policies: []string{"foo"}, Cluster: "bad.name"}
+	if err := v.Validate(); err == nil || !strings.Contains(err.Error(), "Cluster name") {
+		t.Fatalf("err: %v", err)
+	}
+
+	v.Cluster = "secondary"
+	if err := v.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if name := v.ClusterName(); name != "secondary" {
+		t.Fatalf("bad: %v", name)
+	}
+
+	// The default cluster is used when no cluster is given
+	var c *Consul
+	if name := c.ClusterName(); name != DefaultCluster {
+		t.Fatalf("bad: %v", name)
+	}
+	c = &Consul{Cluster: "bad.name"}
+	if err := c.Validate(); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestTaskSchedule_Window(t *testing.T) {
+	s := &TaskSchedule{Pause: "0 2 * * *", Duration: time.Hour}
+	day := time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		Now    time.Time
+		Paused bool
+		Next   time.Time
+	}{
+		{
+			Now:    day.Add(1 * time.Hour),
+			Paused: false,
+			Next:   day.Add(2 * time.Hour),
+		},
+		{
+			Now:    day.Add(2 * time.Hour),
+			Paused: true,
+			Next:   day.Add(3 * time.Hour),
+		},
+		{
+			Now:    day.Add(150 * time.Minute),
+			Paused: true,
+			Next:   day.Add(3 * time.Hour),
+		},
+		{
+			Now:    day.Add(3 * time.Hour),
+			Paused: false,
+			Next:   day.Add(26 * time.Hour),
+		},
+	}
+
+	for i, c := range cases {
+		paused, next := s.Window(c.Now)
+		if paused != c.Paused || !next.Equal(c.Next) {
+			t.Fatalf("case %d: got %v %v; want %v %v", i+1, paused, next, c.Paused, c.Next)
+		}
+	}
+}
+
 func TestConstraint_Validate(t *testing.T) {
 	c := &Constraint{}
 	err := c.Validate()
@@ -704,7 +1269,7 @@ func TestResource_Add(t *testing.T) {
 			&NetworkResource{
 				CIDR:          "10.0.0.0/8",
 				MBits:         100,
-				ReservedPorts: []Port{{"ssh", 22}},
+				ReservedPorts: []Port{{"ssh", 22, 0, ""}},
 			},
 		},
 	}
@@ -717,7 +1282,7 @@ func TestResource_Add(t *testing.T) {
 			&NetworkResource{
 				IP:            "10.0.0.1",
 				MBits:         50,
-				ReservedPorts: []Port{{"web", 80}},
+				ReservedPorts: []Port{{"web", 80, 0, ""}},
 			},
 		},
 	}
@@ -736,7 +1301,7 @@ func TestResource_Add(t *testing.T) {
 			&NetworkResource{
 				CIDR:          "10.0.0.0/8",
 				MBits:         150,
-				ReservedPorts: []Port{{"ssh", 22}, {"web", 80}},
+				ReservedPorts: []Port{{"ssh", 22, 0, ""}, {"web", 80, 0, ""}},
 			},
 		},
 	}
@@ -752,7 +1317,7 @@ func TestResource_Add_Network(t *testing.T) {
 		Networks: []*NetworkResource{
 			&NetworkResource{
 				MBits:        50,
-				DynamicPorts: []Port{{"http", 0}, {"https", 0}},
+				DynamicPorts: []Port{{"http", 0, 0, ""}, {"https", 0, 0, ""}},
 			},
 		},
 	}
@@ -760,7 +1325,7 @@ func TestResource_Add_Network(t *testing.T) {
 		Networks: []*NetworkResource{
 			&NetworkResource{
 				MBits:        25,
-				DynamicPorts: []Port{{"admin", 0}},
+				DynamicPorts: []Port{{"admin", 0, 0, ""}},
 			},
 		},
 	}
@@ -778,7 +1343,7 @@ func TestResource_Add_Network(t *testing.T) {
 		Networks: []*NetworkResource{
 			&NetworkResource{
 				MBits:        75,
-				DynamicPorts: []Port{{"http", 0}, {"https", 0}, {"admin", 0}},
+				DynamicPorts: []Port{{"http", 0, 0, ""}, {"https", 0, 0, ""}, {"admin", 0, 0, ""}},
 			},
 		},
 	}
@@ -788,6 +1353,47 @@ func TestResource_Add_Network(t *testing.T) {
 	}
 }
 
+func TestNodeReliability_Decayed(t *testing.T) {
+	now := time.Now()
+	r := NodeReliability{Penalty: 8, UpdateTime: now.UnixNano()}
+
+	if p := r.Decayed(now, time.Hour); p != 8.0 {
+		t.Fatalf("bad: %v", p)
+	}
+	if p := r.Decayed(now.Add(2*time.Hour), time.Hour); p != 2.0 {
+		t.Fatalf("bad: %v", p)
+	}
+
+	// Adding decays the existing penalty to the update time
+	r = r.Add(3, now.Add(time.Hour), time.Hour)
+	if r.Penalty != 7.0 || r.UpdateTime != now.Add(time.Hour).UnixNano() {
+		t.Fatalf("bad: %#v", r)
+	}
+}
+
+func TestNodeReliabilityConfig_Validate(t *testing.T) {
+	c := &NodeReliabilityConfig{}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	c.Enabled = true
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "half-life") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	c.HalfLife = time.Hour
+	c.FailedAllocWeight = -1
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "negative") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	c.FailedAllocWeight = 1
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func TestEncodeDecode(t *testing.T) {
 	type FooRequest struct {
 		Foo string
@@ -944,6 +1550,38 @@ func TestDistinctCheckID(t *testing.T) {
 
 }
 
+func TestService_Validate_Provider(t *testing.T) {
+	s := Service{
+		Name:     "service-name",
+		Provider: ServiceProviderNomad,
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	s.Provider = "lol"
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "invalid provider") {
+		t.Fatalf("Service should be invalid (unknown provider): %v", err)
+	}
+
+	s = Service{
+		Name:      "service-name",
+		PortLabel: "bar",
+		Provider:  ServiceProviderNomad,
+		Checks: []*ServiceCheck{
+			{
+				Name:     "check-tcp",
+				Type:     ServiceCheckTCP,
+				Interval: 5 * time.Second,
+				Timeout:  2 * time.Second,
+			},
+		},
+	}
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "can not have checks") {
+		t.Fatalf("Service should be invalid (checks with the nomad provider): %v", err)
+	}
+}
+
 func TestService_Canonicalize(t *testing.T) {
 	job := "example"
 	taskGroup := "cache"
@@ -1076,6 +1714,122 @@ func TestPeriodicConfig_NextCron(t *testing.T) {
 	}
 }
 
+func TestPeriodicConfig_TimeZone(t *testing.T) {
+	p := &PeriodicConfig{Enabled: true, SpecType: PeriodicSpecCron, Spec: "0 9 * * *", TimeZone: "Foo/Bar"}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), `Invalid time zone "Foo/Bar"`) {
+		t.Fatalf("expected invalid time zone error: %v", err)
+	}
+
+	p.TimeZone = "America/New_York"
+	if err := p.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The spec is matched against the wall clock of the time zone, while the
+	// returned time is in the location of the passed time
+	from := time.Date(2009, time.November, 10, 12, 0, 0, 0, time.UTC)
+	if n, exp := p.Next(from), time.Date(2009, time.November, 10, 14, 0, 0, 0, time.UTC); n != exp {
+		t.Fatalf("Next(%v) returned %v; want %v", from, n, exp)
+	}
+}
+
+func TestPeriodicConfig_Specs(t *testing.T) {
+	p := &PeriodicConfig{
+		Enabled:  true,
+		SpecType: PeriodicSpecCron,
+		Specs: []*PeriodicSpec{
+			{Spec: "0 9 * * *", Enabled: true},
+			{Spec: "foo", Enabled: false},
+		},
+	}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), `Invalid cron spec "foo"`) {
+		t.Fatalf("expected invalid cron spec error: %v", err)
+	}
+
+	from := time.Date(2009, time.November, 10, 12, 22, 30, 0, time.UTC)
+	p.Spec = "0 0 * * *"
+	p.Specs[1].Spec = "30 12 * * *"
+	if err := p.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Disabled specs are not launched
+	if n, exp := p.Next(from), time.Date(2009, time.November, 11, 0, 0, 0, 0, time.UTC); n != exp {
+		t.Fatalf("Next(%v) returned %v; want %v", from, n, exp)
+	}
+	p.Specs[1].Enabled = true
+	if n, exp := p.Next(from), time.Date(2009, time.November, 10, 12, 30, 0, 0, time.UTC); n != exp {
+		t.Fatalf("Next(%v) returned %v; want %v", from, n, exp)
+	}
+
+	p.Specs[0].Enabled = false
+	expected := []*PeriodicLaunchTime{
+		{Spec: "0 0 * * *", Enabled: true, Next: time.Date(2009, time.November, 11, 0, 0, 0, 0, time.UTC)},
+		{Spec: "0 9 * * *", Enabled: false},
+		{Spec: "30 12 * * *", Enabled: true, Next: time.Date(2009, time.November, 10, 12, 30, 0, 0, time.UTC)},
+	}
+	if launches := p.NextLaunches(from); !reflect.DeepEqual(launches, expected) {
+		t.Fatalf("bad: %#v", launches)
+	}
+
+	// Copies don't share specs
+	c := p.Copy()
+	c.Specs[0].Enabled = true
+	if p.Specs[0].Enabled {
+		t.Fatalf("copy shares specs")
+	}
+}
+
+func TestParameterizedJobConfig_Validate(t *testing.T) {
+	d := &ParameterizedJobConfig{
+		Payload: "foo",
+	}
+
+	if err := d.Validate(); err == nil || !strings.Contains(err.Error(), "payload") {
+		t.Fatalf("Expected unknown payload requirement: %v", err)
+	}
+
+	d.Payload = DispatchPayloadOptional
+	d.MetaOptional = []string{"foo", "bar"}
+	d.MetaRequired = []string{"bar", "baz"}
+
+	if err := d.Validate(); err == nil || !strings.Contains(err.Error(), "disjoint") {
+		t.Fatalf("Expected meta not being disjoint error: %v", err)
+	}
+}
+
+func TestDispatchPayloadConfig_Validate(t *testing.T) {
+	d := &DispatchPayloadConfig{
+		File: "foo",
+	}
+
+	// task/local/haha
+	if err := d.Validate(); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// task/haha
+	d.File = "../haha"
+	if err := d.Validate(); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// ../haha
+	d.File = "../../../haha"
+	if err := d.Validate(); err == nil {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestDispatchedID(t *testing.T) {
+	now := time.Now()
+	id := DispatchedID("foo", now)
+	prefix := fmt.Sprintf("foo%s%d-", DispatchLaunchSuffix, now.Unix())
+	if !strings.HasPrefix(id, prefix) {
+		t.Fatalf("bad: %q", id)
+	}
+}
+
 func TestRestartPolicy_Validate(t *testing.T) {
 	// Policy with acceptable restart options passes
 	p := &RestartPolicy{
@@ -1156,6 +1910,34 @@ func TestTaskArtifact_Validate_Dest(t *testing.T) {
 	}
 }
 
+func TestTaskArtifact_Validate_OnError(t *testing.T) {
+	artifact := &TaskArtifact{GetterSource: "google.com"}
+	artifact.Canonicalize()
+	if artifact.OnError != ArtifactOnErrorRetry || artifact.RetryDelay != DefaultArtifactRetryDelay {
+		t.Fatalf("bad: %#v", artifact)
+	}
+
+	artifact.RetryAttempts = 3
+	if err := artifact.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	artifact.OnError = ArtifactOnErrorContinue
+	if err := artifact.Validate(); err == nil || !strings.Contains(err.Error(), "retry attempts") {
+		t.Fatalf("expected retry attempts error: %v", err)
+	}
+
+	artifact.RetryAttempts = 0
+	if err := artifact.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	artifact.OnError = "ignore"
+	if err := artifact.Validate(); err == nil || !strings.Contains(err.Error(), ArtifactOnErrorInvalidError.Error()) {
+		t.Fatalf("expected on_error error: %v", err)
+	}
+}
+
 func TestTaskArtifact_Validate_Checksum(t *testing.T) {
 	cases := []struct {
 		Input *TaskArtifact
@@ -1238,3 +2020,209 @@ func TestAllocation_Terminated(t *testing.T) {
 		}
 	}
 }
+
+func TestAllocation_LastEventTime(t *testing.T) {
+	alloc := &Allocation{}
+	if last := alloc.LastEventTime(); !last.IsZero() {
+		t.Fatalf("expected zero time: %v", last)
+	}
+
+	now := time.Now()
+	alloc.TaskStates = map[string]*TaskState{
+		"web": &TaskState{
+			Events: []*TaskEvent{
+				{Type: TaskStarted, Time: now.Add(-time.Minute).UnixNano()},
+				{Type: TaskTerminated, Time: now.UnixNano()},
+			},
+		},
+		"sidecar": &TaskState{
+			Events: []*TaskEvent{
+				{Type: TaskStarted, Time: now.Add(-2 * time.Minute).UnixNano()},
+			},
+		},
+	}
+	if last := alloc.LastEventTime(); !last.Equal(time.Unix(0, now.UnixNano())) {
+		t.Fatalf("bad: %v", last)
+	}
+}
+
+func TestAllocation_NextRescheduleTracker(t *testing.T) {
+	alloc := &Allocation{
+		ID:           GenerateUUID(),
+		NodeID:       GenerateUUID(),
+		ClientStatus: AllocClientStatusRunning,
+	}
+
+	// Replacing a healthy allocation is not a reschedule
+	now := time.Now()
+	if tracker := alloc.NextRescheduleTracker(now, 0); tracker != nil {
+		t.Fatalf("expected no tracker: %#v", tracker)
+	}
+
+	alloc.ClientStatus = AllocClientStatusLost
+	tracker := alloc.NextRescheduleTracker(now, 5*time.Second)
+	if tracker == nil || len(tracker.Events) != 1 {
+		t.Fatalf("bad: %#v", tracker)
+	}
+	event := tracker.Events[0]
+	if event.PrevAllocID != alloc.ID || event.PrevNodeID != alloc.NodeID ||
+		event.PrevStatus != AllocClientStatusLost || event.Delay != 5*time.Second ||
+		event.RescheduleTime != now.UTC().UnixNano() {
+		t.Fatalf("bad: %#v", event)
+	}
+
+	// The history is carried over, without modifying the original
+	alloc.RescheduleTracker = tracker
+	next := alloc.NextRescheduleTracker(now, 0)
+	if len(next.Events) != 2 || len(alloc.RescheduleTracker.Events) != 1 {
+		t.Fatalf("bad: %#v %#v", next, alloc.RescheduleTracker)
+	}
+}
+
+func TestVariableDecrypted_Validate(t *testing.T) {
+	v := &VariableDecrypted{
+		VariableMetadata: VariableMetadata{Path: "nomad/jobs/example/web"},
+		Items:            VariableItems{"password": "hunter2"},
+	}
+	if err := v.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, path := range []string{"", "/a", "a/", "a//b", "a b", "nomad", "nomad/secret", strings.Repeat("a", 129)} {
+		v.Path = path
+		if err := v.Validate(); err == nil {
+			t.Fatalf("expected error for path %q", path)
+		}
+	}
+
+	v.Path = "project/db"
+	v.Items = nil
+	if err := v.Validate(); err == nil || !strings.Contains(err.Error(), "at least one item") {
+		t.Fatalf("expected error for missing items: %v", err)
+	}
+	v.Items = VariableItems{"key": strings.Repeat("a", maxVariablesItemsSize)}
+	if err := v.Validate(); err == nil || !strings.Contains(err.Error(), "exceeding the maximum") {
+		t.Fatalf("expected error for oversized items: %v", err)
+	}
+}
+
+func TestMaintenanceWindow_Validate(t *testing.T) {
+	now := time.Now()
+	w := &MaintenanceWindow{
+		NodeClass:     "gpu",
+		Mode:          MaintenanceModeHard,
+		Start:         now,
+		End:           now.Add(time.Hour),
+		MigrateBefore: 10 * time.Minute,
+	}
+	if err := w.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	w.Mode = MaintenanceModeSoft
+	if err := w.Validate(); err == nil || !strings.Contains(err.Error(), "only supported by hard windows") {
+		t.Fatalf("expected error for migrate_before: %v", err)
+	}
+
+	w = &MaintenanceWindow{Mode: "bad", Start: now, End: now}
+	err := w.Validate()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	mErr := err.(*multierror.Error)
+	if len(mErr.Errors) != 3 {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestMaintenanceWindow_InEffect(t *testing.T) {
+	start := time.Now()
+	w := &MaintenanceWindow{
+		Mode:          MaintenanceModeSoft,
+		Start:         start,
+		End:           start.Add(time.Hour),
+		MigrateBefore: 10 * time.Minute,
+	}
+
+	// Soft windows are in effect from their start to their end
+	if w.InEffect(start.Add(-time.Minute)) || !w.InEffect(start) || w.InEffect(w.End) {
+		t.Fatalf("bad: %#v", w)
+	}
+
+	// Hard windows take effect ahead of their start
+	w.Mode = MaintenanceModeHard
+	if !w.InEffect(start.Add(-time.Minute)) || w.InEffect(start.Add(-11*time.Minute)) {
+		t.Fatalf("bad: %#v", w)
+	}
+}
+
+func TestNamespace_Validate_NetworkPolicy(t *testing.T) {
+	ns := &Namespace{
+		Name: "engineering",
+		NetworkPolicy: &NamespaceNetworkPolicy{
+			DenyPrivilegedPorts: true,
+			ExemptJobs:          []string{"ingress-*", "[bad"},
+		},
+	}
+	err := ns.Validate()
+	if err == nil || !strings.Contains(err.Error(), `invalid exempt job pattern "[bad"`) {
+		t.Fatalf("bad: %v", err)
+	}
+
+	ns.NetworkPolicy.ExemptJobs = []string{"ingress-*"}
+	if err := ns.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestNamespaceNetworkPolicy_Admit(t *testing.T) {
+	job := testJob()
+	job.ID = "web"
+	task := job.TaskGroups[0].Tasks[0]
+	task.Resources.Networks[0].ReservedPorts = []Port{{Label: "http", Value: 80}}
+	task.Config["network_mode"] = "host"
+
+	// No policy admits everything
+	var p *NamespaceNetworkPolicy
+	if err := p.Admit(job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	p = &NamespaceNetworkPolicy{DenyPrivilegedPorts: true}
+	err := p.Admit(job)
+	if err == nil || !strings.Contains(err.Error(), `static port "http" uses privileged port 80`) {
+		t.Fatalf("bad: %v", err)
+	}
+	if strings.Contains(err.Error(), "network_mode") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Unprivileged ports are admitted
+	task.Resources.Networks[0].ReservedPorts[0].Value = 8080
+	if err := p.Admit(job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Host networking is denied in the groups and the tasks
+	job.TaskGroups[0].Networks = []*NetworkResource{{Mode: NetworkModeHost}}
+	p = &NamespaceNetworkPolicy{DenyHostNetwork: true}
+	err = p.Admit(job)
+	if err == nil || !strings.Contains(err.Error(), "host network mode is not allowed") ||
+		!strings.Contains(err.Error(), "host network_mode is not allowed") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Isolated group networks are admitted
+	job.TaskGroups[0].Networks[0].Mode = "bridge"
+	delete(task.Config, "network_mode")
+	if err := p.Admit(job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Exempt jobs are admitted
+	job.TaskGroups[0].Networks[0].Mode = NetworkModeHost
+	p.ExemptJobs = []string{"w*"}
+	if err := p.Admit(job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
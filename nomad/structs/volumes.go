@@ -0,0 +1,129 @@
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+const (
+	// VolumeTypeHost is the type of volumes backed by a host volume of the
+	// client the task group is placed on
+	VolumeTypeHost = "host"
+
+	// VolumeTypeCSI is the type of volumes backed by a CSI volume, which is
+	// claimed by the allocations of the task group
+	VolumeTypeCSI = "csi"
+)
+
+// ClientHostVolumeConfig is a named directory of the host of a client that
+// tasks can mount
+type ClientHostVolumeConfig struct {
+	Name     string
+	Path     string
+	ReadOnly bool
+}
+
+func (v *ClientHostVolumeConfig) Copy() *ClientHostVolumeConfig {
+	if v == nil {
+		return nil
+	}
+	nv := new(ClientHostVolumeConfig)
+	*nv = *v
+	return nv
+}
+
+// CopyMapStringClientHostVolumeConfig copies the host volumes of a node
+func CopyMapStringClientHostVolumeConfig(m map[string]*ClientHostVolumeConfig) map[string]*ClientHostVolumeConfig {
+	if m == nil {
+		return nil
+	}
+	nm := make(map[string]*ClientHostVolumeConfig, len(m))
+	for k, v := range m {
+		nm[k] = v.Copy()
+	}
+	return nm
+}
+
+// VolumeRequest is a volume requested by a task group. Host volumes are
+// sourced from the host volume of the same name of the client, and CSI
+// volumes from the CSI volume with the source as ID.
+type VolumeRequest struct {
+	Name     string
+	Type     string
+	Source   string
+	ReadOnly bool `mapstructure:"read_only"`
+}
+
+func (v *VolumeRequest) Copy() *VolumeRequest {
+	if v == nil {
+		return nil
+	}
+	nv := new(VolumeRequest)
+	*nv = *v
+	return nv
+}
+
+// Validate checks the volume request is well formed
+func (v *VolumeRequest) Validate() error {
+	var mErr multierror.Error
+	switch v.Type {
+	case VolumeTypeHost, VolumeTypeCSI:
+	case "":
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume %q missing type", v.Name))
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume %q has unsupported type %q", v.Name, v.Type))
+	}
+	if v.Source == "" {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume %q missing source", v.Name))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// VolumeMount mounts a volume of the task group into the file system of a
+// task at the destination path
+type VolumeMount struct {
+	Volume      string
+	Destination string
+	ReadOnly    bool `mapstructure:"read_only"`
+}
+
+func (m *VolumeMount) Copy() *VolumeMount {
+	if m == nil {
+		return nil
+	}
+	nm := new(VolumeMount)
+	*nm = *m
+	return nm
+}
+
+// Validate checks the volume mount is well formed and mounts one of the
+// volumes of the task group
+func (m *VolumeMount) Validate(volumes map[string]*VolumeRequest) error {
+	var mErr multierror.Error
+	if m.Volume == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("Volume mount missing volume"))
+	} else if _, ok := volumes[m.Volume]; !ok {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume mount references unknown volume %q", m.Volume))
+	}
+	if m.Destination == "" {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume mount of %q missing destination", m.Volume))
+	} else if !path.IsAbs(m.Destination) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Volume mount destination %q must be an absolute path", m.Destination))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// CopySliceVolumeMount copies the volume mounts of a task
+func CopySliceVolumeMount(s []*VolumeMount) []*VolumeMount {
+	if s == nil {
+		return nil
+	}
+	ns := make([]*VolumeMount, len(s))
+	for i, m := range s {
+		ns[i] = m.Copy()
+	}
+	return ns
+}
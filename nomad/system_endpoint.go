@@ -18,6 +18,13 @@ func (s *System) GarbageCollect(args *structs.GenericRequest, reply *structs.Gen
 		return err
 	}
 
+	// Check management level permissions
+	if aclObj, err := s.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Get the states current index
 	snapshotIndex, err := s.srv.fsm.State().LatestIndex()
 	if err != nil {
@@ -35,6 +42,13 @@ func (s *System) ReconcileJobSummaries(args *structs.GenericRequest, reply *stru
 		return err
 	}
 
+	// Check management level permissions
+	if aclObj, err := s.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
 	_, index, err := s.srv.raftApply(structs.ReconcileJobSummariesRequestType, args)
 	if err != nil {
 		return fmt.Errorf("reconciliation of job summaries failed: %v", err)
@@ -0,0 +1,56 @@
+package nomad
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/hashicorp/consul/tlsutil"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+)
+
+const (
+	// tlsDomain is the domain of the names servers are verified against when
+	// hostname verification is enabled: server.<region>.nomad
+	tlsDomain = "nomad"
+)
+
+// rpcTLSEnabled returns whether the TLS configuration enables TLS for RPC
+func rpcTLSEnabled(conf *config.TLSConfig) bool {
+	return conf != nil && conf.EnableRPC
+}
+
+// tlsutilConfig converts the TLS configuration of the agent. Both incoming
+// and outgoing connections are verified against the CA, so that servers and
+// clients mutually authenticate.
+func tlsutilConfig(conf *config.TLSConfig) *tlsutil.Config {
+	return &tlsutil.Config{
+		VerifyIncoming:       true,
+		VerifyOutgoing:       true,
+		VerifyServerHostname: conf.VerifyServerHostname,
+		CAFile:               conf.CAFile,
+		CertFile:             conf.CertFile,
+		KeyFile:              conf.KeyFile,
+		Domain:               tlsDomain,
+	}
+}
+
+// NewTLSWrapper returns the wrapper used to switch outgoing RPC connections
+// to TLS. A nil wrapper is returned if TLS isn't enabled for RPC.
+func NewTLSWrapper(conf *config.TLSConfig) (tlsutil.DCWrapper, error) {
+	if !rpcTLSEnabled(conf) {
+		return nil, nil
+	}
+	if conf.CertFile == "" || conf.KeyFile == "" {
+		return nil, fmt.Errorf("TLS for RPC requires a certificate and key")
+	}
+	return tlsutilConfig(conf).OutgoingTLSWrapper()
+}
+
+// newIncomingTLSConfig returns the TLS configuration for incoming RPC
+// connections. A nil configuration is returned if TLS isn't enabled for RPC.
+func newIncomingTLSConfig(conf *config.TLSConfig) (*tls.Config, error) {
+	if !rpcTLSEnabled(conf) {
+		return nil, nil
+	}
+	return tlsutilConfig(conf).IncomingTLSConfig()
+}
@@ -0,0 +1,169 @@
+package nomad
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/tlsutil"
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+const (
+	fooCAFile     = "test-fixtures/tls/ca.pem"
+	fooServerCert = "test-fixtures/tls/server.pem"
+	fooServerKey  = "test-fixtures/tls/server-key.pem"
+	fooClientCert = "test-fixtures/tls/client.pem"
+	fooClientKey  = "test-fixtures/tls/client-key.pem"
+	otherCAFile   = "test-fixtures/tls/other-ca.pem"
+)
+
+func testTLSServer(t *testing.T, cb func(*Config)) *Server {
+	return testServer(t, func(c *Config) {
+		c.RequireTLS = true
+		c.TLSConfig = &config.TLSConfig{
+			EnableRPC:            true,
+			VerifyServerHostname: true,
+			CAFile:               fooCAFile,
+			CertFile:             fooServerCert,
+			KeyFile:              fooServerKey,
+		}
+		if cb != nil {
+			cb(c)
+		}
+	})
+}
+
+func TestServer_RPC_TLS(t *testing.T) {
+	s1 := testTLSServer(t, nil)
+	defer s1.Shutdown()
+	s2 := testTLSServer(t, func(c *Config) {
+		c.DevDisableBootstrap = true
+	})
+	defer s2.Shutdown()
+	testJoin(t, s1, s2)
+
+	// Raft and the forwarding of RPCs to the leader work over TLS
+	testutil.WaitForLeader(t, s1.RPC)
+	testutil.WaitForLeader(t, s2.RPC)
+
+	isLeader, remote := s2.getLeader()
+	if isLeader || remote == nil {
+		t.Fatalf("bad: %v %v", isLeader, remote)
+	}
+	var out struct{}
+	if err := s2.forwardLeader(remote, "Status.Ping", struct{}{}, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A client presenting a certificate signed by the CA is accepted
+	tlsWrap, err := NewTLSWrapper(&config.TLSConfig{
+		EnableRPC:            true,
+		VerifyServerHostname: true,
+		CAFile:               fooCAFile,
+		CertFile:             fooClientCert,
+		KeyFile:              fooClientKey,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pool := NewPool(os.Stderr, time.Minute, 1, tlsWrap)
+	defer pool.Shutdown()
+	if err := pool.RPC("global", s1.config.RPCAddr, structs.ApiMajorVersion, "Status.Ping", struct{}{}, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Plaintext connections are rejected
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+	if err := msgpackrpc.CallWithCodec(codec, "Status.Ping", struct{}{}, &out); err == nil {
+		t.Fatalf("expected plaintext RPC to fail")
+	}
+}
+
+func TestServer_RPC_TLS_Unauthenticated(t *testing.T) {
+	s1 := testTLSServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// A client without a certificate is rejected
+	tc := &tlsutil.Config{
+		VerifyOutgoing: true,
+		CAFile:         fooCAFile,
+	}
+	tlsWrap, err := tc.OutgoingTLSWrapper()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pool := NewPool(os.Stderr, time.Minute, 1, tlsWrap)
+	defer pool.Shutdown()
+	var out struct{}
+	if err := pool.RPC("global", s1.config.RPCAddr, structs.ApiMajorVersion, "Status.Ping", struct{}{}, &out); err == nil {
+		t.Fatalf("expected RPC without a client certificate to fail")
+	}
+
+	// A client not trusting the CA of the server doesn't connect
+	tlsWrap, err = NewTLSWrapper(&config.TLSConfig{
+		EnableRPC: true,
+		CAFile:    otherCAFile,
+		CertFile:  fooClientCert,
+		KeyFile:   fooClientKey,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pool = NewPool(os.Stderr, time.Minute, 1, tlsWrap)
+	defer pool.Shutdown()
+	if err := pool.RPC("global", s1.config.RPCAddr, structs.ApiMajorVersion, "Status.Ping", struct{}{}, &out); err == nil {
+		t.Fatalf("expected RPC to an untrusted server to fail")
+	}
+}
+
+func TestServer_ReloadTLS(t *testing.T) {
+	s1 := testTLSServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Disabling TLS requires a restart
+	if err := s1.ReloadTLS(&config.TLSConfig{}); err == nil {
+		t.Fatalf("expected disabling TLS to fail")
+	}
+
+	// Reloading with a missing certificate fails and keeps the current one
+	bad := s1.config.TLSConfig.Copy()
+	bad.CertFile = "test-fixtures/tls/missing.pem"
+	if err := s1.ReloadTLS(bad); err == nil {
+		t.Fatalf("expected reload with a missing certificate to fail")
+	}
+	if s1.config.TLSConfig.CertFile != fooServerCert {
+		t.Fatalf("bad: %#v", s1.config.TLSConfig)
+	}
+
+	// Only trust the other CA. The server no longer accepts clients with a
+	// certificate signed by the original CA.
+	reloaded := s1.config.TLSConfig.Copy()
+	reloaded.CAFile = otherCAFile
+	reloaded.VerifyServerHostname = false
+	if err := s1.ReloadTLS(reloaded); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	tlsWrap, err := NewTLSWrapper(&config.TLSConfig{
+		EnableRPC: true,
+		CAFile:    fooCAFile,
+		CertFile:  fooClientCert,
+		KeyFile:   fooClientKey,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pool := NewPool(os.Stderr, time.Minute, 1, tlsWrap)
+	defer pool.Shutdown()
+	var out struct{}
+	if err := pool.RPC("global", s1.config.RPCAddr, structs.ApiMajorVersion, "Status.Ping", struct{}{}, &out); err == nil {
+		t.Fatalf("expected RPC with a certificate of the old CA to fail")
+	}
+}
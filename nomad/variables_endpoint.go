@@ -0,0 +1,287 @@
+package nomad
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/watch"
+)
+
+// Variables endpoint is used for storing and querying the encrypted
+// variables of namespaces
+type Variables struct {
+	srv *Server
+}
+
+// Upsert is used to create or update a variable. The items are encrypted
+// with the active root key before being committed via Raft.
+func (v *Variables) Upsert(args *structs.VariablesUpsertRequest,
+	reply *structs.VariablesUpsertResponse) error {
+	if done, err := v.srv.forward("Variables.Upsert", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "variables", "upsert"}, time.Now())
+
+	if args.Variable == nil {
+		return fmt.Errorf("missing variable for upsert")
+	}
+	variable := args.Variable.Copy()
+	variable.Namespace = args.RequestNamespace()
+	if err := variable.Validate(); err != nil {
+		return err
+	}
+
+	// Check for write permissions on the path
+	if err := v.checkACL(args.AuthToken, variable.Namespace, variable.Path, acl.VariablesCapabilityWrite); err != nil {
+		return err
+	}
+
+	snap, err := v.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	if ns, err := lookupNamespace(snap, variable.Namespace); err != nil {
+		return err
+	} else if ns == nil {
+		return fmt.Errorf("variable %q is in nonexistent namespace %q", variable.Path, variable.Namespace)
+	}
+
+	// Encrypt the variable with the active root key
+	key, err := v.srv.activeRootKey()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().UnixNano()
+	variable.CreateTime = now
+	variable.ModifyTime = now
+	encrypted, err := encryptVariable(v.srv.config.KeyringEncryptionKey, key, variable)
+	if err != nil {
+		return err
+	}
+
+	// Update via Raft
+	req := structs.VariablesEncryptedUpsertRequest{
+		Variable:     encrypted,
+		CheckIndex:   args.CheckIndex,
+		WriteRequest: args.WriteRequest,
+	}
+	resp, index, err := v.srv.raftApply(structs.VariablesUpsertRequestType, &req)
+	if err != nil {
+		v.srv.logger.Printf("[ERR] nomad.variables: Upsert failed: %v", err)
+		return err
+	}
+	if err, ok := resp.(error); ok && err != nil {
+		return err
+	}
+
+	// Return the metadata of the stored variable
+	stored, err := v.srv.fsm.State().VariableByPath(variable.Namespace, variable.Path)
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		meta := stored.VariableMetadata
+		reply.Variable = &meta
+	}
+	reply.Index = index
+	return nil
+}
+
+// Delete is used to delete a variable
+func (v *Variables) Delete(args *structs.VariablesDeleteRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := v.srv.forward("Variables.Delete", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "variables", "delete"}, time.Now())
+
+	if err := structs.ValidateVariablePath(args.Path); err != nil {
+		return err
+	}
+
+	// Check for destroy permissions on the path
+	if err := v.checkACL(args.AuthToken, args.RequestNamespace(), args.Path, acl.VariablesCapabilityDestroy); err != nil {
+		return err
+	}
+
+	// Update via Raft
+	resp, index, err := v.srv.raftApply(structs.VariablesDeleteRequestType, args)
+	if err != nil {
+		v.srv.logger.Printf("[ERR] nomad.variables: Delete failed: %v", err)
+		return err
+	}
+	if err, ok := resp.(error); ok && err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// Read is used to read and decrypt a variable
+func (v *Variables) Read(args *structs.VariablesReadRequest,
+	reply *structs.VariablesReadResponse) error {
+	if done, err := v.srv.forward("Variables.Read", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "variables", "read"}, time.Now())
+
+	// Check for read permissions on the path
+	namespace := args.RequestNamespace()
+	if err := v.checkACL(args.AuthToken, namespace, args.Path, acl.VariablesCapabilityRead); err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "variables"}),
+		run: func(snap *state.StateSnapshot) error {
+			encrypted, err := snap.VariableByPath(namespace, args.Path)
+			if err != nil {
+				return err
+			}
+
+			reply.Variable = nil
+			if encrypted != nil {
+				reply.Variable, err = decryptVariable(v.srv.config.KeyringEncryptionKey, snap, encrypted)
+				if err != nil {
+					return err
+				}
+			}
+
+			// Use the last index that affected the variables table
+			index, err := snap.Index("variables")
+			if err != nil {
+				return err
+			}
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			v.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return v.srv.blockingRPC(&opts)
+}
+
+// List is used to list the metadata of the variables whose path has the
+// requested prefix. Only the variables the token may list are returned.
+func (v *Variables) List(args *structs.VariablesListRequest,
+	reply *structs.VariablesListResponse) error {
+	if done, err := v.srv.forward("Variables.List", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "variables", "list"}, time.Now())
+
+	namespace := args.RequestNamespace()
+	allow, err := v.resolveACL(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "variables"}),
+		run: func(snap *state.StateSnapshot) error {
+			iter, err := snap.VariablesByPathPrefix(namespace, args.Prefix)
+			if err != nil {
+				return err
+			}
+
+			var variables []*structs.VariableMetadata
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				variable := raw.(*structs.VariableEncrypted)
+				if !allow(namespace, variable.Path, acl.VariablesCapabilityList) {
+					continue
+				}
+				meta := variable.VariableMetadata
+				variables = append(variables, &meta)
+			}
+			reply.Variables = variables
+
+			// Use the last index that affected the variables table
+			index, err := snap.Index("variables")
+			if err != nil {
+				return err
+			}
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			v.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return v.srv.blockingRPC(&opts)
+}
+
+// variableAuthorizer returns whether an operation is allowed on the variable
+// path of a namespace
+type variableAuthorizer func(namespace, path, op string) bool
+
+// resolveACL resolves the token of a request into an authorizer. The token
+// is either the secret ID of an ACL token or the workload identity of a
+// task, which may read and list the variables of its job.
+func (v *Variables) resolveACL(token string) (variableAuthorizer, error) {
+	// Fast-path if ACLs are disabled
+	if !v.srv.config.ACLEnabled {
+		return func(string, string, string) bool { return true }, nil
+	}
+
+	if isWorkloadIdentity(token) {
+		claims, err := v.srv.verifyWorkloadIdentity(token)
+		if err != nil {
+			return nil, err
+		}
+		paths := structs.WorkloadVariablePaths(claims)
+		return func(namespace, path, op string) bool {
+			if namespace != claims.Namespace {
+				return false
+			}
+			if op != acl.VariablesCapabilityRead && op != acl.VariablesCapabilityList {
+				return false
+			}
+			for _, p := range paths {
+				if path == p {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	aclObj, err := v.srv.ResolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return aclObj.AllowVariableOperation, nil
+}
+
+// checkACL ensures the token of a request allows the operation on the
+// variable path
+func (v *Variables) checkACL(token, namespace, path, op string) error {
+	allow, err := v.resolveACL(token)
+	if err != nil {
+		return err
+	}
+	if !allow(namespace, path, op) {
+		return structs.ErrPermissionDenied
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package nomad
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestVariablesEndpoint_UpsertReadDelete(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	variable := mock.Variable()
+	req := &structs.VariablesUpsertRequest{
+		Variable:     variable,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.VariablesUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Upsert", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 || resp.Variable == nil || resp.Variable.ModifyIndex != resp.Index {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The items are encrypted at rest with the root key
+	stored, err := s1.fsm.State().VariableByPath(structs.DefaultNamespace, variable.Path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	key, err := s1.fsm.State().ActiveRootKey()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if stored == nil || key == nil || stored.KeyID != key.KeyID {
+		t.Fatalf("bad: %#v", stored)
+	}
+	if bytes.Contains(stored.Data, []byte("hunter2")) {
+		t.Fatalf("variable stored in plain text")
+	}
+
+	// Read the decrypted variable
+	get := &structs.VariablesReadRequest{
+		Path:         variable.Path,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var getResp structs.VariablesReadResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Read", get, &getResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if getResp.Variable == nil || !reflect.DeepEqual(getResp.Variable.Items, variable.Items) {
+		t.Fatalf("bad: %#v", getResp.Variable)
+	}
+	if getResp.Variable.CreateTime == 0 || getResp.Variable.ModifyIndex != resp.Index {
+		t.Fatalf("bad: %#v", getResp.Variable)
+	}
+
+	// A stale check-and-set index conflicts
+	stale := uint64(0)
+	req.CheckIndex = &stale
+	err = msgpackrpc.CallWithCodec(codec, "Variables.Upsert", req, &resp)
+	if err == nil || err.Error() != structs.ErrCASConflict.Error() {
+		t.Fatalf("expected conflict: %v", err)
+	}
+
+	current := getResp.Variable.ModifyIndex
+	req.CheckIndex = &current
+	req.Variable.Items = structs.VariableItems{"password": "correcthorse"}
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Upsert", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Variable.CreateIndex != getResp.Variable.CreateIndex || resp.Variable.ModifyIndex == current {
+		t.Fatalf("bad: %#v", resp.Variable)
+	}
+
+	// Delete the variable with a check-and-set index
+	del := &structs.VariablesDeleteRequest{
+		Path:         variable.Path,
+		CheckIndex:   &current,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var delResp structs.GenericResponse
+	err = msgpackrpc.CallWithCodec(codec, "Variables.Delete", del, &delResp)
+	if err == nil || err.Error() != structs.ErrCASConflict.Error() {
+		t.Fatalf("expected conflict: %v", err)
+	}
+	del.CheckIndex = &resp.Variable.ModifyIndex
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Delete", del, &delResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var deletedResp structs.VariablesReadResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Read", get, &deletedResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if deletedResp.Variable != nil {
+		t.Fatalf("bad: %#v", deletedResp.Variable)
+	}
+}
+
+func TestVariablesEndpoint_WrappedRootKey(t *testing.T) {
+	kek := bytes.Repeat([]byte{1}, rootKeyBytes)
+	s1 := testServer(t, func(c *Config) {
+		c.KeyringEncryptionKey = kek
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	req := &structs.VariablesUpsertRequest{
+		Variable:     mock.Variable(),
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.VariablesUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Upsert", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The state only holds the root key wrapped with the keyring encryption
+	// key
+	key, err := s1.fsm.State().ActiveRootKey()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if key == nil {
+		t.Fatalf("missing root key")
+	}
+	material, err := unwrapRootKey(kek, key)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(material) != rootKeyBytes || bytes.Contains(key.WrappedKey, material) {
+		t.Fatalf("bad: %#v", key)
+	}
+	if _, err := unwrapRootKey(bytes.Repeat([]byte{2}, rootKeyBytes), key); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// Root keys can't be generated without a keyring encryption key
+	if _, err := generateRootKey(nil); err != errNoKeyringEncryptionKey {
+		t.Fatalf("expected missing key error: %v", err)
+	}
+}
+
+func TestVariablesEndpoint_Upsert_Invalid(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	for _, path := range []string{"", "/project", "project//db", "nomad/secret", "project db"} {
+		variable := mock.Variable()
+		variable.Path = path
+		req := &structs.VariablesUpsertRequest{
+			Variable:     variable,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.VariablesUpsertResponse
+		if err := msgpackrpc.CallWithCodec(codec, "Variables.Upsert", req, &resp); err == nil {
+			t.Fatalf("expected error for path %q", path)
+		}
+	}
+
+	// The namespace must exist
+	req := &structs.VariablesUpsertRequest{
+		Variable:     mock.Variable(),
+		WriteRequest: structs.WriteRequest{Region: "global", Namespace: "missing"},
+	}
+	var resp structs.VariablesUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Upsert", req, &resp); err == nil {
+		t.Fatalf("expected error for nonexistent namespace")
+	}
+}
+
+func TestVariablesEndpoint_List(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	for _, path := range []string{"project/db", "project/web", "other"} {
+		variable := mock.Variable()
+		variable.Path = path
+		req := &structs.VariablesUpsertRequest{
+			Variable:     variable,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.VariablesUpsertResponse
+		if err := msgpackrpc.CallWithCodec(codec, "Variables.Upsert", req, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	list := &structs.VariablesListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global", Prefix: "project/"},
+	}
+	var resp structs.VariablesListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.List", list, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Variables) != 2 || resp.Variables[0].Path != "project/db" || resp.Variables[1].Path != "project/web" {
+		t.Fatalf("bad: %#v", resp.Variables)
+	}
+
+	list.Prefix = ""
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.List", list, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Variables) != 3 {
+		t.Fatalf("bad: %#v", resp.Variables)
+	}
+}
+
+func TestVariablesEndpoint_ACL(t *testing.T) {
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+
+	// Create a token that may only read the project variables
+	policy := mock.ACLPolicy()
+	policy.Rules = `
+	namespace "default" {
+		variables {
+			path "project/*" {
+				capabilities = ["read", "list"]
+			}
+		}
+	}`
+	if err := state.UpsertACLPolicies(1000, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token := mock.ACLToken()
+	token.Policies = []string{policy.Name}
+	if err := state.UpsertACLTokens(1001, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Anonymous writes are denied
+	variable := mock.Variable()
+	req := &structs.VariablesUpsertRequest{
+		Variable:     variable,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.VariablesUpsertResponse
+	err := msgpackrpc.CallWithCodec(codec, "Variables.Upsert", req, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	req.AuthToken = token.SecretID
+	err = msgpackrpc.CallWithCodec(codec, "Variables.Upsert", req, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	req.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Upsert", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The token may read the project variables
+	get := &structs.VariablesReadRequest{
+		Path:         variable.Path,
+		QueryOptions: structs.QueryOptions{Region: "global", AuthToken: token.SecretID},
+	}
+	var getResp structs.VariablesReadResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Read", get, &getResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if getResp.Variable == nil {
+		t.Fatalf("missing variable")
+	}
+
+	// Create a variable of a job and an allocation of the job with a
+	// workload identity
+	alloc := mock.Alloc()
+	if err := s1.signAllocIdentities(alloc.Job, alloc, time.Now()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJobSummary(1002, mock.JobSummary(alloc.JobID)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertAllocs(1003, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	jobVar := mock.Variable()
+	jobVar.Path = "nomad/jobs/" + alloc.JobID + "/web/web"
+	req.Variable = jobVar
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Upsert", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The task may read and list the variables of its job
+	identity := alloc.SignedIdentities["web"]
+	get.Path = jobVar.Path
+	get.AuthToken = identity
+	var jobResp structs.VariablesReadResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Read", get, &jobResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if jobResp.Variable == nil || jobResp.Variable.Items["username"] != "admin" {
+		t.Fatalf("bad: %#v", jobResp.Variable)
+	}
+
+	list := &structs.VariablesListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global", AuthToken: identity},
+	}
+	var listResp structs.VariablesListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.List", list, &listResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(listResp.Variables) != 1 || listResp.Variables[0].Path != jobVar.Path {
+		t.Fatalf("bad: %#v", listResp.Variables)
+	}
+
+	// But no other variables, and it may not write
+	get.Path = variable.Path
+	err = msgpackrpc.CallWithCodec(codec, "Variables.Read", get, &getResp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+	req.AuthToken = identity
+	err = msgpackrpc.CallWithCodec(codec, "Variables.Upsert", req, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// Identities of terminal allocations are rejected
+	stopped := alloc.Copy()
+	stopped.DesiredStatus = structs.AllocDesiredStatusStop
+	if err := state.UpsertAllocs(1004, []*structs.Allocation{stopped}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	get.Path = jobVar.Path
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Read", get, &getResp); err == nil {
+		t.Fatalf("expected error for terminal allocation")
+	}
+}
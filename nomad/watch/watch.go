@@ -14,6 +14,7 @@ type Item struct {
 	AllocJob   string
 	AllocNode  string
 	Eval       string
+	EvalJob    string
 	Job        string
 	JobSummary string
 	Node       string
@@ -37,3 +38,39 @@ func NewItems(items ...Item) Items {
 func (wi Items) Add(i Item) {
 	wi[i] = struct{}{}
 }
+
+// Tables returns the table items covering the given item, which are notified
+// of every change the item is notified of.
+func (i Item) Tables() []Item {
+	switch {
+	case i.Alloc != "", i.AllocEval != "", i.AllocJob != "", i.AllocNode != "":
+		return []Item{{Table: "allocs"}}
+	case i.Eval != "", i.EvalJob != "":
+		return []Item{{Table: "evals"}}
+	case i.Job != "":
+		return []Item{{Table: "jobs"}, {Table: "periodic_launch"}}
+	case i.JobSummary != "":
+		return []Item{{Table: "job_summary"}}
+	case i.Node != "":
+		return []Item{{Table: "nodes"}}
+	default:
+		return []Item{i}
+	}
+}
+
+// Bound returns the items if there are at most max of them, and otherwise
+// the table items covering them. Watching a table fires more often than
+// watching its objects, but bounds the number of notify groups a single
+// query subscribes to, no matter how many objects it looks up.
+func (wi Items) Bound(max int) Items {
+	if len(wi) <= max {
+		return wi
+	}
+	bounded := make(Items)
+	for item := range wi {
+		for _, table := range item.Tables() {
+			bounded.Add(table)
+		}
+	}
+	return bounded
+}
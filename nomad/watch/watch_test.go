@@ -1,6 +1,7 @@
 package watch
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -29,3 +30,18 @@ func TestWatchItems(t *testing.T) {
 		t.Fatalf("expected 2 items, got: %#v", wi)
 	}
 }
+
+func TestWatchItems_Bound(t *testing.T) {
+	wi := NewItems(Item{Alloc: "foo"}, Item{AllocNode: "bar"}, Item{Table: "nodes"})
+
+	// Items within the bound are returned as is
+	if out := wi.Bound(3); !reflect.DeepEqual(out, wi) {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Items exceeding the bound are replaced by their tables
+	expected := NewItems(Item{Table: "allocs"}, Item{Table: "nodes"})
+	if out := wi.Bound(2); !reflect.DeepEqual(out, expected) {
+		t.Fatalf("bad: %#v", out)
+	}
+}
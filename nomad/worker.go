@@ -64,6 +64,13 @@ type Worker struct {
 	// first envoked. It is used to mark the SnapshotIndex of evaluations
 	// Created, Updated or Reblocked.
 	snapshotIndex uint64
+
+	// timing is the time spent in the phases of processing the current
+	// evaluation. It is attached to the evaluation when it is updated.
+	timing structs.EvalTiming
+
+	// dequeued is when the current evaluation was dequeued
+	dequeued time.Time
 }
 
 // NewWorker starts a new worker associated with the given server
@@ -113,7 +120,10 @@ func (w *Worker) run() {
 		}
 
 		// Wait for the the raft log to catchup to the evaluation
-		if err := w.waitForIndex(eval.ModifyIndex, raftSyncLimit); err != nil {
+		start := time.Now()
+		err := w.waitForIndex(eval.ModifyIndex, raftSyncLimit)
+		w.timing.Snapshot += time.Since(start)
+		if err != nil {
 			w.sendAck(eval.ID, token, false)
 			continue
 		}
@@ -123,6 +133,7 @@ func (w *Worker) run() {
 			w.sendAck(eval.ID, token, false)
 			continue
 		}
+		w.emitTiming(eval.Type)
 
 		// Complete the evaluation
 		w.sendAck(eval.ID, token, true)
@@ -134,8 +145,7 @@ func (w *Worker) run() {
 func (w *Worker) dequeueEvaluation(timeout time.Duration) (*structs.Evaluation, string, bool) {
 	// Setup the request
 	req := structs.EvalDequeueRequest{
-		Schedulers: w.srv.config.EnabledSchedulers,
-		Timeout:    timeout,
+		Timeout: timeout,
 		WriteRequest: structs.WriteRequest{
 			Region: w.srv.config.Region,
 		},
@@ -146,6 +156,18 @@ REQ:
 	// Check if we are paused
 	w.checkPaused()
 
+	// Only dequeue the evaluations of the core scheduler while scheduling is
+	// paused by the operators
+	req.Schedulers = w.enabledSchedulers()
+	if len(req.Schedulers) == 0 {
+		select {
+		case <-time.After(timeout):
+		case <-w.srv.shutdownCh:
+			return nil, "", true
+		}
+		goto REQ
+	}
+
 	// Make a blocking RPC
 	start := time.Now()
 	err := w.srv.RPC("Eval.Dequeue", &req, &resp)
@@ -164,6 +186,8 @@ REQ:
 	// Check if we got a response
 	if resp.Eval != nil {
 		w.logger.Printf("[DEBUG] worker: dequeued evaluation %s", resp.Eval.ID)
+		w.timing = structs.EvalTiming{QueueWait: resp.QueueWait}
+		w.dequeued = time.Now()
 		return resp.Eval, resp.Token, false
 	}
 
@@ -174,6 +198,27 @@ REQ:
 	goto REQ
 }
 
+// enabledSchedulers returns the schedulers to dequeue evaluations for. While
+// scheduling is paused by the operators, evaluations of jobs stay queued and
+// only those of the core scheduler are processed, so that garbage collection
+// keeps running.
+func (w *Worker) enabledSchedulers() []string {
+	_, config, err := w.srv.fsm.State().SchedulerConfig()
+	if err != nil {
+		w.logger.Printf("[ERR] worker: failed to get scheduler configuration: %v", err)
+	}
+	if config == nil || !config.SchedulingPaused {
+		return w.srv.config.EnabledSchedulers
+	}
+
+	for _, sched := range w.srv.config.EnabledSchedulers {
+		if sched == structs.JobTypeCore {
+			return []string{structs.JobTypeCore}
+		}
+	}
+	return nil
+}
+
 // sendAck makes a best effort to ack or nack the evaluation.
 // Any errors are logged but swallowed.
 func (w *Worker) sendAck(evalID, token string, ack bool) {
@@ -250,7 +295,9 @@ func (w *Worker) invokeScheduler(eval *structs.Evaluation, token string) error {
 	w.evalToken = token
 
 	// Snapshot the current state
+	start := time.Now()
 	snap, err := w.srv.fsm.State().Snapshot()
+	w.timing.Snapshot += time.Since(start)
 	if err != nil {
 		return fmt.Errorf("failed to snapshot state: %v", err)
 	}
@@ -266,7 +313,7 @@ func (w *Worker) invokeScheduler(eval *structs.Evaluation, token string) error {
 	if eval.Type == structs.JobTypeCore {
 		sched = NewCoreScheduler(w.srv, snap)
 	} else {
-		sched, err = scheduler.NewScheduler(eval.Type, w.logger, snap, w)
+		sched, err = w.srv.config.SchedulerMapping.NewScheduler(eval.Type, w.logger, snap, w)
 		if err != nil {
 			return fmt.Errorf("failed to instantiate scheduler: %v", err)
 		}
@@ -288,6 +335,9 @@ func (w *Worker) SubmitPlan(plan *structs.Plan) (*structs.PlanResult, scheduler.
 		return nil, nil, fmt.Errorf("shutdown while planning")
 	}
 	defer metrics.MeasureSince([]string{"nomad", "worker", "submit_plan"}, time.Now())
+	if !w.dequeued.IsZero() {
+		metrics.MeasureSince([]string{"nomad", "worker", "dequeue_to_plan"}, w.dequeued)
+	}
 
 	// Add the evaluation token to the plan
 	plan.EvalToken = w.evalToken
@@ -300,6 +350,7 @@ func (w *Worker) SubmitPlan(plan *structs.Plan) (*structs.PlanResult, scheduler.
 		},
 	}
 	var resp structs.PlanResponse
+	start := time.Now()
 
 SUBMIT:
 	// Make the RPC call
@@ -320,6 +371,8 @@ SUBMIT:
 	if result == nil {
 		return nil, nil, fmt.Errorf("missing result")
 	}
+	w.timing.PlanSubmit += time.Since(start)
+	w.timing.RaftApply += result.RaftApply
 
 	// Check if a state update is required. This could be required if we
 	// planning based on stale data, which is causing issues. For example, a
@@ -329,6 +382,7 @@ SUBMIT:
 	if result.RefreshIndex != 0 {
 		// Wait for the the raft log to catchup to the evaluation
 		w.logger.Printf("[DEBUG] worker: refreshing state to index %d for %q", result.RefreshIndex, plan.EvalID)
+		start := time.Now()
 		if err := w.waitForIndex(result.RefreshIndex, raftSyncLimit); err != nil {
 			return nil, nil, err
 		}
@@ -338,6 +392,7 @@ SUBMIT:
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to snapshot state: %v", err)
 		}
+		w.timing.Snapshot += time.Since(start)
 		state = snap
 	}
 
@@ -354,8 +409,9 @@ func (w *Worker) UpdateEval(eval *structs.Evaluation) error {
 	}
 	defer metrics.MeasureSince([]string{"nomad", "worker", "update_eval"}, time.Now())
 
-	// Store the snapshot index in the eval
+	// Store the snapshot index and timing in the eval
 	eval.SnapshotIndex = w.snapshotIndex
+	w.setTiming(eval)
 
 	// Setup the request
 	req := structs.EvalUpdateRequest{
@@ -432,7 +488,7 @@ func (w *Worker) ReblockEval(eval *structs.Evaluation) error {
 
 	// Update the evaluation if the queued jobs is not same as what is
 	// recorded in the job summary
-	summary, err := w.srv.fsm.state.JobSummaryByID(eval.JobID)
+	summary, err := w.srv.fsm.state.JobSummaryByID(eval.Namespace, eval.JobID)
 	if err != nil {
 		return fmt.Errorf("couldn't retreive job summary: %v", err)
 	}
@@ -453,8 +509,9 @@ func (w *Worker) ReblockEval(eval *structs.Evaluation) error {
 		}
 	}
 
-	// Store the snapshot index in the eval
+	// Store the snapshot index and timing in the eval
 	eval.SnapshotIndex = w.snapshotIndex
+	w.setTiming(eval)
 
 	// Setup the request
 	req := structs.EvalUpdateRequest{
@@ -482,6 +539,37 @@ SUBMIT:
 	return nil
 }
 
+// setTiming attaches the time spent processing the evaluation to it. The time
+// spent checking feasibility and ranking nodes is reported by the scheduler on
+// the evaluation, while the worker tracks the other phases.
+func (w *Worker) setTiming(eval *structs.Evaluation) {
+	if eval.Timing != nil {
+		w.timing.Feasibility = eval.Timing.Feasibility
+		w.timing.Ranking = eval.Timing.Ranking
+	}
+	timing := w.timing
+	eval.Timing = &timing
+}
+
+// emitTiming emits the time spent in each phase of processing the evaluation
+func (w *Worker) emitTiming(evalType string) {
+	phases := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"queue_wait", w.timing.QueueWait},
+		{"snapshot", w.timing.Snapshot},
+		{"feasibility", w.timing.Feasibility},
+		{"ranking", w.timing.Ranking},
+		{"plan_submit", w.timing.PlanSubmit},
+		{"raft_apply", w.timing.RaftApply},
+	}
+	for _, phase := range phases {
+		ms := float32(phase.duration) / float32(time.Millisecond)
+		metrics.AddSample([]string{"nomad", "worker", "eval_timing", phase.name, evalType}, ms)
+	}
+}
+
 // shouldResubmit checks if a given error should be swallowed and the plan
 // resubmitted after a backoff. Usually these are transient errors that
 // the cluster should heal from quickly.
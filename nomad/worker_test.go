@@ -115,6 +115,60 @@ func TestWorker_dequeueEvaluation_paused(t *testing.T) {
 	}
 }
 
+func TestWorker_dequeueEvaluation_SchedulingPaused(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0
+		c.EnabledSchedulers = []string{structs.JobTypeService, structs.JobTypeCore}
+	})
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Pause scheduling
+	config := structs.DefaultSchedulerConfiguration()
+	config.SchedulingPaused = true
+	if err := s1.fsm.State().SchedulerSetConfig(1000, config); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create the evaluations
+	eval1 := mock.Eval()
+	s1.evalBroker.Enqueue(eval1)
+	eval2 := mock.Eval()
+	eval2.Type = structs.JobTypeCore
+	s1.evalBroker.Enqueue(eval2)
+
+	// Create a worker
+	w := &Worker{srv: s1, logger: s1.logger}
+	w.pauseCond = sync.NewCond(&w.pauseLock)
+
+	// Only the core evaluation is dequeued
+	eval, _, shutdown := w.dequeueEvaluation(10 * time.Millisecond)
+	if shutdown {
+		t.Fatalf("should not shutdown")
+	}
+	if eval == nil || eval.ID != eval2.ID {
+		t.Fatalf("bad: %#v", eval)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		s1.fsm.State().SchedulerSetConfig(1001, structs.DefaultSchedulerConfiguration())
+	}()
+
+	// The job evaluation is dequeued once scheduling resumes
+	start := time.Now()
+	eval, _, shutdown = w.dequeueEvaluation(10 * time.Millisecond)
+	if diff := time.Since(start); diff < 100*time.Millisecond {
+		t.Fatalf("should have paused: %v", diff)
+	}
+	if shutdown {
+		t.Fatalf("should not shutdown")
+	}
+	if eval == nil || eval.ID != eval1.ID {
+		t.Fatalf("bad: %#v", eval)
+	}
+}
+
 func TestWorker_dequeueEvaluation_shutdown(t *testing.T) {
 	s1 := testServer(t, func(c *Config) {
 		c.NumSchedulers = 0
@@ -389,9 +443,11 @@ func TestWorker_UpdateEval(t *testing.T) {
 
 	eval2 := evalOut.Copy()
 	eval2.Status = structs.EvalStatusComplete
+	eval2.Timing = &structs.EvalTiming{Feasibility: time.Second, Ranking: time.Second}
 
 	// Attempt to update eval
 	w := &Worker{srv: s1, logger: s1.logger, evalToken: token}
+	w.timing = structs.EvalTiming{QueueWait: time.Second, PlanSubmit: time.Second}
 	err = w.UpdateEval(eval2)
 	if err != nil {
 		t.Fatalf("err: %v", err)
@@ -407,6 +463,17 @@ func TestWorker_UpdateEval(t *testing.T) {
 	if out.SnapshotIndex != w.snapshotIndex {
 		t.Fatalf("bad: %v", out)
 	}
+
+	// The scheduler's timing is merged with the worker's
+	expected := &structs.EvalTiming{
+		QueueWait:   time.Second,
+		Feasibility: time.Second,
+		Ranking:     time.Second,
+		PlanSubmit:  time.Second,
+	}
+	if !reflect.DeepEqual(out.Timing, expected) {
+		t.Fatalf("bad: %#v", out.Timing)
+	}
 }
 
 func TestWorker_CreateEval(t *testing.T) {
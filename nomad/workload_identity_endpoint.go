@@ -0,0 +1,68 @@
+package nomad
+
+import (
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/watch"
+)
+
+// WorkloadIdentity endpoint is used for querying the keys workload identities
+// are signed with
+type WorkloadIdentity struct {
+	srv *Server
+}
+
+// ListPublicKeys is used to list the public keys that verify workload
+// identities. The public keys are not sensitive, so no ACL check is done.
+func (w *WorkloadIdentity) ListPublicKeys(args *structs.WorkloadIdentityPublicKeysRequest,
+	reply *structs.WorkloadIdentityPublicKeysResponse) error {
+	if done, err := w.srv.forward("WorkloadIdentity.ListPublicKeys", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "workload_identity", "list_public_keys"}, time.Now())
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		watch:     watch.NewItems(watch.Item{Table: "identity_keys"}),
+		run: func(snap *state.StateSnapshot) error {
+			// Capture all the keys
+			iter, err := snap.WorkloadIdentityKeys()
+			if err != nil {
+				return err
+			}
+
+			var keys []*structs.WorkloadIdentityPublicKey
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				keys = append(keys, raw.(*structs.WorkloadIdentityKey).PublicKeyStub())
+			}
+			reply.Keys = keys
+
+			// Use the last index that affected the key table
+			index, err := snap.Index("identity_keys")
+			if err != nil {
+				return err
+			}
+
+			// Ensure we never set the index to zero, otherwise a blocking query
+			// cannot be used. We floor the index at one, since realistically
+			// the first write must have a higher index.
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+
+			// Set the query response
+			w.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return w.srv.blockingRPC(&opts)
+}
@@ -0,0 +1,44 @@
+package nomad
+
+import (
+	"testing"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestWorkloadIdentityEndpoint_ListPublicKeys(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	signer, err := s1.workloadIdentitySigner()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	key, err := s1.fsm.State().WorkloadIdentityKeyByID(signer.keyID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.WorkloadIdentityPublicKeysRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.WorkloadIdentityPublicKeysResponse
+	if err := msgpackrpc.CallWithCodec(codec, "WorkloadIdentity.ListPublicKeys", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != key.ModifyIndex {
+		t.Fatalf("Bad index: %d %d", resp.Index, key.ModifyIndex)
+	}
+	if len(resp.Keys) != 1 {
+		t.Fatalf("bad: %#v", resp.Keys)
+	}
+	out := resp.Keys[0]
+	if out.KeyID != key.KeyID || out.Algorithm != structs.WorkloadIdentityAlgorithm ||
+		string(out.PublicKey) != string(key.PublicKey) {
+		t.Fatalf("bad: %#v", out)
+	}
+}
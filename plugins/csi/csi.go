@@ -0,0 +1,320 @@
+// Package csi is the SDK for writing storage plugins following the Container
+// Storage Interface (CSI). A CSI plugin runs as a task of a job with a
+// csi_plugin block, and serves a Plugin implementation on a unix socket in
+// the directory the Nomad client mounts into the task. The controller
+// service of a plugin creates volumes and attaches them to nodes, while its
+// node service mounts the volumes attached to a node for the tasks using
+// them.
+package csi
+
+import "errors"
+
+const (
+	// SocketName is the name of the socket a plugin serves on in its mount
+	// directory
+	SocketName = "csi.sock"
+)
+
+// ErrNotSupported is returned by plugins for the calls of the services they
+// don't provide
+var ErrNotSupported = errors.New("CSI operation not supported by the plugin")
+
+// Plugin is implemented by CSI plugins. Controller plugins implement the
+// identity and controller calls, node plugins the identity and node calls,
+// and monolith plugins all of them. Calls of the services a plugin doesn't
+// provide return ErrNotSupported.
+type Plugin interface {
+	// PluginInfo returns the name, version and capabilities of the plugin
+	PluginInfo() (*PluginInfo, error)
+
+	// Probe returns an error if the plugin isn't ready to serve requests
+	Probe() error
+
+	// ControllerCreateVolume provisions a new volume, empty or from a
+	// snapshot
+	ControllerCreateVolume(req *ControllerCreateVolumeRequest) (*Volume, error)
+
+	// ControllerDeleteVolume deprovisions a volume
+	ControllerDeleteVolume(req *ControllerDeleteVolumeRequest) error
+
+	// ControllerExpandVolume grows the capacity of a volume. The response
+	// tells whether the node plugins of the nodes the volume is published
+	// on must also expand it, such as to grow its file system.
+	ControllerExpandVolume(req *ControllerExpandVolumeRequest) (*ControllerExpandVolumeResponse, error)
+
+	// ControllerCreateSnapshot takes a snapshot of a volume
+	ControllerCreateSnapshot(req *ControllerCreateSnapshotRequest) (*Snapshot, error)
+
+	// ControllerDeleteSnapshot deletes a snapshot
+	ControllerDeleteSnapshot(req *ControllerDeleteSnapshotRequest) error
+
+	// ControllerListSnapshots lists the snapshots of the storage provider,
+	// a page at a time
+	ControllerListSnapshots(req *ControllerListSnapshotsRequest) (*ControllerListSnapshotsResponse, error)
+
+	// ControllerPublishVolume makes the volume available on the node, such
+	// as by attaching a disk to a VM. The returned publish context is passed
+	// to the node calls of the volume.
+	ControllerPublishVolume(req *ControllerPublishVolumeRequest) (*ControllerPublishVolumeResponse, error)
+
+	// ControllerUnpublishVolume undoes ControllerPublishVolume
+	ControllerUnpublishVolume(req *ControllerUnpublishVolumeRequest) error
+
+	// NodeGetInfo returns the ID of the node used by the controller plugin
+	// and the topology the node has access to
+	NodeGetInfo() (*NodeInfo, error)
+
+	// NodeStageVolume mounts the volume at the staging path of the node,
+	// once per node. It is only called if the plugin supports staging.
+	NodeStageVolume(req *NodeStageVolumeRequest) error
+
+	// NodeUnstageVolume undoes NodeStageVolume
+	NodeUnstageVolume(req *NodeUnstageVolumeRequest) error
+
+	// NodePublishVolume mounts the volume at the target path of an
+	// allocation
+	NodePublishVolume(req *NodePublishVolumeRequest) error
+
+	// NodeUnpublishVolume undoes NodePublishVolume
+	NodeUnpublishVolume(req *NodeUnpublishVolumeRequest) error
+
+	// NodeExpandVolume grows the volume published on the node after the
+	// controller expanded it
+	NodeExpandVolume(req *NodeExpandVolumeRequest) (*NodeExpandVolumeResponse, error)
+}
+
+// PluginInfo describes a plugin and the optional capabilities it supports
+type PluginInfo struct {
+	Name    string
+	Version string
+
+	// ControllerService is set if the plugin provides the controller calls
+	ControllerService bool
+
+	// VolumeAccessibilityConstraints is set if volumes are only accessible
+	// from the nodes of some topologies
+	VolumeAccessibilityConstraints bool
+
+	// PublishUnpublishVolume is set if volumes must be published by the
+	// controller before they are used on a node
+	PublishUnpublishVolume bool
+
+	// StageUnstageVolume is set if volumes must be staged on a node before
+	// they are published
+	StageUnstageVolume bool
+}
+
+// Topology is a set of segments, such as a zone or a rack, identifying where
+// nodes are and where volumes are accessible from
+type Topology struct {
+	Segments map[string]string
+}
+
+// VolumeCapability is how a volume is used
+type VolumeCapability struct {
+	// AccessMode is one of the access modes of volumes, such as
+	// single-node-writer
+	AccessMode string
+
+	// AttachmentMode is either block-device or file-system
+	AttachmentMode string
+
+	// FSType and MountFlags are the options of file-system mounts
+	FSType     string
+	MountFlags []string
+}
+
+// Volume is a volume provisioned by a plugin
+type Volume struct {
+	// ExternalID is the ID of the volume in the storage provider
+	ExternalID    string
+	CapacityBytes int64
+
+	// Context is passed to the calls of the volume
+	Context map[string]string
+
+	// Topologies are the topologies the volume is accessible from
+	Topologies []*Topology
+}
+
+// ControllerCreateVolumeRequest is a request to provision a volume
+type ControllerCreateVolumeRequest struct {
+	Name         string
+	CapacityMin  int64
+	CapacityMax  int64
+	Capabilities []*VolumeCapability
+	Parameters   map[string]string
+	Secrets      map[string]string
+
+	// Topologies are the topologies the volume should be accessible from
+	Topologies []*Topology
+
+	// SnapshotID is the ID of the snapshot the volume is created from, if
+	// any
+	SnapshotID string
+}
+
+// ControllerDeleteVolumeRequest is a request to deprovision a volume
+type ControllerDeleteVolumeRequest struct {
+	ExternalID string
+	Secrets    map[string]string
+}
+
+// ControllerExpandVolumeRequest is a request to grow a volume to a capacity
+// between the bounds, in bytes
+type ControllerExpandVolumeRequest struct {
+	ExternalID  string
+	CapacityMin int64
+	CapacityMax int64
+	Capability  *VolumeCapability
+	Secrets     map[string]string
+}
+
+// ControllerExpandVolumeResponse is the response to an expand request
+type ControllerExpandVolumeResponse struct {
+	CapacityBytes int64
+
+	// NodeExpansionRequired is set if the volume must also be expanded by
+	// the node plugins of the nodes it is published on
+	NodeExpansionRequired bool
+}
+
+// Snapshot is a snapshot of a volume taken by a plugin
+type Snapshot struct {
+	// ID is the ID of the snapshot in the storage provider
+	ID string
+
+	// SourceVolumeID is the external ID of the volume the snapshot was
+	// taken of
+	SourceVolumeID string
+
+	SizeBytes int64
+
+	// CreateTime is when the snapshot was taken, in nanoseconds since the
+	// Unix epoch
+	CreateTime int64
+
+	// ReadyToUse is set once volumes can be created from the snapshot
+	ReadyToUse bool
+}
+
+// ControllerCreateSnapshotRequest is a request to snapshot a volume
+type ControllerCreateSnapshotRequest struct {
+	SourceVolumeID string
+	Name           string
+	Parameters     map[string]string
+	Secrets        map[string]string
+}
+
+// ControllerDeleteSnapshotRequest is a request to delete a snapshot
+type ControllerDeleteSnapshotRequest struct {
+	SnapshotID string
+	Secrets    map[string]string
+}
+
+// ControllerListSnapshotsRequest is a request to list snapshots, optionally
+// only those of a volume. At most MaxEntries snapshots are returned if set,
+// starting from the NextToken of a previous response.
+type ControllerListSnapshotsRequest struct {
+	SourceVolumeID string
+	MaxEntries     int
+	StartingToken  string
+	Secrets        map[string]string
+}
+
+// ControllerListSnapshotsResponse is a page of snapshots. NextToken is empty
+// on the last page.
+type ControllerListSnapshotsResponse struct {
+	Snapshots []*Snapshot
+	NextToken string
+}
+
+// ControllerPublishVolumeRequest is a request to publish a volume on a node
+type ControllerPublishVolumeRequest struct {
+	ExternalID string
+	NodeID     string
+	Capability *VolumeCapability
+	ReadOnly   bool
+	Secrets    map[string]string
+	Context    map[string]string
+}
+
+// ControllerPublishVolumeResponse is the response to a publish request
+type ControllerPublishVolumeResponse struct {
+	PublishContext map[string]string
+}
+
+// ControllerUnpublishVolumeRequest is a request to unpublish a volume from a
+// node
+type ControllerUnpublishVolumeRequest struct {
+	ExternalID string
+	NodeID     string
+	Secrets    map[string]string
+}
+
+// NodeInfo describes the node of a node plugin
+type NodeInfo struct {
+	// NodeID is the ID of the node in the storage provider
+	NodeID string
+
+	// MaxVolumes is the maximum number of volumes that can be published on
+	// the node, zero if unlimited
+	MaxVolumes int64
+
+	// AccessibleTopology is the topology of the node
+	AccessibleTopology *Topology
+}
+
+// NodeStageVolumeRequest is a request to stage a volume on the node
+type NodeStageVolumeRequest struct {
+	ExternalID     string
+	PublishContext map[string]string
+	StagingPath    string
+	Capability     *VolumeCapability
+	Secrets        map[string]string
+	Context        map[string]string
+}
+
+// NodeUnstageVolumeRequest is a request to unstage a volume from the node
+type NodeUnstageVolumeRequest struct {
+	ExternalID  string
+	StagingPath string
+}
+
+// NodePublishVolumeRequest is a request to mount a volume at a target path
+// of the node. The staging path is empty if the plugin doesn't stage
+// volumes.
+type NodePublishVolumeRequest struct {
+	ExternalID     string
+	PublishContext map[string]string
+	StagingPath    string
+	TargetPath     string
+	Capability     *VolumeCapability
+	ReadOnly       bool
+	Secrets        map[string]string
+	Context        map[string]string
+}
+
+// NodeUnpublishVolumeRequest is a request to unmount a volume from a target
+// path of the node
+type NodeUnpublishVolumeRequest struct {
+	ExternalID string
+	TargetPath string
+}
+
+// NodeExpandVolumeRequest is a request to grow a volume published on the
+// node at the volume path. The staging path is empty if the plugin doesn't
+// stage volumes.
+type NodeExpandVolumeRequest struct {
+	ExternalID  string
+	VolumePath  string
+	StagingPath string
+	CapacityMin int64
+	CapacityMax int64
+	Capability  *VolumeCapability
+}
+
+// NodeExpandVolumeResponse is the response to a node expand request
+type NodeExpandVolumeResponse struct {
+	CapacityBytes int64
+}
@@ -0,0 +1,289 @@
+package csi
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"os"
+)
+
+// Serve serves the plugin on a unix socket at the path, which is usually
+// SocketName in the mount directory of the plugin. It is meant to be called
+// from the main function of the plugin and only returns if serving fails.
+func Serve(p Plugin, socketPath string) error {
+	// Remove the socket of a previous run of the plugin
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("Plugin", &pluginRPCServer{Impl: p}); err != nil {
+		return err
+	}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go server.ServeConn(conn)
+	}
+}
+
+// Client is the client side of a plugin served on a unix socket
+type Client struct {
+	client *rpc.Client
+}
+
+// NewClient connects to the plugin served on the unix socket at the path
+func NewClient(socketPath string) (*Client, error) {
+	client, err := rpc.Dial("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{client: client}, nil
+}
+
+// Close closes the connection to the plugin
+func (c *Client) Close() error {
+	return c.client.Close()
+}
+
+// call calls the method of the plugin, restoring ErrNotSupported
+func (c *Client) call(method string, args, reply interface{}) error {
+	err := c.client.Call("Plugin."+method, args, reply)
+	if err != nil && err.Error() == ErrNotSupported.Error() {
+		return ErrNotSupported
+	}
+	return err
+}
+
+func (c *Client) PluginInfo() (*PluginInfo, error) {
+	var info PluginInfo
+	if err := c.call("PluginInfo", new(interface{}), &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) Probe() error {
+	return c.call("Probe", new(interface{}), new(interface{}))
+}
+
+func (c *Client) ControllerCreateVolume(req *ControllerCreateVolumeRequest) (*Volume, error) {
+	var vol Volume
+	if err := c.call("ControllerCreateVolume", req, &vol); err != nil {
+		return nil, err
+	}
+	return &vol, nil
+}
+
+func (c *Client) ControllerDeleteVolume(req *ControllerDeleteVolumeRequest) error {
+	return c.call("ControllerDeleteVolume", req, new(interface{}))
+}
+
+func (c *Client) ControllerExpandVolume(req *ControllerExpandVolumeRequest) (*ControllerExpandVolumeResponse, error) {
+	var resp ControllerExpandVolumeResponse
+	if err := c.call("ControllerExpandVolume", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) ControllerCreateSnapshot(req *ControllerCreateSnapshotRequest) (*Snapshot, error) {
+	var snap Snapshot
+	if err := c.call("ControllerCreateSnapshot", req, &snap); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+func (c *Client) ControllerDeleteSnapshot(req *ControllerDeleteSnapshotRequest) error {
+	return c.call("ControllerDeleteSnapshot", req, new(interface{}))
+}
+
+func (c *Client) ControllerListSnapshots(req *ControllerListSnapshotsRequest) (*ControllerListSnapshotsResponse, error) {
+	var resp ControllerListSnapshotsResponse
+	if err := c.call("ControllerListSnapshots", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) ControllerPublishVolume(req *ControllerPublishVolumeRequest) (*ControllerPublishVolumeResponse, error) {
+	var resp ControllerPublishVolumeResponse
+	if err := c.call("ControllerPublishVolume", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) ControllerUnpublishVolume(req *ControllerUnpublishVolumeRequest) error {
+	return c.call("ControllerUnpublishVolume", req, new(interface{}))
+}
+
+func (c *Client) NodeGetInfo() (*NodeInfo, error) {
+	var info NodeInfo
+	if err := c.call("NodeGetInfo", new(interface{}), &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) NodeStageVolume(req *NodeStageVolumeRequest) error {
+	return c.call("NodeStageVolume", req, new(interface{}))
+}
+
+func (c *Client) NodeUnstageVolume(req *NodeUnstageVolumeRequest) error {
+	return c.call("NodeUnstageVolume", req, new(interface{}))
+}
+
+func (c *Client) NodePublishVolume(req *NodePublishVolumeRequest) error {
+	return c.call("NodePublishVolume", req, new(interface{}))
+}
+
+func (c *Client) NodeUnpublishVolume(req *NodeUnpublishVolumeRequest) error {
+	return c.call("NodeUnpublishVolume", req, new(interface{}))
+}
+
+func (c *Client) NodeExpandVolume(req *NodeExpandVolumeRequest) (*NodeExpandVolumeResponse, error) {
+	var resp NodeExpandVolumeResponse
+	if err := c.call("NodeExpandVolume", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// pluginRPCServer is the server side of a plugin
+type pluginRPCServer struct {
+	Impl Plugin
+}
+
+func (s *pluginRPCServer) PluginInfo(args interface{}, info *PluginInfo) error {
+	out, err := s.Impl.PluginInfo()
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return errors.New("CSI plugin returned no plugin info")
+	}
+	*info = *out
+	return nil
+}
+
+func (s *pluginRPCServer) Probe(args interface{}, reply *interface{}) error {
+	return s.Impl.Probe()
+}
+
+func (s *pluginRPCServer) ControllerCreateVolume(req *ControllerCreateVolumeRequest, vol *Volume) error {
+	out, err := s.Impl.ControllerCreateVolume(req)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return errors.New("CSI plugin returned no volume")
+	}
+	*vol = *out
+	return nil
+}
+
+func (s *pluginRPCServer) ControllerDeleteVolume(req *ControllerDeleteVolumeRequest, reply *interface{}) error {
+	return s.Impl.ControllerDeleteVolume(req)
+}
+
+func (s *pluginRPCServer) ControllerExpandVolume(req *ControllerExpandVolumeRequest, resp *ControllerExpandVolumeResponse) error {
+	out, err := s.Impl.ControllerExpandVolume(req)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return errors.New("CSI plugin returned no expansion")
+	}
+	*resp = *out
+	return nil
+}
+
+func (s *pluginRPCServer) ControllerCreateSnapshot(req *ControllerCreateSnapshotRequest, snap *Snapshot) error {
+	out, err := s.Impl.ControllerCreateSnapshot(req)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return errors.New("CSI plugin returned no snapshot")
+	}
+	*snap = *out
+	return nil
+}
+
+func (s *pluginRPCServer) ControllerDeleteSnapshot(req *ControllerDeleteSnapshotRequest, reply *interface{}) error {
+	return s.Impl.ControllerDeleteSnapshot(req)
+}
+
+func (s *pluginRPCServer) ControllerListSnapshots(req *ControllerListSnapshotsRequest, resp *ControllerListSnapshotsResponse) error {
+	out, err := s.Impl.ControllerListSnapshots(req)
+	if err != nil {
+		return err
+	}
+	if out != nil {
+		*resp = *out
+	}
+	return nil
+}
+
+func (s *pluginRPCServer) ControllerPublishVolume(req *ControllerPublishVolumeRequest, resp *ControllerPublishVolumeResponse) error {
+	out, err := s.Impl.ControllerPublishVolume(req)
+	if err != nil {
+		return err
+	}
+	if out != nil {
+		*resp = *out
+	}
+	return nil
+}
+
+func (s *pluginRPCServer) ControllerUnpublishVolume(req *ControllerUnpublishVolumeRequest, reply *interface{}) error {
+	return s.Impl.ControllerUnpublishVolume(req)
+}
+
+func (s *pluginRPCServer) NodeGetInfo(args interface{}, info *NodeInfo) error {
+	out, err := s.Impl.NodeGetInfo()
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return errors.New("CSI plugin returned no node info")
+	}
+	*info = *out
+	return nil
+}
+
+func (s *pluginRPCServer) NodeStageVolume(req *NodeStageVolumeRequest, reply *interface{}) error {
+	return s.Impl.NodeStageVolume(req)
+}
+
+func (s *pluginRPCServer) NodeUnstageVolume(req *NodeUnstageVolumeRequest, reply *interface{}) error {
+	return s.Impl.NodeUnstageVolume(req)
+}
+
+func (s *pluginRPCServer) NodePublishVolume(req *NodePublishVolumeRequest, reply *interface{}) error {
+	return s.Impl.NodePublishVolume(req)
+}
+
+func (s *pluginRPCServer) NodeUnpublishVolume(req *NodeUnpublishVolumeRequest, reply *interface{}) error {
+	return s.Impl.NodeUnpublishVolume(req)
+}
+
+func (s *pluginRPCServer) NodeExpandVolume(req *NodeExpandVolumeRequest, resp *NodeExpandVolumeResponse) error {
+	out, err := s.Impl.NodeExpandVolume(req)
+	if err != nil {
+		return err
+	}
+	if out != nil {
+		*resp = *out
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package csi
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testPluginClient serves the plugin on a socket and returns a client of it
+func testPluginClient(t *testing.T, impl Plugin) (*Client, func()) {
+	dir, err := ioutil.TempDir("", "csi")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	socket := filepath.Join(dir, SocketName)
+	go Serve(impl, socket)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		client, err := NewClient(socket)
+		if err == nil {
+			return client, func() {
+				client.Close()
+				os.RemoveAll(dir)
+			}
+		}
+		if time.Now().After(deadline) {
+			os.RemoveAll(dir)
+			t.Fatalf("err: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPluginRPC(t *testing.T) {
+	impl := NewTestPlugin()
+	impl.Node.AccessibleTopology = &Topology{Segments: map[string]string{"zone": "a"}}
+	p, cleanup := testPluginClient(t, impl)
+	defer cleanup()
+
+	info, err := p.PluginInfo()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(info, impl.Info) {
+		t.Fatalf("bad: %#v", info)
+	}
+	if err := p.Probe(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	vol, err := p.ControllerCreateVolume(&ControllerCreateVolumeRequest{Name: "db", CapacityMin: 1024})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if vol.ExternalID != "vol-db" || vol.CapacityBytes != 1024 {
+		t.Fatalf("bad: %#v", vol)
+	}
+
+	node, err := p.NodeGetInfo()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(node, impl.Node) {
+		t.Fatalf("bad: %#v", node)
+	}
+
+	pub, err := p.ControllerPublishVolume(&ControllerPublishVolumeRequest{ExternalID: vol.ExternalID, NodeID: node.NodeID})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if impl.Published(vol.ExternalID) != node.NodeID {
+		t.Fatalf("volume not published")
+	}
+
+	dir, err := ioutil.TempDir("", "csi")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	staging := filepath.Join(dir, "staging")
+	target := filepath.Join(dir, "target")
+	if err := p.NodeStageVolume(&NodeStageVolumeRequest{
+		ExternalID:     vol.ExternalID,
+		PublishContext: pub.PublishContext,
+		StagingPath:    staging,
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := p.NodePublishVolume(&NodePublishVolumeRequest{
+		ExternalID:  vol.ExternalID,
+		StagingPath: staging,
+		TargetPath:  target,
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if impl.Target(target) != vol.ExternalID {
+		t.Fatalf("volume not published at target")
+	}
+
+	if err := p.NodeUnpublishVolume(&NodeUnpublishVolumeRequest{ExternalID: vol.ExternalID, TargetPath: target}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := p.NodeUnstageVolume(&NodeUnstageVolumeRequest{ExternalID: vol.ExternalID, StagingPath: staging}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := p.ControllerUnpublishVolume(&ControllerUnpublishVolumeRequest{ExternalID: vol.ExternalID, NodeID: node.NodeID}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if impl.Target(target) != "" || impl.Staged(vol.ExternalID) != "" || impl.Published(vol.ExternalID) != "" {
+		t.Fatalf("volume not released")
+	}
+}
+
+func TestPluginRPC_VolumeLifecycle(t *testing.T) {
+	impl := NewTestPlugin()
+	p, cleanup := testPluginClient(t, impl)
+	defer cleanup()
+
+	vol, err := p.ControllerCreateVolume(&ControllerCreateVolumeRequest{Name: "db", CapacityMin: 1024})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Expand the volume
+	resp, err := p.ControllerExpandVolume(&ControllerExpandVolumeRequest{ExternalID: vol.ExternalID, CapacityMin: 2048})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.CapacityBytes != 2048 || resp.NodeExpansionRequired {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if impl.Volume(vol.ExternalID).CapacityBytes != 2048 {
+		t.Fatalf("volume not expanded")
+	}
+
+	// Snapshot the volume and list the snapshots a page at a time
+	for _, name := range []string{"a", "b", "c"} {
+		snap, err := p.ControllerCreateSnapshot(&ControllerCreateSnapshotRequest{SourceVolumeID: vol.ExternalID, Name: name})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if snap.ID != "snap-"+name || snap.SourceVolumeID != vol.ExternalID || snap.SizeBytes != 2048 || !snap.ReadyToUse {
+			t.Fatalf("bad: %#v", snap)
+		}
+	}
+	list, err := p.ControllerListSnapshots(&ControllerListSnapshotsRequest{MaxEntries: 2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(list.Snapshots) != 2 || list.Snapshots[0].ID != "snap-a" || list.NextToken != "snap-c" {
+		t.Fatalf("bad: %#v", list)
+	}
+	list, err = p.ControllerListSnapshots(&ControllerListSnapshotsRequest{MaxEntries: 2, StartingToken: list.NextToken})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(list.Snapshots) != 1 || list.Snapshots[0].ID != "snap-c" || list.NextToken != "" {
+		t.Fatalf("bad: %#v", list)
+	}
+
+	// Restore a snapshot into a new volume
+	restored, err := p.ControllerCreateVolume(&ControllerCreateVolumeRequest{Name: "restored", SnapshotID: "snap-a"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if restored.CapacityBytes != 2048 {
+		t.Fatalf("bad: %#v", restored)
+	}
+
+	// Delete the snapshots and volumes
+	if err := p.ControllerDeleteSnapshot(&ControllerDeleteSnapshotRequest{SnapshotID: "snap-a"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	list, err = p.ControllerListSnapshots(&ControllerListSnapshotsRequest{})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(list.Snapshots) != 2 {
+		t.Fatalf("bad: %#v", list)
+	}
+	for _, id := range []string{vol.ExternalID, restored.ExternalID} {
+		if err := p.ControllerDeleteVolume(&ControllerDeleteVolumeRequest{ExternalID: id}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if impl.Volume(id) != nil {
+			t.Fatalf("volume %q not deleted", id)
+		}
+	}
+}
+
+// nodePlugin is a node plugin without a controller service
+type nodePlugin struct {
+	*TestPlugin
+}
+
+func (p *nodePlugin) ControllerCreateVolume(req *ControllerCreateVolumeRequest) (*Volume, error) {
+	return nil, ErrNotSupported
+}
+
+func TestPluginRPC_NotSupported(t *testing.T) {
+	p, cleanup := testPluginClient(t, &nodePlugin{NewTestPlugin()})
+	defer cleanup()
+
+	if _, err := p.ControllerCreateVolume(&ControllerCreateVolumeRequest{Name: "db"}); err != ErrNotSupported {
+		t.Fatalf("expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package csi
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TestPlugin is a monolith Plugin for tests. Volumes are provisioned in
+// memory and published by creating their target directories.
+type TestPlugin struct {
+	Info *PluginInfo
+	Node *NodeInfo
+
+	// ProbeErr is returned by Probe
+	ProbeErr error
+
+	// NodeExpansionRequired is returned when volumes are expanded
+	NodeExpansionRequired bool
+
+	l         sync.Mutex
+	volumes   map[string]*Volume
+	snapshots map[string]*Snapshot
+	published map[string]string
+	staged    map[string]string
+	targets   map[string]string
+	expanded  map[string]int64
+}
+
+// NewTestPlugin returns a TestPlugin requiring volumes to be published by
+// the controller and staged on the node
+func NewTestPlugin() *TestPlugin {
+	return &TestPlugin{
+		Info: &PluginInfo{
+			Name:                   "test",
+			Version:                "0.1.0",
+			ControllerService:      true,
+			PublishUnpublishVolume: true,
+			StageUnstageVolume:     true,
+		},
+		Node: &NodeInfo{
+			NodeID:     "test-node",
+			MaxVolumes: 8,
+		},
+		volumes:   make(map[string]*Volume),
+		snapshots: make(map[string]*Snapshot),
+		published: make(map[string]string),
+		staged:    make(map[string]string),
+		targets:   make(map[string]string),
+		expanded:  make(map[string]int64),
+	}
+}
+
+// Published returns the node the volume is published on by the controller
+func (p *TestPlugin) Published(externalID string) string {
+	p.l.Lock()
+	defer p.l.Unlock()
+	return p.published[externalID]
+}
+
+// Staged returns the staging path of the volume
+func (p *TestPlugin) Staged(externalID string) string {
+	p.l.Lock()
+	defer p.l.Unlock()
+	return p.staged[externalID]
+}
+
+// Target returns the volume published at the target path
+func (p *TestPlugin) Target(path string) string {
+	p.l.Lock()
+	defer p.l.Unlock()
+	return p.targets[path]
+}
+
+// Volume returns the provisioned volume
+func (p *TestPlugin) Volume(externalID string) *Volume {
+	p.l.Lock()
+	defer p.l.Unlock()
+	return p.volumes[externalID]
+}
+
+// Expanded returns the capacity the volume published at the path was
+// expanded to on the node
+func (p *TestPlugin) Expanded(path string) int64 {
+	p.l.Lock()
+	defer p.l.Unlock()
+	return p.expanded[path]
+}
+
+func (p *TestPlugin) PluginInfo() (*PluginInfo, error) {
+	return p.Info, nil
+}
+
+func (p *TestPlugin) Probe() error {
+	return p.ProbeErr
+}
+
+func (p *TestPlugin) ControllerCreateVolume(req *ControllerCreateVolumeRequest) (*Volume, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	vol := &Volume{
+		ExternalID:    "vol-" + req.Name,
+		CapacityBytes: req.CapacityMin,
+		Context:       map[string]string{"name": req.Name},
+		Topologies:    req.Topologies,
+	}
+	if req.SnapshotID != "" {
+		snap, ok := p.snapshots[req.SnapshotID]
+		if !ok {
+			return nil, fmt.Errorf("snapshot %q not found", req.SnapshotID)
+		}
+		if snap.SizeBytes > vol.CapacityBytes {
+			vol.CapacityBytes = snap.SizeBytes
+		}
+	}
+	p.volumes[vol.ExternalID] = vol
+	return vol, nil
+}
+
+func (p *TestPlugin) ControllerDeleteVolume(req *ControllerDeleteVolumeRequest) error {
+	p.l.Lock()
+	defer p.l.Unlock()
+	if node, ok := p.published[req.ExternalID]; ok {
+		return fmt.Errorf("volume %q published on node %q", req.ExternalID, node)
+	}
+	delete(p.volumes, req.ExternalID)
+	return nil
+}
+
+func (p *TestPlugin) ControllerExpandVolume(req *ControllerExpandVolumeRequest) (*ControllerExpandVolumeResponse, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	if vol, ok := p.volumes[req.ExternalID]; ok {
+		if req.CapacityMin < vol.CapacityBytes {
+			return nil, fmt.Errorf("volume %q can't shrink", req.ExternalID)
+		}
+		vol.CapacityBytes = req.CapacityMin
+	}
+	return &ControllerExpandVolumeResponse{
+		CapacityBytes:         req.CapacityMin,
+		NodeExpansionRequired: p.NodeExpansionRequired,
+	}, nil
+}
+
+func (p *TestPlugin) ControllerCreateSnapshot(req *ControllerCreateSnapshotRequest) (*Snapshot, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	snap := &Snapshot{
+		ID:             "snap-" + req.Name,
+		SourceVolumeID: req.SourceVolumeID,
+		CreateTime:     time.Now().UnixNano(),
+		ReadyToUse:     true,
+	}
+	if vol, ok := p.volumes[req.SourceVolumeID]; ok {
+		snap.SizeBytes = vol.CapacityBytes
+	}
+	p.snapshots[snap.ID] = snap
+	return snap, nil
+}
+
+func (p *TestPlugin) ControllerDeleteSnapshot(req *ControllerDeleteSnapshotRequest) error {
+	p.l.Lock()
+	defer p.l.Unlock()
+	delete(p.snapshots, req.SnapshotID)
+	return nil
+}
+
+func (p *TestPlugin) ControllerListSnapshots(req *ControllerListSnapshotsRequest) (*ControllerListSnapshotsResponse, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	var ids []string
+	for id, snap := range p.snapshots {
+		if req.SourceVolumeID == "" || snap.SourceVolumeID == req.SourceVolumeID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	// The token of the next page is the ID of its first snapshot
+	resp := &ControllerListSnapshotsResponse{}
+	for _, id := range ids {
+		if id < req.StartingToken {
+			continue
+		}
+		if req.MaxEntries > 0 && len(resp.Snapshots) == req.MaxEntries {
+			resp.NextToken = id
+			break
+		}
+		snap := *p.snapshots[id]
+		resp.Snapshots = append(resp.Snapshots, &snap)
+	}
+	return resp, nil
+}
+
+func (p *TestPlugin) ControllerPublishVolume(req *ControllerPublishVolumeRequest) (*ControllerPublishVolumeResponse, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.published[req.ExternalID] = req.NodeID
+	return &ControllerPublishVolumeResponse{
+		PublishContext: map[string]string{"device": "/dev/" + req.ExternalID},
+	}, nil
+}
+
+func (p *TestPlugin) ControllerUnpublishVolume(req *ControllerUnpublishVolumeRequest) error {
+	p.l.Lock()
+	defer p.l.Unlock()
+	delete(p.published, req.ExternalID)
+	return nil
+}
+
+func (p *TestPlugin) NodeGetInfo() (*NodeInfo, error) {
+	return p.Node, nil
+}
+
+func (p *TestPlugin) NodeStageVolume(req *NodeStageVolumeRequest) error {
+	if req.PublishContext["device"] == "" {
+		return fmt.Errorf("volume %q not published", req.ExternalID)
+	}
+	if err := os.MkdirAll(req.StagingPath, 0755); err != nil {
+		return err
+	}
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.staged[req.ExternalID] = req.StagingPath
+	return nil
+}
+
+func (p *TestPlugin) NodeUnstageVolume(req *NodeUnstageVolumeRequest) error {
+	p.l.Lock()
+	defer p.l.Unlock()
+	delete(p.staged, req.ExternalID)
+	return nil
+}
+
+func (p *TestPlugin) NodePublishVolume(req *NodePublishVolumeRequest) error {
+	p.l.Lock()
+	staged := p.staged[req.ExternalID]
+	p.l.Unlock()
+	if staged == "" || staged != req.StagingPath {
+		return fmt.Errorf("volume %q not staged at %q", req.ExternalID, req.StagingPath)
+	}
+	if err := os.MkdirAll(req.TargetPath, 0755); err != nil {
+		return err
+	}
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.targets[req.TargetPath] = req.ExternalID
+	return nil
+}
+
+func (p *TestPlugin) NodeUnpublishVolume(req *NodeUnpublishVolumeRequest) error {
+	p.l.Lock()
+	defer p.l.Unlock()
+	delete(p.targets, req.TargetPath)
+	return os.RemoveAll(req.TargetPath)
+}
+
+func (p *TestPlugin) NodeExpandVolume(req *NodeExpandVolumeRequest) (*NodeExpandVolumeResponse, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	if p.targets[req.VolumePath] != req.ExternalID {
+		return nil, fmt.Errorf("volume %q not published at %q", req.ExternalID, req.VolumePath)
+	}
+	p.expanded[req.VolumePath] = req.CapacityMin
+	return &NodeExpandVolumeResponse{CapacityBytes: req.CapacityMin}, nil
+}
@@ -0,0 +1,94 @@
+// Package devices is the SDK for writing device plugins. A device plugin is
+// an executable, found by the Nomad client in its plugin directory, that
+// reports the devices of the node, such as GPUs, and reserves them for tasks.
+// It serves a DevicePlugin implementation with Serve.
+package devices
+
+import (
+	"github.com/hashicorp/go-plugin"
+
+	cstructs "github.com/hashicorp/nomad/client/structs"
+)
+
+const (
+	// PluginName is the name the device plugin is dispensed by
+	PluginName = "device"
+
+	// ExecutablePrefix is the prefix of the names of the executables of
+	// device plugins
+	ExecutablePrefix = "nomad-device-"
+)
+
+// HandshakeConfig is the handshake between the Nomad client and device
+// plugins. The protocol version is bumped on incompatible changes of the
+// DevicePlugin interface.
+var HandshakeConfig = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "NOMAD_DEVICE_PLUGIN_MAGIC_COOKIE",
+	MagicCookieValue: "5f2c8e1a9b3d7f604e2a8c1b5d9f3e7a0c4b8d2f6a1e5c9b3d7f0a4e8c2b6d1f",
+}
+
+// DevicePlugin is implemented by device plugins. Devices are grouped by
+// vendor, type and name, and identified by an ID unique within their group.
+type DevicePlugin interface {
+	// Fingerprint returns the devices of the node and their health
+	Fingerprint() ([]*DeviceGroup, error)
+
+	// Reserve prepares the devices with the given IDs of the group for use
+	// by a task, returning how they are exposed to it
+	Reserve(req *ReserveRequest) (*Reservation, error)
+
+	// Stats returns the statistics of the devices
+	Stats() ([]*cstructs.DeviceGroupStats, error)
+}
+
+// DeviceGroup is a group of identical devices
+type DeviceGroup struct {
+	// Vendor, Type and Name identify the group, such as nvidia, gpu and
+	// 1080ti
+	Vendor string
+	Type   string
+	Name   string
+
+	// Devices are the devices of the group
+	Devices []*Device
+
+	// Attributes describe the devices of the group, such as their memory
+	Attributes map[string]string
+}
+
+// Device is a device of a group
+type Device struct {
+	// ID identifies the device within its group
+	ID string
+
+	// Healthy marks whether the device can be used, with HealthDescription
+	// describing why it can't
+	Healthy           bool
+	HealthDescription string
+}
+
+// ReserveRequest is a request to reserve devices of a group
+type ReserveRequest struct {
+	Vendor    string
+	Type      string
+	Name      string
+	DeviceIDs []string
+}
+
+// Reservation describes how reserved devices are exposed to a task
+type Reservation struct {
+	// Envs are environment variables to set in the task
+	Envs map[string]string
+}
+
+// Serve serves the device plugin to the Nomad client. It is meant to be
+// called from the main function of the plugin's executable.
+func Serve(d DevicePlugin) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: HandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &Plugin{Impl: d},
+		},
+	})
+}
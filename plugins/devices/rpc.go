@@ -0,0 +1,75 @@
+package devices
+
+import (
+	"errors"
+	"net/rpc"
+
+	"github.com/hashicorp/go-plugin"
+
+	cstructs "github.com/hashicorp/nomad/client/structs"
+)
+
+// Plugin is the go-plugin implementation serving a DevicePlugin over RPC
+type Plugin struct {
+	Impl DevicePlugin
+}
+
+func (p *Plugin) Server(*plugin.MuxBroker) (interface{}, error) {
+	return &devicePluginRPCServer{Impl: p.Impl}, nil
+}
+
+func (p *Plugin) Client(b *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
+	return &devicePluginRPC{client: c}, nil
+}
+
+// devicePluginRPC is the client side of a device plugin
+type devicePluginRPC struct {
+	client *rpc.Client
+}
+
+func (d *devicePluginRPC) Fingerprint() ([]*DeviceGroup, error) {
+	var groups []*DeviceGroup
+	err := d.client.Call("Plugin.Fingerprint", new(interface{}), &groups)
+	return groups, err
+}
+
+func (d *devicePluginRPC) Reserve(req *ReserveRequest) (*Reservation, error) {
+	var res Reservation
+	err := d.client.Call("Plugin.Reserve", req, &res)
+	return &res, err
+}
+
+func (d *devicePluginRPC) Stats() ([]*cstructs.DeviceGroupStats, error) {
+	var stats []*cstructs.DeviceGroupStats
+	err := d.client.Call("Plugin.Stats", new(interface{}), &stats)
+	return stats, err
+}
+
+// devicePluginRPCServer is the server side of a device plugin
+type devicePluginRPCServer struct {
+	Impl DevicePlugin
+}
+
+func (s *devicePluginRPCServer) Fingerprint(args interface{}, groups *[]*DeviceGroup) error {
+	out, err := s.Impl.Fingerprint()
+	*groups = out
+	return err
+}
+
+func (s *devicePluginRPCServer) Reserve(req *ReserveRequest, res *Reservation) error {
+	out, err := s.Impl.Reserve(req)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return errors.New("device plugin returned no reservation")
+	}
+	*res = *out
+	return nil
+}
+
+func (s *devicePluginRPCServer) Stats(args interface{}, stats *[]*cstructs.DeviceGroupStats) error {
+	out, err := s.Impl.Stats()
+	*stats = out
+	return err
+}
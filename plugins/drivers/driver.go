@@ -0,0 +1,195 @@
+// Package drivers is the SDK for writing external task drivers. An external
+// driver is an executable, found by the Nomad client in its plugin directory,
+// that serves a TaskDriver implementation with Serve.
+package drivers
+
+import (
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+
+	cstructs "github.com/hashicorp/nomad/client/structs"
+)
+
+const (
+	// PluginName is the name the task driver is dispensed by
+	PluginName = "driver"
+
+	// ExecutablePrefix is the prefix of the names of the executables of
+	// external task drivers. The rest of the name is the name of the driver.
+	ExecutablePrefix = "nomad-driver-"
+)
+
+// HandshakeConfig is the handshake between the Nomad client and external task
+// drivers. The protocol version is bumped on incompatible changes of the
+// TaskDriver interface.
+var HandshakeConfig = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "NOMAD_DRIVER_PLUGIN_MAGIC_COOKIE",
+	MagicCookieValue: "9d4a1c2b5e0f7d93a6b8c1e2f4d7a9b0c3e5f8a1d2b4c6e8f0a3b5d7c9e1f2a4",
+}
+
+// TaskDriver is implemented by external task drivers. Tasks are identified
+// by the ID the client gives them when they are started.
+type TaskDriver interface {
+	// Capabilities returns the features the driver supports
+	Capabilities() (*Capabilities, error)
+
+	// Fingerprint detects whether the driver can run tasks on the node,
+	// returning the node attributes it sets when it can
+	Fingerprint(req *FingerprintRequest) (*FingerprintResponse, error)
+
+	// StartTask starts a task, returning the handle to recover it with
+	StartTask(cfg *TaskConfig) (*TaskHandle, error)
+
+	// WaitTask blocks until the task exits
+	WaitTask(taskID string) (*ExitResult, error)
+
+	// StopTask stops the task, sending it the signal and killing it if it
+	// hasn't exited within the timeout
+	StopTask(taskID string, timeout time.Duration, signal string) error
+
+	// SignalTask sends the signal to the running task
+	SignalTask(taskID string, signal string) error
+
+	// InspectTask returns the status of the task
+	InspectTask(taskID string) (*TaskStatus, error)
+
+	// TaskStats returns the resource usage of the task
+	TaskStats(taskID string) (*cstructs.TaskResourceUsage, error)
+
+	// RecoverTask recovers a task started by a previous instance of the
+	// driver, such as after the client restarted
+	RecoverTask(handle *TaskHandle) error
+}
+
+// Capabilities describes the features a driver supports
+type Capabilities struct {
+	// SendSignals marks whether the driver can send signals to a task
+	SendSignals bool
+
+	// Exec marks whether the driver can execute commands in the context of
+	// a task
+	Exec bool
+
+	// MountVolumes marks whether the driver can mount volumes into a task
+	MountVolumes bool
+
+	// NetworkIsolation is the set of network isolation modes the driver
+	// supports
+	NetworkIsolation []string
+}
+
+// FingerprintRequest holds the client options and the current attributes of
+// the node
+type FingerprintRequest struct {
+	Options    map[string]string
+	Attributes map[string]string
+}
+
+// FingerprintResponse holds whether the driver was detected and the node
+// attributes it sets. The driver.<name> attribute is set by the client.
+type FingerprintResponse struct {
+	Detected   bool
+	Attributes map[string]string
+}
+
+// TaskConfig is the configuration of a task to start
+type TaskConfig struct {
+	// ID uniquely identifies the task
+	ID string
+
+	// Name is the name of the task and AllocID the ID of its allocation
+	Name    string
+	AllocID string
+
+	// Config is the driver configuration of the task
+	Config map[string]interface{}
+
+	// Env is the environment of the task
+	Env map[string]string
+
+	// User is the user to run the task as
+	User string
+
+	// CPU in MHz and MemoryMB are the resources of the task
+	CPU      int
+	MemoryMB int
+
+	// AllocDir, TaskDir and SecretsDir are the directories of the task
+	AllocDir   string
+	TaskDir    string
+	SecretsDir string
+
+	// StdoutPath and StderrPath are the files the output of the task must
+	// be appended to
+	StdoutPath string
+	StderrPath string
+
+	// Mounts are the directories of the host to mount into the task, for
+	// drivers supporting volumes
+	Mounts []*MountConfig
+}
+
+// MountConfig is a directory of the host to mount into a task
+type MountConfig struct {
+	// HostPath is the directory of the host and TaskPath the absolute path
+	// of the mount within the task
+	HostPath string
+	TaskPath string
+
+	// ReadOnly mounts the directory read-only
+	ReadOnly bool
+
+	// Shared mounts the directory with bidirectional mount propagation, so
+	// that the mounts the task makes below it are visible on the host
+	Shared bool
+}
+
+// TaskHandle identifies a started task
+type TaskHandle struct {
+	// ID is the ID of the task
+	ID string
+
+	// DriverState is opaque state of the driver, given back when the task
+	// is recovered
+	DriverState []byte
+}
+
+// ExitResult is the result of an exited task
+type ExitResult struct {
+	ExitCode int
+	Signal   int
+
+	// Err is the error the task failed with, if any
+	Err string
+}
+
+// The states of a task
+const (
+	TaskStateRunning = "running"
+	TaskStateExited  = "exited"
+	TaskStateUnknown = "unknown"
+)
+
+// TaskStatus is the status of a task
+type TaskStatus struct {
+	ID          string
+	State       string
+	StartedAt   time.Time
+	CompletedAt time.Time
+
+	// ExitResult is set once the task exited
+	ExitResult *ExitResult
+}
+
+// Serve serves the task driver to the Nomad client. It is meant to be called
+// from the main function of the driver's executable.
+func Serve(d TaskDriver) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: HandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &Plugin{Impl: d},
+		},
+	})
+}
@@ -0,0 +1,195 @@
+package drivers
+
+import (
+	"encoding/gob"
+	"errors"
+	"net/rpc"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+
+	cstructs "github.com/hashicorp/nomad/client/structs"
+)
+
+// Registering these types since we have to serialize and de-serialize the
+// driver configuration of tasks over the wire between the client and the
+// driver.
+func init() {
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+	gob.Register([]map[string]interface{}{})
+}
+
+// Plugin is the go-plugin implementation serving a TaskDriver over RPC
+type Plugin struct {
+	Impl TaskDriver
+}
+
+func (p *Plugin) Server(*plugin.MuxBroker) (interface{}, error) {
+	return &taskDriverRPCServer{Impl: p.Impl}, nil
+}
+
+func (p *Plugin) Client(b *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
+	return &taskDriverRPC{client: c}, nil
+}
+
+// StopTaskArgs wraps the arguments of StopTask for the purposes of RPC
+type StopTaskArgs struct {
+	TaskID  string
+	Timeout time.Duration
+	Signal  string
+}
+
+// SignalTaskArgs wraps the arguments of SignalTask for the purposes of RPC
+type SignalTaskArgs struct {
+	TaskID string
+	Signal string
+}
+
+// taskDriverRPC is the client side of an external task driver
+type taskDriverRPC struct {
+	client *rpc.Client
+}
+
+func (d *taskDriverRPC) Capabilities() (*Capabilities, error) {
+	var c Capabilities
+	err := d.client.Call("Plugin.Capabilities", new(interface{}), &c)
+	return &c, err
+}
+
+func (d *taskDriverRPC) Fingerprint(req *FingerprintRequest) (*FingerprintResponse, error) {
+	var resp FingerprintResponse
+	err := d.client.Call("Plugin.Fingerprint", req, &resp)
+	return &resp, err
+}
+
+func (d *taskDriverRPC) StartTask(cfg *TaskConfig) (*TaskHandle, error) {
+	var h TaskHandle
+	err := d.client.Call("Plugin.StartTask", cfg, &h)
+	return &h, err
+}
+
+func (d *taskDriverRPC) WaitTask(taskID string) (*ExitResult, error) {
+	var res ExitResult
+	err := d.client.Call("Plugin.WaitTask", taskID, &res)
+	return &res, err
+}
+
+func (d *taskDriverRPC) StopTask(taskID string, timeout time.Duration, signal string) error {
+	args := StopTaskArgs{TaskID: taskID, Timeout: timeout, Signal: signal}
+	return d.client.Call("Plugin.StopTask", args, new(interface{}))
+}
+
+func (d *taskDriverRPC) SignalTask(taskID string, signal string) error {
+	args := SignalTaskArgs{TaskID: taskID, Signal: signal}
+	return d.client.Call("Plugin.SignalTask", args, new(interface{}))
+}
+
+func (d *taskDriverRPC) InspectTask(taskID string) (*TaskStatus, error) {
+	var status TaskStatus
+	err := d.client.Call("Plugin.InspectTask", taskID, &status)
+	return &status, err
+}
+
+func (d *taskDriverRPC) TaskStats(taskID string) (*cstructs.TaskResourceUsage, error) {
+	var usage cstructs.TaskResourceUsage
+	err := d.client.Call("Plugin.TaskStats", taskID, &usage)
+	return &usage, err
+}
+
+func (d *taskDriverRPC) RecoverTask(handle *TaskHandle) error {
+	return d.client.Call("Plugin.RecoverTask", handle, new(interface{}))
+}
+
+// taskDriverRPCServer is the server side of an external task driver
+type taskDriverRPCServer struct {
+	Impl TaskDriver
+}
+
+// errNilResult is returned when the driver returns neither a result nor an
+// error
+var errNilResult = errors.New("driver returned no result")
+
+func (s *taskDriverRPCServer) Capabilities(args interface{}, c *Capabilities) error {
+	out, err := s.Impl.Capabilities()
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return errNilResult
+	}
+	*c = *out
+	return nil
+}
+
+func (s *taskDriverRPCServer) Fingerprint(req *FingerprintRequest, resp *FingerprintResponse) error {
+	out, err := s.Impl.Fingerprint(req)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return errNilResult
+	}
+	*resp = *out
+	return nil
+}
+
+func (s *taskDriverRPCServer) StartTask(cfg *TaskConfig, h *TaskHandle) error {
+	out, err := s.Impl.StartTask(cfg)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return errNilResult
+	}
+	*h = *out
+	return nil
+}
+
+func (s *taskDriverRPCServer) WaitTask(taskID string, res *ExitResult) error {
+	out, err := s.Impl.WaitTask(taskID)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return errNilResult
+	}
+	*res = *out
+	return nil
+}
+
+func (s *taskDriverRPCServer) StopTask(args StopTaskArgs, resp *interface{}) error {
+	return s.Impl.StopTask(args.TaskID, args.Timeout, args.Signal)
+}
+
+func (s *taskDriverRPCServer) SignalTask(args SignalTaskArgs, resp *interface{}) error {
+	return s.Impl.SignalTask(args.TaskID, args.Signal)
+}
+
+func (s *taskDriverRPCServer) InspectTask(taskID string, status *TaskStatus) error {
+	out, err := s.Impl.InspectTask(taskID)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return errNilResult
+	}
+	*status = *out
+	return nil
+}
+
+func (s *taskDriverRPCServer) TaskStats(taskID string, usage *cstructs.TaskResourceUsage) error {
+	out, err := s.Impl.TaskStats(taskID)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return errNilResult
+	}
+	*usage = *out
+	return nil
+}
+
+func (s *taskDriverRPCServer) RecoverTask(handle *TaskHandle, resp *interface{}) error {
+	return s.Impl.RecoverTask(handle)
+}
@@ -0,0 +1,154 @@
+package drivers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+
+	cstructs "github.com/hashicorp/nomad/client/structs"
+)
+
+// testDriver is a TaskDriver recording the calls made to it
+type testDriver struct {
+	started   *TaskConfig
+	stopped   StopTaskArgs
+	signaled  SignalTaskArgs
+	recovered *TaskHandle
+}
+
+func (d *testDriver) Capabilities() (*Capabilities, error) {
+	return &Capabilities{SendSignals: true, NetworkIsolation: []string{"host"}}, nil
+}
+
+func (d *testDriver) Fingerprint(req *FingerprintRequest) (*FingerprintResponse, error) {
+	return &FingerprintResponse{
+		Detected:   req.Options["driver.test.enable"] == "1",
+		Attributes: map[string]string{"driver.test.version": "0.1.0"},
+	}, nil
+}
+
+func (d *testDriver) StartTask(cfg *TaskConfig) (*TaskHandle, error) {
+	d.started = cfg
+	return &TaskHandle{ID: cfg.ID, DriverState: []byte("pid=42")}, nil
+}
+
+func (d *testDriver) WaitTask(taskID string) (*ExitResult, error) {
+	return &ExitResult{ExitCode: 3, Err: "failed"}, nil
+}
+
+func (d *testDriver) StopTask(taskID string, timeout time.Duration, signal string) error {
+	d.stopped = StopTaskArgs{TaskID: taskID, Timeout: timeout, Signal: signal}
+	return nil
+}
+
+func (d *testDriver) SignalTask(taskID string, signal string) error {
+	d.signaled = SignalTaskArgs{TaskID: taskID, Signal: signal}
+	return nil
+}
+
+func (d *testDriver) InspectTask(taskID string) (*TaskStatus, error) {
+	return &TaskStatus{ID: taskID, State: TaskStateRunning}, nil
+}
+
+func (d *testDriver) TaskStats(taskID string) (*cstructs.TaskResourceUsage, error) {
+	return &cstructs.TaskResourceUsage{Timestamp: 10}, nil
+}
+
+func (d *testDriver) RecoverTask(handle *TaskHandle) error {
+	d.recovered = handle
+	return nil
+}
+
+func testTaskDriverRPC(t *testing.T, impl TaskDriver) TaskDriver {
+	client, _ := plugin.TestPluginRPCConn(t, map[string]plugin.Plugin{
+		PluginName: &Plugin{Impl: impl},
+	})
+	raw, err := client.Dispense(PluginName)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return raw.(TaskDriver)
+}
+
+func TestTaskDriverRPC(t *testing.T) {
+	impl := &testDriver{}
+	d := testTaskDriverRPC(t, impl)
+
+	caps, err := d.Capabilities()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !caps.SendSignals || !reflect.DeepEqual(caps.NetworkIsolation, []string{"host"}) {
+		t.Fatalf("bad: %#v", caps)
+	}
+
+	fp, err := d.Fingerprint(&FingerprintRequest{Options: map[string]string{"driver.test.enable": "1"}})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !fp.Detected || fp.Attributes["driver.test.version"] != "0.1.0" {
+		t.Fatalf("bad: %#v", fp)
+	}
+
+	cfg := &TaskConfig{
+		ID:     "alloc/web",
+		Config: map[string]interface{}{"image": "redis", "args": []interface{}{"-v"}},
+	}
+	h, err := d.StartTask(cfg)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if h.ID != "alloc/web" || string(h.DriverState) != "pid=42" {
+		t.Fatalf("bad: %#v", h)
+	}
+	if !reflect.DeepEqual(impl.started, cfg) {
+		t.Fatalf("bad: %#v", impl.started)
+	}
+
+	res, err := d.WaitTask(h.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if res.ExitCode != 3 || res.Err != "failed" {
+		t.Fatalf("bad: %#v", res)
+	}
+
+	if err := d.StopTask(h.ID, 5*time.Second, "SIGINT"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if impl.stopped != (StopTaskArgs{TaskID: h.ID, Timeout: 5 * time.Second, Signal: "SIGINT"}) {
+		t.Fatalf("bad: %#v", impl.stopped)
+	}
+
+	if err := d.SignalTask(h.ID, "SIGHUP"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if impl.signaled != (SignalTaskArgs{TaskID: h.ID, Signal: "SIGHUP"}) {
+		t.Fatalf("bad: %#v", impl.signaled)
+	}
+
+	status, err := d.InspectTask(h.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if status.State != TaskStateRunning {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	usage, err := d.TaskStats(h.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if usage.Timestamp != 10 {
+		t.Fatalf("bad: %#v", usage)
+	}
+
+	if err := d.RecoverTask(h); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(impl.recovered, h) {
+		t.Fatalf("bad: %#v", impl.recovered)
+	}
+}
@@ -22,6 +22,10 @@ type Context interface {
 	// Metrics returns the current metrics
 	Metrics() *structs.AllocMetric
 
+	// Timing returns the time spent checking feasibility and ranking nodes
+	// over all the placements of the evaluation
+	Timing() *structs.EvalTiming
+
 	// Reset is invoked after making a placement
 	Reset()
 
@@ -39,6 +43,11 @@ type Context interface {
 	// Eligibility returns a tracker for node eligibility in the context of the
 	// eval.
 	Eligibility() *EvalEligibility
+
+	// NodeSnapshot returns the version of the node feasibility is checked
+	// against. The first version of a node seen during the evaluation is
+	// cached so all placements are checked against consistent attributes.
+	NodeSnapshot(node *structs.Node) *structs.Node
 }
 
 // EvalCache is used to cache certain things during an evaluation
@@ -67,7 +76,9 @@ type EvalContext struct {
 	plan        *structs.Plan
 	logger      *log.Logger
 	metrics     *structs.AllocMetric
+	timing      *structs.EvalTiming
 	eligibility *EvalEligibility
+	nodes       map[string]*structs.Node
 }
 
 // NewEvalContext constructs a new EvalContext
@@ -77,6 +88,7 @@ func NewEvalContext(s State, p *structs.Plan, log *log.Logger) *EvalContext {
 		plan:    p,
 		logger:  log,
 		metrics: new(structs.AllocMetric),
+		timing:  new(structs.EvalTiming),
 	}
 	return ctx
 }
@@ -97,10 +109,31 @@ func (e *EvalContext) Metrics() *structs.AllocMetric {
 	return e.metrics
 }
 
+func (e *EvalContext) Timing() *structs.EvalTiming {
+	return e.timing
+}
+
+func (e *EvalContext) NodeSnapshot(node *structs.Node) *structs.Node {
+	if e.nodes == nil {
+		e.nodes = make(map[string]*structs.Node)
+	}
+	if snap, ok := e.nodes[node.ID]; ok {
+		return snap
+	}
+	e.nodes[node.ID] = node
+	return node
+}
+
 func (e *EvalContext) SetState(s State) {
 	e.state = s
 }
 
+// SetTiming sets the timing the context accumulates into. This allows the
+// timing to be kept across the contexts of several scheduling attempts.
+func (e *EvalContext) SetTiming(t *structs.EvalTiming) {
+	e.timing = t
+}
+
 func (e *EvalContext) Reset() {
 	e.metrics = new(structs.AllocMetric)
 }
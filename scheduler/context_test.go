@@ -114,6 +114,25 @@ func TestEvalContext_ProposedAlloc(t *testing.T) {
 	}
 }
 
+func TestEvalContext_NodeSnapshot(t *testing.T) {
+	_, ctx := testContext(t)
+	node := mock.Node()
+	node.ModifyIndex = 10
+
+	if snap := ctx.NodeSnapshot(node); snap != node {
+		t.Fatalf("NodeSnapshot() returned %#v; want %#v", snap, node)
+	}
+
+	// A newer version of the node seen later in the evaluation resolves to
+	// the first version
+	updated := node.Copy()
+	updated.ModifyIndex = 20
+	updated.Attributes["kernel.name"] = "windows"
+	if snap := ctx.NodeSnapshot(updated); snap != node {
+		t.Fatalf("NodeSnapshot() returned %#v; want %#v", snap, node)
+	}
+}
+
 func TestEvalEligibility_JobStatus(t *testing.T) {
 	e := NewEvalEligibility()
 	cc := "v1:100"
@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"fmt"
+	"math/rand"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -30,13 +31,20 @@ type FeasibilityChecker interface {
 }
 
 // StaticIterator is a FeasibleIterator which returns nodes
-// in a static order. This is used at the base of the iterator
-// chain only for testing due to deterministic behavior.
+// in a static order. It is at the base of every iterator chain:
+// NewRandomIterator uses it to shuffle the nodes lazily as they
+// are visited, while tests use the static order for deterministic
+// behavior.
 type StaticIterator struct {
 	ctx    Context
 	nodes  []*structs.Node
 	offset int
 	seen   int
+
+	// random shuffles the nodes as they are visited. shuffled is the number
+	// of leading nodes whose position is already final.
+	random   bool
+	shuffled int
 }
 
 // NewStaticIterator constructs a random iterator from a list of nodes
@@ -61,10 +69,16 @@ func (iter *StaticIterator) Next() *structs.Node {
 
 	// Return the next offset
 	offset := iter.offset
+	if iter.random && offset >= iter.shuffled {
+		// Swap a random node that hasn't been visited into this offset
+		j := offset + rand.Intn(n-offset)
+		iter.nodes[offset], iter.nodes[j] = iter.nodes[j], iter.nodes[offset]
+		iter.shuffled = offset + 1
+	}
 	iter.offset += 1
 	iter.seen += 1
 	iter.ctx.Metrics().EvaluateNode()
-	return iter.nodes[offset]
+	return iter.ctx.NodeSnapshot(iter.nodes[offset])
 }
 
 func (iter *StaticIterator) Reset() {
@@ -75,24 +89,26 @@ func (iter *StaticIterator) SetNodes(nodes []*structs.Node) {
 	iter.nodes = nodes
 	iter.offset = 0
 	iter.seen = 0
+	iter.shuffled = 0
 }
 
-// NewRandomIterator constructs a static iterator from a list of nodes
-// after applying the Fisher-Yates algorithm for a random shuffle. This
-// is applied in-place
+// NewRandomIterator constructs a static iterator from a list of nodes which
+// yields them in a random order. The nodes are shuffled in-place with the
+// Fisher-Yates algorithm as they are visited, so on large clusters only the
+// nodes an evaluation visits pay for the shuffle while concurrent
+// evaluations still start at different nodes.
 func NewRandomIterator(ctx Context, nodes []*structs.Node) *StaticIterator {
-	// shuffle with the Fisher-Yates algorithm
-	shuffleNodes(nodes)
-
-	// Create a static iterator
-	return NewStaticIterator(ctx, nodes)
+	iter := NewStaticIterator(ctx, nodes)
+	iter.random = true
+	return iter
 }
 
 // DriverChecker is a FeasibilityChecker which returns whether a node has the
 // drivers necessary to scheduler a task group.
 type DriverChecker struct {
-	ctx     Context
-	drivers map[string]struct{}
+	ctx          Context
+	drivers      map[string]struct{}
+	capabilities map[string]map[string]struct{}
 }
 
 // NewDriverChecker creates a DriverChecker from a set of drivers
@@ -107,13 +123,66 @@ func (c *DriverChecker) SetDrivers(d map[string]struct{}) {
 	c.drivers = d
 }
 
+// SetCapabilities sets the capabilities required of each driver
+func (c *DriverChecker) SetCapabilities(capabilities map[string]map[string]struct{}) {
+	c.capabilities = capabilities
+}
+
 func (c *DriverChecker) Feasible(option *structs.Node) bool {
-	// Use this node if possible
-	if c.hasDrivers(option) {
-		return true
+	if !c.hasDrivers(option) {
+		c.ctx.Metrics().FilterNode(option, "missing drivers")
+		return false
 	}
-	c.ctx.Metrics().FilterNode(option, "missing drivers")
-	return false
+	if driver, capability, ok := c.hasCapabilities(option); !ok {
+		c.ctx.Metrics().FilterNode(option,
+			fmt.Sprintf("driver %q missing capability %q", driver, capability))
+		return false
+	}
+	return true
+}
+
+// hasCapabilities is used to check if the drivers of the node support the
+// capabilities required by the task group. Capabilities are registered as
+// node attributes like "driver.docker.capability.pause=true". The first
+// missing capability is returned if they are not all supported.
+func (c *DriverChecker) hasCapabilities(option *structs.Node) (string, string, bool) {
+	for driver, capabilities := range c.capabilities {
+		for capability := range capabilities {
+			attr := structs.DriverCapabilityAttribute(driver, capability)
+			value, ok := option.Attributes[attr]
+			if !ok {
+				return driver, capability, false
+			}
+
+			// Network isolation modes are listed rather than flagged and
+			// tasks only rely on the group mode
+			if capability == structs.DriverCapabilityNetworkIsolation {
+				supported := false
+				for _, mode := range strings.Split(value, ",") {
+					if mode == structs.DriverNetworkIsolationGroup {
+						supported = true
+					}
+				}
+				if !supported {
+					return driver, capability, false
+				}
+				continue
+			}
+
+			supported, err := strconv.ParseBool(value)
+			if err != nil {
+				c.ctx.Logger().
+					Printf("[WARN] scheduler.DriverChecker: node %v has invalid driver capability %v: %v",
+						option.ID, attr, value)
+				return driver, capability, false
+			}
+
+			if !supported {
+				return driver, capability, false
+			}
+		}
+	}
+	return "", "", true
 }
 
 // hasDrivers is used to check if the node has all the appropriate
@@ -142,6 +211,52 @@ func (c *DriverChecker) hasDrivers(option *structs.Node) bool {
 	return true
 }
 
+// HostVolumeChecker is a FeasibilityChecker which returns whether a node has
+// the host volumes requested by a task group.
+type HostVolumeChecker struct {
+	ctx     Context
+	volumes map[string]*structs.VolumeRequest
+}
+
+// NewHostVolumeChecker creates a HostVolumeChecker from a set of volumes
+func NewHostVolumeChecker(ctx Context, volumes map[string]*structs.VolumeRequest) *HostVolumeChecker {
+	return &HostVolumeChecker{
+		ctx:     ctx,
+		volumes: volumes,
+	}
+}
+
+// SetVolumes sets the volumes requested by the task group
+func (c *HostVolumeChecker) SetVolumes(volumes map[string]*structs.VolumeRequest) {
+	c.volumes = volumes
+}
+
+func (c *HostVolumeChecker) Feasible(option *structs.Node) bool {
+	if !c.hasVolumes(option) {
+		c.ctx.Metrics().FilterNode(option, "missing compatible host volumes")
+		return false
+	}
+	return true
+}
+
+// hasVolumes is used to check if the node has the host volumes the task
+// group requests. Read-only host volumes only satisfy read-only requests.
+func (c *HostVolumeChecker) hasVolumes(option *structs.Node) bool {
+	for _, req := range c.volumes {
+		if req.Type != structs.VolumeTypeHost {
+			continue
+		}
+		volume, ok := option.HostVolumes[req.Source]
+		if !ok {
+			return false
+		}
+		if volume.ReadOnly && !req.ReadOnly {
+			return false
+		}
+	}
+	return true
+}
+
 // ProposedAllocConstraintIterator is a FeasibleIterator which returns nodes that
 // match constraints that are not static such as Node attributes but are
 // effected by proposed alloc placements. Examples are distinct_hosts and
@@ -241,6 +356,215 @@ func (iter *ProposedAllocConstraintIterator) Reset() {
 	iter.source.Reset()
 }
 
+// CSIVolumeIterator is a FeasibleIterator which returns nodes that can claim
+// the CSI volumes requested by a task group. Nodes must run a healthy node
+// plugin of each volume, be in one of the topologies the volume is accessible
+// from, and be below the maximum number of volumes of the plugin, while the
+// access mode of the volume must allow the claim given its existing and
+// proposed claims. Since claims change with placements, the results can't be
+// cached by node class.
+type CSIVolumeIterator struct {
+	ctx       Context
+	source    FeasibleIterator
+	namespace string
+	volumes   []*structs.VolumeRequest
+}
+
+// NewCSIVolumeIterator creates a CSIVolumeIterator from a source
+func NewCSIVolumeIterator(ctx Context, source FeasibleIterator) *CSIVolumeIterator {
+	return &CSIVolumeIterator{
+		ctx:    ctx,
+		source: source,
+	}
+}
+
+func (iter *CSIVolumeIterator) SetJob(job *structs.Job) {
+	iter.namespace = job.Namespace
+	if iter.namespace == "" {
+		iter.namespace = structs.DefaultNamespace
+	}
+}
+
+func (iter *CSIVolumeIterator) SetTaskGroup(tg *structs.TaskGroup) {
+	iter.volumes = csiVolumeRequests(tg)
+}
+
+func (iter *CSIVolumeIterator) Next() *structs.Node {
+	for {
+		// Get the next option from the source
+		option := iter.source.Next()
+
+		// Hot-path if the option is nil or there are no CSI volumes
+		if option == nil || len(iter.volumes) == 0 {
+			return option
+		}
+
+		if reason := iter.canClaim(option); reason != "" {
+			iter.ctx.Metrics().FilterNode(option, reason)
+			continue
+		}
+
+		return option
+	}
+}
+
+func (iter *CSIVolumeIterator) Reset() {
+	iter.source.Reset()
+}
+
+// canClaim returns why the node can't claim the volumes of the task group, or
+// an empty string if it can
+func (iter *CSIVolumeIterator) canClaim(option *structs.Node) string {
+	for _, req := range iter.volumes {
+		vol, err := iter.ctx.State().CSIVolumeByID(req.Source)
+		if err != nil {
+			iter.ctx.Logger().Printf(
+				"[ERR] scheduler.csi-volume: failed to get volume %q: %v", req.Source, err)
+			return fmt.Sprintf("missing CSI volume %s", req.Source)
+		}
+		if vol == nil || vol.Namespace != iter.namespace {
+			return fmt.Sprintf("missing CSI volume %s", req.Source)
+		}
+
+		info, ok := option.CSINodePlugins[vol.PluginID]
+		if !ok {
+			return fmt.Sprintf("missing CSI plugin %s", vol.PluginID)
+		}
+		if !info.Healthy {
+			return fmt.Sprintf("unhealthy CSI plugin %s", vol.PluginID)
+		}
+
+		if !iter.accessible(vol, info.NodeInfo) {
+			return fmt.Sprintf("CSI volume %s not accessible from node topology", vol.ID)
+		}
+
+		mode := structs.CSIVolumeClaimWrite
+		if req.ReadOnly {
+			mode = structs.CSIVolumeClaimRead
+		}
+		proposed := iter.proposedVolume(vol)
+		if err := proposed.ClaimAllowed("", option.ID, mode); err != nil {
+			return fmt.Sprintf("CSI volume %s has exhausted its available claims", vol.ID)
+		}
+
+		if info.NodeInfo != nil && info.NodeInfo.MaxVolumes > 0 {
+			count, err := iter.proposedPluginVolumes(option.ID, vol)
+			if err != nil {
+				iter.ctx.Logger().Printf(
+					"[ERR] scheduler.csi-volume: failed to get proposed allocations: %v", err)
+				return fmt.Sprintf("CSI plugin %s volumes unknown", vol.PluginID)
+			}
+			if count > info.NodeInfo.MaxVolumes {
+				return fmt.Sprintf("CSI plugin %s has reached its maximum volumes", vol.PluginID)
+			}
+		}
+	}
+	return ""
+}
+
+// accessible returns whether the volume is accessible from the topology of
+// the node
+func (iter *CSIVolumeIterator) accessible(vol *structs.CSIVolume, info *structs.CSINodeInfo) bool {
+	if len(vol.Topologies) == 0 {
+		return true
+	}
+	var topology *structs.CSITopology
+	if info != nil {
+		topology = info.AccessibleTopology
+	}
+	for _, t := range vol.Topologies {
+		if topology.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// proposedVolume returns a copy of the volume with the claims of the
+// allocations the plan stops removed, and claims added for the allocations
+// the plan places
+func (iter *CSIVolumeIterator) proposedVolume(vol *structs.CSIVolume) *structs.CSIVolume {
+	plan := iter.ctx.Plan()
+	proposed := vol.Copy()
+	if proposed.Claims == nil {
+		proposed.Claims = make(map[string]*structs.CSIVolumeClaim)
+	}
+	for _, update := range plan.NodeUpdate {
+		for _, alloc := range update {
+			delete(proposed.Claims, alloc.ID)
+		}
+	}
+	for nodeID, allocs := range plan.NodeAllocation {
+		for _, alloc := range allocs {
+			for _, req := range allocCSIVolumeRequests(alloc) {
+				if req.Source != vol.ID {
+					continue
+				}
+				mode := structs.CSIVolumeClaimWrite
+				if req.ReadOnly {
+					mode = structs.CSIVolumeClaimRead
+				}
+				proposed.Claims[alloc.ID] = &structs.CSIVolumeClaim{
+					AllocID: alloc.ID,
+					NodeID:  nodeID,
+					Mode:    mode,
+				}
+			}
+		}
+	}
+	return proposed
+}
+
+// proposedPluginVolumes returns the number of volumes of the plugin of the
+// volume claimed on the node by its proposed allocations, including the
+// volume
+func (iter *CSIVolumeIterator) proposedPluginVolumes(nodeID string, vol *structs.CSIVolume) (int64, error) {
+	proposed, err := iter.ctx.ProposedAllocs(nodeID)
+	if err != nil {
+		return 0, err
+	}
+
+	volumes := map[string]struct{}{vol.ID: struct{}{}}
+	for _, alloc := range proposed {
+		for _, req := range allocCSIVolumeRequests(alloc) {
+			if _, ok := volumes[req.Source]; ok {
+				continue
+			}
+			other, err := iter.ctx.State().CSIVolumeByID(req.Source)
+			if err != nil {
+				return 0, err
+			}
+			if other != nil && other.PluginID == vol.PluginID {
+				volumes[req.Source] = struct{}{}
+			}
+		}
+	}
+	return int64(len(volumes)), nil
+}
+
+// csiVolumeRequests returns the CSI volumes requested by the task group
+func csiVolumeRequests(tg *structs.TaskGroup) []*structs.VolumeRequest {
+	if tg == nil {
+		return nil
+	}
+	var reqs []*structs.VolumeRequest
+	for _, req := range tg.Volumes {
+		if req.Type == structs.VolumeTypeCSI {
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs
+}
+
+// allocCSIVolumeRequests returns the CSI volumes requested by the task group
+// of the allocation
+func allocCSIVolumeRequests(alloc *structs.Allocation) []*structs.VolumeRequest {
+	if alloc.Job == nil {
+		return nil
+	}
+	return csiVolumeRequests(alloc.Job.LookupTaskGroup(alloc.TaskGroup))
+}
+
 // ConstraintChecker is a FeasibilityChecker which returns nodes that match a
 // given set of constraints. This is used to filter on job, task group, and task
 // constraints.
@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -76,6 +77,63 @@ func TestRandomIterator(t *testing.T) {
 	}
 }
 
+func TestRandomIterator_LazyShuffle(t *testing.T) {
+	_, ctx := testContext(t)
+	// Use a large number of nodes to make the probability of shuffling to the
+	// original order very low.
+	var nodes []*structs.Node
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, mock.Node())
+	}
+
+	nc := make([]*structs.Node, len(nodes))
+	copy(nc, nodes)
+	rand := NewRandomIterator(ctx, nc)
+
+	// The visited nodes are shuffled into the leading positions
+	var visited []*structs.Node
+	for i := 0; i < 3; i++ {
+		visited = append(visited, rand.Next())
+	}
+	for i, node := range visited {
+		if nc[i].ID != node.ID {
+			t.Fatalf("node %d not in place: %#v", i, nc)
+		}
+	}
+
+	// Every node is visited exactly once
+	visited = append(visited, collectFeasible(rand)...)
+	if len(visited) != len(nodes) {
+		t.Fatalf("visited %d nodes; want %d", len(visited), len(nodes))
+	}
+	ids := make(map[string]struct{})
+	for _, node := range visited {
+		ids[node.ID] = struct{}{}
+	}
+	for _, node := range nodes {
+		if _, ok := ids[node.ID]; !ok {
+			t.Fatalf("node %q not visited", node.ID)
+		}
+	}
+
+	// The nodes are visited in a different order than they were given, which
+	// is the order they are left in
+	visitedIDs := make([]string, len(visited))
+	shuffledIDs := make([]string, len(nc))
+	origIDs := make([]string, len(nodes))
+	for i := range nodes {
+		visitedIDs[i] = visited[i].ID
+		shuffledIDs[i] = nc[i].ID
+		origIDs[i] = nodes[i].ID
+	}
+	if !reflect.DeepEqual(visitedIDs, shuffledIDs) {
+		t.Fatalf("visited %v; shuffled %v", visitedIDs, shuffledIDs)
+	}
+	if reflect.DeepEqual(visitedIDs, origIDs) {
+		t.Fatalf("same order")
+	}
+}
+
 func TestDriverChecker(t *testing.T) {
 	_, ctx := testContext(t)
 	nodes := []*structs.Node{
@@ -123,6 +181,251 @@ func TestDriverChecker(t *testing.T) {
 	}
 }
 
+func TestDriverChecker_Capabilities(t *testing.T) {
+	_, ctx := testContext(t)
+	nodes := []*structs.Node{
+		mock.Node(),
+		mock.Node(),
+		mock.Node(),
+		mock.Node(),
+	}
+	pause := structs.DriverCapabilityAttribute("exec", structs.DriverCapabilityPause)
+	exec := structs.DriverCapabilityAttribute("exec", structs.DriverCapabilityExec)
+	for _, node := range nodes {
+		delete(node.Attributes, pause)
+		delete(node.Attributes, exec)
+	}
+	nodes[0].Attributes[pause] = "true"
+	nodes[0].Attributes[exec] = "true"
+	nodes[1].Attributes[pause] = "false"
+	nodes[1].Attributes[exec] = "true"
+	nodes[2].Attributes[pause] = "true"
+	nodes[3].Attributes[exec] = "true"
+
+	checker := NewDriverChecker(ctx, map[string]struct{}{"exec": struct{}{}})
+	checker.SetCapabilities(map[string]map[string]struct{}{
+		"exec": map[string]struct{}{
+			structs.DriverCapabilityPause: struct{}{},
+			structs.DriverCapabilityExec:  struct{}{},
+		},
+	})
+	cases := []struct {
+		Node   *structs.Node
+		Result bool
+	}{
+		{
+			Node:   nodes[0],
+			Result: true,
+		},
+		{
+			Node:   nodes[1],
+			Result: false,
+		},
+		{
+			Node:   nodes[2],
+			Result: false,
+		},
+		{
+			Node:   nodes[3],
+			Result: false,
+		},
+	}
+
+	for i, c := range cases {
+		if act := checker.Feasible(c.Node); act != c.Result {
+			t.Fatalf("case(%d) failed: got %v; want %v", i, act, c.Result)
+		}
+	}
+
+	// The missing capability is surfaced in the metrics
+	reason := fmt.Sprintf("driver %q missing capability %q", "exec", structs.DriverCapabilityPause)
+	if n := ctx.Metrics().ConstraintFiltered[reason]; n != 2 {
+		t.Fatalf("bad: %#v", ctx.Metrics().ConstraintFiltered)
+	}
+}
+
+func TestHostVolumeChecker(t *testing.T) {
+	_, ctx := testContext(t)
+	nodes := []*structs.Node{
+		mock.Node(),
+		mock.Node(),
+		mock.Node(),
+		mock.Node(),
+	}
+	nodes[1].HostVolumes = map[string]*structs.ClientHostVolumeConfig{
+		"data": &structs.ClientHostVolumeConfig{Name: "data", Path: "/srv/data"},
+	}
+	nodes[2].HostVolumes = map[string]*structs.ClientHostVolumeConfig{
+		"data": &structs.ClientHostVolumeConfig{Name: "data", Path: "/srv/data", ReadOnly: true},
+	}
+	nodes[3].HostVolumes = map[string]*structs.ClientHostVolumeConfig{
+		"logs": &structs.ClientHostVolumeConfig{Name: "logs", Path: "/var/log"},
+	}
+
+	checker := NewHostVolumeChecker(ctx, nil)
+	cases := []struct {
+		Node    *structs.Node
+		Volumes map[string]*structs.VolumeRequest
+		Result  bool
+	}{
+		{
+			Node:   nodes[0],
+			Result: true,
+		},
+		{
+			Node: nodes[0],
+			Volumes: map[string]*structs.VolumeRequest{
+				"foo": &structs.VolumeRequest{Name: "foo", Type: structs.VolumeTypeHost, Source: "data"},
+			},
+			Result: false,
+		},
+		{
+			Node: nodes[1],
+			Volumes: map[string]*structs.VolumeRequest{
+				"foo": &structs.VolumeRequest{Name: "foo", Type: structs.VolumeTypeHost, Source: "data"},
+			},
+			Result: true,
+		},
+		{
+			Node: nodes[2],
+			Volumes: map[string]*structs.VolumeRequest{
+				"foo": &structs.VolumeRequest{Name: "foo", Type: structs.VolumeTypeHost, Source: "data"},
+			},
+			Result: false,
+		},
+		{
+			Node: nodes[2],
+			Volumes: map[string]*structs.VolumeRequest{
+				"foo": &structs.VolumeRequest{Name: "foo", Type: structs.VolumeTypeHost, Source: "data", ReadOnly: true},
+			},
+			Result: true,
+		},
+		{
+			Node: nodes[3],
+			Volumes: map[string]*structs.VolumeRequest{
+				"foo": &structs.VolumeRequest{Name: "foo", Type: structs.VolumeTypeHost, Source: "data"},
+				"bar": &structs.VolumeRequest{Name: "bar", Type: structs.VolumeTypeHost, Source: "logs"},
+			},
+			Result: false,
+		},
+	}
+
+	for i, c := range cases {
+		checker.SetVolumes(c.Volumes)
+		if act := checker.Feasible(c.Node); act != c.Result {
+			t.Fatalf("case(%d) failed: got %v; want %v", i, act, c.Result)
+		}
+	}
+}
+
+func TestCSIVolumeIterator(t *testing.T) {
+	state, ctx := testContext(t)
+	nodes := []*structs.Node{
+		mock.Node(),
+		mock.Node(),
+		mock.Node(),
+		mock.Node(),
+	}
+	nodes[1].CSINodePlugins = map[string]*structs.CSIInfo{
+		"ebs": &structs.CSIInfo{PluginID: "ebs", Healthy: true},
+	}
+	nodes[2].CSINodePlugins = map[string]*structs.CSIInfo{
+		"ebs": &structs.CSIInfo{PluginID: "ebs", Healthy: false},
+	}
+	nodes[3].CSINodePlugins = map[string]*structs.CSIInfo{
+		"ebs": &structs.CSIInfo{
+			PluginID: "ebs",
+			Healthy:  true,
+			NodeInfo: &structs.CSINodeInfo{
+				AccessibleTopology: &structs.CSITopology{Segments: map[string]string{"zone": "b"}},
+			},
+		},
+	}
+
+	vol := mock.CSIVolume()
+	claimed := mock.CSIVolume()
+	zoned := mock.CSIVolume()
+	zoned.Topologies = []*structs.CSITopology{
+		&structs.CSITopology{Segments: map[string]string{"zone": "b"}},
+	}
+	if err := state.UpsertCSIVolumes(100, []*structs.CSIVolume{vol, claimed, zoned}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	alloc := mock.Alloc()
+	if err := state.CSIVolumeClaim(101, claimed.ID, &structs.CSIVolumeClaim{
+		AllocID: alloc.ID,
+		NodeID:  nodes[0].ID,
+		Mode:    structs.CSIVolumeClaimWrite,
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	job := mock.Job()
+	cases := []struct {
+		Source string
+		Nodes  []*structs.Node
+	}{
+		{
+			Source: vol.ID,
+			Nodes:  []*structs.Node{nodes[1], nodes[3]},
+		},
+		{
+			Source: "missing",
+			Nodes:  nil,
+		},
+		{
+			Source: claimed.ID,
+			Nodes:  nil,
+		},
+		{
+			Source: zoned.ID,
+			Nodes:  []*structs.Node{nodes[3]},
+		},
+	}
+
+	for i, c := range cases {
+		tg := job.TaskGroups[0].Copy()
+		tg.Volumes = map[string]*structs.VolumeRequest{
+			"data": &structs.VolumeRequest{Name: "data", Type: structs.VolumeTypeCSI, Source: c.Source},
+		}
+
+		static := NewStaticIterator(ctx, nodes)
+		iter := NewCSIVolumeIterator(ctx, static)
+		iter.SetJob(job)
+		iter.SetTaskGroup(tg)
+
+		out := collectFeasible(iter)
+		if !reflect.DeepEqual(out, c.Nodes) {
+			t.Fatalf("case(%d) failed: got %v; want %v", i, out, c.Nodes)
+		}
+	}
+}
+
+func TestDriverChecker_NetworkIsolation(t *testing.T) {
+	_, ctx := testContext(t)
+	nodes := []*structs.Node{
+		mock.Node(),
+		mock.Node(),
+		mock.Node(),
+	}
+	isolation := structs.DriverCapabilityAttribute("exec", structs.DriverCapabilityNetworkIsolation)
+	nodes[0].Attributes[isolation] = "host,group"
+	nodes[1].Attributes[isolation] = "host"
+	delete(nodes[2].Attributes, isolation)
+
+	checker := NewDriverChecker(ctx, map[string]struct{}{"exec": struct{}{}})
+	checker.SetCapabilities(map[string]map[string]struct{}{
+		"exec": map[string]struct{}{
+			structs.DriverCapabilityNetworkIsolation: struct{}{},
+		},
+	})
+	for i, result := range []bool{true, false, false} {
+		if act := checker.Feasible(nodes[i]); act != result {
+			t.Fatalf("case(%d) failed: got %v; want %v", i, act, result)
+		}
+	}
+}
+
 func TestConstraintChecker(t *testing.T) {
 	_, ctx := testContext(t)
 	nodes := []*structs.Node{
@@ -3,6 +3,7 @@ package scheduler
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -29,6 +30,10 @@ const (
 	// allocLost is the status used when an allocation is lost
 	allocLost = "alloc is lost since its node is down"
 
+	// allocNodeConstraints is the status used when an allocation is stopped
+	// since its node no longer satisfies its constraints
+	allocNodeConstraints = "alloc not needed since its node no longer satisfies its constraints"
+
 	// allocInPlace is the status used when speculating on an in-place update
 	allocInPlace = "alloc updating in-place"
 
@@ -75,6 +80,24 @@ type GenericScheduler struct {
 	blocked        *structs.Evaluation
 	failedTGAllocs map[string]*structs.AllocMetric
 	queuedAllocs   map[string]int
+
+	// attemptedNodes tracks, by allocation name, the nodes placements have
+	// failed on. It is seeded from the evaluation and carried over to any
+	// follow-up evaluations.
+	attemptedNodes map[string][]string
+
+	// quotaLimitReached is the quota that rejected placements of the plan.
+	quotaLimitReached string
+
+	// timing is the time spent checking feasibility and ranking nodes over
+	// all the scheduling attempts.
+	timing *structs.EvalTiming
+
+	// maintenanceWindows are the maintenance windows in effect by node class
+	maintenanceWindows map[string]*structs.MaintenanceWindow
+
+	// schedConfig is the scheduler configuration set by the operators
+	schedConfig *structs.SchedulerConfiguration
 }
 
 // NewServiceScheduler is a factory function to instantiate a new service scheduler
@@ -103,17 +126,20 @@ func NewBatchScheduler(logger *log.Logger, state State, planner Planner) Schedul
 func (s *GenericScheduler) Process(eval *structs.Evaluation) error {
 	// Store the evaluation
 	s.eval = eval
+	s.timing = new(structs.EvalTiming)
 
 	// Verify the evaluation trigger reason is understood
 	switch eval.TriggeredBy {
 	case structs.EvalTriggerJobRegister, structs.EvalTriggerNodeUpdate,
 		structs.EvalTriggerJobDeregister, structs.EvalTriggerRollingUpdate,
-		structs.EvalTriggerPeriodicJob, structs.EvalTriggerMaxPlans:
+		structs.EvalTriggerPeriodicJob, structs.EvalTriggerMaxPlans,
+		structs.EvalTriggerNodeDrain, structs.EvalTriggerCustom:
 	default:
 		desc := fmt.Sprintf("scheduler cannot handle '%s' evaluation reason",
 			eval.TriggeredBy)
 		return setStatus(s.logger, s.planner, s.eval, s.nextEval, s.blocked,
-			s.failedTGAllocs, structs.EvalStatusFailed, desc, s.queuedAllocs)
+			s.failedTGAllocs, structs.EvalStatusFailed, desc, s.queuedAllocs,
+			s.timing)
 	}
 
 	// Retry up to the maxScheduleAttempts and reset if progress is made.
@@ -132,7 +158,7 @@ func (s *GenericScheduler) Process(eval *structs.Evaluation) error {
 			}
 			if err := setStatus(s.logger, s.planner, s.eval, s.nextEval, s.blocked,
 				s.failedTGAllocs, statusErr.EvalStatus, err.Error(),
-				s.queuedAllocs); err != nil {
+				s.queuedAllocs, s.timing); err != nil {
 				mErr.Errors = append(mErr.Errors, err)
 			}
 			return mErr.ErrorOrNil()
@@ -142,17 +168,22 @@ func (s *GenericScheduler) Process(eval *structs.Evaluation) error {
 
 	// If the current evaluation is a blocked evaluation and we didn't place
 	// everything, do not update the status to complete.
-	if s.eval.Status == structs.EvalStatusBlocked && len(s.failedTGAllocs) != 0 {
+	if s.eval.Status == structs.EvalStatusBlocked &&
+		(len(s.failedTGAllocs) != 0 || s.quotaLimitReached != "") {
 		e := s.ctx.Eligibility()
 		newEval := s.eval.Copy()
 		newEval.EscapedComputedClass = e.HasEscaped()
 		newEval.ClassEligibility = e.GetClasses()
+		newEval.AttemptedNodes = s.attemptedNodes
+		newEval.QuotaLimitReached = s.quotaLimitReached
+		newEval.Timing = s.timing.Copy()
 		return s.planner.ReblockEval(newEval)
 	}
 
 	// Update the status to complete
 	return setStatus(s.logger, s.planner, s.eval, s.nextEval, s.blocked,
-		s.failedTGAllocs, structs.EvalStatusComplete, "", s.queuedAllocs)
+		s.failedTGAllocs, structs.EvalStatusComplete, "", s.queuedAllocs,
+		s.timing)
 }
 
 // createBlockedEval creates a blocked eval and submits it to the planner. If
@@ -168,6 +199,8 @@ func (s *GenericScheduler) createBlockedEval(planFailure bool) error {
 	}
 
 	s.blocked = s.eval.CreateBlockedEval(classEligibility, escaped)
+	s.blocked.AttemptedNodes = s.attemptedNodes
+	s.blocked.QuotaLimitReached = s.quotaLimitReached
 	if planFailure {
 		s.blocked.TriggeredBy = structs.EvalTriggerMaxPlans
 		s.blocked.StatusDescription = blockedEvalMaxPlanDesc
@@ -183,7 +216,7 @@ func (s *GenericScheduler) createBlockedEval(planFailure bool) error {
 func (s *GenericScheduler) process() (bool, error) {
 	// Lookup the Job by ID
 	var err error
-	s.job, err = s.state.JobByID(s.eval.JobID)
+	s.job, err = s.state.JobByID(s.eval.Namespace, s.eval.JobID)
 	if err != nil {
 		return false, fmt.Errorf("failed to get job '%s': %v",
 			s.eval.JobID, err)
@@ -199,9 +232,17 @@ func (s *GenericScheduler) process() (bool, error) {
 
 	// Reset the failed allocations
 	s.failedTGAllocs = nil
+	s.quotaLimitReached = ""
+
+	// Seed the attempted nodes from the evaluation
+	s.attemptedNodes = make(map[string][]string, len(s.eval.AttemptedNodes))
+	for name, nodes := range s.eval.AttemptedNodes {
+		s.attemptedNodes[name] = append([]string(nil), nodes...)
+	}
 
 	// Create an evaluation context
 	s.ctx = NewEvalContext(s.state, s.plan, s.logger)
+	s.ctx.SetTiming(s.timing)
 
 	// Construct the placement stack
 	s.stack = NewGenericStack(s.batch, s.ctx)
@@ -209,6 +250,21 @@ func (s *GenericScheduler) process() (bool, error) {
 		s.stack.SetJob(s.job)
 	}
 
+	// Apply the scheduler configuration set by the operators
+	_, schedConfig, err := s.state.SchedulerConfig()
+	if err != nil {
+		return false, fmt.Errorf("failed to get scheduler configuration: %v", err)
+	}
+	s.stack.SetSchedulerConfiguration(schedConfig)
+	s.schedConfig = schedConfig
+
+	// Avoid the node classes under maintenance
+	s.maintenanceWindows, err = maintenanceWindowsInEffect(s.state, time.Now())
+	if err != nil {
+		return false, err
+	}
+	s.stack.SetMaintenanceWindows(s.maintenanceWindows)
+
 	// Compute the target job allocations
 	if err := s.computeJobAllocs(); err != nil {
 		s.logger.Printf("[ERR] sched: %#v: %v", s.eval, err)
@@ -236,6 +292,7 @@ func (s *GenericScheduler) process() (bool, error) {
 	// to pickup from here after the stagger period.
 	if s.limitReached && s.nextEval == nil {
 		s.nextEval = s.eval.NextRollingEval(s.job.Update.Stagger)
+		s.nextEval.AttemptedNodes = s.attemptedNodes
 		if err := s.planner.CreateEval(s.nextEval); err != nil {
 			s.logger.Printf("[ERR] sched: %#v failed to make next eval for rolling update: %v", s.eval, err)
 			return false, err
@@ -254,6 +311,22 @@ func (s *GenericScheduler) process() (bool, error) {
 	// number of allocations successfully placed
 	adjustQueuedAllocations(s.logger, result, s.queuedAllocs)
 
+	// If placements were rejected because the namespace's quota is
+	// exhausted, retrying will not help. Block the evaluation until the
+	// quota's usage drops.
+	if result.QuotaLimitReached != "" {
+		s.quotaLimitReached = result.QuotaLimitReached
+		if s.eval.Status != structs.EvalStatusBlocked && s.blocked == nil {
+			if err := s.createBlockedEval(false); err != nil {
+				s.logger.Printf("[ERR] sched: %#v failed to make blocked eval: %v", s.eval, err)
+				return false, err
+			}
+			s.logger.Printf("[DEBUG] sched: %#v: quota %q reached, blocked eval '%s' created",
+				s.eval, s.quotaLimitReached, s.blocked.ID)
+		}
+		return true, nil
+	}
+
 	// If we got a state refresh, try again since we have stale data
 	if newState != nil {
 		s.logger.Printf("[DEBUG] sched: %#v: refresh forced", s.eval)
@@ -347,14 +420,8 @@ func (s *GenericScheduler) filterCompleteAllocs(allocs []*structs.Allocation) ([
 // computeJobAllocs is used to reconcile differences between the job,
 // existing allocations and node status to update the allocations.
 func (s *GenericScheduler) computeJobAllocs() error {
-	// Materialize all the task groups, job could be missing if deregistered
-	var groups map[string]*structs.TaskGroup
-	if s.job != nil {
-		groups = materializeTaskGroups(s.job)
-	}
-
 	// Lookup the allocations by JobID
-	allocs, err := s.state.AllocsByJob(s.eval.JobID)
+	allocs, err := s.state.AllocsByJob(s.eval.Namespace, s.eval.JobID)
 	if err != nil {
 		return fmt.Errorf("failed to get allocs for job '%s': %v",
 			s.eval.JobID, err)
@@ -374,10 +441,28 @@ func (s *GenericScheduler) computeJobAllocs() error {
 	// Filter out the allocations in a terminal state
 	allocs, terminalAllocs := s.filterCompleteAllocs(allocs)
 
+	// Materialize all the task groups, job could be missing if deregistered.
+	// The names of the existing allocations are kept, so scaling in stops the
+	// least useful allocations.
+	var groups map[string]*structs.TaskGroup
+	if s.job != nil {
+		groups = materializeScaledTaskGroups(s.job, allocs, tainted)
+	}
+
 	// Diff the required and existing allocations
 	diff := diffAllocs(s.job, tainted, groups, allocs, terminalAllocs)
 	s.logger.Printf("[DEBUG] sched: %#v: %#v", s.eval, diff)
 
+	// Migrate the allocations whose node no longer satisfies their
+	// constraints if the operators opted in
+	if s.schedConfig.MigrateOnNodeConstraints() {
+		violations, err := nodeConstraintViolations(s.ctx, s.job, diff)
+		if err != nil {
+			return err
+		}
+		diff.migrate = append(diff.migrate, violations...)
+	}
+
 	// Add all the allocs to stop
 	for _, e := range diff.stop {
 		s.plan.AppendUpdate(e.Alloc, structs.AllocDesiredStatusStop, allocNotNeeded, "")
@@ -389,7 +474,8 @@ func (s *GenericScheduler) computeJobAllocs() error {
 
 	if s.eval.AnnotatePlan {
 		s.plan.Annotations = &structs.PlanAnnotations{
-			DesiredTGUpdates: desiredUpdates(diff, inplaceUpdates, destructiveUpdates),
+			DesiredTGUpdates:   desiredUpdates(diff, inplaceUpdates, destructiveUpdates),
+			MaintenanceWindows: sortedMaintenanceWindows(s.maintenanceWindows),
 		}
 	}
 
@@ -452,6 +538,9 @@ func (s *GenericScheduler) computePlacements(place []allocTuple) error {
 			return err
 		}
 
+		// Penalize the nodes previous attempts of this allocation failed on
+		s.stack.SetPenaltyNodes(s.recordAttemptedNode(&missing))
+
 		// Attempt to match the task group
 		var option *RankedNode
 		if preferredNode != nil {
@@ -468,6 +557,7 @@ func (s *GenericScheduler) computePlacements(place []allocTuple) error {
 			// Create an allocation for this
 			alloc := &structs.Allocation{
 				ID:            structs.GenerateUUID(),
+				Namespace:     s.job.Namespace,
 				EvalID:        s.eval.ID,
 				Name:          missing.Name,
 				JobID:         s.job.ID,
@@ -479,7 +569,8 @@ func (s *GenericScheduler) computePlacements(place []allocTuple) error {
 				ClientStatus:  structs.AllocClientStatusPending,
 
 				SharedResources: &structs.Resources{
-					DiskMB: missing.TaskGroup.EphemeralDisk.SizeMB,
+					DiskMB:   missing.TaskGroup.EphemeralDisk.SizeMB,
+					Networks: option.GroupNetworks,
 				},
 			}
 
@@ -487,9 +578,13 @@ func (s *GenericScheduler) computePlacements(place []allocTuple) error {
 			// set the record the older allocation id so that they are chained
 			if missing.Alloc != nil {
 				alloc.PreviousAllocation = missing.Alloc.ID
+				now := time.Now()
+				delay := s.rescheduleDelay(missing.Alloc, now)
+				alloc.RescheduleTracker = missing.Alloc.NextRescheduleTracker(now, delay)
 			}
 
 			s.plan.AppendAlloc(alloc)
+			s.plan.AppendNodeIndex(option.Node)
 		} else {
 			// Lazy initialize the failed map
 			if s.failedTGAllocs == nil {
@@ -503,6 +598,63 @@ func (s *GenericScheduler) computePlacements(place []allocTuple) error {
 	return nil
 }
 
+// rescheduleDelay returns how long the allocation being replaced waited to be
+// replaced: the time since its last task event if it failed, or since the
+// status of its node last changed if it was lost.
+func (s *GenericScheduler) rescheduleDelay(alloc *structs.Allocation, now time.Time) time.Duration {
+	var since time.Time
+	switch alloc.ClientStatus {
+	case structs.AllocClientStatusFailed:
+		since = alloc.LastEventTime()
+	case structs.AllocClientStatusLost:
+		node, err := s.state.NodeByID(alloc.NodeID)
+		if err != nil || node == nil || node.StatusUpdatedAt == 0 {
+			return 0
+		}
+		since = time.Unix(node.StatusUpdatedAt, 0)
+	}
+	if since.IsZero() || since.After(now) {
+		return 0
+	}
+	return now.Sub(since)
+}
+
+// recordAttemptedNode records the node of the allocation being replaced if it
+// failed or was lost, along with the nodes of the allocations it replaced, and
+// returns the set of nodes placements of the allocation have been attempted
+// on. The reschedule history of the allocation is consulted as evaluations
+// created outside of the scheduler, such as on a client update, do not carry
+// the attempted nodes.
+func (s *GenericScheduler) recordAttemptedNode(allocTuple *allocTuple) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, nodeID := range s.attemptedNodes[allocTuple.Name] {
+		set[nodeID] = struct{}{}
+	}
+
+	record := func(nodeID string) {
+		if _, ok := set[nodeID]; ok || nodeID == "" {
+			return
+		}
+		set[nodeID] = struct{}{}
+		s.attemptedNodes[allocTuple.Name] = append(s.attemptedNodes[allocTuple.Name], nodeID)
+	}
+
+	alloc := allocTuple.Alloc
+	if alloc == nil {
+		return set
+	}
+	if alloc.RescheduleTracker != nil {
+		for _, event := range alloc.RescheduleTracker.Events {
+			record(event.PrevNodeID)
+		}
+	}
+	switch alloc.ClientStatus {
+	case structs.AllocClientStatusFailed, structs.AllocClientStatusLost:
+		record(alloc.NodeID)
+	}
+	return set
+}
+
 // findPreferredNode finds the preferred node for an allocation
 func (s *GenericScheduler) findPreferredNode(allocTuple *allocTuple) (node *structs.Node, err error) {
 	if allocTuple.Alloc != nil {
@@ -514,7 +666,7 @@ func (s *GenericScheduler) findPreferredNode(allocTuple *allocTuple) (node *stru
 		if taskGroup.EphemeralDisk.Sticky == true {
 			var preferredNode *structs.Node
 			preferredNode, err = s.state.NodeByID(allocTuple.Alloc.NodeID)
-			if preferredNode.Ready() {
+			if preferredNode.Ready() && !underHardMaintenance(s.maintenanceWindows, preferredNode) {
 				node = preferredNode
 			}
 		}
@@ -26,6 +26,7 @@ func TestServiceSched_JobRegister(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -66,8 +67,17 @@ func TestServiceSched_JobRegister(t *testing.T) {
 		t.Fatalf("bad: %#v", plan)
 	}
 
+	// Ensure the plan records the version of the nodes placed on
+	for nodeID := range plan.NodeAllocation {
+		node, err := h.State.NodeByID(nodeID)
+		noErr(t, err)
+		if index, ok := plan.NodeModifyIndex[nodeID]; !ok || index != node.ModifyIndex {
+			t.Fatalf("bad: %#v", plan.NodeModifyIndex)
+		}
+	}
+
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure all allocations placed
@@ -89,6 +99,11 @@ func TestServiceSched_JobRegister(t *testing.T) {
 	}
 
 	h.AssertEvalStatus(t, structs.EvalStatusComplete)
+
+	// Ensure the time spent placing the allocations was recorded
+	if timing := h.Evals[0].Timing; timing == nil || timing.Feasibility+timing.Ranking == 0 {
+		t.Fatalf("bad: %#v", h.Evals[0])
+	}
 }
 
 func TestServiceSched_JobRegister_StickyAllocs(t *testing.T) {
@@ -107,6 +122,7 @@ func TestServiceSched_JobRegister_StickyAllocs(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -135,6 +151,7 @@ func TestServiceSched_JobRegister_StickyAllocs(t *testing.T) {
 
 	// Create a mock evaluation to handle the update
 	eval = &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerNodeUpdate,
@@ -177,6 +194,7 @@ func TestServiceSched_JobRegister_DiskConstraints(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -215,7 +233,7 @@ func TestServiceSched_JobRegister_DiskConstraints(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure only one allocation was placed
@@ -241,6 +259,7 @@ func TestServiceSched_JobRegister_Annotate(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:    structs.DefaultNamespace,
 		ID:           structs.GenerateUUID(),
 		Priority:     job.Priority,
 		TriggeredBy:  structs.EvalTriggerJobRegister,
@@ -270,7 +289,7 @@ func TestServiceSched_JobRegister_Annotate(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure all allocations placed
@@ -317,6 +336,7 @@ func TestServiceSched_JobRegister_CountZero(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -335,7 +355,7 @@ func TestServiceSched_JobRegister_CountZero(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure no allocations placed
@@ -356,6 +376,7 @@ func TestServiceSched_JobRegister_AllocFail(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -437,6 +458,7 @@ func TestServiceSched_JobRegister_CreateBlockedEval(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -533,6 +555,7 @@ func TestServiceSched_JobRegister_FeasibleAndInfeasibleTG(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -561,7 +584,7 @@ func TestServiceSched_JobRegister_FeasibleAndInfeasibleTG(t *testing.T) {
 	}
 
 	// Ensure two allocations placed
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 	if len(out) != 2 {
 		t.Fatalf("bad: %#v", out)
@@ -607,6 +630,7 @@ func TestServiceSched_EvaluateMaxPlanEval(t *testing.T) {
 
 	// Create a mock blocked evaluation
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Status:      structs.EvalStatusBlocked,
 		Priority:    job.Priority,
@@ -647,6 +671,7 @@ func TestServiceSched_Plan_Partial_Progress(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -680,7 +705,7 @@ func TestServiceSched_Plan_Partial_Progress(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure only one allocations placed
@@ -705,6 +730,7 @@ func TestServiceSched_EvaluateBlockedEval(t *testing.T) {
 
 	// Create a mock blocked evaluation
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Status:      structs.EvalStatusBlocked,
 		Priority:    job.Priority,
@@ -755,6 +781,7 @@ func TestServiceSched_EvaluateBlockedEval_Finished(t *testing.T) {
 
 	// Create a mock blocked evaluation
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Status:      structs.EvalStatusBlocked,
 		Priority:    job.Priority,
@@ -800,7 +827,7 @@ func TestServiceSched_EvaluateBlockedEval_Finished(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure all allocations placed
@@ -871,6 +898,7 @@ func TestServiceSched_JobModify(t *testing.T) {
 
 	// Create a mock evaluation to deal with drain
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    50,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -908,7 +936,7 @@ func TestServiceSched_JobModify(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure all allocations placed
@@ -953,6 +981,7 @@ func TestServiceSched_JobModify_IncrCount_NodeLimit(t *testing.T) {
 
 	// Create a mock evaluation to deal with drain
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    50,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -999,7 +1028,7 @@ func TestServiceSched_JobModify_IncrCount_NodeLimit(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure all allocations placed
@@ -1058,6 +1087,7 @@ func TestServiceSched_JobModify_CountZero(t *testing.T) {
 
 	// Create a mock evaluation to deal with drain
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    50,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -1095,7 +1125,7 @@ func TestServiceSched_JobModify_CountZero(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure all allocations placed
@@ -1107,6 +1137,90 @@ func TestServiceSched_JobModify_CountZero(t *testing.T) {
 	h.AssertEvalStatus(t, structs.EvalStatusComplete)
 }
 
+func TestServiceSched_JobModify_ScaleIn(t *testing.T) {
+	h := NewHarness(t)
+
+	// Create some nodes
+	var nodes []*structs.Node
+	for i := 0; i < 10; i++ {
+		node := mock.Node()
+		nodes = append(nodes, node)
+		noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+	}
+
+	// Generate a fake job with running allocations, one of which is still
+	// pending
+	job := mock.Job()
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	var allocs []*structs.Allocation
+	for i := 0; i < 10; i++ {
+		alloc := mock.Alloc()
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.NodeID = nodes[i].ID
+		alloc.Name = fmt.Sprintf("my-job.web[%d]", i)
+		alloc.ClientStatus = structs.AllocClientStatusRunning
+		allocs = append(allocs, alloc)
+	}
+	allocs[3].ClientStatus = structs.AllocClientStatusPending
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), allocs))
+
+	// Scale the job in
+	job2 := job.Copy()
+	job2.TaskGroups[0].Count = 7
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job2))
+
+	// Create a mock evaluation to deal with the scaling
+	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
+		ID:          structs.GenerateUUID(),
+		Priority:    50,
+		TriggeredBy: structs.EvalTriggerJobRegister,
+		JobID:       job.ID,
+	}
+
+	// Process the evaluation
+	err := h.Process(NewServiceScheduler, eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure a single plan
+	if len(h.Plans) != 1 {
+		t.Fatalf("bad: %#v", h.Plans)
+	}
+	plan := h.Plans[0]
+
+	// Ensure the plan stopped the pending allocation and the running ones
+	// with the highest indexes
+	stopped := make(map[string]struct{})
+	for _, updateList := range plan.NodeUpdate {
+		for _, alloc := range updateList {
+			stopped[alloc.Name] = struct{}{}
+		}
+	}
+	expected := map[string]struct{}{
+		"my-job.web[3]": struct{}{},
+		"my-job.web[8]": struct{}{},
+		"my-job.web[9]": struct{}{},
+	}
+	if !reflect.DeepEqual(stopped, expected) {
+		t.Fatalf("bad: %#v", stopped)
+	}
+
+	// Ensure the plan didn't place any new allocation
+	for _, allocList := range plan.NodeAllocation {
+		for _, alloc := range allocList {
+			if _, ok := expected[alloc.Name]; ok {
+				t.Fatalf("bad: %#v", alloc)
+			}
+		}
+	}
+
+	h.AssertEvalStatus(t, structs.EvalStatusComplete)
+}
+
 func TestServiceSched_JobModify_Rolling(t *testing.T) {
 	h := NewHarness(t)
 
@@ -1147,6 +1261,7 @@ func TestServiceSched_JobModify_Rolling(t *testing.T) {
 
 	// Create a mock evaluation to deal with drain
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    50,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -1241,6 +1356,7 @@ func TestServiceSched_JobModify_InPlace(t *testing.T) {
 
 	// Create a mock evaluation to deal with drain
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    50,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -1283,7 +1399,7 @@ func TestServiceSched_JobModify_InPlace(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure all allocations placed
@@ -1326,6 +1442,7 @@ func TestServiceSched_JobDeregister(t *testing.T) {
 
 	// Create a mock evaluation to deregister the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    50,
 		TriggeredBy: structs.EvalTriggerJobDeregister,
@@ -1350,7 +1467,7 @@ func TestServiceSched_JobDeregister(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure that the job field on the allocation is still populated
@@ -1369,6 +1486,66 @@ func TestServiceSched_JobDeregister(t *testing.T) {
 	h.AssertEvalStatus(t, structs.EvalStatusComplete)
 }
 
+func TestServiceSched_JobDeregister_Stopped(t *testing.T) {
+	h := NewHarness(t)
+
+	// Generate a fake job with allocations that has been stopped
+	job := mock.Job()
+	job.Stop = true
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	var allocs []*structs.Allocation
+	for i := 0; i < 10; i++ {
+		alloc := mock.Alloc()
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.Name = fmt.Sprintf("my-job.web[%d]", i)
+		allocs = append(allocs, alloc)
+	}
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), allocs))
+
+	// Create a mock evaluation to deregister the job
+	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
+		ID:          structs.GenerateUUID(),
+		Priority:    50,
+		TriggeredBy: structs.EvalTriggerJobDeregister,
+		JobID:       job.ID,
+	}
+
+	// Process the evaluation
+	err := h.Process(NewServiceScheduler, eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure a single plan
+	if len(h.Plans) != 1 {
+		t.Fatalf("bad: %#v", h.Plans)
+	}
+	plan := h.Plans[0]
+
+	// Ensure the plan doesn't place anything and evicts all allocations
+	if len(plan.NodeAllocation) != 0 {
+		t.Fatalf("bad: %#v", plan)
+	}
+	if len(plan.NodeUpdate["12345678-abcd-efab-cdef-123456789abc"]) != len(allocs) {
+		t.Fatalf("bad: %#v", plan)
+	}
+
+	// Lookup the allocations by JobID
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
+	noErr(t, err)
+
+	// Ensure no remaining allocations
+	out, _ = structs.FilterTerminalAllocs(out)
+	if len(out) != 0 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	h.AssertEvalStatus(t, structs.EvalStatusComplete)
+}
+
 func TestServiceSched_NodeDown(t *testing.T) {
 	h := NewHarness(t)
 
@@ -1408,10 +1585,11 @@ func TestServiceSched_NodeDown(t *testing.T) {
 	}
 
 	// Mark the node as down
-	noErr(t, h.State.UpdateNodeStatus(h.NextIndex(), node.ID, structs.NodeStatusDown))
+	noErr(t, h.State.UpdateNodeStatus(h.NextIndex(), node.ID, structs.NodeStatusDown, nil))
 
 	// Create a mock evaluation to deal with drain
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    50,
 		TriggeredBy: structs.EvalTriggerNodeUpdate,
@@ -1476,6 +1654,7 @@ func TestServiceSched_NodeUpdate(t *testing.T) {
 
 	// Create a mock evaluation which won't trigger any new placements
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    50,
 		TriggeredBy: structs.EvalTriggerNodeUpdate,
@@ -1526,6 +1705,7 @@ func TestServiceSched_NodeDrain(t *testing.T) {
 
 	// Create a mock evaluation to deal with drain
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    50,
 		TriggeredBy: structs.EvalTriggerNodeUpdate,
@@ -1560,7 +1740,7 @@ func TestServiceSched_NodeDrain(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure all allocations placed
@@ -1572,16 +1752,23 @@ func TestServiceSched_NodeDrain(t *testing.T) {
 	h.AssertEvalStatus(t, structs.EvalStatusComplete)
 }
 
-func TestServiceSched_NodeDrain_Down(t *testing.T) {
+func TestServiceSched_NodeDrain_MarkedAllocs(t *testing.T) {
 	h := NewHarness(t)
 
-	// Register a draining node
+	// Register a node drained with a drain strategy
 	node := mock.Node()
 	node.Drain = true
-	node.Status = structs.NodeStatusDown
+	node.DrainStrategy = &structs.DrainStrategy{}
 	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
 
-	// Generate a fake job with allocations
+	// Create some nodes
+	for i := 0; i < 10; i++ {
+		node := mock.Node()
+		noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+	}
+
+	// Generate a fake job with allocations, marking two of them to be
+	// migrated by the drainer
 	job := mock.Job()
 	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
 
@@ -1592,42 +1779,17 @@ func TestServiceSched_NodeDrain_Down(t *testing.T) {
 		alloc.JobID = job.ID
 		alloc.NodeID = node.ID
 		alloc.Name = fmt.Sprintf("my-job.web[%d]", i)
+		alloc.DesiredTransition.Migrate = i < 2
 		allocs = append(allocs, alloc)
 	}
 	noErr(t, h.State.UpsertAllocs(h.NextIndex(), allocs))
 
-	// Set the desired state of the allocs to stop
-	var stop []*structs.Allocation
-	for i := 0; i < 10; i++ {
-		newAlloc := allocs[i].Copy()
-		newAlloc.ClientStatus = structs.AllocDesiredStatusStop
-		stop = append(stop, newAlloc)
-	}
-	noErr(t, h.State.UpsertAllocs(h.NextIndex(), stop))
-
-	// Mark some of the allocations as running
-	var running []*structs.Allocation
-	for i := 4; i < 6; i++ {
-		newAlloc := stop[i].Copy()
-		newAlloc.ClientStatus = structs.AllocClientStatusRunning
-		running = append(running, newAlloc)
-	}
-	noErr(t, h.State.UpdateAllocsFromClient(h.NextIndex(), running))
-
-	// Mark some of the allocations as complete
-	var complete []*structs.Allocation
-	for i := 6; i < 10; i++ {
-		newAlloc := stop[i].Copy()
-		newAlloc.ClientStatus = structs.AllocClientStatusComplete
-		complete = append(complete, newAlloc)
-	}
-	noErr(t, h.State.UpdateAllocsFromClient(h.NextIndex(), complete))
-
-	// Create a mock evaluation to deal with the node update
+	// Create a mock evaluation to deal with drain
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    50,
-		TriggeredBy: structs.EvalTriggerNodeUpdate,
+		TriggeredBy: structs.EvalTriggerNodeDrain,
 		JobID:       job.ID,
 		NodeID:      node.ID,
 	}
@@ -1644,46 +1806,58 @@ func TestServiceSched_NodeDrain_Down(t *testing.T) {
 	}
 	plan := h.Plans[0]
 
-	// Ensure the plan evicted non terminal allocs
-	if len(plan.NodeUpdate[node.ID]) != 6 {
+	// Ensure the plan only evicted the marked allocs
+	update := plan.NodeUpdate[node.ID]
+	if len(update) != 2 {
 		t.Fatalf("bad: %#v", plan)
 	}
-
-	// Ensure that all the allocations which were in running or pending state
-	// has been marked as lost
-	var lostAllocs []string
-	for _, alloc := range plan.NodeUpdate[node.ID] {
-		lostAllocs = append(lostAllocs, alloc.ID)
+	for _, alloc := range update {
+		if alloc.ID != allocs[0].ID && alloc.ID != allocs[1].ID {
+			t.Fatalf("bad: %#v", alloc)
+		}
 	}
-	sort.Strings(lostAllocs)
 
-	var expectedLostAllocs []string
-	for i := 0; i < 6; i++ {
-		expectedLostAllocs = append(expectedLostAllocs, allocs[i].ID)
+	// Ensure the plan replaced them
+	var planned []*structs.Allocation
+	for _, allocList := range plan.NodeAllocation {
+		planned = append(planned, allocList...)
 	}
-	sort.Strings(expectedLostAllocs)
-
-	if !reflect.DeepEqual(expectedLostAllocs, lostAllocs) {
-		t.Fatalf("expected: %v, actual: %v", expectedLostAllocs, lostAllocs)
+	if len(planned) != 2 {
+		t.Fatalf("bad: %#v", plan)
 	}
 
 	h.AssertEvalStatus(t, structs.EvalStatusComplete)
 }
 
-func TestServiceSched_NodeDrain_Queued_Allocations(t *testing.T) {
+func TestServiceSched_NodeConstraintsChanged(t *testing.T) {
 	h := NewHarness(t)
 
-	// Register a draining node
+	// Register a node that no longer satisfies the constraints of the job
 	node := mock.Node()
+	node.Meta["rack"] = "r2"
+	noErr(t, node.ComputeClass())
 	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
 
-	// Generate a fake job with allocations and an update policy.
+	// Create some nodes satisfying them
+	for i := 0; i < 10; i++ {
+		node := mock.Node()
+		node.Meta["rack"] = "r1"
+		noErr(t, node.ComputeClass())
+		noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+	}
+
+	// Generate a fake job constrained on the rack with allocations on the
+	// first node
 	job := mock.Job()
-	job.TaskGroups[0].Count = 2
+	job.Constraints = append(job.Constraints, &structs.Constraint{
+		LTarget: "${meta.rack}",
+		RTarget: "r1",
+		Operand: "=",
+	})
 	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
 
 	var allocs []*structs.Allocation
-	for i := 0; i < 2; i++ {
+	for i := 0; i < 10; i++ {
 		alloc := mock.Alloc()
 		alloc.Job = job
 		alloc.JobID = job.ID
@@ -1693,11 +1867,9 @@ func TestServiceSched_NodeDrain_Queued_Allocations(t *testing.T) {
 	}
 	noErr(t, h.State.UpsertAllocs(h.NextIndex(), allocs))
 
-	node.Drain = true
-	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
-
-	// Create a mock evaluation to deal with drain
+	// Create a mock evaluation to deal with the node update
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    50,
 		TriggeredBy: structs.EvalTriggerNodeUpdate,
@@ -1705,25 +1877,64 @@ func TestServiceSched_NodeDrain_Queued_Allocations(t *testing.T) {
 		NodeID:      node.ID,
 	}
 
-	// Process the evaluation
+	// By default the allocations are left in place
 	err := h.Process(NewServiceScheduler, eval)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	if len(h.Plans) != 0 {
+		t.Fatalf("bad: %#v", h.Plans)
+	}
 
-	queued := h.Evals[0].QueuedAllocations["web"]
-	if queued != 2 {
-		t.Fatalf("expected: %v, actual: %v", 2, queued)
+	// Opt in to migrating them
+	config := structs.DefaultSchedulerConfiguration()
+	config.NodeConstraintPolicy = structs.NodeConstraintPolicyMigrate
+	noErr(t, h.State.SchedulerSetConfig(h.NextIndex(), config))
+
+	err = h.Process(NewServiceScheduler, eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure a single plan
+	if len(h.Plans) != 1 {
+		t.Fatalf("bad: %#v", h.Plans)
+	}
+	plan := h.Plans[0]
+
+	// Ensure the plan evicted all allocs
+	if len(plan.NodeUpdate[node.ID]) != len(allocs) {
+		t.Fatalf("bad: %#v", plan)
+	}
+
+	// Ensure the plan allocated on the other nodes
+	var planned []*structs.Allocation
+	for nodeID, allocList := range plan.NodeAllocation {
+		if nodeID == node.ID {
+			t.Fatalf("bad: %#v", plan)
+		}
+		planned = append(planned, allocList...)
+	}
+	if len(planned) != 10 {
+		t.Fatalf("bad: %#v", plan)
+	}
+
+	// Both evaluations completed
+	if len(h.Evals) != 2 || h.Evals[1].Status != structs.EvalStatusComplete {
+		t.Fatalf("bad: %#v", h.Evals)
 	}
 }
 
-func TestServiceSched_NodeDrain_UpdateStrategy(t *testing.T) {
+func TestServiceSched_NodeMaintenance(t *testing.T) {
 	h := NewHarness(t)
 
-	// Register a draining node
+	// Register a node whose class is under hard maintenance
 	node := mock.Node()
-	node.Drain = true
+	node.NodeClass = "maintenance"
 	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+	window := mock.MaintenanceWindow()
+	window.NodeClass = node.NodeClass
+	noErr(t, h.State.UpsertMaintenanceWindows(h.NextIndex(), []*structs.MaintenanceWindow{window}))
 
 	// Create some nodes
 	for i := 0; i < 10; i++ {
@@ -1731,13 +1942,8 @@ func TestServiceSched_NodeDrain_UpdateStrategy(t *testing.T) {
 		noErr(t, h.State.UpsertNode(h.NextIndex(), node))
 	}
 
-	// Generate a fake job with allocations and an update policy.
+	// Generate a fake job with allocations on the node under maintenance
 	job := mock.Job()
-	mp := 5
-	job.Update = structs.UpdateStrategy{
-		Stagger:     time.Second,
-		MaxParallel: mp,
-	}
 	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
 
 	var allocs []*structs.Allocation
@@ -1751,13 +1957,15 @@ func TestServiceSched_NodeDrain_UpdateStrategy(t *testing.T) {
 	}
 	noErr(t, h.State.UpsertAllocs(h.NextIndex(), allocs))
 
-	// Create a mock evaluation to deal with drain
+	// Create a mock evaluation to deal with the maintenance
 	eval := &structs.Evaluation{
-		ID:          structs.GenerateUUID(),
-		Priority:    50,
-		TriggeredBy: structs.EvalTriggerNodeUpdate,
-		JobID:       job.ID,
-		NodeID:      node.ID,
+		Namespace:    structs.DefaultNamespace,
+		ID:           structs.GenerateUUID(),
+		Priority:     50,
+		TriggeredBy:  structs.EvalTriggerNodeUpdate,
+		JobID:        job.ID,
+		NodeID:       node.ID,
+		AnnotatePlan: true,
 	}
 
 	// Process the evaluation
@@ -1773,16 +1981,245 @@ func TestServiceSched_NodeDrain_UpdateStrategy(t *testing.T) {
 	plan := h.Plans[0]
 
 	// Ensure the plan evicted all allocs
-	if len(plan.NodeUpdate[node.ID]) != mp {
+	if len(plan.NodeUpdate[node.ID]) != len(allocs) {
 		t.Fatalf("bad: %#v", plan)
 	}
 
-	// Ensure the plan allocated
+	// Ensure the plan allocated elsewhere
 	var planned []*structs.Allocation
-	for _, allocList := range plan.NodeAllocation {
+	for nodeID, allocList := range plan.NodeAllocation {
+		if nodeID == node.ID {
+			t.Fatalf("placed on the node under maintenance: %#v", plan)
+		}
 		planned = append(planned, allocList...)
 	}
-	if len(planned) != mp {
+	if len(planned) != 10 {
+		t.Fatalf("bad: %#v", plan)
+	}
+
+	// Ensure the window is surfaced in the annotations
+	if plan.Annotations == nil || len(plan.Annotations.MaintenanceWindows) != 1 ||
+		plan.Annotations.MaintenanceWindows[0].ID != window.ID {
+		t.Fatalf("bad: %#v", plan.Annotations)
+	}
+
+	h.AssertEvalStatus(t, structs.EvalStatusComplete)
+}
+
+func TestServiceSched_NodeDrain_Down(t *testing.T) {
+	h := NewHarness(t)
+
+	// Register a draining node
+	node := mock.Node()
+	node.Drain = true
+	node.Status = structs.NodeStatusDown
+	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+
+	// Generate a fake job with allocations
+	job := mock.Job()
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	var allocs []*structs.Allocation
+	for i := 0; i < 10; i++ {
+		alloc := mock.Alloc()
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.NodeID = node.ID
+		alloc.Name = fmt.Sprintf("my-job.web[%d]", i)
+		allocs = append(allocs, alloc)
+	}
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), allocs))
+
+	// Set the desired state of the allocs to stop
+	var stop []*structs.Allocation
+	for i := 0; i < 10; i++ {
+		newAlloc := allocs[i].Copy()
+		newAlloc.ClientStatus = structs.AllocDesiredStatusStop
+		stop = append(stop, newAlloc)
+	}
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), stop))
+
+	// Mark some of the allocations as running
+	var running []*structs.Allocation
+	for i := 4; i < 6; i++ {
+		newAlloc := stop[i].Copy()
+		newAlloc.ClientStatus = structs.AllocClientStatusRunning
+		running = append(running, newAlloc)
+	}
+	noErr(t, h.State.UpdateAllocsFromClient(h.NextIndex(), running))
+
+	// Mark some of the allocations as complete
+	var complete []*structs.Allocation
+	for i := 6; i < 10; i++ {
+		newAlloc := stop[i].Copy()
+		newAlloc.ClientStatus = structs.AllocClientStatusComplete
+		complete = append(complete, newAlloc)
+	}
+	noErr(t, h.State.UpdateAllocsFromClient(h.NextIndex(), complete))
+
+	// Create a mock evaluation to deal with the node update
+	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
+		ID:          structs.GenerateUUID(),
+		Priority:    50,
+		TriggeredBy: structs.EvalTriggerNodeUpdate,
+		JobID:       job.ID,
+		NodeID:      node.ID,
+	}
+
+	// Process the evaluation
+	err := h.Process(NewServiceScheduler, eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure a single plan
+	if len(h.Plans) != 1 {
+		t.Fatalf("bad: %#v", h.Plans)
+	}
+	plan := h.Plans[0]
+
+	// Ensure the plan evicted non terminal allocs
+	if len(plan.NodeUpdate[node.ID]) != 6 {
+		t.Fatalf("bad: %#v", plan)
+	}
+
+	// Ensure that all the allocations which were in running or pending state
+	// has been marked as lost
+	var lostAllocs []string
+	for _, alloc := range plan.NodeUpdate[node.ID] {
+		lostAllocs = append(lostAllocs, alloc.ID)
+	}
+	sort.Strings(lostAllocs)
+
+	var expectedLostAllocs []string
+	for i := 0; i < 6; i++ {
+		expectedLostAllocs = append(expectedLostAllocs, allocs[i].ID)
+	}
+	sort.Strings(expectedLostAllocs)
+
+	if !reflect.DeepEqual(expectedLostAllocs, lostAllocs) {
+		t.Fatalf("expected: %v, actual: %v", expectedLostAllocs, lostAllocs)
+	}
+
+	h.AssertEvalStatus(t, structs.EvalStatusComplete)
+}
+
+func TestServiceSched_NodeDrain_Queued_Allocations(t *testing.T) {
+	h := NewHarness(t)
+
+	// Register a draining node
+	node := mock.Node()
+	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+
+	// Generate a fake job with allocations and an update policy.
+	job := mock.Job()
+	job.TaskGroups[0].Count = 2
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	var allocs []*structs.Allocation
+	for i := 0; i < 2; i++ {
+		alloc := mock.Alloc()
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.NodeID = node.ID
+		alloc.Name = fmt.Sprintf("my-job.web[%d]", i)
+		allocs = append(allocs, alloc)
+	}
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), allocs))
+
+	node.Drain = true
+	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+
+	// Create a mock evaluation to deal with drain
+	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
+		ID:          structs.GenerateUUID(),
+		Priority:    50,
+		TriggeredBy: structs.EvalTriggerNodeUpdate,
+		JobID:       job.ID,
+		NodeID:      node.ID,
+	}
+
+	// Process the evaluation
+	err := h.Process(NewServiceScheduler, eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	queued := h.Evals[0].QueuedAllocations["web"]
+	if queued != 2 {
+		t.Fatalf("expected: %v, actual: %v", 2, queued)
+	}
+}
+
+func TestServiceSched_NodeDrain_UpdateStrategy(t *testing.T) {
+	h := NewHarness(t)
+
+	// Register a draining node
+	node := mock.Node()
+	node.Drain = true
+	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+
+	// Create some nodes
+	for i := 0; i < 10; i++ {
+		node := mock.Node()
+		noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+	}
+
+	// Generate a fake job with allocations and an update policy.
+	job := mock.Job()
+	mp := 5
+	job.Update = structs.UpdateStrategy{
+		Stagger:     time.Second,
+		MaxParallel: mp,
+	}
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	var allocs []*structs.Allocation
+	for i := 0; i < 10; i++ {
+		alloc := mock.Alloc()
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.NodeID = node.ID
+		alloc.Name = fmt.Sprintf("my-job.web[%d]", i)
+		allocs = append(allocs, alloc)
+	}
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), allocs))
+
+	// Create a mock evaluation to deal with drain
+	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
+		ID:          structs.GenerateUUID(),
+		Priority:    50,
+		TriggeredBy: structs.EvalTriggerNodeUpdate,
+		JobID:       job.ID,
+		NodeID:      node.ID,
+	}
+
+	// Process the evaluation
+	err := h.Process(NewServiceScheduler, eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure a single plan
+	if len(h.Plans) != 1 {
+		t.Fatalf("bad: %#v", h.Plans)
+	}
+	plan := h.Plans[0]
+
+	// Ensure the plan evicted all allocs
+	if len(plan.NodeUpdate[node.ID]) != mp {
+		t.Fatalf("bad: %#v", plan)
+	}
+
+	// Ensure the plan allocated
+	var planned []*structs.Allocation
+	for _, allocList := range plan.NodeAllocation {
+		planned = append(planned, allocList...)
+	}
+	if len(planned) != mp {
 		t.Fatalf("bad: %#v", plan)
 	}
 
@@ -1811,6 +2248,7 @@ func TestServiceSched_RetryLimit(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -1829,7 +2267,7 @@ func TestServiceSched_RetryLimit(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure no allocations placed
@@ -1841,6 +2279,57 @@ func TestServiceSched_RetryLimit(t *testing.T) {
 	h.AssertEvalStatus(t, structs.EvalStatusFailed)
 }
 
+func TestServiceSched_QuotaLimitReached(t *testing.T) {
+	h := NewHarness(t)
+	h.Planner = &QuotaLimitPlan{Harness: h, Quota: "foo"}
+
+	// Create some nodes
+	for i := 0; i < 10; i++ {
+		node := mock.Node()
+		noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+	}
+
+	// Create a job
+	job := mock.Job()
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	// Create a mock evaluation to register the job
+	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
+		ID:          structs.GenerateUUID(),
+		Priority:    job.Priority,
+		TriggeredBy: structs.EvalTriggerJobRegister,
+		JobID:       job.ID,
+	}
+
+	// Process the evaluation
+	err := h.Process(NewServiceScheduler, eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure the plan was not retried
+	if len(h.Plans) != 1 {
+		t.Fatalf("bad: %#v", h.Plans)
+	}
+
+	// Ensure a blocked eval limited by the quota was created
+	if len(h.CreateEvals) != 1 {
+		t.Fatalf("bad: %#v", h.CreateEvals)
+	}
+	blocked := h.CreateEvals[0]
+	if blocked.Status != structs.EvalStatusBlocked || blocked.QuotaLimitReached != "foo" {
+		t.Fatalf("bad: %#v", blocked)
+	}
+
+	// Ensure the allocations are still queued
+	if queued := h.Evals[0].QueuedAllocations["web"]; queued != 10 {
+		t.Fatalf("expected: %v, actual: %v", 10, queued)
+	}
+
+	h.AssertEvalStatus(t, structs.EvalStatusComplete)
+}
+
 func TestBatchSched_Run_CompleteAlloc(t *testing.T) {
 	h := NewHarness(t)
 
@@ -1864,6 +2353,7 @@ func TestBatchSched_Run_CompleteAlloc(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -1882,7 +2372,7 @@ func TestBatchSched_Run_CompleteAlloc(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure no allocations placed
@@ -1917,6 +2407,7 @@ func TestBatchSched_Run_DrainedAlloc(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -1935,7 +2426,7 @@ func TestBatchSched_Run_DrainedAlloc(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure a replacement alloc was placed.
@@ -1969,6 +2460,7 @@ func TestBatchSched_Run_FailedAlloc(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -1987,7 +2479,7 @@ func TestBatchSched_Run_FailedAlloc(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure a replacement alloc was placed.
@@ -2005,6 +2497,277 @@ func TestBatchSched_Run_FailedAlloc(t *testing.T) {
 	h.AssertEvalStatus(t, structs.EvalStatusComplete)
 }
 
+func TestBatchSched_Run_FailedAlloc_RescheduleTracker(t *testing.T) {
+	h := NewHarness(t)
+
+	// Create a node
+	node := mock.Node()
+	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+
+	// Create a job
+	job := mock.Job()
+	job.TaskGroups[0].Count = 1
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	// Create a failed alloc that was itself already rescheduled once
+	alloc := mock.Alloc()
+	alloc.Job = job
+	alloc.JobID = job.ID
+	alloc.NodeID = node.ID
+	alloc.Name = "my-job.web[0]"
+	alloc.ClientStatus = structs.AllocClientStatusFailed
+	alloc.TaskStates = map[string]*structs.TaskState{
+		"web": &structs.TaskState{
+			State: structs.TaskStateDead,
+			Events: []*structs.TaskEvent{
+				{
+					Type: structs.TaskTerminated,
+					Time: time.Now().Add(-30 * time.Second).UnixNano(),
+				},
+			},
+		},
+	}
+	alloc.RescheduleTracker = &structs.RescheduleTracker{
+		Events: []*structs.RescheduleEvent{
+			{
+				RescheduleTime: 10,
+				PrevAllocID:    structs.GenerateUUID(),
+				PrevNodeID:     structs.GenerateUUID(),
+				PrevStatus:     structs.AllocClientStatusFailed,
+			},
+		},
+	}
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), []*structs.Allocation{alloc}))
+
+	// Create a mock evaluation to register the job
+	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
+		ID:          structs.GenerateUUID(),
+		Priority:    job.Priority,
+		TriggeredBy: structs.EvalTriggerJobRegister,
+		JobID:       job.ID,
+	}
+
+	// Process the evaluation
+	err := h.Process(NewBatchScheduler, eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure a plan
+	if len(h.Plans) != 1 {
+		t.Fatalf("bad: %#v", h.Plans)
+	}
+
+	var planned []*structs.Allocation
+	for _, allocList := range h.Plans[0].NodeAllocation {
+		planned = append(planned, allocList...)
+	}
+	if len(planned) != 1 {
+		t.Fatalf("bad: %#v", planned)
+	}
+
+	// Ensure the reschedule history was extended
+	replacement := planned[0]
+	if replacement.PreviousAllocation != alloc.ID {
+		t.Fatalf("bad previous allocation: %q", replacement.PreviousAllocation)
+	}
+	if n := replacement.RescheduleAttempts(); n != 2 {
+		t.Fatalf("expected 2 reschedule attempts; got %d", n)
+	}
+	last := replacement.RescheduleTracker.Events[1]
+	if last.PrevAllocID != alloc.ID || last.PrevNodeID != node.ID ||
+		last.PrevStatus != structs.AllocClientStatusFailed || last.RescheduleTime == 0 {
+		t.Fatalf("bad reschedule event: %#v", last)
+	}
+
+	// Ensure the delay since the failure was recorded
+	if last.Delay < 30*time.Second || last.Delay > time.Minute {
+		t.Fatalf("bad reschedule delay: %v", last.Delay)
+	}
+
+	h.AssertEvalStatus(t, structs.EvalStatusComplete)
+}
+
+func TestBatchSched_Run_FailedAlloc_AttemptedNodes(t *testing.T) {
+	h := NewHarness(t)
+
+	// Create some nodes
+	var nodes []*structs.Node
+	for i := 0; i < 3; i++ {
+		node := mock.Node()
+		nodes = append(nodes, node)
+		noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+	}
+
+	// Create a job
+	job := mock.Job()
+	job.TaskGroups[0].Count = 1
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	// Create a failed alloc on the first node
+	alloc := mock.Alloc()
+	alloc.Job = job
+	alloc.JobID = job.ID
+	alloc.NodeID = nodes[0].ID
+	alloc.Name = "my-job.web[0]"
+	alloc.ClientStatus = structs.AllocClientStatusFailed
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), []*structs.Allocation{alloc}))
+
+	// Create a mock evaluation that has already attempted the second node
+	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
+		ID:          structs.GenerateUUID(),
+		Priority:    job.Priority,
+		TriggeredBy: structs.EvalTriggerJobRegister,
+		JobID:       job.ID,
+		AttemptedNodes: map[string][]string{
+			alloc.Name: []string{nodes[1].ID},
+		},
+	}
+
+	// Process the evaluation
+	err := h.Process(NewBatchScheduler, eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure a plan
+	if len(h.Plans) != 1 {
+		t.Fatalf("bad: %#v", h.Plans)
+	}
+
+	// Ensure the replacement avoided the attempted nodes
+	planned := h.Plans[0].NodeAllocation[nodes[2].ID]
+	if len(planned) != 1 {
+		t.Fatalf("bad: %#v", h.Plans[0].NodeAllocation)
+	}
+
+	h.AssertEvalStatus(t, structs.EvalStatusComplete)
+}
+
+func TestBatchSched_Run_FailedAlloc_AttemptedNodesRescheduleTracker(t *testing.T) {
+	h := NewHarness(t)
+
+	// Create a node
+	node := mock.Node()
+	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+
+	// Create a job that can no longer be placed on the node
+	job := mock.Job()
+	job.TaskGroups[0].Count = 1
+	job.Constraints = append(job.Constraints, &structs.Constraint{
+		LTarget: "${node.datacenter}",
+		RTarget: "missing",
+		Operand: "=",
+	})
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	// Create a failed alloc that replaced an alloc which failed on another
+	// node
+	prevNodeID := structs.GenerateUUID()
+	alloc := mock.Alloc()
+	alloc.Job = job
+	alloc.JobID = job.ID
+	alloc.NodeID = node.ID
+	alloc.Name = "my-job.web[0]"
+	alloc.ClientStatus = structs.AllocClientStatusFailed
+	alloc.RescheduleTracker = &structs.RescheduleTracker{
+		Events: []*structs.RescheduleEvent{
+			{
+				PrevAllocID: structs.GenerateUUID(),
+				PrevNodeID:  prevNodeID,
+				PrevStatus:  structs.AllocClientStatusFailed,
+			},
+		},
+	}
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), []*structs.Allocation{alloc}))
+
+	// Create a mock evaluation as created on a client update, without any
+	// attempted nodes
+	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
+		ID:          structs.GenerateUUID(),
+		Priority:    job.Priority,
+		TriggeredBy: structs.EvalTriggerNodeUpdate,
+		JobID:       job.ID,
+	}
+
+	// Process the evaluation
+	err := h.Process(NewBatchScheduler, eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure the blocked eval carries the nodes of the reschedule history
+	if len(h.CreateEvals) != 1 {
+		t.Fatalf("bad: %#v", h.CreateEvals)
+	}
+	blocked := h.CreateEvals[0]
+	if blocked.Status != structs.EvalStatusBlocked {
+		t.Fatalf("bad: %#v", blocked)
+	}
+	expected := []string{prevNodeID, node.ID}
+	if !reflect.DeepEqual(blocked.AttemptedNodes[alloc.Name], expected) {
+		t.Fatalf("bad: %#v", blocked.AttemptedNodes)
+	}
+}
+
+func TestBatchSched_Run_FailedAlloc_AttemptedNodesBlocked(t *testing.T) {
+	h := NewHarness(t)
+
+	// Create a node
+	node := mock.Node()
+	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+
+	// Create a job that can no longer be placed on the node
+	job := mock.Job()
+	job.TaskGroups[0].Count = 1
+	job.Constraints = append(job.Constraints, &structs.Constraint{
+		LTarget: "${node.datacenter}",
+		RTarget: "missing",
+		Operand: "=",
+	})
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	// Create a failed alloc
+	alloc := mock.Alloc()
+	alloc.Job = job
+	alloc.JobID = job.ID
+	alloc.NodeID = node.ID
+	alloc.Name = "my-job.web[0]"
+	alloc.ClientStatus = structs.AllocClientStatusFailed
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), []*structs.Allocation{alloc}))
+
+	// Create a mock evaluation to register the job
+	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
+		ID:          structs.GenerateUUID(),
+		Priority:    job.Priority,
+		TriggeredBy: structs.EvalTriggerJobRegister,
+		JobID:       job.ID,
+	}
+
+	// Process the evaluation
+	err := h.Process(NewBatchScheduler, eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure the blocked eval carries the attempted node
+	if len(h.CreateEvals) != 1 {
+		t.Fatalf("bad: %#v", h.CreateEvals)
+	}
+	blocked := h.CreateEvals[0]
+	if blocked.Status != structs.EvalStatusBlocked {
+		t.Fatalf("bad: %#v", blocked)
+	}
+	attempted := blocked.AttemptedNodes[alloc.Name]
+	if len(attempted) != 1 || attempted[0] != node.ID {
+		t.Fatalf("bad: %#v", blocked.AttemptedNodes)
+	}
+}
+
 func TestBatchSched_Run_FailedAllocQueuedAllocations(t *testing.T) {
 	h := NewHarness(t)
 
@@ -2028,6 +2791,7 @@ func TestBatchSched_Run_FailedAllocQueuedAllocations(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -2087,6 +2851,7 @@ func TestBatchSched_ReRun_SuccessfullyFinishedAlloc(t *testing.T) {
 
 	// Create a mock evaluation to rerun the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -2105,7 +2870,7 @@ func TestBatchSched_ReRun_SuccessfullyFinishedAlloc(t *testing.T) {
 	}
 
 	// Lookup the allocations by JobID
-	out, err := h.State.AllocsByJob(job.ID)
+	out, err := h.State.AllocsByJob(job.Namespace, job.ID)
 	noErr(t, err)
 
 	// Ensure no replacement alloc was placed.
@@ -2228,6 +2993,7 @@ func TestGenericSched_ChainedAlloc(t *testing.T) {
 
 	// Create a mock evaluation to register the job
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -2256,6 +3022,7 @@ func TestGenericSched_ChainedAlloc(t *testing.T) {
 
 	// Create a mock evaluation to update the job
 	eval1 := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    job1.Priority,
 		TriggeredBy: structs.EvalTriggerJobRegister,
@@ -2315,6 +3082,7 @@ func TestServiceSched_NodeDrain_Sticky(t *testing.T) {
 
 	// Create a mock evaluation to deal with drain
 	eval := &structs.Evaluation{
+		Namespace:   structs.DefaultNamespace,
 		ID:          structs.GenerateUUID(),
 		Priority:    50,
 		TriggeredBy: structs.EvalTriggerNodeUpdate,
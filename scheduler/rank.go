@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/nomad/nomad/structs"
 )
@@ -14,6 +15,10 @@ type RankedNode struct {
 	Score         float64
 	TaskResources map[string]*structs.Resources
 
+	// GroupNetworks are the networks offered to the task group, shared by
+	// all of its tasks
+	GroupNetworks []*structs.NetworkResource
+
 	// Allocs is used to cache the proposed allocations on the
 	// node. This can be shared between iterators that require it.
 	Proposed []*structs.Allocation
@@ -74,7 +79,9 @@ func NewFeasibleRankIterator(ctx Context, source FeasibleIterator) *FeasibleRank
 }
 
 func (iter *FeasibleRankIterator) Next() *RankedNode {
+	start := time.Now()
 	option := iter.source.Next()
+	iter.ctx.Timing().Feasibility += time.Since(start)
 	if option == nil {
 		return nil
 	}
@@ -135,6 +142,7 @@ type BinPackIterator struct {
 	source    RankIterator
 	evict     bool
 	priority  int
+	algorithm string
 	taskGroup *structs.TaskGroup
 }
 
@@ -142,14 +150,23 @@ type BinPackIterator struct {
 // potentially evicting other tasks based on a given priority.
 func NewBinPackIterator(ctx Context, source RankIterator, evict bool, priority int) *BinPackIterator {
 	iter := &BinPackIterator{
-		ctx:      ctx,
-		source:   source,
-		evict:    evict,
-		priority: priority,
+		ctx:       ctx,
+		source:    source,
+		evict:     evict,
+		priority:  priority,
+		algorithm: structs.SchedulerAlgorithmBinpack,
 	}
 	return iter
 }
 
+// SetSchedulerConfiguration applies the configuration of the schedulers set
+// by the operators. Whether eviction is enabled depends on the type of the
+// scheduler.
+func (iter *BinPackIterator) SetSchedulerConfiguration(config *structs.SchedulerConfiguration, schedulerType string) {
+	iter.evict = config.PreemptionEnabled(schedulerType)
+	iter.algorithm = config.SchedulerAlgorithm
+}
+
 func (iter *BinPackIterator) SetPriority(p int) {
 	iter.priority = p
 }
@@ -185,6 +202,25 @@ OUTER:
 		total := &structs.Resources{
 			DiskMB: iter.taskGroup.EphemeralDisk.SizeMB,
 		}
+
+		// Assign the network shared by the tasks
+		option.GroupNetworks = nil
+		if len(iter.taskGroup.Networks) > 0 {
+			ask := iter.taskGroup.Networks[0].Copy()
+			offer, err := netIdx.AssignNetwork(ask)
+			if offer == nil {
+				iter.ctx.Metrics().ExhaustedNode(option.Node,
+					fmt.Sprintf("network: %s", err))
+				netIdx.Release()
+				continue OUTER
+			}
+
+			// Reserve this to prevent a task from colliding
+			netIdx.AddReserved(offer)
+			option.GroupNetworks = []*structs.NetworkResource{offer}
+			total.Add(&structs.Resources{Networks: option.GroupNetworks})
+		}
+
 		for _, task := range iter.taskGroup.Tasks {
 			taskResources := task.Resources.Copy()
 
@@ -214,7 +250,10 @@ OUTER:
 		}
 
 		// Add the resources we are trying to fit
-		proposed = append(proposed, &structs.Allocation{Resources: total})
+		proposed = append(proposed, &structs.Allocation{
+			Resources:     total,
+			TaskResources: option.TaskResources,
+		})
 
 		// Check if these allocations fit, if they do not, simply skip this node
 		fit, dim, util, _ := structs.AllocsFit(option.Node, proposed, netIdx)
@@ -229,7 +268,14 @@ OUTER:
 		// to make room. This explodes the search space, so it must be done
 		// carefully.
 
-		// Score the fit normally otherwise
+		// Score the fit normally otherwise, preferring emptier nodes when
+		// spreading allocations
+		if iter.algorithm == structs.SchedulerAlgorithmSpread {
+			fitness := structs.ScoreFitSpread(option.Node, util)
+			option.Score += fitness
+			iter.ctx.Metrics().ScoreNode(option.Node, "spread", fitness)
+			return option
+		}
 		fitness := structs.ScoreFit(option.Node, util)
 		option.Score += fitness
 		iter.ctx.Metrics().ScoreNode(option.Node, "binpack", fitness)
@@ -304,3 +350,163 @@ func (iter *JobAntiAffinityIterator) Next() *RankedNode {
 func (iter *JobAntiAffinityIterator) Reset() {
 	iter.source.Reset()
 }
+
+// NodeAttemptPenaltyIterator is used to apply a penalty to nodes that a
+// placement has previously been attempted on and failed. Penalized nodes are
+// held back until the source is exhausted so that a downstream limit does not
+// stop the search before any other node has been considered. This is used to
+// spread retries of a failing allocation across the cluster.
+type NodeAttemptPenaltyIterator struct {
+	ctx          Context
+	source       RankIterator
+	penalty      float64
+	penaltyNodes map[string]struct{}
+	deferred     []*RankedNode
+}
+
+// NewNodeAttemptPenaltyIterator is used to create a NodeAttemptPenaltyIterator
+// that applies the given penalty to previously attempted nodes.
+func NewNodeAttemptPenaltyIterator(ctx Context, source RankIterator, penalty float64) *NodeAttemptPenaltyIterator {
+	iter := &NodeAttemptPenaltyIterator{
+		ctx:     ctx,
+		source:  source,
+		penalty: penalty,
+	}
+	return iter
+}
+
+func (iter *NodeAttemptPenaltyIterator) SetPenaltyNodes(nodes map[string]struct{}) {
+	iter.penaltyNodes = nodes
+}
+
+func (iter *NodeAttemptPenaltyIterator) Next() *RankedNode {
+	for {
+		option := iter.source.Next()
+		if option == nil {
+			// Fall back to the penalized nodes once nothing else is left
+			if len(iter.deferred) == 0 {
+				return nil
+			}
+			option = iter.deferred[0]
+			iter.deferred = iter.deferred[1:]
+			return option
+		}
+
+		if _, ok := iter.penaltyNodes[option.Node.ID]; !ok {
+			return option
+		}
+
+		scorePenalty := -1 * iter.penalty
+		option.Score += scorePenalty
+		iter.ctx.Metrics().ScoreNode(option.Node, "node-attempt-penalty", scorePenalty)
+		iter.deferred = append(iter.deferred, option)
+	}
+}
+
+func (iter *NodeAttemptPenaltyIterator) Reset() {
+	iter.source.Reset()
+	iter.deferred = nil
+}
+
+// MaintenanceWindowIterator is used to apply a penalty to the nodes of the
+// node classes under soft maintenance. Like the NodeAttemptPenaltyIterator,
+// penalized nodes are held back until the source is exhausted, so that they
+// are only used when no other node fits.
+type MaintenanceWindowIterator struct {
+	ctx      Context
+	source   RankIterator
+	penalty  float64
+	windows  map[string]*structs.MaintenanceWindow
+	deferred []*RankedNode
+}
+
+// NewMaintenanceWindowIterator is used to create a MaintenanceWindowIterator
+// that applies the given penalty to the nodes under soft maintenance.
+func NewMaintenanceWindowIterator(ctx Context, source RankIterator, penalty float64) *MaintenanceWindowIterator {
+	iter := &MaintenanceWindowIterator{
+		ctx:     ctx,
+		source:  source,
+		penalty: penalty,
+	}
+	return iter
+}
+
+// SetMaintenanceWindows sets the maintenance windows in effect by node class
+func (iter *MaintenanceWindowIterator) SetMaintenanceWindows(windows map[string]*structs.MaintenanceWindow) {
+	iter.windows = windows
+}
+
+func (iter *MaintenanceWindowIterator) Next() *RankedNode {
+	for {
+		option := iter.source.Next()
+		if option == nil {
+			// Fall back to the nodes under maintenance once nothing else is left
+			if len(iter.deferred) == 0 {
+				return nil
+			}
+			option = iter.deferred[0]
+			iter.deferred = iter.deferred[1:]
+			return option
+		}
+
+		window, ok := iter.windows[option.Node.NodeClass]
+		if !ok || window.Mode != structs.MaintenanceModeSoft {
+			return option
+		}
+
+		scorePenalty := -1 * iter.penalty
+		option.Score += scorePenalty
+		iter.ctx.Metrics().ScoreNode(option.Node, "maintenance-window", scorePenalty)
+		iter.deferred = append(iter.deferred, option)
+	}
+}
+
+func (iter *MaintenanceWindowIterator) Reset() {
+	iter.source.Reset()
+	iter.deferred = nil
+}
+
+// NodeReliabilityIterator is used to apply the reliability penalty of the
+// nodes, which decays over time since the failures seen on them, so that
+// placements prefer the nodes that failed the least recently.
+type NodeReliabilityIterator struct {
+	ctx    Context
+	source RankIterator
+	config structs.NodeReliabilityConfig
+	now    time.Time
+}
+
+// NewNodeReliabilityIterator is used to create a NodeReliabilityIterator
+// that applies no penalty until configured.
+func NewNodeReliabilityIterator(ctx Context, source RankIterator) *NodeReliabilityIterator {
+	iter := &NodeReliabilityIterator{
+		ctx:    ctx,
+		source: source,
+	}
+	return iter
+}
+
+// SetConfig sets the configuration of the penalty and the time the penalty
+// is decayed to
+func (iter *NodeReliabilityIterator) SetConfig(config structs.NodeReliabilityConfig, now time.Time) {
+	iter.config = config
+	iter.now = now
+}
+
+func (iter *NodeReliabilityIterator) Next() *RankedNode {
+	option := iter.source.Next()
+	if option == nil {
+		return nil
+	}
+
+	if penalty := iter.config.Penalty(option.Node, iter.now); penalty > 0 {
+		scorePenalty := -1 * penalty
+		option.Score += scorePenalty
+		iter.ctx.Metrics().ScoreNode(option.Node, "node-reliability", scorePenalty)
+	}
+	return option
+}
+
+func (iter *NodeReliabilityIterator) Reset() {
+	iter.source.Reset()
+}
@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"testing"
+	"time"
 
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -99,6 +100,81 @@ func TestBinPackIterator_NoExistingAlloc(t *testing.T) {
 	}
 }
 
+func TestBinPackIterator_GroupNetwork(t *testing.T) {
+	_, ctx := testContext(t)
+	nodes := []*RankedNode{
+		&RankedNode{
+			Node: &structs.Node{
+				Resources: &structs.Resources{
+					CPU:      2048,
+					MemoryMB: 2048,
+					Networks: []*structs.NetworkResource{
+						{
+							Device: "eth0",
+							CIDR:   "192.168.0.100/32",
+							MBits:  100,
+						},
+					},
+				},
+			},
+		},
+	}
+	static := NewStaticRankIterator(ctx, nodes)
+
+	taskGroup := &structs.TaskGroup{
+		EphemeralDisk: &structs.EphemeralDisk{},
+		Networks: []*structs.NetworkResource{
+			{
+				Mode:          structs.NetworkModeBridge,
+				MBits:         60,
+				ReservedPorts: []structs.Port{{"admin", 9000, 0, ""}},
+				DynamicPorts:  []structs.Port{{"http", 0, 8080, ""}},
+			},
+		},
+		Tasks: []*structs.Task{
+			{
+				Name: "web",
+				Resources: &structs.Resources{
+					CPU:      1024,
+					MemoryMB: 1024,
+				},
+			},
+		},
+	}
+	binp := NewBinPackIterator(ctx, static, false, 0)
+	binp.SetTaskGroup(taskGroup)
+
+	out := collectRanked(binp)
+	if len(out) != 1 {
+		t.Fatalf("Bad: %v", out)
+	}
+
+	if len(out[0].GroupNetworks) != 1 {
+		t.Fatalf("Bad: %v", out[0].GroupNetworks)
+	}
+	offer := out[0].GroupNetworks[0]
+	if offer.Mode != structs.NetworkModeBridge || offer.IP != "192.168.0.100" || offer.MBits != 60 {
+		t.Fatalf("Bad: %#v", offer)
+	}
+	if port := offer.DynamicPorts[0]; port.Value == 0 || port.To != 8080 {
+		t.Fatalf("Bad: %#v", offer.DynamicPorts)
+	}
+
+	// The ask of the task group must not be modified
+	if taskGroup.Networks[0].DynamicPorts[0].Value != 0 {
+		t.Fatalf("Bad: %#v", taskGroup.Networks[0])
+	}
+
+	// Asking for more bandwidth than the node has exhausts it
+	taskGroup.Networks[0].MBits = 200
+	static = NewStaticRankIterator(ctx, nodes)
+	binp = NewBinPackIterator(ctx, static, false, 0)
+	binp.SetTaskGroup(taskGroup)
+	if out := collectRanked(binp); len(out) != 0 {
+		t.Fatalf("Bad: %v", out)
+	}
+}
+
 func TestBinPackIterator_PlannedAlloc(t *testing.T) {
 	_, ctx := testContext(t)
 	nodes := []*RankedNode{
@@ -408,6 +484,148 @@ func TestJobAntiAffinity_PlannedAlloc(t *testing.T) {
 	}
 }
 
+func TestNodeAttemptPenaltyIterator(t *testing.T) {
+	_, ctx := testContext(t)
+	nodes := []*RankedNode{
+		&RankedNode{
+			Node: &structs.Node{
+				ID: structs.GenerateUUID(),
+			},
+		},
+		&RankedNode{
+			Node: &structs.Node{
+				ID: structs.GenerateUUID(),
+			},
+		},
+	}
+	static := NewStaticRankIterator(ctx, nodes)
+
+	// Penalize the first node
+	iter := NewNodeAttemptPenaltyIterator(ctx, static, 50.0)
+	iter.SetPenaltyNodes(map[string]struct{}{nodes[0].Node.ID: struct{}{}})
+
+	// The penalized node should be returned last
+	out := collectRanked(iter)
+	if len(out) != 2 {
+		t.Fatalf("Bad: %#v", out)
+	}
+	if out[0] != nodes[1] {
+		t.Fatalf("Bad: %v", out)
+	}
+	if out[0].Score != 0.0 {
+		t.Fatalf("Bad: %#v", out[0])
+	}
+
+	if out[1] != nodes[0] {
+		t.Fatalf("Bad: %v", out)
+	}
+	if out[1].Score != -50.0 {
+		t.Fatalf("Bad: %v", out[1])
+	}
+}
+
+func TestMaintenanceWindowIterator(t *testing.T) {
+	_, ctx := testContext(t)
+	nodes := []*RankedNode{
+		&RankedNode{
+			Node: &structs.Node{
+				ID:        structs.GenerateUUID(),
+				NodeClass: "soft",
+			},
+		},
+		&RankedNode{
+			Node: &structs.Node{
+				ID:        structs.GenerateUUID(),
+				NodeClass: "none",
+			},
+		},
+	}
+	static := NewStaticRankIterator(ctx, nodes)
+
+	// Put the class of the first node under soft maintenance
+	iter := NewMaintenanceWindowIterator(ctx, static, 50.0)
+	iter.SetMaintenanceWindows(map[string]*structs.MaintenanceWindow{
+		"soft": &structs.MaintenanceWindow{NodeClass: "soft", Mode: structs.MaintenanceModeSoft},
+	})
+
+	// The node under maintenance should be returned last
+	out := collectRanked(iter)
+	if len(out) != 2 {
+		t.Fatalf("Bad: %#v", out)
+	}
+	if out[0] != nodes[1] || out[0].Score != 0.0 {
+		t.Fatalf("Bad: %#v", out[0])
+	}
+	if out[1] != nodes[0] || out[1].Score != -50.0 {
+		t.Fatalf("Bad: %#v", out[1])
+	}
+}
+
+func TestNodeReliabilityIterator(t *testing.T) {
+	_, ctx := testContext(t)
+	now := time.Now()
+	nodes := []*RankedNode{
+		&RankedNode{
+			Node: &structs.Node{
+				ID: structs.GenerateUUID(),
+				Reliability: structs.NodeReliability{
+					Penalty:    20,
+					UpdateTime: now.Add(-time.Hour).UnixNano(),
+				},
+			},
+		},
+		&RankedNode{
+			Node: &structs.Node{
+				ID: structs.GenerateUUID(),
+				Reliability: structs.NodeReliability{
+					Penalty:    100,
+					UpdateTime: now.UnixNano(),
+				},
+			},
+		},
+		&RankedNode{
+			Node: &structs.Node{
+				ID: structs.GenerateUUID(),
+			},
+		},
+	}
+	static := NewStaticRankIterator(ctx, nodes)
+
+	iter := NewNodeReliabilityIterator(ctx, static)
+	iter.SetConfig(structs.NodeReliabilityConfig{
+		Enabled:    true,
+		HalfLife:   time.Hour,
+		MaxPenalty: 50,
+	}, now)
+
+	// The penalty is halved after a half-life and capped
+	out := collectRanked(iter)
+	if len(out) != 3 {
+		t.Fatalf("Bad: %#v", out)
+	}
+	if out[0].Score != -10.0 {
+		t.Fatalf("Bad: %#v", out[0])
+	}
+	if out[1].Score != -50.0 {
+		t.Fatalf("Bad: %#v", out[1])
+	}
+	if out[2].Score != 0.0 {
+		t.Fatalf("Bad: %#v", out[2])
+	}
+
+	// No penalty is applied while disabled
+	for _, node := range nodes {
+		node.Score = 0
+	}
+	iter.SetConfig(structs.NodeReliabilityConfig{HalfLife: time.Hour}, now)
+	iter.Reset()
+	for _, option := range collectRanked(iter) {
+		if option.Score != 0.0 {
+			t.Fatalf("Bad: %#v", option)
+		}
+	}
+}
+
 func collectRanked(iter RankIterator) (out []*RankedNode) {
 	for {
 		next := iter.Next()
@@ -3,6 +3,8 @@ package scheduler
 import (
 	"fmt"
 	"log"
+	"sort"
+	"sync"
 
 	"github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -16,11 +18,73 @@ var BuiltinSchedulers = map[string]Factory{
 	"system":  NewSystemScheduler,
 }
 
+var (
+	// registeredSchedulers contains the schedulers registered in addition to
+	// the built in schedulers
+	registeredSchedulers     = make(map[string]Factory)
+	registeredSchedulersLock sync.RWMutex
+)
+
+// RegisterScheduler makes a scheduler available under the given name in
+// addition to the built in schedulers. It is meant to be called from the
+// init function of the package implementing the scheduler, so custom
+// placement logic can be compiled into Nomad without modifying it. Job types
+// are routed to the scheduler through the server's scheduler mapping.
+func RegisterScheduler(name string, factory Factory) error {
+	if name == "" {
+		return fmt.Errorf("scheduler name must be specified")
+	}
+	if factory == nil {
+		return fmt.Errorf("scheduler %q must have a factory", name)
+	}
+
+	registeredSchedulersLock.Lock()
+	defer registeredSchedulersLock.Unlock()
+	if _, ok := BuiltinSchedulers[name]; ok {
+		return fmt.Errorf("scheduler %q is a built in scheduler", name)
+	}
+	if _, ok := registeredSchedulers[name]; ok {
+		return fmt.Errorf("scheduler %q is already registered", name)
+	}
+	registeredSchedulers[name] = factory
+	return nil
+}
+
+// lookupScheduler returns the factory of the built in or registered
+// scheduler with the given name
+func lookupScheduler(name string) (Factory, bool) {
+	if factory, ok := BuiltinSchedulers[name]; ok {
+		return factory, true
+	}
+
+	registeredSchedulersLock.RLock()
+	defer registeredSchedulersLock.RUnlock()
+	factory, ok := registeredSchedulers[name]
+	return factory, ok
+}
+
+// Schedulers returns the sorted names of the built in and registered
+// schedulers
+func Schedulers() []string {
+	registeredSchedulersLock.RLock()
+	defer registeredSchedulersLock.RUnlock()
+
+	names := make([]string, 0, len(BuiltinSchedulers)+len(registeredSchedulers))
+	for name := range BuiltinSchedulers {
+		names = append(names, name)
+	}
+	for name := range registeredSchedulers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // NewScheduler is used to instantiate and return a new scheduler
 // given the scheduler name, initial state, and planner.
 func NewScheduler(name string, logger *log.Logger, state State, planner Planner) (Scheduler, error) {
 	// Lookup the factory function
-	factory, ok := BuiltinSchedulers[name]
+	factory, ok := lookupScheduler(name)
 	if !ok {
 		return nil, fmt.Errorf("unknown scheduler '%s'", name)
 	}
@@ -30,6 +94,37 @@ func NewScheduler(name string, logger *log.Logger, state State, planner Planner)
 	return sched, nil
 }
 
+// Mapping maps job types to the name of the scheduler that processes the
+// evaluations of their jobs. Job types missing from the mapping are
+// processed by the scheduler of the same name.
+type Mapping map[string]string
+
+// Scheduler returns the name of the scheduler for the job type
+func (m Mapping) Scheduler(jobType string) string {
+	if name, ok := m[jobType]; ok {
+		return name
+	}
+	return jobType
+}
+
+// Validate returns an error if the job type is not processed by a known
+// scheduler
+func (m Mapping) Validate(jobType string) error {
+	name := m.Scheduler(jobType)
+	if _, ok := lookupScheduler(name); !ok {
+		if name != jobType {
+			return fmt.Errorf("job type %q is mapped to unknown scheduler %q", jobType, name)
+		}
+		return fmt.Errorf("unknown job type %q", jobType)
+	}
+	return nil
+}
+
+// NewScheduler instantiates the scheduler of the job type
+func (m Mapping) NewScheduler(jobType string, logger *log.Logger, state State, planner Planner) (Scheduler, error) {
+	return NewScheduler(m.Scheduler(jobType), logger, state, planner)
+}
+
 // Factory is used to instantiate a new Scheduler
 type Factory func(*log.Logger, State, Planner) Scheduler
 
@@ -57,8 +152,13 @@ type State interface {
 	// The type of each result is *structs.Node
 	Nodes() (memdb.ResultIterator, error)
 
-	// AllocsByJob returns the allocations by JobID
-	AllocsByJob(jobID string) ([]*structs.Allocation, error)
+	// NodesByDatacenterStatus returns an iterator over the nodes of the
+	// datacenter with the given status.
+	// The type of each result is *structs.Node
+	NodesByDatacenterStatus(dc, status string) (memdb.ResultIterator, error)
+
+	// AllocsByJob returns the allocations by job namespace and ID
+	AllocsByJob(namespace, jobID string) ([]*structs.Allocation, error)
 
 	// AllocsByNode returns all the allocations by node
 	AllocsByNode(node string) ([]*structs.Allocation, error)
@@ -69,8 +169,20 @@ type State interface {
 	// GetNodeByID is used to lookup a node by ID
 	NodeByID(nodeID string) (*structs.Node, error)
 
-	// GetJobByID is used to lookup a job by ID
-	JobByID(id string) (*structs.Job, error)
+	// GetJobByID is used to lookup a job by namespace and ID
+	JobByID(namespace, id string) (*structs.Job, error)
+
+	// SchedulerConfig returns the configuration of the schedulers set by the
+	// operators, nil if none was set
+	SchedulerConfig() (uint64, *structs.SchedulerConfiguration, error)
+
+	// MaintenanceWindows returns an iterator over the maintenance windows of
+	// the node classes.
+	// The type of each result is *structs.MaintenanceWindow
+	MaintenanceWindows() (memdb.ResultIterator, error)
+
+	// CSIVolumeByID is used to lookup a CSI volume by ID
+	CSIVolumeByID(id string) (*structs.CSIVolume, error)
 }
 
 // Planner interface is used to submit a task allocation plan.
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+type testCustomScheduler struct{}
+
+func (t *testCustomScheduler) Process(*structs.Evaluation) error {
+	return nil
+}
+
+func newTestCustomScheduler(*log.Logger, State, Planner) Scheduler {
+	return &testCustomScheduler{}
+}
+
+func TestRegisterScheduler(t *testing.T) {
+	defer func() {
+		registeredSchedulersLock.Lock()
+		delete(registeredSchedulers, "custom")
+		registeredSchedulersLock.Unlock()
+	}()
+
+	if err := RegisterScheduler("custom", newTestCustomScheduler); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Names may only be registered once and can't shadow built in schedulers
+	if err := RegisterScheduler("custom", newTestCustomScheduler); err == nil {
+		t.Fatalf("expected error registering a scheduler twice")
+	}
+	if err := RegisterScheduler("batch", newTestCustomScheduler); err == nil {
+		t.Fatalf("expected error registering a built in scheduler")
+	}
+	if err := RegisterScheduler("", newTestCustomScheduler); err == nil {
+		t.Fatalf("expected error registering a scheduler without a name")
+	}
+
+	expected := []string{"batch", "custom", "service", "system"}
+	if names := Schedulers(); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad: %v", names)
+	}
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	sched, err := NewScheduler("custom", logger, nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := sched.(*testCustomScheduler); !ok {
+		t.Fatalf("bad: %#v", sched)
+	}
+}
+
+func TestMapping(t *testing.T) {
+	defer func() {
+		registeredSchedulersLock.Lock()
+		delete(registeredSchedulers, "custom")
+		registeredSchedulersLock.Unlock()
+	}()
+	if err := RegisterScheduler("custom", newTestCustomScheduler); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	m := Mapping{
+		"batch":   "custom",
+		"gpu":     "custom",
+		"missing": "nope",
+	}
+	if name := m.Scheduler("batch"); name != "custom" {
+		t.Fatalf("bad: %q", name)
+	}
+	if name := m.Scheduler("service"); name != "service" {
+		t.Fatalf("bad: %q", name)
+	}
+
+	for _, jobType := range []string{"batch", "gpu", "service", "system"} {
+		if err := m.Validate(jobType); err != nil {
+			t.Fatalf("%s: err: %v", jobType, err)
+		}
+	}
+	for _, jobType := range []string{"missing", "unknown"} {
+		if err := m.Validate(jobType); err == nil {
+			t.Fatalf("%s: expected error", jobType)
+		}
+	}
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	sched, err := m.NewScheduler("gpu", logger, nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := sched.(*testCustomScheduler); !ok {
+		t.Fatalf("bad: %#v", sched)
+	}
+}
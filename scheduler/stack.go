@@ -16,6 +16,17 @@ const (
 	// batchJobAntiAffinityPenalty is the same as the
 	// serviceJobAntiAffinityPenalty but for batch type jobs.
 	batchJobAntiAffinityPenalty = 5.0
+
+	// nodeAttemptPenalty is the penalty applied to the score for placing an
+	// alloc on a node that a previous placement of the same alloc failed on.
+	// It outweighs the bin packing score so that any other feasible node is
+	// preferred.
+	nodeAttemptPenalty = 50.0
+
+	// maintenanceWindowPenalty is the penalty applied to the score for
+	// placing an alloc on a node of a class under soft maintenance. Like the
+	// nodeAttemptPenalty, it outweighs the bin packing score.
+	maintenanceWindowPenalty = 50.0
 )
 
 // Stack is a chained collection of iterators. The stack is used to
@@ -39,14 +50,19 @@ type GenericStack struct {
 	ctx    Context
 	source *StaticIterator
 
-	wrappedChecks       *FeasibilityWrapper
-	jobConstraint       *ConstraintChecker
-	taskGroupDrivers    *DriverChecker
-	taskGroupConstraint *ConstraintChecker
+	wrappedChecks        *FeasibilityWrapper
+	jobConstraint        *ConstraintChecker
+	taskGroupDrivers     *DriverChecker
+	taskGroupHostVolumes *HostVolumeChecker
+	taskGroupConstraint  *ConstraintChecker
 
 	proposedAllocConstraint *ProposedAllocConstraintIterator
+	csiVolumes              *CSIVolumeIterator
 	binPack                 *BinPackIterator
 	jobAntiAff              *JobAntiAffinityIterator
+	maintenance             *MaintenanceWindowIterator
+	nodeReliability         *NodeReliabilityIterator
+	nodeAttemptPenalty      *NodeAttemptPenaltyIterator
 	limit                   *LimitIterator
 	maxScore                *MaxScoreIterator
 }
@@ -70,6 +86,9 @@ func NewGenericStack(batch bool, ctx Context) *GenericStack {
 	// Filter on task group drivers first as they are faster
 	s.taskGroupDrivers = NewDriverChecker(ctx, nil)
 
+	// Filter on the host volumes of the task group
+	s.taskGroupHostVolumes = NewHostVolumeChecker(ctx, nil)
+
 	// Filter on task group constraints second
 	s.taskGroupConstraint = NewConstraintChecker(ctx, nil)
 
@@ -78,14 +97,17 @@ func NewGenericStack(batch bool, ctx Context) *GenericStack {
 	// previously been marked as eligible or ineligible. Generally this will be
 	// checks that only needs to examine the single node to determine feasibility.
 	jobs := []FeasibilityChecker{s.jobConstraint}
-	tgs := []FeasibilityChecker{s.taskGroupDrivers, s.taskGroupConstraint}
+	tgs := []FeasibilityChecker{s.taskGroupDrivers, s.taskGroupHostVolumes, s.taskGroupConstraint}
 	s.wrappedChecks = NewFeasibilityWrapper(ctx, s.source, jobs, tgs)
 
 	// Filter on constraints that are affected by propsed allocations.
 	s.proposedAllocConstraint = NewProposedAllocConstraintIterator(ctx, s.wrappedChecks)
 
+	// Filter on the CSI volumes the task group can claim
+	s.csiVolumes = NewCSIVolumeIterator(ctx, s.proposedAllocConstraint)
+
 	// Upgrade from feasible to rank iterator
-	rankSource := NewFeasibleRankIterator(ctx, s.proposedAllocConstraint)
+	rankSource := NewFeasibleRankIterator(ctx, s.csiVolumes)
 
 	// Apply the bin packing, this depends on the resources needed
 	// by a particular task group. Only enable eviction for the service
@@ -102,8 +124,20 @@ func NewGenericStack(batch bool, ctx Context) *GenericStack {
 	}
 	s.jobAntiAff = NewJobAntiAffinityIterator(ctx, s.binPack, penalty, "")
 
+	// Apply a penalty to the nodes of the classes under soft maintenance, so
+	// that placements avoid them.
+	s.maintenance = NewMaintenanceWindowIterator(ctx, s.jobAntiAff, maintenanceWindowPenalty)
+
+	// Apply the reliability penalty of the nodes, so that placements avoid
+	// the nodes that failed recently.
+	s.nodeReliability = NewNodeReliabilityIterator(ctx, s.maintenance)
+
+	// Apply a penalty to nodes that previous placement attempts of the
+	// allocation failed on, so that retries land elsewhere.
+	s.nodeAttemptPenalty = NewNodeAttemptPenaltyIterator(ctx, s.nodeReliability, nodeAttemptPenalty)
+
 	// Apply a limit function. This is to avoid scanning *every* possible node.
-	s.limit = NewLimitIterator(ctx, s.jobAntiAff, 2)
+	s.limit = NewLimitIterator(ctx, s.nodeAttemptPenalty, 2)
 
 	// Select the node with the maximum score for placement
 	s.maxScore = NewMaxScoreIterator(ctx, s.limit)
@@ -111,10 +145,7 @@ func NewGenericStack(batch bool, ctx Context) *GenericStack {
 }
 
 func (s *GenericStack) SetNodes(baseNodes []*structs.Node) {
-	// Shuffle base nodes
-	shuffleNodes(baseNodes)
-
-	// Update the set of base nodes
+	// Update the set of base nodes, which the source visits in a random order
 	s.source.SetNodes(baseNodes)
 
 	// Apply a limit function. This is to avoid scanning *every* possible node.
@@ -135,24 +166,55 @@ func (s *GenericStack) SetNodes(baseNodes []*structs.Node) {
 func (s *GenericStack) SetJob(job *structs.Job) {
 	s.jobConstraint.SetConstraints(job.Constraints)
 	s.proposedAllocConstraint.SetJob(job)
+	s.csiVolumes.SetJob(job)
 	s.binPack.SetPriority(job.Priority)
 	s.jobAntiAff.SetJob(job.ID)
 	s.ctx.Eligibility().SetJob(job)
 }
 
+// SetSchedulerConfiguration applies the configuration of the schedulers set
+// by the operators, or the default one if nil
+func (s *GenericStack) SetSchedulerConfiguration(config *structs.SchedulerConfiguration) {
+	if config == nil {
+		config = structs.DefaultSchedulerConfiguration()
+	}
+	schedulerType := structs.JobTypeService
+	if s.batch {
+		schedulerType = structs.JobTypeBatch
+	}
+	s.binPack.SetSchedulerConfiguration(config, schedulerType)
+	s.nodeReliability.SetConfig(config.NodeReliability, time.Now())
+}
+
+// SetMaintenanceWindows sets the maintenance windows in effect by node class.
+// Nodes under hard maintenance are not ready and never reach the stack.
+func (s *GenericStack) SetMaintenanceWindows(windows map[string]*structs.MaintenanceWindow) {
+	s.maintenance.SetMaintenanceWindows(windows)
+}
+
+// SetPenaltyNodes sets the nodes that should be penalized for the next
+// selection because a placement has already been attempted on them.
+func (s *GenericStack) SetPenaltyNodes(nodes map[string]struct{}) {
+	s.nodeAttemptPenalty.SetPenaltyNodes(nodes)
+}
+
 func (s *GenericStack) Select(tg *structs.TaskGroup) (*RankedNode, *structs.Resources) {
 	// Reset the max selector and context
 	s.maxScore.Reset()
 	s.ctx.Reset()
 	start := time.Now()
+	feasibility := s.ctx.Timing().Feasibility
 
 	// Get the task groups constraints.
 	tgConstr := taskGroupConstraints(tg)
 
 	// Update the parameters of iterators
 	s.taskGroupDrivers.SetDrivers(tgConstr.drivers)
+	s.taskGroupDrivers.SetCapabilities(tgConstr.capabilities)
+	s.taskGroupHostVolumes.SetVolumes(tg.Volumes)
 	s.taskGroupConstraint.SetConstraints(tgConstr.constraints)
 	s.proposedAllocConstraint.SetTaskGroup(tg)
+	s.csiVolumes.SetTaskGroup(tg)
 	s.wrappedChecks.SetTaskGroup(tg.Name)
 	s.binPack.SetTaskGroup(tg)
 
@@ -166,8 +228,11 @@ func (s *GenericStack) Select(tg *structs.TaskGroup) (*RankedNode, *structs.Reso
 		}
 	}
 
-	// Store the compute time
-	s.ctx.Metrics().AllocationTime = time.Since(start)
+	// Store the compute time and the part of it spent ranking nodes
+	elapsed := time.Since(start)
+	s.ctx.Metrics().AllocationTime = elapsed
+	timing := s.ctx.Timing()
+	timing.Ranking += elapsed - (timing.Feasibility - feasibility)
 	return option, tgConstr.size
 }
 
@@ -187,13 +252,15 @@ func (s *GenericStack) SelectPreferringNodes(tg *structs.TaskGroup, nodes []*str
 // SystemStack is the Stack used for the System scheduler. It is designed to
 // attempt to make placements on all nodes.
 type SystemStack struct {
-	ctx                 Context
-	source              *StaticIterator
-	wrappedChecks       *FeasibilityWrapper
-	jobConstraint       *ConstraintChecker
-	taskGroupDrivers    *DriverChecker
-	taskGroupConstraint *ConstraintChecker
-	binPack             *BinPackIterator
+	ctx                  Context
+	source               *StaticIterator
+	wrappedChecks        *FeasibilityWrapper
+	jobConstraint        *ConstraintChecker
+	taskGroupDrivers     *DriverChecker
+	taskGroupHostVolumes *HostVolumeChecker
+	taskGroupConstraint  *ConstraintChecker
+	csiVolumes           *CSIVolumeIterator
+	binPack              *BinPackIterator
 }
 
 // NewSystemStack constructs a stack used for selecting service placements
@@ -211,6 +278,9 @@ func NewSystemStack(ctx Context) *SystemStack {
 	// Filter on task group drivers first as they are faster
 	s.taskGroupDrivers = NewDriverChecker(ctx, nil)
 
+	// Filter on the host volumes of the task group
+	s.taskGroupHostVolumes = NewHostVolumeChecker(ctx, nil)
+
 	// Filter on task group constraints second
 	s.taskGroupConstraint = NewConstraintChecker(ctx, nil)
 
@@ -219,11 +289,14 @@ func NewSystemStack(ctx Context) *SystemStack {
 	// previously been marked as eligible or ineligible. Generally this will be
 	// checks that only needs to examine the single node to determine feasibility.
 	jobs := []FeasibilityChecker{s.jobConstraint}
-	tgs := []FeasibilityChecker{s.taskGroupDrivers, s.taskGroupConstraint}
+	tgs := []FeasibilityChecker{s.taskGroupDrivers, s.taskGroupHostVolumes, s.taskGroupConstraint}
 	s.wrappedChecks = NewFeasibilityWrapper(ctx, s.source, jobs, tgs)
 
+	// Filter on the CSI volumes the task group can claim
+	s.csiVolumes = NewCSIVolumeIterator(ctx, s.wrappedChecks)
+
 	// Upgrade from feasible to rank iterator
-	rankSource := NewFeasibleRankIterator(ctx, s.wrappedChecks)
+	rankSource := NewFeasibleRankIterator(ctx, s.csiVolumes)
 
 	// Apply the bin packing, this depends on the resources needed
 	// by a particular task group. Enable eviction as system jobs are high
@@ -239,22 +312,36 @@ func (s *SystemStack) SetNodes(baseNodes []*structs.Node) {
 
 func (s *SystemStack) SetJob(job *structs.Job) {
 	s.jobConstraint.SetConstraints(job.Constraints)
+	s.csiVolumes.SetJob(job)
 	s.binPack.SetPriority(job.Priority)
 	s.ctx.Eligibility().SetJob(job)
 }
 
+// SetSchedulerConfiguration applies the configuration of the schedulers set
+// by the operators, or the default one if nil
+func (s *SystemStack) SetSchedulerConfiguration(config *structs.SchedulerConfiguration) {
+	if config == nil {
+		config = structs.DefaultSchedulerConfiguration()
+	}
+	s.binPack.SetSchedulerConfiguration(config, structs.JobTypeSystem)
+}
+
 func (s *SystemStack) Select(tg *structs.TaskGroup) (*RankedNode, *structs.Resources) {
 	// Reset the binpack selector and context
 	s.binPack.Reset()
 	s.ctx.Reset()
 	start := time.Now()
+	feasibility := s.ctx.Timing().Feasibility
 
 	// Get the task groups constraints.
 	tgConstr := taskGroupConstraints(tg)
 
 	// Update the parameters of iterators
 	s.taskGroupDrivers.SetDrivers(tgConstr.drivers)
+	s.taskGroupDrivers.SetCapabilities(tgConstr.capabilities)
+	s.taskGroupHostVolumes.SetVolumes(tg.Volumes)
 	s.taskGroupConstraint.SetConstraints(tgConstr.constraints)
+	s.csiVolumes.SetTaskGroup(tg)
 	s.binPack.SetTaskGroup(tg)
 	s.wrappedChecks.SetTaskGroup(tg.Name)
 
@@ -268,7 +355,10 @@ func (s *SystemStack) Select(tg *structs.TaskGroup) (*RankedNode, *structs.Resou
 		}
 	}
 
-	// Store the compute time
-	s.ctx.Metrics().AllocationTime = time.Since(start)
+	// Store the compute time and the part of it spent ranking nodes
+	elapsed := time.Since(start)
+	s.ctx.Metrics().AllocationTime = elapsed
+	timing := s.ctx.Timing()
+	timing.Ranking += elapsed - (timing.Feasibility - feasibility)
 	return option, tgConstr.size
 }
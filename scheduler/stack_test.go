@@ -147,8 +147,10 @@ func TestServiceStack_Select_PreferringNodes(t *testing.T) {
 	}
 
 	// Change the preferred node's kernel to windows and ensure the allocations
-	// are placed elsewhere
+	// are placed elsewhere. The context checks a node against the first
+	// version it has seen, so the changed node is given a new ID.
 	preferredNode1 := preferredNode.Copy()
+	preferredNode1.ID = structs.GenerateUUID()
 	preferredNode1.Attributes["kernel.name"] = "windows"
 	preferredNode1.ComputeClass()
 	option, _ = stack.SelectPreferringNodes(job.TaskGroups[0], []*structs.Node{preferredNode1})
@@ -196,6 +198,42 @@ func TestServiceStack_Select_MetricsReset(t *testing.T) {
 	}
 }
 
+func TestServiceStack_Select_Timing(t *testing.T) {
+	_, ctx := testContext(t)
+	nodes := []*structs.Node{
+		mock.Node(),
+		mock.Node(),
+		mock.Node(),
+		mock.Node(),
+	}
+	stack := NewGenericStack(false, ctx)
+	stack.SetNodes(nodes)
+
+	job := mock.Job()
+	stack.SetJob(job)
+	if n1, _ := stack.Select(job.TaskGroups[0]); n1 == nil {
+		t.Fatalf("missing node %#v", ctx.Metrics())
+	}
+	m1 := ctx.Metrics()
+	t1 := *ctx.Timing()
+	if t1.Feasibility+t1.Ranking != m1.AllocationTime {
+		t.Fatalf("bad: %#v %v", t1, m1.AllocationTime)
+	}
+
+	// The timing accumulates over the placements unlike the metrics
+	if n2, _ := stack.Select(job.TaskGroups[0]); n2 == nil {
+		t.Fatalf("missing node %#v", ctx.Metrics())
+	}
+	m2 := ctx.Metrics()
+	t2 := *ctx.Timing()
+	if t2.Feasibility+t2.Ranking != m1.AllocationTime+m2.AllocationTime {
+		t.Fatalf("bad: %#v %v %v", t2, m1.AllocationTime, m2.AllocationTime)
+	}
+	if t2.Feasibility < t1.Feasibility || t2.Ranking < t1.Ranking {
+		t.Fatalf("bad: %#v %#v", t1, t2)
+	}
+}
+
 func TestServiceStack_Select_DriverFilter(t *testing.T) {
 	_, ctx := testContext(t)
 	nodes := []*structs.Node{
@@ -204,6 +242,7 @@ func TestServiceStack_Select_DriverFilter(t *testing.T) {
 	}
 	zero := nodes[0]
 	zero.Attributes["driver.foo"] = "1"
+	zero.Attributes["driver.foo.capability.exec"] = "true"
 	if err := zero.ComputeClass(); err != nil {
 		t.Fatalf("ComputedClass() failed: %v", err)
 	}
@@ -225,6 +264,47 @@ func TestServiceStack_Select_DriverFilter(t *testing.T) {
 	}
 }
 
+func TestServiceStack_Select_HostVolumeFilter(t *testing.T) {
+	_, ctx := testContext(t)
+	nodes := []*structs.Node{
+		mock.Node(),
+		mock.Node(),
+	}
+	zero := nodes[0]
+	zero.HostVolumes = map[string]*structs.ClientHostVolumeConfig{
+		"data": &structs.ClientHostVolumeConfig{Name: "data", Path: "/srv/data"},
+	}
+	if err := zero.ComputeClass(); err != nil {
+		t.Fatalf("ComputedClass() failed: %v", err)
+	}
+
+	stack := NewGenericStack(false, ctx)
+	stack.SetNodes(nodes)
+
+	job := mock.Job()
+	job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+		"data": &structs.VolumeRequest{Name: "data", Type: structs.VolumeTypeHost, Source: "data"},
+	}
+	stack.SetJob(job)
+
+	node, _ := stack.Select(job.TaskGroups[0])
+	if node == nil {
+		t.Fatalf("missing node %#v", ctx.Metrics())
+	}
+
+	if node.Node != zero {
+		t.Fatalf("bad")
+	}
+
+	met := ctx.Metrics()
+	if met.NodesFiltered != 1 {
+		t.Fatalf("bad: %#v", met)
+	}
+	if met.ConstraintFiltered["missing compatible host volumes"] != 1 {
+		t.Fatalf("bad: %#v", met)
+	}
+}
+
 func TestServiceStack_Select_ConstraintFilter(t *testing.T) {
 	_, ctx := testContext(t)
 	nodes := []*structs.Node{
@@ -410,6 +490,7 @@ func TestSystemStack_Select_DriverFilter(t *testing.T) {
 	}
 	zero := nodes[0]
 	zero.Attributes["driver.foo"] = "1"
+	zero.Attributes["driver.foo.capability.exec"] = "true"
 
 	stack := NewSystemStack(ctx)
 	stack.SetNodes(nodes)
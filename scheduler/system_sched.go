@@ -3,6 +3,7 @@ package scheduler
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/hashicorp/nomad/nomad/structs"
 )
@@ -39,6 +40,16 @@ type SystemScheduler struct {
 
 	failedTGAllocs map[string]*structs.AllocMetric
 	queuedAllocs   map[string]int
+
+	// timing is the time spent checking feasibility and ranking nodes over
+	// all the scheduling attempts.
+	timing *structs.EvalTiming
+
+	// maintenanceWindows are the maintenance windows in effect by node class
+	maintenanceWindows map[string]*structs.MaintenanceWindow
+
+	// schedConfig is the scheduler configuration set by the operators
+	schedConfig *structs.SchedulerConfiguration
 }
 
 // NewSystemScheduler is a factory function to instantiate a new system
@@ -55,16 +66,18 @@ func NewSystemScheduler(logger *log.Logger, state State, planner Planner) Schedu
 func (s *SystemScheduler) Process(eval *structs.Evaluation) error {
 	// Store the evaluation
 	s.eval = eval
+	s.timing = new(structs.EvalTiming)
 
 	// Verify the evaluation trigger reason is understood
 	switch eval.TriggeredBy {
 	case structs.EvalTriggerJobRegister, structs.EvalTriggerNodeUpdate,
-		structs.EvalTriggerJobDeregister, structs.EvalTriggerRollingUpdate:
+		structs.EvalTriggerJobDeregister, structs.EvalTriggerRollingUpdate,
+		structs.EvalTriggerNodeDrain, structs.EvalTriggerCustom:
 	default:
 		desc := fmt.Sprintf("scheduler cannot handle '%s' evaluation reason",
 			eval.TriggeredBy)
 		return setStatus(s.logger, s.planner, s.eval, s.nextEval, nil, s.failedTGAllocs, structs.EvalStatusFailed, desc,
-			s.queuedAllocs)
+			s.queuedAllocs, s.timing)
 	}
 
 	// Retry up to the maxSystemScheduleAttempts and reset if progress is made.
@@ -72,14 +85,14 @@ func (s *SystemScheduler) Process(eval *structs.Evaluation) error {
 	if err := retryMax(maxSystemScheduleAttempts, s.process, progress); err != nil {
 		if statusErr, ok := err.(*SetStatusError); ok {
 			return setStatus(s.logger, s.planner, s.eval, s.nextEval, nil, s.failedTGAllocs, statusErr.EvalStatus, err.Error(),
-				s.queuedAllocs)
+				s.queuedAllocs, s.timing)
 		}
 		return err
 	}
 
 	// Update the status to complete
 	return setStatus(s.logger, s.planner, s.eval, s.nextEval, nil, s.failedTGAllocs, structs.EvalStatusComplete, "",
-		s.queuedAllocs)
+		s.queuedAllocs, s.timing)
 }
 
 // process is wrapped in retryMax to iteratively run the handler until we have no
@@ -87,7 +100,7 @@ func (s *SystemScheduler) Process(eval *structs.Evaluation) error {
 func (s *SystemScheduler) process() (bool, error) {
 	// Lookup the Job by ID
 	var err error
-	s.job, err = s.state.JobByID(s.eval.JobID)
+	s.job, err = s.state.JobByID(s.eval.Namespace, s.eval.JobID)
 	if err != nil {
 		return false, fmt.Errorf("failed to get job '%s': %v",
 			s.eval.JobID, err)
@@ -114,6 +127,7 @@ func (s *SystemScheduler) process() (bool, error) {
 
 	// Create an evaluation context
 	s.ctx = NewEvalContext(s.state, s.plan, s.logger)
+	s.ctx.SetTiming(s.timing)
 
 	// Construct the placement stack
 	s.stack = NewSystemStack(s.ctx)
@@ -121,6 +135,21 @@ func (s *SystemScheduler) process() (bool, error) {
 		s.stack.SetJob(s.job)
 	}
 
+	// Apply the scheduler configuration set by the operators
+	_, schedConfig, err := s.state.SchedulerConfig()
+	if err != nil {
+		return false, fmt.Errorf("failed to get scheduler configuration: %v", err)
+	}
+	s.stack.SetSchedulerConfiguration(schedConfig)
+	s.schedConfig = schedConfig
+
+	// System jobs are placed on every ready node, so only the hard
+	// maintenance windows, which make nodes not ready, affect them
+	s.maintenanceWindows, err = maintenanceWindowsInEffect(s.state, time.Now())
+	if err != nil {
+		return false, err
+	}
+
 	// Compute the target job allocations
 	if err := s.computeJobAllocs(); err != nil {
 		s.logger.Printf("[ERR] sched: %#v: %v", s.eval, err)
@@ -155,6 +184,14 @@ func (s *SystemScheduler) process() (bool, error) {
 	// number of allocations successfully placed
 	adjustQueuedAllocations(s.logger, result, s.queuedAllocs)
 
+	// If placements were rejected because the namespace's quota is
+	// exhausted, retrying will not help. The remaining placements are made
+	// by the next evaluation of the job.
+	if result.QuotaLimitReached != "" {
+		s.logger.Printf("[DEBUG] sched: %#v: quota %q reached", s.eval, result.QuotaLimitReached)
+		return true, nil
+	}
+
 	// If we got a state refresh, try again since we have stale data
 	if newState != nil {
 		s.logger.Printf("[DEBUG] sched: %#v: refresh forced", s.eval)
@@ -178,7 +215,7 @@ func (s *SystemScheduler) process() (bool, error) {
 // existing allocations and node status to update the allocations.
 func (s *SystemScheduler) computeJobAllocs() error {
 	// Lookup the allocations by JobID
-	allocs, err := s.state.AllocsByJob(s.eval.JobID)
+	allocs, err := s.state.AllocsByJob(s.eval.Namespace, s.eval.JobID)
 	if err != nil {
 		return fmt.Errorf("failed to get allocs for job '%s': %v",
 			s.eval.JobID, err)
@@ -202,6 +239,18 @@ func (s *SystemScheduler) computeJobAllocs() error {
 	diff := diffSystemAllocs(s.job, s.nodes, tainted, allocs, terminalAllocs)
 	s.logger.Printf("[DEBUG] sched: %#v: %#v", s.eval, diff)
 
+	// Stop the allocations whose node no longer satisfies their constraints
+	// if the operators opted in. System jobs are not placed on other nodes.
+	if s.schedConfig.MigrateOnNodeConstraints() {
+		violations, err := nodeConstraintViolations(s.ctx, s.job, diff)
+		if err != nil {
+			return err
+		}
+		for _, e := range violations {
+			s.plan.AppendUpdate(e.Alloc, structs.AllocDesiredStatusStop, allocNodeConstraints, "")
+		}
+	}
+
 	// Add all the allocs to stop
 	for _, e := range diff.stop {
 		s.plan.AppendUpdate(e.Alloc, structs.AllocDesiredStatusStop, allocNotNeeded, "")
@@ -219,7 +268,8 @@ func (s *SystemScheduler) computeJobAllocs() error {
 
 	if s.eval.AnnotatePlan {
 		s.plan.Annotations = &structs.PlanAnnotations{
-			DesiredTGUpdates: desiredUpdates(diff, inplaceUpdates, destructiveUpdates),
+			DesiredTGUpdates:   desiredUpdates(diff, inplaceUpdates, destructiveUpdates),
+			MaintenanceWindows: sortedMaintenanceWindows(s.maintenanceWindows),
 		}
 	}
 
@@ -303,6 +353,7 @@ func (s *SystemScheduler) computePlacements(place []allocTuple) error {
 			// Create an allocation for this
 			alloc := &structs.Allocation{
 				ID:            structs.GenerateUUID(),
+				Namespace:     s.job.Namespace,
 				EvalID:        s.eval.ID,
 				Name:          missing.Name,
 				JobID:         s.job.ID,
@@ -314,7 +365,8 @@ func (s *SystemScheduler) computePlacements(place []allocTuple) error {
 				ClientStatus:  structs.AllocClientStatusPending,
 
 				SharedResources: &structs.Resources{
-					DiskMB: missing.TaskGroup.EphemeralDisk.SizeMB,
+					DiskMB:   missing.TaskGroup.EphemeralDisk.SizeMB,
+					Networks: option.GroupNetworks,
 				},
 			}
 
@@ -325,6 +377,7 @@ func (s *SystemScheduler) computePlacements(place []allocTuple) error {
 			}
 
 			s.plan.AppendAlloc(alloc)
+			s.plan.AppendNodeIndex(option.Node)
 		} else {
 			// Lazy initialize the failed map
 			if s.failedTGAllocs == nil {
@@ -34,6 +34,32 @@ func (r *RejectPlan) ReblockEval(*structs.Evaluation) error {
 	return nil
 }
 
+// QuotaLimitPlan is used to always reject the placements of a plan because
+// the given quota has been reached
+type QuotaLimitPlan struct {
+	Harness *Harness
+	Quota   string
+}
+
+func (q *QuotaLimitPlan) SubmitPlan(*structs.Plan) (*structs.PlanResult, State, error) {
+	result := new(structs.PlanResult)
+	result.RefreshIndex = q.Harness.NextIndex()
+	result.QuotaLimitReached = q.Quota
+	return result, q.Harness.State, nil
+}
+
+func (q *QuotaLimitPlan) UpdateEval(eval *structs.Evaluation) error {
+	return nil
+}
+
+func (q *QuotaLimitPlan) CreateEval(*structs.Evaluation) error {
+	return nil
+}
+
+func (q *QuotaLimitPlan) ReblockEval(*structs.Evaluation) error {
+	return nil
+}
+
 // Harness is a lightweight testing harness for schedulers. It manages a state
 // store copy and provides the planner interface. It can be extended for various
 // testing uses or for invoking the scheduler without side effects.
@@ -3,8 +3,11 @@ package scheduler
 import (
 	"fmt"
 	"log"
-	"math/rand"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/nomad/nomad/structs"
 )
@@ -17,10 +20,11 @@ type allocTuple struct {
 }
 
 // materializeTaskGroups is used to materialize all the task groups
-// a job requires. This is used to do the count expansion.
+// a job requires. This is used to do the count expansion. A stopped job
+// requires no task groups.
 func materializeTaskGroups(job *structs.Job) map[string]*structs.TaskGroup {
 	out := make(map[string]*structs.TaskGroup)
-	if job == nil {
+	if job.Stopped() || job.IsParameterized() {
 		return out
 	}
 
@@ -33,6 +37,133 @@ func materializeTaskGroups(job *structs.Job) map[string]*structs.TaskGroup {
 	return out
 }
 
+// materializeScaledTaskGroups materializes the task groups of a job like
+// materializeTaskGroups, but keeps the names of the existing allocations of
+// each task group. When a task group has more allocations than its count, as
+// when it is scaled in, the names that are kept are the ones of the most
+// useful allocations so the others are stopped: allocations on healthy nodes
+// are preferred, then running allocations and then the lowest indexes. The
+// remaining count is filled with the lowest free indexes.
+//
+// allocs is a list of non terminal allocations and tainted is an index of the
+// nodes which are either down or in drain mode.
+func materializeScaledTaskGroups(job *structs.Job, allocs []*structs.Allocation,
+	tainted map[string]*structs.Node) map[string]*structs.TaskGroup {
+	out := make(map[string]*structs.TaskGroup)
+	if job.Stopped() || job.IsParameterized() {
+		return out
+	}
+
+	// Index the existing allocations of each task group by their name index
+	existing := make(map[string]map[int]*structs.Allocation)
+	for _, alloc := range allocs {
+		idx, ok := allocNameIndex(job, alloc)
+		if !ok {
+			continue
+		}
+		byIndex, ok := existing[alloc.TaskGroup]
+		if !ok {
+			byIndex = make(map[int]*structs.Allocation)
+			existing[alloc.TaskGroup] = byIndex
+		}
+		if other, ok := byIndex[idx]; ok && scaleInRank(other, tainted) <= scaleInRank(alloc, tainted) {
+			continue
+		}
+		byIndex[idx] = alloc
+	}
+
+	for _, tg := range job.TaskGroups {
+		for _, idx := range keptAllocIndexes(tg.Count, existing[tg.Name], tainted) {
+			name := fmt.Sprintf("%s.%s[%d]", job.Name, tg.Name, idx)
+			out[name] = tg
+		}
+	}
+	return out
+}
+
+// allocNameIndex returns the index of the allocation's name if the name is
+// one materialized for a task group of the job
+func allocNameIndex(job *structs.Job, alloc *structs.Allocation) (int, bool) {
+	if job.LookupTaskGroup(alloc.TaskGroup) == nil {
+		return 0, false
+	}
+	prefix := fmt.Sprintf("%s.%s[", job.Name, alloc.TaskGroup)
+	if !strings.HasPrefix(alloc.Name, prefix) || !strings.HasSuffix(alloc.Name, "]") {
+		return 0, false
+	}
+	idx, err := strconv.Atoi(alloc.Name[len(prefix) : len(alloc.Name)-1])
+	if err != nil || idx < 0 {
+		return 0, false
+	}
+	return idx, true
+}
+
+// keptAllocIndexes returns the name indexes a task group with the given count
+// requires, given its existing allocations by name index
+func keptAllocIndexes(count int, existing map[int]*structs.Allocation,
+	tainted map[string]*structs.Node) []int {
+	candidates := make(scaleInCandidates, 0, len(existing))
+	for idx, alloc := range existing {
+		candidates = append(candidates, scaleInCandidate{
+			index: idx,
+			rank:  scaleInRank(alloc, tainted),
+		})
+	}
+	sort.Sort(candidates)
+	if len(candidates) > count {
+		candidates = candidates[:count]
+	}
+
+	kept := make(map[int]struct{}, count)
+	indexes := make([]int, 0, count)
+	for _, c := range candidates {
+		kept[c.index] = struct{}{}
+		indexes = append(indexes, c.index)
+	}
+	for idx := 0; len(indexes) < count; idx++ {
+		if _, ok := kept[idx]; !ok {
+			indexes = append(indexes, idx)
+		}
+	}
+	return indexes
+}
+
+// scaleInRank ranks how useful an allocation is to keep when scaling in. Lower
+// ranks are kept first.
+func scaleInRank(alloc *structs.Allocation, tainted map[string]*structs.Node) int {
+	rank := 0
+	if _, ok := tainted[alloc.NodeID]; ok {
+		rank += 3
+	}
+	switch alloc.ClientStatus {
+	case structs.AllocClientStatusRunning, structs.AllocClientStatusComplete:
+	case structs.AllocClientStatusPending:
+		rank++
+	default:
+		rank += 2
+	}
+	return rank
+}
+
+// scaleInCandidate is an existing allocation name index along with the rank
+// of its allocation
+type scaleInCandidate struct {
+	index int
+	rank  int
+}
+
+// scaleInCandidates sorts candidates by rank and then by name index
+type scaleInCandidates []scaleInCandidate
+
+func (c scaleInCandidates) Len() int      { return len(c) }
+func (c scaleInCandidates) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c scaleInCandidates) Less(i, j int) bool {
+	if c[i].rank != c[j].rank {
+		return c[i].rank < c[j].rank
+	}
+	return c[i].index < c[j].index
+}
+
 // diffResult is used to return the sets that result from the diff
 type diffResult struct {
 	place, update, migrate, stop, ignore, lost []allocTuple
@@ -92,8 +223,14 @@ func diffAllocs(job *structs.Job, taintedNodes map[string]*structs.Node,
 		}
 
 		// If we are on a tainted node, we must migrate if we are a service or
-		// if the batch allocation did not finish
-		if node, ok := taintedNodes[exist.NodeID]; ok {
+		// if the batch allocation did not finish. The allocations of a
+		// draining node are only migrated once the drainer marks them.
+		node, tainted := taintedNodes[exist.NodeID]
+		if tainted && node != nil && !node.TerminalStatus() && node.DrainStrategy != nil &&
+			!exist.DesiredTransition.ShouldMigrate() {
+			tainted = false
+		}
+		if tainted {
 			// If the job is batch and finished successfully, the fact that the
 			// node is tainted does not mean it should be migrated or marked as
 			// lost as the work was already successfully finished. However for
@@ -162,7 +299,9 @@ func diffAllocs(job *structs.Job, taintedNodes map[string]*structs.Node,
 // diffResult contain the specific nodeID they should be allocated on.
 //
 // job is the job whose allocs is going to be diff-ed.
-// nodes is a list of nodes in ready state.
+// nodes is a list of nodes in ready state. Allocations on other nodes which
+// aren't tainted, such as nodes ineligible for scheduling, are left as they
+// are.
 // taintedNodes is an index of the nodes which are either down or in drain mode
 // by name.
 // allocs is a list of non terminal allocations.
@@ -177,7 +316,9 @@ func diffSystemAllocs(job *structs.Job, nodes []*structs.Node, taintedNodes map[
 		nodeAllocs[alloc.NodeID] = nallocs
 	}
 
+	eligibleNodes := make(map[string]struct{}, len(nodes))
 	for _, node := range nodes {
+		eligibleNodes[node.ID] = struct{}{}
 		if _, ok := nodeAllocs[node.ID]; !ok {
 			nodeAllocs[node.ID] = nil
 		}
@@ -193,6 +334,12 @@ func diffSystemAllocs(job *structs.Job, nodes []*structs.Node, taintedNodes map[
 		// If the node is tainted there should be no placements made
 		if _, ok := taintedNodes[nodeID]; ok {
 			diff.place = nil
+		} else if _, ok := eligibleNodes[nodeID]; !ok {
+			// Nodes that are ineligible for scheduling keep running their
+			// allocations as they are until they are eligible again
+			diff.place = nil
+			diff.ignore = append(diff.ignore, diff.update...)
+			diff.update = nil
 		} else {
 			// Mark the alloc as being for a specific node.
 			for i := range diff.place {
@@ -219,43 +366,93 @@ func diffSystemAllocs(job *structs.Job, nodes []*structs.Node, taintedNodes map[
 }
 
 // readyNodesInDCs returns all the ready nodes in the given datacenters and a
-// mapping of each data center to the count of ready nodes.
+// mapping of each data center to the count of ready nodes. Datacenters may be
+// given as glob patterns (e.g. "us-east-*"), with "*" matching every
+// datacenter. Each node is returned at most once even if it is matched by
+// multiple patterns.
 func readyNodesInDCs(state State, dcs []string) ([]*structs.Node, map[string]int, error) {
-	// Index the DCs
+	// Nodes under hard maintenance are not ready for placements
+	windows, err := maintenanceWindowsInEffect(state, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Index the DCs. Exact names are seeded with a zero count so that callers
+	// can see datacenters without any ready nodes, while patterns are only
+	// expanded as matching nodes are found.
 	dcMap := make(map[string]int, len(dcs))
+	var exact, patterns []string
 	for _, dc := range dcs {
-		dcMap[dc] = 0
+		if structs.IsDatacenterPattern(dc) {
+			patterns = append(patterns, dc)
+			continue
+		}
+		if _, ok := dcMap[dc]; !ok {
+			exact = append(exact, dc)
+			dcMap[dc] = 0
+		}
 	}
 
-	// Scan the nodes
 	var out []*structs.Node
+	addNode := func(node *structs.Node) {
+		if node.Drain || !node.SchedulingEligible() || underHardMaintenance(windows, node) {
+			return
+		}
+		out = append(out, node)
+		dcMap[node.Datacenter] += 1
+	}
+
+	// Only scan the ready nodes of the exact datacenters
+	for _, dc := range exact {
+		iter, err := state.NodesByDatacenterStatus(dc, structs.NodeStatusReady)
+		if err != nil {
+			return nil, nil, err
+		}
+		for raw := iter.Next(); raw != nil; raw = iter.Next() {
+			addNode(raw.(*structs.Node))
+		}
+	}
+	if len(patterns) == 0 {
+		return out, dcMap, nil
+	}
+
+	// Patterns require scanning all the nodes, skipping those of the exact
+	// datacenters which were already added
+	exactDCs := make(map[string]struct{}, len(exact))
+	for _, dc := range exact {
+		exactDCs[dc] = struct{}{}
+	}
 	iter, err := state.Nodes()
 	if err != nil {
 		return nil, nil, err
 	}
-	for {
-		raw := iter.Next()
-		if raw == nil {
-			break
-		}
-
-		// Filter on datacenter and status
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
 		node := raw.(*structs.Node)
 		if node.Status != structs.NodeStatusReady {
 			continue
 		}
-		if node.Drain {
+		if _, ok := exactDCs[node.Datacenter]; ok {
 			continue
 		}
-		if _, ok := dcMap[node.Datacenter]; !ok {
+		if !matchesDatacenterPattern(node.Datacenter, patterns) {
 			continue
 		}
-		out = append(out, node)
-		dcMap[node.Datacenter] += 1
+		addNode(node)
 	}
 	return out, dcMap, nil
 }
 
+// matchesDatacenterPattern returns whether the datacenter matches any of the
+// passed glob patterns.
+func matchesDatacenterPattern(dc string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if structs.DatacenterMatches(pattern, dc) {
+			return true
+		}
+	}
+	return false
+}
+
 // retryMax is used to retry a callback until it returns success or
 // a maximum number of attempts is reached. An optional reset function may be
 // passed which is called after each failed iteration. If the reset function is
@@ -295,6 +492,11 @@ func progressMade(result *structs.PlanResult) bool {
 // underlying nodes are tainted, and should force a migration of the allocation.
 // All the nodes returned in the map are tainted.
 func taintedNodes(state State, allocs []*structs.Allocation) (map[string]*structs.Node, error) {
+	windows, err := maintenanceWindowsInEffect(state, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	out := make(map[string]*structs.Node)
 	for _, alloc := range allocs {
 		if _, ok := out[alloc.NodeID]; ok {
@@ -311,20 +513,66 @@ func taintedNodes(state State, allocs []*structs.Allocation) (map[string]*struct
 			out[alloc.NodeID] = nil
 			continue
 		}
-		if structs.ShouldDrainNode(node.Status) || node.Drain {
+		if underHardMaintenance(windows, node) && node.DrainStrategy != nil {
+			// The allocations of nodes under hard maintenance are migrated
+			// right away, without waiting for the drainer to mark them
+			node = node.Copy()
+			node.DrainStrategy = nil
+		}
+		if structs.ShouldDrainNode(node.Status) || node.Drain || underHardMaintenance(windows, node) {
 			out[alloc.NodeID] = node
 		}
 	}
 	return out, nil
 }
 
-// shuffleNodes randomizes the slice order with the Fisher-Yates algorithm
-func shuffleNodes(nodes []*structs.Node) {
-	n := len(nodes)
-	for i := n - 1; i > 0; i-- {
-		j := rand.Intn(i + 1)
-		nodes[i], nodes[j] = nodes[j], nodes[i]
+// maintenanceWindowsInEffect returns the maintenance windows in effect at the
+// given time by node class. When several windows of a node class are in
+// effect, a hard window takes precedence over a soft one.
+func maintenanceWindowsInEffect(state State, now time.Time) (map[string]*structs.MaintenanceWindow, error) {
+	iter, err := state.MaintenanceWindows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance windows: %v", err)
+	}
+
+	out := make(map[string]*structs.MaintenanceWindow)
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		window := raw.(*structs.MaintenanceWindow)
+		if !window.InEffect(now) {
+			continue
+		}
+		if existing, ok := out[window.NodeClass]; ok && existing.Mode == structs.MaintenanceModeHard {
+			continue
+		}
+		out[window.NodeClass] = window
+	}
+	return out, nil
+}
+
+// underHardMaintenance returns if the node belongs to a node class with a
+// hard maintenance window in effect
+func underHardMaintenance(windows map[string]*structs.MaintenanceWindow, node *structs.Node) bool {
+	window, ok := windows[node.NodeClass]
+	return ok && window.Mode == structs.MaintenanceModeHard
+}
+
+// sortedMaintenanceWindows returns the maintenance windows in effect ordered
+// by node class, as listed in the plan annotations
+func sortedMaintenanceWindows(windows map[string]*structs.MaintenanceWindow) []*structs.MaintenanceWindow {
+	if len(windows) == 0 {
+		return nil
+	}
+	classes := make([]string, 0, len(windows))
+	for class := range windows {
+		classes = append(classes, class)
 	}
+	sort.Strings(classes)
+
+	out := make([]*structs.MaintenanceWindow, 0, len(classes))
+	for _, class := range classes {
+		out = append(out, windows[class])
+	}
+	return out
 }
 
 // tasksUpdated does a diff between task groups to see if the
@@ -340,6 +588,11 @@ func tasksUpdated(a, b *structs.TaskGroup) bool {
 		return true
 	}
 
+	// Check the networks shared by the tasks
+	if networksUpdated(a.Networks, b.Networks) {
+		return true
+	}
+
 	// Check each task
 	for _, at := range a.Tasks {
 		bt := b.LookupTask(at.Name)
@@ -367,23 +620,16 @@ func tasksUpdated(a, b *structs.TaskGroup) bool {
 		if !reflect.DeepEqual(at.Vault, bt.Vault) {
 			return true
 		}
-
-		// Inspect the network to see if the dynamic ports are different
-		if len(at.Resources.Networks) != len(bt.Resources.Networks) {
+		if !reflect.DeepEqual(at.Consul, bt.Consul) {
+			return true
+		}
+		if !reflect.DeepEqual(at.Lifecycle, bt.Lifecycle) {
 			return true
 		}
-		for idx := range at.Resources.Networks {
-			an := at.Resources.Networks[idx]
-			bn := bt.Resources.Networks[idx]
-
-			if an.MBits != bn.MBits {
-				return true
-			}
 
-			aPorts, bPorts := networkPortMap(an), networkPortMap(bn)
-			if !reflect.DeepEqual(aPorts, bPorts) {
-				return true
-			}
+		// Inspect the network to see if the dynamic ports are different
+		if networksUpdated(at.Resources.Networks, bt.Resources.Networks) {
+			return true
 		}
 
 		// Inspect the non-network resources
@@ -398,6 +644,31 @@ func tasksUpdated(a, b *structs.TaskGroup) bool {
 	return false
 }
 
+// networksUpdated returns whether the networks differ in a way that requires
+// the allocation to be replaced
+func networksUpdated(a, b []*structs.NetworkResource) bool {
+	if len(a) != len(b) {
+		return true
+	}
+	for idx := range a {
+		an := a[idx]
+		bn := b[idx]
+
+		if an.MBits != bn.MBits || an.Mode != bn.Mode {
+			return true
+		}
+
+		aPorts, bPorts := networkPortMap(an), networkPortMap(bn)
+		if !reflect.DeepEqual(aPorts, bPorts) {
+			return true
+		}
+		if !reflect.DeepEqual(networkPortMappings(an), networkPortMappings(bn)) {
+			return true
+		}
+	}
+	return false
+}
+
 // networkPortMap takes a network resource and returns a map of port labels to
 // values. The value for dynamic ports is disregarded even if it is set. This
 // makes this function suitable for comparing two network resources for changes.
@@ -412,11 +683,23 @@ func networkPortMap(n *structs.NetworkResource) map[string]int {
 	return m
 }
 
+// networkPortMappings returns a map of port labels to the port inside the
+// network namespace of the allocation they are mapped to
+func networkPortMappings(n *structs.NetworkResource) map[string]int {
+	m := make(map[string]int)
+	for _, p := range append(n.ReservedPorts, n.DynamicPorts...) {
+		if p.To != 0 {
+			m[p.Label] = p.To
+		}
+	}
+	return m
+}
+
 // setStatus is used to update the status of the evaluation
 func setStatus(logger *log.Logger, planner Planner,
 	eval, nextEval, spawnedBlocked *structs.Evaluation,
 	tgMetrics map[string]*structs.AllocMetric, status, desc string,
-	queuedAllocs map[string]int) error {
+	queuedAllocs map[string]int, timing *structs.EvalTiming) error {
 
 	logger.Printf("[DEBUG] sched: %#v: setting status to %s", eval, status)
 	newEval := eval.Copy()
@@ -432,6 +715,9 @@ func setStatus(logger *log.Logger, planner Planner,
 	if queuedAllocs != nil {
 		newEval.QueuedAllocations = queuedAllocs
 	}
+	if timing != nil {
+		newEval.Timing = timing.Copy()
+	}
 
 	return planner.UpdateEval(newEval)
 }
@@ -506,6 +792,7 @@ func inplaceUpdate(ctx Context, eval *structs.Evaluation, job *structs.Job,
 		newAlloc.TaskResources = option.TaskResources
 		newAlloc.Metrics = ctx.Metrics()
 		ctx.Plan().AppendAlloc(newAlloc)
+		ctx.Plan().AppendNodeIndex(option.Node)
 
 		// Remove this allocation from the slice
 		updates[i], updates[n-1] = updates[n-1], updates[i]
@@ -545,6 +832,14 @@ func markLostAndPlace(ctx Context, diff *diffResult, allocs []allocTuple, desc s
 	for i := 0; i < n && i < *limit; i++ {
 		a := allocs[i]
 		ctx.Plan().AppendUpdate(a.Alloc, structs.AllocDesiredStatusStop, desc, structs.AllocClientStatusLost)
+
+		// Reflect the lost status in the placement so the replacement is
+		// tracked as a reschedule of the lost allocation.
+		lost := new(structs.Allocation)
+		*lost = *a.Alloc
+		lost.ClientStatus = structs.AllocClientStatusLost
+		a.Alloc = lost
+
 		diff.place = append(diff.place, a)
 	}
 	if n <= *limit {
@@ -555,6 +850,51 @@ func markLostAndPlace(ctx Context, diff *diffResult, allocs []allocTuple, desc s
 	return true
 }
 
+// nodeConstraintViolations removes from the ignored allocations of the diff
+// the ones whose node no longer satisfies the constraints of their job, task
+// group or tasks, for instance after the meta or attributes of the node
+// changed, and returns them.
+func nodeConstraintViolations(ctx Context, job *structs.Job, diff *diffResult) ([]allocTuple, error) {
+	if job == nil {
+		return nil, nil
+	}
+
+	checker := NewConstraintChecker(ctx, nil)
+	var ignore, violations []allocTuple
+	for _, tuple := range diff.ignore {
+		// Completed batch allocations are not run again
+		if tuple.TaskGroup == nil || tuple.Alloc.RanSuccessfully() {
+			ignore = append(ignore, tuple)
+			continue
+		}
+
+		node, err := ctx.State().NodeByID(tuple.Alloc.NodeID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node %q: %v", tuple.Alloc.NodeID, err)
+		}
+		if node == nil || meetsConstraints(checker, job, tuple.TaskGroup, node) {
+			ignore = append(ignore, tuple)
+			continue
+		}
+		violations = append(violations, tuple)
+	}
+	diff.ignore = ignore
+	return violations, nil
+}
+
+// meetsConstraints returns whether the node satisfies the constraints of the
+// job, the task group and its tasks
+func meetsConstraints(checker *ConstraintChecker, job *structs.Job, tg *structs.TaskGroup, node *structs.Node) bool {
+	constraints := append([]*structs.Constraint(nil), job.Constraints...)
+	constraints = append(constraints, taskGroupConstraints(tg).constraints...)
+	for _, constraint := range constraints {
+		if !checker.meetsConstraint(constraint, node) {
+			return false
+		}
+	}
+	return true
+}
+
 // tgConstrainTuple is used to store the total constraints of a task group.
 type tgConstrainTuple struct {
 	// Holds the combined constraints of the task group and all it's sub-tasks.
@@ -563,22 +903,41 @@ type tgConstrainTuple struct {
 	// The set of required drivers within the task group.
 	drivers map[string]struct{}
 
+	// The set of capabilities required of each driver within the task group.
+	capabilities map[string]map[string]struct{}
+
 	// The combined resources of all tasks within the task group.
 	size *structs.Resources
 }
 
-// taskGroupConstraints collects the constraints, drivers and resources required by each
+// taskGroupConstraints collects the constraints, drivers, driver capabilities and resources required by each
 // sub-task to aggregate the TaskGroup totals
 func taskGroupConstraints(tg *structs.TaskGroup) tgConstrainTuple {
 	c := tgConstrainTuple{
-		constraints: make([]*structs.Constraint, 0, len(tg.Constraints)),
-		drivers:     make(map[string]struct{}),
-		size:        &structs.Resources{DiskMB: tg.EphemeralDisk.SizeMB},
+		constraints:  make([]*structs.Constraint, 0, len(tg.Constraints)),
+		drivers:      make(map[string]struct{}),
+		capabilities: make(map[string]map[string]struct{}),
+		size:         &structs.Resources{DiskMB: tg.EphemeralDisk.SizeMB},
 	}
 
 	c.constraints = append(c.constraints, tg.Constraints...)
+	c.size.Add(&structs.Resources{Networks: tg.Networks})
+	isolated := tg.IsolatedNetwork() != nil
 	for _, task := range tg.Tasks {
 		c.drivers[task.Driver] = struct{}{}
+
+		// Tasks joining the network namespace of the allocation require their
+		// driver to support the group network isolation mode
+		capabilities := task.RequiredDriverCapabilities()
+		if isolated {
+			capabilities = append(capabilities, structs.DriverCapabilityNetworkIsolation)
+		}
+		for _, capability := range capabilities {
+			if _, ok := c.capabilities[task.Driver]; !ok {
+				c.capabilities[task.Driver] = make(map[string]struct{})
+			}
+			c.capabilities[task.Driver][capability] = struct{}{}
+		}
 		c.constraints = append(c.constraints, task.Constraints...)
 		c.size.Add(task.Resources)
 	}
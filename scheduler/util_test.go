@@ -6,6 +6,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/state"
@@ -36,6 +37,80 @@ func TestMaterializeTaskGroups(t *testing.T) {
 			t.Fatalf("bad")
 		}
 	}
+
+	// Parameterized jobs are only placed once dispatched
+	job.ParameterizedJob = &structs.ParameterizedJobConfig{}
+	if index := materializeTaskGroups(job); len(index) != 0 {
+		t.Fatalf("Bad: %#v", index)
+	}
+	job.Dispatched = true
+	if index := materializeTaskGroups(job); len(index) != 10 {
+		t.Fatalf("Bad: %#v", index)
+	}
+}
+
+func TestMaterializeScaledTaskGroups(t *testing.T) {
+	job := mock.Job()
+	tainted := map[string]*structs.Node{"dead": nil}
+
+	var allocs []*structs.Allocation
+	for i := 0; i < 10; i++ {
+		alloc := mock.Alloc()
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.Name = fmt.Sprintf("my-job.web[%d]", i)
+		alloc.ClientStatus = structs.AllocClientStatusRunning
+		allocs = append(allocs, alloc)
+	}
+	allocs[2].ClientStatus = structs.AllocClientStatusPending
+	allocs[5].NodeID = "dead"
+
+	// Allocations of another job name are not kept
+	other := mock.Alloc()
+	other.Name = "old-job.web[11]"
+	allocs = append(allocs, other)
+
+	// Without scaling, all the names are required
+	index := materializeScaledTaskGroups(job, allocs, tainted)
+	if !reflect.DeepEqual(index, materializeTaskGroups(job)) {
+		t.Fatalf("Bad: %#v", index)
+	}
+
+	// Scaling in keeps the running allocations with the lowest indexes
+	job.TaskGroups[0].Count = 7
+	index = materializeScaledTaskGroups(job, allocs, tainted)
+	if len(index) != 7 {
+		t.Fatalf("Bad: %#v", index)
+	}
+	for _, i := range []int{0, 1, 3, 4, 6, 7, 8} {
+		if _, ok := index[fmt.Sprintf("my-job.web[%d]", i)]; !ok {
+			t.Fatalf("expected index %d to be kept: %#v", i, index)
+		}
+	}
+
+	// Scaling out fills the lowest free indexes
+	var kept []*structs.Allocation
+	for _, alloc := range allocs[:9] {
+		if _, ok := index[alloc.Name]; ok {
+			kept = append(kept, alloc)
+		}
+	}
+	job.TaskGroups[0].Count = 8
+	index = materializeScaledTaskGroups(job, kept, tainted)
+	if len(index) != 8 {
+		t.Fatalf("Bad: %#v", index)
+	}
+	for _, i := range []int{0, 1, 2, 3, 4, 6, 7, 8} {
+		if _, ok := index[fmt.Sprintf("my-job.web[%d]", i)]; !ok {
+			t.Fatalf("expected index %d to be required: %#v", i, index)
+		}
+	}
+
+	// Stopped jobs require no task groups
+	job.Stop = true
+	if index := materializeScaledTaskGroups(job, allocs, tainted); len(index) != 0 {
+		t.Fatalf("Bad: %#v", index)
+	}
 }
 
 func TestDiffAllocs(t *testing.T) {
@@ -172,6 +247,50 @@ func TestDiffAllocs(t *testing.T) {
 	}
 }
 
+func TestDiffAllocs_DrainStrategy(t *testing.T) {
+	job := mock.Job()
+	required := materializeTaskGroups(job)
+
+	// Allocations on a node drained with a drain strategy are only migrated
+	// once they are marked by the drainer
+	drainNode := mock.Node()
+	drainNode.Drain = true
+	drainNode.DrainStrategy = &structs.DrainStrategy{}
+	tainted := map[string]*structs.Node{drainNode.ID: drainNode}
+
+	marked := &structs.Allocation{
+		ID:                structs.GenerateUUID(),
+		NodeID:            drainNode.ID,
+		Name:              "my-job.web[0]",
+		Job:               job,
+		DesiredTransition: structs.DesiredTransition{Migrate: true},
+	}
+	unmarked := &structs.Allocation{
+		ID:     structs.GenerateUUID(),
+		NodeID: drainNode.ID,
+		Name:   "my-job.web[1]",
+		Job:    job,
+	}
+	allocs := []*structs.Allocation{marked, unmarked}
+
+	diff := diffAllocs(job, tainted, required, allocs, nil)
+	if len(diff.migrate) != 1 || diff.migrate[0].Alloc != marked {
+		t.Fatalf("bad: %#v", diff.migrate)
+	}
+	if len(diff.ignore) != 1 || diff.ignore[0].Alloc != unmarked {
+		t.Fatalf("bad: %#v", diff.ignore)
+	}
+
+	// Once the node is down, all of its allocations are lost
+	downNode := drainNode.Copy()
+	downNode.Status = structs.NodeStatusDown
+	tainted[downNode.ID] = downNode
+	diff = diffAllocs(job, tainted, required, allocs, nil)
+	if len(diff.lost) != 2 || len(diff.migrate) != 0 {
+		t.Fatalf("bad: %#v", diff)
+	}
+}
+
 func TestDiffSystemAllocs(t *testing.T) {
 	job := mock.SystemJob()
 
@@ -289,6 +408,42 @@ func TestDiffSystemAllocs(t *testing.T) {
 	}
 }
 
+func TestDiffSystemAllocs_IneligibleNode(t *testing.T) {
+	job := mock.SystemJob()
+
+	// The "old" job has a previous modify index
+	oldJob := new(structs.Job)
+	*oldJob = *job
+	oldJob.JobModifyIndex -= 1
+
+	// Only "foo" is eligible for scheduling
+	nodes := []*structs.Node{{ID: "foo"}}
+	allocs := []*structs.Allocation{
+		// Outdated allocation on the ineligible node
+		&structs.Allocation{
+			ID:     structs.GenerateUUID(),
+			NodeID: "bar",
+			Name:   "my-job.web[0]",
+			Job:    oldJob,
+		},
+	}
+
+	diff := diffSystemAllocs(job, nodes, nil, allocs, nil)
+
+	// The allocation on the ineligible node is left running as it is
+	if len(diff.update) != 0 || len(diff.stop) != 0 {
+		t.Fatalf("bad: %#v", diff)
+	}
+	if len(diff.ignore) != 1 || diff.ignore[0].Alloc != allocs[0] {
+		t.Fatalf("bad: %#v", diff.ignore)
+	}
+
+	// Only the eligible node gets a placement
+	if len(diff.place) != 1 || diff.place[0].Alloc.NodeID != "foo" {
+		t.Fatalf("bad: %#v", diff.place)
+	}
+}
+
 func TestReadyNodesInDCs(t *testing.T) {
 	state, err := state.NewStateStore(os.Stderr)
 	if err != nil {
@@ -303,11 +458,14 @@ func TestReadyNodesInDCs(t *testing.T) {
 	node3.Status = structs.NodeStatusDown
 	node4 := mock.Node()
 	node4.Drain = true
+	node5 := mock.Node()
+	node5.SchedulingEligibility = structs.NodeSchedulingIneligible
 
 	noErr(t, state.UpsertNode(1000, node1))
 	noErr(t, state.UpsertNode(1001, node2))
 	noErr(t, state.UpsertNode(1002, node3))
 	noErr(t, state.UpsertNode(1003, node4))
+	noErr(t, state.UpsertNode(1004, node5))
 
 	nodes, dc, err := readyNodesInDCs(state, []string{"dc1", "dc2"})
 	if err != nil {
@@ -328,6 +486,146 @@ func TestReadyNodesInDCs(t *testing.T) {
 	}
 }
 
+func TestReadyNodesInDCs_Wildcard(t *testing.T) {
+	state, err := state.NewStateStore(os.Stderr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	node1 := mock.Node()
+	node1.Datacenter = "us-east-1"
+	node2 := mock.Node()
+	node2.Datacenter = "us-east-2"
+	node3 := mock.Node()
+	node3.Datacenter = "us-west-1"
+	node4 := mock.Node()
+	node4.Datacenter = "us-east-1"
+	node4.Status = structs.NodeStatusDown
+
+	noErr(t, state.UpsertNode(1000, node1))
+	noErr(t, state.UpsertNode(1001, node2))
+	noErr(t, state.UpsertNode(1002, node3))
+	noErr(t, state.UpsertNode(1003, node4))
+
+	// Overlapping patterns should not return a node twice
+	nodes, dc, err := readyNodesInDCs(state, []string{"us-east-*", "us-east-1", "eu-*"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("bad: %v", nodes)
+	}
+	if count, ok := dc["us-east-1"]; !ok || count != 1 {
+		t.Fatalf("Bad: us-east-1 count %v", count)
+	}
+	if count, ok := dc["us-east-2"]; !ok || count != 1 {
+		t.Fatalf("Bad: us-east-2 count %v", count)
+	}
+	if _, ok := dc["eu-*"]; ok {
+		t.Fatalf("Bad: pattern should not be counted: %v", dc)
+	}
+	if _, ok := dc["us-west-1"]; ok {
+		t.Fatalf("Bad: unmatched datacenter counted: %v", dc)
+	}
+
+	// The wildcard matches every datacenter
+	nodes, dc, err = readyNodesInDCs(state, []string{"*"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(nodes) != 3 {
+		t.Fatalf("bad: %v", nodes)
+	}
+	if len(dc) != 3 {
+		t.Fatalf("bad: %v", dc)
+	}
+}
+
+func TestReadyNodesInDCs_Maintenance(t *testing.T) {
+	state, err := state.NewStateStore(os.Stderr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	node1 := mock.Node()
+	node2 := mock.Node()
+	node2.NodeClass = "hard"
+	node3 := mock.Node()
+	node3.NodeClass = "soft"
+	noErr(t, state.UpsertNode(1000, node1))
+	noErr(t, state.UpsertNode(1001, node2))
+	noErr(t, state.UpsertNode(1002, node3))
+
+	hard := mock.MaintenanceWindow()
+	hard.NodeClass = "hard"
+	soft := mock.MaintenanceWindow()
+	soft.NodeClass = "soft"
+	soft.Mode = structs.MaintenanceModeSoft
+	noErr(t, state.UpsertMaintenanceWindows(1003, []*structs.MaintenanceWindow{hard, soft}))
+
+	// Only the nodes under hard maintenance are not ready
+	nodes, dc, err := readyNodesInDCs(state, []string{"dc1"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(nodes) != 2 || nodes[0].ID == node2.ID || nodes[1].ID == node2.ID {
+		t.Fatalf("bad: %v", nodes)
+	}
+	if count := dc["dc1"]; count != 2 {
+		t.Fatalf("Bad: dc1 count %v", count)
+	}
+
+	// Their allocations are migrated
+	allocs := []*structs.Allocation{
+		&structs.Allocation{NodeID: node1.ID},
+		&structs.Allocation{NodeID: node2.ID},
+		&structs.Allocation{NodeID: node3.ID},
+	}
+	tainted, err := taintedNodes(state, allocs)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if node, ok := tainted[node2.ID]; !ok || node == nil || len(tainted) != 1 {
+		t.Fatalf("Bad: %v", tainted)
+	}
+}
+
+func TestMaintenanceWindowsInEffect(t *testing.T) {
+	state, err := state.NewStateStore(os.Stderr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	now := time.Now()
+	soft := mock.MaintenanceWindow()
+	soft.Mode = structs.MaintenanceModeSoft
+	hard := mock.MaintenanceWindow()
+	hard.Start = now.Add(10 * time.Minute)
+	hard.MigrateBefore = 15 * time.Minute
+	ended := mock.MaintenanceWindow()
+	ended.NodeClass = "ended"
+	ended.End = now.Add(-30 * time.Minute)
+	noErr(t, state.UpsertMaintenanceWindows(1000, []*structs.MaintenanceWindow{soft, hard, ended}))
+
+	// The hard window takes precedence and is in effect ahead of its start
+	windows, err := maintenanceWindowsInEffect(state, now)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(windows) != 1 || windows[soft.NodeClass].ID != hard.ID {
+		t.Fatalf("bad: %#v", windows)
+	}
+
+	// Only the soft window is in effect before that
+	windows, err = maintenanceWindowsInEffect(state, now.Add(-10*time.Minute))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(windows) != 1 || windows[soft.NodeClass].ID != soft.ID {
+		t.Fatalf("bad: %#v", windows)
+	}
+}
+
 func TestRetryMax(t *testing.T) {
 	calls := 0
 	bad := func() (bool, error) {
@@ -428,26 +726,39 @@ func TestTaintedNodes(t *testing.T) {
 	}
 }
 
-func TestShuffleNodes(t *testing.T) {
-	// Use a large number of nodes to make the probability of shuffling to the
-	// original order very low.
-	nodes := []*structs.Node{
-		mock.Node(),
-		mock.Node(),
-		mock.Node(),
-		mock.Node(),
-		mock.Node(),
-		mock.Node(),
-		mock.Node(),
-		mock.Node(),
-		mock.Node(),
-		mock.Node(),
-	}
-	orig := make([]*structs.Node, len(nodes))
-	copy(orig, nodes)
-	shuffleNodes(nodes)
-	if reflect.DeepEqual(nodes, orig) {
-		t.Fatalf("should not match")
+func TestTaintedNodes_HardMaintenance(t *testing.T) {
+	state, err := state.NewStateStore(os.Stderr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A draining node under hard maintenance has its allocations migrated
+	// without waiting for the drainer
+	node := mock.Node()
+	node.NodeClass = "maint"
+	noErr(t, state.UpsertNode(1000, node))
+	noErr(t, state.UpdateNodeDrain(1001, node.ID, &structs.DrainStrategy{}, nil))
+
+	window := mock.MaintenanceWindow()
+	window.NodeClass = node.NodeClass
+	noErr(t, state.UpsertMaintenanceWindows(1002, []*structs.MaintenanceWindow{window}))
+
+	tainted, err := taintedNodes(state, []*structs.Allocation{{NodeID: node.ID}})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, ok := tainted[node.ID]
+	if !ok || out == nil || out.DrainStrategy != nil {
+		t.Fatalf("bad: %#v", tainted)
+	}
+
+	// The node in the state is left untouched
+	stored, err := state.NodeByID(node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if stored.DrainStrategy == nil {
+		t.Fatalf("bad: %#v", stored)
 	}
 }
 
@@ -484,7 +795,7 @@ func TestTasksUpdated(t *testing.T) {
 	}
 
 	j6 := mock.Job()
-	j6.TaskGroups[0].Tasks[0].Resources.Networks[0].DynamicPorts = []structs.Port{{"http", 0}, {"https", 0}, {"admin", 0}}
+	j6.TaskGroups[0].Tasks[0].Resources.Networks[0].DynamicPorts = []structs.Port{{"http", 0, 0, ""}, {"https", 0, 0, ""}, {"admin", 0, 0, ""}}
 	if !tasksUpdated(j1.TaskGroups[0], j6.TaskGroups[0]) {
 		t.Fatalf("bad")
 	}
@@ -552,6 +863,36 @@ func TestTasksUpdated(t *testing.T) {
 	if !tasksUpdated(j1.TaskGroups[0], j16.TaskGroups[0]) {
 		t.Fatal("bad")
 	}
+
+	j17 := mock.Job()
+	j17.TaskGroups[0].Networks = []*structs.NetworkResource{
+		{
+			Mode:         structs.NetworkModeBridge,
+			MBits:        10,
+			DynamicPorts: []structs.Port{{Label: "web", To: 8080}},
+		},
+	}
+	if !tasksUpdated(j1.TaskGroups[0], j17.TaskGroups[0]) {
+		t.Fatal("bad")
+	}
+
+	j18 := j17.Copy()
+	j18.TaskGroups[0].Networks[0].DynamicPorts[0].To = 9090
+	if !tasksUpdated(j17.TaskGroups[0], j18.TaskGroups[0]) {
+		t.Fatal("bad")
+	}
+
+	j19 := mock.Job()
+	j19.TaskGroups[0].Tasks[0].Consul = &structs.Consul{Cluster: "secondary"}
+	if !tasksUpdated(j1.TaskGroups[0], j19.TaskGroups[0]) {
+		t.Fatal("bad")
+	}
+
+	j20 := mock.Job()
+	j20.TaskGroups[0].Tasks[0].Lifecycle = &structs.TaskLifecycleConfig{Hook: structs.TaskLifecycleHookPrestart}
+	if !tasksUpdated(j1.TaskGroups[0], j20.TaskGroups[0]) {
+		t.Fatal("bad")
+	}
 }
 
 func TestEvictAndPlace_LimitLessThanAllocs(t *testing.T) {
@@ -608,7 +949,7 @@ func TestSetStatus(t *testing.T) {
 	eval := mock.Eval()
 	status := "a"
 	desc := "b"
-	if err := setStatus(logger, h, eval, nil, nil, nil, status, desc, nil); err != nil {
+	if err := setStatus(logger, h, eval, nil, nil, nil, status, desc, nil, nil); err != nil {
 		t.Fatalf("setStatus() failed: %v", err)
 	}
 
@@ -624,7 +965,7 @@ func TestSetStatus(t *testing.T) {
 	// Test next evals
 	h = NewHarness(t)
 	next := mock.Eval()
-	if err := setStatus(logger, h, eval, next, nil, nil, status, desc, nil); err != nil {
+	if err := setStatus(logger, h, eval, next, nil, nil, status, desc, nil, nil); err != nil {
 		t.Fatalf("setStatus() failed: %v", err)
 	}
 
@@ -640,7 +981,7 @@ func TestSetStatus(t *testing.T) {
 	// Test blocked evals
 	h = NewHarness(t)
 	blocked := mock.Eval()
-	if err := setStatus(logger, h, eval, nil, blocked, nil, status, desc, nil); err != nil {
+	if err := setStatus(logger, h, eval, nil, blocked, nil, status, desc, nil, nil); err != nil {
 		t.Fatalf("setStatus() failed: %v", err)
 	}
 
@@ -656,7 +997,7 @@ func TestSetStatus(t *testing.T) {
 	// Test metrics
 	h = NewHarness(t)
 	metrics := map[string]*structs.AllocMetric{"foo": nil}
-	if err := setStatus(logger, h, eval, nil, nil, metrics, status, desc, nil); err != nil {
+	if err := setStatus(logger, h, eval, nil, nil, metrics, status, desc, nil, nil); err != nil {
 		t.Fatalf("setStatus() failed: %v", err)
 	}
 
@@ -673,7 +1014,7 @@ func TestSetStatus(t *testing.T) {
 	h = NewHarness(t)
 	queuedAllocs := map[string]int{"web": 1}
 
-	if err := setStatus(logger, h, eval, nil, nil, metrics, status, desc, queuedAllocs); err != nil {
+	if err := setStatus(logger, h, eval, nil, nil, metrics, status, desc, queuedAllocs, nil); err != nil {
 		t.Fatalf("setStatus() failed: %v", err)
 	}
 
@@ -685,6 +1026,22 @@ func TestSetStatus(t *testing.T) {
 	if !reflect.DeepEqual(newEval.QueuedAllocations, queuedAllocs) {
 		t.Fatalf("setStatus() didn't set failed task group metrics correctly: %v", newEval)
 	}
+
+	// Test timing
+	h = NewHarness(t)
+	timing := &structs.EvalTiming{Feasibility: time.Second, Ranking: 2 * time.Second}
+	if err := setStatus(logger, h, eval, nil, nil, nil, status, desc, nil, timing); err != nil {
+		t.Fatalf("setStatus() failed: %v", err)
+	}
+
+	if len(h.Evals) != 1 {
+		t.Fatalf("setStatus() didn't update plan: %v", h.Evals)
+	}
+
+	newEval = h.Evals[0]
+	if !reflect.DeepEqual(newEval.Timing, timing) || newEval.Timing == timing {
+		t.Fatalf("setStatus() didn't set timing correctly: %v", newEval)
+	}
 }
 
 func TestInplaceUpdate_ChangedTaskGroup(t *testing.T) {
@@ -932,6 +1289,7 @@ func TestTaskGroupConstraints(t *testing.T) {
 					MemoryMB: 256,
 				},
 				Constraints: []*structs.Constraint{constr3},
+				Schedule:    &structs.TaskSchedule{},
 			},
 		},
 	}
@@ -939,6 +1297,9 @@ func TestTaskGroupConstraints(t *testing.T) {
 	// Build the expected values.
 	expConstr := []*structs.Constraint{constr, constr2, constr3}
 	expDrivers := map[string]struct{}{"exec": struct{}{}, "docker": struct{}{}}
+	expCapabilities := map[string]map[string]struct{}{
+		"docker": map[string]struct{}{structs.DriverCapabilityPause: struct{}{}},
+	}
 	expSize := &structs.Resources{
 		CPU:      1000,
 		MemoryMB: 512,
@@ -951,10 +1312,32 @@ func TestTaskGroupConstraints(t *testing.T) {
 	if !reflect.DeepEqual(actConstrains.drivers, expDrivers) {
 		t.Fatalf("taskGroupConstraints(%v) returned %v; want %v", tg, actConstrains.drivers, expDrivers)
 	}
+	if !reflect.DeepEqual(actConstrains.capabilities, expCapabilities) {
+		t.Fatalf("taskGroupConstraints(%v) returned %v; want %v", tg, actConstrains.capabilities, expCapabilities)
+	}
 	if !reflect.DeepEqual(actConstrains.size, expSize) {
 		t.Fatalf("taskGroupConstraints(%v) returned %v; want %v", tg, actConstrains.size, expSize)
 	}
 
+	// Tasks joining the network namespace of the allocation require their
+	// drivers to support it
+	tg.Networks = []*structs.NetworkResource{{Mode: structs.NetworkModeBridge, MBits: 10}}
+	expCapabilities = map[string]map[string]struct{}{
+		"exec": map[string]struct{}{structs.DriverCapabilityNetworkIsolation: struct{}{}},
+		"docker": map[string]struct{}{
+			structs.DriverCapabilityPause:            struct{}{},
+			structs.DriverCapabilityNetworkIsolation: struct{}{},
+		},
+	}
+	expSize.Networks = tg.Networks
+
+	actConstrains = taskGroupConstraints(tg)
+	if !reflect.DeepEqual(actConstrains.capabilities, expCapabilities) {
+		t.Fatalf("taskGroupConstraints(%v) returned %v; want %v", tg, actConstrains.capabilities, expCapabilities)
+	}
+	if !reflect.DeepEqual(actConstrains.size, expSize) {
+		t.Fatalf("taskGroupConstraints(%v) returned %v; want %v", tg, actConstrains.size, expSize)
+	}
 }
 
 func TestProgressMade(t *testing.T) {
@@ -40,6 +40,7 @@ type TestServerConfig struct {
 	Server            *ServerConfig `json:"server,omitempty"`
 	Client            *ClientConfig `json:"client,omitempty"`
 	Vault             *VaultConfig  `json:"vault,omitempty"`
+	ACL               *ACLConfig    `json:"acl,omitempty"`
 	DevMode           bool          `json:"-"`
 	Stdout, Stderr    io.Writer     `json:"-"`
 }
@@ -53,8 +54,9 @@ type PortsConfig struct {
 
 // ServerConfig is used to configure the nomad server.
 type ServerConfig struct {
-	Enabled         bool `json:"enabled"`
-	BootstrapExpect int  `json:"bootstrap_expect"`
+	Enabled         bool   `json:"enabled"`
+	BootstrapExpect int    `json:"bootstrap_expect"`
+	EncryptKey      string `json:"encrypt,omitempty"`
 }
 
 // ClientConfig is used to configure the client
@@ -67,6 +69,11 @@ type VaultConfig struct {
 	Enabled bool `json:"enabled"`
 }
 
+// ACLConfig is used to configure ACLs
+type ACLConfig struct {
+	Enabled bool `json:"enabled"`
+}
+
 // ServerConfigCallback is a function interface which can be
 // passed to NewTestServerConfig to modify the server config.
 type ServerConfigCallback func(c *TestServerConfig)
@@ -95,6 +102,9 @@ func defaultServerConfig() *TestServerConfig {
 		Vault: &VaultConfig{
 			Enabled: false,
 		},
+		ACL: &ACLConfig{
+			Enabled: false,
+		},
 	}
 }
 
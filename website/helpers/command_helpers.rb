@@ -9,7 +9,31 @@ module CommandHelpers
   Overrides the `NOMAD_REGION` environment variable if set. Defaults to the
   Agent's local region.
 
+* `-namespace=<namespace>`: The target namespace for queries and actions bound
+  to a namespace. Overrides the `NOMAD_NAMESPACE` environment variable if set.
+  Defaults to the `default` namespace. The namespace `*` lists the objects of
+  every namespace.
+
+* `-token=<secret-id>`: The secret ID of the ACL token used to authenticate
+  requests. Overrides the `NOMAD_TOKEN` environment variable if set.
+
 * `-no-color`: Disables colored command output.
+
+* `-ca-cert=<path>`: Path to a PEM encoded CA cert file to use to verify the
+  Nomad agent serving the HTTP API over TLS. Overrides the `NOMAD_CACERT`
+  environment variable if set.
+
+* `-client-cert=<path>`: Path to a PEM encoded client certificate presented to
+  the Nomad agent when `verify_https_client` is enabled. Must be given with
+  `-client-key`. Overrides the `NOMAD_CLIENT_CERT` environment variable if set.
+
+* `-client-key=<path>`: Path to the unencrypted PEM encoded private key
+  matching the client certificate. Overrides the `NOMAD_CLIENT_KEY`
+  environment variable if set.
+
+* `-tls-skip-verify`: Do not verify the certificate of the Nomad agent. This is
+  highly not recommended. Overrides the `NOMAD_SKIP_VERIFY` environment
+  variable if set.
 EOF
   end
 end